@@ -21,6 +21,7 @@ import (
 	"github.com/drausin/libri/libri/common/id"
 	clogging "github.com/drausin/libri/libri/common/logging"
 	"github.com/drausin/libri/libri/common/parse"
+	"github.com/drausin/libri/libri/common/storage"
 	"github.com/drausin/libri/libri/common/subscribe"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
@@ -156,9 +157,8 @@ func setUp(params *params) *state { // nolint: deadcode
 	selfPeer := peer.New(selfID.ID(), "test client", publicAddr)
 	signer := lclient.NewECDSASigner(selfID.Key())
 	orgSigner := lclient.NewECDSASigner(orgID.Key())
-	knower := comm.NewAlwaysKnower()
-	rec := comm.NewQueryRecorderGetter(knower)
-	preferer := comm.NewRpPreferer(rec)
+	preferer := comm.NewReputationPreferer(
+		comm.NewReputationJudge(&storage.TestSLD{}, comm.NewDefaultReputationParameters()))
 	doctor := comm.NewNaiveDoctor()
 	rParams := routing.NewDefaultParameters()
 
@@ -380,6 +380,7 @@ func newConfig(
 
 	introParams := introduce.NewDefaultParameters()
 	introParams.TargetNumIntroductions = 16
+	introParams.MaxStartDelay = 0
 
 	searchParams := search.NewDefaultParameters()
 
@@ -25,6 +25,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -390,7 +391,7 @@ func countDocReplicas(t *testing.T, state *state) map[string]int {
 		seeds := state.client.rt.Find(key, verifyParams.NClosestResponses)
 		v := verify.NewVerify(state.client.selfID, state.client.orgID, key, docBytes,
 			macKey, verifyParams)
-		err = verifier.Verify(v, seeds)
+		err = verifier.Verify(context.Background(), v, seeds)
 		if err == nil {
 			// don't fail if a verification occasionally errors
 			nReplicas[key.String()] = len(v.Result.Replicas)
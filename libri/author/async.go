@@ -0,0 +1,39 @@
+package author
+
+import (
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"golang.org/x/net/context"
+)
+
+// StoreAsync starts storing the document (e.g., a page of a large entry) directly with the peers
+// closest to it and immediately returns the ID of the operation continuing in the background, so
+// storing many documents doesn't block on each one. Callers poll or subscribe to the operation's
+// completion via StoreOperation and then ForgetStore it.
+func (a *Author) StoreAsync(doc *api.Document) (store.OperationID, error) {
+	if err := a.authorize(ScopeUpload); err != nil {
+		return 0, err
+	}
+	key, err := api.GetKey(doc)
+	if err != nil {
+		return 0, err
+	}
+	seeds, err := a.closest.getSeeds(key)
+	if err != nil {
+		return 0, err
+	}
+	s := store.NewStore(a.ClientID, a.orgID, key, doc, search.NewDefaultParameters(),
+		store.NewDefaultParameters())
+	return a.asyncStorer.StoreAsync(context.Background(), s, seeds), nil
+}
+
+// StoreOperation returns the async store operation with the given ID.
+func (a *Author) StoreOperation(opID store.OperationID) (*store.Operation, error) {
+	return a.asyncStorer.Operation(opID)
+}
+
+// ForgetStore drops the async store operation with the given ID once the caller is done with it.
+func (a *Author) ForgetStore(opID store.OperationID) {
+	a.asyncStorer.Forget(opID)
+}
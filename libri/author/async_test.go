@@ -0,0 +1,54 @@
+package author
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthor_StoreAsync_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a := newTestAuthor()
+	a.closest = &fixedClosestFinder{peers: peer.NewTestPeers(rng, 3)}
+	a.asyncStorer = store.NewAsyncStorer(&fixedStorer{
+		responded: peer.NewTestPeers(rng, int(store.DefaultNReplicas)),
+	})
+	doc, key := api.NewTestDocument(rng)
+
+	opID, err := a.StoreAsync(doc)
+	assert.Nil(t, err)
+	op, err := a.StoreOperation(opID)
+	assert.Nil(t, err)
+	<-op.Done()
+	assert.Nil(t, op.Err())
+	assert.Equal(t, key, op.Store.Search.Key)
+	assert.True(t, op.Store.Stored())
+
+	a.ForgetStore(opID)
+	op, err = a.StoreOperation(opID)
+	assert.Equal(t, store.ErrUnknownOperation, err)
+	assert.Nil(t, op)
+	assert.Nil(t, a.CloseAndRemove())
+}
+
+func TestAuthor_StoreAsync_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a := newTestAuthor()
+	doc, _ := api.NewTestDocument(rng)
+
+	// seeds error
+	a.closest = &fixedClosestFinder{err: errNoRepairSeeds}
+	opID, err := a.StoreAsync(doc)
+	assert.Equal(t, errNoRepairSeeds, err)
+	assert.Zero(t, opID)
+
+	// unknown operation
+	op, err := a.StoreOperation(opID)
+	assert.Equal(t, store.ErrUnknownOperation, err)
+	assert.Nil(t, op)
+	assert.Nil(t, a.CloseAndRemove())
+}
@@ -0,0 +1,159 @@
+package author
+
+import (
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"go.uber.org/zap"
+)
+
+// DocAudit is the audit result for a single document.
+type DocAudit struct {
+	// Key of the document
+	Key id.ID
+
+	// Type of the document
+	Type api.DocumentType
+
+	// NHolders is the number of peers found holding a verified replica of the document
+	NHolders int
+
+	// OK is whether the document has at least the required number of holders
+	OK bool
+
+	// Err is the error encountered, if any, when acquiring or verifying the document
+	Err error
+}
+
+// AuditReport summarizes the durability of an envelope and the entry and pages it references.
+type AuditReport struct {
+	// EnvelopeKey is the key of the audited envelope
+	EnvelopeKey id.ID
+
+	// EntryKey is the key of the entry the envelope references
+	EntryKey id.ID
+
+	// NReplicas is the number of holders each document is required to have
+	NReplicas uint
+
+	// Docs contains the audit result for the envelope, the entry, and each of its pages
+	Docs []*DocAudit
+}
+
+// OK returns whether every document has at least the required number of holders.
+func (r *AuditReport) OK() bool {
+	return r.NUnderReplicated() == 0 && r.NUnverified() == 0
+}
+
+// NUnderReplicated returns the number of documents verified to have fewer than the required number
+// of holders.
+func (r *AuditReport) NUnderReplicated() int {
+	n := 0
+	for _, da := range r.Docs {
+		if da.Err == nil && !da.OK {
+			n++
+		}
+	}
+	return n
+}
+
+// NUnverified returns the number of documents whose holders could not be determined.
+func (r *AuditReport) NUnverified() int {
+	n := 0
+	for _, da := range r.Docs {
+		if da.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Audit checks that the envelope with the given key and the entry and pages it references each
+// still have at least nReplicas holders, returning a report of the holders found for each
+// document. A zero nReplicas uses the default number of replicas. Unlike Repair, Audit stores
+// nothing, so publishers can use it to periodically check the durability of old content.
+func (a *Author) Audit(envKey id.ID, nReplicas uint) (*AuditReport, error) {
+	if err := a.authorize(ScopeDownload); err != nil {
+		return nil, a.logAndReturnErr("audit not authorized", err)
+	}
+	if nReplicas == 0 {
+		nReplicas = store.DefaultNReplicas
+	}
+	startTime := time.Now()
+	a.logger.Debug("auditing envelope", zap.Stringer(logEnvelopeKey, envKey))
+
+	rlc := client.NewRetryGetter(a.getters, true, a.config.Publish.GetTimeout)
+	envDoc, err := a.acquireDoc(envKey, rlc)
+	if err != nil {
+		return nil, a.logAndReturnErr("error acquiring envelope", err)
+	}
+	env := envDoc.GetEnvelope()
+	if env == nil {
+		return nil, a.logAndReturnErr("error auditing envelope", api.ErrUnexpectedDocumentType)
+	}
+
+	rp := &AuditReport{
+		EnvelopeKey: envKey,
+		EntryKey:    id.FromBytes(env.EntryKey),
+		NReplicas:   nReplicas,
+		Docs:        []*DocAudit{a.auditor.audit(envKey, envDoc, nReplicas)},
+	}
+	rp.Docs = append(rp.Docs, a.auditEntry(rp.EntryKey, nReplicas, rlc)...)
+
+	a.logger.Info("audited envelope", auditedEnvelopeFields(rp, time.Since(startTime))...)
+	return rp, nil
+}
+
+// auditEntry audits the entry and each of its pages. Documents that can't be acquired are
+// unverified rather than failing the whole audit, since a lost document is what an audit looks
+// for.
+func (a *Author) auditEntry(entryKey id.ID, nReplicas uint, lc api.Getter) []*DocAudit {
+	entry, err := a.acquireDoc(entryKey, lc)
+	if err != nil {
+		return []*DocAudit{{Key: entryKey, Type: api.EntryType, Err: err}}
+	}
+	pageKeys, err := api.GetEntryPageKeys(entry)
+	if err != nil {
+		return []*DocAudit{{Key: entryKey, Type: api.EntryType, Err: err}}
+	}
+
+	// single-page entries contain their page, so have no others to audit
+	das := make([]*DocAudit, 0, len(pageKeys)+1)
+	das = append(das, a.auditor.audit(entryKey, entry, nReplicas))
+	for _, pageKey := range pageKeys {
+		page, err := a.acquireDoc(pageKey, lc)
+		if err != nil {
+			das = append(das, &DocAudit{Key: pageKey, Type: api.PageType, Err: err})
+			continue
+		}
+		das = append(das, a.auditor.audit(pageKey, page, nReplicas))
+	}
+	return das
+}
+
+// docAuditor counts the holders of a single document.
+type docAuditor interface {
+	audit(key id.ID, doc *api.Document, nReplicas uint) *DocAudit
+}
+
+func (r *docRepairerImpl) audit(key id.ID, doc *api.Document, nReplicas uint) *DocAudit {
+	da := &DocAudit{Key: key, Type: api.GetDocumentType(doc)}
+	params := *r.verifyParams
+	params.NReplicas = nReplicas
+	params.NClosestResponses = nReplicas + params.NMaxErrors
+	v, err := r.verify(key, doc, &params)
+	if err != nil {
+		da.Err = err
+		return da
+	}
+	da.NHolders = len(v.Result.Replicas)
+	da.OK = v.FullyReplicated()
+	if !da.OK && !v.UnderReplicated() {
+		// without finding the closest peers, we can't say the holders are missing
+		da.Err = errVerifyExhausted
+	}
+	return da
+}
@@ -0,0 +1,171 @@
+package author
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAuditReport_counts(t *testing.T) {
+	rp := &AuditReport{
+		Docs: []*DocAudit{
+			{OK: true},
+			{OK: false},
+			{Err: errors.New("some verify error")},
+			{OK: true},
+		},
+	}
+	assert.Equal(t, 1, rp.NUnderReplicated())
+	assert.Equal(t, 1, rp.NUnverified())
+	assert.False(t, rp.OK())
+
+	rp.Docs = rp.Docs[:1]
+	assert.True(t, rp.OK())
+}
+
+func TestAuthor_Audit_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	acq := &mapAcquirer{docs: make(map[string]*api.Document)}
+	page1 := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	page2 := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	pageKey1, pageKey2 := acq.add(page1), acq.add(page2)
+	entry := api.NewTestMultiPageEntry(rng)
+	entry.PageKeys = [][]byte{pageKey1.Bytes(), pageKey2.Bytes()}
+	entryKey := acq.add(&api.Document{Contents: &api.Document_Entry{Entry: entry}})
+	env := api.NewTestEnvelope(rng)
+	env.EntryKey = entryKey.Bytes()
+	envKey := acq.add(&api.Document{Contents: &api.Document_Envelope{Envelope: env}})
+
+	auditor := &fixedDocAuditor{nHolders: int(store.DefaultNReplicas)}
+	a := &Author{
+		config:   NewDefaultConfig(),
+		acquirer: acq,
+		auditor:  auditor,
+		logger:   clogging.NewDevLogger(zapcore.DebugLevel),
+	}
+
+	rp, err := a.Audit(envKey, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, envKey, rp.EnvelopeKey)
+	assert.Equal(t, entryKey, rp.EntryKey)
+	assert.Equal(t, store.DefaultNReplicas, rp.NReplicas)
+	assert.Equal(t, store.DefaultNReplicas, auditor.nReplicas)
+	assert.Len(t, rp.Docs, 4)
+	assert.Equal(t, []id.ID{envKey, entryKey, pageKey1, pageKey2},
+		[]id.ID{rp.Docs[0].Key, rp.Docs[1].Key, rp.Docs[2].Key, rp.Docs[3].Key})
+	assert.True(t, rp.OK())
+
+	// more replicas than holders should be under-replicated
+	rp, err = a.Audit(envKey, store.DefaultNReplicas+1)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, rp.NUnderReplicated())
+
+	// missing page should be unverified
+	delete(acq.docs, pageKey2.String())
+	rp, err = a.Audit(envKey, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rp.NUnverified())
+	assert.Equal(t, api.PageType, rp.Docs[3].Type)
+	assert.NotNil(t, rp.Docs[3].Err)
+
+	// missing entry should be unverified, w/o its pages
+	delete(acq.docs, entryKey.String())
+	rp, err = a.Audit(envKey, 0)
+	assert.Nil(t, err)
+	assert.Len(t, rp.Docs, 2)
+	assert.Equal(t, api.EntryType, rp.Docs[1].Type)
+	assert.NotNil(t, rp.Docs[1].Err)
+}
+
+func TestAuthor_Audit_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	acq := &mapAcquirer{docs: make(map[string]*api.Document)}
+	a := &Author{
+		config:   NewDefaultConfig(),
+		acquirer: acq,
+		auditor:  &fixedDocAuditor{},
+		logger:   clogging.NewDevLogger(zapcore.DebugLevel),
+	}
+
+	// missing envelope
+	rp, err := a.Audit(id.NewPseudoRandom(rng), 0)
+	assert.NotNil(t, err)
+	assert.Nil(t, rp)
+
+	// doc is not an envelope
+	page := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	rp, err = a.Audit(acq.add(page), 0)
+	assert.Equal(t, api.ErrUnexpectedDocumentType, err)
+	assert.Nil(t, rp)
+
+	// delegation w/o download scope
+	a.delegation = &DelegationClaims{Scopes: []string{ScopeUpload}}
+	rp, err = a.Audit(id.NewPseudoRandom(rng), 0)
+	assert.Equal(t, ErrDelegationScope, err)
+	assert.Nil(t, rp)
+}
+
+func TestDocRepairer_audit(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, key := api.NewTestDocument(rng)
+	seeds := peer.NewTestPeers(rng, 8)
+	finder := &fixedFinder{rp: &api.FindResponse{Peers: peer.ToAPIs(seeds)}}
+
+	cases := map[string]struct {
+		verifier *fixedVerifier
+		nHolders int
+		ok       bool
+		err      bool
+	}{
+		"fully replicated": {
+			verifier: &fixedVerifier{replicas: seeds[:4]},
+			nHolders: 4,
+			ok:       true,
+		},
+		"under-replicated": {
+			verifier: &fixedVerifier{replicas: seeds[:1], closest: seeds[1:]},
+			nHolders: 1,
+		},
+		"exhausted": {
+			verifier: &fixedVerifier{replicas: seeds[:1]},
+			nHolders: 1,
+			err:      true,
+		},
+		"verify error": {
+			verifier: &fixedVerifier{err: errors.New("some verify error")},
+			err:      true,
+		},
+	}
+	for desc, c := range cases {
+		r := newTestDocRepairer(rng, finder, c.verifier, nil)
+		da := r.audit(key, doc, 4)
+		assert.Equal(t, key, da.Key, desc)
+		assert.Equal(t, api.GetDocumentType(doc), da.Type, desc)
+		assert.Equal(t, c.nHolders, da.NHolders, desc)
+		assert.Equal(t, c.ok, da.OK, desc)
+		assert.Equal(t, c.err, da.Err != nil, desc)
+	}
+}
+
+type fixedDocAuditor struct {
+	nHolders  int
+	nReplicas uint
+}
+
+func (f *fixedDocAuditor) audit(key id.ID, doc *api.Document, nReplicas uint) *DocAudit {
+	f.nReplicas = nReplicas
+	return &DocAudit{
+		Key:      key,
+		Type:     api.GetDocumentType(doc),
+		NHolders: f.nHolders,
+		OK:       f.nHolders >= int(nReplicas),
+	}
+}
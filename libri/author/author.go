@@ -2,8 +2,10 @@ package author
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"io"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/drausin/libri/libri/author/io/enc"
@@ -15,15 +17,26 @@ import (
 	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/logging"
 	"github.com/drausin/libri/libri/common/storage"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/drausin/libri/libri/librarian/server/verify"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 )
 
 var (
+	// ErrZeroUploadConcurrency indicates when the UploadConcurrency config is zero, which would
+	// block every upload waiting for a slot.
+	ErrZeroUploadConcurrency = errors.New("UploadConcurrency must be at least 1")
+
 	healthcheckTimeout = 2 * time.Second
 )
 
@@ -65,9 +78,16 @@ type Author struct {
 	// load balancer for librarian clients
 	librarians client.Balancer
 
+	// load balancer for librarian Get clients
+	getters client.GetterBalancer
+
 	// librarian address -> health check client for all librarians
 	librarianHealths map[string]healthpb.HealthClient
 
+	// most recently probed librarian capabilities
+	caps   capabilitiesCache
+	capsMu sync.Mutex
+
 	// creates entry documents from raw content
 	entryPacker pack.EntryPacker
 
@@ -78,12 +98,52 @@ type Author struct {
 
 	receiver ship.Receiver
 
+	// records the librarians serving the documents received, for forensic bundles
+	servers *servingGetters
+
+	// Gets individual documents from libri
+	acquirer publish.Acquirer
+
+	// Gets and stores multiple documents from libri
+	msAcquirer publish.MultiStoreAcquirer
+
+	// verifies and repairs replication of individual documents
+	repairer docRepairer
+
+	// counts the holders of individual documents
+	auditor docAuditor
+
+	// finds the peers closest to a document key
+	closest closestFinder
+
+	// stores many documents directly with the peers closest to each
+	batchStorer store.BatchStorer
+
+	// stores documents directly with the peers closest to each in the background
+	asyncStorer store.AsyncStorer
+
 	// stores Pages in chan to local storage
 	pageSL page.StorerLoader
 
 	// signs requests
 	signer client.Signer
 
+	// signs requests on behalf of the organization
+	orgSigner client.Signer
+
+	// pool of librarian clients
+	clients client.Pool
+
+	// prefetches the entries of subscribed publications
+	prefetcher entryPrefetcher
+
+	// delegation holds the claims of the delegation token the author operates under, or nil if it
+	// holds the keychain owner's root keys
+	delegation *DelegationClaims
+
+	// slots limiting the number of contents uploaded concurrently across batch uploads
+	uploadSlots chan struct{}
+
 	// logger for this instance
 	logger *zap.Logger
 
@@ -99,10 +159,28 @@ func NewAuthor(
 	selfReaderKeys keychain.GetterSampler,
 	logger *zap.Logger,
 ) (*Author, error) {
+	logger = logging.NewDedupLogger(logger, logging.DefaultDedupWindow, logPeerAddress)
+	if config.UploadConcurrency < 1 {
+		return nil, ErrZeroUploadConcurrency
+	}
 
-	rdb, err := db.NewRocksDB(config.DbDir)
+	// fail fast on an invalid delegation, e.g., one presented alongside the root keys
+	delegation, err := getDelegation(config, authorKeys, selfReaderKeys)
 	if err != nil {
-		logger.Error("unable to init RocksDB", zap.Error(err))
+		return nil, err
+	}
+
+	_, clientCreds, err := client.NewTLSCredentials(config.TLS)
+	if err != nil {
+		logger.Error("unable to load TLS credentials", zap.Error(err))
+		return nil, err
+	}
+	interceptors := client.WithCredentials(config.ClientInterceptors, clientCreds)
+
+	rdb, err := db.Open(config.DBDriver, config.DbDir)
+	if err != nil {
+		logger.Error("unable to init DB", zap.String(logDBDriver, config.DBDriver),
+			zap.Error(err))
 		return nil, err
 	}
 	clientSL := storage.NewClientSL(rdb)
@@ -127,7 +205,7 @@ func NewAuthor(
 
 	// use client ID for rng seed so search client queries librarians in different order
 	rng := rand.New(rand.NewSource(clientID.Int().Int64()))
-	clients, err := client.NewDefaultLRUPool()
+	clients, err := client.NewDefaultLRUPoolWithInterceptors(interceptors)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +215,7 @@ func NewAuthor(
 	}
 	getters := client.NewUniformGetterBalancer(librarians)
 	putters := client.NewUniformPutterBalancer(librarians)
-	librarianHealths, err := getLibrarianHealthClients(config.LibrarianAddrs)
+	librarianHealths, err := getLibrarianHealthClients(config.LibrarianAddrs, interceptors)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +225,13 @@ func NewAuthor(
 		orgSigner = client.NewECDSASigner(config.OrgID.Key())
 	}
 
+	if config.NetworkParamsPubKey != nil {
+		getter := client.NewNetworkParametersGetter(clientID, config.OrgID, peerSigner,
+			orgSigner, config.NetworkParamsPubKey, config.Publish.GetTimeout)
+		adoptNetworkParameters(config, getter, getLibrarianGetters(config.LibrarianAddrs,
+			clients), clientLogger)
+	}
+
 	publisher := publish.NewPublisher(clientID, config.OrgID, peerSigner, orgSigner,
 		config.Publish)
 	acquirer := publish.NewAcquirer(clientID, config.OrgID, peerSigner, orgSigner,
@@ -156,12 +241,41 @@ func NewAuthor(
 	mlPublisher := publish.NewMultiLoadPublisher(slPublisher, config.Publish)
 	msAcquirer := publish.NewMultiStoreAcquirer(ssAcquirer, config.Publish)
 	shipper := ship.NewShipper(putters, publisher, mlPublisher)
-	receiver := ship.NewReceiver(getters, allKeys, acquirer, msAcquirer, documentSL)
+	servers := newServingGetters(getters)
+	receiver := ship.NewReceiver(servers, allKeys, acquirer, msAcquirer, documentSL)
 
 	mdEncDec := enc.NewMetadataEncrypterDecrypter()
 	entryPacker := pack.NewEntryPacker(config.Print, mdEncDec, documentSL)
 	entryUnpacker := pack.NewEntryUnpacker(config.Print, mdEncDec, documentSL)
 
+	// repairs use a fresh recorder and naive doctor since the author has no prior knowledge of
+	// peer responsiveness
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	doc := comm.NewNaiveDoctor()
+	repairer := &docRepairerImpl{
+		clientID:       clientID,
+		orgID:          config.OrgID,
+		clientSigner:   peerSigner,
+		orgSigner:      orgSigner,
+		librarianAddrs: config.LibrarianAddrs,
+		finders:        client.NewFinderCreator(clients),
+		fromer:         peer.NewFromer(),
+		verifier:       verify.NewDefaultVerifier(peerSigner, orgSigner, rec, doc, clients),
+		storer: store.NewDefaultStorer(peerSigner, orgSigner, rec,
+			comm.NewNoOpKeyspaceRecorder(), doc, clients),
+		verifyParams: verify.NewDefaultParameters(),
+		storeParams:  store.NewDefaultParameters(),
+	}
+
+	// prefetched documents go into the same document cache the receiver reads from
+	prefetcher := &entryPrefetcherImpl{
+		getters:    getters,
+		acquirer:   acquirer,
+		msAcquirer: msAcquirer,
+		docS:       documentSL,
+		verifyOnly: config.Prefetch.VerifyOnly,
+	}
+
 	author := &Author{
 		ClientID:         clientID,
 		orgID:            config.OrgID,
@@ -174,13 +288,27 @@ func NewAuthor(
 		clientSL:         clientSL,
 		documentSLD:      documentSL,
 		librarians:       librarians,
+		getters:          getters,
 		librarianHealths: librarianHealths,
 		entryPacker:      entryPacker,
 		entryUnpacker:    entryUnpacker,
 		shipper:          shipper,
 		receiver:         receiver,
+		servers:          servers,
+		acquirer:         acquirer,
+		msAcquirer:       msAcquirer,
+		repairer:         repairer,
+		auditor:          repairer,
+		closest:          repairer,
+		batchStorer:      store.NewBatchStorer(repairer.storer),
+		asyncStorer:      store.NewAsyncStorer(repairer.storer),
 		pageSL:           page.NewStorerLoader(documentSL),
 		signer:           peerSigner,
+		orgSigner:        orgSigner,
+		clients:          clients,
+		prefetcher:       prefetcher,
+		delegation:       delegation,
+		uploadSlots:      make(chan struct{}, config.UploadConcurrency),
 		logger:           clientLogger,
 		stop:             make(chan struct{}),
 	}
@@ -197,13 +325,14 @@ func (a *Author) Healthcheck() (bool, map[string]healthpb.HealthCheckResponse_Se
 	allHealthy := true
 	for addrStr, healthClient := range a.librarianHealths {
 		ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
-		rp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+		var header metadata.MD
+		rp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
 		cancel()
 		if err != nil {
 			healthStatus[addrStr] = healthpb.HealthCheckResponse_UNKNOWN
 			allHealthy = false
 			a.logger.Info("librarian peer is not reachable",
-				zap.String("peer_address", addrStr),
+				zap.String(logPeerAddress, addrStr),
 			)
 			continue
 		}
@@ -211,14 +340,14 @@ func (a *Author) Healthcheck() (bool, map[string]healthpb.HealthCheckResponse_Se
 		healthStatus[addrStr] = rp.Status
 		if rp.Status == healthpb.HealthCheckResponse_SERVING {
 			a.logger.Info("librarian peer is healthy",
-				zap.String("peer_address", addrStr),
+				healthyFields(addrStr, verifiedBuildInfo(header))...,
 			)
 			continue
 		}
 
 		allHealthy = false
 		a.logger.Warn("librarian peer is not healthy",
-			zap.String("peer_address", addrStr),
+			zap.String(logPeerAddress, addrStr),
 		)
 
 	}
@@ -228,6 +357,10 @@ func (a *Author) Healthcheck() (bool, map[string]healthpb.HealthCheckResponse_Se
 // Upload compresses, encrypts, and splits the content into pages and then stores them in the
 // libri network. It returns the uploaded envelope for self-storage and its key.
 func (a *Author) Upload(content io.Reader, mediaType string) (*api.Document, id.ID, error) {
+	if err := a.authorize(ScopeUpload); err != nil {
+		return nil, nil, a.logAndReturnErr("upload not authorized", err)
+	}
+	content = a.limitUpload(content)
 	startTime := time.Now()
 	a.logger.Debug("uploading document")
 
@@ -237,7 +370,7 @@ func (a *Author) Upload(content io.Reader, mediaType string) (*api.Document, id.
 	}
 
 	a.logger.Debug("packing content", packingContentFields(authorPub)...)
-	entry, metadata, err := a.entryPacker.Pack(content, mediaType, eek, authorPub)
+	entry, metadata, err := a.getEntryPacker().Pack(content, mediaType, eek, authorPub)
 	if err != nil {
 		return nil, nil, a.logAndReturnErr("error packing content", err)
 	}
@@ -254,29 +387,23 @@ func (a *Author) Upload(content io.Reader, mediaType string) (*api.Document, id.
 }
 
 // Download downloads, join, decrypts, and decompressed the content, writing it to a unified output
-// content writer.
+// content writer. If the content fails its integrity checks, a forensic bundle describing the
+// download is logged and written to the forensics directory.
 func (a *Author) Download(content io.Writer, envKey id.ID) error {
+	if err := a.authorize(ScopeDownload); err != nil {
+		return a.logAndReturnErr("download not authorized", err)
+	}
 	startTime := time.Now()
 	a.logger.Debug("downloading document", downloadingDocFields(envKey)...)
 
-	entry, keys, err := a.receiver.ReceiveEntry(envKey)
-	if err != nil {
-		return a.logAndReturnErr("error receiving entry", err)
-	}
-	entryKey, nPages, err := getEntryInfo(entry)
+	rp, err := a.download(content, envKey, a.receiver, a.servers)
 	if err != nil {
-		return a.logAndReturnErr("error getting entry info", err)
-	}
-
-	a.logger.Debug("unpacking content", unpackingContentFields(entryKey, nPages)...)
-	metadata, err := a.entryUnpacker.Unpack(content, entry, keys)
-	if err != nil {
-		return a.logAndReturnErr("error unpacking content", err)
+		return err
 	}
 
 	elapsedTime := time.Since(startTime)
 	a.logger.Info("downloaded document",
-		downloadedDocFields(envKey, entryKey, metadata, elapsedTime)...,
+		downloadedDocFields(envKey, rp.EntryKey, rp.Metadata, elapsedTime)...,
 	)
 	return nil
 }
@@ -296,6 +423,9 @@ func (a *Author) Share(envKey id.ID, readerPub *ecdsa.PublicKey) (*api.Document,
 // envelope has the same entry and entry encryption key as the envelope passed in.
 func (a *Author) ShareEnvelope(env *api.Envelope, readerPub *ecdsa.PublicKey) (
 	*api.Document, id.ID, error) {
+	if err := a.authorize(ScopeShare); err != nil {
+		return nil, nil, a.logAndReturnErr("share not authorized", err)
+	}
 	eek, err := a.receiver.GetEEK(env)
 	if err != nil {
 		return nil, nil, a.logAndReturnErr("error getting EEK", err)
@@ -24,11 +24,13 @@ import (
 	"github.com/drausin/libri/libri/common/id"
 	clogging "github.com/drausin/libri/libri/common/logging"
 	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 )
 
 const (
@@ -38,7 +40,7 @@ const (
 func TestNewAuthor(t *testing.T) {
 	// return empty map of health clients
 	orig := getLibrarianHealthClients
-	getLibrarianHealthClients = func(librarianAddrs []*net.TCPAddr) (
+	getLibrarianHealthClients = func(_ []*net.TCPAddr, _ *client.Interceptors) (
 		map[string]healthpb.HealthClient, error) {
 		return make(map[string]healthpb.HealthClient), nil
 	}
@@ -63,10 +65,19 @@ func TestNewAuthor(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestNewAuthor_zeroUploadConcurrency(t *testing.T) {
+	config := newTestConfig()
+	config.UploadConcurrency = 0
+	a, err := NewAuthor(config, keychain.New(nInitialKeys), keychain.New(nInitialKeys),
+		clogging.NewDevInfoLogger())
+	assert.Equal(t, ErrZeroUploadConcurrency, err)
+	assert.Nil(t, a)
+}
+
 func TestAuthor_Healthcheck_ok(t *testing.T) {
 	// return fixed map of health clients
 	orig := getLibrarianHealthClients
-	getLibrarianHealthClients = func(librarianAddrs []*net.TCPAddr) (
+	getLibrarianHealthClients = func(_ []*net.TCPAddr, _ *client.Interceptors) (
 		map[string]healthpb.HealthClient, error) {
 		return map[string]healthpb.HealthClient{
 			"peerAddr1": &fixedHealthClient{
@@ -95,7 +106,7 @@ func TestAuthor_Healthcheck_ok(t *testing.T) {
 func TestAuthor_Healthcheck_err(t *testing.T) {
 	// return fixed map of health clients
 	orig := getLibrarianHealthClients
-	getLibrarianHealthClients = func(librarianAddrs []*net.TCPAddr) (
+	getLibrarianHealthClients = func(_ []*net.TCPAddr, _ *client.Interceptors) (
 		map[string]healthpb.HealthClient, error) {
 		return map[string]healthpb.HealthClient{
 			"peerAddr1": &fixedHealthClient{
@@ -182,7 +193,7 @@ func TestAuthor_Download_ok(t *testing.T) {
 	}
 	a := &Author{
 		logger:        clogging.NewDevInfoLogger(),
-		receiver:      &fixedReceiver{entry: doc},
+		receiver:      &fixedReceiver{entry: doc, keys: enc.NewPseudoRandomEEK(rng)},
 		entryUnpacker: &fixedUnpacker{metadata: metadata},
 	}
 	err := a.Download(nil, docKey)
@@ -205,7 +216,7 @@ func TestAuthor_Download_err(t *testing.T) {
 	// check Unpack error bubbles up
 	a2 := &Author{
 		logger:        clogging.NewDevInfoLogger(),
-		receiver:      &fixedReceiver{entry: doc},
+		receiver:      &fixedReceiver{entry: doc, keys: enc.NewPseudoRandomEEK(rng)},
 		entryUnpacker: &fixedUnpacker{err: errors.New("some Unpack error")},
 	}
 	err = a2.Download(nil, docKey)
@@ -463,6 +474,7 @@ func (f *fixedPutterBalancer) Next() (api.Putter, error) {
 
 type fixedHealthClient struct {
 	response *healthpb.HealthCheckResponse
+	header   metadata.MD
 	err      error
 }
 
@@ -475,6 +487,11 @@ func (f *fixedHealthClient) Watch(
 func (f *fixedHealthClient) Check(
 	ctx context.Context, in *healthpb.HealthCheckRequest, opts ...grpc.CallOption,
 ) (*healthpb.HealthCheckResponse, error) {
+	for _, opt := range opts {
+		if h, ok := opt.(grpc.HeaderCallOption); ok && f.header != nil {
+			*h.HeaderAddr = f.header
+		}
+	}
 	return f.response, f.err
 }
 
@@ -524,10 +541,11 @@ func newTestConfig() *Config {
 	defer func() { cerrors.MaybePanic(os.RemoveAll(dir)) }()
 	cerrors.MaybePanic(err)
 
-	// set data dir and resets DB and Keychain dirs to use it
+	// set data dir and resets DB, Keychain, and forensics dirs to use it
 	config.WithDataDir(dir).
 		WithDefaultDBDir().
-		WithDefaultKeychainDir()
+		WithDefaultKeychainDir().
+		WithDefaultForensicsDir()
 
 	return config
 }
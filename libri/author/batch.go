@@ -0,0 +1,159 @@
+package author
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"golang.org/x/net/context"
+)
+
+var errDocNotStored = errors.New("unable to store enough replicas")
+
+// UploadItem is a single content to upload in a batch.
+type UploadItem struct {
+	// Content is the content to upload
+	Content io.Reader
+
+	// MediaType is the media type of the content
+	MediaType string
+}
+
+// UploadResult is the result of uploading a single item in a batch.
+type UploadResult struct {
+	// Envelope is the uploaded envelope document
+	Envelope *api.Document
+
+	// EnvelopeKey is the key of the uploaded envelope
+	EnvelopeKey id.ID
+
+	// Err is the error encountered uploading the item, if any
+	Err error
+}
+
+// UploadBatch uploads each item concurrently, sharing the author's publishers and librarian
+// connections across them. At most Config.UploadConcurrency items are uploaded at once, a budget
+// shared by all concurrent batch uploads. The results are in the same order as the items, and an
+// item failing to upload doesn't stop the others.
+func (a *Author) UploadBatch(items []*UploadItem) []*UploadResult {
+	startTime := time.Now()
+	a.logger.Debug("uploading batch", uploadingBatchFields(len(items))...)
+	results := make([]*UploadResult, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		a.uploadSlots <- struct{}{}
+		go func(i int, item *UploadItem) {
+			defer wg.Done()
+			defer func() { <-a.uploadSlots }()
+			env, envKey, err := a.Upload(item.Content, item.MediaType)
+			results[i] = &UploadResult{
+				Envelope:    env,
+				EnvelopeKey: envKey,
+				Err:         err,
+			}
+		}(i, item)
+	}
+	wg.Wait()
+
+	nErrored := 0
+	for _, result := range results {
+		if result.Err != nil {
+			nErrored++
+		}
+	}
+	a.logger.Info("uploaded batch",
+		uploadedBatchFields(len(items), nErrored, time.Since(startTime))...)
+	return results
+}
+
+// StoreResult is the result of storing a single document in a batch.
+type StoreResult struct {
+	// Key is the key of the stored document
+	Key id.ID
+
+	// NStored is the number of peers that stored the document
+	NStored int
+
+	// Err is the error encountered storing the document, if any
+	Err error
+}
+
+// StoreBatch stores the documents (e.g., all the pages of a large entry) directly with the peers
+// closest to each in one operation. The stores share the peers found by each other's searches
+// for nearby keys, the author's librarian connections, and a single concurrency budget. The
+// results are in the same order as the documents, and a document failing to store doesn't stop
+// the others.
+func (a *Author) StoreBatch(docs []*api.Document) ([]*StoreResult, error) {
+	if err := a.authorize(ScopeUpload); err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	keys := make([]id.ID, len(docs))
+	for i, doc := range docs {
+		key, err := api.GetKey(doc)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	results := make([]*StoreResult, len(docs))
+	if len(docs) == 0 {
+		return results, nil
+	}
+	seeds, err := a.closest.getSeeds(keys[len(keys)/2])
+	if err != nil {
+		return nil, err
+	}
+
+	searchParams, storeParams := a.batchStoreParams()
+	batch := store.NewBatchStore(a.ClientID, a.orgID, keys, docs, searchParams, storeParams,
+		a.config.Publish.Replication)
+	defer batch.Release()
+	if a.config.Publish.TTL > 0 {
+		expires := time.Now().Add(a.config.Publish.TTL)
+		for _, s := range batch.Stores {
+			s.ExpireAt(expires)
+		}
+	}
+	_ = a.batchStorer.StoreBatch(context.Background(), batch, seeds) // errors in results
+
+	nErrored := 0
+	for i, s := range batch.Stores {
+		results[i] = &StoreResult{Key: keys[i]}
+		if s.Result.FatalErr != nil {
+			results[i].Err = s.Result.FatalErr
+		} else if !s.Stored() {
+			results[i].Err = errDocNotStored
+		}
+		if results[i].Err != nil {
+			nErrored++
+			continue
+		}
+		results[i].NStored = len(s.Result.Responded)
+	}
+	a.logger.Info("stored batch",
+		uploadedBatchFields(len(docs), nErrored, time.Since(startTime))...)
+	return results, nil
+}
+
+// batchStoreParams returns the search and store parameters for a batch store, taken from the
+// author's publish parameters where they have an equivalent: its queries time out like Put
+// requests, share the Put parallelism as their concurrency budget, and carry the store
+// authorization token and macaroon.
+func (a *Author) batchStoreParams() (*search.Parameters, *store.Parameters) {
+	searchParams := search.NewDefaultParameters()
+	searchParams.Timeout = a.config.Publish.PutTimeout
+	searchParams.Macaroon = a.config.Publish.Macaroon
+	storeParams := store.NewDefaultParameters()
+	storeParams.Timeout = a.config.Publish.PutTimeout
+	storeParams.Concurrency = uint(a.config.Publish.PutParallelism)
+	storeParams.AuthToken = a.config.Publish.StoreAuthToken
+	storeParams.Macaroon = a.config.Publish.Macaroon
+	return searchParams, storeParams
+}
@@ -0,0 +1,172 @@
+package author
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/common"
+	"github.com/drausin/libri/libri/author/io/page"
+	"github.com/drausin/libri/libri/author/io/publish"
+	"github.com/drausin/libri/libri/author/io/ship"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestAuthor_UploadBatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	page.MinSize = 64 // just for testing
+
+	for _, concurrency := range []int{1, 3} {
+		a := newTestAuthor()
+		a.uploadSlots = make(chan struct{}, concurrency)
+		a.config.Print.PageSize = 128
+
+		// just mock interaction with libri network
+		pubAcq := &memPublisherAcquirer{
+			docs: make(map[string]*api.Document),
+		}
+		slPublisher := publish.NewSingleLoadPublisher(pubAcq, a.documentSLD)
+		ssAcquirer := publish.NewSingleStoreAcquirer(pubAcq, a.documentSLD)
+		mlPublisher := publish.NewMultiLoadPublisher(slPublisher, a.config.Publish)
+		a.shipper = ship.NewShipper(&fixedPutterBalancer{}, pubAcq, mlPublisher)
+		msAcquirer := publish.NewMultiStoreAcquirer(ssAcquirer, a.config.Publish)
+		a.receiver = ship.NewReceiver(&fixedGetterBalancer{}, a.allKeys, pubAcq, msAcquirer,
+			a.documentSLD)
+
+		contents := make([][]byte, 4)
+		items := make([]*UploadItem, len(contents)+1)
+		for i := range contents {
+			contents[i] = common.NewCompressableBytes(rng, 256*(i+1)).Bytes()
+			items[i] = &UploadItem{
+				Content:   bytes.NewReader(contents[i]),
+				MediaType: "application/x-pdf",
+			}
+		}
+		items[len(contents)] = &UploadItem{
+			Content:   &errReader{err: errors.New("some Read error")},
+			MediaType: "application/x-pdf",
+		}
+
+		results := a.UploadBatch(items)
+		assert.Len(t, results, len(items))
+		for i, content := range contents {
+			assert.Nil(t, results[i].Err)
+			assert.NotNil(t, results[i].Envelope)
+			downloaded := new(bytes.Buffer)
+			err := a.Download(downloaded, results[i].EnvelopeKey)
+			assert.Nil(t, err)
+			assert.Equal(t, content, downloaded.Bytes())
+		}
+
+		// erroring item doesn't stop the others
+		errResult := results[len(contents)]
+		assert.NotNil(t, errResult.Err)
+		assert.Nil(t, errResult.Envelope)
+		assert.Nil(t, errResult.EnvelopeKey)
+
+		// all upload slots released
+		assert.Len(t, a.uploadSlots, 0)
+		assert.Nil(t, a.CloseAndRemove())
+	}
+}
+
+func TestAuthor_StoreBatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a := newTestAuthor()
+	a.closest = &fixedClosestFinder{peers: peer.NewTestPeers(rng, 3)}
+	storeErr := errors.New("some Store error")
+	a.batchStorer = &fixedBatchStorer{
+		responded: peer.NewTestPeers(rng, int(store.DefaultNReplicas)),
+		errs:      map[int]error{1: storeErr},
+	}
+	docs := make([]*api.Document, 3)
+	for i := range docs {
+		docs[i], _ = api.NewTestDocument(rng)
+	}
+
+	results, err := a.StoreBatch(docs)
+	assert.Nil(t, err)
+	assert.Len(t, results, len(docs))
+	for i, result := range results {
+		key, err := api.GetKey(docs[i])
+		assert.Nil(t, err)
+		assert.Equal(t, key, result.Key)
+		if i == 1 {
+			assert.Equal(t, storeErr, result.Err)
+			assert.Zero(t, result.NStored)
+			continue
+		}
+		assert.Nil(t, result.Err)
+		assert.Equal(t, int(store.DefaultNReplicas), result.NStored)
+	}
+
+	// batch stores with the author's publish parameters
+	a.config.Publish.PutTimeout = 7 * time.Second
+	a.config.Publish.PutParallelism = 5
+	a.config.Publish.StoreAuthToken = "some token"
+	a.config.Publish.Macaroon = "some macaroon"
+	a.config.Publish.Replication = api.TypeReplicas{api.GetDocumentType(docs[0]): 7}
+	a.config.Publish.TTL = time.Hour
+	bs := &fixedBatchStorer{responded: peer.NewTestPeers(rng, 7)}
+	a.batchStorer = bs
+	results, err = a.StoreBatch(docs[:1])
+	assert.Nil(t, err)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, uint(5), bs.batch.Params.Concurrency)
+	assert.Equal(t, 7*time.Second, bs.batch.Params.Timeout)
+	assert.Equal(t, "some token", bs.batch.Params.AuthToken)
+	assert.Equal(t, "some macaroon", bs.batch.Params.Macaroon)
+	s := bs.batch.Stores[0]
+	assert.Equal(t, uint(7), s.Params.NReplicas)
+	assert.Equal(t, 7*time.Second, s.Search.Params.Timeout)
+	assert.Equal(t, "some macaroon", s.Search.Params.Macaroon)
+	assert.True(t, s.CreateRq().ExpireTime > time.Now().Unix())
+
+	// empty batch
+	results, err = a.StoreBatch([]*api.Document{})
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+
+	// seeds error
+	a.closest = &fixedClosestFinder{err: errNoRepairSeeds}
+	results, err = a.StoreBatch(docs)
+	assert.Equal(t, errNoRepairSeeds, err)
+	assert.Nil(t, results)
+	assert.Nil(t, a.CloseAndRemove())
+}
+
+// fixedBatchStorer stores each document with the responded peers, unless it has an error for
+// that document's index.
+type fixedBatchStorer struct {
+	responded []peer.Peer
+	errs      map[int]error
+	batch     *store.BatchStore
+}
+
+func (f *fixedBatchStorer) StoreBatch(
+	ctx context.Context, batch *store.BatchStore, seeds []peer.Peer,
+) error {
+	f.batch = batch
+	for i, s := range batch.Stores {
+		if err, in := f.errs[i]; in {
+			s.Result = store.NewFatalResult(err)
+			continue
+		}
+		s.Result = &store.Result{Responded: f.responded}
+	}
+	return nil
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
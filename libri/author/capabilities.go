@@ -0,0 +1,123 @@
+package author
+
+import (
+	"errors"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/enc"
+	"github.com/drausin/libri/libri/author/io/pack"
+	"github.com/drausin/libri/libri/author/io/print"
+	"github.com/drausin/libri/libri/librarian/api"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	// capabilitiesTTL is how long probed librarian capabilities are reused before re-probing.
+	capabilitiesTTL = 1 * time.Minute
+
+	errNoCapabilities = errors.New("no librarians advertised capabilities")
+)
+
+// capabilitiesCache holds the most recently probed librarian capabilities.
+type capabilitiesCache struct {
+	capabilities *api.Capabilities
+	err          error
+	probed       time.Time
+}
+
+// ProbeCapabilities queries the capabilities advertised by each librarian and returns those
+// supported by all of them, i.e., the smallest max page size, the common codecs, and the highest
+// load. Librarians that are unreachable or don't advertise capabilities are ignored.
+func (a *Author) ProbeCapabilities() (*api.Capabilities, error) {
+	caps := make([]*api.Capabilities, 0, len(a.librarianHealths))
+	for addrStr, healthClient := range a.librarianHealths {
+		md := metadata.MD{}
+		ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+		_, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&md))
+		cancel()
+		if err != nil {
+			a.logger.Debug("unable to probe librarian capabilities",
+				zap.String(logPeerAddress, addrStr),
+				zap.Error(err),
+			)
+			continue
+		}
+		c, err := api.FromCapabilitiesMetadata(md)
+		if err != nil {
+			a.logger.Warn("librarian advertised invalid capabilities",
+				zap.String(logPeerAddress, addrStr),
+				zap.Error(err),
+			)
+			continue
+		}
+		caps = append(caps, c)
+	}
+	merged := api.IntersectCapabilities(caps...)
+	if merged == nil {
+		return nil, errNoCapabilities
+	}
+	return merged, nil
+}
+
+// getCapabilities returns the cached librarian capabilities, probing them again if the cache has
+// expired.
+func (a *Author) getCapabilities() (*api.Capabilities, error) {
+	a.capsMu.Lock()
+	defer a.capsMu.Unlock()
+	if time.Since(a.caps.probed) > capabilitiesTTL {
+		c, err := a.ProbeCapabilities()
+		a.caps = capabilitiesCache{capabilities: c, err: err, probed: time.Now()}
+	}
+	return a.caps.capabilities, a.caps.err
+}
+
+// getEntryPacker returns the entry packer to use for the next upload, adapting the configured
+// print parameters to the librarian capabilities if necessary.
+func (a *Author) getEntryPacker() pack.EntryPacker {
+	caps, err := a.getCapabilities()
+	if err != nil {
+		a.logger.Debug("using configured print parameters", zap.Error(err))
+		return a.entryPacker
+	}
+	params, adapted := adaptPrintParameters(a.config.Print, caps)
+	if !adapted {
+		return a.entryPacker
+	}
+	a.logger.Info("adapted print parameters to librarian capabilities",
+		adaptedParamsFields(a.config.Print, params, caps)...)
+	return pack.NewEntryPacker(params, enc.NewMetadataEncrypterDecrypter(), a.documentSLD)
+}
+
+// adaptPrintParameters returns a copy of the print parameters adapted to the given capabilities
+// and whether any adaptation was necessary. Pages are made no larger than the librarians accept,
+// compression is restricted to the codecs they support, and print parallelism is reduced in
+// proportion to their load.
+func adaptPrintParameters(
+	params *print.Parameters, caps *api.Capabilities,
+) (*print.Parameters, bool) {
+	adapted := *params
+	changed := false
+	if caps.MaxPageSize > 0 && caps.MaxPageSize < params.PageSize {
+		adapted.PageSize = caps.MaxPageSize
+		changed = true
+	}
+	if len(caps.Codecs) < len(api.CompressionCodec_name) {
+		adapted.Codecs = caps.Codecs
+		changed = true
+	}
+	if caps.Load > 0 {
+		parallelism := uint32(float64(params.Parallelism) * (1 - caps.Load))
+		if parallelism < 1 {
+			parallelism = 1
+		}
+		if parallelism != params.Parallelism {
+			adapted.Parallelism = parallelism
+			changed = true
+		}
+	}
+	return &adapted, changed
+}
@@ -0,0 +1,163 @@
+package author
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/common"
+	"github.com/drausin/libri/libri/author/io/page"
+	"github.com/drausin/libri/libri/author/io/print"
+	"github.com/drausin/libri/libri/author/io/publish"
+	"github.com/drausin/libri/libri/author/io/ship"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestAuthor_ProbeCapabilities_ok(t *testing.T) {
+	c1 := &api.Capabilities{
+		MaxPageSize: 2048,
+		Codecs:      []api.CompressionCodec{api.CompressionCodec_NONE, api.CompressionCodec_GZIP},
+		Load:        0.1,
+	}
+	c2 := &api.Capabilities{
+		MaxPageSize: 1024,
+		Codecs:      []api.CompressionCodec{api.CompressionCodec_NONE},
+		Load:        0.5,
+	}
+	a := &Author{
+		librarianHealths: map[string]healthpb.HealthClient{
+			"peerAddr1": &fixedHealthClient{header: c1.ToMetadata()},
+			"peerAddr2": &fixedHealthClient{header: c2.ToMetadata()},
+			"peerAddr3": &fixedHealthClient{err: errors.New("some Check error")},
+			"peerAddr4": &fixedHealthClient{}, // doesn't advertise capabilities
+		},
+		logger: zap.NewNop(),
+	}
+	merged, err := a.ProbeCapabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, api.IntersectCapabilities(c1, c2), merged)
+}
+
+func TestAuthor_ProbeCapabilities_err(t *testing.T) {
+	a := &Author{
+		librarianHealths: map[string]healthpb.HealthClient{
+			"peerAddr1": &fixedHealthClient{err: errors.New("some Check error")},
+			"peerAddr2": &fixedHealthClient{},
+		},
+		logger: zap.NewNop(),
+	}
+	merged, err := a.ProbeCapabilities()
+	assert.Equal(t, errNoCapabilities, err)
+	assert.Nil(t, merged)
+}
+
+func TestAuthor_getCapabilities(t *testing.T) {
+	c1 := &api.Capabilities{MaxPageSize: 2048, Load: 0.1}
+	hc := &fixedHealthClient{header: c1.ToMetadata()}
+	a := &Author{
+		librarianHealths: map[string]healthpb.HealthClient{"peerAddr1": hc},
+		logger:           zap.NewNop(),
+	}
+	c2, err := a.getCapabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, c1.MaxPageSize, c2.MaxPageSize)
+
+	// cached value is returned even though advertised capabilities have changed
+	hc.header = (&api.Capabilities{MaxPageSize: 1024}).ToMetadata()
+	c3, err := a.getCapabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, c1.MaxPageSize, c3.MaxPageSize)
+
+	// after cache expires, capabilities are probed again
+	a.caps.probed = time.Now().Add(-2 * capabilitiesTTL)
+	c4, err := a.getCapabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1024), c4.MaxPageSize)
+}
+
+func TestAdaptPrintParameters(t *testing.T) {
+	allCodecs := []api.CompressionCodec{api.CompressionCodec_NONE, api.CompressionCodec_GZIP}
+	params := print.NewDefaultParameters()
+	params.Parallelism = 4
+
+	// no adaptation necessary
+	caps := &api.Capabilities{MaxPageSize: params.PageSize, Codecs: allCodecs}
+	adapted, changed := adaptPrintParameters(params, caps)
+	assert.False(t, changed)
+	assert.Equal(t, params, adapted)
+
+	// smaller page size
+	caps = &api.Capabilities{MaxPageSize: params.PageSize / 2, Codecs: allCodecs}
+	adapted, changed = adaptPrintParameters(params, caps)
+	assert.True(t, changed)
+	assert.Equal(t, params.PageSize/2, adapted.PageSize)
+
+	// restricted codecs
+	caps = &api.Capabilities{
+		MaxPageSize: params.PageSize,
+		Codecs:      []api.CompressionCodec{api.CompressionCodec_NONE},
+	}
+	adapted, changed = adaptPrintParameters(params, caps)
+	assert.True(t, changed)
+	assert.Equal(t, caps.Codecs, adapted.Codecs)
+
+	// high load
+	caps = &api.Capabilities{MaxPageSize: params.PageSize, Codecs: allCodecs, Load: 0.5}
+	adapted, changed = adaptPrintParameters(params, caps)
+	assert.True(t, changed)
+	assert.Equal(t, uint32(2), adapted.Parallelism)
+
+	// parallelism never drops below 1
+	caps = &api.Capabilities{MaxPageSize: params.PageSize, Codecs: allCodecs, Load: 1.0}
+	adapted, changed = adaptPrintParameters(params, caps)
+	assert.True(t, changed)
+	assert.Equal(t, uint32(1), adapted.Parallelism)
+
+	// original params are unchanged
+	assert.Equal(t, uint32(4), params.Parallelism)
+	assert.Equal(t, print.NewDefaultParameters().PageSize, params.PageSize)
+	assert.Nil(t, params.Codecs)
+}
+
+func TestAuthor_Upload_adaptedPageSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a := newTestAuthor()
+	a.config.Print.PageSize = 1024
+	caps := &api.Capabilities{
+		MaxPageSize: 256,
+		Codecs:      []api.CompressionCodec{api.CompressionCodec_NONE, api.CompressionCodec_GZIP},
+	}
+	a.librarianHealths = map[string]healthpb.HealthClient{
+		"peerAddr1": &fixedHealthClient{header: caps.ToMetadata()},
+	}
+	page.MinSize = 64 // just for testing
+
+	// just mock interaction with libri network
+	pubAcq := &memPublisherAcquirer{docs: make(map[string]*api.Document)}
+	slPublisher := publish.NewSingleLoadPublisher(pubAcq, a.documentSLD)
+	mlPublisher := publish.NewMultiLoadPublisher(slPublisher, a.config.Publish)
+	a.shipper = ship.NewShipper(&fixedPutterBalancer{}, pubAcq, mlPublisher)
+
+	content := common.NewCompressableBytes(rng, 4096)
+	_, _, err := a.Upload(bytes.NewReader(content.Bytes()), "application/x-gzip")
+	assert.Nil(t, err)
+
+	// all pages should be within the librarian's max page size
+	nPages := 0
+	for _, doc := range pubAcq.docs {
+		if p, ok := doc.Contents.(*api.Document_Page); ok {
+			assert.True(t,
+				len(p.Page.Ciphertext) <= int(caps.MaxPageSize+api.PageCiphertextOverhead))
+			nPages++
+		}
+	}
+	assert.True(t, nPages > 1)
+
+	err = a.CloseAndRemove()
+	assert.Nil(t, err)
+}
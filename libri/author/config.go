@@ -7,9 +7,12 @@ import (
 
 	"github.com/drausin/libri/libri/author/io/print"
 	"github.com/drausin/libri/libri/author/io/publish"
+	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/parse"
+	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/client"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -32,6 +35,13 @@ const (
 
 	// KeychainSubDir is the default DB subdirectory within the data dir.
 	KeychainSubDir = "keychain"
+
+	// ForensicsSubDir is the default forensic bundle subdirectory within the data dir.
+	ForensicsSubDir = "forensics"
+
+	// DefaultUploadConcurrency is the default maximum number of contents uploaded concurrently
+	// by batch uploads.
+	DefaultUploadConcurrency = 4
 )
 
 // Config is used to configure an Author.
@@ -43,9 +53,16 @@ type Config struct {
 	// DbDir is the local directory where this node's DB state is stored.
 	DbDir string
 
+	// DBDriver is the name of the driver (e.g., db.LevelDBDriver) for the local DB.
+	DBDriver string
+
 	// KeychainDir is the local directory where the author keys are stored.
 	KeychainDir string
 
+	// ForensicsDir is the local directory where forensic bundles of downloads failing their
+	// integrity checks are written. Empty disables writing them, though they are still logged.
+	ForensicsDir string
+
 	// OrgID is the organization ID of the peer, if one exists.
 	OrgID ecid.ID
 
@@ -58,8 +75,38 @@ type Config struct {
 	// Publish defines parameters for publishing pages to libri.
 	Publish *publish.Parameters
 
+	// SubscribeTo defines parameters for subscriptions to librarians' publications.
+	SubscribeTo *subscribe.ToParameters
+
+	// Prefetch defines parameters for prefetching the entries of subscribed publications.
+	Prefetch *PrefetchParameters
+
+	// UploadConcurrency is the maximum number of contents uploaded concurrently across all
+	// batch uploads.
+	UploadConcurrency uint
+
 	// LogLevel is the log level
 	LogLevel zapcore.Level
+
+	// DelegationToken is an optional token from the keychain owner scoping which operations
+	// this author may perform.
+	DelegationToken string
+
+	// DelegatorPubKey is the public key of the keychain owner that signed the DelegationToken.
+	DelegatorPubKey []byte
+
+	// NetworkParamsPubKey is the public key of the network maintainers publishing recommended
+	// network parameters. When set, the parameters are fetched from the librarians on startup
+	// and adopted.
+	NetworkParamsPubKey []byte
+
+	// ClientInterceptors are optional gRPC interceptors applied to every librarian connection,
+	// e.g., for the embedding application's metrics, auth headers, or tracing.
+	ClientInterceptors *client.Interceptors
+
+	// TLS defines the mutual TLS of connections to librarians that require it, disabled by
+	// default.
+	TLS *client.TLSParameters
 }
 
 // NewDefaultConfig returns a reasonable default author configuration.
@@ -70,11 +117,17 @@ func NewDefaultConfig() *Config {
 	// should be set before config B
 	config.WithDefaultDataDir()
 	config.WithDefaultDBDir()
+	config.WithDefaultDBDriver()
 	config.WithDefaultKeychainDir()
+	config.WithDefaultForensicsDir()
 	config.WithDefaultLibrarianAddrs()
 	config.WithDefaultPrint()
 	config.WithDefaultPublish()
+	config.WithDefaultSubscribeTo()
+	config.WithDefaultPrefetch()
+	config.WithDefaultUploadConcurrency()
 	config.WithDefaultLogLevel()
+	config.WithDefaultTLS()
 
 	return config
 }
@@ -111,6 +164,21 @@ func (c *Config) WithDefaultDBDir() *Config {
 	return c
 }
 
+// WithDBDriver sets the DB driver to the given value or the default if the given value is empty.
+func (c *Config) WithDBDriver(driver string) *Config {
+	if driver == "" {
+		return c.WithDefaultDBDriver()
+	}
+	c.DBDriver = driver
+	return c
+}
+
+// WithDefaultDBDriver sets the DB driver to the default.
+func (c *Config) WithDefaultDBDriver() *Config {
+	c.DBDriver = db.DefaultDriver
+	return c
+}
+
 // WithKeychainDir sets the keychain dir to the given value or the default if the given value is
 // empty.
 func (c *Config) WithKeychainDir(keychainDir string) *Config {
@@ -127,6 +195,22 @@ func (c *Config) WithDefaultKeychainDir() *Config {
 	return c
 }
 
+// WithForensicsDir sets the forensics dir to the given value or the default if the given value is
+// empty.
+func (c *Config) WithForensicsDir(forensicsDir string) *Config {
+	if forensicsDir == "" {
+		return c.WithDefaultForensicsDir()
+	}
+	c.ForensicsDir = forensicsDir
+	return c
+}
+
+// WithDefaultForensicsDir sets the forensics dir to a local name subdir of the data dir.
+func (c *Config) WithDefaultForensicsDir() *Config {
+	c.ForensicsDir = filepath.Join(c.DataDir, ForensicsSubDir)
+	return c
+}
+
 // WithLibrarianAddrs sets the librarian addresses to the given value or the default if the given
 // value is empty.
 func (c *Config) WithLibrarianAddrs(librarianAddrs []*net.TCPAddr) *Config {
@@ -178,6 +262,54 @@ func (c *Config) WithDefaultPublish() *Config {
 	return c
 }
 
+// WithSubscribeTo sets the SubscribeTo parameters to the given value or the default if it is nil.
+func (c *Config) WithSubscribeTo(params *subscribe.ToParameters) *Config {
+	if params == nil {
+		return c.WithDefaultSubscribeTo()
+	}
+	c.SubscribeTo = params
+	return c
+}
+
+// WithDefaultSubscribeTo sets the SubscribeTo parameters to the default values specified in the
+// subscribe package.
+func (c *Config) WithDefaultSubscribeTo() *Config {
+	c.SubscribeTo = subscribe.NewDefaultToParameters()
+	return c
+}
+
+// WithPrefetch sets the Prefetch parameters to the given value or the default if it is nil.
+func (c *Config) WithPrefetch(params *PrefetchParameters) *Config {
+	if params == nil {
+		return c.WithDefaultPrefetch()
+	}
+	c.Prefetch = params
+	return c
+}
+
+// WithDefaultPrefetch sets the Prefetch parameters to the default values, which disable
+// prefetching.
+func (c *Config) WithDefaultPrefetch() *Config {
+	c.Prefetch = NewDefaultPrefetchParameters()
+	return c
+}
+
+// WithUploadConcurrency sets the upload concurrency to the given value or the default if it is
+// zero.
+func (c *Config) WithUploadConcurrency(uploadConcurrency uint) *Config {
+	if uploadConcurrency == 0 {
+		return c.WithDefaultUploadConcurrency()
+	}
+	c.UploadConcurrency = uploadConcurrency
+	return c
+}
+
+// WithDefaultUploadConcurrency sets the upload concurrency to the default value.
+func (c *Config) WithDefaultUploadConcurrency() *Config {
+	c.UploadConcurrency = DefaultUploadConcurrency
+	return c
+}
+
 // WithLogLevel sets the log level to the given value, though this doesn't have any direct effect
 // on the creation of the logger instance.
 func (c *Config) WithLogLevel(logLevel zapcore.Level) *Config {
@@ -193,3 +325,39 @@ func (c *Config) WithDefaultLogLevel() *Config {
 	c.LogLevel = DefaultLogLevel
 	return c
 }
+
+// WithDelegation sets the delegation token and the public key of the keychain owner that signed
+// it.
+func (c *Config) WithDelegation(token string, delegatorPubKey []byte) *Config {
+	c.DelegationToken = token
+	c.DelegatorPubKey = delegatorPubKey
+	return c
+}
+
+// WithNetworkParamsPubKey sets the public key of the network maintainers publishing recommended
+// network parameters.
+func (c *Config) WithNetworkParamsPubKey(pubKey []byte) *Config {
+	c.NetworkParamsPubKey = pubKey
+	return c
+}
+
+// WithClientInterceptors sets the gRPC interceptors applied to every librarian connection.
+func (c *Config) WithClientInterceptors(interceptors *client.Interceptors) *Config {
+	c.ClientInterceptors = interceptors
+	return c
+}
+
+// WithTLS sets the mutual TLS parameters to the given value or the default if it is nil.
+func (c *Config) WithTLS(params *client.TLSParameters) *Config {
+	if params == nil {
+		return c.WithDefaultTLS()
+	}
+	c.TLS = params
+	return c
+}
+
+// WithDefaultTLS sets the mutual TLS parameters to the default.
+func (c *Config) WithDefaultTLS() *Config {
+	c.TLS = client.NewDefaultTLSParameters()
+	return c
+}
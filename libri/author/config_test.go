@@ -6,7 +6,10 @@ import (
 
 	"github.com/drausin/libri/libri/author/io/print"
 	"github.com/drausin/libri/libri/author/io/publish"
+	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/parse"
+	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zapcore"
 )
@@ -16,9 +19,12 @@ func TestNewDefaultConfig(t *testing.T) {
 	assert.NotEmpty(t, c.DataDir)
 	assert.NotEmpty(t, c.DbDir)
 	assert.NotEmpty(t, c.KeychainDir)
+	assert.NotEmpty(t, c.ForensicsDir)
 	assert.NotEmpty(t, c.LibrarianAddrs)
 	assert.NotEmpty(t, c.Print)
 	assert.NotEmpty(t, c.Publish)
+	assert.NotZero(t, c.UploadConcurrency)
+	assert.NotNil(t, c.TLS)
 }
 
 func TestConfig_WithDataDir(t *testing.T) {
@@ -28,6 +34,13 @@ func TestConfig_WithDataDir(t *testing.T) {
 	assert.NotEqual(t, c1.DataDir, c3.WithDataDir("/some/other/dir").DataDir)
 }
 
+func TestConfig_WithDBDriver(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultDBDriver()
+	assert.Equal(t, c1.DBDriver, c2.WithDBDriver("").DBDriver)
+	assert.NotEqual(t, c1.DBDriver, c3.WithDBDriver(db.RocksDBDriver).DBDriver)
+}
+
 func TestConfig_WithDBDir(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultDBDir()
@@ -42,6 +55,13 @@ func TestConfig_WithKeychainDir(t *testing.T) {
 	assert.NotEqual(t, c1.KeychainDir, c3.WithKeychainDir("/some/other/dir").KeychainDir)
 }
 
+func TestConfig_WithForensicsDir(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultForensicsDir()
+	assert.Equal(t, c1.ForensicsDir, c2.WithForensicsDir("").ForensicsDir)
+	assert.NotEqual(t, c1.ForensicsDir, c3.WithForensicsDir("/some/other/dir").ForensicsDir)
+}
+
 func TestConfig_WithBootstrapAddrs(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultLibrarianAddrs()
@@ -74,6 +94,34 @@ func TestConfig_WithPublish(t *testing.T) {
 	)
 }
 
+func TestConfig_WithSubscribeTo(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultSubscribeTo()
+	assert.Equal(t, c1.SubscribeTo, c2.WithSubscribeTo(nil).SubscribeTo)
+	assert.NotEqual(t,
+		c1.SubscribeTo,
+		c3.WithSubscribeTo(&subscribe.ToParameters{NSubscriptions: 1}).SubscribeTo,
+	)
+}
+
+func TestConfig_WithPrefetch(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultPrefetch()
+	assert.Equal(t, c1.Prefetch, c2.WithPrefetch(nil).Prefetch)
+	assert.False(t, c1.Prefetch.Enabled)
+	assert.NotEqual(t,
+		c1.Prefetch,
+		c3.WithPrefetch(&PrefetchParameters{Enabled: true}).Prefetch,
+	)
+}
+
+func TestConfig_WithUploadConcurrency(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultUploadConcurrency()
+	assert.Equal(t, c1.UploadConcurrency, c2.WithUploadConcurrency(0).UploadConcurrency)
+	assert.NotEqual(t, c1.UploadConcurrency, c3.WithUploadConcurrency(16).UploadConcurrency)
+}
+
 func TestConfig_WithLogLevel(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultLogLevel()
@@ -83,3 +131,34 @@ func TestConfig_WithLogLevel(t *testing.T) {
 		c3.WithLogLevel(zapcore.DebugLevel).LogLevel,
 	)
 }
+
+func TestConfig_WithDelegation(t *testing.T) {
+	c := &Config{}
+	c.WithDelegation("some token", []byte{1, 2, 3})
+	assert.Equal(t, "some token", c.DelegationToken)
+	assert.Equal(t, []byte{1, 2, 3}, c.DelegatorPubKey)
+}
+
+func TestConfig_WithNetworkParamsPubKey(t *testing.T) {
+	c := &Config{}
+	c.WithNetworkParamsPubKey([]byte{1, 2, 3})
+	assert.Equal(t, []byte{1, 2, 3}, c.NetworkParamsPubKey)
+}
+
+func TestConfig_WithClientInterceptors(t *testing.T) {
+	c := &Config{}
+	interceptors := &client.Interceptors{}
+	c.WithClientInterceptors(interceptors)
+	assert.Equal(t, interceptors, c.ClientInterceptors)
+}
+
+func TestConfig_WithTLS(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultTLS()
+	assert.False(t, c1.TLS.Enabled())
+	assert.Equal(t, c1.TLS, c2.WithTLS(nil).TLS)
+	assert.NotEqual(t,
+		c1.TLS,
+		c3.WithTLS(&client.TLSParameters{CertFile: "cert.pem"}).TLS,
+	)
+}
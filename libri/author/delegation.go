@@ -0,0 +1,266 @@
+package author
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/drausin/libri/libri/author/keychain"
+	"github.com/drausin/libri/libri/common/ecid"
+	"go.uber.org/zap"
+)
+
+const (
+	// ScopeUpload allows a delegate to upload new documents.
+	ScopeUpload = "upload"
+
+	// ScopeDownload allows a delegate to download documents.
+	ScopeDownload = "download"
+
+	// ScopeShare allows a delegate to share documents with other readers.
+	ScopeShare = "share"
+
+	// ScopeRepair allows a delegate to repair the replication of existing documents.
+	ScopeRepair = "repair"
+)
+
+var (
+	// ErrDelegationExpired indicates when a delegation token has expired.
+	ErrDelegationExpired = errors.New("delegation token expired")
+
+	// ErrDelegationWrongDelegate indicates when the keychains presenting a delegation token hold
+	// keys other than the ones it delegates, e.g., the keychain owner's root keys.
+	ErrDelegationWrongDelegate = errors.New("delegation token issued for other keys")
+
+	// ErrDelegationScope indicates when a delegation token does not grant the attempted
+	// operation.
+	ErrDelegationScope = errors.New("delegation token does not grant operation")
+
+	// ErrDelegationSizeLimit indicates when uploaded content exceeds the delegation token's size
+	// limit.
+	ErrDelegationSizeLimit = errors.New("content exceeds delegation token size limit")
+
+	errDelegationMissingKeys  = errors.New("delegation token missing delegated keys")
+	errDelegationMissingOwner = errors.New("delegation token given without owner public key")
+)
+
+// DelegationClaims holds the claims of a token by which a keychain owner delegates a scoped
+// subset of author operations to a daemon, so the daemon need not hold the owner's root keys.
+type DelegationClaims struct {
+	// DelegatedKeys are the hex public keys of the only author and self-reader keys the delegate
+	// may hold
+	DelegatedKeys []string `json:"delegated_keys"`
+
+	// Scopes are the operations (e.g., ScopeUpload) the delegate may perform
+	Scopes []string `json:"scopes"`
+
+	// MaxUploadBytes is the maximum content size of a single upload, or zero if unlimited
+	MaxUploadBytes int64 `json:"max_upload_bytes,omitempty"`
+
+	// ExpiresAt is the Unix time after which the token is no longer valid, or zero if it never
+	// expires
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+// Valid returns whether the claims are valid or invalid via an error.
+func (c *DelegationClaims) Valid() error {
+	if len(c.DelegatedKeys) == 0 {
+		return errDelegationMissingKeys
+	}
+	if c.expired() {
+		return ErrDelegationExpired
+	}
+	return nil
+}
+
+// Allows returns nil if the claims (still) grant the given scope or an error indicating why not.
+func (c *DelegationClaims) Allows(scope string) error {
+	if c.expired() {
+		return ErrDelegationExpired
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return nil
+		}
+	}
+	return ErrDelegationScope
+}
+
+func (c *DelegationClaims) expired() bool {
+	return c.ExpiresAt != 0 && time.Now().Unix() > c.ExpiresAt
+}
+
+// checkKeychains returns ErrDelegationWrongDelegate unless each keychain holds only delegated
+// keys.
+func (c *DelegationClaims) checkKeychains(kcs ...keychain.GetterSampler) error {
+	for _, kc := range kcs {
+		nDelegated := 0
+		for _, pubHex := range c.DelegatedKeys {
+			pub, err := hex.DecodeString(pubHex)
+			if err != nil {
+				return err
+			}
+			if _, in := kc.Get(pub); in {
+				nDelegated++
+			}
+		}
+		if nDelegated == 0 || nDelegated < kc.Len() {
+			return ErrDelegationWrongDelegate
+		}
+	}
+	return nil
+}
+
+// NewDelegationToken returns a new delegation token (in the form of an encoded json web token)
+// for the author and self-reader keys with the given public keys, signed by the keychain owner's
+// key. A zero maxUploadBytes means uploads are unlimited in size, and a zero expiresAt means the
+// token never expires.
+func NewDelegationToken(
+	ownerKey *ecdsa.PrivateKey,
+	delegatedKeys [][]byte,
+	scopes []string,
+	maxUploadBytes int64,
+	expiresAt time.Time,
+) (string, error) {
+	claims := &DelegationClaims{
+		DelegatedKeys:  make([]string, len(delegatedKeys)),
+		Scopes:         scopes,
+		MaxUploadBytes: maxUploadBytes,
+	}
+	for i, pub := range delegatedKeys {
+		claims.DelegatedKeys[i] = hex.EncodeToString(pub)
+	}
+	if !expiresAt.IsZero() {
+		claims.ExpiresAt = expiresAt.Unix()
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	return token.SignedString(ownerKey)
+}
+
+// ParseDelegationToken verifies that the encoded token has been signed by the owner, returning
+// its claims.
+func ParseDelegationToken(encToken string, ownerPubKey *ecdsa.PublicKey) (
+	*DelegationClaims, error) {
+	if ownerPubKey == nil {
+		return nil, errDelegationMissingOwner
+	}
+	token, err := jwt.ParseWithClaims(encToken, &DelegationClaims{}, func(token *jwt.Token) (
+		interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return ownerPubKey, nil
+	})
+	if err != nil {
+		// received error when parsing claims or verifying signature
+		return nil, err
+	}
+	claims, ok := token.Claims.(*DelegationClaims)
+	if !ok {
+		return nil, fmt.Errorf("token claims %v are not expected DelegationClaims", token.Claims)
+	}
+	return claims, nil
+}
+
+// MintDelegation creates author and self-reader keychains each holding a single new key in the
+// delegate keychain directory and returns a token, signed by the owner's key, delegating the
+// given scopes to them. The delegated daemon loads only these keychains, so a compromise of it
+// doesn't expose the owner's root keys. The owner keeps a copy of the delegate keychains to
+// read the documents the delegate uploads.
+func MintDelegation(
+	logger *zap.Logger,
+	ownerKey *ecdsa.PrivateKey,
+	delegateKeychainDir, auth string,
+	scryptN, scryptP int,
+	scopes []string,
+	maxUploadBytes int64,
+	expiresAt time.Time,
+) (string, error) {
+	missing, err := MissingKeychains(delegateKeychainDir)
+	if err != nil {
+		return "", err
+	}
+	if !missing {
+		return "", ErrKeychainExists
+	}
+	if err = os.MkdirAll(delegateKeychainDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	filenames := []string{AuthorKeychainFilename, SelfReaderKeychainFilename}
+	delegatedKeys := make([][]byte, len(filenames))
+	for i, filename := range filenames {
+		delegatedID := ecid.NewRandom()
+		kc := keychain.FromECIDs([]ecid.ID{delegatedID})
+		fp := path.Join(delegateKeychainDir, filename)
+		if err = keychain.Save(fp, auth, kc, scryptN, scryptP); err != nil {
+			return "", err
+		}
+		logger.Info("saved new delegate keychain", zap.String(LoggerKeychainFilepath, fp),
+			zap.Int(LoggerKeychainNKeys, kc.Len()))
+		delegatedKeys[i] = delegatedID.PublicKeyBytes()
+	}
+	return NewDelegationToken(ownerKey, delegatedKeys, scopes, maxUploadBytes, expiresAt)
+}
+
+// getDelegation returns the claims of the configured delegation token, if any, after checking
+// that the author and self-reader keychains hold only the keys it delegates.
+func getDelegation(
+	config *Config, authorKeys, selfReaderKeys keychain.GetterSampler,
+) (*DelegationClaims, error) {
+	if config.DelegationToken == "" {
+		return nil, nil
+	}
+	if config.DelegatorPubKey == nil {
+		return nil, errDelegationMissingOwner
+	}
+	ownerPubKey, err := ecid.FromPublicKeyBytes(config.DelegatorPubKey)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := ParseDelegationToken(config.DelegationToken, ownerPubKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = claims.checkKeychains(authorKeys, selfReaderKeys); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// authorize returns nil if the author may perform the operation with the given scope. Authors
+// without a delegation token hold the root keys and may perform any operation.
+func (a *Author) authorize(scope string) error {
+	if a.delegation == nil {
+		return nil
+	}
+	return a.delegation.Allows(scope)
+}
+
+// limitUpload wraps the upload content in a reader that errors once the delegation token's size
+// limit, if any, is exceeded.
+func (a *Author) limitUpload(content io.Reader) io.Reader {
+	if a.delegation == nil || a.delegation.MaxUploadBytes == 0 {
+		return content
+	}
+	return &sizeLimitedReader{r: content, remaining: a.delegation.MaxUploadBytes}
+}
+
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrDelegationSizeLimit
+	}
+	return n, err
+}
@@ -0,0 +1,241 @@
+package author
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/author/keychain"
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDelegationClaims_Valid(t *testing.T) {
+	assert.Nil(t, (&DelegationClaims{DelegatedKeys: []string{"some-key"}}).Valid())
+	assert.Nil(t, (&DelegationClaims{
+		DelegatedKeys: []string{"some-key"},
+		ExpiresAt:     time.Now().Add(time.Hour).Unix(),
+	}).Valid())
+
+	assert.Equal(t, errDelegationMissingKeys, (&DelegationClaims{}).Valid())
+	assert.Equal(t, ErrDelegationExpired, (&DelegationClaims{
+		DelegatedKeys: []string{"some-key"},
+		ExpiresAt:     time.Now().Add(-time.Hour).Unix(),
+	}).Valid())
+}
+
+func TestDelegationClaims_Allows(t *testing.T) {
+	c := &DelegationClaims{Scopes: []string{ScopeUpload}}
+	assert.Nil(t, c.Allows(ScopeUpload))
+	assert.Equal(t, ErrDelegationScope, c.Allows(ScopeShare))
+
+	c.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+	assert.Equal(t, ErrDelegationExpired, c.Allows(ScopeUpload))
+}
+
+func TestDelegationClaims_checkKeychains(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	authorID, readerID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	authorKeys := keychain.FromECIDs([]ecid.ID{authorID})
+	readerKeys := keychain.FromECIDs([]ecid.ID{readerID})
+	ownerID := ecid.NewPseudoRandom(rng)
+	token, err := NewDelegationToken(ownerID.Key(),
+		[][]byte{authorID.PublicKeyBytes(), readerID.PublicKeyBytes()}, nil, 0, time.Time{})
+	assert.Nil(t, err)
+	claims, err := ParseDelegationToken(token, &ownerID.Key().PublicKey)
+	assert.Nil(t, err)
+
+	assert.Nil(t, claims.checkKeychains(authorKeys, readerKeys))
+
+	// root keychains hold more keys than those delegated
+	rootKeys := keychain.FromECIDs([]ecid.ID{authorID, ecid.NewPseudoRandom(rng)})
+	assert.Equal(t, ErrDelegationWrongDelegate, claims.checkKeychains(rootKeys, readerKeys))
+
+	// keychain without any delegated keys
+	otherKeys := keychain.FromECIDs([]ecid.ID{ecid.NewPseudoRandom(rng)})
+	assert.Equal(t, ErrDelegationWrongDelegate, claims.checkKeychains(authorKeys, otherKeys))
+
+	// malformed delegated key
+	claims.DelegatedKeys = []string{"not hex"}
+	assert.NotNil(t, claims.checkKeychains(authorKeys))
+}
+
+func TestNewDelegationTokenParse_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ownerID, delegatedID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	delegatedKeys := [][]byte{delegatedID.PublicKeyBytes()}
+	scopes := []string{ScopeUpload, ScopeDownload}
+
+	for _, expiresAt := range []time.Time{{}, time.Now().Add(time.Hour)} {
+		token, err := NewDelegationToken(ownerID.Key(), delegatedKeys, scopes, 1024, expiresAt)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, token)
+
+		claims, err := ParseDelegationToken(token, &ownerID.Key().PublicKey)
+		assert.Nil(t, err)
+		assert.Equal(t, scopes, claims.Scopes)
+		assert.Equal(t, int64(1024), claims.MaxUploadBytes)
+		assert.Len(t, claims.DelegatedKeys, 1)
+	}
+}
+
+func TestNewDelegationTokenParse_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ownerID, delegatedID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	otherID := ecid.NewPseudoRandom(rng)
+	ownerPub := &ownerID.Key().PublicKey
+	delegatedKeys := [][]byte{delegatedID.PublicKeyBytes()}
+	scopes := []string{ScopeUpload}
+
+	// signed by different key
+	token, err := NewDelegationToken(otherID.Key(), delegatedKeys, scopes, 0, time.Time{})
+	assert.Nil(t, err)
+	_, err = ParseDelegationToken(token, ownerPub)
+	assert.NotNil(t, err)
+
+	// no delegated keys
+	token, err = NewDelegationToken(ownerID.Key(), nil, scopes, 0, time.Time{})
+	assert.Nil(t, err)
+	_, err = ParseDelegationToken(token, ownerPub)
+	assert.NotNil(t, err)
+
+	// expired
+	token, err = NewDelegationToken(ownerID.Key(), delegatedKeys, scopes, 0,
+		time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+	_, err = ParseDelegationToken(token, ownerPub)
+	assert.NotNil(t, err)
+
+	// missing owner public key
+	_, err = ParseDelegationToken(token, nil)
+	assert.Equal(t, errDelegationMissingOwner, err)
+
+	// malformed token
+	_, err = ParseDelegationToken("not a token", ownerPub)
+	assert.NotNil(t, err)
+}
+
+func TestMintDelegation(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ownerID := ecid.NewPseudoRandom(rng)
+	dir, err := ioutil.TempDir("", "delegate-keychains")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	delegateDir := path.Join(dir, "delegate")
+	auth := "some delegate passphrase"
+
+	token, err := MintDelegation(zap.NewNop(), ownerID.Key(), delegateDir, auth,
+		keychain.LightScryptN, keychain.LightScryptP, []string{ScopeUpload}, 0, time.Time{})
+	assert.Nil(t, err)
+
+	// token delegates exactly the keys in the delegate keychains
+	authorKeys, readerKeys, err := LoadKeychains(delegateDir, auth)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, authorKeys.Len())
+	assert.Equal(t, 1, readerKeys.Len())
+	c := (&Config{}).WithDelegation(token, ownerID.PublicKeyBytes())
+	claims, err := getDelegation(c, authorKeys, readerKeys)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ScopeUpload}, claims.Scopes)
+
+	// won't overwrite existing keychains
+	_, err = MintDelegation(zap.NewNop(), ownerID.Key(), delegateDir, auth,
+		keychain.LightScryptN, keychain.LightScryptP, []string{ScopeUpload}, 0, time.Time{})
+	assert.Equal(t, ErrKeychainExists, err)
+}
+
+func TestGetDelegation(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ownerID := ecid.NewPseudoRandom(rng)
+	authorID, readerID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	authorKeys := keychain.FromECIDs([]ecid.ID{authorID})
+	readerKeys := keychain.FromECIDs([]ecid.ID{readerID})
+	token, err := NewDelegationToken(ownerID.Key(),
+		[][]byte{authorID.PublicKeyBytes(), readerID.PublicKeyBytes()},
+		[]string{ScopeDownload}, 0, time.Time{})
+	assert.Nil(t, err)
+
+	// no token means no delegation
+	claims, err := getDelegation(&Config{}, authorKeys, readerKeys)
+	assert.Nil(t, err)
+	assert.Nil(t, claims)
+
+	c := (&Config{}).WithDelegation(token, ownerID.PublicKeyBytes())
+	claims, err = getDelegation(c, authorKeys, readerKeys)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ScopeDownload}, claims.Scopes)
+
+	// token presented alongside root keys
+	rootKeys := keychain.New(3)
+	claims, err = getDelegation(c, rootKeys, readerKeys)
+	assert.Equal(t, ErrDelegationWrongDelegate, err)
+	assert.Nil(t, claims)
+
+	// token without owner
+	claims, err = getDelegation((&Config{}).WithDelegation(token, nil), authorKeys, readerKeys)
+	assert.Equal(t, errDelegationMissingOwner, err)
+	assert.Nil(t, claims)
+
+	// bad owner public key
+	c = (&Config{}).WithDelegation(token, []byte{1, 2, 3})
+	claims, err = getDelegation(c, authorKeys, readerKeys)
+	assert.NotNil(t, err)
+	assert.Nil(t, claims)
+
+	// token signed by someone else
+	c = (&Config{}).WithDelegation(token, ecid.NewPseudoRandom(rng).PublicKeyBytes())
+	claims, err = getDelegation(c, authorKeys, readerKeys)
+	assert.NotNil(t, err)
+	assert.Nil(t, claims)
+}
+
+func TestAuthor_delegationScope(t *testing.T) {
+	a := newTestAuthor()
+	a.delegation = &DelegationClaims{Scopes: []string{ScopeDownload}}
+
+	env, envKey, err := a.Upload(nil, "")
+	assert.Equal(t, ErrDelegationScope, err)
+	assert.Nil(t, env)
+	assert.Nil(t, envKey)
+
+	env, envKey, err = a.ShareEnvelope(nil, nil)
+	assert.Equal(t, ErrDelegationScope, err)
+	assert.Nil(t, env)
+	assert.Nil(t, envKey)
+
+	plan, err := a.DryRunUpload(nil, "")
+	assert.Equal(t, ErrDelegationScope, err)
+	assert.Nil(t, plan)
+
+	report, err := a.Repair(nil)
+	assert.Equal(t, ErrDelegationScope, err)
+	assert.Nil(t, report)
+
+	a.delegation.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+	assert.Equal(t, ErrDelegationExpired, a.Download(nil, nil))
+
+	err = a.CloseAndRemove()
+	assert.Nil(t, err)
+}
+
+func TestAuthor_limitUpload(t *testing.T) {
+	a := &Author{}
+	content := bytes.NewReader(make([]byte, 64))
+	assert.Equal(t, content, a.limitUpload(content))
+
+	// within limit
+	a.delegation = &DelegationClaims{MaxUploadBytes: 64}
+	read, err := ioutil.ReadAll(a.limitUpload(bytes.NewReader(make([]byte, 64))))
+	assert.Nil(t, err)
+	assert.Len(t, read, 64)
+
+	// exceeds limit
+	a.delegation.MaxUploadBytes = 63
+	_, err = ioutil.ReadAll(a.limitUpload(bytes.NewReader(make([]byte, 64))))
+	assert.Equal(t, ErrDelegationSizeLimit, err)
+}
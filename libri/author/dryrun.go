@@ -0,0 +1,141 @@
+package author
+
+import (
+	"io"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/pack"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/golang/protobuf/proto"
+)
+
+// PlannedDoc describes a single document an upload would store.
+type PlannedDoc struct {
+	// Key of the document
+	Key id.ID
+
+	// Size of the marshaled document in bytes
+	Size int
+
+	// ClosestPeers are the peers the librarians know to be closest to the key, i.e., the peers
+	// that would be asked to store the document
+	ClosestPeers []peer.Peer
+
+	// Err is the error encountered, if any, when finding the closest peers
+	Err error
+}
+
+// UploadPlan describes what uploading some content would store in the libri network without
+// actually storing anything.
+type UploadPlan struct {
+	// EnvelopeKey is the key the uploaded envelope would have
+	EnvelopeKey id.ID
+
+	// EntryKey is the key the uploaded entry would have
+	EntryKey id.ID
+
+	// Metadata of the packed entry
+	Metadata *api.EntryMetadata
+
+	// Docs contains the envelope, entry, and each (separately stored) page document
+	Docs []*PlannedDoc
+
+	// NPages is the number of pages in the entry
+	NPages int
+
+	// NReplicas is the number of replicas librarians store of each document by default
+	NReplicas uint
+}
+
+// TotalBytes returns the total size of all the documents in the plan.
+func (p *UploadPlan) TotalBytes() int {
+	n := 0
+	for _, pd := range p.Docs {
+		n += pd.Size
+	}
+	return n
+}
+
+// ReplicaBytes returns the estimated bytes stored across the network for all replicas of all the
+// documents in the plan.
+func (p *UploadPlan) ReplicaBytes() int {
+	return p.TotalBytes() * int(p.NReplicas)
+}
+
+// closestFinder finds the peers the librarians know to be closest to a key.
+type closestFinder interface {
+	getSeeds(key id.ID) ([]peer.Peer, error)
+}
+
+// DryRunUpload compresses, encrypts, and pages the content and computes the resulting document
+// keys as Upload would, but instead of publishing anything, it returns a plan describing the
+// document sizes and the peers that would store them. The only network requests made are
+// (read-only) Finds for the closest peers.
+func (a *Author) DryRunUpload(content io.Reader, mediaType string) (*UploadPlan, error) {
+	if err := a.authorize(ScopeUpload); err != nil {
+		return nil, a.logAndReturnErr("dry-run upload not authorized", err)
+	}
+	content = a.limitUpload(content)
+	startTime := time.Now()
+	a.logger.Debug("dry-run uploading document")
+
+	authorPub, readerPub, kek, eek, err := a.envKeys.sample()
+	if err != nil {
+		return nil, a.logAndReturnErr("error sampling keys", err)
+	}
+	entry, metadata, err := a.getEntryPacker().Pack(content, mediaType, eek, authorPub)
+	if err != nil {
+		return nil, a.logAndReturnErr("error packing content", err)
+	}
+	entryKey, nPages, err := getEntryInfo(entry)
+	if err != nil {
+		return nil, a.logAndReturnErr("error getting entry info", err)
+	}
+	pageKeys, err := api.GetEntryPageKeys(entry)
+	if err != nil {
+		return nil, a.logAndReturnErr("error getting entry page keys", err)
+	}
+	eekCiphertext, eekCiphertextMAC, err := kek.Encrypt(eek)
+	if err != nil {
+		return nil, a.logAndReturnErr("error encrypting entry keys", err)
+	}
+	env := pack.NewEnvelopeDoc(entryKey, authorPub, readerPub, eekCiphertext, eekCiphertextMAC)
+	envKey, err := api.GetKey(env)
+	if err != nil {
+		return nil, a.logAndReturnErr("error getting envelope key", err)
+	}
+
+	plan := &UploadPlan{
+		EnvelopeKey: envKey,
+		EntryKey:    entryKey,
+		Metadata:    metadata,
+		Docs:        make([]*PlannedDoc, 0, len(pageKeys)+2),
+		NPages:      nPages,
+		NReplicas:   store.DefaultNReplicas,
+	}
+	plan.Docs = append(plan.Docs, a.planDoc(envKey, env), a.planDoc(entryKey, entry))
+	for _, pageKey := range pageKeys {
+		page, err := a.documentSLD.Load(pageKey)
+		if err != nil {
+			return nil, a.logAndReturnErr("error loading page", err)
+		}
+		plan.Docs = append(plan.Docs, a.planDoc(pageKey, page))
+
+		// pages would otherwise be deleted after they're published
+		if err = a.documentSLD.Delete(pageKey); err != nil {
+			return nil, a.logAndReturnErr("error deleting page", err)
+		}
+	}
+
+	a.logger.Info("planned document upload", plannedUploadFields(plan, time.Since(startTime))...)
+	return plan, nil
+}
+
+func (a *Author) planDoc(key id.ID, doc *api.Document) *PlannedDoc {
+	pd := &PlannedDoc{Key: key, Size: proto.Size(doc)}
+	pd.ClosestPeers, pd.Err = a.closest.getSeeds(key)
+	return pd
+}
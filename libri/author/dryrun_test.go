@@ -0,0 +1,86 @@
+package author
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/author/io/common"
+	"github.com/drausin/libri/libri/author/io/page"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthor_DryRunUpload_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a := newTestAuthor()
+	closest := peer.NewTestPeers(rng, 3)
+	a.closest = &fixedClosestFinder{peers: closest}
+
+	// no network writes should happen
+	a.shipper = &fixedShipper{err: errors.New("some Ship error")}
+
+	page.MinSize = 64 // just for testing
+	a.config.Print.PageSize = 128
+	content := common.NewCompressableBytes(rng, 1024)
+
+	plan, err := a.DryRunUpload(content, "application/x-pdf")
+	assert.Nil(t, err)
+	assert.NotNil(t, plan.EnvelopeKey)
+	assert.NotNil(t, plan.EntryKey)
+	assert.NotNil(t, plan.Metadata)
+	assert.True(t, plan.NPages > 1)
+	assert.Len(t, plan.Docs, plan.NPages+2)
+	assert.Equal(t, plan.EnvelopeKey, plan.Docs[0].Key)
+	assert.Equal(t, plan.EntryKey, plan.Docs[1].Key)
+	assert.Equal(t, store.DefaultNReplicas, plan.NReplicas)
+	assert.Equal(t, plan.TotalBytes()*int(store.DefaultNReplicas), plan.ReplicaBytes())
+	for _, pd := range plan.Docs {
+		assert.True(t, pd.Size > 0)
+		assert.Equal(t, closest, pd.ClosestPeers)
+		assert.Nil(t, pd.Err)
+	}
+
+	// pages shouldn't be left in local storage
+	for _, pd := range plan.Docs[2:] {
+		doc, err := a.documentSLD.Load(pd.Key)
+		assert.Nil(t, err)
+		assert.Nil(t, doc)
+	}
+
+	err = a.CloseAndRemove()
+	assert.Nil(t, err)
+}
+
+func TestAuthor_DryRunUpload_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a := newTestAuthor()
+	a.closest = &fixedClosestFinder{err: errNoRepairSeeds}
+
+	// Find errors are reported per document rather than failing the plan
+	plan, err := a.DryRunUpload(common.NewCompressableBytes(rng, 256), "application/x-pdf")
+	assert.Nil(t, err)
+	for _, pd := range plan.Docs {
+		assert.Equal(t, errNoRepairSeeds, pd.Err)
+	}
+
+	// pack error should bubble up
+	a.entryPacker = &fixedEntryPacker{err: errors.New("some Pack error")}
+	plan, err = a.DryRunUpload(nil, "")
+	assert.NotNil(t, err)
+	assert.Nil(t, plan)
+
+	err = a.CloseAndRemove()
+	assert.Nil(t, err)
+}
+
+type fixedClosestFinder struct {
+	peers []peer.Peer
+	err   error
+}
+
+func (f *fixedClosestFinder) getSeeds(key id.ID) ([]peer.Peer, error) {
+	return f.peers, f.err
+}
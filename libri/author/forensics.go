@@ -0,0 +1,155 @@
+package author
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/enc"
+	"github.com/drausin/libri/libri/author/io/page"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	forensicsDirPerm  = 0700
+	forensicsFilePerm = 0600
+
+	logForensicBundle     = "forensic_bundle"
+	logForensicBundlePath = "forensic_bundle_path"
+)
+
+// ForensicBundle captures the evidence of a download whose content failed its integrity checks,
+// so the corruption can be reported and reproduced rather than just surfacing as an error.
+type ForensicBundle struct {
+	// Time is when the bundle was captured, in RFC3339 format
+	Time string `json:"time"`
+
+	// Error is the integrity error encountered
+	Error string `json:"error"`
+
+	// EnvelopeKey is the hex key of the envelope downloaded
+	EnvelopeKey string `json:"envelope_key"`
+
+	// EntryKey is the hex key of the entry downloaded
+	EntryKey string `json:"entry_key"`
+
+	// EnvelopeServedBy is the hex public key of the librarian that served the envelope, if known
+	EnvelopeServedBy string `json:"envelope_served_by,omitempty"`
+
+	// EntryServedBy is the hex public key of the librarian that served the entry, if known
+	EntryServedBy string `json:"entry_served_by,omitempty"`
+
+	// Expected contains the sizes and MACs in the entry metadata
+	Expected *ForensicSizes `json:"expected,omitempty"`
+
+	// Computed contains the sizes and MACs computed from the downloaded content
+	Computed *ForensicSizes `json:"computed"`
+
+	// Pages contains the verification result for each page, in index order
+	Pages []*ForensicPage `json:"pages"`
+}
+
+// ForensicSizes contains the (hex) MACs and sizes of a document's ciphertext and uncompressed
+// content.
+type ForensicSizes struct {
+	CiphertextSize   uint64 `json:"ciphertext_size"`
+	CiphertextMAC    string `json:"ciphertext_mac"`
+	UncompressedSize uint64 `json:"uncompressed_size"`
+	UncompressedMAC  string `json:"uncompressed_mac"`
+}
+
+// ForensicPage is the verification result for a single page of a ForensicBundle.
+type ForensicPage struct {
+	Key            string `json:"key"`
+	Index          uint32 `json:"index"`
+	CiphertextSize uint64 `json:"ciphertext_size"`
+	MACOK          bool   `json:"mac_ok"`
+	ServedBy       string `json:"served_by,omitempty"`
+}
+
+// NewForensicBundle creates a new *ForensicBundle from the report of a failed download.
+func NewForensicBundle(rp *DownloadReport, now time.Time) *ForensicBundle {
+	b := &ForensicBundle{
+		Time:             now.UTC().Format(time.RFC3339Nano),
+		EnvelopeKey:      rp.EnvelopeKey.String(),
+		EntryKey:         rp.EntryKey.String(),
+		EnvelopeServedBy: hex.EncodeToString(rp.EnvelopeServedBy),
+		EntryServedBy:    hex.EncodeToString(rp.EntryServedBy),
+		Computed: &ForensicSizes{
+			CiphertextSize:   rp.CiphertextSize,
+			CiphertextMAC:    hex.EncodeToString(rp.CiphertextMAC),
+			UncompressedSize: rp.UncompressedSize,
+			UncompressedMAC:  hex.EncodeToString(rp.UncompressedMAC),
+		},
+		Pages: make([]*ForensicPage, len(rp.Pages)),
+	}
+	if rp.Err != nil {
+		b.Error = rp.Err.Error()
+	}
+	if rp.Metadata != nil {
+		b.Expected = &ForensicSizes{
+			CiphertextSize:   rp.Metadata.CiphertextSize,
+			CiphertextMAC:    hex.EncodeToString(rp.Metadata.CiphertextMac),
+			UncompressedSize: rp.Metadata.UncompressedSize,
+			UncompressedMAC:  hex.EncodeToString(rp.Metadata.UncompressedMac),
+		}
+	}
+	for i, pv := range rp.Pages {
+		b.Pages[i] = &ForensicPage{
+			Key:            pv.Key.String(),
+			Index:          pv.Index,
+			CiphertextSize: pv.CiphertextSize,
+			MACOK:          pv.MACOK,
+			ServedBy:       hex.EncodeToString(pv.ServedBy),
+		}
+	}
+	return b
+}
+
+// WriteForensicBundle writes the bundle as JSON to a new file in the given directory, returning
+// the file path.
+func WriteForensicBundle(dir string, b *ForensicBundle, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, forensicsDirPerm); err != nil {
+		return "", err
+	}
+	buf, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	fp := filepath.Join(dir, fmt.Sprintf("%s-%d.json", b.EntryKey, now.UnixNano()))
+	return fp, ioutil.WriteFile(fp, buf, forensicsFilePerm)
+}
+
+// captureForensics logs the forensic bundle for the failed download and writes it to the
+// forensics directory, if one is configured.
+func (a *Author) captureForensics(rp *DownloadReport) {
+	now := time.Now()
+	b := NewForensicBundle(rp, now)
+	fields := []zapcore.Field{zap.Reflect(logForensicBundle, b)}
+	if a.config != nil && a.config.ForensicsDir != "" {
+		fp, err := WriteForensicBundle(a.config.ForensicsDir, b, now)
+		if err != nil {
+			a.logger.Error("error writing forensic bundle", zap.Error(err))
+		} else {
+			fields = append(fields, zap.String(logForensicBundlePath, fp))
+		}
+	}
+	a.logger.Error("downloaded content failed integrity checks", fields...)
+}
+
+// isIntegrityErr returns whether the error indicates the downloaded content does not match its
+// expected sizes or MACs.
+func isIntegrityErr(err error) bool {
+	switch err {
+	case enc.ErrUnexpectedCiphertextSize, enc.ErrUnexpectedCiphertextMAC,
+		enc.ErrUnexpectedUncompressedSize, enc.ErrUnexpectedUncompressedMAC,
+		page.ErrUnexpectedCiphertextMAC:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package author
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/enc"
+	"github.com/drausin/libri/libri/author/io/page"
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthor_Download_forensics(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, docKey := api.NewTestDocument(rng)
+	metadata := newTestEntryMetadata(rng)
+	dir, err := ioutil.TempDir("", "author-test-forensics-dir")
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	assert.Nil(t, err)
+
+	a := &Author{
+		config:        NewDefaultConfig().WithForensicsDir(dir),
+		logger:        clogging.NewDevInfoLogger(),
+		receiver:      &fixedReceiver{entry: doc, keys: enc.NewPseudoRandomEEK(rng)},
+		entryUnpacker: &fixedUnpacker{metadata: metadata, err: enc.ErrUnexpectedCiphertextMAC},
+	}
+	err = a.Download(nil, docKey)
+	assert.Equal(t, enc.ErrUnexpectedCiphertextMAC, err)
+
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	buf, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.Nil(t, err)
+	b := &ForensicBundle{}
+	assert.Nil(t, json.Unmarshal(buf, b))
+	assert.Equal(t, enc.ErrUnexpectedCiphertextMAC.Error(), b.Error)
+	assert.Equal(t, docKey.String(), b.EnvelopeKey)
+	assert.Equal(t, metadata.CiphertextSize, b.Expected.CiphertextSize)
+	assert.Len(t, b.Pages, 1)
+
+	// non-integrity errors don't capture a bundle
+	a.entryUnpacker = &fixedUnpacker{err: errors.New("some Unpack error")}
+	err = a.Download(nil, docKey)
+	assert.NotNil(t, err)
+	files, err = ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	// empty forensics dir only logs bundle
+	a.config.ForensicsDir = ""
+	a.entryUnpacker = &fixedUnpacker{err: page.ErrUnexpectedCiphertextMAC}
+	err = a.Download(nil, docKey)
+	assert.Equal(t, page.ErrUnexpectedCiphertextMAC, err)
+	files, err = ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestNewForensicBundle(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rp := &DownloadReport{
+		EnvelopeKey:   id.NewPseudoRandom(rng),
+		EntryKey:      id.NewPseudoRandom(rng),
+		EntryServedBy: api.RandBytes(rng, api.ECPubKeyLength),
+		Pages: []*PageVerification{
+			{Key: id.NewPseudoRandom(rng), Index: 0, CiphertextSize: 10, MACOK: true},
+			{Key: id.NewPseudoRandom(rng), Index: 1, CiphertextSize: 5, MACOK: false},
+		},
+		Metadata:       newTestEntryMetadata(rng),
+		CiphertextSize: 15,
+		CiphertextMAC:  api.RandBytes(rng, api.HMAC256Length),
+		Err:            enc.ErrUnexpectedCiphertextSize,
+	}
+	b := NewForensicBundle(rp, time.Now())
+	assert.Equal(t, rp.EntryKey.String(), b.EntryKey)
+	assert.Empty(t, b.EnvelopeServedBy)
+	assert.NotEmpty(t, b.EntryServedBy)
+	assert.Equal(t, rp.Metadata.CiphertextSize, b.Expected.CiphertextSize)
+	assert.Equal(t, uint64(15), b.Computed.CiphertextSize)
+	assert.NotEqual(t, b.Expected.CiphertextMAC, b.Computed.CiphertextMAC)
+	assert.Len(t, b.Pages, 2)
+	assert.False(t, b.Pages[1].MACOK)
+
+	// missing metadata has no expected values
+	rp.Metadata, rp.Err = nil, nil
+	b = NewForensicBundle(rp, time.Now())
+	assert.Nil(t, b.Expected)
+	assert.Empty(t, b.Error)
+}
+
+func TestWriteForensicBundle_err(t *testing.T) {
+	file, err := ioutil.TempFile("", "author-test-forensics-file")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.Remove(file.Name())) }()
+
+	// can't make dir under a file
+	fp, err := WriteForensicBundle(filepath.Join(file.Name(), "dir"), &ForensicBundle{}, time.Now())
+	assert.NotNil(t, err)
+	assert.Empty(t, fp)
+}
+
+func TestIsIntegrityErr(t *testing.T) {
+	assert.True(t, isIntegrityErr(enc.ErrUnexpectedCiphertextSize))
+	assert.True(t, isIntegrityErr(enc.ErrUnexpectedCiphertextMAC))
+	assert.True(t, isIntegrityErr(enc.ErrUnexpectedUncompressedSize))
+	assert.True(t, isIntegrityErr(enc.ErrUnexpectedUncompressedMAC))
+	assert.True(t, isIntegrityErr(page.ErrUnexpectedCiphertextMAC))
+	assert.False(t, isIntegrityErr(errors.New("some other error")))
+	assert.False(t, isIntegrityErr(nil))
+}
+
+func newTestEntryMetadata(rng *rand.Rand) *api.EntryMetadata {
+	return &api.EntryMetadata{
+		MediaType:        "application/x-pdf",
+		CiphertextSize:   1,
+		CiphertextMac:    api.RandBytes(rng, api.HMAC256Length),
+		UncompressedSize: 2,
+		UncompressedMac:  api.RandBytes(rng, api.HMAC256Length),
+	}
+}
@@ -5,8 +5,11 @@ import (
 
 	"github.com/drausin/libri/libri/author/io/enc"
 	"github.com/drausin/libri/libri/author/keychain"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
 	"google.golang.org/grpc"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 )
 
 type envelopeKeySampler interface {
@@ -43,13 +46,13 @@ func (s *envelopeKeySamplerImpl) sample() ([]byte, []byte, *enc.KEK, *enc.EEK, e
 
 // use var so it's easy to replace for tests w/o a single-method interface
 var getLibrarianHealthClients = func(
-	librarianAddrs []*net.TCPAddr,
+	librarianAddrs []*net.TCPAddr, interceptors *client.Interceptors,
 ) (map[string]healthpb.HealthClient, error) {
 
 	healthClients := make(map[string]healthpb.HealthClient)
 	for _, librarianAddr := range librarianAddrs {
 		addrStr := librarianAddr.String()
-		conn, err := grpc.Dial(addrStr, grpc.WithInsecure())
+		conn, err := grpc.Dial(addrStr, interceptors.DialOptions()...)
 		if err != nil {
 			return nil, err
 		}
@@ -57,3 +60,16 @@ var getLibrarianHealthClients = func(
 	}
 	return healthClients, nil
 }
+
+// verifiedBuildInfo returns the librarian's build info from the health check response header if
+// present and validly signed and nil otherwise.
+func verifiedBuildInfo(header metadata.MD) *api.BuildInfo {
+	sbi, err := client.FromBuildInfoHeader(header)
+	if err != nil {
+		return nil
+	}
+	if err := client.VerifySignedBuildInfo(client.NewVerifier(), sbi); err != nil {
+		return nil
+	}
+	return sbi.BuildInfo
+}
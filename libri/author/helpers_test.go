@@ -2,13 +2,17 @@ package author
 
 import (
 	"errors"
+	"math/rand"
 	"net"
 	"testing"
 
 	"github.com/drausin/libri/libri/author/io/enc"
 	"github.com/drausin/libri/libri/author/keychain"
 	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestEnvelopeKeySampler_Sample_ok(t *testing.T) {
@@ -70,7 +74,7 @@ func TestGetLibrarianHealthClients(t *testing.T) {
 		{IP: net.ParseIP("127.0.0.1"), Port: 20100},
 		{IP: net.ParseIP("127.0.0.1"), Port: 20101},
 	}
-	healthClients, err := getLibrarianHealthClients(librarianAddrs)
+	healthClients, err := getLibrarianHealthClients(librarianAddrs, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(healthClients))
 	_, in := healthClients["127.0.0.1:20100"]
@@ -95,3 +99,28 @@ func (f *fixedKeychain) Get(publicKey []byte) (ecid.ID, bool) {
 func (f *fixedKeychain) Len() int {
 	return 0
 }
+
+func TestVerifiedBuildInfo(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID1, peerID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	info := &api.BuildInfo{Version: "0.1.0", ProtocolVersion: 1}
+
+	// missing
+	assert.Nil(t, verifiedBuildInfo(metadata.MD{}))
+
+	// ok
+	sbi, err := client.NewSignedBuildInfo(client.NewECDSASigner(peerID1.Key()),
+		peerID1.PublicKeyBytes(), info)
+	assert.Nil(t, err)
+	header, err := client.NewBuildInfoHeader(sbi)
+	assert.Nil(t, err)
+	assert.Equal(t, info, verifiedBuildInfo(header))
+
+	// bad signature
+	sbi, err = client.NewSignedBuildInfo(client.NewECDSASigner(peerID2.Key()),
+		peerID1.PublicKeyBytes(), info)
+	assert.Nil(t, err)
+	header, err = client.NewBuildInfoHeader(sbi)
+	assert.Nil(t, err)
+	assert.Nil(t, verifiedBuildInfo(header))
+}
@@ -32,6 +32,10 @@ type Parameters struct {
 	// Parallelism is the parallelism used by Printers and Scanners when storing and loading
 	// pages.
 	Parallelism uint32
+
+	// Codecs optionally restricts the compression codecs Printers may use. When the codec for a
+	// media type isn't among them, content is left uncompressed. Nil allows all codecs.
+	Codecs []api.CompressionCodec
 }
 
 // NewParameters creates a new *Parameters instance.
@@ -55,6 +59,18 @@ func NewDefaultParameters() *Parameters {
 	return params
 }
 
+func (p *Parameters) allowsCodec(codec api.CompressionCodec) bool {
+	if p.Codecs == nil {
+		return true
+	}
+	for _, c := range p.Codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
 // Printer stores pages created from (uncompressed) content.
 type Printer interface {
 	// Print creates pages from the given content and stores them via an internal page.Storer.
@@ -90,6 +106,9 @@ func (p *printer) Print(content io.Reader, mediaType string, keys *enc.EEK, auth
 	if err != nil {
 		return nil, nil, err
 	}
+	if !p.params.allowsCodec(codec) {
+		codec = api.CompressionCodec_NONE
+	}
 	compressor, paginator, err := p.init.Initialize(content, codec, keys, authorPub, pages)
 	if err != nil {
 		return nil, nil, err
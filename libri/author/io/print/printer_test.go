@@ -82,6 +82,33 @@ func TestPrinter_Print_ok(t *testing.T) {
 	assert.Equal(t, ciphertextSum, entryMetadata.CiphertextMac)
 }
 
+func TestPrinter_Print_codecs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	authorPub := api.RandBytes(rng, api.ECPubKeyLength)
+	keys := enc.NewPseudoRandomEEK(rng)
+	pageSL := page.NewStorerLoader(storage.NewTestDocSLD())
+	content := common.NewCompressableBytes(rng, 1024).Bytes()
+
+	cases := []struct {
+		codecs   []api.CompressionCodec
+		expected api.CompressionCodec
+	}{
+		{nil, api.CompressionCodec_GZIP},
+		{[]api.CompressionCodec{api.CompressionCodec_GZIP}, api.CompressionCodec_GZIP},
+		{[]api.CompressionCodec{api.CompressionCodec_NONE}, api.CompressionCodec_NONE},
+		{[]api.CompressionCodec{}, api.CompressionCodec_NONE},
+	}
+	for _, c := range cases {
+		params := NewDefaultParameters()
+		params.Codecs = c.codecs
+		p := NewPrinter(params, pageSL)
+		_, metadata, err := p.Print(bytes.NewReader(content), "application/x-pdf", keys,
+			authorPub)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, metadata.CompressionCodec)
+	}
+}
+
 func TestPrinter_Print_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	params, err := NewParameters(comp.MinBufferSize, page.MinSize, DefaultParallelism)
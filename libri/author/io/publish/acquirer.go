@@ -41,11 +41,13 @@ func NewAcquirer(
 
 func (a *acquirer) Acquire(docKey id.ID, authorPub []byte, lc api.Getter) (*api.Document, error) {
 	rq := client.NewGetRequest(a.clientID, a.orgID, docKey)
+	rq.ReadRepair = a.params.ReadRepair
 	ctx, cancel, err := client.NewSignedTimeoutContext(a.clientSigner, a.orgSigner, rq,
 		a.params.GetTimeout)
 	if err != nil {
 		return nil, err
 	}
+	ctx = client.AddMacaroonContext(ctx, a.params.Macaroon)
 	rp, err := lc.Get(ctx, rq)
 	cancel()
 	if err != nil {
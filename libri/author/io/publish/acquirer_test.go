@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestAcquirer_Acquire_ok(t *testing.T) {
@@ -34,11 +35,22 @@ func TestAcquirer_Acquire_ok(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, actualDoc, expectedDoc)
 	assert.Equal(t, docKey.Bytes(), lc.request.Key)
+	assert.False(t, lc.request.ReadRepair)
 
+	params.ReadRepair = true
 	actualDoc, err = acq.Acquire(docKey, authorPub, lc)
 	assert.Nil(t, err)
 	assert.Equal(t, actualDoc, expectedDoc)
 	assert.Equal(t, docKey.Bytes(), lc.request.Key)
+	assert.True(t, lc.request.ReadRepair)
+	md, _ := metadata.FromOutgoingContext(lc.ctx)
+	assert.Empty(t, md["macaroon"])
+
+	params.Macaroon = "some macaroon"
+	_, err = acq.Acquire(docKey, authorPub, lc)
+	assert.Nil(t, err)
+	md, _ = metadata.FromOutgoingContext(lc.ctx)
+	assert.Equal(t, []string{params.Macaroon}, md["macaroon"])
 }
 
 func TestAcquirer_Acquire_err(t *testing.T) {
@@ -174,6 +186,7 @@ func TestMultiStoreAcquirer_Acquire_err(t *testing.T) {
 }
 
 type fixedGetter struct {
+	ctx           context.Context
 	request       *api.GetRequest
 	responseValue *api.Document
 	err           error
@@ -182,6 +195,7 @@ type fixedGetter struct {
 func (f *fixedGetter) Get(ctx context.Context, in *api.GetRequest, opts ...grpc.CallOption) (
 	*api.GetResponse, error) {
 
+	f.ctx = ctx
 	f.request = in
 	return &api.GetResponse{
 		Metadata: &api.ResponseMetadata{
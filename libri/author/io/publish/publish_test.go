@@ -18,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestNewParameters_ok(t *testing.T) {
@@ -68,6 +69,58 @@ func TestPublisher_Publish_ok(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, expectedDocKey, actualDocKey)
 	assert.Equal(t, doc, lc.request.Value)
+	md, _ := metadata.FromOutgoingContext(lc.ctx)
+	assert.Empty(t, md["storeauth"])
+}
+
+func TestPublisher_Publish_storeAuth(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	clientID := ecid.NewPseudoRandom(rng)
+	signer := client.NewECDSASigner(clientID.Key())
+	orgID := ecid.NewPseudoRandom(rng)
+	orgSigner := client.NewECDSASigner(orgID.Key())
+	params := NewDefaultParameters()
+	params.StoreAuthToken = "some store auth token"
+	params.Macaroon = "some macaroon"
+	lc := &fixedPutter{}
+	pub := NewPublisher(clientID, orgID, signer, orgSigner, params)
+
+	doc, _ := api.NewTestDocument(rng)
+	_, err := pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.Nil(t, err)
+	md, _ := metadata.FromOutgoingContext(lc.ctx)
+	assert.Equal(t, []string{params.StoreAuthToken}, md["storeauth"])
+	assert.Equal(t, []string{params.Macaroon}, md["macaroon"])
+}
+
+func TestPublisher_Publish_replication(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	clientID := ecid.NewPseudoRandom(rng)
+	signer := client.NewECDSASigner(clientID.Key())
+	orgID := ecid.NewPseudoRandom(rng)
+	orgSigner := client.NewECDSASigner(orgID.Key())
+	params := NewDefaultParameters()
+	lc := &fixedPutter{}
+	pub := NewPublisher(clientID, orgID, signer, orgSigner, params)
+	doc, _ := api.NewTestDocument(rng)
+
+	// no policy leaves number of replicas to librarian
+	_, err := pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.Nil(t, err)
+	assert.Zero(t, lc.request.NReplicas)
+
+	params.Replication = api.TypeReplicas{api.GetDocumentType(doc): 5}
+	_, err = pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(5), lc.request.NReplicas)
+
+	// no TTL stores document permanently
+	assert.Zero(t, lc.request.TtlSeconds)
+
+	params.TTL = time.Hour
+	_, err = pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3600), lc.request.TtlSeconds)
 }
 
 func TestPublisher_Publish_err(t *testing.T) {
@@ -123,6 +176,53 @@ func TestPublisher_Publish_err(t *testing.T) {
 	assert.Nil(t, docKey)
 }
 
+func TestPublisher_Publish_receipts(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	clientID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	peerID := ecid.NewPseudoRandom(rng)
+	peerSigner := client.NewECDSASigner(peerID.Key())
+	pub := NewPublisher(clientID, orgID, client.NewECDSASigner(clientID.Key()),
+		client.NewECDSASigner(orgID.Key()), NewDefaultParameters())
+	doc, docKey := api.NewTestDocument(rng)
+	newReceipt := func(key []byte, value *api.Document) *api.SignedStoreReceipt {
+		ssr, err := client.NewSignedStoreReceipt(peerSigner, peerID.PublicKeyBytes(), key, value,
+			time.Now())
+		assert.Nil(t, err)
+		return ssr
+	}
+	shard, shardKey := api.NewTestShardDocument(rng)
+	manifest, _ := api.NewTestShardManifestDocument(rng)
+	manifestKey := api.GetShardManifestKey(docKey.Bytes())
+
+	// receipts for the document, its shards, and its manifest are accepted
+	lc := &fixedPutter{receipts: []*api.SignedStoreReceipt{
+		newReceipt(docKey.Bytes(), doc),
+		newReceipt(shardKey.Bytes(), shard),
+		newReceipt(manifestKey.Bytes(), manifest),
+	}}
+	_, err := pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.Nil(t, err)
+
+	// receipt for a different value
+	other, _ := api.NewTestDocument(rng)
+	lc = &fixedPutter{receipts: []*api.SignedStoreReceipt{newReceipt(docKey.Bytes(), other)}}
+	_, err = pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.Equal(t, ErrUnexpectedReceipt, err)
+
+	// receipt for a different key
+	otherKey := id.NewPseudoRandom(rng).Bytes()
+	lc = &fixedPutter{receipts: []*api.SignedStoreReceipt{newReceipt(otherKey, doc)}}
+	_, err = pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.Equal(t, ErrUnexpectedReceipt, err)
+
+	// receipt with a bad signature
+	ssr := newReceipt(docKey.Bytes(), doc)
+	ssr.Receipt.Timestamp++
+	lc = &fixedPutter{receipts: []*api.SignedStoreReceipt{ssr}}
+	_, err = pub.Publish(doc, api.GetAuthorPub(doc), lc)
+	assert.NotNil(t, err)
+}
+
 func TestSingleLoadPublisher_Publish_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	pub := &fixedPublisher{}
@@ -302,19 +402,23 @@ func TestMultiAcquirePublish(t *testing.T) {
 }
 
 type fixedPutter struct {
-	request *api.PutRequest
-	err     error
+	ctx      context.Context
+	request  *api.PutRequest
+	receipts []*api.SignedStoreReceipt
+	err      error
 }
 
 func (p *fixedPutter) Put(
 	ctx context.Context, in *api.PutRequest, opts ...grpc.CallOption,
 ) (*api.PutResponse, error) {
 
+	p.ctx = ctx
 	p.request = in
 	return &api.PutResponse{
 		Metadata: &api.ResponseMetadata{
 			RequestId: in.Metadata.RequestId,
 		},
+		Receipts: p.receipts,
 	}, p.err
 }
 
@@ -55,6 +55,10 @@ var (
 	// ErrInconsistentAuthorPubKey indicates when the document author public key is different
 	// from the expected value.
 	ErrInconsistentAuthorPubKey = errors.New("inconsistent author public key")
+
+	// ErrUnexpectedReceipt indicates when a store receipt in a Put response is for neither the
+	// published document nor its erasure-coded shards.
+	ErrUnexpectedReceipt = errors.New("unexpected store receipt")
 )
 
 // Parameters define configuration used by a Publisher.
@@ -72,6 +76,27 @@ type Parameters struct {
 	// GetParallelism is the number of simultaneous Ge requests (for different documents) that
 	// can occur.
 	GetParallelism uint32
+
+	// StoreAuthToken is an optional store authorization token attached to Put requests for
+	// librarians that require one.
+	StoreAuthToken string
+
+	// Macaroon is an optional encoded macaroon attached to Put and Get requests for librarians
+	// that require one authorizing the organization's puts, gets, and shares.
+	Macaroon string
+
+	// Replication optionally gives the number of replicas librarians should store each
+	// document with, e.g., more for envelopes and entries than for bulk pages. When nil, the
+	// librarians' defaults are used.
+	Replication api.ReplicationPolicy
+
+	// ReadRepair determines whether Get requests ask librarians to re-store each acquired document
+	// with the closest peers found missing it, healing the replication of the documents read.
+	ReadRepair bool
+
+	// TTL optionally gives how long librarians should store each published document before it
+	// expires. When zero, documents never expire.
+	TTL time.Duration
 }
 
 // NewParameters validates the parameters and returns a new *Parameters instance.
@@ -121,6 +146,7 @@ type publisher struct {
 	orgID        ecid.ID
 	clientSigner client.Signer
 	orgSigner    client.Signer
+	verifier     client.Verifier
 	params       *Parameters
 }
 
@@ -133,6 +159,7 @@ func NewPublisher(
 		orgID:        orgID,
 		clientSigner: clientSigner,
 		orgSigner:    orgSigner,
+		verifier:     client.NewVerifier(),
 		params:       params,
 	}
 }
@@ -146,11 +173,19 @@ func (p *publisher) Publish(doc *api.Document, authorPub []byte, lc api.Putter)
 		return nil, ErrInconsistentAuthorPubKey
 	}
 	rq := client.NewPutRequest(p.clientID, p.orgID, docKey, doc)
+	if p.params.Replication != nil {
+		rq.NReplicas = uint32(p.params.Replication.NReplicas(doc))
+	}
+	if p.params.TTL > 0 {
+		rq.TtlSeconds = uint64(p.params.TTL / time.Second)
+	}
 	ctx, cancel, err := client.NewSignedTimeoutContext(p.clientSigner, p.orgSigner, rq,
 		p.params.PutTimeout)
 	if err != nil {
 		return nil, err
 	}
+	ctx = client.AddStoreAuthContext(ctx, p.params.StoreAuthToken)
+	ctx = client.AddMacaroonContext(ctx, p.params.Macaroon)
 	rp, err := lc.Put(ctx, rq)
 	cancel()
 	if err != nil {
@@ -159,9 +194,37 @@ func (p *publisher) Publish(doc *api.Document, authorPub []byte, lc api.Putter)
 	if !bytes.Equal(rq.Metadata.RequestId, rp.Metadata.RequestId) {
 		return nil, client.ErrUnexpectedRequestID
 	}
+	if err := p.checkReceipts(rp.Receipts, docKey, doc); err != nil {
+		return nil, err
+	}
 	return docKey, nil
 }
 
+// checkReceipts verifies the signed store receipts from the peers storing the document. Receipts
+// for replicas of the document must be for its key and value. Receipts for erasure-coded shards,
+// whose values the author doesn't have, must be for shard keys or the document's shard manifest.
+func (p *publisher) checkReceipts(
+	receipts []*api.SignedStoreReceipt, docKey id.ID, doc *api.Document,
+) error {
+	manifestKey := api.GetShardManifestKey(docKey.Bytes()).Bytes()
+	for _, ssr := range receipts {
+		if err := client.VerifySignedStoreReceipt(p.verifier, ssr); err != nil {
+			return err
+		}
+		key := ssr.Receipt.Key
+		if bytes.HasPrefix(key, api.ShardKeyPrefix) || bytes.Equal(key, manifestKey) {
+			continue
+		}
+		err := client.CheckSignedStoreReceipt(p.verifier, ssr, docKey.Bytes(), doc)
+		if err == client.ErrUnexpectedStoreReceipt {
+			return ErrUnexpectedReceipt
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SingleLoadPublisher publishes documents from internal storage.
 type SingleLoadPublisher interface {
 	// Publish loads a document with the given key and publishes them using the given
@@ -29,17 +29,17 @@ type receiver struct {
 	readerKeys keychain.Getter
 	acquirer   publish.Acquirer
 	msAcquirer publish.MultiStoreAcquirer
-	docS       storage.DocumentStorer
+	docS       storage.DocumentSL
 }
 
 // NewReceiver creates a new Receiver from the librarian balancer, keychain of reader keys,
-// acquirers, and storage.DocumentStorer.
+// acquirers, and storage.DocumentSL.
 func NewReceiver(
 	librarians client.GetterBalancer,
 	readerKeys keychain.Getter,
 	acquirer publish.Acquirer,
 	msAcquirer publish.MultiStoreAcquirer,
-	docS storage.DocumentStorer,
+	docS storage.DocumentSL,
 ) Receiver {
 	return &receiver{
 		librarians: librarians,
@@ -62,7 +62,7 @@ func (r *receiver) ReceiveEntry(envelopeKey id.ID) (*api.Document, *enc.EEK, err
 	// get the entry and pages
 	entryKey := id.FromBytes(envelope.EntryKey)
 	rlc := r.msAcquirer.GetRetryGetter(r.librarians)
-	entryDoc, err := r.acquirer.Acquire(entryKey, envelope.AuthorPublicKey, rlc)
+	entryDoc, err := r.acquire(entryKey, envelope.AuthorPublicKey, rlc)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -74,7 +74,7 @@ func (r *receiver) ReceiveEntry(envelopeKey id.ID) (*api.Document, *enc.EEK, err
 
 func (r *receiver) ReceiveEnvelope(envelopeKey id.ID) (*api.Envelope, error) {
 	rlc := r.msAcquirer.GetRetryGetter(r.librarians)
-	envelopeDoc, err := r.acquirer.Acquire(envelopeKey, nil, rlc)
+	envelopeDoc, err := r.acquire(envelopeKey, nil, rlc)
 	if err != nil {
 		return nil, err
 	}
@@ -115,9 +115,38 @@ func (r *receiver) getPages(entryDoc *api.Document, authorPubBytes []byte) error
 	if entry.Entry.PageKeys != nil {
 		pageKeys, err := api.GetEntryPageKeys(entryDoc)
 		errors.MaybePanic(err) // should never happen
-		return r.msAcquirer.Acquire(pageKeys, authorPubBytes, r.librarians)
+		missingKeys, err := r.missing(pageKeys)
+		if err != nil || len(missingKeys) == 0 {
+			return err
+		}
+		return r.msAcquirer.Acquire(missingKeys, authorPubBytes, r.librarians)
 	}
 
 	// should never get here
 	return api.ErrUnknownDocumentType
 }
+
+// acquire returns the document with the given key from the local store if it's already there
+// (e.g., because it was prefetched) and otherwise acquires it from the librarians.
+func (r *receiver) acquire(key id.ID, authorPub []byte, lc api.Getter) (*api.Document, error) {
+	doc, err := r.docS.Load(key)
+	if err != nil || doc != nil {
+		return doc, err
+	}
+	return r.acquirer.Acquire(key, authorPub, lc)
+}
+
+// missing returns the keys of the documents not already in the local store.
+func (r *receiver) missing(keys []id.ID) ([]id.ID, error) {
+	missing := make([]id.ID, 0, len(keys))
+	for _, key := range keys {
+		doc, err := r.docS.Load(key)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
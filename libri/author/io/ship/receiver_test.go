@@ -82,6 +82,49 @@ func TestReceiver_ReceiveEntry_ok(t *testing.T) {
 	}
 }
 
+func TestReceiver_ReceiveEntry_stored(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	authorKeys, readerKeys := keychain.New(3), keychain.New(3)
+	authorKey, err := authorKeys.Sample()
+	assert.Nil(t, err)
+	readerKey, err := readerKeys.Sample()
+	assert.Nil(t, err)
+	kek, err := enc.NewKEK(authorKey.Key(), &readerKey.Key().PublicKey)
+	assert.Nil(t, err)
+	entry1 := &api.Document{
+		Contents: &api.Document_Entry{Entry: api.NewTestMultiPageEntry(rng)},
+	}
+	pageKeys, err := api.GetEntryPageKeys(entry1)
+	assert.Nil(t, err)
+	entryKey, err := api.GetKey(entry1)
+	assert.Nil(t, err)
+	eek1 := enc.NewPseudoRandomEEK(rng)
+	eekCiphertext, eekCiphertextMAC, err := kek.Encrypt(eek1)
+	assert.Nil(t, err)
+	envelope := pack.NewEnvelopeDoc(entryKey, authorKey.PublicKeyBytes(),
+		readerKey.PublicKeyBytes(), eekCiphertext, eekCiphertextMAC)
+	envelopeKey, err := api.GetKey(envelope)
+	assert.Nil(t, err)
+
+	// envelope, entry, and first page were already stored (e.g., prefetched)
+	docS := storage.NewTestDocSLD()
+	assert.Nil(t, docS.Store(envelopeKey, envelope))
+	assert.Nil(t, docS.Store(entryKey, entry1))
+	page, _ := api.NewTestDocument(rng)
+	assert.Nil(t, docS.Store(pageKeys[0], page))
+	acq := &fixedAcquirer{docs: make(map[string]*api.Document)}
+	msAcq := &fixedMultiStoreAcquirer{}
+	r := NewReceiver(&fixedGetterBalancer{}, readerKeys, acq, msAcq, docS)
+
+	entry2, eek2, err := r.ReceiveEntry(envelopeKey)
+	assert.Nil(t, err)
+	assert.Equal(t, entry1, entry2)
+	assert.Equal(t, eek1, eek2)
+
+	// only the pages not already stored are acquired
+	assert.Equal(t, pageKeys[1:], msAcq.docKeys)
+}
+
 func TestReceiver_ReceiveEntry_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	cb := &fixedGetterBalancer{}
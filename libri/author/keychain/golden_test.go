@@ -0,0 +1,36 @@
+package keychain
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden keychain in testdata was saved by earlier versions of the code and stands in for
+// users' keychain files. It must never be regenerated: if this test fails, the change to the
+// keychain format is breaking and would lock users out of their keys.
+
+const goldenAuth = "golden passphrase"
+
+func TestGolden_Keychain(t *testing.T) {
+	kc, err := Load(filepath.Join("testdata", "keychain.golden"), goldenAuth)
+	assert.Nil(t, err)
+	for _, b := range []byte{0x11, 0x22} {
+		priv, err := crypto.ToECDSA(bytes.Repeat([]byte{b}, 32))
+		assert.Nil(t, err)
+		expected, err := ecid.FromPrivateKey(priv)
+		assert.Nil(t, err)
+
+		loaded, in := kc.Get(expected.PublicKeyBytes())
+		assert.True(t, in)
+		assert.Equal(t, expected.Key().D, loaded.Key().D)
+	}
+
+	kc, err = Load(filepath.Join("testdata", "keychain.golden"), "wrong passphrase")
+	assert.NotNil(t, err)
+	assert.Nil(t, kc)
+}
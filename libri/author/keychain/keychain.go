@@ -54,6 +54,9 @@ type Sampler interface {
 type GetterSampler interface {
 	Getter
 	Sampler
+
+	// Len returns the number of keys in the collection.
+	Len() int
 }
 
 type keychain struct {
@@ -106,6 +109,10 @@ func (kc *keychain) Get(publicKey []byte) (ecid.ID, bool) {
 	return value, in
 }
 
+func (kc *keychain) Len() int {
+	return len(kc.pubs)
+}
+
 type keychains struct {
 	kcs []Getter
 }
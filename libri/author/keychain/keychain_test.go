@@ -16,6 +16,7 @@ func TestSampler_Sample_ok(t *testing.T) {
 	k1, err := kc.Sample()
 	assert.Nil(t, err)
 	assert.NotNil(t, k1)
+	assert.Equal(t, 3, kc.Len())
 }
 
 func TestSampler_Sample_err(t *testing.T) {
@@ -4,6 +4,10 @@ import "os"
 
 // Close disconnects the author from its librarians and closes the DB.
 func (a *Author) Close() error {
+	// flush summaries of any suppressed repeated errors; sync errors on stdout/stderr are
+	// expected on some platforms, so ignore them
+	defer func() { _ = a.logger.Sync() }()
+
 	// send stop signal to listener
 	a.stop <- struct{}{}
 
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/drausin/libri/libri/author/io/print"
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/subscribe"
 	"github.com/drausin/libri/libri/librarian/api"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -14,6 +16,7 @@ import (
 
 const (
 	logClientIDShort  = "client_id_short"
+	logDBDriver       = "db_driver"
 	logEntryKey       = "entry_key"
 	logEnvelopeKey    = "envelope_key"
 	logAuthorPubShort = "author_pub_short"
@@ -21,8 +24,62 @@ const (
 	logNPages         = "n_pages"
 	logMetadata       = "metadata"
 	logSpeedMbps      = "speed_Mbps"
+	logPeerAddress    = "peer_address"
+	logPageSize       = "page_size"
+	logIssuedTime     = "issued_time"
+	logParallelism    = "parallelism"
+	logCodecs         = "codecs"
+	logLoad           = "load"
+	logNHealthy       = "n_healthy"
+	logNRepaired      = "n_repaired"
+	logNUnrepaired    = "n_unrepaired"
+	logNUnverified    = "n_unverified"
+	logNUnderRepl     = "n_under_replicated"
+	logNReplicas      = "n_replicas"
+	logNDocs          = "n_docs"
+	logTotalBytes     = "total_bytes"
+	logReplicaBytes   = "est_replica_bytes"
+	logElapsedTime    = "elapsed_time"
+	logVersion        = "version"
+	logGitRevision    = "git_revision"
+	logProtocolVer    = "protocol_version"
+	logMACFailures    = "n_mac_failures"
+	logVerified       = "verified"
+	logPubKey         = "publication_key"
+	logNBytes         = "n_bytes"
+	logNItems         = "n_items"
+	logNErrored       = "n_errored"
 )
 
+func healthyFields(addrStr string, info *api.BuildInfo) []zapcore.Field {
+	fields := []zapcore.Field{zap.String(logPeerAddress, addrStr)}
+	if info == nil {
+		return fields
+	}
+	return append(fields,
+		zap.String(logVersion, info.Version),
+		zap.String(logGitRevision, info.GitRevision),
+		zap.Uint32(logProtocolVer, info.ProtocolVersion),
+	)
+}
+
+func adaptedParamsFields(
+	configured, adapted *print.Parameters, caps *api.Capabilities,
+) []zapcore.Field {
+	codecs := make([]string, len(adapted.Codecs))
+	for i, codec := range adapted.Codecs {
+		codecs[i] = codec.String()
+	}
+	return []zapcore.Field{
+		zap.Uint32(logPageSize, adapted.PageSize),
+		zap.Uint32(logParallelism, adapted.Parallelism),
+		zap.Strings(logCodecs, codecs),
+		zap.Float64(logLoad, caps.Load),
+		zap.Uint32("configured_"+logPageSize, configured.PageSize),
+		zap.Uint32("configured_"+logParallelism, configured.Parallelism),
+	}
+}
+
 func packingContentFields(authorPub []byte) []zapcore.Field {
 	return []zapcore.Field{
 		zap.String(logAuthorPubShort, id.ShortHex(authorPub[1:9])),
@@ -69,6 +126,32 @@ func docFields(
 	}
 }
 
+func uploadingBatchFields(nItems int) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNItems, nItems),
+	}
+}
+
+func uploadedBatchFields(nItems, nErrored int, elapsed time.Duration) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNItems, nItems),
+		zap.Int(logNErrored, nErrored),
+		zap.Duration(logElapsedTime, elapsed),
+	}
+}
+
+func plannedUploadFields(plan *UploadPlan, elapsed time.Duration) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Stringer(logEnvelopeKey, plan.EnvelopeKey),
+		zap.Stringer(logEntryKey, plan.EntryKey),
+		zap.Int(logNPages, plan.NPages),
+		zap.Int(logNDocs, len(plan.Docs)),
+		zap.Int(logTotalBytes, plan.TotalBytes()),
+		zap.Int(logReplicaBytes, plan.ReplicaBytes()),
+		zap.Duration(logElapsedTime, elapsed),
+	}
+}
+
 func unpackingContentFields(entryKey fmt.Stringer, nPages int) []zapcore.Field {
 	return []zapcore.Field{
 		zap.Stringer(logEntryKey, entryKey),
@@ -91,3 +174,53 @@ func sharedDocFields(envKey, entryKey fmt.Stringer, authorPub, readerPub []byte)
 		zap.String(logReaderPubShort, id.ShortHex(readerPub[1:9])),
 	}
 }
+
+func downloadReportFields(rp *DownloadReport, elapsedTime time.Duration) []zapcore.Field {
+	return append(docFields(rp.EnvelopeKey, rp.EntryKey, rp.Metadata, elapsedTime),
+		zap.Int(logNPages, len(rp.Pages)),
+		zap.Int(logMACFailures, rp.NMACFailures()),
+		zap.Bool(logVerified, rp.Verified()),
+	)
+}
+
+func repairedEntryFields(rp *RepairReport, elapsedTime time.Duration) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Stringer(logEntryKey, rp.EntryKey),
+		zap.Int(logNPages, len(rp.Docs)-1),
+		zap.Int(logNHealthy, rp.NOutcome(Healthy)),
+		zap.Int(logNRepaired, rp.NOutcome(Repaired)),
+		zap.Int(logNUnrepaired, rp.NOutcome(Unrepaired)),
+		zap.Int(logNUnverified, rp.NOutcome(Unverified)),
+		zap.Duration(logElapsedTime, elapsedTime),
+	}
+}
+
+func auditedEnvelopeFields(rp *AuditReport, elapsedTime time.Duration) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Stringer(logEnvelopeKey, rp.EnvelopeKey),
+		zap.Stringer(logEntryKey, rp.EntryKey),
+		zap.Uint(logNReplicas, rp.NReplicas),
+		zap.Int(logNDocs, len(rp.Docs)),
+		zap.Int(logNUnderRepl, rp.NUnderReplicated()),
+		zap.Int(logNUnverified, rp.NUnverified()),
+		zap.Duration(logElapsedTime, elapsedTime),
+	}
+}
+
+func publicationFields(pub *subscribe.KeyedPub) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Stringer(logPubKey, pub.Key),
+		zap.String(logEntryKey, id.Hex(pub.Value.EntryKey)),
+	}
+}
+
+func prefetchedEntryFields(
+	pub *api.Publication, nBytes int, elapsedTime time.Duration,
+) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEnvelopeKey, id.Hex(pub.EnvelopeKey)),
+		zap.String(logEntryKey, id.Hex(pub.EntryKey)),
+		zap.Int(logNBytes, nBytes),
+		zap.Duration(logElapsedTime, elapsedTime),
+	}
+}
@@ -0,0 +1,46 @@
+package author
+
+import (
+	"net"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"go.uber.org/zap"
+)
+
+// AdoptNetworkParameters sets the config values recommended by the network parameters. Since
+// librarians reject pages larger than their max page size, pages are capped at the recommended
+// one.
+func (c *Config) AdoptNetworkParameters(params *api.NetworkParameters) *Config {
+	if params.MaxPageSize > 0 && params.MaxPageSize < c.Print.PageSize {
+		c.Print.PageSize = params.MaxPageSize
+	}
+	return c
+}
+
+// adoptNetworkParameters gets the network parameters from the librarians and adopts them. Since
+// they are only recommendations, failing to get them isn't fatal.
+func adoptNetworkParameters(
+	config *Config, getter client.NetworkParametersGetter, lcs []api.Getter, logger *zap.Logger,
+) {
+	params, err := getter.Get(lcs)
+	if err != nil {
+		logger.Warn("unable to get network parameters, using local values", zap.Error(err))
+		return
+	}
+	config.AdoptNetworkParameters(params)
+	logger.Info("adopted network parameters",
+		zap.Uint32(logPageSize, config.Print.PageSize),
+		zap.Int64(logIssuedTime, params.IssuedTime),
+	)
+}
+
+func getLibrarianGetters(librarianAddrs []*net.TCPAddr, clients client.Pool) []api.Getter {
+	lcs := make([]api.Getter, 0, len(librarianAddrs))
+	for _, addr := range librarianAddrs {
+		if lc, err := clients.Get(addr.String()); err == nil {
+			lcs = append(lcs, lc)
+		}
+	}
+	return lcs
+}
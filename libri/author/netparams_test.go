@@ -0,0 +1,67 @@
+package author
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestConfig_AdoptNetworkParameters(t *testing.T) {
+	c := NewDefaultConfig()
+	pageSize := c.Print.PageSize
+
+	// larger recommended max page size leaves page size unchanged
+	c.AdoptNetworkParameters(&api.NetworkParameters{MaxPageSize: pageSize + 1})
+	assert.Equal(t, pageSize, c.Print.PageSize)
+
+	// zero value leaves page size unchanged
+	c.AdoptNetworkParameters(&api.NetworkParameters{})
+	assert.Equal(t, pageSize, c.Print.PageSize)
+
+	// smaller recommended max page size caps page size
+	c.AdoptNetworkParameters(&api.NetworkParameters{MaxPageSize: pageSize / 2})
+	assert.Equal(t, pageSize/2, c.Print.PageSize)
+}
+
+func TestAdoptNetworkParameters(t *testing.T) {
+	// ok
+	c := NewDefaultConfig()
+	pageSize := c.Print.PageSize
+	getter := &fixedNetworkParametersGetter{
+		params: &api.NetworkParameters{MaxPageSize: pageSize / 2},
+	}
+	adoptNetworkParameters(c, getter, nil, zap.NewNop())
+	assert.Equal(t, pageSize/2, c.Print.PageSize)
+
+	// Get error leaves local values
+	c = NewDefaultConfig()
+	getter = &fixedNetworkParametersGetter{err: errors.New("some Get error")}
+	adoptNetworkParameters(c, getter, nil, zap.NewNop())
+	assert.Equal(t, pageSize, c.Print.PageSize)
+}
+
+func TestGetLibrarianGetters(t *testing.T) {
+	clients, err := client.NewDefaultLRUPool()
+	assert.Nil(t, err)
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("127.0.0.1"), Port: 20100},
+		{IP: net.ParseIP("127.0.0.1"), Port: 20101},
+	}
+	lcs := getLibrarianGetters(addrs, clients)
+	assert.Len(t, lcs, len(addrs))
+	assert.Nil(t, clients.CloseAll())
+}
+
+type fixedNetworkParametersGetter struct {
+	params *api.NetworkParameters
+	err    error
+}
+
+func (f *fixedNetworkParametersGetter) Get(lcs []api.Getter) (*api.NetworkParameters, error) {
+	return f.params, f.err
+}
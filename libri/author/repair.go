@@ -0,0 +1,275 @@
+package author
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/replicate"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/drausin/libri/libri/librarian/server/verify"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	// repairMacKeySize is the size of the MAC key used for repair verify operations.
+	repairMacKeySize = 32
+
+	// repairFindTimeout is the timeout for Find queries used to get verification seeds.
+	repairFindTimeout = 5 * time.Second
+)
+
+var (
+	errUnexpectedDocKey = errors.New("acquired document has unexpected key")
+	errNoRepairSeeds    = errors.New("no librarians returned peers to verify against")
+	errVerifyExhausted  = errors.New("verification exhausted peers")
+	errRepairNotStored  = errors.New("unable to store additional replicas")
+)
+
+// RepairOutcome describes the result of repairing a single document.
+type RepairOutcome int
+
+const (
+	// Healthy indicates the document was already fully replicated.
+	Healthy RepairOutcome = iota
+
+	// Repaired indicates the document was under-replicated and additional replicas were stored.
+	Repaired
+
+	// Unrepaired indicates the document was under-replicated, but storing additional replicas
+	// failed.
+	Unrepaired
+
+	// Unverified indicates the document's replication could not be determined.
+	Unverified
+)
+
+func (o RepairOutcome) String() string {
+	switch o {
+	case Healthy:
+		return "healthy"
+	case Repaired:
+		return "repaired"
+	case Unrepaired:
+		return "unrepaired"
+	case Unverified:
+		return "unverified"
+	default:
+		return fmt.Sprintf("RepairOutcome(%d)", o)
+	}
+}
+
+// DocRepair is the repair result for a single document.
+type DocRepair struct {
+	// Key of the document
+	Key id.ID
+
+	// NReplicas is the number of verified replicas found before repair
+	NReplicas int
+
+	// NStored is the number of additional replicas stored during repair
+	NStored int
+
+	// Outcome of the repair
+	Outcome RepairOutcome
+
+	// Err is the error encountered, if any, when verifying or repairing the document
+	Err error
+}
+
+// RepairReport summarizes the repair of an entry and its pages.
+type RepairReport struct {
+	// EntryKey is the key of the repaired entry
+	EntryKey id.ID
+
+	// Docs contains the repair result for the entry document and each of its pages
+	Docs []*DocRepair
+}
+
+// NOutcome returns the number of documents with the given repair outcome.
+func (r *RepairReport) NOutcome(outcome RepairOutcome) int {
+	n := 0
+	for _, dr := range r.Docs {
+		if dr.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// Repair verifies that the entry with the given key and each of its pages are fully replicated
+// across the network, storing additional replicas of any under-replicated documents from a
+// healthy replica. It returns a report of what was found and fixed.
+func (a *Author) Repair(entryKey id.ID) (*RepairReport, error) {
+	if err := a.authorize(ScopeRepair); err != nil {
+		return nil, a.logAndReturnErr("repair not authorized", err)
+	}
+	startTime := time.Now()
+	a.logger.Debug("repairing entry", zap.Stringer(logEntryKey, entryKey))
+
+	rlc := client.NewRetryGetter(a.getters, true, a.config.Publish.GetTimeout)
+	entry, err := a.acquireDoc(entryKey, rlc)
+	if err != nil {
+		return nil, a.logAndReturnErr("error acquiring entry", err)
+	}
+	pageKeys, err := api.GetEntryPageKeys(entry)
+	if err != nil {
+		return nil, a.logAndReturnErr("error getting entry page keys", err)
+	}
+
+	rp := &RepairReport{
+		EntryKey: entryKey,
+		Docs:     make([]*DocRepair, 0, len(pageKeys)+1),
+	}
+	rp.Docs = append(rp.Docs, a.repairer.repair(entryKey, entry))
+	for _, pageKey := range pageKeys {
+		page, err := a.acquireDoc(pageKey, rlc)
+		if err != nil {
+			// without a healthy replica, there's nothing to re-store from
+			rp.Docs = append(rp.Docs, &DocRepair{Key: pageKey, Outcome: Unverified, Err: err})
+			continue
+		}
+		rp.Docs = append(rp.Docs, a.repairer.repair(pageKey, page))
+	}
+
+	a.logger.Info("repaired entry", repairedEntryFields(rp, time.Since(startTime))...)
+	return rp, nil
+}
+
+func (a *Author) acquireDoc(docKey id.ID, lc api.Getter) (*api.Document, error) {
+	doc, err := a.acquirer.Acquire(docKey, nil, lc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := api.GetKey(doc)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(key.Bytes(), docKey.Bytes()) {
+		return nil, errUnexpectedDocKey
+	}
+	return doc, nil
+}
+
+// docRepairer verifies the replication of a single document and stores additional replicas if
+// necessary.
+type docRepairer interface {
+	repair(key id.ID, doc *api.Document) *DocRepair
+}
+
+type docRepairerImpl struct {
+	clientID       ecid.ID
+	orgID          ecid.ID
+	clientSigner   client.Signer
+	orgSigner      client.Signer
+	librarianAddrs []*net.TCPAddr
+	finders        client.FinderCreator
+	fromer         peer.Fromer
+	verifier       verify.Verifier
+	storer         store.Storer
+	verifyParams   *verify.Parameters
+	storeParams    *store.Parameters
+}
+
+func (r *docRepairerImpl) repair(key id.ID, doc *api.Document) *DocRepair {
+	dr := &DocRepair{Key: key, Outcome: Unverified}
+	v, err := r.verify(key, doc, r.verifyParams)
+	if err != nil {
+		dr.Err = err
+		return dr
+	}
+	dr.NReplicas = len(v.Result.Replicas)
+	if v.FullyReplicated() {
+		dr.Outcome = Healthy
+		return dr
+	}
+	if !v.UnderReplicated() {
+		dr.Err = errVerifyExhausted
+		return dr
+	}
+
+	// empty seeds b/c verification has already, in effect, replaced the search component of
+	// the store operation
+	s := replicate.NewStore(r.clientID, r.orgID, v, *r.storeParams)
+	defer s.Release()
+	dr.Outcome = Unrepaired
+	if err = r.storer.Store(context.Background(), s, []peer.Peer{}); err != nil {
+		dr.Err = err
+		return dr
+	}
+	dr.NStored = len(s.Result.Responded)
+	if !s.Stored() {
+		dr.Err = errRepairNotStored
+		return dr
+	}
+	dr.Outcome = Repaired
+	return dr
+}
+
+// verify finds the replicas of the document among the peers closest to its key.
+func (r *docRepairerImpl) verify(key id.ID, doc *api.Document, params *verify.Parameters) (
+	*verify.Verify, error) {
+	value, err := proto.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	macKey := make([]byte, repairMacKeySize)
+	if _, err = crand.Read(macKey); err != nil {
+		return nil, err
+	}
+	seeds, err := r.getSeeds(key)
+	if err != nil {
+		return nil, err
+	}
+	v := verify.NewVerify(r.clientID, r.orgID, key, value, macKey, params)
+	if err = r.verifier.Verify(context.Background(), v, seeds); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// getSeeds returns the peers the librarians know to be closest to the key. Librarians holding
+// the document would return it rather than peers for a Find on the key itself, so we instead
+// Find the adjacent key, whose closest peers are effectively the same.
+func (r *docRepairerImpl) getSeeds(key id.ID) ([]peer.Peer, error) {
+	neighborBytes := key.Bytes()
+	neighborBytes[len(neighborBytes)-1] ^= 1
+	neighbor := id.FromBytes(neighborBytes)
+
+	seeds := make([]peer.Peer, 0)
+	for _, addr := range r.librarianAddrs {
+		lc, err := r.finders.Create(addr.String())
+		if err != nil {
+			continue
+		}
+		rq := client.NewFindRequest(r.clientID, r.orgID, neighbor,
+			r.verifyParams.NClosestResponses)
+		ctx, cancel, err := client.NewSignedTimeoutContext(r.clientSigner, r.orgSigner, rq,
+			repairFindTimeout)
+		if err != nil {
+			return nil, err
+		}
+		rp, err := lc.Find(ctx, rq)
+		cancel()
+		if err != nil {
+			continue
+		}
+		for _, pa := range rp.Peers {
+			seeds = append(seeds, r.fromer.FromAPI(pa))
+		}
+	}
+	if len(seeds) == 0 {
+		return nil, errNoRepairSeeds
+	}
+	return seeds, nil
+}
@@ -0,0 +1,298 @@
+package author
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/drausin/libri/libri/librarian/server/verify"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestRepairOutcome_String(t *testing.T) {
+	assert.Equal(t, "healthy", Healthy.String())
+	assert.Equal(t, "repaired", Repaired.String())
+	assert.Equal(t, "unrepaired", Unrepaired.String())
+	assert.Equal(t, "unverified", Unverified.String())
+	assert.Equal(t, "RepairOutcome(10)", RepairOutcome(10).String())
+}
+
+func TestRepairReport_NOutcome(t *testing.T) {
+	rp := &RepairReport{
+		Docs: []*DocRepair{
+			{Outcome: Healthy},
+			{Outcome: Repaired},
+			{Outcome: Healthy},
+		},
+	}
+	assert.Equal(t, 2, rp.NOutcome(Healthy))
+	assert.Equal(t, 1, rp.NOutcome(Repaired))
+	assert.Equal(t, 0, rp.NOutcome(Unrepaired))
+}
+
+func TestAuthor_Repair_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	acq := &mapAcquirer{docs: make(map[string]*api.Document)}
+
+	// single-page entry
+	entry1 := &api.Document{
+		Contents: &api.Document_Entry{Entry: api.NewTestSinglePageEntry(rng)},
+	}
+	entryKey1 := acq.add(entry1)
+
+	// multi-page entry
+	page1 := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	page2 := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	pageKey1, pageKey2 := acq.add(page1), acq.add(page2)
+	entry2Contents := api.NewTestMultiPageEntry(rng)
+	entry2Contents.PageKeys = [][]byte{pageKey1.Bytes(), pageKey2.Bytes()}
+	entryKey2 := acq.add(&api.Document{Contents: &api.Document_Entry{Entry: entry2Contents}})
+
+	a := &Author{
+		config:   NewDefaultConfig(),
+		acquirer: acq,
+		repairer: &fixedDocRepairer{outcome: Healthy},
+		logger:   clogging.NewDevLogger(zapcore.DebugLevel),
+	}
+
+	rp, err := a.Repair(entryKey1)
+	assert.Nil(t, err)
+	assert.Equal(t, entryKey1, rp.EntryKey)
+	assert.Len(t, rp.Docs, 1)
+	assert.Equal(t, 1, rp.NOutcome(Healthy))
+
+	rp, err = a.Repair(entryKey2)
+	assert.Nil(t, err)
+	assert.Len(t, rp.Docs, 3)
+	assert.Equal(t, entryKey2, rp.Docs[0].Key)
+	assert.Equal(t, pageKey1, rp.Docs[1].Key)
+	assert.Equal(t, pageKey2, rp.Docs[2].Key)
+	assert.Equal(t, 3, rp.NOutcome(Healthy))
+
+	// missing page should be unverified
+	delete(acq.docs, pageKey2.String())
+	rp, err = a.Repair(entryKey2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, rp.NOutcome(Healthy))
+	assert.Equal(t, Unverified, rp.Docs[2].Outcome)
+	assert.NotNil(t, rp.Docs[2].Err)
+}
+
+func TestAuthor_Repair_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	acq := &mapAcquirer{docs: make(map[string]*api.Document)}
+	a := &Author{
+		config:   NewDefaultConfig(),
+		acquirer: acq,
+		repairer: &fixedDocRepairer{outcome: Healthy},
+		logger:   clogging.NewDevLogger(zapcore.DebugLevel),
+	}
+
+	// missing entry
+	rp, err := a.Repair(id.NewPseudoRandom(rng))
+	assert.NotNil(t, err)
+	assert.Nil(t, rp)
+
+	// acquired doc has different key
+	doc, _ := api.NewTestDocument(rng)
+	otherKey := id.NewPseudoRandom(rng)
+	acq.docs[otherKey.String()] = doc
+	rp, err = a.Repair(otherKey)
+	assert.Equal(t, errUnexpectedDocKey, err)
+	assert.Nil(t, rp)
+
+	// doc is not an entry
+	page := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	rp, err = a.Repair(acq.add(page))
+	assert.Equal(t, api.ErrUnexpectedDocumentType, err)
+	assert.Nil(t, rp)
+}
+
+func TestDocRepairer_repair(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, key := api.NewTestDocument(rng)
+	seeds := peer.NewTestPeers(rng, 8)
+	finder := &fixedFinder{rp: &api.FindResponse{Peers: peer.ToAPIs(seeds)}}
+	verifyParams := verify.NewDefaultParameters()
+
+	cases := map[string]struct {
+		verifier verify.Verifier
+		storer   store.Storer
+		outcome  RepairOutcome
+		nStored  int
+		err      bool
+	}{
+		"healthy": {
+			verifier: &fixedVerifier{replicas: seeds[:verifyParams.NReplicas]},
+			outcome:  Healthy,
+		},
+		"repaired": {
+			verifier: &fixedVerifier{replicas: seeds[:1], closest: seeds[1:]},
+			storer:   &fixedStorer{responded: seeds[1 : 1+verifyParams.NReplicas-1]},
+			outcome:  Repaired,
+			nStored:  int(verifyParams.NReplicas) - 1,
+		},
+		"verify error": {
+			verifier: &fixedVerifier{err: errors.New("some verify error")},
+			outcome:  Unverified,
+			err:      true,
+		},
+		"exhausted": {
+			verifier: &fixedVerifier{replicas: seeds[:1]},
+			outcome:  Unverified,
+			err:      true,
+		},
+		"store error": {
+			verifier: &fixedVerifier{replicas: seeds[:1], closest: seeds[1:]},
+			storer:   &fixedStorer{err: errors.New("some store error")},
+			outcome:  Unrepaired,
+			err:      true,
+		},
+		"not stored": {
+			verifier: &fixedVerifier{replicas: seeds[:1], closest: seeds[1:]},
+			storer:   &fixedStorer{responded: seeds[1:2]},
+			outcome:  Unrepaired,
+			nStored:  1,
+			err:      true,
+		},
+	}
+	for desc, c := range cases {
+		r := newTestDocRepairer(rng, finder, c.verifier, c.storer)
+		dr := r.repair(key, doc)
+		assert.Equal(t, key, dr.Key, desc)
+		assert.Equal(t, c.outcome, dr.Outcome, desc)
+		assert.Equal(t, c.nStored, dr.NStored, desc)
+		assert.Equal(t, c.err, dr.Err != nil, desc)
+	}
+}
+
+func TestDocRepairer_getSeeds(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	seeds := peer.NewTestPeers(rng, 4)
+
+	finder := &fixedFinder{rp: &api.FindResponse{Peers: peer.ToAPIs(seeds)}}
+	r := newTestDocRepairer(rng, finder, nil, nil)
+	found, err := r.getSeeds(key)
+	assert.Nil(t, err)
+	assert.Len(t, found, len(seeds))
+
+	// should query the adjacent key rather than the key itself
+	assert.NotEqual(t, key.Bytes(), finder.rq.Key)
+	assert.Equal(t, key.Bytes()[:id.Length-1], finder.rq.Key[:id.Length-1])
+
+	// Find errors should result in no seeds
+	finder = &fixedFinder{err: errors.New("some Find error")}
+	r = newTestDocRepairer(rng, finder, nil, nil)
+	found, err = r.getSeeds(key)
+	assert.Equal(t, errNoRepairSeeds, err)
+	assert.Nil(t, found)
+}
+
+func newTestDocRepairer(
+	rng *rand.Rand, finder api.Finder, verifier verify.Verifier, storer store.Storer,
+) *docRepairerImpl {
+	clientID := ecid.NewPseudoRandom(rng)
+	return &docRepairerImpl{
+		clientID:       clientID,
+		clientSigner:   client.NewECDSASigner(clientID.Key()),
+		orgSigner:      client.NewEmptySigner(),
+		librarianAddrs: []*net.TCPAddr{peer.NewTestPublicAddr(0)},
+		finders:        &fixedFinderCreator{finder: finder},
+		fromer:         peer.NewFromer(),
+		verifier:       verifier,
+		storer:         storer,
+		verifyParams:   verify.NewDefaultParameters(),
+		storeParams:    store.NewDefaultParameters(),
+	}
+}
+
+type mapAcquirer struct {
+	docs map[string]*api.Document
+}
+
+func (a *mapAcquirer) add(doc *api.Document) id.ID {
+	key, err := api.GetKey(doc)
+	if err != nil {
+		panic(err)
+	}
+	a.docs[key.String()] = doc
+	return key
+}
+
+func (a *mapAcquirer) Acquire(docKey id.ID, authorPub []byte, lc api.Getter) (
+	*api.Document, error) {
+	doc, in := a.docs[docKey.String()]
+	if !in {
+		return nil, errors.New("missing document")
+	}
+	return doc, nil
+}
+
+type fixedDocRepairer struct {
+	outcome RepairOutcome
+}
+
+func (f *fixedDocRepairer) repair(key id.ID, doc *api.Document) *DocRepair {
+	return &DocRepair{Key: key, Outcome: f.outcome}
+}
+
+type fixedFinderCreator struct {
+	finder api.Finder
+	err    error
+}
+
+func (f *fixedFinderCreator) Create(address string) (api.Finder, error) {
+	return f.finder, f.err
+}
+
+type fixedFinder struct {
+	rq  *api.FindRequest
+	rp  *api.FindResponse
+	err error
+}
+
+func (f *fixedFinder) Find(
+	ctx context.Context, in *api.FindRequest, opts ...grpc.CallOption,
+) (*api.FindResponse, error) {
+	f.rq = in
+	return f.rp, f.err
+}
+
+type fixedVerifier struct {
+	replicas []peer.Peer
+	closest  []peer.Peer
+	err      error
+}
+
+func (f *fixedVerifier) Verify(ctx context.Context, v *verify.Verify, seeds []peer.Peer) error {
+	for _, p := range f.replicas {
+		v.Result.Replicas[p.ID().String()] = p
+	}
+	for _, p := range f.closest {
+		v.Result.Closest.SafePush(p)
+	}
+	return f.err
+}
+
+type fixedStorer struct {
+	responded []peer.Peer
+	err       error
+}
+
+func (f *fixedStorer) Store(ctx context.Context, s *store.Store, seeds []peer.Peer) error {
+	s.Result = store.NewInitialResult(s.Search.Result)
+	s.Result.Responded = f.responded
+	return f.err
+}
@@ -0,0 +1,238 @@
+package author
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/publish"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultPrefetchMaxBytesPerSecond is the default bandwidth budget for prefetching entries.
+	DefaultPrefetchMaxBytesPerSecond = uint64(1 << 20) // 1 MiB/s
+
+	// DefaultPrefetchQueueSize is the default maximum number of publications waiting for their
+	// entries to be prefetched.
+	DefaultPrefetchQueueSize = uint(64)
+)
+
+// ErrPrefetchKeyMismatch indicates when a prefetched document does not hash to its key.
+var ErrPrefetchKeyMismatch = errors.New("prefetched document does not match its key")
+
+// PrefetchParameters define how the entries referenced by subscribed publications are prefetched.
+type PrefetchParameters struct {
+	// Enabled determines whether the envelope, entry, and pages referenced by each new
+	// publication are fetched as it arrives.
+	Enabled bool
+
+	// VerifyOnly determines whether prefetched documents are only checked against their keys
+	// rather than also persisted to the author's DB.
+	VerifyOnly bool
+
+	// MaxBytesPerSecond is the bandwidth budget for prefetching, with zero meaning no limit.
+	MaxBytesPerSecond uint64
+
+	// QueueSize is the maximum number of publications waiting for their entries to be
+	// prefetched. Publications arriving when the queue is full (e.g., because prefetching is
+	// held back by the bandwidth budget) aren't prefetched.
+	QueueSize uint
+}
+
+// NewDefaultPrefetchParameters returns a *PrefetchParameters object with default values, which
+// disable prefetching.
+func NewDefaultPrefetchParameters() *PrefetchParameters {
+	return &PrefetchParameters{
+		MaxBytesPerSecond: DefaultPrefetchMaxBytesPerSecond,
+		QueueSize:         DefaultPrefetchQueueSize,
+	}
+}
+
+// Subscribe subscribes to the publications of the librarians, sending new ones to pubs, which is
+// closed once the returned subscriptions are ended. The caller runs the subscriptions via their
+// Begin method. If prefetching is enabled, the entries referenced by the publications are also
+// fetched as they arrive, so mirrors and indexers stay current without polling.
+func (a *Author) Subscribe(pubs chan *subscribe.KeyedPub) (subscribe.To, error) {
+	if err := a.authorize(ScopeDownload); err != nil {
+		return nil, a.logAndReturnErr("subscribe not authorized", err)
+	}
+	rng := rand.New(rand.NewSource(a.ClientID.Int().Int64()))
+	csb, err := client.NewSetBalancer(a.config.LibrarianAddrs, a.clients, rng)
+	if err != nil {
+		return nil, a.logAndReturnErr("error creating librarian balancer", err)
+	}
+	recent, err := subscribe.NewRecentPublications(a.config.SubscribeTo.RecentCacheSize)
+	if err != nil {
+		return nil, a.logAndReturnErr("error creating recent publications cache", err)
+	}
+	newPubs := make(chan *subscribe.KeyedPub, a.config.SubscribeTo.NSubscriptions)
+	to := subscribe.NewTo(a.config.SubscribeTo, a.logger, a.ClientID, a.orgID, csb, a.signer,
+		a.orgSigner, recent, newPubs)
+	go a.forwardPubs(newPubs, pubs)
+	return to, nil
+}
+
+// forwardPubs forwards new publications to pubs, queueing them for prefetching if it is enabled.
+// Prefetching never holds up forwarding.
+func (a *Author) forwardPubs(newPubs, pubs chan *subscribe.KeyedPub) {
+	var toPrefetch chan *api.Publication
+	if a.config.Prefetch.Enabled {
+		toPrefetch = make(chan *api.Publication, a.config.Prefetch.QueueSize)
+		go a.prefetchAll(toPrefetch)
+	}
+	for pub := range newPubs {
+		if toPrefetch != nil {
+			select {
+			case toPrefetch <- pub.Value:
+			default:
+				a.logger.Info("skipping prefetch of full queue", publicationFields(pub)...)
+			}
+		}
+		pubs <- pub
+	}
+	if toPrefetch != nil {
+		close(toPrefetch)
+	}
+	close(pubs)
+}
+
+// prefetchAll prefetches the entries of the queued publications within the bandwidth budget.
+func (a *Author) prefetchAll(toPrefetch chan *api.Publication) {
+	budget := newBandwidthBudget(a.config.Prefetch.MaxBytesPerSecond)
+	for pub := range toPrefetch {
+		time.Sleep(budget.delay(time.Now()))
+		startTime := time.Now()
+		nBytes, err := a.prefetcher.prefetch(pub)
+		budget.spend(nBytes, time.Now())
+		fields := prefetchedEntryFields(pub, nBytes, time.Since(startTime))
+		if err != nil {
+			a.logger.Error("error prefetching entry", append(fields, zap.Error(err))...)
+			continue
+		}
+		a.logger.Debug("prefetched entry", fields...)
+	}
+}
+
+// bandwidthBudget spaces out transfers so that their average rate stays within a maximum number
+// of bytes per second. Since the size of each transfer isn't known until it completes, the wait
+// before a transfer pays for those before it.
+type bandwidthBudget struct {
+	bytesPerSecond uint64
+	next           time.Time
+	mu             sync.Mutex
+}
+
+func newBandwidthBudget(bytesPerSecond uint64) *bandwidthBudget {
+	return &bandwidthBudget{bytesPerSecond: bytesPerSecond}
+}
+
+// delay returns how long to wait from now before the next transfer.
+func (b *bandwidthBudget) delay(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if d := b.next.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// spend records a transfer of nBytes completing at now.
+func (b *bandwidthBudget) spend(nBytes int, now time.Time) {
+	if b.bytesPerSecond == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.next.Before(now) {
+		// unused budget doesn't accumulate
+		b.next = now
+	}
+	b.next = b.next.Add(time.Duration(uint64(nBytes) * uint64(time.Second) / b.bytesPerSecond))
+}
+
+type entryPrefetcher interface {
+	// prefetch Gets the envelope, entry, and pages referenced by the publication, returning the
+	// number of bytes fetched.
+	prefetch(pub *api.Publication) (int, error)
+}
+
+type entryPrefetcherImpl struct {
+	getters    client.GetterBalancer
+	acquirer   publish.Acquirer
+	msAcquirer publish.MultiStoreAcquirer
+	docS       storage.DocumentStorer
+	verifyOnly bool
+}
+
+func (p *entryPrefetcherImpl) prefetch(pub *api.Publication) (int, error) {
+	rlc := p.msAcquirer.GetRetryGetter(p.getters)
+	_, nBytes, err := p.acquire(id.FromBytes(pub.EnvelopeKey), nil, rlc)
+	if err != nil {
+		return nBytes, err
+	}
+	entryDoc, n, err := p.acquire(id.FromBytes(pub.EntryKey), pub.AuthorPublicKey, rlc)
+	nBytes += n
+	if err != nil {
+		return nBytes, err
+	}
+	entry, ok := entryDoc.Contents.(*api.Document_Entry)
+	if !ok {
+		return nBytes, api.ErrUnexpectedDocumentType
+	}
+	if entry.Entry.Page != nil {
+		// single page is already in the entry
+		if p.verifyOnly {
+			return nBytes, nil
+		}
+		pageDoc, pageKey, err2 := api.GetPageDocument(entry.Entry.Page)
+		if err2 != nil {
+			return nBytes, err2
+		}
+		return nBytes, p.docS.Store(pageKey, pageDoc)
+	}
+	pageKeys, err := api.GetEntryPageKeys(entryDoc)
+	if err != nil {
+		return nBytes, err
+	}
+	for _, pageKey := range pageKeys {
+		_, n, err = p.acquire(pageKey, pub.AuthorPublicKey, rlc)
+		nBytes += n
+		if err != nil {
+			return nBytes, err
+		}
+	}
+	return nBytes, nil
+}
+
+// acquire Gets the document with the given key, checks that it hashes to the key, and (unless
+// only verifying) stores it, returning the document and its size.
+func (p *entryPrefetcherImpl) acquire(key id.ID, authorPub []byte, lc api.Getter) (
+	*api.Document, int, error) {
+	doc, err := p.acquirer.Acquire(key, authorPub, lc)
+	if err != nil {
+		return nil, 0, err
+	}
+	nBytes := proto.Size(doc)
+	docKey, err := api.GetKey(doc)
+	if err != nil {
+		return nil, nBytes, err
+	}
+	if !bytes.Equal(docKey.Bytes(), key.Bytes()) {
+		return nil, nBytes, ErrPrefetchKeyMismatch
+	}
+	if !p.verifyOnly {
+		if err := p.docS.Store(key, doc); err != nil {
+			return nil, nBytes, err
+		}
+	}
+	return doc, nBytes, nil
+}
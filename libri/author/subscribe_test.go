@@ -0,0 +1,236 @@
+package author
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthor_Subscribe_err(t *testing.T) {
+	// check delegation error bubbles up
+	a := &Author{
+		logger:     clogging.NewDevInfoLogger(),
+		delegation: &DelegationClaims{Scopes: []string{ScopeUpload}},
+	}
+	to, err := a.Subscribe(make(chan *subscribe.KeyedPub))
+	assert.NotNil(t, err)
+	assert.Nil(t, to)
+}
+
+func TestAuthor_forwardPubs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	nPubs := 8
+	for _, enabled := range []bool{false, true} {
+		config := newTestConfig()
+		config.Prefetch.Enabled = enabled
+		config.Prefetch.MaxBytesPerSecond = 0
+		prefetcher := &fixedEntryPrefetcher{}
+		a := &Author{
+			config:     config,
+			prefetcher: prefetcher,
+			logger:     clogging.NewDevInfoLogger(),
+		}
+		newPubs := make(chan *subscribe.KeyedPub, nPubs)
+		pubs := make(chan *subscribe.KeyedPub, nPubs)
+		for c := 0; c < nPubs; c++ {
+			pub := api.NewTestPublication(rng)
+			key, err := api.GetKey(pub)
+			assert.Nil(t, err)
+			newPubs <- &subscribe.KeyedPub{Key: key, Value: pub}
+		}
+		close(newPubs)
+
+		a.forwardPubs(newPubs, pubs)
+		nForwarded := 0
+		for range pubs {
+			nForwarded++
+		}
+		assert.Equal(t, nPubs, nForwarded)
+
+		if !enabled {
+			assert.Zero(t, prefetcher.count())
+			continue
+		}
+		for prefetcher.count() < nPubs {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Equal(t, nPubs, prefetcher.count())
+	}
+}
+
+func TestBandwidthBudget(t *testing.T) {
+	now := time.Now()
+
+	// no budget never delays
+	b := newBandwidthBudget(0)
+	b.spend(1<<30, now)
+	assert.Zero(t, b.delay(now))
+
+	b = newBandwidthBudget(1000)
+	assert.Zero(t, b.delay(now))
+	b.spend(500, now)
+	assert.Equal(t, 500*time.Millisecond, b.delay(now))
+	assert.Equal(t, 250*time.Millisecond, b.delay(now.Add(250*time.Millisecond)))
+
+	// transfer before budget is available pushes it further out
+	b.spend(500, now)
+	assert.Equal(t, time.Second, b.delay(now))
+
+	// unused budget doesn't accumulate
+	later := now.Add(time.Minute)
+	assert.Zero(t, b.delay(later))
+	b.spend(1000, later)
+	assert.Equal(t, time.Second, b.delay(later))
+}
+
+func TestEntryPrefetcher_prefetch_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, verifyOnly := range []bool{false, true} {
+		acq := &mapAcquirer{docs: make(map[string]*api.Document)}
+		pub := newTestPrefetchPub(rng, acq)
+		docS := storage.NewTestDocSLD()
+		p := &entryPrefetcherImpl{
+			acquirer:   acq,
+			msAcquirer: &fixedRetryGetterAcquirer{},
+			docS:       docS,
+			verifyOnly: verifyOnly,
+		}
+		nBytes, err := p.prefetch(pub)
+		assert.Nil(t, err)
+		assert.True(t, nBytes > 0)
+		if verifyOnly {
+			assert.Len(t, docS.Stored, 0)
+		} else {
+			// envelope, entry, and both pages
+			assert.Len(t, docS.Stored, 4)
+		}
+	}
+
+	// single-page entry stores its page without getting it
+	acq := &mapAcquirer{docs: make(map[string]*api.Document)}
+	entryKey := acq.add(&api.Document{
+		Contents: &api.Document_Entry{Entry: api.NewTestSinglePageEntry(rng)},
+	})
+	envelope := api.NewTestEnvelope(rng)
+	envelope.EntryKey = entryKey.Bytes()
+	envKey := acq.add(&api.Document{Contents: &api.Document_Envelope{Envelope: envelope}})
+	docS := storage.NewTestDocSLD()
+	p := &entryPrefetcherImpl{
+		acquirer:   acq,
+		msAcquirer: &fixedRetryGetterAcquirer{},
+		docS:       docS,
+	}
+	_, err := p.prefetch(&api.Publication{EnvelopeKey: envKey.Bytes(), EntryKey: entryKey.Bytes()})
+	assert.Nil(t, err)
+	assert.Len(t, docS.Stored, 3)
+}
+
+func TestEntryPrefetcher_prefetch_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	// check Acquire error bubbles up
+	acq := &mapAcquirer{docs: make(map[string]*api.Document)}
+	pub := newTestPrefetchPub(rng, acq)
+	p := &entryPrefetcherImpl{
+		acquirer:   &fixedAcquirer{err: errors.New("some Acquire error")},
+		msAcquirer: &fixedRetryGetterAcquirer{},
+		docS:       storage.NewTestDocSLD(),
+	}
+	_, err := p.prefetch(pub)
+	assert.NotNil(t, err)
+
+	// check document not matching its key errors
+	doc, _ := api.NewTestDocument(rng)
+	p = &entryPrefetcherImpl{
+		acquirer:   &fixedAcquirer{doc: doc},
+		msAcquirer: &fixedRetryGetterAcquirer{},
+		docS:       storage.NewTestDocSLD(),
+	}
+	_, err = p.prefetch(pub)
+	assert.Equal(t, ErrPrefetchKeyMismatch, err)
+
+	// check Store error bubbles up
+	docS := storage.NewTestDocSLD()
+	docS.StoreErr = errors.New("some Store error")
+	p = &entryPrefetcherImpl{
+		acquirer:   acq,
+		msAcquirer: &fixedRetryGetterAcquirer{},
+		docS:       docS,
+	}
+	_, err = p.prefetch(pub)
+	assert.NotNil(t, err)
+
+	// check non-entry document errors
+	envKey := id.FromBytes(pub.EnvelopeKey)
+	p = &entryPrefetcherImpl{
+		acquirer:   acq,
+		msAcquirer: &fixedRetryGetterAcquirer{},
+		docS:       storage.NewTestDocSLD(),
+	}
+	_, err = p.prefetch(&api.Publication{EnvelopeKey: envKey.Bytes(), EntryKey: envKey.Bytes()})
+	assert.Equal(t, api.ErrUnexpectedDocumentType, err)
+}
+
+// newTestPrefetchPub adds an envelope, multi-page entry, and its pages to the acquirer, returning
+// a publication of the envelope.
+func newTestPrefetchPub(rng *rand.Rand, acq *mapAcquirer) *api.Publication {
+	entry := api.NewTestMultiPageEntry(rng)
+	for i := range entry.PageKeys {
+		page := api.NewTestPage(rng)
+		page.AuthorPublicKey = entry.AuthorPublicKey
+		page.Index = uint32(i)
+		entry.PageKeys[i] = acq.add(&api.Document{Contents: &api.Document_Page{Page: page}}).Bytes()
+	}
+	entryKey := acq.add(&api.Document{Contents: &api.Document_Entry{Entry: entry}})
+	envelope := api.NewTestEnvelope(rng)
+	envelope.AuthorPublicKey = entry.AuthorPublicKey
+	envelope.EntryKey = entryKey.Bytes()
+	envKey := acq.add(&api.Document{Contents: &api.Document_Envelope{Envelope: envelope}})
+	return &api.Publication{
+		EnvelopeKey:     envKey.Bytes(),
+		EntryKey:        entryKey.Bytes(),
+		AuthorPublicKey: entry.AuthorPublicKey,
+		ReaderPublicKey: envelope.ReaderPublicKey,
+	}
+}
+
+type fixedEntryPrefetcher struct {
+	n   int
+	err error
+	mu  sync.Mutex
+}
+
+func (f *fixedEntryPrefetcher) prefetch(pub *api.Publication) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n++
+	return 1, f.err
+}
+
+func (f *fixedEntryPrefetcher) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.n
+}
+
+type fixedRetryGetterAcquirer struct{}
+
+func (f *fixedRetryGetterAcquirer) Acquire(
+	docKeys []id.ID, authorPub []byte, cb client.GetterBalancer,
+) error {
+	return nil
+}
+
+func (f *fixedRetryGetterAcquirer) GetRetryGetter(cb client.GetterBalancer) api.Getter {
+	return nil
+}
@@ -0,0 +1,274 @@
+package author
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/author/io/enc"
+	"github.com/drausin/libri/libri/author/io/ship"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// PageVerification is the verification result for a single downloaded page.
+type PageVerification struct {
+	// Key of the page document
+	Key id.ID
+
+	// Index of the page within the entry
+	Index uint32
+
+	// CiphertextSize is the size of the page ciphertext
+	CiphertextSize uint64
+
+	// MACOK is whether the page ciphertext matches its MAC
+	MACOK bool
+
+	// ServedBy is the public key of the librarian that served the page, if known
+	ServedBy []byte
+}
+
+// DownloadReport gives a paper trail of the integrity of a downloaded document, from the
+// librarians serving each document to the sizes and MACs of the decrypted content.
+type DownloadReport struct {
+	// EnvelopeKey is the key of the downloaded envelope
+	EnvelopeKey id.ID
+
+	// EntryKey is the key of the downloaded entry
+	EntryKey id.ID
+
+	// EnvelopeServedBy is the public key of the librarian that served the envelope, if known
+	EnvelopeServedBy []byte
+
+	// EntryServedBy is the public key of the librarian that served the entry, if known
+	EntryServedBy []byte
+
+	// Pages contains the verification result for each page, in index order
+	Pages []*PageVerification
+
+	// Metadata is the entry metadata, containing the expected sizes and MACs
+	Metadata *api.EntryMetadata
+
+	// CiphertextSize is the total size of the pages' ciphertext
+	CiphertextSize uint64
+
+	// CiphertextMAC is the MAC computed over the pages' ciphertext
+	CiphertextMAC []byte
+
+	// UncompressedSize is the size of the content written
+	UncompressedSize uint64
+
+	// UncompressedMAC is the MAC computed over the content written
+	UncompressedMAC []byte
+
+	// Err is the error encountered, if any, when unpacking and checking the content against the
+	// metadata
+	Err error
+}
+
+// NMACFailures returns the number of pages whose ciphertext does not match their MAC.
+func (r *DownloadReport) NMACFailures() int {
+	n := 0
+	for _, pv := range r.Pages {
+		if !pv.MACOK {
+			n++
+		}
+	}
+	return n
+}
+
+// Verified returns whether the content integrity held end to end: every page matches its MAC and
+// the ciphertext and uncompressed content match the sizes and MACs in the metadata.
+func (r *DownloadReport) Verified() bool {
+	return r.Err == nil &&
+		r.NMACFailures() == 0 &&
+		r.Metadata != nil &&
+		r.Metadata.CiphertextSize == r.CiphertextSize &&
+		r.Metadata.UncompressedSize == r.UncompressedSize
+}
+
+// DownloadWithReport downloads the content like Download, additionally returning a report on the
+// integrity of the download. The report is returned whenever the entry was received, even if
+// unpacking the content subsequently fails, so the failure can be inspected.
+func (a *Author) DownloadWithReport(content io.Writer, envKey id.ID) (*DownloadReport, error) {
+	if err := a.authorize(ScopeDownload); err != nil {
+		return nil, a.logAndReturnErr("download not authorized", err)
+	}
+	startTime := time.Now()
+	a.logger.Debug("downloading document with report", downloadingDocFields(envKey)...)
+
+	servers := newServingGetters(a.getters)
+	receiver := ship.NewReceiver(servers, a.allKeys, a.acquirer, a.msAcquirer, a.documentSLD)
+	rp, err := a.download(content, envKey, receiver, servers)
+	if err != nil {
+		return rp, err
+	}
+
+	a.logger.Info("downloaded document with report",
+		downloadReportFields(rp, time.Since(startTime))...,
+	)
+	return rp, nil
+}
+
+// download receives the entry and checks its page MACs before unpacking the content, capturing a
+// forensic bundle if the content then fails its integrity checks. The report is returned whenever
+// the entry was received.
+func (a *Author) download(
+	content io.Writer, envKey id.ID, receiver ship.Receiver, servers *servingGetters,
+) (*DownloadReport, error) {
+	entry, keys, err := receiver.ReceiveEntry(envKey)
+	if err != nil {
+		return nil, a.logAndReturnErr("error receiving entry", err)
+	}
+	entryKey, nPages, err := getEntryInfo(entry)
+	if err != nil {
+		return nil, a.logAndReturnErr("error getting entry info", err)
+	}
+	rp := &DownloadReport{
+		EnvelopeKey:      envKey,
+		EntryKey:         entryKey,
+		EnvelopeServedBy: servers.servedBy(envKey),
+		EntryServedBy:    servers.servedBy(entryKey),
+		Pages:            make([]*PageVerification, 0, nPages),
+	}
+
+	// check page MACs before unpacking, which removes pages from local storage
+	if err = a.verifyPages(rp, entry, keys, servers); err != nil {
+		return nil, a.logAndReturnErr("error verifying pages", err)
+	}
+
+	a.logger.Debug("unpacking content", unpackingContentFields(entryKey, nPages)...)
+	cw := &countingWriter{inner: content, mac: enc.NewHMAC(keys.HMACKey)}
+	rp.Metadata, rp.Err = a.entryUnpacker.Unpack(cw, entry, keys)
+	rp.UncompressedSize, rp.UncompressedMAC = cw.n, cw.mac.Sum(nil)
+	if rp.Err != nil {
+		if isIntegrityErr(rp.Err) {
+			a.captureForensics(rp)
+		}
+		return rp, a.logAndReturnErr("error unpacking content", rp.Err)
+	}
+	return rp, nil
+}
+
+func (a *Author) verifyPages(
+	rp *DownloadReport, entry *api.Document, keys *enc.EEK, servers *servingGetters,
+) error {
+	ciphertextMAC := enc.NewHMAC(keys.HMACKey)
+	defer func() { rp.CiphertextMAC = ciphertextMAC.Sum(nil) }()
+	if single := entry.GetEntry().Page; single != nil {
+		pageDoc, pageKey, err := api.GetPageDocument(single)
+		if err != nil {
+			return err
+		}
+		// single page lives within the entry, so was served along with it
+		rp.addPage(pageKey, pageDoc.GetPage(), keys, ciphertextMAC, rp.EntryServedBy)
+		return nil
+	}
+	pageKeys, err := api.GetEntryPageKeys(entry)
+	if err != nil {
+		return err
+	}
+	for _, pageKey := range pageKeys {
+		pageDoc, err := a.documentSLD.Load(pageKey)
+		if err != nil {
+			return err
+		}
+		if pageDoc == nil || pageDoc.GetPage() == nil {
+			return api.ErrUnexpectedDocumentType
+		}
+		rp.addPage(pageKey, pageDoc.GetPage(), keys, ciphertextMAC, servers.servedBy(pageKey))
+	}
+	return nil
+}
+
+func (r *DownloadReport) addPage(
+	key id.ID, page *api.Page, keys *enc.EEK, ciphertextMAC enc.MAC, servedBy []byte,
+) {
+	_, _ = ciphertextMAC.Write(page.Ciphertext) // HMAC Write always returns nil error
+	r.Pages = append(r.Pages, &PageVerification{
+		Key:            key,
+		Index:          page.Index,
+		CiphertextSize: uint64(len(page.Ciphertext)),
+		MACOK:          bytes.Equal(enc.HMAC(page.Ciphertext, keys.HMACKey), page.CiphertextMac),
+		ServedBy:       servedBy,
+	})
+	r.CiphertextSize += uint64(len(page.Ciphertext))
+}
+
+// maxServedKeys is the maximum number of document keys whose serving librarian is recorded
+// before the record is reset, bounding the memory of long-lived authors.
+const maxServedKeys = 16384
+
+// servingGetters wraps a client.GetterBalancer, recording the public key of the librarian
+// serving each document.
+type servingGetters struct {
+	inner   client.GetterBalancer
+	mu      sync.Mutex
+	servers map[string][]byte
+}
+
+func newServingGetters(inner client.GetterBalancer) *servingGetters {
+	return &servingGetters{
+		inner:   inner,
+		servers: make(map[string][]byte),
+	}
+}
+
+func (sg *servingGetters) Next() (api.Getter, error) {
+	lc, err := sg.inner.Next()
+	if err != nil {
+		return nil, err
+	}
+	return &servingGetter{inner: lc, sg: sg}, nil
+}
+
+func (sg *servingGetters) record(key []byte, pubKey []byte) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	if len(sg.servers) >= maxServedKeys {
+		sg.servers = make(map[string][]byte)
+	}
+	sg.servers[id.FromBytes(key).String()] = pubKey
+}
+
+func (sg *servingGetters) servedBy(key id.ID) []byte {
+	if sg == nil {
+		return nil
+	}
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	return sg.servers[key.String()]
+}
+
+type servingGetter struct {
+	inner api.Getter
+	sg    *servingGetters
+}
+
+func (g *servingGetter) Get(ctx context.Context, rq *api.GetRequest, opts ...grpc.CallOption) (
+	*api.GetResponse, error) {
+	rp, err := g.inner.Get(ctx, rq, opts...)
+	if err == nil && rp.Value != nil && rp.Metadata != nil {
+		g.sg.record(rq.Key, rp.Metadata.PubKey)
+	}
+	return rp, err
+}
+
+// countingWriter counts and MACs the bytes written to an inner io.Writer.
+type countingWriter struct {
+	inner io.Writer
+	mac   enc.MAC
+	n     uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.inner.Write(p)
+	w.n += uint64(n)
+	_, _ = w.mac.Write(p[:n]) // HMAC Write always returns nil error
+	return n, err
+}
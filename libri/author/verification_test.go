@@ -0,0 +1,206 @@
+package author
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/author/io/common"
+	"github.com/drausin/libri/libri/author/io/enc"
+	"github.com/drausin/libri/libri/author/io/page"
+	"github.com/drausin/libri/libri/author/io/publish"
+	"github.com/drausin/libri/libri/author/io/ship"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestAuthor_UploadDownloadWithReport(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a := newTestAuthor()
+
+	// just mock interaction with libri network
+	pubAcq := &memPublisherAcquirer{
+		docs: make(map[string]*api.Document),
+	}
+	slPublisher := publish.NewSingleLoadPublisher(pubAcq, a.documentSLD)
+	ssAcquirer := publish.NewSingleStoreAcquirer(pubAcq, a.documentSLD)
+	mlPublisher := publish.NewMultiLoadPublisher(slPublisher, a.config.Publish)
+	a.shipper = ship.NewShipper(&fixedPutterBalancer{}, pubAcq, mlPublisher)
+	a.acquirer = pubAcq
+	a.msAcquirer = publish.NewMultiStoreAcquirer(ssAcquirer, a.config.Publish)
+	a.getters = &fixedGetterBalancer{}
+
+	page.MinSize = 64 // just for testing
+	a.config.Print.PageSize = 128
+	for _, uncompressedSize := range []int{128, 1024} {
+		content1 := common.NewCompressableBytes(rng, uncompressedSize)
+		content1Bytes := content1.Bytes()
+		_, envelopeKey, err := a.Upload(content1, "application/x-pdf")
+		assert.Nil(t, err)
+
+		content2 := new(bytes.Buffer)
+		rp, err := a.DownloadWithReport(content2, envelopeKey)
+		assert.Nil(t, err)
+		assert.Equal(t, content1Bytes, content2.Bytes())
+
+		assert.True(t, rp.Verified())
+		assert.Equal(t, envelopeKey, rp.EnvelopeKey)
+		assert.NotNil(t, rp.EntryKey)
+		assert.NotEmpty(t, rp.Pages)
+		assert.Zero(t, rp.NMACFailures())
+		assert.Equal(t, uint64(len(content1Bytes)), rp.UncompressedSize)
+		assert.Equal(t, rp.Metadata.CiphertextSize, rp.CiphertextSize)
+		assert.Equal(t, rp.Metadata.CiphertextMac, rp.CiphertextMAC)
+		assert.Equal(t, rp.Metadata.UncompressedMac, rp.UncompressedMAC)
+		for i, pv := range rp.Pages {
+			assert.Equal(t, uint32(i), pv.Index)
+		}
+	}
+
+	err := a.CloseAndRemove()
+	assert.Nil(t, err)
+}
+
+func TestAuthor_DownloadWithReport_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := api.NewTestDocument(rng)
+
+	// ReceiveEntry error should bubble up
+	a := newTestAuthor()
+	a.getters = &fixedGetterBalancer{}
+	a.acquirer = &fixedAcquirer{err: errors.New("some Acquire error")}
+	rp, err := a.DownloadWithReport(nil, id.NewPseudoRandom(rng))
+	assert.NotNil(t, err)
+	assert.Nil(t, rp)
+	assert.Nil(t, a.CloseAndRemove())
+
+	// unexpected envelope document type should bubble up
+	a = newTestAuthor()
+	a.getters = &fixedGetterBalancer{}
+	a.acquirer = &fixedAcquirer{doc: doc}
+	rp, err = a.DownloadWithReport(nil, id.NewPseudoRandom(rng))
+	assert.NotNil(t, err)
+	assert.Nil(t, rp)
+	assert.Nil(t, a.CloseAndRemove())
+}
+
+func TestDownloadReport_Verified(t *testing.T) {
+	newReport := func() *DownloadReport {
+		return &DownloadReport{
+			Pages:            []*PageVerification{{MACOK: true}, {MACOK: true}},
+			Metadata:         &api.EntryMetadata{CiphertextSize: 10, UncompressedSize: 20},
+			CiphertextSize:   10,
+			UncompressedSize: 20,
+		}
+	}
+	assert.True(t, newReport().Verified())
+
+	cases := map[string]func(rp *DownloadReport){
+		"page MAC failure":   func(rp *DownloadReport) { rp.Pages[1].MACOK = false },
+		"missing metadata":   func(rp *DownloadReport) { rp.Metadata = nil },
+		"ciphertext size":    func(rp *DownloadReport) { rp.CiphertextSize++ },
+		"uncompressed size":  func(rp *DownloadReport) { rp.UncompressedSize-- },
+		"unpack/check error": func(rp *DownloadReport) { rp.Err = enc.ErrUnexpectedCiphertextMAC },
+	}
+	for desc, mutate := range cases {
+		rp := newReport()
+		mutate(rp)
+		assert.False(t, rp.Verified(), desc)
+	}
+}
+
+func TestDownloadReport_addPage(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	keys := enc.NewPseudoRandomEEK(rng)
+	p := api.NewTestPage(rng)
+	p.CiphertextMac = enc.HMAC(p.Ciphertext, keys.HMACKey)
+	servedBy := api.RandBytes(rng, api.ECPubKeyLength)
+	rp := &DownloadReport{}
+	ciphertextMAC := enc.NewHMAC(keys.HMACKey)
+
+	rp.addPage(id.NewPseudoRandom(rng), p, keys, ciphertextMAC, servedBy)
+	assert.Len(t, rp.Pages, 1)
+	assert.Equal(t, p.CiphertextMac, ciphertextMAC.Sum(nil))
+	assert.True(t, rp.Pages[0].MACOK)
+	assert.Equal(t, servedBy, rp.Pages[0].ServedBy)
+	assert.Equal(t, uint64(len(p.Ciphertext)), rp.CiphertextSize)
+
+	// corrupted ciphertext fails MAC check
+	p.Ciphertext[0]++
+	rp.addPage(id.NewPseudoRandom(rng), p, keys, ciphertextMAC, nil)
+	assert.False(t, rp.Pages[1].MACOK)
+	assert.Equal(t, 1, rp.NMACFailures())
+	assert.Equal(t, 2*uint64(len(p.Ciphertext)), rp.CiphertextSize)
+}
+
+func TestServingGetters(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	pubKey := api.RandBytes(rng, api.ECPubKeyLength)
+	value, _ := api.NewTestDocument(rng)
+	lc := &fixedServingGetter{
+		rp: &api.GetResponse{
+			Metadata: &api.ResponseMetadata{PubKey: pubKey},
+			Value:    value,
+		},
+	}
+	sg := newServingGetters(&fixedGetterBalancer{client: lc})
+
+	g, err := sg.Next()
+	assert.Nil(t, err)
+	_, err = g.Get(context.Background(), &api.GetRequest{Key: key.Bytes()})
+	assert.Nil(t, err)
+	assert.Equal(t, pubKey, sg.servedBy(key))
+
+	// responses without values and errors aren't recorded
+	key2, key3 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	lc.rp = &api.GetResponse{Metadata: &api.ResponseMetadata{PubKey: pubKey}}
+	_, err = g.Get(context.Background(), &api.GetRequest{Key: key2.Bytes()})
+	assert.Nil(t, err)
+	assert.Nil(t, sg.servedBy(key2))
+	lc.err = errors.New("some Get error")
+	_, err = g.Get(context.Background(), &api.GetRequest{Key: key3.Bytes()})
+	assert.NotNil(t, err)
+	assert.Nil(t, sg.servedBy(key3))
+
+	// record is reset once it gets too large
+	for i := 0; i < maxServedKeys; i++ {
+		sg.record(id.NewPseudoRandom(rng).Bytes(), pubKey)
+	}
+	assert.Nil(t, sg.servedBy(key))
+	assert.True(t, len(sg.servers) < maxServedKeys)
+
+	// nil servingGetters doesn't know any servers
+	var nilSG *servingGetters
+	assert.Nil(t, nilSG.servedBy(key))
+
+	// balancer errors bubble up
+	sg = newServingGetters(&fixedGetterBalancer{err: errors.New("some Next error")})
+	g, err = sg.Next()
+	assert.NotNil(t, err)
+	assert.Nil(t, g)
+}
+
+type fixedServingGetter struct {
+	rp  *api.GetResponse
+	err error
+}
+
+func (f *fixedServingGetter) Get(ctx context.Context, rq *api.GetRequest,
+	opts ...grpc.CallOption) (*api.GetResponse, error) {
+	return f.rp, f.err
+}
+
+type fixedAcquirer struct {
+	doc *api.Document
+	err error
+}
+
+func (f *fixedAcquirer) Acquire(docKey id.ID, authorPub []byte, lc api.Getter) (
+	*api.Document, error) {
+	return f.doc, f.err
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/drausin/libri/libri/author"
 	lauthor "github.com/drausin/libri/libri/author"
 	"github.com/drausin/libri/libri/author/keychain"
+	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/id"
 	clogging "github.com/drausin/libri/libri/common/logging"
@@ -25,6 +26,12 @@ const (
 	passphraseVar        = "passphrase"
 	authorLibrariansFlag = "authorLibrarians"
 	timeoutFlag          = "timeout"
+	delegationTokenFlag  = "delegationToken"
+	delegatorPubKeyFlag  = "delegatorPubKey"
+	readRepairFlag       = "readRepair"
+	ttlFlag              = "ttl"
+	macaroonFlag         = "macaroon"
+	typeReplicasFlag     = "typeReplicas"
 )
 
 // authorCmd represents the author command
@@ -41,6 +48,25 @@ func init() {
 		"comma-separated addresses (IPv4:Port) of librarian(s)")
 	authorCmd.PersistentFlags().Int(timeoutFlag, 5,
 		"timeout (seconds) for requests to librarians")
+	authorCmd.PersistentFlags().String(storeAuthTokenFlag, "",
+		"[sensitive] store authorization token to send with Put requests to librarians")
+	authorCmd.PersistentFlags().String(macaroonFlag, "",
+		"[sensitive] macaroon authorizing Get and Put requests to librarians that require one")
+	authorCmd.PersistentFlags().Bool(readRepairFlag, false,
+		"ask librarians to re-store downloaded documents with the closest peers missing them")
+	authorCmd.PersistentFlags().Duration(ttlFlag, 0,
+		"how long librarians store uploaded documents before they expire (0 stores them "+
+			"permanently)")
+	authorCmd.PersistentFlags().StringSlice(typeReplicasFlag, nil,
+		"number of replicas librarians store of each uploaded document type, e.g., "+
+			"envelope=5,entry=5,page=3, with the librarians' default for other types")
+	authorCmd.PersistentFlags().String(delegationTokenFlag, "",
+		"delegation token scoping the operations this author may perform")
+	authorCmd.PersistentFlags().String(delegatorPubKeyFlag, "",
+		"hex public key of the keychain owner that signed the delegation token")
+	authorCmd.PersistentFlags().String(netParamsPubKeyFlag, "",
+		"hex public key of the network maintainers whose published network parameters are "+
+			"fetched from the librarians and adopted")
 
 	// bind viper flags
 	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
@@ -84,11 +110,18 @@ type authorConfigGetterImpl struct{}
 func (*authorConfigGetterImpl) get(librariansFlag string) (*author.Config, *zap.Logger, error) {
 	config := author.NewDefaultConfig().
 		WithDataDir(viper.GetString(dataDirFlag)).
-		WithDefaultDBDir(). // depends on DataDir
+		WithDefaultDBDir().        // depends on DataDir
+		WithDefaultForensicsDir(). // depends on DataDir
+		WithDBDriver(viper.GetString(dbDriverFlag)).
 		WithLogLevel(getLogLevel())
 	timeout := time.Duration(viper.GetInt(timeoutFlag) * 1e9)
 	config.Publish.PutTimeout = timeout
 	config.Publish.GetTimeout = timeout
+	config.Publish.StoreAuthToken = viper.GetString(storeAuthTokenFlag)
+	config.Publish.ReadRepair = viper.GetBool(readRepairFlag)
+	config.Publish.TTL = viper.GetDuration(ttlFlag)
+	config.Publish.Macaroon = viper.GetString(macaroonFlag)
+	config.WithTLS(getTLSParameters())
 
 	logger := clogging.NewDevLogger(config.LogLevel)
 	librarianNetAddrs, err := parse.Addrs(viper.GetStringSlice(librariansFlag))
@@ -97,6 +130,31 @@ func (*authorConfigGetterImpl) get(librariansFlag string) (*author.Config, *zap.
 		return nil, logger, err
 	}
 	config.WithLibrarianAddrs(librarianNetAddrs)
+	delegatorPub, err := getPubKey(logger, delegatorPubKeyFlag, "delegator")
+	if err != nil {
+		return nil, logger, err
+	}
+	if delegatorPub != nil {
+		config.WithDelegation(viper.GetString(delegationTokenFlag),
+			ecid.ToPublicKeyBytes(delegatorPub))
+	} else {
+		config.WithDelegation(viper.GetString(delegationTokenFlag), nil)
+	}
+
+	netParamsPub, err := getNetworkParamsPubKey(logger)
+	if err != nil {
+		return nil, logger, err
+	}
+	if netParamsPub != nil {
+		config.WithNetworkParamsPubKey(ecid.ToPublicKeyBytes(netParamsPub))
+	}
+	typeReplicas, err := getTypeReplicas(logger, typeReplicasFlag)
+	if err != nil {
+		return nil, logger, err
+	}
+	if len(typeReplicas) > 0 {
+		config.Publish.Replication = typeReplicas
+	}
 
 	WriteAuthorBanner(os.Stdout)
 	logger.Info("author configuration",
@@ -123,6 +181,8 @@ func (*terminalPassphraseGetter) get() (string, error) {
 // is a struct rather than an interface
 type authorUploader interface {
 	upload(author *lauthor.Author, content io.Reader, mediaType string) (id.ID, error)
+	dryRun(author *lauthor.Author, content io.Reader, mediaType string) (*lauthor.UploadPlan,
+		error)
 }
 
 type authorUploaderImpl struct{}
@@ -133,9 +193,16 @@ func (*authorUploaderImpl) upload(author *lauthor.Author, content io.Reader, med
 	return envelopeKey, err
 }
 
-// authorDownloader just wraps an *author.Author Download call for the same reason as authorUploader
+func (*authorUploaderImpl) dryRun(author *lauthor.Author, content io.Reader, mediaType string) (
+	*lauthor.UploadPlan, error) {
+	return author.DryRunUpload(content, mediaType)
+}
+
+// authorDownloader just wraps *author.Author Download calls for the same reason as authorUploader
 type authorDownloader interface {
 	download(author *lauthor.Author, content io.Writer, envelopeKey id.ID) error
+	downloadWithReport(author *lauthor.Author, content io.Writer, envelopeKey id.ID) (
+		*lauthor.DownloadReport, error)
 }
 
 type authorDownloaderImpl struct{}
@@ -145,3 +212,21 @@ func (*authorDownloaderImpl) download(
 ) error {
 	return author.Download(content, envelopeKey)
 }
+
+func (*authorDownloaderImpl) downloadWithReport(
+	author *lauthor.Author, content io.Writer, envelopeKey id.ID,
+) (*lauthor.DownloadReport, error) {
+	return author.DownloadWithReport(content, envelopeKey)
+}
+
+// authorRepairer just wraps an *author.Author Repair call for the same reason as authorUploader
+type authorRepairer interface {
+	repair(author *lauthor.Author, entryKey id.ID) (*lauthor.RepairReport, error)
+}
+
+type authorRepairerImpl struct{}
+
+func (*authorRepairerImpl) repair(author *lauthor.Author, entryKey id.ID) (
+	*lauthor.RepairReport, error) {
+	return author.Repair(entryKey)
+}
@@ -6,12 +6,14 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"path/filepath"
 
 	"github.com/drausin/libri/libri/author"
 	"github.com/drausin/libri/libri/author/keychain"
 	"github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -66,12 +68,21 @@ func TestAuthorConfigGetter_get_ok(t *testing.T) {
 	viper.Set(dataDirFlag, dataDir)
 	viper.Set(logLevelFlag, logLevel)
 	viper.Set(authorLibrariansFlag, libAddrsArg)
+	viper.Set(ttlFlag, time.Hour)
+	defer viper.Set(ttlFlag, 0)
+	viper.Set(typeReplicasFlag, []string{"page=3"})
+	defer viper.Set(typeReplicasFlag, []string{})
+	viper.Set(tlsCertFileFlag, "some/cert.pem")
+	defer viper.Set(tlsCertFileFlag, "")
 	acg := &authorConfigGetterImpl{}
 
 	config, logger, err := acg.get(authorLibrariansFlag)
 
 	assert.Nil(t, err)
 	assert.Equal(t, logLevel, config.LogLevel)
+	assert.Equal(t, "some/cert.pem", config.TLS.CertFile)
+	assert.Equal(t, time.Hour, config.Publish.TTL)
+	assert.Equal(t, api.TypeReplicas{api.PageType: 3}, config.Publish.Replication)
 	assert.Equal(t, len(libAddrs), len(config.LibrarianAddrs))
 	for i, la := range config.LibrarianAddrs {
 		assert.Equal(t, libAddrs[i], la.String())
@@ -94,6 +105,19 @@ func TestAuthorConfigGetter_get_err(t *testing.T) {
 	assert.NotNil(t, logger) // still should have been created
 }
 
+func TestAuthorConfigGetter_get_delegationErr(t *testing.T) {
+	viper.Set(authorLibrariansFlag, "127.0.0.1:1234")
+	viper.Set(delegatorPubKeyFlag, "not hex")
+	defer viper.Set(delegatorPubKeyFlag, "")
+	acg := &authorConfigGetterImpl{}
+
+	config, logger, err := acg.get(authorLibrariansFlag)
+
+	assert.NotNil(t, err)
+	assert.Nil(t, config)
+	assert.NotNil(t, logger)
+}
+
 type fixedAuthorConfigGetter struct {
 	config *author.Config
 	logger *zap.Logger
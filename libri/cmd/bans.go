@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	librarianAddrFlag = "librarianAddr"
+	adminKeyFlag      = "adminKey"
+	adminTimeoutFlag  = "adminTimeout"
+	banReasonFlag     = "reason"
+	banDurationFlag   = "duration"
+)
+
+var (
+	errMissingBannedPeerID = errors.New("missing banned peer ID")
+	errMissingAdminKey     = errors.New("missing admin private key")
+)
+
+// bansCmd represents the librarian bans command
+var bansCmd = &cobra.Command{
+	Use:   "bans",
+	Short: "manage a librarian's persisted peer bans",
+}
+
+// listBansCmd represents the librarian bans list command
+var listBansCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list a librarian's unexpired peer bans",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newBanManager()
+		if err != nil {
+			return err
+		}
+		return m.list()
+	},
+}
+
+// addBanCmd represents the librarian bans add command
+var addBanCmd = &cobra.Command{
+	Use:   "add <peerID>",
+	Short: "ban a peer, replacing any existing ban on it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errMissingBannedPeerID
+		}
+		m, err := newBanManager()
+		if err != nil {
+			return err
+		}
+		return m.add(args[0], viper.GetString(banReasonFlag), viper.GetDuration(banDurationFlag))
+	},
+}
+
+// removeBanCmd represents the librarian bans remove command
+var removeBanCmd = &cobra.Command{
+	Use:   "remove <peerID>",
+	Short: "lift the ban on a peer",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errMissingBannedPeerID
+		}
+		m, err := newBanManager()
+		if err != nil {
+			return err
+		}
+		return m.remove(args[0])
+	},
+}
+
+func init() {
+	librarianCmd.AddCommand(bansCmd)
+	bansCmd.AddCommand(listBansCmd)
+	bansCmd.AddCommand(addBanCmd)
+	bansCmd.AddCommand(removeBanCmd)
+
+	bansCmd.PersistentFlags().String(librarianAddrFlag, "",
+		"address of the librarian to manage")
+	bansCmd.PersistentFlags().String(adminKeyFlag, "",
+		"[sensitive] hex value of the librarian admin's private key")
+	bansCmd.PersistentFlags().Int(adminTimeoutFlag, 5,
+		"timeout (secs) of librarian requests")
+	addBanCmd.Flags().String(banReasonFlag, "", "reason for the ban")
+	addBanCmd.Flags().Duration(banDurationFlag, 0,
+		"how long the ban lasts, with 0 meaning permanent")
+
+	// bind viper flags
+	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
+	viper.AutomaticEnv()             // read in environment variables that match
+	cerrors.MaybePanic(viper.BindPFlags(bansCmd.PersistentFlags()))
+	cerrors.MaybePanic(viper.BindPFlags(addBanCmd.Flags()))
+}
+
+// banManager lists, adds, and removes a librarian's bans via its admin API.
+type banManager struct {
+	lc      api.LibrarianClient
+	adminID ecid.ID
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func newBanManager() (*banManager, error) {
+	logger := clogging.NewDevLogger(getLogLevel())
+	adminID, err := getAdminID(logger)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := newClientPool()
+	if err != nil {
+		return nil, err
+	}
+	lc, err := clients.Get(viper.GetString(librarianAddrFlag))
+	if err != nil {
+		return nil, err
+	}
+	return &banManager{
+		lc:      lc,
+		adminID: adminID,
+		timeout: time.Duration(viper.GetInt(adminTimeoutFlag) * 1e9),
+		logger:  logger,
+	}, nil
+}
+
+func (m *banManager) list() error {
+	rq := client.NewListBansRequest(m.adminID, nil)
+	ctx, cancel, err := client.NewSignedTimeoutContext(m.signer(), client.NewEmptySigner(), rq,
+		m.timeout)
+	if err != nil {
+		return err
+	}
+	rp, err := m.lc.ListBans(ctx, rq)
+	cancel()
+	if err != nil {
+		return err
+	}
+	m.logger.Info("listed bans", zap.Int("n_bans", len(rp.Bans)))
+	for _, ban := range rp.Bans {
+		m.logger.Info("ban", banFields(ban)...)
+	}
+	return nil
+}
+
+func (m *banManager) add(peerIDStr, reason string, duration time.Duration) error {
+	peerID, err := parseBannedPeerID(peerIDStr)
+	if err != nil {
+		return err
+	}
+	ban := &api.PeerBan{PeerId: peerID.Bytes(), Reason: reason}
+	if duration > 0 {
+		ban.ExpiryNanos = time.Now().Add(duration).UnixNano()
+	}
+	rq := client.NewBanRequest(m.adminID, nil, ban)
+	ctx, cancel, err := client.NewSignedTimeoutContext(m.signer(), client.NewEmptySigner(), rq,
+		m.timeout)
+	if err != nil {
+		return err
+	}
+	_, err = m.lc.Ban(ctx, rq)
+	cancel()
+	if err != nil {
+		return err
+	}
+	m.logger.Info("banned peer", banFields(ban)...)
+	return nil
+}
+
+func (m *banManager) remove(peerIDStr string) error {
+	peerID, err := parseBannedPeerID(peerIDStr)
+	if err != nil {
+		return err
+	}
+	rq := client.NewUnbanRequest(m.adminID, nil, peerID)
+	ctx, cancel, err := client.NewSignedTimeoutContext(m.signer(), client.NewEmptySigner(), rq,
+		m.timeout)
+	if err != nil {
+		return err
+	}
+	rp, err := m.lc.Unban(ctx, rq)
+	cancel()
+	if err != nil {
+		return err
+	}
+	m.logger.Info("unbanned peer",
+		zap.Stringer("peer_id", peerID),
+		zap.Bool("removed", rp.Removed),
+	)
+	return nil
+}
+
+func (m *banManager) signer() client.Signer {
+	return client.NewECDSASigner(m.adminID.Key())
+}
+
+func parseBannedPeerID(peerIDStr string) (id.ID, error) {
+	if peerIDStr == "" {
+		return nil, errMissingBannedPeerID
+	}
+	return id.FromString(peerIDStr)
+}
+
+func banFields(ban *api.PeerBan) []zap.Field {
+	fields := []zap.Field{
+		zap.String("peer_id", id.Hex(ban.PeerId)),
+		zap.String("reason", ban.Reason),
+	}
+	if ban.ExpiryNanos == 0 {
+		return append(fields, zap.Bool("permanent", true))
+	}
+	return append(fields, zap.Time("expiry", time.Unix(0, ban.ExpiryNanos)))
+}
+
+func getAdminID(logger *zap.Logger) (ecid.ID, error) {
+	adminID, err := getPrivateKeyID(logger, adminKeyFlag, "admin")
+	if err != nil {
+		return nil, err
+	}
+	if adminID == nil {
+		logger.Error("admin private key must be set")
+		return nil, errMissingAdminKey
+	}
+	return adminID, nil
+}
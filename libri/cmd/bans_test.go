@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestBanCmds_err(t *testing.T) {
+	assert.Equal(t, errMissingBannedPeerID, addBanCmd.RunE(addBanCmd, []string{}))
+	assert.Equal(t, errMissingBannedPeerID, removeBanCmd.RunE(removeBanCmd, []string{}))
+}
+
+func TestBanManager_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := id.NewPseudoRandom(rng)
+	lc := &fixedBansClient{
+		listRp: &api.ListBansResponse{
+			Bans: []*api.PeerBan{
+				{PeerId: peerID.Bytes(), Reason: "spam"},
+				{PeerId: id.NewPseudoRandom(rng).Bytes(), ExpiryNanos: time.Now().UnixNano()},
+			},
+		},
+		unbanRp: &api.UnbanResponse{Removed: true},
+	}
+	m := &banManager{
+		lc:      lc,
+		adminID: ecid.NewPseudoRandom(rng),
+		timeout: time.Second,
+		logger:  zap.NewNop(),
+	}
+
+	assert.Nil(t, m.list())
+
+	// permanent ban
+	assert.Nil(t, m.add(peerID.String(), "spam", 0))
+	assert.Equal(t, peerID.Bytes(), lc.banRq.Ban.PeerId)
+	assert.Equal(t, "spam", lc.banRq.Ban.Reason)
+	assert.Zero(t, lc.banRq.Ban.ExpiryNanos)
+
+	// temporary ban
+	assert.Nil(t, m.add(peerID.String(), "", time.Hour))
+	assert.True(t, lc.banRq.Ban.ExpiryNanos > time.Now().UnixNano())
+
+	assert.Nil(t, m.remove(peerID.String()))
+	assert.Equal(t, peerID.Bytes(), lc.unbanRq.PeerId)
+}
+
+func TestBanManager_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerIDStr := id.NewPseudoRandom(rng).String()
+	newManager := func(lc api.LibrarianClient) *banManager {
+		return &banManager{
+			lc:      lc,
+			adminID: ecid.NewPseudoRandom(rng),
+			timeout: time.Second,
+			logger:  zap.NewNop(),
+		}
+	}
+
+	// check bad peer IDs error
+	m := newManager(&fixedBansClient{})
+	assert.Equal(t, errMissingBannedPeerID, m.add("", "", 0))
+	assert.Equal(t, errMissingBannedPeerID, m.remove(""))
+	assert.NotNil(t, m.add("not hex", "", 0))
+	assert.NotNil(t, m.remove("not hex"))
+
+	// check librarian errors bubble up
+	m = newManager(&fixedBansClient{err: errors.New("some librarian error")})
+	assert.NotNil(t, m.list())
+	assert.NotNil(t, m.add(peerIDStr, "", 0))
+	assert.NotNil(t, m.remove(peerIDStr))
+}
+
+func TestGetAdminID(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	lg := zap.NewNop()
+
+	viper.Set(adminKeyFlag, "")
+	adminID, err := getAdminID(lg)
+	assert.Equal(t, errMissingAdminKey, err)
+	assert.Nil(t, adminID)
+
+	viper.Set(adminKeyFlag, "not hex")
+	adminID, err = getAdminID(lg)
+	assert.NotNil(t, err)
+	assert.Nil(t, adminID)
+
+	adminID1 := ecid.NewPseudoRandom(rng)
+	viper.Set(adminKeyFlag, hex.EncodeToString(adminID1.Key().D.Bytes()))
+	adminID2, err := getAdminID(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, adminID1.Key(), adminID2.Key())
+}
+
+type fixedBansClient struct {
+	api.LibrarianClient
+	listRp  *api.ListBansResponse
+	banRq   *api.BanRequest
+	unbanRq *api.UnbanRequest
+	unbanRp *api.UnbanResponse
+	err     error
+}
+
+func (f *fixedBansClient) ListBans(
+	ctx context.Context, in *api.ListBansRequest, opts ...grpc.CallOption,
+) (*api.ListBansResponse, error) {
+	return f.listRp, f.err
+}
+
+func (f *fixedBansClient) Ban(
+	ctx context.Context, in *api.BanRequest, opts ...grpc.CallOption,
+) (*api.BanResponse, error) {
+	f.banRq = in
+	return &api.BanResponse{}, f.err
+}
+
+func (f *fixedBansClient) Unban(
+	ctx context.Context, in *api.UnbanRequest, opts ...grpc.CallOption,
+) (*api.UnbanResponse, error) {
+	f.unbanRq = in
+	return f.unbanRp, f.err
+}
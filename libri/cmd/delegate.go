@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	lauthor "github.com/drausin/libri/libri/author"
+	"github.com/drausin/libri/libri/author/keychain"
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	delegateKeychainDirFlag = "delegateKeychainsDir"
+	delegatePassphraseVar   = "delegatePassphrase"
+	scopesFlag              = "scopes"
+	maxUploadBytesFlag      = "maxUploadBytes"
+	delegationTTLFlag       = "delegationTTL"
+	logDelegationToken      = "delegation_token"
+	logDelegatorPubKey      = "delegator_pub_key"
+)
+
+var errMissingDelegateKeychainDir = errors.New("delegateKeychainsDir cannot be empty")
+
+// delegateCmd represents the author delegate command
+var delegateCmd = &cobra.Command{
+	Use:   "delegate",
+	Short: "mint delegate keychains and a token scoping what an author holding them may do",
+	Long: `Creates author and self-reader keychains holding a single new key each in the delegate
+keychains directory and prints a token, signed by a key from the owner's keychains, delegating
+the given scopes to them. Run the author daemon with the delegate keychains and the
+delegationToken and delegatorPubKey flags instead of the owner's keychains. Keep a copy of the
+delegate keychains to read what the delegate uploads.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return newDelegationMinter().mint()
+	},
+}
+
+func init() {
+	authorCmd.AddCommand(delegateCmd)
+
+	delegateCmd.Flags().String(delegateKeychainDirFlag, "",
+		"directory to create the delegate keychains in")
+	delegateCmd.Flags().StringSlice(scopesFlag, []string{lauthor.ScopeUpload},
+		"comma-separated operations (upload, download, share, repair) to delegate")
+	delegateCmd.Flags().Int64(maxUploadBytesFlag, 0,
+		"maximum content size (bytes) of a single upload, or 0 for unlimited")
+	delegateCmd.Flags().Int(delegationTTLFlag, 0,
+		"seconds until the delegation token expires, or 0 for never")
+
+	// bind viper flags
+	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
+	viper.AutomaticEnv()             // read in environment variables that match
+	cerrors.MaybePanic(viper.BindPFlags(delegateCmd.Flags()))
+}
+
+type delegationMinter interface {
+	mint() error
+}
+
+func newDelegationMinter() delegationMinter {
+	return &delegationMinterImpl{
+		kc: &keychainsGetterImpl{
+			pg: &terminalPassphraseGetter{},
+		},
+		pg1:     &terminalPassphraseGetter{},
+		pg2:     &terminalPassphraseGetter{},
+		scryptN: keychain.LightScryptN,
+		scryptP: keychain.LightScryptP,
+	}
+}
+
+type delegationMinterImpl struct {
+	kc      keychainsGetter
+	pg1     passphraseGetter
+	pg2     passphraseGetter
+	scryptN int
+	scryptP int
+}
+
+func (m *delegationMinterImpl) mint() error {
+	delegateKeychainDir := viper.GetString(delegateKeychainDirFlag)
+	if delegateKeychainDir == "" {
+		return errMissingDelegateKeychainDir
+	}
+	authorKeys, _, err := m.kc.get()
+	if err != nil {
+		return err
+	}
+	ownerID, err := authorKeys.Sample()
+	if err != nil {
+		return err
+	}
+	passphrase, err := m.delegatePassphrase()
+	if err != nil {
+		return err
+	}
+	var expiresAt time.Time
+	if ttl := viper.GetInt(delegationTTLFlag); ttl > 0 {
+		expiresAt = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+
+	logger := clogging.NewDevLogger(getLogLevel())
+	token, err := lauthor.MintDelegation(logger, ownerID.Key(), delegateKeychainDir, passphrase,
+		m.scryptN, m.scryptP, viper.GetStringSlice(scopesFlag),
+		viper.GetInt64(maxUploadBytesFlag), expiresAt)
+	if err != nil {
+		return err
+	}
+	logger.Info("minted delegation",
+		zap.String(logDelegationToken, token),
+		zap.String(logDelegatorPubKey, hex.EncodeToString(ownerID.PublicKeyBytes())),
+	)
+	return nil
+}
+
+// delegatePassphrase returns the passphrase for the new delegate keychains, which should differ
+// from that of the owner's keychains.
+func (m *delegationMinterImpl) delegatePassphrase() (string, error) {
+	passphrase := viper.GetString(delegatePassphraseVar) // intentionally not bound to flag
+	if passphrase != "" {
+		return passphrase, nil
+	}
+	fmt.Print("Enter passphrase for new delegate keychains: ")
+	passphrase, err := m.pg1.get()
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("\nEnter passphrase again: ")
+	repeated, err := m.pg2.get()
+	if err != nil {
+		return "", err
+	}
+	fmt.Println()
+	if passphrase != repeated {
+		return "", errMismatchedPassphrase
+	}
+	return passphrase, nil
+}
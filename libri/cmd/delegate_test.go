@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	lauthor "github.com/drausin/libri/libri/author"
+	"github.com/drausin/libri/libri/author/keychain"
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelegationMinter_mint_ok(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-delegate-keychains")
+	defer func() { cerrors.MaybePanic(os.RemoveAll(dir)) }()
+	assert.Nil(t, err)
+	delegateDir := path.Join(dir, "delegate")
+	passphrase := "some delegate passphrase"
+	viper.Set(delegateKeychainDirFlag, delegateDir)
+	viper.Set(scopesFlag, []string{lauthor.ScopeUpload})
+
+	m := &delegationMinterImpl{
+		kc:      &fixedKeychainsGetter{authorKeys: keychain.New(3)},
+		pg1:     &fixedPassphraseGetter{passphrase: passphrase},
+		pg2:     &fixedPassphraseGetter{passphrase: passphrase},
+		scryptN: veryLightScryptN,
+		scryptP: veryLightScryptP,
+	}
+	err = m.mint()
+	assert.Nil(t, err)
+
+	authorKeys, selfReaderKeys, err := lauthor.LoadKeychains(delegateDir, passphrase)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, authorKeys.Len())
+	assert.Equal(t, 1, selfReaderKeys.Len())
+}
+
+func TestDelegationMinter_mint_err(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-delegate-keychains")
+	defer func() { cerrors.MaybePanic(os.RemoveAll(dir)) }()
+	assert.Nil(t, err)
+	passphrase := "some delegate passphrase"
+	okKC := &fixedKeychainsGetter{authorKeys: keychain.New(3)}
+	okPG := &fixedPassphraseGetter{passphrase: passphrase}
+
+	// missing delegate keychains dir
+	viper.Set(delegateKeychainDirFlag, "")
+	m := &delegationMinterImpl{kc: okKC, pg1: okPG, pg2: okPG}
+	assert.Equal(t, errMissingDelegateKeychainDir, m.mint())
+
+	// keychains getter error
+	viper.Set(delegateKeychainDirFlag, path.Join(dir, "delegate"))
+	m = &delegationMinterImpl{kc: &fixedKeychainsGetter{err: errors.New("some get error")}}
+	assert.NotNil(t, m.mint())
+
+	// empty owner keychain
+	m = &delegationMinterImpl{kc: &fixedKeychainsGetter{authorKeys: keychain.New(0)}}
+	assert.Equal(t, keychain.ErrEmptyKeychain, m.mint())
+
+	// mismatched passphrases
+	m = &delegationMinterImpl{
+		kc:  okKC,
+		pg1: okPG,
+		pg2: &fixedPassphraseGetter{passphrase: "some other passphrase"},
+	}
+	assert.Equal(t, errMismatchedPassphrase, m.mint())
+
+	// passphrase getter error
+	m = &delegationMinterImpl{
+		kc:  okKC,
+		pg1: &fixedPassphraseGetter{err: errors.New("some passphrase error")},
+	}
+	assert.NotNil(t, m.mint())
+
+	// existing delegate keychains
+	viper.Set(delegateKeychainDirFlag, dir)
+	viper.Set(delegatePassphraseVar, passphrase)
+	defer viper.Set(delegatePassphraseVar, "")
+	cerrors.MaybePanic(lauthor.CreateKeychains(clogging.NewDevInfoLogger(), dir, passphrase,
+		veryLightScryptN, veryLightScryptP))
+	m = &delegationMinterImpl{kc: okKC, scryptN: veryLightScryptN, scryptP: veryLightScryptP}
+	assert.Equal(t, lauthor.ErrKeychainExists, m.mint())
+}
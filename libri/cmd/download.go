@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"encoding/hex"
 	"os"
 
+	lauthor "github.com/drausin/libri/libri/author"
 	cerrors "github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/pkg/errors"
@@ -14,10 +16,12 @@ import (
 const (
 	envelopeKeyFlag  = "envelopeKey"
 	downFilepathFlag = "downFilepath"
+	downReportFlag   = "report"
 )
 
 var (
 	errMissingEnvelopeKey = errors.New("missing envelope key")
+	errDownloadUnverified = errors.New("downloaded content failed verification")
 )
 
 // downloadCmd represents the download command
@@ -38,6 +42,8 @@ func init() {
 		"path of local file to write downloaded contents to")
 	downloadCmd.Flags().StringP(envelopeKeyFlag, "e", "",
 		"key of envelope to download")
+	downloadCmd.Flags().Bool(downReportFlag, false,
+		"print a report verifying the integrity of the downloaded content")
 
 	// bind viper flags
 	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
@@ -94,9 +100,61 @@ func (d *fileDownloaderImpl) download() error {
 		zap.Stringer("envelope_key", envelopeKey),
 		zap.String("filepath", downFilepath),
 	)
+	if viper.GetBool(downReportFlag) {
+		report, err2 := d.ad.downloadWithReport(author, file, envelopeKey)
+		if report != nil {
+			logDownloadReport(logger, report)
+		}
+		if err2 != nil {
+			return err2
+		}
+		if report == nil || !report.Verified() {
+			return errDownloadUnverified
+		}
+		return file.Close()
+	}
 	err = d.ad.download(author, file, envelopeKey)
 	if err != nil {
 		return err
 	}
 	return file.Close()
 }
+
+func logDownloadReport(logger *zap.Logger, report *lauthor.DownloadReport) {
+	for _, pv := range report.Pages {
+		fields := []zap.Field{
+			zap.Stringer("key", pv.Key),
+			zap.Uint32("index", pv.Index),
+			zap.Uint64("ciphertext_size", pv.CiphertextSize),
+			zap.Bool("mac_ok", pv.MACOK),
+			zap.String("served_by", hex.EncodeToString(pv.ServedBy)),
+		}
+		if pv.MACOK {
+			logger.Info("page verification", fields...)
+		} else {
+			logger.Error("page verification", fields...)
+		}
+	}
+	fields := []zap.Field{
+		zap.Stringer("envelope_key", report.EnvelopeKey),
+		zap.String("envelope_served_by", hex.EncodeToString(report.EnvelopeServedBy)),
+		zap.Stringer("entry_key", report.EntryKey),
+		zap.String("entry_served_by", hex.EncodeToString(report.EntryServedBy)),
+		zap.Int("n_pages", len(report.Pages)),
+		zap.Int("n_mac_failures", report.NMACFailures()),
+		zap.Uint64("ciphertext_size", report.CiphertextSize),
+		zap.Uint64("uncompressed_size", report.UncompressedSize),
+		zap.Bool("verified", report.Verified()),
+	}
+	if report.Metadata != nil {
+		fields = append(fields,
+			zap.Uint64("expected_ciphertext_size", report.Metadata.CiphertextSize),
+			zap.Uint64("expected_uncompressed_size", report.Metadata.UncompressedSize),
+		)
+	}
+	if report.Verified() {
+		logger.Info("download verification", fields...)
+		return
+	}
+	logger.Error("download verification", append(fields, zap.Error(report.Err))...)
+}
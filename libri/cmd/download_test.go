@@ -10,6 +10,7 @@ import (
 	lauthor "github.com/drausin/libri/libri/author"
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
@@ -51,6 +52,59 @@ func TestFileDownloader_download_ok(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestFileDownloader_download_report(t *testing.T) {
+	toDownloadFile, err := ioutil.TempFile("", "to-download")
+	assert.Nil(t, err)
+	err = toDownloadFile.Close()
+	assert.Nil(t, err)
+	defer func() {
+		viper.Set(downReportFlag, false)
+		assert.Nil(t, os.Remove(toDownloadFile.Name()))
+	}()
+	viper.Set(downFilepathFlag, toDownloadFile.Name())
+	viper.Set(envelopeKeyFlag, id.LowerBound.String())
+	viper.Set(downReportFlag, true)
+	newDownloader := func(ad authorDownloader) *fileDownloaderImpl {
+		return &fileDownloaderImpl{
+			ag: &fixedAuthorGetter{
+				author: nil, // ok since we're passing it into a mocked method anyway
+				logger: logging.NewDevInfoLogger(),
+			},
+			ad: ad,
+			kc: &fixedKeychainsGetter{}, // ok that KCs are null since passing to mock
+		}
+	}
+	verified := &lauthor.DownloadReport{
+		EnvelopeKey: id.LowerBound,
+		EntryKey:    id.UpperBound,
+		Pages: []*lauthor.PageVerification{
+			{Key: id.LowerBound, CiphertextSize: 10, MACOK: true},
+		},
+		Metadata:         &api.EntryMetadata{CiphertextSize: 10, UncompressedSize: 20},
+		CiphertextSize:   10,
+		UncompressedSize: 20,
+	}
+
+	// verified download
+	err = newDownloader(&fixedAuthorDownloader{report: verified}).download()
+	assert.Nil(t, err)
+
+	// unverified download
+	unverified := *verified
+	unverified.Pages = []*lauthor.PageVerification{{Key: id.LowerBound, MACOK: false}}
+	err = newDownloader(&fixedAuthorDownloader{report: &unverified}).download()
+	assert.Equal(t, errDownloadUnverified, err)
+
+	// download error with report
+	unverified.Err = errors.New("some unpack error")
+	d := newDownloader(&fixedAuthorDownloader{report: &unverified, err: unverified.Err})
+	assert.Equal(t, unverified.Err, d.download())
+
+	// no report and no error shouldn't panic
+	err = newDownloader(&fixedAuthorDownloader{}).download()
+	assert.Equal(t, errDownloadUnverified, err)
+}
+
 func TestFileDownloader_download_err(t *testing.T) {
 	// should error on missing envelopeKey
 	d1 := &fileDownloaderImpl{}
@@ -110,7 +164,14 @@ func TestFileDownloader_download_err(t *testing.T) {
 }
 
 type fixedAuthorDownloader struct {
-	err error
+	err    error
+	report *lauthor.DownloadReport
+}
+
+func (f *fixedAuthorDownloader) downloadWithReport(
+	author *lauthor.Author, content io.Writer, envelopeKey id.ID,
+) (*lauthor.DownloadReport, error) {
+	return f.report, f.err
 }
 
 func (f *fixedAuthorDownloader) download(
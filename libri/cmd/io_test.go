@@ -100,6 +100,12 @@ type fixedAuthorUploaderDownloader struct {
 	downloadErr error
 }
 
+func (f *fixedAuthorUploaderDownloader) dryRun(
+	author *lauthor.Author, content io.Reader, mediaType string,
+) (*lauthor.UploadPlan, error) {
+	return nil, nil
+}
+
 func (f *fixedAuthorUploaderDownloader) upload(
 	author *lauthor.Author, content io.Reader, mediaType string,
 ) (id.ID, error) {
@@ -126,3 +132,9 @@ func (f *fixedAuthorUploaderDownloader) download(
 	_, err := doc.WriteTo(content)
 	return err
 }
+
+func (f *fixedAuthorUploaderDownloader) downloadWithReport(
+	author *lauthor.Author, content io.Writer, envelopeKey id.ID,
+) (*lauthor.DownloadReport, error) {
+	return nil, f.download(author, content, envelopeKey)
+}
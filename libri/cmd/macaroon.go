@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	macaroonOpsFlag       = "ops"
+	macaroonExpiresInFlag = "expiresIn"
+)
+
+var (
+	errMissingMacaroonOrg     = errors.New("missing macaroon organization public key")
+	errMissingMacaroonRootKey = errors.New("missing macaroon root key")
+	errInvalidMacaroonOp      = errors.New("invalid macaroon operation")
+)
+
+// macaroonCmd represents the librarian macaroon command
+var macaroonCmd = &cobra.Command{
+	Use:   "macaroon",
+	Short: "manage the macaroons librarians require of authors and peers",
+}
+
+// mintMacaroonCmd represents the librarian macaroon mint command
+var mintMacaroonCmd = &cobra.Command{
+	Use:   "mint <orgPubKey>",
+	Short: "mint a macaroon authorizing an organization's requests and print it",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// bind here rather than in init since the flags share names with those of other commands
+		return viper.BindPFlags(cmd.Flags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errMissingMacaroonOrg
+		}
+		encMacaroon, err := mintMacaroon(clogging.NewDevLogger(getLogLevel()), args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(encMacaroon)
+		return nil
+	},
+}
+
+func init() {
+	librarianCmd.AddCommand(macaroonCmd)
+	macaroonCmd.AddCommand(mintMacaroonCmd)
+
+	mintMacaroonCmd.Flags().String(macaroonRootKeyFlag, "",
+		"[sensitive] hex value of the root key the librarians verify macaroons with")
+	mintMacaroonCmd.Flags().StringSlice(macaroonOpsFlag,
+		[]string{client.MacaroonOpGet, client.MacaroonOpPut},
+		"operations the macaroon authorizes, from get, put, and share")
+	mintMacaroonCmd.Flags().Duration(macaroonExpiresInFlag, 0,
+		"how long until the macaroon expires, with 0 meaning never")
+
+	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
+	viper.AutomaticEnv()             // read in environment variables that match
+}
+
+// mintMacaroon returns a new encoded macaroon issued with the root key given by the flags
+// authorizing the organization with the given public key hex to perform the operations given by
+// the flags.
+func mintMacaroon(logger *zap.Logger, orgPubKeyHex string) (string, error) {
+	rootKey, err := getMacaroonRootKey(logger)
+	if err != nil {
+		return "", err
+	}
+	if rootKey == nil {
+		logger.Error("macaroon root key must be set")
+		return "", errMissingMacaroonRootKey
+	}
+	orgPubKey, err := hex.DecodeString(strings.TrimSpace(orgPubKeyHex))
+	if err != nil {
+		logger.Error("fatal error parsing organization public key hex")
+		return "", err
+	}
+	if _, err = ecid.FromPublicKeyBytes(orgPubKey); err != nil {
+		logger.Error("unable to construct organization public key")
+		return "", err
+	}
+	ops := viper.GetStringSlice(macaroonOpsFlag)
+	for _, op := range ops {
+		switch op {
+		case client.MacaroonOpGet, client.MacaroonOpPut, client.MacaroonOpShare:
+		default:
+			logger.Error("unknown macaroon operation", zap.String(macaroonOpsFlag, op))
+			return "", errInvalidMacaroonOp
+		}
+	}
+	var expiresAt time.Time
+	if expiresIn := viper.GetDuration(macaroonExpiresInFlag); expiresIn > 0 {
+		expiresAt = time.Now().Add(expiresIn)
+	}
+	return client.NewMacaroonToken(rootKey, orgPubKey, ops, expiresAt)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestMintMacaroonCmd_err(t *testing.T) {
+	assert.Equal(t, errMissingMacaroonOrg, mintMacaroonCmd.RunE(mintMacaroonCmd, []string{}))
+}
+
+func TestMintMacaroon_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	lg := zap.NewNop()
+	orgID := ecid.NewPseudoRandom(rng)
+	rootKey := []byte("some root key")
+	viper.Set(macaroonRootKeyFlag, hex.EncodeToString(rootKey))
+	viper.Set(macaroonOpsFlag, []string{client.MacaroonOpShare})
+	viper.Set(macaroonExpiresInFlag, time.Hour)
+	defer func() {
+		viper.Set(macaroonRootKeyFlag, "")
+		viper.Set(macaroonOpsFlag, nil)
+		viper.Set(macaroonExpiresInFlag, 0)
+	}()
+
+	encMacaroon, err := mintMacaroon(lg, hex.EncodeToString(orgID.PublicKeyBytes()))
+	assert.Nil(t, err)
+	mv := client.NewMacaroonVerifier(rootKey)
+	assert.Nil(t, mv.Verify(encMacaroon, client.MacaroonOpShare, orgID.PublicKeyBytes()))
+	assert.Equal(t, client.ErrMacaroonOp,
+		mv.Verify(encMacaroon, client.MacaroonOpPut, orgID.PublicKeyBytes()))
+	m, err := client.DecodeMacaroon(encMacaroon)
+	assert.Nil(t, err)
+	assert.Len(t, m.Caveats, 3) // org, ops, and expires
+}
+
+func TestMintMacaroon_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	lg := zap.NewNop()
+	orgPubKeyHex := hex.EncodeToString(ecid.NewPseudoRandom(rng).PublicKeyBytes())
+	defer func() {
+		viper.Set(macaroonRootKeyFlag, "")
+		viper.Set(macaroonOpsFlag, nil)
+	}()
+
+	// missing root key
+	viper.Set(macaroonRootKeyFlag, "")
+	_, err := mintMacaroon(lg, orgPubKeyHex)
+	assert.Equal(t, errMissingMacaroonRootKey, err)
+
+	// bad root key
+	viper.Set(macaroonRootKeyFlag, "not hex")
+	_, err = mintMacaroon(lg, orgPubKeyHex)
+	assert.NotNil(t, err)
+
+	// bad org public keys
+	viper.Set(macaroonRootKeyFlag, "0a1b2c")
+	_, err = mintMacaroon(lg, "not hex")
+	assert.NotNil(t, err)
+	_, err = mintMacaroon(lg, "0a1b2c")
+	assert.NotNil(t, err)
+
+	// unknown op
+	viper.Set(macaroonOpsFlag, []string{client.MacaroonOpGet, "delete"})
+	_, err = mintMacaroon(lg, orgPubKeyHex)
+	assert.Equal(t, errInvalidMacaroonOp, err)
+}
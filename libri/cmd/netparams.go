@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	maintainerKeyFlag      = "maintainerKey"
+	nReplicasFlag          = "nReplicas"
+	maxPageSizeFlag        = "maxPageSize"
+	requesterPeerRatesFlag = "requesterPeerRates"
+	requesterOrgRatesFlag  = "requesterOrgRates"
+	logOperation           = "operation"
+)
+
+var (
+	errMissingMaintainerKey   = errors.New("missing network maintainer private key")
+	errInvalidRequesterRate   = errors.New("invalid requester rate")
+	errUnexpectedPutRequestID = errors.New("unexpected Put response request ID")
+)
+
+// netParamsCmd represents the librarian netparams command
+var netParamsCmd = &cobra.Command{
+	Use:   "netparams",
+	Short: "manage the network parameters recommended to librarians and authors",
+}
+
+// publishNetParamsCmd represents the librarian netparams publish command
+var publishNetParamsCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "sign and publish the network parameters, replacing any previously published",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// bind here rather than in init since the flags share names with those of other commands
+		return viper.BindPFlags(cmd.Flags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newNetParamsPublisher()
+		if err != nil {
+			return err
+		}
+		params, err := getNetworkParameters(p.logger)
+		if err != nil {
+			return err
+		}
+		return p.publish(params)
+	},
+}
+
+func init() {
+	librarianCmd.AddCommand(netParamsCmd)
+	netParamsCmd.AddCommand(publishNetParamsCmd)
+
+	publishNetParamsCmd.Flags().String(librarianAddrFlag, "",
+		"address of the librarian to publish the parameters to")
+	publishNetParamsCmd.Flags().String(maintainerKeyFlag, "",
+		"[sensitive] hex value of the network maintainer's private key")
+	publishNetParamsCmd.Flags().Int(timeoutFlag, 5,
+		"timeout (secs) of librarian requests")
+	publishNetParamsCmd.Flags().Uint32(nReplicasFlag, 0,
+		"recommended number of replicas stored of each document (0 leaves unchanged)")
+	publishNetParamsCmd.Flags().Uint32(maxPageSizeFlag, 0,
+		"recommended maximum size (in bytes) of a page (0 leaves unchanged)")
+	publishNetParamsCmd.Flags().StringSlice(workerPoolSizesFlag, nil,
+		"recommended number of workers handling requests to each endpoint, e.g., "+
+			"Find=64,Put=16")
+	publishNetParamsCmd.Flags().StringSlice(requesterPeerRatesFlag, nil,
+		"recommended max requests per second from each peer to each endpoint, e.g., "+
+			"Find=64,Store=16")
+	publishNetParamsCmd.Flags().StringSlice(requesterOrgRatesFlag, nil,
+		"recommended max requests per second from each org-signing peer to each endpoint, e.g., "+
+			"Find=512,Store=128")
+
+	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
+	viper.AutomaticEnv()             // read in environment variables that match
+}
+
+// netParamsPublisher signs and publishes the network parameters via a librarian's Put endpoint.
+type netParamsPublisher struct {
+	lc           api.Putter
+	maintainerID ecid.ID
+	timeout      time.Duration
+	logger       *zap.Logger
+}
+
+func newNetParamsPublisher() (*netParamsPublisher, error) {
+	logger := clogging.NewDevLogger(getLogLevel())
+	maintainerID, err := getPrivateKeyID(logger, maintainerKeyFlag, "network maintainer")
+	if err != nil {
+		return nil, err
+	}
+	if maintainerID == nil {
+		logger.Error("network maintainer private key must be set")
+		return nil, errMissingMaintainerKey
+	}
+	clients, err := newClientPool()
+	if err != nil {
+		return nil, err
+	}
+	lc, err := clients.Get(viper.GetString(librarianAddrFlag))
+	if err != nil {
+		return nil, err
+	}
+	return &netParamsPublisher{
+		lc:           lc,
+		maintainerID: maintainerID,
+		timeout:      time.Duration(viper.GetInt(timeoutFlag) * 1e9),
+		logger:       logger,
+	}, nil
+}
+
+func (p *netParamsPublisher) publish(params *api.NetworkParameters) error {
+	doc, err := client.NewNetworkParametersDocument(
+		client.NewECDSASigner(p.maintainerID.Key()), p.maintainerID.PublicKeyBytes(), params)
+	if err != nil {
+		return err
+	}
+	key, err := api.GetKey(doc)
+	if err != nil {
+		return err
+	}
+
+	// the maintainer key only signs the parameters, so Put with a throwaway client ID
+	clientID := ecid.NewRandom()
+	rq := client.NewPutRequest(clientID, nil, key, doc)
+	ctx, cancel, err := client.NewSignedTimeoutContext(
+		client.NewECDSASigner(clientID.Key()), client.NewEmptySigner(), rq, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	rp, err := p.lc.Put(ctx, rq)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rq.Metadata.RequestId, rp.Metadata.RequestId) {
+		return errUnexpectedPutRequestID
+	}
+	p.logger.Info("published network parameters",
+		zap.Stringer(logKey, key),
+		zap.Stringer(logOperation, rp.Operation),
+		zap.Int64("issued_time", params.IssuedTime),
+	)
+	return nil
+}
+
+// getNetworkParameters returns the network parameters given by the flags, issued now.
+func getNetworkParameters(logger *zap.Logger) (*api.NetworkParameters, error) {
+	workerPoolSizes, err := getRecommendedWorkerPoolSizes(logger)
+	if err != nil {
+		return nil, err
+	}
+	peerRates, err := getRecommendedRequesterRates(logger, requesterPeerRatesFlag)
+	if err != nil {
+		return nil, err
+	}
+	orgRates, err := getRecommendedRequesterRates(logger, requesterOrgRatesFlag)
+	if err != nil {
+		return nil, err
+	}
+	return &api.NetworkParameters{
+		NReplicas:          uint32(viper.GetInt(nReplicasFlag)),
+		MaxPageSize:        uint32(viper.GetInt(maxPageSizeFlag)),
+		WorkerPoolSizes:    workerPoolSizes,
+		RequesterPeerRates: peerRates,
+		RequesterOrgRates:  orgRates,
+		IssuedTime:         time.Now().Unix(),
+	}, nil
+}
+
+func getRecommendedWorkerPoolSizes(logger *zap.Logger) (map[string]uint32, error) {
+	sizes := make(map[string]uint32)
+	for _, sizeStr := range viper.GetStringSlice(workerPoolSizesFlag) {
+		pair := strings.SplitN(strings.TrimSpace(sizeStr), "=", 2)
+		if len(pair) != 2 {
+			logger.Error("worker pool size must have form Endpoint=size",
+				zap.String(workerPoolSizesFlag, sizeStr))
+			return nil, errInvalidWorkerPoolSize
+		}
+		if _, ok := parseEndpoint(pair[0]); !ok {
+			logger.Error("unknown worker pool size endpoint",
+				zap.String(workerPoolSizesFlag, sizeStr))
+			return nil, errInvalidWorkerPoolSize
+		}
+		size, err := strconv.ParseUint(pair[1], 10, 32)
+		if err != nil {
+			logger.Error("worker pool size must be a non-negative integer",
+				zap.String(workerPoolSizesFlag, sizeStr))
+			return nil, errInvalidWorkerPoolSize
+		}
+		sizes[pair[0]] = uint32(size)
+	}
+	return sizes, nil
+}
+
+func getRecommendedRequesterRates(logger *zap.Logger, flag string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, rateStr := range viper.GetStringSlice(flag) {
+		pair := strings.SplitN(strings.TrimSpace(rateStr), "=", 2)
+		if len(pair) != 2 {
+			logger.Error("requester rate must have form Endpoint=rate",
+				zap.String(flag, rateStr))
+			return nil, errInvalidRequesterRate
+		}
+		if _, ok := parseEndpoint(pair[0]); !ok {
+			logger.Error("unknown requester rate endpoint", zap.String(flag, rateStr))
+			return nil, errInvalidRequesterRate
+		}
+		rate, err := strconv.ParseFloat(pair[1], 64)
+		if err != nil || rate <= 0 {
+			logger.Error("requester rate must be positive", zap.String(flag, rateStr))
+			return nil, errInvalidRequesterRate
+		}
+		rates[pair[0]] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestNetParamsPublisher_publish_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	maintainerID := ecid.NewPseudoRandom(rng)
+	lc := &fixedNetParamsPutter{}
+	p := &netParamsPublisher{
+		lc:           lc,
+		maintainerID: maintainerID,
+		timeout:      time.Second,
+		logger:       zap.NewNop(),
+	}
+	params := &api.NetworkParameters{
+		NReplicas:          5,
+		RequesterPeerRates: map[string]float64{api.Find.String(): 32},
+		IssuedTime:         time.Now().Unix(),
+	}
+	assert.Nil(t, p.publish(params))
+
+	pubKey := maintainerID.PublicKeyBytes()
+	assert.Equal(t, client.GetNetworkParametersKey(pubKey).Bytes(), lc.rq.Key)
+	published, err := client.ParseNetworkParametersDocument(client.NewVerifier(), pubKey,
+		lc.rq.Value)
+	assert.Nil(t, err)
+	assert.Equal(t, params, published)
+}
+
+func TestNetParamsPublisher_publish_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	newPublisher := func(lc api.Putter) *netParamsPublisher {
+		return &netParamsPublisher{
+			lc:           lc,
+			maintainerID: ecid.NewPseudoRandom(rng),
+			timeout:      time.Second,
+			logger:       zap.NewNop(),
+		}
+	}
+
+	// check Put error bubbles up
+	p := newPublisher(&fixedNetParamsPutter{err: errors.New("some Put error")})
+	assert.NotNil(t, p.publish(&api.NetworkParameters{}))
+
+	// check unexpected response request ID
+	p = newPublisher(&fixedNetParamsPutter{requestID: []byte{1, 2, 3}})
+	assert.Equal(t, errUnexpectedPutRequestID, p.publish(&api.NetworkParameters{}))
+}
+
+func TestGetNetworkParameters(t *testing.T) {
+	lg := zap.NewNop()
+	viper.Set(nReplicasFlag, 5)
+	viper.Set(maxPageSizeFlag, 1024)
+	viper.Set(workerPoolSizesFlag, []string{"Find=8", "Put=0"})
+	viper.Set(requesterPeerRatesFlag, []string{"Find=32"})
+	viper.Set(requesterOrgRatesFlag, []string{"Store=128.5"})
+
+	params, err := getNetworkParameters(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(5), params.NReplicas)
+	assert.Equal(t, uint32(1024), params.MaxPageSize)
+	assert.Equal(t, map[string]uint32{"Find": 8, "Put": 0}, params.WorkerPoolSizes)
+	assert.Equal(t, map[string]float64{"Find": 32}, params.RequesterPeerRates)
+	assert.Equal(t, map[string]float64{"Store": 128.5}, params.RequesterOrgRates)
+	assert.True(t, params.IssuedTime > 0)
+
+	// bad worker pool sizes
+	for _, bad := range []string{"Find", "Unknown=4", "Find=many", "Find=-1"} {
+		viper.Set(workerPoolSizesFlag, []string{bad})
+		params, err = getNetworkParameters(lg)
+		assert.Nil(t, params)
+		assert.Equal(t, errInvalidWorkerPoolSize, err)
+	}
+	viper.Set(workerPoolSizesFlag, []string{})
+
+	// bad requester rates
+	for _, flag := range []string{requesterPeerRatesFlag, requesterOrgRatesFlag} {
+		for _, bad := range []string{"Find", "Unknown=4", "Find=many", "Find=0"} {
+			viper.Set(flag, []string{bad})
+			params, err = getNetworkParameters(lg)
+			assert.Nil(t, params)
+			assert.Equal(t, errInvalidRequesterRate, err)
+		}
+		viper.Set(flag, []string{})
+	}
+
+	viper.Set(nReplicasFlag, 0)
+	viper.Set(maxPageSizeFlag, 0)
+}
+
+type fixedNetParamsPutter struct {
+	rq        *api.PutRequest
+	requestID []byte
+	err       error
+}
+
+func (f *fixedNetParamsPutter) Put(
+	ctx context.Context, in *api.PutRequest, opts ...grpc.CallOption,
+) (*api.PutResponse, error) {
+	f.rq = in
+	if f.err != nil {
+		return nil, f.err
+	}
+	requestID := in.Metadata.RequestId
+	if f.requestID != nil {
+		requestID = f.requestID
+	}
+	return &api.PutResponse{
+		Metadata:  &api.ResponseMetadata{RequestId: requestID},
+		Operation: api.PutOperation_STORED,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/server"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	logKey              = "key"
+	logNSeeds           = "n_seeds"
+	logSeed             = "seed"
+	logRank             = "rank"
+	logPeerID           = "peer_id"
+	logAddress          = "address"
+	logQueried          = "queried"
+	logStoreTo          = "store_to"
+	logSelfAmongClosest = "self_among_closest"
+)
+
+var errMissingKey = errors.New("missing key")
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan <key>",
+	Short: "show the peers in the local routing table a search or store of a key would use",
+	Long: "show the peers in the local routing table a search or store of a key would use, " +
+		"without making any network calls; the librarian using the data directory must be " +
+		"stopped first, since it holds the DB lock",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errMissingKey
+		}
+		config := server.NewDefaultConfig().
+			WithDataDir(viper.GetString(dataDirFlag)).
+			WithDefaultDBDir(). // depends on DataDir
+			WithDBDriver(viper.GetString(dbDriverFlag))
+		pr := &planPrinter{
+			lp:     server.LoadPlan,
+			logger: clogging.NewDevLogger(getLogLevel()),
+		}
+		return pr.print(config, args[0])
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(planCmd)
+}
+
+type planPrinter struct {
+	lp     func(config *server.Config, key id.ID) (*server.Plan, error)
+	logger *zap.Logger
+}
+
+func (pr *planPrinter) print(config *server.Config, keyStr string) error {
+	if keyStr == "" {
+		return errMissingKey
+	}
+	key, err := id.FromString(keyStr)
+	if err != nil {
+		return err
+	}
+	plan, err := pr.lp(config, key)
+	if err != nil {
+		return err
+	}
+	queried := peerIDSet(plan.Queried)
+	storeTo := peerIDSet(plan.StoreTo)
+	for i, p := range plan.Seeds {
+		_, isQueried := queried[p.ID().String()]
+		_, isStoreTo := storeTo[p.ID().String()]
+		pr.logger.Info(logSeed,
+			zap.Int(logRank, i),
+			zap.Stringer(logPeerID, p.ID()),
+			zap.Stringer(logAddress, p.Address()),
+			zap.Bool(logQueried, isQueried),
+			zap.Bool(logStoreTo, isStoreTo),
+		)
+	}
+	pr.logger.Info("planned search and store",
+		zap.Stringer(logKey, key),
+		zap.Int(logNSeeds, len(plan.Seeds)),
+		zap.Bool(logSelfAmongClosest, plan.SelfAmongClosest),
+	)
+	return nil
+}
+
+func peerIDSet(peers []peer.Peer) map[string]struct{} {
+	ids := make(map[string]struct{}, len(peers))
+	for _, p := range peers {
+		ids[p.ID().String()] = struct{}{}
+	}
+	return ids
+}
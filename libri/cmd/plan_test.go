@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestPlanCmd_err(t *testing.T) {
+	err := planCmd.RunE(planCmd, []string{})
+	assert.Equal(t, errMissingKey, err)
+}
+
+func TestPlanPrinter_print_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	seeds := peer.NewTestPeers(rng, 4)
+	pr := &planPrinter{
+		lp: func(config *server.Config, key id.ID) (*server.Plan, error) {
+			return &server.Plan{
+				Key:     key,
+				Seeds:   seeds,
+				Queried: seeds[:1],
+				StoreTo: seeds[:3],
+			}, nil
+		},
+		logger: zap.NewNop(),
+	}
+	assert.Nil(t, pr.print(server.NewDefaultConfig(), id.LowerBound.String()))
+}
+
+func TestPlanPrinter_print_err(t *testing.T) {
+	pr := &planPrinter{
+		lp: func(config *server.Config, key id.ID) (*server.Plan, error) {
+			return nil, errors.New("some load error")
+		},
+		logger: zap.NewNop(),
+	}
+
+	// should error on missing key
+	assert.Equal(t, errMissingKey, pr.print(server.NewDefaultConfig(), ""))
+
+	// should error on bad key
+	assert.NotNil(t, pr.print(server.NewDefaultConfig(), "0"))
+
+	// error loading plan should bubble up
+	assert.NotNil(t, pr.print(server.NewDefaultConfig(), id.LowerBound.String()))
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	lauthor "github.com/drausin/libri/libri/author"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	errMissingEntryKey = errors.New("missing entry key")
+	errRepairFailed    = errors.New("some documents could not be repaired")
+)
+
+// repairCmd represents the repair command
+var repairCmd = &cobra.Command{
+	Use:   "repair <entryKey>",
+	Short: "verify an entry's pages across a Libri network and re-store any under-replicated",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errMissingEntryKey
+		}
+		return newEntryRepairer().repair(args[0])
+	},
+}
+
+func init() {
+	authorCmd.AddCommand(repairCmd)
+}
+
+type entryRepairer interface {
+	repair(entryKeyStr string) error
+}
+
+func newEntryRepairer() entryRepairer {
+	return &entryRepairerImpl{
+		ag: newAuthorGetter(),
+		ar: &authorRepairerImpl{},
+		kc: &keychainsGetterImpl{
+			pg: &terminalPassphraseGetter{},
+		},
+	}
+}
+
+type entryRepairerImpl struct {
+	ag authorGetter
+	ar authorRepairer
+	kc keychainsGetter
+}
+
+func (r *entryRepairerImpl) repair(entryKeyStr string) error {
+	if entryKeyStr == "" {
+		return errMissingEntryKey
+	}
+	entryKey, err := id.FromString(entryKeyStr)
+	if err != nil {
+		return err
+	}
+	authorKeys, selfReaderKeys, err := r.kc.get()
+	if err != nil {
+		return err
+	}
+	author, logger, err := r.ag.get(authorKeys, selfReaderKeys)
+	if err != nil {
+		return err
+	}
+	logger.Info("repairing entry", zap.Stringer("entry_key", entryKey))
+	report, err := r.ar.repair(author, entryKey)
+	if err != nil {
+		return err
+	}
+	nFailed := 0
+	for _, dr := range report.Docs {
+		fields := []zap.Field{
+			zap.Stringer("key", dr.Key),
+			zap.Stringer("outcome", dr.Outcome),
+			zap.Int("n_replicas", dr.NReplicas),
+			zap.Int("n_stored", dr.NStored),
+		}
+		switch dr.Outcome {
+		case lauthor.Healthy, lauthor.Repaired:
+			logger.Info("document replication", fields...)
+		default:
+			nFailed++
+			logger.Error("document replication", append(fields, zap.Error(dr.Err))...)
+		}
+	}
+	if nFailed > 0 {
+		return errRepairFailed
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	lauthor "github.com/drausin/libri/libri/author"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairCmd_err(t *testing.T) {
+	err := repairCmd.RunE(repairCmd, []string{})
+	assert.Equal(t, errMissingEntryKey, err)
+}
+
+func TestEntryRepairer_repair_ok(t *testing.T) {
+	r := &entryRepairerImpl{
+		ag: &fixedAuthorGetter{
+			logger: logging.NewDevInfoLogger(),
+		},
+		ar: &fixedAuthorRepairer{
+			report: &lauthor.RepairReport{
+				EntryKey: id.LowerBound,
+				Docs: []*lauthor.DocRepair{
+					{Key: id.LowerBound, Outcome: lauthor.Healthy, NReplicas: 3},
+					{Key: id.UpperBound, Outcome: lauthor.Repaired, NReplicas: 1, NStored: 2},
+				},
+			},
+		},
+		kc: &fixedKeychainsGetter{},
+	}
+	err := r.repair(id.LowerBound.String())
+	assert.Nil(t, err)
+}
+
+func TestEntryRepairer_repair_err(t *testing.T) {
+	lg := logging.NewDevInfoLogger()
+
+	// should error on missing entry key
+	r1 := &entryRepairerImpl{}
+	assert.Equal(t, errMissingEntryKey, r1.repair(""))
+
+	// should error on bad entry key
+	r2 := &entryRepairerImpl{}
+	assert.NotNil(t, r2.repair("0"))
+
+	// error getting keychains should bubble up
+	r3 := &entryRepairerImpl{
+		kc: &fixedKeychainsGetter{err: errors.New("some get error")},
+	}
+	assert.NotNil(t, r3.repair(id.LowerBound.String()))
+
+	// error getting author should bubble up
+	r4 := &entryRepairerImpl{
+		ag: &fixedAuthorGetter{err: errors.New("some get error")},
+		kc: &fixedKeychainsGetter{},
+	}
+	assert.NotNil(t, r4.repair(id.LowerBound.String()))
+
+	// error repairing should bubble up
+	r5 := &entryRepairerImpl{
+		ag: &fixedAuthorGetter{logger: lg},
+		ar: &fixedAuthorRepairer{err: errors.New("some repair error")},
+		kc: &fixedKeychainsGetter{},
+	}
+	assert.NotNil(t, r5.repair(id.LowerBound.String()))
+
+	// unrepaired documents should return error
+	r6 := &entryRepairerImpl{
+		ag: &fixedAuthorGetter{logger: lg},
+		ar: &fixedAuthorRepairer{
+			report: &lauthor.RepairReport{
+				EntryKey: id.LowerBound,
+				Docs: []*lauthor.DocRepair{
+					{Key: id.LowerBound, Outcome: lauthor.Healthy, NReplicas: 3},
+					{
+						Key:     id.UpperBound,
+						Outcome: lauthor.Unrepaired,
+						Err:     errors.New("some store error"),
+					},
+				},
+			},
+		},
+		kc: &fixedKeychainsGetter{},
+	}
+	assert.Equal(t, errRepairFailed, r6.repair(id.LowerBound.String()))
+}
+
+type fixedAuthorRepairer struct {
+	report *lauthor.RepairReport
+	err    error
+}
+
+func (f *fixedAuthorRepairer) repair(author *lauthor.Author, entryKey id.ID) (
+	*lauthor.RepairReport, error) {
+	return f.report, f.err
+}
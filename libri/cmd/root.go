@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -12,9 +14,14 @@ import (
 )
 
 const (
-	dataDirFlag  = "dataDir"
-	logLevelFlag = "logLevel"
-	envVarPrefix = "LIBRI"
+	dataDirFlag      = "dataDir"
+	dbDriverFlag     = "dbDriver"
+	logLevelFlag     = "logLevel"
+	tlsCertFileFlag  = "tlsCertFile"
+	tlsKeyFileFlag   = "tlsKeyFile"
+	tlsCAFileFlag    = "tlsCAFile"
+	tlsSPIFFEIDsFlag = "tlsPeerSPIFFEIDs"
+	envVarPrefix     = "LIBRI"
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -34,8 +41,20 @@ func Execute() {
 func init() {
 	RootCmd.PersistentFlags().StringP(dataDirFlag, "d", "",
 		"local data directory")
+	RootCmd.PersistentFlags().String(dbDriverFlag, db.DefaultDriver,
+		fmt.Sprintf("local DB driver, one of %v in this build", db.Drivers()))
 	RootCmd.PersistentFlags().StringP(logLevelFlag, "l", zap.InfoLevel.String(),
 		"log level")
+	RootCmd.PersistentFlags().String(tlsCertFileFlag, "",
+		"path of the PEM certificate presented to librarians requiring mutual TLS (and, when "+
+			"starting one, to other peers), which is reloaded when it changes (empty disables "+
+			"mutual TLS)")
+	RootCmd.PersistentFlags().String(tlsKeyFileFlag, "",
+		"path of the PEM private key of the TLS certificate")
+	RootCmd.PersistentFlags().String(tlsCAFileFlag, "",
+		"path of the PEM CA certificates that librarians' TLS certificates must chain to")
+	RootCmd.PersistentFlags().StringSlice(tlsSPIFFEIDsFlag, nil,
+		"SPIFFE IDs, one of which librarians' TLS certificates must have (empty allows any)")
 
 	// bind viper flags
 	viper.SetEnvPrefix("LIBRI") // look for env vars with "LIBRI_" prefix
@@ -48,3 +67,23 @@ func getLogLevel() zapcore.Level {
 	errors.MaybePanic(ll.Set(viper.GetString(logLevelFlag)))
 	return ll
 }
+
+// getTLSParameters returns the mutual TLS parameters given by the flags.
+func getTLSParameters() *client.TLSParameters {
+	params := client.NewDefaultTLSParameters()
+	params.CertFile = viper.GetString(tlsCertFileFlag)
+	params.KeyFile = viper.GetString(tlsKeyFileFlag)
+	params.CAFile = viper.GetString(tlsCAFileFlag)
+	params.SPIFFEIDs = viper.GetStringSlice(tlsSPIFFEIDsFlag)
+	return params
+}
+
+// newClientPool returns a pool of librarian clients dialing with the mutual TLS given by the
+// flags, if any.
+func newClientPool() (client.Pool, error) {
+	_, clientCreds, err := client.NewTLSCredentials(getTLSParameters())
+	if err != nil {
+		return nil, err
+	}
+	return client.NewDefaultLRUPoolWithInterceptors(client.WithCredentials(nil, clientCreds))
+}
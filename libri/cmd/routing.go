@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	logDepth         = "depth"
+	logLowerBound    = "lower_bound"
+	logUpperBound    = "upper_bound"
+	logContainsSelf  = "contains_self"
+	logNPeers        = "n_peers"
+	logPeerName      = "peer_name"
+	logNSuccesses    = "n_successes"
+	logNErrors       = "n_errors"
+	logLatestSuccess = "latest_success"
+	logLatestError   = "latest_error"
+)
+
+// routingCmd represents the librarian routing command
+var routingCmd = &cobra.Command{
+	Use:   "routing",
+	Short: "print a librarian's routing table buckets and peers",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// bind here rather than in init since the flags share names with those of bansCmd
+		return viper.BindPFlags(cmd.Flags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newRoutingPrinter()
+		if err != nil {
+			return err
+		}
+		return p.print()
+	},
+}
+
+func init() {
+	librarianCmd.AddCommand(routingCmd)
+
+	routingCmd.Flags().String(librarianAddrFlag, "",
+		"address of the librarian to inspect")
+	routingCmd.Flags().String(adminKeyFlag, "",
+		"[sensitive] hex value of the librarian admin's private key")
+	routingCmd.Flags().Int(adminTimeoutFlag, 5,
+		"timeout (secs) of librarian requests")
+
+	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
+	viper.AutomaticEnv()             // read in environment variables that match
+}
+
+// routingPrinter prints a librarian's routing table via its admin API.
+type routingPrinter struct {
+	lc      api.LibrarianClient
+	adminID ecid.ID
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func newRoutingPrinter() (*routingPrinter, error) {
+	logger := clogging.NewDevLogger(getLogLevel())
+	adminID, err := getAdminID(logger)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := newClientPool()
+	if err != nil {
+		return nil, err
+	}
+	lc, err := clients.Get(viper.GetString(librarianAddrFlag))
+	if err != nil {
+		return nil, err
+	}
+	return &routingPrinter{
+		lc:      lc,
+		adminID: adminID,
+		timeout: time.Duration(viper.GetInt(adminTimeoutFlag) * 1e9),
+		logger:  logger,
+	}, nil
+}
+
+func (p *routingPrinter) print() error {
+	rq := client.NewRoutingTableRequest(p.adminID, nil)
+	ctx, cancel, err := client.NewSignedTimeoutContext(
+		client.NewECDSASigner(p.adminID.Key()), client.NewEmptySigner(), rq, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	stream, err := p.lc.RoutingTable(ctx, rq)
+	if err != nil {
+		return err
+	}
+	for {
+		rp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p.printBucket(rp.Bucket)
+	}
+}
+
+func (p *routingPrinter) printBucket(b *api.RoutingBucket) {
+	p.logger.Info("bucket",
+		zap.Uint32(logDepth, b.Depth),
+		zap.String(logLowerBound, id.Hex(b.LowerBound)),
+		zap.String(logUpperBound, id.Hex(b.UpperBound)),
+		zap.Bool(logContainsSelf, b.ContainsSelf),
+		zap.Int(logNPeers, len(b.Peers)),
+	)
+	for _, rp := range b.Peers {
+		fields := []zap.Field{
+			zap.Uint64(logNSuccesses, rp.NResponseSuccesses),
+			zap.Uint64(logNErrors, rp.NResponseErrors),
+		}
+		if rp.Address != nil {
+			fields = append(fields,
+				zap.String(logPeerID, id.Hex(rp.Address.PeerId)),
+				zap.String(logPeerName, rp.Address.PeerName),
+				zap.String(logAddress, net.JoinHostPort(rp.Address.Ip,
+					strconv.Itoa(int(rp.Address.Port)))),
+			)
+		}
+		if rp.LatestSuccessNanos != 0 {
+			fields = append(fields,
+				zap.Time(logLatestSuccess, time.Unix(0, rp.LatestSuccessNanos)))
+		}
+		if rp.LatestErrorNanos != 0 {
+			fields = append(fields, zap.Time(logLatestError, time.Unix(0, rp.LatestErrorNanos)))
+		}
+		p.logger.Info("peer", fields...)
+	}
+}
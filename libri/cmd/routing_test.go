@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestRoutingPrinter_print_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	lc := &fixedRoutingClient{
+		buckets: []*api.RoutingBucket{
+			{Depth: 1, ContainsSelf: true},
+			{
+				Depth: 1,
+				Peers: []*api.RoutingPeer{
+					{
+						Address: &api.PeerAddress{
+							PeerId: id.NewPseudoRandom(rng).Bytes(),
+							Ip:     "1.2.3.4",
+							Port:   20100,
+						},
+						NResponseSuccesses: 2,
+						LatestSuccessNanos: time.Now().UnixNano(),
+					},
+					{NResponseErrors: 1, LatestErrorNanos: time.Now().UnixNano()},
+				},
+			},
+		},
+	}
+	p := &routingPrinter{
+		lc:      lc,
+		adminID: ecid.NewPseudoRandom(rng),
+		timeout: time.Second,
+		logger:  zap.NewNop(),
+	}
+	assert.Nil(t, p.print())
+	assert.NotNil(t, lc.rq)
+}
+
+func TestRoutingPrinter_print_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	newPrinter := func(lc api.LibrarianClient) *routingPrinter {
+		return &routingPrinter{
+			lc:      lc,
+			adminID: ecid.NewPseudoRandom(rng),
+			timeout: time.Second,
+			logger:  zap.NewNop(),
+		}
+	}
+
+	// check RoutingTable error bubbles up
+	p := newPrinter(&fixedRoutingClient{err: errors.New("some RoutingTable error")})
+	assert.NotNil(t, p.print())
+
+	// check Recv error bubbles up
+	p = newPrinter(&fixedRoutingClient{recvErr: errors.New("some Recv error")})
+	assert.NotNil(t, p.print())
+}
+
+type fixedRoutingClient struct {
+	api.LibrarianClient
+	rq      *api.RoutingTableRequest
+	buckets []*api.RoutingBucket
+	err     error
+	recvErr error
+}
+
+func (f *fixedRoutingClient) RoutingTable(
+	ctx context.Context, in *api.RoutingTableRequest, opts ...grpc.CallOption,
+) (api.Librarian_RoutingTableClient, error) {
+	f.rq = in
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &fixedRoutingTableClient{buckets: f.buckets, err: f.recvErr}, nil
+}
+
+type fixedRoutingTableClient struct {
+	grpc.ClientStream
+	buckets []*api.RoutingBucket
+	err     error
+}
+
+func (f *fixedRoutingTableClient) Recv() (*api.RoutingTableResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.buckets) == 0 {
+		return nil, io.EOF
+	}
+	next := f.buckets[0]
+	f.buckets = f.buckets[1:]
+	return &api.RoutingTableResponse{Bucket: next}, nil
+}
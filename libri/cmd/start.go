@@ -1,20 +1,29 @@
 package cmd
 
 import (
+	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/drausin/libri/libri/common/ecid"
-	"github.com/drausin/libri/libri/common/errors"
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
 	clogging "github.com/drausin/libri/libri/common/logging"
 	"github.com/drausin/libri/libri/common/parse"
 	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/server"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/introduce"
 	"github.com/drausin/libri/libri/librarian/server/replicate"
 	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -22,24 +31,69 @@ import (
 
 const (
 	bootstrapsFlag        = "bootstraps"
+	seedHostsFlag         = "seedHosts"
 	localPortFlag         = "localPort"
 	localMetricsPortFlag  = "localMetricsPort"
 	localProfilerPortFlag = "localProfilerPort"
 	publicHostFlag        = "publicHost"
 	publicNameFlag        = "publicName"
 	publicPortFlag        = "publicPort"
+	altPublicAddrsFlag    = "altPublicAddrs"
+	zoneFlag              = "zone"
 	nSubscriptionsFlag    = "nSubscriptions"
 	fpRateFlag            = "fpRate"
 	profileFlag           = "profile"
 	maxBucketPeersFlag    = "maxRoutingBucketPeers"
+	maxFailuresFlag       = "maxConsecutiveFailures"
 	verifyIntervalFlag    = "verifyInterval"
+	verifySampleRateFlag  = "verifySampleRate"
+	replicateIntervalFlag = "replicateInterval"
+	maxNReplicasFlag      = "maxNReplicas"
 	organizationIDFlag    = "organizationID"
+	storeAuthPubKeyFlag   = "storeAuthPubKey"
+	storeAuthTokenFlag    = "storeAuthToken"
+	macaroonRootKeyFlag   = "macaroonRootKey"
+	peerMacaroonFlag      = "peerMacaroon"
+	adminPubKeyFlag       = "adminPubKey"
+	netParamsPubKeyFlag   = "networkParamsPubKey"
+	traceSampleRatesFlag  = "traceSampleRates"
+	storageHookCmdFlag    = "storageHookCommand"
+	workerPoolSizesFlag   = "workerPoolSizes"
+	coldDBDirFlag         = "coldDBDir"
+	demoteAfterFlag       = "demoteAfter"
+	nDataShardsFlag       = "nDataShards"
+	nParityShardsFlag     = "nParityShards"
+	storeSpilloverFlag    = "storeSpillover"
+	storeChallengeFlag    = "storeChallengeLength"
+	storeHintedFlag       = "storeHintedHandoff"
+	getReadRepairFlag     = "getReadRepair"
+	latencyWeightedFlag   = "getLatencyWeighted"
+	mirrorAuthorsFlag     = "mirrorAuthors"
+	orgQuotaBytesFlag     = "orgQuotaBytes"
+	syncIntervalFlag      = "syncInterval"
+	probeIntervalFlag     = "probeInterval"
+	probeNPeersFlag       = "probeNPeers"
+	probeJitterFlag       = "probeJitter"
+	minReadyPeersFlag     = "minReadyPeers"
+	throttleRateFlag      = "throttleRate"
+	throttleBurstFlag     = "throttleBurst"
+	throttleQueueFlag     = "throttleQueue"
+	trustedPeersFlag      = "trustedPeers"
+	puzzleDifficultyFlag  = "puzzleDifficulty"
+	allowListFileFlag     = "allowListFile"
+	storeTypeReplicasFlag = "storeTypeReplicas"
 
 	logLocalPort        = "localPort"
 	logLocalMetricsPort = "localMetricsPort"
 	logPublicAddr       = "publicAddr"
 )
 
+var (
+	errInvalidTraceSampleRate = errors.New("invalid trace sample rate")
+	errInvalidWorkerPoolSize  = errors.New("invalid worker pool size")
+	errInvalidTypeReplicas    = errors.New("invalid document type replicas")
+)
+
 // startLibrarianCmd represents the librarian start command
 var startLibrarianCmd = &cobra.Command{
 	Use:   "start",
@@ -67,10 +121,16 @@ func init() {
 		"public host (IPv4 or URL)")
 	startLibrarianCmd.Flags().IntP(publicPortFlag, "p", server.DefaultPort,
 		"public port")
+	startLibrarianCmd.Flags().StringSlice(altPublicAddrsFlag, nil,
+		"comma-separated additional public addresses (IP:Port), e.g., for dual-stack peers")
 	startLibrarianCmd.Flags().StringSliceP(bootstrapsFlag, "b", nil,
 		"comma-separated addresses (IPv4:Port) of bootstrap peers")
+	startLibrarianCmd.Flags().StringSlice(seedHostsFlag, nil,
+		"comma-separated DNS hostnames whose TXT/SRV records list additional bootstrap peers")
 	startLibrarianCmd.Flags().StringP(publicNameFlag, "n", "",
 		"public peer name")
+	startLibrarianCmd.Flags().String(zoneFlag, "",
+		"failure domain (e.g., zone or region) of the peer, across which replicas are spread")
 	startLibrarianCmd.Flags().IntP(nSubscriptionsFlag, "s", subscribe.DefaultNSubscriptionsTo,
 		"number of active subscriptions to other peers to maintain")
 	startLibrarianCmd.Flags().Float32P(fpRateFlag, "f", subscribe.DefaultFPRate,
@@ -79,15 +139,110 @@ func init() {
 		"enable /debug/pprof profiler endpoint")
 	startLibrarianCmd.Flags().Uint(maxBucketPeersFlag, routing.DefaultMaxActivePeers,
 		"max number of peers allowed in a routing table bucket")
+	startLibrarianCmd.Flags().Uint(maxFailuresFlag, routing.DefaultMaxConsecutiveFailures,
+		"number of consecutive failed queries after which a peer is evicted from the routing "+
+			"table (0 disables eviction)")
 	startLibrarianCmd.Flags().Duration(verifyIntervalFlag, replicate.DefaultVerifyInterval,
 		"verify interval duration")
+	startLibrarianCmd.Flags().Float32(verifySampleRateFlag, replicate.DefaultSampleRate,
+		"fraction of stored documents verified on each verify pass")
+	startLibrarianCmd.Flags().Duration(replicateIntervalFlag, replicate.DefaultReplicateInterval,
+		"minimum duration between stores of under-replicated documents (0 disables the limit)")
+	startLibrarianCmd.Flags().Uint(maxNReplicasFlag, replicate.DefaultMaxNReplicas,
+		"maximum number of replicas per document when tuning replicas to peer churn "+
+			"(0 disables tuning)")
 	startLibrarianCmd.Flags().String(organizationIDFlag, "",
 		"[sensitive] hex value of organization ID private key")
+	startLibrarianCmd.Flags().String(storeAuthPubKeyFlag, "",
+		"hex value of the operator public key whose store authorization tokens are required "+
+			"for Store and Put requests")
+	startLibrarianCmd.Flags().String(storeAuthTokenFlag, "",
+		"[sensitive] store authorization token to send with Store requests to other peers")
+	startLibrarianCmd.Flags().String(macaroonRootKeyFlag, "",
+		"[sensitive] hex value of the root key with which the macaroons required of authors' "+
+			"Get, Put, Store, and value-returning Find requests are issued (empty disables)")
+	startLibrarianCmd.Flags().String(peerMacaroonFlag, "",
+		"[sensitive] macaroon to send with Store and Find requests to other peers")
+	startLibrarianCmd.Flags().String(adminPubKeyFlag, "",
+		"hex value of the operator public key allowed to make admin requests, e.g., for the "+
+			"routing table contents")
+	startLibrarianCmd.Flags().String(netParamsPubKeyFlag, "",
+		"hex value of the network maintainer public key whose published network parameters "+
+			"are fetched from the bootstrap peers and adopted on startup")
+	startLibrarianCmd.Flags().StringSlice(traceSampleRatesFlag, nil,
+		"fraction of requests traced for each endpoint, e.g., Store=1.0,Find=0.01, overriding "+
+			"the defaults for the given endpoints")
+	startLibrarianCmd.Flags().StringSlice(workerPoolSizesFlag, nil,
+		"number of workers handling requests to each endpoint, e.g., Find=64,Put=16, "+
+			"overriding the defaults for the given endpoints, with 0 meaning unbounded")
+	startLibrarianCmd.Flags().String(storageHookCmdFlag, "",
+		"command run on each document storage event with the event (put, delete, or "+
+			"verify_fail) and hex document key appended as arguments")
+	startLibrarianCmd.Flags().String(coldDBDirFlag, "",
+		"local directory, typically on a slower volume, of the cold tier DB holding documents "+
+			"not accessed recently (empty disables tiering)")
+	startLibrarianCmd.Flags().Duration(demoteAfterFlag, server.DefaultTieringDemoteAfter,
+		"duration after which documents not accessed are demoted to the cold tier")
+	startLibrarianCmd.Flags().Uint(nDataShardsFlag, store.DefaultNDataShards,
+		"number of data shards large documents are split into when stored in erasure-coded "+
+			"mode instead of as full replicas (0 disables erasure coding)")
+	startLibrarianCmd.Flags().Uint(nParityShardsFlag, store.DefaultNParityShards,
+		"number of parity shards, i.e., shards that may be lost, in erasure-coded mode")
+	startLibrarianCmd.Flags().Bool(storeSpilloverFlag, store.DefaultSpillover,
+		"continue storing to peers beyond the closest set when too many of the closest peers "+
+			"error")
+	startLibrarianCmd.Flags().Uint(storeChallengeFlag, store.DefaultChallengeLength,
+		"length of the random slice of each stored document peers must return the MAC of to "+
+			"prove they persisted it (0 disables challenges)")
+	startLibrarianCmd.Flags().Bool(storeHintedFlag, store.DefaultHintedHandoff,
+		"store documents for unreachable peers on other peers, which hand them off to the "+
+			"unreachable peers when they return")
+	startLibrarianCmd.Flags().StringSlice(storeTypeReplicasFlag, nil,
+		"number of replicas stored of each document type, e.g., envelope=5,page=3, overriding "+
+			"the default for the given types")
+	startLibrarianCmd.Flags().Bool(getReadRepairFlag, search.DefaultReadRepair,
+		"re-store documents found by Get requests with the closest peers missing them")
+	startLibrarianCmd.Flags().Bool(latencyWeightedFlag, search.DefaultLatencyWeighted,
+		"query the peers with the lowest recent latencies first among equally close peers "+
+			"when searching for Get requests")
+	startLibrarianCmd.Flags().StringSlice(mirrorAuthorsFlag, nil,
+		"hex values of the author public keys whose publications are replicated and served in "+
+			"read-only mirror mode, rejecting Store and Put requests (empty disables mirror mode)")
+	startLibrarianCmd.Flags().Uint64(orgQuotaBytesFlag, server.DefaultOrgQuotaLimitBytes,
+		"maximum number of bytes each organization may store, unless the admin sets a limit "+
+			"for it (0 means unlimited)")
+	startLibrarianCmd.Flags().Duration(syncIntervalFlag, server.DefaultAntiEntropyInterval,
+		"interval between syncs of the stored keys with the nearest neighbors, pulling the "+
+			"documents missing locally (0 disables syncing)")
+	startLibrarianCmd.Flags().Duration(probeIntervalFlag, comm.DefaultProbeInterval,
+		"mean interval between rounds of routing table peer health probes (0 disables probing)")
+	startLibrarianCmd.Flags().Uint(probeNPeersFlag, comm.DefaultProbeNPeers,
+		"number of routing table peers probed each round")
+	startLibrarianCmd.Flags().Float64(probeJitterFlag, comm.DefaultProbeJitter,
+		"fraction, in [0, 1), of the probe interval by which each interval is randomly varied")
+	startLibrarianCmd.Flags().Uint(minReadyPeersFlag, server.DefaultReadinessMinPeers,
+		"minimum number of routing table peers before health checks report serving (0 means "+
+			"serving once bootstrapped)")
+	startLibrarianCmd.Flags().Float64(throttleRateFlag, comm.DefaultThrottleRate,
+		"maximum sustained outbound queries per second to each peer (0 disables throttling)")
+	startLibrarianCmd.Flags().Uint(throttleBurstFlag, comm.DefaultThrottleBurst,
+		"maximum outbound queries to each peer in a burst above the sustained rate")
+	startLibrarianCmd.Flags().Bool(throttleQueueFlag, comm.DefaultThrottleQueue,
+		"whether throttled outbound queries wait for allowance rather than being dropped")
+	startLibrarianCmd.Flags().StringSlice(trustedPeersFlag, nil,
+		"hex IDs of the peers whose Find, Store, and Verify requests aren't rate limited")
+	startLibrarianCmd.Flags().Uint(puzzleDifficultyFlag, introduce.DefaultPuzzleDifficulty,
+		"minimum number of leading zero bits, at most 32, in the hash of the IDs of peers "+
+			"introduced or added to the routing table, which a newly created peer ID also has "+
+			"(0 accepts all IDs)")
+	startLibrarianCmd.Flags().String(allowListFileFlag, "",
+		"path of the admin-signed allow-list of the peers allowed in a private cluster, which "+
+			"is reloaded when it changes (empty disables allow-list mode)")
 
 	// bind viper flags
 	viper.SetEnvPrefix("LIBRI") // look for env vars with "LIBRI_" prefix
 	viper.AutomaticEnv()        // read in environment variables that match
-	errors.MaybePanic(viper.BindPFlags(startLibrarianCmd.Flags()))
+	cerrors.MaybePanic(viper.BindPFlags(startLibrarianCmd.Flags()))
 }
 
 func getLibrarianConfig() (*server.Config, *zap.Logger, error) {
@@ -108,10 +263,49 @@ func getLibrarianConfig() (*server.Config, *zap.Logger, error) {
 	}
 	replicateParams := replicate.NewDefaultParameters()
 	replicateParams.VerifyInterval = viper.GetDuration(verifyIntervalFlag)
+	replicateParams.SampleRate = float32(viper.GetFloat64(verifySampleRateFlag))
+	replicateParams.ReplicateInterval = viper.GetDuration(replicateIntervalFlag)
+	replicateParams.MaxNReplicas = uint(viper.GetInt(maxNReplicasFlag))
 	orgID, err := getOrgID(logger)
 	if err != nil {
 		return nil, nil, err
 	}
+	storeAuthPubKey, err := getStoreAuthPubKey(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	macaroonRootKey, err := getMacaroonRootKey(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	adminPubKey, err := getAdminPubKey(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	netParamsPubKey, err := getNetworkParamsPubKey(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	traceSampleRates, err := getTraceSampleRates(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	workerPoolSizes, err := getWorkerPoolSizes(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	mirrorAuthorPubKeys, err := getMirrorAuthorPubKeys(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	trustedPeers, err := getTrustedPeers(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	typeReplicas, err := getTypeReplicas(logger, storeTypeReplicasFlag)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	config := server.NewDefaultConfig().
 		WithLocalPort(localPort).
@@ -120,14 +314,54 @@ func getLibrarianConfig() (*server.Config, *zap.Logger, error) {
 		WithProfile(profile).
 		WithPublicAddr(publicAddr).
 		WithPublicName(viper.GetString(publicNameFlag)).
+		WithZone(viper.GetString(zoneFlag)).
 		WithOrgID(orgID).
+		WithStoreAuthPubKey(storeAuthPubKey).
+		WithMacaroonRootKey(macaroonRootKey).
+		WithAdminPubKey(adminPubKey).
+		WithNetworkParamsPubKey(netParamsPubKey).
+		WithTraceSampleRates(traceSampleRates).
+		WithWorkerPoolSizes(workerPoolSizes).
+		WithStorageHookCommand(strings.Fields(viper.GetString(storageHookCmdFlag))).
 		WithReplicate(replicateParams).
 		WithDataDir(viper.GetString(dataDirFlag)).
 		WithDefaultDBDir(). // depends on DataDir
+		WithDBDriver(viper.GetString(dbDriverFlag)).
 		WithLogLevel(logLevel)
 	config.SubscribeTo.NSubscriptions = uint32(viper.GetInt(nSubscriptionsFlag))
 	config.SubscribeTo.FPRate = float32(viper.GetFloat64(fpRateFlag))
 	config.Routing.MaxBucketPeers = uint(viper.GetInt(maxBucketPeersFlag))
+	config.Routing.MaxConsecutiveFailures = uint(viper.GetInt(maxFailuresFlag))
+	config.Store.AuthToken = viper.GetString(storeAuthTokenFlag)
+	config.Store.Macaroon = viper.GetString(peerMacaroonFlag)
+	config.Search.Macaroon = viper.GetString(peerMacaroonFlag)
+	config.Tiering.ColdDbDir = viper.GetString(coldDBDirFlag)
+	config.Tiering.DemoteAfter = viper.GetDuration(demoteAfterFlag)
+	config.Store.NDataShards = uint(viper.GetInt(nDataShardsFlag))
+	config.Store.NParityShards = uint(viper.GetInt(nParityShardsFlag))
+	config.Store.Spillover = viper.GetBool(storeSpilloverFlag)
+	config.Store.ChallengeLength = uint(viper.GetInt(storeChallengeFlag))
+	config.Store.HintedHandoff = viper.GetBool(storeHintedFlag)
+	if len(typeReplicas) > 0 {
+		config.WithStorePolicy(store.NewReplicationPolicy(typeReplicas))
+	}
+	config.Search.ReadRepair = viper.GetBool(getReadRepairFlag)
+	config.GetLatencyWeighted = viper.GetBool(latencyWeightedFlag)
+	config.Mirror.AuthorPubKeys = mirrorAuthorPubKeys
+	config.Quota.DefaultLimitBytes = viper.GetUint64(orgQuotaBytesFlag)
+	config.AntiEntropy.Interval = viper.GetDuration(syncIntervalFlag)
+	config.Probe.Interval = viper.GetDuration(probeIntervalFlag)
+	config.Probe.NPeers = uint(viper.GetInt(probeNPeersFlag))
+	config.Probe.Jitter = viper.GetFloat64(probeJitterFlag)
+	config.Readiness.MinPeers = uint(viper.GetInt(minReadyPeersFlag))
+	config.Throttle.Rate = viper.GetFloat64(throttleRateFlag)
+	config.Throttle.Burst = uint(viper.GetInt(throttleBurstFlag))
+	config.Throttle.Queue = viper.GetBool(throttleQueueFlag)
+	config.RequesterLimit.Trusted = trustedPeers
+	config.Introduce.PuzzleDifficulty = uint(viper.GetInt(puzzleDifficultyFlag))
+	config.Routing.PuzzleDifficulty = config.Introduce.PuzzleDifficulty
+	config.AllowList.File = viper.GetString(allowListFileFlag)
+	config.WithTLS(getTLSParameters())
 
 	bootstrapNetAddrs, err := parse.Addrs(viper.GetStringSlice(bootstrapsFlag))
 	if err != nil {
@@ -136,46 +370,247 @@ func getLibrarianConfig() (*server.Config, *zap.Logger, error) {
 
 	}
 	config.WithBootstrapAddrs(bootstrapNetAddrs)
+	altPublicAddrs, err := parse.Addrs(viper.GetStringSlice(altPublicAddrsFlag))
+	if err != nil {
+		logger.Error("unable to parse additional public address", zap.Error(err))
+		return nil, nil, err
+	}
+	config.WithAltPublicAddrs(altPublicAddrs)
+	config.WithSeedHosts(viper.GetStringSlice(seedHostsFlag))
 
 	WriteLibrarianBanner(os.Stdout)
 	logger.Info("librarian configuration",
 		zap.Int(logLocalPort, config.LocalPort),
 		zap.Int(logLocalMetricsPort, config.LocalMetricsPort),
 		zap.Stringer(logPublicAddr, config.PublicAddr),
+		zap.String(altPublicAddrsFlag, fmt.Sprintf("%v", config.AltPublicAddrs)),
 		zap.String(bootstrapsFlag, fmt.Sprintf("%v", config.BootstrapAddrs)),
+		zap.Strings(seedHostsFlag, config.SeedHosts),
 		zap.String(publicNameFlag, config.PublicName),
+		zap.String(zoneFlag, config.Zone),
 		zap.String(dataDirFlag, config.DataDir),
+		zap.String(coldDBDirFlag, config.Tiering.ColdDbDir),
 		zap.Stringer(logLevelFlag, config.LogLevel),
 		zap.Uint32(nSubscriptionsFlag, config.SubscribeTo.NSubscriptions),
 		zap.Float32(fpRateFlag, config.SubscribeTo.FPRate),
 		zap.Uint(maxBucketPeersFlag, config.Routing.MaxBucketPeers),
+		zap.Uint(maxFailuresFlag, config.Routing.MaxConsecutiveFailures),
 	)
 	return config, logger, nil
 }
 
 func getOrgID(logger *zap.Logger) (ecid.ID, error) {
-	orgIDPrivHex := viper.GetString(organizationIDFlag)
-	if len(orgIDPrivHex) == 0 {
-		// ok if org ID isn't set
+	// ok if org ID isn't set
+	return getPrivateKeyID(logger, organizationIDFlag, "organization ID")
+}
+
+// getPrivateKeyID gets the ID from the private key hex in the given flag, returning nil if the flag
+// isn't set.
+func getPrivateKeyID(logger *zap.Logger, flag, name string) (ecid.ID, error) {
+	privHex := viper.GetString(flag)
+	if len(privHex) == 0 {
 		return nil, nil
 	}
-	orgIDPrivBytes, err := hex.DecodeString(strings.TrimSpace(orgIDPrivHex))
+	privBytes, err := hex.DecodeString(strings.TrimSpace(privHex))
 	if err != nil {
-		logger.Error("fatal error parsing organization ID private key hex")
+		logger.Error("fatal error parsing " + name + " private key hex")
 		return nil, err
 	}
-	expectedByteLen := ecid.Curve.BitSize / 8
-	if len(orgIDPrivBytes) != expectedByteLen {
-		logger.Error("organization ID private key hex is not the expected length",
+	expectedByteLen := ecid.Curve.Params().BitSize / 8
+	if len(privBytes) != expectedByteLen {
+		logger.Error(name+" private key hex is not the expected length",
 			zap.Int("expected_length", expectedByteLen),
-			zap.Int("actual_length", len(orgIDPrivBytes)),
+			zap.Int("actual_length", len(privBytes)),
 		)
 		return nil, err
 	}
-	priv, err := crypto.ToECDSA(orgIDPrivBytes)
+	priv, err := crypto.ToECDSA(privBytes)
 	if err != nil {
-		logger.Error("unable to construct organization ID private key")
+		logger.Error("unable to construct " + name + " private key")
 		return nil, err
 	}
 	return ecid.FromPrivateKey(priv)
 }
+
+func getTraceSampleRates(logger *zap.Logger) (server.TraceSampleRates, error) {
+	rates := server.NewDefaultTraceSampleRates()
+	for _, rateStr := range viper.GetStringSlice(traceSampleRatesFlag) {
+		pair := strings.SplitN(strings.TrimSpace(rateStr), "=", 2)
+		if len(pair) != 2 {
+			logger.Error("trace sample rate must have form Endpoint=rate",
+				zap.String(traceSampleRatesFlag, rateStr))
+			return nil, errInvalidTraceSampleRate
+		}
+		e, ok := parseEndpoint(pair[0])
+		if !ok {
+			logger.Error("unknown trace sample rate endpoint",
+				zap.String(traceSampleRatesFlag, rateStr))
+			return nil, errInvalidTraceSampleRate
+		}
+		rate, err := strconv.ParseFloat(pair[1], 64)
+		if err != nil || rate < 0 || rate > 1 {
+			logger.Error("trace sample rate must be in [0, 1]",
+				zap.String(traceSampleRatesFlag, rateStr))
+			return nil, errInvalidTraceSampleRate
+		}
+		rates[e] = rate
+	}
+	return rates, nil
+}
+
+func getWorkerPoolSizes(logger *zap.Logger) (server.WorkerPoolSizes, error) {
+	sizes := server.NewDefaultWorkerPoolSizes()
+	for _, sizeStr := range viper.GetStringSlice(workerPoolSizesFlag) {
+		pair := strings.SplitN(strings.TrimSpace(sizeStr), "=", 2)
+		if len(pair) != 2 {
+			logger.Error("worker pool size must have form Endpoint=size",
+				zap.String(workerPoolSizesFlag, sizeStr))
+			return nil, errInvalidWorkerPoolSize
+		}
+		e, ok := parseEndpoint(pair[0])
+		if !ok {
+			logger.Error("unknown worker pool size endpoint",
+				zap.String(workerPoolSizesFlag, sizeStr))
+			return nil, errInvalidWorkerPoolSize
+		}
+		size, err := strconv.ParseUint(pair[1], 10, 32)
+		if err != nil {
+			logger.Error("worker pool size must be a non-negative integer",
+				zap.String(workerPoolSizesFlag, sizeStr))
+			return nil, errInvalidWorkerPoolSize
+		}
+		sizes[e] = uint(size)
+	}
+	return sizes, nil
+}
+
+// getTypeReplicas parses the number of replicas of each document type from the "type=replicas"
+// values of the given flag.
+func getTypeReplicas(logger *zap.Logger, flag string) (api.TypeReplicas, error) {
+	replicas := make(api.TypeReplicas)
+	for _, replicasStr := range viper.GetStringSlice(flag) {
+		pair := strings.SplitN(strings.TrimSpace(replicasStr), "=", 2)
+		if len(pair) != 2 {
+			logger.Error("document type replicas must have form type=replicas",
+				zap.String(flag, replicasStr))
+			return nil, errInvalidTypeReplicas
+		}
+		t, ok := parseDocumentType(pair[0])
+		if !ok {
+			logger.Error("unknown document type", zap.String(flag, replicasStr))
+			return nil, errInvalidTypeReplicas
+		}
+		nReplicas, err := strconv.ParseUint(pair[1], 10, 32)
+		if err != nil || nReplicas == 0 {
+			logger.Error("document type replicas must be a positive integer",
+				zap.String(flag, replicasStr))
+			return nil, errInvalidTypeReplicas
+		}
+		replicas[t] = uint(nReplicas)
+	}
+	return replicas, nil
+}
+
+func parseDocumentType(name string) (api.DocumentType, bool) {
+	for t := api.EnvelopeType; t <= api.ShardManifestType; t++ {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+func parseEndpoint(name string) (api.Endpoint, bool) {
+	for _, e := range api.Endpoints {
+		if e.String() == name {
+			return e, true
+		}
+	}
+	return api.All, false
+}
+
+// getMirrorAuthorPubKeys parses the author public keys from the hex values of the mirror authors
+// flag, validating that each is a public key.
+func getMirrorAuthorPubKeys(logger *zap.Logger) ([][]byte, error) {
+	pubKeys := make([][]byte, 0)
+	for _, pubHex := range viper.GetStringSlice(mirrorAuthorsFlag) {
+		pubBytes, err := hex.DecodeString(strings.TrimSpace(pubHex))
+		if err != nil {
+			logger.Error("fatal error parsing mirrored author public key hex",
+				zap.String(mirrorAuthorsFlag, pubHex))
+			return nil, err
+		}
+		if _, err := ecid.FromPublicKeyBytes(pubBytes); err != nil {
+			logger.Error("unable to construct mirrored author public key",
+				zap.String(mirrorAuthorsFlag, pubHex))
+			return nil, err
+		}
+		pubKeys = append(pubKeys, pubBytes)
+	}
+	return pubKeys, nil
+}
+
+// getTrustedPeers parses the IDs of the peers exempt from requester rate limits from the hex
+// values of the trusted peers flag.
+func getTrustedPeers(logger *zap.Logger) ([]id.ID, error) {
+	peerIDs := make([]id.ID, 0)
+	for _, idHex := range viper.GetStringSlice(trustedPeersFlag) {
+		peerID, err := id.FromString(strings.TrimSpace(idHex))
+		if err != nil {
+			logger.Error("fatal error parsing trusted peer ID",
+				zap.String(trustedPeersFlag, idHex))
+			return nil, err
+		}
+		peerIDs = append(peerIDs, peerID)
+	}
+	return peerIDs, nil
+}
+
+func getStoreAuthPubKey(logger *zap.Logger) (*ecdsa.PublicKey, error) {
+	// ok if store authorization isn't required
+	return getPubKey(logger, storeAuthPubKeyFlag, "store authorization")
+}
+
+// getMacaroonRootKey parses the macaroon root key hex, returning nil if it isn't set.
+func getMacaroonRootKey(logger *zap.Logger) ([]byte, error) {
+	rootKeyHex := strings.TrimSpace(viper.GetString(macaroonRootKeyFlag))
+	if len(rootKeyHex) == 0 {
+		// ok if macaroons aren't required
+		return nil, nil
+	}
+	rootKey, err := hex.DecodeString(rootKeyHex)
+	if err != nil {
+		logger.Error("fatal error parsing macaroon root key hex")
+		return nil, err
+	}
+	return rootKey, nil
+}
+
+func getAdminPubKey(logger *zap.Logger) (*ecdsa.PublicKey, error) {
+	// ok if admin requests are disabled
+	return getPubKey(logger, adminPubKeyFlag, "admin")
+}
+
+func getNetworkParamsPubKey(logger *zap.Logger) (*ecdsa.PublicKey, error) {
+	// ok if network parameters aren't adopted
+	return getPubKey(logger, netParamsPubKeyFlag, "network parameters")
+}
+
+// getPubKey parses the public key from the hex value of the flag, returning nil if it isn't set.
+func getPubKey(logger *zap.Logger, flag string, desc string) (*ecdsa.PublicKey, error) {
+	pubHex := viper.GetString(flag)
+	if len(pubHex) == 0 {
+		return nil, nil
+	}
+	pubBytes, err := hex.DecodeString(strings.TrimSpace(pubHex))
+	if err != nil {
+		logger.Error(fmt.Sprintf("fatal error parsing %s public key hex", desc))
+		return nil, err
+	}
+	pub, err := ecid.FromPublicKeyBytes(pubBytes)
+	if err != nil {
+		logger.Error(fmt.Sprintf("unable to construct %s public key", desc))
+		return nil, err
+	}
+	return pub, nil
+}
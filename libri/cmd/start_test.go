@@ -14,7 +14,10 @@ import (
 	"strings"
 
 	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/server"
+	"github.com/drausin/libri/libri/librarian/server/store"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -44,6 +47,7 @@ func TestGetLibrarianConfig_ok(t *testing.T) {
 	nSubscriptions, fpRate := 5, 0.5
 	bootstraps := "1.2.3.5:1000 1.2.3.6:1000"
 	nBucketPeers := uint(8)
+	maxFailures := uint(3)
 	verifyInterval := 5 * time.Second
 	orgID := ecid.NewPseudoRandom(rng)
 	orgIDHex := hex.EncodeToString(orgID.Key().D.Bytes())
@@ -56,32 +60,109 @@ func TestGetLibrarianConfig_ok(t *testing.T) {
 	viper.Set(profileFlag, profile)
 	viper.Set(publicPortFlag, publicPort)
 	viper.Set(publicNameFlag, publicName)
+	viper.Set(zoneFlag, "us-east1-b")
+	viper.Set(altPublicAddrsFlag, "192.168.1.1:20100")
 	viper.Set(dataDirFlag, dataDir)
 	viper.Set(nSubscriptionsFlag, nSubscriptions)
 	viper.Set(fpRateFlag, fpRate)
 	viper.Set(bootstrapsFlag, bootstraps)
+	viper.Set(seedHostsFlag, "seeds1.example.com seeds2.example.com")
 	viper.Set(maxBucketPeersFlag, nBucketPeers)
+	viper.Set(maxFailuresFlag, maxFailures)
 	viper.Set(verifyIntervalFlag, verifyInterval)
+	viper.Set(verifySampleRateFlag, 0.25)
+	viper.Set(replicateIntervalFlag, time.Second)
+	viper.Set(maxNReplicasFlag, 5)
 	viper.Set(organizationIDFlag, orgIDHex)
+	viper.Set(storageHookCmdFlag, "/usr/local/bin/index-doc --verbose")
+	viper.Set(coldDBDirFlag, "some/cold/db/dir")
+	viper.Set(demoteAfterFlag, time.Hour)
+	viper.Set(nDataShardsFlag, 4)
+	viper.Set(nParityShardsFlag, 3)
+	viper.Set(storeSpilloverFlag, true)
+	viper.Set(storeChallengeFlag, 256)
+	viper.Set(storeHintedFlag, true)
+	viper.Set(getReadRepairFlag, true)
+	viper.Set(latencyWeightedFlag, true)
+	viper.Set(syncIntervalFlag, time.Hour)
+	viper.Set(probeIntervalFlag, time.Minute)
+	viper.Set(probeNPeersFlag, 4)
+	viper.Set(probeJitterFlag, 0.5)
+	viper.Set(minReadyPeersFlag, 3)
+	viper.Set(throttleRateFlag, 8.0)
+	viper.Set(throttleBurstFlag, 4)
+	viper.Set(throttleQueueFlag, false)
+	viper.Set(puzzleDifficultyFlag, 8)
+	viper.Set(allowListFileFlag, "some/allowlist.bin")
+	viper.Set(tlsCertFileFlag, "some/cert.pem")
+	viper.Set(tlsKeyFileFlag, "some/key.pem")
+	viper.Set(tlsCAFileFlag, "some/ca.pem")
+	viper.Set(tlsSPIFFEIDsFlag, []string{"spiffe://example.org/librarian"})
+	defer func() {
+		for _, flag := range []string{tlsCertFileFlag, tlsKeyFileFlag, tlsCAFileFlag} {
+			viper.Set(flag, "")
+		}
+		viper.Set(tlsSPIFFEIDsFlag, []string{})
+		viper.Set(peerMacaroonFlag, "")
+	}()
+	viper.Set(peerMacaroonFlag, "some macaroon")
+	viper.Set(storeTypeReplicasFlag, []string{"page=3"})
 
 	config, logger, err := getLibrarianConfig()
 	assert.Nil(t, err)
 	assert.NotNil(t, logger)
 	assert.Equal(t, localPort, config.LocalPort)
+	assert.Equal(t, "some/cold/db/dir", config.Tiering.ColdDbDir)
+	assert.Equal(t, time.Hour, config.Tiering.DemoteAfter)
+	assert.Equal(t, uint(4), config.Store.NDataShards)
+	assert.Equal(t, uint(3), config.Store.NParityShards)
+	assert.True(t, config.Store.Spillover)
+	assert.Equal(t, uint(256), config.Store.ChallengeLength)
+	assert.True(t, config.Store.HintedHandoff)
+	assert.Equal(t, "some macaroon", config.Store.Macaroon)
+	assert.Equal(t, "some macaroon", config.Search.Macaroon)
+	assert.True(t, config.Search.ReadRepair)
+	assert.True(t, config.GetLatencyWeighted)
+	assert.False(t, config.Search.LatencyWeighted)
+	assert.Equal(t, time.Hour, config.AntiEntropy.Interval)
+	assert.Equal(t, time.Minute, config.Probe.Interval)
+	assert.Equal(t, uint(4), config.Probe.NPeers)
+	assert.Equal(t, uint(3), config.Readiness.MinPeers)
+	assert.Equal(t, 0.5, config.Probe.Jitter)
+	assert.Equal(t, 8.0, config.Throttle.Rate)
+	assert.Equal(t, uint(4), config.Throttle.Burst)
+	assert.False(t, config.Throttle.Queue)
+	assert.Equal(t, uint(8), config.Introduce.PuzzleDifficulty)
+	assert.Equal(t, uint(8), config.Routing.PuzzleDifficulty)
+	assert.Equal(t, "some/allowlist.bin", config.AllowList.File)
+	assert.Equal(t, "some/cert.pem", config.TLS.CertFile)
+	assert.Equal(t, "some/key.pem", config.TLS.KeyFile)
+	assert.Equal(t, "some/ca.pem", config.TLS.CAFile)
+	assert.Equal(t, []string{"spiffe://example.org/librarian"}, config.TLS.SPIFFEIDs)
+	expectedPolicy := store.NewReplicationPolicy(api.TypeReplicas{api.PageType: 3})
+	assert.Equal(t, expectedPolicy, config.StorePolicy)
 	assert.Equal(t, localMetricsPort, config.LocalMetricsPort)
 	assert.Equal(t, localProfilerPort, config.LocalProfilerPort)
 	assert.Equal(t, profile, config.Profile)
 	assert.Equal(t, fmt.Sprintf("%s:%d", publicIP, publicPort), config.PublicAddr.String())
 	assert.Equal(t, publicName, config.PublicName)
+	assert.Equal(t, "us-east1-b", config.Zone)
+	assert.Len(t, config.AltPublicAddrs, 1)
 	assert.Equal(t, dataDir, config.DataDir)
 	assert.Equal(t, dataDir+"/"+server.DBSubDir, config.DbDir)
 	assert.Equal(t, logLevel, config.LogLevel.String())
 	assert.Equal(t, uint32(nSubscriptions), config.SubscribeTo.NSubscriptions)
 	assert.Equal(t, float32(fpRate), config.SubscribeTo.FPRate)
 	assert.Equal(t, 2, len(config.BootstrapAddrs))
+	assert.Equal(t, []string{"seeds1.example.com", "seeds2.example.com"}, config.SeedHosts)
 	assert.Equal(t, nBucketPeers, config.Routing.MaxBucketPeers)
+	assert.Equal(t, maxFailures, config.Routing.MaxConsecutiveFailures)
 	assert.Equal(t, verifyInterval, config.Replicate.VerifyInterval)
+	assert.Equal(t, float32(0.25), config.Replicate.SampleRate)
+	assert.Equal(t, time.Second, config.Replicate.ReplicateInterval)
+	assert.Equal(t, uint(5), config.Replicate.MaxNReplicas)
 	assert.Equal(t, orgID.Key(), config.OrgID.Key())
+	assert.Equal(t, []string{"/usr/local/bin/index-doc", "--verbose"}, config.StorageHookCommand)
 
 	assert.Nil(t, os.RemoveAll(config.DataDir))
 }
@@ -122,6 +203,245 @@ func TestGetOrgID_ok(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestGetStoreAuthPubKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	operatorID := ecid.NewPseudoRandom(rng)
+	lg := zap.NewNop()
+
+	// no public key set
+	viper.Set(storeAuthPubKeyFlag, "")
+	pub, err := getStoreAuthPubKey(lg)
+	assert.Nil(t, pub)
+	assert.Nil(t, err)
+
+	// public key set
+	viper.Set(storeAuthPubKeyFlag, hex.EncodeToString(operatorID.PublicKeyBytes()))
+	pub, err = getStoreAuthPubKey(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, &operatorID.Key().PublicKey, pub)
+
+	// bad public keys
+	for _, bad := range []string{"not hex", strings.Repeat("0", 66)} {
+		viper.Set(storeAuthPubKeyFlag, bad)
+		pub, err = getStoreAuthPubKey(lg)
+		assert.Nil(t, pub)
+		assert.NotNil(t, err)
+	}
+	viper.Set(storeAuthPubKeyFlag, "")
+}
+
+func TestGetMacaroonRootKey(t *testing.T) {
+	lg := zap.NewNop()
+
+	// no root key set
+	viper.Set(macaroonRootKeyFlag, "")
+	rootKey, err := getMacaroonRootKey(lg)
+	assert.Nil(t, rootKey)
+	assert.Nil(t, err)
+
+	// root key set
+	viper.Set(macaroonRootKeyFlag, "0a1b2c")
+	rootKey, err = getMacaroonRootKey(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x0a, 0x1b, 0x2c}, rootKey)
+
+	// bad root key
+	viper.Set(macaroonRootKeyFlag, "not hex")
+	rootKey, err = getMacaroonRootKey(lg)
+	assert.Nil(t, rootKey)
+	assert.NotNil(t, err)
+	viper.Set(macaroonRootKeyFlag, "")
+}
+
+func TestGetAdminPubKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID := ecid.NewPseudoRandom(rng)
+	lg := zap.NewNop()
+
+	// no public key set
+	viper.Set(adminPubKeyFlag, "")
+	pub, err := getAdminPubKey(lg)
+	assert.Nil(t, pub)
+	assert.Nil(t, err)
+
+	// public key set
+	viper.Set(adminPubKeyFlag, hex.EncodeToString(adminID.PublicKeyBytes()))
+	pub, err = getAdminPubKey(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, &adminID.Key().PublicKey, pub)
+
+	// bad public key
+	viper.Set(adminPubKeyFlag, "not hex")
+	pub, err = getAdminPubKey(lg)
+	assert.Nil(t, pub)
+	assert.NotNil(t, err)
+	viper.Set(adminPubKeyFlag, "")
+}
+
+func TestGetNetworkParamsPubKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	maintainerID := ecid.NewPseudoRandom(rng)
+	lg := zap.NewNop()
+
+	// no public key set
+	viper.Set(netParamsPubKeyFlag, "")
+	pub, err := getNetworkParamsPubKey(lg)
+	assert.Nil(t, pub)
+	assert.Nil(t, err)
+
+	// public key set
+	viper.Set(netParamsPubKeyFlag, hex.EncodeToString(maintainerID.PublicKeyBytes()))
+	pub, err = getNetworkParamsPubKey(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, &maintainerID.Key().PublicKey, pub)
+
+	// bad public key
+	viper.Set(netParamsPubKeyFlag, "not hex")
+	pub, err = getNetworkParamsPubKey(lg)
+	assert.Nil(t, pub)
+	assert.NotNil(t, err)
+	viper.Set(netParamsPubKeyFlag, "")
+}
+
+func TestGetMirrorAuthorPubKeys(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	authorID1, authorID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	lg := zap.NewNop()
+
+	// no authors set
+	viper.Set(mirrorAuthorsFlag, []string{})
+	pubKeys, err := getMirrorAuthorPubKeys(lg)
+	assert.Nil(t, err)
+	assert.Empty(t, pubKeys)
+
+	// authors set
+	viper.Set(mirrorAuthorsFlag, []string{
+		hex.EncodeToString(authorID1.PublicKeyBytes()),
+		hex.EncodeToString(authorID2.PublicKeyBytes()),
+	})
+	pubKeys, err = getMirrorAuthorPubKeys(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{authorID1.PublicKeyBytes(), authorID2.PublicKeyBytes()}, pubKeys)
+
+	// bad hex
+	viper.Set(mirrorAuthorsFlag, []string{"not hex"})
+	pubKeys, err = getMirrorAuthorPubKeys(lg)
+	assert.NotNil(t, err)
+	assert.Nil(t, pubKeys)
+
+	// not a public key
+	viper.Set(mirrorAuthorsFlag, []string{"abcd"})
+	pubKeys, err = getMirrorAuthorPubKeys(lg)
+	assert.NotNil(t, err)
+	assert.Nil(t, pubKeys)
+	viper.Set(mirrorAuthorsFlag, []string{})
+}
+
+func TestGetTrustedPeers(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	lg := zap.NewNop()
+
+	// no peers set
+	viper.Set(trustedPeersFlag, []string{})
+	peerIDs, err := getTrustedPeers(lg)
+	assert.Nil(t, err)
+	assert.Empty(t, peerIDs)
+
+	// peers set
+	viper.Set(trustedPeersFlag, []string{peerID1.String(), peerID2.String()})
+	peerIDs, err = getTrustedPeers(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, []id.ID{peerID1, peerID2}, peerIDs)
+
+	// bad ID
+	viper.Set(trustedPeersFlag, []string{"not an ID"})
+	peerIDs, err = getTrustedPeers(lg)
+	assert.NotNil(t, err)
+	assert.Nil(t, peerIDs)
+	viper.Set(trustedPeersFlag, []string{})
+}
+
+func TestGetTraceSampleRates(t *testing.T) {
+	lg := zap.NewNop()
+
+	// no rates set
+	viper.Set(traceSampleRatesFlag, []string{})
+	rates, err := getTraceSampleRates(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, server.NewDefaultTraceSampleRates(), rates)
+
+	// rates override defaults
+	viper.Set(traceSampleRatesFlag, []string{"Find=0.5", "Store=0"})
+	rates, err = getTraceSampleRates(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, rates[api.Find])
+	assert.Equal(t, 0.0, rates[api.Store])
+	assert.Equal(t, server.NewDefaultTraceSampleRates()[api.Put], rates[api.Put])
+
+	// bad rates
+	for _, bad := range []string{"Find", "Unknown=0.5", "Find=high", "Find=1.5", "Find=-1"} {
+		viper.Set(traceSampleRatesFlag, []string{bad})
+		rates, err = getTraceSampleRates(lg)
+		assert.Nil(t, rates)
+		assert.Equal(t, errInvalidTraceSampleRate, err)
+	}
+	viper.Set(traceSampleRatesFlag, []string{})
+}
+
+func TestGetWorkerPoolSizes(t *testing.T) {
+	lg := zap.NewNop()
+
+	// no sizes set
+	viper.Set(workerPoolSizesFlag, []string{})
+	sizes, err := getWorkerPoolSizes(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, server.NewDefaultWorkerPoolSizes(), sizes)
+
+	// sizes override defaults
+	viper.Set(workerPoolSizesFlag, []string{"Find=8", "Introduce=4", "Put=0"})
+	sizes, err = getWorkerPoolSizes(lg)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(8), sizes[api.Find])
+	assert.Equal(t, uint(4), sizes[api.Introduce])
+	assert.Equal(t, uint(0), sizes[api.Put])
+	assert.Equal(t, server.NewDefaultWorkerPoolSizes()[api.Get], sizes[api.Get])
+
+	// bad sizes
+	for _, bad := range []string{"Find", "Unknown=4", "Find=many", "Find=-1"} {
+		viper.Set(workerPoolSizesFlag, []string{bad})
+		sizes, err = getWorkerPoolSizes(lg)
+		assert.Nil(t, sizes)
+		assert.Equal(t, errInvalidWorkerPoolSize, err)
+	}
+	viper.Set(workerPoolSizesFlag, []string{})
+}
+
+func TestGetTypeReplicas(t *testing.T) {
+	lg := zap.NewNop()
+
+	// no replicas set
+	viper.Set(storeTypeReplicasFlag, []string{})
+	replicas, err := getTypeReplicas(lg, storeTypeReplicasFlag)
+	assert.Nil(t, err)
+	assert.Len(t, replicas, 0)
+
+	viper.Set(storeTypeReplicasFlag, []string{"envelope=5", "shard_manifest=4", "page=3"})
+	replicas, err = getTypeReplicas(lg, storeTypeReplicasFlag)
+	assert.Nil(t, err)
+	expected := api.TypeReplicas{api.EnvelopeType: 5, api.ShardManifestType: 4, api.PageType: 3}
+	assert.Equal(t, expected, replicas)
+
+	// bad replicas
+	for _, bad := range []string{"envelope", "unknown=4", "page=many", "page=-1", "page=0"} {
+		viper.Set(storeTypeReplicasFlag, []string{bad})
+		replicas, err = getTypeReplicas(lg, storeTypeReplicasFlag)
+		assert.Nil(t, replicas)
+		assert.Equal(t, errInvalidTypeReplicas, err)
+	}
+	viper.Set(storeTypeReplicasFlag, []string{})
+}
+
 func TestGetOrgID_err(t *testing.T) {
 	lg := zap.NewNop()
 	badOrgIDHexs := map[string]string{
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	lauthor "github.com/drausin/libri/libri/author"
 	"github.com/drausin/libri/libri/author/keychain"
@@ -19,6 +20,7 @@ import (
 
 const (
 	upFilepathFlag = "upFilepath"
+	dryRunFlag     = "dry-run"
 	octetMediaType = "application/octet-stream"
 )
 
@@ -43,10 +45,14 @@ func init() {
 		"number of parallel processes")
 	uploadCmd.Flags().StringP(upFilepathFlag, "f", "",
 		"path of local file to upload")
+	uploadCmd.Flags().Bool(dryRunFlag, false,
+		"pack the file and report the documents that would be stored without storing them")
 
 	// bind viper flags
 	viper.SetEnvPrefix(envVarPrefix) // look for env vars with "LIBRI_" prefix
 	viper.AutomaticEnv()             // read in environment variables that match
+	// map dashed flags to underscored env vars, e.g., dry-run to LIBRI_DRY_RUN
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	cerrors.MaybePanic(viper.BindPFlags(uploadCmd.Flags()))
 }
 
@@ -97,6 +103,19 @@ func (u *fileUploaderImpl) upload() error {
 		return err
 	}
 
+	if viper.GetBool(dryRunFlag) {
+		logger.Info("planning document upload",
+			zap.String("filepath", upFilepath),
+			zap.String("media_type", mediaType),
+		)
+		plan, err := u.au.dryRun(author, file, mediaType)
+		if err != nil {
+			return err
+		}
+		logUploadPlan(logger, plan)
+		return file.Close()
+	}
+
 	logger.Info("uploading document",
 		zap.String("filepath", upFilepath),
 		zap.String("media_type", mediaType),
@@ -107,6 +126,33 @@ func (u *fileUploaderImpl) upload() error {
 	return file.Close()
 }
 
+func logUploadPlan(logger *zap.Logger, plan *lauthor.UploadPlan) {
+	for _, pd := range plan.Docs {
+		closest := make([]string, len(pd.ClosestPeers))
+		for i, p := range pd.ClosestPeers {
+			closest[i] = p.Address().String()
+		}
+		fields := []zap.Field{
+			zap.Stringer("key", pd.Key),
+			zap.Int("size", pd.Size),
+			zap.Strings("closest_peers", closest),
+		}
+		if pd.Err != nil {
+			logger.Warn("planned document", append(fields, zap.Error(pd.Err))...)
+			continue
+		}
+		logger.Info("planned document", fields...)
+	}
+	logger.Info("planned upload",
+		zap.Stringer("envelope_key", plan.EnvelopeKey),
+		zap.Stringer("entry_key", plan.EntryKey),
+		zap.Int("n_pages", plan.NPages),
+		zap.Int("total_bytes", plan.TotalBytes()),
+		zap.Uint("n_replicas", plan.NReplicas),
+		zap.Int("est_replica_bytes", plan.ReplicaBytes()),
+	)
+}
+
 type mediaTypeGetter interface {
 	get(upFilepath string) (string, error)
 }
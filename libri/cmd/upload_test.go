@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path"
 	"testing"
@@ -16,6 +17,7 @@ import (
 	cerrors "github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -61,6 +63,45 @@ func TestFileUploader_upload_ok(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestFileUploader_upload_dryRun(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	plan := &lauthor.UploadPlan{
+		EnvelopeKey: id.NewPseudoRandom(rng),
+		EntryKey:    id.NewPseudoRandom(rng),
+		Docs: []*lauthor.PlannedDoc{
+			{Key: id.NewPseudoRandom(rng), Size: 1, ClosestPeers: peer.NewTestPeers(rng, 2)},
+			{Key: id.NewPseudoRandom(rng), Size: 2, Err: errors.New("some Find error")},
+		},
+		NPages:    1,
+		NReplicas: 3,
+	}
+	u := &fileUploaderImpl{
+		ag: &fixedAuthorGetter{
+			author: nil, // ok since we're passing it into a mocked method anyway
+			logger: logging.NewDevInfoLogger(),
+		},
+		au:  &fixedAuthorUploader{plan: plan},
+		mtg: &fixedMediaTypeGetter{}, // ok that mediaType is nil since passing to mock
+		kc:  &fixedKeychainsGetter{}, // ok that KCs are null for same reason
+	}
+	toUploadFile, err := ioutil.TempFile("", "to-upload")
+	defer func() { cerrors.MaybePanic(os.Remove(toUploadFile.Name())) }()
+	assert.Nil(t, err)
+	err = toUploadFile.Close()
+	assert.Nil(t, err)
+	viper.Set(upFilepathFlag, toUploadFile.Name())
+	viper.Set(dryRunFlag, true)
+	defer viper.Set(dryRunFlag, false)
+
+	err = u.upload()
+	assert.Nil(t, err)
+
+	// dry-run error should bubble up
+	u.au = &fixedAuthorUploader{err: errors.New("some dry-run error")}
+	err = u.upload()
+	assert.NotNil(t, err)
+}
+
 func TestFileUploader_upload_err(t *testing.T) {
 
 	// should error on missing filepath
@@ -257,6 +298,7 @@ func TestKeychainsGetter_get_err(t *testing.T) {
 
 type fixedAuthorUploader struct {
 	envelopeKey id.ID
+	plan        *lauthor.UploadPlan
 	err         error
 }
 
@@ -265,6 +307,11 @@ func (f *fixedAuthorUploader) upload(author *lauthor.Author, content io.Reader,
 	return f.envelopeKey, f.err
 }
 
+func (f *fixedAuthorUploader) dryRun(author *lauthor.Author, content io.Reader, mediaType string) (
+	*lauthor.UploadPlan, error) {
+	return f.plan, f.err
+}
+
 type fixedAuthorGetter struct {
 	author *lauthor.Author
 	logger *zap.Logger
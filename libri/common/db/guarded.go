@@ -0,0 +1,159 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultWriteRetryInterval is the default interval between writes retried by a read-only
+// GuardedDB to check whether the disk has recovered, e.g., after space is freed.
+const DefaultWriteRetryInterval = 30 * time.Second
+
+// ErrReadOnly indicates when a write is rejected because the DB is read-only after a disk-full or
+// IO error.
+var ErrReadOnly = errors.New("DB is read-only after disk write error")
+
+// diskErrMsgs are (lower-case) fragments of the messages of disk-full and IO errors, since the
+// drivers often wrap the underlying syscall errors in strings.
+var diskErrMsgs = []string{
+	"no space left on device",
+	"disk quota exceeded",
+	"input/output error",
+	"read-only file system",
+	"io error",
+}
+
+// IsDiskError returns whether the error is from the disk being full or failing, as opposed to,
+// e.g., a bad key or value.
+func IsDiskError(err error) bool {
+	if err == nil {
+		return false
+	}
+	cause := err
+	switch e := err.(type) {
+	case *os.PathError:
+		cause = e.Err
+	case *os.SyscallError:
+		cause = e.Err
+	}
+	if errno, ok := cause.(syscall.Errno); ok {
+		return errno == syscall.ENOSPC || errno == syscall.EIO || errno == syscall.EROFS
+	}
+	msg := strings.ToLower(err.Error())
+	for _, diskErrMsg := range diskErrMsgs {
+		if strings.Contains(msg, diskErrMsg) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteObserver observes a GuardedDB switching between read-only and writable, e.g., to alert on
+// and report it.
+type WriteObserver interface {
+	// ReadOnly is called when the DB becomes read-only after the given write error.
+	ReadOnly(err error)
+
+	// Writable is called when the DB becomes writable again after a retried write succeeds.
+	Writable()
+}
+
+// GuardedDB is a KVDB that becomes read-only when a write fails because the disk is full or
+// failing, rejecting further writes with ErrReadOnly rather than hammering the disk and leaving
+// partial state. Reads are unaffected.
+type GuardedDB interface {
+	KVDB
+
+	// ReadOnly returns whether the DB is read-only.
+	ReadOnly() bool
+}
+
+type guardedDB struct {
+	KVDB
+	retryInterval time.Duration
+	obs           WriteObserver
+	readOnly      bool
+	retryAfter    time.Time
+	now           func() time.Time
+	mu            sync.Mutex
+}
+
+// NewGuardedDB wraps the KVDB so it becomes read-only after a disk-full or IO error. While
+// read-only, one write is let through every retry interval, and once one succeeds (e.g., after
+// space is freed) the DB becomes writable again.
+func NewGuardedDB(kvdb KVDB, retryInterval time.Duration, obs WriteObserver) GuardedDB {
+	return &guardedDB{
+		KVDB:          kvdb,
+		retryInterval: retryInterval,
+		obs:           obs,
+		now:           time.Now,
+	}
+}
+
+// Put stores the value for a key unless the DB is read-only.
+func (g *guardedDB) Put(key []byte, value []byte) error {
+	if !g.allowWrite() {
+		return ErrReadOnly
+	}
+	return g.wrote(g.KVDB.Put(key, value))
+}
+
+// Delete removes the value for a key. Deletes are attempted even when the DB is read-only, since
+// they can free space.
+func (g *guardedDB) Delete(key []byte) error {
+	return g.wrote(g.KVDB.Delete(key))
+}
+
+func (g *guardedDB) ReadOnly() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.readOnly
+}
+
+// Stats returns the statistics of the wrapped KVDB, if it reports any.
+func (g *guardedDB) Stats() map[string]float64 {
+	if sr, ok := g.KVDB.(StatsReporter); ok {
+		return sr.Stats()
+	}
+	return nil
+}
+
+// allowWrite returns whether a write may be attempted, i.e., the DB is writable or it's time to
+// retry.
+func (g *guardedDB) allowWrite() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.readOnly {
+		return true
+	}
+	if g.now().Before(g.retryAfter) {
+		return false
+	}
+	// push back the next retry so concurrent writes don't all retry at once
+	g.retryAfter = g.now().Add(g.retryInterval)
+	return true
+}
+
+// wrote updates whether the DB is read-only given a write's error, which it returns as ErrReadOnly
+// if it's a disk error.
+func (g *guardedDB) wrote(err error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if IsDiskError(err) {
+		wasReadOnly := g.readOnly
+		g.readOnly, g.retryAfter = true, g.now().Add(g.retryInterval)
+		if !wasReadOnly {
+			g.obs.ReadOnly(err)
+		}
+		return ErrReadOnly
+	}
+	if err == nil && g.readOnly {
+		g.readOnly = false
+		g.obs.Writable()
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDiskError(t *testing.T) {
+	diskErrs := []error{
+		syscall.ENOSPC,
+		syscall.EIO,
+		&os.PathError{Op: "write", Path: "some/db/000001.log", Err: syscall.ENOSPC},
+		os.NewSyscallError("fsync", syscall.EIO),
+		errors.New("IO error: No space left on device: some/db/000001.log"),
+		errors.New("write some/db/MANIFEST: read-only file system"),
+	}
+	for _, err := range diskErrs {
+		assert.True(t, IsDiskError(err), err.Error())
+	}
+
+	otherErrs := []error{
+		nil,
+		syscall.EINVAL,
+		&os.PathError{Op: "open", Path: "some/db/LOCK", Err: syscall.EACCES},
+		errors.New("leveldb: closed"),
+	}
+	for _, err := range otherErrs {
+		assert.False(t, IsDiskError(err))
+	}
+}
+
+func TestGuardedDB(t *testing.T) {
+	fdb := &failingDB{Memory: NewMemoryDB()}
+	obs := &fixedWriteObserver{}
+	g := NewGuardedDB(fdb, time.Minute, obs)
+	now := time.Now()
+	g.(*guardedDB).now = func() time.Time { return now }
+	key, value := []byte("key"), []byte("value")
+
+	assert.Nil(t, g.Put(key, value))
+	assert.False(t, g.ReadOnly())
+
+	// non-disk errors bubble up without making DB read-only
+	fdb.err = errors.New("some Put error")
+	assert.Equal(t, fdb.err, g.Put(key, value))
+	assert.False(t, g.ReadOnly())
+
+	// disk full makes DB read-only
+	fdb.err = syscall.ENOSPC
+	assert.Equal(t, ErrReadOnly, g.Put(key, value))
+	assert.True(t, g.ReadOnly())
+	assert.Equal(t, 1, obs.nReadOnly)
+	assert.Equal(t, syscall.ENOSPC, obs.err)
+
+	// writes rejected w/o trying disk until retry interval passes, but reads still work
+	fdb.err, fdb.nWrites = nil, 0
+	assert.Equal(t, ErrReadOnly, g.Put(key, value))
+	assert.Zero(t, fdb.nWrites)
+	getValue, err := g.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, getValue)
+
+	// failed retry leaves DB read-only without observing it again
+	now = now.Add(time.Minute)
+	fdb.err = syscall.ENOSPC
+	assert.Equal(t, ErrReadOnly, g.Put(key, value))
+	assert.Equal(t, 1, fdb.nWrites)
+	assert.True(t, g.ReadOnly())
+	assert.Equal(t, 1, obs.nReadOnly)
+
+	// successful retry makes DB writable again
+	now = now.Add(time.Minute)
+	fdb.err = nil
+	assert.Nil(t, g.Put(key, value))
+	assert.False(t, g.ReadOnly())
+	assert.Equal(t, 1, obs.nWritable)
+
+	// deletes are attempted even when read-only
+	fdb.err = syscall.EIO
+	assert.Equal(t, ErrReadOnly, g.Delete(key))
+	assert.True(t, g.ReadOnly())
+	fdb.err = nil
+	assert.Nil(t, g.Delete(key))
+	assert.False(t, g.ReadOnly())
+	assert.Equal(t, 2, obs.nReadOnly)
+	assert.Equal(t, 2, obs.nWritable)
+}
+
+// failingDB is a Memory KVDB whose writes return a given error.
+func TestGuardedDB_Stats(t *testing.T) {
+	g := NewGuardedDB(NewMemoryDB(), time.Minute, &fixedWriteObserver{})
+	assert.Nil(t, g.(StatsReporter).Stats())
+
+	stats := map[string]float64{"estimate_num_keys": 2}
+	g = NewGuardedDB(&fixedStatsDB{Memory: NewMemoryDB(), stats: stats}, time.Minute,
+		&fixedWriteObserver{})
+	assert.Equal(t, stats, g.(StatsReporter).Stats())
+}
+
+type failingDB struct {
+	*Memory
+	err     error
+	nWrites int
+}
+
+func (f *failingDB) Put(key []byte, value []byte) error {
+	f.nWrites++
+	if f.err != nil {
+		return f.err
+	}
+	return f.Memory.Put(key, value)
+}
+
+func (f *failingDB) Delete(key []byte) error {
+	f.nWrites++
+	if f.err != nil {
+		return f.err
+	}
+	return f.Memory.Delete(key)
+}
+
+type fixedWriteObserver struct {
+	nReadOnly int
+	nWritable int
+	err       error
+}
+
+func (f *fixedWriteObserver) ReadOnly(err error) {
+	f.nReadOnly++
+	f.err = err
+}
+
+func (f *fixedWriteObserver) Writable() {
+	f.nWritable++
+}
+
+type fixedStatsDB struct {
+	*Memory
+	stats map[string]float64
+}
+
+func (f *fixedStatsDB) Stats() map[string]float64 {
+	return f.stats
+}
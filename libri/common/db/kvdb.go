@@ -6,11 +6,38 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	errors2 "github.com/drausin/libri/libri/common/errors"
-	"github.com/tecbot/gorocksdb"
+)
+
+const (
+	// LevelDBDriver is the name of the pure-Go LevelDB driver.
+	LevelDBDriver = "leveldb"
+
+	// RocksDBDriver is the name of the RocksDB driver, which is only available in binaries built
+	// with the rocksdb build tag (since it requires cgo and the RocksDB library).
+	RocksDBDriver = "rocksdb"
+
+	// DefaultDriver is the name of the driver used when none is specified and the DB directory
+	// doesn't already contain a DB.
+	DefaultDriver = LevelDBDriver
+)
+
+var (
+	// ErrUnknownDriver indicates when a KVDB driver isn't available in this binary.
+	ErrUnknownDriver = errors.New("unknown KVDB driver")
+
+	// ErrDriverMismatch indicates when a DB directory already contains a DB created by a
+	// different driver than the one requested.
+	ErrDriverMismatch = errors.New("DB directory created by a different KVDB driver")
+
+	// ErrLocked indicates when a DB directory is already open by another process, e.g., a running
+	// librarian.
+	ErrLocked = errors.New("DB directory locked by another process")
 )
 
 // KVDB is the (thin) abstraction layer of an implementation-agnostic key-value store.
@@ -31,64 +58,84 @@ type KVDB interface {
 	Close()
 }
 
-// RocksDB implements the KVStore interface with a thinly wrapped RocksDB instance.
-type RocksDB struct {
-	// Pointer to the RocksDB object
-	rdb *gorocksdb.DB
+// StatsReporter is a KVDB that reports internal statistics, e.g., its estimated number of keys
+// and the memory used by its caches.
+type StatsReporter interface {
+	// Stats returns the current value of each statistic by name.
+	Stats() map[string]float64
+}
 
-	// Read options for generic reads
-	ro *gorocksdb.ReadOptions
+// Driver opens a KVDB stored in the given directory, creating it if necessary.
+type Driver func(dbDir string) (KVDB, error)
 
-	// Write options for generic writes
-	wo *gorocksdb.WriteOptions
-}
+var (
+	drivers   = map[string]Driver{}
+	driversMu sync.Mutex
+)
 
-// NewRocksDB creates a new RocksDB instance with default read and write options.
-func NewRocksDB(dbDir string) (*RocksDB, error) {
-	err := os.MkdirAll(dbDir, os.ModePerm)
-	if err != nil {
-		return nil, err
-	}
-	options := newRocksDBOptimizedOptions()
-	db, err := gorocksdb.OpenDb(options, dbDir)
-	if err != nil {
-		return nil, err
+// RegisterDriver makes the KVDB driver available by the given name. It is usually called from the
+// init function of the file implementing the driver.
+func RegisterDriver(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = driver
+}
+
+// Drivers returns the sorted names of the KVDB drivers available in this binary.
+func Drivers() []string {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
 	}
-
-	return &RocksDB{
-		rdb: db,
-		ro:  gorocksdb.NewDefaultReadOptions(),
-		wo:  gorocksdb.NewDefaultWriteOptions(),
-	}, nil
+	sort.Strings(names)
+	return names
 }
 
-func newRocksDBDefaultOptions() *gorocksdb.Options {
-	opts := gorocksdb.NewDefaultOptions()
-	opts.SetCreateIfMissing(true)
-	return opts
+// Open opens the KVDB in the given directory with the named driver. An empty name uses the driver
+// of the DB already in the directory, or the default driver if there isn't one. Opening an existing
+// DB with a different driver returns ErrDriverMismatch rather than silently starting empty.
+func Open(driver string, dbDir string) (KVDB, error) {
+	existing := DetectDriver(dbDir)
+	if driver == "" {
+		driver = existing
+	}
+	if driver == "" {
+		driver = DefaultDriver
+	}
+	if existing != "" && existing != driver {
+		return nil, ErrDriverMismatch
+	}
+	driversMu.Lock()
+	open, in := drivers[driver]
+	driversMu.Unlock()
+	if !in {
+		return nil, ErrUnknownDriver
+	}
+	return open(dbDir)
 }
 
-func newRocksDBOptimizedOptions() *gorocksdb.Options {
-	// TODO (drausin) figure out best way to parameterize this
-	opts := newRocksDBDefaultOptions()
-	opts.IncreaseParallelism(4)
-
-	bbtOpts := gorocksdb.NewDefaultBlockBasedTableOptions()
-	bbtOpts.SetBlockCache(gorocksdb.NewLRUCache(1024 * 1024 * 1024)) // 1 GB
-	bbtOpts.SetBlockSize(32 * 1024)                                  // 32K
-	bbtOpts.SetFilterPolicy(gorocksdb.NewBloomFilter(10))
-	opts.SetBlockBasedTableFactory(bbtOpts)
-
-	opts.SetAllowConcurrentMemtableWrites(true)
-	opts.OptimizeLevelStyleCompaction(500 * 1024 * 1024) // 500 MB memtable
-	opts.SetStatsDumpPeriodSec(10 * 60)
-	return opts
+// DetectDriver returns the name of the driver that created the DB in the given directory, or an
+// empty string if the directory doesn't contain a DB. Both drivers write CURRENT and MANIFEST
+// files, but only RocksDB writes IDENTITY and OPTIONS files.
+func DetectDriver(dbDir string) string {
+	if _, err := os.Stat(filepath.Join(dbDir, "CURRENT")); err != nil {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(dbDir, "IDENTITY")); err == nil {
+		return RocksDBDriver
+	}
+	if options, _ := filepath.Glob(filepath.Join(dbDir, "OPTIONS-*")); len(options) > 0 {
+		return RocksDBDriver
+	}
+	return LevelDBDriver
 }
 
-// NewTempDirRocksDB creates a new RocksDB instance (used mostly for local testing) in a local
-// temporary directory.
-func NewTempDirRocksDB() (*RocksDB, func(), error) {
-	dir, err := ioutil.TempDir("", "kvdb-test-rocksdb")
+// NewTempDirDB creates a new KVDB instance with the default driver (used mostly for local testing)
+// in a local temporary directory.
+func NewTempDirDB() (KVDB, func(), error) {
+	dir, err := ioutil.TempDir("", "kvdb-test")
 	cleanup := func() {
 		rmErr := os.RemoveAll(dir)
 		if rmErr != nil {
@@ -98,58 +145,8 @@ func NewTempDirRocksDB() (*RocksDB, func(), error) {
 	if err != nil {
 		return nil, cleanup, err
 	}
-	rdb, err := NewRocksDB(dir)
-	return rdb, cleanup, err
-}
-
-// Get returns the value for a key.
-func (db *RocksDB) Get(key []byte) ([]byte, error) {
-	// Return copy of bytes instead of a slice to make it simpler for the user. If this proves
-	// slow for large reads we might want to add a separate method for getting the slice
-	// (or an abstraction of it) directly.
-	if db.rdb == nil {
-		return nil, errors.New("rdb is nil")
-	}
-	return db.rdb.GetBytes(db.ro, key)
-}
-
-// Put stores the value for a key.
-func (db *RocksDB) Put(key []byte, value []byte) error {
-	return db.rdb.Put(db.wo, key, value)
-}
-
-// Delete removes the value for a key.
-func (db *RocksDB) Delete(key []byte) error {
-	return db.rdb.Delete(db.wo, key)
-}
-
-// Iterate iterates over the values in the DB.
-func (db *RocksDB) Iterate(
-	keyLB, keyUB []byte, done chan struct{}, callback func(key, value []byte),
-) error {
-	opts := gorocksdb.NewDefaultReadOptions()
-	opts.SetIterateUpperBound(keyUB)
-	opts.SetFillCache(false)
-	iter := db.rdb.NewIterator(opts)
-	defer iter.Close()
-	defer opts.Destroy()
-
-	iter.Seek(keyLB)
-	for ; iter.Valid(); iter.Next() {
-		callback(iter.Key().Data(), iter.Value().Data())
-		select {
-		case <-done:
-			return iter.Err()
-		default:
-			// continue
-		}
-	}
-	return iter.Err()
-}
-
-// Close gracefully shuts down the database.
-func (db *RocksDB) Close() {
-	db.rdb.Close()
+	db, err := Open(DefaultDriver, dir)
+	return db, cleanup, err
 }
 
 // NewMemoryDB creates a new in-memory KVDB.
@@ -1,129 +1,66 @@
 package db
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestRocksDB_NewRocksDB(t *testing.T) {
-	db, cleanup, err := NewTempDirRocksDB()
-	defer cleanup()
-	defer db.Close()
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-test")
 	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
 
-	assert.NotNil(t, db.wo)
-	assert.NotNil(t, db.ro)
-	assert.NotNil(t, db.rdb)
-}
-
-func TestRocksDB_PutGet(t *testing.T) {
-	db, cleanup, err := NewTempDirRocksDB()
-	defer cleanup()
-	defer db.Close()
-	assert.Nil(t, err)
-	key, value1 := []byte("key"), []byte("value1")
-
-	assert.Nil(t, db.Put(key, value1))
-	getValue1, err := db.Get(key)
+	kvdb, err := Open(LevelDBDriver, dir)
 	assert.Nil(t, err)
-	assert.Equal(t, value1, getValue1)
-}
-
-func TestRocksDB_Get_err(t *testing.T) {
-	db := &RocksDB{}
-	value, err := db.Get([]byte("key"))
-	assert.Nil(t, value)
-	assert.NotNil(t, err)
-}
+	_, isLevelDB := kvdb.(*LevelDB)
+	assert.True(t, isLevelDB)
+	kvdb.Close()
 
-func TestRocksDB_PutGetPutGet(t *testing.T) {
-	db, cleanup, err := NewTempDirRocksDB()
-	defer cleanup()
-	defer db.Close()
+	// empty driver uses that of the existing DB
+	kvdb, err = Open("", dir)
 	assert.Nil(t, err)
-	key, value1, value2 := []byte("key"), []byte("value1"), []byte("value2")
+	_, isLevelDB = kvdb.(*LevelDB)
+	assert.True(t, isLevelDB)
+	kvdb.Close()
 
-	assert.Nil(t, db.Put(key, value1))
-	getValue1, err := db.Get(key)
-	assert.Nil(t, err)
-	assert.Equal(t, value1, getValue1)
+	// existing DB isn't opened by a different driver
+	kvdb, err = Open(RocksDBDriver, dir)
+	assert.Nil(t, kvdb)
+	assert.Equal(t, ErrDriverMismatch, err)
 
-	assert.Nil(t, db.Put(key, value2))
-	getValue2, err := db.Get(key)
-	assert.Nil(t, err)
-	assert.Equal(t, value2, getValue2)
+	kvdb, err = Open("unknown", "")
+	assert.Nil(t, kvdb)
+	assert.Equal(t, ErrUnknownDriver, err)
 }
 
-func TestRocksDB_PutGetDeleteGet(t *testing.T) {
-	db, cleanup, err := NewTempDirRocksDB()
-	defer cleanup()
-	defer db.Close()
-	assert.Nil(t, err)
-	key, value1 := []byte("key"), []byte("value1")
-
-	assert.Nil(t, db.Put(key, value1))
-	getValue1, err := db.Get(key)
+func TestDetectDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-test")
 	assert.Nil(t, err)
-	assert.Equal(t, value1, getValue1)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	touch := func(name string) {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0600))
+	}
 
-	assert.Nil(t, db.Delete(key))
-	getValue2, err := db.Get(key)
-	assert.Nil(t, err)
-	assert.Nil(t, getValue2)
-}
+	assert.Equal(t, "", DetectDriver(dir))
+	assert.Equal(t, "", DetectDriver(filepath.Join(dir, "missing")))
 
-func TestRocksDB_Iterate(t *testing.T) {
-	db, cleanup, err := NewTempDirRocksDB()
-	defer cleanup()
-	defer db.Close()
-	assert.Nil(t, err)
+	touch("CURRENT")
+	assert.Equal(t, LevelDBDriver, DetectDriver(dir))
 
-	// add data
-	vals := map[string][]byte{
-		"key1": []byte("val1"),
-		"key2": []byte("val2"),
-		"key3": []byte("val3"),
-	}
-	for key, val := range vals {
-		err = db.Put([]byte(key), val)
-		assert.Nil(t, err)
-	}
+	touch("OPTIONS-000005")
+	assert.Equal(t, RocksDBDriver, DetectDriver(dir))
 
-	// iterate through everything
-	nIters := 0
-	callback := func(key, value []byte) {
-		nIters++
-		expected, in := vals[string(key)]
-		assert.True(t, in)
-		assert.Equal(t, expected, value)
-	}
-	lb, ub := []byte("key0"), []byte("key9")
-	err = db.Iterate(lb, ub, make(chan struct{}), callback)
-	assert.Nil(t, err)
-	assert.Equal(t, len(vals), nIters)
-
-	// iterate through only some
-	nIters = 0
-	lb, ub = []byte("key0"), []byte("key3")
-	err = db.Iterate(lb, ub, make(chan struct{}), callback)
-	assert.Nil(t, err)
-	assert.Equal(t, 2, nIters)
+	assert.Nil(t, os.Remove(filepath.Join(dir, "OPTIONS-000005")))
+	touch("IDENTITY")
+	assert.Equal(t, RocksDBDriver, DetectDriver(dir))
+}
 
-	// iterate through single value and send done signal
-	nIters = 0
-	done := make(chan struct{})
-	callback = func(key, value []byte) {
-		nIters++
-		expected, in := vals[string(key)]
-		assert.True(t, in)
-		assert.Equal(t, expected, value)
-		close(done)
-	}
-	lb, ub = []byte("key0"), []byte("key9")
-	err = db.Iterate(lb, ub, done, callback)
-	assert.Nil(t, err)
-	assert.Equal(t, 1, nIters)
+func TestDrivers(t *testing.T) {
+	assert.Contains(t, Drivers(), DefaultDriver)
 }
 
 func TestMemory_PutGet(t *testing.T) {
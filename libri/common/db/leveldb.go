@@ -0,0 +1,92 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func init() {
+	RegisterDriver(LevelDBDriver, func(dbDir string) (KVDB, error) {
+		return NewLevelDB(dbDir)
+	})
+}
+
+// LevelDB implements the KVDB interface with a thinly wrapped (pure-Go) LevelDB instance.
+type LevelDB struct {
+	// Pointer to the LevelDB object
+	ldb *leveldb.DB
+}
+
+// NewLevelDB creates a new LevelDB instance with default options.
+func NewLevelDB(dbDir string) (*LevelDB, error) {
+	err := os.MkdirAll(dbDir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	db, err := leveldb.OpenFile(dbDir, newLevelDBOptions())
+	if err == syscall.EAGAIN {
+		return nil, ErrLocked
+	} else if err != nil {
+		return nil, err
+	}
+	return &LevelDB{ldb: db}, nil
+}
+
+func newLevelDBOptions() *opt.Options {
+	return &opt.Options{
+		BlockCacheCapacity: 256 * opt.MiB,
+		BlockSize:          32 * opt.KiB,
+		WriteBuffer:        64 * opt.MiB,
+	}
+}
+
+// Get returns the value for a key or nil if the key doesn't exist.
+func (db *LevelDB) Get(key []byte) ([]byte, error) {
+	if db.ldb == nil {
+		return nil, errors.New("ldb is nil")
+	}
+	value, err := db.ldb.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Put stores the value for a key.
+func (db *LevelDB) Put(key []byte, value []byte) error {
+	return db.ldb.Put(key, value, nil)
+}
+
+// Delete removes the value for a key.
+func (db *LevelDB) Delete(key []byte) error {
+	return db.ldb.Delete(key, nil)
+}
+
+// Iterate iterates over the values in the DB.
+func (db *LevelDB) Iterate(
+	keyLB, keyUB []byte, done chan struct{}, callback func(key, value []byte),
+) error {
+	iter := db.ldb.NewIterator(&util.Range{Start: keyLB, Limit: keyUB},
+		&opt.ReadOptions{DontFillCache: true})
+	defer iter.Release()
+	for iter.Next() {
+		callback(iter.Key(), iter.Value())
+		select {
+		case <-done:
+			return iter.Error()
+		default:
+			// continue
+		}
+	}
+	return iter.Error()
+}
+
+// Close gracefully shuts down the database.
+func (db *LevelDB) Close() {
+	_ = db.ldb.Close()
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelDB_NewLevelDB(t *testing.T) {
+	db, cleanup, err := newTempDirLevelDB()
+	defer cleanup()
+	defer db.Close()
+	assert.Nil(t, err)
+
+	assert.NotNil(t, db.ldb)
+}
+
+func TestLevelDB_PutGet(t *testing.T) {
+	db, cleanup, err := newTempDirLevelDB()
+	defer cleanup()
+	defer db.Close()
+	assert.Nil(t, err)
+	key, value1 := []byte("key"), []byte("value1")
+
+	assert.Nil(t, db.Put(key, value1))
+	getValue1, err := db.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value1, getValue1)
+}
+
+func TestLevelDB_NewLevelDB_locked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-test-leveldb")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	db1, err := NewLevelDB(dir)
+	assert.Nil(t, err)
+	defer db1.Close()
+
+	db2, err := NewLevelDB(dir)
+	assert.Nil(t, db2)
+	assert.Equal(t, ErrLocked, err)
+}
+
+func TestLevelDB_Get_err(t *testing.T) {
+	db := &LevelDB{}
+	value, err := db.Get([]byte("key"))
+	assert.Nil(t, value)
+	assert.NotNil(t, err)
+}
+
+func TestLevelDB_PutGetPutGet(t *testing.T) {
+	db, cleanup, err := newTempDirLevelDB()
+	defer cleanup()
+	defer db.Close()
+	assert.Nil(t, err)
+	key, value1, value2 := []byte("key"), []byte("value1"), []byte("value2")
+
+	assert.Nil(t, db.Put(key, value1))
+	getValue1, err := db.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value1, getValue1)
+
+	assert.Nil(t, db.Put(key, value2))
+	getValue2, err := db.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value2, getValue2)
+}
+
+func TestLevelDB_PutGetDeleteGet(t *testing.T) {
+	db, cleanup, err := newTempDirLevelDB()
+	defer cleanup()
+	defer db.Close()
+	assert.Nil(t, err)
+	key, value1 := []byte("key"), []byte("value1")
+
+	assert.Nil(t, db.Put(key, value1))
+	getValue1, err := db.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value1, getValue1)
+
+	assert.Nil(t, db.Delete(key))
+	getValue2, err := db.Get(key)
+	assert.Nil(t, err)
+	assert.Nil(t, getValue2)
+}
+
+func TestLevelDB_Iterate(t *testing.T) {
+	db, cleanup, err := newTempDirLevelDB()
+	defer cleanup()
+	defer db.Close()
+	assert.Nil(t, err)
+
+	// add data
+	vals := map[string][]byte{
+		"key1": []byte("val1"),
+		"key2": []byte("val2"),
+		"key3": []byte("val3"),
+	}
+	for key, val := range vals {
+		err = db.Put([]byte(key), val)
+		assert.Nil(t, err)
+	}
+
+	// iterate through everything
+	nIters := 0
+	callback := func(key, value []byte) {
+		nIters++
+		expected, in := vals[string(key)]
+		assert.True(t, in)
+		assert.Equal(t, expected, value)
+	}
+	lb, ub := []byte("key0"), []byte("key9")
+	err = db.Iterate(lb, ub, make(chan struct{}), callback)
+	assert.Nil(t, err)
+	assert.Equal(t, len(vals), nIters)
+
+	// iterate through only some
+	nIters = 0
+	lb, ub = []byte("key0"), []byte("key3")
+	err = db.Iterate(lb, ub, make(chan struct{}), callback)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, nIters)
+
+	// iterate through single value and send done signal
+	nIters = 0
+	done := make(chan struct{})
+	callback = func(key, value []byte) {
+		nIters++
+		expected, in := vals[string(key)]
+		assert.True(t, in)
+		assert.Equal(t, expected, value)
+		close(done)
+	}
+	lb, ub = []byte("key0"), []byte("key9")
+	err = db.Iterate(lb, ub, done, callback)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, nIters)
+}
+
+func newTempDirLevelDB() (*LevelDB, func(), error) {
+	dir, err := ioutil.TempDir("", "kvdb-test-leveldb")
+	cleanup := func() {
+		rmErr := os.RemoveAll(dir)
+		if rmErr != nil {
+			panic(rmErr)
+		}
+	}
+	if err != nil {
+		return nil, cleanup, err
+	}
+	ldb, err := NewLevelDB(dir)
+	return ldb, cleanup, err
+}
@@ -0,0 +1,170 @@
+// +build rocksdb
+
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tecbot/gorocksdb"
+)
+
+// rocksDBStatProperties are the RocksDB properties reported as statistics.
+var rocksDBStatProperties = []string{
+	"rocksdb.estimate-num-keys",
+	"rocksdb.estimate-live-data-size",
+	"rocksdb.total-sst-files-size",
+	"rocksdb.cur-size-all-mem-tables",
+	"rocksdb.block-cache-usage",
+	"rocksdb.estimate-pending-compaction-bytes",
+	"rocksdb.num-running-compactions",
+	"rocksdb.num-running-flushes",
+}
+
+func init() {
+	RegisterDriver(RocksDBDriver, func(dbDir string) (KVDB, error) {
+		return NewRocksDB(dbDir)
+	})
+}
+
+// RocksDB implements the KVStore interface with a thinly wrapped RocksDB instance.
+type RocksDB struct {
+	// Pointer to the RocksDB object
+	rdb *gorocksdb.DB
+
+	// Read options for generic reads
+	ro *gorocksdb.ReadOptions
+
+	// Write options for generic writes
+	wo *gorocksdb.WriteOptions
+}
+
+// NewRocksDB creates a new RocksDB instance with default read and write options.
+func NewRocksDB(dbDir string) (*RocksDB, error) {
+	err := os.MkdirAll(dbDir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	options := newRocksDBOptimizedOptions()
+	db, err := gorocksdb.OpenDb(options, dbDir)
+	if err != nil && strings.Contains(err.Error(), "LOCK:") {
+		return nil, ErrLocked
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &RocksDB{
+		rdb: db,
+		ro:  gorocksdb.NewDefaultReadOptions(),
+		wo:  gorocksdb.NewDefaultWriteOptions(),
+	}, nil
+}
+
+func newRocksDBDefaultOptions() *gorocksdb.Options {
+	opts := gorocksdb.NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+	return opts
+}
+
+func newRocksDBOptimizedOptions() *gorocksdb.Options {
+	// TODO (drausin) figure out best way to parameterize this
+	opts := newRocksDBDefaultOptions()
+	opts.IncreaseParallelism(4)
+
+	bbtOpts := gorocksdb.NewDefaultBlockBasedTableOptions()
+	bbtOpts.SetBlockCache(gorocksdb.NewLRUCache(1024 * 1024 * 1024)) // 1 GB
+	bbtOpts.SetBlockSize(32 * 1024)                                  // 32K
+	bbtOpts.SetFilterPolicy(gorocksdb.NewBloomFilter(10))
+	opts.SetBlockBasedTableFactory(bbtOpts)
+
+	opts.SetAllowConcurrentMemtableWrites(true)
+	opts.OptimizeLevelStyleCompaction(500 * 1024 * 1024) // 500 MB memtable
+	opts.SetStatsDumpPeriodSec(10 * 60)
+	return opts
+}
+
+// NewTempDirRocksDB creates a new RocksDB instance (used mostly for local testing) in a local
+// temporary directory.
+func NewTempDirRocksDB() (*RocksDB, func(), error) {
+	dir, err := ioutil.TempDir("", "kvdb-test-rocksdb")
+	cleanup := func() {
+		rmErr := os.RemoveAll(dir)
+		if rmErr != nil {
+			panic(rmErr)
+		}
+	}
+	if err != nil {
+		return nil, cleanup, err
+	}
+	rdb, err := NewRocksDB(dir)
+	return rdb, cleanup, err
+}
+
+// Get returns the value for a key.
+func (db *RocksDB) Get(key []byte) ([]byte, error) {
+	// Return copy of bytes instead of a slice to make it simpler for the user. If this proves
+	// slow for large reads we might want to add a separate method for getting the slice
+	// (or an abstraction of it) directly.
+	if db.rdb == nil {
+		return nil, errors.New("rdb is nil")
+	}
+	return db.rdb.GetBytes(db.ro, key)
+}
+
+// Put stores the value for a key.
+func (db *RocksDB) Put(key []byte, value []byte) error {
+	return db.rdb.Put(db.wo, key, value)
+}
+
+// Delete removes the value for a key.
+func (db *RocksDB) Delete(key []byte) error {
+	return db.rdb.Delete(db.wo, key)
+}
+
+// Iterate iterates over the values in the DB.
+func (db *RocksDB) Iterate(
+	keyLB, keyUB []byte, done chan struct{}, callback func(key, value []byte),
+) error {
+	opts := gorocksdb.NewDefaultReadOptions()
+	opts.SetIterateUpperBound(keyUB)
+	opts.SetFillCache(false)
+	iter := db.rdb.NewIterator(opts)
+	defer iter.Close()
+	defer opts.Destroy()
+
+	iter.Seek(keyLB)
+	for ; iter.Valid(); iter.Next() {
+		callback(iter.Key().Data(), iter.Value().Data())
+		select {
+		case <-done:
+			return iter.Err()
+		default:
+			// continue
+		}
+	}
+	return iter.Err()
+}
+
+// Stats returns the RocksDB properties, e.g., rocksdb.estimate-num-keys, as statistics named
+// without the rocksdb prefix, e.g., estimate_num_keys.
+func (db *RocksDB) Stats() map[string]float64 {
+	stats := make(map[string]float64, len(rocksDBStatProperties))
+	for _, property := range rocksDBStatProperties {
+		value, err := strconv.ParseFloat(db.rdb.GetProperty(property), 64)
+		if err != nil {
+			// property not supported by this RocksDB version
+			continue
+		}
+		name := strings.Replace(strings.TrimPrefix(property, "rocksdb."), "-", "_", -1)
+		stats[name] = value
+	}
+	return stats
+}
+
+// Close gracefully shuts down the database.
+func (db *RocksDB) Close() {
+	db.rdb.Close()
+}
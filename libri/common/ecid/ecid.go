@@ -12,12 +12,13 @@ import (
 	cerrors "github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 )
 
 // Curve defines the elliptic curve public & private keys use. Curve S256 implies 32-byte private
-// and 33-byte (compressed) public keys. The X value of the public key point is 32 bytes.
-var Curve = secp256k1.S256()
+// and 33-byte (compressed) public keys. The X value of the public key point is 32 bytes. The curve
+// implementation comes from go-ethereum's crypto package, which uses a cgo libsecp256k1 binding by
+// default and a pure-Go one in binaries built with the nocgo build tag.
+var Curve = crypto.S256()
 
 // CurveName gives the name of the elliptic curve used for the private key.
 const CurveName = "secp256k1"
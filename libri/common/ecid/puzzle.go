@@ -0,0 +1,80 @@
+package ecid
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	mrand "math/rand"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+// MaxPuzzleDifficulty is the maximum number of leading zero bits an ID's puzzle may require,
+// beyond which generating a solving ID would take unreasonably long.
+const MaxPuzzleDifficulty = 32
+
+var (
+	// ErrPuzzleUnsolved indicates when an ID does not solve the required puzzle.
+	ErrPuzzleUnsolved = errors.New("ID does not solve required puzzle")
+
+	// ErrPuzzleTooDifficult indicates when a puzzle difficulty is above MaxPuzzleDifficulty.
+	ErrPuzzleTooDifficult = errors.New("puzzle difficulty above maximum")
+)
+
+// PuzzleBits returns the number of leading zero bits of the SHA-256 hash of the ID, the x-value
+// of its public key. Since finding a key whose hash has d leading zero bits takes 2^d attempts on
+// average, requiring IDs to have a minimum number makes creating many of them (e.g., to eclipse a
+// part of the keyspace) expensive.
+func PuzzleBits(i id.ID) uint {
+	hash := sha256.Sum256(i.Bytes())
+	n := uint(0)
+	for _, b := range hash {
+		if b != 0 {
+			for mask := byte(0x80); b&mask == 0; mask >>= 1 {
+				n++
+			}
+			return n
+		}
+		n += 8
+	}
+	return n
+}
+
+// SolvesPuzzle returns whether the ID has at least the given number of leading zero puzzle bits.
+// A zero difficulty is solved by every ID.
+func SolvesPuzzle(i id.ID, difficulty uint) bool {
+	return difficulty == 0 || PuzzleBits(i) >= difficulty
+}
+
+// CheckPuzzleDifficulty returns ErrPuzzleTooDifficult if the difficulty is above
+// MaxPuzzleDifficulty.
+func CheckPuzzleDifficulty(difficulty uint) error {
+	if difficulty > MaxPuzzleDifficulty {
+		return ErrPuzzleTooDifficult
+	}
+	return nil
+}
+
+// NewRandomSolving creates a new ID solving the puzzle of the given difficulty using a
+// crypto.Reader source of entropy. It panics if the difficulty is above MaxPuzzleDifficulty.
+func NewRandomSolving(difficulty uint) ID {
+	return newRandomSolving(crand.Reader, difficulty)
+}
+
+// NewPseudoRandomSolving creates a new ID solving the puzzle of the given difficulty using a
+// math.Rand source of entropy. It panics if the difficulty is above MaxPuzzleDifficulty.
+func NewPseudoRandomSolving(rng *mrand.Rand, difficulty uint) ID {
+	return newRandomSolving(rng, difficulty)
+}
+
+func newRandomSolving(reader io.Reader, difficulty uint) ID {
+	if err := CheckPuzzleDifficulty(difficulty); err != nil {
+		panic(err)
+	}
+	for {
+		if i := newRandom(reader); SolvesPuzzle(i.ID(), difficulty) {
+			return i
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package ecid
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPuzzleBits(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	nSolving := 0
+	for c := 0; c < 256; c++ {
+		i := id.NewPseudoRandom(rng)
+		bits := PuzzleBits(i)
+		assert.True(t, bits <= 256)
+		assert.True(t, SolvesPuzzle(i, 0))
+		assert.True(t, SolvesPuzzle(i, bits))
+		assert.False(t, SolvesPuzzle(i, bits+1))
+		if bits >= 1 {
+			nSolving++
+		}
+	}
+
+	// about half of IDs should have a leading zero bit
+	assert.True(t, nSolving > 64)
+	assert.True(t, nSolving < 192)
+}
+
+func TestNewPseudoRandomSolving(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, difficulty := range []uint{0, 1, 4, 8} {
+		i := NewPseudoRandomSolving(rng, difficulty)
+		assert.True(t, SolvesPuzzle(i.ID(), difficulty))
+		assert.True(t, PuzzleBits(i.ID()) >= difficulty)
+	}
+	assert.Panics(t, func() {
+		NewPseudoRandomSolving(rng, MaxPuzzleDifficulty+1)
+	})
+}
+
+func TestNewRandomSolving(t *testing.T) {
+	i := NewRandomSolving(4)
+	assert.True(t, SolvesPuzzle(i.ID(), 4))
+}
@@ -6,7 +6,6 @@ import (
 	"math/big"
 
 	"github.com/drausin/libri/libri/common/id"
-	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 )
 
 // FromStored creates a new ID instance from a ECID instance.
@@ -14,8 +13,8 @@ func FromStored(stored *ECDSAPrivateKey) (ID, error) {
 	key := new(ecdsa.PrivateKey)
 
 	switch stored.Curve {
-	case "secp256k1":
-		key.PublicKey.Curve = secp256k1.S256()
+	case CurveName:
+		key.PublicKey.Curve = Curve
 	default:
 		return nil, fmt.Errorf("unrecognized curve %v", stored.Curve)
 	}
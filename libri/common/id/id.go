@@ -20,7 +20,7 @@ const (
 
 var (
 	// UpperBound is the upper bound of the ID space, i.e., all 256 bits on.
-	UpperBound = FromBytes(bytes.Repeat([]byte{255}, Length))
+	UpperBound = UpperBoundOf(Length)
 
 	// LowerBound is the lower bound of the ID space, i.e., all 256 bits off.
 	LowerBound = FromInt(big.NewInt(0))
@@ -106,6 +106,55 @@ func FromString(value string) (ID, error) {
 	return FromBytes(bytesID), nil
 }
 
+// CheckWidth returns an error if a keyspace of the given byte width can't hold IDs, i.e., if it
+// isn't in [1, Length].
+func CheckWidth(width uint) error {
+	if width == 0 || width > Length {
+		return fmt.Errorf("keyspace width %d not in [1, %d]", width, Length)
+	}
+	return nil
+}
+
+// WidthOrDefault returns the given keyspace byte width, or Length if it's zero.
+func WidthOrDefault(width uint) uint {
+	if width == 0 {
+		return Length
+	}
+	return width
+}
+
+// UpperBoundOf returns the upper bound of a keyspace with IDs of the given byte width, i.e., all
+// of its bits on.
+func UpperBoundOf(width uint) ID {
+	return FromBytes(bytes.Repeat([]byte{255}, int(width)))
+}
+
+// InKeyspace returns whether the ID is within the keyspace [LowerBound, UpperBoundOf(width)) of
+// IDs with the given byte width. The upper bound is excluded for consistency with the exclusive
+// upper bounds of routing buckets.
+func InKeyspace(x ID, width uint) bool {
+	return x.Int().Sign() >= 0 && x.Cmp(UpperBoundOf(width)) < 0
+}
+
+// CmpDistance compares the XOR distances of x and y to the target, returning a negative number if
+// x is closer, a positive number if y is closer, and zero if they are equidistant (i.e., x and y
+// are equal).
+func CmpDistance(target, x, y ID) int {
+	return x.Distance(target).Cmp(y.Distance(target))
+}
+
+// SplitLowerBound extends a lower bound one bit deeper with a 1 bit, thereby splitting the domain
+// implied by the current lower bound and depth within a keyspace of nBits bits, e.g., with
+// nBits = 8,
+//
+//	SplitLowerBound(00000000, 0) -> 10000000
+//	SplitLowerBound(10000000, 1) -> 11000000
+//	...
+//	SplitLowerBound(11000000, 4) -> 11001000
+func SplitLowerBound(lowerBound ID, depth, nBits uint) ID {
+	return FromInt(new(big.Int).SetBit(lowerBound.Int(), int(nBits-depth-1), 1))
+}
+
 // NewRandom returns a random 32-byte ID using local machine's local random number generator.
 func NewRandom() ID {
 	b := make([]byte, Length)
@@ -120,6 +169,20 @@ func NewPseudoRandom(rng *mrand.Rand) ID {
 	return FromInt(intVal)
 }
 
+// NewPseudoRandomOfWidth returns a pseudo-random ID within a keyspace of the given byte width.
+func NewPseudoRandomOfWidth(rng *mrand.Rand, width uint) ID {
+	intVal := new(big.Int).Rand(rng, UpperBoundOf(width).Int())
+	return FromInt(intVal)
+}
+
+// NewPseudoRandomInRange returns a pseudo-random ID in the range [lowerBound, upperBound), which
+// must not be empty.
+func NewPseudoRandomInRange(rng *mrand.Rand, lowerBound, upperBound ID) ID {
+	span := new(big.Int).Sub(upperBound.Int(), lowerBound.Int())
+	intVal := new(big.Int).Rand(rng, span)
+	return FromInt(intVal.Add(intVal, lowerBound.Int()))
+}
+
 // FromPublicKey returns an ID instance from an elliptic curve public key.
 func FromPublicKey(pubKey *ecdsa.PublicKey) ID {
 	return FromInt(pubKey.X)
@@ -105,6 +105,50 @@ func TestNewPsuedoRandom(t *testing.T) {
 	}
 }
 
+func TestNewPseudoRandomOfWidth(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for c := 0; c < 10; c++ {
+		val := NewPseudoRandomOfWidth(rng, 2)
+		assert.True(t, val.Cmp(LowerBound) >= 0)
+		assert.True(t, val.Cmp(UpperBoundOf(2)) <= 0)
+		assert.True(t, InKeyspace(val, 2))
+	}
+}
+
+func TestNewPseudoRandomInRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	lb, ub := FromInt64(1000), FromInt64(1010)
+	for c := 0; c < 100; c++ {
+		val := NewPseudoRandomInRange(rng, lb, ub)
+		assert.True(t, val.Cmp(lb) >= 0)
+		assert.True(t, val.Cmp(ub) < 0)
+	}
+
+	// single-ID range
+	assert.Equal(t, lb, NewPseudoRandomInRange(rng, lb, FromInt64(1001)))
+}
+
+func TestCheckWidth(t *testing.T) {
+	assert.Nil(t, CheckWidth(1))
+	assert.Nil(t, CheckWidth(Length))
+	assert.NotNil(t, CheckWidth(0))
+	assert.NotNil(t, CheckWidth(Length+1))
+}
+
+func TestUpperBoundOf(t *testing.T) {
+	assert.Equal(t, UpperBound, UpperBoundOf(Length))
+	assert.Equal(t, FromInt64(65535), UpperBoundOf(2))
+}
+
+func TestInKeyspace(t *testing.T) {
+	assert.True(t, InKeyspace(LowerBound, 1))
+	assert.True(t, InKeyspace(FromInt64(254), 1))
+	assert.False(t, InKeyspace(FromInt64(255), 1)) // upper bound is excluded
+	assert.False(t, InKeyspace(FromInt64(256), 1))
+	assert.True(t, InKeyspace(FromInt64(256), 2))
+	assert.False(t, InKeyspace(UpperBound, Length))
+}
+
 func TestDistance(t *testing.T) {
 	cases := []struct {
 		x   ID
@@ -122,6 +166,41 @@ func TestDistance(t *testing.T) {
 	}
 }
 
+func TestCmpDistance(t *testing.T) {
+	target := FromInt64(8)                                               // 1000
+	assert.True(t, CmpDistance(target, FromInt64(9), FromInt64(0)) < 0)  // 0001 vs 1000
+	assert.True(t, CmpDistance(target, FromInt64(7), FromInt64(12)) > 0) // 1111 vs 0100
+	assert.Equal(t, 0, CmpDistance(target, FromInt64(3), FromInt64(3)))
+	assert.True(t, CmpDistance(target, target, FromInt64(9)) < 0)
+}
+
+func TestSplitLowerBound(t *testing.T) {
+	check := func(lowerBound ID, depth uint, expected ID) {
+		actual := SplitLowerBound(lowerBound, depth, Length*8)
+		assert.Equal(t, expected, actual)
+	}
+
+	check(FromInt64(0), 0, newIDLsh(1, 255))                       // no prefix
+	check(newIDLsh(128, 248), 1, newIDLsh(192, 248))               // prefix 1
+	check(newIDLsh(1, 254), 2, newIDLsh(3, 253))                   // prefix 01
+	check(newIDLsh(3, 254), 2, newIDLsh(7, 253))                   // prefix 11
+	check(newIDLsh(170, 248), 7, newIDLsh(171, 248))               // prefix 1010101
+	check(newIDLsh(128, 248), 7, newIDLsh(129, 248))               // prefix 1000000
+	check(newIDLsh(254, 248), 7, newIDLsh(255, 248))               // prefix 1111111
+	check(newIDLsh(0, 248), 8, newIDLsh(128, 240))                 // prefix 00000000
+	check(newIDLsh(128, 248), 8, newIDLsh(128<<8|128, 240))        // prefix 10000000
+	check(newIDLsh(255, 248), 8, newIDLsh(255<<8|128, 240))        // prefix 11111111
+	check(newIDLsh(0, 240), 9, newIDLsh(64, 240))                  // prefix 00000000 0
+	check(newIDLsh(128, 240), 9, newIDLsh(192, 240))               // prefix 00000000 1
+	check(newIDLsh(255, 248), 9, newIDLsh(255<<8|64, 240))         // prefix 11111111 0
+	check(newIDLsh(255<<8|128, 240), 9, newIDLsh(255<<8|192, 240)) // prefix 11111111 1
+
+	// smaller keyspace
+	assert.Equal(t, FromInt64(128), SplitLowerBound(FromInt64(0), 0, 8))
+	assert.Equal(t, FromInt64(192), SplitLowerBound(FromInt64(128), 1, 8))
+	assert.Equal(t, FromInt64(255), SplitLowerBound(FromInt64(254), 7, 8))
+}
+
 func TestString(t *testing.T) {
 	assert.Equal(t, strings.Repeat("00", Length), FromInt(big.NewInt(0)).String())
 	assert.Equal(t, strings.Repeat("00", Length-1)+"01", FromInt(big.NewInt(1)).String())
@@ -171,3 +250,7 @@ func TestShortHex(t *testing.T) {
 		assert.True(t, len(ShortHex(c)) <= 16)
 	}
 }
+
+func newIDLsh(x int64, n uint) ID {
+	return FromInt(new(big.Int).Lsh(big.NewInt(x), n))
+}
@@ -0,0 +1,193 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultDedupWindow is the default window over which repeated identical errors are collapsed
+	// into a single summary.
+	DefaultDedupWindow = 1 * time.Minute
+
+	// LogNRepeats is the log field key for the number of suppressed repeats of an error.
+	LogNRepeats = "n_repeats"
+
+	// LogRepeatWindow is the log field key for the window over which repeats were suppressed.
+	LogRepeatWindow = "repeat_window"
+
+	errorKey = "error"
+)
+
+// NewDedupLogger wraps the logger's core with one that collapses repeated identical errors. See
+// NewDedupCore.
+func NewDedupLogger(logger *zap.Logger, window time.Duration, keyFields ...string) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return NewDedupCore(core, window, keyFields...)
+	}))
+}
+
+// NewDedupCore wraps a zapcore.Core so that repeated log entries with the same level, message,
+// error, and key field (e.g., peer ID) values are only written once per window. Subsequent repeats
+// within the window are counted and written as a single summary entry once the window has
+// elapsed or the core is synced. Entries without an error field are always written.
+func NewDedupCore(core zapcore.Core, window time.Duration, keyFields ...string) zapcore.Core {
+	return &dedupCore{
+		Core: core,
+		state: &dedupState{
+			window:    window,
+			keyFields: keyFields,
+			seen:      make(map[string]*dedupEntry),
+			now:       time.Now,
+		},
+	}
+}
+
+type dedupCore struct {
+	zapcore.Core
+	state *dedupState
+
+	// context fields added via With, which may contain key fields
+	ctxFields []zapcore.Field
+}
+
+type dedupState struct {
+	window    time.Duration
+	keyFields []string
+	seen      map[string]*dedupEntry
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+type dedupEntry struct {
+	first    time.Time
+	ent      zapcore.Entry
+	fields   []zapcore.Field
+	core     zapcore.Core
+	nRepeats int
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	ctxFields := make([]zapcore.Field, 0, len(c.ctxFields)+len(fields))
+	ctxFields = append(ctxFields, c.ctxFields...)
+	ctxFields = append(ctxFields, fields...)
+	return &dedupCore{
+		Core:      c.Core.With(fields),
+		state:     c.state,
+		ctxFields: ctxFields,
+	}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key, ok := c.dedupKey(ent, fields)
+	if !ok {
+		return c.Core.Write(ent, fields)
+	}
+
+	s := c.state
+	s.mu.Lock()
+	now := s.now()
+	summaries := s.popExpired(now)
+	de, seen := s.seen[key]
+	if seen {
+		de.nRepeats++
+	} else {
+		s.seen[key] = &dedupEntry{first: now, ent: ent, fields: fields, core: c.Core}
+	}
+	s.mu.Unlock()
+
+	err := s.writeSummaries(summaries, now)
+	if seen {
+		return err
+	}
+	if err2 := c.Core.Write(ent, fields); err2 != nil {
+		return err2
+	}
+	return err
+}
+
+func (c *dedupCore) Sync() error {
+	s := c.state
+	s.mu.Lock()
+	now := s.now()
+	summaries := make([]*dedupEntry, 0, len(s.seen))
+	for key, de := range s.seen {
+		if de.nRepeats > 0 {
+			summaries = append(summaries, de)
+		}
+		delete(s.seen, key)
+	}
+	s.mu.Unlock()
+
+	if err := s.writeSummaries(summaries, now); err != nil {
+		return err
+	}
+	return c.Core.Sync()
+}
+
+// dedupKey returns the key identifying repeats of the entry and whether the entry should be
+// deduplicated at all.
+func (c *dedupCore) dedupKey(ent zapcore.Entry, fields []zapcore.Field) (string, bool) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.ctxFields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	errValue, in := enc.Fields[errorKey]
+	if !in {
+		return "", false
+	}
+	parts := []string{ent.Level.String(), ent.Message, fmt.Sprint(errValue)}
+	for _, keyField := range c.state.keyFields {
+		parts = append(parts, fmt.Sprint(enc.Fields[keyField]))
+	}
+	return strings.Join(parts, "|"), true
+}
+
+// popExpired removes the entries whose window has elapsed, returning those with repeats to
+// summarize. It assumes the state lock is held.
+func (s *dedupState) popExpired(now time.Time) []*dedupEntry {
+	var summaries []*dedupEntry
+	for key, de := range s.seen {
+		if now.Sub(de.first) < s.window {
+			continue
+		}
+		if de.nRepeats > 0 {
+			summaries = append(summaries, de)
+		}
+		delete(s.seen, key)
+	}
+	return summaries
+}
+
+func (s *dedupState) writeSummaries(summaries []*dedupEntry, now time.Time) error {
+	var err error
+	for _, de := range summaries {
+		ent := de.ent
+		ent.Time = now
+		fields := make([]zapcore.Field, 0, len(de.fields)+2)
+		fields = append(fields, de.fields...)
+		fields = append(fields,
+			zap.Int(LogNRepeats, de.nRepeats),
+			zap.Duration(LogRepeatWindow, now.Sub(de.first)),
+		)
+		if err2 := de.core.Write(ent, fields); err2 != nil {
+			err = err2
+		}
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDedupCore_Write(t *testing.T) {
+	rec := &recordingCore{LevelEnabler: zap.DebugLevel}
+	core := NewDedupCore(rec, time.Minute, "peer_id")
+	now := time.Unix(0, 0)
+	core.(*dedupCore).state.now = func() time.Time { return now }
+	logger := zap.New(core)
+	err1, err2 := errors.New("some error 1"), errors.New("some error 2")
+
+	// entries without errors are always written
+	logger.Info("no error")
+	logger.Info("no error")
+	assert.Len(t, rec.entries, 2)
+
+	// repeats of the same error & peer are suppressed
+	for i := 0; i < 5; i++ {
+		logger.Info("query failed", zap.String("peer_id", "peer 1"), zap.Error(err1))
+	}
+	assert.Len(t, rec.entries, 3)
+
+	// but different errors, peers, and messages are not
+	logger.Info("query failed", zap.String("peer_id", "peer 1"), zap.Error(err2))
+	logger.Info("query failed", zap.String("peer_id", "peer 2"), zap.Error(err1))
+	logger.With(zap.String("peer_id", "peer 3")).Info("query failed", zap.Error(err1))
+	logger.Info("other query failed", zap.String("peer_id", "peer 1"), zap.Error(err1))
+	assert.Len(t, rec.entries, 7)
+
+	// after the window, the next write flushes the summary of suppressed repeats
+	now = now.Add(time.Minute)
+	logger.Info("query failed", zap.String("peer_id", "peer 1"), zap.Error(err1))
+	assert.Len(t, rec.entries, 9)
+	summary := rec.entries[7]
+	assert.Equal(t, "query failed", summary.ent.Message)
+	assert.Equal(t, int64(4), fieldValue(summary.fields, LogNRepeats).Integer)
+	assert.Equal(t, int64(time.Minute), fieldValue(summary.fields, LogRepeatWindow).Integer)
+	assert.Nil(t, fieldValue(rec.entries[8].fields, LogNRepeats))
+}
+
+func TestDedupCore_Sync(t *testing.T) {
+	rec := &recordingCore{LevelEnabler: zap.DebugLevel}
+	logger := zap.New(NewDedupCore(rec, time.Minute))
+	err := errors.New("some error")
+	for i := 0; i < 3; i++ {
+		logger.Error("query failed", zap.Error(err))
+	}
+	logger.Error("other query failed", zap.Error(err))
+	assert.Len(t, rec.entries, 2)
+
+	// sync should flush summaries of entries with repeats
+	assert.Nil(t, logger.Sync())
+	assert.Len(t, rec.entries, 3)
+	assert.Equal(t, int64(2), fieldValue(rec.entries[2].fields, LogNRepeats).Integer)
+	assert.Equal(t, 1, rec.nSyncs)
+
+	// and reset the state
+	logger.Error("query failed", zap.Error(err))
+	assert.Len(t, rec.entries, 4)
+}
+
+func TestDedupCore_Check(t *testing.T) {
+	rec := &recordingCore{LevelEnabler: zap.InfoLevel}
+	logger := NewDedupLogger(zap.New(rec), time.Minute)
+	logger.Debug("some debug", zap.Error(errors.New("some error")))
+	assert.Len(t, rec.entries, 0)
+	logger.Info("some info", zap.Error(errors.New("some error")))
+	assert.Len(t, rec.entries, 1)
+}
+
+type recordedEntry struct {
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+type recordingCore struct {
+	zapcore.LevelEnabler
+	entries []*recordedEntry
+	nSyncs  int
+}
+
+func (c *recordingCore) With(fields []zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *recordingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.LevelEnabler.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *recordingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.entries = append(c.entries, &recordedEntry{ent: ent, fields: fields})
+	return nil
+}
+
+func (c *recordingCore) Sync() error {
+	c.nSyncs++
+	return nil
+}
+
+func fieldValue(fields []zapcore.Field, key string) *zapcore.Field {
+	for i := range fields {
+		if fields[i].Key == key {
+			return &fields[i]
+		}
+	}
+	return nil
+}
@@ -5,6 +5,10 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
 )
 
 // Checker checks that a key or value is value.
@@ -105,3 +109,76 @@ func (hc *hashChecker) Check(key []byte, value []byte) error {
 	}
 	return nil
 }
+
+type documentChecker struct {
+	hc KeyValueChecker
+}
+
+// NewDocumentKeyValueChecker returns a new KeyValueChecker for marshaled api.Document values.
+// Keys outside the reserved system and shard keyspaces must be the SHA256 hash of the value, while
+// keys inside them must hold a system or shard document whose derived key equals the key. System
+// documents must also match their checksum.
+func NewDocumentKeyValueChecker() KeyValueChecker {
+	return &documentChecker{
+		hc: NewHashKeyValueChecker(),
+	}
+}
+
+func (dc *documentChecker) Check(key []byte, value []byte) error {
+	if len(key) != id.Length {
+		return dc.hc.Check(key, value)
+	}
+	if api.IsShardKey(id.FromBytes(key)) {
+		return dc.checkShard(key, value)
+	}
+	if api.IsShardManifestKey(id.FromBytes(key)) {
+		return dc.checkShardManifest(key, value)
+	}
+	if !api.IsSystemKey(id.FromBytes(key)) {
+		return dc.hc.Check(key, value)
+	}
+	doc := &api.Document{}
+	if err := proto.Unmarshal(value, doc); err != nil {
+		return err
+	}
+	sys := doc.GetSystem()
+	if sys == nil || sys.Record == nil {
+		return api.ErrReservedKey
+	}
+	sysKey := api.GetSystemKey(sys.Record.Type, sys.PubKey, sys.Record.Name)
+	if !bytes.Equal(key, sysKey.Bytes()) {
+		return api.ErrUnexpectedKey
+	}
+	// since the key isn't a hash of the value, the checksum is what catches corruption
+	return api.CheckSystemChecksum(sys)
+}
+
+func (dc *documentChecker) checkShard(key []byte, value []byte) error {
+	doc := &api.Document{}
+	if err := proto.Unmarshal(value, doc); err != nil {
+		return err
+	}
+	shard := doc.GetShard()
+	if shard == nil {
+		return api.ErrReservedShardKey
+	}
+	if !bytes.Equal(key, api.GetShardKey(shard).Bytes()) {
+		return api.ErrUnexpectedKey
+	}
+	return nil
+}
+
+func (dc *documentChecker) checkShardManifest(key []byte, value []byte) error {
+	doc := &api.Document{}
+	if err := proto.Unmarshal(value, doc); err != nil {
+		return err
+	}
+	manifest := doc.GetShardManifest()
+	if manifest == nil {
+		return api.ErrReservedShardManifestKey
+	}
+	if !bytes.Equal(key, api.GetShardManifestKey(manifest.OriginalKey).Bytes()) {
+		return api.ErrUnexpectedKey
+	}
+	return nil
+}
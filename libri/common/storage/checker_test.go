@@ -3,8 +3,11 @@ package storage
 import (
 	"bytes"
 	"crypto/sha256"
+	"math/rand"
 	"testing"
 
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -107,3 +110,90 @@ func TestHashChecker_Check_err(t *testing.T) {
 	c := NewHashKeyValueChecker()
 	assert.NotNil(t, c.Check([]byte{0, 1, 2}, []byte{0, 1, 2}))
 }
+
+func TestDocumentChecker_Check_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	c := NewDocumentKeyValueChecker()
+
+	// content doc keyed by hash
+	value, key := api.NewTestDocument(rng)
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Check(key.Bytes(), valueBytes))
+
+	// system doc keyed by system key
+	value, key = api.NewTestSystemDocument(rng)
+	valueBytes, err = proto.Marshal(value)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Check(key.Bytes(), valueBytes))
+
+	// shard doc keyed by shard key
+	value, key = api.NewTestShardDocument(rng)
+	valueBytes, err = proto.Marshal(value)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Check(key.Bytes(), valueBytes))
+
+	// shard manifest doc keyed by shard manifest key
+	value, key = api.NewTestShardManifestDocument(rng)
+	valueBytes, err = proto.Marshal(value)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Check(key.Bytes(), valueBytes))
+}
+
+func TestDocumentChecker_Check_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	c := NewDocumentKeyValueChecker()
+	sysValue, sysKey := api.NewTestSystemDocument(rng)
+	sysValueBytes, err := proto.Marshal(sysValue)
+	assert.Nil(t, err)
+
+	// content doc with bad hash
+	assert.NotNil(t, c.Check([]byte{0, 1, 2}, []byte{0, 1, 2}))
+
+	// value under system key can't be unmarshaled
+	assert.NotNil(t, c.Check(sysKey.Bytes(), []byte{255, 255, 255}))
+
+	// content doc under system key
+	value, _ := api.NewTestDocument(rng)
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	assert.Equal(t, api.ErrReservedKey, c.Check(sysKey.Bytes(), valueBytes))
+
+	// system doc under another system key
+	_, otherSysKey := api.NewTestSystemDocument(rng)
+	assert.Equal(t, api.ErrUnexpectedKey, c.Check(otherSysKey.Bytes(), sysValueBytes))
+
+	// system doc whose record doesn't match its checksum
+	sysValue.GetSystem().Record.Contents = api.RandBytes(rng, 64)
+	corruptBytes, err := proto.Marshal(sysValue)
+	assert.Nil(t, err)
+	assert.Equal(t, api.ErrUnexpectedChecksum, c.Check(sysKey.Bytes(), corruptBytes))
+
+	// value under shard key can't be unmarshaled
+	shardValue, shardKey := api.NewTestShardDocument(rng)
+	assert.NotNil(t, c.Check(shardKey.Bytes(), []byte{255, 255, 255}))
+
+	// content doc under shard key
+	assert.Equal(t, api.ErrReservedShardKey, c.Check(shardKey.Bytes(), valueBytes))
+
+	// shard doc under another shard key
+	shardValueBytes, err := proto.Marshal(shardValue)
+	assert.Nil(t, err)
+	_, otherShardKey := api.NewTestShardDocument(rng)
+	assert.Equal(t, api.ErrUnexpectedKey, c.Check(otherShardKey.Bytes(), shardValueBytes))
+
+	// value under shard manifest key can't be unmarshaled
+	manifestValue, manifestKey := api.NewTestShardManifestDocument(rng)
+	assert.NotNil(t, c.Check(manifestKey.Bytes(), []byte{255, 255, 255}))
+
+	// shard doc under shard manifest key
+	assert.Equal(t, api.ErrReservedShardManifestKey,
+		c.Check(manifestKey.Bytes(), shardValueBytes))
+
+	// shard manifest doc under another shard manifest key
+	manifestValueBytes, err := proto.Marshal(manifestValue)
+	assert.Nil(t, err)
+	_, otherManifestKey := api.NewTestShardManifestDocument(rng)
+	assert.Equal(t, api.ErrUnexpectedKey,
+		c.Check(otherManifestKey.Bytes(), manifestValueBytes))
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+)
+
+// expireTimeLength is the length of stored (big-endian Unix second) expiration times.
+const expireTimeLength = 8
+
+// Expiries namespace contains the expiration times of documents stored with a TTL, keyed by
+// document key.
+var Expiries = []byte("expiries")
+
+// ExpiringDocumentSLD is a DocumentSLD whose documents may be stored with an expiration time.
+// Expired documents are treated as missing until Sweep deletes them.
+type ExpiringDocumentSLD interface {
+	DocumentSLD
+
+	// StoreExpiring stores the document under the given key until the given expiration time.
+	StoreExpiring(key id.ID, value *api.Document, expires time.Time) error
+
+	// ExpireTime returns the expiration time of the document with the given key and whether it
+	// has one.
+	ExpireTime(key id.ID) (time.Time, bool, error)
+
+	// SetExpireTime changes the expiration time of the stored document with the given key,
+	// removing it if the given time is zero.
+	SetExpireTime(key id.ID, expires time.Time) error
+
+	// Sweep deletes up to max documents that expired before the given time, returning the number
+	// deleted.
+	Sweep(now time.Time, max uint) (uint, error)
+}
+
+type expiringDocumentSLD struct {
+	DocumentSLD
+	expiries StorerLoaderDeleter
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// NewExpiringDocumentSLD returns an ExpiringDocumentSLD wrapping the given DocumentSLD, with the
+// expiration times of its documents indexed in the "expiries" namespace of the given DB.
+func NewExpiringDocumentSLD(inner DocumentSLD, kvdb db.KVDB) ExpiringDocumentSLD {
+	return &expiringDocumentSLD{
+		DocumentSLD: inner,
+		expiries: NewKVDBStorerLoaderDeleter(
+			Expiries,
+			kvdb,
+			NewExactLengthChecker(id.Length),
+			NewExactLengthChecker(expireTimeLength),
+		),
+		now: time.Now,
+	}
+}
+
+// Store stores the document without an expiration time, removing any it had before.
+func (e *expiringDocumentSLD) Store(key id.ID, value *api.Document) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.DocumentSLD.Store(key, value); err != nil {
+		return err
+	}
+	return e.expiries.Delete(key.Bytes())
+}
+
+func (e *expiringDocumentSLD) StoreExpiring(
+	key id.ID, value *api.Document, expires time.Time,
+) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// index expiration first, so the document is never stored without it
+	if err := e.expiries.Store(key.Bytes(), encodeExpireTime(expires)); err != nil {
+		return err
+	}
+	return e.DocumentSLD.Store(key, value)
+}
+
+func (e *expiringDocumentSLD) Iterate(
+	done chan struct{}, callback func(key id.ID, value []byte),
+) error {
+	// collect expired keys first, since loading while iterating over the DB isn't safe
+	expired := make(map[string]struct{})
+	err := e.iterateExpired(e.now(), func(key id.ID) bool {
+		expired[key.String()] = struct{}{}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return e.DocumentSLD.Iterate(done, func(key id.ID, value []byte) {
+		if _, in := expired[key.String()]; !in {
+			callback(key, value)
+		}
+	})
+}
+
+func (e *expiringDocumentSLD) Load(key id.ID) (*api.Document, error) {
+	if expired, err := e.expired(key); err != nil || expired {
+		return nil, err
+	}
+	return e.DocumentSLD.Load(key)
+}
+
+func (e *expiringDocumentSLD) Mac(key id.ID, macKey []byte) ([]byte, error) {
+	if expired, err := e.expired(key); err != nil || expired {
+		return nil, err
+	}
+	return e.DocumentSLD.Mac(key, macKey)
+}
+
+func (e *expiringDocumentSLD) Delete(key id.ID) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.delete(key)
+}
+
+func (e *expiringDocumentSLD) ExpireTime(key id.ID) (time.Time, bool, error) {
+	expiresBytes, err := e.expiries.Load(key.Bytes())
+	if err != nil || expiresBytes == nil {
+		return time.Time{}, false, err
+	}
+	return decodeExpireTime(expiresBytes), true, nil
+}
+
+func (e *expiringDocumentSLD) SetExpireTime(key id.ID, expires time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if expires.IsZero() {
+		return e.expiries.Delete(key.Bytes())
+	}
+	return e.expiries.Store(key.Bytes(), encodeExpireTime(expires))
+}
+
+func (e *expiringDocumentSLD) Sweep(now time.Time, max uint) (uint, error) {
+	// collect keys first, since deleting documents while iterating over them isn't safe
+	keys := make([]id.ID, 0)
+	err := e.iterateExpired(now, func(key id.ID) bool {
+		if uint(len(keys)) >= max {
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	nSwept := uint(0)
+	for _, key := range keys {
+		expires, in, err := e.ExpireTime(key)
+		if err != nil {
+			return nSwept, err
+		}
+		if !in || !expires.Before(now) {
+			// re-stored since collecting keys
+			continue
+		}
+		if err := e.delete(key); err != nil {
+			return nSwept, err
+		}
+		nSwept++
+	}
+	return nSwept, nil
+}
+
+func (e *expiringDocumentSLD) delete(key id.ID) error {
+	if err := e.DocumentSLD.Delete(key); err != nil {
+		return err
+	}
+	return e.expiries.Delete(key.Bytes())
+}
+
+func (e *expiringDocumentSLD) expired(key id.ID) (bool, error) {
+	expires, in, err := e.ExpireTime(key)
+	if err != nil || !in {
+		return false, err
+	}
+	return expires.Before(e.now()), nil
+}
+
+// iterateExpired calls the callback with the key of each document expired before the given time
+// until it returns false.
+func (e *expiringDocumentSLD) iterateExpired(now time.Time, callback func(key id.ID) bool) error {
+	done := make(chan struct{})
+	lb, ub := id.LowerBound.Bytes(), id.UpperBound.Bytes()
+	return e.expiries.Iterate(lb, ub, done, func(key, value []byte) {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if len(value) == expireTimeLength && decodeExpireTime(value).Before(now) {
+			if !callback(id.FromBytes(key)) {
+				close(done)
+			}
+		}
+	})
+}
+
+func encodeExpireTime(expires time.Time) []byte {
+	expiresBytes := make([]byte, expireTimeLength)
+	binary.BigEndian.PutUint64(expiresBytes, uint64(expires.Unix()))
+	return expiresBytes
+}
+
+func decodeExpireTime(expiresBytes []byte) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(expiresBytes)), 0)
+}
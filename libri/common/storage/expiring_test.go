@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiringDocumentSLD_StoreLoadSweep(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	esld := NewExpiringDocumentSLD(NewDocumentSLD(kvdb), kvdb)
+	now := time.Now()
+	esld.(*expiringDocumentSLD).now = func() time.Time { return now }
+
+	value1, key1 := api.NewTestDocument(rng)
+	value2, key2 := api.NewTestDocument(rng)
+	value3, key3 := api.NewTestDocument(rng)
+	assert.Nil(t, esld.Store(key1, value1))
+	assert.Nil(t, esld.StoreExpiring(key2, value2, now.Add(time.Minute)))
+	assert.Nil(t, esld.StoreExpiring(key3, value3, now.Add(time.Hour)))
+
+	_, in, err := esld.ExpireTime(key1)
+	assert.Nil(t, err)
+	assert.False(t, in)
+	expires, in, err := esld.ExpireTime(key2)
+	assert.Nil(t, err)
+	assert.True(t, in)
+	assert.Equal(t, now.Add(time.Minute).Unix(), expires.Unix())
+
+	// nothing expired yet
+	for key, value := range map[id.ID]*api.Document{key1: value1, key2: value2, key3: value3} {
+		loaded, err2 := esld.Load(key)
+		assert.Nil(t, err2)
+		assert.Equal(t, value, loaded)
+	}
+
+	// expired documents are missing before they're swept
+	now = now.Add(2 * time.Minute)
+	loaded, err := esld.Load(key2)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+	mac, err := esld.Mac(key2, []byte("some MAC key"))
+	assert.Nil(t, err)
+	assert.Nil(t, mac)
+	iterated := make(map[string]struct{})
+	err = esld.Iterate(make(chan struct{}), func(key id.ID, value []byte) {
+		iterated[key.String()] = struct{}{}
+	})
+	assert.Nil(t, err)
+	assert.Len(t, iterated, 2)
+	assert.NotContains(t, iterated, key2.String())
+
+	// only expired documents are swept
+	nSwept, err := esld.Sweep(now, 8)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), nSwept)
+	inner, err := NewDocumentSLD(kvdb).Load(key2)
+	assert.Nil(t, err)
+	assert.Nil(t, inner)
+	_, in, err = esld.ExpireTime(key2)
+	assert.Nil(t, err)
+	assert.False(t, in)
+
+	// storing without an expiration removes the existing one
+	assert.Nil(t, esld.Store(key3, value3))
+	nSwept, err = esld.Sweep(now.Add(2*time.Hour), 8)
+	assert.Nil(t, err)
+	assert.Zero(t, nSwept)
+	loaded, err = esld.Load(key3)
+	assert.Nil(t, err)
+	assert.Equal(t, value3, loaded)
+}
+
+func TestExpiringDocumentSLD_Sweep_max(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	esld := NewExpiringDocumentSLD(NewDocumentSLD(kvdb), kvdb)
+	now := time.Now()
+	for c := 0; c < 4; c++ {
+		value, key := api.NewTestDocument(rng)
+		assert.Nil(t, esld.StoreExpiring(key, value, now.Add(-time.Minute)))
+	}
+
+	nSwept, err := esld.Sweep(now, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(3), nSwept)
+	nSwept, err = esld.Sweep(now, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), nSwept)
+}
+
+func TestExpiringDocumentSLD_Delete(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	esld := NewExpiringDocumentSLD(NewDocumentSLD(kvdb), kvdb)
+	value, key := api.NewTestDocument(rng)
+	assert.Nil(t, esld.StoreExpiring(key, value, time.Now().Add(time.Hour)))
+
+	assert.Nil(t, esld.Delete(key))
+	loaded, err := esld.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+	_, in, err := esld.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.False(t, in)
+}
+
+func TestExpiringDocumentSLD_SetExpireTime(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	esld := NewExpiringDocumentSLD(NewDocumentSLD(kvdb), kvdb)
+	value, key := api.NewTestDocument(rng)
+	assert.Nil(t, esld.Store(key, value))
+
+	expires := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	assert.Nil(t, esld.SetExpireTime(key, expires))
+	loadedExpires, in, err := esld.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.True(t, in)
+	assert.Equal(t, expires, loadedExpires)
+
+	// zero time removes expire time
+	assert.Nil(t, esld.SetExpireTime(key, time.Time{}))
+	_, in, err = esld.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.False(t, in)
+	loaded, err := esld.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, loaded)
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+)
+
+// DocumentHooks are called on document storage events, allowing extensions like indexers,
+// external backups, or alerting to be attached to a DocumentSLD. Hooks are called synchronously
+// after the event has happened, so implementations that do slow work should do it asynchronously.
+type DocumentHooks interface {
+	// OnPut is called after a document has been stored.
+	OnPut(key id.ID, value *api.Document)
+
+	// OnDelete is called after a document has been deleted, including when it is removed for
+	// failing verification.
+	OnDelete(key id.ID)
+
+	// OnVerifyFail is called when a stored document fails verification against its key, just
+	// before OnDelete is called for its removal.
+	OnVerifyFail(key id.ID, err error)
+}
+
+// MultiDocumentHooks calls each of its DocumentHooks in order.
+type MultiDocumentHooks []DocumentHooks
+
+// OnPut calls OnPut on each of the hooks.
+func (mh MultiDocumentHooks) OnPut(key id.ID, value *api.Document) {
+	for _, h := range mh {
+		h.OnPut(key, value)
+	}
+}
+
+// OnDelete calls OnDelete on each of the hooks.
+func (mh MultiDocumentHooks) OnDelete(key id.ID) {
+	for _, h := range mh {
+		h.OnDelete(key)
+	}
+}
+
+// OnVerifyFail calls OnVerifyFail on each of the hooks.
+func (mh MultiDocumentHooks) OnVerifyFail(key id.ID, err error) {
+	for _, h := range mh {
+		h.OnVerifyFail(key, err)
+	}
+}
+
+// DocumentHookFuncs implements DocumentHooks with optional callbacks, so callers need only
+// register the events they care about. Nil callbacks are ignored.
+type DocumentHookFuncs struct {
+	Put        func(key id.ID, value *api.Document)
+	Delete     func(key id.ID)
+	VerifyFail func(key id.ID, err error)
+}
+
+// OnPut calls the Put callback if it is set.
+func (hf *DocumentHookFuncs) OnPut(key id.ID, value *api.Document) {
+	if hf.Put != nil {
+		hf.Put(key, value)
+	}
+}
+
+// OnDelete calls the Delete callback if it is set.
+func (hf *DocumentHookFuncs) OnDelete(key id.ID) {
+	if hf.Delete != nil {
+		hf.Delete(key)
+	}
+}
+
+// OnVerifyFail calls the VerifyFail callback if it is set.
+func (hf *DocumentHookFuncs) OnVerifyFail(key id.ID, err error) {
+	if hf.VerifyFail != nil {
+		hf.VerifyFail(key, err)
+	}
+}
+
+type hookedDocumentSLD struct {
+	DocumentSLD
+	hooks DocumentHooks
+}
+
+// NewHookedDocumentSLD wraps a DocumentSLD so the given hooks are called on successful stores and
+// deletes and on documents that fail verification when loaded or MACed.
+func NewHookedDocumentSLD(inner DocumentSLD, hooks DocumentHooks) DocumentSLD {
+	return &hookedDocumentSLD{
+		DocumentSLD: inner,
+		hooks:       hooks,
+	}
+}
+
+func (h *hookedDocumentSLD) Store(key id.ID, value *api.Document) error {
+	if err := h.DocumentSLD.Store(key, value); err != nil {
+		return err
+	}
+	h.hooks.OnPut(key, value)
+	return nil
+}
+
+func (h *hookedDocumentSLD) Load(key id.ID) (*api.Document, error) {
+	value, err := h.DocumentSLD.Load(key)
+	h.maybeVerifyFail(key, err)
+	return value, err
+}
+
+func (h *hookedDocumentSLD) Mac(key id.ID, macKey []byte) ([]byte, error) {
+	mac, err := h.DocumentSLD.Mac(key, macKey)
+	h.maybeVerifyFail(key, err)
+	return mac, err
+}
+
+func (h *hookedDocumentSLD) Delete(key id.ID) error {
+	if err := h.DocumentSLD.Delete(key); err != nil {
+		return err
+	}
+	h.hooks.OnDelete(key)
+	return nil
+}
+
+func (h *hookedDocumentSLD) maybeVerifyFail(key id.ID, err error) {
+	if err == ErrCorruptDocument {
+		// inner DocumentSLD has already removed the corrupt document
+		h.hooks.OnVerifyFail(key, err)
+		h.hooks.OnDelete(key)
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookedDocumentSLD_StoreDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hooks := &recordingHooks{}
+	dsld := NewHookedDocumentSLD(NewDocumentSLD(db.NewMemoryDB()), hooks)
+	value, key := api.NewTestDocument(rng)
+
+	assert.Nil(t, dsld.Store(key, value))
+	assert.Equal(t, []string{"put " + key.String()}, hooks.events)
+
+	loaded, err := dsld.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, loaded)
+	assert.Len(t, hooks.events, 1)
+
+	assert.Nil(t, dsld.Delete(key))
+	assert.Equal(t, "delete "+key.String(), hooks.events[1])
+}
+
+func TestHookedDocumentSLD_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hooks := &recordingHooks{}
+	inner := &TestDocSLD{
+		Stored:    make(map[string]*api.Document),
+		StoreErr:  errors.New("some Store error"),
+		DeleteErr: errors.New("some Delete error"),
+	}
+	dsld := NewHookedDocumentSLD(inner, hooks)
+	value, key := api.NewTestDocument(rng)
+
+	assert.NotNil(t, dsld.Store(key, value))
+	assert.NotNil(t, dsld.Delete(key))
+	assert.Len(t, hooks.events, 0)
+}
+
+func TestHookedDocumentSLD_verifyFail(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, _ := api.NewTestDocument(rng)
+	key := id.NewPseudoRandom(rng)
+	kvdb := db.NewMemoryDB()
+	hooks := &recordingHooks{}
+	dsld := NewHookedDocumentSLD(NewDocumentSLD(kvdb), hooks)
+
+	// hackily put a value with a non-hash key; should never happen in the wild
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	err = kvdb.Put(append(Documents, key.Bytes()...), valueBytes)
+	assert.Nil(t, err)
+
+	_, err = dsld.Load(key)
+	assert.Equal(t, ErrCorruptDocument, err)
+	assert.Equal(t, []string{"verifyFail " + key.String(), "delete " + key.String()},
+		hooks.events)
+
+	// same for Mac
+	err = kvdb.Put(append(Documents, key.Bytes()...), valueBytes)
+	assert.Nil(t, err)
+	_, err = dsld.Mac(key, []byte("some MAC key"))
+	assert.Equal(t, ErrCorruptDocument, err)
+	assert.Len(t, hooks.events, 4)
+}
+
+func TestMultiDocumentHooks(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	h1, h2 := &recordingHooks{}, &recordingHooks{}
+	mh := MultiDocumentHooks{h1, h2}
+	value, key := api.NewTestDocument(rng)
+
+	mh.OnPut(key, value)
+	mh.OnDelete(key)
+	mh.OnVerifyFail(key, ErrCorruptDocument)
+	assert.Len(t, h1.events, 3)
+	assert.Equal(t, h1.events, h2.events)
+}
+
+func TestDocumentHookFuncs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+
+	// nil callbacks are ignored
+	hf := &DocumentHookFuncs{}
+	hf.OnPut(key, value)
+	hf.OnDelete(key)
+	hf.OnVerifyFail(key, ErrCorruptDocument)
+
+	nCalls := 0
+	hf = &DocumentHookFuncs{
+		Put:        func(key id.ID, value *api.Document) { nCalls++ },
+		Delete:     func(key id.ID) { nCalls++ },
+		VerifyFail: func(key id.ID, err error) { nCalls++ },
+	}
+	hf.OnPut(key, value)
+	hf.OnDelete(key)
+	hf.OnVerifyFail(key, ErrCorruptDocument)
+	assert.Equal(t, 3, nCalls)
+}
+
+type recordingHooks struct {
+	events []string
+}
+
+func (h *recordingHooks) OnPut(key id.ID, value *api.Document) {
+	h.events = append(h.events, "put "+key.String())
+}
+
+func (h *recordingHooks) OnDelete(key id.ID) {
+	h.events = append(h.events, "delete "+key.String())
+}
+
+func (h *recordingHooks) OnVerifyFail(key id.ID, err error) {
+	h.events = append(h.events, "verifyFail "+key.String())
+}
@@ -3,9 +3,10 @@ package storage
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"errors"
 
 	"github.com/drausin/libri/libri/common/db"
-	"github.com/drausin/libri/libri/common/errors"
+	cerrors "github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/golang/protobuf/proto"
@@ -21,6 +22,10 @@ const (
 	// EntriesKeyLength is the fixed length (in Bytes) of all entry keys.
 	EntriesKeyLength = 32
 
+	// HintKeyLength is the fixed length (in Bytes) of all hint keys, the document key followed by
+	// the ID of its intended holder.
+	HintKeyLength = 2 * id.Length
+
 	// MaxEntriesValueLength is the maximum length of all entry values. We add a little buffer
 	// on top of the value to account for Entry values other than the actual ciphertext (which
 	// we want to be <= 2MB).
@@ -36,6 +41,24 @@ var (
 
 	// Documents namespace contains all libri p2p Stored values.
 	Documents = []byte("documents")
+
+	// Routing namespace contains incremental changes to a server's routing table, keyed by
+	// peer ID.
+	Routing = []byte("routing")
+
+	// Hints namespace contains the intended holders of documents stored on their behalf while
+	// they were unreachable, keyed by document key followed by holder peer ID.
+	Hints = []byte("hints")
+
+	// Quotas namespace contains organizations' storage quotas, keyed by org ID.
+	Quotas = []byte("quotas")
+
+	// QuotaCharges namespace contains the organization each stored document is charged to, keyed
+	// by document key.
+	QuotaCharges = []byte("quota_charges")
+
+	// ErrCorruptDocument indicates when a stored document value no longer matches its checksum.
+	ErrCorruptDocument = errors.New("stored document does not match its checksum")
 )
 
 // NewServerSL creates a new NamespaceSL for the "server" namespace backed by a db.KVDB instance.
@@ -58,6 +81,50 @@ func NewClientSL(kvdb db.KVDB) StorerLoader {
 	)
 }
 
+// NewRoutingSLD creates a new StorerLoaderDeleter for the "routing" namespace backed by a db.KVDB
+// instance.
+func NewRoutingSLD(kvdb db.KVDB) StorerLoaderDeleter {
+	return NewKVDBStorerLoaderDeleter(
+		Routing,
+		kvdb,
+		NewExactLengthChecker(id.Length),
+		NewMaxLengthChecker(MaxValueLength),
+	)
+}
+
+// NewHintSLD creates a new StorerLoaderDeleter for the "hints" namespace backed by a db.KVDB
+// instance.
+func NewHintSLD(kvdb db.KVDB) StorerLoaderDeleter {
+	return NewKVDBStorerLoaderDeleter(
+		Hints,
+		kvdb,
+		NewExactLengthChecker(HintKeyLength),
+		NewMaxLengthChecker(MaxValueLength),
+	)
+}
+
+// NewQuotaSLD creates a new StorerLoaderDeleter for the "quotas" namespace backed by a db.KVDB
+// instance.
+func NewQuotaSLD(kvdb db.KVDB) StorerLoaderDeleter {
+	return NewKVDBStorerLoaderDeleter(
+		Quotas,
+		kvdb,
+		NewExactLengthChecker(id.Length),
+		NewMaxLengthChecker(MaxValueLength),
+	)
+}
+
+// NewQuotaChargeSLD creates a new StorerLoaderDeleter for the "quota_charges" namespace backed by
+// a db.KVDB instance.
+func NewQuotaChargeSLD(kvdb db.KVDB) StorerLoaderDeleter {
+	return NewKVDBStorerLoaderDeleter(
+		QuotaCharges,
+		kvdb,
+		NewExactLengthChecker(id.Length),
+		NewMaxLengthChecker(MaxValueLength),
+	)
+}
+
 // DocumentStorer stores api.Document values.
 type DocumentStorer interface {
 	// Store an api.Document value under the given key.
@@ -105,7 +172,11 @@ type documentSLD struct {
 }
 
 // NewDocumentSLD creates a new NamespaceSL for the "entries" namespace
-// backed by a db.KVDB instance.
+// backed by a db.KVDB instance. Each document is keyed by the SHA-256 hash of its value, so the key
+// doubles as a checksum stored alongside the value. It is verified on every read, and a value that
+// fails verification is deleted (so it can be replicated again from healthy peers) and
+// ErrCorruptDocument is returned. System documents are instead keyed by their system key (see
+// api.GetSystemKey) in the reserved system keyspace.
 func NewDocumentSLD(kvdb db.KVDB) DocumentSLD {
 	return &documentSLD{
 		sld: NewKVDBStorerLoaderDeleter(
@@ -114,21 +185,36 @@ func NewDocumentSLD(kvdb db.KVDB) DocumentSLD {
 			NewExactLengthChecker(EntriesKeyLength),
 			NewMaxLengthChecker(MaxEntriesValueLength),
 		),
-		c: NewHashKeyValueChecker(),
+		c: NewDocumentKeyValueChecker(),
 	}
 }
 
-// Store checks that the key equals the SHA256 hash of the value before storing it.
+// Store checks that the key equals the SHA256 hash of the value (or its system or shard key for
+// system and shard documents) before storing it.
 func (dsld *documentSLD) Store(key id.ID, value *api.Document) error {
 	if err := api.ValidateDocument(value); err != nil {
 		return err
 	}
+	if value.GetSystem() != nil && !api.IsSystemKey(key) {
+		return api.ErrUnexpectedKey
+	}
+	if value.GetShard() != nil && !api.IsShardKey(key) {
+		return api.ErrUnexpectedKey
+	}
+	if value.GetShardManifest() != nil && !api.IsShardManifestKey(key) {
+		return api.ErrUnexpectedKey
+	}
 	valueBytes, err := proto.Marshal(value)
-	errors.MaybePanic(err) // should never happen
+	cerrors.MaybePanic(err) // should never happen
 	keyBytes := key.Bytes()
 	if err := dsld.c.Check(keyBytes, valueBytes); err != nil {
 		return err
 	}
+	if sys := value.GetSystem(); sys != nil {
+		if err := dsld.checkSequence(key, sys); err != nil {
+			return err
+		}
+	}
 	if err := dsld.sld.Store(keyBytes, valueBytes); err != nil {
 		return err
 	}
@@ -165,7 +251,7 @@ func (dsld *documentSLD) Mac(key id.ID, macKey []byte) ([]byte, error) {
 	}
 	macer := hmac.New(sha256.New, macKey)
 	_, err = macer.Write(valueBytes)
-	errors.MaybePanic(err) // should never happen b/c sha256.Write always returns nil error
+	cerrors.MaybePanic(err) // should never happen b/c sha256.Write always returns nil error
 	return macer.Sum(nil), nil
 }
 
@@ -173,6 +259,28 @@ func (dsld *documentSLD) Delete(key id.ID) error {
 	return dsld.sld.Delete(key.Bytes())
 }
 
+// checkSequence checks that the system document is newer than any different version already
+// stored under the key, so old versions can't be replayed over it.
+func (dsld *documentSLD) checkSequence(key id.ID, sys *api.SystemDocument) error {
+	existing, err := dsld.Load(key)
+	if err == ErrCorruptDocument || existing == nil || existing.GetSystem() == nil {
+		// nothing (valid) to replace
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	prev := existing.GetSystem()
+	if proto.Equal(prev, sys) {
+		// re-storing the same version is fine
+		return nil
+	}
+	if sys.Record.Sequence <= prev.Record.Sequence {
+		return api.ErrStaleSystemDocument
+	}
+	return nil
+}
+
 func (dsld *documentSLD) loadCheckBytes(key id.ID) ([]byte, error) {
 	keyBytes := key.Bytes()
 	valueBytes, err := dsld.sld.Load(keyBytes)
@@ -183,8 +291,11 @@ func (dsld *documentSLD) loadCheckBytes(key id.ID) ([]byte, error) {
 		return nil, nil
 	}
 	if err := dsld.c.Check(keyBytes, valueBytes); err != nil {
-		// should never happen b/c we check on Store, but being defensive just in case
-		return nil, err
+		// value has been corrupted in storage since we checked it on Store, so remove it
+		if err := dsld.sld.Delete(keyBytes); err != nil {
+			return nil, err
+		}
+		return nil, ErrCorruptDocument
 	}
 	return valueBytes, nil
 }
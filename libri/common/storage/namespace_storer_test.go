@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"math/rand"
 	"testing"
@@ -23,7 +24,7 @@ func TestServerClientSL_StoreLoad_ok(t *testing.T) {
 		{id.NewPseudoRandom(rng).Bytes(), []byte("test value")},
 	}
 
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -58,7 +59,7 @@ func TestServerClientSL_Store_err(t *testing.T) {
 		{[]byte("test key"), []byte("")},                       // empty value
 	}
 
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -83,7 +84,7 @@ func TestServerClientSL_Load_err(t *testing.T) {
 		{bytes.Repeat([]byte{255}, 257), []byte("test value")}, // too long key
 	}
 
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -98,8 +99,80 @@ func TestServerClientSL_Load_err(t *testing.T) {
 	}
 }
 
+func TestRoutingSLD(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	kvdb := db.NewMemoryDB()
+	rsld := NewRoutingSLD(kvdb)
+	key, value := id.NewPseudoRandom(rng).Bytes(), []byte("test value")
+
+	assert.Nil(t, rsld.Store(key, value))
+	loaded, err := rsld.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, loaded)
+
+	// shouldn't collide with server namespace
+	loaded, err = NewServerSL(kvdb).Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+
+	assert.Nil(t, rsld.Delete(key))
+	loaded, err = rsld.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+
+	// keys must be IDs
+	assert.NotNil(t, rsld.Store([]byte("short key"), value))
+}
+
+func TestHintSLD(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	kvdb := db.NewMemoryDB()
+	hsld := NewHintSLD(kvdb)
+	key := append(id.NewPseudoRandom(rng).Bytes(), id.NewPseudoRandom(rng).Bytes()...)
+	value := []byte("test value")
+
+	assert.Nil(t, hsld.Store(key, value))
+	loaded, err := hsld.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, loaded)
+
+	assert.Nil(t, hsld.Delete(key))
+	loaded, err = hsld.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+
+	// keys must be a document key followed by a peer ID
+	assert.NotNil(t, hsld.Store(id.NewPseudoRandom(rng).Bytes(), value))
+}
+
+func TestQuotaSLDs(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	kvdb := db.NewMemoryDB()
+	qsld, csld := NewQuotaSLD(kvdb), NewQuotaChargeSLD(kvdb)
+	key, value := id.NewPseudoRandom(rng).Bytes(), []byte("test value")
+
+	assert.Nil(t, qsld.Store(key, value))
+	loaded, err := qsld.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, loaded)
+
+	// quotas and charges shouldn't collide
+	loaded, err = csld.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+
+	assert.Nil(t, qsld.Delete(key))
+	loaded, err = qsld.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+
+	// keys must be IDs
+	assert.NotNil(t, qsld.Store([]byte("short key"), value))
+	assert.NotNil(t, csld.Store([]byte("short key"), value))
+}
+
 func TestDocumentSLD_StoreLoadDelete_ok(t *testing.T) {
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -126,7 +199,7 @@ func TestDocumentSLD_StoreLoadDelete_ok(t *testing.T) {
 func TestDocumentSLD_Store_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -145,12 +218,87 @@ func TestDocumentSLD_Store_err(t *testing.T) {
 	key2 := id.NewPseudoRandom(rng)
 	err = dsld.Store(key2, value2)
 	assert.NotNil(t, err)
+
+	// check system doc outside system keyspace returns error
+	value3, _ := api.NewTestSystemDocument(rng)
+	valueBytes3, err := proto.Marshal(value3)
+	assert.Nil(t, err)
+	hash3 := sha256.Sum256(valueBytes3)
+	key3 := id.FromBytes(hash3[:])
+	err = dsld.Store(key3, value3)
+	assert.Equal(t, api.ErrUnexpectedKey, err)
+
+	// check shard doc outside shard keyspace returns error
+	value4, _ := api.NewTestShardDocument(rng)
+	valueBytes4, err := proto.Marshal(value4)
+	assert.Nil(t, err)
+	hash4 := sha256.Sum256(valueBytes4)
+	err = dsld.Store(id.FromBytes(hash4[:]), value4)
+	assert.Equal(t, api.ErrUnexpectedKey, err)
+
+	// check shard manifest doc outside shard manifest keyspace returns error
+	value5, _ := api.NewTestShardManifestDocument(rng)
+	valueBytes5, err := proto.Marshal(value5)
+	assert.Nil(t, err)
+	hash5 := sha256.Sum256(valueBytes5)
+	err = dsld.Store(id.FromBytes(hash5[:]), value5)
+	assert.Equal(t, api.ErrUnexpectedKey, err)
+}
+
+func TestDocumentSLD_StoreLoad_system(t *testing.T) {
+	kvdb, cleanup, err := db.NewTempDirDB()
+	defer cleanup()
+	defer kvdb.Close()
+	assert.Nil(t, err)
+	dsld := NewDocumentSLD(kvdb)
+
+	rng := rand.New(rand.NewSource(0))
+	value1, key := api.NewTestSystemDocument(rng)
+	assert.True(t, api.IsSystemKey(key))
+
+	err = dsld.Store(key, value1)
+	assert.Nil(t, err)
+
+	value2, err := dsld.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value1, value2)
+
+	// re-storing the same version is fine
+	assert.Nil(t, dsld.Store(key, value1))
+
+	// newer versions replace older ones
+	value3 := newSystemVersion(value1, 1)
+	assert.Nil(t, dsld.Store(key, value3))
+	value4, err := dsld.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value3, value4)
+
+	// but older or different same-sequence versions can't be replayed over newer ones
+	assert.Equal(t, api.ErrStaleSystemDocument, dsld.Store(key, value1))
+	value5 := newSystemVersion(value1, 1)
+	value5.GetSystem().Record.Contents = api.RandBytes(rng, 64)
+	value5.GetSystem().Checksum, err = api.GetSystemChecksum(value5.GetSystem().Record)
+	assert.Nil(t, err)
+	assert.Equal(t, api.ErrStaleSystemDocument, dsld.Store(key, value5))
+}
+
+// newSystemVersion returns a copy of the system document with the given sequence number.
+func newSystemVersion(doc *api.Document, sequence uint64) *api.Document {
+	next := proto.Clone(doc).(*api.Document)
+	sys := next.GetSystem()
+	sys.Record.Sequence = sequence
+	checksum, err := api.GetSystemChecksum(sys.Record)
+	if err != nil {
+		panic(err)
+	}
+	sys.Checksum = checksum
+	return next
 }
 
 func TestDocumentSLD_Iterate(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -212,7 +360,7 @@ func TestDocumentSLD_Load_checkErr(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	value, _ := api.NewTestDocument(rng)
 	key := id.NewPseudoRandom(rng)
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -224,9 +372,15 @@ func TestDocumentSLD_Load_checkErr(t *testing.T) {
 	err = kvdb.Put(append(Documents, key.Bytes()...), valueBytes)
 	assert.Nil(t, err)
 
-	// check Check error propagates up
+	// check corruption is detected and the corrupt value removed
 	_, err = dsl.Load(key)
-	assert.NotNil(t, err)
+	assert.Equal(t, ErrCorruptDocument, err)
+	stored, err := kvdb.Get(append(Documents, key.Bytes()...))
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+	loaded, err := dsl.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
 }
 
 func TestDocumentSLD_Load_validateDocumentErr(t *testing.T) {
@@ -234,7 +388,7 @@ func TestDocumentSLD_Load_validateDocumentErr(t *testing.T) {
 	value, _ := api.NewTestDocument(rng)
 	value.Contents.(*api.Document_Entry).Entry.AuthorPublicKey = nil
 	key := id.NewPseudoRandom(rng)
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -283,6 +437,25 @@ func TestDocumentSLD_Mac(t *testing.T) {
 	mac, err = dsld.Mac(key, macKey)
 	assert.NotNil(t, err)
 	assert.Nil(t, mac)
+
+	// check corrupt value isn't MACed
+	dsld = &documentSLD{
+		sld: &TestSLD{Bytes: value},
+		c:   &fixedKVChecker{err: errors.New("some Check error")},
+	}
+	mac, err = dsld.Mac(key, macKey)
+	assert.Equal(t, ErrCorruptDocument, err)
+	assert.Nil(t, mac)
+
+	// check error deleting corrupt value bubbles up
+	deleteErr := errors.New("some Delete error")
+	dsld = &documentSLD{
+		sld: &TestSLD{Bytes: value, DeleteErr: deleteErr},
+		c:   &fixedKVChecker{err: errors.New("some Check error")},
+	}
+	mac, err = dsld.Mac(key, macKey)
+	assert.Equal(t, deleteErr, err)
+	assert.Nil(t, mac)
 }
 
 func TestDocumentSLD_Delete_err(t *testing.T) {
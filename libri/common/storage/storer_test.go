@@ -28,7 +28,7 @@ func TestKvdbSLD_StoreLoadDelete(t *testing.T) {
 		{[]byte("test namespace"), id.NewPseudoRandom(rng).Bytes(), []byte("test value")},
 	}
 
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -65,7 +65,7 @@ func TestKvdbSLD_Delete_err(t *testing.T) {
 }
 
 func TestKvdbSLD_Iterate(t *testing.T) {
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/hashicorp/golang-lru"
+)
+
+const (
+	// Hot is the fast storage tier holding recently accessed and pinned documents.
+	Hot Tier = iota
+
+	// Cold is the slower (and cheaper) storage tier holding documents not accessed recently.
+	Cold
+)
+
+// DefaultMaxTrackedAccesses is the default maximum number of document access times tracked, past
+// which the least recently accessed documents are forgotten.
+const DefaultMaxTrackedAccesses = 1 << 20
+
+// Tier is a class of storage.
+type Tier int
+
+func (t Tier) String() string {
+	switch t {
+	case Hot:
+		return "hot"
+	case Cold:
+		return "cold"
+	}
+	panic(fmt.Errorf("unknown Tier value %d", t))
+}
+
+// TierObserver observes documents being loaded from and migrated between tiers, e.g., to report
+// per-tier metrics.
+type TierObserver interface {
+	// Loaded is called after a document is loaded (or MACed) from the given tier.
+	Loaded(tier Tier)
+
+	// Migrated is called after a document is moved from one tier to another.
+	Migrated(from, to Tier)
+}
+
+// TieredDocumentSLD is a DocumentSLD storing documents in a hot and a cold tier. New documents are
+// stored in the hot tier, documents loaded from the cold tier are promoted to the hot tier, and
+// Demote moves documents not accessed recently to the cold tier.
+type TieredDocumentSLD interface {
+	DocumentSLD
+
+	// Pin keeps the document with the given key in the hot tier regardless of when it was last
+	// accessed, promoting it if it's in the cold tier. Pins aren't persisted, so they need to be
+	// renewed on restart.
+	Pin(key id.ID) error
+
+	// Unpin lets the document with the given key be demoted once it isn't accessed recently.
+	Unpin(key id.ID)
+
+	// Demote moves up to max hot documents not accessed since the given time to the cold tier,
+	// returning the number moved. Pinned and system documents are never demoted.
+	Demote(notAccessedSince time.Time, max uint) (uint, error)
+}
+
+type tieredDocumentSLD struct {
+	hot      DocumentSLD
+	cold     DocumentSLD
+	obs      TierObserver
+	accessed *lru.Cache
+	pinned   map[string]struct{}
+	now      func() time.Time
+	// forgotten is the latest access time of the documents whose access times were evicted
+	forgotten time.Time
+	mu        sync.Mutex
+}
+
+// NewTieredDocumentSLD returns a TieredDocumentSLD over the given hot and cold tiers. Documents
+// already in the hot tier are considered accessed when it is created, since access times aren't
+// persisted. At most DefaultMaxTrackedAccesses access times are tracked, and documents whose
+// access times are forgotten are considered accessed as recently as the latest one forgotten.
+func NewTieredDocumentSLD(hot, cold DocumentSLD, obs TierObserver) TieredDocumentSLD {
+	return newTieredDocumentSLD(hot, cold, obs, DefaultMaxTrackedAccesses)
+}
+
+func newTieredDocumentSLD(
+	hot, cold DocumentSLD, obs TierObserver, maxTracked int,
+) *tieredDocumentSLD {
+	t := &tieredDocumentSLD{
+		hot:       hot,
+		cold:      cold,
+		obs:       obs,
+		pinned:    make(map[string]struct{}),
+		now:       time.Now,
+		forgotten: time.Now(),
+	}
+	accessed, err := lru.NewWithEvict(maxTracked, t.forget)
+	errors.MaybePanic(err) // should never happen b/c maxTracked is always positive
+	t.accessed = accessed
+	return t
+}
+
+func (t *tieredDocumentSLD) Store(key id.ID, value *api.Document) error {
+	if err := t.hot.Store(key, value); err != nil {
+		return err
+	}
+	t.touch(key)
+	return nil
+}
+
+func (t *tieredDocumentSLD) Iterate(
+	done chan struct{}, callback func(key id.ID, value []byte),
+) error {
+	if err := t.hot.Iterate(done, callback); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+		return nil
+	default:
+		return t.cold.Iterate(done, callback)
+	}
+}
+
+func (t *tieredDocumentSLD) Load(key id.ID) (*api.Document, error) {
+	value, err := t.hot.Load(key)
+	if err != nil || value != nil {
+		t.loaded(key, Hot, value != nil)
+		return value, err
+	}
+	value, err = t.cold.Load(key)
+	if err != nil || value == nil {
+		return value, err
+	}
+	t.loaded(key, Cold, true)
+	if err := t.move(key, value, t.cold, t.hot); err != nil {
+		return nil, err
+	}
+	t.obs.Migrated(Cold, Hot)
+	return value, nil
+}
+
+func (t *tieredDocumentSLD) Mac(key id.ID, macKey []byte) ([]byte, error) {
+	mac, err := t.hot.Mac(key, macKey)
+	if err != nil || mac != nil {
+		t.loaded(key, Hot, mac != nil)
+		return mac, err
+	}
+	mac, err = t.cold.Mac(key, macKey)
+	if mac != nil {
+		// MACs are only used to verify replicas, so don't promote the document
+		t.obs.Loaded(Cold)
+	}
+	return mac, err
+}
+
+func (t *tieredDocumentSLD) Delete(key id.ID) error {
+	if err := t.hot.Delete(key); err != nil {
+		return err
+	}
+	return t.cold.Delete(key)
+}
+
+func (t *tieredDocumentSLD) Pin(key id.ID) error {
+	t.mu.Lock()
+	t.pinned[key.String()] = struct{}{}
+	t.mu.Unlock()
+
+	// promote the document if it has already been demoted
+	value, err := t.cold.Load(key)
+	if err != nil || value == nil {
+		return err
+	}
+	if err := t.move(key, value, t.cold, t.hot); err != nil {
+		return err
+	}
+	t.touch(key)
+	t.obs.Migrated(Cold, Hot)
+	return nil
+}
+
+func (t *tieredDocumentSLD) Unpin(key id.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pinned, key.String())
+}
+
+func (t *tieredDocumentSLD) Demote(notAccessedSince time.Time, max uint) (uint, error) {
+	// collect keys first, since moving documents while iterating over them isn't safe
+	if max == 0 {
+		return 0, nil
+	}
+	keys := make([]id.ID, 0)
+	done := make(chan struct{})
+	err := t.hot.Iterate(done, func(key id.ID, value []byte) {
+		if !t.demotable(key, notAccessedSince) {
+			return
+		}
+		keys = append(keys, key)
+		if uint(len(keys)) == max {
+			// stop iterating once we have as many as we'll demote
+			close(done)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	nDemoted := uint(0)
+	for _, key := range keys {
+		value, err := t.hot.Load(key)
+		if err != nil {
+			return nDemoted, err
+		}
+		if value == nil || !t.demotable(key, notAccessedSince) {
+			// deleted or accessed since collecting keys
+			continue
+		}
+		if err := t.move(key, value, t.hot, t.cold); err != nil {
+			return nDemoted, err
+		}
+		t.obs.Migrated(Hot, Cold)
+		nDemoted++
+	}
+	return nDemoted, nil
+}
+
+// move stores the document in one tier before deleting it from the other, so it's never missing.
+func (t *tieredDocumentSLD) move(key id.ID, value *api.Document, from, to DocumentSLD) error {
+	if err := to.Store(key, value); err != nil {
+		return err
+	}
+	return from.Delete(key)
+}
+
+func (t *tieredDocumentSLD) loaded(key id.ID, tier Tier, found bool) {
+	if !found {
+		return
+	}
+	t.touch(key)
+	t.obs.Loaded(tier)
+}
+
+func (t *tieredDocumentSLD) touch(key id.ID) {
+	t.accessed.Add(key.String(), t.now())
+}
+
+// forget is called when the least recently accessed document's access time is evicted.
+func (t *tieredDocumentSLD) forget(key interface{}, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if accessed := value.(time.Time); accessed.After(t.forgotten) {
+		t.forgotten = accessed
+	}
+}
+
+func (t *tieredDocumentSLD) demotable(key id.ID, notAccessedSince time.Time) bool {
+	if api.IsSystemKey(key) {
+		return false
+	}
+	t.mu.Lock()
+	_, pinned := t.pinned[key.String()]
+	forgotten := t.forgotten
+	t.mu.Unlock()
+	if pinned {
+		return false
+	}
+	if accessed, in := t.accessed.Peek(key.String()); in {
+		return accessed.(time.Time).Before(notAccessedSince)
+	}
+	return forgotten.Before(notAccessedSince)
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTier_String(t *testing.T) {
+	assert.Equal(t, "hot", Hot.String())
+	assert.Equal(t, "cold", Cold.String())
+	assert.Panics(t, func() { _ = Tier(-1).String() })
+}
+
+func TestTieredDocumentSLD_StoreLoadDemote(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hot, cold := NewDocumentSLD(db.NewMemoryDB()), NewDocumentSLD(db.NewMemoryDB())
+	obs := &recordingTierObserver{}
+	tsld := NewTieredDocumentSLD(hot, cold, obs)
+	now := time.Now()
+	tsld.(*tieredDocumentSLD).now = func() time.Time { return now }
+
+	value1, key1 := api.NewTestDocument(rng)
+	value2, key2 := api.NewTestDocument(rng)
+	value3, key3 := api.NewTestDocument(rng)
+	for key, value := range map[id.ID]*api.Document{key1: value1, key2: value2, key3: value3} {
+		assert.Nil(t, tsld.Store(key, value))
+		inHot, err := hot.Load(key)
+		assert.Nil(t, err)
+		assert.Equal(t, value, inHot)
+	}
+	assert.Nil(t, tsld.Pin(key3))
+
+	// only unpinned docs not accessed since cutoff are demoted
+	now = now.Add(time.Hour)
+	loaded, err := tsld.Load(key2)
+	assert.Nil(t, err)
+	assert.Equal(t, value2, loaded)
+	nDemoted, err := tsld.Demote(now.Add(-time.Minute), 8)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), nDemoted)
+	inHot, err := hot.Load(key1)
+	assert.Nil(t, err)
+	assert.Nil(t, inHot)
+	inCold, err := cold.Load(key1)
+	assert.Nil(t, err)
+	assert.Equal(t, value1, inCold)
+	assert.Equal(t, 1, obs.migrated[[2]Tier{Hot, Cold}])
+
+	// MACing cold doc doesn't promote it
+	mac, err := tsld.Mac(key1, []byte("some MAC key"))
+	assert.Nil(t, err)
+	assert.NotNil(t, mac)
+	assert.Equal(t, 1, obs.loaded[Cold])
+	inHot, err = hot.Load(key1)
+	assert.Nil(t, err)
+	assert.Nil(t, inHot)
+
+	// loading cold doc promotes it
+	loaded, err = tsld.Load(key1)
+	assert.Nil(t, err)
+	assert.Equal(t, value1, loaded)
+	assert.Equal(t, 2, obs.loaded[Cold])
+	assert.Equal(t, 1, obs.migrated[[2]Tier{Cold, Hot}])
+	inCold, err = cold.Load(key1)
+	assert.Nil(t, err)
+	assert.Nil(t, inCold)
+
+	// unpinned docs can be demoted
+	tsld.Unpin(key3)
+	now = now.Add(time.Hour)
+	nDemoted, err = tsld.Demote(now.Add(-time.Minute), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), nDemoted)
+	nDemoted, err = tsld.Demote(now.Add(-time.Minute), 8)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), nDemoted)
+
+	// iterate covers both tiers
+	n := 0
+	err = tsld.Iterate(make(chan struct{}), func(key id.ID, value []byte) { n++ })
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	// deletes from both tiers
+	assert.Nil(t, tsld.Delete(key1))
+	loaded, err = tsld.Load(key1)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+}
+
+func TestTieredDocumentSLD_Demote_system(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hot, cold := NewTestDocSLD(), NewTestDocSLD()
+	tsld := NewTieredDocumentSLD(hot, cold, &recordingTierObserver{})
+	key := id.FromBytes(append(api.SystemKeyPrefix,
+		id.NewPseudoRandom(rng).Bytes()[len(api.SystemKeyPrefix):]...))
+	value, _ := api.NewTestDocument(rng)
+	hot.Stored[key.String()] = value
+
+	nDemoted, err := tsld.Demote(time.Now().Add(time.Hour), 8)
+	assert.Nil(t, err)
+	assert.Zero(t, nDemoted)
+	assert.Len(t, hot.Stored, 1)
+}
+
+func TestTieredDocumentSLD_Demote_max(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hot := &iterCountingDocSLD{TestDocSLD: NewTestDocSLD()}
+	tsld := NewTieredDocumentSLD(hot, NewTestDocSLD(), &recordingTierObserver{})
+	for c := 0; c < 4; c++ {
+		value, key := api.NewTestDocument(rng)
+		hot.Stored[key.String()] = value
+	}
+
+	// stops iterating once it has collected the max
+	nDemoted, err := tsld.Demote(time.Now().Add(time.Hour), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), nDemoted)
+	assert.Equal(t, 2, hot.nIterated)
+	assert.Len(t, hot.Stored, 2)
+
+	nDemoted, err = tsld.Demote(time.Now().Add(time.Hour), 0)
+	assert.Nil(t, err)
+	assert.Zero(t, nDemoted)
+}
+
+func TestTieredDocumentSLD_Pin(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hot, cold := NewTestDocSLD(), NewTestDocSLD()
+	obs := &recordingTierObserver{}
+	tsld := NewTieredDocumentSLD(hot, cold, obs)
+	value, key := api.NewTestDocument(rng)
+	cold.Stored[key.String()] = value
+
+	// pinning a cold doc promotes it and keeps it hot
+	assert.Nil(t, tsld.Pin(key))
+	assert.Len(t, cold.Stored, 0)
+	assert.Equal(t, value, hot.Stored[key.String()])
+	assert.Equal(t, 1, obs.migrated[[2]Tier{Cold, Hot}])
+	nDemoted, err := tsld.Demote(time.Now().Add(time.Hour), 8)
+	assert.Nil(t, err)
+	assert.Zero(t, nDemoted)
+
+	// check promotion error bubbles up
+	cold.Stored[key.String()] = value
+	hot.StoreErr = errors.New("some Store error")
+	assert.NotNil(t, tsld.Pin(key))
+}
+
+func TestTieredDocumentSLD_forget(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tsld := newTieredDocumentSLD(NewTestDocSLD(), NewTestDocSLD(), &recordingTierObserver{}, 2)
+	now := time.Now()
+	tsld.now = func() time.Time { return now }
+	key1, key2, key3 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	tsld.touch(key1)
+	accessed1 := now
+	now = now.Add(time.Minute)
+	tsld.touch(key2)
+	now = now.Add(time.Minute)
+	tsld.touch(key3)
+
+	// only tracks the most recent accesses, remembering the latest one forgotten
+	assert.Equal(t, 2, tsld.accessed.Len())
+	assert.Equal(t, accessed1, tsld.forgotten)
+	assert.False(t, tsld.demotable(key1, accessed1))
+	assert.True(t, tsld.demotable(key1, accessed1.Add(time.Second)))
+	assert.False(t, tsld.demotable(key2, accessed1.Add(time.Second)))
+}
+
+func TestTieredDocumentSLD_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	obs := &recordingTierObserver{}
+
+	// check Store error bubbles up
+	hot := &TestDocSLD{Stored: make(map[string]*api.Document), StoreErr: errors.New("some error")}
+	tsld := NewTieredDocumentSLD(hot, NewTestDocSLD(), obs)
+	assert.NotNil(t, tsld.Store(key, value))
+
+	// check promotion error bubbles up
+	hot = &TestDocSLD{Stored: make(map[string]*api.Document), StoreErr: errors.New("some error")}
+	cold := NewTestDocSLD()
+	cold.Stored[key.String()] = value
+	tsld = NewTieredDocumentSLD(hot, cold, obs)
+	loaded, err := tsld.Load(key)
+	assert.NotNil(t, err)
+	assert.Nil(t, loaded)
+
+	// check Delete errors bubble up
+	hot = &TestDocSLD{Stored: make(map[string]*api.Document), DeleteErr: errors.New("some error")}
+	tsld = NewTieredDocumentSLD(hot, NewTestDocSLD(), obs)
+	assert.NotNil(t, tsld.Delete(key))
+	cold = &TestDocSLD{Stored: make(map[string]*api.Document), DeleteErr: errors.New("some error")}
+	tsld = NewTieredDocumentSLD(NewTestDocSLD(), cold, obs)
+	assert.NotNil(t, tsld.Delete(key))
+
+	// check Demote errors bubble up
+	hot = &TestDocSLD{Stored: make(map[string]*api.Document), IterateErr: errors.New("some error")}
+	tsld = NewTieredDocumentSLD(hot, NewTestDocSLD(), obs)
+	_, err = tsld.Demote(time.Now().Add(time.Hour), 8)
+	assert.NotNil(t, err)
+
+	hot = NewTestDocSLD()
+	hot.Stored[key.String()] = value
+	cold = &TestDocSLD{Stored: make(map[string]*api.Document), StoreErr: errors.New("some error")}
+	tsld = NewTieredDocumentSLD(hot, cold, obs)
+	nDemoted, err := tsld.Demote(time.Now().Add(time.Hour), 8)
+	assert.NotNil(t, err)
+	assert.Zero(t, nDemoted)
+}
+
+type recordingTierObserver struct {
+	loaded   map[Tier]int
+	migrated map[[2]Tier]int
+}
+
+func (o *recordingTierObserver) Loaded(tier Tier) {
+	if o.loaded == nil {
+		o.loaded = make(map[Tier]int)
+	}
+	o.loaded[tier]++
+}
+
+func (o *recordingTierObserver) Migrated(from, to Tier) {
+	if o.migrated == nil {
+		o.migrated = make(map[[2]Tier]int)
+	}
+	o.migrated[[2]Tier{from, to}]++
+}
+
+type iterCountingDocSLD struct {
+	*TestDocSLD
+	nIterated int
+}
+
+func (f *iterCountingDocSLD) Iterate(
+	done chan struct{}, callback func(key id.ID, value []byte),
+) error {
+	return f.TestDocSLD.Iterate(done, func(key id.ID, value []byte) {
+		f.nIterated++
+		callback(key, value)
+	})
+}
@@ -0,0 +1,186 @@
+package subscribe
+
+import (
+	"errors"
+	"sync"
+
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/hashicorp/golang-lru"
+	"golang.org/x/net/context"
+)
+
+// feedKeysSize is the number of most recent publication keys each feed remembers to detect
+// duplicates.
+const feedKeysSize = 1 << 14
+
+// errUnreliableFeed indicates when a subscription was ended because its peer was delivering
+// too small a share of the publications expected from it.
+var errUnreliableFeed = errors.New("subscription feed unreliable")
+
+// feed tracks how reliably a single subscription to a peer delivers publications relative to
+// those seen from all subscriptions.
+type feed struct {
+	address    string
+	nSeenStart uint64
+	nReceived  uint64
+	nDuplicate uint64
+	keys       *lru.Cache
+	unreliable bool
+	cancel     context.CancelFunc
+	mu         sync.Mutex
+}
+
+func newFeed(address string, nSeenStart uint64) *feed {
+	keys, err := lru.New(feedKeysSize)
+	cerrors.MaybePanic(err) // should never happen
+	return &feed{
+		address:    address,
+		nSeenStart: nSeenStart,
+		keys:       keys,
+	}
+}
+
+// receive records a publication received from the feed's peer. Only duplicates of the most recent
+// feedKeysSize publications are detected.
+func (f *feed) receive(key id.ID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nReceived++
+	if in, _ := f.keys.ContainsOrAdd(key.String(), struct{}{}); in {
+		f.nDuplicate++
+	}
+}
+
+// setCancel sets the function canceling the feed's subscription, calling it immediately if the
+// feed has already been found unreliable.
+func (f *feed) setCancel(cancel context.CancelFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancel = cancel
+	if f.unreliable {
+		f.cancel()
+	}
+}
+
+// reliability returns the fraction of the publications expected from the feed that it
+// delivered, discounted by the fraction of duplicates it sent, along with the number of
+// publications expected from it. Since each subscription only sends (roughly) an fpRate share of
+// all publications, a feed is expected to deliver fpRate of those seen since it began.
+func (f *feed) reliability(fpRate float32, nSeen uint64) (float32, float32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reliabilityUnlocked(fpRate, nSeen)
+}
+
+func (f *feed) reliabilityUnlocked(fpRate float32, nSeen uint64) (float32, float32) {
+	expected := fpRate * float32(nSeen-f.nSeenStart)
+	if f.nReceived == 0 {
+		if expected == 0 {
+			return 1.0, 0
+		}
+		return 0, expected
+	}
+	delivered := float32(f.nReceived - f.nDuplicate)
+	coverage := float32(1.0)
+	if expected > delivered {
+		coverage = delivered / expected
+	}
+	return coverage * delivered / float32(f.nReceived), expected
+}
+
+// maybeEnd marks the feed unreliable and cancels its subscription if it has delivered less than
+// the minimum reliability after enough publications were expected from it, returning whether it
+// did so.
+func (f *feed) maybeEnd(params *ToParameters, nSeen uint64) bool {
+	if params.MinFeedReliability == 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.unreliable {
+		return false
+	}
+	reliability, expected := f.reliabilityUnlocked(params.FPRate, nSeen)
+	if expected < float32(params.MinFeedExpected) || reliability >= params.MinFeedReliability {
+		return false
+	}
+	f.unreliable = true
+	if f.cancel != nil {
+		f.cancel()
+	}
+	return true
+}
+
+func (f *feed) isUnreliable() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.unreliable
+}
+
+// feeds tracks the active subscription feeds, the number of distinct publications seen across
+// all of them, and the addresses of peers whose last feed was found unreliable.
+type feeds struct {
+	active     map[*feed]struct{}
+	nSeen      uint64
+	unreliable map[string]struct{}
+	mu         sync.Mutex
+}
+
+func newFeeds() *feeds {
+	return &feeds{
+		active:     make(map[*feed]struct{}),
+		unreliable: make(map[string]struct{}),
+	}
+}
+
+// add begins tracking a new feed from the peer with the given address.
+func (fs *feeds) add(address string) *feed {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	f := newFeed(address, fs.nSeen)
+	fs.active[f] = struct{}{}
+	return f
+}
+
+// remove stops tracking the feed, remembering whether its peer was unreliable.
+func (fs *feeds) remove(f *feed) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.active, f)
+	if f.isUnreliable() {
+		fs.unreliable[f.address] = struct{}{}
+	} else {
+		delete(fs.unreliable, f.address)
+	}
+}
+
+// seen records a new publication and ends any active feeds that have become unreliable,
+// returning them.
+func (fs *feeds) seen(params *ToParameters) []*feed {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.nSeen++
+	ended := make([]*feed, 0)
+	for f := range fs.active {
+		if f.maybeEnd(params, fs.nSeen) {
+			ended = append(ended, f)
+		}
+	}
+	return ended
+}
+
+// isUnreliable returns whether the last feed from the peer with the given address was found
+// unreliable.
+func (fs *feeds) isUnreliable(address string) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	_, in := fs.unreliable[address]
+	return in
+}
+
+func (fs *feeds) getNSeen() uint64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.nSeen
+}
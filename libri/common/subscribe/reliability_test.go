@@ -0,0 +1,183 @@
+package subscribe
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeed_reliability(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	f := newFeed("1.2.3.4:20100", 10)
+
+	// nothing expected or received yet
+	reliability, expected := f.reliability(0.5, 10)
+	assert.Equal(t, float32(1.0), reliability)
+	assert.Zero(t, expected)
+
+	// nothing received when some expected
+	reliability, expected = f.reliability(0.5, 30)
+	assert.Zero(t, reliability)
+	assert.Equal(t, float32(10), expected)
+
+	// received half of expected
+	keys := make([]id.ID, 5)
+	for i := range keys {
+		keys[i] = id.NewPseudoRandom(rng)
+		f.receive(keys[i])
+	}
+	reliability, _ = f.reliability(0.5, 30)
+	assert.Equal(t, float32(0.5), reliability)
+
+	// received more than expected
+	reliability, _ = f.reliability(0.5, 18)
+	assert.Equal(t, float32(1.0), reliability)
+
+	// duplicates discount reliability
+	for _, key := range keys {
+		f.receive(key)
+	}
+	reliability, _ = f.reliability(0.5, 18)
+	assert.Equal(t, float32(0.5), reliability)
+}
+
+func TestFeed_receive_capped(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	f := newFeed("1.2.3.4:20100", 0)
+	first := id.NewPseudoRandom(rng)
+	f.receive(first)
+	for c := 0; c < feedKeysSize; c++ {
+		f.receive(id.NewPseudoRandom(rng))
+	}
+	assert.Equal(t, feedKeysSize, f.keys.Len())
+
+	// oldest key evicted, so no longer detected as a duplicate
+	f.receive(first)
+	assert.Zero(t, f.nDuplicate)
+}
+
+func TestFeed_maybeEnd(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultToParameters()
+	params.FPRate = 0.5
+	params.MinFeedExpected = 10
+	f := newFeed("1.2.3.4:20100", 0)
+	canceled := false
+	f.setCancel(func() { canceled = true })
+
+	// not enough expected to judge yet
+	assert.False(t, f.maybeEnd(params, 10))
+	assert.False(t, f.isUnreliable())
+
+	// reliable enough
+	for c := 0; c < 8; c++ {
+		f.receive(id.NewPseudoRandom(rng))
+	}
+	assert.False(t, f.maybeEnd(params, 20))
+	assert.False(t, canceled)
+
+	// disabled when no min reliability
+	params.MinFeedReliability = 0
+	assert.False(t, f.maybeEnd(params, 100))
+	assert.False(t, canceled)
+
+	// unreliable
+	params.MinFeedReliability = DefaultMinFeedReliability
+	assert.True(t, f.maybeEnd(params, 100))
+	assert.True(t, f.isUnreliable())
+	assert.True(t, canceled)
+
+	// only ended once
+	assert.False(t, f.maybeEnd(params, 200))
+
+	// cancel set after being found unreliable is called immediately
+	canceled = false
+	f.setCancel(func() { canceled = true })
+	assert.True(t, canceled)
+}
+
+func TestFeeds(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultToParameters()
+	params.MinFeedExpected = 4
+	fs := newFeeds()
+	addr1, addr2 := "1.2.3.4:20100", "1.2.3.4:20101"
+
+	f1, f2 := fs.add(addr1), fs.add(addr2)
+	for c := 0; c < 3; c++ {
+		f1.receive(id.NewPseudoRandom(rng))
+		assert.Empty(t, fs.seen(params))
+	}
+
+	// f2 hasn't delivered anything, so is ended once enough publications expected from it
+	f1.receive(id.NewPseudoRandom(rng))
+	ended := fs.seen(params)
+	assert.Equal(t, []*feed{f2}, ended)
+	assert.Equal(t, uint64(4), fs.getNSeen())
+
+	fs.remove(f1)
+	fs.remove(f2)
+	assert.False(t, fs.isUnreliable(addr1))
+	assert.True(t, fs.isUnreliable(addr2))
+	assert.Empty(t, fs.active)
+
+	// reliable subsequent feed clears unreliable peer
+	f3 := fs.add(addr2)
+	assert.Equal(t, uint64(4), f3.nSeenStart)
+	fs.remove(f3)
+	assert.False(t, fs.isUnreliable(addr2))
+}
+
+func TestTo_addNext(t *testing.T) {
+	params := NewDefaultToParameters()
+	csb := &rotatingClientSetBalancer{}
+	toImpl := &to{
+		params: params,
+		logger: clogging.NewDevInfoLogger(),
+		csb:    csb,
+		feeds:  newFeeds(),
+	}
+
+	// reliable librarian is used
+	_, addr1, err := toImpl.addNext()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, csb.inSet[addr1])
+
+	// unreliable librarian is skipped for the next and removed from the set
+	toImpl.feeds.unreliable["1.2.3.4:20101"] = struct{}{}
+	_, addr2, err := toImpl.addNext()
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.3.4:20102", addr2)
+	assert.Equal(t, 0, csb.inSet["1.2.3.4:20101"])
+
+	// unreliable librarian is used when no others available
+	toImpl.feeds.unreliable["1.2.3.4:20103"] = struct{}{}
+	toImpl.csb = &onceClientSetBalancer{addr: "1.2.3.4:20103"}
+	_, addr3, err := toImpl.addNext()
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.3.4:20103", addr3)
+}
+
+// onceClientSetBalancer returns the address the first time AddNext is called and
+// client.ErrNoNewClients thereafter.
+type onceClientSetBalancer struct {
+	addr  string
+	added bool
+}
+
+func (o *onceClientSetBalancer) AddNext() (api.LibrarianClient, string, error) {
+	if o.added {
+		return nil, "", client.ErrNoNewClients
+	}
+	o.added = true
+	return nil, o.addr, nil
+}
+
+func (o *onceClientSetBalancer) Remove(address string) error {
+	return nil
+}
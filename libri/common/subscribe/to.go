@@ -12,8 +12,10 @@ import (
 	cerrors "github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
+	prom "github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -57,8 +59,27 @@ const (
 	// DefaultRecentCacheSize is the default recent publications LRU cache size.
 	DefaultRecentCacheSize = 1 << 12
 
+	// DefaultReconnectInitialBackoff is the default initial backoff before resubscribing after a
+	// subscription drops.
+	DefaultReconnectInitialBackoff = 250 * time.Millisecond
+
+	// DefaultReconnectMaxBackoff is the default maximum backoff before resubscribing after a
+	// subscription drops.
+	DefaultReconnectMaxBackoff = 1 * time.Minute
+
+	// DefaultMinFeedReliability is the default minimum reliability below which a subscription
+	// is ended and rotated to another peer.
+	DefaultMinFeedReliability = 0.5
+
+	// DefaultMinFeedExpected is the default minimum number of publications expected from a
+	// subscription before its reliability is judged.
+	DefaultMinFeedExpected = 32
+
 	// errQueueSize is the size of the error queue used to calculate the running error rate.
 	errQueueSize = 100
+
+	promNamespace = "libri"
+	promSubsystem = "subscribe"
 )
 
 // ToParameters define how the collection of subscriptions to other peers will be managed.
@@ -80,16 +101,38 @@ type ToParameters struct {
 	// RecentCacheSize is the size of the LRU cache used in deduplicating and grouping
 	// publications.
 	RecentCacheSize uint32
+
+	// ReconnectInitialBackoff is the initial backoff before resubscribing after a subscription
+	// drops. It doubles on each consecutive failed attempt, up to ReconnectMaxBackoff, and the
+	// actual wait is jittered uniformly between zero and that value.
+	ReconnectInitialBackoff time.Duration
+
+	// ReconnectMaxBackoff is the maximum backoff before resubscribing after a subscription
+	// drops.
+	ReconnectMaxBackoff time.Duration
+
+	// MinFeedReliability is the minimum reliability of a subscription, i.e., the share of the
+	// publications expected from it that it delivered (discounted by the share of duplicates it
+	// sent), below which it is ended and rotated to another peer. Zero disables rotation.
+	MinFeedReliability float32
+
+	// MinFeedExpected is the minimum number of publications expected from a subscription
+	// before its reliability is judged.
+	MinFeedExpected uint32
 }
 
 // NewDefaultToParameters returns a *ToParameters object with default values.
 func NewDefaultToParameters() *ToParameters {
 	return &ToParameters{
-		NSubscriptions:  DefaultNSubscriptionsTo,
-		FPRate:          DefaultFPRate,
-		Timeout:         DefaultTimeout,
-		MaxErrRate:      DefaultMaxErrRate,
-		RecentCacheSize: DefaultRecentCacheSize,
+		NSubscriptions:          DefaultNSubscriptionsTo,
+		FPRate:                  DefaultFPRate,
+		Timeout:                 DefaultTimeout,
+		MaxErrRate:              DefaultMaxErrRate,
+		RecentCacheSize:         DefaultRecentCacheSize,
+		ReconnectInitialBackoff: DefaultReconnectInitialBackoff,
+		ReconnectMaxBackoff:     DefaultReconnectMaxBackoff,
+		MinFeedReliability:      DefaultMinFeedReliability,
+		MinFeedExpected:         DefaultMinFeedExpected,
 	}
 }
 
@@ -107,6 +150,18 @@ type To interface {
 	Send(pub *api.Publication) error
 }
 
+// PromTo is a To that reports the gaps in its subscriptions, during which publications may have
+// been missed, as Prometheus metrics.
+type PromTo interface {
+	To
+
+	// Register registers the Prometheus metrics with the default Prometheus registerer.
+	Register()
+
+	// Unregister unregisters the Prometheus metrics from the default Prometheus registerer.
+	Unregister()
+}
+
 type to struct {
 	params   *ToParameters
 	logger   *zap.Logger
@@ -114,9 +169,13 @@ type to struct {
 	csb      client.SetBalancer
 	sb       subscriptionBeginner
 	recent   RecentPublications
+	feeds    *feeds
 	received chan *pubValueReceipt
 	new      chan *KeyedPub
 	end      chan struct{}
+
+	gaps        prom.Counter
+	gapDuration prom.Histogram
 }
 
 // NewTo creates a new To instance, writing merged, deduplicated publications to the given new
@@ -145,9 +204,23 @@ func NewTo(
 			params:     params,
 		},
 		recent:   recent,
+		feeds:    newFeeds(),
 		received: make(chan *pubValueReceipt, params.NSubscriptions),
 		new:      new,
 		end:      make(chan struct{}),
+		gaps: prom.NewCounter(prom.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "gaps_total",
+			Help:      "Number of subscription gaps, during which publications may be missed.",
+		}),
+		gapDuration: prom.NewHistogram(prom.HistogramOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "gap_duration_seconds",
+			Help:      "Duration of subscription gaps.",
+			Buckets:   prom.DefBuckets,
+		}),
 	}
 }
 
@@ -169,32 +242,7 @@ func (t *to) Begin() error {
 
 	// subscription threads writing to received & errs channels
 	for c := uint32(0); c < t.params.NSubscriptions; c++ {
-		go func(i uint32) {
-			rng, fp := rand.New(rand.NewSource(int64(i))), float64(t.params.FPRate)
-			for {
-				lc, address, err := t.csb.AddNext()
-				if err != nil {
-					fatal <- err
-					return
-				}
-				sub, err := NewFPSubscription(fp, rng)
-				if err != nil {
-					fatal <- err
-					return
-				}
-				t.logger.Debug("beginning new subscription",
-					zap.Int("index", int(i)),
-					zap.Float64("false_positive_rate", fp),
-					zap.String("peer_address", address),
-				)
-				select {
-				case <-t.end:
-					return
-				case errs <- t.sb.begin(lc, sub, t.received, errs, t.end):
-				}
-				cerrors.MaybePanic(t.csb.Remove(address)) // should never happen
-			}
-		}(c)
+		go t.subscribe(c, errs, fatal)
 	}
 
 	select {
@@ -209,6 +257,143 @@ func (t *to) Begin() error {
 	}
 }
 
+func (t *to) Register() {
+	prom.MustRegister(t.gaps)
+	prom.MustRegister(t.gapDuration)
+}
+
+func (t *to) Unregister() {
+	_ = prom.Unregister(t.gaps)
+	_ = prom.Unregister(t.gapDuration)
+}
+
+// subscribe maintains the ith subscription, resubscribing to a different librarian (if one is
+// available) with jittered exponential backoff whenever the current subscription drops. Dropped
+// subscriptions are not counted toward the running error rate, so only bad publications can
+// cause a fatal error. Since the Subscribe endpoint has no durable cursor from which to resume,
+// publications sent while disconnected cannot be recovered, so each such gap is logged and
+// counted (see PromTo) along with its duration. Subscriptions delivering too few of the
+// publications seen from the others are ended and rotated to another peer, skipping peers whose
+// last subscription was unreliable when others are available.
+func (t *to) subscribe(i uint32, errs chan error, fatal chan error) {
+	rng, fp := rand.New(rand.NewSource(int64(i))), float64(t.params.FPRate)
+	lg := t.logger.With(zap.Int("index", int(i)))
+	attempt, prevAddress, dropped := uint(0), "", time.Time{}
+	for {
+		lc, address, err := t.addNext()
+		if prevAddress != "" {
+			// only release the previous librarian after choosing the next, so we rotate away
+			// from it when others are available
+			cerrors.MaybePanic(t.csb.Remove(prevAddress)) // should never happen
+			prevAddress = ""
+		}
+		if err == client.ErrNoNewClients {
+			lg.Info("no librarians available for subscription", zap.Error(err))
+			if !t.waitReconnect(attempt, rng) {
+				return
+			}
+			attempt++
+			continue
+		}
+		if err != nil {
+			fatal <- err
+			return
+		}
+		sub, err := NewFPSubscription(fp, rng)
+		if err != nil {
+			fatal <- err
+			return
+		}
+		if !dropped.IsZero() {
+			gap := time.Since(dropped)
+			t.gaps.Inc()
+			t.gapDuration.Observe(gap.Seconds())
+			lg.Warn("resubscribing after subscription gap; publications during gap may "+
+				"have been missed",
+				zap.Duration("gap", gap),
+				zap.String("peer_address", address),
+			)
+		}
+		lg.Debug("beginning new subscription",
+			zap.Float64("false_positive_rate", fp),
+			zap.String("peer_address", address),
+		)
+		f := t.feeds.add(address)
+		err = t.sb.begin(lc, sub, f, t.received, errs, t.end)
+		t.feeds.remove(f)
+		prevAddress = address
+		select {
+		case <-t.end:
+			return
+		default:
+		}
+		if err == errUnreliableFeed {
+			reliability, expected := f.reliability(t.params.FPRate, t.feeds.getNSeen())
+			lg.Info("rotating away from unreliable subscription",
+				zap.Float32("reliability", reliability),
+				zap.Float32("n_expected", expected),
+				zap.String("peer_address", address),
+			)
+			attempt, dropped = 0, time.Time{}
+			continue
+		}
+		if err == nil {
+			// subscription ended normally (e.g., timed out), so start next right away
+			attempt, dropped = 0, time.Time{}
+			continue
+		}
+		dropped = time.Now()
+		lg.Info("subscription dropped", zap.Error(err), zap.String("peer_address", address))
+		if !t.waitReconnect(attempt, rng) {
+			return
+		}
+		attempt++
+	}
+}
+
+// addNext adds the next librarian to the set, preferring another if the last subscription to
+// the first was unreliable.
+func (t *to) addNext() (api.LibrarianClient, string, error) {
+	lc, address, err := t.csb.AddNext()
+	if err != nil || !t.feeds.isUnreliable(address) {
+		return lc, address, err
+	}
+	lc2, address2, err := t.csb.AddNext()
+	if err == client.ErrNoNewClients {
+		// no other librarians available, so give the unreliable one another chance
+		return lc, address, nil
+	}
+	cerrors.MaybePanic(t.csb.Remove(address)) // should never happen
+	return lc2, address2, err
+}
+
+// waitReconnect waits for the jittered backoff of the given attempt, returning false if the
+// subscriptions are ended in the meantime.
+func (t *to) waitReconnect(attempt uint, rng *rand.Rand) bool {
+	select {
+	case <-t.end:
+		return false
+	case <-time.After(reconnectBackoff(t.params, attempt, rng)):
+		return true
+	}
+}
+
+// reconnectBackoff returns a duration drawn uniformly from [0, b), where b is the initial backoff
+// doubled for each previous attempt and capped at the max backoff.
+func reconnectBackoff(params *ToParameters, attempt uint, rng *rand.Rand) time.Duration {
+	b := params.ReconnectInitialBackoff
+	for c := uint(0); c < attempt && b < params.ReconnectMaxBackoff; c++ {
+		b *= 2
+	}
+	if b > params.ReconnectMaxBackoff {
+		b = params.ReconnectMaxBackoff
+	}
+	if b <= 0 {
+		return 0
+	}
+	return time.Duration(rng.Int63n(int64(b)))
+}
+
 func (t *to) End() {
 	t.logger.Info("ending subscriptions")
 	select {
@@ -259,6 +444,10 @@ func (t *to) dedup() {
 				zap.String("publication_key", pvr.pub.Key.String()),
 			)
 			t.logger.Debug("publication value", getLoggerValues(pvr.pub.Value)...)
+			for _, f := range t.feeds.seen(t.params) {
+				t.logger.Info("ending unreliable subscription",
+					zap.String("peer_address", f.address))
+			}
 			t.new <- pvr.pub
 		}
 	}
@@ -279,8 +468,10 @@ func getLoggerValues(pub *api.Publication) []zapcore.Field {
 }
 
 type subscriptionBeginner interface {
-	// begin begins a subscription and writes publications to received and errors to errs
-	begin(lc api.Subscriber, sub *api.Subscription, received chan *pubValueReceipt,
+	// begin begins a subscription and writes publications to received and errors to errs,
+	// recording each publication in the feed and returning errUnreliableFeed if it is ended
+	// for being unreliable
+	begin(lc api.Subscriber, sub *api.Subscription, f *feed, received chan *pubValueReceipt,
 		errs chan error, end chan struct{}) error
 }
 
@@ -295,6 +486,7 @@ type subscriptionBeginnerImpl struct {
 func (sb *subscriptionBeginnerImpl) begin(
 	lc api.Subscriber,
 	sub *api.Subscription,
+	f *feed,
 	received chan *pubValueReceipt,
 	errs chan error,
 	end chan struct{},
@@ -305,12 +497,18 @@ func (sb *subscriptionBeginnerImpl) begin(
 	if err != nil {
 		return err
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	f.setCancel(cancel)
 	subscribeClient, err := lc.Subscribe(ctx, rq)
 	if err != nil {
 		return err
 	}
 	for {
 		rp, err := subscribeClient.Recv()
+		if f.isUnreliable() {
+			return errUnreliableFeed
+		}
 		if err == io.EOF {
 			return nil
 		}
@@ -325,6 +523,7 @@ func (sb *subscriptionBeginnerImpl) begin(
 		if err != nil {
 			return err
 		}
+		f.receive(pvr.pub.Key)
 		select {
 		case <-end:
 			return nil
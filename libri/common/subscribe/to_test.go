@@ -2,16 +2,20 @@ package subscribe
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/drausin/libri/libri/common/ecid"
 	cerrors "github.com/drausin/libri/libri/common/errors"
 	clogging "github.com/drausin/libri/libri/common/logging"
 	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/net/context"
@@ -142,12 +146,14 @@ func TestTo_Begin_err(t *testing.T) {
 	recent, err := NewRecentPublications(2)
 	csb := &fixedClientSetBalancer{}
 	assert.Nil(t, err)
-	newPubs := make(chan *KeyedPub, 1)
+
+	// each to gets its own new publications channel since Begin closes it when it ends
 
 	// check csb.Next() error bubbles up
 	nextErr := errors.New("some Next() error")
 	csb1 := &fixedClientSetBalancer{err: nextErr}
-	toImpl1 := NewTo(params, lg, clientID, orgID, csb1, nil, nil, recent, newPubs).(*to)
+	newPubs1 := make(chan *KeyedPub, 1)
+	toImpl1 := NewTo(params, lg, clientID, orgID, csb1, nil, nil, recent, newPubs1).(*to)
 	toImpl1.sb = &fixedSubscriptionBeginner{subscribeErr: errors.New("some subscribe error")}
 	err = toImpl1.Begin()
 	assert.Equal(t, nextErr, err)
@@ -155,7 +161,8 @@ func TestTo_Begin_err(t *testing.T) {
 	// check NewFPSubscription error bubbles up
 	params2 := NewDefaultToParameters()
 	params2.FPRate = 0.0 // will trigger error
-	toImpl2 := NewTo(params2, lg, clientID, orgID, csb, nil, nil, recent, newPubs).(*to)
+	newPubs2 := make(chan *KeyedPub, 1)
+	toImpl2 := NewTo(params2, lg, clientID, orgID, csb, nil, nil, recent, newPubs2).(*to)
 	toImpl2.sb = &fixedSubscriptionBeginner{subscribeErr: errors.New("some subscribe error")}
 	err = toImpl2.Begin()
 	assert.Equal(t, ErrOutOfBoundsFPRate, err)
@@ -163,15 +170,21 @@ func TestTo_Begin_err(t *testing.T) {
 	// check running error count above threshold triggers error
 	received := make(chan *pubValueReceipt)
 	errs := make(chan error)
-	toImpl3 := NewTo(params, lg, clientID, orgID, csb, nil, nil, recent, newPubs).(*to)
+	newPubs3 := make(chan *KeyedPub, 1)
+	toImpl3 := NewTo(params, lg, clientID, orgID, csb, nil, nil, recent, newPubs3).(*to)
 	toImpl3.sb = &fixedSubscriptionBeginner{
-		received:     received,
-		errs:         errs,
-		subscribeErr: errors.New("some subscribe error"),
+		received: received,
+		errs:     errs,
 	}
+	value := api.NewTestPublication(rng)
+	key, err := api.GetKey(value)
+	assert.Nil(t, err)
+	pvr, err := newPublicationValueReceipt(key.Bytes(), value,
+		api.RandBytes(rng, api.ECPubKeyLength))
+	assert.Nil(t, err)
 	go func() {
-		for c := 0; c < int(params.FPRate*float32(errQueueSize)); c++ {
-			received <- nil
+		for c := 0; c < int(params.MaxErrRate*float32(errQueueSize)); c++ {
+			received <- pvr
 			errs <- errors.New("some Recv error")
 		}
 	}()
@@ -179,6 +192,81 @@ func TestTo_Begin_err(t *testing.T) {
 	assert.Equal(t, cerrors.ErrTooManyErrs, err)
 }
 
+func TestTo_Begin_reconnect(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultToParameters()
+	params.NSubscriptions = 2
+	params.ReconnectInitialBackoff = time.Millisecond
+	params.ReconnectMaxBackoff = 5 * time.Millisecond
+	lg := clogging.NewDevInfoLogger()
+	clientID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	recent, err := NewRecentPublications(2)
+	assert.Nil(t, err)
+	newPubs := make(chan *KeyedPub, 1)
+
+	// more dropped subscriptions and unavailable librarians than the max error rate allows
+	nDrops := int(2 * params.MaxErrRate * errQueueSize)
+	csb := &rotatingClientSetBalancer{nNoNewClients: nDrops}
+	sb := &droppingSubscriptionBeginner{nDrops: nDrops, done: make(chan struct{})}
+	toImpl := NewTo(params, lg, clientID, orgID, csb, nil, nil, recent, newPubs).(*to)
+	toImpl.sb = sb
+
+	beginErrs := make(chan error)
+	go func() { beginErrs <- toImpl.Begin() }()
+
+	// should keep resubscribing rather than giving up
+	select {
+	case <-sb.done:
+	case err := <-beginErrs:
+		t.Fatalf("unexpected Begin end: %v", err)
+	}
+	toImpl.End()
+	assert.Nil(t, <-beginErrs)
+
+	// each resubscription after a drop is counted as a gap
+	gaps := &dto.Metric{}
+	assert.Nil(t, toImpl.gaps.Write(gaps))
+	assert.True(t, *gaps.Counter.Value > 0)
+	assert.Nil(t, toImpl.gapDuration.Write(gaps))
+	assert.Equal(t, uint64(*gaps.Counter.Value), *gaps.Histogram.SampleCount)
+	toImpl.Register()
+	toImpl.Unregister()
+
+	csb.mu.Lock()
+	defer csb.mu.Unlock()
+	// all the unavailable librarians were waited out, and each drop followed an added librarian
+	assert.Zero(t, csb.nNoNewClients)
+	assert.True(t, csb.nAdded >= nDrops)
+	assert.True(t, csb.nRemoved > 0)
+	for addr, n := range csb.inSet {
+		// each subscription goroutine holds at most one librarian at a time
+		assert.True(t, n <= 1, addr)
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := &ToParameters{
+		ReconnectInitialBackoff: 10 * time.Millisecond,
+		ReconnectMaxBackoff:     80 * time.Millisecond,
+	}
+	for attempt := uint(0); attempt < 10; attempt++ {
+		maxBackoff := params.ReconnectInitialBackoff << attempt
+		if maxBackoff > params.ReconnectMaxBackoff {
+			maxBackoff = params.ReconnectMaxBackoff
+		}
+		for c := 0; c < 16; c++ {
+			b := reconnectBackoff(params, attempt, rng)
+			assert.True(t, b >= 0)
+			assert.True(t, b < maxBackoff)
+		}
+	}
+
+	// zero backoff
+	assert.Zero(t, reconnectBackoff(&ToParameters{}, 3, rng))
+}
+
 func TestFrom_Send(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	toImpl := &to{
@@ -245,7 +333,7 @@ func TestSubscriptionBeginnerImpl_Begin_ok(t *testing.T) {
 	responseErrs <- nil
 
 	go func() {
-		beginErr := sb.begin(lc, sub, received, errs, end)
+		beginErr := sb.begin(lc, sub, newFeed("", 0), received, errs, end)
 		assert.Nil(t, beginErr)
 	}()
 
@@ -262,7 +350,7 @@ func TestSubscriptionBeginnerImpl_Begin_ok(t *testing.T) {
 
 	// start again
 	go func() {
-		beginErr := sb.begin(lc, sub, received, errs, end)
+		beginErr := sb.begin(lc, sub, newFeed("", 0), received, errs, end)
 		assert.Nil(t, beginErr)
 	}()
 
@@ -301,7 +389,7 @@ func TestSubscriptionBeginnerImpl_Begin_err(t *testing.T) {
 		params:     NewDefaultToParameters(),
 	}
 	lc1 := &fixedSubscriber{}
-	err = sb1.begin(lc1, sub, received, errs, end)
+	err = sb1.begin(lc1, sub, newFeed("", 0), received, errs, end)
 	assert.NotNil(t, err)
 
 	// check Subscribe error bubbles up
@@ -315,7 +403,7 @@ func TestSubscriptionBeginnerImpl_Begin_err(t *testing.T) {
 		client: nil,
 		err:    errors.New("some Subscribe error"),
 	}
-	err = sb2.begin(lc2, sub, received, errs, end)
+	err = sb2.begin(lc2, sub, newFeed("", 0), received, errs, end)
 	assert.NotNil(t, err)
 
 	// check Recv error bubbles up
@@ -335,7 +423,7 @@ func TestSubscriptionBeginnerImpl_Begin_err(t *testing.T) {
 	}
 	responses3 <- nil
 	responseErrs3 <- errors.New("some Recv error")
-	err = sb3.begin(lc3, sub, received, errs, end)
+	err = sb3.begin(lc3, sub, newFeed("", 0), received, errs, end)
 	assert.NotNil(t, err)
 
 	// check newPublicationValueReceipt error bubbles up
@@ -362,8 +450,24 @@ func TestSubscriptionBeginnerImpl_Begin_err(t *testing.T) {
 		Value: value,
 	}
 	responseErrs4 <- nil
-	err = sb4.begin(lc4, sub, received, errs, end)
+	err = sb4.begin(lc4, sub, newFeed("", 0), received, errs, end)
 	assert.NotNil(t, err)
+
+	// check unreliable feed returns errUnreliableFeed
+	responses5 := make(chan *api.SubscribeResponse, 1)
+	responseErrs5 := make(chan error, 1)
+	lc5 := &fixedSubscriber{
+		client: &fixedLibrarianSubscribeClient{
+			responses: responses5,
+			err:       responseErrs5,
+		},
+	}
+	f5 := newFeed("", 0)
+	f5.unreliable = true
+	responses5 <- nil
+	responseErrs5 <- errors.New("context canceled")
+	err = sb3.begin(lc5, sub, f5, received, errs, end)
+	assert.Equal(t, errUnreliableFeed, err)
 }
 
 func TestDedup(t *testing.T) {
@@ -386,6 +490,8 @@ func TestDedup(t *testing.T) {
 		received: receivedPVRs,
 		new:      newPVRs,
 		recent:   rp,
+		feeds:    newFeeds(),
+		params:   NewDefaultToParameters(),
 		logger:   clogging.NewDevLogger(zapcore.DebugLevel),
 	}
 
@@ -423,7 +529,7 @@ type fixedSubscriptionBeginner struct {
 	subscribeErr error
 }
 
-func (f *fixedSubscriptionBeginner) begin(lc api.Subscriber, sub *api.Subscription,
+func (f *fixedSubscriptionBeginner) begin(lc api.Subscriber, sub *api.Subscription, fd *feed,
 	received chan *pubValueReceipt, errs chan error, end chan struct{}) error {
 	if f.subscribeErr == nil {
 		prv := <-f.received
@@ -434,6 +540,63 @@ func (f *fixedSubscriptionBeginner) begin(lc api.Subscriber, sub *api.Subscripti
 	return f.subscribeErr
 }
 
+// droppingSubscriptionBeginner drops (i.e., errors) each subscription and closes done once
+// nDrops subscriptions have been dropped.
+type droppingSubscriptionBeginner struct {
+	nDrops int
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+func (d *droppingSubscriptionBeginner) begin(lc api.Subscriber, sub *api.Subscription, f *feed,
+	received chan *pubValueReceipt, errs chan error, end chan struct{}) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nDrops--
+	if d.nDrops == 0 {
+		close(d.done)
+	}
+	return errors.New("some Recv error")
+}
+
+// rotatingClientSetBalancer returns client.ErrNoNewClients for the first nNoNewClients AddNext
+// calls and otherwise rotates between a few addresses, tracking how many times each is in the
+// set.
+type rotatingClientSetBalancer struct {
+	nNoNewClients int
+	nAdded        int
+	nRemoved      int
+	inSet         map[string]int
+	mu            sync.Mutex
+}
+
+func (r *rotatingClientSetBalancer) AddNext() (api.LibrarianClient, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inSet == nil {
+		r.inSet = make(map[string]int)
+	}
+	if r.nNoNewClients > 0 {
+		r.nNoNewClients--
+		return nil, "", client.ErrNoNewClients
+	}
+	addr := fmt.Sprintf("1.2.3.4:%d", 20100+r.nAdded)
+	r.nAdded++
+	r.inSet[addr]++
+	return nil, addr, nil
+}
+
+func (r *rotatingClientSetBalancer) Remove(address string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inSet[address] == 0 {
+		return client.ErrClientMissingFromSet
+	}
+	r.inSet[address]--
+	r.nRemoved++
+	return nil
+}
+
 type fixedClientSetBalancer struct {
 	err error
 }
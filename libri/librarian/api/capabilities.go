@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DefaultMaxPageSize is the default maximum size (in bytes) of a Page that a librarian
+	// accepts.
+	DefaultMaxPageSize = uint32(2 * 1024 * 1024) // 2 MB
+
+	// PageCiphertextOverhead is the number of bytes of encryption info a Page ciphertext has
+	// beyond its page size.
+	PageCiphertextOverhead = 16
+
+	capMaxPageSizeKey = "capabilities-max-page-size"
+	capCodecsKey      = "capabilities-codecs"
+	capLoadKey        = "capabilities-load"
+)
+
+// ErrInvalidCapabilities indicates when advertised capabilities metadata cannot be parsed.
+var ErrInvalidCapabilities = errors.New("invalid capabilities metadata")
+
+// Capabilities are what a librarian advertises to clients in the headers of its responses, so
+// clients can adapt their requests to what the librarian will accept.
+type Capabilities struct {
+
+	// MaxPageSize is the maximum size (in bytes) of a Page the librarian accepts, not including
+	// the PageCiphertextOverhead.
+	MaxPageSize uint32
+
+	// Codecs are the compression codecs the librarian supports.
+	Codecs []CompressionCodec
+
+	// Load is the fraction of the librarian's request capacity currently in use.
+	Load float64
+}
+
+// SupportsCodec returns whether the given codec is among the supported codecs.
+func (c *Capabilities) SupportsCodec(codec CompressionCodec) bool {
+	for _, c2 := range c.Codecs {
+		if c2 == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// ToMetadata returns the metadata representation of the capabilities.
+func (c *Capabilities) ToMetadata() metadata.MD {
+	codecs := make([]string, len(c.Codecs))
+	for i, codec := range c.Codecs {
+		codecs[i] = codec.String()
+	}
+	return metadata.Pairs(
+		capMaxPageSizeKey, strconv.FormatUint(uint64(c.MaxPageSize), 10),
+		capCodecsKey, strings.Join(codecs, ","),
+		capLoadKey, strconv.FormatFloat(c.Load, 'f', -1, 64),
+	)
+}
+
+// FromCapabilitiesMetadata parses the capabilities from response header metadata. It returns nil
+// capabilities when the metadata has none, e.g., from librarians that don't advertise them.
+func FromCapabilitiesMetadata(md metadata.MD) (*Capabilities, error) {
+	maxPageSizes, codecs, loads := md[capMaxPageSizeKey], md[capCodecsKey], md[capLoadKey]
+	if len(maxPageSizes) == 0 && len(codecs) == 0 && len(loads) == 0 {
+		return nil, nil
+	}
+	if len(maxPageSizes) != 1 || len(codecs) != 1 || len(loads) != 1 {
+		return nil, ErrInvalidCapabilities
+	}
+	maxPageSize, err := strconv.ParseUint(maxPageSizes[0], 10, 32)
+	if err != nil {
+		return nil, ErrInvalidCapabilities
+	}
+	load, err := strconv.ParseFloat(loads[0], 64)
+	if err != nil {
+		return nil, ErrInvalidCapabilities
+	}
+	c := &Capabilities{
+		MaxPageSize: uint32(maxPageSize),
+		Codecs:      make([]CompressionCodec, 0),
+		Load:        load,
+	}
+	if codecs[0] == "" {
+		return c, nil
+	}
+	for _, codecStr := range strings.Split(codecs[0], ",") {
+		codec, in := CompressionCodec_value[codecStr]
+		if !in {
+			// ignore codecs this client doesn't know about
+			continue
+		}
+		c.Codecs = append(c.Codecs, CompressionCodec(codec))
+	}
+	return c, nil
+}
+
+// IntersectCapabilities returns the capabilities supported by all of the given capabilities, i.e.,
+// the smallest max page size, the common codecs, and the highest load. Nil capabilities are
+// ignored, and nil is returned if all are nil.
+func IntersectCapabilities(cs ...*Capabilities) *Capabilities {
+	var merged *Capabilities
+	for _, c := range cs {
+		if c == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &Capabilities{
+				MaxPageSize: c.MaxPageSize,
+				Codecs:      append([]CompressionCodec{}, c.Codecs...),
+				Load:        c.Load,
+			}
+			continue
+		}
+		if c.MaxPageSize < merged.MaxPageSize {
+			merged.MaxPageSize = c.MaxPageSize
+		}
+		codecs := make([]CompressionCodec, 0, len(merged.Codecs))
+		for _, codec := range merged.Codecs {
+			if c.SupportsCodec(codec) {
+				codecs = append(codecs, codec)
+			}
+		}
+		merged.Codecs = codecs
+		if c.Load > merged.Load {
+			merged.Load = c.Load
+		}
+	}
+	return merged
+}
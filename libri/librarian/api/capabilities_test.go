@@ -0,0 +1,84 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCapabilities_ToFromMetadata(t *testing.T) {
+	cases := []*Capabilities{
+		{MaxPageSize: DefaultMaxPageSize, Codecs: []CompressionCodec{}, Load: 0},
+		{
+			MaxPageSize: 1024,
+			Codecs:      []CompressionCodec{CompressionCodec_NONE, CompressionCodec_GZIP},
+			Load:        0.25,
+		},
+	}
+	for _, c1 := range cases {
+		c2, err := FromCapabilitiesMetadata(c1.ToMetadata())
+		assert.Nil(t, err)
+		assert.Equal(t, c1, c2)
+	}
+}
+
+func TestFromCapabilitiesMetadata_missing(t *testing.T) {
+	c, err := FromCapabilitiesMetadata(metadata.MD{})
+	assert.Nil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestFromCapabilitiesMetadata_unknownCodec(t *testing.T) {
+	md := metadata.Pairs(
+		capMaxPageSizeKey, "1024",
+		capCodecsKey, "GZIP,SOME_FUTURE_CODEC",
+		capLoadKey, "0.5",
+	)
+	c, err := FromCapabilitiesMetadata(md)
+	assert.Nil(t, err)
+	assert.Equal(t, []CompressionCodec{CompressionCodec_GZIP}, c.Codecs)
+}
+
+func TestFromCapabilitiesMetadata_err(t *testing.T) {
+	cases := []metadata.MD{
+		metadata.Pairs(capMaxPageSizeKey, "1024"),
+		metadata.Pairs(capMaxPageSizeKey, "not a number", capCodecsKey, "", capLoadKey, "0"),
+		metadata.Pairs(capMaxPageSizeKey, "1024", capCodecsKey, "", capLoadKey, "high"),
+	}
+	for _, md := range cases {
+		c, err := FromCapabilitiesMetadata(md)
+		assert.Equal(t, ErrInvalidCapabilities, err)
+		assert.Nil(t, c)
+	}
+}
+
+func TestIntersectCapabilities(t *testing.T) {
+	assert.Nil(t, IntersectCapabilities())
+	assert.Nil(t, IntersectCapabilities(nil, nil))
+
+	c1 := &Capabilities{
+		MaxPageSize: 2048,
+		Codecs:      []CompressionCodec{CompressionCodec_NONE, CompressionCodec_GZIP},
+		Load:        0.1,
+	}
+	c2 := &Capabilities{
+		MaxPageSize: 1024,
+		Codecs:      []CompressionCodec{CompressionCodec_NONE},
+		Load:        0.5,
+	}
+	merged := IntersectCapabilities(c1, nil, c2)
+	assert.Equal(t, uint32(1024), merged.MaxPageSize)
+	assert.Equal(t, []CompressionCodec{CompressionCodec_NONE}, merged.Codecs)
+	assert.Equal(t, 0.5, merged.Load)
+
+	// inputs are not modified
+	assert.Equal(t, uint32(2048), c1.MaxPageSize)
+	assert.Len(t, c1.Codecs, 2)
+}
+
+func TestCapabilities_SupportsCodec(t *testing.T) {
+	c := &Capabilities{Codecs: []CompressionCodec{CompressionCodec_GZIP}}
+	assert.True(t, c.SupportsCodec(CompressionCodec_GZIP))
+	assert.False(t, c.SupportsCodec(CompressionCodec_NONE))
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"crypto/hmac"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -86,10 +87,26 @@ var (
 
 	// ErrEmptyPageKeys indicates when the PageKeys of an entry are unexpectedly zero-length.
 	ErrEmptyPageKeys = errors.New("empty page keys")
+
+	// ErrSliceOutOfRange indicates when a slice extends past the end of the value it slices.
+	ErrSliceOutOfRange = errors.New("slice out of range")
 )
 
-// GetKey calculates the key from the has of the proto.Message.
+// GetKey calculates the key from the has of the proto.Message. System documents are instead
+// keyed by GetSystemKey, shard documents by GetShardKey, and shard manifest documents by
+// GetShardManifestKey.
 func GetKey(value proto.Message) (id.ID, error) {
+	if doc, ok := value.(*Document); ok {
+		if sys := doc.GetSystem(); sys != nil && sys.Record != nil {
+			return GetSystemKey(sys.Record.Type, sys.PubKey, sys.Record.Name), nil
+		}
+		if shard := doc.GetShard(); shard != nil {
+			return GetShardKey(shard), nil
+		}
+		if manifest := doc.GetShardManifest(); manifest != nil {
+			return GetShardManifestKey(manifest.OriginalKey), nil
+		}
+	}
 	valueBytes, err := proto.Marshal(value)
 	if err != nil {
 		return nil, err
@@ -98,7 +115,98 @@ func GetKey(value proto.Message) (id.ID, error) {
 	return id.FromBytes(hash[:]), nil
 }
 
-// GetAuthorPub returns the author public key for a given document.
+// SliceHMAC256 returns the HMAC-256, given the MAC key, of the length bytes of the value
+// starting at offset.
+func SliceHMAC256(value, macKey []byte, offset, length uint32) ([]byte, error) {
+	end := uint64(offset) + uint64(length)
+	if end > uint64(len(value)) {
+		return nil, ErrSliceOutOfRange
+	}
+	macer := hmac.New(sha256.New, macKey)
+	_, err := macer.Write(value[offset:end])
+	cerrors.MaybePanic(err) // should never happen b/c sha256.Write always returns nil error
+	return macer.Sum(nil), nil
+}
+
+// DocumentType is the type of a document's contents.
+type DocumentType int
+
+const (
+	// EnvelopeType is the type of documents containing an Envelope.
+	EnvelopeType DocumentType = iota
+
+	// EntryType is the type of documents containing an Entry.
+	EntryType
+
+	// PageType is the type of documents containing a Page.
+	PageType
+
+	// SystemType is the type of documents containing a SystemDocument.
+	SystemType
+
+	// ShardType is the type of documents containing a Shard.
+	ShardType
+
+	// ShardManifestType is the type of documents containing a ShardManifest.
+	ShardManifestType
+)
+
+func (t DocumentType) String() string {
+	switch t {
+	case EnvelopeType:
+		return "envelope"
+	case EntryType:
+		return "entry"
+	case PageType:
+		return "page"
+	case SystemType:
+		return "system"
+	case ShardType:
+		return "shard"
+	case ShardManifestType:
+		return "shard_manifest"
+	}
+	panic(fmt.Errorf("unknown DocumentType value %d", t))
+}
+
+// GetDocumentType returns the type of the document's contents.
+func GetDocumentType(d *Document) DocumentType {
+	switch d.Contents.(type) {
+	case *Document_Envelope:
+		return EnvelopeType
+	case *Document_Entry:
+		return EntryType
+	case *Document_Page:
+		return PageType
+	case *Document_System:
+		return SystemType
+	case *Document_Shard:
+		return ShardType
+	case *Document_ShardManifest:
+		return ShardManifestType
+	}
+	panic(ErrUnknownDocumentType)
+}
+
+// ReplicationPolicy gives the number of replicas to store documents with, e.g., so critical
+// metadata is more durable than bulk payload.
+type ReplicationPolicy interface {
+	// NReplicas returns the number of replicas to store the document with, or zero for the
+	// default.
+	NReplicas(d *Document) uint
+}
+
+// TypeReplicas is a ReplicationPolicy giving the number of replicas for each document type, e.g.,
+// envelopes at 5 replicas and pages at 3. Types not in the map use the default.
+type TypeReplicas map[DocumentType]uint
+
+// NReplicas returns the number of replicas for the document's type.
+func (r TypeReplicas) NReplicas(d *Document) uint {
+	return r[GetDocumentType(d)]
+}
+
+// GetAuthorPub returns the author public key for a given document. Shards and shard manifests
+// have no author, so it returns nil for them.
 func GetAuthorPub(d *Document) []byte {
 	switch c := d.Contents.(type) {
 	case *Document_Entry:
@@ -107,6 +215,10 @@ func GetAuthorPub(d *Document) []byte {
 		return c.Page.AuthorPublicKey
 	case *Document_Envelope:
 		return c.Envelope.AuthorPublicKey
+	case *Document_System:
+		return c.System.PubKey
+	case *Document_Shard, *Document_ShardManifest:
+		return nil
 	}
 	panic(ErrUnknownDocumentType)
 }
@@ -158,6 +270,12 @@ func ValidateDocument(d *Document) error {
 		return ValidateEntry(c.Entry)
 	case *Document_Page:
 		return ValidatePage(c.Page)
+	case *Document_System:
+		return ValidateSystemDocument(c.System)
+	case *Document_Shard:
+		return ValidateShard(c.Shard)
+	case *Document_ShardManifest:
+		return ValidateShardManifest(c.ShardManifest)
 	}
 	return ErrUnknownDocumentType
 }
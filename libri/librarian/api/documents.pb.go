@@ -5,16 +5,23 @@
 Package api is a generated protocol buffer package.
 
 It is generated from these files:
+
 	librarian/api/documents.proto
 	librarian/api/librarian.proto
 
 It has these top-level messages:
+
 	Document
 	Envelope
 	Entry
 	EntryMetadata
 	SchemaArtifact
 	Page
+	SystemDocument
+	SystemRecord
+	Shard
+	NetworkParameters
+	ShardManifest
 	RequestMetadata
 	ResponseMetadata
 	IntroduceRequest
@@ -35,6 +42,39 @@ It has these top-level messages:
 	Publication
 	Subscription
 	BloomFilter
+	BuildInfo
+	SignedBuildInfo
+	RoutingTableRequest
+	RoutingTableResponse
+	RoutingBucket
+	RoutingPeer
+	PeerBan
+	ListBansRequest
+	ListBansResponse
+	BanRequest
+	BanResponse
+	UnbanRequest
+	UnbanResponse
+	StoreReceipt
+	SignedStoreReceipt
+	OrgQuota
+	ListQuotasRequest
+	ListQuotasResponse
+	SetQuotaRequest
+	SetQuotaResponse
+	SyncRequest
+	KeyRangeDigest
+	SyncResponse
+	AllowList
+	SignedAllowList
+	GetAllowListRequest
+	GetAllowListResponse
+	SetAllowListRequest
+	SetAllowListResponse
+	PinRequest
+	PinResponse
+	UnpinRequest
+	UnpinResponse
 */
 package api
 
@@ -75,12 +115,45 @@ func (x CompressionCodec) String() string {
 }
 func (CompressionCodec) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
-// Document contains either an Envelope, Entry, or Page message.
+// SystemDocumentType denotes the kind of protocol-level record a SystemDocument contains.
+type SystemDocumentType int32
+
+const (
+	// unset type, which is never valid
+	SystemDocumentType_SYSTEM_DOCUMENT_TYPE_UNSPECIFIED SystemDocumentType = 0
+	SystemDocumentType_ORG_PROFILE                      SystemDocumentType = 1
+	SystemDocumentType_POINTER_RECORD                   SystemDocumentType = 2
+	SystemDocumentType_NETWORK_PARAMETERS               SystemDocumentType = 3
+)
+
+var SystemDocumentType_name = map[int32]string{
+	0: "SYSTEM_DOCUMENT_TYPE_UNSPECIFIED",
+	1: "ORG_PROFILE",
+	2: "POINTER_RECORD",
+	3: "NETWORK_PARAMETERS",
+}
+var SystemDocumentType_value = map[string]int32{
+	"SYSTEM_DOCUMENT_TYPE_UNSPECIFIED": 0,
+	"ORG_PROFILE":                      1,
+	"POINTER_RECORD":                   2,
+	"NETWORK_PARAMETERS":               3,
+}
+
+func (x SystemDocumentType) String() string {
+	return proto.EnumName(SystemDocumentType_name, int32(x))
+}
+func (SystemDocumentType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+// Document contains either an Envelope, Entry, Page, SystemDocument, Shard, or ShardManifest
+// message.
 type Document struct {
 	// Types that are valid to be assigned to Contents:
 	//	*Document_Envelope
 	//	*Document_Entry
 	//	*Document_Page
+	//	*Document_System
+	//	*Document_Shard
+	//	*Document_ShardManifest
 	Contents isDocument_Contents `protobuf_oneof:"contents"`
 }
 
@@ -89,9 +162,7 @@ func (m *Document) String() string            { return proto.CompactTextString(m
 func (*Document) ProtoMessage()               {}
 func (*Document) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
-type isDocument_Contents interface {
-	isDocument_Contents()
-}
+type isDocument_Contents interface{ isDocument_Contents() }
 
 type Document_Envelope struct {
 	Envelope *Envelope `protobuf:"bytes,1,opt,name=envelope,oneof"`
@@ -102,10 +173,22 @@ type Document_Entry struct {
 type Document_Page struct {
 	Page *Page `protobuf:"bytes,3,opt,name=page,oneof"`
 }
+type Document_System struct {
+	System *SystemDocument `protobuf:"bytes,4,opt,name=system,oneof"`
+}
+type Document_Shard struct {
+	Shard *Shard `protobuf:"bytes,5,opt,name=shard,oneof"`
+}
+type Document_ShardManifest struct {
+	ShardManifest *ShardManifest `protobuf:"bytes,6,opt,name=shard_manifest,json=shardManifest,oneof"`
+}
 
-func (*Document_Envelope) isDocument_Contents() {}
-func (*Document_Entry) isDocument_Contents()    {}
-func (*Document_Page) isDocument_Contents()     {}
+func (*Document_Envelope) isDocument_Contents()      {}
+func (*Document_Entry) isDocument_Contents()         {}
+func (*Document_Page) isDocument_Contents()          {}
+func (*Document_System) isDocument_Contents()        {}
+func (*Document_Shard) isDocument_Contents()         {}
+func (*Document_ShardManifest) isDocument_Contents() {}
 
 func (m *Document) GetContents() isDocument_Contents {
 	if m != nil {
@@ -135,12 +218,36 @@ func (m *Document) GetPage() *Page {
 	return nil
 }
 
+func (m *Document) GetSystem() *SystemDocument {
+	if x, ok := m.GetContents().(*Document_System); ok {
+		return x.System
+	}
+	return nil
+}
+
+func (m *Document) GetShard() *Shard {
+	if x, ok := m.GetContents().(*Document_Shard); ok {
+		return x.Shard
+	}
+	return nil
+}
+
+func (m *Document) GetShardManifest() *ShardManifest {
+	if x, ok := m.GetContents().(*Document_ShardManifest); ok {
+		return x.ShardManifest
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Document) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Document_OneofMarshaler, _Document_OneofUnmarshaler, _Document_OneofSizer, []interface{}{
 		(*Document_Envelope)(nil),
 		(*Document_Entry)(nil),
 		(*Document_Page)(nil),
+		(*Document_System)(nil),
+		(*Document_Shard)(nil),
+		(*Document_ShardManifest)(nil),
 	}
 }
 
@@ -163,6 +270,21 @@ func _Document_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Page); err != nil {
 			return err
 		}
+	case *Document_System:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.System); err != nil {
+			return err
+		}
+	case *Document_Shard:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Shard); err != nil {
+			return err
+		}
+	case *Document_ShardManifest:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ShardManifest); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Document.Contents has unexpected type %T", x)
@@ -197,6 +319,30 @@ func _Document_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffe
 		err := b.DecodeMessage(msg)
 		m.Contents = &Document_Page{msg}
 		return true, err
+	case 4: // contents.system
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(SystemDocument)
+		err := b.DecodeMessage(msg)
+		m.Contents = &Document_System{msg}
+		return true, err
+	case 5: // contents.shard
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Shard)
+		err := b.DecodeMessage(msg)
+		m.Contents = &Document_Shard{msg}
+		return true, err
+	case 6: // contents.shard_manifest
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ShardManifest)
+		err := b.DecodeMessage(msg)
+		m.Contents = &Document_ShardManifest{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -221,6 +367,21 @@ func _Document_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(3<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Document_System:
+		s := proto.Size(x.System)
+		n += proto.SizeVarint(4<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Document_Shard:
+		s := proto.Size(x.Shard)
+		n += proto.SizeVarint(5<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Document_ShardManifest:
+		s := proto.Size(x.ShardManifest)
+		n += proto.SizeVarint(6<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -477,9 +638,8 @@ func (m *EntryMetadata) GetDataDictionary() *SchemaArtifact {
 // know how to handle explicitly.
 //
 // Preferred formats:
-//  - schema: Protobuf
-//  - data dictionary: Markdown
-//
+//   - schema: Protobuf
+//   - data dictionary: Markdown
 type SchemaArtifact struct {
 	// group owning the schema (commonly a Github user, e.g., 'drausin')
 	Group string `protobuf:"bytes,1,opt,name=group" json:"group,omitempty"`
@@ -579,6 +739,287 @@ func (m *Page) GetCiphertextMac() []byte {
 	return nil
 }
 
+// SystemDocument is a protocol-level record stored in the reserved system keyspace. Rather than
+// the hash of its contents, its key is derived from its type, name, and signing public key, so
+// only the holder of that key can create it and application content can never collide with it.
+type SystemDocument struct {
+	Record *SystemRecord `protobuf:"bytes,1,opt,name=record" json:"record,omitempty"`
+	// ECDSA public key of the record signer
+	PubKey []byte `protobuf:"bytes,2,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	// signature (in the form of an encoded json web token) on the record by the signer key
+	Signature string `protobuf:"bytes,3,opt,name=signature" json:"signature,omitempty"`
+	// 32-byte SHA-256 hash of the serialized record, verified on every read since the key
+	// isn't the hash of the value as it is for other documents
+	Checksum []byte `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+}
+
+func (m *SystemDocument) Reset()                    { *m = SystemDocument{} }
+func (m *SystemDocument) String() string            { return proto.CompactTextString(m) }
+func (*SystemDocument) ProtoMessage()               {}
+func (*SystemDocument) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *SystemDocument) GetRecord() *SystemRecord {
+	if m != nil {
+		return m.Record
+	}
+	return nil
+}
+
+func (m *SystemDocument) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
+	}
+	return nil
+}
+
+func (m *SystemDocument) GetSignature() string {
+	if m != nil {
+		return m.Signature
+	}
+	return ""
+}
+
+func (m *SystemDocument) GetChecksum() []byte {
+	if m != nil {
+		return m.Checksum
+	}
+	return nil
+}
+
+// SystemRecord contains the signed contents of a SystemDocument.
+type SystemRecord struct {
+	// kind of record
+	Type SystemDocumentType `protobuf:"varint,1,opt,name=type,enum=api.SystemDocumentType" json:"type,omitempty"`
+	// name of the record, unique among the signer's records of the same type
+	Name []byte `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// serialized record contents, whose format depends on the type
+	Contents []byte `protobuf:"bytes,3,opt,name=contents,proto3" json:"contents,omitempty"`
+	// sequence number of the record, which must increase with each new version under the same
+	// key so that old versions can't be replayed over newer ones
+	Sequence uint64 `protobuf:"varint,4,opt,name=sequence" json:"sequence,omitempty"`
+}
+
+func (m *SystemRecord) Reset()                    { *m = SystemRecord{} }
+func (m *SystemRecord) String() string            { return proto.CompactTextString(m) }
+func (*SystemRecord) ProtoMessage()               {}
+func (*SystemRecord) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *SystemRecord) GetType() SystemDocumentType {
+	if m != nil {
+		return m.Type
+	}
+	return SystemDocumentType_SYSTEM_DOCUMENT_TYPE_UNSPECIFIED
+}
+
+func (m *SystemRecord) GetName() []byte {
+	if m != nil {
+		return m.Name
+	}
+	return nil
+}
+
+func (m *SystemRecord) GetContents() []byte {
+	if m != nil {
+		return m.Contents
+	}
+	return nil
+}
+
+func (m *SystemRecord) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+// Shard is one of the data or parity shards a document is split into when stored in erasure-coded
+// mode. It is stored in the reserved shard keyspace under a key derived from its contents, which
+// the document's ShardManifest lists, so each shard can be verified when fetched.
+type Shard struct {
+	// 32-byte key of the original document
+	OriginalKey []byte `protobuf:"bytes,1,opt,name=original_key,json=originalKey,proto3" json:"original_key,omitempty"`
+	// index of the shard, with the data shards first and the parity shards last
+	Index uint32 `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
+	// number of data shards the original document was split into
+	NDataShards uint32 `protobuf:"varint,3,opt,name=n_data_shards,json=nDataShards" json:"n_data_shards,omitempty"`
+	// number of parity shards computed from the data shards
+	NParityShards uint32 `protobuf:"varint,4,opt,name=n_parity_shards,json=nParityShards" json:"n_parity_shards,omitempty"`
+	// byte length of the marshaled original document
+	Size uint64 `protobuf:"varint,5,opt,name=size" json:"size,omitempty"`
+	// shard contents
+	Data []byte `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *Shard) Reset()                    { *m = Shard{} }
+func (m *Shard) String() string            { return proto.CompactTextString(m) }
+func (*Shard) ProtoMessage()               {}
+func (*Shard) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *Shard) GetOriginalKey() []byte {
+	if m != nil {
+		return m.OriginalKey
+	}
+	return nil
+}
+
+func (m *Shard) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *Shard) GetNDataShards() uint32 {
+	if m != nil {
+		return m.NDataShards
+	}
+	return 0
+}
+
+func (m *Shard) GetNParityShards() uint32 {
+	if m != nil {
+		return m.NParityShards
+	}
+	return 0
+}
+
+func (m *Shard) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Shard) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// NetworkParameters are the values network maintainers recommend librarians and authors adopt.
+// They are published as the contents of a NETWORK_PARAMETERS SystemDocument signed by the
+// maintainers' key, so parameter changes can be coordinated across the network. Zero values leave
+// the local setting unchanged.
+type NetworkParameters struct {
+	// recommended number of replicas stored of each document
+	NReplicas uint32 `protobuf:"varint,1,opt,name=n_replicas,json=nReplicas" json:"n_replicas,omitempty"`
+	// recommended maximum size (in bytes) of a Page
+	MaxPageSize uint32 `protobuf:"varint,2,opt,name=max_page_size,json=maxPageSize" json:"max_page_size,omitempty"`
+	// recommended number of workers handling requests to each endpoint, keyed by endpoint name
+	WorkerPoolSizes map[string]uint32 `protobuf:"bytes,3,rep,name=worker_pool_sizes,json=workerPoolSizes" json:"worker_pool_sizes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// epoch time (in seconds) when the parameters were issued
+	IssuedTime int64 `protobuf:"varint,4,opt,name=issued_time,json=issuedTime" json:"issued_time,omitempty"`
+	// recommended max requests per second from each peer to each endpoint, keyed by endpoint name
+	RequesterPeerRates map[string]float64 `protobuf:"bytes,5,rep,name=requester_peer_rates,json=requesterPeerRates" json:"requester_peer_rates,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	// recommended max requests per second from each org-signing peer to each endpoint, keyed by
+	// endpoint name
+	RequesterOrgRates map[string]float64 `protobuf:"bytes,6,rep,name=requester_org_rates,json=requesterOrgRates" json:"requester_org_rates,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+}
+
+func (m *NetworkParameters) Reset()                    { *m = NetworkParameters{} }
+func (m *NetworkParameters) String() string            { return proto.CompactTextString(m) }
+func (*NetworkParameters) ProtoMessage()               {}
+func (*NetworkParameters) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *NetworkParameters) GetNReplicas() uint32 {
+	if m != nil {
+		return m.NReplicas
+	}
+	return 0
+}
+
+func (m *NetworkParameters) GetMaxPageSize() uint32 {
+	if m != nil {
+		return m.MaxPageSize
+	}
+	return 0
+}
+
+func (m *NetworkParameters) GetWorkerPoolSizes() map[string]uint32 {
+	if m != nil {
+		return m.WorkerPoolSizes
+	}
+	return nil
+}
+
+func (m *NetworkParameters) GetIssuedTime() int64 {
+	if m != nil {
+		return m.IssuedTime
+	}
+	return 0
+}
+
+func (m *NetworkParameters) GetRequesterPeerRates() map[string]float64 {
+	if m != nil {
+		return m.RequesterPeerRates
+	}
+	return nil
+}
+
+func (m *NetworkParameters) GetRequesterOrgRates() map[string]float64 {
+	if m != nil {
+		return m.RequesterOrgRates
+	}
+	return nil
+}
+
+// ShardManifest records that a document is stored as erasure-coded shards. It is stored in the
+// reserved shard manifest keyspace under a key derived from the original document's key, so
+// whether a document is sharded and where its shards are can be found from its key alone.
+type ShardManifest struct {
+	// 32-byte key of the original document
+	OriginalKey []byte `protobuf:"bytes,1,opt,name=original_key,json=originalKey,proto3" json:"original_key,omitempty"`
+	// number of data shards the original document was split into
+	NDataShards uint32 `protobuf:"varint,2,opt,name=n_data_shards,json=nDataShards" json:"n_data_shards,omitempty"`
+	// number of parity shards computed from the data shards
+	NParityShards uint32 `protobuf:"varint,3,opt,name=n_parity_shards,json=nParityShards" json:"n_parity_shards,omitempty"`
+	// byte length of the marshaled original document
+	Size uint64 `protobuf:"varint,4,opt,name=size" json:"size,omitempty"`
+	// 32-byte keys of the shards, in shard index order
+	ShardKeys [][]byte `protobuf:"bytes,5,rep,name=shard_keys,json=shardKeys,proto3" json:"shard_keys,omitempty"`
+}
+
+func (m *ShardManifest) Reset()                    { *m = ShardManifest{} }
+func (m *ShardManifest) String() string            { return proto.CompactTextString(m) }
+func (*ShardManifest) ProtoMessage()               {}
+func (*ShardManifest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *ShardManifest) GetOriginalKey() []byte {
+	if m != nil {
+		return m.OriginalKey
+	}
+	return nil
+}
+
+func (m *ShardManifest) GetNDataShards() uint32 {
+	if m != nil {
+		return m.NDataShards
+	}
+	return 0
+}
+
+func (m *ShardManifest) GetNParityShards() uint32 {
+	if m != nil {
+		return m.NParityShards
+	}
+	return 0
+}
+
+func (m *ShardManifest) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *ShardManifest) GetShardKeys() [][]byte {
+	if m != nil {
+		return m.ShardKeys
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Document)(nil), "api.Document")
 	proto.RegisterType((*Envelope)(nil), "api.Envelope")
@@ -586,56 +1027,98 @@ func init() {
 	proto.RegisterType((*EntryMetadata)(nil), "api.EntryMetadata")
 	proto.RegisterType((*SchemaArtifact)(nil), "api.SchemaArtifact")
 	proto.RegisterType((*Page)(nil), "api.Page")
+	proto.RegisterType((*SystemDocument)(nil), "api.SystemDocument")
+	proto.RegisterType((*SystemRecord)(nil), "api.SystemRecord")
+	proto.RegisterType((*Shard)(nil), "api.Shard")
+	proto.RegisterType((*NetworkParameters)(nil), "api.NetworkParameters")
+	proto.RegisterType((*ShardManifest)(nil), "api.ShardManifest")
 	proto.RegisterEnum("api.CompressionCodec", CompressionCodec_name, CompressionCodec_value)
+	proto.RegisterEnum("api.SystemDocumentType", SystemDocumentType_name, SystemDocumentType_value)
 }
 
 func init() { proto.RegisterFile("librarian/api/documents.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 718 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0x4d, 0x4f, 0xdb, 0x4a,
-	0x14, 0xc5, 0xf9, 0x80, 0xf8, 0x42, 0x3e, 0x18, 0x40, 0xcf, 0xe2, 0x89, 0xf7, 0x78, 0x91, 0x5e,
-	0x4b, 0xa1, 0x0a, 0x12, 0x95, 0xaa, 0xaa, 0x55, 0x17, 0xe5, 0x43, 0xa5, 0x42, 0xd0, 0xc8, 0xb0,
-	0xea, 0x26, 0x1a, 0xc6, 0x17, 0x32, 0x25, 0xb6, 0x47, 0xe3, 0x09, 0xc2, 0x2c, 0xbb, 0xea, 0xae,
-	0x3f, 0xa2, 0x7f, 0xa9, 0xff, 0xa6, 0x9b, 0x6a, 0xae, 0x9d, 0xc4, 0x49, 0xa9, 0xd4, 0xae, 0x3c,
-	0x73, 0xee, 0x99, 0x33, 0xf7, 0x9e, 0xb9, 0xd7, 0xb0, 0x31, 0x90, 0x97, 0x9a, 0x6b, 0xc9, 0xa3,
-	0x5d, 0xae, 0xe4, 0x6e, 0x10, 0x8b, 0x61, 0x88, 0x91, 0x49, 0x3a, 0x4a, 0xc7, 0x26, 0x66, 0x65,
-	0xae, 0x64, 0xfb, 0xb3, 0x03, 0xb5, 0xc3, 0x3c, 0xc0, 0x76, 0xa0, 0x86, 0xd1, 0x2d, 0x0e, 0x62,
-	0x85, 0x9e, 0xb3, 0xe9, 0x6c, 0x2d, 0xee, 0xd5, 0x3b, 0x5c, 0xc9, 0xce, 0x51, 0x0e, 0x1e, 0xcf,
-	0xf9, 0x63, 0x02, 0x6b, 0x43, 0x15, 0x23, 0xa3, 0x53, 0xaf, 0x44, 0x4c, 0xc8, 0x99, 0x46, 0xa7,
-	0xc7, 0x73, 0x7e, 0x16, 0x62, 0xff, 0x42, 0x45, 0xf1, 0x6b, 0xf4, 0xca, 0x44, 0x71, 0x89, 0xd2,
-	0xe5, 0xd7, 0x56, 0x88, 0x02, 0xfb, 0x00, 0x35, 0x11, 0x47, 0xc6, 0x66, 0xd5, 0xfe, 0xe6, 0x40,
-	0x6d, 0x74, 0x13, 0xfb, 0x1b, 0x5c, 0x92, 0xe8, 0xdd, 0x60, 0x4a, 0xb9, 0x2c, 0xd9, 0xab, 0x8d,
-	0x4e, 0x4f, 0x30, 0x65, 0xdb, 0xb0, 0xcc, 0x87, 0xa6, 0x1f, 0xeb, 0x9e, 0x1a, 0x5e, 0x0e, 0xa4,
-	0x20, 0x52, 0x89, 0x48, 0xcd, 0x2c, 0xd0, 0x25, 0x3c, 0xe7, 0x6a, 0xe4, 0x01, 0x4e, 0x71, 0xcb,
-	0x19, 0x37, 0x0b, 0x4c, 0xb8, 0xff, 0x43, 0x03, 0xf1, 0xa6, 0x27, 0xa4, 0xea, 0xa3, 0x36, 0x78,
-	0x67, 0xbc, 0x0a, 0x11, 0xeb, 0x88, 0x37, 0x07, 0x63, 0x90, 0x3d, 0x05, 0x36, 0x4d, 0xeb, 0x85,
-	0x5c, 0x78, 0x55, 0xa2, 0xb6, 0xa6, 0xa8, 0xa7, 0x5c, 0xb4, 0xbf, 0x3b, 0x50, 0x25, 0x5b, 0x1e,
-	0x4e, 0xdb, 0x79, 0x38, 0xed, 0x8d, 0xdc, 0xb9, 0xd2, 0x8c, 0x73, 0x99, 0x6f, 0xd6, 0x1e, 0xfb,
-	0xb5, 0x0a, 0x89, 0x57, 0xde, 0x2c, 0x5b, 0x7b, 0x2c, 0x70, 0x82, 0x69, 0xc2, 0xfe, 0x83, 0x25,
-	0xa1, 0x91, 0x1b, 0x0c, 0x7a, 0x46, 0x86, 0x48, 0x45, 0xd4, 0xfd, 0xc5, 0x1c, 0xbb, 0x90, 0x21,
-	0xb2, 0x5d, 0x58, 0x09, 0xd1, 0xf0, 0x80, 0x1b, 0x5e, 0x2c, 0x37, 0xab, 0x81, 0x8d, 0x42, 0x85,
-	0x9a, 0x9f, 0xc3, 0x5f, 0x0f, 0x1c, 0xa0, 0xc2, 0xe7, 0xe9, 0xd0, 0xda, 0xcf, 0x87, 0x6c, 0xf5,
-	0x5f, 0x2b, 0x50, 0xa7, 0xea, 0x4f, 0xf3, 0x30, 0xdb, 0x00, 0x08, 0x31, 0x90, 0xbc, 0x67, 0xd2,
-	0xbc, 0xcd, 0x5c, 0xdf, 0x25, 0xe4, 0x22, 0x55, 0xc8, 0xf6, 0x61, 0x59, 0xc4, 0xa1, 0xd2, 0x98,
-	0x24, 0x32, 0x8e, 0x7a, 0x22, 0x0e, 0x50, 0x90, 0x0b, 0x8d, 0xbd, 0x35, 0x72, 0xe1, 0x60, 0x12,
-	0x3d, 0xb0, 0x41, 0xbf, 0x25, 0x66, 0x10, 0xf6, 0x18, 0x9a, 0x85, 0x1c, 0x13, 0x79, 0x9f, 0x75,
-	0x60, 0xc5, 0x6f, 0x4c, 0xe0, 0x73, 0x79, 0x8f, 0xf6, 0xc1, 0x67, 0x8a, 0xc9, 0x1f, 0x5c, 0x14,
-	0x8b, 0x60, 0x3b, 0xb0, 0x3c, 0x8c, 0x46, 0xb7, 0x60, 0x90, 0x29, 0x56, 0x49, 0xb1, 0x55, 0x0c,
-	0x90, 0xe6, 0x13, 0x98, 0xc2, 0x0a, 0x16, 0x35, 0x8b, 0xb8, 0xd5, 0xdd, 0x07, 0x50, 0x3a, 0x56,
-	0xa8, 0x8d, 0xc4, 0xc4, 0x5b, 0xd8, 0x2c, 0x6f, 0x2d, 0xee, 0xb5, 0x27, 0x73, 0x34, 0xb2, 0xac,
-	0xd3, 0x1d, 0x93, 0x08, 0xf7, 0x0b, 0xa7, 0xd8, 0x3a, 0xd4, 0xae, 0xe4, 0x00, 0x15, 0x37, 0x7d,
-	0xaf, 0x46, 0x66, 0x8e, 0xf7, 0x6c, 0x07, 0xe6, 0x13, 0xd1, 0xc7, 0x90, 0x7b, 0x2e, 0xb5, 0xd1,
-	0x0a, 0x69, 0x9f, 0x13, 0xf4, 0x46, 0x1b, 0x79, 0xc5, 0x85, 0xf1, 0x73, 0x0a, 0x7b, 0x05, 0x0d,
-	0x7b, 0xd9, 0xa1, 0x14, 0x46, 0xc6, 0x11, 0xd7, 0xa9, 0x07, 0xbf, 0x3e, 0x34, 0x43, 0x5d, 0x7f,
-	0x0d, 0xcd, 0x99, 0x24, 0x59, 0x0b, 0xca, 0xa3, 0xfe, 0x76, 0x7d, 0xbb, 0x64, 0xab, 0x50, 0xbd,
-	0xe5, 0x83, 0x21, 0xe6, 0xa3, 0x9a, 0x6d, 0x5e, 0x96, 0x5e, 0x38, 0xed, 0x4f, 0x0e, 0x34, 0xa6,
-	0x6f, 0xb0, 0xe4, 0x6b, 0x1d, 0x0f, 0x55, 0x2e, 0x90, 0x6d, 0x98, 0x07, 0x0b, 0x4a, 0xc7, 0x1f,
-	0x51, 0x18, 0x12, 0x71, 0xfd, 0xd1, 0x96, 0x31, 0x3b, 0x30, 0xa6, 0x4f, 0x0f, 0xed, 0xfa, 0xb4,
-	0xb6, 0x58, 0xc4, 0xf3, 0x01, 0x70, 0x7d, 0x5a, 0x5b, 0x85, 0x5b, 0xd4, 0xb6, 0x57, 0xe8, 0x05,
-	0x5d, 0x7f, 0xb4, 0x6d, 0x7f, 0x71, 0xa0, 0x62, 0x47, 0xec, 0x8f, 0xe6, 0x74, 0x15, 0xaa, 0x32,
-	0x0a, 0xf0, 0x8e, 0xd2, 0xa9, 0xfb, 0xd9, 0x86, 0xfd, 0x03, 0x50, 0x98, 0xaa, 0xec, 0x6f, 0x53,
-	0x40, 0x7e, 0xb3, 0xef, 0xb6, 0x1f, 0x41, 0x6b, 0xb6, 0xdb, 0x59, 0x0d, 0x2a, 0x67, 0xef, 0xcf,
-	0x8e, 0x5a, 0x73, 0x76, 0xf5, 0xf6, 0xc3, 0xbb, 0x6e, 0xcb, 0xb9, 0x9c, 0xa7, 0x1f, 0xfa, 0xb3,
-	0x1f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x99, 0x7c, 0xfb, 0xd4, 0xf1, 0x05, 0x00, 0x00,
+	// 1289 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0xdd, 0x6e, 0x1b, 0x45,
+	0x14, 0xce, 0xfa, 0xaf, 0xde, 0xe3, 0xf8, 0x6f, 0x9a, 0x36, 0x56, 0x4a, 0x68, 0xb0, 0xa0, 0xb4,
+	0x0d, 0x4d, 0xa5, 0x20, 0x21, 0x04, 0xe2, 0xa2, 0x89, 0xb7, 0x4d, 0x54, 0x62, 0x5b, 0x63, 0x57,
+	0x55, 0x91, 0xd0, 0x32, 0x59, 0x4f, 0xed, 0x21, 0xde, 0x1f, 0x66, 0xc7, 0x6d, 0xdc, 0x4b, 0x6e,
+	0xb8, 0x83, 0x5b, 0xee, 0x79, 0x02, 0x24, 0x1e, 0x85, 0x27, 0xe0, 0x35, 0xb8, 0x41, 0x73, 0x76,
+	0x6c, 0xaf, 0x9d, 0x84, 0xb6, 0x57, 0x3b, 0xf3, 0x9d, 0x6f, 0xce, 0xef, 0x9c, 0x33, 0x0b, 0xdb,
+	0x63, 0x71, 0x2a, 0x99, 0x14, 0x2c, 0x78, 0xc8, 0x22, 0xf1, 0x70, 0x10, 0x7a, 0x13, 0x9f, 0x07,
+	0x2a, 0xde, 0x8b, 0x64, 0xa8, 0x42, 0x92, 0x65, 0x91, 0x68, 0xfe, 0x9e, 0x81, 0x62, 0xcb, 0x08,
+	0xc8, 0x2e, 0x14, 0x79, 0xf0, 0x8a, 0x8f, 0xc3, 0x88, 0x37, 0xac, 0x1d, 0xeb, 0x6e, 0x69, 0xbf,
+	0xbc, 0xc7, 0x22, 0xb1, 0xe7, 0x18, 0xf0, 0x68, 0x8d, 0xce, 0x09, 0xa4, 0x09, 0x79, 0x1e, 0x28,
+	0x39, 0x6d, 0x64, 0x90, 0x09, 0x86, 0xa9, 0xe4, 0xf4, 0x68, 0x8d, 0x26, 0x22, 0x72, 0x1b, 0x72,
+	0x11, 0x1b, 0xf2, 0x46, 0x16, 0x29, 0x36, 0x52, 0xba, 0x6c, 0xa8, 0x15, 0xa1, 0x80, 0x3c, 0x80,
+	0x42, 0x3c, 0x8d, 0x15, 0xf7, 0x1b, 0x39, 0xa4, 0x5c, 0x47, 0x4a, 0x0f, 0xa1, 0x99, 0x5b, 0x47,
+	0x6b, 0xd4, 0x90, 0xb4, 0xcd, 0x78, 0xc4, 0xe4, 0xa0, 0x91, 0x4f, 0xd9, 0xec, 0x69, 0x44, 0xdb,
+	0x44, 0x11, 0xf9, 0x1a, 0x2a, 0xb8, 0x70, 0x7d, 0x16, 0x88, 0x97, 0x3c, 0x56, 0x8d, 0x02, 0x92,
+	0xc9, 0x82, 0x7c, 0x62, 0x24, 0x47, 0x6b, 0xb4, 0x1c, 0xa7, 0x81, 0x03, 0x80, 0xa2, 0x17, 0x06,
+	0x4a, 0x67, 0xa9, 0xf9, 0xb7, 0x05, 0xc5, 0x59, 0xe4, 0xe4, 0x16, 0xd8, 0x18, 0x92, 0x7b, 0xc6,
+	0xa7, 0x98, 0x9b, 0x75, 0x9d, 0x0a, 0x25, 0xa7, 0x4f, 0xf9, 0x94, 0xdc, 0x87, 0x3a, 0x9b, 0xa8,
+	0x51, 0x28, 0xdd, 0x68, 0x72, 0x3a, 0x16, 0x1e, 0x92, 0x32, 0x48, 0xaa, 0x26, 0x82, 0x2e, 0xe2,
+	0x86, 0x2b, 0x39, 0x1b, 0xf0, 0x25, 0x6e, 0x36, 0xe1, 0x26, 0x82, 0x05, 0xf7, 0x13, 0xa8, 0x70,
+	0x7e, 0xe6, 0x7a, 0x22, 0x1a, 0x71, 0xa9, 0xf8, 0xb9, 0xc2, 0x2c, 0xad, 0xd3, 0x32, 0xe7, 0x67,
+	0x87, 0x73, 0x90, 0x7c, 0x06, 0x64, 0x99, 0xe6, 0xfa, 0xcc, 0xc3, 0x14, 0xad, 0xd3, 0xda, 0x12,
+	0xf5, 0x84, 0x79, 0xcd, 0x7f, 0x2d, 0xc8, 0x63, 0x99, 0x2e, 0x77, 0xdb, 0xba, 0xdc, 0xed, 0x6d,
+	0x53, 0xc9, 0xcc, 0x4a, 0x25, 0x4d, 0x1d, 0x6f, 0x81, 0xad, 0xbf, 0x5a, 0x43, 0xdc, 0xc8, 0xee,
+	0x64, 0x75, 0x7a, 0x34, 0xf0, 0x94, 0x4f, 0x63, 0xf2, 0x11, 0xac, 0x7b, 0x92, 0x33, 0xc5, 0x07,
+	0xae, 0x12, 0x3e, 0xc7, 0x20, 0xca, 0xb4, 0x64, 0xb0, 0xbe, 0xf0, 0x39, 0x79, 0x08, 0xd7, 0x7d,
+	0xae, 0xd8, 0x80, 0x29, 0x96, 0x0e, 0x37, 0x89, 0x81, 0xcc, 0x44, 0xa9, 0x98, 0xbf, 0x80, 0xcd,
+	0x4b, 0x0e, 0x60, 0xe0, 0x05, 0x3c, 0x74, 0xe3, 0xe2, 0x21, 0x1d, 0xfd, 0x1f, 0x39, 0x28, 0x63,
+	0xf4, 0x27, 0x46, 0x4c, 0xb6, 0x01, 0x7c, 0x3e, 0x10, 0xcc, 0x55, 0x53, 0x73, 0xed, 0x6d, 0x6a,
+	0x23, 0xd2, 0x9f, 0x46, 0x9c, 0x1c, 0x40, 0xdd, 0x0b, 0xfd, 0x48, 0xf2, 0x38, 0x16, 0x61, 0xe0,
+	0x7a, 0xe1, 0x80, 0x7b, 0x98, 0x85, 0xca, 0xfe, 0x0d, 0xcc, 0xc2, 0xe1, 0x42, 0x7a, 0xa8, 0x85,
+	0xb4, 0xe6, 0xad, 0x20, 0xe4, 0x53, 0xa8, 0xa6, 0x7c, 0x8c, 0xc5, 0x9b, 0xa4, 0x23, 0x72, 0xb4,
+	0xb2, 0x80, 0x7b, 0xe2, 0x0d, 0xd7, 0x05, 0x5f, 0x09, 0xc6, 0x14, 0xdc, 0x4b, 0x07, 0x41, 0x76,
+	0xa1, 0x3e, 0x09, 0x66, 0x56, 0xf8, 0x20, 0xd1, 0x98, 0x47, 0x8d, 0xb5, 0xb4, 0x00, 0x75, 0xde,
+	0x83, 0x25, 0x2c, 0x95, 0xa2, 0x6a, 0x1a, 0xd7, 0x7a, 0x0f, 0x00, 0x22, 0x19, 0x46, 0x5c, 0x2a,
+	0xc1, 0xe3, 0xc6, 0xb5, 0x9d, 0xec, 0xdd, 0xd2, 0x7e, 0x73, 0xd1, 0xd7, 0xb3, 0x94, 0xed, 0x75,
+	0xe7, 0x24, 0xc4, 0x69, 0xea, 0x14, 0xd9, 0x82, 0xe2, 0x4b, 0x31, 0xe6, 0x11, 0x53, 0xa3, 0x46,
+	0x11, 0x93, 0x39, 0xdf, 0x93, 0x5d, 0x28, 0xc4, 0xde, 0x88, 0xfb, 0xac, 0x61, 0xa7, 0xbb, 0x1d,
+	0xa1, 0x47, 0x52, 0x89, 0x97, 0xcc, 0x53, 0xd4, 0x50, 0x74, 0x1f, 0x6b, 0x63, 0x2d, 0xe1, 0x29,
+	0x11, 0x06, 0x4c, 0x4e, 0x1b, 0x70, 0xf5, 0xa1, 0x15, 0xea, 0xd6, 0x37, 0x50, 0x5d, 0x71, 0x92,
+	0xd4, 0x20, 0x3b, 0xbb, 0xdf, 0x36, 0xd5, 0x4b, 0xb2, 0x01, 0xf9, 0x57, 0x6c, 0x3c, 0xe1, 0xa6,
+	0x55, 0x93, 0xcd, 0x57, 0x99, 0x2f, 0xad, 0xe6, 0xcf, 0x16, 0x54, 0x96, 0x2d, 0x68, 0xf2, 0x50,
+	0x86, 0x93, 0xc8, 0x28, 0x48, 0x36, 0xa4, 0x01, 0xd7, 0x22, 0x19, 0xfe, 0xc8, 0x3d, 0x85, 0x4a,
+	0x6c, 0x3a, 0xdb, 0x12, 0xa2, 0x1b, 0x46, 0x8d, 0xb0, 0xd0, 0x36, 0xc5, 0xb5, 0xc6, 0x02, 0x66,
+	0x1a, 0xc0, 0xa6, 0xb8, 0xd6, 0x1a, 0x5e, 0x71, 0xa9, 0xef, 0x0a, 0x56, 0xd0, 0xa6, 0xb3, 0x6d,
+	0xf3, 0x37, 0x0b, 0x72, 0xba, 0xc5, 0xde, 0xab, 0x4f, 0x37, 0x20, 0x2f, 0x82, 0x01, 0x3f, 0x47,
+	0x77, 0xca, 0x34, 0xd9, 0x90, 0x0f, 0x01, 0x52, 0x5d, 0x95, 0x4c, 0x9b, 0x14, 0xf2, 0x8e, 0xf7,
+	0xae, 0xf9, 0xab, 0x4e, 0xcb, 0xd2, 0x6c, 0x26, 0xf7, 0xa0, 0x20, 0xb9, 0x17, 0xca, 0x81, 0x79,
+	0x30, 0xea, 0xa9, 0x01, 0x4e, 0x51, 0x40, 0x0d, 0x81, 0x6c, 0xc2, 0xb5, 0x68, 0x72, 0x9a, 0x9a,
+	0x8d, 0x85, 0x68, 0x72, 0xaa, 0x7d, 0xfe, 0x00, 0xec, 0x58, 0x0c, 0x03, 0xa6, 0x26, 0x92, 0x9b,
+	0x7c, 0x2d, 0x00, 0x7d, 0xa1, 0xbc, 0x11, 0xf7, 0xce, 0xe2, 0x89, 0x6f, 0xbc, 0x9a, 0xef, 0x9b,
+	0xbf, 0x58, 0xb0, 0x9e, 0xb6, 0x45, 0x76, 0x21, 0x37, 0x6f, 0xe3, 0xca, 0xfe, 0xe6, 0x25, 0xaf,
+	0x89, 0x6e, 0x6a, 0x8a, 0xa4, 0x79, 0x39, 0x12, 0x6f, 0x70, 0x8d, 0xd6, 0xcc, 0x03, 0x60, 0xf2,
+	0x34, 0xdf, 0x6b, 0x59, 0xcc, 0x7f, 0x9a, 0xf0, 0xc0, 0x4b, 0x4a, 0x98, 0xa3, 0xf3, 0x7d, 0xf3,
+	0x2f, 0x0b, 0xf2, 0xf8, 0xb6, 0xe8, 0x69, 0x17, 0x4a, 0x31, 0x14, 0x01, 0x1b, 0xa7, 0x0a, 0x55,
+	0x9a, 0x61, 0x57, 0x17, 0xa9, 0x09, 0xe5, 0xc0, 0xc5, 0x81, 0x86, 0x6f, 0x52, 0x62, 0xbf, 0x4c,
+	0x4b, 0x41, 0x8b, 0x29, 0x86, 0xba, 0x63, 0x72, 0x07, 0xaa, 0x81, 0x1b, 0x31, 0x29, 0xd4, 0x74,
+	0xc6, 0x4a, 0xa6, 0x69, 0x39, 0xe8, 0x22, 0x6a, 0x78, 0x04, 0x72, 0xa9, 0xa1, 0x80, 0x6b, 0x8d,
+	0x69, 0xed, 0xa6, 0xf9, 0x71, 0xdd, 0xfc, 0x27, 0x07, 0xf5, 0x36, 0x57, 0xaf, 0x43, 0x79, 0xd6,
+	0x65, 0x92, 0xf9, 0x5c, 0x71, 0x19, 0xeb, 0x91, 0x18, 0xb8, 0x92, 0x47, 0x63, 0xe1, 0xb1, 0x18,
+	0x03, 0x28, 0x53, 0x3b, 0xa0, 0x06, 0xd0, 0x8e, 0xfa, 0xec, 0xdc, 0xc5, 0x81, 0x8f, 0x56, 0x92,
+	0x30, 0x4a, 0x3e, 0x3b, 0xd7, 0xf7, 0x15, 0xa7, 0xce, 0x73, 0xa8, 0x6b, 0xa5, 0xfa, 0x99, 0x0b,
+	0xc3, 0x31, 0xd2, 0x92, 0x87, 0xa1, 0xb4, 0xbf, 0x8b, 0x55, 0xb9, 0x60, 0x75, 0xef, 0x39, 0xf2,
+	0xbb, 0x61, 0x38, 0xd6, 0x1a, 0xcc, 0x68, 0xa9, 0xbe, 0x5e, 0x46, 0xc9, 0x6d, 0x28, 0x89, 0x38,
+	0x9e, 0xa4, 0xdf, 0x92, 0x2c, 0x85, 0x04, 0xc2, 0xa7, 0xe4, 0x07, 0xd8, 0x90, 0xba, 0x2a, 0xb1,
+	0xd2, 0xc6, 0x39, 0x97, 0xae, 0x64, 0x8a, 0xc7, 0x8d, 0x3c, 0x1a, 0xdf, 0xbb, 0xc2, 0x38, 0x9d,
+	0x1d, 0xe9, 0x72, 0x2e, 0x29, 0x53, 0x33, 0xfb, 0x44, 0x5e, 0x10, 0x90, 0xef, 0xe1, 0xfa, 0xc2,
+	0x42, 0x28, 0x87, 0xc6, 0x40, 0x01, 0x0d, 0x3c, 0x78, 0x9b, 0x81, 0x8e, 0x1c, 0xa6, 0xf4, 0xd7,
+	0xe5, 0x2a, 0xbe, 0x75, 0x00, 0x1b, 0x97, 0xa5, 0xe2, 0x6d, 0x03, 0xac, 0x9c, 0x1a, 0x60, 0x5b,
+	0x0e, 0x6c, 0x5e, 0x11, 0xd1, 0xdb, 0xd4, 0x58, 0x69, 0x35, 0x2d, 0xb8, 0x79, 0xb9, 0xdf, 0xef,
+	0xa3, 0xa5, 0xf9, 0xa7, 0x05, 0xe5, 0xa5, 0xff, 0xae, 0x77, 0xe9, 0x91, 0x0b, 0xdd, 0x90, 0x79,
+	0xa7, 0x6e, 0xc8, 0xfe, 0x5f, 0x37, 0xe4, 0x52, 0xdd, 0xb0, 0x0d, 0x80, 0x47, 0x92, 0x5f, 0x96,
+	0x3c, 0xfe, 0xb2, 0xd8, 0x88, 0xe8, 0x7f, 0x96, 0xfb, 0x77, 0xa0, 0xb6, 0xfa, 0xb0, 0x93, 0x22,
+	0xe4, 0xda, 0x9d, 0xb6, 0x53, 0x5b, 0xd3, 0xab, 0x27, 0xdf, 0x1d, 0x77, 0x6b, 0xd6, 0xfd, 0xd7,
+	0x40, 0x2e, 0xce, 0x17, 0xf2, 0x31, 0xec, 0xf4, 0x5e, 0xf4, 0xfa, 0xce, 0x89, 0xdb, 0xea, 0x1c,
+	0x3e, 0x3b, 0x71, 0xda, 0x7d, 0xb7, 0xff, 0xa2, 0xeb, 0xb8, 0xcf, 0xda, 0xbd, 0xae, 0x73, 0x78,
+	0xfc, 0xf8, 0xd8, 0x69, 0xd5, 0xd6, 0x48, 0x15, 0x4a, 0x1d, 0xfa, 0xc4, 0xed, 0xd2, 0xce, 0xe3,
+	0xe3, 0x6f, 0x9d, 0x9a, 0x45, 0x08, 0x54, 0xba, 0x9d, 0xe3, 0x76, 0xdf, 0xa1, 0x2e, 0x75, 0x0e,
+	0x3b, 0xb4, 0x55, 0xcb, 0x90, 0x9b, 0x40, 0xda, 0x4e, 0xff, 0x79, 0x87, 0x3e, 0x75, 0xbb, 0x8f,
+	0xe8, 0xa3, 0x13, 0xa7, 0xef, 0xd0, 0x5e, 0x2d, 0x7b, 0x5a, 0xc0, 0x9f, 0xf8, 0xcf, 0xff, 0x1b,
+	0x00, 0x05, 0xce, 0x6d, 0xc6, 0xe5, 0x0b, 0x00, 0x00,
 }
@@ -17,6 +17,31 @@ func TestGetKey(t *testing.T) {
 	assert.Nil(t, ValidateBytes(key.Bytes(), DocumentKeyLength, "key"))
 }
 
+func TestSliceHMAC256(t *testing.T) {
+	value, macKey := []byte("some stored value"), []byte("some MAC key")
+	mac1, err := SliceHMAC256(value, macKey, 5, 6)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateHMAC256(mac1))
+
+	// check MAC only depends on the slice
+	mac2, err := SliceHMAC256([]byte("XXXXXstoredXXXXXX"), macKey, 5, 6)
+	assert.Nil(t, err)
+	assert.Equal(t, mac1, mac2)
+
+	// check different slice gives different MAC
+	mac3, err := SliceHMAC256(value, macKey, 0, 6)
+	assert.Nil(t, err)
+	assert.NotEqual(t, mac1, mac3)
+
+	// check whole value is in range
+	_, err = SliceHMAC256(value, macKey, 0, uint32(len(value)))
+	assert.Nil(t, err)
+
+	mac4, err := SliceHMAC256(value, macKey, 12, 6)
+	assert.Equal(t, ErrSliceOutOfRange, err)
+	assert.Nil(t, mac4)
+}
+
 func TestGetAuthorPub(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	expected := ecid.NewPseudoRandom(rng).PublicKeyBytes()
@@ -36,6 +61,49 @@ func TestGetAuthorPub(t *testing.T) {
 	envelope := NewTestEnvelope(rng)
 	envelope.AuthorPublicKey = expected
 	assert.Equal(t, expected, GetAuthorPub(&Document{&Document_Envelope{Envelope: envelope}}))
+
+	sys, _ := NewTestSystemDocument(rng)
+	sys.GetSystem().PubKey = expected
+	assert.Equal(t, expected, GetAuthorPub(sys))
+
+	shard, _ := NewTestShardDocument(rng)
+	assert.Nil(t, GetAuthorPub(shard))
+
+	manifest, _ := NewTestShardManifestDocument(rng)
+	assert.Nil(t, GetAuthorPub(manifest))
+}
+
+func TestGetDocumentType(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sys, _ := NewTestSystemDocument(rng)
+	shard, _ := NewTestShardDocument(rng)
+	manifest, _ := NewTestShardManifestDocument(rng)
+	cases := map[DocumentType]*Document{
+		EnvelopeType:      {Contents: &Document_Envelope{Envelope: NewTestEnvelope(rng)}},
+		EntryType:         {Contents: &Document_Entry{Entry: NewTestSinglePageEntry(rng)}},
+		PageType:          {Contents: &Document_Page{Page: NewTestPage(rng)}},
+		SystemType:        sys,
+		ShardType:         shard,
+		ShardManifestType: manifest,
+	}
+	for expected, doc := range cases {
+		assert.Equal(t, expected, GetDocumentType(doc))
+		assert.NotEmpty(t, expected.String())
+	}
+	assert.Panics(t, func() { GetDocumentType(&Document{}) })
+	assert.Panics(t, func() { _ = DocumentType(-1).String() })
+}
+
+func TestTypeReplicas_NReplicas(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := TypeReplicas{EnvelopeType: 5, PageType: 3}
+	envelope := &Document{Contents: &Document_Envelope{Envelope: NewTestEnvelope(rng)}}
+	entry := &Document{Contents: &Document_Entry{Entry: NewTestSinglePageEntry(rng)}}
+	page := &Document{Contents: &Document_Page{Page: NewTestPage(rng)}}
+
+	assert.Equal(t, uint(5), r.NReplicas(envelope))
+	assert.Zero(t, r.NReplicas(entry))
+	assert.Equal(t, uint(3), r.NReplicas(page))
 }
 
 func TestGetEntryPageKeys_ok(t *testing.T) {
@@ -95,6 +163,9 @@ func TestValidateDocument_ok(t *testing.T) {
 
 	d3 := &Document{&Document_Page{NewTestPage(rng)}}
 	assert.Nil(t, ValidateDocument(d3))
+
+	d4, _ := NewTestSystemDocument(rng)
+	assert.Nil(t, ValidateDocument(d4))
 }
 
 func TestValidateEnvelope_ok(t *testing.T) {
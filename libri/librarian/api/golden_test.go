@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden files in testdata were serialized by earlier versions of the code and stand in for
+// documents already stored by peers. They must never be regenerated: if these tests fail, the
+// change to the document format or keys is breaking and strands stored documents.
+
+func TestGolden_Documents(t *testing.T) {
+	authorPub := bytes.Repeat([]byte{2}, ECPubKeyLength)
+	cases := []struct {
+		filename string
+		expected *Document
+		key      string
+	}{
+		{
+			filename: "envelope.golden",
+			expected: &Document{Contents: &Document_Envelope{Envelope: &Envelope{
+				EntryKey:         bytes.Repeat([]byte{1}, DocumentKeyLength),
+				AuthorPublicKey:  authorPub,
+				ReaderPublicKey:  bytes.Repeat([]byte{3}, ECPubKeyLength),
+				EekCiphertext:    bytes.Repeat([]byte{4}, EEKCiphertextLength),
+				EekCiphertextMac: bytes.Repeat([]byte{5}, HMAC256Length),
+			}}},
+			key: "c0ef63203e3fc227acf63b15e0625afc672f6f333693a353af1f81031e6a7628",
+		},
+		{
+			filename: "entry.golden",
+			expected: &Document{Contents: &Document_Entry{Entry: &Entry{
+				AuthorPublicKey: authorPub,
+				Page: &Page{
+					AuthorPublicKey: authorPub,
+					Ciphertext:      bytes.Repeat([]byte{6}, 64),
+					CiphertextMac:   bytes.Repeat([]byte{7}, HMAC256Length),
+				},
+				CreatedTime:           1514764800,
+				MetadataCiphertext:    bytes.Repeat([]byte{8}, 64),
+				MetadataCiphertextMac: bytes.Repeat([]byte{9}, HMAC256Length),
+			}}},
+			key: "15f9486e672dcc3af51a829b79471ccb37c83e9a15818aed5314d11e7a299d1d",
+		},
+		{
+			filename: "page.golden",
+			expected: &Document{Contents: &Document_Page{Page: &Page{
+				AuthorPublicKey: authorPub,
+				Index:           1,
+				Ciphertext:      bytes.Repeat([]byte{10}, 64),
+				CiphertextMac:   bytes.Repeat([]byte{11}, HMAC256Length),
+			}}},
+			key: "8033cebdbb371eaa2692d4b68ad9041581abc2a4266f3c744bc19641e3b3c7ec",
+		},
+	}
+	for _, c := range cases {
+		buf, err := ioutil.ReadFile(filepath.Join("testdata", c.filename))
+		assert.Nil(t, err, c.filename)
+		doc := &Document{}
+		assert.Nil(t, proto.Unmarshal(buf, doc), c.filename)
+		assert.Equal(t, c.expected, doc, c.filename)
+		assert.Nil(t, ValidateDocument(doc), c.filename)
+
+		// re-serializing must give the same bytes, or the document's key would change
+		key, err := GetKey(doc)
+		assert.Nil(t, err, c.filename)
+		assert.Equal(t, c.key, hex.EncodeToString(key.Bytes()), c.filename)
+	}
+}
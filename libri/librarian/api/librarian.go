@@ -32,11 +32,14 @@ const (
 
 	// Subscribe represents the Introduce endpoint.
 	Subscribe
+
+	// Sync represents the Sync endpoint.
+	Sync
 )
 
 var (
 	// Endpoints is a list of all the librarian endpoints (not including All).
-	Endpoints = []Endpoint{Introduce, Find, Store, Verify, Get, Put, Subscribe}
+	Endpoints = []Endpoint{Introduce, Find, Store, Verify, Get, Put, Subscribe, Sync}
 )
 
 func (e Endpoint) String() string {
@@ -57,6 +60,8 @@ func (e Endpoint) String() string {
 		return "Put"
 	case Subscribe:
 		return "Subscribe"
+	case Sync:
+		return "Sync"
 	default:
 		panic("unknown endpoint")
 	}
@@ -109,6 +114,12 @@ type PutterGetter interface {
 	Putter
 }
 
+// Syncer issues Sync queries.
+type Syncer interface {
+	// Sync returns a digest of the keys a peer stores within a key range.
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+}
+
 // Subscriber issues Subscribe queries.
 type Subscriber interface {
 	// Subscribe subscribes to a defined publication stream.
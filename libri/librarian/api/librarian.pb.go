@@ -24,15 +24,20 @@ const (
 	PutOperation_STORED PutOperation = 0
 	// value already existed
 	PutOperation_LEFT_EXISTING PutOperation = 1
+	// value matches one the librarian recently put (same key and MAC), e.g., when the Put is a
+	// retry after a slow response, so it wasn't stored again
+	PutOperation_ALREADY_STORED PutOperation = 2
 )
 
 var PutOperation_name = map[int32]string{
 	0: "STORED",
 	1: "LEFT_EXISTING",
+	2: "ALREADY_STORED",
 }
 var PutOperation_value = map[string]int32{
-	"STORED":        0,
-	"LEFT_EXISTING": 1,
+	"STORED":         0,
+	"LEFT_EXISTING":  1,
+	"ALREADY_STORED": 2,
 }
 
 func (x PutOperation) String() string {
@@ -151,6 +156,10 @@ type IntroduceResponse struct {
 	Self *PeerAddress `protobuf:"bytes,2,opt,name=self" json:"self,omitempty"`
 	// info about other peers
 	Peers []*PeerAddress `protobuf:"bytes,3,rep,name=peers" json:"peers,omitempty"`
+	// responder's clock time when creating the response, in nanoseconds since the Unix epoch
+	TimeNanos int64 `protobuf:"varint,4,opt,name=time_nanos,json=timeNanos" json:"time_nanos,omitempty"`
+	// responder's build info, signed with its peer key
+	BuildInfo *SignedBuildInfo `protobuf:"bytes,5,opt,name=build_info,json=buildInfo" json:"build_info,omitempty"`
 }
 
 func (m *IntroduceResponse) Reset()                    { *m = IntroduceResponse{} }
@@ -179,6 +188,20 @@ func (m *IntroduceResponse) GetPeers() []*PeerAddress {
 	return nil
 }
 
+func (m *IntroduceResponse) GetTimeNanos() int64 {
+	if m != nil {
+		return m.TimeNanos
+	}
+	return 0
+}
+
+func (m *IntroduceResponse) GetBuildInfo() *SignedBuildInfo {
+	if m != nil {
+		return m.BuildInfo
+	}
+	return nil
+}
+
 type FindRequest struct {
 	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	// 32-byte target to find peers around
@@ -219,6 +242,8 @@ type FindResponse struct {
 	Peers []*PeerAddress `protobuf:"bytes,2,rep,name=peers" json:"peers,omitempty"`
 	// value, if found
 	Value *Document `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	// Unix time (seconds) the value expires, or zero if it never does
+	ExpireTime int64 `protobuf:"varint,4,opt,name=expire_time,json=expireTime" json:"expire_time,omitempty"`
 }
 
 func (m *FindResponse) Reset()                    { *m = FindResponse{} }
@@ -247,6 +272,13 @@ func (m *FindResponse) GetValue() *Document {
 	return nil
 }
 
+func (m *FindResponse) GetExpireTime() int64 {
+	if m != nil {
+		return m.ExpireTime
+	}
+	return 0
+}
+
 type VerifyRequest struct {
 	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	// 32-byte key of document to verify
@@ -255,6 +287,10 @@ type VerifyRequest struct {
 	MacKey []byte `protobuf:"bytes,3,opt,name=mac_key,json=macKey,proto3" json:"mac_key,omitempty"`
 	// the number of closests peers to return
 	NumPeers uint32 `protobuf:"varint,4,opt,name=num_peers,json=numPeers" json:"num_peers,omitempty"`
+	// when positive, only the slice_length bytes of the document's serialized bytes starting at
+	// slice_offset are MACed, e.g., for proof of possession challenges
+	SliceOffset uint32 `protobuf:"varint,5,opt,name=slice_offset,json=sliceOffset" json:"slice_offset,omitempty"`
+	SliceLength uint32 `protobuf:"varint,6,opt,name=slice_length,json=sliceLength" json:"slice_length,omitempty"`
 }
 
 func (m *VerifyRequest) Reset()                    { *m = VerifyRequest{} }
@@ -290,6 +326,20 @@ func (m *VerifyRequest) GetNumPeers() uint32 {
 	return 0
 }
 
+func (m *VerifyRequest) GetSliceOffset() uint32 {
+	if m != nil {
+		return m.SliceOffset
+	}
+	return 0
+}
+
+func (m *VerifyRequest) GetSliceLength() uint32 {
+	if m != nil {
+		return m.SliceLength
+	}
+	return 0
+}
+
 type VerifyResponse struct {
 	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	// nil if the peer does not have the document, otherwise the HMAC-256 of the document's
@@ -334,6 +384,14 @@ type PeerAddress struct {
 	Ip string `protobuf:"bytes,3,opt,name=ip" json:"ip,omitempty"`
 	// public address TCP port
 	Port uint32 `protobuf:"varint,4,opt,name=port" json:"port,omitempty"`
+	// operator-assigned failure domain (e.g., zone or region) of the peer, if any
+	Zone string `protobuf:"bytes,5,opt,name=zone" json:"zone,omitempty"`
+	// additional public addresses ("ip:port") in preference order after ip and port, e.g., for
+	// dual-stack peers
+	AltAddresses []string `protobuf:"bytes,6,rep,name=alt_addresses,json=altAddresses" json:"alt_addresses,omitempty"`
+	// whether the peer is a read-only mirror, which doesn't store documents for other peers and so
+	// shouldn't be added to routing tables
+	Mirror bool `protobuf:"varint,7,opt,name=mirror" json:"mirror,omitempty"`
 }
 
 func (m *PeerAddress) Reset()                    { *m = PeerAddress{} }
@@ -369,12 +427,43 @@ func (m *PeerAddress) GetPort() uint32 {
 	return 0
 }
 
+func (m *PeerAddress) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+func (m *PeerAddress) GetAltAddresses() []string {
+	if m != nil {
+		return m.AltAddresses
+	}
+	return nil
+}
+
+func (m *PeerAddress) GetMirror() bool {
+	if m != nil {
+		return m.Mirror
+	}
+	return false
+}
+
 type StoreRequest struct {
 	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	// key to store value under
 	Key []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
 	// value to store for key
 	Value *Document `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	// Unix time (seconds) after which the value expires and is deleted, or zero for never
+	ExpireTime int64 `protobuf:"varint,4,opt,name=expire_time,json=expireTime" json:"expire_time,omitempty"`
+	// intended holder the value is stored for while it is unreachable, so the receiving peer
+	// hands the value off to it when it returns, or empty if the receiving peer is the intended
+	// holder
+	Hint *PeerAddress `protobuf:"bytes,5,opt,name=hint" json:"hint,omitempty"`
+	// 32-byte ID of the organization whose quota the value is charged to when the request is
+	// forwarded (e.g., by a Put or replication) rather than signed by that organization, or empty
+	// if none
+	OriginOrgId []byte `protobuf:"bytes,6,opt,name=origin_org_id,json=originOrgId,proto3" json:"origin_org_id,omitempty"`
 }
 
 func (m *StoreRequest) Reset()                    { *m = StoreRequest{} }
@@ -403,8 +492,33 @@ func (m *StoreRequest) GetValue() *Document {
 	return nil
 }
 
+func (m *StoreRequest) GetExpireTime() int64 {
+	if m != nil {
+		return m.ExpireTime
+	}
+	return 0
+}
+
+func (m *StoreRequest) GetHint() *PeerAddress {
+	if m != nil {
+		return m.Hint
+	}
+	return nil
+}
+
+func (m *StoreRequest) GetOriginOrgId() []byte {
+	if m != nil {
+		return m.OriginOrgId
+	}
+	return nil
+}
+
 type StoreResponse struct {
 	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// whether the peer already stored the value (same key and MAC), so didn't store it again
+	AlreadyStored bool `protobuf:"varint,2,opt,name=already_stored,json=alreadyStored" json:"already_stored,omitempty"`
+	// receipt for the stored value, signed with the peer key
+	Receipt *SignedStoreReceipt `protobuf:"bytes,3,opt,name=receipt" json:"receipt,omitempty"`
 }
 
 func (m *StoreResponse) Reset()                    { *m = StoreResponse{} }
@@ -419,10 +533,26 @@ func (m *StoreResponse) GetMetadata() *ResponseMetadata {
 	return nil
 }
 
+func (m *StoreResponse) GetAlreadyStored() bool {
+	if m != nil {
+		return m.AlreadyStored
+	}
+	return false
+}
+
+func (m *StoreResponse) GetReceipt() *SignedStoreReceipt {
+	if m != nil {
+		return m.Receipt
+	}
+	return nil
+}
+
 type GetRequest struct {
 	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	// 32-byte key of document to get
 	Key []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// whether to re-store the document with the closest peers found missing it
+	ReadRepair bool `protobuf:"varint,3,opt,name=read_repair,json=readRepair" json:"read_repair,omitempty"`
 }
 
 func (m *GetRequest) Reset()                    { *m = GetRequest{} }
@@ -444,6 +574,13 @@ func (m *GetRequest) GetKey() []byte {
 	return nil
 }
 
+func (m *GetRequest) GetReadRepair() bool {
+	if m != nil {
+		return m.ReadRepair
+	}
+	return false
+}
+
 type GetResponse struct {
 	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	// value to store for key
@@ -475,6 +612,10 @@ type PutRequest struct {
 	Key []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
 	// value to store for key
 	Value *Document `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	// seconds after which the value expires and is deleted, or zero for never
+	TtlSeconds uint64 `protobuf:"varint,4,opt,name=ttl_seconds,json=ttlSeconds" json:"ttl_seconds,omitempty"`
+	// number of replicas to store the value with, or zero for the librarian's default
+	NReplicas uint32 `protobuf:"varint,5,opt,name=n_replicas,json=nReplicas" json:"n_replicas,omitempty"`
 }
 
 func (m *PutRequest) Reset()                    { *m = PutRequest{} }
@@ -503,12 +644,29 @@ func (m *PutRequest) GetValue() *Document {
 	return nil
 }
 
+func (m *PutRequest) GetTtlSeconds() uint64 {
+	if m != nil {
+		return m.TtlSeconds
+	}
+	return 0
+}
+
+func (m *PutRequest) GetNReplicas() uint32 {
+	if m != nil {
+		return m.NReplicas
+	}
+	return 0
+}
+
 type PutResponse struct {
 	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	// result of the put operation
 	Operation PutOperation `protobuf:"varint,2,opt,name=operation,enum=api.PutOperation" json:"operation,omitempty"`
 	// number of replicas of the stored value; only populated for operation = STORED
 	NReplicas uint32 `protobuf:"varint,3,opt,name=n_replicas,json=nReplicas" json:"n_replicas,omitempty"`
+	// signed receipts from the peers storing replicas of the value; only populated for
+	// operation = STORED
+	Receipts []*SignedStoreReceipt `protobuf:"bytes,4,rep,name=receipts" json:"receipts,omitempty"`
 }
 
 func (m *PutResponse) Reset()                    { *m = PutResponse{} }
@@ -537,6 +695,13 @@ func (m *PutResponse) GetNReplicas() uint32 {
 	return 0
 }
 
+func (m *PutResponse) GetReceipts() []*SignedStoreReceipt {
+	if m != nil {
+		return m.Receipts
+	}
+	return nil
+}
+
 type SubscribeRequest struct {
 	Metadata     *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
 	Subscription *Subscription    `protobuf:"bytes,2,opt,name=subscription" json:"subscription,omitempty"`
@@ -674,158 +839,1297 @@ func (m *BloomFilter) GetEncoded() []byte {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*RequestMetadata)(nil), "api.RequestMetadata")
-	proto.RegisterType((*ResponseMetadata)(nil), "api.ResponseMetadata")
-	proto.RegisterType((*IntroduceRequest)(nil), "api.IntroduceRequest")
-	proto.RegisterType((*IntroduceResponse)(nil), "api.IntroduceResponse")
-	proto.RegisterType((*FindRequest)(nil), "api.FindRequest")
-	proto.RegisterType((*FindResponse)(nil), "api.FindResponse")
-	proto.RegisterType((*VerifyRequest)(nil), "api.VerifyRequest")
-	proto.RegisterType((*VerifyResponse)(nil), "api.VerifyResponse")
-	proto.RegisterType((*PeerAddress)(nil), "api.PeerAddress")
-	proto.RegisterType((*StoreRequest)(nil), "api.StoreRequest")
-	proto.RegisterType((*StoreResponse)(nil), "api.StoreResponse")
-	proto.RegisterType((*GetRequest)(nil), "api.GetRequest")
-	proto.RegisterType((*GetResponse)(nil), "api.GetResponse")
-	proto.RegisterType((*PutRequest)(nil), "api.PutRequest")
-	proto.RegisterType((*PutResponse)(nil), "api.PutResponse")
-	proto.RegisterType((*SubscribeRequest)(nil), "api.SubscribeRequest")
-	proto.RegisterType((*SubscribeResponse)(nil), "api.SubscribeResponse")
-	proto.RegisterType((*Publication)(nil), "api.Publication")
-	proto.RegisterType((*Subscription)(nil), "api.Subscription")
-	proto.RegisterType((*BloomFilter)(nil), "api.BloomFilter")
-	proto.RegisterEnum("api.PutOperation", PutOperation_name, PutOperation_value)
+type BuildInfo struct {
+	// semantic version of the build
+	Version string `protobuf:"bytes,1,opt,name=version" json:"version,omitempty"`
+	// git branch of the build
+	GitBranch string `protobuf:"bytes,2,opt,name=git_branch,json=gitBranch" json:"git_branch,omitempty"`
+	// git commit hash of the build
+	GitRevision string `protobuf:"bytes,3,opt,name=git_revision,json=gitRevision" json:"git_revision,omitempty"`
+	// ISO 8601 date of the build
+	BuildDate string `protobuf:"bytes,4,opt,name=build_date,json=buildDate" json:"build_date,omitempty"`
+	// version of the librarian wire protocol
+	ProtocolVersion uint32 `protobuf:"varint,5,opt,name=protocol_version,json=protocolVersion" json:"protocol_version,omitempty"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *BuildInfo) Reset()                    { *m = BuildInfo{} }
+func (m *BuildInfo) String() string            { return proto.CompactTextString(m) }
+func (*BuildInfo) ProtoMessage()               {}
+func (*BuildInfo) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{20} }
 
-// Client API for Librarian service
+func (m *BuildInfo) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
 
-type LibrarianClient interface {
-	// Introduce identifies the node by name and ID.
-	Introduce(ctx context.Context, in *IntroduceRequest, opts ...grpc.CallOption) (*IntroduceResponse, error)
-	// Find returns the value for a key or the closest peers to it.
-	Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error)
-	// Verify checks that a peer has the value for a given key or returns the closest peers to
-	// that value.
-	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
-	// Store stores a value in a given key.
-	Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreResponse, error)
-	// Get retrieves a value, if it exists.
-	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
-	// Put stores a value.
-	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
-	// Subscribe streams Publications to the client per a subscription filter.
-	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Librarian_SubscribeClient, error)
+func (m *BuildInfo) GetGitBranch() string {
+	if m != nil {
+		return m.GitBranch
+	}
+	return ""
 }
 
-type librarianClient struct {
-	cc *grpc.ClientConn
+func (m *BuildInfo) GetGitRevision() string {
+	if m != nil {
+		return m.GitRevision
+	}
+	return ""
 }
 
-func NewLibrarianClient(cc *grpc.ClientConn) LibrarianClient {
-	return &librarianClient{cc}
+func (m *BuildInfo) GetBuildDate() string {
+	if m != nil {
+		return m.BuildDate
+	}
+	return ""
 }
 
-func (c *librarianClient) Introduce(ctx context.Context, in *IntroduceRequest, opts ...grpc.CallOption) (*IntroduceResponse, error) {
-	out := new(IntroduceResponse)
-	err := grpc.Invoke(ctx, "/api.Librarian/Introduce", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *BuildInfo) GetProtocolVersion() uint32 {
+	if m != nil {
+		return m.ProtocolVersion
 	}
-	return out, nil
+	return 0
 }
 
-func (c *librarianClient) Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error) {
-	out := new(FindResponse)
-	err := grpc.Invoke(ctx, "/api.Librarian/Find", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+type SignedBuildInfo struct {
+	BuildInfo *BuildInfo `protobuf:"bytes,1,opt,name=build_info,json=buildInfo" json:"build_info,omitempty"`
+	// ECDSA public key of the peer running the build
+	PubKey []byte `protobuf:"bytes,2,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	// signature (in the form of an encoded json web token) on the build info by the peer key
+	Signature string `protobuf:"bytes,3,opt,name=signature" json:"signature,omitempty"`
+}
+
+func (m *SignedBuildInfo) Reset()                    { *m = SignedBuildInfo{} }
+func (m *SignedBuildInfo) String() string            { return proto.CompactTextString(m) }
+func (*SignedBuildInfo) ProtoMessage()               {}
+func (*SignedBuildInfo) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{21} }
+
+func (m *SignedBuildInfo) GetBuildInfo() *BuildInfo {
+	if m != nil {
+		return m.BuildInfo
 	}
-	return out, nil
+	return nil
 }
 
-func (c *librarianClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
-	out := new(VerifyResponse)
-	err := grpc.Invoke(ctx, "/api.Librarian/Verify", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *SignedBuildInfo) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
 	}
-	return out, nil
+	return nil
 }
 
-func (c *librarianClient) Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreResponse, error) {
-	out := new(StoreResponse)
-	err := grpc.Invoke(ctx, "/api.Librarian/Store", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *SignedBuildInfo) GetSignature() string {
+	if m != nil {
+		return m.Signature
 	}
-	return out, nil
+	return ""
 }
 
-func (c *librarianClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
-	out := new(GetResponse)
-	err := grpc.Invoke(ctx, "/api.Librarian/Get", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+type RoutingTableRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *RoutingTableRequest) Reset()                    { *m = RoutingTableRequest{} }
+func (m *RoutingTableRequest) String() string            { return proto.CompactTextString(m) }
+func (*RoutingTableRequest) ProtoMessage()               {}
+func (*RoutingTableRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{22} }
+
+func (m *RoutingTableRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
 	}
-	return out, nil
+	return nil
 }
 
-func (c *librarianClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error) {
-	out := new(PutResponse)
-	err := grpc.Invoke(ctx, "/api.Librarian/Put", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+type RoutingTableResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// bucket of the routing table, sent in order of increasing ID range
+	Bucket *RoutingBucket `protobuf:"bytes,2,opt,name=bucket" json:"bucket,omitempty"`
+}
+
+func (m *RoutingTableResponse) Reset()                    { *m = RoutingTableResponse{} }
+func (m *RoutingTableResponse) String() string            { return proto.CompactTextString(m) }
+func (*RoutingTableResponse) ProtoMessage()               {}
+func (*RoutingTableResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{23} }
+
+func (m *RoutingTableResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
 	}
-	return out, nil
+	return nil
 }
 
-func (c *librarianClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Librarian_SubscribeClient, error) {
-	stream, err := grpc.NewClientStream(ctx, &_Librarian_serviceDesc.Streams[0], c.cc, "/api.Librarian/Subscribe", opts...)
-	if err != nil {
-		return nil, err
+func (m *RoutingTableResponse) GetBucket() *RoutingBucket {
+	if m != nil {
+		return m.Bucket
 	}
-	x := &librarianSubscribeClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+	return nil
+}
+
+type RoutingBucket struct {
+	// bit depth of the bucket in the routing tree
+	Depth uint32 `protobuf:"varint,1,opt,name=depth" json:"depth,omitempty"`
+	// (inclusive) 32-byte lower bound of IDs in the bucket
+	LowerBound []byte `protobuf:"bytes,2,opt,name=lower_bound,json=lowerBound,proto3" json:"lower_bound,omitempty"`
+	// (exclusive) 32-byte upper bound of IDs in the bucket
+	UpperBound []byte `protobuf:"bytes,3,opt,name=upper_bound,json=upperBound,proto3" json:"upper_bound,omitempty"`
+	// whether the bucket contains the librarian's own ID
+	ContainsSelf bool `protobuf:"varint,4,opt,name=contains_self,json=containsSelf" json:"contains_self,omitempty"`
+	// peers in the bucket
+	Peers []*RoutingPeer `protobuf:"bytes,5,rep,name=peers" json:"peers,omitempty"`
+}
+
+func (m *RoutingBucket) Reset()                    { *m = RoutingBucket{} }
+func (m *RoutingBucket) String() string            { return proto.CompactTextString(m) }
+func (*RoutingBucket) ProtoMessage()               {}
+func (*RoutingBucket) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{24} }
+
+func (m *RoutingBucket) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	return 0
+}
+
+func (m *RoutingBucket) GetLowerBound() []byte {
+	if m != nil {
+		return m.LowerBound
 	}
-	return x, nil
+	return nil
 }
 
-type Librarian_SubscribeClient interface {
-	Recv() (*SubscribeResponse, error)
-	grpc.ClientStream
+func (m *RoutingBucket) GetUpperBound() []byte {
+	if m != nil {
+		return m.UpperBound
+	}
+	return nil
 }
 
-type librarianSubscribeClient struct {
-	grpc.ClientStream
+func (m *RoutingBucket) GetContainsSelf() bool {
+	if m != nil {
+		return m.ContainsSelf
+	}
+	return false
 }
 
-func (x *librarianSubscribeClient) Recv() (*SubscribeResponse, error) {
-	m := new(SubscribeResponse)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *RoutingBucket) GetPeers() []*RoutingPeer {
+	if m != nil {
+		return m.Peers
 	}
-	return m, nil
+	return nil
 }
 
-// Server API for Librarian service
+type RoutingPeer struct {
+	// address of the peer
+	Address *PeerAddress `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	// number of successful responses from the peer
+	NResponseSuccesses uint64 `protobuf:"varint,2,opt,name=n_response_successes,json=nResponseSuccesses" json:"n_response_successes,omitempty"`
+	// number of errored responses from the peer
+	NResponseErrors uint64 `protobuf:"varint,3,opt,name=n_response_errors,json=nResponseErrors" json:"n_response_errors,omitempty"`
+	// time of the most recent successful response, in nanoseconds since the Unix epoch
+	LatestSuccessNanos int64 `protobuf:"varint,4,opt,name=latest_success_nanos,json=latestSuccessNanos" json:"latest_success_nanos,omitempty"`
+	// time of the most recent errored response, in nanoseconds since the Unix epoch
+	LatestErrorNanos int64 `protobuf:"varint,5,opt,name=latest_error_nanos,json=latestErrorNanos" json:"latest_error_nanos,omitempty"`
+}
 
-type LibrarianServer interface {
-	// Introduce identifies the node by name and ID.
-	Introduce(context.Context, *IntroduceRequest) (*IntroduceResponse, error)
-	// Find returns the value for a key or the closest peers to it.
+func (m *RoutingPeer) Reset()                    { *m = RoutingPeer{} }
+func (m *RoutingPeer) String() string            { return proto.CompactTextString(m) }
+func (*RoutingPeer) ProtoMessage()               {}
+func (*RoutingPeer) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{25} }
+
+func (m *RoutingPeer) GetAddress() *PeerAddress {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *RoutingPeer) GetNResponseSuccesses() uint64 {
+	if m != nil {
+		return m.NResponseSuccesses
+	}
+	return 0
+}
+
+func (m *RoutingPeer) GetNResponseErrors() uint64 {
+	if m != nil {
+		return m.NResponseErrors
+	}
+	return 0
+}
+
+func (m *RoutingPeer) GetLatestSuccessNanos() int64 {
+	if m != nil {
+		return m.LatestSuccessNanos
+	}
+	return 0
+}
+
+func (m *RoutingPeer) GetLatestErrorNanos() int64 {
+	if m != nil {
+		return m.LatestErrorNanos
+	}
+	return 0
+}
+
+type PeerBan struct {
+	// 32-byte ID of the banned peer
+	PeerId []byte `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	// reason given for the ban
+	Reason string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+	// expiry time of the ban, in nanoseconds since the Unix epoch, with zero meaning permanent
+	ExpiryNanos int64 `protobuf:"varint,3,opt,name=expiry_nanos,json=expiryNanos" json:"expiry_nanos,omitempty"`
+}
+
+func (m *PeerBan) Reset()                    { *m = PeerBan{} }
+func (m *PeerBan) String() string            { return proto.CompactTextString(m) }
+func (*PeerBan) ProtoMessage()               {}
+func (*PeerBan) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{26} }
+
+func (m *PeerBan) GetPeerId() []byte {
+	if m != nil {
+		return m.PeerId
+	}
+	return nil
+}
+
+func (m *PeerBan) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *PeerBan) GetExpiryNanos() int64 {
+	if m != nil {
+		return m.ExpiryNanos
+	}
+	return 0
+}
+
+type ListBansRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *ListBansRequest) Reset()                    { *m = ListBansRequest{} }
+func (m *ListBansRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListBansRequest) ProtoMessage()               {}
+func (*ListBansRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{27} }
+
+func (m *ListBansRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type ListBansResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// unexpired bans, ordered by peer ID
+	Bans []*PeerBan `protobuf:"bytes,2,rep,name=bans" json:"bans,omitempty"`
+}
+
+func (m *ListBansResponse) Reset()                    { *m = ListBansResponse{} }
+func (m *ListBansResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListBansResponse) ProtoMessage()               {}
+func (*ListBansResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{28} }
+
+func (m *ListBansResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *ListBansResponse) GetBans() []*PeerBan {
+	if m != nil {
+		return m.Bans
+	}
+	return nil
+}
+
+type BanRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// ban to add, replacing any existing ban on the peer
+	Ban *PeerBan `protobuf:"bytes,2,opt,name=ban" json:"ban,omitempty"`
+}
+
+func (m *BanRequest) Reset()                    { *m = BanRequest{} }
+func (m *BanRequest) String() string            { return proto.CompactTextString(m) }
+func (*BanRequest) ProtoMessage()               {}
+func (*BanRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{29} }
+
+func (m *BanRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *BanRequest) GetBan() *PeerBan {
+	if m != nil {
+		return m.Ban
+	}
+	return nil
+}
+
+type BanResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *BanResponse) Reset()                    { *m = BanResponse{} }
+func (m *BanResponse) String() string            { return proto.CompactTextString(m) }
+func (*BanResponse) ProtoMessage()               {}
+func (*BanResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{30} }
+
+func (m *BanResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type UnbanRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// 32-byte ID of the peer to unban
+	PeerId []byte `protobuf:"bytes,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+}
+
+func (m *UnbanRequest) Reset()                    { *m = UnbanRequest{} }
+func (m *UnbanRequest) String() string            { return proto.CompactTextString(m) }
+func (*UnbanRequest) ProtoMessage()               {}
+func (*UnbanRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{31} }
+
+func (m *UnbanRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *UnbanRequest) GetPeerId() []byte {
+	if m != nil {
+		return m.PeerId
+	}
+	return nil
+}
+
+type UnbanResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// whether the peer was banned
+	Removed bool `protobuf:"varint,2,opt,name=removed" json:"removed,omitempty"`
+}
+
+func (m *UnbanResponse) Reset()                    { *m = UnbanResponse{} }
+func (m *UnbanResponse) String() string            { return proto.CompactTextString(m) }
+func (*UnbanResponse) ProtoMessage()               {}
+func (*UnbanResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{32} }
+
+func (m *UnbanResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *UnbanResponse) GetRemoved() bool {
+	if m != nil {
+		return m.Removed
+	}
+	return false
+}
+
+type StoreReceipt struct {
+	// 32-byte key of the stored value
+	Key []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// ECDSA public key of the peer storing the value
+	PeerPubKey []byte `protobuf:"bytes,2,opt,name=peer_pub_key,json=peerPubKey,proto3" json:"peer_pub_key,omitempty"`
+	// epoch time (seconds) when the peer stored the value
+	Timestamp int64 `protobuf:"varint,3,opt,name=timestamp" json:"timestamp,omitempty"`
+	// 32-byte SHA-256 hash of the marshaled stored value
+	ValueHash []byte `protobuf:"bytes,4,opt,name=value_hash,json=valueHash,proto3" json:"value_hash,omitempty"`
+}
+
+func (m *StoreReceipt) Reset()                    { *m = StoreReceipt{} }
+func (m *StoreReceipt) String() string            { return proto.CompactTextString(m) }
+func (*StoreReceipt) ProtoMessage()               {}
+func (*StoreReceipt) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{33} }
+
+func (m *StoreReceipt) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *StoreReceipt) GetPeerPubKey() []byte {
+	if m != nil {
+		return m.PeerPubKey
+	}
+	return nil
+}
+
+func (m *StoreReceipt) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *StoreReceipt) GetValueHash() []byte {
+	if m != nil {
+		return m.ValueHash
+	}
+	return nil
+}
+
+type SignedStoreReceipt struct {
+	Receipt *StoreReceipt `protobuf:"bytes,1,opt,name=receipt" json:"receipt,omitempty"`
+	// signature (in the form of an encoded json web token) on the receipt by the peer key
+	Signature string `protobuf:"bytes,2,opt,name=signature" json:"signature,omitempty"`
+}
+
+func (m *SignedStoreReceipt) Reset()                    { *m = SignedStoreReceipt{} }
+func (m *SignedStoreReceipt) String() string            { return proto.CompactTextString(m) }
+func (*SignedStoreReceipt) ProtoMessage()               {}
+func (*SignedStoreReceipt) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{34} }
+
+func (m *SignedStoreReceipt) GetReceipt() *StoreReceipt {
+	if m != nil {
+		return m.Receipt
+	}
+	return nil
+}
+
+func (m *SignedStoreReceipt) GetSignature() string {
+	if m != nil {
+		return m.Signature
+	}
+	return ""
+}
+
+type OrgQuota struct {
+	// 32-byte ID of the organization
+	OrgId []byte `protobuf:"bytes,1,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	// maximum number of bytes the organization may store, with zero meaning unlimited
+	LimitBytes uint64 `protobuf:"varint,2,opt,name=limit_bytes,json=limitBytes" json:"limit_bytes,omitempty"`
+	// number of bytes the organization has stored
+	UsedBytes uint64 `protobuf:"varint,3,opt,name=used_bytes,json=usedBytes" json:"used_bytes,omitempty"`
+}
+
+func (m *OrgQuota) Reset()                    { *m = OrgQuota{} }
+func (m *OrgQuota) String() string            { return proto.CompactTextString(m) }
+func (*OrgQuota) ProtoMessage()               {}
+func (*OrgQuota) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{35} }
+
+func (m *OrgQuota) GetOrgId() []byte {
+	if m != nil {
+		return m.OrgId
+	}
+	return nil
+}
+
+func (m *OrgQuota) GetLimitBytes() uint64 {
+	if m != nil {
+		return m.LimitBytes
+	}
+	return 0
+}
+
+func (m *OrgQuota) GetUsedBytes() uint64 {
+	if m != nil {
+		return m.UsedBytes
+	}
+	return 0
+}
+
+type ListQuotasRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *ListQuotasRequest) Reset()                    { *m = ListQuotasRequest{} }
+func (m *ListQuotasRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListQuotasRequest) ProtoMessage()               {}
+func (*ListQuotasRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{36} }
+
+func (m *ListQuotasRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type ListQuotasResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// quotas of the organizations that have stored documents or have a custom limit
+	Quotas []*OrgQuota `protobuf:"bytes,2,rep,name=quotas" json:"quotas,omitempty"`
+}
+
+func (m *ListQuotasResponse) Reset()                    { *m = ListQuotasResponse{} }
+func (m *ListQuotasResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListQuotasResponse) ProtoMessage()               {}
+func (*ListQuotasResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{37} }
+
+func (m *ListQuotasResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *ListQuotasResponse) GetQuotas() []*OrgQuota {
+	if m != nil {
+		return m.Quotas
+	}
+	return nil
+}
+
+type SetQuotaRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// 32-byte ID of the organization
+	OrgId []byte `protobuf:"bytes,2,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	// maximum number of bytes the organization may store, with zero meaning unlimited
+	LimitBytes uint64 `protobuf:"varint,3,opt,name=limit_bytes,json=limitBytes" json:"limit_bytes,omitempty"`
+	// whether to reset the number of bytes the organization has stored to zero
+	ResetUsed bool `protobuf:"varint,4,opt,name=reset_used,json=resetUsed" json:"reset_used,omitempty"`
+}
+
+func (m *SetQuotaRequest) Reset()                    { *m = SetQuotaRequest{} }
+func (m *SetQuotaRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetQuotaRequest) ProtoMessage()               {}
+func (*SetQuotaRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{38} }
+
+func (m *SetQuotaRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *SetQuotaRequest) GetOrgId() []byte {
+	if m != nil {
+		return m.OrgId
+	}
+	return nil
+}
+
+func (m *SetQuotaRequest) GetLimitBytes() uint64 {
+	if m != nil {
+		return m.LimitBytes
+	}
+	return 0
+}
+
+func (m *SetQuotaRequest) GetResetUsed() bool {
+	if m != nil {
+		return m.ResetUsed
+	}
+	return false
+}
+
+type SetQuotaResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// organization's updated quota
+	Quota *OrgQuota `protobuf:"bytes,2,opt,name=quota" json:"quota,omitempty"`
+}
+
+func (m *SetQuotaResponse) Reset()                    { *m = SetQuotaResponse{} }
+func (m *SetQuotaResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetQuotaResponse) ProtoMessage()               {}
+func (*SetQuotaResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{39} }
+
+func (m *SetQuotaResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *SetQuotaResponse) GetQuota() *OrgQuota {
+	if m != nil {
+		return m.Quota
+	}
+	return nil
+}
+
+type SyncRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// 32-byte inclusive lower bound of the key range
+	LowerBound []byte `protobuf:"bytes,2,opt,name=lower_bound,json=lowerBound,proto3" json:"lower_bound,omitempty"`
+	// 32-byte inclusive upper bound of the key range
+	UpperBound []byte `protobuf:"bytes,3,opt,name=upper_bound,json=upperBound,proto3" json:"upper_bound,omitempty"`
+}
+
+func (m *SyncRequest) Reset()                    { *m = SyncRequest{} }
+func (m *SyncRequest) String() string            { return proto.CompactTextString(m) }
+func (*SyncRequest) ProtoMessage()               {}
+func (*SyncRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{40} }
+
+func (m *SyncRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *SyncRequest) GetLowerBound() []byte {
+	if m != nil {
+		return m.LowerBound
+	}
+	return nil
+}
+
+func (m *SyncRequest) GetUpperBound() []byte {
+	if m != nil {
+		return m.UpperBound
+	}
+	return nil
+}
+
+type KeyRangeDigest struct {
+	// 32-byte inclusive lower bound of the key range
+	LowerBound []byte `protobuf:"bytes,1,opt,name=lower_bound,json=lowerBound,proto3" json:"lower_bound,omitempty"`
+	// 32-byte inclusive upper bound of the key range
+	UpperBound []byte `protobuf:"bytes,2,opt,name=upper_bound,json=upperBound,proto3" json:"upper_bound,omitempty"`
+	// number of keys stored within the range
+	NKeys uint32 `protobuf:"varint,3,opt,name=n_keys,json=nKeys" json:"n_keys,omitempty"`
+	// SHA-256 hash of the (sorted) keys stored within the range
+	Digest []byte `protobuf:"bytes,4,opt,name=digest,proto3" json:"digest,omitempty"`
+}
+
+func (m *KeyRangeDigest) Reset()                    { *m = KeyRangeDigest{} }
+func (m *KeyRangeDigest) String() string            { return proto.CompactTextString(m) }
+func (*KeyRangeDigest) ProtoMessage()               {}
+func (*KeyRangeDigest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{41} }
+
+func (m *KeyRangeDigest) GetLowerBound() []byte {
+	if m != nil {
+		return m.LowerBound
+	}
+	return nil
+}
+
+func (m *KeyRangeDigest) GetUpperBound() []byte {
+	if m != nil {
+		return m.UpperBound
+	}
+	return nil
+}
+
+func (m *KeyRangeDigest) GetNKeys() uint32 {
+	if m != nil {
+		return m.NKeys
+	}
+	return 0
+}
+
+func (m *KeyRangeDigest) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+type SyncResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// digests of the sub-ranges of the requested range when it contains too many keys to list
+	Ranges []*KeyRangeDigest `protobuf:"bytes,2,rep,name=ranges" json:"ranges,omitempty"`
+	// 32-byte keys stored within the requested range when there are few enough to list
+	Keys [][]byte `protobuf:"bytes,3,rep,name=keys,proto3" json:"keys,omitempty"`
+}
+
+func (m *SyncResponse) Reset()                    { *m = SyncResponse{} }
+func (m *SyncResponse) String() string            { return proto.CompactTextString(m) }
+func (*SyncResponse) ProtoMessage()               {}
+func (*SyncResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{42} }
+
+func (m *SyncResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *SyncResponse) GetRanges() []*KeyRangeDigest {
+	if m != nil {
+		return m.Ranges
+	}
+	return nil
+}
+
+func (m *SyncResponse) GetKeys() [][]byte {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type AllowList struct {
+	// ECDSA public keys of the peers allowed in the cluster
+	PubKeys [][]byte `protobuf:"bytes,1,rep,name=pub_keys,json=pubKeys,proto3" json:"pub_keys,omitempty"`
+	// epoch time (nanoseconds) when the allow-list was issued
+	IssuedNanos int64 `protobuf:"varint,2,opt,name=issued_nanos,json=issuedNanos" json:"issued_nanos,omitempty"`
+}
+
+func (m *AllowList) Reset()                    { *m = AllowList{} }
+func (m *AllowList) String() string            { return proto.CompactTextString(m) }
+func (*AllowList) ProtoMessage()               {}
+func (*AllowList) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{43} }
+
+func (m *AllowList) GetPubKeys() [][]byte {
+	if m != nil {
+		return m.PubKeys
+	}
+	return nil
+}
+
+func (m *AllowList) GetIssuedNanos() int64 {
+	if m != nil {
+		return m.IssuedNanos
+	}
+	return 0
+}
+
+type SignedAllowList struct {
+	AllowList *AllowList `protobuf:"bytes,1,opt,name=allow_list,json=allowList" json:"allow_list,omitempty"`
+	// ECDSA public key of the admin issuing the allow-list
+	PubKey []byte `protobuf:"bytes,2,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	// signature (in the form of an encoded json web token) on the allow-list by the admin key
+	Signature string `protobuf:"bytes,3,opt,name=signature" json:"signature,omitempty"`
+}
+
+func (m *SignedAllowList) Reset()                    { *m = SignedAllowList{} }
+func (m *SignedAllowList) String() string            { return proto.CompactTextString(m) }
+func (*SignedAllowList) ProtoMessage()               {}
+func (*SignedAllowList) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{44} }
+
+func (m *SignedAllowList) GetAllowList() *AllowList {
+	if m != nil {
+		return m.AllowList
+	}
+	return nil
+}
+
+func (m *SignedAllowList) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
+	}
+	return nil
+}
+
+func (m *SignedAllowList) GetSignature() string {
+	if m != nil {
+		return m.Signature
+	}
+	return ""
+}
+
+type GetAllowListRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *GetAllowListRequest) Reset()                    { *m = GetAllowListRequest{} }
+func (m *GetAllowListRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetAllowListRequest) ProtoMessage()               {}
+func (*GetAllowListRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{45} }
+
+func (m *GetAllowListRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type GetAllowListResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// current allow-list, or empty if the librarian isn't running in allow-list mode
+	AllowList *SignedAllowList `protobuf:"bytes,2,opt,name=allow_list,json=allowList" json:"allow_list,omitempty"`
+}
+
+func (m *GetAllowListResponse) Reset()                    { *m = GetAllowListResponse{} }
+func (m *GetAllowListResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetAllowListResponse) ProtoMessage()               {}
+func (*GetAllowListResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{46} }
+
+func (m *GetAllowListResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *GetAllowListResponse) GetAllowList() *SignedAllowList {
+	if m != nil {
+		return m.AllowList
+	}
+	return nil
+}
+
+type SetAllowListRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// allow-list to replace the current one, signed by the librarian's admin key
+	AllowList *SignedAllowList `protobuf:"bytes,2,opt,name=allow_list,json=allowList" json:"allow_list,omitempty"`
+}
+
+func (m *SetAllowListRequest) Reset()                    { *m = SetAllowListRequest{} }
+func (m *SetAllowListRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetAllowListRequest) ProtoMessage()               {}
+func (*SetAllowListRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{47} }
+
+func (m *SetAllowListRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *SetAllowListRequest) GetAllowList() *SignedAllowList {
+	if m != nil {
+		return m.AllowList
+	}
+	return nil
+}
+
+type SetAllowListResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *SetAllowListResponse) Reset()                    { *m = SetAllowListResponse{} }
+func (m *SetAllowListResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetAllowListResponse) ProtoMessage()               {}
+func (*SetAllowListResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{48} }
+
+func (m *SetAllowListResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type PinRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// 32-byte key of the document to pin
+	Key []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *PinRequest) Reset()                    { *m = PinRequest{} }
+func (m *PinRequest) String() string            { return proto.CompactTextString(m) }
+func (*PinRequest) ProtoMessage()               {}
+func (*PinRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{49} }
+
+func (m *PinRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *PinRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type PinResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *PinResponse) Reset()                    { *m = PinResponse{} }
+func (m *PinResponse) String() string            { return proto.CompactTextString(m) }
+func (*PinResponse) ProtoMessage()               {}
+func (*PinResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{50} }
+
+func (m *PinResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type UnpinRequest struct {
+	Metadata *RequestMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	// 32-byte key of the document to unpin
+	Key []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *UnpinRequest) Reset()                    { *m = UnpinRequest{} }
+func (m *UnpinRequest) String() string            { return proto.CompactTextString(m) }
+func (*UnpinRequest) ProtoMessage()               {}
+func (*UnpinRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{51} }
+
+func (m *UnpinRequest) GetMetadata() *RequestMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *UnpinRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type UnpinResponse struct {
+	Metadata *ResponseMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *UnpinResponse) Reset()                    { *m = UnpinResponse{} }
+func (m *UnpinResponse) String() string            { return proto.CompactTextString(m) }
+func (*UnpinResponse) ProtoMessage()               {}
+func (*UnpinResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{52} }
+
+func (m *UnpinResponse) GetMetadata() *ResponseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*RequestMetadata)(nil), "api.RequestMetadata")
+	proto.RegisterType((*ResponseMetadata)(nil), "api.ResponseMetadata")
+	proto.RegisterType((*IntroduceRequest)(nil), "api.IntroduceRequest")
+	proto.RegisterType((*IntroduceResponse)(nil), "api.IntroduceResponse")
+	proto.RegisterType((*FindRequest)(nil), "api.FindRequest")
+	proto.RegisterType((*FindResponse)(nil), "api.FindResponse")
+	proto.RegisterType((*VerifyRequest)(nil), "api.VerifyRequest")
+	proto.RegisterType((*VerifyResponse)(nil), "api.VerifyResponse")
+	proto.RegisterType((*PeerAddress)(nil), "api.PeerAddress")
+	proto.RegisterType((*StoreRequest)(nil), "api.StoreRequest")
+	proto.RegisterType((*StoreResponse)(nil), "api.StoreResponse")
+	proto.RegisterType((*GetRequest)(nil), "api.GetRequest")
+	proto.RegisterType((*GetResponse)(nil), "api.GetResponse")
+	proto.RegisterType((*PutRequest)(nil), "api.PutRequest")
+	proto.RegisterType((*PutResponse)(nil), "api.PutResponse")
+	proto.RegisterType((*SubscribeRequest)(nil), "api.SubscribeRequest")
+	proto.RegisterType((*SubscribeResponse)(nil), "api.SubscribeResponse")
+	proto.RegisterType((*Publication)(nil), "api.Publication")
+	proto.RegisterType((*Subscription)(nil), "api.Subscription")
+	proto.RegisterType((*BloomFilter)(nil), "api.BloomFilter")
+	proto.RegisterType((*BuildInfo)(nil), "api.BuildInfo")
+	proto.RegisterType((*SignedBuildInfo)(nil), "api.SignedBuildInfo")
+	proto.RegisterType((*RoutingTableRequest)(nil), "api.RoutingTableRequest")
+	proto.RegisterType((*RoutingTableResponse)(nil), "api.RoutingTableResponse")
+	proto.RegisterType((*RoutingBucket)(nil), "api.RoutingBucket")
+	proto.RegisterType((*RoutingPeer)(nil), "api.RoutingPeer")
+	proto.RegisterType((*PeerBan)(nil), "api.PeerBan")
+	proto.RegisterType((*ListBansRequest)(nil), "api.ListBansRequest")
+	proto.RegisterType((*ListBansResponse)(nil), "api.ListBansResponse")
+	proto.RegisterType((*BanRequest)(nil), "api.BanRequest")
+	proto.RegisterType((*BanResponse)(nil), "api.BanResponse")
+	proto.RegisterType((*UnbanRequest)(nil), "api.UnbanRequest")
+	proto.RegisterType((*UnbanResponse)(nil), "api.UnbanResponse")
+	proto.RegisterType((*StoreReceipt)(nil), "api.StoreReceipt")
+	proto.RegisterType((*SignedStoreReceipt)(nil), "api.SignedStoreReceipt")
+	proto.RegisterType((*OrgQuota)(nil), "api.OrgQuota")
+	proto.RegisterType((*ListQuotasRequest)(nil), "api.ListQuotasRequest")
+	proto.RegisterType((*ListQuotasResponse)(nil), "api.ListQuotasResponse")
+	proto.RegisterType((*SetQuotaRequest)(nil), "api.SetQuotaRequest")
+	proto.RegisterType((*SetQuotaResponse)(nil), "api.SetQuotaResponse")
+	proto.RegisterType((*SyncRequest)(nil), "api.SyncRequest")
+	proto.RegisterType((*KeyRangeDigest)(nil), "api.KeyRangeDigest")
+	proto.RegisterType((*SyncResponse)(nil), "api.SyncResponse")
+	proto.RegisterType((*AllowList)(nil), "api.AllowList")
+	proto.RegisterType((*SignedAllowList)(nil), "api.SignedAllowList")
+	proto.RegisterType((*GetAllowListRequest)(nil), "api.GetAllowListRequest")
+	proto.RegisterType((*GetAllowListResponse)(nil), "api.GetAllowListResponse")
+	proto.RegisterType((*SetAllowListRequest)(nil), "api.SetAllowListRequest")
+	proto.RegisterType((*SetAllowListResponse)(nil), "api.SetAllowListResponse")
+	proto.RegisterType((*PinRequest)(nil), "api.PinRequest")
+	proto.RegisterType((*PinResponse)(nil), "api.PinResponse")
+	proto.RegisterType((*UnpinRequest)(nil), "api.UnpinRequest")
+	proto.RegisterType((*UnpinResponse)(nil), "api.UnpinResponse")
+	proto.RegisterEnum("api.PutOperation", PutOperation_name, PutOperation_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Librarian service
+
+type LibrarianClient interface {
+	// Introduce identifies the node by name and ID.
+	Introduce(ctx context.Context, in *IntroduceRequest, opts ...grpc.CallOption) (*IntroduceResponse, error)
+	// Find returns the value for a key or the closest peers to it.
+	Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error)
+	// Verify checks that a peer has the value for a given key or returns the closest peers to
+	// that value.
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	// Store stores a value in a given key.
+	Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreResponse, error)
+	// Get retrieves a value, if it exists.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Put stores a value.
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
+	// Subscribe streams Publications to the client per a subscription filter.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Librarian_SubscribeClient, error)
+	// Sync returns a digest of the keys the peer stores within a key range, split into
+	// sub-ranges, or the keys themselves if there are few enough, so neighboring peers can find
+	// the documents they are missing.
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+	// RoutingTable streams the current routing table contents, one bucket per response. It is
+	// only available to requests signed by the librarian's configured admin key.
+	RoutingTable(ctx context.Context, in *RoutingTableRequest, opts ...grpc.CallOption) (Librarian_RoutingTableClient, error)
+	// ListBans lists the librarian's persisted peer bans. It is only available to requests
+	// signed by the librarian's configured admin key.
+	ListBans(ctx context.Context, in *ListBansRequest, opts ...grpc.CallOption) (*ListBansResponse, error)
+	// Ban bans a peer, persisting the ban. It is only available to requests signed by the
+	// librarian's configured admin key.
+	Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanResponse, error)
+	// Unban lifts and removes a persisted peer ban. It is only available to requests signed by
+	// the librarian's configured admin key.
+	Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanResponse, error)
+	// ListQuotas lists the organizations' storage quotas and usage. It is only available to
+	// requests signed by the librarian's configured admin key.
+	ListQuotas(ctx context.Context, in *ListQuotasRequest, opts ...grpc.CallOption) (*ListQuotasResponse, error)
+	// SetQuota sets an organization's storage quota, persisting it. It is only available to
+	// requests signed by the librarian's configured admin key.
+	SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*SetQuotaResponse, error)
+	// GetAllowList returns the librarian's signed peer allow-list, if any. It is only available
+	// to requests signed by the librarian's configured admin key.
+	GetAllowList(ctx context.Context, in *GetAllowListRequest, opts ...grpc.CallOption) (*GetAllowListResponse, error)
+	// SetAllowList replaces the librarian's signed peer allow-list, persisting it. It is only
+	// available to requests signed by the librarian's configured admin key.
+	SetAllowList(ctx context.Context, in *SetAllowListRequest, opts ...grpc.CallOption) (*SetAllowListResponse, error)
+	// Pin keeps a stored document in the hot storage tier regardless of when it was last
+	// accessed. It is only available to requests signed by the librarian's configured admin key.
+	Pin(ctx context.Context, in *PinRequest, opts ...grpc.CallOption) (*PinResponse, error)
+	// Unpin lets a pinned document be demoted to the cold storage tier once it isn't accessed
+	// recently. It is only available to requests signed by the librarian's configured admin key.
+	Unpin(ctx context.Context, in *UnpinRequest, opts ...grpc.CallOption) (*UnpinResponse, error)
+}
+
+type librarianClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewLibrarianClient(cc *grpc.ClientConn) LibrarianClient {
+	return &librarianClient{cc}
+}
+
+func (c *librarianClient) Introduce(ctx context.Context, in *IntroduceRequest, opts ...grpc.CallOption) (*IntroduceResponse, error) {
+	out := new(IntroduceResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Introduce", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error) {
+	out := new(FindResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Find", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	out := new(VerifyResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Verify", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreResponse, error) {
+	out := new(StoreResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Store", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	out := new(GetResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Get", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error) {
+	out := new(PutResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Put", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Librarian_SubscribeClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Librarian_serviceDesc.Streams[0], c.cc, "/api.Librarian/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &librarianSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Librarian_SubscribeClient interface {
+	Recv() (*SubscribeResponse, error)
+	grpc.ClientStream
+}
+
+type librarianSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *librarianSubscribeClient) Recv() (*SubscribeResponse, error) {
+	m := new(SubscribeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *librarianClient) Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	out := new(SyncResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Sync", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) RoutingTable(ctx context.Context, in *RoutingTableRequest, opts ...grpc.CallOption) (Librarian_RoutingTableClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Librarian_serviceDesc.Streams[1], c.cc, "/api.Librarian/RoutingTable", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &librarianRoutingTableClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Librarian_RoutingTableClient interface {
+	Recv() (*RoutingTableResponse, error)
+	grpc.ClientStream
+}
+
+type librarianRoutingTableClient struct {
+	grpc.ClientStream
+}
+
+func (x *librarianRoutingTableClient) Recv() (*RoutingTableResponse, error) {
+	m := new(RoutingTableResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *librarianClient) ListBans(ctx context.Context, in *ListBansRequest, opts ...grpc.CallOption) (*ListBansResponse, error) {
+	out := new(ListBansResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/ListBans", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanResponse, error) {
+	out := new(BanResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Ban", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanResponse, error) {
+	out := new(UnbanResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Unban", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) ListQuotas(ctx context.Context, in *ListQuotasRequest, opts ...grpc.CallOption) (*ListQuotasResponse, error) {
+	out := new(ListQuotasResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/ListQuotas", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*SetQuotaResponse, error) {
+	out := new(SetQuotaResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/SetQuota", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) GetAllowList(ctx context.Context, in *GetAllowListRequest, opts ...grpc.CallOption) (*GetAllowListResponse, error) {
+	out := new(GetAllowListResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/GetAllowList", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) SetAllowList(ctx context.Context, in *SetAllowListRequest, opts ...grpc.CallOption) (*SetAllowListResponse, error) {
+	out := new(SetAllowListResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/SetAllowList", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Pin(ctx context.Context, in *PinRequest, opts ...grpc.CallOption) (*PinResponse, error) {
+	out := new(PinResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Pin", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *librarianClient) Unpin(ctx context.Context, in *UnpinRequest, opts ...grpc.CallOption) (*UnpinResponse, error) {
+	out := new(UnpinResponse)
+	err := grpc.Invoke(ctx, "/api.Librarian/Unpin", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Librarian service
+
+type LibrarianServer interface {
+	// Introduce identifies the node by name and ID.
+	Introduce(context.Context, *IntroduceRequest) (*IntroduceResponse, error)
+	// Find returns the value for a key or the closest peers to it.
 	Find(context.Context, *FindRequest) (*FindResponse, error)
 	// Verify checks that a peer has the value for a given key or returns the closest peers to
 	// that value.
@@ -838,6 +2142,40 @@ type LibrarianServer interface {
 	Put(context.Context, *PutRequest) (*PutResponse, error)
 	// Subscribe streams Publications to the client per a subscription filter.
 	Subscribe(*SubscribeRequest, Librarian_SubscribeServer) error
+	// Sync returns a digest of the keys the peer stores within a key range, split into
+	// sub-ranges, or the keys themselves if there are few enough, so neighboring peers can find
+	// the documents they are missing.
+	Sync(context.Context, *SyncRequest) (*SyncResponse, error)
+	// RoutingTable streams the current routing table contents, one bucket per response. It is
+	// only available to requests signed by the librarian's configured admin key.
+	RoutingTable(*RoutingTableRequest, Librarian_RoutingTableServer) error
+	// ListBans lists the librarian's persisted peer bans. It is only available to requests
+	// signed by the librarian's configured admin key.
+	ListBans(context.Context, *ListBansRequest) (*ListBansResponse, error)
+	// Ban bans a peer, persisting the ban. It is only available to requests signed by the
+	// librarian's configured admin key.
+	Ban(context.Context, *BanRequest) (*BanResponse, error)
+	// Unban lifts and removes a persisted peer ban. It is only available to requests signed by
+	// the librarian's configured admin key.
+	Unban(context.Context, *UnbanRequest) (*UnbanResponse, error)
+	// ListQuotas lists the organizations' storage quotas and usage. It is only available to
+	// requests signed by the librarian's configured admin key.
+	ListQuotas(context.Context, *ListQuotasRequest) (*ListQuotasResponse, error)
+	// SetQuota sets an organization's storage quota, persisting it. It is only available to
+	// requests signed by the librarian's configured admin key.
+	SetQuota(context.Context, *SetQuotaRequest) (*SetQuotaResponse, error)
+	// GetAllowList returns the librarian's signed peer allow-list, if any. It is only available
+	// to requests signed by the librarian's configured admin key.
+	GetAllowList(context.Context, *GetAllowListRequest) (*GetAllowListResponse, error)
+	// SetAllowList replaces the librarian's signed peer allow-list, persisting it. It is only
+	// available to requests signed by the librarian's configured admin key.
+	SetAllowList(context.Context, *SetAllowListRequest) (*SetAllowListResponse, error)
+	// Pin keeps a stored document in the hot storage tier regardless of when it was last
+	// accessed. It is only available to requests signed by the librarian's configured admin key.
+	Pin(context.Context, *PinRequest) (*PinResponse, error)
+	// Unpin lets a pinned document be demoted to the cold storage tier once it isn't accessed
+	// recently. It is only available to requests signed by the librarian's configured admin key.
+	Unpin(context.Context, *UnpinRequest) (*UnpinResponse, error)
 }
 
 func RegisterLibrarianServer(s *grpc.Server, srv LibrarianServer) {
@@ -973,6 +2311,207 @@ func (x *librarianSubscribeServer) Send(m *SubscribeResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Librarian_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/Sync",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).Sync(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_RoutingTable_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RoutingTableRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LibrarianServer).RoutingTable(m, &librarianRoutingTableServer{stream})
+}
+
+type Librarian_RoutingTableServer interface {
+	Send(*RoutingTableResponse) error
+	grpc.ServerStream
+}
+
+type librarianRoutingTableServer struct {
+	grpc.ServerStream
+}
+
+func (x *librarianRoutingTableServer) Send(m *RoutingTableResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Librarian_ListBans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).ListBans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/ListBans",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).ListBans(ctx, req.(*ListBansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_Ban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).Ban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/Ban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).Ban(ctx, req.(*BanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_Unban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnbanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).Unban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/Unban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).Unban(ctx, req.(*UnbanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_ListQuotas_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuotasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).ListQuotas(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/ListQuotas",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).ListQuotas(ctx, req.(*ListQuotasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_SetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).SetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/SetQuota",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).SetQuota(ctx, req.(*SetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_GetAllowList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllowListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).GetAllowList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/GetAllowList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).GetAllowList(ctx, req.(*GetAllowListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_SetAllowList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAllowListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).SetAllowList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/SetAllowList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).SetAllowList(ctx, req.(*SetAllowListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_Pin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).Pin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/Pin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).Pin(ctx, req.(*PinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Librarian_Unpin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnpinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LibrarianServer).Unpin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Librarian/Unpin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LibrarianServer).Unpin(ctx, req.(*UnpinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Librarian_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.Librarian",
 	HandlerType: (*LibrarianServer)(nil),
@@ -1001,6 +2540,46 @@ var _Librarian_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Put",
 			Handler:    _Librarian_Put_Handler,
 		},
+		{
+			MethodName: "Sync",
+			Handler:    _Librarian_Sync_Handler,
+		},
+		{
+			MethodName: "ListBans",
+			Handler:    _Librarian_ListBans_Handler,
+		},
+		{
+			MethodName: "Ban",
+			Handler:    _Librarian_Ban_Handler,
+		},
+		{
+			MethodName: "Unban",
+			Handler:    _Librarian_Unban_Handler,
+		},
+		{
+			MethodName: "ListQuotas",
+			Handler:    _Librarian_ListQuotas_Handler,
+		},
+		{
+			MethodName: "SetQuota",
+			Handler:    _Librarian_SetQuota_Handler,
+		},
+		{
+			MethodName: "GetAllowList",
+			Handler:    _Librarian_GetAllowList_Handler,
+		},
+		{
+			MethodName: "SetAllowList",
+			Handler:    _Librarian_SetAllowList_Handler,
+		},
+		{
+			MethodName: "Pin",
+			Handler:    _Librarian_Pin_Handler,
+		},
+		{
+			MethodName: "Unpin",
+			Handler:    _Librarian_Unpin_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1008,6 +2587,11 @@ var _Librarian_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Librarian_Subscribe_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "RoutingTable",
+			Handler:       _Librarian_RoutingTable_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "librarian/api/librarian.proto",
 }
@@ -1015,60 +2599,144 @@ var _Librarian_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("librarian/api/librarian.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 870 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x56, 0xcf, 0x6f, 0xe3, 0x44,
-	0x14, 0xae, 0x93, 0x34, 0xad, 0x9f, 0x93, 0xd6, 0x19, 0x7e, 0x6c, 0x15, 0xb4, 0x08, 0x0c, 0x5a,
-	0x50, 0xa5, 0x6d, 0x4b, 0x56, 0xdc, 0xd0, 0x4a, 0xac, 0xb6, 0xad, 0xa2, 0x5d, 0x76, 0x23, 0xa7,
-	0x42, 0xdc, 0xa2, 0x89, 0xfd, 0xb6, 0x0c, 0xd8, 0x1e, 0x33, 0x1e, 0x2f, 0x8a, 0x10, 0x12, 0x37,
-	0xc4, 0x05, 0x71, 0xe0, 0x5f, 0xe0, 0x6f, 0xe4, 0x8a, 0x3c, 0x33, 0x76, 0x6c, 0x07, 0x55, 0x4b,
-	0xb6, 0xec, 0x2d, 0x7e, 0xef, 0x9b, 0xbc, 0xef, 0x7d, 0xf3, 0x7e, 0x0c, 0xdc, 0x8d, 0xd8, 0x52,
-	0x50, 0xc1, 0x68, 0x72, 0x4a, 0x53, 0x76, 0x5a, 0x7d, 0x9d, 0xa4, 0x82, 0x4b, 0x4e, 0xba, 0x34,
-	0x65, 0xe3, 0x16, 0x26, 0xe4, 0x41, 0x1e, 0x63, 0x22, 0x33, 0x8d, 0xf1, 0x18, 0x1c, 0xfa, 0xf8,
-	0x43, 0x8e, 0x99, 0xfc, 0x0a, 0x25, 0x0d, 0xa9, 0xa4, 0xe4, 0x2e, 0x80, 0xd0, 0xa6, 0x05, 0x0b,
-	0x8f, 0xac, 0x0f, 0xac, 0x4f, 0x07, 0xbe, 0x6d, 0x2c, 0xd3, 0x90, 0xdc, 0x81, 0xbd, 0x34, 0x5f,
-	0x2e, 0xbe, 0xc7, 0xd5, 0x51, 0x47, 0xf9, 0xfa, 0x69, 0xbe, 0x7c, 0x82, 0x2b, 0xf2, 0x3e, 0x38,
-	0x5c, 0x5c, 0x2f, 0x4a, 0x67, 0x57, 0x1f, 0xe4, 0xe2, 0x7a, 0xa6, 0xfc, 0xde, 0x77, 0xe0, 0xfa,
-	0x98, 0xa5, 0x3c, 0xc9, 0xf0, 0x7f, 0x8f, 0xf5, 0xab, 0x05, 0xee, 0x34, 0x91, 0x82, 0x87, 0x79,
-	0x80, 0x26, 0x41, 0x72, 0x06, 0xfb, 0xb1, 0x09, 0xac, 0x42, 0x39, 0x93, 0xb7, 0x4f, 0x68, 0xca,
-	0x4e, 0x5a, 0x02, 0xf8, 0x15, 0x8a, 0x7c, 0x0c, 0xbd, 0x0c, 0xa3, 0x17, 0x2a, 0xb8, 0x33, 0x71,
-	0x15, 0x7a, 0x86, 0x28, 0xbe, 0x0c, 0x43, 0x81, 0x59, 0xe6, 0x2b, 0x2f, 0x79, 0x0f, 0xec, 0x24,
-	0x8f, 0x17, 0x29, 0xa2, 0xc8, 0x14, 0x95, 0xa1, 0xbf, 0x9f, 0xe4, 0x71, 0x01, 0xcc, 0xbc, 0x3f,
-	0x2d, 0x18, 0xd5, 0x98, 0xe8, 0xfc, 0xc9, 0x67, 0x1b, 0x54, 0xde, 0x31, 0x54, 0x9a, 0x02, 0xfd,
-	0x67, 0x2e, 0xf7, 0x60, 0xb7, 0xe4, 0xd1, 0xfd, 0x57, 0x98, 0x76, 0x7b, 0x09, 0x38, 0x17, 0x2c,
-	0x09, 0xb7, 0x97, 0xc6, 0x85, 0xee, 0xfa, 0x5a, 0x8a, 0x9f, 0x37, 0xcb, 0xf0, 0xbb, 0x05, 0x03,
-	0x1d, 0x70, 0x7b, 0x05, 0xaa, 0xdc, 0x3a, 0x37, 0xe6, 0x46, 0x3e, 0x82, 0xdd, 0x97, 0x34, 0xca,
-	0x51, 0x91, 0x70, 0x26, 0x43, 0x85, 0x7b, 0x6c, 0x0a, 0xdf, 0xd7, 0x3e, 0xef, 0x37, 0x0b, 0x86,
-	0x5f, 0xa3, 0x60, 0x2f, 0x56, 0xb7, 0xa9, 0xc1, 0x1d, 0xd8, 0x8b, 0x69, 0x50, 0xab, 0xc9, 0x7e,
-	0x4c, 0x83, 0x27, 0x6d, 0x71, 0x7a, 0x2d, 0x71, 0x7e, 0x86, 0x83, 0x92, 0xca, 0xf6, 0xea, 0xb8,
-	0xd0, 0x8d, 0x69, 0x50, 0x92, 0x89, 0x69, 0xf0, 0xca, 0xb5, 0x70, 0x0d, 0x4e, 0xcd, 0xaa, 0x9a,
-	0x0e, 0x51, 0xac, 0x1b, 0xb2, 0x5f, 0x7c, 0x4e, 0xc3, 0x22, 0x07, 0xe5, 0x48, 0x68, 0x8c, 0x2a,
-	0x8e, 0xed, 0xef, 0x17, 0x86, 0x67, 0x34, 0x46, 0x72, 0x00, 0x1d, 0x96, 0xaa, 0xa4, 0x6d, 0xbf,
-	0xc3, 0x52, 0x42, 0xa0, 0x97, 0x72, 0x21, 0x4d, 0xae, 0xea, 0xb7, 0xf7, 0x23, 0x0c, 0xe6, 0x92,
-	0x0b, 0xbc, 0x4d, 0xc5, 0x5f, 0xe9, 0xb2, 0x1f, 0xc1, 0xd0, 0x04, 0xde, 0x5a, 0x5f, 0x6f, 0x06,
-	0x70, 0x89, 0xf2, 0x16, 0xa9, 0x7b, 0x08, 0x8e, 0xfa, 0xc7, 0xed, 0xef, 0xbc, 0x4a, 0xbe, 0x73,
-	0x43, 0xf2, 0x39, 0xc0, 0x2c, 0x97, 0x6f, 0x5c, 0xf3, 0x3f, 0x2c, 0x70, 0x54, 0xdc, 0xed, 0xd3,
-	0x3b, 0x05, 0x9b, 0xa7, 0x28, 0xa8, 0x64, 0x3c, 0x51, 0xf1, 0x0f, 0x26, 0x23, 0x5d, 0xc4, 0xb9,
-	0x7c, 0x5e, 0x3a, 0xfc, 0x35, 0xa6, 0x58, 0x27, 0xc9, 0x42, 0x60, 0x1a, 0xb1, 0x80, 0x96, 0x33,
-	0xc8, 0x4e, 0x7c, 0x63, 0xf0, 0x7e, 0x02, 0x77, 0x9e, 0x2f, 0xb3, 0x40, 0xb0, 0xe5, 0x6b, 0xd4,
-	0xe0, 0xe7, 0x30, 0xc8, 0xf4, 0xbf, 0xa4, 0x15, 0x31, 0xc7, 0x10, 0x9b, 0xd7, 0x1c, 0x7e, 0x03,
-	0xe6, 0xfd, 0x62, 0xc1, 0xa8, 0x16, 0xfd, 0xb5, 0x1a, 0xbd, 0x75, 0x1f, 0xf7, 0x9a, 0xf7, 0x61,
-	0x1a, 0x3d, 0x5f, 0x16, 0x59, 0x2b, 0x26, 0xe6, 0x4a, 0xfe, 0x52, 0x57, 0x52, 0x99, 0xc9, 0x87,
-	0x30, 0xc0, 0xe4, 0x25, 0x46, 0x3c, 0x45, 0x35, 0xb2, 0x74, 0xbb, 0x3b, 0xa5, 0xcd, 0xcc, 0x2d,
-	0x4c, 0xa4, 0x58, 0xd5, 0x76, 0xf0, 0xbe, 0x32, 0x14, 0xce, 0x63, 0x18, 0xd1, 0x5c, 0x7e, 0xcb,
-	0x45, 0xb1, 0x88, 0x23, 0x56, 0x9f, 0x7b, 0x87, 0xda, 0xa1, 0xa3, 0x19, 0xac, 0x40, 0x1a, 0x62,
-	0x03, 0xdb, 0xd3, 0x58, 0xed, 0xa8, 0xb0, 0x6a, 0x59, 0xd4, 0x95, 0x24, 0x0f, 0x81, 0x6c, 0x04,
-	0xca, 0x8c, 0x5e, 0x3a, 0xdb, 0x47, 0x11, 0xe7, 0xf1, 0x05, 0x8b, 0x24, 0x0a, 0xdf, 0x6d, 0xc5,
-	0xce, 0x8a, 0xf3, 0x1b, 0xc1, 0xb3, 0xc6, 0x26, 0x6d, 0x9c, 0x6f, 0xf1, 0xc9, 0xbc, 0x4f, 0xc0,
-	0xa9, 0x01, 0xc8, 0x11, 0xec, 0x61, 0x12, 0xf0, 0x10, 0xcb, 0x09, 0x59, 0x7e, 0x1e, 0xdf, 0x87,
-	0x41, 0xbd, 0x36, 0x09, 0x40, 0x7f, 0x7e, 0xf5, 0xdc, 0x3f, 0x7f, 0xec, 0xee, 0x90, 0x11, 0x0c,
-	0x9f, 0x9e, 0x5f, 0x5c, 0x2d, 0xce, 0xbf, 0x99, 0xce, 0xaf, 0xa6, 0xcf, 0x2e, 0x5d, 0x6b, 0xf2,
-	0x77, 0x07, 0xec, 0xa7, 0xe5, 0xfb, 0x8c, 0x7c, 0x01, 0x76, 0xf5, 0x52, 0x20, 0xba, 0x0c, 0xda,
-	0x6f, 0x98, 0xf1, 0xbb, 0x6d, 0xb3, 0x2e, 0x13, 0x6f, 0x87, 0xdc, 0x87, 0x5e, 0xb1, 0x60, 0x89,
-	0xce, 0xa7, 0xb6, 0xdc, 0xc7, 0xa3, 0x9a, 0xa5, 0x82, 0x3f, 0x80, 0xbe, 0xde, 0x39, 0x84, 0x28,
-	0x77, 0x63, 0x17, 0x8e, 0xdf, 0x6a, 0xd8, 0xaa, 0x43, 0x67, 0xb0, 0xab, 0xe6, 0x28, 0x31, 0xd5,
-	0x5e, 0x1b, 0xe6, 0x63, 0x52, 0x37, 0x55, 0x27, 0x8e, 0xa1, 0x7b, 0x89, 0x92, 0x1c, 0x2a, 0xe7,
-	0x7a, 0x7e, 0x8e, 0xdd, 0xb5, 0xa1, 0x8e, 0x9d, 0xe5, 0x25, 0x76, 0x3d, 0xb2, 0xc6, 0xee, 0xda,
-	0x50, 0x61, 0x1f, 0x82, 0x5d, 0x35, 0x93, 0xd1, 0xaa, 0xdd, 0xda, 0x46, 0xab, 0x8d, 0x9e, 0xf3,
-	0x76, 0xce, 0xac, 0x65, 0x5f, 0x3d, 0x7f, 0x1f, 0xfc, 0x13, 0x00, 0x00, 0xff, 0xff, 0x7e, 0x17,
-	0xea, 0x04, 0x43, 0x0b, 0x00, 0x00,
+	// 2220 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x59, 0xcf, 0x73, 0x1b, 0x49,
+	0xf5, 0xcf, 0x48, 0xb6, 0xac, 0x79, 0x92, 0x6c, 0xb9, 0xe3, 0x38, 0x8a, 0xbe, 0xdf, 0xdd, 0x35,
+	0x13, 0x58, 0x42, 0x96, 0xfc, 0xd8, 0xa4, 0x38, 0x6c, 0x15, 0xb5, 0x60, 0x13, 0xc7, 0xb8, 0x12,
+	0x12, 0xd3, 0xca, 0x6e, 0xb1, 0x55, 0xc0, 0x54, 0x4b, 0xd3, 0x96, 0x9b, 0x1d, 0xf5, 0x4c, 0x66,
+	0x7a, 0x12, 0xc4, 0x6f, 0xb8, 0x70, 0xe3, 0x0f, 0xa0, 0xb8, 0x72, 0xa6, 0xa8, 0x82, 0x2a, 0xfe,
+	0x01, 0xce, 0xfc, 0x0f, 0x9c, 0x38, 0x72, 0xe3, 0x4a, 0xf5, 0x8f, 0x19, 0xf5, 0x8c, 0xe4, 0x00,
+	0x63, 0x53, 0xdc, 0x34, 0x9f, 0xf7, 0xfa, 0xbd, 0xd7, 0x9f, 0xee, 0x7e, 0xef, 0x75, 0x0b, 0xde,
+	0x0a, 0xd9, 0x38, 0x21, 0x09, 0x23, 0xfc, 0x1e, 0x89, 0xd9, 0xbd, 0xe2, 0xeb, 0x6e, 0x9c, 0x44,
+	0x22, 0x42, 0x4d, 0x12, 0xb3, 0x61, 0x45, 0x27, 0x88, 0x26, 0xd9, 0x8c, 0x72, 0x91, 0x6a, 0x1d,
+	0x8f, 0xc1, 0x16, 0xa6, 0x2f, 0x33, 0x9a, 0x8a, 0x6f, 0x50, 0x41, 0x02, 0x22, 0x08, 0x7a, 0x0b,
+	0x20, 0xd1, 0x90, 0xcf, 0x82, 0x81, 0xb3, 0xe7, 0xdc, 0xea, 0x62, 0xd7, 0x20, 0xc7, 0x01, 0xba,
+	0x0e, 0x1b, 0x71, 0x36, 0xf6, 0x3f, 0xa5, 0xf3, 0x41, 0x43, 0xc9, 0x5a, 0x71, 0x36, 0x7e, 0x42,
+	0xe7, 0xe8, 0x6d, 0xe8, 0x44, 0xc9, 0xd4, 0xcf, 0x85, 0x4d, 0x3d, 0x30, 0x4a, 0xa6, 0x27, 0x4a,
+	0xee, 0x7d, 0x0f, 0xfa, 0x98, 0xa6, 0x71, 0xc4, 0x53, 0xfa, 0x5f, 0xf7, 0xf5, 0x4b, 0x07, 0xfa,
+	0xc7, 0x5c, 0x24, 0x51, 0x90, 0x4d, 0xa8, 0x99, 0x20, 0xba, 0x0f, 0xed, 0x99, 0x71, 0xac, 0x5c,
+	0x75, 0x1e, 0xec, 0xdc, 0x25, 0x31, 0xbb, 0x5b, 0x21, 0x00, 0x17, 0x5a, 0xe8, 0xb3, 0xb0, 0x96,
+	0xd2, 0xf0, 0x54, 0x39, 0xef, 0x3c, 0xe8, 0x2b, 0xed, 0x13, 0x4a, 0x93, 0xfd, 0x20, 0x48, 0x68,
+	0x9a, 0x62, 0x25, 0x45, 0xff, 0x07, 0x2e, 0xcf, 0x66, 0x7e, 0x4c, 0x69, 0x92, 0xaa, 0x50, 0x7a,
+	0xb8, 0xcd, 0xb3, 0x99, 0x54, 0x4c, 0xbd, 0xbf, 0x39, 0xb0, 0x6d, 0x45, 0xa2, 0xe7, 0x8f, 0xde,
+	0x5f, 0x0a, 0xe5, 0x9a, 0x09, 0xa5, 0x4c, 0xd0, 0x7f, 0x1c, 0xcb, 0xbb, 0xb0, 0x9e, 0xc7, 0xd1,
+	0x5c, 0xa9, 0xa6, 0xc5, 0x92, 0x78, 0xc1, 0x66, 0xd4, 0xe7, 0x84, 0x47, 0xe9, 0x60, 0x6d, 0xcf,
+	0xb9, 0xd5, 0xc4, 0xae, 0x44, 0x9e, 0x49, 0x00, 0x3d, 0x04, 0x18, 0x67, 0x2c, 0x0c, 0x7c, 0xc6,
+	0x4f, 0xa3, 0xc1, 0xba, 0x45, 0xd6, 0x88, 0x4d, 0x39, 0x0d, 0x0e, 0xa4, 0xf0, 0x98, 0x9f, 0x46,
+	0xd8, 0x1d, 0xe7, 0x3f, 0x3d, 0x0e, 0x9d, 0xc7, 0x8c, 0x07, 0xf5, 0xe9, 0xee, 0x43, 0x73, 0xb1,
+	0xd4, 0xf2, 0xe7, 0x9b, 0xa9, 0xfd, 0x9d, 0x03, 0x5d, 0xed, 0xb0, 0x3e, 0xab, 0x05, 0x5f, 0x8d,
+	0x37, 0xf3, 0x75, 0x13, 0xd6, 0x5f, 0x91, 0x30, 0xa3, 0x2a, 0x88, 0xce, 0x83, 0x9e, 0xd2, 0x7b,
+	0x64, 0x0e, 0x13, 0xd6, 0x32, 0xf4, 0x0e, 0x74, 0xe8, 0xf7, 0x63, 0x96, 0x50, 0x5f, 0x32, 0x69,
+	0x58, 0x05, 0x0d, 0xbd, 0x60, 0x33, 0xea, 0xfd, 0xc5, 0x81, 0xde, 0xc7, 0x34, 0x61, 0xa7, 0xf3,
+	0xcb, 0x24, 0xe9, 0x3a, 0x6c, 0xcc, 0xc8, 0xc4, 0x3a, 0x08, 0xad, 0x19, 0x99, 0x3c, 0xa9, 0xb2,
+	0xb7, 0x56, 0x66, 0x0f, 0x7d, 0x06, 0xba, 0x69, 0xc8, 0x26, 0xd4, 0x8f, 0x4e, 0x4f, 0x53, 0x2a,
+	0xd4, 0x22, 0xf7, 0x70, 0x47, 0x61, 0xcf, 0x15, 0xb4, 0x50, 0x09, 0x29, 0x9f, 0x8a, 0xb3, 0x41,
+	0xcb, 0x52, 0x79, 0xaa, 0x20, 0xef, 0xc7, 0xb0, 0x99, 0x4f, 0xa8, 0xfe, 0x22, 0xf4, 0xa1, 0x39,
+	0x23, 0x93, 0x7c, 0x4a, 0x33, 0x32, 0xf9, 0x77, 0xb7, 0xb1, 0xf7, 0x27, 0x07, 0x3a, 0x16, 0xac,
+	0x12, 0x06, 0xa5, 0xc9, 0x22, 0x99, 0xb4, 0xe4, 0xe7, 0x71, 0x20, 0xa9, 0x50, 0x02, 0x4e, 0x66,
+	0x54, 0x39, 0x72, 0x71, 0x5b, 0x02, 0xcf, 0xc8, 0x8c, 0xa2, 0x4d, 0x68, 0xb0, 0x58, 0x71, 0xe7,
+	0xe2, 0x06, 0x8b, 0x11, 0x82, 0xb5, 0x38, 0x4a, 0x84, 0xa1, 0x4c, 0xfd, 0x96, 0xd8, 0x0f, 0x22,
+	0x4e, 0x15, 0x4d, 0x2e, 0x56, 0xbf, 0xd1, 0x4d, 0xe8, 0x91, 0x50, 0xf8, 0x44, 0x3b, 0xa7, 0xe9,
+	0xa0, 0xb5, 0xd7, 0xbc, 0xe5, 0xe2, 0x2e, 0x09, 0xc5, 0x7e, 0x8e, 0xa1, 0x5d, 0x68, 0xcd, 0x58,
+	0x92, 0x44, 0xc9, 0x60, 0x63, 0xcf, 0xb9, 0xd5, 0xc6, 0xe6, 0xcb, 0xfb, 0xab, 0x03, 0xdd, 0x91,
+	0x88, 0x12, 0x7a, 0x99, 0x5b, 0xe1, 0x52, 0xb6, 0xa9, 0x4c, 0x35, 0x67, 0x8c, 0x0b, 0x73, 0xee,
+	0x57, 0xa4, 0x1a, 0x29, 0x45, 0x1e, 0xf4, 0xa2, 0x84, 0x4d, 0x19, 0xf7, 0x65, 0x2a, 0x66, 0x81,
+	0xda, 0x1e, 0x5d, 0xdc, 0xd1, 0xe0, 0xf3, 0x64, 0x7a, 0x1c, 0x78, 0xbf, 0x71, 0xa0, 0x67, 0x26,
+	0x59, 0x7f, 0x7b, 0x7c, 0x0e, 0x36, 0x49, 0x98, 0x50, 0x12, 0xcc, 0xfd, 0x54, 0xda, 0x0a, 0xd4,
+	0x8c, 0xdb, 0xb8, 0x67, 0x50, 0xe5, 0x20, 0x40, 0xef, 0xc3, 0x46, 0x42, 0x27, 0x94, 0xc5, 0xc2,
+	0xcc, 0xfe, 0xba, 0x95, 0xb0, 0x4c, 0x10, 0x4a, 0x8c, 0x73, 0x3d, 0xef, 0x25, 0xc0, 0x11, 0x15,
+	0x97, 0xb9, 0x00, 0xef, 0x40, 0x47, 0xc6, 0xe4, 0x27, 0x34, 0x26, 0x2c, 0x51, 0x81, 0xb4, 0x31,
+	0x48, 0x08, 0x2b, 0xc4, 0xa3, 0xd0, 0x51, 0x2e, 0xeb, 0xd3, 0x51, 0xac, 0x71, 0xe3, 0xfc, 0x35,
+	0xf6, 0xfe, 0xe8, 0x00, 0x9c, 0x64, 0xe2, 0x7f, 0xb1, 0xb7, 0x84, 0x08, 0xfd, 0x94, 0x4e, 0x22,
+	0x1e, 0xe8, 0xa4, 0xb3, 0x86, 0x41, 0x88, 0x70, 0xa4, 0x11, 0x59, 0x78, 0xb8, 0x64, 0x27, 0x64,
+	0x13, 0x92, 0x9a, 0xa4, 0xe3, 0x72, 0x6c, 0x00, 0xef, 0xcf, 0xf2, 0x40, 0x67, 0x17, 0xe2, 0xe7,
+	0x1e, 0xb8, 0x51, 0x4c, 0x13, 0x22, 0x58, 0xc4, 0x55, 0xfc, 0x9b, 0x0f, 0xb6, 0xf5, 0x16, 0xce,
+	0xc4, 0xf3, 0x5c, 0x80, 0x17, 0x3a, 0x95, 0x90, 0x9a, 0x95, 0x90, 0xd0, 0x43, 0x68, 0x9b, 0xfd,
+	0x22, 0xe7, 0xd3, 0x7c, 0xd3, 0xc6, 0x2a, 0x14, 0xbd, 0x1f, 0x42, 0x7f, 0x94, 0x8d, 0xd3, 0x49,
+	0xc2, 0xc6, 0x17, 0x38, 0xe0, 0x5f, 0x82, 0x6e, 0xaa, 0xad, 0xc4, 0xc5, 0x6c, 0x3a, 0x66, 0x36,
+	0x23, 0x4b, 0x80, 0x4b, 0x6a, 0xde, 0xcf, 0x1c, 0xd8, 0xb6, 0xbc, 0x5f, 0x28, 0x31, 0x57, 0x36,
+	0xc1, 0xbb, 0xe5, 0x4d, 0x60, 0x72, 0x43, 0x36, 0x96, 0x54, 0xa9, 0x48, 0xcc, 0xfe, 0xfb, 0xad,
+	0x5a, 0xc7, 0x02, 0x96, 0xa5, 0x84, 0xf2, 0x57, 0x34, 0x8c, 0x62, 0xaa, 0x0a, 0x95, 0xce, 0xce,
+	0x9d, 0x1c, 0x33, 0xd5, 0x8a, 0x72, 0x91, 0xcc, 0xad, 0x76, 0xaf, 0xad, 0x00, 0x29, 0xbc, 0x0d,
+	0xdb, 0x24, 0x13, 0x67, 0x51, 0x22, 0x7b, 0xbe, 0x90, 0xd9, 0xd5, 0x6e, 0x4b, 0x0b, 0xb4, 0x37,
+	0xa3, 0x2b, 0x0f, 0x1c, 0x2d, 0xe9, 0xae, 0x69, 0x5d, 0x2d, 0x28, 0x74, 0xbd, 0x5f, 0xc9, 0x2c,
+	0x6c, 0x71, 0x87, 0x3e, 0x04, 0xb4, 0xe4, 0x28, 0x1d, 0x38, 0xd6, 0x6c, 0x0f, 0xc2, 0x28, 0x9a,
+	0x3d, 0x66, 0xa1, 0xa0, 0x09, 0xee, 0x57, 0x7c, 0xa7, 0x72, 0xfc, 0x92, 0xf3, 0x74, 0xd0, 0x38,
+	0x6f, 0x7c, 0x25, 0x9e, 0xd4, 0xfb, 0x3c, 0x74, 0x2c, 0x05, 0x34, 0x80, 0x0d, 0xca, 0x27, 0x51,
+	0x40, 0xf3, 0x82, 0x96, 0x7f, 0x7a, 0xbf, 0x77, 0xc0, 0x2d, 0xda, 0x30, 0xa9, 0xf7, 0x8a, 0x26,
+	0xa9, 0xdc, 0x24, 0x8e, 0xaa, 0x50, 0xf9, 0xa7, 0xdc, 0xdd, 0x53, 0x26, 0xfc, 0x71, 0x42, 0xf8,
+	0xe4, 0xcc, 0x94, 0x3e, 0x77, 0xca, 0xc4, 0x81, 0x02, 0xe4, 0xc2, 0x48, 0x71, 0x42, 0x5f, 0x31,
+	0x35, 0x5a, 0x57, 0xc1, 0xce, 0x94, 0x09, 0x6c, 0x20, 0x69, 0x41, 0x37, 0x83, 0x01, 0x11, 0xba,
+	0x5c, 0xb8, 0xa6, 0xed, 0x7b, 0x44, 0x04, 0x45, 0x5f, 0x80, 0xbe, 0xba, 0x4b, 0x4c, 0xa2, 0xd0,
+	0xcf, 0x63, 0xd0, 0xe7, 0x7a, 0x2b, 0xc7, 0x3f, 0xd6, 0xb0, 0xf7, 0x1a, 0xb6, 0x2a, 0xfd, 0x23,
+	0xba, 0x53, 0xea, 0x34, 0x35, 0xcf, 0x9b, 0x9a, 0xa7, 0x15, 0x3d, 0xe6, 0xf9, 0x37, 0x82, 0xff,
+	0x07, 0x37, 0x65, 0x53, 0x4e, 0x44, 0x96, 0x50, 0x33, 0x89, 0x05, 0xe0, 0x1d, 0xc1, 0x55, 0x1c,
+	0x65, 0x82, 0xf1, 0xe9, 0x0b, 0x32, 0x0e, 0xeb, 0x9f, 0x48, 0x2f, 0x83, 0x9d, 0xb2, 0xa1, 0xfa,
+	0x87, 0xeb, 0x36, 0xb4, 0xc6, 0xd9, 0xe4, 0x53, 0x2a, 0xcc, 0xee, 0x40, 0x7a, 0x80, 0xb6, 0x7e,
+	0xa0, 0x24, 0xd8, 0x68, 0x78, 0x7f, 0x70, 0xa0, 0x57, 0x92, 0xa0, 0x1d, 0x58, 0x0f, 0x68, 0x2c,
+	0xce, 0x94, 0xb7, 0x1e, 0xd6, 0x1f, 0x32, 0xfd, 0x86, 0xd1, 0x6b, 0x9a, 0xf8, 0xe3, 0x28, 0xe3,
+	0x81, 0xa1, 0x08, 0x14, 0x74, 0x20, 0x11, 0xa9, 0x90, 0xc5, 0x71, 0xa1, 0xa0, 0x4f, 0x10, 0x28,
+	0x48, 0x2b, 0xdc, 0x84, 0xde, 0x24, 0xe2, 0x82, 0x30, 0x9e, 0xfa, 0xea, 0xbe, 0xb1, 0xa6, 0x4a,
+	0x58, 0x37, 0x07, 0x47, 0xa5, 0x5b, 0xc6, 0xba, 0xd5, 0x9e, 0x99, 0xf8, 0x64, 0xa3, 0x90, 0xb7,
+	0x67, 0xff, 0x70, 0xa0, 0x63, 0xc1, 0xe8, 0x36, 0x6c, 0x98, 0x66, 0xa9, 0x74, 0xa2, 0xec, 0xde,
+	0x22, 0x57, 0x40, 0xf7, 0x61, 0x47, 0x66, 0x65, 0x4d, 0x9f, 0x9f, 0x66, 0x93, 0x89, 0xee, 0xb1,
+	0x1a, 0xaa, 0xa4, 0x20, 0x9e, 0x33, 0x3b, 0xca, 0x25, 0xf2, 0xdc, 0x5b, 0x23, 0xa8, 0xec, 0xb2,
+	0x74, 0x3a, 0x5f, 0xc3, 0x5b, 0x85, 0xfa, 0xa1, 0x82, 0xa5, 0xf5, 0x90, 0x08, 0x79, 0xef, 0x34,
+	0x96, 0x4b, 0x37, 0x21, 0xa4, 0x65, 0xc6, 0xb4, 0xbe, 0x12, 0x7d, 0x11, 0x0c, 0xaa, 0x2d, 0x1b,
+	0xfd, 0x75, 0xa5, 0xdf, 0xd7, 0x12, 0x65, 0x5b, 0x69, 0x7b, 0xdf, 0x81, 0x0d, 0x39, 0xab, 0x03,
+	0xc2, 0xcf, 0xef, 0x49, 0x77, 0xa1, 0x95, 0x50, 0x92, 0x9a, 0xbc, 0xee, 0x62, 0xf3, 0xa5, 0x72,
+	0xa5, 0x6c, 0xc6, 0xe6, 0xc6, 0x47, 0x53, 0xf9, 0xd0, 0x3d, 0xdb, 0x5c, 0x9b, 0xff, 0x1a, 0x6c,
+	0x3d, 0x65, 0xa9, 0x38, 0x20, 0x3c, 0xad, 0xbf, 0x97, 0xa7, 0xd0, 0x5f, 0x18, 0xa9, 0xbf, 0x8f,
+	0xf7, 0x60, 0x6d, 0x4c, 0x78, 0x7e, 0x83, 0xea, 0x16, 0x2b, 0x7a, 0x40, 0x38, 0x56, 0x12, 0xef,
+	0xbb, 0x00, 0xf2, 0xa3, 0x76, 0x19, 0x7c, 0x1b, 0x9a, 0x63, 0x92, 0x57, 0xbf, 0xb2, 0x03, 0x29,
+	0xf0, 0xbe, 0x0a, 0x1d, 0xf9, 0xbb, 0xfe, 0x1c, 0xbc, 0x4f, 0xa0, 0xfb, 0x11, 0x1f, 0x5f, 0x24,
+	0x46, 0x6b, 0x95, 0x1b, 0xf6, 0x2a, 0x7b, 0xdf, 0x86, 0x9e, 0x31, 0x5d, 0x9f, 0xe2, 0x81, 0x6c,
+	0x6d, 0x67, 0xd1, 0xab, 0xa2, 0xf5, 0xcd, 0x3f, 0xbd, 0x9f, 0x16, 0x97, 0x08, 0xd5, 0x78, 0xe4,
+	0x15, 0xdb, 0x59, 0x54, 0xec, 0x3d, 0xe8, 0xaa, 0xc0, 0xca, 0x69, 0x13, 0x24, 0x76, 0x52, 0xa4,
+	0x4e, 0x79, 0x11, 0x48, 0x05, 0x99, 0xc5, 0x66, 0xb3, 0x2d, 0x00, 0x99, 0xfd, 0x55, 0x49, 0xf7,
+	0xcf, 0x48, 0x7a, 0x66, 0xca, 0xa8, 0xab, 0x90, 0xaf, 0x93, 0xf4, 0xcc, 0xf3, 0x01, 0x2d, 0x37,
+	0x42, 0xe8, 0xbd, 0x45, 0x2f, 0xee, 0xd8, 0x3d, 0xcb, 0xaa, 0x2e, 0xbc, 0x9c, 0xba, 0x1b, 0xd5,
+	0xd4, 0x4d, 0xa0, 0xfd, 0x3c, 0x99, 0x7e, 0x33, 0x8b, 0x04, 0x41, 0xd7, 0xa0, 0x65, 0xee, 0x1a,
+	0x7a, 0x82, 0xeb, 0x91, 0xbc, 0x65, 0xa8, 0xac, 0xc7, 0x66, 0xb2, 0xc8, 0xcd, 0x45, 0x91, 0x21,
+	0x40, 0x41, 0x07, 0x12, 0x91, 0x73, 0xc8, 0x52, 0x1a, 0x18, 0xb9, 0x4e, 0x09, 0xae, 0x44, 0x94,
+	0xd8, 0x3b, 0x84, 0x6d, 0x79, 0x10, 0x94, 0x8f, 0x0b, 0x9c, 0x27, 0x0e, 0xc8, 0x36, 0x73, 0x91,
+	0x0b, 0x4f, 0xeb, 0xa5, 0x32, 0x62, 0xce, 0x94, 0x6e, 0xb5, 0x73, 0x16, 0xb0, 0x11, 0x7a, 0xbf,
+	0x76, 0x60, 0x6b, 0x44, 0xb5, 0xbf, 0xfa, 0x1b, 0x77, 0xc1, 0x69, 0xe3, 0x0d, 0x9c, 0x36, 0x57,
+	0x71, 0x9a, 0xd0, 0x94, 0x0a, 0x5f, 0xf2, 0x68, 0xaa, 0x84, 0xab, 0x90, 0x8f, 0x52, 0x1a, 0xc8,
+	0xd7, 0xbe, 0x45, 0x6c, 0x17, 0xba, 0xec, 0xa8, 0xd9, 0x96, 0x2e, 0x3b, 0x05, 0x13, 0x5a, 0xe6,
+	0xfd, 0xdc, 0x81, 0xce, 0x68, 0xce, 0x27, 0xf5, 0x49, 0xb8, 0x70, 0xdd, 0x94, 0x31, 0x6c, 0x3e,
+	0xa1, 0x73, 0x4c, 0xf8, 0x94, 0x3e, 0x62, 0x53, 0x19, 0x46, 0xc5, 0xa8, 0xf3, 0xaf, 0x8c, 0x36,
+	0x96, 0x8a, 0xf1, 0x35, 0x68, 0x71, 0xdd, 0x40, 0xea, 0x5b, 0xc9, 0x3a, 0x57, 0x3d, 0xe6, 0x2e,
+	0xb4, 0x02, 0xe5, 0xc2, 0x1c, 0x47, 0xf3, 0xe5, 0xfd, 0x42, 0x36, 0xb3, 0x8a, 0x87, 0xfa, 0x84,
+	0xbf, 0x07, 0xad, 0x44, 0xce, 0x21, 0xdf, 0x7b, 0x57, 0xd5, 0x80, 0xf2, 0xcc, 0xb0, 0x51, 0x91,
+	0x8f, 0x22, 0x26, 0xba, 0xe6, 0xad, 0x2e, 0x56, 0xbf, 0xbd, 0x63, 0x70, 0xf7, 0xc3, 0x30, 0x7a,
+	0x2d, 0x8f, 0x02, 0xba, 0x01, 0x6d, 0x93, 0x77, 0x64, 0xc5, 0x97, 0x4a, 0x1b, 0xba, 0x5f, 0x53,
+	0xef, 0x4f, 0x2c, 0x4d, 0x33, 0x1a, 0x98, 0x2a, 0xd7, 0xd0, 0x55, 0x4e, 0x63, 0xba, 0xca, 0x15,
+	0xed, 0xe2, 0xc2, 0xe0, 0x1d, 0x00, 0x22, 0x3f, 0xfc, 0x90, 0xa5, 0xa2, 0xd4, 0x2e, 0x16, 0x3a,
+	0xd8, 0x25, 0x85, 0x7a, 0xfd, 0x76, 0xf1, 0x88, 0x8a, 0x85, 0xc5, 0xda, 0x29, 0xe1, 0x27, 0xb0,
+	0x53, 0x36, 0x54, 0x7f, 0x61, 0x1e, 0x96, 0x66, 0xde, 0x58, 0x7a, 0x92, 0x5d, 0x35, 0x7f, 0xef,
+	0x47, 0x70, 0x75, 0x74, 0x19, 0x13, 0xa9, 0xe7, 0xfd, 0x18, 0x76, 0x46, 0x97, 0x33, 0x7b, 0xef,
+	0x04, 0xe0, 0x84, 0xf1, 0x4b, 0x7c, 0xce, 0x90, 0x4d, 0x83, 0xb2, 0x58, 0x3f, 0x26, 0x2c, 0x9b,
+	0x86, 0xf8, 0x72, 0xa3, 0x3a, 0x80, 0x9e, 0xb1, 0x59, 0x3b, 0xae, 0xdb, 0xfb, 0xd0, 0xb5, 0x9f,
+	0x3a, 0x10, 0x40, 0x6b, 0xf4, 0xe2, 0x39, 0x3e, 0x7c, 0xd4, 0xbf, 0x82, 0xb6, 0xa1, 0xf7, 0xf4,
+	0xf0, 0xf1, 0x0b, 0xff, 0xf0, 0x5b, 0xc7, 0xa3, 0x17, 0xc7, 0xcf, 0x8e, 0xfa, 0x0e, 0x42, 0xb0,
+	0xb9, 0xff, 0x14, 0x1f, 0xee, 0x3f, 0xfa, 0xc4, 0x37, 0x6a, 0x8d, 0x07, 0x7f, 0xdf, 0x00, 0xf7,
+	0x69, 0xfe, 0xc7, 0x11, 0xfa, 0x32, 0xb8, 0xc5, 0x5f, 0x18, 0x48, 0xbb, 0xaf, 0xfe, 0xb9, 0x32,
+	0xdc, 0xad, 0xc2, 0x3a, 0x3c, 0xef, 0x0a, 0xba, 0x03, 0x6b, 0xf2, 0x95, 0x1e, 0xe9, 0x5e, 0xdf,
+	0xfa, 0x87, 0x60, 0xb8, 0x6d, 0x21, 0x85, 0xfa, 0x43, 0x68, 0xe9, 0x17, 0x65, 0xa4, 0x2f, 0x44,
+	0xa5, 0xf7, 0xf2, 0xe1, 0xd5, 0x12, 0x56, 0x0c, 0xba, 0x0f, 0xeb, 0xaa, 0xb7, 0x40, 0xa5, 0x3e,
+	0x43, 0x0f, 0x41, 0x36, 0x54, 0x8c, 0xb8, 0x0d, 0xcd, 0x23, 0x2a, 0xd0, 0x96, 0x12, 0x2e, 0x1e,
+	0x01, 0x87, 0xfd, 0x05, 0x60, 0xeb, 0x9e, 0x64, 0xb9, 0xee, 0x49, 0x56, 0xd1, 0xb5, 0x9e, 0xab,
+	0xbc, 0x2b, 0xe8, 0x43, 0x70, 0x8b, 0xa7, 0x17, 0xc3, 0x55, 0xf5, 0x21, 0x68, 0xb8, 0x5b, 0x85,
+	0xf3, 0xd1, 0xf7, 0x1d, 0xc9, 0x96, 0x4c, 0xe1, 0x86, 0x2d, 0xab, 0xaa, 0x0d, 0xb7, 0x2d, 0xa4,
+	0x70, 0x77, 0x04, 0x5d, 0xfb, 0x3e, 0x8a, 0x06, 0xf6, 0x55, 0xcc, 0xbe, 0xeb, 0x0e, 0x6f, 0xac,
+	0x90, 0x58, 0x7e, 0x3f, 0x80, 0x76, 0x7e, 0x19, 0x40, 0x7a, 0xdb, 0x56, 0x2e, 0x18, 0xc3, 0x6b,
+	0x15, 0xd4, 0xa6, 0x47, 0xde, 0x73, 0x34, 0x3d, 0x8b, 0x46, 0x7f, 0xd8, 0x5f, 0x00, 0xf6, 0x42,
+	0xa9, 0x6e, 0xd8, 0x2c, 0x94, 0xdd, 0x74, 0x0f, 0x91, 0x0d, 0x15, 0x23, 0xbe, 0x02, 0xb0, 0xe8,
+	0xaa, 0xd0, 0x6e, 0x11, 0x44, 0xa9, 0x5b, 0x1b, 0x5e, 0x5f, 0xc2, 0x0b, 0x03, 0x1f, 0x40, 0x3b,
+	0xef, 0x44, 0xcc, 0xcc, 0x2a, 0x4d, 0xd3, 0xf0, 0x5a, 0x05, 0x2d, 0x86, 0x1e, 0x42, 0xd7, 0x4e,
+	0xdf, 0x86, 0xdd, 0x15, 0xa5, 0x61, 0x78, 0x63, 0x85, 0xc4, 0x36, 0x33, 0x5a, 0x36, 0x33, 0x3a,
+	0xd7, 0xcc, 0x68, 0xb5, 0x19, 0xb9, 0x0d, 0x59, 0xce, 0xf3, 0x09, 0xab, 0xf0, 0x7c, 0xc2, 0x96,
+	0x78, 0x8e, 0xd9, 0x82, 0xe7, 0x98, 0x2d, 0xf1, 0x1c, 0xdb, 0x23, 0xc6, 0x2d, 0xf5, 0x58, 0xf3,
+	0xf0, 0x9f, 0x03, 0x00, 0x3b, 0x89, 0x49, 0x5c, 0x55, 0x1e, 0x00, 0x00,
 }
@@ -19,6 +19,7 @@ func TestEndpoint_String(t *testing.T) {
 		"get":       {value: Get, expected: "Get"},
 		"put":       {value: Put, expected: "Put"},
 		"subscribe": {value: Subscribe, expected: "Subscribe"},
+		"sync":      {value: Sync, expected: "Sync"},
 	}
 	for desc, c := range cases {
 		assert.Equal(t, c.expected, c.value.String(), desc)
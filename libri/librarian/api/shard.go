@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/golang/protobuf/proto"
+)
+
+// MaxShards is the maximum total number of data and parity shards a document may be split into.
+const MaxShards = 256
+
+// ShardKeyPrefix is the prefix of all keys in the reserved shard keyspace. Only shard documents
+// may be stored under such keys, and shard documents may only be stored under them.
+var ShardKeyPrefix = []byte{0x00, 0x00, 0x00, 0x01}
+
+// ShardManifestKeyPrefix is the prefix of all keys in the reserved shard manifest keyspace. Only
+// shard manifest documents may be stored under such keys, and shard manifest documents may only be
+// stored under them.
+var ShardManifestKeyPrefix = []byte{0x00, 0x00, 0x00, 0x02}
+
+var (
+	// ErrReservedShardKey indicates when a document other than a shard has a key in the reserved
+	// shard keyspace.
+	ErrReservedShardKey = errors.New("key is reserved for shard documents")
+
+	// ErrMissingShard indicates when a shard is unexpectedly missing.
+	ErrMissingShard = errors.New("missing shard")
+
+	// ErrZeroDataShards indicates when a shard has zero data shards.
+	ErrZeroDataShards = errors.New("number of data shards is zero")
+
+	// ErrTooManyShards indicates when a shard has more than MaxShards total shards.
+	ErrTooManyShards = errors.New("total number of shards exceeds max")
+
+	// ErrShardIndexOutOfRange indicates when a shard's index is not less than the total number
+	// of shards.
+	ErrShardIndexOutOfRange = errors.New("shard index out of range")
+
+	// ErrShardSizeOutOfRange indicates when a shard's original document size is zero or
+	// larger than the data shards can hold (e.g., because the shard data is empty).
+	ErrShardSizeOutOfRange = errors.New("shard original document size out of range")
+
+	// ErrReservedShardManifestKey indicates when a document other than a shard manifest has a
+	// key in the reserved shard manifest keyspace.
+	ErrReservedShardManifestKey = errors.New("key is reserved for shard manifest documents")
+
+	// ErrMissingShardManifest indicates when a shard manifest is unexpectedly missing.
+	ErrMissingShardManifest = errors.New("missing shard manifest")
+
+	// ErrUnexpectedNShardKeys indicates when a shard manifest doesn't have one shard key per
+	// shard.
+	ErrUnexpectedNShardKeys = errors.New("number of shard keys differs from number of shards")
+
+	// ErrInvalidShardKey indicates when a shard manifest's shard key isn't in the shard keyspace.
+	ErrInvalidShardKey = errors.New("invalid shard key")
+)
+
+// IsShardKey returns whether the key is in the reserved shard keyspace.
+func IsShardKey(key id.ID) bool {
+	return bytes.HasPrefix(key.Bytes(), ShardKeyPrefix)
+}
+
+// IsShardManifestKey returns whether the key is in the reserved shard manifest keyspace.
+func IsShardManifestKey(key id.ID) bool {
+	return bytes.HasPrefix(key.Bytes(), ShardManifestKeyPrefix)
+}
+
+// GetShardKey returns the key of the shard. It is the shard keyspace prefix followed by the leading
+// bytes of the SHA256 hash of the marshaled shard, so the key binds the shard's contents.
+func GetShardKey(shard *Shard) id.ID {
+	shardBytes, err := proto.Marshal(shard)
+	cerrors.MaybePanic(err) // should never happen
+	return prefixedKey(ShardKeyPrefix, shardBytes)
+}
+
+// GetShardManifestKey returns the key of the shard manifest of the document with the given key.
+// It is the shard manifest keyspace prefix followed by the leading bytes of the SHA256 hash of the
+// document key.
+func GetShardManifestKey(originalKey []byte) id.ID {
+	return prefixedKey(ShardManifestKeyPrefix, originalKey)
+}
+
+func prefixedKey(prefix []byte, value []byte) id.ID {
+	hash := sha256.Sum256(value)
+	key := make([]byte, 0, id.Length)
+	key = append(key, prefix...)
+	key = append(key, hash[:id.Length-len(prefix)]...)
+	return id.FromBytes(key)
+}
+
+// ValidateShard checks that all fields of a Shard are populated and consistent with each other.
+func ValidateShard(s *Shard) error {
+	if s == nil {
+		return ErrMissingShard
+	}
+	if err := ValidateBytes(s.OriginalKey, DocumentKeyLength, "OriginalKey"); err != nil {
+		return err
+	}
+	if s.NDataShards == 0 {
+		return ErrZeroDataShards
+	}
+	nShards := uint64(s.NDataShards) + uint64(s.NParityShards)
+	if nShards > MaxShards {
+		return ErrTooManyShards
+	}
+	if uint64(s.Index) >= nShards {
+		return ErrShardIndexOutOfRange
+	}
+	// parity shard data may legitimately be all zeros, so only check it can hold the document
+	if s.Size == 0 || s.Size > uint64(s.NDataShards)*uint64(len(s.Data)) {
+		return ErrShardSizeOutOfRange
+	}
+	return nil
+}
+
+// ValidateShardManifest checks that all fields of a ShardManifest are populated and consistent
+// with each other.
+func ValidateShardManifest(m *ShardManifest) error {
+	if m == nil {
+		return ErrMissingShardManifest
+	}
+	if err := ValidateBytes(m.OriginalKey, DocumentKeyLength, "OriginalKey"); err != nil {
+		return err
+	}
+	if m.NDataShards == 0 {
+		return ErrZeroDataShards
+	}
+	nShards := uint64(m.NDataShards) + uint64(m.NParityShards)
+	if nShards > MaxShards {
+		return ErrTooManyShards
+	}
+	if m.Size == 0 {
+		return ErrShardSizeOutOfRange
+	}
+	if uint64(len(m.ShardKeys)) != nShards {
+		return ErrUnexpectedNShardKeys
+	}
+	for _, shardKey := range m.ShardKeys {
+		if len(shardKey) != id.Length || !IsShardKey(id.FromBytes(shardKey)) {
+			return ErrInvalidShardKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsShardKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	_, contentKey := NewTestDocument(rng)
+	assert.False(t, IsShardKey(contentKey))
+	assert.False(t, IsShardKey(id.LowerBound))
+
+	_, shardKey := NewTestShardDocument(rng)
+	assert.True(t, IsShardKey(shardKey))
+	assert.False(t, IsSystemKey(shardKey))
+}
+
+func TestIsShardManifestKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	_, contentKey := NewTestDocument(rng)
+	assert.False(t, IsShardManifestKey(contentKey))
+
+	_, shardKey := NewTestShardDocument(rng)
+	assert.False(t, IsShardManifestKey(shardKey))
+
+	_, manifestKey := NewTestShardManifestDocument(rng)
+	assert.True(t, IsShardManifestKey(manifestKey))
+	assert.False(t, IsShardKey(manifestKey))
+	assert.False(t, IsSystemKey(manifestKey))
+}
+
+func TestGetShardKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := NewTestShardDocument(rng)
+	shard := doc.GetShard()
+
+	key := GetShardKey(shard)
+	assert.True(t, IsShardKey(key))
+	assert.Len(t, key.Bytes(), id.Length)
+
+	// deterministic
+	assert.Equal(t, key, GetShardKey(shard))
+
+	// binds the shard contents
+	shard.Data = RandBytes(rng, 64)
+	assert.NotEqual(t, key, GetShardKey(shard))
+}
+
+func TestGetShardManifestKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	origKey1, origKey2 := RandBytes(rng, id.Length), RandBytes(rng, id.Length)
+
+	key := GetShardManifestKey(origKey1)
+	assert.True(t, IsShardManifestKey(key))
+	assert.Len(t, key.Bytes(), id.Length)
+	assert.Equal(t, key, GetShardManifestKey(origKey1))
+	assert.NotEqual(t, key, GetShardManifestKey(origKey2))
+}
+
+func TestGetKey_shard(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := NewTestShardDocument(rng)
+	key, err := GetKey(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, GetShardKey(doc.GetShard()), key)
+
+	doc, _ = NewTestShardManifestDocument(rng)
+	key, err = GetKey(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, GetShardManifestKey(doc.GetShardManifest().OriginalKey), key)
+}
+
+func TestValidateShard_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := NewTestShardDocument(rng)
+	assert.Nil(t, ValidateDocument(doc))
+
+	// all-zero parity data is fine
+	doc.GetShard().Data = make([]byte, 64)
+	assert.Nil(t, ValidateShard(doc.GetShard()))
+}
+
+func TestValidateShard_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	assert.Equal(t, ErrMissingShard, ValidateShard(nil))
+
+	cases := []func(s *Shard){
+		func(s *Shard) { s.OriginalKey = nil },                // 0) can't be nil
+		func(s *Shard) { s.OriginalKey = RandBytes(rng, 16) }, // 1) length must be 32
+		func(s *Shard) { s.NDataShards = 0 },                  // 2) must have data shards
+		func(s *Shard) { s.NParityShards = MaxShards },        // 3) too many shards
+		func(s *Shard) { s.Index = 3 },                        // 4) index out of range
+		func(s *Shard) { s.Size = 0 },                         // 5) size can't be zero
+		func(s *Shard) { s.Size = 129 },                       // 6) size too big for shards
+		func(s *Shard) { s.Data = nil },                       // 7) data can't be empty
+	}
+	for i, c := range cases {
+		doc, _ := NewTestShardDocument(rng)
+		c(doc.GetShard())
+		assert.NotNil(t, ValidateShard(doc.GetShard()), i)
+	}
+}
+
+func TestValidateShardManifest_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := NewTestShardManifestDocument(rng)
+	assert.Nil(t, ValidateDocument(doc))
+}
+
+func TestValidateShardManifest_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	assert.Equal(t, ErrMissingShardManifest, ValidateShardManifest(nil))
+
+	cases := []func(m *ShardManifest){
+		func(m *ShardManifest) { m.OriginalKey = nil },                 // 0) can't be nil
+		func(m *ShardManifest) { m.OriginalKey = RandBytes(rng, 16) },  // 1) length must be 32
+		func(m *ShardManifest) { m.NDataShards = 0 },                   // 2) must have data shards
+		func(m *ShardManifest) { m.NParityShards = MaxShards },         // 3) too many shards
+		func(m *ShardManifest) { m.Size = 0 },                          // 4) size can't be zero
+		func(m *ShardManifest) { m.ShardKeys = m.ShardKeys[:2] },       // 5) one key per shard
+		func(m *ShardManifest) { m.ShardKeys[0] = RandBytes(rng, 16) }, // 6) key length
+		func(m *ShardManifest) { m.ShardKeys[1] = m.OriginalKey },      // 7) not a shard key
+	}
+	for i, c := range cases {
+		doc, _ := NewTestShardManifestDocument(rng)
+		c(doc.GetShardManifest())
+		assert.NotNil(t, ValidateShardManifest(doc.GetShardManifest()), i)
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/golang/protobuf/proto"
+)
+
+// SystemKeyPrefix is the prefix of all keys in the reserved system keyspace. Only system
+// documents may be stored under such keys, and system documents may only be stored under them.
+// Since content keys are hashes, the chance of application content landing in the reserved
+// keyspace is 2^-32.
+var SystemKeyPrefix = []byte{0x00, 0x00, 0x00, 0x00}
+
+var (
+	// ErrReservedKey indicates when a document other than a system document has a key in the
+	// reserved system keyspace.
+	ErrReservedKey = errors.New("key is reserved for system documents")
+
+	// ErrMissingSystemDocument indicates when a system document is unexpectedly missing.
+	ErrMissingSystemDocument = errors.New("missing system document")
+
+	// ErrMissingSystemRecord indicates when a system document is missing its record.
+	ErrMissingSystemRecord = errors.New("missing system record")
+
+	// ErrUnknownSystemDocumentType indicates when a system record has an unknown type.
+	ErrUnknownSystemDocumentType = errors.New("unknown system document type")
+
+	// ErrMissingSignature indicates when a system document is missing its signature.
+	ErrMissingSignature = errors.New("missing signature")
+
+	// ErrUnexpectedChecksum indicates when a system document's checksum doesn't match its record.
+	ErrUnexpectedChecksum = errors.New("checksum does not match record")
+
+	// ErrStaleSystemDocument indicates when a system document doesn't have a greater sequence
+	// number than the different version already stored under its key.
+	ErrStaleSystemDocument = errors.New("system document sequence is not newer than stored")
+)
+
+// IsSystemKey returns whether the key is in the reserved system keyspace.
+func IsSystemKey(key id.ID) bool {
+	return bytes.HasPrefix(key.Bytes(), SystemKeyPrefix)
+}
+
+// GetSystemKey returns the key of the system document with the given type, signer public key, and
+// name. It is the system keyspace prefix followed by the leading bytes of the SHA256 hash of
+// those fields.
+func GetSystemKey(docType SystemDocumentType, pubKey []byte, name []byte) id.ID {
+	typeBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(typeBytes, uint32(docType))
+	hasher := sha256.New()
+	_, _ = hasher.Write(typeBytes) // sha256 Write always returns nil error
+	_, _ = hasher.Write(pubKey)
+	_, _ = hasher.Write(name)
+	hash := hasher.Sum(nil)
+
+	key := make([]byte, 0, id.Length)
+	key = append(key, SystemKeyPrefix...)
+	key = append(key, hash[:id.Length-len(SystemKeyPrefix)]...)
+	return id.FromBytes(key)
+}
+
+// ValidateSystemDocument checks that all fields of a SystemDocument are populated and have the
+// expected lengths. It does not verify the signature.
+func ValidateSystemDocument(d *SystemDocument) error {
+	if d == nil {
+		return ErrMissingSystemDocument
+	}
+	if d.Record == nil {
+		return ErrMissingSystemRecord
+	}
+	if _, in := SystemDocumentType_name[int32(d.Record.Type)]; !in ||
+		d.Record.Type == SystemDocumentType_SYSTEM_DOCUMENT_TYPE_UNSPECIFIED {
+		return ErrUnknownSystemDocumentType
+	}
+	if err := ValidateNotEmpty(d.Record.Name, "Name"); err != nil {
+		return err
+	}
+	if err := ValidatePublicKey(d.PubKey); err != nil {
+		return err
+	}
+	if d.Signature == "" {
+		return ErrMissingSignature
+	}
+	return ValidateBytes(d.Checksum, sha256.Size, "Checksum")
+}
+
+// GetSystemChecksum returns the SHA256 hash of the serialized system record.
+func GetSystemChecksum(r *SystemRecord) ([]byte, error) {
+	recordBytes, err := proto.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(recordBytes)
+	return hash[:], nil
+}
+
+// CheckSystemChecksum checks that the system document's checksum matches its record.
+func CheckSystemChecksum(d *SystemDocument) error {
+	checksum, err := GetSystemChecksum(d.Record)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(checksum, d.Checksum) {
+		return ErrUnexpectedChecksum
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSystemKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	_, contentKey := NewTestDocument(rng)
+	assert.False(t, IsSystemKey(contentKey))
+	assert.False(t, IsSystemKey(id.UpperBound))
+	assert.True(t, IsSystemKey(id.LowerBound))
+
+	_, sysKey := NewTestSystemDocument(rng)
+	assert.True(t, IsSystemKey(sysKey))
+}
+
+func TestGetSystemKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pubKey1, pubKey2 := fakePubKey(rng), fakePubKey(rng)
+	name1, name2 := []byte("name1"), []byte("name2")
+
+	key := GetSystemKey(SystemDocumentType_ORG_PROFILE, pubKey1, name1)
+	assert.True(t, IsSystemKey(key))
+	assert.Len(t, key.Bytes(), id.Length)
+
+	// deterministic
+	assert.Equal(t, key, GetSystemKey(SystemDocumentType_ORG_PROFILE, pubKey1, name1))
+
+	// differs by each field
+	assert.NotEqual(t, key, GetSystemKey(SystemDocumentType_POINTER_RECORD, pubKey1, name1))
+	assert.NotEqual(t, key, GetSystemKey(SystemDocumentType_ORG_PROFILE, pubKey2, name1))
+	assert.NotEqual(t, key, GetSystemKey(SystemDocumentType_ORG_PROFILE, pubKey1, name2))
+}
+
+func TestGetKey_system(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := NewTestSystemDocument(rng)
+	sys := doc.GetSystem()
+	key, err := GetKey(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, GetSystemKey(sys.Record.Type, sys.PubKey, sys.Record.Name), key)
+
+	// signature and contents don't affect key
+	sys.Signature = "other.signature.token"
+	sys.Record.Contents = RandBytes(rng, 64)
+	key2, err := GetKey(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, key, key2)
+}
+
+func TestValidateSystemDocument_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := NewTestSystemDocument(rng)
+	assert.Nil(t, ValidateSystemDocument(doc.GetSystem()))
+}
+
+func TestValidateSystemDocument_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	cases := map[string]func(d *SystemDocument){
+		"missing record": func(d *SystemDocument) { d.Record = nil },
+		"unknown type":   func(d *SystemDocument) { d.Record.Type = SystemDocumentType(-1) },
+		"unset type": func(d *SystemDocument) {
+			d.Record.Type = SystemDocumentType_SYSTEM_DOCUMENT_TYPE_UNSPECIFIED
+		},
+		"empty name":   func(d *SystemDocument) { d.Record.Name = nil },
+		"bad pub key":  func(d *SystemDocument) { d.PubKey = []byte{1, 2, 3} },
+		"no signature": func(d *SystemDocument) { d.Signature = "" },
+		"no checksum":  func(d *SystemDocument) { d.Checksum = nil },
+	}
+	for desc, mutate := range cases {
+		doc, _ := NewTestSystemDocument(rng)
+		mutate(doc.GetSystem())
+		assert.NotNil(t, ValidateSystemDocument(doc.GetSystem()), desc)
+	}
+	assert.Equal(t, ErrMissingSystemDocument, ValidateSystemDocument(nil))
+}
+
+func TestCheckSystemChecksum(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	doc, _ := NewTestSystemDocument(rng)
+	sys := doc.GetSystem()
+	assert.Nil(t, CheckSystemChecksum(sys))
+
+	// any change to the record, e.g., from corruption, changes the checksum
+	sys.Record.Sequence++
+	assert.Equal(t, ErrUnexpectedChecksum, CheckSystemChecksum(sys))
+}
@@ -16,6 +16,25 @@ func NewTestDocument(rng *rand.Rand) (*Document, id.ID) {
 	return doc, key
 }
 
+// NewTestSystemDocument generates a dummy (unsigned) System document for use in testing.
+func NewTestSystemDocument(rng *rand.Rand) (*Document, id.ID) {
+	doc := &Document{&Document_System{&SystemDocument{
+		Record: &SystemRecord{
+			Type:     SystemDocumentType_POINTER_RECORD,
+			Name:     RandBytes(rng, 16),
+			Contents: RandBytes(rng, 64),
+		},
+		PubKey:    fakePubKey(rng),
+		Signature: "dummy.signature.token",
+	}}}
+	checksum, err := GetSystemChecksum(doc.GetSystem().Record)
+	errors.MaybePanic(err)
+	doc.GetSystem().Checksum = checksum
+	key, err := GetKey(doc)
+	errors.MaybePanic(err)
+	return doc, key
+}
+
 // NewTestEnvelope generates a dummy Envelope document for use in testing.
 func NewTestEnvelope(rng *rand.Rand) *Envelope {
 	return &Envelope{
@@ -83,3 +102,38 @@ func RandBytes(rng *rand.Rand, length int) []byte {
 func fakePubKey(rng *rand.Rand) []byte {
 	return RandBytes(rng, ECPubKeyLength)
 }
+
+// NewTestShardDocument generates a dummy Shard document for use in testing.
+func NewTestShardDocument(rng *rand.Rand) (*Document, id.ID) {
+	doc := &Document{&Document_Shard{&Shard{
+		OriginalKey:   RandBytes(rng, DocumentKeyLength),
+		Index:         1,
+		NDataShards:   2,
+		NParityShards: 1,
+		Size:          100,
+		Data:          RandBytes(rng, 64),
+	}}}
+	key, err := GetKey(doc)
+	errors.MaybePanic(err)
+	return doc, key
+}
+
+// NewTestShardManifestDocument generates a dummy ShardManifest document for use in testing.
+func NewTestShardManifestDocument(rng *rand.Rand) (*Document, id.ID) {
+	shardKeys := make([][]byte, 3)
+	for i := range shardKeys {
+		shardDoc, shardKey := NewTestShardDocument(rng)
+		shardDoc.GetShard().Index = uint32(i)
+		shardKeys[i] = shardKey.Bytes()
+	}
+	doc := &Document{&Document_ShardManifest{&ShardManifest{
+		OriginalKey:   RandBytes(rng, DocumentKeyLength),
+		NDataShards:   2,
+		NParityShards: 1,
+		Size:          100,
+		ShardKeys:     shardKeys,
+	}}}
+	key, err := GetKey(doc)
+	errors.MaybePanic(err)
+	return doc, key
+}
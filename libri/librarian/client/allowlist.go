@@ -0,0 +1,39 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+)
+
+// ErrMissingAllowList indicates when a signed allow-list is missing its allow-list.
+var ErrMissingAllowList = errors.New("signed allow-list missing allow-list")
+
+// NewSignedAllowList signs the allow-list with the admin signer, whose public key is pubKey.
+func NewSignedAllowList(
+	signer Signer, pubKey []byte, list *api.AllowList,
+) (*api.SignedAllowList, error) {
+	signature, err := signer.Sign(list)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SignedAllowList{
+		AllowList: list,
+		PubKey:    pubKey,
+		Signature: signature,
+	}, nil
+}
+
+// VerifySignedAllowList verifies that the allow-list was signed by the key with the included
+// public key.
+func VerifySignedAllowList(v Verifier, sal *api.SignedAllowList) error {
+	if sal.AllowList == nil {
+		return ErrMissingAllowList
+	}
+	pubKey, err := ecid.FromPublicKeyBytes(sal.PubKey)
+	if err != nil {
+		return err
+	}
+	return v.Verify(sal.Signature, pubKey, sal.AllowList)
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSignedAllowList_VerifySignedAllowList_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, peerID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	list := &api.AllowList{PubKeys: [][]byte{peerID.PublicKeyBytes()}, IssuedNanos: 1}
+
+	sal, err := NewSignedAllowList(NewECDSASigner(adminID.Key()), adminID.PublicKeyBytes(), list)
+	assert.Nil(t, err)
+	assert.Equal(t, list, sal.AllowList)
+	assert.NotEmpty(t, sal.Signature)
+	assert.Nil(t, VerifySignedAllowList(NewVerifier(), sal))
+}
+
+func TestNewSignedAllowList_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID := ecid.NewPseudoRandom(rng)
+	sal, err := NewSignedAllowList(&TestErrSigner{}, adminID.PublicKeyBytes(), &api.AllowList{})
+	assert.NotNil(t, err)
+	assert.Nil(t, sal)
+}
+
+func TestVerifySignedAllowList_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID1, adminID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	list := &api.AllowList{PubKeys: [][]byte{adminID2.PublicKeyBytes()}, IssuedNanos: 1}
+	v := NewVerifier()
+
+	// missing allow-list
+	err := VerifySignedAllowList(v, &api.SignedAllowList{PubKey: adminID1.PublicKeyBytes()})
+	assert.Equal(t, ErrMissingAllowList, err)
+
+	// bad public key
+	sal, err := NewSignedAllowList(NewECDSASigner(adminID1.Key()), []byte{1, 2, 3}, list)
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySignedAllowList(v, sal))
+
+	// signed by another key
+	sal, err = NewSignedAllowList(NewECDSASigner(adminID2.Key()), adminID1.PublicKeyBytes(), list)
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySignedAllowList(v, sal))
+
+	// allow-list changed after signing
+	sal, err = NewSignedAllowList(NewECDSASigner(adminID1.Key()), adminID1.PublicKeyBytes(), list)
+	assert.Nil(t, err)
+	sal.AllowList = &api.AllowList{IssuedNanos: 2}
+	assert.NotNil(t, VerifySignedAllowList(v, sal))
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrStoreAuthExpired indicates when a store authorization token has expired.
+	ErrStoreAuthExpired = errors.New("store authorization token expired")
+
+	// ErrStoreAuthWrongOrg indicates when a store authorization token was issued to an
+	// organization different from the one making the request.
+	ErrStoreAuthWrongOrg = errors.New("store authorization token issued to different " +
+		"organization")
+
+	errStoreAuthMissingOrg = errors.New("store authorization token missing organization")
+)
+
+// StoreAuthClaims holds the claims of a token authorizing an organization to make Store and Put
+// requests to librarians that require it.
+type StoreAuthClaims struct {
+	// OrgPubKey is the base-64-url encoded public key of the authorized organization
+	OrgPubKey string `json:"org_pub_key"`
+
+	// ExpiresAt is the Unix time after which the token is no longer valid, or zero if it never
+	// expires
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+// Valid returns whether the claims are valid or invalid via an error.
+func (c *StoreAuthClaims) Valid() error {
+	if c.OrgPubKey == "" {
+		return errStoreAuthMissingOrg
+	}
+	if c.ExpiresAt != 0 && time.Now().Unix() > c.ExpiresAt {
+		return ErrStoreAuthExpired
+	}
+	return nil
+}
+
+// NewStoreAuthToken returns a new store authorization token (in the form of an encoded json web
+// token) for the organization with the given public key, signed by the librarian operator's key. A
+// zero expiresAt value means the token never expires.
+func NewStoreAuthToken(
+	operatorKey *ecdsa.PrivateKey, orgPubKey []byte, expiresAt time.Time,
+) (string, error) {
+	claims := &StoreAuthClaims{
+		OrgPubKey: base64.URLEncoding.EncodeToString(orgPubKey),
+	}
+	if !expiresAt.IsZero() {
+		claims.ExpiresAt = expiresAt.Unix()
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	return token.SignedString(operatorKey)
+}
+
+// StoreAuthVerifier verifies store authorization tokens.
+type StoreAuthVerifier interface {
+	// Verify verifies that the encoded token has been signed by the operator and issued to the
+	// organization with the given public key.
+	Verify(encToken string, orgPubKey []byte) error
+}
+
+type ecdsaStoreAuthVerifier struct {
+	operatorPubKey *ecdsa.PublicKey
+}
+
+// NewStoreAuthVerifier returns a new StoreAuthVerifier for tokens signed by the given operator
+// public key.
+func NewStoreAuthVerifier(operatorPubKey *ecdsa.PublicKey) StoreAuthVerifier {
+	return &ecdsaStoreAuthVerifier{operatorPubKey: operatorPubKey}
+}
+
+func (v *ecdsaStoreAuthVerifier) Verify(encToken string, orgPubKey []byte) error {
+	token, err := jwt.ParseWithClaims(encToken, &StoreAuthClaims{}, func(token *jwt.Token) (
+		interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return v.operatorPubKey, nil
+	})
+	if err != nil {
+		// received error when parsing claims or verifying signature
+		return err
+	}
+	claims, ok := token.Claims.(*StoreAuthClaims)
+	if !ok {
+		return fmt.Errorf("token claims %v are not expected StoreAuthClaims", token.Claims)
+	}
+	claimedOrgPubKey, err := base64.URLEncoding.DecodeString(claims.OrgPubKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(claimedOrgPubKey, orgPubKey) {
+		return ErrStoreAuthWrongOrg
+	}
+	return nil
+}
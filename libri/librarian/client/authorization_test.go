@@ -0,0 +1,63 @@
+package client
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreAuthClaims_Valid(t *testing.T) {
+	assert.Nil(t, (&StoreAuthClaims{OrgPubKey: "some-key"}).Valid())
+	assert.Nil(t, (&StoreAuthClaims{
+		OrgPubKey: "some-key",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}).Valid())
+
+	assert.Equal(t, errStoreAuthMissingOrg, (&StoreAuthClaims{}).Valid())
+	assert.Equal(t, ErrStoreAuthExpired, (&StoreAuthClaims{
+		OrgPubKey: "some-key",
+		ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+	}).Valid())
+}
+
+func TestNewStoreAuthTokenVerify_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	operatorID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	v := NewStoreAuthVerifier(&operatorID.Key().PublicKey)
+
+	for _, expiresAt := range []time.Time{{}, time.Now().Add(time.Hour)} {
+		token, err := NewStoreAuthToken(operatorID.Key(), orgID.PublicKeyBytes(), expiresAt)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, token)
+		assert.Nil(t, v.Verify(token, orgID.PublicKeyBytes()))
+	}
+}
+
+func TestNewStoreAuthTokenVerify_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	operatorID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	otherID := ecid.NewPseudoRandom(rng)
+	v := NewStoreAuthVerifier(&operatorID.Key().PublicKey)
+
+	// signed by different key
+	token, err := NewStoreAuthToken(otherID.Key(), orgID.PublicKeyBytes(), time.Time{})
+	assert.Nil(t, err)
+	assert.NotNil(t, v.Verify(token, orgID.PublicKeyBytes()))
+
+	// issued to different org
+	token, err = NewStoreAuthToken(operatorID.Key(), otherID.PublicKeyBytes(), time.Time{})
+	assert.Nil(t, err)
+	assert.NotNil(t, v.Verify(token, orgID.PublicKeyBytes()))
+
+	// expired
+	expiresAt := time.Now().Add(-time.Hour)
+	token, err = NewStoreAuthToken(operatorID.Key(), orgID.PublicKeyBytes(), expiresAt)
+	assert.Nil(t, err)
+	assert.NotNil(t, v.Verify(token, orgID.PublicKeyBytes()))
+
+	// malformed
+	assert.NotNil(t, v.Verify("not.a.token", orgID.PublicKeyBytes()))
+}
@@ -149,6 +149,7 @@ func NewSetBalancer(libAddrs []*net.TCPAddr, clients Pool, rng *rand.Rand) (SetB
 func (b *setRandBalancer) AddNext() (api.LibrarianClient, string, error) {
 	b.mu.Lock()
 	if len(b.available) == 0 {
+		b.mu.Unlock()
 		return nil, "", ErrNoNewClients
 	}
 	i := b.rng.Intn(len(b.available))
@@ -167,7 +168,6 @@ func (b *setRandBalancer) AddNext() (api.LibrarianClient, string, error) {
 func (b *setRandBalancer) Remove(address string) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.available = append(b.available, address)
 	if _, in := b.set[address]; !in {
 		return ErrClientMissingFromSet
 	}
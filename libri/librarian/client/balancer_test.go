@@ -148,10 +148,18 @@ func TestSetRandBalancer_AddNext_err(t *testing.T) {
 	assert.NotEmpty(t, addr)
 
 	// no more addresses
-	lc2, addr, err = sb.AddNext()
+	lc3, addr3, err := sb.AddNext()
 	assert.Equal(t, ErrNoNewClients, err)
-	assert.Nil(t, lc2)
-	assert.Empty(t, addr)
+	assert.Nil(t, lc3)
+	assert.Empty(t, addr3)
+
+	// address available again once removed
+	assert.Nil(t, sb.Remove(addr))
+	assert.Equal(t, []string{addr}, sb.(*setRandBalancer).available)
+	lc3, addr3, err = sb.AddNext()
+	assert.Nil(t, err)
+	assert.NotNil(t, lc3)
+	assert.Equal(t, addr, addr3)
 }
 
 func TestSetRandBalancer_Remove_err(t *testing.T) {
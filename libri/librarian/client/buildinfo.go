@@ -0,0 +1,71 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+// buildInfoKey is the response header key for the marshaled api.SignedBuildInfo, whose -bin
+// suffix tells gRPC the value is binary.
+const buildInfoKey = "libri-build-info-bin"
+
+var (
+	// ErrMissingBuildInfo indicates when signed build info is missing its build info.
+	ErrMissingBuildInfo = errors.New("signed build info missing build info")
+
+	errHeaderMissingBuildInfo = errors.New("header build info key does not exist")
+)
+
+// NewSignedBuildInfo signs the build info with the peer signer, whose public key is pubKey.
+func NewSignedBuildInfo(
+	signer Signer, pubKey []byte, info *api.BuildInfo,
+) (*api.SignedBuildInfo, error) {
+	signature, err := signer.Sign(info)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SignedBuildInfo{
+		BuildInfo: info,
+		PubKey:    pubKey,
+		Signature: signature,
+	}, nil
+}
+
+// VerifySignedBuildInfo verifies that the build info was signed by the key with the included
+// public key.
+func VerifySignedBuildInfo(v Verifier, sbi *api.SignedBuildInfo) error {
+	if sbi.BuildInfo == nil {
+		return ErrMissingBuildInfo
+	}
+	pubKey, err := ecid.FromPublicKeyBytes(sbi.PubKey)
+	if err != nil {
+		return err
+	}
+	return v.Verify(sbi.Signature, pubKey, sbi.BuildInfo)
+}
+
+// NewBuildInfoHeader returns response header metadata containing the signed build info.
+func NewBuildInfoHeader(sbi *api.SignedBuildInfo) (metadata.MD, error) {
+	sbiBytes, err := proto.Marshal(sbi)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Pairs(buildInfoKey, string(sbiBytes)), nil
+}
+
+// FromBuildInfoHeader extracts the signed build info from response header metadata.
+func FromBuildInfoHeader(md metadata.MD) (*api.SignedBuildInfo, error) {
+	values, exists := md[buildInfoKey]
+	if !exists || len(values) == 0 {
+		return nil, errHeaderMissingBuildInfo
+	}
+	sbi := &api.SignedBuildInfo{}
+	if err := proto.Unmarshal([]byte(values[0]), sbi); err != nil {
+		return nil, err
+	}
+	return sbi, nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNewSignedBuildInfo_VerifySignedBuildInfo_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	info := &api.BuildInfo{Version: "0.1.0", GitRevision: "abcdef", ProtocolVersion: 1}
+
+	sbi, err := NewSignedBuildInfo(NewECDSASigner(peerID.Key()), peerID.PublicKeyBytes(), info)
+	assert.Nil(t, err)
+	assert.Equal(t, info, sbi.BuildInfo)
+	assert.NotEmpty(t, sbi.Signature)
+	assert.Nil(t, VerifySignedBuildInfo(NewVerifier(), sbi))
+}
+
+func TestNewSignedBuildInfo_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	sbi, err := NewSignedBuildInfo(&TestErrSigner{},
+		peerID.PublicKeyBytes(), &api.BuildInfo{})
+	assert.NotNil(t, err)
+	assert.Nil(t, sbi)
+}
+
+func TestVerifySignedBuildInfo_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID1, peerID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	info := &api.BuildInfo{Version: "0.1.0", GitRevision: "abcdef", ProtocolVersion: 1}
+	v := NewVerifier()
+
+	// missing build info
+	err := VerifySignedBuildInfo(v, &api.SignedBuildInfo{PubKey: peerID1.PublicKeyBytes()})
+	assert.Equal(t, ErrMissingBuildInfo, err)
+
+	// bad public key
+	sbi, err := NewSignedBuildInfo(NewECDSASigner(peerID1.Key()), []byte{1, 2, 3}, info)
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySignedBuildInfo(v, sbi))
+
+	// signed by another key
+	sbi, err = NewSignedBuildInfo(NewECDSASigner(peerID2.Key()), peerID1.PublicKeyBytes(), info)
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySignedBuildInfo(v, sbi))
+
+	// build info changed after signing
+	sbi, err = NewSignedBuildInfo(NewECDSASigner(peerID1.Key()), peerID1.PublicKeyBytes(), info)
+	assert.Nil(t, err)
+	sbi.BuildInfo = &api.BuildInfo{Version: "9.9.9", GitRevision: "abcdef", ProtocolVersion: 1}
+	assert.NotNil(t, VerifySignedBuildInfo(v, sbi))
+}
+
+func TestNewBuildInfoHeader_FromBuildInfoHeader(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	info := &api.BuildInfo{Version: "0.1.0", GitRevision: "abcdef", ProtocolVersion: 1}
+	sbi1, err := NewSignedBuildInfo(NewECDSASigner(peerID.Key()), peerID.PublicKeyBytes(), info)
+	assert.Nil(t, err)
+
+	md, err := NewBuildInfoHeader(sbi1)
+	assert.Nil(t, err)
+	sbi2, err := FromBuildInfoHeader(md)
+	assert.Nil(t, err)
+	assert.Equal(t, sbi1, sbi2)
+	assert.Nil(t, VerifySignedBuildInfo(NewVerifier(), sbi2))
+}
+
+func TestFromBuildInfoHeader_err(t *testing.T) {
+	sbi, err := FromBuildInfoHeader(metadata.MD{})
+	assert.Equal(t, errHeaderMissingBuildInfo, err)
+	assert.Nil(t, sbi)
+
+	sbi, err = FromBuildInfoHeader(metadata.Pairs(buildInfoKey, "not a proto"))
+	assert.NotNil(t, err)
+	assert.Nil(t, sbi)
+}
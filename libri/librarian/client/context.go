@@ -12,6 +12,8 @@ import (
 const (
 	signatureKey    = "signature"
 	orgSignatureKey = "orgsignature"
+	storeAuthKey    = "storeauth"
+	macaroonKey     = "macaroon"
 )
 
 var (
@@ -20,6 +22,14 @@ var (
 		"exist")
 	errContextMissingOrgSignature = errors.New("metadata organization signature key " +
 		"unexpectedly does not exist")
+
+	// ErrContextMissingStoreAuth indicates when the context is missing a store authorization
+	// token.
+	ErrContextMissingStoreAuth = errors.New("metadata store authorization token key does not " +
+		"exist")
+
+	// ErrContextMissingMacaroon indicates when the context is missing a macaroon.
+	ErrContextMissingMacaroon = errors.New("metadata macaroon key does not exist")
 )
 
 // NewSignatureContext creates a new context with the signed JSON web token (JWT) string.
@@ -62,9 +72,81 @@ func FromSignatureContext(ctx context.Context) (string, string, error) {
 	return signedJWTs[0], signedOrgJWTs[0], nil
 }
 
+// AddStoreAuthContext adds the store authorization token to the context's outgoing metadata. An
+// empty token leaves the context unchanged.
+func AddStoreAuthContext(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, storeAuthKey, token)
+}
+
+// NewIncomingStoreAuthContext creates a new context with the store authorization token in the
+// incoming metadata field, alongside any existing incoming metadata. This function should only be
+// used for testing.
+func NewIncomingStoreAuthContext(ctx context.Context, token string) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+	return metadata.NewIncomingContext(ctx, metadata.Join(md, metadata.Pairs(storeAuthKey, token)))
+}
+
+// FromStoreAuthContext extracts the store authorization token from the context.
+func FromStoreAuthContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errContextMissingMetadata
+	}
+	tokens, exists := md[storeAuthKey]
+	if !exists || len(tokens) == 0 {
+		return "", ErrContextMissingStoreAuth
+	}
+	return tokens[0], nil
+}
+
+// AddMacaroonContext adds the encoded macaroon to the context's outgoing metadata. An empty
+// macaroon leaves the context unchanged.
+func AddMacaroonContext(ctx context.Context, encMacaroon string) context.Context {
+	if encMacaroon == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, macaroonKey, encMacaroon)
+}
+
+// NewIncomingMacaroonContext creates a new context with the encoded macaroon in the incoming
+// metadata field, alongside any existing incoming metadata. This function should only be used for
+// testing.
+func NewIncomingMacaroonContext(ctx context.Context, encMacaroon string) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+	return metadata.NewIncomingContext(ctx,
+		metadata.Join(md, metadata.Pairs(macaroonKey, encMacaroon)))
+}
+
+// FromMacaroonContext extracts the encoded macaroon from the context.
+func FromMacaroonContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errContextMissingMetadata
+	}
+	macaroons, exists := md[macaroonKey]
+	if !exists || len(macaroons) == 0 {
+		return "", ErrContextMissingMacaroon
+	}
+	return macaroons[0], nil
+}
+
 // NewSignedContext creates a new context with a request signature.
 func NewSignedContext(signer, orgSigner Signer, request proto.Message) (context.Context, error) {
-	ctx := context.Background()
+	return newSignedContextFrom(context.Background(), signer, orgSigner, request)
+}
+
+func newSignedContextFrom(
+	ctx context.Context, signer, orgSigner Signer, request proto.Message,
+) (context.Context, error) {
 
 	// sign the message
 	signedJWT, err := signer.Sign(request)
@@ -85,7 +167,19 @@ func NewSignedTimeoutContext(
 ) (
 	context.Context, context.CancelFunc, error) {
 
-	ctx, err := NewSignedContext(signer, orgSigner, request)
+	return NewSignedTimeoutContextFrom(context.Background(), signer, orgSigner, request, timeout)
+}
+
+// NewSignedTimeoutContextFrom creates a new context with a timeout and request signature derived
+// from the parent context, whose cancellation and deadline still apply.
+func NewSignedTimeoutContextFrom(
+	parent context.Context,
+	signer, orgSigner Signer,
+	request proto.Message,
+	timeout time.Duration,
+) (context.Context, context.CancelFunc, error) {
+
+	ctx, err := newSignedContextFrom(parent, signer, orgSigner, request)
 	if err != nil {
 		return nil, func() {}, err
 	}
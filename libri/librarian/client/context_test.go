@@ -93,3 +93,98 @@ func TestNewSignedTimeoutContext_err(t *testing.T) {
 	assert.NotNil(t, cancel)
 	assert.NotNil(t, err)
 }
+
+func TestNewSignedTimeoutContextFrom(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	rq := NewFindRequest(
+		ecid.NewPseudoRandom(rng),
+		ecid.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng),
+		20,
+	)
+	parent, parentCancel := context.WithCancel(context.Background())
+	ctx, cancel, err := NewSignedTimeoutContextFrom(parent, &TestNoOpSigner{},
+		&TestNoOpSigner{}, rq, 5*time.Second)
+	assert.Nil(t, err)
+	defer cancel()
+	_, in := metadata.FromOutgoingContext(ctx)
+	assert.True(t, in)
+
+	// canceling parent cancels child
+	assert.Nil(t, ctx.Err())
+	parentCancel()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	ctx, cancel, err = NewSignedTimeoutContextFrom(context.Background(), &TestErrSigner{},
+		&TestErrSigner{}, rq, 5*time.Second)
+	assert.Nil(t, ctx)
+	assert.NotNil(t, cancel)
+	assert.NotNil(t, err)
+}
+
+func TestAddStoreAuthContext(t *testing.T) {
+	ctx := NewSignatureContext(context.Background(), "some.signed.token", "")
+	token := "some.store-auth.token"
+	ctx = AddStoreAuthContext(ctx, token)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, []string{token}, md[storeAuthKey])
+	assert.Equal(t, []string{"some.signed.token"}, md[signatureKey])
+
+	// empty token leaves context unchanged
+	ctx = AddStoreAuthContext(context.Background(), "")
+	_, ok = metadata.FromOutgoingContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestFromStoreAuthContext(t *testing.T) {
+	token1 := "some.store-auth.token"
+	ctx := NewIncomingSignatureContext(context.Background(), "some.signed.token", "")
+	ctx = NewIncomingStoreAuthContext(ctx, token1)
+	token2, err := FromStoreAuthContext(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, token1, token2)
+
+	token2, err = FromStoreAuthContext(context.Background())
+	assert.NotNil(t, err)
+	assert.Zero(t, token2)
+
+	ctx = NewIncomingSignatureContext(context.Background(), "some.signed.token", "")
+	token2, err = FromStoreAuthContext(ctx)
+	assert.Equal(t, ErrContextMissingStoreAuth, err)
+	assert.Zero(t, token2)
+}
+
+func TestAddMacaroonContext(t *testing.T) {
+	ctx := NewSignatureContext(context.Background(), "some.signed.token", "")
+	encMacaroon := "some-macaroon"
+	ctx = AddMacaroonContext(ctx, encMacaroon)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, []string{encMacaroon}, md[macaroonKey])
+	assert.Equal(t, []string{"some.signed.token"}, md[signatureKey])
+
+	// empty macaroon leaves context unchanged
+	ctx = AddMacaroonContext(context.Background(), "")
+	_, ok = metadata.FromOutgoingContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestFromMacaroonContext(t *testing.T) {
+	encMacaroon1 := "some-macaroon"
+	ctx := NewIncomingSignatureContext(context.Background(), "some.signed.token", "")
+	ctx = NewIncomingMacaroonContext(ctx, encMacaroon1)
+	encMacaroon2, err := FromMacaroonContext(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, encMacaroon1, encMacaroon2)
+
+	encMacaroon2, err = FromMacaroonContext(context.Background())
+	assert.NotNil(t, err)
+	assert.Zero(t, encMacaroon2)
+
+	ctx = NewIncomingSignatureContext(context.Background(), "some.signed.token", "")
+	encMacaroon2, err = FromMacaroonContext(ctx)
+	assert.Equal(t, ErrContextMissingMacaroon, err)
+	assert.Zero(t, encMacaroon2)
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Interceptors are client-side gRPC interceptors applied to every connection dialed to a
+// librarian, so embedding applications can add their own metrics, auth headers, and tracing.
+// Interceptors are called in order, with the first being the outermost.
+type Interceptors struct {
+	// Unary are the interceptors of unary requests, e.g., Store and Get.
+	Unary []grpc.UnaryClientInterceptor
+
+	// Stream are the interceptors of streaming requests, e.g., Subscribe.
+	Stream []grpc.StreamClientInterceptor
+
+	// Credentials optionally secure every connection, e.g., with mutual TLS (see
+	// NewTLSCredentials). Connections are insecure when nil.
+	Credentials credentials.TransportCredentials
+}
+
+// DialOptions returns the options for dialing a librarian with the interceptors. A nil
+// *Interceptors adds no interceptors and dials insecurely.
+func (i *Interceptors) DialOptions() []grpc.DialOption {
+	if i == nil {
+		return []grpc.DialOption{grpc.WithInsecure()}
+	}
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if i.Credentials != nil {
+		opts[0] = grpc.WithTransportCredentials(i.Credentials)
+	}
+	if len(i.Unary) > 0 {
+		opts = append(opts, grpc.WithUnaryInterceptor(chainUnary(i.Unary)))
+	}
+	if len(i.Stream) > 0 {
+		opts = append(opts, grpc.WithStreamInterceptor(chainStream(i.Stream)))
+	}
+	return opts
+}
+
+// WithCredentials returns the interceptors dialing with the given transport credentials, if they
+// aren't nil, leaving the given interceptors unmodified.
+func WithCredentials(
+	interceptors *Interceptors, creds credentials.TransportCredentials,
+) *Interceptors {
+	if creds == nil {
+		return interceptors
+	}
+	secured := &Interceptors{}
+	if interceptors != nil {
+		*secured = *interceptors
+	}
+	secured.Credentials = creds
+	return secured
+}
+
+// chainUnary combines the unary interceptors into one, since a connection only takes one.
+func chainUnary(interceptors []grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		chained := invoker
+		for j := len(interceptors) - 1; j >= 0; j-- {
+			interceptor, next := interceptors[j], chained
+			chained = func(
+				ctx context.Context,
+				method string,
+				req, reply interface{},
+				cc *grpc.ClientConn,
+				opts ...grpc.CallOption,
+			) error {
+				return interceptor(ctx, method, req, reply, cc, next, opts...)
+			}
+		}
+		return chained(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// chainStream combines the stream interceptors into one, since a connection only takes one.
+func chainStream(interceptors []grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		chained := streamer
+		for j := len(interceptors) - 1; j >= 0; j-- {
+			interceptor, next := interceptors[j], chained
+			chained = func(
+				ctx context.Context,
+				desc *grpc.StreamDesc,
+				cc *grpc.ClientConn,
+				method string,
+				opts ...grpc.CallOption,
+			) (grpc.ClientStream, error) {
+				return interceptor(ctx, desc, cc, method, next, opts...)
+			}
+		}
+		return chained(ctx, desc, cc, method, opts...)
+	}
+}
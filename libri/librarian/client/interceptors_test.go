@@ -0,0 +1,137 @@
+package client
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func TestInterceptors_DialOptions(t *testing.T) {
+	var i *Interceptors
+	assert.Len(t, i.DialOptions(), 1)
+
+	i = &Interceptors{}
+	assert.Len(t, i.DialOptions(), 1)
+
+	i.Unary = []grpc.UnaryClientInterceptor{noOpUnaryInterceptor}
+	assert.Len(t, i.DialOptions(), 2)
+
+	i.Stream = []grpc.StreamClientInterceptor{noOpStreamInterceptor}
+	assert.Len(t, i.DialOptions(), 3)
+
+	i.Credentials = credentials.NewTLS(&tls.Config{})
+	assert.Len(t, i.DialOptions(), 3)
+}
+
+func TestWithCredentials(t *testing.T) {
+	interceptors := &Interceptors{Unary: []grpc.UnaryClientInterceptor{noOpUnaryInterceptor}}
+
+	// no credentials added when nil
+	assert.Equal(t, interceptors, WithCredentials(interceptors, nil))
+
+	creds := credentials.NewTLS(&tls.Config{})
+	secured := WithCredentials(nil, creds)
+	assert.Equal(t, creds, secured.Credentials)
+
+	// credentials added without modifying existing interceptors
+	secured = WithCredentials(interceptors, creds)
+	assert.Equal(t, creds, secured.Credentials)
+	assert.Len(t, secured.Unary, 1)
+	assert.Nil(t, interceptors.Credentials)
+}
+
+func TestChainUnary(t *testing.T) {
+	calls := make([]string, 0)
+	newInterceptor := func(name string) grpc.UnaryClientInterceptor {
+		return func(
+			ctx context.Context,
+			method string,
+			req, reply interface{},
+			cc *grpc.ClientConn,
+			invoker grpc.UnaryInvoker,
+			opts ...grpc.CallOption,
+		) error {
+			calls = append(calls, name)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	}
+	invoker := func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		opts ...grpc.CallOption,
+	) error {
+		calls = append(calls, method)
+		return nil
+	}
+	chained := chainUnary([]grpc.UnaryClientInterceptor{
+		newInterceptor("first"),
+		newInterceptor("second"),
+	})
+
+	err := chained(context.Background(), "/api.Librarian/Store", nil, nil, nil, invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second", "/api.Librarian/Store"}, calls)
+}
+
+func TestChainStream(t *testing.T) {
+	calls := make([]string, 0)
+	newInterceptor := func(name string) grpc.StreamClientInterceptor {
+		return func(
+			ctx context.Context,
+			desc *grpc.StreamDesc,
+			cc *grpc.ClientConn,
+			method string,
+			streamer grpc.Streamer,
+			opts ...grpc.CallOption,
+		) (grpc.ClientStream, error) {
+			calls = append(calls, name)
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+	}
+	streamer := func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		calls = append(calls, method)
+		return nil, nil
+	}
+	chained := chainStream([]grpc.StreamClientInterceptor{
+		newInterceptor("first"),
+		newInterceptor("second"),
+	})
+
+	_, err := chained(context.Background(), nil, nil, "/api.Librarian/Subscribe", streamer)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second", "/api.Librarian/Subscribe"}, calls)
+}
+
+func noOpUnaryInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func noOpStreamInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return streamer(ctx, desc, cc, method, opts...)
+}
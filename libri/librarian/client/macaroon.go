@@ -0,0 +1,223 @@
+package client
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MacaroonOpPut authorizes Put requests of entries and pages.
+	MacaroonOpPut = "put"
+
+	// MacaroonOpGet authorizes Get requests.
+	MacaroonOpGet = "get"
+
+	// MacaroonOpShare authorizes Put requests of envelopes, which share entries with readers.
+	MacaroonOpShare = "share"
+
+	opsCaveatKey     = "ops"
+	orgCaveatKey     = "org"
+	expiresCaveatKey = "expires"
+
+	caveatSep        = " = "
+	macaroonIDLength = 16
+)
+
+var (
+	// ErrMacaroonBadSignature indicates when a macaroon wasn't issued with the root key or has
+	// been tampered with.
+	ErrMacaroonBadSignature = errors.New("macaroon signature invalid")
+
+	// ErrMacaroonExpired indicates when a macaroon has expired.
+	ErrMacaroonExpired = errors.New("macaroon expired")
+
+	// ErrMacaroonWrongOrg indicates when a macaroon was scoped to an organization different from
+	// the one making the request.
+	ErrMacaroonWrongOrg = errors.New("macaroon scoped to different organization")
+
+	// ErrMacaroonOp indicates when a macaroon doesn't authorize the requested operation.
+	ErrMacaroonOp = errors.New("macaroon does not authorize operation")
+
+	// ErrMacaroonUnknownCaveat indicates when a macaroon has a caveat the librarian doesn't know
+	// how to check, which it must therefore reject.
+	ErrMacaroonUnknownCaveat = errors.New("macaroon has unknown caveat")
+)
+
+// Macaroon is a bearer token authorizing the operations allowed by its caveats, e.g., Put and Get
+// requests for a particular organization. It is issued with a root key shared by the issuer and
+// the librarians, which verify it offline. Since each caveat's signature chains from the previous
+// one, any holder may further restrict a macaroon by adding caveats, but none may remove them.
+type Macaroon struct {
+	// ID is the random identifier the macaroon was issued with
+	ID []byte `json:"id"`
+
+	// Caveats are the conditions under which the macaroon authorizes requests, all of which must
+	// be satisfied
+	Caveats []string `json:"caveats"`
+
+	// Signature is the HMAC chained from the root key through the ID and each caveat
+	Signature []byte `json:"sig"`
+}
+
+// NewMacaroon returns a new macaroon with the given ID and caveats issued with the root key.
+func NewMacaroon(rootKey, id []byte, caveats ...string) *Macaroon {
+	m := &Macaroon{
+		ID:        id,
+		Caveats:   make([]string, 0, len(caveats)),
+		Signature: macaroonHMAC(rootKey, id),
+	}
+	for _, caveat := range caveats {
+		m.AddCaveat(caveat)
+	}
+	return m
+}
+
+// AddCaveat restricts the macaroon with the given caveat, which needs no root key.
+func (m *Macaroon) AddCaveat(caveat string) {
+	m.Caveats = append(m.Caveats, caveat)
+	m.Signature = macaroonHMAC(m.Signature, []byte(caveat))
+}
+
+// Encode returns the base-64-url encoded JSON of the macaroon.
+func (m *Macaroon) Encode() (string, error) {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(buf), nil
+}
+
+// DecodeMacaroon decodes a macaroon encoded with Encode.
+func DecodeMacaroon(encMacaroon string) (*Macaroon, error) {
+	buf, err := base64.URLEncoding.DecodeString(encMacaroon)
+	if err != nil {
+		return nil, err
+	}
+	m := &Macaroon{}
+	if err := json.Unmarshal(buf, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OpsCaveat returns a caveat restricting a macaroon to the given operations (e.g., MacaroonOpGet).
+func OpsCaveat(ops ...string) string {
+	return opsCaveatKey + caveatSep + strings.Join(ops, ",")
+}
+
+// OrgCaveat returns a caveat restricting a macaroon to requests from the organization with the
+// given public key.
+func OrgCaveat(orgPubKey []byte) string {
+	return orgCaveatKey + caveatSep + base64.URLEncoding.EncodeToString(orgPubKey)
+}
+
+// ExpiresCaveat returns a caveat restricting a macaroon to requests before the given time.
+func ExpiresCaveat(expiresAt time.Time) string {
+	return expiresCaveatKey + caveatSep + strconv.FormatInt(expiresAt.Unix(), 10)
+}
+
+// NewMacaroonToken returns a new encoded macaroon issued with the root key authorizing the
+// organization with the given public key to perform the given operations. A zero expiresAt value
+// means the macaroon never expires.
+func NewMacaroonToken(
+	rootKey, orgPubKey []byte, ops []string, expiresAt time.Time,
+) (string, error) {
+	id := make([]byte, macaroonIDLength)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	caveats := []string{OrgCaveat(orgPubKey), OpsCaveat(ops...)}
+	if !expiresAt.IsZero() {
+		caveats = append(caveats, ExpiresCaveat(expiresAt))
+	}
+	return NewMacaroon(rootKey, id, caveats...).Encode()
+}
+
+// MacaroonVerifier verifies macaroons.
+type MacaroonVerifier interface {
+	// Verify verifies that the encoded macaroon was issued with the root key and authorizes the
+	// organization with the given public key to perform the operation.
+	Verify(encMacaroon string, op string, orgPubKey []byte) error
+}
+
+type hmacMacaroonVerifier struct {
+	rootKey []byte
+	now     func() time.Time
+}
+
+// NewMacaroonVerifier returns a new MacaroonVerifier for macaroons issued with the given root key.
+func NewMacaroonVerifier(rootKey []byte) MacaroonVerifier {
+	return &hmacMacaroonVerifier{rootKey: rootKey, now: time.Now}
+}
+
+func (v *hmacMacaroonVerifier) Verify(encMacaroon string, op string, orgPubKey []byte) error {
+	m, err := DecodeMacaroon(encMacaroon)
+	if err != nil {
+		return err
+	}
+	sig := macaroonHMAC(v.rootKey, m.ID)
+	for _, caveat := range m.Caveats {
+		sig = macaroonHMAC(sig, []byte(caveat))
+	}
+	if !hmac.Equal(sig, m.Signature) {
+		return ErrMacaroonBadSignature
+	}
+	for _, caveat := range m.Caveats {
+		if err := v.check(caveat, op, orgPubKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns an error if the caveat isn't satisfied by the request.
+func (v *hmacMacaroonVerifier) check(caveat string, op string, orgPubKey []byte) error {
+	parts := strings.SplitN(caveat, caveatSep, 2)
+	if len(parts) != 2 {
+		return ErrMacaroonUnknownCaveat
+	}
+	switch key, value := parts[0], parts[1]; key {
+	case opsCaveatKey:
+		for _, allowed := range strings.Split(value, ",") {
+			if allowed == op {
+				return nil
+			}
+		}
+		return ErrMacaroonOp
+	case orgCaveatKey:
+		allowed, err := base64.URLEncoding.DecodeString(value)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(allowed, orgPubKey) {
+			return ErrMacaroonWrongOrg
+		}
+		return nil
+	case expiresCaveatKey:
+		expiresAt, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid macaroon expiry %q: %v", value, err)
+		}
+		if v.now().Unix() > expiresAt {
+			return ErrMacaroonExpired
+		}
+		return nil
+	default:
+		return ErrMacaroonUnknownCaveat
+	}
+}
+
+func macaroonHMAC(key, msg []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(msg)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMacaroonToken_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rootKey := []byte("some root key")
+	orgPubKey := ecid.NewPseudoRandom(rng).PublicKeyBytes()
+	v := NewMacaroonVerifier(rootKey)
+
+	ops := []string{MacaroonOpPut, MacaroonOpShare}
+	encMacaroon, err := NewMacaroonToken(rootKey, orgPubKey, ops, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Nil(t, v.Verify(encMacaroon, MacaroonOpPut, orgPubKey))
+	assert.Nil(t, v.Verify(encMacaroon, MacaroonOpShare, orgPubKey))
+
+	// never expires
+	encMacaroon, err = NewMacaroonToken(rootKey, orgPubKey, ops, time.Time{})
+	assert.Nil(t, err)
+	m, err := DecodeMacaroon(encMacaroon)
+	assert.Nil(t, err)
+	assert.Len(t, m.Caveats, 2)
+	assert.Nil(t, v.Verify(encMacaroon, MacaroonOpPut, orgPubKey))
+}
+
+func TestMacaroonVerifier_Verify_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rootKey := []byte("some root key")
+	orgPubKey := ecid.NewPseudoRandom(rng).PublicKeyBytes()
+	otherOrgPubKey := ecid.NewPseudoRandom(rng).PublicKeyBytes()
+	v := NewMacaroonVerifier(rootKey)
+	ops := []string{MacaroonOpGet}
+	encMacaroon, err := NewMacaroonToken(rootKey, orgPubKey, ops, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+
+	// bad encoding
+	assert.NotNil(t, v.Verify("not a macaroon", MacaroonOpGet, orgPubKey))
+
+	// wrong root key
+	otherV := NewMacaroonVerifier([]byte("other root key"))
+	assert.Equal(t, ErrMacaroonBadSignature, otherV.Verify(encMacaroon, MacaroonOpGet, orgPubKey))
+
+	// wrong op
+	assert.Equal(t, ErrMacaroonOp, v.Verify(encMacaroon, MacaroonOpPut, orgPubKey))
+
+	// wrong org
+	assert.Equal(t, ErrMacaroonWrongOrg, v.Verify(encMacaroon, MacaroonOpGet, otherOrgPubKey))
+
+	// expired
+	v.(*hmacMacaroonVerifier).now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	assert.Equal(t, ErrMacaroonExpired, v.Verify(encMacaroon, MacaroonOpGet, orgPubKey))
+	v.(*hmacMacaroonVerifier).now = time.Now
+
+	// removed caveat
+	m, err := DecodeMacaroon(encMacaroon)
+	assert.Nil(t, err)
+	m.Caveats = m.Caveats[:1]
+	encMacaroon, err = m.Encode()
+	assert.Nil(t, err)
+	assert.Equal(t, ErrMacaroonBadSignature, v.Verify(encMacaroon, MacaroonOpGet, orgPubKey))
+
+	// unknown caveats
+	for _, caveat := range []string{"some caveat", "region = us-east1"} {
+		m = NewMacaroon(rootKey, []byte("some ID"), caveat)
+		encMacaroon, err = m.Encode()
+		assert.Nil(t, err)
+		assert.Equal(t, ErrMacaroonUnknownCaveat, v.Verify(encMacaroon, MacaroonOpGet, orgPubKey))
+	}
+
+	// bad caveat values
+	for _, caveat := range []string{"org = not base64!", "expires = never"} {
+		m = NewMacaroon(rootKey, []byte("some ID"), caveat)
+		encMacaroon, err = m.Encode()
+		assert.Nil(t, err)
+		assert.NotNil(t, v.Verify(encMacaroon, MacaroonOpGet, orgPubKey))
+	}
+}
+
+func TestMacaroon_AddCaveat(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rootKey := []byte("some root key")
+	orgPubKey := ecid.NewPseudoRandom(rng).PublicKeyBytes()
+	v := NewMacaroonVerifier(rootKey)
+	m := NewMacaroon(rootKey, []byte("some ID"), OrgCaveat(orgPubKey),
+		OpsCaveat(MacaroonOpPut, MacaroonOpGet))
+	encMacaroon, err := m.Encode()
+	assert.Nil(t, err)
+	assert.Nil(t, v.Verify(encMacaroon, MacaroonOpPut, orgPubKey))
+
+	// holder restricts macaroon to Get requests without root key
+	m.AddCaveat(OpsCaveat(MacaroonOpGet))
+	encMacaroon, err = m.Encode()
+	assert.Nil(t, err)
+	assert.Nil(t, v.Verify(encMacaroon, MacaroonOpGet, orgPubKey))
+	assert.Equal(t, ErrMacaroonOp, v.Verify(encMacaroon, MacaroonOpPut, orgPubKey))
+}
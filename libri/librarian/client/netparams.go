@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	// ErrUnexpectedNetworkParameters indicates when a document isn't the network parameters
+	// published under the expected public key.
+	ErrUnexpectedNetworkParameters = errors.New("document is not the expected network " +
+		"parameters")
+
+	// ErrNoNetworkParameters indicates when none of the librarians returned the network
+	// parameters.
+	ErrNoNetworkParameters = errors.New("no librarians returned the network parameters")
+)
+
+// NetworkParametersName is the name of the network parameters system document published by
+// network maintainers under their public key.
+var NetworkParametersName = []byte("network-parameters")
+
+// GetNetworkParametersKey returns the well-known key of the network parameters published under
+// the given maintainer public key.
+func GetNetworkParametersKey(pubKey []byte) id.ID {
+	return api.GetSystemKey(api.SystemDocumentType_NETWORK_PARAMETERS, pubKey,
+		NetworkParametersName)
+}
+
+// NewNetworkParametersDocument returns the network parameters signed by the signer, whose public
+// key is pubKey, and wrapped in a system document stored under GetNetworkParametersKey(pubKey).
+func NewNetworkParametersDocument(
+	signer Signer, pubKey []byte, params *api.NetworkParameters,
+) (*api.Document, error) {
+	contents, err := proto.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	record := &api.SystemRecord{
+		Type:     api.SystemDocumentType_NETWORK_PARAMETERS,
+		Name:     NetworkParametersName,
+		Contents: contents,
+	}
+	return NewSystemDocument(signer, pubKey, record)
+}
+
+// ParseNetworkParametersDocument verifies that the document contains network parameters signed by
+// the key with the given public key and returns them.
+func ParseNetworkParametersDocument(
+	v Verifier, pubKey []byte, doc *api.Document,
+) (*api.NetworkParameters, error) {
+	sd := doc.GetSystem()
+	if sd == nil || sd.Record == nil ||
+		sd.Record.Type != api.SystemDocumentType_NETWORK_PARAMETERS ||
+		!bytes.Equal(sd.Record.Name, NetworkParametersName) ||
+		!bytes.Equal(sd.PubKey, pubKey) {
+		return nil, ErrUnexpectedNetworkParameters
+	}
+	if err := VerifySystemDocument(v, sd); err != nil {
+		return nil, err
+	}
+	params := &api.NetworkParameters{}
+	if err := proto.Unmarshal(sd.Record.Contents, params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// NetworkParametersGetter gets the network parameters published under a maintainer public key.
+type NetworkParametersGetter interface {
+	// Get returns the most recently issued of the verified network parameters returned by the
+	// librarians, so a librarian with stale parameters can't override newer ones.
+	Get(lcs []api.Getter) (*api.NetworkParameters, error)
+}
+
+type networkParametersGetter struct {
+	peerID    ecid.ID
+	orgID     ecid.ID
+	signer    Signer
+	orgSigner Signer
+	verifier  Verifier
+	pubKey    []byte
+	timeout   time.Duration
+}
+
+// NewNetworkParametersGetter returns a new NetworkParametersGetter for the parameters published
+// under the given maintainer public key, whose Get requests time out after the given duration.
+func NewNetworkParametersGetter(
+	peerID, orgID ecid.ID, signer, orgSigner Signer, pubKey []byte, timeout time.Duration,
+) NetworkParametersGetter {
+	return &networkParametersGetter{
+		peerID:    peerID,
+		orgID:     orgID,
+		signer:    signer,
+		orgSigner: orgSigner,
+		verifier:  NewVerifier(),
+		pubKey:    pubKey,
+		timeout:   timeout,
+	}
+}
+
+func (g *networkParametersGetter) Get(lcs []api.Getter) (*api.NetworkParameters, error) {
+	key := GetNetworkParametersKey(g.pubKey)
+	var newest *api.NetworkParameters
+	err := ErrNoNetworkParameters
+	for _, lc := range lcs {
+		params, lcErr := g.get(lc, key)
+		if lcErr != nil {
+			err = lcErr
+			continue
+		}
+		if newest == nil || params.IssuedTime > newest.IssuedTime {
+			newest = params
+		}
+	}
+	if newest == nil {
+		return nil, err
+	}
+	return newest, nil
+}
+
+func (g *networkParametersGetter) get(lc api.Getter, key id.ID) (*api.NetworkParameters, error) {
+	rq := NewGetRequest(g.peerID, g.orgID, key)
+	ctx, cancel, err := NewSignedTimeoutContext(g.signer, g.orgSigner, rq, g.timeout)
+	if err != nil {
+		return nil, err
+	}
+	rp, err := lc.Get(ctx, rq)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(rq.Metadata.RequestId, rp.Metadata.RequestId) {
+		return nil, ErrUnexpectedRequestID
+	}
+	return ParseNetworkParametersDocument(g.verifier, g.pubKey, rp.Value)
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestNewNetworkParametersDocument_ParseNetworkParametersDocument_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	maintainerID := ecid.NewPseudoRandom(rng)
+	params := newTestNetworkParameters()
+
+	doc, err := NewNetworkParametersDocument(NewECDSASigner(maintainerID.Key()),
+		maintainerID.PublicKeyBytes(), params)
+	assert.Nil(t, err)
+	assert.Nil(t, api.ValidateDocument(doc))
+	key, err := api.GetKey(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, GetNetworkParametersKey(maintainerID.PublicKeyBytes()), key)
+
+	parsed, err := ParseNetworkParametersDocument(NewVerifier(), maintainerID.PublicKeyBytes(),
+		doc)
+	assert.Nil(t, err)
+	assert.Equal(t, params, parsed)
+}
+
+func TestParseNetworkParametersDocument_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	maintainerID1, maintainerID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	pubKey1 := maintainerID1.PublicKeyBytes()
+	v := NewVerifier()
+
+	// not a system document
+	doc, _ := api.NewTestDocument(rng)
+	_, err := ParseNetworkParametersDocument(v, pubKey1, doc)
+	assert.Equal(t, ErrUnexpectedNetworkParameters, err)
+
+	// published under another key
+	doc, err = NewNetworkParametersDocument(NewECDSASigner(maintainerID2.Key()),
+		maintainerID2.PublicKeyBytes(), newTestNetworkParameters())
+	assert.Nil(t, err)
+	_, err = ParseNetworkParametersDocument(v, pubKey1, doc)
+	assert.Equal(t, ErrUnexpectedNetworkParameters, err)
+
+	// different system record type
+	doc, err = NewNetworkParametersDocument(NewECDSASigner(maintainerID1.Key()), pubKey1,
+		newTestNetworkParameters())
+	assert.Nil(t, err)
+	doc.GetSystem().Record.Type = api.SystemDocumentType_ORG_PROFILE
+	_, err = ParseNetworkParametersDocument(v, pubKey1, doc)
+	assert.Equal(t, ErrUnexpectedNetworkParameters, err)
+
+	// signed by another key
+	doc, err = NewNetworkParametersDocument(NewECDSASigner(maintainerID2.Key()), pubKey1,
+		newTestNetworkParameters())
+	assert.Nil(t, err)
+	_, err = ParseNetworkParametersDocument(v, pubKey1, doc)
+	assert.NotNil(t, err)
+
+	// bad contents
+	doc, err = NewSystemDocument(NewECDSASigner(maintainerID1.Key()), pubKey1,
+		&api.SystemRecord{
+			Type:     api.SystemDocumentType_NETWORK_PARAMETERS,
+			Name:     NetworkParametersName,
+			Contents: []byte{255, 255, 255},
+		})
+	assert.Nil(t, err)
+	_, err = ParseNetworkParametersDocument(v, pubKey1, doc)
+	assert.NotNil(t, err)
+}
+
+func TestNetworkParametersGetter_Get(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, maintainerID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	params := newTestNetworkParameters()
+	doc, err := NewNetworkParametersDocument(NewECDSASigner(maintainerID.Key()),
+		maintainerID.PublicKeyBytes(), params)
+	assert.Nil(t, err)
+	g := NewNetworkParametersGetter(peerID, nil, &TestNoOpSigner{}, &TestNoOpSigner{},
+		maintainerID.PublicKeyBytes(), time.Second)
+
+	// first librarian errors, second returns parameters
+	lcs := []api.Getter{
+		&echoGetter{err: errors.New("some Get error")},
+		&echoGetter{value: doc},
+	}
+	gotten, err := g.Get(lcs)
+	assert.Nil(t, err)
+	assert.Equal(t, params, gotten)
+
+	// newest parameters win, regardless of order
+	staleParams := newTestNetworkParameters()
+	staleParams.NReplicas, staleParams.IssuedTime = 3, params.IssuedTime-1
+	staleDoc, err := NewNetworkParametersDocument(NewECDSASigner(maintainerID.Key()),
+		maintainerID.PublicKeyBytes(), staleParams)
+	assert.Nil(t, err)
+	lcs = []api.Getter{
+		&echoGetter{value: staleDoc},
+		&echoGetter{value: doc},
+		&echoGetter{value: staleDoc},
+	}
+	gotten, err = g.Get(lcs)
+	assert.Nil(t, err)
+	assert.Equal(t, params, gotten)
+
+	// no librarians
+	gotten, err = g.Get([]api.Getter{})
+	assert.Equal(t, ErrNoNetworkParameters, err)
+	assert.Nil(t, gotten)
+
+	// all librarians error
+	otherDoc, _ := api.NewTestDocument(rng)
+	lcs = []api.Getter{&echoGetter{value: otherDoc}}
+	gotten, err = g.Get(lcs)
+	assert.Equal(t, ErrUnexpectedNetworkParameters, err)
+	assert.Nil(t, gotten)
+}
+
+func newTestNetworkParameters() *api.NetworkParameters {
+	return &api.NetworkParameters{
+		NReplicas:          5,
+		MaxPageSize:        1024 * 1024,
+		WorkerPoolSizes:    map[string]uint32{api.Store.String(): 16},
+		IssuedTime:         1500000000,
+		RequesterPeerRates: map[string]float64{api.Find.String(): 32},
+		RequesterOrgRates:  map[string]float64{api.Find.String(): 256},
+	}
+}
+
+// echoGetter returns the value in a response with the same metadata as the request.
+type echoGetter struct {
+	value *api.Document
+	err   error
+}
+
+func (e *echoGetter) Get(ctx context.Context, in *api.GetRequest, opts ...grpc.CallOption) (
+	*api.GetResponse, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &api.GetResponse{Metadata: &api.ResponseMetadata{RequestId: in.Metadata.RequestId},
+		Value: e.value}, nil
+}
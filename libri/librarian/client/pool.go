@@ -30,7 +30,7 @@ type lruPool struct {
 
 // NewLRUPool creates a new LRU Pool with the given number of max connections.
 func NewLRUPool(maxConns int) (Pool, error) {
-	return newLRUPool(maxConns, insecureDialer{}, closerImpl{})
+	return NewLRUPoolWithInterceptors(maxConns, nil)
 }
 
 // NewDefaultLRUPool creates a new LRU pool with the default number of max connections.
@@ -38,6 +38,18 @@ func NewDefaultLRUPool() (Pool, error) {
 	return NewLRUPool(defaultMaxConns)
 }
 
+// NewLRUPoolWithInterceptors creates a new LRU Pool with the given number of max connections,
+// each dialed with the given (possibly nil) client interceptors.
+func NewLRUPoolWithInterceptors(maxConns int, interceptors *Interceptors) (Pool, error) {
+	return newLRUPool(maxConns, insecureDialer{interceptors: interceptors}, closerImpl{})
+}
+
+// NewDefaultLRUPoolWithInterceptors creates a new LRU pool with the default number of max
+// connections, each dialed with the given (possibly nil) client interceptors.
+func NewDefaultLRUPoolWithInterceptors(interceptors *Interceptors) (Pool, error) {
+	return NewLRUPoolWithInterceptors(defaultMaxConns, interceptors)
+}
+
 func newLRUPool(maxConns int, dialer dialer, closer closer) (Pool, error) {
 	evictionErrs := make(chan error, 1)
 	onEvicted := func(key interface{}, value interface{}) {
@@ -93,10 +105,12 @@ type dialer interface {
 	dial(address string) (*grpc.ClientConn, error)
 }
 
-type insecureDialer struct{}
+type insecureDialer struct {
+	interceptors *Interceptors
+}
 
-func (insecureDialer) dial(address string) (*grpc.ClientConn, error) {
-	return grpc.Dial(address, grpc.WithInsecure())
+func (d insecureDialer) dial(address string) (*grpc.ClientConn, error) {
+	return grpc.Dial(address, d.interceptors.DialOptions()...)
 }
 
 // closer is a very thin wrapper around (*grpc.ClientConn).Close() to facilitate mocking during
@@ -16,6 +16,13 @@ func TestNewDefaultLRUPool(t *testing.T) {
 	assert.NotNil(t, p)
 }
 
+func TestNewDefaultLRUPoolWithInterceptors(t *testing.T) {
+	interceptors := &Interceptors{Unary: []grpc.UnaryClientInterceptor{noOpUnaryInterceptor}}
+	p, err := NewDefaultLRUPoolWithInterceptors(interceptors)
+	assert.Nil(t, err)
+	assert.Equal(t, interceptors, p.(*lruPool).dialer.(insecureDialer).interceptors)
+}
+
 func TestLRUPool_Get_ok(t *testing.T) {
 	cc := &grpc.ClientConn{}
 	dialer := &fixedDialer{conn: cc}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	// ErrMissingStoreReceipt indicates when a signed store receipt is missing its receipt.
+	ErrMissingStoreReceipt = errors.New("signed store receipt missing receipt")
+
+	// ErrUnexpectedStoreReceipt indicates when a store receipt is for a different key or value
+	// than expected.
+	ErrUnexpectedStoreReceipt = errors.New("store receipt for unexpected key or value")
+)
+
+// NewSignedStoreReceipt creates a receipt for storing the value with the given key at the given
+// time and signs it with the peer signer, whose public key is pubKey.
+func NewSignedStoreReceipt(
+	signer Signer, pubKey []byte, key []byte, value *api.Document, storedTime time.Time,
+) (*api.SignedStoreReceipt, error) {
+	valueHash, err := HashValue(value)
+	if err != nil {
+		return nil, err
+	}
+	receipt := &api.StoreReceipt{
+		Key:        key,
+		PeerPubKey: pubKey,
+		Timestamp:  storedTime.Unix(),
+		ValueHash:  valueHash,
+	}
+	signature, err := signer.Sign(receipt)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SignedStoreReceipt{
+		Receipt:   receipt,
+		Signature: signature,
+	}, nil
+}
+
+// VerifySignedStoreReceipt verifies that the receipt was signed by the key with the public key
+// included in the receipt.
+func VerifySignedStoreReceipt(v Verifier, ssr *api.SignedStoreReceipt) error {
+	if ssr.Receipt == nil {
+		return ErrMissingStoreReceipt
+	}
+	pubKey, err := ecid.FromPublicKeyBytes(ssr.Receipt.PeerPubKey)
+	if err != nil {
+		return err
+	}
+	return v.Verify(ssr.Signature, pubKey, ssr.Receipt)
+}
+
+// CheckSignedStoreReceipt verifies the receipt's signature and that it is for storing the given
+// value with the given key.
+func CheckSignedStoreReceipt(
+	v Verifier, ssr *api.SignedStoreReceipt, key []byte, value *api.Document,
+) error {
+	if err := VerifySignedStoreReceipt(v, ssr); err != nil {
+		return err
+	}
+	valueHash, err := HashValue(value)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ssr.Receipt.Key, key) || !bytes.Equal(ssr.Receipt.ValueHash, valueHash) {
+		return ErrUnexpectedStoreReceipt
+	}
+	return nil
+}
+
+// HashValue returns the SHA-256 hash of the marshaled value.
+func HashValue(value *api.Document) ([]byte, error) {
+	valueBytes, err := proto.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(valueBytes)
+	return hash[:], nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSignedStoreReceipt_VerifySignedStoreReceipt_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	now := time.Now()
+
+	ssr, err := NewSignedStoreReceipt(NewECDSASigner(peerID.Key()), peerID.PublicKeyBytes(),
+		key.Bytes(), value, now)
+	assert.Nil(t, err)
+	assert.Equal(t, key.Bytes(), ssr.Receipt.Key)
+	assert.Equal(t, peerID.PublicKeyBytes(), ssr.Receipt.PeerPubKey)
+	assert.Equal(t, now.Unix(), ssr.Receipt.Timestamp)
+	assert.Len(t, ssr.Receipt.ValueHash, sha256.Size)
+	assert.NotEmpty(t, ssr.Signature)
+	assert.Nil(t, VerifySignedStoreReceipt(NewVerifier(), ssr))
+	assert.Nil(t, CheckSignedStoreReceipt(NewVerifier(), ssr, key.Bytes(), value))
+}
+
+func TestNewSignedStoreReceipt_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	ssr, err := NewSignedStoreReceipt(&TestErrSigner{}, peerID.PublicKeyBytes(), key.Bytes(),
+		value, time.Now())
+	assert.NotNil(t, err)
+	assert.Nil(t, ssr)
+}
+
+func TestVerifySignedStoreReceipt_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID1, peerID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	v := NewVerifier()
+
+	// missing receipt
+	err := VerifySignedStoreReceipt(v, &api.SignedStoreReceipt{})
+	assert.Equal(t, ErrMissingStoreReceipt, err)
+
+	// bad public key
+	ssr, err := NewSignedStoreReceipt(NewECDSASigner(peerID1.Key()), []byte{1, 2, 3},
+		key.Bytes(), value, time.Now())
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySignedStoreReceipt(v, ssr))
+
+	// signed by another key
+	ssr, err = NewSignedStoreReceipt(NewECDSASigner(peerID2.Key()), peerID1.PublicKeyBytes(),
+		key.Bytes(), value, time.Now())
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySignedStoreReceipt(v, ssr))
+
+	// receipt changed after signing
+	ssr, err = NewSignedStoreReceipt(NewECDSASigner(peerID1.Key()), peerID1.PublicKeyBytes(),
+		key.Bytes(), value, time.Now())
+	assert.Nil(t, err)
+	ssr.Receipt.Key = id.NewPseudoRandom(rng).Bytes()
+	assert.NotNil(t, VerifySignedStoreReceipt(v, ssr))
+}
+
+func TestCheckSignedStoreReceipt_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	signer := NewECDSASigner(peerID.Key())
+	value, key := api.NewTestDocument(rng)
+	v := NewVerifier()
+
+	// missing receipt
+	err := CheckSignedStoreReceipt(v, &api.SignedStoreReceipt{}, key.Bytes(), value)
+	assert.Equal(t, ErrMissingStoreReceipt, err)
+
+	// receipt for another key
+	ssr, err := NewSignedStoreReceipt(signer, peerID.PublicKeyBytes(),
+		id.NewPseudoRandom(rng).Bytes(), value, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, ErrUnexpectedStoreReceipt, CheckSignedStoreReceipt(v, ssr, key.Bytes(), value))
+
+	// receipt for another value
+	other, _ := api.NewTestDocument(rng)
+	ssr, err = NewSignedStoreReceipt(signer, peerID.PublicKeyBytes(), key.Bytes(), other,
+		time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, ErrUnexpectedStoreReceipt, CheckSignedStoreReceipt(v, ssr, key.Bytes(), value))
+}
+
+func TestHashValue(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, _ := api.NewTestDocument(rng)
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	expected := sha256.Sum256(valueBytes)
+
+	hash, err := HashValue(value)
+	assert.Nil(t, err)
+	assert.Equal(t, expected[:], hash)
+}
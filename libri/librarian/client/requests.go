@@ -56,6 +56,17 @@ func NewVerifyRequest(
 	}
 }
 
+// NewChallengeRequest creates a VerifyRequest object for the MAC of just the length bytes of the
+// document's serialized bytes starting at offset, challenging the peer to prove it possesses the
+// document.
+func NewChallengeRequest(
+	peerID, orgID ecid.ID, key id.ID, macKey []byte, offset, length uint32,
+) *api.VerifyRequest {
+	rq := NewVerifyRequest(peerID, orgID, key, macKey, 0)
+	rq.SliceOffset, rq.SliceLength = offset, length
+	return rq
+}
+
 // NewStoreRequest creates a StoreRequest object.
 func NewStoreRequest(peerID, orgID ecid.ID, key id.ID, value *api.Document) *api.StoreRequest {
 	return &api.StoreRequest{
@@ -91,3 +102,95 @@ func NewSubscribeRequest(
 		Subscription: subscription,
 	}
 }
+
+// NewSyncRequest creates a SyncRequest object for the keys within the inclusive range between
+// the lower and upper bounds.
+func NewSyncRequest(peerID, orgID ecid.ID, lowerBound, upperBound id.ID) *api.SyncRequest {
+	return &api.SyncRequest{
+		Metadata:   NewRequestMetadata(peerID, orgID),
+		LowerBound: lowerBound.Bytes(),
+		UpperBound: upperBound.Bytes(),
+	}
+}
+
+// NewRoutingTableRequest creates a RoutingTableRequest object.
+func NewRoutingTableRequest(peerID, orgID ecid.ID) *api.RoutingTableRequest {
+	return &api.RoutingTableRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+	}
+}
+
+// NewListBansRequest creates a ListBansRequest object.
+func NewListBansRequest(peerID, orgID ecid.ID) *api.ListBansRequest {
+	return &api.ListBansRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+	}
+}
+
+// NewBanRequest creates a BanRequest object.
+func NewBanRequest(peerID, orgID ecid.ID, ban *api.PeerBan) *api.BanRequest {
+	return &api.BanRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+		Ban:      ban,
+	}
+}
+
+// NewUnbanRequest creates an UnbanRequest object.
+func NewUnbanRequest(peerID, orgID ecid.ID, bannedID id.ID) *api.UnbanRequest {
+	return &api.UnbanRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+		PeerId:   bannedID.Bytes(),
+	}
+}
+
+// NewListQuotasRequest creates a ListQuotasRequest object.
+func NewListQuotasRequest(peerID, orgID ecid.ID) *api.ListQuotasRequest {
+	return &api.ListQuotasRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+	}
+}
+
+// NewSetQuotaRequest creates a SetQuotaRequest object.
+func NewSetQuotaRequest(
+	peerID, orgID ecid.ID, quotaOrgID id.ID, limitBytes uint64, resetUsed bool,
+) *api.SetQuotaRequest {
+	return &api.SetQuotaRequest{
+		Metadata:   NewRequestMetadata(peerID, orgID),
+		OrgId:      quotaOrgID.Bytes(),
+		LimitBytes: limitBytes,
+		ResetUsed:  resetUsed,
+	}
+}
+
+// NewGetAllowListRequest creates a GetAllowListRequest object.
+func NewGetAllowListRequest(peerID, orgID ecid.ID) *api.GetAllowListRequest {
+	return &api.GetAllowListRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+	}
+}
+
+// NewSetAllowListRequest creates a SetAllowListRequest object.
+func NewSetAllowListRequest(
+	peerID, orgID ecid.ID, allowList *api.SignedAllowList,
+) *api.SetAllowListRequest {
+	return &api.SetAllowListRequest{
+		Metadata:  NewRequestMetadata(peerID, orgID),
+		AllowList: allowList,
+	}
+}
+
+// NewPinRequest creates a PinRequest object.
+func NewPinRequest(peerID, orgID ecid.ID, key id.ID) *api.PinRequest {
+	return &api.PinRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+		Key:      key.Bytes(),
+	}
+}
+
+// NewUnpinRequest creates an UnpinRequest object.
+func NewUnpinRequest(peerID, orgID ecid.ID, key id.ID) *api.UnpinRequest {
+	return &api.UnpinRequest{
+		Metadata: NewRequestMetadata(peerID, orgID),
+		Key:      key.Bytes(),
+	}
+}
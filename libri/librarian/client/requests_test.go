@@ -58,6 +58,20 @@ func TestNewVerifyRequest(t *testing.T) {
 	assert.Equal(t, uint32(nPeers), rq.NumPeers)
 }
 
+func TestNewChallengeRequest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	key, macKey := id.NewPseudoRandom(rng), api.RandBytes(rng, 32)
+
+	rq := NewChallengeRequest(peerID, orgID, key, macKey, 8, 16)
+	assert.NotNil(t, rq.Metadata)
+	assert.Equal(t, key.Bytes(), rq.Key)
+	assert.Equal(t, macKey, rq.MacKey)
+	assert.Zero(t, rq.NumPeers)
+	assert.Equal(t, uint32(8), rq.SliceOffset)
+	assert.Equal(t, uint32(16), rq.SliceLength)
+}
+
 func TestNewStoreRequest(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	peerID := ecid.NewPseudoRandom(rng)
@@ -102,3 +116,91 @@ func TestNewSubscribeRequest(t *testing.T) {
 	assert.NotNil(t, rq.Metadata)
 	assert.Equal(t, sub, rq.Subscription)
 }
+
+func TestNewSyncRequest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+
+	rq := NewSyncRequest(peerID, orgID, id.LowerBound, id.UpperBound)
+	assert.NotNil(t, rq.Metadata)
+	assert.Equal(t, id.LowerBound.Bytes(), rq.LowerBound)
+	assert.Equal(t, id.UpperBound.Bytes(), rq.UpperBound)
+}
+
+func TestNewRoutingTableRequest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+
+	rq := NewRoutingTableRequest(peerID, orgID)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq.Metadata.PubKey)
+	assert.Equal(t, orgID.PublicKeyBytes(), rq.Metadata.OrgPubKey)
+}
+
+func TestNewBanRequests(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	bannedID := id.NewPseudoRandom(rng)
+
+	rq1 := NewListBansRequest(peerID, orgID)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq1.Metadata.PubKey)
+	assert.Equal(t, orgID.PublicKeyBytes(), rq1.Metadata.OrgPubKey)
+
+	ban := &api.PeerBan{PeerId: bannedID.Bytes(), Reason: "spam"}
+	rq2 := NewBanRequest(peerID, orgID, ban)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq2.Metadata.PubKey)
+	assert.Equal(t, ban, rq2.Ban)
+
+	rq3 := NewUnbanRequest(peerID, orgID, bannedID)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq3.Metadata.PubKey)
+	assert.Equal(t, bannedID.Bytes(), rq3.PeerId)
+}
+
+func TestNewQuotaRequests(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	quotaOrgID := id.NewPseudoRandom(rng)
+
+	rq1 := NewListQuotasRequest(peerID, orgID)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq1.Metadata.PubKey)
+	assert.Equal(t, orgID.PublicKeyBytes(), rq1.Metadata.OrgPubKey)
+
+	rq2 := NewSetQuotaRequest(peerID, orgID, quotaOrgID, 1024, true)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq2.Metadata.PubKey)
+	assert.Equal(t, quotaOrgID.Bytes(), rq2.OrgId)
+	assert.Equal(t, uint64(1024), rq2.LimitBytes)
+	assert.True(t, rq2.ResetUsed)
+}
+
+func TestNewAllowListRequests(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	allowList := &api.SignedAllowList{AllowList: &api.AllowList{IssuedNanos: 1}}
+
+	rq1 := NewGetAllowListRequest(peerID, orgID)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq1.Metadata.PubKey)
+	assert.Equal(t, orgID.PublicKeyBytes(), rq1.Metadata.OrgPubKey)
+
+	rq2 := NewSetAllowListRequest(peerID, orgID, allowList)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq2.Metadata.PubKey)
+	assert.Equal(t, allowList, rq2.AllowList)
+}
+
+func TestNewPinRequests(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	key := id.NewPseudoRandom(rng)
+
+	rq1 := NewPinRequest(peerID, orgID, key)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq1.Metadata.PubKey)
+	assert.Equal(t, key.Bytes(), rq1.Key)
+
+	rq2 := NewUnpinRequest(peerID, orgID, key)
+	assert.Equal(t, peerID.PublicKeyBytes(), rq2.Metadata.PubKey)
+	assert.Equal(t, key.Bytes(), rq2.Key)
+}
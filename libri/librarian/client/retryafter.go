@@ -0,0 +1,31 @@
+package client
+
+import (
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// retryAfterKey is the response trailer key for how long a rate-limited requester should wait
+// before retrying its request.
+const retryAfterKey = "libri-retry-after"
+
+// NewRetryAfterTrailer returns response trailer metadata hinting that the requester should wait
+// the given duration before retrying its request.
+func NewRetryAfterTrailer(retryAfter time.Duration) metadata.MD {
+	return metadata.Pairs(retryAfterKey, retryAfter.String())
+}
+
+// FromRetryAfterTrailer returns the retry-after hint of response trailer metadata and whether
+// the metadata had one.
+func FromRetryAfterTrailer(md metadata.MD) (time.Duration, bool) {
+	values, exists := md[retryAfterKey]
+	if !exists || len(values) == 0 {
+		return 0, false
+	}
+	retryAfter, err := time.ParseDuration(values[0])
+	if err != nil {
+		return 0, false
+	}
+	return retryAfter, true
+}
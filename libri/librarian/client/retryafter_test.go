@@ -0,0 +1,27 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNewRetryAfterTrailer_FromRetryAfterTrailer(t *testing.T) {
+	md := NewRetryAfterTrailer(1500 * time.Millisecond)
+	retryAfter, ok := FromRetryAfterTrailer(md)
+	assert.True(t, ok)
+	assert.Equal(t, 1500*time.Millisecond, retryAfter)
+
+	// missing or bad hints
+	for _, md := range []metadata.MD{
+		nil,
+		metadata.Pairs("other-key", "1s"),
+		metadata.Pairs(retryAfterKey, "not a duration"),
+	} {
+		retryAfter, ok = FromRetryAfterTrailer(md)
+		assert.False(t, ok)
+		assert.Zero(t, retryAfter)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+)
+
+// NewSystemDocument signs the system record with the signer, whose public key is pubKey, and
+// returns it wrapped in a document, whose key (see api.GetKey) is in the reserved system keyspace.
+func NewSystemDocument(
+	signer Signer, pubKey []byte, record *api.SystemRecord,
+) (*api.Document, error) {
+	signature, err := signer.Sign(record)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := api.GetSystemChecksum(record)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Document{
+		Contents: &api.Document_System{
+			System: &api.SystemDocument{
+				Record:    record,
+				PubKey:    pubKey,
+				Signature: signature,
+				Checksum:  checksum,
+			},
+		},
+	}, nil
+}
+
+// VerifySystemDocument verifies that the system record was signed by the key with the included
+// public key.
+func VerifySystemDocument(v Verifier, sd *api.SystemDocument) error {
+	if sd == nil {
+		return api.ErrMissingSystemDocument
+	}
+	if sd.Record == nil {
+		return api.ErrMissingSystemRecord
+	}
+	pubKey, err := ecid.FromPublicKeyBytes(sd.PubKey)
+	if err != nil {
+		return err
+	}
+	return v.Verify(sd.Signature, pubKey, sd.Record)
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSystemDocument_VerifySystemDocument_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	signerID := ecid.NewPseudoRandom(rng)
+	record := newTestSystemRecord(rng)
+
+	doc, err := NewSystemDocument(NewECDSASigner(signerID.Key()), signerID.PublicKeyBytes(),
+		record)
+	assert.Nil(t, err)
+	assert.Nil(t, api.ValidateDocument(doc))
+	assert.Equal(t, record, doc.GetSystem().Record)
+	assert.Nil(t, api.CheckSystemChecksum(doc.GetSystem()))
+	assert.Nil(t, VerifySystemDocument(NewVerifier(), doc.GetSystem()))
+
+	key, err := api.GetKey(doc)
+	assert.Nil(t, err)
+	assert.True(t, api.IsSystemKey(key))
+}
+
+func TestNewSystemDocument_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	signerID := ecid.NewPseudoRandom(rng)
+	doc, err := NewSystemDocument(&TestErrSigner{}, signerID.PublicKeyBytes(),
+		newTestSystemRecord(rng))
+	assert.NotNil(t, err)
+	assert.Nil(t, doc)
+}
+
+func TestVerifySystemDocument_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	signerID1, signerID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	record := newTestSystemRecord(rng)
+	v := NewVerifier()
+
+	// missing system document
+	assert.Equal(t, api.ErrMissingSystemDocument, VerifySystemDocument(v, nil))
+
+	// missing record
+	err := VerifySystemDocument(v, &api.SystemDocument{PubKey: signerID1.PublicKeyBytes()})
+	assert.Equal(t, api.ErrMissingSystemRecord, err)
+
+	// bad public key
+	doc, err := NewSystemDocument(NewECDSASigner(signerID1.Key()), []byte{1, 2, 3}, record)
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySystemDocument(v, doc.GetSystem()))
+
+	// signed by another key
+	doc, err = NewSystemDocument(NewECDSASigner(signerID2.Key()),
+		signerID1.PublicKeyBytes(), record)
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifySystemDocument(v, doc.GetSystem()))
+
+	// record changed after signing
+	doc, err = NewSystemDocument(NewECDSASigner(signerID1.Key()),
+		signerID1.PublicKeyBytes(), record)
+	assert.Nil(t, err)
+	doc.GetSystem().Record = newTestSystemRecord(rng)
+	assert.NotNil(t, VerifySystemDocument(v, doc.GetSystem()))
+}
+
+func newTestSystemRecord(rng *rand.Rand) *api.SystemRecord {
+	return &api.SystemRecord{
+		Type:     api.SystemDocumentType_ORG_PROFILE,
+		Name:     api.RandBytes(rng, 16),
+		Contents: api.RandBytes(rng, 64),
+	}
+}
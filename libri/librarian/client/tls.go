@@ -0,0 +1,234 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// DefaultTLSReloadInterval is the default minimum interval between checks of the TLS certificate,
+// key, and CA files for changes.
+const DefaultTLSReloadInterval = 30 * time.Second
+
+const spiffeScheme = "spiffe"
+
+var (
+	// ErrTLSMissingFiles indicates that mutual TLS was enabled without all of the certificate,
+	// key, and CA files.
+	ErrTLSMissingFiles = errors.New("mutual TLS requires certificate, key, and CA files")
+
+	// ErrNoPeerCertificate indicates that the peer did not present a certificate.
+	ErrNoPeerCertificate = errors.New("peer presented no certificate")
+
+	// ErrUnexpectedSPIFFEID indicates that the peer certificate's SPIFFE ID isn't one of those
+	// expected.
+	ErrUnexpectedSPIFFEID = errors.New("peer certificate has unexpected SPIFFE ID")
+
+	errNoCACerts = errors.New("CA file contains no certificates")
+)
+
+// TLSParameters define the mutual TLS used to encrypt and authenticate the connections between
+// librarians, whose certificates must all be signed by the CA.
+type TLSParameters struct {
+	// CertFile is the path of the PEM certificate (chain) presented to peers. Mutual TLS is
+	// disabled when empty.
+	CertFile string
+
+	// KeyFile is the path of the PEM private key of the certificate.
+	KeyFile string
+
+	// CAFile is the path of the PEM CA certificates that peer certificates must chain to.
+	CAFile string
+
+	// SPIFFEIDs are the SPIFFE IDs (e.g., spiffe://example.org/librarian) of which a peer
+	// certificate must have one among its URI SANs. Any certificate signed by the CA is
+	// accepted when empty.
+	SPIFFEIDs []string
+
+	// ReloadInterval is the minimum interval between checks of the files for changes, so
+	// rotated certificates are used without restarting.
+	ReloadInterval time.Duration
+}
+
+// NewDefaultTLSParameters returns a *TLSParameters object with default values, which leave mutual
+// TLS disabled.
+func NewDefaultTLSParameters() *TLSParameters {
+	return &TLSParameters{
+		ReloadInterval: DefaultTLSReloadInterval,
+	}
+}
+
+// Enabled returns whether connections between librarians use mutual TLS.
+func (p *TLSParameters) Enabled() bool {
+	return p != nil && p.CertFile != ""
+}
+
+// NewTLSCredentials returns the server and client transport credentials for mutual TLS with the
+// given parameters, which share certificates reloaded from their files when they change. Both
+// are nil when mutual TLS is disabled.
+func NewTLSCredentials(params *TLSParameters) (
+	serverCreds, clientCreds credentials.TransportCredentials, err error) {
+	if !params.Enabled() {
+		return nil, nil, nil
+	}
+	r, err := newCertReloader(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return credentials.NewTLS(r.serverConfig()), credentials.NewTLS(r.clientConfig()), nil
+}
+
+// certReloader holds the certificate and CA pool loaded from the TLS files, reloading them when
+// the files change.
+type certReloader struct {
+	params   *TLSParameters
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	modTimes [3]time.Time
+	checked  time.Time
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+func newCertReloader(params *TLSParameters) (*certReloader, error) {
+	if params.KeyFile == "" || params.CAFile == "" {
+		return nil, ErrTLSMissingFiles
+	}
+	r := &certReloader{params: params, now: time.Now}
+	if err := r.maybeReload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// maybeReload reloads the certificate and CA pool if the reload interval has passed since the last
+// check and any of the files have changed. When a reload fails, e.g., because the certificate has
+// been rotated but its key hasn't yet, the previous certificate and CA pool remain in use and the
+// reload is retried on the next check.
+func (r *certReloader) maybeReload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if r.cert != nil && now.Sub(r.checked) < r.params.ReloadInterval {
+		return nil
+	}
+	r.checked = now
+	var modTimes [3]time.Time
+	for i, file := range []string{r.params.CertFile, r.params.KeyFile, r.params.CAFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	if r.cert != nil && modTimes == r.modTimes {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.params.CertFile, r.params.KeyFile)
+	if err != nil {
+		return err
+	}
+	caPEM, err := ioutil.ReadFile(r.params.CAFile)
+	if err != nil {
+		return err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return errNoCACerts
+	}
+	r.cert, r.roots, r.modTimes = &cert, roots, modTimes
+	return nil
+}
+
+// current returns the current certificate and CA pool, reloading them first if they've changed.
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	// a failed reload keeps the previous certificate, which is better than failing handshakes
+	_ = r.maybeReload()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, r.roots
+}
+
+// serverConfig returns the TLS config for accepting connections, which requires the client to
+// present a certificate signed by the CA.
+func (r *certReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		VerifyPeerCertificate: r.verifyPeer,
+	}
+}
+
+// clientConfig returns the TLS config for dialing peers. Since peers are dialed by IP address,
+// their certificates are verified against the CA and SPIFFE IDs instead of their host names.
+func (r *certReloader) clientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true, // verified by verifyPeer instead
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		VerifyPeerCertificate: r.verifyPeer,
+	}
+}
+
+// verifyPeer verifies that the peer's certificate chains to the current CA pool and has one of
+// the expected SPIFFE IDs.
+func (r *certReloader) verifyPeer(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return ErrNoPeerCertificate
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+	_, roots := r.current()
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		// librarians are both clients and servers of each other
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return err
+	}
+	return checkSPIFFEID(certs[0], r.params.SPIFFEIDs)
+}
+
+// checkSPIFFEID returns an error if the certificate doesn't have one of the expected SPIFFE IDs
+// among its URI SANs. Any certificate passes when no SPIFFE IDs are expected.
+func checkSPIFFEID(cert *x509.Certificate, expected []string) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme != spiffeScheme {
+			continue
+		}
+		for _, spiffeID := range expected {
+			if uri.String() == spiffeID {
+				return nil
+			}
+		}
+	}
+	return ErrUnexpectedSPIFFEID
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSPIFFEID = "spiffe://libri.test/librarian"
+
+func TestNewDefaultTLSParameters(t *testing.T) {
+	p := NewDefaultTLSParameters()
+	assert.NotZero(t, p.ReloadInterval)
+	assert.False(t, p.Enabled())
+}
+
+func TestNewTLSCredentials(t *testing.T) {
+	serverCreds, clientCreds, err := NewTLSCredentials(NewDefaultTLSParameters())
+	assert.Nil(t, err)
+	assert.Nil(t, serverCreds)
+	assert.Nil(t, clientCreds)
+
+	dir, err := ioutil.TempDir("", "tls-test")
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	assert.Nil(t, err)
+	ca := newTestCA(t)
+	params := writeTestTLSFiles(t, dir, ca, ca.issue(t, testSPIFFEID))
+	serverCreds, clientCreds, err = NewTLSCredentials(params)
+	assert.Nil(t, err)
+	assert.NotNil(t, serverCreds)
+	assert.NotNil(t, clientCreds)
+
+	// missing files
+	params.CAFile = ""
+	serverCreds, clientCreds, err = NewTLSCredentials(params)
+	assert.Equal(t, ErrTLSMissingFiles, err)
+	assert.Nil(t, serverCreds)
+	assert.Nil(t, clientCreds)
+}
+
+func TestCertReloader_handshake_ok(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-test")
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	assert.Nil(t, err)
+	ca := newTestCA(t)
+	params := writeTestTLSFiles(t, dir, ca, ca.issue(t, testSPIFFEID))
+	params.SPIFFEIDs = []string{testSPIFFEID}
+	r, err := newCertReloader(params)
+	assert.Nil(t, err)
+
+	serverErr, clientErr := handshake(t, r.serverConfig(), r.clientConfig())
+	assert.Nil(t, serverErr)
+	assert.Nil(t, clientErr)
+}
+
+func TestCertReloader_handshake_err(t *testing.T) {
+	dir1, err := ioutil.TempDir("", "tls-test")
+	defer func() { assert.Nil(t, os.RemoveAll(dir1)) }()
+	assert.Nil(t, err)
+	dir2, err := ioutil.TempDir("", "tls-test")
+	defer func() { assert.Nil(t, os.RemoveAll(dir2)) }()
+	assert.Nil(t, err)
+	ca1, ca2 := newTestCA(t), newTestCA(t)
+
+	// peer with cert signed by other CA
+	params1 := writeTestTLSFiles(t, dir1, ca1, ca1.issue(t, testSPIFFEID))
+	r1, err := newCertReloader(params1)
+	assert.Nil(t, err)
+	params2 := writeTestTLSFiles(t, dir2, ca1, ca2.issue(t, testSPIFFEID))
+	r2, err := newCertReloader(params2)
+	assert.Nil(t, err)
+	serverErr, _ := handshake(t, r1.serverConfig(), r2.clientConfig())
+	assert.NotNil(t, serverErr)
+
+	// peer with unexpected SPIFFE ID
+	params1.SPIFFEIDs = []string{testSPIFFEID}
+	r1, err = newCertReloader(params1)
+	assert.Nil(t, err)
+	params2 = writeTestTLSFiles(t, dir2, ca1, ca1.issue(t, "spiffe://libri.test/other"))
+	r2, err = newCertReloader(params2)
+	assert.Nil(t, err)
+	serverErr, _ = handshake(t, r1.serverConfig(), r2.clientConfig())
+	assert.Equal(t, ErrUnexpectedSPIFFEID, serverErr)
+
+	// peer doesn't present SPIFFE ID expected of it
+	serverErr, clientErr := handshake(t, r2.serverConfig(), r1.clientConfig())
+	assert.NotNil(t, clientErr)
+	assert.NotNil(t, serverErr)
+}
+
+func TestCertReloader_maybeReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-test")
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	assert.Nil(t, err)
+	ca := newTestCA(t)
+	params := writeTestTLSFiles(t, dir, ca, ca.issue(t, testSPIFFEID))
+	r, err := newCertReloader(params)
+	assert.Nil(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	cert1, _ := r.current()
+
+	// rotated cert not loaded before reload interval
+	writeTestTLSFiles(t, dir, ca, ca.issue(t, testSPIFFEID))
+	for _, file := range []string{params.CertFile, params.KeyFile} {
+		err = os.Chtimes(file, now.Add(time.Second), now.Add(time.Second))
+		assert.Nil(t, err)
+	}
+	cert2, _ := r.current()
+	assert.Equal(t, cert1, cert2)
+
+	// rotated cert loaded after reload interval
+	now = now.Add(params.ReloadInterval)
+	cert3, _ := r.current()
+	assert.NotEqual(t, cert1.Certificate, cert3.Certificate)
+
+	// previous cert kept when reload fails
+	err = ioutil.WriteFile(params.KeyFile, []byte("not a key"), 0600)
+	assert.Nil(t, err)
+	err = os.Chtimes(params.KeyFile, now.Add(2*time.Second), now.Add(2*time.Second))
+	assert.Nil(t, err)
+	now = now.Add(params.ReloadInterval)
+	assert.NotNil(t, r.maybeReload())
+	cert4, _ := r.current()
+	assert.Equal(t, cert3, cert4)
+}
+
+func TestCheckSPIFFEID(t *testing.T) {
+	cert := &x509.Certificate{URIs: []*url.URL{
+		{Scheme: "https", Host: "libri.test", Path: "/librarian"},
+		{Scheme: spiffeScheme, Host: "libri.test", Path: "/librarian"},
+	}}
+	assert.Nil(t, checkSPIFFEID(cert, nil))
+	assert.Nil(t, checkSPIFFEID(cert, []string{"spiffe://libri.test/other", testSPIFFEID}))
+	assert.Equal(t, ErrUnexpectedSPIFFEID,
+		checkSPIFFEID(cert, []string{"https://libri.test/librarian"}))
+	assert.Equal(t, ErrUnexpectedSPIFFEID, checkSPIFFEID(&x509.Certificate{}, []string{
+		testSPIFFEID}))
+}
+
+func TestCertReloader_verifyPeer_noCert(t *testing.T) {
+	r := &certReloader{}
+	assert.Equal(t, ErrNoPeerCertificate, r.verifyPeer(nil, nil))
+}
+
+type testCA struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+	der  []byte
+}
+
+type testCert struct {
+	keyDER []byte
+	der    []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "libri test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCA{key: key, cert: cert, der: der}
+}
+
+func (ca *testCA) issue(t *testing.T, spiffeID string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	uri, err := url.Parse(spiffeID)
+	assert.Nil(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return &testCert{keyDER: keyDER, der: der}
+}
+
+func writeTestTLSFiles(t *testing.T, dir string, ca *testCA, cert *testCert) *TLSParameters {
+	params := NewDefaultTLSParameters()
+	params.CertFile = filepath.Join(dir, "cert.pem")
+	params.KeyFile = filepath.Join(dir, "key.pem")
+	params.CAFile = filepath.Join(dir, "ca.pem")
+	files := map[string]*pem.Block{
+		params.CertFile: {Type: "CERTIFICATE", Bytes: cert.der},
+		params.KeyFile:  {Type: "EC PRIVATE KEY", Bytes: cert.keyDER},
+		params.CAFile:   {Type: "CERTIFICATE", Bytes: ca.der},
+	}
+	for file, block := range files {
+		err := ioutil.WriteFile(file, pem.EncodeToMemory(block), 0600)
+		assert.Nil(t, err)
+	}
+	return params
+}
+
+// handshake performs a TLS handshake between a server and client with the given configs over a
+// loopback connection, returning each side's error.
+func handshake(t *testing.T, serverConfig, clientConfig *tls.Config) (error, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, lis.Close()) }()
+	serverErrs := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			serverErrs <- err
+			return
+		}
+		server := tls.Server(conn, serverConfig)
+		serverErrs <- server.Handshake()
+		_ = server.Close()
+	}()
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	assert.Nil(t, err)
+	client := tls.Client(conn, clientConfig)
+	clientErr := client.Handshake()
+	_ = client.Close()
+	return <-serverErrs, clientErr
+}
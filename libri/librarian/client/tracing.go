@@ -0,0 +1,114 @@
+package client
+
+import (
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TextMapCarrier carries the trace context between processes as string key-value pairs, e.g.,
+// the W3C traceparent and tracestate headers. Its methods mirror those of an OpenTelemetry
+// TextMapCarrier.
+type TextMapCarrier interface {
+	// Get returns the value for the key, or an empty string if it has none.
+	Get(key string) string
+
+	// Set sets the value for the key.
+	Set(key string, value string)
+
+	// Keys returns the keys with values.
+	Keys() []string
+}
+
+// TracePropagator propagates the trace context of the span in a context to the peers it queries,
+// so the spans of a single request (e.g., a Put and the searches and stores it triggers) are
+// traced together across librarians. Its methods mirror those of an OpenTelemetry
+// TextMapPropagator, so one is easily adapted to it.
+type TracePropagator interface {
+	// Inject sets the trace context of the span in the context on the carrier.
+	Inject(ctx context.Context, carrier TextMapCarrier)
+
+	// Extract returns a copy of the context with the trace context from the carrier, so spans
+	// started from it are children of the remote span.
+	Extract(ctx context.Context, carrier TextMapCarrier) context.Context
+}
+
+// MetadataCarrier is a TextMapCarrier of gRPC metadata, whose keys are lower case.
+type MetadataCarrier metadata.MD
+
+// Get returns the first value for the key.
+func (c MetadataCarrier) Get(key string) string {
+	values := c[strings.ToLower(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set sets the value for the key.
+func (c MetadataCarrier) Set(key string, value string) {
+	c[strings.ToLower(key)] = []string{value}
+}
+
+// Keys returns the keys with values.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// NewTracingUnaryInterceptor returns a unary client interceptor that injects the trace context
+// of the span in each request's context into its outgoing metadata.
+func NewTracingUnaryInterceptor(p TracePropagator) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(injectTraceContext(ctx, p), method, req, reply, cc, opts...)
+	}
+}
+
+// NewTracingStreamInterceptor returns a stream client interceptor that injects the trace context
+// of the span in each stream's context into its outgoing metadata.
+func NewTracingStreamInterceptor(p TracePropagator) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(injectTraceContext(ctx, p), desc, cc, method, opts...)
+	}
+}
+
+// ExtractTraceContext returns a copy of the context with the trace context from its incoming
+// metadata, if any.
+func ExtractTraceContext(ctx context.Context, p TracePropagator) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return p.Extract(ctx, MetadataCarrier(md))
+}
+
+func injectTraceContext(ctx context.Context, p TracePropagator) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		// don't modify metadata other contexts may share
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	p.Inject(ctx, MetadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const testTraceHeader = "Traceparent"
+
+func TestMetadataCarrier(t *testing.T) {
+	c := MetadataCarrier(metadata.MD{})
+	assert.Equal(t, "", c.Get(testTraceHeader))
+	assert.Empty(t, c.Keys())
+
+	c.Set(testTraceHeader, "some trace")
+	assert.Equal(t, "some trace", c.Get(testTraceHeader))
+	assert.Equal(t, []string{"traceparent"}, c.Keys())
+}
+
+func TestNewTracingUnaryInterceptor(t *testing.T) {
+	p := &fixedPropagator{}
+	var invokedCtx context.Context
+	invoker := func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		opts ...grpc.CallOption,
+	) error {
+		invokedCtx = ctx
+		return nil
+	}
+	unary := NewTracingUnaryInterceptor(p)
+
+	// trace context injected alongside existing metadata, which isn't modified
+	md := metadata.Pairs(storeAuthKey, "some token")
+	ctx := metadata.NewOutgoingContext(withTestTrace("some trace"), md)
+	err := unary(ctx, "/api.Librarian/Store", nil, nil, nil, invoker)
+	assert.Nil(t, err)
+	outMD, _ := metadata.FromOutgoingContext(invokedCtx)
+	assert.Equal(t, []string{"some trace"}, outMD[p.header()])
+	assert.Equal(t, []string{"some token"}, outMD[storeAuthKey])
+	assert.Nil(t, md[p.header()])
+
+	// trace context injected without existing metadata
+	err = unary(withTestTrace("other trace"), "/api.Librarian/Find", nil, nil, nil, invoker)
+	assert.Nil(t, err)
+	outMD, _ = metadata.FromOutgoingContext(invokedCtx)
+	assert.Equal(t, []string{"other trace"}, outMD[p.header()])
+}
+
+func TestNewTracingStreamInterceptor(t *testing.T) {
+	p := &fixedPropagator{}
+	var streamedCtx context.Context
+	streamer := func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		streamedCtx = ctx
+		return nil, nil
+	}
+	stream := NewTracingStreamInterceptor(p)
+
+	_, err := stream(withTestTrace("some trace"), nil, nil, "/api.Librarian/Subscribe", streamer)
+	assert.Nil(t, err)
+	outMD, _ := metadata.FromOutgoingContext(streamedCtx)
+	assert.Equal(t, []string{"some trace"}, outMD[p.header()])
+}
+
+func TestExtractTraceContext(t *testing.T) {
+	p := &fixedPropagator{}
+
+	// no incoming metadata
+	ctx := ExtractTraceContext(context.Background(), p)
+	assert.Nil(t, ctx.Value(testTraceKey{}))
+
+	md := metadata.Pairs(testTraceHeader, "some trace")
+	ctx = ExtractTraceContext(metadata.NewIncomingContext(context.Background(), md), p)
+	assert.Equal(t, "some trace", ctx.Value(testTraceKey{}))
+}
+
+type testTraceKey struct{}
+
+func withTestTrace(trace string) context.Context {
+	return context.WithValue(context.Background(), testTraceKey{}, trace)
+}
+
+// fixedPropagator propagates the trace string in the context under a single header.
+type fixedPropagator struct{}
+
+func (p *fixedPropagator) header() string {
+	return "traceparent"
+}
+
+func (p *fixedPropagator) Inject(ctx context.Context, carrier TextMapCarrier) {
+	if trace, ok := ctx.Value(testTraceKey{}).(string); ok {
+		carrier.Set(testTraceHeader, trace)
+	}
+}
+
+func (p *fixedPropagator) Extract(ctx context.Context, carrier TextMapCarrier) context.Context {
+	if trace := carrier.Get(testTraceHeader); trace != "" {
+		return context.WithValue(ctx, testTraceKey{}, trace)
+	}
+	return ctx
+}
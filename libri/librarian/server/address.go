@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"go.uber.org/zap"
+)
+
+var errUnexpectedPeerID = errors.New("peer at claimed address has unexpected ID")
+
+// introduceConfirmer confirms a peer's claimed addresses by sending an Introduce request to each
+// and checking that the responder has the claimed peer's ID. Since the requests carry fresh
+// random request IDs that the responses must echo, a stale or replayed response can't confirm an
+// address.
+type introduceConfirmer struct {
+	peerID     ecid.ID
+	orgID      ecid.ID
+	apiSelf    *api.PeerAddress
+	signer     client.Signer
+	orgSigner  client.Signer
+	introducer client.IntroducerCreator
+	timeout    time.Duration
+}
+
+func (c *introduceConfirmer) Confirm(claimed peer.Peer) error {
+	for _, address := range claimed.Addresses() {
+		if err := c.confirmAddress(claimed, address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *introduceConfirmer) confirmAddress(claimed peer.Peer, address *net.TCPAddr) error {
+	lc, err := c.introducer.Create(address.String())
+	if err != nil {
+		return err
+	}
+	rq := client.NewIntroduceRequest(c.peerID, c.orgID, c.apiSelf, 0)
+	ctx, cancel, err := client.NewSignedTimeoutContext(c.signer, c.orgSigner, rq, c.timeout)
+	if err != nil {
+		return err
+	}
+	rp, err := lc.Introduce(ctx, rq)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rp.Metadata.RequestId, rq.Metadata.RequestId) {
+		return client.ErrUnexpectedRequestID
+	}
+	responderID, err := newIDFromPublicKeyBytes(rp.Metadata.PubKey)
+	if err != nil {
+		return err
+	}
+	if responderID.Cmp(claimed.ID()) != 0 || rp.Self == nil ||
+		!bytes.Equal(rp.Self.PeerId, claimed.ID().Bytes()) {
+		return errUnexpectedPeerID
+	}
+	return nil
+}
+
+// maybeUpdateAddress applies a known peer's claimed change of address once it's confirmed.
+func (l *Librarian) maybeUpdateAddress(claimed peer.Peer) {
+	updated, err := l.addressUpdater.MaybeUpdate(claimed)
+	if err == routing.ErrAddressConfirmThrottled {
+		return
+	}
+	if err != nil {
+		l.logger.Info("unable to confirm peer address change",
+			zap.Stringer(logPeerID, claimed.ID()),
+			zap.Stringer(logAddress, claimed.Address()),
+			zap.Error(err),
+		)
+		return
+	}
+	if updated {
+		l.logger.Info("updated peer address",
+			zap.Stringer(logPeerID, claimed.ID()),
+			zap.Stringer(logAddress, claimed.Address()),
+		)
+	}
+}
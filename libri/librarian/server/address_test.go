@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestIntroduceConfirmer_Confirm_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	claimedID := ecid.NewPseudoRandom(rng)
+	claimed := peer.NewWithAddresses(claimedID.ID(), "", []*net.TCPAddr{
+		{IP: net.ParseIP("192.168.1.1"), Port: 20100},
+		{IP: net.ParseIP("2001:db8::1"), Port: 20100},
+	})
+	lc := &fixedConfirmIntroducerClient{responderID: claimedID}
+	c := newTestIntroduceConfirmer(rng, lc)
+
+	assert.Nil(t, c.Confirm(claimed))
+	assert.Equal(t, 2, lc.nCalls)
+}
+
+func TestIntroduceConfirmer_Confirm_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	claimedID := ecid.NewPseudoRandom(rng)
+	claimed := peer.New(claimedID.ID(), "", &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 1})
+
+	cases := map[string]struct {
+		lc       *fixedConfirmIntroducerClient
+		expected error
+	}{
+		"Introduce error": {
+			lc:       &fixedConfirmIntroducerClient{err: errors.New("some Introduce error")},
+			expected: errors.New("some Introduce error"),
+		},
+		"unexpected request ID": {
+			lc: &fixedConfirmIntroducerClient{
+				responderID: claimedID,
+				requestID:   api.RandBytes(rng, 32),
+			},
+			expected: client.ErrUnexpectedRequestID,
+		},
+		"different responder": {
+			lc:       &fixedConfirmIntroducerClient{responderID: ecid.NewPseudoRandom(rng)},
+			expected: errUnexpectedPeerID,
+		},
+		"different self": {
+			lc: &fixedConfirmIntroducerClient{
+				responderID: claimedID,
+				selfID:      ecid.NewPseudoRandom(rng),
+			},
+			expected: errUnexpectedPeerID,
+		},
+	}
+	for desc, c := range cases {
+		ic := newTestIntroduceConfirmer(rng, c.lc)
+		assert.Equal(t, c.expected, ic.Confirm(claimed), desc)
+	}
+
+	// signing error
+	ic := newTestIntroduceConfirmer(rng, &fixedConfirmIntroducerClient{responderID: claimedID})
+	ic.signer = &client.TestErrSigner{}
+	assert.NotNil(t, ic.Confirm(claimed))
+
+	// client creation error
+	ic.introducer = client.NewIntroducerCreator(&fixedPool{err: errors.New("some Get error")})
+	assert.NotNil(t, ic.Confirm(claimed))
+}
+
+func TestLibrarian_maybeUpdateAddress(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	claimed := peer.NewTestPeer(rng, 0)
+	l := &Librarian{logger: zap.NewNop()}
+
+	cases := []*fixedAddressUpdater{
+		{updated: true},
+		{updated: false},
+		{err: routing.ErrAddressConfirmThrottled},
+		{err: errors.New("some confirm error")},
+	}
+	for _, u := range cases {
+		l.addressUpdater = u
+		l.maybeUpdateAddress(claimed)
+		assert.Equal(t, claimed, u.claimed)
+	}
+}
+
+func newTestIntroduceConfirmer(
+	rng *rand.Rand, lc *fixedConfirmIntroducerClient,
+) *introduceConfirmer {
+	peerID := ecid.NewPseudoRandom(rng)
+	return &introduceConfirmer{
+		peerID:     peerID,
+		orgID:      ecid.NewPseudoRandom(rng),
+		apiSelf:    peer.NewTestPeer(rng, 0).ToAPI(),
+		signer:     &client.TestNoOpSigner{},
+		orgSigner:  &client.TestNoOpSigner{},
+		introducer: client.NewIntroducerCreator(&fixedPool{lc: lc}),
+		timeout:    time.Second,
+	}
+}
+
+type fixedConfirmIntroducerClient struct {
+	api.LibrarianClient
+	responderID ecid.ID
+	selfID      ecid.ID
+	requestID   []byte
+	err         error
+	nCalls      int
+}
+
+func (f *fixedConfirmIntroducerClient) Introduce(
+	ctx context.Context, in *api.IntroduceRequest, opts ...grpc.CallOption,
+) (*api.IntroduceResponse, error) {
+	f.nCalls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	requestID, selfID := f.requestID, f.selfID
+	if requestID == nil {
+		requestID = in.Metadata.RequestId
+	}
+	if selfID == nil {
+		selfID = f.responderID
+	}
+	return &api.IntroduceResponse{
+		Metadata: &api.ResponseMetadata{
+			RequestId: requestID,
+			PubKey:    f.responderID.PublicKeyBytes(),
+		},
+		Self: &api.PeerAddress{PeerId: selfID.ID().Bytes()},
+	}, nil
+}
+
+type fixedAddressUpdater struct {
+	claimed peer.Peer
+	updated bool
+	err     error
+}
+
+func (f *fixedAddressUpdater) MaybeUpdate(claimed peer.Peer) (bool, error) {
+	f.claimed = claimed
+	return f.updated, f.err
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultAllowListReloadInterval is the default interval at which the allow-list file is checked
+// for changes.
+const DefaultAllowListReloadInterval = 10 * time.Second
+
+var (
+	errAllowListNoAdmin   = errors.New("allow-list mode requires an admin public key")
+	errAllowListNotAdmin  = errors.New("allow-list not signed by admin key")
+	errAllowListOutdated  = errors.New("allow-list issued before current allow-list")
+	errAllowListDisabled  = errors.New("allow-list mode disabled")
+	errAllowListMissing   = errors.New("missing signed allow-list")
+	errNotOnAllowList     = errors.New("requester not on allow-list")
+	errAllowListBadPubKey = errors.New("allow-list contains invalid public key")
+)
+
+// AllowListParameters define allow-list mode, in which only peers whose public keys appear in an
+// allow-list signed by the admin key may be added to the routing table or serviced, making for a
+// fully private cluster.
+type AllowListParameters struct {
+	// File is the path of the signed allow-list, which is reloaded whenever it changes.
+	// Allow-list mode is disabled when empty.
+	File string
+
+	// ReloadInterval is the interval at which the file is checked for changes.
+	ReloadInterval time.Duration
+}
+
+// NewDefaultAllowListParameters returns a *AllowListParameters object with default values, which
+// leave allow-list mode disabled.
+func NewDefaultAllowListParameters() *AllowListParameters {
+	return &AllowListParameters{
+		ReloadInterval: DefaultAllowListReloadInterval,
+	}
+}
+
+// Enabled returns whether the librarian only allows peers on the allow-list.
+func (p *AllowListParameters) Enabled() bool {
+	return p.File != ""
+}
+
+// allowList manages the librarian's current signed allow-list and its file.
+type allowList struct {
+	params      *AllowListParameters
+	adminPubKey []byte
+	verifier    client.Verifier
+	peers       comm.AllowList
+	signed      *api.SignedAllowList
+	modTime     time.Time
+	mu          sync.Mutex
+}
+
+// newAllowList creates a new allowList applying the allowed peers to the given comm.AllowList
+// and loads the allow-list file, if allow-list mode is enabled. A missing file leaves no peers
+// allowed until the admin sets the allow-list.
+func newAllowList(
+	params *AllowListParameters, adminPubKey *ecdsa.PublicKey, peers comm.AllowList,
+) (*allowList, error) {
+	al := &allowList{
+		params:   params,
+		verifier: client.NewVerifier(),
+		peers:    peers,
+	}
+	if !params.Enabled() {
+		return al, nil
+	}
+	if adminPubKey == nil {
+		return nil, errAllowListNoAdmin
+	}
+	al.adminPubKey = ecid.ToPublicKeyBytes(adminPubKey)
+	if _, err := al.reload(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return al, nil
+}
+
+// reload loads and applies the allow-list file if it has changed since it was last loaded,
+// returning whether it was applied.
+func (al *allowList) reload() (bool, error) {
+	info, err := os.Stat(al.params.File)
+	if err != nil {
+		return false, err
+	}
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if info.ModTime().Equal(al.modTime) {
+		return false, nil
+	}
+	buf, err := ioutil.ReadFile(al.params.File)
+	if err != nil {
+		return false, err
+	}
+	sal := &api.SignedAllowList{}
+	if err = proto.Unmarshal(buf, sal); err != nil {
+		return false, err
+	}
+	peerIDs, err := al.verify(sal)
+	if err != nil {
+		return false, err
+	}
+	al.apply(sal, peerIDs, info.ModTime())
+	return true, nil
+}
+
+// set verifies and persists the signed allow-list to the file before applying it.
+func (al *allowList) set(sal *api.SignedAllowList) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	peerIDs, err := al.verify(sal)
+	if err != nil {
+		return err
+	}
+	buf, err := proto.Marshal(sal)
+	if err != nil {
+		return err
+	}
+
+	// write to temporary file and rename so the reload loop never reads a partial file
+	tmpFile := al.params.File + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, buf, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFile, al.params.File); err != nil {
+		return err
+	}
+	info, err := os.Stat(al.params.File)
+	if err != nil {
+		return err
+	}
+	al.apply(sal, peerIDs, info.ModTime())
+	return nil
+}
+
+// check returns an error if the signed allow-list is invalid.
+func (al *allowList) check(sal *api.SignedAllowList) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	_, err := al.verify(sal)
+	return err
+}
+
+// get returns the current signed allow-list, which is nil if none has been applied.
+func (al *allowList) get() *api.SignedAllowList {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.signed
+}
+
+// verify checks that the allow-list was signed by the admin key and wasn't issued before the
+// current one, returning the IDs of its peers.
+func (al *allowList) verify(sal *api.SignedAllowList) ([]id.ID, error) {
+	if err := client.VerifySignedAllowList(al.verifier, sal); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sal.PubKey, al.adminPubKey) {
+		return nil, errAllowListNotAdmin
+	}
+	if al.signed != nil && sal.AllowList.IssuedNanos < al.signed.AllowList.IssuedNanos {
+		return nil, errAllowListOutdated
+	}
+	peerIDs := make([]id.ID, len(sal.AllowList.PubKeys))
+	for i, pubKeyBytes := range sal.AllowList.PubKeys {
+		pubKey, err := ecid.FromPublicKeyBytes(pubKeyBytes)
+		if err != nil {
+			return nil, errAllowListBadPubKey
+		}
+		peerIDs[i] = id.FromPublicKey(pubKey)
+	}
+	return peerIDs, nil
+}
+
+func (al *allowList) apply(sal *api.SignedAllowList, peerIDs []id.ID, modTime time.Time) {
+	al.peers.Set(peerIDs)
+	al.signed = sal
+	al.modTime = modTime
+}
+
+// allowed returns whether the request may be serviced, which it may be if allow-list mode is
+// disabled or if it is from the admin or a peer or organization on the allow-list. Requests
+// without metadata (e.g., health checks) are always allowed. The organization is only honored
+// when the request is signed by it, since the org signature isn't verified when absent.
+func (al *allowList) allowed(ctx context.Context, req interface{}) bool {
+	if !al.params.Enabled() {
+		return true
+	}
+	rq, ok := req.(interface{ GetMetadata() *api.RequestMetadata })
+	if !ok || rq.GetMetadata() == nil {
+		return true
+	}
+	md := rq.GetMetadata()
+	if bytes.Equal(md.PubKey, al.adminPubKey) {
+		return true
+	}
+	if al.allowedPubKey(md.PubKey) {
+		return true
+	}
+	_, encOrgToken, err := client.FromSignatureContext(ctx)
+	return err == nil && encOrgToken != "" && al.allowedPubKey(md.OrgPubKey)
+}
+
+func (al *allowList) allowedPubKey(pubKeyBytes []byte) bool {
+	if len(pubKeyBytes) == 0 {
+		return false
+	}
+	pubKey, err := ecid.FromPublicKeyBytes(pubKeyBytes)
+	if err != nil {
+		return false
+	}
+	return al.peers.Allowed(id.FromPublicKey(pubKey))
+}
+
+// unaryInterceptor returns a unary server interceptor denying requests from requesters not on
+// the allow-list. It must run after the signature interceptor, which verifies the org signature.
+func (al *allowList) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !al.allowed(ctx, req) {
+			return nil, status.Error(codes.PermissionDenied, errNotOnAllowList.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor returns a stream server interceptor denying streams whose requests are from
+// requesters not on the allow-list.
+func (al *allowList) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &allowListServerStream{ServerStream: ss, al: al})
+	}
+}
+
+// allowListServerStream checks that each message received on the stream is allowed.
+type allowListServerStream struct {
+	grpc.ServerStream
+	al *allowList
+}
+
+func (s *allowListServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !s.al.allowed(s.ServerStream.Context(), m) {
+		return status.Error(codes.PermissionDenied, errNotOnAllowList.Error())
+	}
+	return nil
+}
+
+// reloadAllowList periodically reloads the allow-list file when it changes until the server
+// stops.
+func (l *Librarian) reloadAllowList() {
+	if !l.config.AllowList.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(l.config.AllowList.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			reloaded, err := l.allowList.reload()
+			if err != nil {
+				l.logger.Error("error reloading allow-list", zap.Error(err))
+			} else if reloaded {
+				l.logger.Info("reloaded allow-list", zap.Int(logNAllowed, l.allowList.peers.Len()))
+			}
+		}
+	}
+}
+
+// GetAllowList returns the current signed allow-list to requests signed by the admin key.
+func (l *Librarian) GetAllowList(ctx context.Context, rq *api.GetAllowListRequest) (
+	*api.GetAllowListResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received get allow-list request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+
+	return &api.GetAllowListResponse{
+		Metadata:  l.NewResponseMetadata(rq.Metadata),
+		AllowList: l.allowList.get(),
+	}, nil
+}
+
+// SetAllowList verifies, persists, and applies a new signed allow-list for requests signed by the
+// admin key.
+func (l *Librarian) SetAllowList(ctx context.Context, rq *api.SetAllowListRequest) (
+	*api.SetAllowListResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received set allow-list request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if !l.config.AllowList.Enabled() {
+		return nil, logReturnNotAllowedErr(lg,
+			status.Error(codes.FailedPrecondition, errAllowListDisabled.Error()))
+	}
+	if rq.AllowList == nil {
+		return nil, logReturnInvalidRqErr(lg, errAllowListMissing)
+	}
+	if err := l.allowList.check(rq.AllowList); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.allowList.set(rq.AllowList); err != nil {
+		return nil, logReturnInternalErr(lg, "error saving allow-list", err)
+	}
+	lg.Info("set allow-list", zap.Int(logNAllowed, l.allowList.peers.Len()))
+	return &api.SetAllowListResponse{Metadata: l.NewResponseMetadata(rq.Metadata)}, nil
+}
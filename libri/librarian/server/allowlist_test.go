@@ -0,0 +1,331 @@
+package server
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAllowListParameters_Enabled(t *testing.T) {
+	assert.False(t, NewDefaultAllowListParameters().Enabled())
+	assert.True(t, (&AllowListParameters{File: "allowlist.bin"}).Enabled())
+}
+
+func TestNewAllowList_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, peerID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	dir, err := ioutil.TempDir("", "test-allowlist")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	params := &AllowListParameters{File: filepath.Join(dir, "allowlist.bin")}
+
+	// disabled
+	al, err := newAllowList(NewDefaultAllowListParameters(), nil, comm.NewAllowList(nil))
+	assert.Nil(t, err)
+	assert.Nil(t, al.get())
+
+	// missing file allows no peers
+	peers := comm.NewAllowList(nil)
+	al, err = newAllowList(params, &adminID.Key().PublicKey, peers)
+	assert.Nil(t, err)
+	assert.Nil(t, al.get())
+	assert.Zero(t, peers.Len())
+
+	// existing file loaded
+	sal := newTestSignedAllowList(t, adminID, 1, peerID)
+	writeTestAllowList(t, params.File, sal)
+	peers = comm.NewAllowList(nil)
+	al, err = newAllowList(params, &adminID.Key().PublicKey, peers)
+	assert.Nil(t, err)
+	assert.Equal(t, sal, al.get())
+	assert.True(t, peers.Allowed(peerID.ID()))
+}
+
+func TestNewAllowList_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	dir, err := ioutil.TempDir("", "test-allowlist")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	params := &AllowListParameters{File: filepath.Join(dir, "allowlist.bin")}
+
+	// missing admin key
+	al, err := newAllowList(params, nil, comm.NewAllowList(nil))
+	assert.Equal(t, errAllowListNoAdmin, err)
+	assert.Nil(t, al)
+
+	// bad file contents
+	assert.Nil(t, ioutil.WriteFile(params.File, []byte("not a proto"), 0600))
+	al, err = newAllowList(params, &adminID.Key().PublicKey, comm.NewAllowList(nil))
+	assert.NotNil(t, err)
+	assert.Nil(t, al)
+
+	// signed by non-admin
+	writeTestAllowList(t, params.File, newTestSignedAllowList(t, otherID, 1))
+	al, err = newAllowList(params, &adminID.Key().PublicKey, comm.NewAllowList(nil))
+	assert.Equal(t, errAllowListNotAdmin, err)
+	assert.Nil(t, al)
+}
+
+func TestAllowList_reload(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID := ecid.NewPseudoRandom(rng)
+	peerID1, peerID2 := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	dir, err := ioutil.TempDir("", "test-allowlist")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	params := &AllowListParameters{File: filepath.Join(dir, "allowlist.bin")}
+	writeTestAllowList(t, params.File, newTestSignedAllowList(t, adminID, 1, peerID1))
+	revoked := 0
+	peers := comm.NewAllowList(func(peerID id.ID) { revoked++ })
+	al, err := newAllowList(params, &adminID.Key().PublicKey, peers)
+	assert.Nil(t, err)
+
+	// unchanged file not reapplied
+	reloaded, err := al.reload()
+	assert.Nil(t, err)
+	assert.False(t, reloaded)
+
+	// changed file reapplied
+	writeTestAllowList(t, params.File, newTestSignedAllowList(t, adminID, 2, peerID2))
+	future := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(params.File, future, future))
+	reloaded, err = al.reload()
+	assert.Nil(t, err)
+	assert.True(t, reloaded)
+	assert.False(t, peers.Allowed(peerID1.ID()))
+	assert.True(t, peers.Allowed(peerID2.ID()))
+	assert.Equal(t, 1, revoked)
+
+	// outdated allow-list not applied
+	writeTestAllowList(t, params.File, newTestSignedAllowList(t, adminID, 1, peerID1))
+	past := time.Now().Add(-time.Minute)
+	assert.Nil(t, os.Chtimes(params.File, past, past))
+	reloaded, err = al.reload()
+	assert.Equal(t, errAllowListOutdated, err)
+	assert.False(t, reloaded)
+	assert.True(t, peers.Allowed(peerID2.ID()))
+
+	// missing file
+	assert.Nil(t, os.Remove(params.File))
+	reloaded, err = al.reload()
+	assert.NotNil(t, err)
+	assert.False(t, reloaded)
+}
+
+func TestAllowList_allowed(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, peerID, orgID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	peers := comm.NewAllowList(nil)
+	peers.Set([]id.ID{peerID.ID(), orgID.ID()})
+	al := &allowList{
+		params:      &AllowListParameters{File: "allowlist.bin"},
+		adminPubKey: adminID.PublicKeyBytes(),
+		peers:       peers,
+	}
+
+	ctx := context.Background()
+	orgSignedCtx := client.NewIncomingSignatureContext(ctx, "signature", "org signature")
+	assert.True(t, al.allowed(ctx, client.NewFindRequest(adminID, nil, peerID.ID(), 8)))
+	assert.True(t, al.allowed(ctx, client.NewFindRequest(peerID, nil, peerID.ID(), 8)))
+	assert.True(t, al.allowed(orgSignedCtx, client.NewFindRequest(otherID, orgID, peerID.ID(), 8)))
+	assert.False(t, al.allowed(ctx, client.NewFindRequest(otherID, nil, peerID.ID(), 8)))
+	assert.False(t, al.allowed(ctx, &api.FindRequest{Metadata: &api.RequestMetadata{
+		PubKey: []byte{1, 2, 3},
+	}}))
+
+	// org not honored unless the request is org-signed
+	assert.False(t, al.allowed(ctx, client.NewFindRequest(otherID, orgID, peerID.ID(), 8)))
+	unsignedCtx := client.NewIncomingSignatureContext(ctx, "signature", "")
+	assert.False(t, al.allowed(unsignedCtx, client.NewFindRequest(otherID, orgID, peerID.ID(), 8)))
+
+	// requests without metadata always allowed
+	assert.True(t, al.allowed(ctx, nil))
+	assert.True(t, al.allowed(ctx, &api.FindRequest{}))
+
+	// everyone allowed when disabled
+	al.params = NewDefaultAllowListParameters()
+	assert.True(t, al.allowed(ctx, client.NewFindRequest(otherID, nil, peerID.ID(), 8)))
+}
+
+func TestAllowList_interceptors(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	peers := comm.NewAllowList(nil)
+	peers.Set([]id.ID{peerID.ID()})
+	al := &allowList{params: &AllowListParameters{File: "allowlist.bin"}, peers: peers}
+	allowedRq := client.NewFindRequest(peerID, nil, peerID.ID(), 8)
+	deniedRq := client.NewFindRequest(otherID, nil, peerID.ID(), 8)
+
+	handled := false
+	unaryHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handled = true
+		return nil, nil
+	}
+	unary := al.unaryInterceptor()
+	_, err := unary(context.Background(), allowedRq, &grpc.UnaryServerInfo{}, unaryHandler)
+	assert.Nil(t, err)
+	assert.True(t, handled)
+
+	handled = false
+	_, err = unary(context.Background(), deniedRq, &grpc.UnaryServerInfo{}, unaryHandler)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	assert.False(t, handled)
+
+	stream := al.streamInterceptor()
+	streamHandler := func(srv interface{}, ss grpc.ServerStream) error {
+		return ss.RecvMsg(&api.FindRequest{})
+	}
+	err = stream(nil, &fixedRecvServerStream{rq: allowedRq}, &grpc.StreamServerInfo{},
+		streamHandler)
+	assert.Nil(t, err)
+	err = stream(nil, &fixedRecvServerStream{rq: deniedRq}, &grpc.StreamServerInfo{},
+		streamHandler)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+}
+
+func TestLibrarian_GetSetAllowList_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, peerID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	dir, err := ioutil.TempDir("", "test-allowlist")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	l, peers := newAllowListLibrarian(t, rng, adminID, filepath.Join(dir, "allowlist.bin"))
+	ctx := context.Background()
+
+	rp1, err := l.GetAllowList(ctx, client.NewGetAllowListRequest(adminID, nil))
+	assert.Nil(t, err)
+	assert.NotNil(t, rp1.Metadata)
+	assert.Nil(t, rp1.AllowList)
+
+	sal := newTestSignedAllowList(t, adminID, 1, peerID)
+	rp2, err := l.SetAllowList(ctx, client.NewSetAllowListRequest(adminID, nil, sal))
+	assert.Nil(t, err)
+	assert.NotNil(t, rp2.Metadata)
+	assert.True(t, peers.Allowed(peerID.ID()))
+
+	rp1, err = l.GetAllowList(ctx, client.NewGetAllowListRequest(adminID, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, sal, rp1.AllowList)
+
+	// set allow-list persisted, so unchanged on reload
+	reloaded, err := l.allowList.reload()
+	assert.Nil(t, err)
+	assert.False(t, reloaded)
+	stored := &api.SignedAllowList{}
+	buf, err := ioutil.ReadFile(l.config.AllowList.File)
+	assert.Nil(t, err)
+	assert.Nil(t, proto.Unmarshal(buf, stored))
+	assert.Equal(t, sal, stored)
+}
+
+func TestLibrarian_GetSetAllowList_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	dir, err := ioutil.TempDir("", "test-allowlist")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	ctx := context.Background()
+	sal := newTestSignedAllowList(t, adminID, 1, otherID)
+
+	// check non-admin requester denied
+	l, _ := newAllowListLibrarian(t, rng, adminID, filepath.Join(dir, "allowlist.bin"))
+	_, err = l.GetAllowList(ctx, client.NewGetAllowListRequest(otherID, nil))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	_, err = l.SetAllowList(ctx, client.NewSetAllowListRequest(otherID, nil, sal))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// check request error bubbles up
+	getRq := client.NewGetAllowListRequest(adminID, nil)
+	getRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.GetAllowList(ctx, getRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	setRq := client.NewSetAllowListRequest(adminID, nil, sal)
+	setRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.SetAllowList(ctx, setRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check invalid allow-lists
+	_, err = l.SetAllowList(ctx, client.NewSetAllowListRequest(adminID, nil, nil))
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	_, err = l.SetAllowList(ctx, client.NewSetAllowListRequest(adminID, nil,
+		newTestSignedAllowList(t, otherID, 1)))
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check file write error bubbles up
+	l, _ = newAllowListLibrarian(t, rng, adminID, filepath.Join(dir, "missing", "allowlist.bin"))
+	_, err = l.SetAllowList(ctx, client.NewSetAllowListRequest(adminID, nil, sal))
+	assert.Equal(t, codes.Internal, getErrCode(t, err))
+
+	// check disabled allow-list mode
+	l.config.WithDefaultAllowList()
+	_, err = l.SetAllowList(ctx, client.NewSetAllowListRequest(adminID, nil, sal))
+	assert.Equal(t, codes.FailedPrecondition, getErrCode(t, err))
+}
+
+func newAllowListLibrarian(
+	t *testing.T, rng *rand.Rand, adminID ecid.ID, file string,
+) (*Librarian, comm.AllowList) {
+	config := NewDefaultConfig().
+		WithAdminPubKey(&adminID.Key().PublicKey).
+		WithAllowList(&AllowListParameters{File: file})
+	peers := comm.NewAllowList(nil)
+	al, err := newAllowList(config.AllowList, config.AdminPubKey, peers)
+	assert.Nil(t, err)
+	return &Librarian{
+		peerID:    ecid.NewPseudoRandom(rng),
+		config:    config,
+		rqv:       &alwaysRequestVerifier{},
+		allowList: al,
+		logger:    zap.NewNop(),
+	}, peers
+}
+
+func newTestSignedAllowList(
+	t *testing.T, signerID ecid.ID, issuedNanos int64, peerIDs ...ecid.ID,
+) *api.SignedAllowList {
+	list := &api.AllowList{IssuedNanos: issuedNanos}
+	for _, peerID := range peerIDs {
+		list.PubKeys = append(list.PubKeys, peerID.PublicKeyBytes())
+	}
+	sal, err := client.NewSignedAllowList(client.NewECDSASigner(signerID.Key()),
+		signerID.PublicKeyBytes(), list)
+	assert.Nil(t, err)
+	return sal
+}
+
+func writeTestAllowList(t *testing.T, file string, sal *api.SignedAllowList) {
+	buf, err := proto.Marshal(sal)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(file, buf, 0600))
+}
+
+type fixedRecvServerStream struct {
+	grpc.ServerStream
+	rq proto.Message
+}
+
+func (s *fixedRecvServerStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.rq)
+	return nil
+}
+
+func (s *fixedRecvServerStream) Context() context.Context {
+	return context.Background()
+}
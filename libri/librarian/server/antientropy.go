@@ -0,0 +1,505 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultAntiEntropyInterval is the default interval between syncs with the nearest
+	// neighbors.
+	DefaultAntiEntropyInterval = 10 * time.Minute
+
+	// DefaultAntiEntropyNNeighbors is the default number of nearest neighbors synced with.
+	DefaultAntiEntropyNNeighbors = uint(3)
+
+	// DefaultAntiEntropyMaxRangeKeys is the default maximum number of keys in a range for Sync
+	// to list them rather than split the range into sub-ranges.
+	DefaultAntiEntropyMaxRangeKeys = uint(64)
+
+	// DefaultAntiEntropyNSubRanges is the default number of sub-ranges Sync splits a range with
+	// too many keys into.
+	DefaultAntiEntropyNSubRanges = uint(16)
+
+	// DefaultAntiEntropyMaxPulls is the default maximum number of documents pulled from
+	// neighbors per sync.
+	DefaultAntiEntropyMaxPulls = uint(1024)
+
+	// DefaultAntiEntropyTimeout is the default timeout for each Sync and Find request to a
+	// neighbor.
+	DefaultAntiEntropyTimeout = 10 * time.Second
+
+	// storedKeysTTL is how long a snapshot of the stored keys is used to respond to Sync requests
+	// before it's reloaded.
+	storedKeysTTL = 1 * time.Minute
+)
+
+var (
+	errSyncBoundsOrder    = errors.New("sync lower bound is greater than upper bound")
+	errSyncRangeNotWithin = errors.New("sync response range is not within requested range")
+)
+
+// AntiEntropyParameters define how a librarian periodically syncs the keys it stores with those of
+// its nearest neighbors, pulling the documents it should hold but doesn't. This catches replicas
+// lost to churn without relying solely on client-driven repair.
+type AntiEntropyParameters struct {
+	// Interval is the interval between syncs with the nearest neighbors. Anti-entropy is
+	// disabled when zero.
+	Interval time.Duration
+
+	// NNeighbors is the number of nearest neighbors synced with.
+	NNeighbors uint
+
+	// MaxRangeKeys is the maximum number of keys in a range for Sync to list them rather than
+	// split the range into sub-ranges.
+	MaxRangeKeys uint
+
+	// NSubRanges is the number of sub-ranges Sync splits a range with too many keys into.
+	NSubRanges uint
+
+	// MaxPulls is the maximum number of documents pulled from neighbors per sync.
+	MaxPulls uint
+
+	// Timeout is the timeout for each Sync and Find request to a neighbor.
+	Timeout time.Duration
+}
+
+// NewDefaultAntiEntropyParameters returns a *AntiEntropyParameters object with default values.
+func NewDefaultAntiEntropyParameters() *AntiEntropyParameters {
+	return &AntiEntropyParameters{
+		Interval:     DefaultAntiEntropyInterval,
+		NNeighbors:   DefaultAntiEntropyNNeighbors,
+		MaxRangeKeys: DefaultAntiEntropyMaxRangeKeys,
+		NSubRanges:   DefaultAntiEntropyNSubRanges,
+		MaxPulls:     DefaultAntiEntropyMaxPulls,
+		Timeout:      DefaultAntiEntropyTimeout,
+	}
+}
+
+// Enabled returns whether the librarian periodically syncs with its nearest neighbors.
+func (p *AntiEntropyParameters) Enabled() bool {
+	return p.Interval > 0
+}
+
+// Sync returns the digests of the sub-ranges of the requested key range, or the keys stored
+// within it if there are few enough.
+func (l *Librarian) Sync(ctx context.Context, rq *api.SyncRequest) (*api.SyncResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received sync request", syncRequestFields(rq)...)
+	endpoint := api.Sync
+
+	requesterID, err := l.checkSyncRequest(ctx, rq)
+	if err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err = l.allower.Allow(requesterID, endpoint); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	l.record(requesterID, endpoint, comm.Request, comm.Success)
+
+	keys, err := l.storedKeys.get()
+	if err != nil {
+		return nil, logReturnInternalErr(lg, "error loading stored keys", err)
+	}
+	kr := keyRange{lower: id.FromBytes(rq.LowerBound), upper: id.FromBytes(rq.UpperBound)}
+	rp := &api.SyncResponse{Metadata: l.NewResponseMetadata(rq.Metadata)}
+	rp.Ranges, rp.Keys = digestRange(keys, kr, l.config.AntiEntropy.MaxRangeKeys,
+		l.config.AntiEntropy.NSubRanges)
+	lg.Debug("synced key range", syncResponseFields(rq, rp)...)
+	return rp, nil
+}
+
+// checkSyncRequest verifies the request signature and key range, returning the ID of the
+// requester.
+func (l *Librarian) checkSyncRequest(ctx context.Context, rq *api.SyncRequest) (id.ID, error) {
+	requesterID, err := l.checkRequestAndKey(rq.Metadata, rq.LowerBound)
+	if err != nil {
+		return requesterID, err
+	}
+	if err = l.kc.Check(rq.UpperBound); err != nil {
+		return requesterID, err
+	}
+	if bytes.Compare(rq.LowerBound, rq.UpperBound) > 0 {
+		return requesterID, errSyncBoundsOrder
+	}
+	return requesterID, nil
+}
+
+// syncNeighbors periodically syncs with the nearest neighbors until the server stops.
+func (l *Librarian) syncNeighbors() {
+	if !l.config.AntiEntropy.Enabled() || l.config.Mirror.Enabled() {
+		// mirrors only store the documents of the authors they mirror
+		return
+	}
+	ticker := time.NewTicker(l.config.AntiEntropy.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.syncNeighborsOnce()
+		}
+	}
+}
+
+func (l *Librarian) syncNeighborsOnce() {
+	neighbors := l.rt.Find(l.peerID.ID(), l.config.AntiEntropy.NNeighbors)
+	nPulled := uint(0)
+	for _, p := range neighbors {
+		if nPulled >= l.config.AntiEntropy.MaxPulls {
+			break
+		}
+		n, err := l.syncNeighbor(p, l.config.AntiEntropy.MaxPulls-nPulled)
+		nPulled += n
+		if err != nil {
+			l.record(p.ID(), api.Sync, comm.Response, comm.Error)
+			l.logger.Debug("failed to sync with neighbor",
+				zap.Stringer(logPeerID, p.ID()),
+				zap.Error(err),
+			)
+			continue
+		}
+		l.record(p.ID(), api.Sync, comm.Response, comm.Success)
+	}
+	l.logger.Info("synced with neighbors",
+		zap.Int(logNPeers, len(neighbors)),
+		zap.Uint(logNPulled, nPulled),
+	)
+}
+
+// neighborSync is the state of a sync with a single neighbor.
+type neighborSync struct {
+	lc       api.LibrarianClient
+	local    storedKeys
+	nPulled  uint
+	maxPulls uint
+}
+
+// syncNeighbor compares the keys stored by the neighbor with those stored locally, pulling up to
+// maxPulls documents it should hold but doesn't. It returns the number of documents pulled.
+func (l *Librarian) syncNeighbor(p peer.Peer, maxPulls uint) (uint, error) {
+	lc, err := l.clients.Get(p.Address().String())
+	if err != nil {
+		return 0, err
+	}
+	local, err := l.storedKeys.get()
+	if err != nil {
+		return 0, err
+	}
+	ns := &neighborSync{lc: lc, local: local, maxPulls: maxPulls}
+	err = l.syncRange(ns, keyRange{lower: id.LowerBound, upper: id.UpperBound})
+	if ns.nPulled > 0 {
+		// so subsequent Syncs reflect the pulled documents
+		l.storedKeys.invalidate()
+	}
+	return ns.nPulled, err
+}
+
+// syncRange recursively compares the neighbor's sub-range digests with the local ones, descending
+// into those that differ until the neighbor lists the keys in a range.
+func (l *Librarian) syncRange(ns *neighborSync, kr keyRange) error {
+	rq := client.NewSyncRequest(l.peerID, l.orgID, kr.lower, kr.upper)
+	ctx, cancel, err := client.NewSignedTimeoutContext(l.signer, l.orgSigner, rq,
+		l.config.AntiEntropy.Timeout)
+	if err != nil {
+		return err
+	}
+	rp, err := ns.lc.Sync(ctx, rq)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rp.Metadata.RequestId, rq.Metadata.RequestId) {
+		return client.ErrUnexpectedRequestID
+	}
+	if len(rp.Ranges) == 0 {
+		return l.pullMissing(ns, rp.Keys)
+	}
+	for _, rd := range rp.Ranges {
+		sub := keyRange{lower: id.FromBytes(rd.LowerBound), upper: id.FromBytes(rd.UpperBound)}
+		if !kr.strictlyContains(sub) {
+			// otherwise a misbehaving neighbor could keep us syncing forever
+			return errSyncRangeNotWithin
+		}
+		if rd.NKeys == 0 || bytes.Equal(rd.Digest, ns.local.within(sub).digest()) {
+			continue
+		}
+		if ns.nPulled >= ns.maxPulls {
+			return nil
+		}
+		if err := l.syncRange(ns, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pullMissing fetches from the neighbor and stores the documents with the given keys that aren't
+// stored locally but should be, i.e., self is among the peers closest to them.
+func (l *Librarian) pullMissing(ns *neighborSync, keys [][]byte) error {
+	for _, keyBytes := range keys {
+		if ns.nPulled >= ns.maxPulls {
+			return nil
+		}
+		key := id.FromBytes(keyBytes)
+		if ns.local.contains(key) || !l.rt.AmongClosest(key, l.config.Store.NReplicas) {
+			continue
+		}
+		value, err := l.pull(ns.lc, key)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			// neighbor no longer has it
+			continue
+		}
+		stored, err := l.storePulled(key, value)
+		if err != nil {
+			return err
+		}
+		if stored {
+			ns.nPulled++
+		}
+	}
+	return nil
+}
+
+// pull fetches the document with the given key from the neighbor, returning nil if it doesn't
+// have it.
+func (l *Librarian) pull(lc api.Finder, key id.ID) (*api.Document, error) {
+	rq := client.NewFindRequest(l.peerID, l.orgID, key, 0)
+	ctx, cancel, err := client.NewSignedTimeoutContext(l.signer, l.orgSigner, rq,
+		l.config.AntiEntropy.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	rp, err := lc.Find(ctx, rq)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(rp.Metadata.RequestId, rq.Metadata.RequestId) {
+		return nil, client.ErrUnexpectedRequestID
+	}
+	return rp.Value, nil
+}
+
+// storePulled validates a document pulled from a neighbor the same way the Store handler does
+// before storing it, returning whether it was stored. Since the organization that originally
+// stored it is unknown, it is charged to the zero ID, like requests not signed by their
+// organization.
+func (l *Librarian) storePulled(key id.ID, value *api.Document) (bool, error) {
+	lg := l.logger.With(zap.Stringer(logKey, key))
+	if err := l.checkKeyValue(key.Bytes(), value); err != nil {
+		lg.Debug("pulled invalid document", zap.Error(err))
+		return false, nil
+	}
+	if err := l.checkPageSize(value); err != nil {
+		lg.Debug("pulled invalid document", zap.Error(err))
+		return false, nil
+	}
+	if err := l.reserveQuota(id.LowerBound, key, value); err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			lg.Debug("pulled document exceeds quota", zap.Error(err))
+			return false, nil
+		}
+		return false, err
+	}
+	if err := l.documentSL.Store(key, value); err != nil {
+		if err := l.quotas.Release(key); err != nil {
+			lg.Error("error releasing quota", zap.Error(err))
+		}
+		return false, err
+	}
+	l.forgetNotFound(key)
+	if err := l.storageMetrics.Add(value); err != nil {
+		// don't hard-fail on this since just internal book-keeping
+		lg.Error("error storing metric", zap.Error(err))
+	}
+	return true, nil
+}
+
+// digestRange returns the digests of the nSubRanges sub-ranges of the key range if it contains
+// more than maxKeys keys and otherwise the keys themselves.
+func digestRange(keys storedKeys, kr keyRange, maxKeys, nSubRanges uint) (
+	[]*api.KeyRangeDigest, [][]byte) {
+	within := keys.within(kr)
+	subs := kr.split(nSubRanges)
+	if uint(len(within)) <= maxKeys || len(subs) == 1 {
+		return nil, within.bytes()
+	}
+	digests := make([]*api.KeyRangeDigest, len(subs))
+	for i, sub := range subs {
+		subKeys := within.within(sub)
+		digests[i] = &api.KeyRangeDigest{
+			LowerBound: sub.lower.Bytes(),
+			UpperBound: sub.upper.Bytes(),
+			NKeys:      uint32(len(subKeys)),
+			Digest:     subKeys.digest(),
+		}
+	}
+	return digests, nil
+}
+
+// keyRange is an inclusive range of keys.
+type keyRange struct {
+	lower id.ID
+	upper id.ID
+}
+
+// split divides the range into n (or, if the range is narrower, as many as it has keys)
+// contiguous sub-ranges of (nearly) equal width.
+func (kr keyRange) split(n uint) []keyRange {
+	if n < 2 {
+		n = 2
+	}
+	one := big.NewInt(1)
+	width := new(big.Int).Sub(kr.upper.Int(), kr.lower.Int())
+	width.Add(width, one)
+	nBig := new(big.Int).SetUint64(uint64(n))
+	if width.Cmp(nBig) < 0 {
+		nBig.Set(width)
+	}
+	step := new(big.Int).Div(width, nBig)
+	subs := make([]keyRange, 0, nBig.Int64())
+	lower := kr.lower.Int()
+	for i := int64(0); i < nBig.Int64(); i++ {
+		upper := new(big.Int).Add(lower, step)
+		upper.Sub(upper, one)
+		if i == nBig.Int64()-1 {
+			upper = kr.upper.Int()
+		}
+		subs = append(subs, keyRange{lower: id.FromInt(lower), upper: id.FromInt(upper)})
+		lower = new(big.Int).Add(upper, one)
+	}
+	return subs
+}
+
+// strictlyContains returns whether the other range is within but narrower than this range.
+func (kr keyRange) strictlyContains(other keyRange) bool {
+	if other.lower.Cmp(kr.lower) < 0 || other.upper.Cmp(kr.upper) > 0 {
+		return false
+	}
+	return other.lower.Cmp(kr.lower) != 0 || other.upper.Cmp(kr.upper) != 0
+}
+
+// storedKeys is a sorted snapshot of the keys of the stored documents.
+type storedKeys []id.ID
+
+// loadStoredKeys returns the keys of the stored documents. Documents stored with a TTL are
+// excluded since their expire times aren't synced, so neighbors pulling them would keep them
+// forever. Shards are also excluded since each is only stored on the single peer closest to it.
+func loadStoredKeys(docs storage.ExpiringDocumentSLD) (storedKeys, error) {
+	all := make(storedKeys, 0)
+	err := docs.Iterate(make(chan struct{}), func(key id.ID, _ []byte) {
+		if !api.IsShardKey(key) {
+			all = append(all, key)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// check expire times after iterating, since loading while iterating over the DB isn't safe
+	keys := make(storedKeys, 0, len(all))
+	for _, key := range all {
+		_, expiring, err := docs.ExpireTime(key)
+		if err != nil {
+			return nil, err
+		}
+		if !expiring {
+			keys = append(keys, key)
+		}
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+func sortKeys(keys storedKeys) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Cmp(keys[j]) < 0 })
+}
+
+// within returns the keys within the range.
+func (k storedKeys) within(kr keyRange) storedKeys {
+	i := sort.Search(len(k), func(i int) bool { return k[i].Cmp(kr.lower) >= 0 })
+	j := sort.Search(len(k), func(j int) bool { return k[j].Cmp(kr.upper) > 0 })
+	return k[i:j]
+}
+
+// contains returns whether the key is stored.
+func (k storedKeys) contains(key id.ID) bool {
+	i := sort.Search(len(k), func(i int) bool { return k[i].Cmp(key) >= 0 })
+	return i < len(k) && k[i].Cmp(key) == 0
+}
+
+// digest returns the SHA-256 hash of the concatenated keys.
+func (k storedKeys) digest() []byte {
+	h := sha256.New()
+	for _, key := range k {
+		_, _ = h.Write(key.Bytes())
+	}
+	return h.Sum(nil)
+}
+
+func (k storedKeys) bytes() [][]byte {
+	bs := make([][]byte, len(k))
+	for i, key := range k {
+		bs[i] = key.Bytes()
+	}
+	return bs
+}
+
+// storedKeysCache caches the snapshot of the stored keys so Sync requests from each neighbor
+// don't iterate over all the stored documents.
+type storedKeysCache struct {
+	docs     storage.ExpiringDocumentSLD
+	ttl      time.Duration
+	keys     storedKeys
+	loadedAt time.Time
+	mu       sync.Mutex
+}
+
+func newStoredKeysCache(docs storage.ExpiringDocumentSLD) *storedKeysCache {
+	return &storedKeysCache{docs: docs, ttl: storedKeysTTL}
+}
+
+// get returns the snapshot of the stored keys, reloading it if it is stale.
+func (c *storedKeysCache) get() (storedKeys, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys != nil && time.Since(c.loadedAt) < c.ttl {
+		return c.keys, nil
+	}
+	keys, err := loadStoredKeys(c.docs)
+	if err != nil {
+		return nil, err
+	}
+	c.keys, c.loadedAt = keys, time.Now()
+	return keys, nil
+}
+
+// invalidate makes the next get reload the snapshot.
+func (c *storedKeysCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = nil
+}
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestAntiEntropyParameters_Enabled(t *testing.T) {
+	assert.True(t, NewDefaultAntiEntropyParameters().Enabled())
+	assert.False(t, (&AntiEntropyParameters{}).Enabled())
+}
+
+func TestLibrarian_Sync(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestSyncLibrarian(rng, 2)
+	keys := make(storedKeys, 0)
+	for i := 0; i < 100; i++ {
+		value, key := api.NewTestDocument(rng)
+		assert.Nil(t, l.documentSL.Store(key, value))
+		keys = append(keys, key)
+	}
+
+	// many keys in full range should give sub-range digests
+	rq := client.NewSyncRequest(l.peerID, l.orgID, id.LowerBound, id.UpperBound)
+	rp, err := l.Sync(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+	assert.Len(t, rp.Ranges, int(l.config.AntiEntropy.NSubRanges))
+	assert.Nil(t, rp.Keys)
+	nKeys := uint32(0)
+	for _, rd := range rp.Ranges {
+		nKeys += rd.NKeys
+	}
+	assert.Equal(t, uint32(len(keys)), nKeys)
+
+	// narrow range should give keys
+	rq = client.NewSyncRequest(l.peerID, l.orgID, keys[0], keys[0])
+	rp, err = l.Sync(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Nil(t, rp.Ranges)
+	assert.Equal(t, [][]byte{keys[0].Bytes()}, rp.Keys)
+
+	// bounds out of order should be invalid
+	rq = client.NewSyncRequest(l.peerID, l.orgID, id.UpperBound, id.LowerBound)
+	rp, err = l.Sync(context.Background(), rq)
+	assert.NotNil(t, err)
+	assert.Nil(t, rp)
+}
+
+func TestLibrarian_syncNeighbor(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestSyncLibrarian(rng, 2)
+	neighbor := &fixedSyncClient{
+		docs:   make(map[string]*api.Document),
+		params: l.config.AntiEntropy,
+	}
+	for i := 0; i < 200; i++ {
+		value, key := api.NewTestDocument(rng)
+		neighbor.add(key, value)
+		if i%2 == 0 {
+			assert.Nil(t, l.documentSL.Store(key, value))
+		}
+	}
+	l.clients = &fixedPool{lc: neighbor}
+	p := peer.NewTestPeer(rng, 0)
+
+	// pull limited number of missing documents
+	nPulled, err := l.syncNeighbor(p, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(10), nPulled)
+
+	// pull the rest of the missing documents
+	nPulled, err = l.syncNeighbor(p, DefaultAntiEntropyMaxPulls)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(90), nPulled)
+	for _, key := range neighbor.keys {
+		value, err := l.documentSL.Load(key)
+		assert.Nil(t, err)
+		assert.Equal(t, neighbor.docs[key.String()], value)
+	}
+
+	// nothing left to pull, so only the full range should be requested
+	neighbor.nSyncs = 0
+	nPulled, err = l.syncNeighbor(p, DefaultAntiEntropyMaxPulls)
+	assert.Nil(t, err)
+	assert.Zero(t, nPulled)
+	assert.Equal(t, 1, neighbor.nSyncs)
+}
+
+func TestLibrarian_syncNeighbor_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestSyncLibrarian(rng, 2)
+	p := peer.NewTestPeer(rng, 0)
+
+	// bad sub-range from neighbor
+	neighbor := &fixedSyncClient{
+		docs:      make(map[string]*api.Document),
+		params:    l.config.AntiEntropy,
+		badRanges: true,
+	}
+	for i := 0; i < 100; i++ {
+		value, key := api.NewTestDocument(rng)
+		neighbor.add(key, value)
+	}
+	l.clients = &fixedPool{lc: neighbor}
+	nPulled, err := l.syncNeighbor(p, DefaultAntiEntropyMaxPulls)
+	assert.Equal(t, errSyncRangeNotWithin, err)
+	assert.Zero(t, nPulled)
+
+	// invalid document from neighbor isn't stored
+	_, key := api.NewTestDocument(rng)
+	otherValue, _ := api.NewTestDocument(rng)
+	neighbor = &fixedSyncClient{
+		docs:   map[string]*api.Document{key.String(): otherValue},
+		keys:   storedKeys{key},
+		params: l.config.AntiEntropy,
+	}
+	l.clients = &fixedPool{lc: neighbor}
+	nPulled, err = l.syncNeighbor(p, DefaultAntiEntropyMaxPulls)
+	assert.Nil(t, err)
+	assert.Zero(t, nPulled)
+	stored, err := l.documentSL.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestLibrarian_storePulled(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestSyncLibrarian(rng, 2)
+
+	// valid document is stored and charged to the zero ID
+	value, key := api.NewTestDocument(rng)
+	stored, err := l.storePulled(key, value)
+	assert.Nil(t, err)
+	assert.True(t, stored)
+	loaded, err := l.documentSL.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, loaded)
+	orgID, in, err := l.quotas.OriginOrgID(key)
+	assert.Nil(t, err)
+	assert.True(t, in)
+	// compared by value since the loaded zero ID's big.Int differs in representation
+	assert.Equal(t, 0, id.LowerBound.Cmp(orgID))
+
+	// page that's too large isn't stored
+	page := api.NewTestPage(rng)
+	pageDoc := &api.Document{Contents: &api.Document_Page{Page: page}}
+	pageKey, err := api.GetKey(pageDoc)
+	assert.Nil(t, err)
+	l.config.WithMaxPageSize(uint32(len(page.Ciphertext)) - api.PageCiphertextOverhead - 1)
+	stored, err = l.storePulled(pageKey, pageDoc)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+	l.config.WithDefaultMaxPageSize()
+
+	// shard under non-shard key isn't stored
+	shard, _ := api.NewTestShardDocument(rng)
+	_, otherKey := api.NewTestDocument(rng)
+	stored, err = l.storePulled(otherKey, shard)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+
+	// document exceeding the quota isn't stored
+	l.quotas = newTestOrgQuotas(&QuotaParameters{DefaultLimitBytes: 1})
+	value, key = api.NewTestDocument(rng)
+	stored, err = l.storePulled(key, value)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+	loaded, err = l.documentSL.Load(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+}
+
+func TestLibrarian_syncNeighborsOnce(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestSyncLibrarian(rng, 2)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	l.rec = rec
+	neighbor := &fixedSyncClient{
+		docs:   make(map[string]*api.Document),
+		params: l.config.AntiEntropy,
+	}
+	value, key := api.NewTestDocument(rng)
+	neighbor.add(key, value)
+	l.clients = &fixedPool{lc: neighbor}
+
+	l.syncNeighborsOnce()
+	stored, err := l.documentSL.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, stored)
+	for _, p := range l.rt.Find(l.peerID.ID(), l.config.AntiEntropy.NNeighbors) {
+		qo := rec.Get(p.ID(), api.Sync)
+		assert.Equal(t, uint64(1), qo[comm.Response][comm.Success].Count)
+	}
+}
+
+func TestKeyRange_split(t *testing.T) {
+	full := keyRange{lower: id.LowerBound, upper: id.UpperBound}
+	subs := full.split(16)
+	assert.Len(t, subs, 16)
+	assert.Equal(t, 0, id.LowerBound.Cmp(subs[0].lower))
+	assert.Equal(t, 0, id.UpperBound.Cmp(subs[15].upper))
+	for i := 1; i < len(subs); i++ {
+		// sub-ranges should be contiguous
+		expected := new(big.Int).Add(subs[i-1].upper.Int(), big.NewInt(1))
+		assert.Equal(t, 0, subs[i].lower.Int().Cmp(expected))
+		assert.True(t, full.strictlyContains(subs[i]))
+	}
+
+	// ranges narrower than the number of sub-ranges split into single keys
+	narrow := keyRange{lower: id.FromInt64(10), upper: id.FromInt64(12)}
+	subs = narrow.split(16)
+	assert.Len(t, subs, 3)
+	for i, sub := range subs {
+		assert.Equal(t, 0, sub.lower.Cmp(id.FromInt64(int64(10+i))))
+		assert.Equal(t, 0, sub.upper.Cmp(id.FromInt64(int64(10+i))))
+	}
+	assert.Len(t, subs[0].split(16), 1)
+	assert.False(t, subs[0].strictlyContains(subs[0]))
+}
+
+func TestStoredKeys(t *testing.T) {
+	keys := storedKeys{id.FromInt64(1), id.FromInt64(3), id.FromInt64(5), id.FromInt64(7)}
+	within := keys.within(keyRange{lower: id.FromInt64(2), upper: id.FromInt64(5)})
+	assert.Equal(t, storedKeys{id.FromInt64(3), id.FromInt64(5)}, within)
+	assert.True(t, keys.contains(id.FromInt64(5)))
+	assert.False(t, keys.contains(id.FromInt64(4)))
+	assert.False(t, keys.contains(id.FromInt64(8)))
+
+	assert.Equal(t, within.digest(), storedKeys{id.FromInt64(3), id.FromInt64(5)}.digest())
+	assert.NotEqual(t, within.digest(), keys.digest())
+	assert.Len(t, storedKeys{}.digest(), 32)
+}
+
+func TestLoadStoredKeys(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	docs := storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb)
+	for i := 0; i < 8; i++ {
+		value, key := api.NewTestDocument(rng)
+		assert.Nil(t, docs.Store(key, value))
+	}
+	value, key := api.NewTestDocument(rng)
+	assert.Nil(t, docs.StoreExpiring(key, value, time.Now().Add(time.Hour)))
+	shard, shardKey := api.NewTestShardDocument(rng)
+	assert.Nil(t, docs.Store(shardKey, shard))
+
+	// expiring documents and shards are excluded
+	keys, err := loadStoredKeys(docs)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 8)
+	assert.False(t, keys.contains(key))
+	assert.False(t, keys.contains(shardKey))
+	for i := 1; i < len(keys); i++ {
+		assert.True(t, keys[i-1].Cmp(keys[i]) < 0)
+	}
+
+	// cache shouldn't reload until invalidated
+	c := newStoredKeysCache(docs)
+	keys, err = c.get()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 8)
+	value, key = api.NewTestDocument(rng)
+	assert.Nil(t, docs.Store(key, value))
+	keys, err = c.get()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 8)
+	c.invalidate()
+	keys, err = c.get()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 9)
+}
+
+func newTestSyncLibrarian(rng *rand.Rand, nPeers int) *Librarian {
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, nPeers)
+	kvdb := db.NewMemoryDB()
+	expiring := storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb)
+	config := NewDefaultConfig()
+	config.AntiEntropy.MaxRangeKeys = 8
+	config.AntiEntropy.NSubRanges = 4
+	return &Librarian{
+		peerID:         peerID,
+		config:         config,
+		documentSL:     expiring,
+		expiring:       expiring,
+		storedKeys:     newStoredKeysCache(expiring),
+		rt:             rt,
+		kc:             storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:            storage.NewDocumentKeyValueChecker(),
+		rqv:            &alwaysRequestVerifier{},
+		rec:            comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
+		allower:        &fixedAllower{},
+		rqLimiter:      comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		storageMetrics: newStorageMetrics(storage.NewServerSL(kvdb)),
+		quotas:         newTestOrgQuotas(NewDefaultQuotaParameters()),
+		signer:         &client.TestNoOpSigner{},
+		orgSigner:      &client.TestNoOpSigner{},
+		logger:         zap.NewNop(),
+	}
+}
+
+// fixedSyncClient responds to Sync and Find requests from a fixed set of documents.
+type fixedSyncClient struct {
+	api.LibrarianClient
+	keys      storedKeys
+	docs      map[string]*api.Document
+	params    *AntiEntropyParameters
+	badRanges bool
+	nSyncs    int
+}
+
+func (f *fixedSyncClient) add(key id.ID, value *api.Document) {
+	f.docs[key.String()] = value
+	f.keys = append(f.keys, key)
+	sortKeys(f.keys)
+}
+
+func (f *fixedSyncClient) Sync(
+	ctx context.Context, in *api.SyncRequest, opts ...grpc.CallOption,
+) (*api.SyncResponse, error) {
+	f.nSyncs++
+	kr := keyRange{lower: id.FromBytes(in.LowerBound), upper: id.FromBytes(in.UpperBound)}
+	rp := &api.SyncResponse{Metadata: &api.ResponseMetadata{RequestId: in.Metadata.RequestId}}
+	rp.Ranges, rp.Keys = digestRange(f.keys, kr, f.params.MaxRangeKeys, f.params.NSubRanges)
+	if f.badRanges && len(rp.Ranges) > 0 {
+		rp.Ranges[0].LowerBound, rp.Ranges[0].UpperBound = in.LowerBound, in.UpperBound
+	}
+	return rp, nil
+}
+
+func (f *fixedSyncClient) Find(
+	ctx context.Context, in *api.FindRequest, opts ...grpc.CallOption,
+) (*api.FindResponse, error) {
+	return &api.FindResponse{
+		Metadata: &api.ResponseMetadata{RequestId: in.Metadata.RequestId},
+		Value:    f.docs[id.FromBytes(in.Key).String()],
+	}, nil
+}
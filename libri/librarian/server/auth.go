@@ -0,0 +1,147 @@
+package server
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/golang/protobuf/proto"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// librarianServicePrefix prefixes the full methods of the Librarian service, whose requests
+	// are all signed, as opposed to those of, e.g., the health and reflection services.
+	librarianServicePrefix = "/api.Librarian/"
+
+	authReasonLabel = "reason"
+
+	authReasonUnsigned  = "unsigned"
+	authReasonSignature = "invalid_signature"
+)
+
+var errUnsignedRequest = errors.New("request missing signed metadata")
+
+// signedRequest is a request of the Librarian service.
+type signedRequest interface {
+	proto.Message
+	GetMetadata() *api.RequestMetadata
+}
+
+// signatureUnaryInterceptor returns a unary server interceptor that verifies the signature of
+// each Librarian request before its handler runs, rejecting unsigned requests and those whose
+// signatures don't verify. Handlers don't verify signatures themselves, so it must run for every
+// Librarian request, after the worker pools bound the concurrent verifications.
+func (l *Librarian) signatureUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, librarianServicePrefix) {
+			return handler(ctx, req)
+		}
+		if err := l.verifySignature(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// signatureStreamInterceptor returns a stream server interceptor that verifies the signature of
+// each request received on Librarian streams, e.g., Subscribe, before its handler runs.
+func (l *Librarian) signatureStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if !strings.HasPrefix(info.FullMethod, librarianServicePrefix) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &signatureServerStream{
+			ServerStream: ss,
+			method:       info.FullMethod,
+			l:            l,
+		})
+	}
+}
+
+// signatureServerStream verifies the signature of each request received on the stream.
+type signatureServerStream struct {
+	grpc.ServerStream
+	method string
+	l      *Librarian
+}
+
+func (s *signatureServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.l.verifySignature(s.Context(), s.method, m)
+}
+
+// verifySignature verifies the signature of the request in the context metadata, recording the
+// failure against the method if it doesn't verify. Failures are InvalidArgument errors, as they
+// were when handlers verified signatures themselves.
+func (l *Librarian) verifySignature(ctx context.Context, fullMethod string, req interface{}) error {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	rq, ok := req.(signedRequest)
+	if !ok || rq.GetMetadata() == nil {
+		l.authMetrics.failures.WithLabelValues(method, authReasonUnsigned).Inc()
+		return status.Error(codes.InvalidArgument, errUnsignedRequest.Error())
+	}
+	if err := l.rqv.Verify(ctx, rq, rq.GetMetadata()); err != nil {
+		l.recordInvalidSignature(ctx)
+		l.authMetrics.failures.WithLabelValues(method, authReasonSignature).Inc()
+		l.logger.Debug("rejected request with invalid signature",
+			zap.String(logMethod, method),
+			zap.Error(err),
+		)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// recordInvalidSignature records an invalid signature misbehavior against the remote IP of the
+// request, if known. The requester ID isn't trustworthy since the signature didn't verify.
+func (l *Librarian) recordInvalidSignature(ctx context.Context) {
+	if ip := remoteIP(ctx); ip != nil {
+		l.blacklist.RecordIPMisbehavior(ip, comm.InvalidSignature)
+	}
+}
+
+// authMetrics reports the requests rejected by the signature interceptors.
+type authMetrics struct {
+	failures *prom.CounterVec
+}
+
+func newAuthMetrics() *authMetrics {
+	return &authMetrics{
+		failures: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: "grpc",
+				Subsystem: "server",
+				Name:      "auth_failure_count",
+				Help:      "Total number of requests rejected for missing or invalid signatures.",
+			},
+			[]string{endpointLabel, authReasonLabel},
+		),
+	}
+}
+
+func (am *authMetrics) register() {
+	prom.MustRegister(am.failures)
+}
+
+func (am *authMetrics) unregister() {
+	_ = prom.Unregister(am.failures)
+}
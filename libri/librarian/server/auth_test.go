@@ -0,0 +1,135 @@
+package server
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcpeer "google.golang.org/grpc/peer"
+)
+
+const findMethod = librarianServicePrefix + "Find"
+
+func TestLibrarian_signatureUnaryInterceptor(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	rq := client.NewFindRequest(peerID, nil, peerID.ID(), 8)
+	l := &Librarian{
+		rqv:         &alwaysRequestVerifier{},
+		authMetrics: newAuthMetrics(),
+		logger:      zap.NewNop(),
+	}
+	var handledCtx context.Context
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handledCtx = ctx
+		return nil, nil
+	}
+	unary := l.signatureUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: findMethod}
+
+	// verified request handled
+	_, err := unary(context.Background(), rq, info, handler)
+	assert.Nil(t, err)
+	assert.NotNil(t, handledCtx)
+
+	// other services' requests passed through
+	handledCtx = nil
+	healthInfo := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	_, err = unary(context.Background(), nil, healthInfo, handler)
+	assert.Nil(t, err)
+	assert.NotNil(t, handledCtx)
+
+	// unsigned request rejected
+	handledCtx = nil
+	_, err = unary(context.Background(), &api.FindRequest{}, info, handler)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	assert.Nil(t, handledCtx)
+	assert.Equal(t, float64(1), authFailures(t, l, "Find", authReasonUnsigned))
+
+	// request with invalid signature rejected
+	l.rqv = &neverRequestVerifier{}
+	_, err = unary(context.Background(), rq, info, handler)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	assert.Nil(t, handledCtx)
+	assert.Equal(t, float64(1), authFailures(t, l, "Find", authReasonSignature))
+}
+
+func TestLibrarian_signatureStreamInterceptor(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	rq := client.NewSubscribeRequest(peerID, nil, &api.Subscription{})
+	l := &Librarian{
+		rqv:         &alwaysRequestVerifier{},
+		authMetrics: newAuthMetrics(),
+		logger:      zap.NewNop(),
+	}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		return ss.RecvMsg(&api.SubscribeRequest{})
+	}
+	stream := l.signatureStreamInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: librarianServicePrefix + "Subscribe"}
+	ss := &ctxServerStream{
+		fixedRecvServerStream: &fixedRecvServerStream{rq: rq},
+		ctx:                   context.Background(),
+	}
+
+	err := stream(nil, ss, info, handler)
+	assert.Nil(t, err)
+
+	l.rqv = &neverRequestVerifier{}
+	err = stream(nil, ss, info, handler)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	assert.Equal(t, float64(1), authFailures(t, l, "Subscribe", authReasonSignature))
+}
+
+func TestLibrarian_verifySignature_blacklist(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	rq := client.NewGetRequest(peerID, nil, id.NewPseudoRandom(rng))
+	params := comm.NewDefaultBlacklistParameters()
+	l := &Librarian{
+		rqv:         &neverRequestVerifier{},
+		blacklist:   comm.NewBlacklister(params, nil),
+		authMetrics: newAuthMetrics(),
+		logger:      zap.NewNop(),
+	}
+	remoteIP := net.ParseIP("1.2.3.4")
+	ctx := grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+		Addr: &net.TCPAddr{IP: remoteIP, Port: 20100},
+	})
+
+	// invalid signatures are attributed to the remote IP, which is banned after too many
+	for c := uint(0); c < params.MaxMisbehaviors; c++ {
+		assert.False(t, l.blacklist.BannedIP(remoteIP))
+		err := l.verifySignature(ctx, librarianServicePrefix+"Get", rq)
+		assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	}
+	assert.True(t, l.blacklist.BannedIP(remoteIP))
+	assert.False(t, l.blacklist.Banned(peerID.ID()))
+}
+
+func authFailures(t *testing.T, l *Librarian, method, reason string) float64 {
+	written := &dto.Metric{}
+	assert.Nil(t, l.authMetrics.failures.WithLabelValues(method, reason).Write(written))
+	return *written.Counter.Value
+}
+
+type ctxServerStream struct {
+	*fixedRecvServerStream
+	ctx context.Context
+}
+
+func (s *ctxServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+var errInvalidBannedPeerID = errors.New("banned peer ID must be 32 bytes")
+
+// ListBans lists the persisted bans to requests signed by the admin key.
+func (l *Librarian) ListBans(ctx context.Context, rq *api.ListBansRequest) (
+	*api.ListBansResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received list bans request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+
+	bans := l.banList.List()
+	rp := &api.ListBansResponse{
+		Metadata: l.NewResponseMetadata(rq.Metadata),
+		Bans:     make([]*api.PeerBan, len(bans)),
+	}
+	for i, ban := range bans {
+		rp.Bans[i] = toAPIBan(ban)
+	}
+	lg.Debug("listed bans", zap.Int(logNBans, len(bans)))
+	return rp, nil
+}
+
+// Ban bans a peer and persists the ban for requests signed by the admin key.
+func (l *Librarian) Ban(ctx context.Context, rq *api.BanRequest) (*api.BanResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received ban request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if rq.Ban == nil || len(rq.Ban.PeerId) != id.Length {
+		return nil, logReturnInvalidRqErr(lg, errInvalidBannedPeerID)
+	}
+
+	ban := fromAPIBan(rq.Ban)
+	if err := l.banList.Add(ban); err != nil {
+		return nil, logReturnInternalErr(lg, "error saving ban", err)
+	}
+	lg.Info("banned peer",
+		zap.Stringer(logPeerID, ban.PeerID),
+		zap.String(logReason, ban.Reason),
+		zap.Time(logExpiry, ban.Expiry),
+	)
+	return &api.BanResponse{Metadata: l.NewResponseMetadata(rq.Metadata)}, nil
+}
+
+// Unban lifts and removes a persisted ban for requests signed by the admin key.
+func (l *Librarian) Unban(ctx context.Context, rq *api.UnbanRequest) (*api.UnbanResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received unban request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if len(rq.PeerId) != id.Length {
+		return nil, logReturnInvalidRqErr(lg, errInvalidBannedPeerID)
+	}
+
+	peerID := id.FromBytes(rq.PeerId)
+	removed, err := l.banList.Remove(peerID)
+	if err != nil {
+		return nil, logReturnInternalErr(lg, "error removing ban", err)
+	}
+	lg.Info("unbanned peer", zap.Stringer(logPeerID, peerID), zap.Bool(logRemoved, removed))
+	return &api.UnbanResponse{
+		Metadata: l.NewResponseMetadata(rq.Metadata),
+		Removed:  removed,
+	}, nil
+}
+
+func toAPIBan(ban *comm.Ban) *api.PeerBan {
+	pb := &api.PeerBan{
+		PeerId: ban.PeerID.Bytes(),
+		Reason: ban.Reason,
+	}
+	if !ban.Expiry.IsZero() {
+		pb.ExpiryNanos = ban.Expiry.UnixNano()
+	}
+	return pb
+}
+
+func fromAPIBan(pb *api.PeerBan) *comm.Ban {
+	ban := &comm.Ban{
+		PeerID: id.FromBytes(pb.PeerId),
+		Reason: pb.Reason,
+	}
+	if pb.ExpiryNanos != 0 {
+		ban.Expiry = time.Unix(0, pb.ExpiryNanos)
+	}
+	return ban
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestLibrarian_BanListUnban_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID := ecid.NewPseudoRandom(rng)
+	l, bl := newBansLibrarian(t, rng, adminID, &cstorage.TestSLD{})
+	ctx := context.Background()
+	bannedID1, bannedID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	ban1 := &api.PeerBan{PeerId: bannedID1.Bytes(), Reason: "spam"}
+	rp1, err := l.Ban(ctx, client.NewBanRequest(adminID, nil, ban1))
+	assert.Nil(t, err)
+	assert.NotNil(t, rp1.Metadata)
+	ban2 := &api.PeerBan{
+		PeerId:      bannedID2.Bytes(),
+		Reason:      "flaky",
+		ExpiryNanos: time.Now().Add(time.Hour).UnixNano(),
+	}
+	_, err = l.Ban(ctx, client.NewBanRequest(adminID, nil, ban2))
+	assert.Nil(t, err)
+	assert.True(t, bl.Banned(bannedID1))
+	assert.True(t, bl.Banned(bannedID2))
+
+	rp2, err := l.ListBans(ctx, client.NewListBansRequest(adminID, nil))
+	assert.Nil(t, err)
+	assert.Len(t, rp2.Bans, 2)
+	for _, ban := range rp2.Bans {
+		if id.FromBytes(ban.PeerId).Cmp(bannedID1) == 0 {
+			assert.Equal(t, ban1, ban)
+		} else {
+			assert.Equal(t, ban2, ban)
+		}
+	}
+
+	rp3, err := l.Unban(ctx, client.NewUnbanRequest(adminID, nil, bannedID1))
+	assert.Nil(t, err)
+	assert.True(t, rp3.Removed)
+	assert.False(t, bl.Banned(bannedID1))
+	rp3, err = l.Unban(ctx, client.NewUnbanRequest(adminID, nil, bannedID1))
+	assert.Nil(t, err)
+	assert.False(t, rp3.Removed)
+
+	rp2, err = l.ListBans(ctx, client.NewListBansRequest(adminID, nil))
+	assert.Nil(t, err)
+	assert.Len(t, rp2.Bans, 1)
+}
+
+func TestLibrarian_BanListUnban_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	ctx := context.Background()
+	bannedID := id.NewPseudoRandom(rng)
+	ban := &api.PeerBan{PeerId: bannedID.Bytes()}
+
+	// check non-admin requester denied
+	l, _ := newBansLibrarian(t, rng, adminID, &cstorage.TestSLD{})
+	_, err := l.ListBans(ctx, client.NewListBansRequest(otherID, nil))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	_, err = l.Ban(ctx, client.NewBanRequest(otherID, nil, ban))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	_, err = l.Unban(ctx, client.NewUnbanRequest(otherID, nil, bannedID))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// check request error bubbles up
+	listRq := client.NewListBansRequest(adminID, nil)
+	listRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.ListBans(ctx, listRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	banRq := client.NewBanRequest(adminID, nil, ban)
+	banRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.Ban(ctx, banRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	unbanRq := client.NewUnbanRequest(adminID, nil, bannedID)
+	unbanRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.Unban(ctx, unbanRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check invalid banned peer IDs
+	_, err = l.Ban(ctx, client.NewBanRequest(adminID, nil, nil))
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	_, err = l.Ban(ctx, client.NewBanRequest(adminID, nil, &api.PeerBan{PeerId: []byte{1}}))
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	rq := client.NewUnbanRequest(adminID, nil, bannedID)
+	rq.PeerId = []byte{1}
+	_, err = l.Unban(ctx, rq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check Store error bubbles up
+	l, _ = newBansLibrarian(t, rng, adminID, &cstorage.TestSLD{
+		StoreErr: errors.New("some Store error"),
+	})
+	_, err = l.Ban(ctx, client.NewBanRequest(adminID, nil, ban))
+	assert.Equal(t, codes.Internal, getErrCode(t, err))
+}
+
+func newBansLibrarian(
+	t *testing.T, rng *rand.Rand, adminID ecid.ID, sl cstorage.StorerLoader,
+) (*Librarian, comm.Blacklister) {
+	bl := comm.NewBlacklister(comm.NewDefaultBlacklistParameters(), nil)
+	banList, err := comm.LoadBanList(sl, bl)
+	assert.Nil(t, err)
+	return &Librarian{
+		peerID:  ecid.NewPseudoRandom(rng),
+		config:  NewDefaultConfig().WithAdminPubKey(&adminID.Key().PublicKey),
+		rqv:     &alwaysRequestVerifier{},
+		banList: banList,
+		logger:  zap.NewNop(),
+	}, bl
+}
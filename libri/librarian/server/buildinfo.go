@@ -0,0 +1,60 @@
+package server
+
+import (
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/version"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func newBuildInfo(bi version.BuildInfo) *api.BuildInfo {
+	return &api.BuildInfo{
+		Version:         bi.Version.String(),
+		GitBranch:       bi.GitBranch,
+		GitRevision:     bi.GitRevision,
+		BuildDate:       bi.BuildDate,
+		ProtocolVersion: version.ProtocolVersion,
+	}
+}
+
+// buildInfoHealthServer wraps a health server, adding the signed build info to the response
+// header of each health check so crawlers can inventory versions without introducing
+// themselves.
+type buildInfoHealthServer struct {
+	health healthpb.HealthServer
+	header metadata.MD
+}
+
+func newBuildInfoHealthServer(
+	health healthpb.HealthServer, sbi *api.SignedBuildInfo,
+) (healthpb.HealthServer, error) {
+	header, err := client.NewBuildInfoHeader(sbi)
+	if err != nil {
+		return nil, err
+	}
+	return &buildInfoHealthServer{
+		health: health,
+		header: header,
+	}, nil
+}
+
+func (s *buildInfoHealthServer) Check(
+	ctx context.Context, rq *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	if err := grpc.SetHeader(ctx, s.header); err != nil {
+		return nil, err
+	}
+	return s.health.Check(ctx, rq)
+}
+
+func (s *buildInfoHealthServer) Watch(
+	rq *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer,
+) error {
+	if err := stream.SetHeader(s.header); err != nil {
+		return err
+	}
+	return s.health.Watch(rq, stream)
+}
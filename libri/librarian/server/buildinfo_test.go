@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/version"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNewBuildInfo(t *testing.T) {
+	bi := version.BuildInfo{
+		Version:     semver.MustParse("1.2.3"),
+		GitBranch:   "master",
+		GitRevision: "abcdef",
+		BuildDate:   "2018-01-01",
+	}
+	info := newBuildInfo(bi)
+	assert.Equal(t, "1.2.3", info.Version)
+	assert.Equal(t, bi.GitBranch, info.GitBranch)
+	assert.Equal(t, bi.GitRevision, info.GitRevision)
+	assert.Equal(t, bi.BuildDate, info.BuildDate)
+	assert.Equal(t, version.ProtocolVersion, info.ProtocolVersion)
+}
+
+func TestBuildInfoHealthServer_Check_err(t *testing.T) {
+	sbi := &api.SignedBuildInfo{BuildInfo: &api.BuildInfo{Version: "0.1.0"}}
+	hs, err := newBuildInfoHealthServer(health.NewServer(), sbi)
+	assert.Nil(t, err)
+
+	// setting header fails outside of a gRPC server call
+	rp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NotNil(t, err)
+	assert.Nil(t, rp)
+}
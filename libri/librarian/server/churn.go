@@ -0,0 +1,31 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/drausin/libri/libri/librarian/server/comm"
+)
+
+const churnPath = "/peers/churn"
+
+// churnHandler serves the aggregate statistics of the peers' uptime sessions as JSON, revealing
+// how quickly the network's membership turns over.
+type churnHandler struct {
+	getter comm.ChurnGetter
+}
+
+func newChurnHandler(getter comm.ChurnGetter) http.Handler {
+	return &churnHandler{getter: getter}
+}
+
+func (h *churnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.getter.Stats()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
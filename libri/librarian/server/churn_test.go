@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChurnHandler_ServeHTTP_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	crg := comm.NewChurnRecorderGetter(comm.DefaultSessionTimeout, comm.NewAlwaysKnower())
+	for c := 0; c < 8; c++ {
+		peerID := id.NewPseudoRandom(rng)
+		crg.Record(peerID, api.Find, comm.Response, comm.Success)
+		if c%2 == 0 {
+			crg.Record(peerID, api.Find, comm.Response, comm.Error)
+		}
+	}
+	h := newChurnHandler(crg)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, churnPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	stats := &comm.ChurnStats{}
+	err := json.Unmarshal(rec.Body.Bytes(), stats)
+	assert.Nil(t, err)
+	assert.Equal(t, crg.Stats(), stats)
+	assert.Equal(t, 8, stats.NPeers)
+	assert.Equal(t, 4, stats.NOnline)
+	assert.Equal(t, 4, stats.DailyDisconnects)
+}
+
+func TestChurnHandler_ServeHTTP_err(t *testing.T) {
+	crg := comm.NewChurnRecorderGetter(comm.DefaultSessionTimeout, comm.NewAlwaysKnower())
+	h := newChurnHandler(crg)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, churnPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
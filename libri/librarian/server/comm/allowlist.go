@@ -0,0 +1,84 @@
+package comm
+
+import (
+	"sync"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+// AllowList is the set of peers allowed to be added to the routing table and serviced in a
+// private cluster.
+type AllowList interface {
+	// Allowed returns whether the peer is on the allow-list.
+	Allowed(peerID id.ID) bool
+
+	// Set replaces the peers on the allow-list.
+	Set(peerIDs []id.ID)
+
+	// Len returns the number of peers on the allow-list.
+	Len() int
+}
+
+type allowList struct {
+	onRevoke func(peerID id.ID)
+	allowed  map[string]id.ID
+	mu       sync.RWMutex
+}
+
+// NewAllowList returns a new, empty AllowList. It calls onRevoke (if not nil) for each peer
+// removed from the allow-list when it is replaced, e.g., to evict it from the routing table.
+func NewAllowList(onRevoke func(peerID id.ID)) AllowList {
+	return &allowList{
+		onRevoke: onRevoke,
+		allowed:  make(map[string]id.ID),
+	}
+}
+
+func (al *allowList) Allowed(peerID id.ID) bool {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	_, in := al.allowed[peerID.String()]
+	return in
+}
+
+func (al *allowList) Set(peerIDs []id.ID) {
+	allowed := make(map[string]id.ID, len(peerIDs))
+	for _, peerID := range peerIDs {
+		allowed[peerID.String()] = peerID
+	}
+	al.mu.Lock()
+	prev := al.allowed
+	al.allowed = allowed
+	al.mu.Unlock()
+
+	if al.onRevoke == nil {
+		return
+	}
+	for key, peerID := range prev {
+		if _, in := allowed[key]; !in {
+			al.onRevoke(peerID)
+		}
+	}
+}
+
+func (al *allowList) Len() int {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	return len(al.allowed)
+}
+
+type allowListBlacklister struct {
+	Blacklister
+	al AllowList
+}
+
+// NewAllowListBlacklister returns a Blacklister that, in addition to deferring to the given
+// Blacklister, deems banned every peer not on the allow-list, so they are neither added to the
+// routing table nor queried in searches.
+func NewAllowListBlacklister(al AllowList, bl Blacklister) Blacklister {
+	return &allowListBlacklister{Blacklister: bl, al: al}
+}
+
+func (b *allowListBlacklister) Banned(peerID id.ID) bool {
+	return !b.al.Allowed(peerID) || b.Blacklister.Banned(peerID)
+}
@@ -0,0 +1,50 @@
+package comm
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowList_Set(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	revoked := make([]id.ID, 0)
+	al := NewAllowList(func(peerID id.ID) { revoked = append(revoked, peerID) })
+	peerID1, peerID2, peerID3 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng)
+	assert.Equal(t, 0, al.Len())
+	assert.False(t, al.Allowed(peerID1))
+
+	al.Set([]id.ID{peerID1, peerID2})
+	assert.Equal(t, 2, al.Len())
+	assert.True(t, al.Allowed(peerID1))
+	assert.True(t, al.Allowed(peerID2))
+	assert.False(t, al.Allowed(peerID3))
+	assert.Empty(t, revoked)
+
+	// peers removed from allow-list are revoked
+	al.Set([]id.ID{peerID2, peerID3})
+	assert.Equal(t, 2, al.Len())
+	assert.False(t, al.Allowed(peerID1))
+	assert.True(t, al.Allowed(peerID3))
+	assert.Equal(t, []id.ID{peerID1}, revoked)
+}
+
+func TestAllowListBlacklister_Banned(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	al := NewAllowList(nil)
+	bl := NewAllowListBlacklister(al, NewBlacklister(NewDefaultBlacklistParameters(), nil))
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	// nobody allowed by default
+	assert.True(t, bl.Banned(peerID1))
+
+	al.Set([]id.ID{peerID1, peerID2})
+	assert.False(t, bl.Banned(peerID1))
+
+	// allowed peers may still be banned
+	bl.Ban(peerID2, Permanent)
+	assert.True(t, bl.Banned(peerID2))
+}
@@ -0,0 +1,189 @@
+package comm
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+)
+
+var banListKey = []byte("BanList")
+
+// Ban is a ban on a peer made by the librarian's admin.
+type Ban struct {
+	// PeerID is the ID of the banned peer.
+	PeerID id.ID
+
+	// Reason is the reason given for the ban.
+	Reason string
+
+	// Expiry is when the ban expires, with the zero value meaning the ban is permanent.
+	Expiry time.Time
+}
+
+// BanList manages the admin's bans, persisting them so they survive restarts and applying them to
+// a Blacklister.
+type BanList interface {
+	// Add bans a peer, replacing any existing ban on it.
+	Add(ban *Ban) error
+
+	// Remove lifts the ban on a peer, returning whether it was banned.
+	Remove(peerID id.ID) (bool, error)
+
+	// List returns the unexpired bans, ordered by peer ID.
+	List() []*Ban
+}
+
+type banList struct {
+	bans map[string]*Ban
+	sl   cstorage.StorerLoader
+	bl   Blacklister
+	now  func() time.Time
+	mu   sync.Mutex
+}
+
+// LoadBanList loads the persisted bans, applying those not yet expired to the Blacklister.
+func LoadBanList(sl cstorage.StorerLoader, bl Blacklister) (BanList, error) {
+	l, err := loadBanList(sl, bl, time.Now)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// loadBanList loads the persisted bans as of the time given by now.
+func loadBanList(
+	sl cstorage.StorerLoader, bl Blacklister, now func() time.Time,
+) (*banList, error) {
+	l := &banList{
+		bans: make(map[string]*Ban),
+		sl:   sl,
+		bl:   bl,
+		now:  now,
+	}
+	bytes, err := sl.Load(banListKey)
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return l, nil
+	}
+	stored := &sstorage.BanList{}
+	if err := proto.Unmarshal(bytes, stored); err != nil {
+		return nil, err
+	}
+	for _, sb := range stored.Bans {
+		ban := fromStoredBan(sb)
+		if l.expired(ban) {
+			continue
+		}
+		l.bans[ban.PeerID.String()] = ban
+		l.apply(ban)
+	}
+	return l, nil
+}
+
+func (l *banList) Add(ban *Ban) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := ban.PeerID.String()
+	prev, hadPrev := l.bans[key]
+	l.bans[key] = ban
+	if err := l.save(); err != nil {
+		// leave the bans as they were
+		if hadPrev {
+			l.bans[key] = prev
+		} else {
+			delete(l.bans, key)
+		}
+		return err
+	}
+	l.apply(ban)
+	return nil
+}
+
+func (l *banList) Remove(peerID id.ID) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := peerID.String()
+	ban, in := l.bans[key]
+	if !in {
+		return false, nil
+	}
+	delete(l.bans, key)
+	if err := l.save(); err != nil {
+		// leave the bans as they were
+		l.bans[key] = ban
+		return false, err
+	}
+	l.bl.Unban(peerID)
+	return true, nil
+}
+
+func (l *banList) List() []*Ban {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bans := make([]*Ban, 0, len(l.bans))
+	for _, ban := range l.bans {
+		if !l.expired(ban) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].PeerID.Cmp(bans[j].PeerID) < 0 })
+	return bans
+}
+
+func (l *banList) apply(ban *Ban) {
+	if ban.Expiry.IsZero() {
+		l.bl.Ban(ban.PeerID, Permanent)
+		return
+	}
+	l.bl.Ban(ban.PeerID, ban.Expiry.Sub(l.now()))
+}
+
+func (l *banList) expired(ban *Ban) bool {
+	return !ban.Expiry.IsZero() && !l.now().Before(ban.Expiry)
+}
+
+// save persists the unexpired bans.
+func (l *banList) save() error {
+	stored := &sstorage.BanList{Bans: make([]*sstorage.Ban, 0, len(l.bans))}
+	for key, ban := range l.bans {
+		if l.expired(ban) {
+			delete(l.bans, key)
+			continue
+		}
+		stored.Bans = append(stored.Bans, toStoredBan(ban))
+	}
+	bytes, err := proto.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return l.sl.Store(banListKey, bytes)
+}
+
+func toStoredBan(ban *Ban) *sstorage.Ban {
+	sb := &sstorage.Ban{
+		PeerId: ban.PeerID.Bytes(),
+		Reason: ban.Reason,
+	}
+	if !ban.Expiry.IsZero() {
+		sb.ExpiryNanos = ban.Expiry.UnixNano()
+	}
+	return sb
+}
+
+func fromStoredBan(sb *sstorage.Ban) *Ban {
+	ban := &Ban{
+		PeerID: id.FromBytes(sb.PeerId),
+		Reason: sb.Reason,
+	}
+	if sb.ExpiryNanos != 0 {
+		ban.Expiry = time.Unix(0, sb.ExpiryNanos)
+	}
+	return ban
+}
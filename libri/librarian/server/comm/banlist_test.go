@@ -0,0 +1,122 @@
+package comm
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanList_AddRemove(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sl := &cstorage.TestSLD{}
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+	l, err := LoadBanList(sl, bl)
+	assert.Nil(t, err)
+	assert.Len(t, l.List(), 0)
+
+	ban1 := &Ban{PeerID: id.NewPseudoRandom(rng), Reason: "spam"}
+	ban2 := &Ban{
+		PeerID: id.NewPseudoRandom(rng),
+		Reason: "flaky",
+		Expiry: time.Now().Add(time.Hour),
+	}
+	assert.Nil(t, l.Add(ban1))
+	assert.Nil(t, l.Add(ban2))
+	assert.True(t, bl.Banned(ban1.PeerID))
+	assert.True(t, bl.Banned(ban2.PeerID))
+	assert.Len(t, l.List(), 2)
+	assert.True(t, l.List()[0].PeerID.Cmp(l.List()[1].PeerID) < 0)
+
+	// check bans survive reloading
+	bl2 := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+	l2, err := LoadBanList(sl, bl2)
+	assert.Nil(t, err)
+	assert.Len(t, l2.List(), 2)
+	assert.True(t, bl2.Banned(ban1.PeerID))
+	assert.True(t, bl2.Banned(ban2.PeerID))
+	for _, ban := range l2.List() {
+		if ban.PeerID.Cmp(ban1.PeerID) == 0 {
+			assert.Equal(t, ban1.Reason, ban.Reason)
+			assert.True(t, ban.Expiry.IsZero())
+		} else {
+			assert.Equal(t, ban2.Reason, ban.Reason)
+			assert.Equal(t, ban2.Expiry.UnixNano(), ban.Expiry.UnixNano())
+		}
+	}
+
+	removed, err := l2.Remove(ban1.PeerID)
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	assert.False(t, bl2.Banned(ban1.PeerID))
+	removed, err = l2.Remove(ban1.PeerID)
+	assert.Nil(t, err)
+	assert.False(t, removed)
+
+	l3, err := LoadBanList(sl, NewBlacklister(NewDefaultBlacklistParameters(), nil))
+	assert.Nil(t, err)
+	assert.Len(t, l3.List(), 1)
+}
+
+func TestBanList_expired(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sl := &cstorage.TestSLD{}
+	now := time.Now()
+	clock := func() time.Time { return now }
+	l, err := loadBanList(sl, NewBlacklister(NewDefaultBlacklistParameters(), nil), clock)
+	assert.Nil(t, err)
+
+	ban := &Ban{PeerID: id.NewPseudoRandom(rng), Expiry: now.Add(time.Minute)}
+	assert.Nil(t, l.Add(ban))
+	assert.Len(t, l.List(), 1)
+
+	// expired bans aren't listed or loaded
+	now = now.Add(time.Hour)
+	assert.Len(t, l.List(), 0)
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+	l2, err := loadBanList(sl, bl, clock)
+	assert.Nil(t, err)
+	assert.Len(t, l2.List(), 0)
+	assert.False(t, bl.Banned(ban.PeerID))
+}
+
+func TestLoadBanList_err(t *testing.T) {
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+
+	// check Load error bubbles up
+	l, err := LoadBanList(&cstorage.TestSLD{LoadErr: errors.New("some Load error")}, bl)
+	assert.NotNil(t, err)
+	assert.Nil(t, l)
+
+	// check Unmarshal error bubbles up
+	l, err = LoadBanList(&cstorage.TestSLD{Bytes: []byte("not a ban list")}, bl)
+	assert.NotNil(t, err)
+	assert.Nil(t, l)
+}
+
+func TestBanList_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sl := &cstorage.TestSLD{}
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+	l, err := LoadBanList(sl, bl)
+	assert.Nil(t, err)
+	ban := &Ban{PeerID: id.NewPseudoRandom(rng)}
+	assert.Nil(t, l.Add(ban))
+
+	// check Store errors bubble up without changing the blacklist
+	sl.StoreErr = errors.New("some Store error")
+	removed, err := l.Remove(ban.PeerID)
+	assert.NotNil(t, err)
+	assert.False(t, removed)
+	assert.True(t, bl.Banned(ban.PeerID))
+	assert.Len(t, l.List(), 1)
+
+	ban2 := &Ban{PeerID: id.NewPseudoRandom(rng)}
+	assert.NotNil(t, l.Add(ban2))
+	assert.False(t, bl.Banned(ban2.PeerID))
+	assert.Len(t, l.List(), 1)
+}
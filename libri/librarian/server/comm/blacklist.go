@@ -0,0 +1,275 @@
+package comm
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+const (
+	// DefaultMaxMisbehaviors is the default number of misbehaviors within the misbehavior window
+	// after which a peer is banned.
+	DefaultMaxMisbehaviors = uint(3)
+
+	// DefaultMisbehaviorWindow is the default window over which misbehaviors are counted.
+	DefaultMisbehaviorWindow = 1 * time.Hour
+
+	// DefaultMisbehaviorBan is the default duration of the ban for a peer that has exceeded the
+	// max number of misbehaviors.
+	DefaultMisbehaviorBan = 24 * time.Hour
+
+	// Permanent is the ban duration of permanent bans.
+	Permanent = time.Duration(0)
+)
+
+// Misbehavior is a type of peer behavior indicating the peer is faulty or malicious.
+type Misbehavior int
+
+const (
+	// InvalidSignature indicates a request with a signature that doesn't verify.
+	InvalidSignature Misbehavior = iota
+
+	// ProtocolViolation indicates a response that violates the protocol.
+	ProtocolViolation
+)
+
+func (m Misbehavior) String() string {
+	switch m {
+	case InvalidSignature:
+		return "InvalidSignature"
+	case ProtocolViolation:
+		return "ProtocolViolation"
+	}
+	return fmt.Sprintf("Misbehavior(%d)", m)
+}
+
+// BlacklistParameters defines when misbehaving peers are banned.
+type BlacklistParameters struct {
+	// MaxMisbehaviors is the number of misbehaviors within MisbehaviorWindow after which a peer
+	// (or IP) is banned, with zero disabling misbehavior bans.
+	MaxMisbehaviors uint
+
+	// MisbehaviorWindow is the window over which misbehaviors are counted.
+	MisbehaviorWindow time.Duration
+
+	// MisbehaviorBan is the duration of bans for misbehavior.
+	MisbehaviorBan time.Duration
+}
+
+// NewDefaultBlacklistParameters returns the default BlacklistParameters.
+func NewDefaultBlacklistParameters() *BlacklistParameters {
+	return &BlacklistParameters{
+		MaxMisbehaviors:   DefaultMaxMisbehaviors,
+		MisbehaviorWindow: DefaultMisbehaviorWindow,
+		MisbehaviorBan:    DefaultMisbehaviorBan,
+	}
+}
+
+// Blacklister bans peers, by ID or IP range, from being added to the routing table or queried.
+type Blacklister interface {
+	// Ban bans the peer for the given duration or permanently if the duration is Permanent.
+	Ban(peerID id.ID, duration time.Duration)
+
+	// BanIPNet bans all peers with an IP in the given range for the given duration or
+	// permanently if the duration is Permanent.
+	BanIPNet(ipNet *net.IPNet, duration time.Duration)
+
+	// Unban lifts any ban on the peer.
+	Unban(peerID id.ID)
+
+	// Banned returns whether the peer is currently banned.
+	Banned(peerID id.ID) bool
+
+	// BannedIP returns whether the IP is currently in a banned range.
+	BannedIP(ip net.IP) bool
+
+	// RecordMisbehavior records a misbehavior by the peer, banning it if it has misbehaved too
+	// often. The peer ID must be authenticated, e.g., by a verified signature.
+	RecordMisbehavior(peerID id.ID, m Misbehavior)
+
+	// RecordIPMisbehavior records a misbehavior by an unauthenticated remote IP, banning the IP
+	// if it has misbehaved too often. Misbehaviors like invalid signatures are attributed to IPs
+	// rather than peer IDs, since otherwise anyone could get a peer banned by sending requests
+	// with its public key and a bogus signature.
+	RecordIPMisbehavior(ip net.IP, m Misbehavior)
+}
+
+type blacklister struct {
+	params       *BlacklistParameters
+	onBan        func(peerID id.ID)
+	bannedPeers  map[string]time.Time
+	bannedIPNets map[string]*bannedIPNet
+	misbehaviors map[string][]time.Time
+	now          func() time.Time
+	mu           sync.Mutex
+}
+
+type bannedIPNet struct {
+	ipNet *net.IPNet
+	until time.Time
+}
+
+// NewBlacklister returns a new Blacklister that calls onBan (if not nil) whenever a peer is banned,
+// e.g., to evict it from the routing table.
+func NewBlacklister(params *BlacklistParameters, onBan func(peerID id.ID)) Blacklister {
+	return &blacklister{
+		params:       params,
+		onBan:        onBan,
+		bannedPeers:  make(map[string]time.Time),
+		bannedIPNets: make(map[string]*bannedIPNet),
+		misbehaviors: make(map[string][]time.Time),
+		now:          time.Now,
+	}
+}
+
+func (b *blacklister) Ban(peerID id.ID, duration time.Duration) {
+	b.mu.Lock()
+	b.bannedPeers[peerID.String()] = b.until(duration)
+	b.mu.Unlock()
+	if b.onBan != nil {
+		b.onBan(peerID)
+	}
+}
+
+func (b *blacklister) BanIPNet(ipNet *net.IPNet, duration time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bannedIPNets[ipNet.String()] = &bannedIPNet{ipNet: ipNet, until: b.until(duration)}
+}
+
+func (b *blacklister) Unban(peerID id.ID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.bannedPeers, peerID.String())
+	delete(b.misbehaviors, peerID.String())
+}
+
+func (b *blacklister) Banned(peerID id.ID) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, in := b.bannedPeers[peerID.String()]
+	if !in {
+		return false
+	}
+	if b.expired(until) {
+		delete(b.bannedPeers, peerID.String())
+		return false
+	}
+	return true
+}
+
+func (b *blacklister) BannedIP(ip net.IP) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, banned := range b.bannedIPNets {
+		if b.expired(banned.until) {
+			delete(b.bannedIPNets, key)
+			continue
+		}
+		if banned.ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *blacklister) RecordMisbehavior(peerID id.ID, m Misbehavior) {
+	if b.recordMisbehavior(peerID.String()) {
+		b.Ban(peerID, b.params.MisbehaviorBan)
+	}
+}
+
+func (b *blacklister) RecordIPMisbehavior(ip net.IP, m Misbehavior) {
+	if b.recordMisbehavior(ip.String()) {
+		b.BanIPNet(singleIPNet(ip), b.params.MisbehaviorBan)
+	}
+}
+
+// recordMisbehavior records a misbehavior for the given key and returns whether the key has
+// exceeded the max number of misbehaviors.
+func (b *blacklister) recordMisbehavior(key string) bool {
+	if b.params.MaxMisbehaviors == 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.pruneMisbehaviors(now)
+	recent := make([]time.Time, 0, len(b.misbehaviors[key])+1)
+	for _, t := range b.misbehaviors[key] {
+		if now.Sub(t) < b.params.MisbehaviorWindow {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	if uint(len(recent)) >= b.params.MaxMisbehaviors {
+		delete(b.misbehaviors, key)
+		return true
+	}
+	b.misbehaviors[key] = recent
+	return false
+}
+
+// pruneMisbehaviors removes keys whose misbehaviors have all fallen outside the misbehavior
+// window, so keys that misbehave once and go away don't accumulate.
+func (b *blacklister) pruneMisbehaviors(now time.Time) {
+	for key, ts := range b.misbehaviors {
+		if len(ts) == 0 || now.Sub(ts[len(ts)-1]) >= b.params.MisbehaviorWindow {
+			delete(b.misbehaviors, key)
+		}
+	}
+}
+
+func (b *blacklister) until(duration time.Duration) time.Time {
+	if duration == Permanent {
+		return time.Time{}
+	}
+	return b.now().Add(duration)
+}
+
+func (b *blacklister) expired(until time.Time) bool {
+	return !until.IsZero() && b.now().After(until)
+}
+
+func singleIPNet(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+type naiveBlacklister struct{}
+
+// NewNaiveBlacklister returns a Blacklister that never bans any peers.
+func NewNaiveBlacklister() Blacklister {
+	return &naiveBlacklister{}
+}
+
+func (b *naiveBlacklister) Ban(peerID id.ID, duration time.Duration)          {}
+func (b *naiveBlacklister) BanIPNet(ipNet *net.IPNet, duration time.Duration) {}
+func (b *naiveBlacklister) Unban(peerID id.ID)                                {}
+func (b *naiveBlacklister) Banned(peerID id.ID) bool                          { return false }
+func (b *naiveBlacklister) BannedIP(ip net.IP) bool                           { return false }
+func (b *naiveBlacklister) RecordMisbehavior(peerID id.ID, m Misbehavior)     {}
+func (b *naiveBlacklister) RecordIPMisbehavior(ip net.IP, m Misbehavior)      {}
+
+// NewBlacklistDoctor returns a Doctor that deems banned peers unhealthy and otherwise defers to
+// the given Doctor.
+func NewBlacklistDoctor(doctor Doctor, bl Blacklister) Doctor {
+	return &blacklistDoctor{
+		doctor: doctor,
+		bl:     bl,
+	}
+}
+
+type blacklistDoctor struct {
+	doctor Doctor
+	bl     Blacklister
+}
+
+func (d *blacklistDoctor) Healthy(peerID id.ID) bool {
+	return !d.bl.Banned(peerID) && d.doctor.Healthy(peerID)
+}
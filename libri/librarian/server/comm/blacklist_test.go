@@ -0,0 +1,155 @@
+package comm
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMisbehavior_String(t *testing.T) {
+	assert.Equal(t, "InvalidSignature", InvalidSignature.String())
+	assert.Equal(t, "ProtocolViolation", ProtocolViolation.String())
+	assert.Equal(t, "Misbehavior(-1)", Misbehavior(-1).String())
+}
+
+func TestBlacklister_BanUnban(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	banned := make([]id.ID, 0)
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), func(peerID id.ID) {
+		banned = append(banned, peerID)
+	})
+	bl.(*blacklister).now = func() time.Time { return now }
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	bl.Ban(peerID1, time.Minute)
+	bl.Ban(peerID2, Permanent)
+	assert.True(t, bl.Banned(peerID1))
+	assert.True(t, bl.Banned(peerID2))
+	assert.Equal(t, []id.ID{peerID1, peerID2}, banned)
+
+	// temporary ban expires, but permanent doesn't
+	now = now.Add(time.Hour)
+	assert.False(t, bl.Banned(peerID1))
+	assert.True(t, bl.Banned(peerID2))
+
+	bl.Unban(peerID2)
+	assert.False(t, bl.Banned(peerID2))
+}
+
+func TestBlacklister_BanIPNet(t *testing.T) {
+	now := time.Unix(0, 0)
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+	bl.(*blacklister).now = func() time.Time { return now }
+	_, ipNet, err := net.ParseCIDR("10.1.0.0/16")
+	assert.Nil(t, err)
+
+	bl.BanIPNet(ipNet, time.Minute)
+	assert.True(t, bl.BannedIP(net.ParseIP("10.1.2.3")))
+	assert.False(t, bl.BannedIP(net.ParseIP("10.2.2.3")))
+
+	now = now.Add(time.Hour)
+	assert.False(t, bl.BannedIP(net.ParseIP("10.1.2.3")))
+	assert.Len(t, bl.(*blacklister).bannedIPNets, 0)
+}
+
+func TestBlacklister_RecordMisbehavior(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	params := NewDefaultBlacklistParameters()
+	bl := NewBlacklister(params, nil)
+	bl.(*blacklister).now = func() time.Time { return now }
+	peerID := id.NewPseudoRandom(rng)
+
+	// misbehaviors outside the window don't count toward a ban
+	for c := uint(0); c < params.MaxMisbehaviors-1; c++ {
+		bl.RecordMisbehavior(peerID, ProtocolViolation)
+	}
+	now = now.Add(params.MisbehaviorWindow)
+	bl.RecordMisbehavior(peerID, ProtocolViolation)
+	assert.False(t, bl.Banned(peerID))
+
+	for c := uint(1); c < params.MaxMisbehaviors; c++ {
+		bl.RecordMisbehavior(peerID, ProtocolViolation)
+	}
+	assert.True(t, bl.Banned(peerID))
+
+	// ban lasts for MisbehaviorBan
+	now = now.Add(params.MisbehaviorBan + time.Second)
+	assert.False(t, bl.Banned(peerID))
+}
+
+func TestBlacklister_RecordMisbehavior_prune(t *testing.T) {
+	now := time.Unix(0, 0)
+	params := NewDefaultBlacklistParameters()
+	bl := NewBlacklister(params, nil)
+	bl.(*blacklister).now = func() time.Time { return now }
+
+	bl.RecordIPMisbehavior(net.ParseIP("1.2.3.4"), InvalidSignature)
+	bl.RecordIPMisbehavior(net.ParseIP("1.2.3.5"), InvalidSignature)
+	assert.Len(t, bl.(*blacklister).misbehaviors, 2)
+
+	// keys with only expired misbehaviors are pruned on the next record
+	now = now.Add(params.MisbehaviorWindow)
+	bl.RecordIPMisbehavior(net.ParseIP("1.2.3.6"), InvalidSignature)
+	assert.Len(t, bl.(*blacklister).misbehaviors, 1)
+}
+
+func TestBlacklister_RecordMisbehavior_disabled(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultBlacklistParameters()
+	params.MaxMisbehaviors = 0
+	bl := NewBlacklister(params, nil)
+	peerID := id.NewPseudoRandom(rng)
+
+	for c := 0; c < 8; c++ {
+		bl.RecordMisbehavior(peerID, ProtocolViolation)
+	}
+	assert.False(t, bl.Banned(peerID))
+}
+
+func TestBlacklister_RecordIPMisbehavior(t *testing.T) {
+	params := NewDefaultBlacklistParameters()
+	bl := NewBlacklister(params, nil)
+	ip4, ip6 := net.ParseIP("1.2.3.4"), net.ParseIP("2001:db8::1")
+
+	for c := uint(0); c < params.MaxMisbehaviors; c++ {
+		bl.RecordIPMisbehavior(ip4, InvalidSignature)
+		bl.RecordIPMisbehavior(ip6, InvalidSignature)
+	}
+	assert.True(t, bl.BannedIP(ip4))
+	assert.True(t, bl.BannedIP(ip6))
+	assert.False(t, bl.BannedIP(net.ParseIP("1.2.3.5")))
+	assert.False(t, bl.BannedIP(net.ParseIP("2001:db8::2")))
+}
+
+func TestNaiveBlacklister(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	bl := NewNaiveBlacklister()
+	peerID, ip := id.NewPseudoRandom(rng), net.ParseIP("1.2.3.4")
+	_, ipNet, err := net.ParseCIDR("1.2.3.0/24")
+	assert.Nil(t, err)
+
+	bl.Ban(peerID, Permanent)
+	bl.BanIPNet(ipNet, Permanent)
+	bl.RecordMisbehavior(peerID, ProtocolViolation)
+	bl.RecordIPMisbehavior(ip, InvalidSignature)
+	bl.Unban(peerID)
+	assert.False(t, bl.Banned(peerID))
+	assert.False(t, bl.BannedIP(ip))
+}
+
+func TestBlacklistDoctor_Healthy(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+	d := NewBlacklistDoctor(NewNaiveDoctor(), bl)
+	peerID := id.NewPseudoRandom(rng)
+
+	assert.True(t, d.Healthy(peerID))
+	bl.Ban(peerID, Permanent)
+	assert.False(t, d.Healthy(peerID))
+}
@@ -0,0 +1,50 @@
+package comm
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket allows events at a sustained rate, with bursts of events above it up to a maximum.
+type TokenBucket struct {
+	perSecond float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+	mu        sync.Mutex
+}
+
+// NewTokenBucket returns a full TokenBucket allowing perSecond events per second with bursts of
+// up to burst events. Non-positive rates allow all events.
+func NewTokenBucket(perSecond float64, burst float64) *TokenBucket {
+	return &TokenBucket{perSecond: perSecond, burst: burst, tokens: burst}
+}
+
+// Take takes the allowance for an event at now, returning zero if one was available or otherwise
+// how long until one will be.
+func (b *TokenBucket) Take(now time.Time) time.Duration {
+	if b.perSecond <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+}
+
+// idle returns whether the bucket would be full at now.
+func (b *TokenBucket) idle(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens+now.Sub(b.last).Seconds()*b.perSecond >= b.burst
+}
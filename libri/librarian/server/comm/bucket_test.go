@@ -0,0 +1,46 @@
+package comm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	now := time.Now()
+
+	// no limit never throttles
+	b := NewTokenBucket(0, 1)
+	for c := 0; c < 100; c++ {
+		assert.Zero(t, b.Take(now))
+	}
+
+	// allows burst
+	b = NewTokenBucket(4, 4)
+	for c := 0; c < 4; c++ {
+		assert.Zero(t, b.Take(now))
+	}
+	assert.Equal(t, 250*time.Millisecond, b.Take(now))
+
+	// throttled takes don't consume allowance
+	assert.Equal(t, 125*time.Millisecond, b.Take(now.Add(125*time.Millisecond)))
+	assert.Zero(t, b.Take(now.Add(250*time.Millisecond)))
+	assert.Equal(t, 250*time.Millisecond, b.Take(now.Add(250*time.Millisecond)))
+
+	// unused allowance accumulates up to the burst
+	later := now.Add(time.Minute)
+	for c := 0; c < 4; c++ {
+		assert.Zero(t, b.Take(later))
+	}
+	assert.NotZero(t, b.Take(later))
+}
+
+func TestTokenBucket_idle(t *testing.T) {
+	now := time.Now()
+	b := NewTokenBucket(1, 2)
+	assert.True(t, b.idle(now))
+	assert.Zero(t, b.Take(now))
+	assert.False(t, b.idle(now))
+	assert.True(t, b.idle(now.Add(time.Second)))
+}
@@ -0,0 +1,221 @@
+package comm
+
+import (
+	"sort"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// DefaultQueryCheckpointInterval is the default interval between checkpoints of the peers'
+	// recorded queries.
+	DefaultQueryCheckpointInterval = 5 * time.Minute
+
+	// DefaultQueryMaxPeerAge is the default time since a peer's latest query after which its
+	// queries aren't checkpointed.
+	DefaultQueryMaxPeerAge = 48 * time.Hour
+
+	// DefaultQueryMaxPeers is the default maximum number of peers whose queries are checkpointed
+	// for each window.
+	DefaultQueryMaxPeers = 256
+)
+
+var queryWindowsKey = []byte("QueryWindows")
+
+// QueryCheckpointParameters define how the peers' recorded queries and reputations are persisted,
+// so the librarian's view of its peers survives restarts.
+type QueryCheckpointParameters struct {
+	// Interval is the interval between checkpoints. Periodic checkpoints are disabled when zero,
+	// though the queries are still saved when the librarian closes.
+	Interval time.Duration
+
+	// MaxPeerAge is the time since a peer's latest query after which the peer is considered
+	// gone and its queries are no longer checkpointed.
+	MaxPeerAge time.Duration
+
+	// MaxPeers is the maximum number of peers, those with the latest queries, whose queries are
+	// checkpointed for each window. Since any peer may query us, this keeps the checkpoint under
+	// the storage value size limit however many peers do.
+	MaxPeers uint
+}
+
+// NewDefaultQueryCheckpointParameters returns the default QueryCheckpointParameters.
+func NewDefaultQueryCheckpointParameters() *QueryCheckpointParameters {
+	return &QueryCheckpointParameters{
+		Interval:   DefaultQueryCheckpointInterval,
+		MaxPeerAge: DefaultQueryMaxPeerAge,
+		MaxPeers:   DefaultQueryMaxPeers,
+	}
+}
+
+// Enabled returns whether the queries are periodically checkpointed.
+func (p *QueryCheckpointParameters) Enabled() bool {
+	return p.Interval > 0
+}
+
+// LoadWindowQueryRecorderGetters is like NewWindowQueryRecorderGetters but also restores the
+// queries saved by an earlier WindowQueryGetters' Save for the windows still in progress.
+func LoadWindowQueryRecorderGetters(
+	l cstorage.Loader, knower Knower, windows []time.Duration,
+) (QueryRecorder, WindowQueryGetters, error) {
+	recorder, getters := NewWindowQueryRecorderGetters(knower, windows)
+	bytes, err := l.Load(queryWindowsKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes == nil {
+		return recorder, getters, nil
+	}
+	stored := &sstorage.QueryWindows{}
+	if err := proto.Unmarshal(bytes, stored); err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	for _, sw := range stored.Windows {
+		rg, ok := getters[time.Duration(sw.WindowNanos)].(*windowRG)
+		if !ok {
+			// window no longer recorded
+			continue
+		}
+		start := time.Unix(0, sw.StartNanos)
+		end := start.Add(rg.window)
+		if !now.Before(end) {
+			// window already over, so its queries would be reset anyway
+			continue
+		}
+		rg.mu.Lock()
+		rg.start, rg.end = start, end
+		rg.mu.Unlock()
+		rg.fromStored(sw.Peers)
+	}
+	return recorder, getters, nil
+}
+
+// Save persists the queries of each window, omitting those of peers without queries in the past
+// MaxPeerAge and all but the MaxPeers peers with the latest queries.
+func (gs WindowQueryGetters) Save(s cstorage.Storer, params *QueryCheckpointParameters) error {
+	now := time.Now()
+	stored := &sstorage.QueryWindows{
+		Windows: make([]*sstorage.QueryWindow, 0, len(gs)),
+	}
+	for window, g := range gs {
+		rg, ok := g.(*windowRG)
+		if !ok {
+			continue
+		}
+		rg.maybeNextWindow()
+		rg.mu.Lock()
+		start := rg.start
+		rg.mu.Unlock()
+		stored.Windows = append(stored.Windows, &sstorage.QueryWindow{
+			WindowNanos: int64(window),
+			StartNanos:  start.UnixNano(),
+			Peers:       rg.toStored(now.Add(-params.MaxPeerAge), params.MaxPeers),
+		})
+	}
+	bytes, err := proto.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return s.Store(queryWindowsKey, bytes)
+}
+
+// toStored returns the stored representation of the queries of up to maxPeers peers with the
+// latest queries, if after the given cutoff.
+func (r *scalarRG) toStored(cutoff time.Time, maxPeers uint) []*sstorage.PeerQueries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := make([]*sstorage.PeerQueries, 0, len(r.peers))
+	latests := make(map[*sstorage.PeerQueries]time.Time, len(r.peers))
+	for idStr, eqos := range r.peers {
+		all := eqos[api.All]
+		latest := time.Time{}
+		for _, qt := range []QueryType{Request, Response} {
+			for _, m := range all[qt] {
+				if l := m.latest(); l.After(latest) {
+					latest = l
+				}
+			}
+		}
+		if !latest.After(cutoff) {
+			// forget long-gone peers
+			continue
+		}
+		peerID, err := id.FromString(idStr)
+		if err != nil {
+			continue
+		}
+		pq := &sstorage.PeerQueries{
+			PeerId:  peerID.Bytes(),
+			Metrics: eqos.toStored(),
+		}
+		stored = append(stored, pq)
+		latests[pq] = latest
+	}
+	if uint(len(stored)) > maxPeers {
+		sort.Slice(stored, func(i, j int) bool {
+			return latests[stored[i]].After(latests[stored[j]])
+		})
+		stored = stored[:maxPeers]
+	}
+	return stored
+}
+
+// fromStored restores the stored queries of each peer.
+func (r *scalarRG) fromStored(stored []*sstorage.PeerQueries) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, pq := range stored {
+		peerID := id.FromBytes(pq.PeerId)
+		idStr, known := peerID.String(), r.knower.Know(peerID)
+		eqos := newEndpointQueryOutcomes()
+		for _, sm := range pq.Metrics {
+			endpoint, qt, o := api.Endpoint(sm.Endpoint), QueryType(sm.QueryType),
+				Outcome(sm.Outcome)
+			m, ok := eqos[endpoint][qt][o]
+			if !ok || sm.Count == 0 {
+				// e.g., an endpoint since removed
+				continue
+			}
+			m.Count = sm.Count
+			m.Earliest = time.Unix(0, sm.EarliestNanos)
+			m.Latest = time.Unix(0, sm.LatestNanos)
+			r.endpointQueryPeers[endpoint][qt][known][idStr] = struct{}{}
+		}
+		r.peers[idStr] = eqos
+	}
+}
+
+func (eqos endpointQueryOutcomes) toStored() []*sstorage.QueryMetrics {
+	stored := make([]*sstorage.QueryMetrics, 0)
+	for endpoint, qos := range eqos {
+		for qt, outcomes := range qos {
+			for o, m := range outcomes {
+				m.mu.Lock()
+				if m.Count > 0 {
+					stored = append(stored, &sstorage.QueryMetrics{
+						Endpoint:      int32(endpoint),
+						QueryType:     int32(qt),
+						Outcome:       int32(o),
+						Count:         m.Count,
+						EarliestNanos: m.Earliest.UnixNano(),
+						LatestNanos:   m.Latest.UnixNano(),
+					})
+				}
+				m.mu.Unlock()
+			}
+		}
+	}
+	return stored
+}
+
+func (m *ScalarMetrics) latest() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Latest
+}
@@ -0,0 +1,123 @@
+package comm
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryCheckpointParameters_Enabled(t *testing.T) {
+	assert.True(t, NewDefaultQueryCheckpointParameters().Enabled())
+	assert.False(t, (&QueryCheckpointParameters{}).Enabled())
+}
+
+func TestWindowQueryGetters_Save_LoadWindowQueryRecorderGetters(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sl := &cstorage.TestSLD{}
+	knower := NewAlwaysKnower()
+	windows := []time.Duration{Day, Week}
+	r1, gs1 := NewWindowQueryRecorderGetters(knower, windows)
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	r1.Record(peerID1, api.Find, Response, Success)
+	r1.Record(peerID1, api.Find, Response, Success)
+	r1.Record(peerID1, api.Store, Response, Error)
+	r1.Record(peerID2, api.Verify, Request, Success)
+	assert.Nil(t, gs1.Save(sl, NewDefaultQueryCheckpointParameters()))
+
+	_, gs2, err := LoadWindowQueryRecorderGetters(sl, knower, windows)
+	assert.Nil(t, err)
+	for _, window := range windows {
+		for _, peerID := range []id.ID{peerID1, peerID2} {
+			for _, e := range []api.Endpoint{api.All, api.Find, api.Store, api.Verify} {
+				qos1, qos2 := gs1[window].Get(peerID, e), gs2[window].Get(peerID, e)
+				for _, qt := range []QueryType{Request, Response} {
+					for _, o := range []Outcome{Success, Error} {
+						assert.Equal(t, qos1[qt][o].Count, qos2[qt][o].Count)
+						assert.Equal(t, qos1[qt][o].Latest.UnixNano(),
+							qos2[qt][o].Latest.UnixNano())
+					}
+				}
+			}
+		}
+		assert.Equal(t, 1, gs2[window].CountPeers(api.Find, Response, true))
+		assert.Equal(t, 2, gs2[window].CountPeers(api.All, Response, true)+
+			gs2[window].CountPeers(api.All, Request, true))
+	}
+	assert.Equal(t, uint64(2), gs2[Day].Get(peerID1, api.Find)[Response][Success].Count)
+
+	// long-gone peers aren't saved
+	assert.Nil(t, gs2.Save(sl, &QueryCheckpointParameters{MaxPeers: DefaultQueryMaxPeers}))
+	_, gs3, err := LoadWindowQueryRecorderGetters(sl, knower, windows)
+	assert.Nil(t, err)
+	assert.Zero(t, gs3[Day].Get(peerID1, api.All)[Response][Success].Count)
+	assert.Zero(t, gs3[Day].CountPeers(api.All, Response, true))
+}
+
+func TestWindowQueryGetters_Save_maxPeers(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sl := &cstorage.TestSLD{}
+	knower := NewAlwaysKnower()
+	r1, gs1 := NewWindowQueryRecorderGetters(knower, []time.Duration{Day})
+	peerIDs := make([]id.ID, 4)
+	for i := range peerIDs {
+		peerIDs[i] = id.NewPseudoRandom(rng)
+		r1.Record(peerIDs[i], api.Find, Request, Success)
+		time.Sleep(time.Millisecond)
+	}
+	params := NewDefaultQueryCheckpointParameters()
+	params.MaxPeers = 2
+	assert.Nil(t, gs1.Save(sl, params))
+
+	// only the peers with the latest queries are saved
+	_, gs2, err := LoadWindowQueryRecorderGetters(sl, knower, []time.Duration{Day})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, gs2[Day].CountPeers(api.Find, Request, true))
+	for i, peerID := range peerIDs {
+		count := gs2[Day].Get(peerID, api.Find)[Request][Success].Count
+		assert.Equal(t, i >= 2, count == 1)
+	}
+}
+
+func TestLoadWindowQueryRecorderGetters_pastWindow(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sl := &cstorage.TestSLD{}
+	knower := NewAlwaysKnower()
+	r1, gs1 := NewWindowQueryRecorderGetters(knower, []time.Duration{Day})
+	peerID := id.NewPseudoRandom(rng)
+	r1.Record(peerID, api.Find, Response, Success)
+
+	assert.Nil(t, gs1.Save(sl, NewDefaultQueryCheckpointParameters()))
+
+	// simulate window having ended since saving
+	stored := &sstorage.QueryWindows{}
+	assert.Nil(t, proto.Unmarshal(sl.Bytes, stored))
+	stored.Windows[0].StartNanos -= int64(2 * Day)
+	sl.Bytes, _ = proto.Marshal(stored)
+
+	_, gs2, err := LoadWindowQueryRecorderGetters(sl, knower, []time.Duration{Day})
+	assert.Nil(t, err)
+	assert.Zero(t, gs2[Day].Get(peerID, api.Find)[Response][Success].Count)
+}
+
+func TestLoadWindowQueryRecorderGetters_err(t *testing.T) {
+	knower := NewAlwaysKnower()
+	r, gs, err := LoadWindowQueryRecorderGetters(
+		&cstorage.TestSLD{LoadErr: errors.New("some Load error")}, knower, []time.Duration{Day})
+	assert.NotNil(t, err)
+	assert.Nil(t, r)
+	assert.Nil(t, gs)
+
+	r, gs, err = LoadWindowQueryRecorderGetters(
+		&cstorage.TestSLD{Bytes: []byte("not a proto")}, knower, []time.Duration{Day})
+	assert.NotNil(t, err)
+	assert.Nil(t, r)
+	assert.Nil(t, gs)
+}
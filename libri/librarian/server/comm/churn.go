@@ -0,0 +1,266 @@
+package comm
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+)
+
+const (
+	// DefaultSessionTimeout is the default duration without a successful query to or from a peer
+	// after which its uptime session is considered to have ended at its last successful query.
+	DefaultSessionTimeout = 10 * time.Minute
+
+	// maxChurnPeers is the maximum number of peers whose uptime sessions are kept.
+	maxChurnPeers = 4096
+
+	// maxPeerSessions is the maximum number of each peer's most recent ended sessions kept.
+	maxPeerSessions = 32
+
+	// churnStatsInterval is the interval at which the aggregate churn statistics are recomputed.
+	churnStatsInterval = 1 * time.Minute
+)
+
+// Session is a period during which a peer was continuously available.
+type Session struct {
+	// Start is when the peer was first seen in the session
+	Start time.Time `json:"start"`
+
+	// End is when the peer was last seen before disconnecting, or zero if still connected
+	End time.Time `json:"end"`
+}
+
+// Length returns the session length, measured up to the given time if it hasn't ended.
+func (s Session) Length(now time.Time) time.Duration {
+	if s.End.IsZero() {
+		return now.Sub(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// PeerAvailability contains a peer's uptime sessions.
+type PeerAvailability struct {
+	// FirstSeen is when the peer was first seen
+	FirstSeen time.Time `json:"first_seen"`
+
+	// LastSeen is when the peer was last seen
+	LastSeen time.Time `json:"last_seen"`
+
+	// Sessions are the peer's most recent sessions, ordered by start time, with the last still
+	// ongoing if the peer is connected
+	Sessions []Session `json:"sessions"`
+}
+
+// Online returns whether the peer's latest session is ongoing.
+func (a *PeerAvailability) Online() bool {
+	return len(a.Sessions) > 0 && a.Sessions[len(a.Sessions)-1].End.IsZero()
+}
+
+// Uptime returns the fraction of the time since the peer was first seen that it was connected,
+// over its kept sessions.
+func (a *PeerAvailability) Uptime(now time.Time) float64 {
+	if len(a.Sessions) == 0 || !now.After(a.Sessions[0].Start) {
+		return 0
+	}
+	var up time.Duration
+	for _, s := range a.Sessions {
+		up += s.Length(now)
+	}
+	return float64(up) / float64(now.Sub(a.Sessions[0].Start))
+}
+
+// ChurnStats are aggregate statistics of the peers' uptime sessions.
+type ChurnStats struct {
+	// NPeers is the number of peers whose sessions are tracked
+	NPeers int `json:"n_peers"`
+
+	// NOnline is the number of those peers currently connected
+	NOnline int `json:"n_online"`
+
+	// NEndedSessions is the number of kept ended sessions
+	NEndedSessions int `json:"n_ended_sessions"`
+
+	// MedianSession is the median length of the ended sessions, or zero if there are none
+	MedianSession time.Duration `json:"median_session"`
+
+	// DailyDisconnects is the number of sessions that ended over the last day
+	DailyDisconnects int `json:"daily_disconnects"`
+
+	// DailyChurnRate is the fraction of the peers connected at some point over the last day that
+	// disconnected in it
+	DailyChurnRate float64 `json:"daily_churn_rate"`
+}
+
+// ChurnGetter gets the peers' uptime sessions and aggregate churn statistics.
+type ChurnGetter interface {
+	// Availability returns the peer's uptime sessions and whether it has been seen.
+	Availability(peerID id.ID) (*PeerAvailability, bool)
+
+	// Stats returns the aggregate churn statistics, recomputed at most every minute.
+	Stats() *ChurnStats
+}
+
+// ChurnRecorderGetter tracks each peer's uptime sessions from the outcomes of queries to and
+// from it.
+type ChurnRecorderGetter interface {
+	QueryRecorder
+	ChurnGetter
+}
+
+type churnRG struct {
+	sessionTimeout time.Duration
+	knower         Knower
+	peers          map[string]*PeerAvailability
+	stats          *ChurnStats
+	statsTime      time.Time
+	now            func() time.Time
+	mu             sync.Mutex
+}
+
+// NewChurnRecorderGetter returns a new ChurnRecorderGetter. A successful query to or from a peer
+// starts or extends its session, while an errored query to it or no successful query for the
+// session timeout ends it. Only peers the Knower knows (e.g., those in the routing table) are
+// tracked, so clients and throwaway IDs don't inflate the churn.
+func NewChurnRecorderGetter(sessionTimeout time.Duration, knower Knower) ChurnRecorderGetter {
+	return &churnRG{
+		sessionTimeout: sessionTimeout,
+		knower:         knower,
+		peers:          make(map[string]*PeerAvailability),
+		now:            time.Now,
+	}
+}
+
+func (c *churnRG) Record(peerID id.ID, endpoint api.Endpoint, qt QueryType, o Outcome) {
+	if peerID == nil || (o == Error && qt == Request) {
+		// errored requests from a peer say nothing about its availability
+		return
+	}
+	idStr := peerID.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	a, in := c.peers[idStr]
+	if !in {
+		if o == Error || !c.knower.Know(peerID) {
+			return
+		}
+		if len(c.peers) >= maxChurnPeers {
+			c.evictStalest()
+		}
+		a = &PeerAvailability{FirstSeen: now}
+		c.peers[idStr] = a
+	}
+	c.maybeTimeOut(a, now)
+	if o == Error {
+		if a.Online() {
+			a.Sessions[len(a.Sessions)-1].End = a.LastSeen
+		}
+		return
+	}
+	if !a.Online() {
+		a.Sessions = append(a.Sessions, Session{Start: now})
+		if len(a.Sessions) > maxPeerSessions {
+			a.Sessions = a.Sessions[len(a.Sessions)-maxPeerSessions:]
+		}
+	}
+	a.LastSeen = now
+}
+
+func (c *churnRG) Availability(peerID id.ID) (*PeerAvailability, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a, in := c.peers[peerID.String()]
+	if !in {
+		return nil, false
+	}
+	c.maybeTimeOut(a, c.now())
+	sessions := make([]Session, len(a.Sessions))
+	copy(sessions, a.Sessions)
+	return &PeerAvailability{FirstSeen: a.FirstSeen, LastSeen: a.LastSeen, Sessions: sessions},
+		true
+}
+
+func (c *churnRG) Stats() *ChurnStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.stats == nil || now.Sub(c.statsTime) >= churnStatsInterval {
+		c.stats, c.statsTime = c.computeStats(now), now
+	}
+	stats := *c.stats
+	return &stats
+}
+
+// computeStats computes the aggregate churn statistics over all tracked peers.
+func (c *churnRG) computeStats(now time.Time) *ChurnStats {
+	dayAgo := now.Add(-Day)
+	stats := &ChurnStats{NPeers: len(c.peers)}
+	lengths := make([]time.Duration, 0)
+	nDailyPeers := 0
+	for _, a := range c.peers {
+		c.maybeTimeOut(a, now)
+		if a.Online() {
+			stats.NOnline++
+		}
+		if a.LastSeen.After(dayAgo) {
+			nDailyPeers++
+		}
+		for _, s := range a.Sessions {
+			if s.End.IsZero() {
+				continue
+			}
+			lengths = append(lengths, s.Length(now))
+			if s.End.After(dayAgo) {
+				stats.DailyDisconnects++
+			}
+		}
+	}
+	stats.NEndedSessions = len(lengths)
+	if len(lengths) > 0 {
+		sort.Slice(lengths, func(i, j int) bool { return lengths[i] < lengths[j] })
+		stats.MedianSession = lengths[len(lengths)/2]
+	}
+	if nDailyPeers > 0 {
+		stats.DailyChurnRate = float64(stats.DailyDisconnects) / float64(nDailyPeers)
+	}
+	return stats
+}
+
+// maybeTimeOut ends the peer's ongoing session at its last successful query if there hasn't
+// been one for the session timeout.
+func (c *churnRG) maybeTimeOut(a *PeerAvailability, now time.Time) {
+	if a.Online() && now.Sub(a.LastSeen) > c.sessionTimeout {
+		a.Sessions[len(a.Sessions)-1].End = a.LastSeen
+	}
+}
+
+// evictStalest removes the peer seen least recently to make room for another.
+func (c *churnRG) evictStalest() {
+	var stalest string
+	var stalestSeen time.Time
+	for idStr, a := range c.peers {
+		if stalest == "" || a.LastSeen.Before(stalestSeen) {
+			stalest, stalestSeen = idStr, a.LastSeen
+		}
+	}
+	delete(c.peers, stalest)
+}
+
+type churnRecorder struct {
+	QueryRecorder
+	churn QueryRecorder
+}
+
+// NewChurnRecorder returns a QueryRecorder that also records query outcomes with the
+// ChurnRecorderGetter, so the outcomes recorded throughout the librarian track peers' sessions.
+func NewChurnRecorder(inner QueryRecorder, churn ChurnRecorderGetter) QueryRecorder {
+	return &churnRecorder{QueryRecorder: inner, churn: churn}
+}
+
+func (r *churnRecorder) Record(peerID id.ID, endpoint api.Endpoint, qt QueryType, o Outcome) {
+	r.QueryRecorder.Record(peerID, endpoint, qt, o)
+	r.churn.Record(peerID, endpoint, qt, o)
+}
@@ -0,0 +1,193 @@
+package comm
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_Length(t *testing.T) {
+	start := time.Unix(0, 0)
+	assert.Equal(t, time.Hour, Session{Start: start, End: start.Add(time.Hour)}.Length(
+		start.Add(2*time.Hour)))
+	assert.Equal(t, 2*time.Hour, Session{Start: start}.Length(start.Add(2*time.Hour)))
+}
+
+func TestPeerAvailability_Uptime(t *testing.T) {
+	start := time.Unix(0, 0)
+	a := &PeerAvailability{}
+	assert.Zero(t, a.Uptime(start))
+	assert.False(t, a.Online())
+
+	a.Sessions = []Session{
+		{Start: start, End: start.Add(time.Hour)},
+		{Start: start.Add(3 * time.Hour)},
+	}
+	assert.True(t, a.Online())
+	assert.Equal(t, 0.5, a.Uptime(start.Add(4*time.Hour)))
+}
+
+func TestChurnRecorderGetter_Record(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	c := NewChurnRecorderGetter(DefaultSessionTimeout, NewAlwaysKnower())
+	c.(*churnRG).now = func() time.Time { return now }
+	peerID := id.NewPseudoRandom(rng)
+
+	// errors from unseen peers and nil peers ignored
+	c.Record(peerID, api.Find, Response, Error)
+	c.Record(nil, api.Find, Response, Success)
+	_, in := c.Availability(peerID)
+	assert.False(t, in)
+
+	// success starts session
+	c.Record(peerID, api.Find, Response, Success)
+	now = now.Add(time.Minute)
+	c.Record(peerID, api.Store, Request, Success)
+	a, in := c.Availability(peerID)
+	assert.True(t, in)
+	assert.True(t, a.Online())
+	assert.Equal(t, time.Unix(0, 0), a.FirstSeen)
+	assert.Equal(t, now, a.LastSeen)
+	assert.Len(t, a.Sessions, 1)
+
+	// errored requests from peer don't end session
+	c.Record(peerID, api.Store, Request, Error)
+	a, _ = c.Availability(peerID)
+	assert.True(t, a.Online())
+
+	// errored query to peer ends session at last success
+	now = now.Add(time.Minute)
+	c.Record(peerID, api.Find, Response, Error)
+	a, _ = c.Availability(peerID)
+	assert.False(t, a.Online())
+	assert.Equal(t, time.Minute, a.Sessions[0].Length(now))
+
+	// next success starts new session
+	c.Record(peerID, api.Find, Response, Success)
+	a, _ = c.Availability(peerID)
+	assert.True(t, a.Online())
+	assert.Len(t, a.Sessions, 2)
+
+	// session times out without successes
+	now = now.Add(DefaultSessionTimeout + time.Second)
+	a, _ = c.Availability(peerID)
+	assert.False(t, a.Online())
+	assert.Zero(t, a.Sessions[1].Length(now))
+}
+
+func TestChurnRecorderGetter_Record_unknown(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	c := NewChurnRecorderGetter(DefaultSessionTimeout, &neverKnower{})
+	peerID := id.NewPseudoRandom(rng)
+
+	// peers unknown when first seen aren't tracked
+	c.Record(peerID, api.Find, Request, Success)
+	_, in := c.Availability(peerID)
+	assert.False(t, in)
+	assert.Zero(t, c.Stats().NPeers)
+}
+
+func TestChurnRecorderGetter_Record_maxSessions(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	c := NewChurnRecorderGetter(DefaultSessionTimeout, NewAlwaysKnower())
+	c.(*churnRG).now = func() time.Time { return now }
+	peerID := id.NewPseudoRandom(rng)
+
+	for i := 0; i < 2*maxPeerSessions; i++ {
+		c.Record(peerID, api.Find, Response, Success)
+		now = now.Add(time.Minute)
+		c.Record(peerID, api.Find, Response, Error)
+	}
+	a, _ := c.Availability(peerID)
+	assert.Len(t, a.Sessions, maxPeerSessions)
+	assert.Equal(t, time.Unix(0, 0), a.FirstSeen)
+}
+
+func TestChurnRecorderGetter_Record_maxPeers(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	c := NewChurnRecorderGetter(DefaultSessionTimeout, NewAlwaysKnower())
+	c.(*churnRG).now = func() time.Time { return now }
+	stalest := id.NewPseudoRandom(rng)
+	c.Record(stalest, api.Find, Response, Success)
+	for i := 1; i < maxChurnPeers+1; i++ {
+		now = now.Add(time.Second)
+		c.Record(id.NewPseudoRandom(rng), api.Find, Response, Success)
+	}
+	assert.Equal(t, maxChurnPeers, c.Stats().NPeers)
+	_, in := c.Availability(stalest)
+	assert.False(t, in)
+}
+
+func TestChurnRecorderGetter_Stats(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0).Add(2 * Day)
+	// timeout longer than the sessions, so only the errors end them
+	c := NewChurnRecorderGetter(4*time.Hour, NewAlwaysKnower())
+	c.(*churnRG).now = func() time.Time { return now }
+	assert.Equal(t, &ChurnStats{}, c.Stats())
+
+	// peer with sessions lasting 1, 2, and 3 hours
+	peerID1 := id.NewPseudoRandom(rng)
+	for _, length := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		c.Record(peerID1, api.Find, Response, Success)
+		now = now.Add(length)
+		c.Record(peerID1, api.Find, Response, Success)
+		c.Record(peerID1, api.Find, Response, Error)
+		now = now.Add(time.Hour)
+	}
+
+	// peer still online
+	peerID2 := id.NewPseudoRandom(rng)
+	c.Record(peerID2, api.Find, Response, Success)
+
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.NPeers)
+	assert.Equal(t, 1, stats.NOnline)
+	assert.Equal(t, 3, stats.NEndedSessions)
+	assert.Equal(t, 2*time.Hour, stats.MedianSession)
+	assert.Equal(t, 3, stats.DailyDisconnects)
+	assert.Equal(t, 1.5, stats.DailyChurnRate)
+
+	// disconnects more than a day ago not counted
+	now = now.Add(2 * Day)
+	stats = c.Stats()
+	assert.Zero(t, stats.NOnline)
+	assert.Equal(t, 4, stats.NEndedSessions)
+	assert.Zero(t, stats.DailyDisconnects)
+	assert.Zero(t, stats.DailyChurnRate)
+}
+
+func TestChurnRecorderGetter_Stats_cached(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	c := NewChurnRecorderGetter(DefaultSessionTimeout, NewAlwaysKnower())
+	c.(*churnRG).now = func() time.Time { return now }
+	assert.Zero(t, c.Stats().NPeers)
+
+	// stats not recomputed until the interval has passed
+	c.Record(id.NewPseudoRandom(rng), api.Find, Response, Success)
+	assert.Zero(t, c.Stats().NPeers)
+	now = now.Add(churnStatsInterval)
+	assert.Equal(t, 1, c.Stats().NPeers)
+}
+
+func TestChurnRecorder_Record(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	inner := NewQueryRecorderGetter(NewAlwaysKnower())
+	churn := NewChurnRecorderGetter(DefaultSessionTimeout, NewAlwaysKnower())
+	r := NewChurnRecorder(inner, churn)
+	peerID := id.NewPseudoRandom(rng)
+
+	r.Record(peerID, api.Find, Response, Success)
+	assert.Equal(t, uint64(1), inner.Get(peerID, api.Find)[Response][Success].Count)
+	a, in := churn.Availability(peerID)
+	assert.True(t, in)
+	assert.True(t, a.Online())
+}
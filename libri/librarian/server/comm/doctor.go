@@ -1,6 +1,9 @@
 package comm
 
 import (
+	"errors"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/drausin/libri/libri/common/id"
@@ -40,16 +43,183 @@ type responseTimeDoctor struct {
 func (d *responseTimeDoctor) Healthy(peerID id.ID) bool {
 	// Verify endpoint should most regularly be used, so just check that one for now
 	verifyOutcomes := d.recorder.Get(peerID, api.Verify)
-	latestErrTime := verifyOutcomes[Response][Error].Latest
+	latestErrTime := latestFailure(verifyOutcomes[Response])
 	latestSuccessTime := verifyOutcomes[Response][Success].Latest
 
 	if latestErrTime.IsZero() && latestSuccessTime.IsZero() {
 		// fall back to Find if no Verifications
 		verifyOutcomes = d.recorder.Get(peerID, api.Find)
-		latestErrTime = verifyOutcomes[Response][Error].Latest
+		latestErrTime = latestFailure(verifyOutcomes[Response])
 		latestSuccessTime = verifyOutcomes[Response][Success].Latest
 	}
 
 	// assume healthy if latest error time less than 5 mins after success time
 	return latestErrTime.Before(latestSuccessTime.Add(5 * time.Minute))
 }
+
+// latestFailure returns the latest time of either an error or integrity failure.
+func latestFailure(outcomes map[Outcome]*ScalarMetrics) time.Time {
+	latest := outcomes[Error].Latest
+	if integrityFailure := outcomes[IntegrityFailure].Latest; integrityFailure.After(latest) {
+		return integrityFailure
+	}
+	return latest
+}
+
+const (
+	// DefaultProbeInterval is the default mean interval between rounds of health probes.
+	DefaultProbeInterval = 30 * time.Second
+
+	// DefaultProbeNPeers is the default number of routing table peers probed each round.
+	DefaultProbeNPeers = uint(8)
+
+	// DefaultProbeJitter is the default fraction of the probe interval by which each interval is
+	// randomly lengthened or shortened.
+	DefaultProbeJitter = 0.2
+
+	// DefaultProbeMaxFailures is the default number of consecutive failed probes after which a
+	// peer is deemed unhealthy.
+	DefaultProbeMaxFailures = uint(2)
+
+	// DefaultProbeTTL is the default duration a probe's outcome determines a peer's health.
+	DefaultProbeTTL = 10 * time.Minute
+)
+
+// ErrInvalidProbeJitter indicates that the probe jitter is outside [0, 1).
+var ErrInvalidProbeJitter = errors.New("probe jitter must be in [0, 1)")
+
+// ProbeParameters define how a librarian actively probes the health of its routing table peers.
+type ProbeParameters struct {
+	// Interval is the mean interval between rounds of probes, with zero disabling probing.
+	Interval time.Duration
+
+	// NPeers is the number of routing table peers probed each round, so the probe rate is
+	// NPeers / Interval.
+	NPeers uint
+
+	// Jitter is the fraction, in [0, 1), of Interval by which each interval is randomly
+	// lengthened or shortened, so peers' probes don't synchronize.
+	Jitter float64
+
+	// MaxFailures is the number of consecutive failed probes after which a peer is deemed
+	// unhealthy.
+	MaxFailures uint
+
+	// TTL is the duration a probe's outcome determines a peer's health, after which the
+	// peer's health is again judged from its responses to other queries.
+	TTL time.Duration
+}
+
+// NewDefaultProbeParameters returns the default ProbeParameters.
+func NewDefaultProbeParameters() *ProbeParameters {
+	return &ProbeParameters{
+		Interval:    DefaultProbeInterval,
+		NPeers:      DefaultProbeNPeers,
+		Jitter:      DefaultProbeJitter,
+		MaxFailures: DefaultProbeMaxFailures,
+		TTL:         DefaultProbeTTL,
+	}
+}
+
+// Enabled returns whether peers are probed.
+func (p *ProbeParameters) Enabled() bool {
+	return p.Interval > 0 && p.NPeers > 0
+}
+
+// Validate returns an error if any of the parameters are invalid.
+func (p *ProbeParameters) Validate() error {
+	if p.Jitter < 0 || p.Jitter >= 1 {
+		// otherwise intervals could be non-positive, and the probe loop would spin
+		return ErrInvalidProbeJitter
+	}
+	return nil
+}
+
+// NextInterval returns the jittered interval before the next round of probes.
+func (p *ProbeParameters) NextInterval(rng *rand.Rand) time.Duration {
+	jitter := p.Jitter * (2*rng.Float64() - 1)
+	return time.Duration(float64(p.Interval) * (1 + jitter))
+}
+
+// ProbingDoctor is a Doctor judging peers' health by the outcomes of active probes.
+type ProbingDoctor interface {
+	Doctor
+
+	// RecordProbe records the outcome of probing the peer, with a nil error meaning it
+	// responded.
+	RecordProbe(peerID id.ID, err error)
+
+	// Prune forgets the probes older than the TTL, returning the number forgotten.
+	Prune() int
+}
+
+type probeState struct {
+	nFailures uint
+	latest    time.Time
+}
+
+type probingDoctor struct {
+	inner  Doctor
+	params *ProbeParameters
+	probes map[string]*probeState
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// NewProbingDoctor returns a ProbingDoctor that deems a recently probed peer unhealthy after
+// MaxFailures consecutive failed probes, deferring to the inner Doctor for peers not probed
+// within the TTL.
+func NewProbingDoctor(inner Doctor, params *ProbeParameters) ProbingDoctor {
+	return &probingDoctor{
+		inner:  inner,
+		params: params,
+		probes: make(map[string]*probeState),
+		now:    time.Now,
+	}
+}
+
+func (d *probingDoctor) RecordProbe(peerID id.ID, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := peerID.String()
+	ps, in := d.probes[key]
+	if !in {
+		ps = &probeState{}
+		d.probes[key] = ps
+	}
+	ps.latest = d.now()
+	if err == nil {
+		ps.nFailures = 0
+		return
+	}
+	ps.nFailures++
+}
+
+func (d *probingDoctor) Prune() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now, nPruned := d.now(), 0
+	for key, ps := range d.probes {
+		if now.Sub(ps.latest) > d.params.TTL {
+			delete(d.probes, key)
+			nPruned++
+		}
+	}
+	return nPruned
+}
+
+func (d *probingDoctor) Healthy(peerID id.ID) bool {
+	d.mu.Lock()
+	key := peerID.String()
+	ps, in := d.probes[key]
+	if in && d.now().Sub(ps.latest) > d.params.TTL {
+		delete(d.probes, key)
+		in = false
+	}
+	healthy := in && ps.nFailures < d.params.MaxFailures
+	d.mu.Unlock()
+	if !in {
+		return d.inner.Healthy(peerID)
+	}
+	return healthy
+}
@@ -1,6 +1,7 @@
 package comm
 
 import (
+	"errors"
 	"math/rand"
 	"testing"
 	"time"
@@ -54,6 +55,14 @@ func TestResponseTimeDoctor_Healthy(t *testing.T) {
 	qo3[Response][Success].Latest = now.Add(5 * time.Minute)
 	d3 := NewResponseTimeDoctor(&fixedRecorder{getValue: qo3})
 	assert.True(t, d3.Healthy(peerID))
+
+	// check not healthy since latest success was 15 mins before latest integrity failure
+	qo5 := newQueryOutcomes()
+	qo5[Response][Error].Latest = now.Add(-30 * time.Minute)
+	qo5[Response][IntegrityFailure].Latest = now
+	qo5[Response][Success].Latest = now.Add(-15 * time.Minute)
+	d5 := NewResponseTimeDoctor(&fixedRecorder{getValue: qo5})
+	assert.False(t, d5.Healthy(peerID))
 }
 
 type fixedGetter struct {
@@ -67,3 +76,76 @@ func (f *fixedGetter) Get(peerID id.ID, endpoint api.Endpoint) QueryOutcomes {
 func (f *fixedGetter) CountPeers(endpoint api.Endpoint, qt QueryType, known bool) int {
 	panic("implement me")
 }
+
+func TestProbeParameters(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p := NewDefaultProbeParameters()
+	assert.True(t, p.Enabled())
+	assert.False(t, (&ProbeParameters{NPeers: 1}).Enabled())
+	assert.False(t, (&ProbeParameters{Interval: time.Second}).Enabled())
+
+	for c := 0; c < 16; c++ {
+		interval := p.NextInterval(rng)
+		assert.True(t, float64(interval) >= float64(p.Interval)*(1-p.Jitter))
+		assert.True(t, float64(interval) <= float64(p.Interval)*(1+p.Jitter))
+	}
+	p.Jitter = 0
+	assert.Equal(t, p.Interval, p.NextInterval(rng))
+
+	assert.Nil(t, p.Validate())
+	for _, jitter := range []float64{-0.1, 1, 1.5} {
+		p.Jitter = jitter
+		assert.Equal(t, ErrInvalidProbeJitter, p.Validate())
+	}
+}
+
+func TestProbingDoctor_Healthy(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultProbeParameters()
+	d := NewProbingDoctor(NewNaiveDoctor(), params)
+	now := time.Now()
+	d.(*probingDoctor).now = func() time.Time { return now }
+	peerID := id.NewPseudoRandom(rng)
+
+	// unprobed peer defers to inner doctor
+	assert.True(t, d.Healthy(peerID))
+
+	// unhealthy after max consecutive failures
+	for c := uint(0); c < params.MaxFailures; c++ {
+		assert.True(t, d.Healthy(peerID))
+		d.RecordProbe(peerID, errors.New("some Introduce error"))
+	}
+	assert.False(t, d.Healthy(peerID))
+
+	// healthy again after successful probe
+	d.RecordProbe(peerID, nil)
+	assert.True(t, d.Healthy(peerID))
+
+	// stale probes defer to inner doctor
+	for c := uint(0); c < params.MaxFailures; c++ {
+		d.RecordProbe(peerID, errors.New("some Introduce error"))
+	}
+	assert.False(t, d.Healthy(peerID))
+	now = now.Add(2 * params.TTL)
+	assert.True(t, d.Healthy(peerID))
+}
+
+func TestProbingDoctor_Prune(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultProbeParameters()
+	d := NewProbingDoctor(NewNaiveDoctor(), params)
+	now := time.Now()
+	d.(*probingDoctor).now = func() time.Time { return now }
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	d.RecordProbe(peerID1, nil)
+	now = now.Add(params.TTL)
+	d.RecordProbe(peerID2, errors.New("some Introduce error"))
+	assert.Equal(t, 0, d.Prune())
+
+	// only peer 1's probe is older than the TTL
+	now = now.Add(time.Second)
+	assert.Equal(t, 1, d.Prune())
+	assert.Len(t, d.(*probingDoctor).probes, 1)
+	assert.Contains(t, d.(*probingDoctor).probes, peerID2.String())
+}
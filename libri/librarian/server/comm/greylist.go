@@ -0,0 +1,202 @@
+package comm
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+const (
+	// DefaultGreylistPeriod is the default duration a peer is first greylisted for.
+	DefaultGreylistPeriod = 1 * time.Minute
+
+	// DefaultGreylistThreshold is the default number of recent offenses after which a peer is
+	// first greylisted.
+	DefaultGreylistThreshold = uint(3)
+
+	// DefaultMaxGreylistPeriod is the default maximum duration a peer is greylisted for.
+	DefaultMaxGreylistPeriod = 6 * time.Hour
+
+	// DefaultGreylistForgetAfter is the default duration after a peer's latest offense after
+	// which its past offenses are forgotten.
+	DefaultGreylistForgetAfter = 24 * time.Hour
+
+	// maxGreylistedPeers is the maximum number of peers (and IPs) with recorded offenses, above
+	// which those with forgotten or else the oldest offenses are pruned.
+	maxGreylistedPeers = 4096
+)
+
+// GreylistParameters define how long misbehaving peers are greylisted.
+type GreylistParameters struct {
+	// Period is the duration a peer is first greylisted for, with each repeat offense doubling
+	// it. Zero disables greylisting.
+	Period time.Duration
+
+	// Threshold is the number of recent offenses after which a peer is first greylisted, with
+	// zero meaning it is greylisted on its first offense.
+	Threshold uint
+
+	// MaxPeriod is the maximum duration a peer is greylisted for, with zero meaning repeat
+	// offenses aren't greylisted for longer than the first.
+	MaxPeriod time.Duration
+
+	// ForgetAfter is the duration after a peer's latest offense after which its past offenses
+	// are forgotten, so its next offense is treated as its first.
+	ForgetAfter time.Duration
+}
+
+// NewDefaultGreylistParameters returns the default GreylistParameters.
+func NewDefaultGreylistParameters() *GreylistParameters {
+	return &GreylistParameters{
+		Period:      DefaultGreylistPeriod,
+		Threshold:   DefaultGreylistThreshold,
+		MaxPeriod:   DefaultMaxGreylistPeriod,
+		ForgetAfter: DefaultGreylistForgetAfter,
+	}
+}
+
+// Enabled returns whether misbehaving peers are greylisted.
+func (p *GreylistParameters) Enabled() bool {
+	return p.Period > 0
+}
+
+type offenses struct {
+	count  uint
+	latest time.Time
+	until  time.Time
+}
+
+type greylister struct {
+	Blacklister
+	params     *GreylistParameters
+	onGreylist func(peerID id.ID)
+	offenses   map[string]*offenses
+	now        func() time.Time
+	mu         sync.Mutex
+}
+
+// NewGreylister returns a Blacklister that, in addition to deferring to the given Blacklister,
+// temporarily greylists a peer (or IP) once it has misbehaved (e.g., malformed responses or bad
+// signatures) the threshold number of times, deeming it banned until it is paroled. Each repeat
+// offense doubles the greylist period, up to the max. It calls onGreylist (if not nil) whenever a
+// peer is greylisted, e.g., to evict it from the routing table, to which it may be re-added after
+// parole.
+func NewGreylister(
+	params *GreylistParameters, bl Blacklister, onGreylist func(peerID id.ID),
+) Blacklister {
+	if !params.Enabled() {
+		return bl
+	}
+	return &greylister{
+		Blacklister: bl,
+		params:      params,
+		onGreylist:  onGreylist,
+		offenses:    make(map[string]*offenses),
+		now:         time.Now,
+	}
+}
+
+// RecordMisbehavior records a misbehavior by the peer, which callers should only attribute to
+// authenticated peer IDs (e.g., from a verified signature) lest anyone get a peer greylisted.
+// Misbehaviors from unauthenticated sources should instead use RecordIPMisbehavior.
+func (g *greylister) RecordMisbehavior(peerID id.ID, m Misbehavior) {
+	if g.greylist(peerID.String()) && g.onGreylist != nil {
+		g.onGreylist(peerID)
+	}
+	g.Blacklister.RecordMisbehavior(peerID, m)
+}
+
+func (g *greylister) RecordIPMisbehavior(ip net.IP, m Misbehavior) {
+	g.greylist(ip.String())
+	g.Blacklister.RecordIPMisbehavior(ip, m)
+}
+
+func (g *greylister) Unban(peerID id.ID) {
+	g.mu.Lock()
+	delete(g.offenses, peerID.String())
+	g.mu.Unlock()
+	g.Blacklister.Unban(peerID)
+}
+
+func (g *greylister) Banned(peerID id.ID) bool {
+	return g.greylisted(peerID.String()) || g.Blacklister.Banned(peerID)
+}
+
+func (g *greylister) BannedIP(ip net.IP) bool {
+	return g.greylisted(ip.String()) || g.Blacklister.BannedIP(ip)
+}
+
+// greylist records an offense by the peer (or IP) with the given key, greylisting it for a period
+// doubling with each of its recent offenses beyond the threshold. It returns whether the peer was
+// greylisted.
+func (g *greylister) greylist(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	o, in := g.offenses[key]
+	if !in || now.Sub(o.latest) > g.params.ForgetAfter {
+		if !in && len(g.offenses) >= maxGreylistedPeers {
+			g.prune(now)
+		}
+		o = &offenses{}
+		g.offenses[key] = o
+	}
+	o.count++
+	o.latest = now
+	if o.count < g.params.Threshold {
+		return false
+	}
+	threshold := g.params.Threshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	o.until = now.Add(g.period(o.count - threshold + 1))
+	return true
+}
+
+// period returns the greylist period for the given number of offenses.
+func (g *greylister) period(count uint) time.Duration {
+	period := g.params.Period
+	for c := uint(1); c < count && period < g.params.MaxPeriod; c++ {
+		period *= 2
+	}
+	if g.params.MaxPeriod > 0 && period > g.params.MaxPeriod {
+		return g.params.MaxPeriod
+	}
+	return period
+}
+
+// greylisted returns whether the peer (or IP) with the given key is greylisted and not yet
+// paroled.
+func (g *greylister) greylisted(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	o, in := g.offenses[key]
+	return in && g.now().Before(o.until)
+}
+
+// prune removes the peers (and IPs) whose offenses have been forgotten and then, if still at the
+// max, those with the oldest latest offenses, leaving room for a new one.
+func (g *greylister) prune(now time.Time) {
+	for key, o := range g.offenses {
+		if now.Sub(o.latest) > g.params.ForgetAfter {
+			delete(g.offenses, key)
+		}
+	}
+	if len(g.offenses) < maxGreylistedPeers {
+		return
+	}
+	keys := make([]string, 0, len(g.offenses))
+	for key := range g.offenses {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return g.offenses[keys[i]].latest.Before(g.offenses[keys[j]].latest)
+	})
+	for _, key := range keys[:len(keys)-maxGreylistedPeers+1] {
+		delete(g.offenses, key)
+	}
+}
@@ -0,0 +1,163 @@
+package comm
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGreylistParameters_Enabled(t *testing.T) {
+	assert.True(t, NewDefaultGreylistParameters().Enabled())
+	assert.False(t, (&GreylistParameters{}).Enabled())
+}
+
+func TestNewGreylister_disabled(t *testing.T) {
+	bl := NewNaiveBlacklister()
+	assert.Equal(t, bl, NewGreylister(&GreylistParameters{}, bl, nil))
+}
+
+func TestGreylister_RecordMisbehavior(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	greylisted := make([]id.ID, 0)
+	params := NewDefaultGreylistParameters()
+	params.Threshold = 1
+	g := NewGreylister(params, NewNaiveBlacklister(),
+		func(peerID id.ID) { greylisted = append(greylisted, peerID) })
+	g.(*greylister).now = func() time.Time { return now }
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	g.RecordMisbehavior(peerID1, ProtocolViolation)
+	assert.True(t, g.Banned(peerID1))
+	assert.False(t, g.Banned(peerID2))
+	assert.Equal(t, []id.ID{peerID1}, greylisted)
+
+	// paroled after period
+	now = now.Add(DefaultGreylistPeriod + time.Second)
+	assert.False(t, g.Banned(peerID1))
+
+	// repeat offense doubles period
+	g.RecordMisbehavior(peerID1, ProtocolViolation)
+	now = now.Add(DefaultGreylistPeriod + time.Second)
+	assert.True(t, g.Banned(peerID1))
+	now = now.Add(DefaultGreylistPeriod)
+	assert.False(t, g.Banned(peerID1))
+
+	// past offenses forgotten after a while
+	now = now.Add(DefaultGreylistForgetAfter + time.Second)
+	g.RecordMisbehavior(peerID1, ProtocolViolation)
+	now = now.Add(DefaultGreylistPeriod + time.Second)
+	assert.False(t, g.Banned(peerID1))
+
+	// unban lifts greylisting
+	g.RecordMisbehavior(peerID2, ProtocolViolation)
+	g.Unban(peerID2)
+	assert.False(t, g.Banned(peerID2))
+}
+
+func TestGreylister_RecordIPMisbehavior(t *testing.T) {
+	now := time.Unix(0, 0)
+	params := NewDefaultGreylistParameters()
+	params.Threshold = 1
+	g := NewGreylister(params, NewNaiveBlacklister(), nil)
+	g.(*greylister).now = func() time.Time { return now }
+	ip1, ip2 := net.ParseIP("10.1.2.3"), net.ParseIP("10.1.2.4")
+
+	g.RecordIPMisbehavior(ip1, InvalidSignature)
+	assert.True(t, g.BannedIP(ip1))
+	assert.False(t, g.BannedIP(ip2))
+
+	now = now.Add(DefaultGreylistPeriod + time.Second)
+	assert.False(t, g.BannedIP(ip1))
+}
+
+func TestGreylister_RecordMisbehavior_threshold(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	greylisted := make([]id.ID, 0)
+	g := NewGreylister(NewDefaultGreylistParameters(), NewNaiveBlacklister(),
+		func(peerID id.ID) { greylisted = append(greylisted, peerID) })
+	g.(*greylister).now = func() time.Time { return now }
+	peerID := id.NewPseudoRandom(rng)
+
+	// not greylisted until threshold reached
+	for c := uint(1); c < DefaultGreylistThreshold; c++ {
+		g.RecordMisbehavior(peerID, ProtocolViolation)
+		assert.False(t, g.Banned(peerID))
+	}
+	assert.Empty(t, greylisted)
+
+	g.RecordMisbehavior(peerID, ProtocolViolation)
+	assert.True(t, g.Banned(peerID))
+	assert.Equal(t, []id.ID{peerID}, greylisted)
+
+	// first greylisting lasts the base period
+	now = now.Add(DefaultGreylistPeriod + time.Second)
+	assert.False(t, g.Banned(peerID))
+}
+
+func TestGreylister_Banned_blacklisted(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	bl := NewBlacklister(NewDefaultBlacklistParameters(), nil)
+	g := NewGreylister(NewDefaultGreylistParameters(), bl, nil)
+	peerID := id.NewPseudoRandom(rng)
+
+	bl.Ban(peerID, Permanent)
+	assert.True(t, g.Banned(peerID))
+}
+
+func TestGreylister_period(t *testing.T) {
+	g := NewGreylister(&GreylistParameters{
+		Period:    time.Minute,
+		MaxPeriod: 5 * time.Minute,
+	}, NewNaiveBlacklister(), nil).(*greylister)
+	assert.Equal(t, time.Minute, g.period(1))
+	assert.Equal(t, 2*time.Minute, g.period(2))
+	assert.Equal(t, 4*time.Minute, g.period(3))
+	assert.Equal(t, 5*time.Minute, g.period(4))
+	assert.Equal(t, 5*time.Minute, g.period(100))
+
+	g.params.MaxPeriod = 0
+	assert.Equal(t, time.Minute, g.period(3))
+}
+
+func TestGreylister_prune(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	g := NewGreylister(NewDefaultGreylistParameters(), NewNaiveBlacklister(), nil).(*greylister)
+	g.now = func() time.Time { return now }
+	for c := 0; c < maxGreylistedPeers; c++ {
+		g.RecordMisbehavior(id.NewPseudoRandom(rng), ProtocolViolation)
+	}
+	assert.Len(t, g.offenses, maxGreylistedPeers)
+
+	// forgotten offenses pruned when adding another
+	now = now.Add(DefaultGreylistForgetAfter + time.Second)
+	g.RecordMisbehavior(id.NewPseudoRandom(rng), ProtocolViolation)
+	assert.Len(t, g.offenses, 1)
+}
+
+func TestGreylister_prune_oldest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	now := time.Unix(0, 0)
+	g := NewGreylister(NewDefaultGreylistParameters(), NewNaiveBlacklister(), nil).(*greylister)
+	g.now = func() time.Time { return now }
+	oldest := id.NewPseudoRandom(rng)
+	g.RecordMisbehavior(oldest, ProtocolViolation)
+	for c := 1; c < maxGreylistedPeers; c++ {
+		now = now.Add(time.Millisecond)
+		g.RecordMisbehavior(id.NewPseudoRandom(rng), ProtocolViolation)
+	}
+	assert.Len(t, g.offenses, maxGreylistedPeers)
+
+	// oldest offenses pruned when adding another over the max
+	now = now.Add(time.Millisecond)
+	g.RecordMisbehavior(id.NewPseudoRandom(rng), ProtocolViolation)
+	assert.Len(t, g.offenses, maxGreylistedPeers)
+	_, in := g.offenses[oldest.String()]
+	assert.False(t, in)
+}
@@ -0,0 +1,163 @@
+package comm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+const (
+	// DefaultKeyspacePrefixBits is the default number of high-order key bits used to define a
+	// keyspace region.
+	DefaultKeyspacePrefixBits = uint(4)
+
+	maxKeyspacePrefixBits = uint(8)
+)
+
+var (
+	// LatencyBucketBounds are the (inclusive) upper bounds of the latency histogram buckets for
+	// each keyspace region. Latencies above the last bound are counted in an overflow bucket.
+	LatencyBucketBounds = []time.Duration{
+		10 * time.Millisecond,
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		250 * time.Millisecond,
+		500 * time.Millisecond,
+		1 * time.Second,
+		2500 * time.Millisecond,
+	}
+)
+
+// KeyspaceRecorder records query latencies aggregated by the keyspace region of the query target.
+type KeyspaceRecorder interface {
+
+	// Record the latency and outcome of a query for the given target key.
+	Record(target id.ID, latency time.Duration, o Outcome)
+}
+
+// KeyspaceGetter gets the query latency distributions for each keyspace region.
+type KeyspaceGetter interface {
+
+	// Regions returns a snapshot of the latency distribution of every keyspace region, ordered by
+	// region prefix.
+	Regions() []*RegionLatencies
+}
+
+// KeyspaceRecorderGetter both records and gets query latencies by keyspace region.
+type KeyspaceRecorderGetter interface {
+	KeyspaceRecorder
+	KeyspaceGetter
+}
+
+// RegionLatencies contains the query latency distribution for a keyspace region.
+type RegionLatencies struct {
+
+	// Prefix is the binary representation of the high-order bits defining the region
+	Prefix string `json:"prefix"`
+
+	// NQueries is the number of queries targeting keys in the region
+	NQueries uint64 `json:"n_queries"`
+
+	// NErrors is the number of those queries that errored
+	NErrors uint64 `json:"n_errors"`
+
+	// MeanLatency is the mean query latency
+	MeanLatency time.Duration `json:"mean_latency"`
+
+	// MaxLatency is the max query latency
+	MaxLatency time.Duration `json:"max_latency"`
+
+	// Histogram contains the number of queries in each of the LatencyBucketBounds buckets, plus
+	// a final overflow bucket
+	Histogram []uint64 `json:"histogram"`
+
+	sumLatency time.Duration
+}
+
+func newRegionLatencies(prefix string) *RegionLatencies {
+	return &RegionLatencies{
+		Prefix:    prefix,
+		Histogram: make([]uint64, len(LatencyBucketBounds)+1),
+	}
+}
+
+func (rl *RegionLatencies) record(latency time.Duration, o Outcome) {
+	rl.NQueries++
+	if o == Error {
+		rl.NErrors++
+	}
+	rl.sumLatency += latency
+	rl.MeanLatency = rl.sumLatency / time.Duration(rl.NQueries)
+	if latency > rl.MaxLatency {
+		rl.MaxLatency = latency
+	}
+	i := sort.Search(len(LatencyBucketBounds), func(j int) bool {
+		return latency <= LatencyBucketBounds[j]
+	})
+	rl.Histogram[i]++
+}
+
+func (rl *RegionLatencies) clone() *RegionLatencies {
+	clone := *rl
+	clone.Histogram = make([]uint64, len(rl.Histogram))
+	copy(clone.Histogram, rl.Histogram)
+	return &clone
+}
+
+type keyspaceRG struct {
+	prefixBits uint
+	regions    []*RegionLatencies
+	mu         sync.Mutex
+}
+
+// NewKeyspaceRecorderGetter returns a new KeyspaceRecorderGetter dividing the keyspace into
+// regions defined by the given number of high-order key bits.
+func NewKeyspaceRecorderGetter(prefixBits uint) KeyspaceRecorderGetter {
+	if prefixBits == 0 || prefixBits > maxKeyspacePrefixBits {
+		panic(fmt.Errorf("prefix bits must be in [1, %d]", maxKeyspacePrefixBits))
+	}
+	nRegions := 1 << prefixBits
+	regions := make([]*RegionLatencies, nRegions)
+	for i := range regions {
+		regions[i] = newRegionLatencies(fmt.Sprintf("%0*b", prefixBits, i))
+	}
+	return &keyspaceRG{
+		prefixBits: prefixBits,
+		regions:    regions,
+	}
+}
+
+func (r *keyspaceRG) Record(target id.ID, latency time.Duration, o Outcome) {
+	i := r.regionIndex(target)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.regions[i].record(latency, o)
+}
+
+func (r *keyspaceRG) Regions() []*RegionLatencies {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	regions := make([]*RegionLatencies, len(r.regions))
+	for i, rl := range r.regions {
+		regions[i] = rl.clone()
+	}
+	return regions
+}
+
+// regionIndex returns the index of the region containing the target, i.e., the value of the
+// target's high-order prefix bits.
+func (r *keyspaceRG) regionIndex(target id.ID) int {
+	return int(target.Bytes()[0] >> (8 - r.prefixBits))
+}
+
+type noOpKeyspaceRecorder struct{}
+
+// NewNoOpKeyspaceRecorder returns a KeyspaceRecorder that doesn't record anything.
+func NewNoOpKeyspaceRecorder() KeyspaceRecorder {
+	return &noOpKeyspaceRecorder{}
+}
+
+func (r *noOpKeyspaceRecorder) Record(target id.ID, latency time.Duration, o Outcome) {}
@@ -0,0 +1,87 @@
+package comm
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeyspaceRecorderGetter(t *testing.T) {
+	for prefixBits := uint(1); prefixBits <= maxKeyspacePrefixBits; prefixBits++ {
+		r := NewKeyspaceRecorderGetter(prefixBits)
+		regions := r.Regions()
+		assert.Len(t, regions, 1<<prefixBits)
+		for _, rl := range regions {
+			assert.Len(t, rl.Prefix, int(prefixBits))
+			assert.Len(t, rl.Histogram, len(LatencyBucketBounds)+1)
+		}
+	}
+
+	assert.Panics(t, func() {
+		NewKeyspaceRecorderGetter(0)
+	})
+	assert.Panics(t, func() {
+		NewKeyspaceRecorderGetter(maxKeyspacePrefixBits + 1)
+	})
+}
+
+func TestKeyspaceRG_Record(t *testing.T) {
+	r := NewKeyspaceRecorderGetter(2)
+
+	// region 00
+	r.Record(id.FromBytes([]byte{0x00, 0xff}), 5*time.Millisecond, Success)
+	r.Record(id.FromBytes([]byte{0x3f}), 15*time.Millisecond, Error)
+
+	// region 11
+	key := make([]byte, id.Length)
+	key[0] = 0xc0
+	r.Record(id.FromBytes(key), 10*time.Second, Success)
+
+	regions := r.Regions()
+	assert.Equal(t, "00", regions[0].Prefix)
+	assert.Equal(t, uint64(2), regions[0].NQueries)
+	assert.Equal(t, uint64(1), regions[0].NErrors)
+	assert.Equal(t, 10*time.Millisecond, regions[0].MeanLatency)
+	assert.Equal(t, 15*time.Millisecond, regions[0].MaxLatency)
+	assert.Equal(t, uint64(1), regions[0].Histogram[0])
+	assert.Equal(t, uint64(1), regions[0].Histogram[1])
+
+	assert.Zero(t, regions[1].NQueries)
+	assert.Zero(t, regions[2].NQueries)
+
+	assert.Equal(t, "11", regions[3].Prefix)
+	assert.Equal(t, uint64(1), regions[3].NQueries)
+	assert.Equal(t, uint64(1), regions[3].Histogram[len(LatencyBucketBounds)])
+
+	// check snapshots are independent of internal state
+	regions[3].Histogram[0] = 10
+	assert.Zero(t, r.Regions()[3].Histogram[0])
+}
+
+func TestKeyspaceRG_Record_random(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := NewKeyspaceRecorderGetter(DefaultKeyspacePrefixBits)
+	n := 256
+	for c := 0; c < n; c++ {
+		r.Record(id.NewPseudoRandom(rng), time.Duration(rng.Int63n(int64(time.Second))),
+			Success)
+	}
+	nQueries := uint64(0)
+	for _, rl := range r.Regions() {
+		nHist := uint64(0)
+		for _, count := range rl.Histogram {
+			nHist += count
+		}
+		assert.Equal(t, rl.NQueries, nHist)
+		nQueries += rl.NQueries
+	}
+	assert.Equal(t, uint64(n), nQueries)
+}
+
+func TestNoOpKeyspaceRecorder_Record(t *testing.T) {
+	r := NewNoOpKeyspaceRecorder()
+	r.Record(id.FromInt64(1), time.Second, Success)
+}
@@ -0,0 +1,77 @@
+package comm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+const (
+	// DefaultLatencySmoothing is the default weight of each new round-trip time in a peer's
+	// exponentially weighted moving average latency.
+	DefaultLatencySmoothing = 0.2
+
+	// maxLatencyPeers is the maximum number of peers whose latencies are kept.
+	maxLatencyPeers = 4096
+)
+
+// SmoothLatency returns the exponentially weighted moving average latency after a new
+// round-trip time with the given smoothing weight in (0, 1]. A zero previous average is
+// considered unmeasured, so the new latency replaces it.
+func SmoothLatency(prev, latency time.Duration, smoothing float64) time.Duration {
+	if prev == 0 {
+		return latency
+	}
+	return time.Duration(smoothing*float64(latency) + (1-smoothing)*float64(prev))
+}
+
+// LatencyTracker tracks the moving average round-trip time of queries to each peer.
+type LatencyTracker interface {
+	// RecordLatency records the round-trip time of a query to the peer.
+	RecordLatency(peerID id.ID, latency time.Duration)
+
+	// Latency returns the peer's moving average round-trip time and whether it has been
+	// measured.
+	Latency(peerID id.ID) (time.Duration, bool)
+}
+
+type latencyTracker struct {
+	smoothing float64
+	latencies map[string]time.Duration
+	mu        sync.Mutex
+}
+
+// NewLatencyTracker returns a new LatencyTracker keeping an exponentially weighted moving
+// average of each peer's round-trip times, weighting each new one by the smoothing.
+func NewLatencyTracker(smoothing float64) LatencyTracker {
+	return &latencyTracker{
+		smoothing: smoothing,
+		latencies: make(map[string]time.Duration),
+	}
+}
+
+func (t *latencyTracker) RecordLatency(peerID id.ID, latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+	idStr := peerID.String()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, in := t.latencies[idStr]
+	if !in && len(t.latencies) >= maxLatencyPeers {
+		// evict an arbitrary peer to make room
+		for other := range t.latencies {
+			delete(t.latencies, other)
+			break
+		}
+	}
+	t.latencies[idStr] = SmoothLatency(prev, latency, t.smoothing)
+}
+
+func (t *latencyTracker) Latency(peerID id.ID) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	latency, in := t.latencies[peerID.String()]
+	return latency, in
+}
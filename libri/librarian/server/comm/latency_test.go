@@ -0,0 +1,47 @@
+package comm
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmoothLatency(t *testing.T) {
+	assert.Equal(t, 10*time.Millisecond, SmoothLatency(0, 10*time.Millisecond, 0.5))
+	assert.Equal(t, 15*time.Millisecond,
+		SmoothLatency(10*time.Millisecond, 20*time.Millisecond, 0.5))
+	assert.Equal(t, 20*time.Millisecond,
+		SmoothLatency(10*time.Millisecond, 20*time.Millisecond, 1))
+}
+
+func TestLatencyTracker_RecordLatency_Latency(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tracker := NewLatencyTracker(0.5)
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	_, ok := tracker.Latency(peerID1)
+	assert.False(t, ok)
+
+	tracker.RecordLatency(peerID1, 10*time.Millisecond)
+	tracker.RecordLatency(peerID1, 20*time.Millisecond)
+	latency, ok := tracker.Latency(peerID1)
+	assert.True(t, ok)
+	assert.Equal(t, 15*time.Millisecond, latency)
+
+	// non-positive latencies ignored
+	tracker.RecordLatency(peerID2, 0)
+	_, ok = tracker.Latency(peerID2)
+	assert.False(t, ok)
+}
+
+func TestLatencyTracker_RecordLatency_evict(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tracker := NewLatencyTracker(DefaultLatencySmoothing).(*latencyTracker)
+	for c := 0; c < maxLatencyPeers+1; c++ {
+		tracker.RecordLatency(id.NewPseudoRandom(rng), time.Millisecond)
+	}
+	assert.Len(t, tracker.latencies, maxLatencyPeers)
+}
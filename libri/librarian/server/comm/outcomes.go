@@ -30,7 +30,8 @@ func (qt QueryType) String() string {
 	}
 }
 
-// Outcome is the outcome of a query, for now just distinguishing between successes and failures.
+// Outcome is the outcome of a query, distinguishing between successes, failures, and integrity
+// failures.
 type Outcome int
 
 const (
@@ -39,6 +40,10 @@ const (
 
 	// Error denotes a failed query.
 	Error
+
+	// IntegrityFailure denotes a query whose response shows the peer is serving corrupted data,
+	// e.g., a Verify MAC that doesn't match the expected one.
+	IntegrityFailure
 )
 
 // String returns a string representation of the outcome.
@@ -48,6 +53,8 @@ func (o Outcome) String() string {
 		return "SUCCESS"
 	case Error:
 		return "ERROR"
+	case IntegrityFailure:
+		return "INTEGRITY_FAILURE"
 	default:
 		panic("unknown outcome")
 	}
@@ -85,18 +92,20 @@ func (m *ScalarMetrics) Record() {
 	}
 }
 
-// QueryOutcomes contains the metrics for the 4 (query type, outcome) tuples.
+// QueryOutcomes contains the metrics for the 6 (query type, outcome) tuples.
 type QueryOutcomes map[QueryType]map[Outcome]*ScalarMetrics
 
 func newQueryOutcomes() QueryOutcomes {
 	return QueryOutcomes{
 		Request: map[Outcome]*ScalarMetrics{
-			Success: newScalarMetrics(),
-			Error:   newScalarMetrics(),
+			Success:          newScalarMetrics(),
+			Error:            newScalarMetrics(),
+			IntegrityFailure: newScalarMetrics(),
 		},
 		Response: map[Outcome]*ScalarMetrics{
-			Success: newScalarMetrics(),
-			Error:   newScalarMetrics(),
+			Success:          newScalarMetrics(),
+			Error:            newScalarMetrics(),
+			IntegrityFailure: newScalarMetrics(),
 		},
 	}
 }
@@ -14,6 +14,7 @@ func TestQueryType_String(t *testing.T) {
 func TestOutcome_String(t *testing.T) {
 	assert.Equal(t, "SUCCESS", Success.String())
 	assert.Equal(t, "ERROR", Error.String())
+	assert.Equal(t, "INTEGRITY_FAILURE", IntegrityFailure.String())
 }
 
 func TestMetrics_Record(t *testing.T) {
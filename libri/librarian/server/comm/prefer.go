@@ -1,9 +1,6 @@
 package comm
 
-import (
-	"github.com/drausin/libri/libri/common/id"
-	"github.com/drausin/libri/libri/librarian/api"
-)
+import "github.com/drausin/libri/libri/common/id"
 
 // Preferer judges whether one peer is preferable over another.
 type Preferer interface {
@@ -13,22 +10,40 @@ type Preferer interface {
 	Prefer(peerID1, peerID2 id.ID) bool
 }
 
-// NewRpPreferer returns a Preferer that prefers peers with a larger number of successful
-// Verify or Find responses.
-func NewRpPreferer(getter QueryGetter) Preferer {
-	return &rpPreferer{getter}
+// IntegrityFailureWeight is the number of failed responses each integrity failure counts as in a
+// peer's reputation, since serving corrupted data should hurt a peer far more than, e.g., a
+// timeout.
+const IntegrityFailureWeight = 100
+
+// NewReputationPreferer returns a Preferer that prefers peers with better reputations, as scored
+// by the ReputationJudge.
+func NewReputationPreferer(judge ReputationJudge) Preferer {
+	return &reputationPreferer{judge}
+}
+
+type reputationPreferer struct {
+	judge ReputationJudge
+}
+
+func (p *reputationPreferer) Prefer(peerID1, peerID2 id.ID) bool {
+	return p.judge.Score(peerID1) > p.judge.Score(peerID2)
+}
+
+// NewLatencyPreferer returns a Preferer that prefers peers with lower moving average round-trip
+// times, as tracked by the LatencyTracker. Measured peers are preferred over unmeasured ones.
+func NewLatencyPreferer(tracker LatencyTracker) Preferer {
+	return &latencyPreferer{tracker}
 }
 
-type rpPreferer struct {
-	getter QueryGetter
+type latencyPreferer struct {
+	tracker LatencyTracker
 }
 
-func (p *rpPreferer) Prefer(peerID1, peerID2 id.ID) bool {
-	nRps1 := p.getter.Get(peerID1, api.Verify)[Response][Success].Count
-	nRps2 := p.getter.Get(peerID2, api.Verify)[Response][Success].Count
-	if nRps1 == 0 || nRps2 == 0 {
-		nRps1 = p.getter.Get(peerID1, api.Find)[Response][Success].Count
-		nRps2 = p.getter.Get(peerID2, api.Find)[Response][Success].Count
+func (p *latencyPreferer) Prefer(peerID1, peerID2 id.ID) bool {
+	latency1, ok1 := p.tracker.Latency(peerID1)
+	latency2, ok2 := p.tracker.Latency(peerID2)
+	if !ok1 || !ok2 {
+		return ok1 && !ok2
 	}
-	return nRps1 > nRps2
+	return latency1 < latency2
 }
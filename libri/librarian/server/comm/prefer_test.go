@@ -4,76 +4,58 @@ import (
 	"testing"
 
 	"math/rand"
+	"time"
 
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestRpPreferer_Prefer(t *testing.T) {
+func TestReputationPreferer_Prefer(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
+	judge := newTestReputationJudge(t)
+	p := NewReputationPreferer(judge)
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	assert.False(t, p.Prefer(peerID1, peerID2))
+	assert.False(t, p.Prefer(peerID2, peerID1))
 
-	peerID1 := id.NewPseudoRandom(rng)
-	peerID2 := id.NewPseudoRandom(rng)
-	peerID3 := id.NewPseudoRandom(rng)
-	peerID4 := id.NewPseudoRandom(rng)
-	qo1 := endpointQueryOutcomes{
-		api.Verify: QueryOutcomes{
-			Response: map[Outcome]*ScalarMetrics{
-				Success: {Count: 1},
-			},
-		},
-		api.Find: QueryOutcomes{
-			Response: map[Outcome]*ScalarMetrics{
-				Success: {Count: 1},
-			},
-		},
-	}
-	qo2 := endpointQueryOutcomes{
-		api.Verify: {
-			Response: map[Outcome]*ScalarMetrics{
-				Success: {Count: 2},
-			},
-		},
-		api.Find: QueryOutcomes{
-			Response: map[Outcome]*ScalarMetrics{
-				Success: {Count: 2},
-			},
-		},
-	}
-	qo3 := endpointQueryOutcomes{
-		api.Verify: {
-			Response: map[Outcome]*ScalarMetrics{
-				Success: {Count: 3},
-			},
-		},
-		api.Find: QueryOutcomes{
-			Response: map[Outcome]*ScalarMetrics{
-				Success: {Count: 3},
-			},
-		},
-	}
-	qo4 := endpointQueryOutcomes{
-		api.Verify: newQueryOutcomes(),
-		api.Find: QueryOutcomes{
-			Response: map[Outcome]*ScalarMetrics{
-				Success: {Count: 5},
-			},
-		},
-	}
+	judge.Record(peerID1, api.Find, Response, Success)
+	assert.True(t, p.Prefer(peerID1, peerID2))
+	assert.False(t, p.Prefer(peerID2, peerID1))
+}
 
-	rec := &scalarRG{
-		peers: map[string]endpointQueryOutcomes{
-			peerID1.String(): qo1,
-			peerID2.String(): qo2,
-			peerID3.String(): qo3,
-			peerID4.String(): qo4,
-		},
+func TestReputationPreferer_Prefer_integrityFailures(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	judge := newTestReputationJudge(t)
+	p := NewReputationPreferer(judge)
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	judge.Record(peerID1, api.Verify, Response, Success)
+	for c := 0; c < 50; c++ {
+		judge.Record(peerID2, api.Verify, Response, Success)
 	}
-	p := NewRpPreferer(rec)
+	judge.Record(peerID2, api.Verify, Response, IntegrityFailure)
+
+	// single integrity failure outweighs many more successful responses
+	assert.True(t, p.Prefer(peerID1, peerID2))
+	assert.False(t, p.Prefer(peerID2, peerID1))
+}
+
+func TestLatencyPreferer_Prefer(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tracker := NewLatencyTracker(DefaultLatencySmoothing)
+	p := NewLatencyPreferer(tracker)
+	peerID1, peerID2, peerID3 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng)
+	assert.False(t, p.Prefer(peerID1, peerID2))
+	assert.False(t, p.Prefer(peerID2, peerID1))
+
+	// prefer measured peer over unmeasured one
+	tracker.RecordLatency(peerID1, 100*time.Millisecond)
+	assert.True(t, p.Prefer(peerID1, peerID3))
+	assert.False(t, p.Prefer(peerID3, peerID1))
 
-	// prefer peer w/ more successful Verify or Find responses
+	// prefer faster peer
+	tracker.RecordLatency(peerID2, 10*time.Millisecond)
 	assert.True(t, p.Prefer(peerID2, peerID1))
-	assert.True(t, p.Prefer(peerID3, peerID2))
-	assert.True(t, p.Prefer(peerID4, peerID3))
+	assert.False(t, p.Prefer(peerID1, peerID2))
 }
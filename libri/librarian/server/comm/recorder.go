@@ -19,9 +19,10 @@ const (
 	queryTypeLabel = "query_type"
 	outcomeLabel   = "outcome"
 
-	counterNamespace = "libri"
-	counterSubsystem = "routing"
-	counterName      = "peer_query_count"
+	counterNamespace    = "libri"
+	counterSubsystem    = "routing"
+	counterName         = "peer_query_count"
+	endpointCounterName = "endpoint_query_count"
 )
 
 var (
@@ -44,7 +45,8 @@ type QueryRecorder interface {
 // MaybeRecordRpErr records skips recording an error using th given QueryRecorder if the given error
 // has a health error status code (indicating that the problem is on the client end).
 func MaybeRecordRpErr(r QueryRecorder, peerID id.ID, endpoint api.Endpoint, err error) {
-	if peerID == nil {
+	if peerID == nil || err == ErrQueryThrottled {
+		// throttled queries were never sent to the peer
 		return
 	}
 	if errSt, ok := status.FromError(err); ok {
@@ -226,7 +228,11 @@ type PromRecorder interface {
 }
 
 // NewPromScalarRecorder creates a new scalar recorder that also emits Prometheus metrics for each
-// (peer, endpoint, query, outcome).
+// (peer, endpoint, query, outcome) and, without the per-peer labels, for each (endpoint, query,
+// outcome). Error ratios toward (RESPONSE) or from (REQUEST) peers are then, e.g.,
+//
+//	sum by (endpoint) (rate(libri_routing_endpoint_query_count{outcome="ERROR"}[5m])) /
+//	sum by (endpoint) (rate(libri_routing_endpoint_query_count[5m]))
 func NewPromScalarRecorder(selfID id.ID, inner QueryRecorder) PromRecorder {
 	counter := prom.NewCounterVec(
 		prom.CounterOpts{
@@ -243,18 +249,33 @@ func NewPromScalarRecorder(selfID id.ID, inner QueryRecorder) PromRecorder {
 			outcomeLabel,
 		},
 	)
+	endpointCounter := prom.NewCounterVec(
+		prom.CounterOpts{
+			Namespace: counterNamespace,
+			Subsystem: counterSubsystem,
+			Name:      endpointCounterName,
+			Help:      "Number of queries to and from all other peers on each endpoint.",
+		},
+		[]string{
+			endpointLabel,
+			queryTypeLabel,
+			outcomeLabel,
+		},
+	)
 	return &promQR{
-		inner:   inner,
-		selfID:  selfID,
-		counter: counter,
+		inner:           inner,
+		selfID:          selfID,
+		counter:         counter,
+		endpointCounter: endpointCounter,
 	}
 }
 
 type promQR struct {
 	inner QueryRecorder
 
-	selfID  id.ID
-	counter *prom.CounterVec
+	selfID          id.ID
+	counter         *prom.CounterVec
+	endpointCounter *prom.CounterVec
 }
 
 func (r *promQR) Record(peerID id.ID, endpoint api.Endpoint, qt QueryType, o Outcome) {
@@ -266,12 +287,19 @@ func (r *promQR) Record(peerID id.ID, endpoint api.Endpoint, qt QueryType, o Out
 		queryTypeLabel: qt.String(),
 		outcomeLabel:   o.String(),
 	}).Inc()
+	r.endpointCounter.With(prom.Labels{
+		endpointLabel:  endpoint.String(),
+		queryTypeLabel: qt.String(),
+		outcomeLabel:   o.String(),
+	}).Inc()
 }
 
 func (r *promQR) Register() {
 	prom.MustRegister(r.counter)
+	prom.MustRegister(r.endpointCounter)
 }
 
 func (r *promQR) Unregister() {
 	_ = prom.Unregister(r.counter)
+	_ = prom.Unregister(r.endpointCounter)
 }
@@ -45,6 +45,12 @@ func TestMaybeRecordErr(t *testing.T) {
 			errRecorded:     false,
 			successRecorded: true,
 		},
+		"throttled query": {
+			peerID:          peerID,
+			err:             ErrQueryThrottled,
+			errRecorded:     false,
+			successRecorded: false,
+		},
 	}
 	for desc, c := range cases {
 		r := &fixedRecorder{}
@@ -62,19 +68,20 @@ func TestScalarRG(t *testing.T) {
 	// record every possible combination
 	for _, e := range api.Endpoints {
 		for _, qt := range []QueryType{Request, Response} {
-			for _, o := range []Outcome{Success, Error} {
+			for _, o := range []Outcome{Success, Error, IntegrityFailure} {
 				r.Record(id1, e, qt, o)
 			}
 		}
 	}
 
-	// check that all 4 query type + outcome combos are populated for each endpoint
+	// check that all 6 query type + outcome combos are populated for each endpoint
 	for _, e := range api.Endpoints {
 		eo := r.Get(id1, e)
 		assert.Equal(t, uint64(1), eo[Request][Success].Count)
 		assert.Equal(t, uint64(1), eo[Response][Success].Count)
 		assert.Equal(t, uint64(1), eo[Request][Error].Count)
 		assert.Equal(t, uint64(1), eo[Response][Error].Count)
+		assert.Equal(t, uint64(1), eo[Response][IntegrityFailure].Count)
 		assert.Equal(t, 1, r.CountPeers(e, Request, false))
 		assert.Equal(t, 1, r.CountPeers(e, Response, false))
 	}
@@ -159,4 +166,43 @@ func TestPromScalarRecorder_Record(t *testing.T) {
 		assert.Equal(t, float64(1), *written.Counter.Value)
 		assert.Equal(t, 5, len(written.Label))
 	}
+
+	// endpoint counts are aggregated over peers
+	r.Record(id2, api.Find, Request, Success)
+	r.Record(id2, api.Find, Request, Error)
+	r.Record(id2, api.Verify, Response, IntegrityFailure)
+	metrics = make(chan prom.Metric, 8)
+	r.(*promQR).endpointCounter.Collect(metrics)
+	close(metrics)
+	counts := make(map[string]float64)
+	for m := range metrics {
+		written := &dto.Metric{}
+		m.Write(written)
+		assert.Equal(t, 3, len(written.Label))
+		labels := make(map[string]string)
+		for _, lp := range written.Label {
+			labels[*lp.Name] = *lp.Value
+		}
+		key := labels[endpointLabel] + "/" + labels[queryTypeLabel] + "/" + labels[outcomeLabel]
+		counts[key] = *written.Counter.Value
+	}
+	assert.Equal(t, map[string]float64{
+		"Find/REQUEST/SUCCESS":   2,
+		"Find/REQUEST/ERROR":     1,
+		"Store/REQUEST/SUCCESS":  1,
+		"Store/RESPONSE/SUCCESS": 1,
+
+		"Verify/RESPONSE/INTEGRITY_FAILURE": 1,
+	}, counts)
+}
+
+func TestPromScalarRecorder_RegisterUnregister(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := NewPromScalarRecorder(id.NewPseudoRandom(rng), &fixedRecorder{})
+	r.Register()
+	r.Unregister()
+
+	// should be able to register again after unregistering
+	assert.NotPanics(t, r.Register)
+	r.Unregister()
 }
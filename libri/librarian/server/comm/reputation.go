@@ -0,0 +1,275 @@
+package comm
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// DefaultReputationHalfLife is the default duration over which a peer's past responses lose
+	// half their weight in its reputation.
+	DefaultReputationHalfLife = 6 * time.Hour
+
+	// DefaultReputationTargetLatency is the default response latency at which a peer's latency
+	// neither helps nor hurts its reputation relative to a peer whose latency is unknown.
+	DefaultReputationTargetLatency = 500 * time.Millisecond
+
+	// DefaultReputationLatencyWeight is the default fraction of a peer's reputation determined by
+	// its response latency rather than its success rate.
+	DefaultReputationLatencyWeight = 0.25
+
+	// DefaultReputationLatencySmoothing is the default weight of each new latency in a peer's
+	// moving average latency.
+	DefaultReputationLatencySmoothing = 0.2
+
+	// minReputationWeight is the total decayed number of responses below which a peer's
+	// reputation is forgotten rather than persisted.
+	minReputationWeight = 0.01
+)
+
+var reputationsKey = []byte("Reputations")
+
+// ReputationParameters define how peers' reputations are scored.
+type ReputationParameters struct {
+	// HalfLife is the duration over which a peer's past responses lose half their weight, so
+	// recent behavior counts most and long-past failures are eventually forgiven. Zero disables
+	// decay.
+	HalfLife time.Duration
+
+	// TargetLatency is the response latency at which a peer's latency neither helps nor hurts
+	// its reputation relative to a peer whose latency is unknown.
+	TargetLatency time.Duration
+
+	// LatencyWeight is the fraction, in [0, 1], of a peer's reputation determined by its
+	// response latency rather than its success rate.
+	LatencyWeight float64
+
+	// LatencySmoothing is the weight, in (0, 1], of each new latency in a peer's exponentially
+	// weighted moving average latency.
+	LatencySmoothing float64
+}
+
+// NewDefaultReputationParameters returns the default ReputationParameters.
+func NewDefaultReputationParameters() *ReputationParameters {
+	return &ReputationParameters{
+		HalfLife:         DefaultReputationHalfLife,
+		TargetLatency:    DefaultReputationTargetLatency,
+		LatencyWeight:    DefaultReputationLatencyWeight,
+		LatencySmoothing: DefaultReputationLatencySmoothing,
+	}
+}
+
+// ReputationJudge scores peers by their reputations, combining the success rate and latency of
+// their responses to our queries, with older responses exponentially decayed.
+type ReputationJudge interface {
+	QueryRecorder
+
+	// RecordLatency records the latency of a peer's response to a query.
+	RecordLatency(peerID id.ID, latency time.Duration)
+
+	// Score returns the peer's reputation in (0, 1), with higher being better. Peers without
+	// recorded responses have a neutral reputation.
+	Score(peerID id.ID) float64
+
+	// Save persists the reputations so they survive restarts.
+	Save() error
+}
+
+type reputation struct {
+	peerID     id.ID
+	nSuccesses float64
+	nFailures  float64
+	latency    time.Duration
+	updated    time.Time
+}
+
+// decay decays the response counts for the time elapsed since they were last updated.
+func (r *reputation) decay(now time.Time, halfLife time.Duration) {
+	if elapsed := now.Sub(r.updated); halfLife > 0 && elapsed > 0 && !r.updated.IsZero() {
+		f := math.Exp2(-float64(elapsed) / float64(halfLife))
+		r.nSuccesses *= f
+		r.nFailures *= f
+	}
+	if now.After(r.updated) {
+		r.updated = now
+	}
+}
+
+type reputationJudge struct {
+	params *ReputationParameters
+	reps   map[string]*reputation
+	sl     cstorage.StorerLoader
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// NewReputationJudge returns a ReputationJudge without any reputations, which its Save persists
+// to the given StorerLoader.
+func NewReputationJudge(sl cstorage.StorerLoader, params *ReputationParameters) ReputationJudge {
+	return newReputationJudge(sl, params)
+}
+
+func newReputationJudge(sl cstorage.StorerLoader, params *ReputationParameters) *reputationJudge {
+	return &reputationJudge{
+		params: params,
+		reps:   make(map[string]*reputation),
+		sl:     sl,
+		now:    time.Now,
+	}
+}
+
+// LoadReputationJudge returns a ReputationJudge with the reputations persisted by an earlier
+// one's Save, if any.
+func LoadReputationJudge(
+	sl cstorage.StorerLoader, params *ReputationParameters,
+) (ReputationJudge, error) {
+	j := newReputationJudge(sl, params)
+	bytes, err := sl.Load(reputationsKey)
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return j, nil
+	}
+	stored := &sstorage.Reputations{}
+	if err := proto.Unmarshal(bytes, stored); err != nil {
+		return nil, err
+	}
+	for _, sr := range stored.Reputations {
+		peerID := id.FromBytes(sr.PeerId)
+		j.reps[peerID.String()] = &reputation{
+			peerID:     peerID,
+			nSuccesses: sr.NSuccesses,
+			nFailures:  sr.NFailures,
+			latency:    time.Duration(sr.LatencyNanos),
+			updated:    time.Unix(0, sr.UpdatedNanos),
+		}
+	}
+	return j, nil
+}
+
+// Record records the outcome of a query to the peer. Queries from the peer don't affect its
+// reputation, and integrity failures count as IntegrityFailureWeight failures.
+func (j *reputationJudge) Record(peerID id.ID, endpoint api.Endpoint, qt QueryType, o Outcome) {
+	if qt != Response {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	r := j.get(peerID)
+	switch o {
+	case Success:
+		r.nSuccesses++
+	case Error:
+		r.nFailures++
+	case IntegrityFailure:
+		r.nFailures += IntegrityFailureWeight
+	}
+}
+
+func (j *reputationJudge) RecordLatency(peerID id.ID, latency time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	r := j.get(peerID)
+	r.latency = SmoothLatency(r.latency, latency, j.params.LatencySmoothing)
+}
+
+func (j *reputationJudge) Score(peerID id.ID) float64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	r, in := j.reps[peerID.String()]
+	if !in {
+		return j.score(&reputation{})
+	}
+	r.decay(j.now(), j.params.HalfLife)
+	return j.score(r)
+}
+
+func (j *reputationJudge) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	stored := &sstorage.Reputations{
+		Reputations: make([]*sstorage.Reputation, 0, len(j.reps)),
+	}
+	for key, r := range j.reps {
+		r.decay(now, j.params.HalfLife)
+		if r.nSuccesses+r.nFailures < minReputationWeight {
+			// forget peers we haven't heard from in a long time
+			delete(j.reps, key)
+			continue
+		}
+		stored.Reputations = append(stored.Reputations, &sstorage.Reputation{
+			PeerId:       r.peerID.Bytes(),
+			NSuccesses:   r.nSuccesses,
+			NFailures:    r.nFailures,
+			LatencyNanos: int64(r.latency),
+			UpdatedNanos: r.updated.UnixNano(),
+		})
+	}
+	bytes, err := proto.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return j.sl.Store(reputationsKey, bytes)
+}
+
+// get returns the peer's reputation with its counts decayed to now, creating it if necessary.
+func (j *reputationJudge) get(peerID id.ID) *reputation {
+	key := peerID.String()
+	r, in := j.reps[key]
+	if !in {
+		r = &reputation{peerID: peerID}
+		j.reps[key] = r
+	}
+	r.decay(j.now(), j.params.HalfLife)
+	return r
+}
+
+// score combines the success rate, shrunk toward one half when there are few responses, and the
+// latency score, one half at TargetLatency and approaching one as latency decreases.
+func (j *reputationJudge) score(r *reputation) float64 {
+	successRate := (r.nSuccesses + 1) / (r.nSuccesses + r.nFailures + 2)
+	latencyScore := 0.5
+	if r.latency > 0 && j.params.TargetLatency > 0 {
+		target := float64(j.params.TargetLatency)
+		latencyScore = target / (target + float64(r.latency))
+	}
+	w := j.params.LatencyWeight
+	return successRate * (1 - w + w*latencyScore)
+}
+
+type judgedRecorder struct {
+	QueryRecorder
+	judge ReputationJudge
+}
+
+// NewJudgedRecorder returns a ReputationJudge that also records query outcomes with the inner
+// QueryRecorder, so the outcomes recorded throughout the librarian feed peers' reputations.
+func NewJudgedRecorder(inner QueryRecorder, judge ReputationJudge) ReputationJudge {
+	return &judgedRecorder{QueryRecorder: inner, judge: judge}
+}
+
+func (r *judgedRecorder) Record(peerID id.ID, endpoint api.Endpoint, qt QueryType, o Outcome) {
+	r.QueryRecorder.Record(peerID, endpoint, qt, o)
+	r.judge.Record(peerID, endpoint, qt, o)
+}
+
+func (r *judgedRecorder) RecordLatency(peerID id.ID, latency time.Duration) {
+	r.judge.RecordLatency(peerID, latency)
+}
+
+func (r *judgedRecorder) Score(peerID id.ID) float64 {
+	return r.judge.Score(peerID)
+}
+
+func (r *judgedRecorder) Save() error {
+	return r.judge.Save()
+}
@@ -0,0 +1,136 @@
+package comm
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReputationJudge_Score(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	j := newTestReputationJudge(t)
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	neutral := j.Score(peerID1)
+	assert.True(t, neutral > 0 && neutral < 1)
+
+	// successes help and errors hurt
+	j.Record(peerID1, api.Find, Response, Success)
+	j.Record(peerID2, api.Find, Response, Error)
+	assert.True(t, j.Score(peerID1) > neutral)
+	assert.True(t, j.Score(peerID2) < neutral)
+
+	// requests from peers don't affect their reputations
+	j.Record(peerID2, api.Find, Request, Success)
+	assert.True(t, j.Score(peerID2) < neutral)
+
+	// single integrity failure outweighs many successes
+	for c := 0; c < 50; c++ {
+		j.Record(peerID2, api.Verify, Response, Success)
+	}
+	j.Record(peerID2, api.Verify, Response, IntegrityFailure)
+	assert.True(t, j.Score(peerID2) < neutral)
+}
+
+func TestReputationJudge_RecordLatency(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	j := newTestReputationJudge(t)
+	fast, slow := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	neutral := j.Score(fast)
+
+	j.RecordLatency(fast, 10*time.Millisecond)
+	j.RecordLatency(slow, 5*time.Second)
+	assert.True(t, j.Score(fast) > neutral)
+	assert.True(t, j.Score(slow) < neutral)
+
+	// latency is smoothed
+	j.RecordLatency(slow, 10*time.Millisecond)
+	slowRep := j.(*reputationJudge).reps[slow.String()]
+	assert.True(t, slowRep.latency > 10*time.Millisecond)
+	assert.True(t, slowRep.latency < 5*time.Second)
+}
+
+func TestReputationJudge_decay(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	j := newTestReputationJudge(t)
+	now := time.Now()
+	j.(*reputationJudge).now = func() time.Time { return now }
+	peerID := id.NewPseudoRandom(rng)
+	neutral := j.Score(peerID)
+
+	j.Record(peerID, api.Verify, Response, IntegrityFailure)
+	failed := j.Score(peerID)
+	assert.True(t, failed < neutral)
+
+	// failures are forgiven over time
+	now = now.Add(4 * DefaultReputationHalfLife)
+	assert.True(t, j.Score(peerID) > failed)
+	now = now.Add(20 * DefaultReputationHalfLife)
+	assert.InDelta(t, neutral, j.Score(peerID), 1e-3)
+}
+
+func TestReputationJudge_SaveLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	sl := &cstorage.TestSLD{}
+	params := NewDefaultReputationParameters()
+	j1, err := LoadReputationJudge(sl, params)
+	assert.Nil(t, err)
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	j1.Record(peerID1, api.Find, Response, Success)
+	j1.RecordLatency(peerID1, 10*time.Millisecond)
+	j1.Record(peerID2, api.Find, Response, Error)
+	assert.Nil(t, j1.Save())
+
+	j2, err := LoadReputationJudge(sl, params)
+	assert.Nil(t, err)
+	assert.InDelta(t, j1.Score(peerID1), j2.Score(peerID1), 1e-6)
+	assert.InDelta(t, j1.Score(peerID2), j2.Score(peerID2), 1e-6)
+
+	// long forgotten peers aren't saved
+	j2.(*reputationJudge).now = func() time.Time {
+		return time.Now().Add(20 * DefaultReputationHalfLife)
+	}
+	assert.Nil(t, j2.Save())
+	assert.Empty(t, j2.(*reputationJudge).reps)
+}
+
+func TestLoadReputationJudge_err(t *testing.T) {
+	params := NewDefaultReputationParameters()
+	j, err := LoadReputationJudge(&cstorage.TestSLD{LoadErr: errors.New("some Load error")},
+		params)
+	assert.NotNil(t, err)
+	assert.Nil(t, j)
+
+	j, err = LoadReputationJudge(&cstorage.TestSLD{Bytes: []byte("not a proto")}, params)
+	assert.NotNil(t, err)
+	assert.Nil(t, j)
+}
+
+func TestJudgedRecorder(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	inner := NewQueryRecorderGetter(NewAlwaysKnower())
+	judge := newTestReputationJudge(t)
+	now := time.Now()
+	judge.(*reputationJudge).now = func() time.Time { return now }
+	r := NewJudgedRecorder(inner, judge)
+	peerID := id.NewPseudoRandom(rng)
+	neutral := r.Score(peerID)
+
+	r.Record(peerID, api.Find, Response, Success)
+	r.RecordLatency(peerID, 10*time.Millisecond)
+	assert.Equal(t, uint64(1), inner.Get(peerID, api.Find)[Response][Success].Count)
+	assert.InDelta(t, judge.Score(peerID), r.Score(peerID), 1e-9)
+	assert.True(t, r.Score(peerID) > neutral)
+	assert.Nil(t, r.Save())
+}
+
+func newTestReputationJudge(t *testing.T) ReputationJudge {
+	j, err := LoadReputationJudge(&cstorage.TestSLD{}, NewDefaultReputationParameters())
+	assert.Nil(t, err)
+	return j
+}
@@ -0,0 +1,195 @@
+package comm
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultRequesterLimitBurstSeconds is the default number of seconds' worth of requests a
+	// requester may make in a burst above its sustained rate.
+	DefaultRequesterLimitBurstSeconds = 2.0
+
+	// maxLimitedRequesters is the number of requester buckets above which idle ones are pruned.
+	maxLimitedRequesters = 4096
+
+	limitedCountName = "limited_request_count"
+	requesterLabel   = "requester"
+	peerRequester    = "PEER"
+	orgRequester     = "ORG"
+)
+
+var (
+	// ErrRequesterLimited indicates when a requester peer has recently made too many requests on
+	// an endpoint to make another.
+	ErrRequesterLimited = errors.New("requester above request rate limit")
+
+	defaultRequesterPeerRates = EndpointRates{
+		api.Find:   64,
+		api.Verify: 32,
+		api.Store:  16,
+	}
+
+	defaultRequesterOrgRates = EndpointRates{
+		api.Find:   512,
+		api.Verify: 256,
+		api.Store:  128,
+	}
+)
+
+// EndpointRates defines the maximum sustained number of requests per second on each endpoint.
+// Endpoints without rates are unlimited.
+type EndpointRates map[api.Endpoint]float64
+
+// RequesterLimitParameters define how the rates of requests from each requester peer and
+// organization are limited.
+type RequesterLimitParameters struct {
+	// PeerRates are the maximum sustained request rates of each requester peer.
+	PeerRates EndpointRates
+
+	// OrgRates are the maximum sustained request rates of each requester peer signing its
+	// requests with an organization, in place of its PeerRates. Each of the organization's peers
+	// has its own allowance, so one noisy peer can't throttle the rest.
+	OrgRates EndpointRates
+
+	// BurstSeconds is the number of seconds' worth of requests a requester may make in a burst
+	// above its sustained rate.
+	BurstSeconds float64
+
+	// Trusted are the IDs of peers whose requests are never limited.
+	Trusted []id.ID
+}
+
+// NewDefaultRequesterLimitParameters returns the default RequesterLimitParameters.
+func NewDefaultRequesterLimitParameters() *RequesterLimitParameters {
+	peerRates, orgRates := make(EndpointRates), make(EndpointRates)
+	for e, rate := range defaultRequesterPeerRates {
+		peerRates[e] = rate
+	}
+	for e, rate := range defaultRequesterOrgRates {
+		orgRates[e] = rate
+	}
+	return &RequesterLimitParameters{
+		PeerRates:    peerRates,
+		OrgRates:     orgRates,
+		BurstSeconds: DefaultRequesterLimitBurstSeconds,
+		Trusted:      []id.ID{},
+	}
+}
+
+// RequesterLimiter limits the rates of requests from each requester peer, so no one requester can
+// take more than its fair share of the librarian's capacity.
+type RequesterLimiter interface {
+	// Take takes the allowance for a request on the endpoint from the peer, signed by the
+	// organization, if any, whose OrgRates then apply rather than the PeerRates. It returns zero
+	// if the request is allowed or otherwise how long the requester should wait before retrying.
+	Take(peerID, orgID id.ID, endpoint api.Endpoint) time.Duration
+
+	// Register registers the Prometheus metric(s) with the default Prometheus registerer.
+	Register()
+
+	// Unregister unregisters the Prometheus metrics from the default Prometheus registerer.
+	Unregister()
+}
+
+type requesterLimiter struct {
+	params  *RequesterLimitParameters
+	trusted map[string]struct{}
+	buckets map[string]*TokenBucket
+	now     func() time.Time
+	limited *prom.CounterVec
+	mu      sync.Mutex
+}
+
+// NewRequesterLimiter returns a new RequesterLimiter with a token bucket for each requester peer,
+// and organization signing its requests, on each limited endpoint.
+func NewRequesterLimiter(params *RequesterLimitParameters) RequesterLimiter {
+	trusted := make(map[string]struct{}, len(params.Trusted))
+	for _, peerID := range params.Trusted {
+		trusted[peerID.String()] = struct{}{}
+	}
+	return &requesterLimiter{
+		params:  params,
+		trusted: trusted,
+		buckets: make(map[string]*TokenBucket),
+		now:     time.Now,
+		limited: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: counterNamespace,
+				Subsystem: throttleSubsystem,
+				Name:      limitedCountName,
+				Help:      "Number of requests refused for exceeding a requester rate limit.",
+			},
+			[]string{endpointLabel, requesterLabel},
+		),
+	}
+}
+
+func (l *requesterLimiter) Take(peerID, orgID id.ID, endpoint api.Endpoint) time.Duration {
+	if _, in := l.trusted[peerID.String()]; in {
+		return 0
+	}
+	requester, key, rates := peerRequester, peerID.String(), l.params.PeerRates
+	if orgID != nil && orgID.Cmp(id.LowerBound) != 0 {
+		// key by peer too, so the org's peers don't share (and exhaust) one allowance
+		requester, key, rates = orgRequester, orgID.String()+"/"+key, l.params.OrgRates
+	}
+	b := l.bucket(requester, key, endpoint, rates)
+	if b == nil {
+		return 0
+	}
+	wait := b.Take(l.now())
+	if wait > 0 {
+		l.inc(endpoint, requester)
+	}
+	return wait
+}
+
+func (l *requesterLimiter) Register() {
+	prom.MustRegister(l.limited)
+}
+
+func (l *requesterLimiter) Unregister() {
+	_ = prom.Unregister(l.limited)
+}
+
+func (l *requesterLimiter) inc(endpoint api.Endpoint, requester string) {
+	l.limited.With(prom.Labels{
+		endpointLabel:  endpoint.String(),
+		requesterLabel: requester,
+	}).Inc()
+}
+
+// bucket returns the requester's token bucket for the endpoint, creating it if necessary, or nil
+// if the endpoint is unlimited.
+func (l *requesterLimiter) bucket(
+	requester string, requesterKey string, endpoint api.Endpoint, rates EndpointRates,
+) *TokenBucket {
+	rate, in := rates[endpoint]
+	if !in || rate <= 0 {
+		return nil
+	}
+	key := requester + "/" + endpoint.String() + "/" + requesterKey
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, in := l.buckets[key]
+	if !in {
+		if len(l.buckets) >= maxLimitedRequesters {
+			now := l.now()
+			for other, ob := range l.buckets {
+				if ob.idle(now) {
+					delete(l.buckets, other)
+				}
+			}
+		}
+		b = NewTokenBucket(rate, math.Max(1, math.Ceil(rate*l.params.BurstSeconds)))
+		l.buckets[key] = b
+	}
+	return b
+}
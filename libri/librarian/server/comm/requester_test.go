@@ -0,0 +1,106 @@
+package comm
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequesterLimiter_Take_peer(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestRequesterLimiter(&RequesterLimitParameters{
+		PeerRates:    EndpointRates{api.Find: 2},
+		BurstSeconds: 1,
+	})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	// burst allowed, then limited with a retry-after
+	assert.Zero(t, l.Take(peerID1, nil, api.Find))
+	assert.Zero(t, l.Take(peerID1, nil, api.Find))
+	assert.Equal(t, 500*time.Millisecond, l.Take(peerID1, nil, api.Find))
+
+	// other peers and endpoints unaffected
+	assert.Zero(t, l.Take(peerID2, nil, api.Find))
+	for c := 0; c < 10; c++ {
+		assert.Zero(t, l.Take(peerID1, nil, api.Store))
+	}
+
+	// allowance refills over time
+	now = now.Add(time.Second)
+	assert.Zero(t, l.Take(peerID1, nil, api.Find))
+}
+
+func TestRequesterLimiter_Take_org(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestRequesterLimiter(&RequesterLimitParameters{
+		PeerRates:    EndpointRates{api.Store: 2},
+		OrgRates:     EndpointRates{api.Store: 3},
+		BurstSeconds: 1,
+	})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	peerID1, peerID2, orgID := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng)
+
+	// org's rates apply to each of its peers in place of the peer rates
+	for c := 0; c < 3; c++ {
+		assert.Zero(t, l.Take(peerID1, orgID, api.Store))
+	}
+	assert.NotZero(t, l.Take(peerID1, orgID, api.Store))
+
+	// one noisy peer doesn't limit the org's others
+	for c := 0; c < 3; c++ {
+		assert.Zero(t, l.Take(peerID2, orgID, api.Store))
+	}
+
+	// peer's allowance not taken by requests signed by its org
+	assert.Zero(t, l.Take(peerID1, nil, api.Store))
+	assert.Zero(t, l.Take(peerID1, nil, api.Store))
+	assert.NotZero(t, l.Take(peerID1, nil, api.Store))
+
+	// unsigned requests aren't limited by org
+	peerID3 := id.NewPseudoRandom(rng)
+	assert.Zero(t, l.Take(peerID3, id.LowerBound, api.Store))
+	assert.Zero(t, l.Take(peerID3, id.LowerBound, api.Store))
+}
+
+func TestRequesterLimiter_Take_trusted(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	trusted := id.NewPseudoRandom(rng)
+	params := NewDefaultRequesterLimitParameters()
+	params.Trusted = []id.ID{trusted}
+	l := newTestRequesterLimiter(params)
+	for c := 0; c < 1000; c++ {
+		assert.Zero(t, l.Take(trusted, nil, api.Store))
+	}
+	assert.Empty(t, l.buckets)
+}
+
+func TestRequesterLimiter_bucket_prune(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := newTestRequesterLimiter(&RequesterLimitParameters{
+		PeerRates:    EndpointRates{api.Find: 1},
+		BurstSeconds: 1,
+	})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	for c := 0; c < maxLimitedRequesters; c++ {
+		l.Take(id.NewPseudoRandom(rng), nil, api.Find)
+	}
+	assert.Len(t, l.buckets, maxLimitedRequesters)
+
+	// idle buckets are pruned when adding another
+	now = now.Add(time.Minute)
+	l.Take(id.NewPseudoRandom(rng), nil, api.Find)
+	assert.Len(t, l.buckets, 1)
+}
+
+func newTestRequesterLimiter(params *RequesterLimitParameters) *requesterLimiter {
+	return NewRequesterLimiter(params).(*requesterLimiter)
+}
@@ -0,0 +1,134 @@
+package comm
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultMaxSelfClockSkew is the default magnitude of estimated self clock skew beyond which
+	// self is considered out of sync with the network.
+	DefaultMaxSelfClockSkew = 30 * time.Second
+
+	// minSkewPeers is the minimum number of peers with measured skews needed to estimate the
+	// skew of self.
+	minSkewPeers = 5
+
+	// maxSkewPeers is the maximum number of peers whose latest skews are kept.
+	maxSkewPeers = 256
+
+	skewSubsystem = "introduce"
+)
+
+var skewBuckets = []float64{-300, -60, -10, -1, -0.1, 0.1, 1, 10, 60, 300}
+
+// EstimateClockSkew estimates how far ahead a peer's clock is relative to self from the peer's
+// time in a response, assuming the response was created halfway between sending the request and
+// receiving the response. It returns false if the peer didn't report its time.
+func EstimateClockSkew(sent, received time.Time, peerTimeNanos int64) (time.Duration, bool) {
+	if peerTimeNanos == 0 {
+		return 0, false
+	}
+	midpoint := sent.Add(received.Sub(sent) / 2)
+	return time.Unix(0, peerTimeNanos).Sub(midpoint), true
+}
+
+// SkewRecorder records the apparent clock skew of peers relative to self.
+type SkewRecorder interface {
+	// Record records how far ahead of self the peer's clock appears to be.
+	Record(peerID id.ID, skew time.Duration)
+
+	// SelfSkew estimates how far ahead of the network self's clock is as the negative median
+	// of the latest skews of recently measured peers. It returns false if too few peers have been
+	// measured to make an estimate.
+	SelfSkew() (time.Duration, bool)
+
+	// Register registers the Prometheus metric(s) with the default Prometheus registerer.
+	Register()
+
+	// Unregister unregisters the Prometheus metrics form the default Prometheus registerer.
+	Unregister()
+}
+
+type skewRecorder struct {
+	skews     map[string]time.Duration
+	peerSkews prom.Histogram
+	selfSkew  prom.Gauge
+	mu        sync.Mutex
+}
+
+// NewSkewRecorder creates a new SkewRecorder that also emits Prometheus metrics for the skew
+// distribution of peers and the estimated skew of self.
+func NewSkewRecorder() SkewRecorder {
+	return &skewRecorder{
+		skews: make(map[string]time.Duration),
+		peerSkews: prom.NewHistogram(prom.HistogramOpts{
+			Namespace: counterNamespace,
+			Subsystem: skewSubsystem,
+			Name:      "peer_clock_skew_seconds",
+			Help:      "Apparent clock skew of peers relative to self.",
+			Buckets:   skewBuckets,
+		}),
+		selfSkew: prom.NewGauge(prom.GaugeOpts{
+			Namespace: counterNamespace,
+			Subsystem: skewSubsystem,
+			Name:      "self_clock_skew_seconds",
+			Help:      "Estimated clock skew of self relative to the network.",
+		}),
+	}
+}
+
+func (r *skewRecorder) Record(peerID id.ID, skew time.Duration) {
+	r.peerSkews.Observe(skew.Seconds())
+	r.mu.Lock()
+	idStr := peerID.String()
+	if _, in := r.skews[idStr]; !in && len(r.skews) >= maxSkewPeers {
+		// evict an arbitrary peer to make room
+		for other := range r.skews {
+			delete(r.skews, other)
+			break
+		}
+	}
+	r.skews[idStr] = skew
+	selfSkew, ok := r.selfSkewLocked()
+	r.mu.Unlock()
+	if ok {
+		r.selfSkew.Set(selfSkew.Seconds())
+	}
+}
+
+func (r *skewRecorder) SelfSkew() (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.selfSkewLocked()
+}
+
+func (r *skewRecorder) selfSkewLocked() (time.Duration, bool) {
+	if len(r.skews) < minSkewPeers {
+		return 0, false
+	}
+	skews := make([]time.Duration, 0, len(r.skews))
+	for _, skew := range r.skews {
+		skews = append(skews, skew)
+	}
+	sort.Slice(skews, func(i, j int) bool { return skews[i] < skews[j] })
+	median := skews[len(skews)/2]
+	if len(skews)%2 == 0 {
+		median = (skews[len(skews)/2-1] + median) / 2
+	}
+	return -median, true
+}
+
+func (r *skewRecorder) Register() {
+	prom.MustRegister(r.peerSkews)
+	prom.MustRegister(r.selfSkew)
+}
+
+func (r *skewRecorder) Unregister() {
+	_ = prom.Unregister(r.peerSkews)
+	_ = prom.Unregister(r.selfSkew)
+}
@@ -0,0 +1,82 @@
+package comm
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateClockSkew(t *testing.T) {
+	sent := time.Unix(1000, 0)
+	received := sent.Add(2 * time.Second)
+
+	// peer created response at its 1010s, when our clock read 1001s
+	skew, ok := EstimateClockSkew(sent, received, time.Unix(1010, 0).UnixNano())
+	assert.True(t, ok)
+	assert.Equal(t, 9*time.Second, skew)
+
+	skew, ok = EstimateClockSkew(sent, received, time.Unix(991, 0).UnixNano())
+	assert.True(t, ok)
+	assert.Equal(t, -10*time.Second, skew)
+
+	// missing peer time
+	_, ok = EstimateClockSkew(sent, received, 0)
+	assert.False(t, ok)
+}
+
+func TestSkewRecorder_SelfSkew(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := NewSkewRecorder()
+	peerIDs := make([]id.ID, minSkewPeers)
+	for i := range peerIDs {
+		peerIDs[i] = id.NewPseudoRandom(rng)
+	}
+
+	// too few peers for an estimate
+	for _, peerID := range peerIDs[:minSkewPeers-1] {
+		r.Record(peerID, time.Second)
+	}
+	_, ok := r.SelfSkew()
+	assert.False(t, ok)
+
+	// one outlier shouldn't affect the median much
+	r.Record(peerIDs[minSkewPeers-1], time.Hour)
+	selfSkew, ok := r.SelfSkew()
+	assert.True(t, ok)
+	assert.Equal(t, -time.Second, selfSkew)
+
+	// re-recording a peer replaces its previous skew
+	for _, peerID := range peerIDs {
+		r.Record(peerID, -time.Minute)
+	}
+	selfSkew, ok = r.SelfSkew()
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, selfSkew)
+
+	// even number of peers uses mean of middle two
+	r = NewSkewRecorder()
+	for i := 1; i <= 6; i++ {
+		r.Record(id.NewPseudoRandom(rng), time.Duration(i)*time.Second)
+	}
+	selfSkew, ok = r.SelfSkew()
+	assert.True(t, ok)
+	assert.Equal(t, -3500*time.Millisecond, selfSkew)
+}
+
+func TestSkewRecorder_Record_maxPeers(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := NewSkewRecorder().(*skewRecorder)
+	for i := 0; i < maxSkewPeers*2; i++ {
+		r.Record(id.NewPseudoRandom(rng), time.Second)
+	}
+	assert.Len(t, r.skews, maxSkewPeers)
+}
+
+func TestSkewRecorder_Register(t *testing.T) {
+	r := NewSkewRecorder()
+	r.Register()
+	r.Unregister()
+}
@@ -0,0 +1,216 @@
+package comm
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const (
+	// DefaultThrottleRate is the default maximum sustained rate of outbound queries per second
+	// to each peer.
+	DefaultThrottleRate = 32.0
+
+	// DefaultThrottleBurst is the default maximum number of outbound queries sent to each peer
+	// in a burst above the sustained rate.
+	DefaultThrottleBurst = uint(16)
+
+	// DefaultThrottleQueue is the default of whether throttled queries wait for allowance rather
+	// than being dropped.
+	DefaultThrottleQueue = true
+
+	// DefaultThrottleMaxWait is the default maximum time a throttled query waits for allowance
+	// before being dropped.
+	DefaultThrottleMaxWait = 1 * time.Second
+
+	// maxThrottledPeers is the number of peer buckets above which idle ones are pruned.
+	maxThrottledPeers = 1024
+
+	throttleSubsystem   = "comm"
+	throttledCountName  = "throttled_query_count"
+	throttleQueuedLabel = "QUEUED"
+	throttleDropLabel   = "DROPPED"
+)
+
+// ErrQueryThrottled indicates when an outbound query was dropped because too many queries were
+// recently sent to the same peer. Since the query was never sent, it's neither the peer's success
+// nor its error, so callers shouldn't record or count it as either.
+var ErrQueryThrottled = errors.New("outbound queries to peer throttled")
+
+// ThrottleParameters define how outbound queries to each peer are rate limited.
+type ThrottleParameters struct {
+	// Rate is the maximum sustained rate of outbound queries per second to each peer. Zero
+	// disables throttling.
+	Rate float64
+
+	// Burst is the maximum number of queries sent to a peer in a burst above the sustained rate.
+	Burst uint
+
+	// Queue is whether throttled queries wait for allowance, up to MaxWait or their deadline,
+	// rather than being dropped immediately.
+	Queue bool
+
+	// MaxWait is the maximum time a throttled query waits for allowance before being dropped.
+	MaxWait time.Duration
+}
+
+// NewDefaultThrottleParameters returns the default ThrottleParameters.
+func NewDefaultThrottleParameters() *ThrottleParameters {
+	return &ThrottleParameters{
+		Rate:    DefaultThrottleRate,
+		Burst:   DefaultThrottleBurst,
+		Queue:   DefaultThrottleQueue,
+		MaxWait: DefaultThrottleMaxWait,
+	}
+}
+
+// Enabled returns whether outbound queries are throttled.
+func (p *ThrottleParameters) Enabled() bool {
+	return p.Rate > 0
+}
+
+// Throttler limits the rate of outbound queries to each peer, so a burst of searches or
+// replication can't overwhelm a single slow peer.
+type Throttler interface {
+	// Acquire takes the allowance for a query on the endpoint to the peer at the given address.
+	// When none is available, it either waits for allowance or returns ErrQueryThrottled,
+	// depending on the ThrottleParameters, or the context's error if it's done first.
+	Acquire(ctx context.Context, address string, endpoint string) error
+
+	// UnaryClientInterceptor returns a client interceptor acquiring allowance for each query
+	// before sending it.
+	UnaryClientInterceptor() grpc.UnaryClientInterceptor
+
+	// Register registers the Prometheus metric(s) with the default Prometheus registerer.
+	Register()
+
+	// Unregister unregisters the Prometheus metrics from the default Prometheus registerer.
+	Unregister()
+}
+
+type throttler struct {
+	params    *ThrottleParameters
+	buckets   map[string]*TokenBucket
+	now       func() time.Time
+	throttled *prom.CounterVec
+	mu        sync.Mutex
+}
+
+// NewThrottler returns a new Throttler with a token bucket for each peer address.
+func NewThrottler(params *ThrottleParameters) Throttler {
+	return &throttler{
+		params:  params,
+		buckets: make(map[string]*TokenBucket),
+		now:     time.Now,
+		throttled: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: counterNamespace,
+				Subsystem: throttleSubsystem,
+				Name:      throttledCountName,
+				Help:      "Number of outbound queries queued or dropped by per-peer throttling.",
+			},
+			[]string{endpointLabel, outcomeLabel},
+		),
+	}
+}
+
+func (t *throttler) Acquire(ctx context.Context, address string, endpoint string) error {
+	if !t.params.Enabled() {
+		return nil
+	}
+	b := t.bucket(address)
+	wait := b.Take(t.now())
+	if wait == 0 {
+		return nil
+	}
+	if !t.params.Queue {
+		return t.drop(endpoint)
+	}
+	t.throttled.With(prom.Labels{
+		endpointLabel: endpoint,
+		outcomeLabel:  throttleQueuedLabel,
+	}).Inc()
+	giveUp := time.NewTimer(t.params.MaxWait)
+	defer giveUp.Stop()
+	for ; wait > 0; wait = b.Take(t.now()) {
+		select {
+		case <-ctx.Done():
+			t.drop(endpoint)
+			return ctx.Err()
+		case <-giveUp.C:
+			return t.drop(endpoint)
+		case <-time.After(wait):
+		}
+	}
+	return nil
+}
+
+func (t *throttler) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if err := t.Acquire(ctx, cc.Target(), methodEndpoint(method)); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func (t *throttler) Register() {
+	prom.MustRegister(t.throttled)
+}
+
+func (t *throttler) Unregister() {
+	_ = prom.Unregister(t.throttled)
+}
+
+func (t *throttler) drop(endpoint string) error {
+	t.throttled.With(prom.Labels{
+		endpointLabel: endpoint,
+		outcomeLabel:  throttleDropLabel,
+	}).Inc()
+	return ErrQueryThrottled
+}
+
+// bucket returns the token bucket for the peer address, creating it if necessary.
+func (t *throttler) bucket(address string) *TokenBucket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, in := t.buckets[address]
+	if !in {
+		if len(t.buckets) >= maxThrottledPeers {
+			t.pruneLocked()
+		}
+		b = NewTokenBucket(t.params.Rate, math.Max(1, float64(t.params.Burst)))
+		t.buckets[address] = b
+	}
+	return b
+}
+
+// pruneLocked removes the buckets of peers not queried for long enough that their buckets have
+// refilled, since they're equivalent to new ones.
+func (t *throttler) pruneLocked() {
+	now := t.now()
+	for address, b := range t.buckets {
+		if b.idle(now) {
+			delete(t.buckets, address)
+		}
+	}
+}
+
+// methodEndpoint returns the endpoint name of a full gRPC method, e.g., "Find" for
+// "/api.Librarian/Find".
+func methodEndpoint(method string) string {
+	return method[strings.LastIndex(method, "/")+1:]
+}
@@ -0,0 +1,91 @@
+package comm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestThrottleParameters_Enabled(t *testing.T) {
+	assert.True(t, NewDefaultThrottleParameters().Enabled())
+	assert.False(t, (&ThrottleParameters{}).Enabled())
+}
+
+func TestThrottler_Acquire_disabled(t *testing.T) {
+	th := NewThrottler(&ThrottleParameters{})
+	for c := 0; c < 100; c++ {
+		assert.Nil(t, th.Acquire(context.Background(), "peer1", "Find"))
+	}
+}
+
+func TestThrottler_Acquire_drop(t *testing.T) {
+	th := NewThrottler(&ThrottleParameters{Rate: 1, Burst: 3})
+	now := time.Now()
+	th.(*throttler).now = func() time.Time { return now }
+
+	// burst allowed, then dropped
+	for c := 0; c < 3; c++ {
+		assert.Nil(t, th.Acquire(context.Background(), "peer1", "Find"))
+	}
+	assert.Equal(t, ErrQueryThrottled, th.Acquire(context.Background(), "peer1", "Find"))
+
+	// other peers unaffected
+	assert.Nil(t, th.Acquire(context.Background(), "peer2", "Find"))
+
+	// allowance refills over time
+	now = now.Add(time.Second)
+	assert.Nil(t, th.Acquire(context.Background(), "peer1", "Find"))
+}
+
+func TestThrottler_Acquire_queue(t *testing.T) {
+	th := NewThrottler(&ThrottleParameters{
+		Rate:    100,
+		Burst:   1,
+		Queue:   true,
+		MaxWait: time.Second,
+	})
+	assert.Nil(t, th.Acquire(context.Background(), "peer1", "Find"))
+
+	// waits for allowance
+	start := time.Now()
+	assert.Nil(t, th.Acquire(context.Background(), "peer1", "Find"))
+	assert.True(t, time.Since(start) > 0)
+
+	// drops when allowance doesn't come before context is done or max wait
+	th = NewThrottler(&ThrottleParameters{
+		Rate:    0.01,
+		Burst:   1,
+		Queue:   true,
+		MaxWait: 10 * time.Millisecond,
+	})
+	assert.Nil(t, th.Acquire(context.Background(), "peer1", "Find"))
+	assert.Equal(t, ErrQueryThrottled, th.Acquire(context.Background(), "peer1", "Find"))
+
+	// context's error returned when it's done first
+	th.(*throttler).params.MaxWait = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, th.Acquire(ctx, "peer1", "Find"))
+}
+
+func TestThrottler_bucket_prune(t *testing.T) {
+	th := NewThrottler(&ThrottleParameters{Rate: 1, Burst: 1}).(*throttler)
+	now := time.Now()
+	th.now = func() time.Time { return now }
+	for c := 0; c < maxThrottledPeers; c++ {
+		th.bucket(string(rune(c))).Take(now)
+	}
+	assert.Len(t, th.buckets, maxThrottledPeers)
+
+	// idle buckets are pruned when adding another
+	now = now.Add(time.Minute)
+	th.bucket("another")
+	assert.Len(t, th.buckets, 1)
+}
+
+func TestMethodEndpoint(t *testing.T) {
+	assert.Equal(t, "Find", methodEndpoint("/api.Librarian/Find"))
+	assert.Equal(t, "Find", methodEndpoint("Find"))
+}
@@ -1,20 +1,27 @@
 package server
 
 import (
+	"crypto/ecdsa"
 	"crypto/sha256"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 
+	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/parse"
+	"github.com/drausin/libri/libri/common/storage"
 	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/introduce"
 	"github.com/drausin/libri/libri/librarian/server/replicate"
 	"github.com/drausin/libri/libri/librarian/server/routing"
 	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/seed"
 	"github.com/drausin/libri/libri/librarian/server/store"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -42,6 +49,10 @@ const (
 
 	// DBSubDir is the default DB subdirectory within the data dir.
 	DBSubDir = "db"
+
+	// DefaultNPrewarmPeers is the default number of peers closest to self to connect to after
+	// bootstrapping.
+	DefaultNPrewarmPeers = uint(8)
 )
 
 // Config is used to configure a Librarian server
@@ -58,12 +69,49 @@ type Config struct {
 	// PublicAddr is the public address clients make requests to.
 	PublicAddr *net.TCPAddr
 
+	// AltPublicAddrs are additional public addresses clients may make requests to, in preference
+	// order after PublicAddr, e.g., the IPv4 address of a dual-stack peer.
+	AltPublicAddrs []*net.TCPAddr
+
 	// PublicName is the public facing name of the peer.
 	PublicName string
 
+	// Zone is the operator-assigned failure domain (e.g., zone or region) the peer advertises,
+	// which stores use to spread replicas across distinct zones. Empty means no zone.
+	Zone string
+
 	// OrgID is the organization ID of the peer, if one exists.
 	OrgID ecid.ID
 
+	// StoreAuthPubKey is the public key of the operator issuing store authorization tokens. When
+	// set, Store and Put requests must carry a token signed by this key for the requester's
+	// organization, though all other requests remain public.
+	StoreAuthPubKey *ecdsa.PublicKey
+
+	// MacaroonRootKey is the secret key shared with the operator issuing macaroons to authors.
+	// When set, Get, Put, Store, and value-returning Find requests must carry a macaroon issued
+	// with this key authorizing the requester's organization to get, put, or share (i.e., put
+	// envelopes), so semi-public librarians needn't accept writes from anyone. Peers send theirs
+	// via Store.Macaroon and Search.Macaroon.
+	MacaroonRootKey []byte
+
+	// AdminPubKey is the public key of the operator allowed to make admin requests, e.g., for
+	// the routing table contents. When not set, admin requests are denied.
+	AdminPubKey *ecdsa.PublicKey
+
+	// NetworkParamsPubKey is the public key of the network maintainers publishing recommended
+	// network parameters. When set, the parameters are fetched from the bootstrap peers on
+	// startup and adopted.
+	NetworkParamsPubKey *ecdsa.PublicKey
+
+	// StorageHooks are called on document storage events, e.g., to update an external index of
+	// stored documents.
+	StorageHooks storage.DocumentHooks
+
+	// StorageHookCommand is an external command (and its leading arguments) run on each
+	// document storage event with the event name and hex document key as its final arguments.
+	StorageHookCommand []string
+
 	// DataDir is the directory on the local machine where the state and output of all the
 	// peer running on that machine are stored.
 	DataDir string
@@ -71,9 +119,43 @@ type Config struct {
 	// DbDir is the local directory where this node's DB state is stored.
 	DbDir string
 
+	// DBDriver is the name of the driver (e.g., db.LevelDBDriver) for the local DB.
+	DBDriver string
+
+	// Tiering defines how stored documents are split between hot and cold storage tiers.
+	Tiering *TieringParameters
+
+	// Expiry defines how documents stored with a TTL are swept once they expire.
+	Expiry *ExpiryParameters
+
+	// Mirror defines read-only public mirror mode, disabled by default.
+	Mirror *MirrorParameters
+
+	// AllowList defines allow-list mode for private clusters, disabled by default.
+	AllowList *AllowListParameters
+
+	// Quota defines the storage quotas enforced on each organization's Store requests.
+	Quota *QuotaParameters
+
 	// BootstrapAddrs is a list of addresses for bootstrap peers.
 	BootstrapAddrs []*net.TCPAddr
 
+	// SeedHosts is a list of DNS hostnames whose TXT and SRV records are resolved into
+	// additional bootstrap addresses.
+	SeedHosts []string
+
+	// Seed defines how seed hosts are resolved.
+	Seed *seed.Parameters
+
+	// MaxPageSize is the maximum size (in bytes) of a Page, not including encryption overhead,
+	// accepted by Store and Put requests. It is advertised to clients so they can size pages
+	// accordingly.
+	MaxPageSize uint32
+
+	// NPrewarmPeers is the number of peers closest to self to dial and health-check after
+	// bootstrapping, so the first searches and stores don't pay cold-dial latency.
+	NPrewarmPeers uint
+
 	// Routing defines parameters for the server's routing table.
 	Routing *routing.Parameters
 
@@ -83,12 +165,56 @@ type Config struct {
 	// Search defines parameters for searches the server performs.
 	Search *search.Parameters
 
+	// GetLatencyWeighted determines whether the searches for Get requests, unlike the server's
+	// other searches, query the peers with the lowest recent latencies first among equally close
+	// peers (see search.Parameters.LatencyWeighted).
+	GetLatencyWeighted bool
+
 	// Store defines parameters for stores the server performs.
 	Store *store.Parameters
 
+	// StorePolicy optionally overrides the Store parameters for particular documents, e.g., the
+	// number of replicas for each document type. Put requests asking for a number of replicas
+	// take precedence.
+	StorePolicy store.Policy
+
 	// Replicate defines parameters for replications the server performs.
 	Replicate *replicate.Parameters
 
+	// AntiEntropy defines how the keys stored are periodically synced with those of the nearest
+	// neighbors.
+	AntiEntropy *AntiEntropyParameters
+
+	// Handoff defines how documents stored on behalf of unreachable peers are handed off to them
+	// when they return.
+	Handoff *HandoffParameters
+
+	// Blacklist defines when misbehaving peers are banned.
+	Blacklist *comm.BlacklistParameters
+
+	// Greylist defines when and how long misbehaving peers are temporarily greylisted.
+	Greylist *comm.GreylistParameters
+
+	// Reputation defines how peers' reputations, which order the routing table and
+	// reputation-weighted searches, are scored.
+	Reputation *comm.ReputationParameters
+
+	// Probe defines how the routing table peers' health is actively probed.
+	Probe *comm.ProbeParameters
+
+	// Readiness defines when the server reports itself as serving to health checks.
+	Readiness *ReadinessParameters
+
+	// Throttle defines how outbound queries to each peer are rate limited.
+	Throttle *comm.ThrottleParameters
+
+	// QueryCheckpoint defines how the peers' recorded queries are persisted across restarts.
+	QueryCheckpoint *comm.QueryCheckpointParameters
+
+	// RequesterLimit defines how the rates of requests from each requester peer are limited,
+	// depending on the organization signing them.
+	RequesterLimit *comm.RequesterLimitParameters
+
 	// SubscribeTo defines parameters for subscriptions to other peers.
 	SubscribeTo *subscribe.ToParameters
 
@@ -98,6 +224,29 @@ type Config struct {
 	// ReportMetrics determines whether the server reports Prometheus metrics.
 	ReportMetrics bool
 
+	// TraceSampleRates defines the fraction of requests to each endpoint that are traced.
+	TraceSampleRates TraceSampleRates
+
+	// Tracer optionally traces the server's requests, searches, and stores, with a span per
+	// request handled and children spans for the searches and stores it triggers and their peer
+	// queries.
+	Tracer search.Tracer
+
+	// TracePropagator optionally propagates the trace context of spans between librarians in
+	// request metadata, so a single request (e.g., an author Put) is traced across the search
+	// hops and replica stores it triggers.
+	TracePropagator client.TracePropagator
+
+	// ClientInterceptors are optional gRPC interceptors applied to every connection to other
+	// peers, e.g., for the embedding application's metrics, auth headers, or tracing.
+	ClientInterceptors *client.Interceptors
+
+	// TLS defines the mutual TLS of connections to and from other peers, disabled by default.
+	TLS *client.TLSParameters
+
+	// WorkerPoolSizes defines the number of workers handling requests to each endpoint.
+	WorkerPoolSizes WorkerPoolSizes
+
 	// Profile determines whether the profiler endpoint (/debug/pprof) is enabled.
 	Profile bool
 
@@ -118,7 +267,16 @@ func NewDefaultConfig() *Config {
 	config.WithDefaultPublicName()
 	config.WithDefaultDataDir()
 	config.WithDefaultDBDir()
+	config.WithDefaultDBDriver()
+	config.WithDefaultTiering()
+	config.WithDefaultExpiry()
+	config.WithDefaultMirror()
+	config.WithDefaultAllowList()
+	config.WithDefaultQuota()
 	config.WithDefaultBootstrapAddrs()
+	config.WithDefaultSeed()
+	config.WithDefaultNPrewarmPeers()
+	config.WithDefaultMaxPageSize()
 	config.WithDefaultRouting()
 	config.WithDefaultIntroduce()
 	config.WithDefaultSearch()
@@ -126,7 +284,20 @@ func NewDefaultConfig() *Config {
 	config.WithDefaultSubscribeTo()
 	config.WithDefaultSubscribeFrom()
 	config.WithDefaultReplicate()
+	config.WithDefaultAntiEntropy()
+	config.WithDefaultHandoff()
+	config.WithDefaultBlacklist()
+	config.WithDefaultGreylist()
+	config.WithDefaultReputation()
+	config.WithDefaultProbe()
+	config.WithDefaultReadiness()
+	config.WithDefaultThrottle()
+	config.WithDefaultRequesterLimit()
+	config.WithDefaultQueryCheckpoint()
 	config.WithDefaultReportMetrics()
+	config.WithDefaultTraceSampleRates()
+	config.WithDefaultTLS()
+	config.WithDefaultWorkerPoolSizes()
 	config.WithDefaultProfile()
 	config.WithDefaultLogLevel()
 
@@ -165,6 +336,37 @@ func (c *Config) WithDefaultLocalMetricsPort() *Config {
 	return c
 }
 
+// WithNPrewarmPeers sets the number of closest peers to prewarm to the given value or to the
+// default if the given value is zero.
+func (c *Config) WithNPrewarmPeers(nPrewarmPeers uint) *Config {
+	if nPrewarmPeers == 0 {
+		return c.WithDefaultNPrewarmPeers()
+	}
+	c.NPrewarmPeers = nPrewarmPeers
+	return c
+}
+
+// WithDefaultNPrewarmPeers sets the number of closest peers to prewarm to the default value.
+func (c *Config) WithDefaultNPrewarmPeers() *Config {
+	c.NPrewarmPeers = DefaultNPrewarmPeers
+	return c
+}
+
+// WithMaxPageSize sets the max page size to the given value or the default if it is zero.
+func (c *Config) WithMaxPageSize(maxPageSize uint32) *Config {
+	if maxPageSize == 0 {
+		return c.WithDefaultMaxPageSize()
+	}
+	c.MaxPageSize = maxPageSize
+	return c
+}
+
+// WithDefaultMaxPageSize sets the max page size to the default value.
+func (c *Config) WithDefaultMaxPageSize() *Config {
+	c.MaxPageSize = api.DefaultMaxPageSize
+	return c
+}
+
 // WithLocalProfilerPort sets config's local profiler address to the given value or to the default
 // if the given value is nil.
 func (c *Config) WithLocalProfilerPort(localProfilerPort int) *Config {
@@ -200,6 +402,12 @@ func (c *Config) WithDefaultPublicAddr() *Config {
 	return c
 }
 
+// WithAltPublicAddrs sets the additional public addresses.
+func (c *Config) WithAltPublicAddrs(addrs []*net.TCPAddr) *Config {
+	c.AltPublicAddrs = addrs
+	return c
+}
+
 // WithPublicName sets the public name to the given value or the default if the given value is
 // empty.
 func (c *Config) WithPublicName(publicName string) *Config {
@@ -210,12 +418,56 @@ func (c *Config) WithPublicName(publicName string) *Config {
 	return c
 }
 
+// WithZone sets the failure domain the peer advertises.
+func (c *Config) WithZone(zone string) *Config {
+	c.Zone = zone
+	return c
+}
+
 // WithOrgID sets the organization ID.
 func (c *Config) WithOrgID(orgID ecid.ID) *Config {
 	c.OrgID = orgID
 	return c
 }
 
+// WithStoreAuthPubKey sets the public key of the operator issuing store authorization tokens.
+func (c *Config) WithStoreAuthPubKey(pubKey *ecdsa.PublicKey) *Config {
+	c.StoreAuthPubKey = pubKey
+	return c
+}
+
+// WithMacaroonRootKey sets the secret key with which the macaroons required of Get, Put, Store,
+// and value-returning Find requests are issued.
+func (c *Config) WithMacaroonRootKey(rootKey []byte) *Config {
+	c.MacaroonRootKey = rootKey
+	return c
+}
+
+// WithAdminPubKey sets the public key of the operator allowed to make admin requests.
+func (c *Config) WithAdminPubKey(pubKey *ecdsa.PublicKey) *Config {
+	c.AdminPubKey = pubKey
+	return c
+}
+
+// WithNetworkParamsPubKey sets the public key of the network maintainers publishing recommended
+// network parameters.
+func (c *Config) WithNetworkParamsPubKey(pubKey *ecdsa.PublicKey) *Config {
+	c.NetworkParamsPubKey = pubKey
+	return c
+}
+
+// WithStorageHooks sets the hooks called on document storage events.
+func (c *Config) WithStorageHooks(hooks storage.DocumentHooks) *Config {
+	c.StorageHooks = hooks
+	return c
+}
+
+// WithStorageHookCommand sets the external command run on document storage events.
+func (c *Config) WithStorageHookCommand(command []string) *Config {
+	c.StorageHookCommand = command
+	return c
+}
+
 // WithDefaultPublicName sets the public name to the default value, which uses a hash of the
 // public address.
 func (c *Config) WithDefaultPublicName() *Config {
@@ -255,6 +507,96 @@ func (c *Config) WithDefaultDBDir() *Config {
 	return c
 }
 
+// WithDBDriver sets the DB driver to the given value or the default if the given value is empty.
+func (c *Config) WithDBDriver(driver string) *Config {
+	if driver == "" {
+		return c.WithDefaultDBDriver()
+	}
+	c.DBDriver = driver
+	return c
+}
+
+// WithDefaultDBDriver sets the DB driver to the default.
+func (c *Config) WithDefaultDBDriver() *Config {
+	c.DBDriver = db.DefaultDriver
+	return c
+}
+
+// WithTiering sets the tiering parameters to the given value or the default if it is nil.
+func (c *Config) WithTiering(params *TieringParameters) *Config {
+	if params == nil {
+		return c.WithDefaultTiering()
+	}
+	c.Tiering = params
+	return c
+}
+
+// WithDefaultTiering sets the tiering parameters to the default.
+func (c *Config) WithDefaultTiering() *Config {
+	c.Tiering = NewDefaultTieringParameters()
+	return c
+}
+
+// WithExpiry sets the expiry parameters to the given value or the default if it is nil.
+func (c *Config) WithExpiry(params *ExpiryParameters) *Config {
+	if params == nil {
+		return c.WithDefaultExpiry()
+	}
+	c.Expiry = params
+	return c
+}
+
+// WithDefaultExpiry sets the expiry parameters to the default.
+func (c *Config) WithDefaultExpiry() *Config {
+	c.Expiry = NewDefaultExpiryParameters()
+	return c
+}
+
+// WithMirror sets the mirror parameters to the given value or the default if it is nil.
+func (c *Config) WithMirror(params *MirrorParameters) *Config {
+	if params == nil {
+		return c.WithDefaultMirror()
+	}
+	c.Mirror = params
+	return c
+}
+
+// WithDefaultMirror sets the mirror parameters to the default.
+func (c *Config) WithDefaultMirror() *Config {
+	c.Mirror = NewDefaultMirrorParameters()
+	return c
+}
+
+// WithAllowList sets the allow-list parameters to the given value or the default if it is nil.
+func (c *Config) WithAllowList(params *AllowListParameters) *Config {
+	if params == nil {
+		return c.WithDefaultAllowList()
+	}
+	c.AllowList = params
+	return c
+}
+
+// WithDefaultAllowList sets the allow-list parameters to the default.
+func (c *Config) WithDefaultAllowList() *Config {
+	c.AllowList = NewDefaultAllowListParameters()
+	return c
+}
+
+// WithQuota sets the quota parameters to the given value or the default if it is nil.
+func (c *Config) WithQuota(params *QuotaParameters) *Config {
+	if params == nil {
+		return c.WithDefaultQuota()
+	}
+	c.Quota = params
+	return c
+}
+
+// WithDefaultQuota sets the quota parameters to the default.
+func (c *Config) WithDefaultQuota() *Config {
+	c.Quota = NewDefaultQuotaParameters()
+	return c
+}
+
 // WithBootstrapAddrs sets the bootstrap addresses to the given value or the default if the given
 // value is empty.
 func (c *Config) WithBootstrapAddrs(bootstrapAddrs []*net.TCPAddr) *Config {
@@ -275,6 +617,27 @@ func (c *Config) WithDefaultBootstrapAddrs() *Config {
 	return c
 }
 
+// WithSeedHosts sets the DNS seed hosts to resolve into additional bootstrap addresses.
+func (c *Config) WithSeedHosts(seedHosts []string) *Config {
+	c.SeedHosts = seedHosts
+	return c
+}
+
+// WithSeed sets the seed parameters to the given value or the default if it is nil.
+func (c *Config) WithSeed(params *seed.Parameters) *Config {
+	if params == nil {
+		return c.WithDefaultSeed()
+	}
+	c.Seed = params
+	return c
+}
+
+// WithDefaultSeed sets the seed parameters to the default.
+func (c *Config) WithDefaultSeed() *Config {
+	c.Seed = seed.NewDefaultParameters()
+	return c
+}
+
 // WithRouting sets the routing parameters to the given value or the default if it is nil.
 func (c *Config) WithRouting(params *routing.Parameters) *Config {
 	if params == nil {
@@ -323,6 +686,12 @@ func (c *Config) WithDefaultSearch() *Config {
 	return c
 }
 
+// WithGetLatencyWeighted sets whether the searches for Get requests are latency weighted.
+func (c *Config) WithGetLatencyWeighted(latencyWeighted bool) *Config {
+	c.GetLatencyWeighted = latencyWeighted
+	return c
+}
+
 // WithStore sets the store parameters to the given value or the default if it is nil.
 func (c *Config) WithStore(params *store.Parameters) *Config {
 	if params == nil {
@@ -339,6 +708,12 @@ func (c *Config) WithDefaultStore() *Config {
 	return c
 }
 
+// WithStorePolicy sets the policy overriding the store parameters for particular documents.
+func (c *Config) WithStorePolicy(policy store.Policy) *Config {
+	c.StorePolicy = policy
+	return c
+}
+
 // WithSubscribeTo sets the subscription to parameters to the given value or the default it it is
 // nil.
 func (c *Config) WithSubscribeTo(params *subscribe.ToParameters) *Config {
@@ -387,6 +762,159 @@ func (c *Config) WithDefaultReplicate() *Config {
 	return c
 }
 
+// WithAntiEntropy sets the anti-entropy parameters to the given value or the default if it is nil.
+func (c *Config) WithAntiEntropy(params *AntiEntropyParameters) *Config {
+	if params == nil {
+		return c.WithDefaultAntiEntropy()
+	}
+	c.AntiEntropy = params
+	return c
+}
+
+// WithDefaultAntiEntropy sets the anti-entropy parameters to the default.
+func (c *Config) WithDefaultAntiEntropy() *Config {
+	c.AntiEntropy = NewDefaultAntiEntropyParameters()
+	return c
+}
+
+// WithHandoff sets the hinted handoff parameters to the given value or the default if it is nil.
+func (c *Config) WithHandoff(params *HandoffParameters) *Config {
+	if params == nil {
+		return c.WithDefaultHandoff()
+	}
+	c.Handoff = params
+	return c
+}
+
+// WithDefaultHandoff sets the hinted handoff parameters to the default.
+func (c *Config) WithDefaultHandoff() *Config {
+	c.Handoff = NewDefaultHandoffParameters()
+	return c
+}
+
+// WithBlacklist sets the blacklist parameters to the given value or the default if it is nil.
+func (c *Config) WithBlacklist(params *comm.BlacklistParameters) *Config {
+	if params == nil {
+		return c.WithDefaultBlacklist()
+	}
+	c.Blacklist = params
+	return c
+}
+
+// WithDefaultBlacklist sets the blacklist parameters to the default.
+func (c *Config) WithDefaultBlacklist() *Config {
+	c.Blacklist = comm.NewDefaultBlacklistParameters()
+	return c
+}
+
+// WithGreylist sets the greylist parameters to the given value or the default if it is nil.
+func (c *Config) WithGreylist(params *comm.GreylistParameters) *Config {
+	if params == nil {
+		return c.WithDefaultGreylist()
+	}
+	c.Greylist = params
+	return c
+}
+
+// WithDefaultGreylist sets the greylist parameters to the default.
+func (c *Config) WithDefaultGreylist() *Config {
+	c.Greylist = comm.NewDefaultGreylistParameters()
+	return c
+}
+
+// WithReputation sets the reputation parameters to the given value or the default if it is nil.
+func (c *Config) WithReputation(params *comm.ReputationParameters) *Config {
+	if params == nil {
+		return c.WithDefaultReputation()
+	}
+	c.Reputation = params
+	return c
+}
+
+// WithDefaultReputation sets the reputation parameters to the default.
+func (c *Config) WithDefaultReputation() *Config {
+	c.Reputation = comm.NewDefaultReputationParameters()
+	return c
+}
+
+// WithProbe sets the health probe parameters to the given value or the default if it is nil.
+func (c *Config) WithProbe(params *comm.ProbeParameters) *Config {
+	if params == nil {
+		return c.WithDefaultProbe()
+	}
+	c.Probe = params
+	return c
+}
+
+// WithDefaultProbe sets the health probe parameters to the default.
+func (c *Config) WithDefaultProbe() *Config {
+	c.Probe = comm.NewDefaultProbeParameters()
+	return c
+}
+
+// WithReadiness sets the readiness parameters to the given value or the default if it is nil.
+func (c *Config) WithReadiness(params *ReadinessParameters) *Config {
+	if params == nil {
+		return c.WithDefaultReadiness()
+	}
+	c.Readiness = params
+	return c
+}
+
+// WithDefaultReadiness sets the readiness parameters to the default.
+func (c *Config) WithDefaultReadiness() *Config {
+	c.Readiness = NewDefaultReadinessParameters()
+	return c
+}
+
+// WithThrottle sets the outbound query throttle parameters to the given value or the default if
+// it is nil.
+func (c *Config) WithThrottle(params *comm.ThrottleParameters) *Config {
+	if params == nil {
+		return c.WithDefaultThrottle()
+	}
+	c.Throttle = params
+	return c
+}
+
+// WithDefaultThrottle sets the outbound query throttle parameters to the default.
+func (c *Config) WithDefaultThrottle() *Config {
+	c.Throttle = comm.NewDefaultThrottleParameters()
+	return c
+}
+
+// WithRequesterLimit sets the requester rate limit parameters to the given value or the default
+// if it is nil.
+func (c *Config) WithRequesterLimit(params *comm.RequesterLimitParameters) *Config {
+	if params == nil {
+		return c.WithDefaultRequesterLimit()
+	}
+	c.RequesterLimit = params
+	return c
+}
+
+// WithDefaultRequesterLimit sets the requester rate limit parameters to the default.
+func (c *Config) WithDefaultRequesterLimit() *Config {
+	c.RequesterLimit = comm.NewDefaultRequesterLimitParameters()
+	return c
+}
+
+// WithQueryCheckpoint sets the query checkpoint parameters to the given value or the default if
+// it is nil.
+func (c *Config) WithQueryCheckpoint(params *comm.QueryCheckpointParameters) *Config {
+	if params == nil {
+		return c.WithDefaultQueryCheckpoint()
+	}
+	c.QueryCheckpoint = params
+	return c
+}
+
+// WithDefaultQueryCheckpoint sets the query checkpoint parameters to the default.
+func (c *Config) WithDefaultQueryCheckpoint() *Config {
+	c.QueryCheckpoint = comm.NewDefaultQueryCheckpointParameters()
+	return c
+}
+
 // WithDefaultReportMetrics sets the default state for whether to report metrics.
 func (c *Config) WithDefaultReportMetrics() *Config {
 	c.ReportMetrics = true
@@ -401,6 +929,71 @@ func (c *Config) WithReportMetrics(reportMetrics bool) *Config {
 	return c
 }
 
+// WithTraceSampleRates sets the per-endpoint trace sample rates to the given value or the default
+// if it is nil.
+func (c *Config) WithTraceSampleRates(rates TraceSampleRates) *Config {
+	if rates == nil {
+		return c.WithDefaultTraceSampleRates()
+	}
+	c.TraceSampleRates = rates
+	return c
+}
+
+// WithDefaultTraceSampleRates sets the per-endpoint trace sample rates to the default.
+func (c *Config) WithDefaultTraceSampleRates() *Config {
+	c.TraceSampleRates = NewDefaultTraceSampleRates()
+	return c
+}
+
+// WithTracer sets the tracer of the server's requests, searches, and stores.
+func (c *Config) WithTracer(tracer search.Tracer) *Config {
+	c.Tracer = tracer
+	return c
+}
+
+// WithTracePropagator sets the propagator of trace context between librarians.
+func (c *Config) WithTracePropagator(p client.TracePropagator) *Config {
+	c.TracePropagator = p
+	return c
+}
+
+// WithClientInterceptors sets the gRPC interceptors applied to every connection to other peers.
+func (c *Config) WithClientInterceptors(interceptors *client.Interceptors) *Config {
+	c.ClientInterceptors = interceptors
+	return c
+}
+
+// WithTLS sets the mutual TLS parameters to the given value or the default if it is nil.
+func (c *Config) WithTLS(params *client.TLSParameters) *Config {
+	if params == nil {
+		return c.WithDefaultTLS()
+	}
+	c.TLS = params
+	return c
+}
+
+// WithDefaultTLS sets the mutual TLS parameters to the default.
+func (c *Config) WithDefaultTLS() *Config {
+	c.TLS = client.NewDefaultTLSParameters()
+	return c
+}
+
+// WithWorkerPoolSizes sets the per-endpoint worker pool sizes to the given value or the default if
+// it is nil.
+func (c *Config) WithWorkerPoolSizes(sizes WorkerPoolSizes) *Config {
+	if sizes == nil {
+		return c.WithDefaultWorkerPoolSizes()
+	}
+	c.WorkerPoolSizes = sizes
+	return c
+}
+
+// WithDefaultWorkerPoolSizes sets the per-endpoint worker pool sizes to the default.
+func (c *Config) WithDefaultWorkerPoolSizes() *Config {
+	c.WorkerPoolSizes = NewDefaultWorkerPoolSizes()
+	return c
+}
+
 // WithDefaultProfile sets the default state for whether to enable the profiler.
 func (c *Config) WithDefaultProfile() *Config {
 	c.Profile = false
@@ -3,13 +3,19 @@ package server
 import (
 	"net"
 	"testing"
+	"time"
 
+	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/parse"
 	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/introduce"
 	"github.com/drausin/libri/libri/librarian/server/replicate"
 	"github.com/drausin/libri/libri/librarian/server/routing"
 	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/seed"
 	"github.com/drausin/libri/libri/librarian/server/store"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zapcore"
@@ -24,6 +30,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.NotEmpty(t, c.DataDir)
 	assert.NotEmpty(t, c.DbDir)
 	assert.NotEmpty(t, c.BootstrapAddrs)
+	assert.NotEmpty(t, c.Seed)
 	assert.NotEmpty(t, c.Routing)
 	assert.NotEmpty(t, c.Introduce)
 	assert.NotEmpty(t, c.Search)
@@ -31,6 +38,16 @@ func TestDefaultConfig(t *testing.T) {
 	assert.NotEmpty(t, c.SubscribeTo)
 	assert.NotEmpty(t, c.SubscribeFrom)
 	assert.NotEmpty(t, c.Replicate)
+	assert.NotEmpty(t, c.Blacklist)
+	assert.NotEmpty(t, c.Greylist)
+	assert.NotEmpty(t, c.AllowList)
+	assert.NotEmpty(t, c.TLS)
+	assert.NotEmpty(t, c.Reputation)
+	assert.NotEmpty(t, c.Probe)
+	assert.NotEmpty(t, c.Readiness)
+	assert.NotEmpty(t, c.Throttle)
+	assert.NotEmpty(t, c.RequesterLimit)
+	assert.NotEmpty(t, c.QueryCheckpoint)
 }
 
 func TestConfig_WithLocalPort(t *testing.T) {
@@ -57,6 +74,20 @@ func TestConfig_WithLocalProfilerPort(t *testing.T) {
 	assert.NotEqual(t, c1.LocalProfilerPort, c3.WithLocalProfilerPort(c3Port).LocalProfilerPort)
 }
 
+func TestConfig_WithNPrewarmPeers(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultNPrewarmPeers()
+	assert.Equal(t, c1.NPrewarmPeers, c2.WithNPrewarmPeers(0).NPrewarmPeers)
+	assert.NotEqual(t, c1.NPrewarmPeers, c3.WithNPrewarmPeers(3).NPrewarmPeers)
+}
+
+func TestConfig_WithMaxPageSize(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultMaxPageSize()
+	assert.Equal(t, c1.MaxPageSize, c2.WithMaxPageSize(0).MaxPageSize)
+	assert.NotEqual(t, c1.MaxPageSize, c3.WithMaxPageSize(1024).MaxPageSize)
+}
+
 func TestConfig_WithPublicAddr(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultPublicAddr()
@@ -69,6 +100,20 @@ func TestConfig_WithPublicAddr(t *testing.T) {
 	)
 }
 
+func TestConfig_WithAltPublicAddrs(t *testing.T) {
+	c := &Config{}
+	assert.Empty(t, c.AltPublicAddrs)
+	addr, err := parse.Addr("192.168.1.1", 1234)
+	assert.Nil(t, err)
+	assert.Equal(t, []*net.TCPAddr{addr}, c.WithAltPublicAddrs([]*net.TCPAddr{addr}).AltPublicAddrs)
+}
+
+func TestConfig_WithZone(t *testing.T) {
+	c := &Config{}
+	assert.Empty(t, c.Zone)
+	assert.Equal(t, "us-east1-b", c.WithZone("us-east1-b").Zone)
+}
+
 func TestConfig_WithPublicName(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultPublicName()
@@ -83,6 +128,13 @@ func TestConfig_WithDataDir(t *testing.T) {
 	assert.NotEqual(t, c1.DataDir, c3.WithDataDir("/some/other/dir").DataDir)
 }
 
+func TestConfig_WithDBDriver(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultDBDriver()
+	assert.Equal(t, c1.DBDriver, c2.WithDBDriver("").DBDriver)
+	assert.NotEqual(t, c1.DBDriver, c3.WithDBDriver(db.RocksDBDriver).DBDriver)
+}
+
 func TestConfig_WithDBDir(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultDBDir()
@@ -142,6 +194,12 @@ func TestConfig_WithStore(t *testing.T) {
 	)
 }
 
+func TestConfig_WithStorePolicy(t *testing.T) {
+	c := &Config{}
+	policy := store.NewReplicationPolicy(api.TypeReplicas{api.EnvelopeType: 5})
+	assert.Equal(t, policy, c.WithStorePolicy(policy).StorePolicy)
+}
+
 func TestConfig_WithSubscribeTo(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultSubscribeTo()
@@ -172,6 +230,189 @@ func TestConfig_WithReplicate(t *testing.T) {
 	)
 }
 
+func TestConfig_WithSeedHosts(t *testing.T) {
+	c := &Config{}
+	assert.Nil(t, c.SeedHosts)
+	seedHosts := []string{"seeds.example.com"}
+	assert.Equal(t, seedHosts, c.WithSeedHosts(seedHosts).SeedHosts)
+}
+
+func TestConfig_WithSeed(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultSeed()
+	assert.Equal(t, c1.Seed, c2.WithSeed(nil).Seed)
+	assert.NotEqual(t,
+		c1.Seed,
+		c3.WithSeed(&seed.Parameters{Timeout: 0}).Seed,
+	)
+}
+
+func TestConfig_WithTiering(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultTiering()
+	assert.False(t, c1.Tiering.Enabled())
+	assert.Equal(t, c1.Tiering, c2.WithTiering(nil).Tiering)
+	assert.NotEqual(t,
+		c1.Tiering,
+		c3.WithTiering(&TieringParameters{ColdDbDir: "cold"}).Tiering,
+	)
+	assert.True(t, c3.Tiering.Enabled())
+}
+
+func TestConfig_WithExpiry(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultExpiry()
+	assert.NotZero(t, c1.Expiry.SweepInterval)
+	assert.NotZero(t, c1.Expiry.MaxSweeps)
+	assert.Equal(t, c1.Expiry, c2.WithExpiry(nil).Expiry)
+	assert.NotEqual(t,
+		c1.Expiry,
+		c3.WithExpiry(&ExpiryParameters{SweepInterval: time.Hour}).Expiry,
+	)
+}
+
+func TestConfig_WithAntiEntropy(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultAntiEntropy()
+	assert.True(t, c1.AntiEntropy.Enabled())
+	assert.NotZero(t, c1.AntiEntropy.NNeighbors)
+	assert.Equal(t, c1.AntiEntropy, c2.WithAntiEntropy(nil).AntiEntropy)
+	assert.NotEqual(t,
+		c1.AntiEntropy,
+		c3.WithAntiEntropy(&AntiEntropyParameters{Interval: time.Hour}).AntiEntropy,
+	)
+}
+
+func TestConfig_WithHandoff(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultHandoff()
+	assert.True(t, c1.Handoff.Enabled())
+	assert.NotZero(t, c1.Handoff.MaxAge)
+	assert.Equal(t, c1.Handoff, c2.WithHandoff(nil).Handoff)
+	assert.NotEqual(t,
+		c1.Handoff,
+		c3.WithHandoff(&HandoffParameters{Interval: time.Hour}).Handoff,
+	)
+}
+
+func TestConfig_WithReadiness(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultReadiness()
+	assert.Equal(t, DefaultReadinessMinPeers, c1.Readiness.MinPeers)
+	assert.NotZero(t, c1.Readiness.CheckInterval)
+	assert.Equal(t, c1.Readiness, c2.WithReadiness(nil).Readiness)
+	assert.NotEqual(t,
+		c1.Readiness,
+		c3.WithReadiness(&ReadinessParameters{MinPeers: 8}).Readiness,
+	)
+}
+
+func TestConfig_WithMirror(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultMirror()
+	assert.False(t, c1.Mirror.Enabled())
+	assert.NotZero(t, c1.Mirror.FetchTimeout)
+	assert.Equal(t, c1.Mirror, c2.WithMirror(nil).Mirror)
+	assert.NotEqual(t,
+		c1.Mirror,
+		c3.WithMirror(&MirrorParameters{AuthorPubKeys: [][]byte{{1, 2, 3}}}).Mirror,
+	)
+}
+
+func TestConfig_WithAllowList(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultAllowList()
+	assert.False(t, c1.AllowList.Enabled())
+	assert.NotZero(t, c1.AllowList.ReloadInterval)
+	assert.Equal(t, c1.AllowList, c2.WithAllowList(nil).AllowList)
+	assert.NotEqual(t,
+		c1.AllowList,
+		c3.WithAllowList(&AllowListParameters{File: "allowlist.bin"}).AllowList,
+	)
+}
+
+func TestConfig_WithQuota(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultQuota()
+	assert.Equal(t, uint64(DefaultOrgQuotaLimitBytes), c1.Quota.DefaultLimitBytes)
+	assert.Equal(t, c1.Quota, c2.WithQuota(nil).Quota)
+	assert.NotEqual(t,
+		c1.Quota,
+		c3.WithQuota(&QuotaParameters{DefaultLimitBytes: 1024}).Quota,
+	)
+}
+
+func TestConfig_WithBlacklist(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultBlacklist()
+	assert.Equal(t, c1.Blacklist, c2.WithBlacklist(nil).Blacklist)
+	assert.NotEqual(t,
+		c1.Blacklist,
+		c3.WithBlacklist(&comm.BlacklistParameters{MaxMisbehaviors: 0}).Blacklist,
+	)
+}
+
+func TestConfig_WithGreylist(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultGreylist()
+	assert.Equal(t, c1.Greylist, c2.WithGreylist(nil).Greylist)
+	assert.NotEqual(t,
+		c1.Greylist,
+		c3.WithGreylist(&comm.GreylistParameters{Period: 0}).Greylist,
+	)
+}
+
+func TestConfig_WithReputation(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultReputation()
+	assert.Equal(t, c1.Reputation, c2.WithReputation(nil).Reputation)
+	assert.NotEqual(t,
+		c1.Reputation,
+		c3.WithReputation(&comm.ReputationParameters{HalfLife: time.Minute}).Reputation,
+	)
+}
+
+func TestConfig_WithProbe(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultProbe()
+	assert.True(t, c1.Probe.Enabled())
+	assert.Equal(t, c1.Probe, c2.WithProbe(nil).Probe)
+	assert.NotEqual(t,
+		c1.Probe,
+		c3.WithProbe(&comm.ProbeParameters{Interval: time.Hour}).Probe,
+	)
+}
+
+func TestConfig_WithThrottle(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultThrottle()
+	assert.True(t, c1.Throttle.Enabled())
+	assert.Equal(t, c1.Throttle, c2.WithThrottle(nil).Throttle)
+	assert.NotEqual(t,
+		c1.Throttle,
+		c3.WithThrottle(&comm.ThrottleParameters{Rate: 1}).Throttle,
+	)
+}
+
+func TestConfig_WithRequesterLimit(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultRequesterLimit()
+	assert.Equal(t, c1.RequesterLimit, c2.WithRequesterLimit(nil).RequesterLimit)
+	assert.NotEqual(t,
+		c1.RequesterLimit,
+		c3.WithRequesterLimit(&comm.RequesterLimitParameters{BurstSeconds: 1}).RequesterLimit,
+	)
+}
+
+func TestConfig_WithQueryCheckpoint(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultQueryCheckpoint()
+	assert.True(t, c1.QueryCheckpoint.Enabled())
+	assert.Equal(t, c1.QueryCheckpoint, c2.WithQueryCheckpoint(nil).QueryCheckpoint)
+	params := &comm.QueryCheckpointParameters{Interval: time.Hour}
+	assert.NotEqual(t, c1.QueryCheckpoint, c3.WithQueryCheckpoint(params).QueryCheckpoint)
+}
+
 func TestConfig_WithReportMetrics(t *testing.T) {
 	c1, c2, c3 := NewDefaultConfig(), NewDefaultConfig(), NewDefaultConfig()
 	c1.WithDefaultReportMetrics()
@@ -185,6 +426,64 @@ func TestConfig_WithReportMetrics(t *testing.T) {
 	assert.False(t, c3.Replicate.ReportMetrics)
 }
 
+func TestConfig_WithTraceSampleRates(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultTraceSampleRates()
+	assert.Equal(t, c1.TraceSampleRates, c2.WithTraceSampleRates(nil).TraceSampleRates)
+	assert.NotEqual(t,
+		c1.TraceSampleRates,
+		c3.WithTraceSampleRates(TraceSampleRates{api.Find: 1.0}).TraceSampleRates,
+	)
+}
+
+func TestConfig_WithTracer(t *testing.T) {
+	c := &Config{}
+	tracer := search.NewNoOpTracer()
+	assert.Equal(t, tracer, c.WithTracer(tracer).Tracer)
+}
+
+func TestConfig_WithTracePropagator(t *testing.T) {
+	c := &Config{}
+	p := &fixedPropagator{}
+	assert.Equal(t, p, c.WithTracePropagator(p).TracePropagator)
+}
+
+func TestConfig_WithClientInterceptors(t *testing.T) {
+	c := &Config{}
+	interceptors := &client.Interceptors{}
+	assert.Equal(t, interceptors, c.WithClientInterceptors(interceptors).ClientInterceptors)
+}
+
+func TestConfig_WithTLS(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultTLS()
+	assert.False(t, c1.TLS.Enabled())
+	assert.NotZero(t, c1.TLS.ReloadInterval)
+	assert.Equal(t, c1.TLS, c2.WithTLS(nil).TLS)
+	assert.NotEqual(t,
+		c1.TLS,
+		c3.WithTLS(&client.TLSParameters{CertFile: "cert.pem"}).TLS,
+	)
+}
+
+func TestConfig_WithWorkerPoolSizes(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultWorkerPoolSizes()
+	assert.Equal(t, c1.WorkerPoolSizes, c2.WithWorkerPoolSizes(nil).WorkerPoolSizes)
+	assert.NotEqual(t,
+		c1.WorkerPoolSizes,
+		c3.WithWorkerPoolSizes(WorkerPoolSizes{api.Find: 1}).WorkerPoolSizes,
+	)
+}
+
+func TestConfig_WithGetLatencyWeighted(t *testing.T) {
+	c1, c2 := &Config{}, &Config{}
+	c1.WithGetLatencyWeighted(true)
+	assert.True(t, c1.GetLatencyWeighted)
+	c2.WithGetLatencyWeighted(false)
+	assert.False(t, c2.GetLatencyWeighted)
+}
+
 func TestConfig_WithProfile(t *testing.T) {
 	c1, c2, c3 := &Config{}, &Config{}, &Config{}
 	c1.WithDefaultProfile()
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/drausin/libri/libri/common/db"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	dbLabel   = "db"
+	statLabel = "stat"
+)
+
+// newDBStatsCollector returns a Prometheus collector that reports the internal statistics (e.g.,
+// RocksDB's estimated number of keys) of each named DB that reports any each time it's scraped.
+func newDBStatsCollector(dbs map[string]db.KVDB) prom.Collector {
+	return &dbStatsCollector{
+		dbs: dbs,
+		stat: prom.NewDesc(
+			prom.BuildFQName("libri", "db", "stat"),
+			"Value of each of the DB's internal statistics.",
+			[]string{dbLabel, statLabel}, nil,
+		),
+	}
+}
+
+type dbStatsCollector struct {
+	dbs  map[string]db.KVDB
+	stat *prom.Desc
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.stat
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prom.Metric) {
+	for name, kvdb := range c.dbs {
+		sr, ok := kvdb.(db.StatsReporter)
+		if !ok {
+			continue
+		}
+		for stat, value := range sr.Stats() {
+			ch <- prom.MustNewConstMetric(c.stat, prom.GaugeValue, value, name, stat)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/drausin/libri/libri/common/db"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBStatsCollector(t *testing.T) {
+	c := newDBStatsCollector(map[string]db.KVDB{
+		mainDBName: &fixedStatsDB{
+			Memory: db.NewMemoryDB(),
+			stats:  map[string]float64{"estimate_num_keys": 8, "block_cache_usage": 1024},
+		},
+		coldDBName: db.NewMemoryDB(), // reports no stats
+	})
+
+	descs := make(chan *prom.Desc, 4)
+	c.Describe(descs)
+	close(descs)
+	assert.Len(t, descs, 1)
+
+	metrics := make(chan prom.Metric, 4)
+	c.Collect(metrics)
+	close(metrics)
+	assert.Len(t, metrics, 2)
+
+	registry := prom.NewRegistry()
+	assert.Nil(t, registry.Register(c))
+	mfs, err := registry.Gather()
+	assert.Nil(t, err)
+	assert.Len(t, mfs, 1)
+	assert.Len(t, mfs[0].Metric, 2)
+}
+
+type fixedStatsDB struct {
+	*db.Memory
+	stats map[string]float64
+}
+
+func (f *fixedStatsDB) Stats() map[string]float64 {
+	return f.stats
+}
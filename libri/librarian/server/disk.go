@@ -0,0 +1,83 @@
+package server
+
+import (
+	"github.com/drausin/libri/libri/common/db"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	mainDBName = "main"
+	coldDBName = "cold"
+
+	dbNameLabel = "db"
+)
+
+// diskMetrics reports DBs becoming read-only after disk-full or IO errors.
+type diskMetrics struct {
+	readOnly      *prom.GaugeVec
+	readOnlyCount *prom.CounterVec
+}
+
+func newDiskMetrics() *diskMetrics {
+	return &diskMetrics{
+		readOnly: prom.NewGaugeVec(
+			prom.GaugeOpts{
+				Namespace: "libri",
+				Subsystem: "storage",
+				Name:      "read_only",
+				Help:      "Whether the DB is read-only after a disk write error (1) or not (0).",
+			},
+			[]string{dbNameLabel},
+		),
+		readOnlyCount: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: "libri",
+				Subsystem: "storage",
+				Name:      "read_only_total",
+				Help:      "Number of times the DB became read-only after a disk write error.",
+			},
+			[]string{dbNameLabel},
+		),
+	}
+}
+
+// guard wraps the named DB so it becomes read-only after disk-full or IO errors, alerting on and
+// reporting when it does.
+func (dm *diskMetrics) guard(kvdb db.KVDB, name string, logger *zap.Logger) db.GuardedDB {
+	dm.readOnly.WithLabelValues(name).Set(0)
+	obs := &diskObserver{
+		name:    name,
+		metrics: dm,
+		logger:  logger.With(zap.String(logDBName, name)),
+	}
+	return db.NewGuardedDB(kvdb, db.DefaultWriteRetryInterval, obs)
+}
+
+func (dm *diskMetrics) register() {
+	prom.MustRegister(dm.readOnly)
+	prom.MustRegister(dm.readOnlyCount)
+}
+
+func (dm *diskMetrics) unregister() {
+	_ = prom.Unregister(dm.readOnly)
+	_ = prom.Unregister(dm.readOnlyCount)
+}
+
+type diskObserver struct {
+	name    string
+	metrics *diskMetrics
+	logger  *zap.Logger
+}
+
+func (o *diskObserver) ReadOnly(err error) {
+	o.metrics.readOnly.WithLabelValues(o.name).Set(1)
+	o.metrics.readOnlyCount.WithLabelValues(o.name).Inc()
+	o.logger.Error("DB read-only after disk write error, rejecting writes until retry succeeds",
+		zap.Error(err))
+}
+
+func (o *diskObserver) Writable() {
+	o.metrics.readOnly.WithLabelValues(o.name).Set(0)
+	o.logger.Info("DB writable again after retried write succeeded")
+}
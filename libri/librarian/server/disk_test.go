@@ -0,0 +1,47 @@
+package server
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/db"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDiskMetrics(t *testing.T) {
+	dm := newDiskMetrics()
+	dm.register()
+	defer dm.unregister()
+	g := dm.guard(&diskFullDB{Memory: db.NewMemoryDB()}, mainDBName, zap.NewNop())
+	assert.Equal(t, float64(0), readOnlyValue(t, dm))
+
+	assert.Equal(t, db.ErrReadOnly, g.Put([]byte("key"), []byte("value")))
+	assert.True(t, g.ReadOnly())
+	assert.Equal(t, float64(1), readOnlyValue(t, dm))
+
+	written := &dto.Metric{}
+	assert.Nil(t, dm.readOnlyCount.WithLabelValues(mainDBName).Write(written))
+	assert.Equal(t, float64(1), *written.Counter.Value)
+
+	// deletes still go through and make DB writable again
+	assert.Nil(t, g.Delete([]byte("key")))
+	assert.False(t, g.ReadOnly())
+	assert.Equal(t, float64(0), readOnlyValue(t, dm))
+}
+
+func readOnlyValue(t *testing.T, dm *diskMetrics) float64 {
+	written := &dto.Metric{}
+	assert.Nil(t, dm.readOnly.WithLabelValues(mainDBName).Write(written))
+	return *written.Gauge.Value
+}
+
+// diskFullDB is a Memory KVDB whose disk is too full to put values.
+type diskFullDB struct {
+	*db.Memory
+}
+
+func (d *diskFullDB) Put(key []byte, value []byte) error {
+	return syscall.ENOSPC
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"time"
+
+	"github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/golang-lru"
+)
+
+// recentPutsSize is the number of recently completed Puts remembered for detecting retries.
+const recentPutsSize = 1024
+
+// recentPuts remembers the document MACs of recently completed Puts, so that a client retrying a
+// Put after a slow response gets a quick success instead of the librarian storing it again.
+type recentPuts struct {
+	cache *lru.Cache
+}
+
+type recentPut struct {
+	mac       []byte
+	nReplicas uint32
+}
+
+func newRecentPuts(size int) *recentPuts {
+	cache, err := lru.New(size)
+	errors.MaybePanic(err) // should never happen b/c size is positive
+	return &recentPuts{cache: cache}
+}
+
+// add remembers a completed Put of the document with the given key and MAC.
+func (r *recentPuts) add(key id.ID, mac []byte, nReplicas uint32) {
+	r.cache.Add(key.String(), &recentPut{mac: mac, nReplicas: nReplicas})
+}
+
+// get returns the number of replicas from a recent Put of the document with the given key and
+// MAC and whether there was one.
+func (r *recentPuts) get(key id.ID, mac []byte) (uint32, bool) {
+	value, in := r.cache.Get(key.String())
+	if !in {
+		return 0, false
+	}
+	rp := value.(*recentPut)
+	if !hmac.Equal(rp.mac, mac) {
+		return 0, false
+	}
+	return rp.nReplicas, true
+}
+
+const (
+	// recentStoresSize is the number of recently completed Stores remembered for detecting
+	// retries.
+	recentStoresSize = 1024
+
+	// recentStoresTTL is how long a completed Store is remembered for detecting retries.
+	recentStoresTTL = 1 * time.Minute
+)
+
+// recentStores remembers the responses to recently completed Stores, keyed on the document key
+// and MAC, so that a client retrying a Store gets the original response without the librarian
+// reading or writing the document again.
+type recentStores struct {
+	cache *lru.Cache
+	ttl   time.Duration
+}
+
+type recentStore struct {
+	mac     []byte
+	rp      *api.StoreResponse
+	expires time.Time
+}
+
+func newRecentStores(size int, ttl time.Duration) *recentStores {
+	cache, err := lru.New(size)
+	errors.MaybePanic(err) // should never happen b/c size is positive
+	return &recentStores{cache: cache, ttl: ttl}
+}
+
+// add remembers the response to a completed Store of the document with the given key and MAC.
+func (r *recentStores) add(key id.ID, mac []byte, rp *api.StoreResponse) {
+	r.cache.Add(key.String(), &recentStore{
+		mac:     mac,
+		rp:      rp,
+		expires: time.Now().Add(r.ttl),
+	})
+}
+
+// get returns the response to a recent Store of the document with the given key and MAC and
+// whether there was one.
+func (r *recentStores) get(key id.ID, mac []byte) (*api.StoreResponse, bool) {
+	value, in := r.cache.Get(key.String())
+	if !in {
+		return nil, false
+	}
+	rs := value.(*recentStore)
+	if !time.Now().Before(rs.expires) {
+		r.cache.Remove(key.String())
+		return nil, false
+	}
+	if !hmac.Equal(rs.mac, mac) {
+		return nil, false
+	}
+	return rs.rp, true
+}
+
+// remove forgets any recent Store of the document with the given key, e.g., when a later Store of
+// it fails or it is removed, so a retry doesn't get a response for a document no longer stored.
+func (r *recentStores) remove(key id.ID) {
+	r.cache.Remove(key.String())
+}
+
+// documentHooks returns the DocumentHooks forgetting the recent Stores of removed documents.
+func (r *recentStores) documentHooks() storage.DocumentHooks {
+	return &storage.DocumentHookFuncs{Delete: r.remove}
+}
+
+// documentMAC returns the HMAC-SHA256 of the marshaled document, keyed by the document's key. It
+// matches the MAC storage.DocumentSL gives for the stored document with the same MAC key.
+func documentMAC(key id.ID, value *api.Document) ([]byte, error) {
+	valueBytes, err := proto.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	macer := hmac.New(sha256.New, key.Bytes())
+	_, err = macer.Write(valueBytes)
+	errors.MaybePanic(err) // should never happen b/c sha256.Write always returns nil error
+	return macer.Sum(nil), nil
+}
+
+// alreadyStored returns whether the librarian already stores the document with the given key
+// and MAC.
+func (l *Librarian) alreadyStored(key id.ID, mac []byte) (bool, error) {
+	storedMAC, err := l.documentSL.Mac(key, key.Bytes())
+	if err == storage.ErrCorruptDocument {
+		// corrupt value has been removed, so it needs to be stored again
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return storedMAC != nil && hmac.Equal(storedMAC, mac), nil
+}
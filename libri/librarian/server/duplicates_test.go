@@ -0,0 +1,121 @@
+package server
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentPuts_addGet(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rp := newRecentPuts(2)
+	key1, key2, key3 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	mac1, mac2 := []byte{1, 2, 3}, []byte{4, 5, 6}
+
+	rp.add(key1, mac1, 3)
+	nReplicas, in := rp.get(key1, mac1)
+	assert.True(t, in)
+	assert.Equal(t, uint32(3), nReplicas)
+
+	// different MAC or key isn't a match
+	_, in = rp.get(key1, mac2)
+	assert.False(t, in)
+	_, in = rp.get(key2, mac1)
+	assert.False(t, in)
+
+	// oldest Put is forgotten once full
+	rp.add(key2, mac2, 3)
+	rp.add(key3, mac2, 3)
+	_, in = rp.get(key1, mac1)
+	assert.False(t, in)
+}
+
+func TestRecentStores_addGet(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rs := newRecentStores(2, time.Minute)
+	key1, key2, key3 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	mac1, mac2 := []byte{1, 2, 3}, []byte{4, 5, 6}
+	rp1 := &api.StoreResponse{Receipt: &api.SignedStoreReceipt{Signature: "some.signature"}}
+
+	rs.add(key1, mac1, rp1)
+	rp, in := rs.get(key1, mac1)
+	assert.True(t, in)
+	assert.Equal(t, rp1, rp)
+
+	// different MAC or key isn't a match
+	_, in = rs.get(key1, mac2)
+	assert.False(t, in)
+	_, in = rs.get(key2, mac1)
+	assert.False(t, in)
+
+	// oldest Store is forgotten once full
+	rs.add(key2, mac2, rp1)
+	rs.add(key3, mac2, rp1)
+	_, in = rs.get(key1, mac1)
+	assert.False(t, in)
+
+	// Store is forgotten once expired
+	rs = newRecentStores(2, 0)
+	rs.add(key1, mac1, rp1)
+	_, in = rs.get(key1, mac1)
+	assert.False(t, in)
+	assert.Zero(t, rs.cache.Len())
+
+	// Store is forgotten once removed or its document deleted
+	rs = newRecentStores(2, time.Minute)
+	rs.add(key1, mac1, rp1)
+	rs.add(key2, mac2, rp1)
+	rs.remove(key1)
+	_, in = rs.get(key1, mac1)
+	assert.False(t, in)
+	rs.documentHooks().OnDelete(key2)
+	_, in = rs.get(key2, mac2)
+	assert.False(t, in)
+}
+
+func TestDocumentMAC(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value1, key1 := api.NewTestDocument(rng)
+	value2, key2 := api.NewTestDocument(rng)
+
+	mac1, err := documentMAC(key1, value1)
+	assert.Nil(t, err)
+	mac2, err := documentMAC(key2, value2)
+	assert.Nil(t, err)
+	assert.NotEqual(t, mac1, mac2)
+
+	// matches MAC of stored document
+	sld := storage.NewDocumentSLD(db.NewMemoryDB())
+	assert.Nil(t, sld.Store(key1, value1))
+	storedMAC, err := sld.Mac(key1, key1.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, mac1, storedMAC)
+}
+
+func TestLibrarian_alreadyStored(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := &Librarian{documentSL: storage.NewDocumentSLD(db.NewMemoryDB())}
+	value, key := api.NewTestDocument(rng)
+	mac, err := documentMAC(key, value)
+	assert.Nil(t, err)
+
+	stored, err := l.alreadyStored(key, mac)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+
+	assert.Nil(t, l.documentSL.Store(key, value))
+	stored, err = l.alreadyStored(key, mac)
+	assert.Nil(t, err)
+	assert.True(t, stored)
+
+	// different MAC
+	stored, err = l.alreadyStored(key, []byte{1, 2, 3})
+	assert.Nil(t, err)
+	assert.False(t, stored)
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+// putShards stores the value as erasure-coded data and parity shards, each on the peer closest to
+// its shard key, along with the manifest listing them. The response's NReplicas is the number of
+// shards stored (or already existing), and its receipts are those of the newly stored shards and
+// manifest. The shards and manifest are charged to the given org.
+func (l *Librarian) putShards(
+	ctx context.Context, lg *zap.Logger, rq *api.PutRequest, key id.ID, mac []byte, orgID id.ID,
+) (*api.PutResponse, error) {
+	expires := putExpireTime(rq)
+	nStored, nExisting, receipts, err := l.storeShards(ctx, key, rq.Value, expires, orgID)
+	if interrupted(err) {
+		return nil, logReturnCanceledErr(lg, err)
+	} else if err == store.ErrInvalidShardCounts {
+		return nil, logReturnInternalErr(lg, "error sharding value", err)
+	} else if err != nil {
+		return nil, logReturnUnavailErr(lg, "shard store failed", err)
+	}
+	rp := &api.PutResponse{
+		Metadata:  l.NewResponseMetadata(rq.Metadata),
+		Operation: api.PutOperation_STORED,
+		NReplicas: nStored + nExisting,
+	}
+	if nStored == 0 {
+		rp.Operation = api.PutOperation_LEFT_EXISTING
+	} else {
+		rp.Receipts = receipts
+	}
+	l.forgetNotFound(key)
+	l.recentPuts.add(key, mac, rp.NReplicas)
+	lg.Info("put erasure-coded value", putResponseFields(rq, rp)...)
+	return rp, nil
+}
+
+// storeShards concurrently stores each shard of the value, returning the number newly stored and
+// already existing, and then the manifest listing them, so a manifest is only found once its shards
+// are stored. It also returns the receipts from the peers storing the shards and manifest. All
+// shards must be stored for the value to have its full redundancy, so any failed shard store
+// results in an error. Shards expire at the given time unless it is zero and are charged to the
+// given org.
+func (l *Librarian) storeShards(
+	ctx context.Context, key id.ID, value *api.Document, expires time.Time, orgID id.ID,
+) (uint32, uint32, []*api.SignedStoreReceipt, error) {
+	stores, manifest, err := store.NewShardStores(l.peerID, l.orgID, key, value,
+		l.config.Search, l.config.Store)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	for _, s := range append(stores, manifest) {
+		if !expires.IsZero() {
+			s.ExpireAt(expires)
+		}
+		s.ChargeOrg(orgID)
+	}
+	nStored, nExisting, receipts, err := l.storeAll(ctx, stores)
+	if err != nil {
+		manifest.Release()
+		return 0, 0, nil, err
+	}
+	_, _, manifestReceipts, err := l.storeAll(ctx, []*store.Store{manifest})
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return nStored, nExisting, append(receipts, manifestReceipts...), nil
+}
+
+// storeAll concurrently runs and releases the stores, returning the number newly stored and
+// already existing along with the receipts of the newly stored. Any failed store results in an
+// error.
+func (l *Librarian) storeAll(ctx context.Context, stores []*store.Store) (
+	uint32, uint32, []*api.SignedStoreReceipt, error) {
+	var nStored, nExisting uint32
+	receipts := make([]*api.SignedStoreReceipt, 0, len(stores))
+	var storeErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, s := range stores {
+		wg.Add(1)
+		go func(s *store.Store) {
+			defer wg.Done()
+			defer s.Release()
+			seeds := l.rt.Find(s.Search.Key, s.Search.Params.NClosestResponses)
+			err := l.storer.Store(ctx, s, seeds)
+			if err == nil {
+				for _, p := range s.Result.Responded {
+					l.rt.Push(p)
+				}
+				l.forgetNotFound(s.Search.Key)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil && s.Stored() {
+				nStored++
+				receipts = append(receipts, s.Result.Receipts...)
+				return
+			}
+			if err == nil && s.Exists() {
+				nExisting++
+				return
+			}
+			if err == nil {
+				if err = s.Result.FatalErr; err == nil {
+					err = errStoreUnexpectedResult
+				}
+			}
+			if storeErr == nil || interrupted(err) {
+				// cancellations take precedence, since they make other errors moot
+				storeErr = err
+			}
+		}(s)
+	}
+	wg.Wait()
+	return nStored, nExisting, receipts, storeErr
+}
+
+// getShards searches for the shard manifest of the value with the given key using the manifest
+// search and, if it is found, the erasure-coded shards it lists in a single batch, reconstructing
+// the value from them. It returns a nil value if the value isn't sharded and store.ErrTooFewShards
+// if too few shards are found. The caller releases the manifest search.
+func (l *Librarian) getShards(
+	ctx context.Context, key id.ID, manifestSearch *search.Search,
+) (*api.Document, error) {
+	manifest, err := l.getShardManifest(ctx, manifestSearch)
+	if err != nil || manifest == nil {
+		return nil, err
+	}
+	shardKeys := make([]id.ID, len(manifest.ShardKeys))
+	for i, shardKey := range manifest.ShardKeys {
+		shardKeys[i] = id.FromBytes(shardKey)
+	}
+	batch := search.NewBatchSearch(l.peerID, l.orgID, shardKeys, l.config.Search,
+		search.DefaultMaxBatchQueries)
+	defer batch.Release()
+	seeds := l.shardSeeds(shardKeys, l.config.Search.NClosestResponses)
+	errs := search.NewBatchSearcher(l.searcher).SearchBatch(ctx, batch, seeds)
+	shards := make([]*api.Shard, len(shardKeys))
+	for i, s := range batch.Searches {
+		if interrupted(errs[i]) {
+			return nil, errs[i]
+		}
+		if errs[i] != nil {
+			continue
+		}
+		for _, p := range s.Result.Responded {
+			l.rt.Push(p)
+		}
+		if s.FoundValue() {
+			shards[i] = s.Result.Value.GetShard()
+		}
+	}
+	return store.JoinShards(key, manifest, shards)
+}
+
+// newShardManifestSearch returns a new search for the shard manifest of the value with the given
+// key.
+func (l *Librarian) newShardManifestSearch(key id.ID) *search.Search {
+	manifestKey := api.GetShardManifestKey(key.Bytes())
+	return search.NewSearch(l.peerID, l.orgID, manifestKey, l.config.Search)
+}
+
+// getShardManifest runs the shard manifest search, returning nil if the value isn't sharded.
+func (l *Librarian) getShardManifest(
+	ctx context.Context, s *search.Search,
+) (*api.ShardManifest, error) {
+	seeds := l.rt.Find(s.Key, s.Params.NClosestResponses)
+	if err := l.searcher.Search(ctx, s, seeds); err != nil {
+		return nil, err
+	}
+	for _, p := range s.Result.Responded {
+		l.rt.Push(p)
+	}
+	if !s.FoundValue() {
+		return nil, nil
+	}
+	manifest := s.Result.Value.GetShardManifest()
+	if err := api.ValidateShardManifest(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// shardSeeds returns the union of the n closest peers in the routing table to each shard key, so
+// the shard searches can share one set of seeds.
+func (l *Librarian) shardSeeds(shardKeys []id.ID, n uint) []peer.Peer {
+	seeds := make([]peer.Peer, 0, n)
+	seen := make(map[string]struct{})
+	for _, shardKey := range shardKeys {
+		for _, p := range l.rt.Find(shardKey, n) {
+			if _, in := seen[p.ID().String()]; !in {
+				seen[p.ID().String()] = struct{}{}
+				seeds = append(seeds, p)
+			}
+		}
+	}
+	return seeds
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestLibrarian_PutGet_erasureCoded(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	storer := &shardStorer{stored: make(map[string]*api.Document)}
+	l := newPutLibrarian(rng, nil, nil)
+	l.storer = storer
+	setErasureParams(l, 3, 2)
+
+	rq := client.NewPutRequest(peerID, orgID, key, value)
+	rp, err := l.Put(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, api.PutOperation_STORED, rp.Operation)
+	assert.Equal(t, uint32(5), rp.NReplicas)
+	assert.Len(t, storer.stored, 6) // 5 shards + 1 manifest
+
+	// one receipt per shard and one per manifest replica
+	assert.Len(t, rp.Receipts, 5+int(l.config.Store.NReplicas))
+	manifest := storer.stored[api.GetShardManifestKey(key.Bytes()).String()].GetShardManifest()
+	assert.NotNil(t, manifest)
+	shardKey := func(i int) string { return id.FromBytes(manifest.ShardKeys[i]).String() }
+
+	// all shards existing leaves them
+	l = newPutLibrarian(rng, nil, nil)
+	l.storer = &shardStorer{stored: storer.stored, exists: true}
+	setErasureParams(l, 3, 2)
+	rp, err = l.Put(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, api.PutOperation_LEFT_EXISTING, rp.Operation)
+	assert.Equal(t, uint32(5), rp.NReplicas)
+	assert.Empty(t, rp.Receipts)
+
+	// value reconstructed with up to nParity shards missing
+	delete(storer.stored, shardKey(0))
+	delete(storer.stored, shardKey(3))
+	l = newGetLibrarian(rng, nil, nil)
+	l.searcher = &shardSearcher{found: storer.stored}
+	setErasureParams(l, 3, 2)
+	getRq := client.NewGetRequest(peerID, orgID, key)
+	getRp, err := l.Get(context.Background(), getRq)
+	assert.Nil(t, err)
+	assert.Equal(t, value, getRp.Value)
+
+	// too few shards returns closest peers
+	delete(storer.stored, shardKey(4))
+	getRp, err = l.Get(context.Background(), getRq)
+	assert.Nil(t, err)
+	assert.Nil(t, getRp.Value)
+}
+
+func TestLibrarian_Put_erasureCodedErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	rq := client.NewPutRequest(peerID, orgID, key, value)
+
+	cases := map[error]codes.Code{
+		errors.New("some store error"): codes.Unavailable,
+		store.ErrStoreCanceled:         codes.Canceled,
+		search.ErrSearchCanceled:       codes.Canceled,
+		context.DeadlineExceeded:       codes.DeadlineExceeded,
+	}
+	for storeErr, code := range cases {
+		l := newPutLibrarian(rng, nil, nil)
+		l.storer = &shardStorer{stored: make(map[string]*api.Document), err: storeErr}
+		setErasureParams(l, 3, 2)
+		rp, err := l.Put(context.Background(), rq)
+		assert.Nil(t, rp)
+		assert.Equal(t, code, getErrCode(t, err))
+	}
+
+	// too many shards
+	l := newPutLibrarian(rng, nil, nil)
+	setErasureParams(l, api.MaxShards, 1)
+	rp, err := l.Put(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.Internal, getErrCode(t, err))
+}
+
+func TestLibrarian_Get_erasureCodedErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	_, key := api.NewTestDocument(rng)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+
+	l := newGetLibrarian(rng, nil, nil)
+	l.searcher = &shardSearcher{shardErr: search.ErrSearchCanceled}
+	setErasureParams(l, 3, 2)
+	rp, err := l.Get(context.Background(), client.NewGetRequest(peerID, orgID, key))
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.Canceled, getErrCode(t, err))
+}
+
+func TestLibrarian_Get_notSharded(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	_, key := api.NewTestDocument(rng)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+
+	// without a manifest, no shards are searched for, even when erasure coding is disabled
+	l := newGetLibrarian(rng, nil, nil)
+	searcher := &shardSearcher{}
+	l.searcher = searcher
+	rp, err := l.Get(context.Background(), client.NewGetRequest(peerID, orgID, key))
+	assert.Nil(t, err)
+	assert.Nil(t, rp.Value)
+	assert.Len(t, searcher.searched, 2)
+	for _, searched := range searcher.searched {
+		assert.False(t, api.IsShardKey(searched))
+	}
+}
+
+func TestLibrarian_Get_bogusShards(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	storer := &shardStorer{stored: make(map[string]*api.Document)}
+	l := newPutLibrarian(rng, nil, nil)
+	l.storer = storer
+	setErasureParams(l, 3, 2)
+	_, err := l.Put(context.Background(), client.NewPutRequest(peerID, orgID, key, value))
+	assert.Nil(t, err)
+
+	// shards not matching the manifest aren't used to reconstruct the value
+	manifest := storer.stored[api.GetShardManifestKey(key.Bytes()).String()].GetShardManifest()
+	for _, shardKey := range manifest.ShardKeys[:3] {
+		shard := storer.stored[id.FromBytes(shardKey).String()].GetShard()
+		shard.Data = api.RandBytes(rng, len(shard.Data))
+	}
+	l = newGetLibrarian(rng, nil, nil)
+	l.searcher = &shardSearcher{found: storer.stored}
+	rp, err := l.Get(context.Background(), client.NewGetRequest(peerID, orgID, key))
+	assert.Nil(t, err)
+	assert.Nil(t, rp.Value)
+}
+
+func setErasureParams(l *Librarian, nData, nParity uint) {
+	l.config.Store.NDataShards = nData
+	l.config.Store.NParityShards = nParity
+	l.config.Store.MinErasureSize = 0
+}
+
+// shardStorer stores each shard (or manifest) on as many peers as its store needs replicas (or
+// finds it already exists), returning a receipt from each, unless it has an error.
+type shardStorer struct {
+	stored map[string]*api.Document
+	exists bool
+	err    error
+	mu     sync.Mutex
+}
+
+func (f *shardStorer) Store(ctx context.Context, s *store.Store, seeds []peer.Peer) error {
+	if f.err != nil {
+		return f.err
+	}
+	rq := s.CreateRq()
+	sr := search.NewInitialResult(s.Search.Key, s.Search.Params)
+	if f.exists {
+		sr.Value = rq.Value
+	}
+	s.Result = store.NewInitialResult(sr)
+	if !f.exists {
+		nReplicas := int(s.Params.NReplicas)
+		s.Result.Responded = peer.NewTestPeers(rand.New(rand.NewSource(0)), nReplicas)
+		for c := 0; c < nReplicas; c++ {
+			s.Result.Receipts = append(s.Result.Receipts,
+				&api.SignedStoreReceipt{Receipt: &api.StoreReceipt{Key: s.Search.Key.Bytes()}})
+		}
+		f.mu.Lock()
+		f.stored[s.Search.Key.String()] = rq.Value
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// shardSearcher finds the shards and manifests it has and the closest peers otherwise.
+type shardSearcher struct {
+	found    map[string]*api.Document
+	shardErr error
+	searched []id.ID
+	mu       sync.Mutex
+}
+
+func (f *shardSearcher) Search(ctx context.Context, s *search.Search, seeds []peer.Peer) error {
+	f.mu.Lock()
+	f.searched = append(f.searched, s.Key)
+	f.mu.Unlock()
+	if f.shardErr != nil && (api.IsShardKey(s.Key) || api.IsShardManifestKey(s.Key)) {
+		return f.shardErr
+	}
+	s.Result = search.NewInitialResult(s.Key, s.Params)
+	if value, in := f.found[s.Key.String()]; in {
+		s.Result.Value = value
+		return nil
+	}
+	rng := rand.New(rand.NewSource(0))
+	s.Result.Closest.SafePushMany(peer.NewTestPeers(rng, int(s.Params.NClosestResponses)))
+	return nil
+}
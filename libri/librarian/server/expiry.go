@@ -0,0 +1,103 @@
+package server
+
+import (
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultExpirySweepInterval is the default interval between runs of the expiry sweeper.
+	DefaultExpirySweepInterval = 1 * time.Minute
+
+	// DefaultExpiryMaxSweeps is the default maximum number of expired documents deleted per run
+	// of the expiry sweeper.
+	DefaultExpiryMaxSweeps = uint(1024)
+)
+
+// ExpiryParameters define how documents stored with a TTL are deleted once they expire. Expired
+// documents are treated as missing even before they are swept.
+type ExpiryParameters struct {
+	// SweepInterval is the interval between runs of the sweeper deleting expired documents.
+	SweepInterval time.Duration
+
+	// MaxSweeps is the maximum number of expired documents deleted per run of the sweeper.
+	MaxSweeps uint
+}
+
+// NewDefaultExpiryParameters returns a *ExpiryParameters object with default values.
+func NewDefaultExpiryParameters() *ExpiryParameters {
+	return &ExpiryParameters{
+		SweepInterval: DefaultExpirySweepInterval,
+		MaxSweeps:     DefaultExpiryMaxSweeps,
+	}
+}
+
+// checkExpireTime verifies that a Store request's expire time, if it has one, hasn't already
+// passed.
+func checkExpireTime(expireTime int64, now time.Time) error {
+	if expireTime != 0 && !time.Unix(expireTime, 0).After(now) {
+		return errAlreadyExpired
+	}
+	return nil
+}
+
+// putExpireTime returns the time at which a Put request's value expires, or the zero time if it
+// doesn't.
+func putExpireTime(rq *api.PutRequest) time.Time {
+	if rq.TtlSeconds == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(rq.TtlSeconds) * time.Second)
+}
+
+// storeDocument stores the document, expiring it at the given (Unix second) expire time unless it
+// is zero.
+func (l *Librarian) storeDocument(key id.ID, value *api.Document, expireTime int64) error {
+	if expireTime == 0 {
+		return l.documentSL.Store(key, value)
+	}
+	return l.expiring.StoreExpiring(key, value, time.Unix(expireTime, 0))
+}
+
+// reconcileExpireTime makes the already stored document expire at the Store request's (Unix
+// second) expire time, or never if it is zero, so the latest Store of a document decides its TTL.
+func (l *Librarian) reconcileExpireTime(key id.ID, expireTime int64) error {
+	expires, in, err := l.expiring.ExpireTime(key)
+	if err != nil {
+		return err
+	}
+	if (expireTime == 0 && !in) || (in && expires.Unix() == expireTime) {
+		return nil
+	}
+	if expireTime == 0 {
+		return l.expiring.SetExpireTime(key, time.Time{})
+	}
+	return l.expiring.SetExpireTime(key, time.Unix(expireTime, 0))
+}
+
+// sweepExpired periodically deletes expired documents until the server stops.
+func (l *Librarian) sweepExpired() {
+	ticker := time.NewTicker(l.config.Expiry.SweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.sweepOnce()
+		}
+	}
+}
+
+func (l *Librarian) sweepOnce() {
+	nSwept, err := l.expiring.Sweep(time.Now(), l.config.Expiry.MaxSweeps)
+	if err != nil {
+		l.logger.Error("error sweeping expired documents", zap.Error(err),
+			zap.Uint(logNSwept, nSwept))
+		return
+	}
+	l.logger.Debug("swept expired documents", zap.Uint(logNSwept, nSwept))
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCheckExpireTime(t *testing.T) {
+	now := time.Now()
+	assert.Nil(t, checkExpireTime(0, now))
+	assert.Nil(t, checkExpireTime(now.Add(time.Minute).Unix(), now))
+	assert.Equal(t, errAlreadyExpired, checkExpireTime(now.Add(-time.Minute).Unix(), now))
+}
+
+func TestPutExpireTime(t *testing.T) {
+	assert.True(t, putExpireTime(&api.PutRequest{}).IsZero())
+
+	expires := putExpireTime(&api.PutRequest{TtlSeconds: 60})
+	assert.InDelta(t, time.Now().Add(time.Minute).Unix(), expires.Unix(), 1)
+}
+
+func TestLibrarian_storeDocument_sweepOnce(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	expiring := storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb)
+	l := &Librarian{
+		config:     NewDefaultConfig(),
+		documentSL: expiring,
+		expiring:   expiring,
+		logger:     zap.NewNop(),
+	}
+	value1, key1 := api.NewTestDocument(rng)
+	value2, key2 := api.NewTestDocument(rng)
+	assert.Nil(t, l.storeDocument(key1, value1, 0))
+	assert.Nil(t, l.storeDocument(key2, value2, time.Now().Add(-time.Second).Unix()))
+
+	// expired document is missing even before being swept
+	loaded, err := l.documentSL.Load(key2)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+
+	l.sweepOnce()
+	_, in, err := expiring.ExpireTime(key2)
+	assert.Nil(t, err)
+	assert.False(t, in)
+	loaded, err = l.documentSL.Load(key1)
+	assert.Nil(t, err)
+	assert.Equal(t, value1, loaded)
+}
+
+func TestLibrarian_reconcileExpireTime(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	expiring := storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb)
+	l := &Librarian{expiring: expiring}
+	value, key := api.NewTestDocument(rng)
+	assert.Nil(t, l.expiring.Store(key, value))
+
+	// TTL'd Store of permanent document makes it expire
+	expireTime := time.Now().Add(time.Hour).Unix()
+	assert.Nil(t, l.reconcileExpireTime(key, expireTime))
+	expires, in, err := expiring.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.True(t, in)
+	assert.Equal(t, expireTime, expires.Unix())
+
+	// same expire time leaves it
+	assert.Nil(t, l.reconcileExpireTime(key, expireTime))
+	expires, in, err = expiring.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.True(t, in)
+	assert.Equal(t, expireTime, expires.Unix())
+
+	// permanent Store of TTL'd document makes it permanent
+	assert.Nil(t, l.reconcileExpireTime(key, 0))
+	_, in, err = expiring.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.False(t, in)
+}
+
+func TestLibrarian_sweepExpired(t *testing.T) {
+	kvdb := db.NewMemoryDB()
+	config := NewDefaultConfig()
+	config.Expiry.SweepInterval = time.Millisecond
+	l := &Librarian{
+		config:   config,
+		expiring: storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb),
+		logger:   zap.NewNop(),
+		stop:     make(chan struct{}),
+	}
+	done := make(chan struct{})
+	go func() {
+		l.sweepExpired()
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(l.stop)
+	<-done
+}
@@ -0,0 +1,433 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultHandoffInterval is the default interval between attempts to hand off hinted
+	// documents to their intended holders.
+	DefaultHandoffInterval = 1 * time.Minute
+
+	// DefaultHandoffMaxHints is the default maximum number of hinted documents handed off per
+	// attempt.
+	DefaultHandoffMaxHints = uint(256)
+
+	// DefaultHandoffMaxAge is the default age after which hints are dropped if their intended
+	// holders haven't returned.
+	DefaultHandoffMaxAge = 24 * time.Hour
+
+	// DefaultHandoffTimeout is the default timeout for each Store request handing off a
+	// document.
+	DefaultHandoffTimeout = 5 * time.Second
+
+	// DefaultHandoffMaxStoredHints is the default maximum number of hints stored.
+	DefaultHandoffMaxStoredHints = uint(1 << 16)
+
+	// DefaultHandoffMaxRequesterHints is the default maximum number of hints stored from the
+	// Store requests of a single peer.
+	DefaultHandoffMaxRequesterHints = uint(1024)
+)
+
+var (
+	errInvalidHint = errors.New("hint must be a peer other than self")
+
+	errTooManyHints = errors.New("too many hints stored")
+)
+
+// HandoffParameters define how a librarian hands off the documents it stored on behalf of
+// unreachable peers (with a hint) to those peers when they return.
+type HandoffParameters struct {
+	// Interval is the interval between attempts to hand off hinted documents. Handoff is
+	// disabled when zero.
+	Interval time.Duration
+
+	// MaxHints is the maximum number of hinted documents handed off per attempt.
+	MaxHints uint
+
+	// MaxAge is the age after which hints are dropped if their intended holders haven't
+	// returned, leaving re-replication of the documents to anti-entropy and repair.
+	MaxAge time.Duration
+
+	// Timeout is the timeout for each Store request handing off a document.
+	Timeout time.Duration
+
+	// MaxStoredHints is the maximum number of hints stored.
+	MaxStoredHints uint
+
+	// MaxRequesterHints is the maximum number of hints stored from the Store requests of a
+	// single peer.
+	MaxRequesterHints uint
+}
+
+// NewDefaultHandoffParameters returns a *HandoffParameters object with default values.
+func NewDefaultHandoffParameters() *HandoffParameters {
+	return &HandoffParameters{
+		Interval:          DefaultHandoffInterval,
+		MaxHints:          DefaultHandoffMaxHints,
+		MaxAge:            DefaultHandoffMaxAge,
+		Timeout:           DefaultHandoffTimeout,
+		MaxStoredHints:    DefaultHandoffMaxStoredHints,
+		MaxRequesterHints: DefaultHandoffMaxRequesterHints,
+	}
+}
+
+// Enabled returns whether the librarian hands off hinted documents.
+func (p *HandoffParameters) Enabled() bool {
+	return p.Interval > 0
+}
+
+// checkHint checks that the Store request's hint, if any, is for a peer other than self.
+func checkHint(hint *api.PeerAddress, selfID id.ID) error {
+	if hint == nil {
+		return nil
+	}
+	if len(hint.PeerId) != id.Length || bytes.Equal(hint.PeerId, selfID.Bytes()) ||
+		net.ParseIP(hint.Ip) == nil {
+		return errInvalidHint
+	}
+	return nil
+}
+
+// addHint records that the document with the given key is stored on behalf of the hinted peer,
+// if any. Hints for peers not in the routing table among those closest to the key are ignored,
+// and hand-offs go to the address in the routing table rather than the one in the hint, so
+// requesters can't direct them to arbitrary addresses. It returns errTooManyHints if the hint
+// would exceed the number stored in total or from the requester.
+func (l *Librarian) addHint(key, requesterID id.ID, hint *api.PeerAddress) error {
+	if hint == nil {
+		return nil
+	}
+	holderID := id.FromBytes(hint.PeerId)
+	for _, p := range l.rt.Find(key, l.config.Store.NReplicas) {
+		if p.ID().Cmp(holderID) == 0 {
+			return l.hints.add(key, requesterID, p)
+		}
+	}
+	l.logger.Debug("ignoring hint for peer not among those closest to key",
+		zap.Stringer(logKey, key),
+		zap.Stringer(logPeerID, holderID),
+	)
+	return nil
+}
+
+// handOffHints periodically hands off hinted documents to their intended holders until the
+// server stops.
+func (l *Librarian) handOffHints() {
+	if !l.config.Handoff.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(l.config.Handoff.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.handOffHintsOnce()
+		}
+	}
+}
+
+func (l *Librarian) handOffHintsOnce() {
+	hs, err := l.hints.list(l.config.Handoff.MaxHints)
+	if err != nil {
+		l.logger.Error("error listing hints", zap.Error(err))
+		return
+	}
+	nHandedOff, nDropped := 0, 0
+	for _, h := range hs {
+		handedOff, dropped, err := l.handOff(h)
+		if err != nil {
+			l.logger.Error("error handing off hinted document",
+				zap.Stringer(logKey, h.key),
+				zap.Stringer(logPeerID, h.holder.ID()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if handedOff {
+			nHandedOff++
+		}
+		if dropped {
+			nDropped++
+		}
+	}
+	if len(hs) > 0 {
+		l.logger.Info("handed off hinted documents",
+			zap.Int(logNHints, len(hs)),
+			zap.Int(logNHandedOff, nHandedOff),
+			zap.Int(logNDropped, nDropped),
+		)
+	}
+}
+
+// handOff stores the hinted document with its intended holder, removing the hint if it succeeds
+// or the hint is too old or for a document no longer stored. It returns whether the document was
+// handed off and whether the hint was instead dropped. The hinted copy is kept, since it's an
+// extra replica the usual replication and expiration manage like any other.
+func (l *Librarian) handOff(h *hint) (bool, bool, error) {
+	if time.Since(h.created()) > l.config.Handoff.MaxAge {
+		return false, true, l.hints.remove(h)
+	}
+	value, err := l.documentSL.Load(h.key)
+	if err != nil {
+		return false, false, err
+	}
+	if value == nil {
+		// e.g., the document has since expired
+		return false, true, l.hints.remove(h)
+	}
+	rq := client.NewStoreRequest(l.peerID, l.orgID, h.key, value)
+	expires, hasExpiry, err := l.expiring.ExpireTime(h.key)
+	if err != nil {
+		return false, false, err
+	}
+	if hasExpiry {
+		rq.ExpireTime = expires.Unix()
+	}
+	err = peer.QueryAddresses(h.holder, func(address *net.TCPAddr) error {
+		return l.storeTo(address, rq, l.config.Handoff.Timeout)
+	})
+	if err != nil {
+		// holder still unreachable, so try again once the hint is due again
+		comm.MaybeRecordRpErr(l.rec, h.holder.ID(), api.Store, err)
+		return false, false, l.hints.recordAttempt(h)
+	}
+	l.record(h.holder.ID(), api.Store, comm.Response, comm.Success)
+	return true, false, l.hints.remove(h)
+}
+
+// storeTo sends the Store request to the peer at the given address.
+func (l *Librarian) storeTo(
+	address *net.TCPAddr, rq *api.StoreRequest, timeout time.Duration,
+) error {
+	lc, err := l.clients.Get(address.String())
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := client.NewSignedTimeoutContext(l.signer, l.orgSigner, rq, timeout)
+	if err != nil {
+		return err
+	}
+	ctx = client.AddStoreAuthContext(ctx, l.config.Store.AuthToken)
+	ctx = client.AddMacaroonContext(ctx, l.config.Store.Macaroon)
+	rp, err := lc.Store(ctx, rq)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rp.Metadata.RequestId, rq.Metadata.RequestId) {
+		return client.ErrUnexpectedRequestID
+	}
+	return nil
+}
+
+// hint is a document stored on behalf of its unreachable intended holder.
+type hint struct {
+	key    id.ID
+	holder peer.Peer
+	stored *sstorage.Hint
+}
+
+func (h *hint) created() time.Time {
+	return time.Unix(0, h.stored.CreatedNanos)
+}
+
+// due returns whether the hinted document should be handed off now, i.e., if the hint hasn't
+// been attempted within its number of failed attempts times the interval or is older than the max
+// age. Backing off like this keeps hints whose holders are gone from crowding out newer ones.
+func (h *hint) due(now time.Time, params *HandoffParameters) bool {
+	if now.Sub(h.created()) > params.MaxAge {
+		return true
+	}
+	backoff := time.Duration(h.stored.NAttempts) * params.Interval
+	return now.Sub(time.Unix(0, h.stored.LastAttemptNanos)) >= backoff
+}
+
+// hintStore persists hints, keyed by document key followed by holder ID, limiting the number
+// stored in total and from each requester.
+type hintStore struct {
+	sld    cstorage.StorerLoaderDeleter
+	params *HandoffParameters
+	now    func() time.Time
+
+	// number of hints stored in total and from each requester
+	nHints          uint
+	nRequesterHints map[string]uint
+	mu              sync.Mutex
+}
+
+func newHintStore(sld cstorage.StorerLoaderDeleter, params *HandoffParameters) *hintStore {
+	return &hintStore{
+		sld:             sld,
+		params:          params,
+		now:             time.Now,
+		nRequesterHints: make(map[string]uint),
+	}
+}
+
+// loadHintStore returns a hintStore with the counts of the hints already stored.
+func loadHintStore(sld cstorage.StorerLoaderDeleter, params *HandoffParameters) (
+	*hintStore, error) {
+	s := newHintStore(sld, params)
+	err := s.iterate(func(h *hint) bool {
+		s.count(h.stored.RequesterId, 1)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// add records that the document with the given key is stored on behalf of the given holder,
+// replacing any previous hint for them. It returns errTooManyHints if a new hint would exceed
+// the number stored in total or from the requester.
+func (s *hintStore) add(key, requesterID id.ID, holder peer.Peer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prevValue, err := s.sld.Load(hintKey(key, holder.ID()))
+	if err != nil {
+		return err
+	}
+	if prevValue == nil && (s.nHints >= s.params.MaxStoredHints ||
+		s.nRequesterHints[string(requesterID.Bytes())] >= s.params.MaxRequesterHints) {
+		return errTooManyHints
+	}
+	h := &hint{
+		key:    key,
+		holder: holder,
+		stored: &sstorage.Hint{
+			Holder:       holder.ToStored(),
+			CreatedNanos: s.now().UnixNano(),
+			RequesterId:  requesterID.Bytes(),
+		},
+	}
+	if err := s.store(h); err != nil {
+		return err
+	}
+	if prevValue != nil {
+		prev := &sstorage.Hint{}
+		if err := proto.Unmarshal(prevValue, prev); err != nil {
+			return err
+		}
+		s.count(prev.RequesterId, -1)
+	}
+	s.count(h.stored.RequesterId, 1)
+	return nil
+}
+
+// list returns up to max hints that are due to be handed off.
+func (s *hintStore) list(max uint) ([]*hint, error) {
+	hs := make([]*hint, 0)
+	now := s.now()
+	err := s.iterate(func(h *hint) bool {
+		if h.due(now, s.params) {
+			hs = append(hs, h)
+		}
+		return uint(len(hs)) < max
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hs, nil
+}
+
+// iterate calls the callback with each stored hint until it returns false.
+func (s *hintStore) iterate(callback func(h *hint) bool) error {
+	var iterErr error
+	done := make(chan struct{})
+	lb := bytes.Repeat([]byte{0}, cstorage.HintKeyLength)
+	ub := bytes.Repeat([]byte{255}, cstorage.HintKeyLength)
+	err := s.sld.Iterate(lb, ub, done, func(key, value []byte) {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		stored := &sstorage.Hint{}
+		if err := proto.Unmarshal(value, stored); err != nil {
+			iterErr = err
+			close(done)
+			return
+		}
+		h := &hint{
+			key:    id.FromBytes(key[:id.Length]),
+			holder: peer.FromStored(stored.Holder),
+			stored: stored,
+		}
+		if !callback(h) {
+			close(done)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return iterErr
+}
+
+// recordAttempt records a failed attempt to hand off the hinted document.
+func (s *hintStore) recordAttempt(h *hint) error {
+	h.stored.NAttempts++
+	h.stored.LastAttemptNanos = s.now().UnixNano()
+	return s.store(h)
+}
+
+// remove deletes the hint.
+func (s *hintStore) remove(h *hint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hk := hintKey(h.key, h.holder.ID())
+	value, err := s.sld.Load(hk)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		// already removed
+		return nil
+	}
+	if err := s.sld.Delete(hk); err != nil {
+		return err
+	}
+	s.count(h.stored.RequesterId, -1)
+	return nil
+}
+
+func (s *hintStore) store(h *hint) error {
+	value, err := proto.Marshal(h.stored)
+	cerrors.MaybePanic(err) // should never happen
+	return s.sld.Store(hintKey(h.key, h.holder.ID()), value)
+}
+
+// count adds delta to the number of hints stored in total and from the requester.
+func (s *hintStore) count(requesterID []byte, delta int) {
+	s.nHints = uint(int(s.nHints) + delta)
+	n := uint(int(s.nRequesterHints[string(requesterID)]) + delta)
+	if n == 0 {
+		delete(s.nRequesterHints, string(requesterID))
+		return
+	}
+	s.nRequesterHints[string(requesterID)] = n
+}
+
+func hintKey(key, holderID id.ID) []byte {
+	hk := make([]byte, 0, cstorage.HintKeyLength)
+	hk = append(hk, key.Bytes()...)
+	return append(hk, holderID.Bytes()...)
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestHandoffParameters_Enabled(t *testing.T) {
+	assert.True(t, NewDefaultHandoffParameters().Enabled())
+	assert.False(t, (&HandoffParameters{}).Enabled())
+}
+
+func TestCheckHint(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	selfID := id.NewPseudoRandom(rng)
+	addr := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 20100}
+
+	assert.Nil(t, checkHint(nil, selfID))
+	assert.Nil(t, checkHint(peer.FromAddress(id.NewPseudoRandom(rng), "", addr), selfID))
+
+	invalid := []*api.PeerAddress{
+		peer.FromAddress(selfID, "", addr),
+		{PeerId: []byte{1, 2, 3}, Ip: "192.168.1.1", Port: 20100},
+		{PeerId: id.NewPseudoRandom(rng).Bytes(), Ip: "not an IP", Port: 20100},
+	}
+	for _, hint := range invalid {
+		assert.Equal(t, errInvalidHint, checkHint(hint, selfID))
+	}
+}
+
+func TestHintStore(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hs := newHintStore(storage.NewHintSLD(db.NewMemoryDB()), NewDefaultHandoffParameters())
+	now := time.Now()
+	hs.now = func() time.Time { return now }
+
+	requesterID := id.NewPseudoRandom(rng)
+	holders := peer.NewTestPeers(rng, 4)
+	keys := make([]id.ID, len(holders))
+	for i, holder := range holders {
+		keys[i] = id.NewPseudoRandom(rng)
+		assert.Nil(t, hs.add(keys[i], requesterID, holder))
+	}
+	assert.Equal(t, uint(len(holders)), hs.nHints)
+
+	listed, err := hs.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, listed, len(holders))
+	for _, h := range listed {
+		assert.Equal(t, now.UnixNano(), h.created().UnixNano())
+		assert.NotNil(t, h.holder.Address())
+		assert.Zero(t, h.stored.NAttempts)
+	}
+
+	// lists no more than max
+	listed, err = hs.list(2)
+	assert.Nil(t, err)
+	assert.Len(t, listed, 2)
+
+	// failed attempts are counted and aren't listed again until they're due, so the others are
+	for _, h := range listed {
+		assert.Nil(t, hs.recordAttempt(h))
+	}
+	relisted, err := hs.list(2)
+	assert.Nil(t, err)
+	assert.Len(t, relisted, 2)
+	for _, h := range relisted {
+		assert.Zero(t, h.stored.NAttempts)
+	}
+	now = now.Add(DefaultHandoffInterval)
+	relisted, err = hs.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, relisted, len(holders))
+
+	// removed hints are no longer listed or counted
+	assert.Nil(t, hs.remove(listed[0]))
+	assert.Nil(t, hs.remove(listed[0]))
+	listed, err = hs.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, listed, len(holders)-1)
+	assert.Equal(t, uint(len(holders)-1), hs.nHints)
+	assert.Equal(t, uint(len(holders)-1), hs.nRequesterHints[string(requesterID.Bytes())])
+}
+
+func TestHintStore_add_tooMany(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultHandoffParameters()
+	params.MaxStoredHints, params.MaxRequesterHints = 3, 2
+	sld := storage.NewHintSLD(db.NewMemoryDB())
+	hs := newHintStore(sld, params)
+	requesterID1, requesterID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	holder := peer.NewTestPeer(rng, 0)
+	key1, key2, key3 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	// too many from the requester
+	assert.Nil(t, hs.add(key1, requesterID1, holder))
+	assert.Nil(t, hs.add(key2, requesterID1, holder))
+	assert.Equal(t, errTooManyHints, hs.add(key3, requesterID1, holder))
+
+	// replacing a hint doesn't count as another
+	assert.Nil(t, hs.add(key2, requesterID1, holder))
+
+	// too many in total
+	assert.Nil(t, hs.add(key3, requesterID2, holder))
+	assert.Equal(t, errTooManyHints, hs.add(id.NewPseudoRandom(rng), requesterID2, holder))
+
+	// loaded store has the same counts
+	loaded, err := loadHintStore(sld, params)
+	assert.Nil(t, err)
+	assert.Equal(t, hs.nHints, loaded.nHints)
+	assert.Equal(t, hs.nRequesterHints, loaded.nRequesterHints)
+}
+
+func TestHint_due(t *testing.T) {
+	params := NewDefaultHandoffParameters()
+	now := time.Now()
+	h := &hint{stored: &sstorage.Hint{CreatedNanos: now.UnixNano()}}
+	assert.True(t, h.due(now, params))
+
+	// backs off by the interval for each failed attempt
+	h.stored.NAttempts, h.stored.LastAttemptNanos = 2, now.UnixNano()
+	assert.False(t, h.due(now.Add(params.Interval), params))
+	assert.True(t, h.due(now.Add(2*params.Interval), params))
+
+	// always due once too old, so it's dropped
+	h.stored.NAttempts = 1 << 20
+	assert.True(t, h.due(now.Add(2*params.MaxAge), params))
+}
+
+func TestLibrarian_addHint(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l, _ := newTestHandoffLibrarian(rng)
+	requesterID := id.NewPseudoRandom(rng)
+	key := id.NewPseudoRandom(rng)
+
+	// hint for peer among those closest to key is added with routing table's address
+	closest := l.rt.Find(key, l.config.Store.NReplicas)[0]
+	hintAddr := peer.FromAddress(closest.ID(), "", &net.TCPAddr{IP: net.ParseIP("10.1.1.1")})
+	assert.Nil(t, l.addHint(key, requesterID, hintAddr))
+	hs, err := l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, hs, 1)
+	assert.Equal(t, closest.Address().String(), hs[0].holder.Address().String())
+	assert.Equal(t, requesterID.Bytes(), hs[0].stored.RequesterId)
+
+	// hint for unknown peer is ignored
+	assert.Nil(t, l.addHint(key, requesterID, peer.NewTestPeer(rng, 0).ToAPI()))
+	hs, err = l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, hs, 1)
+}
+
+func TestLibrarian_handOff(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l, lc := newTestHandoffLibrarian(rng)
+	holder := peer.NewTestPeer(rng, 0)
+	value, key := api.NewTestDocument(rng)
+	expires := time.Now().Add(time.Hour)
+	assert.Nil(t, l.expiring.StoreExpiring(key, value, expires))
+	assert.Nil(t, l.hints.add(key, id.NewPseudoRandom(rng), holder))
+
+	// holder unreachable, so hint kept with an attempt and not due again for an interval
+	lc.err = errors.New("some Store error")
+	l.handOffHintsOnce()
+	hs, err := l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, hs, 0)
+	l.hints.now = func() time.Time { return time.Now().Add(l.config.Handoff.Interval) }
+	hs, err = l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, hs, 1)
+	assert.Equal(t, uint32(1), hs[0].stored.NAttempts)
+
+	// holder returns, so document handed off with its expiration and hint removed
+	lc.err = nil
+	l.handOffHintsOnce()
+	assert.Len(t, lc.stored, 1)
+	assert.Equal(t, key.Bytes(), lc.stored[0].Key)
+	assert.Equal(t, value, lc.stored[0].Value)
+	assert.Equal(t, expires.Unix(), lc.stored[0].ExpireTime)
+	hs, err = l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, hs, 0)
+
+	// hinted copy is kept
+	stored, err := l.documentSL.Load(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, stored)
+}
+
+func TestLibrarian_handOff_dropped(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l, lc := newTestHandoffLibrarian(rng)
+	holder := peer.NewTestPeer(rng, 0)
+
+	// hint for document no longer stored is dropped
+	_, key := api.NewTestDocument(rng)
+	assert.Nil(t, l.hints.add(key, id.NewPseudoRandom(rng), holder))
+	hs, err := l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	handedOff, dropped, err := l.handOff(hs[0])
+	assert.Nil(t, err)
+	assert.False(t, handedOff)
+	assert.True(t, dropped)
+
+	// hint older than max age is dropped
+	value, key := api.NewTestDocument(rng)
+	assert.Nil(t, l.documentSL.Store(key, value))
+	l.hints.now = func() time.Time { return time.Now().Add(-2 * l.config.Handoff.MaxAge) }
+	assert.Nil(t, l.hints.add(key, id.NewPseudoRandom(rng), holder))
+	hs, err = l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	handedOff, dropped, err = l.handOff(hs[0])
+	assert.Nil(t, err)
+	assert.False(t, handedOff)
+	assert.True(t, dropped)
+
+	assert.Empty(t, lc.stored)
+	hs, err = l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, hs, 0)
+}
+
+func newTestHandoffLibrarian(rng *rand.Rand) (*Librarian, *fixedHandoffClient) {
+	l := newTestSyncLibrarian(rng, 8)
+	lc := &fixedHandoffClient{}
+	l.clients = &fixedPool{lc: lc}
+	l.hints = newHintStore(storage.NewHintSLD(db.NewMemoryDB()), l.config.Handoff)
+	return l, lc
+}
+
+// fixedHandoffClient records the Store requests it receives.
+type fixedHandoffClient struct {
+	api.LibrarianClient
+	stored []*api.StoreRequest
+	err    error
+}
+
+func (f *fixedHandoffClient) Store(
+	ctx context.Context, in *api.StoreRequest, opts ...grpc.CallOption,
+) (*api.StoreResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.stored = append(f.stored, in)
+	return &api.StoreResponse{
+		Metadata: &api.ResponseMetadata{RequestId: in.Metadata.RequestId},
+	}, nil
+}
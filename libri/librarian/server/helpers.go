@@ -1,21 +1,30 @@
 package server
 
 import (
+	"bytes"
+	"net"
+
+	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
 	"github.com/golang/protobuf/proto"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
+	grpcpeer "google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
 const (
 	invalidRequestMsg    = "invalid request"
 	requestNotAllowedMsg = "request not allowed"
+	requestCanceledMsg   = "request canceled"
 )
 
 // newStubPeerFromPublicKeyBytes creates a new stub peer with an ID coming from an ECDSA public key.
@@ -36,32 +45,29 @@ func (l *Librarian) NewResponseMetadata(m *api.RequestMetadata) *api.ResponseMet
 	}
 }
 
-// checkRequest verifies the request signature and records an error with the peer if necessary. It
-// returns the ID of the requester or an error.
-func (l *Librarian) checkRequest(
-	ctx context.Context, rq proto.Message, meta *api.RequestMetadata,
-) (id.ID, error) {
-	requesterID, err := newIDFromPublicKeyBytes(meta.PubKey)
-	if err != nil {
-		return nil, err
-	}
+// checkRequest returns the ID of the requester, whose signature the signature interceptors have
+// already verified, or an error if its public key is invalid.
+func (l *Librarian) checkRequest(meta *api.RequestMetadata) (id.ID, error) {
+	return newIDFromPublicKeyBytes(meta.PubKey)
+}
 
-	// record request verification issue, if it exists
-	if err := l.rqv.Verify(ctx, rq, meta); err != nil {
-		return requesterID, err
+// remoteIP returns the IP of the remote end of the request or nil if it isn't known.
+func remoteIP(ctx context.Context) net.IP {
+	remote, ok := grpcpeer.FromContext(ctx)
+	if !ok || remote.Addr == nil {
+		return nil
 	}
-	return requesterID, nil
+	host, _, err := net.SplitHostPort(remote.Addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
 }
 
-// checkRequestAndKey verifies the request signature and key, recording errors with the peer if
-// necessary. It returns the ID of the requester or an error.
-func (l *Librarian) checkRequestAndKey(
-	ctx context.Context,
-	rq proto.Message,
-	meta *api.RequestMetadata,
-	key []byte,
-) (id.ID, error) {
-	requesterID, err := l.checkRequest(ctx, rq, meta)
+// checkRequestAndKey checks the request and verifies the key. It returns the ID of the requester
+// or an error.
+func (l *Librarian) checkRequestAndKey(meta *api.RequestMetadata, key []byte) (id.ID, error) {
+	requesterID, err := l.checkRequest(meta)
 	if err != nil {
 		return nil, err
 	}
@@ -71,31 +77,165 @@ func (l *Librarian) checkRequestAndKey(
 	return requesterID, nil
 }
 
-// checkRequestAndKey verifies the request signature and key/value combo, recording errors with
-// the peer if necessary. It returns the ID of the requester or an error.
+// checkRequestAndKeyValue checks the request and verifies the key/value combo. It returns the ID
+// of the requester or an error.
 func (l *Librarian) checkRequestAndKeyValue(
-	ctx context.Context,
-	rq proto.Message,
-	meta *api.RequestMetadata,
-	key []byte,
-	value *api.Document,
+	meta *api.RequestMetadata, key []byte, value *api.Document,
 ) (id.ID, error) {
-	requesterID, err := l.checkRequest(ctx, rq, meta)
+	requesterID, err := l.checkRequest(meta)
 	if err != nil {
 		return nil, err
 	}
+	if err := l.checkKeyValue(key, value); err != nil {
+		return nil, err
+	}
+	return requesterID, nil
+}
+
+// checkKeyValue verifies the key/value combo, including that system documents are signed and
+// shards and shard manifests are under their own kinds of keys.
+func (l *Librarian) checkKeyValue(key []byte, value *api.Document) error {
 	valueBytes, err := proto.Marshal(value)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if err := l.kvc.Check(key, valueBytes); err != nil {
-		return nil, err
+		return err
 	}
-	return requesterID, nil
+	if sys := value.GetSystem(); sys != nil {
+		// system docs must be under their system key and signed by their included public key
+		if !api.IsSystemKey(id.FromBytes(key)) {
+			return api.ErrUnexpectedKey
+		}
+		if err := client.VerifySystemDocument(l.sysv, sys); err != nil {
+			return err
+		}
+	}
+	if value.GetShard() != nil && !api.IsShardKey(id.FromBytes(key)) {
+		return api.ErrUnexpectedKey
+	}
+	if value.GetShardManifest() != nil && !api.IsShardManifestKey(id.FromBytes(key)) {
+		return api.ErrUnexpectedKey
+	}
+	return nil
+}
+
+// checkPageSize verifies that a page document's ciphertext is no larger than the max page size
+// (plus encryption overhead) advertised to clients.
+func (l *Librarian) checkPageSize(value *api.Document) error {
+	page, ok := value.Contents.(*api.Document_Page)
+	if !ok {
+		return nil
+	}
+	if uint32(len(page.Page.Ciphertext)) > l.config.MaxPageSize+api.PageCiphertextOverhead {
+		return errPageTooLarge
+	}
+	return nil
+}
+
+// checkStoreAuth verifies the store authorization token in the context was issued to the
+// requester's organization, if the librarian requires such tokens. It returns a grpc status error
+// if the requester is not authorized.
+func (l *Librarian) checkStoreAuth(ctx context.Context, meta *api.RequestMetadata) error {
+	if l.sav == nil {
+		// no token required
+		return nil
+	}
+	token, err := client.FromStoreAuthContext(ctx)
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err := l.sav.Verify(token, meta.OrgPubKey); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// checkGetMacaroon verifies the macaroon in the context authorizes the requester's organization to
+// get documents, if the librarian requires macaroons. System documents, e.g., the network
+// parameters other peers fetch, remain public.
+func (l *Librarian) checkGetMacaroon(ctx context.Context, rq *api.GetRequest) error {
+	if api.IsSystemKey(id.FromBytes(rq.Key)) {
+		return nil
+	}
+	return l.checkMacaroon(ctx, client.MacaroonOpGet, rq.Metadata)
+}
+
+// checkFindMacaroon verifies the macaroon in the context authorizes the requester's organization
+// to get the value a Find request would return, if the librarian requires macaroons. Finds
+// returning only peers remain public, since other peers need them for routing.
+func (l *Librarian) checkFindMacaroon(ctx context.Context, rq *api.FindRequest) error {
+	if api.IsSystemKey(id.FromBytes(rq.Key)) {
+		return nil
+	}
+	return l.checkMacaroon(ctx, client.MacaroonOpGet, rq.Metadata)
+}
+
+// checkPutMacaroon verifies the macaroon in the context authorizes the requester's organization to
+// put the document, which for envelopes means sharing it, if the librarian requires macaroons.
+func (l *Librarian) checkPutMacaroon(ctx context.Context, rq *api.PutRequest) error {
+	return l.checkMacaroon(ctx, macaroonWriteOp(rq.Value), rq.Metadata)
+}
+
+// checkStoreMacaroon verifies the macaroon in the context authorizes the requester's organization
+// to store the document, like checkPutMacaroon, if the librarian requires macaroons.
+func (l *Librarian) checkStoreMacaroon(ctx context.Context, rq *api.StoreRequest) error {
+	return l.checkMacaroon(ctx, macaroonWriteOp(rq.Value), rq.Metadata)
+}
+
+// macaroonWriteOp returns the macaroon operation authorizing writes of the document.
+func macaroonWriteOp(value *api.Document) string {
+	if value.GetEnvelope() != nil {
+		return client.MacaroonOpShare
+	}
+	return client.MacaroonOpPut
+}
+
+// checkMacaroon verifies the macaroon in the context authorizes the requester's organization to
+// perform the operation, if the librarian requires macaroons. Only requests signed by their
+// organization satisfy org caveats, since the org public key is otherwise unauthenticated. It
+// returns a grpc status error if the requester is not authorized.
+func (l *Librarian) checkMacaroon(ctx context.Context, op string, meta *api.RequestMetadata) error {
+	if l.mv == nil {
+		// no macaroon required
+		return nil
+	}
+	encMacaroon, err := client.FromMacaroonContext(ctx)
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	var orgPubKey []byte
+	if _, encOrgToken, err := client.FromSignatureContext(ctx); err == nil && encOrgToken != "" {
+		orgPubKey = meta.OrgPubKey
+	}
+	if err := l.mv.Verify(encMacaroon, op, orgPubKey); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// checkAdmin verifies the request was made with the admin key. It returns a grpc status error if
+// the requester is not the admin or admin requests are disabled.
+func (l *Librarian) checkAdmin(meta *api.RequestMetadata) error {
+	if l.config.AdminPubKey == nil {
+		return status.Error(codes.PermissionDenied, errAdminDisabled.Error())
+	}
+	if !bytes.Equal(meta.PubKey, ecid.ToPublicKeyBytes(l.config.AdminPubKey)) {
+		return status.Error(codes.PermissionDenied, errNotAdmin.Error())
+	}
+	return nil
 }
 
 // record records query outcome for a particular peer if that peer is in the
 // routing table.
+// forgetNotFound removes the key from the not-found search cache, if there is one, once a value
+// for it has been stored, so subsequent Gets don't miss the value until the cached entry expires.
+func (l *Librarian) forgetNotFound(key id.ID) {
+	if cs, ok := l.searcher.(search.CachingSearcher); ok {
+		cs.Forget(key)
+	}
+}
+
 func (l *Librarian) record(fromPeerID id.ID, e api.Endpoint, qt comm.QueryType, o comm.Outcome) {
 	if fromPeerID == nil {
 		return
@@ -128,6 +268,36 @@ func logReturnUnavailErr(lg *zap.Logger, msg string, err error, fields ...zapcor
 	return status.Error(codes.Unavailable, codes.Unavailable.String())
 }
 
+// logReturnStorageErr returns an Unavailable error when the DB is read-only after a disk error,
+// so clients try another peer, and an Internal error otherwise.
+func logReturnStorageErr(lg *zap.Logger, msg string, err error, fields ...zapcore.Field) error {
+	if err == db.ErrReadOnly {
+		return logReturnUnavailErr(lg, msg, err, fields...)
+	}
+	return logReturnInternalErr(lg, msg, err, fields...)
+}
+
+func logReturnCanceledErr(lg *zap.Logger, err error, fields ...zapcore.Field) error {
+	// info level b/c requester abandoned the request
+	fields = append(fields, zap.Error(err))
+	lg.Info(requestCanceledMsg, fields...)
+	if err == context.DeadlineExceeded {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Canceled, err.Error())
+}
+
+// interrupted returns whether a search or store error means the request's context was done before
+// it finished.
+func interrupted(err error) bool {
+	switch err {
+	case search.ErrSearchCanceled, store.ErrStoreCanceled, context.Canceled,
+		context.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
 func logReturnNotAllowedErr(lg *zap.Logger, err error) error {
 	// assume err is already grpc status error
 	lg.Info(requestNotAllowedMsg, zap.Error(err))
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"crypto/sha256"
 	"errors"
 	"math/rand"
 	"testing"
@@ -37,7 +38,7 @@ func TestCheckRequest_ok(t *testing.T) {
 	peerID := ecid.NewPseudoRandom(rng)
 	orgID := ecid.NewPseudoRandom(rng)
 	rq := client.NewGetRequest(peerID, orgID, id.NewPseudoRandom(rng))
-	requesterID, err := l.checkRequest(context.TODO(), rq, rq.Metadata)
+	requesterID, err := l.checkRequest(rq.Metadata)
 
 	assert.Nil(t, err)
 	assert.Equal(t, peerID.ID(), requesterID)
@@ -57,28 +58,12 @@ func TestCheckRequest_newIDErr(t *testing.T) {
 	rq := client.NewGetRequest(peerID, orgID, id.NewPseudoRandom(rng))
 	rq.Metadata.PubKey = []byte("bad pub key")
 	l := &Librarian{}
-	requesterID, err := l.checkRequest(context.TODO(), rq, rq.Metadata)
+	requesterID, err := l.checkRequest(rq.Metadata)
 
 	assert.Nil(t, requesterID)
 	assert.NotNil(t, err)
 }
 
-func TestCheckRequest_verifyErr(t *testing.T) {
-	rng := rand.New(rand.NewSource(0))
-	peerID := ecid.NewPseudoRandom(rng)
-	orgID := ecid.NewPseudoRandom(rng)
-	rq := client.NewGetRequest(peerID, orgID, id.NewPseudoRandom(rng))
-	p, d := &fixedPreferer{}, &fixedDoctor{}
-	l := &Librarian{
-		rqv: &neverRequestVerifier{},
-		rt:  routing.NewEmpty(peerID.ID(), p, d, routing.NewDefaultParameters()),
-	}
-	requesterID, err := l.checkRequest(context.TODO(), rq, rq.Metadata)
-
-	assert.Zero(t, peerID.ID().Cmp(requesterID))
-	assert.NotNil(t, err)
-}
-
 func TestCheckRequestAndKey_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	peerID, key := ecid.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
@@ -90,7 +75,7 @@ func TestCheckRequestAndKey_ok(t *testing.T) {
 		rt:  routing.NewEmpty(peerID.ID(), p, d, routing.NewDefaultParameters()),
 	}
 	rq := client.NewGetRequest(peerID, orgID, key)
-	requesterID, err := l.checkRequestAndKey(context.TODO(), rq, rq.Metadata, key.Bytes())
+	requesterID, err := l.checkRequestAndKey(rq.Metadata, key.Bytes())
 
 	assert.Nil(t, err)
 	assert.Equal(t, peerID.ID(), requesterID)
@@ -103,7 +88,7 @@ func TestCheckRequestAndKey_checkRequestErr(t *testing.T) {
 	rq := client.NewGetRequest(peerID, orgID, key)
 	rq.Metadata.PubKey = []byte("bad pub key")
 	l := &Librarian{}
-	requesterID, err := l.checkRequestAndKey(context.TODO(), rq, rq.Metadata, key.Bytes())
+	requesterID, err := l.checkRequestAndKey(rq.Metadata, key.Bytes())
 
 	assert.Nil(t, requesterID)
 	assert.NotNil(t, err)
@@ -120,7 +105,7 @@ func TestCheckRequestAndKey_checkErr(t *testing.T) {
 		kc:  storage.NewExactLengthChecker(storage.EntriesKeyLength),
 		rt:  routing.NewEmpty(peerID.ID(), p, d, routing.NewDefaultParameters()),
 	}
-	requesterID, err := l.checkRequestAndKey(context.TODO(), rq, rq.Metadata, []byte("bad key"))
+	requesterID, err := l.checkRequestAndKey(rq.Metadata, []byte("bad key"))
 
 	assert.Nil(t, requesterID)
 	assert.NotNil(t, err)
@@ -139,8 +124,7 @@ func TestCheckRequestAndKeyValue_ok(t *testing.T) {
 		kvc: storage.NewHashKeyValueChecker(),
 	}
 	rq := client.NewGetRequest(peerID, orgID, key)
-	requesterID, err := l.checkRequestAndKeyValue(context.TODO(), rq, rq.Metadata, key.Bytes(),
-		value)
+	requesterID, err := l.checkRequestAndKeyValue(rq.Metadata, key.Bytes(), value)
 
 	assert.Nil(t, err)
 	assert.Equal(t, peerID.ID(), requesterID)
@@ -154,8 +138,7 @@ func TestCheckRequestAndKeyValue_checkRequestErr(t *testing.T) {
 	rq := client.NewGetRequest(peerID, orgID, key)
 	rq.Metadata.PubKey = []byte("bad pub key")
 	l := &Librarian{}
-	requesterID, err := l.checkRequestAndKeyValue(context.TODO(), rq, rq.Metadata, key.Bytes(),
-		value)
+	requesterID, err := l.checkRequestAndKeyValue(rq.Metadata, key.Bytes(), value)
 
 	assert.Nil(t, requesterID)
 	assert.NotNil(t, err)
@@ -175,13 +158,63 @@ func TestCheckRequestAndKeyValue_checkErr(t *testing.T) {
 		kc:  storage.NewExactLengthChecker(storage.EntriesKeyLength),
 		kvc: storage.NewHashKeyValueChecker(),
 	}
-	requesterID, err := l.checkRequestAndKeyValue(context.TODO(), rq, rq.Metadata, key.Bytes(),
-		value)
+	requesterID, err := l.checkRequestAndKeyValue(rq.Metadata, key.Bytes(), value)
 
 	assert.Nil(t, requesterID)
 	assert.NotNil(t, err)
 }
 
+func TestCheckRequestAndKeyValue_system(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	signerID := ecid.NewPseudoRandom(rng)
+	p, d := &fixedPreferer{}, &fixedDoctor{}
+	l := &Librarian{
+		rt:   routing.NewEmpty(peerID.ID(), p, d, routing.NewDefaultParameters()),
+		rqv:  &alwaysRequestVerifier{},
+		kc:   storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:  storage.NewDocumentKeyValueChecker(),
+		sysv: client.NewVerifier(),
+	}
+	record := &api.SystemRecord{
+		Type:     api.SystemDocumentType_ORG_PROFILE,
+		Name:     []byte("profile"),
+		Contents: api.RandBytes(rng, 64),
+	}
+	value, err := client.NewSystemDocument(client.NewECDSASigner(signerID.Key()),
+		signerID.PublicKeyBytes(), record)
+	assert.Nil(t, err)
+	key, err := api.GetKey(value)
+	assert.Nil(t, err)
+
+	// ok
+	rq := client.NewGetRequest(peerID, orgID, key)
+	requesterID, err := l.checkRequestAndKeyValue(rq.Metadata, key.Bytes(), value)
+	assert.Nil(t, err)
+	assert.Equal(t, peerID.ID(), requesterID)
+
+	// system doc under its content hash instead of its system key
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	hashKey := sha256.Sum256(valueBytes)
+	requesterID, err = l.checkRequestAndKeyValue(rq.Metadata, hashKey[:], value)
+	assert.Equal(t, api.ErrUnexpectedKey, err)
+	assert.Nil(t, requesterID)
+
+	// bad signature
+	value.GetSystem().Signature = "bad.signature.token"
+	requesterID, err = l.checkRequestAndKeyValue(rq.Metadata, key.Bytes(), value)
+	assert.NotNil(t, err)
+	assert.Nil(t, requesterID)
+
+	// content doc under a reserved key
+	value, _ = api.NewTestDocument(rng)
+	requesterID, err = l.checkRequestAndKeyValue(rq.Metadata, key.Bytes(), value)
+	assert.Equal(t, api.ErrReservedKey, err)
+	assert.Nil(t, requesterID)
+}
+
 type fixedPreferer struct {
 	prefer bool
 }
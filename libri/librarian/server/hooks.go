@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"os/exec"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	// DefaultStorageHookTimeout is the default timeout for a single run of the storage hook
+	// command.
+	DefaultStorageHookTimeout = 10 * time.Second
+
+	// maxConcurrentHookRuns is the max number of storage hook command runs in progress at once.
+	// Events beyond this are dropped (and logged) rather than blocking storage.
+	maxConcurrentHookRuns = 16
+
+	hookEventPut        = "put"
+	hookEventDelete     = "delete"
+	hookEventVerifyFail = "verify_fail"
+
+	logHookEvent   = "hook_event"
+	logHookCommand = "hook_command"
+)
+
+// execDocumentHooks runs an external command for each document storage event. The command is
+// called with the event name and hex document key as its final two arguments. For put events,
+// the marshaled api.Document is written to its stdin.
+type execDocumentHooks struct {
+	command []string
+	timeout time.Duration
+	runs    chan struct{}
+	logger  *zap.Logger
+}
+
+func newExecDocumentHooks(
+	command []string, timeout time.Duration, logger *zap.Logger,
+) storage.DocumentHooks {
+	return &execDocumentHooks{
+		command: command,
+		timeout: timeout,
+		runs:    make(chan struct{}, maxConcurrentHookRuns),
+		logger:  logger,
+	}
+}
+
+func (h *execDocumentHooks) OnPut(key id.ID, value *api.Document) {
+	valueBytes, err := proto.Marshal(value)
+	if err != nil {
+		h.logger.Error("error marshaling document for storage hook", zap.Error(err))
+		return
+	}
+	h.run(hookEventPut, key, valueBytes)
+}
+
+func (h *execDocumentHooks) OnDelete(key id.ID) {
+	h.run(hookEventDelete, key, nil)
+}
+
+func (h *execDocumentHooks) OnVerifyFail(key id.ID, err error) {
+	h.run(hookEventVerifyFail, key, nil)
+}
+
+func (h *execDocumentHooks) run(event string, key id.ID, stdin []byte) {
+	lg := h.logger.With(
+		zap.String(logHookEvent, event),
+		zap.String(logKey, id.Hex(key.Bytes())),
+	)
+	select {
+	case h.runs <- struct{}{}:
+	default:
+		lg.Warn("dropping storage hook event since too many hook runs in progress")
+		return
+	}
+	go func() {
+		defer func() { <-h.runs }()
+		if err := h.runCommand(event, key, stdin); err != nil {
+			lg.Error("error running storage hook command",
+				zap.Strings(logHookCommand, h.command), zap.Error(err))
+		}
+	}()
+}
+
+func (h *execDocumentHooks) runCommand(event string, key id.ID, stdin []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	args := append(append([]string{}, h.command[1:]...), event, id.Hex(key.Bytes()))
+	cmd := exec.CommandContext(ctx, h.command[0], args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	return cmd.Run()
+}
+
+// getDocumentHooks returns the given internal document hooks followed by those configured for the
+// server.
+func getDocumentHooks(
+	config *Config, logger *zap.Logger, internal ...storage.DocumentHooks,
+) storage.DocumentHooks {
+	hooks := storage.MultiDocumentHooks(internal)
+	if config.StorageHooks != nil {
+		hooks = append(hooks, config.StorageHooks)
+	}
+	if len(config.StorageHookCommand) > 0 {
+		hooks = append(hooks, newExecDocumentHooks(config.StorageHookCommand,
+			DefaultStorageHookTimeout, logger))
+	}
+	return hooks
+}
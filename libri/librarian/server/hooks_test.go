@@ -0,0 +1,100 @@
+package server
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	clogging "github.com/drausin/libri/libri/common/logging"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestExecDocumentHooks(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	dir, err := ioutil.TempDir("", "test-storage-hooks")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+
+	// hook command writes its stdin to a file named after the event and key, moving it into
+	// place once fully written
+	command := []string{"sh", "-c", `cat > "` + dir + `/.$0-$1" && mv "` + dir + `/.$0-$1" "` +
+		dir + `/$0-$1"`}
+	hooks := newExecDocumentHooks(command, DefaultStorageHookTimeout, clogging.NewDevInfoLogger())
+	value, key := api.NewTestDocument(rng)
+	keyHex := id.Hex(key.Bytes())
+
+	hooks.OnPut(key, value)
+	hooks.OnDelete(key)
+	hooks.OnVerifyFail(key, storage.ErrCorruptDocument)
+
+	for _, event := range []string{hookEventPut, hookEventDelete, hookEventVerifyFail} {
+		waitForFile(t, filepath.Join(dir, event+"-"+keyHex))
+	}
+	putStdin, err := ioutil.ReadFile(filepath.Join(dir, hookEventPut+"-"+keyHex))
+	assert.Nil(t, err)
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	assert.Equal(t, valueBytes, putStdin)
+}
+
+func TestExecDocumentHooks_dropped(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hooks := newExecDocumentHooks([]string{"true"}, DefaultStorageHookTimeout, zap.NewNop())
+	eh := hooks.(*execDocumentHooks)
+	for c := 0; c < maxConcurrentHookRuns; c++ {
+		eh.runs <- struct{}{}
+	}
+
+	// event is dropped rather than blocking when too many runs are in progress
+	hooks.OnDelete(id.NewPseudoRandom(rng))
+	assert.Len(t, eh.runs, maxConcurrentHookRuns)
+}
+
+func TestExecDocumentHooks_runCommand_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hooks := newExecDocumentHooks([]string{"false"}, DefaultStorageHookTimeout, zap.NewNop())
+	err := hooks.(*execDocumentHooks).runCommand(hookEventDelete, id.NewPseudoRandom(rng), nil)
+	assert.NotNil(t, err)
+
+	hooks = newExecDocumentHooks([]string{"sleep", "1"}, time.Millisecond, zap.NewNop())
+	err = hooks.(*execDocumentHooks).runCommand(hookEventDelete, id.NewPseudoRandom(rng), nil)
+	assert.NotNil(t, err)
+}
+
+func TestGetDocumentHooks(t *testing.T) {
+	lg := zap.NewNop()
+	config := NewDefaultConfig()
+	internal := &storage.DocumentHookFuncs{}
+	assert.Len(t, getDocumentHooks(config, lg), 0)
+	hooks := getDocumentHooks(config, lg, internal)
+	assert.Len(t, hooks, 1)
+
+	config.WithStorageHooks(&storage.DocumentHookFuncs{})
+	hooks = getDocumentHooks(config, lg, internal)
+	assert.Len(t, hooks, 2)
+	assert.Equal(t, internal, hooks.(storage.MultiDocumentHooks)[0])
+
+	config.WithStorageHookCommand([]string{"true"})
+	hooks = getDocumentHooks(config, lg, internal)
+	assert.Len(t, hooks, 3)
+	_, ok := hooks.(storage.MultiDocumentHooks)[2].(*execDocumentHooks)
+	assert.True(t, ok)
+}
+
+func waitForFile(t *testing.T, path string) {
+	for c := 0; c < 100; c++ {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "file not created", path)
+}
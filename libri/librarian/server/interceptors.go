@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errBannedIP = errors.New("remote IP is banned")
+
+// chainUnaryServer chains the unary interceptors into a single interceptor, where the first is
+// the outermost and the last calls the handler.
+func chainUnaryServer(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// chainStreamServer chains the stream interceptors into a single interceptor, where the first is
+// the outermost and the last calls the handler.
+func chainStreamServer(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, inner)
+			}
+		}
+		return next(srv, ss)
+	}
+}
+
+// bannedIPUnaryInterceptor returns a unary server interceptor rejecting requests from remote IPs
+// the blacklist has banned, e.g., for repeatedly sending requests with invalid signatures.
+func (l *Librarian) bannedIPUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := l.checkRemoteIP(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// bannedIPStreamInterceptor returns a stream server interceptor rejecting streams from remote IPs
+// the blacklist has banned.
+func (l *Librarian) bannedIPStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := l.checkRemoteIP(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// checkRemoteIP returns a PermissionDenied error if the remote IP of the request is banned.
+func (l *Librarian) checkRemoteIP(ctx context.Context) error {
+	if ip := remoteIP(ctx); ip != nil && l.blacklist.BannedIP(ip) {
+		l.logger.Debug("rejecting request from banned IP", zap.Stringer(logRemoteIP, ip))
+		return status.Error(codes.PermissionDenied, errBannedIP.Error())
+	}
+	return nil
+}
+
+// capabilitiesUnaryInterceptor returns a unary server interceptor that tracks the number of
+// in-flight requests and advertises the librarian's capabilities in the response headers.
+func (l *Librarian) capabilitiesUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		atomic.AddInt64(&l.nInFlight, 1)
+		defer atomic.AddInt64(&l.nInFlight, -1)
+		if err := grpc.SetHeader(ctx, l.capabilities().ToMetadata()); err != nil {
+			// not fatal, since clients fall back to their configured parameters
+			l.logger.Debug("unable to set capabilities header", zap.Error(err))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// capabilities returns the librarian's current capabilities.
+func (l *Librarian) capabilities() *api.Capabilities {
+	load := float64(atomic.LoadInt64(&l.nInFlight)) / maxConcurrentStreams
+	if load > 1.0 {
+		load = 1.0
+	}
+	return &api.Capabilities{
+		MaxPageSize: l.config.MaxPageSize,
+		Codecs:      []api.CompressionCodec{api.CompressionCodec_NONE, api.CompressionCodec_GZIP},
+		Load:        load,
+	}
+}
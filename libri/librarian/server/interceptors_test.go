@@ -0,0 +1,139 @@
+package server
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestChainUnaryServer(t *testing.T) {
+	calls := make([]string, 0)
+	newInterceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	}
+	chained := chainUnaryServer(newInterceptor("first"), newInterceptor("second"))
+
+	rp, err := chained(context.Background(), "request", &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "request", rp)
+	assert.Equal(t, []string{"first", "second", "handler"}, calls)
+}
+
+func TestChainStreamServer(t *testing.T) {
+	calls := make([]string, 0)
+	newInterceptor := func(name string) grpc.StreamServerInterceptor {
+		return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			calls = append(calls, name)
+			return handler(srv, ss)
+		}
+	}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		calls = append(calls, "handler")
+		return nil
+	}
+	chained := chainStreamServer(newInterceptor("first"), newInterceptor("second"))
+
+	err := chained(nil, nil, &grpc.StreamServerInfo{}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second", "handler"}, calls)
+}
+
+func TestLibrarian_bannedIPUnaryInterceptor(t *testing.T) {
+	bl := comm.NewBlacklister(comm.NewDefaultBlacklistParameters(), nil)
+	l := &Librarian{blacklist: bl, logger: zap.NewNop()}
+	banned, allowed := net.ParseIP("1.2.3.4"), net.ParseIP("1.2.3.5")
+	bl.BanIPNet(&net.IPNet{IP: banned, Mask: net.CIDRMask(32, 32)}, comm.Permanent)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	interceptor := l.bannedIPUnaryInterceptor()
+	newCtx := func(ip net.IP) context.Context {
+		return grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{
+			Addr: &net.TCPAddr{IP: ip, Port: 20100},
+		})
+	}
+
+	rp, err := interceptor(newCtx(banned), "request", &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(t, rp)
+	errSt, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.PermissionDenied, errSt.Code())
+
+	rp, err = interceptor(newCtx(allowed), "request", &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "request", rp)
+
+	// requests without a known remote IP pass through
+	rp, err = interceptor(context.Background(), "request", &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "request", rp)
+}
+
+func TestLibrarian_capabilitiesUnaryInterceptor(t *testing.T) {
+	l := &Librarian{
+		config: NewDefaultConfig(),
+		logger: zap.NewNop(),
+	}
+	var inHandler *api.Capabilities
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		inHandler = l.capabilities()
+		return nil, nil
+	}
+	interceptor := l.capabilitiesUnaryInterceptor()
+
+	// setting the header fails outside of a real grpc server, which is fine
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0/maxConcurrentStreams, inHandler.Load)
+	assert.Zero(t, l.capabilities().Load)
+}
+
+func TestLibrarian_capabilities(t *testing.T) {
+	l := &Librarian{config: NewDefaultConfig().WithMaxPageSize(1024)}
+	c := l.capabilities()
+	assert.Equal(t, uint32(1024), c.MaxPageSize)
+	assert.True(t, c.SupportsCodec(api.CompressionCodec_NONE))
+	assert.True(t, c.SupportsCodec(api.CompressionCodec_GZIP))
+	assert.Zero(t, c.Load)
+
+	// load is capped at 1
+	l.nInFlight = 2 * maxConcurrentStreams
+	assert.Equal(t, 1.0, l.capabilities().Load)
+}
+
+func TestLibrarian_checkPageSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := &Librarian{config: NewDefaultConfig()}
+	page := api.NewTestPage(rng)
+	pageDoc := &api.Document{Contents: &api.Document_Page{Page: page}}
+	assert.Nil(t, l.checkPageSize(pageDoc))
+
+	l.config.WithMaxPageSize(uint32(len(page.Ciphertext)) - api.PageCiphertextOverhead)
+	assert.Nil(t, l.checkPageSize(pageDoc))
+
+	l.config.WithMaxPageSize(uint32(len(page.Ciphertext)) - api.PageCiphertextOverhead - 1)
+	assert.Equal(t, errPageTooLarge, l.checkPageSize(pageDoc))
+
+	// non-page documents aren't checked
+	entryDoc := &api.Document{Contents: &api.Document_Entry{Entry: api.NewTestSinglePageEntry(rng)}}
+	assert.Nil(t, l.checkPageSize(entryDoc))
+}
@@ -3,13 +3,18 @@ package introduce
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"sync"
+	"time"
 
+	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // Introducer executes recursive introductions.
@@ -24,6 +29,7 @@ type introducer struct {
 	introducerCreator client.IntroducerCreator
 	repProcessor      ResponseProcessor
 	rec               comm.QueryRecorder
+	skewRec           comm.SkewRecorder
 }
 
 // NewIntroducer creates a new Introducer instance with the given peerSigner, querier, and response
@@ -32,6 +38,7 @@ func NewIntroducer(
 	peerSigner client.Signer,
 	orgSigner client.Signer,
 	rec comm.QueryRecorder,
+	skewRec comm.SkewRecorder,
 	c client.IntroducerCreator,
 	rp ResponseProcessor,
 ) Introducer {
@@ -41,6 +48,7 @@ func NewIntroducer(
 		introducerCreator: c,
 		repProcessor:      rp,
 		rec:               rec,
+		skewRec:           skewRec,
 	}
 }
 
@@ -50,12 +58,13 @@ func NewDefaultIntroducer(
 	peerSigner client.Signer,
 	orgSigner client.Signer,
 	rec comm.QueryRecorder,
+	skewRec comm.SkewRecorder,
 	selfID id.ID,
 	clients client.Pool,
 ) Introducer {
 	ic := client.NewIntroducerCreator(clients)
 	rp := NewResponseProcessor(peer.NewFromer(), selfID)
-	return NewIntroducer(peerSigner, orgSigner, rec, ic, rp)
+	return NewIntroducer(peerSigner, orgSigner, rec, skewRec, ic, rp)
 }
 
 func (i *introducer) Introduce(intro *Introduction, seeds []peer.Peer) error {
@@ -66,17 +75,18 @@ func (i *introducer) Introduce(intro *Introduction, seeds []peer.Peer) error {
 		intro.Result.Unqueried[seedIDStr] = seed
 	}
 
+	pacer := NewThrottle(intro.Params.MaxQueriesPerSecond)
 	var wg sync.WaitGroup
 	for c := uint(0); c < intro.Params.Concurrency; c++ {
 		wg.Add(1)
-		go i.introduceWork(intro, &wg)
+		go i.introduceWork(intro, pacer, &wg)
 	}
 	wg.Wait()
 
 	return intro.Result.FatalErr
 }
 
-func (i *introducer) introduceWork(intro *Introduction, wg *sync.WaitGroup) {
+func (i *introducer) introduceWork(intro *Introduction, pacer Throttle, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for !intro.Finished() {
 
@@ -90,7 +100,23 @@ func (i *introducer) introduceWork(intro *Introduction, wg *sync.WaitGroup) {
 		}
 
 		// do the query
-		rp, err := i.query(next, intro)
+		wait(pacer)
+		rp, skew, err := i.query(next, intro)
+		if err == comm.ErrQueryThrottled {
+			// we never sent the query, so it's neither the peer's error nor ours
+			continue
+		}
+		if retryAfter, throttled := RetryAfter(err); throttled {
+			// a busy peer isn't an erroring one, so wait as long as it asked (within reason)
+			// before trying it again, up to a few times
+			time.Sleep(minDuration(retryAfter, intro.Params.Timeout))
+			intro.wrapLock(func() {
+				if intro.Result.addThrottle(nextIDStr) <= maxThrottledRetries {
+					intro.Result.Unqueried[nextIDStr] = next
+				}
+			})
+			continue
+		}
 		if err != nil {
 			// if we had an issue querying, skip to next peer
 			intro.mu.Lock()
@@ -101,16 +127,44 @@ func (i *introducer) introduceWork(intro *Introduction, wg *sync.WaitGroup) {
 		}
 
 		// process the heap's rp
+		responderID := id.FromBytes(rp.Self.PeerId)
+		var responder peer.Peer
 		intro.wrapLock(func() {
 			delete(intro.Result.Unqueried, nextIDStr)
 			i.repProcessor.Process(rp, intro.Result)
+			responder = intro.Result.Responded[responderID.String()]
 		})
-		i.rec.Record(id.FromBytes(rp.Self.PeerId), api.Introduce, comm.Response, comm.Success)
+		i.rec.Record(responderID, api.Introduce, comm.Response, comm.Success)
+		if rp.TimeNanos != 0 && responder != nil {
+			responder.RecordClockSkew(skew)
+			i.skewRec.Record(responderID, skew)
+		}
 	}
 }
 
-func (i *introducer) query(next peer.Peer, intro *Introduction) (*api.IntroduceResponse, error) {
-	lc, err := i.introducerCreator.Create(next.Address().String())
+// query sends an introduction request to the next peer, returning the response along with the
+// responder's estimated clock skew, which is zero if the responder didn't report its time.
+func (i *introducer) query(
+	next peer.Peer, intro *Introduction,
+) (*api.IntroduceResponse, time.Duration, error) {
+	var rp *api.IntroduceResponse
+	var skew time.Duration
+	err := peer.QueryAddresses(next, func(address *net.TCPAddr) error {
+		var err error
+		sent := time.Now()
+		rp, err = i.queryAddress(address, intro)
+		if err == nil {
+			skew, _ = comm.EstimateClockSkew(sent, time.Now(), rp.TimeNanos)
+		}
+		return err
+	})
+	return rp, skew, err
+}
+
+func (i *introducer) queryAddress(
+	address *net.TCPAddr, intro *Introduction,
+) (*api.IntroduceResponse, error) {
+	lc, err := i.introducerCreator.Create(address.String())
 	if err != nil {
 		return nil, err
 	}
@@ -120,18 +174,43 @@ func (i *introducer) query(next peer.Peer, intro *Introduction) (*api.IntroduceR
 	if err != nil {
 		return nil, err
 	}
-	rp, err := lc.Introduce(ctx, rq)
+	var trailer metadata.MD
+	rp, err := lc.Introduce(ctx, rq, grpc.Trailer(&trailer))
 	cancel()
 	if err != nil {
-		return nil, err
+		return nil, fromThrottledTrailer(err, trailer)
 	}
 	if !bytes.Equal(rp.Metadata.RequestId, rq.Metadata.RequestId) {
 		return nil, client.ErrUnexpectedRequestID
 	}
+	if difficulty := intro.Params.PuzzleDifficulty; difficulty > 0 {
+		if rp.Self == nil || !ecid.SolvesPuzzle(id.FromBytes(rp.Self.PeerId), difficulty) {
+			return nil, ecid.ErrPuzzleUnsolved
+		}
+		rp.Peers = solvingPeers(rp.Peers, difficulty)
+	}
 
 	return rp, nil
 }
 
+// solvingPeers returns the peers whose IDs solve the puzzle of the given difficulty.
+func solvingPeers(pas []*api.PeerAddress, difficulty uint) []*api.PeerAddress {
+	solving := make([]*api.PeerAddress, 0, len(pas))
+	for _, pa := range pas {
+		if ecid.SolvesPuzzle(id.FromBytes(pa.PeerId), difficulty) {
+			solving = append(solving, pa)
+		}
+	}
+	return solving
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 func removeAny(m map[string]peer.Peer) (string, peer.Peer) {
 	for k, v := range m {
 		delete(m, k)
@@ -148,24 +227,32 @@ type ResponseProcessor interface {
 }
 
 type responseProcessor struct {
-	fromer peer.Fromer
-	selfID id.ID
+	fromer   peer.Fromer
+	selfID   id.ID
+	verifier client.Verifier
 }
 
 // NewResponseProcessor creates a new ResponseProcessor with a given peer.Fromer.
 func NewResponseProcessor(f peer.Fromer, selfID id.ID) ResponseProcessor {
 	return &responseProcessor{
-		fromer: f,
-		selfID: selfID,
+		fromer:   f,
+		selfID:   selfID,
+		verifier: client.NewVerifier(),
 	}
 }
 
 func (irp *responseProcessor) Process(rp *api.IntroduceResponse, result *Result) {
 
-	// add newly introduced peer to responded map
+	// add newly introduced peer to responded map, unless it's a read-only mirror, which doesn't
+	// store documents for other peers and so shouldn't be added to routing tables
 	idStr := id.FromBytes(rp.Self.PeerId).String()
-	newPeer := irp.fromer.FromAPI(rp.Self)
-	result.Responded[idStr] = newPeer
+	if !rp.Self.Mirror {
+		newPeer := irp.fromer.FromAPI(rp.Self)
+		if info := irp.verifiedBuildInfo(rp); info != nil {
+			newPeer.RecordBuildInfo(info)
+		}
+		result.Responded[idStr] = newPeer
+	}
 
 	// add newly discovered peers to list of peers to query if they're not already there
 	selfIDStr := irp.selfID.String()
@@ -179,3 +266,19 @@ func (irp *responseProcessor) Process(rp *api.IntroduceResponse, result *Result)
 		}
 	}
 }
+
+// verifiedBuildInfo returns the responder's build info if it was signed by the responder's peer
+// key and nil otherwise.
+func (irp *responseProcessor) verifiedBuildInfo(rp *api.IntroduceResponse) *api.BuildInfo {
+	if rp.BuildInfo == nil {
+		return nil
+	}
+	pubKey, err := ecid.FromPublicKeyBytes(rp.BuildInfo.PubKey)
+	if err != nil || id.FromPublicKey(pubKey).Cmp(id.FromBytes(rp.Self.PeerId)) != 0 {
+		return nil
+	}
+	if err := client.VerifySignedBuildInfo(irp.verifier, rp.BuildInfo); err != nil {
+		return nil
+	}
+	return rp.BuildInfo.BuildInfo
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"sync"
+	"time"
 
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
@@ -18,6 +19,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestNewDefaultIntroducer(t *testing.T) {
@@ -28,6 +30,7 @@ func TestNewDefaultIntroducer(t *testing.T) {
 		&lclient.TestNoOpSigner{},
 		&lclient.TestNoOpSigner{},
 		&fixedRecorder{},
+		&fixedSkewRecorder{},
 		id.NewPseudoRandom(rng),
 		p,
 	)
@@ -35,6 +38,7 @@ func TestNewDefaultIntroducer(t *testing.T) {
 	assert.NotNil(t, s.(*introducer).introducerCreator)
 	assert.NotNil(t, s.(*introducer).repProcessor)
 	assert.NotNil(t, s.(*introducer).rec)
+	assert.NotNil(t, s.(*introducer).skewRec)
 }
 
 func TestIntroducer_Introduce_ok(t *testing.T) {
@@ -68,6 +72,14 @@ func TestIntroducer_Introduce_ok(t *testing.T) {
 		// check successes recorded properly
 		assert.True(t, len(intro.Result.Responded) <= rec.nSuccesses)
 		assert.Zero(t, rec.nErrors)
+
+		// check clock skews recorded for responders
+		skewRec := getFixedSkewRecorder(introducer)
+		assert.Equal(t, rec.nSuccesses, skewRec.nRecords)
+		for _, p := range intro.Result.Responded {
+			_, measured := p.ClockSkew()
+			assert.True(t, measured)
+		}
 	}
 }
 
@@ -111,10 +123,13 @@ func TestIntroducer_query_ok(t *testing.T) {
 	}
 
 	next := peer.NewTestPeer(rng, 0)
-	rp, err := introducerImpl.query(next, intro)
+	rp, skew, err := introducerImpl.query(next, intro)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, rp.Metadata.RequestId)
+
+	// fixed introducer's clock is a minute ahead
+	assert.InDelta(t, time.Minute.Seconds(), skew.Seconds(), 1.0)
 }
 
 func TestIntroducer_query_err(t *testing.T) {
@@ -158,12 +173,47 @@ func TestIntroducer_query_err(t *testing.T) {
 	}
 	for i, c := range cases {
 		info := fmt.Sprintf("case %d", i)
-		rp1, err := c.query(next, intro)
+		rp1, _, err := c.query(next, intro)
 		assert.Nil(t, rp1, info)
 		assert.NotNil(t, err, info)
 	}
 }
 
+func TestIntroducer_query_puzzle(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	intro, _ := newQueryTestIntroduction()
+	intro.Params.PuzzleDifficulty = 4
+	next := peer.NewTestPeer(rng, 0)
+	solving := peer.New(ecid.NewPseudoRandomSolving(rng, 4).ID(), "", next.Address())
+	unsolving := peer.NewTestPeer(rng, 1)
+	for ecid.SolvesPuzzle(unsolving.ID(), 4) {
+		unsolving = peer.NewTestPeer(rng, 1)
+	}
+	newIntroducer := func(self peer.Peer) *introducer {
+		return &introducer{
+			peerSigner: &lclient.TestNoOpSigner{},
+			orgSigner:  &lclient.TestNoOpSigner{},
+			introducerCreator: &fixedIntroducerCreator{
+				introducers: map[string]api.Introducer{
+					next.Address().String(): &fixedIntroducer{
+						self:      self.ToAPI(),
+						addresses: []*api.PeerAddress{solving.ToAPI(), unsolving.ToAPI()},
+					},
+				},
+			},
+		}
+	}
+
+	// responder must solve puzzle, and only its peers that solve it are kept
+	rp, _, err := newIntroducer(solving).query(next, intro)
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PeerAddress{solving.ToAPI()}, rp.Peers)
+
+	rp, _, err = newIntroducer(unsolving).query(next, intro)
+	assert.Equal(t, ecid.ErrPuzzleUnsolved, err)
+	assert.Nil(t, rp)
+}
+
 func TestResponseProcessor_Process(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	nPeers := 16
@@ -212,6 +262,73 @@ func TestResponseProcessor_Process(t *testing.T) {
 	}
 }
 
+func TestResponseProcessor_Process_mirror(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	nPeers := 4
+	mirror := peer.NewTestPeer(rng, nPeers)
+	peers := peer.NewTestPeers(rng, nPeers)
+	rp := NewResponseProcessor(peer.NewFromer(), id.NewPseudoRandom(rng))
+	result := NewInitialResult()
+
+	mirrorSelf := mirror.ToAPI()
+	mirrorSelf.Mirror = true
+	rp.Process(&api.IntroduceResponse{Self: mirrorSelf, Peers: peer.ToAPIs(peers)}, result)
+
+	// read-only mirror isn't added as responded, but the peers it introduces are unqueried
+	_, in := result.Responded[mirror.ID().String()]
+	assert.False(t, in)
+	assert.Equal(t, nPeers, len(result.Unqueried))
+}
+
+func TestResponseProcessor_Process_buildInfo(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	responderID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	responder := peer.New(responderID.ID(), "", peer.NewTestPublicAddr(1))
+	rp := NewResponseProcessor(peer.NewFromer(), id.NewPseudoRandom(rng))
+	info := &api.BuildInfo{Version: "0.1.0", ProtocolVersion: 1}
+
+	cases := map[string]struct {
+		signer   lclient.Signer
+		pubKey   []byte
+		verified bool
+	}{
+		"signed by responder": {
+			signer:   lclient.NewECDSASigner(responderID.Key()),
+			pubKey:   responderID.PublicKeyBytes(),
+			verified: true,
+		},
+		"signed by other": {
+			signer: lclient.NewECDSASigner(otherID.Key()),
+			pubKey: responderID.PublicKeyBytes(),
+		},
+		"other's key": {
+			signer: lclient.NewECDSASigner(otherID.Key()),
+			pubKey: otherID.PublicKeyBytes(),
+		},
+		"bad key": {
+			signer: lclient.NewECDSASigner(responderID.Key()),
+			pubKey: []byte{1, 2, 3},
+		},
+	}
+	for desc, c := range cases {
+		sbi, err := lclient.NewSignedBuildInfo(c.signer, c.pubKey, info)
+		assert.Nil(t, err, desc)
+		result := NewInitialResult()
+		rp.Process(&api.IntroduceResponse{Self: responder.ToAPI(), BuildInfo: sbi}, result)
+		responded := result.Responded[responder.ID().String()]
+		if c.verified {
+			assert.Equal(t, info, responded.BuildInfo(), desc)
+		} else {
+			assert.Nil(t, responded.BuildInfo(), desc)
+		}
+	}
+
+	// no build info
+	result := NewInitialResult()
+	rp.Process(&api.IntroduceResponse{Self: responder.ToAPI()}, result)
+	assert.Nil(t, result.Responded[responder.ID().String()].BuildInfo())
+}
+
 func newQueryTestIntroduction() (*Introduction, map[string]api.Introducer) {
 	n, _ := 32, uint(8)
 	rng := rand.New(rand.NewSource(int64(n)))
@@ -248,6 +365,7 @@ func newTestIntroducer(
 		&lclient.TestNoOpSigner{},
 		&lclient.TestNoOpSigner{},
 		rec,
+		&fixedSkewRecorder{},
 		&fixedIntroducerCreator{introducers: addressIntroducers},
 		&responseProcessor{
 			fromer: &search.TestFromer{Peers: peersMap},
@@ -297,12 +415,18 @@ type fixedIntroducer struct {
 	self      *api.PeerAddress
 	addresses []*api.PeerAddress
 	requestID []byte
+	trailer   metadata.MD
 	err       error
 }
 
 func (f *fixedIntroducer) Introduce(ctx context.Context, rq *api.IntroduceRequest,
 	opts ...grpc.CallOption) (*api.IntroduceResponse, error) {
 
+	for _, opt := range opts {
+		if to, ok := opt.(grpc.TrailerCallOption); ok && f.trailer != nil {
+			*to.TrailerAddr = f.trailer
+		}
+	}
 	if f.err != nil {
 		return nil, f.err
 	}
@@ -314,11 +438,35 @@ func (f *fixedIntroducer) Introduce(ctx context.Context, rq *api.IntroduceReques
 		Metadata: &api.ResponseMetadata{
 			RequestId: requestID,
 		},
-		Self:  f.self,
-		Peers: f.addresses,
+		Self:      f.self,
+		Peers:     f.addresses,
+		TimeNanos: time.Now().Add(time.Minute).UnixNano(),
 	}, nil
 }
 
+type fixedSkewRecorder struct {
+	nRecords int
+	mu       sync.Mutex
+}
+
+func (f *fixedSkewRecorder) Record(peerID id.ID, skew time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nRecords++
+}
+
+func (f *fixedSkewRecorder) SelfSkew() (time.Duration, bool) {
+	return 0, false
+}
+
+func (f *fixedSkewRecorder) Register() {}
+
+func getFixedSkewRecorder(i Introducer) *fixedSkewRecorder {
+	return i.(*introducer).skewRec.(*fixedSkewRecorder)
+}
+
+func (f *fixedSkewRecorder) Unregister() {}
+
 type fixedRecorder struct {
 	nSuccesses int
 	nErrors    int
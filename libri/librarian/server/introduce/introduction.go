@@ -31,8 +31,26 @@ var (
 
 	// DefaultQueryTimeout is the timeout for each query to a peer.
 	DefaultQueryTimeout = 5 * time.Second
+
+	// DefaultMaxStartDelay is the default maximum random delay before bootstrapping.
+	DefaultMaxStartDelay = 5 * time.Second
+
+	// DefaultMaxQueriesPerSecond is the default maximum rate of introduce queries an introduction
+	// sends.
+	DefaultMaxQueriesPerSecond = 32.0
+
+	// DefaultMaxServedPerSecond is the default maximum rate of introduce requests a peer serves.
+	DefaultMaxServedPerSecond = 64.0
+
+	// DefaultPuzzleDifficulty is the default number of leading zero puzzle bits (see
+	// ecid.PuzzleBits) peer IDs must have, which accepts all IDs.
+	DefaultPuzzleDifficulty = uint(0)
 )
 
+// maxThrottledRetries is the maximum number of times a peer that throttled our introduce query is
+// queried again.
+const maxThrottledRetries = 3
+
 // Parameters define the parameters of the introduction.
 type Parameters struct {
 	// target number of peers to become introduced to
@@ -52,6 +70,22 @@ type Parameters struct {
 
 	// timeout for queries to individual peers
 	Timeout time.Duration
+
+	// maximum random delay before bootstrapping, which spreads out the introductions of peers
+	// restarted together (e.g., by a fleet deploy) so they don't all hit the seeds at once
+	MaxStartDelay time.Duration
+
+	// maximum rate of introduce queries sent during an introduction, with zero meaning no limit
+	MaxQueriesPerSecond float64
+
+	// maximum rate of introduce requests served, beyond which requesters are told how long to
+	// wait before retrying, with zero meaning no limit
+	MaxServedPerSecond float64
+
+	// minimum number of leading zero puzzle bits (see ecid.PuzzleBits) the IDs of introduced and
+	// introducing peers must have, which makes Sybil and eclipse attacks expensive, with zero
+	// meaning no requirement
+	PuzzleDifficulty uint
 }
 
 // NewDefaultParameters creates a new instance of default introduction parameters.
@@ -63,6 +97,10 @@ func NewDefaultParameters() *Parameters {
 		NMaxErrors:             DefaultNMaxErrors,
 		Concurrency:            DefaultConcurrency,
 		Timeout:                DefaultQueryTimeout,
+		MaxStartDelay:          DefaultMaxStartDelay,
+		MaxQueriesPerSecond:    DefaultMaxQueriesPerSecond,
+		MaxServedPerSecond:     DefaultMaxServedPerSecond,
+		PuzzleDifficulty:       DefaultPuzzleDifficulty,
 	}
 }
 
@@ -77,6 +115,9 @@ type Result struct {
 	// number of errors encountered while querying peers
 	NErrors uint
 
+	// number of times each peer has throttled our queries, which don't count as errors
+	throttles map[string]uint
+
 	// fatal error that occurred during the search
 	FatalErr error
 }
@@ -86,9 +127,17 @@ func NewInitialResult() *Result {
 	return &Result{
 		Unqueried: make(map[string]peer.Peer),
 		Responded: make(map[string]peer.Peer),
+		throttles: make(map[string]uint),
 	}
 }
 
+// addThrottle records that the peer with the given ID throttled a query, returning the number of
+// times it has.
+func (r *Result) addThrottle(peerIDStr string) uint {
+	r.throttles[peerIDStr]++
+	return r.throttles[peerIDStr]
+}
+
 // Introduction contains things involved in bootstrapping introductions.
 type Introduction struct {
 	// function to generate a new introduction request
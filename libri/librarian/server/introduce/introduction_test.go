@@ -19,6 +19,8 @@ func TestNewDefaultParameters(t *testing.T) {
 	assert.NotNil(t, params.NMaxErrors)
 	assert.NotNil(t, params.Concurrency)
 	assert.NotNil(t, params.Timeout)
+	assert.NotZero(t, params.MaxQueriesPerSecond)
+	assert.NotZero(t, params.MaxServedPerSecond)
 }
 
 func TestIntroduction_ReachedTarget(t *testing.T) {
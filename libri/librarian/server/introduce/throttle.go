@@ -0,0 +1,76 @@
+package introduce
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrIntroductionsThrottled indicates when a peer is serving too many introductions to accept
+// another one.
+var ErrIntroductionsThrottled = errors.New("introductions throttled")
+
+// NewThrottledErr returns a ResourceExhausted grpc status error for a throttled introduction. The
+// server sends how long the requester should wait before retrying in the response trailer (see
+// client.NewRetryAfterTrailer).
+func NewThrottledErr() error {
+	return status.Error(codes.ResourceExhausted, ErrIntroductionsThrottled.Error())
+}
+
+// throttledErr is the error from an introduce query a peer throttled, along with how long it
+// asked us to wait before retrying.
+type throttledErr struct {
+	retryAfter time.Duration
+}
+
+func (e *throttledErr) Error() string {
+	return ErrIntroductionsThrottled.Error()
+}
+
+// fromThrottledTrailer returns a *throttledErr if the error and response trailer are from a
+// throttled introduce query and otherwise the original error.
+func fromThrottledTrailer(err error, trailer metadata.MD) error {
+	if status.Code(err) != codes.ResourceExhausted {
+		return err
+	}
+	retryAfter, throttled := client.FromRetryAfterTrailer(trailer)
+	if !throttled {
+		return err
+	}
+	return &throttledErr{retryAfter: retryAfter}
+}
+
+// RetryAfter returns the retry-after hint of an error from a throttled introduce query and whether
+// the error had one.
+func RetryAfter(err error) (time.Duration, bool) {
+	if te, ok := err.(*throttledErr); ok {
+		return te.retryAfter, true
+	}
+	return 0, false
+}
+
+// Throttle limits events to a maximum rate, allowing bursts of up to a second's worth of them.
+type Throttle interface {
+	// Take takes the allowance for an event at now, returning zero if one was available or
+	// otherwise how long until one will be.
+	Take(now time.Time) time.Duration
+}
+
+// NewThrottle returns a Throttle allowing up to perSecond events per second, with non-positive
+// values allowing all events.
+func NewThrottle(perSecond float64) Throttle {
+	return comm.NewTokenBucket(perSecond, math.Max(1, math.Ceil(perSecond)))
+}
+
+// wait blocks until the throttle allows another event.
+func wait(t Throttle) {
+	for d := t.Take(time.Now()); d > 0; d = t.Take(time.Now()) {
+		time.Sleep(d)
+	}
+}
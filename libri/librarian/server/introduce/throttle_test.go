@@ -0,0 +1,97 @@
+package introduce
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryAfter(t *testing.T) {
+	trailer := client.NewRetryAfterTrailer(1500 * time.Millisecond)
+	err := fromThrottledTrailer(NewThrottledErr(), trailer)
+	retryAfter, throttled := RetryAfter(err)
+	assert.True(t, throttled)
+	assert.Equal(t, 1500*time.Millisecond, retryAfter)
+
+	// check errors without hints
+	cases := []struct {
+		err     error
+		trailer metadata.MD
+	}{
+		{nil, trailer},
+		{errors.New("some non-status error"), trailer},
+		{status.Error(codes.Unavailable, "some Unavailable error"), trailer},
+		{NewThrottledErr(), nil},
+		{NewThrottledErr(), metadata.Pairs("some-key", "some value")},
+	}
+	for _, c := range cases {
+		err = fromThrottledTrailer(c.err, c.trailer)
+		assert.Equal(t, c.err, err)
+		retryAfter, throttled = RetryAfter(err)
+		assert.False(t, throttled)
+		assert.Zero(t, retryAfter)
+	}
+}
+
+func TestThrottle_Take(t *testing.T) {
+	now := time.Now()
+
+	// no limit never throttles
+	th := NewThrottle(0)
+	for c := 0; c < 100; c++ {
+		assert.Zero(t, th.Take(now))
+	}
+
+	// allows burst of a second's worth of events
+	th = NewThrottle(4)
+	for c := 0; c < 4; c++ {
+		assert.Zero(t, th.Take(now))
+	}
+	assert.Equal(t, 250*time.Millisecond, th.Take(now))
+
+	// throttled takes don't consume allowance
+	assert.Equal(t, 125*time.Millisecond, th.Take(now.Add(125*time.Millisecond)))
+	assert.Zero(t, th.Take(now.Add(250*time.Millisecond)))
+	assert.Equal(t, 250*time.Millisecond, th.Take(now.Add(250*time.Millisecond)))
+
+	// unused allowance accumulates up to the burst
+	later := now.Add(time.Minute)
+	for c := 0; c < 4; c++ {
+		assert.Zero(t, th.Take(later))
+	}
+	assert.NotZero(t, th.Take(later))
+}
+
+func TestIntroducer_Introduce_throttled(t *testing.T) {
+	rec := &fixedRecorder{}
+	introducerImpl, intro, selfPeerIdxs, peers := newTestIntros(1, rec)
+	seeds := search.NewTestSeeds(peers, selfPeerIdxs[:1])
+
+	// seed tells us to wait before trying again
+	ic := introducerImpl.(*introducer).introducerCreator.(*fixedIntroducerCreator)
+	for addr := range ic.introducers {
+		ic.introducers[addr] = &fixedIntroducer{
+			err:     NewThrottledErr(),
+			trailer: client.NewRetryAfterTrailer(10 * time.Millisecond),
+		}
+	}
+	intro.Params.MaxQueriesPerSecond = 100
+
+	start := time.Now()
+	err := introducerImpl.Introduce(intro, seeds)
+	assert.Nil(t, err)
+
+	// throttles aren't errors, and the throttled seed is retried a few times before giving up
+	assert.False(t, intro.Errored())
+	assert.Zero(t, intro.Result.NErrors)
+	assert.True(t, intro.Exhausted())
+	assert.True(t, time.Since(start) >= (maxThrottledRetries+1)*10*time.Millisecond)
+	assert.Zero(t, rec.nErrors)
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/drausin/libri/libri/librarian/server/comm"
+)
+
+const keyspaceLatenciesPath = "/keyspace/latencies"
+
+// keyspaceLatenciesHandler serves the query latency distribution of each keyspace region as JSON,
+// revealing regions with poor peer coverage or slow holders.
+type keyspaceLatenciesHandler struct {
+	getter comm.KeyspaceGetter
+}
+
+func newKeyspaceLatenciesHandler(getter comm.KeyspaceGetter) http.Handler {
+	return &keyspaceLatenciesHandler{getter: getter}
+}
+
+func (h *keyspaceLatenciesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.getter.Regions()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyspaceLatenciesHandler_ServeHTTP_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	krg := comm.NewKeyspaceRecorderGetter(comm.DefaultKeyspacePrefixBits)
+	for c := 0; c < 64; c++ {
+		krg.Record(id.NewPseudoRandom(rng), time.Duration(rng.Intn(1000))*time.Millisecond,
+			comm.Success)
+	}
+	h := newKeyspaceLatenciesHandler(krg)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, keyspaceLatenciesPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	regions := make([]*comm.RegionLatencies, 0)
+	err := json.Unmarshal(rec.Body.Bytes(), &regions)
+	assert.Nil(t, err)
+	expected := krg.Regions()
+	assert.Len(t, regions, len(expected))
+	for i, rl := range regions {
+		assert.Equal(t, expected[i].Prefix, rl.Prefix)
+		assert.Equal(t, expected[i].NQueries, rl.NQueries)
+		assert.Equal(t, expected[i].MeanLatency, rl.MeanLatency)
+		assert.Equal(t, expected[i].Histogram, rl.Histogram)
+	}
+}
+
+func TestKeyspaceLatenciesHandler_ServeHTTP_err(t *testing.T) {
+	krg := comm.NewKeyspaceRecorderGetter(comm.DefaultKeyspacePrefixBits)
+	h := newKeyspaceLatenciesHandler(krg)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, keyspaceLatenciesPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"errors"
 	"fmt"
+	"math/rand"
 	"net"
 	"net/http"
 	_ "net/http/pprof" // pprof doc calls for black import
@@ -14,11 +15,15 @@ import (
 
 	cbackoff "github.com/cenkalti/backoff"
 	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/subscribe"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/introduce"
 	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/net/context"
@@ -46,6 +51,9 @@ const (
 	// LoggerNBootstrappedPeers is the logger key used for the number of peers found
 	// during a bootstrap operation.
 	LoggerNBootstrappedPeers = "n_peers"
+
+	// LoggerSeedHosts is the logger key used for the DNS seed hosts of a bootstrap operation.
+	LoggerSeedHosts = "seed_hosts"
 )
 
 var errInsufficientBootstrappedPeers = errors.New("failed to bootstrap enough other peers")
@@ -54,18 +62,24 @@ var errInsufficientBootstrappedPeers = errors.New("failed to bootstrap enough ot
 // routing table and then begins listening for and handling requests. It notifies the up channel
 // just before
 func Start(logger *zap.Logger, config *Config, up chan *Librarian) error {
+	if config.NetworkParamsPubKey != nil {
+		fetchNetworkParameters(config, logger)
+	}
+
 	// create librarian
 	l, err := NewLibrarian(config, logger)
 	if err != nil {
 		return err
 	}
+	// flush summaries of any suppressed repeated errors, even when ending without Close
+	defer func() { _ = l.logger.Sync() }()
 
 	errs := make(chan error, 2)
 
 	// populate routing table
 	bootstrapped := make(chan struct{})
 	go func() {
-		if err := l.bootstrapPeers(config.BootstrapAddrs); err != nil {
+		if err := l.bootstrapPeers(l.withSeedAddrs(config.BootstrapAddrs)); err != nil {
 			errs <- err
 		}
 		close(bootstrapped)
@@ -81,6 +95,12 @@ func Start(logger *zap.Logger, config *Config, up chan *Librarian) error {
 
 func (l *Librarian) bootstrapPeers(bootstrapAddrs []*net.TCPAddr) error {
 	bootstraps, bootstrapAddrStrs := makeBootstrapPeers(bootstrapAddrs)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if delay := startDelay(l.config.Introduce.MaxStartDelay, rng); delay > 0 {
+		// spread out the bootstraps of peers (re)started together
+		l.logger.Info("delaying peer bootstrap", zap.Duration("delay", delay))
+		time.Sleep(delay)
+	}
 	l.logger.Info("beginning peer bootstrap", zap.Strings(LoggerSeeds, bootstrapAddrStrs))
 
 	var intro *introduce.Introduction
@@ -132,10 +152,55 @@ func (l *Librarian) bootstrapPeers(bootstrapAddrs []*net.TCPAddr) error {
 		zap.Int(LoggerNBootstrappedPeers, len(intro.Result.Responded)),
 		zap.Int("routing_table_n_peers", l.rt.NumPeers()),
 		zap.Int("routing_table_n_buckets", l.rt.NumBuckets()),
+		zap.Float64("est_network_size", l.rt.EstimateKeyspace().NetworkSize),
 	)
+	l.prewarmClosestPeers()
+	l.maybeWarnClockSkew()
 	return nil
 }
 
+// withSeedAddrs returns the bootstrap addresses along with any others resolved from the
+// configured DNS seed hosts. Failing to resolve the seed hosts isn't fatal since the explicit
+// bootstrap addresses may still suffice.
+func (l *Librarian) withSeedAddrs(bootstrapAddrs []*net.TCPAddr) []*net.TCPAddr {
+	if len(l.config.SeedHosts) == 0 {
+		return bootstrapAddrs
+	}
+	seedAddrs, err := l.seeder.Seed(l.config.SeedHosts)
+	if err != nil {
+		l.logger.Warn("unable to resolve seed hosts",
+			zap.Strings(LoggerSeedHosts, l.config.SeedHosts),
+			zap.Error(err),
+		)
+		return bootstrapAddrs
+	}
+	addrs := make([]*net.TCPAddr, len(bootstrapAddrs), len(bootstrapAddrs)+len(seedAddrs))
+	copy(addrs, bootstrapAddrs)
+	seen := make(map[string]struct{})
+	for _, addr := range bootstrapAddrs {
+		seen[addr.String()] = struct{}{}
+	}
+	for _, addr := range seedAddrs {
+		if _, in := seen[addr.String()]; !in {
+			seen[addr.String()] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	l.logger.Info("resolved seed hosts",
+		zap.Strings(LoggerSeedHosts, l.config.SeedHosts),
+		zap.Int("n_seed_addrs", len(seedAddrs)),
+	)
+	return addrs
+}
+
+// startDelay returns a random delay in [0, maxDelay).
+func startDelay(maxDelay time.Duration, rng *rand.Rand) time.Duration {
+	if maxDelay <= 0 {
+		return 0
+	}
+	return time.Duration(rng.Int63n(int64(maxDelay)))
+}
+
 func makeBootstrapPeers(bootstrapAddrs []*net.TCPAddr) (
 	[]peer.Peer, []string) {
 	peers, addrStrs := make([]peer.Peer, 0), make([]string, 0)
@@ -148,21 +213,66 @@ func makeBootstrapPeers(bootstrapAddrs []*net.TCPAddr) (
 }
 
 func (l *Librarian) listenAndServe(up chan *Librarian, bootstrapped chan struct{}) error {
-	s := grpc.NewServer(
-		grpc.StreamInterceptor(grpc_prometheus.StreamServerInterceptor),
-		grpc.UnaryInterceptor(grpc_prometheus.UnaryServerInterceptor),
+	pools := newWorkerPools(l.config.WorkerPoolSizes)
+	opts := []grpc.ServerOption{
+		grpc.StreamInterceptor(chainStreamServer(
+			l.tracer.streamInterceptor(),
+			grpc_prometheus.StreamServerInterceptor,
+			l.bannedIPStreamInterceptor(),
+			pools.streamInterceptor(),
+			l.signatureStreamInterceptor(),
+			l.allowList.streamInterceptor(),
+		)),
+		grpc.UnaryInterceptor(chainUnaryServer(
+			l.tracer.unaryInterceptor(),
+			grpc_prometheus.UnaryServerInterceptor,
+			l.bannedIPUnaryInterceptor(),
+			pools.unaryInterceptor(),
+			l.signatureUnaryInterceptor(),
+			l.allowList.unaryInterceptor(),
+			l.capabilitiesUnaryInterceptor(),
+		)),
 		grpc.MaxConcurrentStreams(maxConcurrentStreams),
-	)
+	}
+	if l.serverCreds != nil {
+		opts = append(opts, grpc.Creds(l.serverCreds))
+	}
+	s := grpc.NewServer(opts...)
 
 	api.RegisterLibrarianServer(s, l)
-	healthpb.RegisterHealthServer(s, l.health)
+	healthServer, err := newBuildInfoHealthServer(l.health, l.buildInfo)
+	if err != nil {
+		return err
+	}
+	healthpb.RegisterHealthServer(s, healthServer)
+	// not ready to serve until bootstrapped with enough peers
+	l.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
 	if l.config.ReportMetrics {
 		grpc_prometheus.Register(s)
 		grpc_prometheus.EnableHandlingTimeHistogram()
 		l.storageMetrics.register()
+		l.tieringMetrics.register()
+		l.diskMetrics.register()
+		l.authMetrics.register()
+		pools.metrics.register()
+		prom.MustRegister(l.rtMetrics)
+		prom.MustRegister(l.dbMetrics)
+		l.searchMetrics.Register()
+		if ps, ok := l.storer.(store.PromStorer); ok {
+			ps.Register()
+		}
+		l.skewRec.Register()
+		l.throttler.Register()
+		l.rqLimiter.Register()
 		if rec, ok := l.rec.(comm.PromRecorder); ok {
 			rec.Register()
 		}
+		if cs, ok := l.searcher.(search.CachingSearcher); ok {
+			cs.Register()
+		}
+		if pt, ok := l.subscribeTo.(subscribe.PromTo); ok {
+			pt.Register()
+		}
 	}
 	reflection.Register(s)
 
@@ -178,12 +288,33 @@ func (l *Librarian) listenAndServe(up chan *Librarian, bootstrapped chan struct{
 	go func() {
 		<-l.stop
 		l.logger.Info("gracefully stopping server", zap.Int(LoggerPortKey, l.config.LocalPort))
+		l.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
 		s.GracefulStop()
+		pools.stop()
 		if l.config.ReportMetrics {
 			l.storageMetrics.unregister()
+			l.tieringMetrics.unregister()
+			l.diskMetrics.unregister()
+			l.authMetrics.unregister()
+			pools.metrics.unregister()
+			prom.Unregister(l.rtMetrics)
+			prom.Unregister(l.dbMetrics)
+			l.searchMetrics.Unregister()
+			if ps, ok := l.storer.(store.PromStorer); ok {
+				ps.Unregister()
+			}
+			l.skewRec.Unregister()
+			l.throttler.Unregister()
+			l.rqLimiter.Unregister()
 			if rec, ok := l.rec.(comm.PromRecorder); ok {
 				rec.Unregister()
 			}
+			if cs, ok := l.searcher.(search.CachingSearcher); ok {
+				cs.Unregister()
+			}
+			if pt, ok := l.subscribeTo.(subscribe.PromTo); ok {
+				pt.Unregister()
+			}
 		}
 		close(l.stopped)
 	}()
@@ -192,10 +323,6 @@ func (l *Librarian) listenAndServe(up chan *Librarian, bootstrapped chan struct{
 	go func() {
 		time.Sleep(postListenNotifyWait)
 		l.logger.Info("listening for requests", zap.Int(LoggerPortKey, l.config.LocalPort))
-
-		// set top-level health status
-		l.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
-
 		up <- l
 	}()
 
@@ -255,6 +382,37 @@ func (l *Librarian) startAuxRoutines(bootstrapped chan struct{}) {
 		}
 	}()
 
+	// long-running goroutine reporting whether ready to serve to health checks
+	go l.updateReadiness(bootstrapped)
+
+	// long-running goroutine checkpointing the routing table
+	go l.checkpointRoutingTable()
+
+	// long-running goroutine checkpointing the peers' recorded queries
+	go l.checkpointQueries()
+
+	// long-running goroutine compacting the routing table's buckets
+	go l.compactRoutingTable()
+
+	// long-running goroutine demoting documents not accessed recently to the cold tier
+	go l.demoteDocuments()
+
+	// long-running goroutine deleting expired documents
+	go l.sweepExpired()
+
+	// long-running goroutine probing the health of routing table peers
+	go l.probePeers()
+
+	// long-running goroutine reloading the allow-list file when it changes
+	go l.reloadAllowList()
+
+	if l.config.Mirror.Enabled() {
+		// long-running goroutine mirroring publications; mirrors don't replicate documents
+		// since they don't participate in general DHT storage
+		go l.mirrorPublications()
+		return
+	}
+
 	// long-running goroutine replicating documents
 	go func() {
 		// wait until have bootstrapped peers
@@ -265,11 +423,87 @@ func (l *Librarian) startAuxRoutines(bootstrapped chan struct{}) {
 			cerrors.MaybePanic(l.Close()) // don't try to recover from Close error
 		}
 	}()
+
+	// long-running goroutine syncing stored keys with the nearest neighbors
+	go func() {
+		// wait until have bootstrapped peers
+		<-bootstrapped
+		l.syncNeighbors()
+	}()
+
+	// long-running goroutine handing off hinted documents to their intended holders
+	go l.handOffHints()
+}
+
+// checkpointRoutingTable periodically persists the routing table peers changed since the
+// previous checkpoint until the server stops.
+func (l *Librarian) checkpointRoutingTable() {
+	if l.config.Routing.CheckpointInterval == 0 {
+		return
+	}
+	ticker := time.NewTicker(l.config.Routing.CheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if err := l.rtCheckpointer.Checkpoint(); err != nil {
+				l.logger.Error("error checkpointing routing table", zap.Error(err))
+			}
+		}
+	}
+}
+
+// checkpointQueries periodically persists the peers' recorded queries and reputations until the
+// server stops.
+func (l *Librarian) checkpointQueries() {
+	if !l.config.QueryCheckpoint.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(l.config.QueryCheckpoint.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			err := l.windowQGs.Save(l.serverSL, l.config.QueryCheckpoint)
+			if err != nil {
+				l.logger.Error("error checkpointing peer queries", zap.Error(err))
+			}
+			if err := l.judge.Save(); err != nil {
+				l.logger.Error("error checkpointing peer reputations", zap.Error(err))
+			}
+		}
+	}
+}
+
+// compactRoutingTable periodically compacts the routing table's buckets until the server stops.
+func (l *Librarian) compactRoutingTable() {
+	if l.config.Routing.CompactInterval <= 0 {
+		// validateConfig rejects negative intervals, but NewTicker would panic on one
+		return
+	}
+	ticker := time.NewTicker(l.config.Routing.CompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			nCompacted := l.rt.Compact()
+			l.logger.Debug("compacted routing table", zap.Int(logNCompacted, nCompacted))
+		}
+	}
 }
 
 // StopAuxRoutines ends the replicator and subscriptions auxiliary routines.
 func (l *Librarian) StopAuxRoutines() {
-	l.replicator.Stop()
+	if !l.config.Mirror.Enabled() {
+		// mirrors never start the replicator
+		l.replicator.Stop()
+	}
 	l.subscribeTo.End()
 }
 
@@ -303,8 +537,26 @@ func (l *Librarian) Close() error {
 	// wait for server to stop
 	<-l.stopped
 
-	// close the DB
+	// persist the final routing table state
+	if err := l.rtCheckpointer.Close(); err != nil {
+		l.logger.Error("error saving routing table", zap.Error(err))
+	}
+	if err := l.judge.Save(); err != nil {
+		l.logger.Error("error saving peer reputations", zap.Error(err))
+	}
+	if err := l.windowQGs.Save(l.serverSL, l.config.QueryCheckpoint); err != nil {
+		l.logger.Error("error saving peer queries", zap.Error(err))
+	}
+
+	// close the DBs
 	l.db.Close()
+	if l.coldDB != nil {
+		l.coldDB.Close()
+	}
+
+	// flush summaries of any suppressed repeated errors; sync errors on stdout/stderr are
+	// expected on some platforms, so ignore them
+	_ = l.logger.Sync()
 
 	return nil
 }
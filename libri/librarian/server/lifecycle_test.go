@@ -17,6 +17,8 @@ import (
 	"github.com/drausin/libri/libri/common/id"
 	clogging "github.com/drausin/libri/libri/common/logging"
 	"github.com/drausin/libri/libri/common/parse"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/introduce"
 	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/drausin/libri/libri/librarian/server/routing"
@@ -26,6 +28,7 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestStart_ok(t *testing.T) {
@@ -34,6 +37,7 @@ func TestStart_ok(t *testing.T) {
 	config.WithProfile(true).WithReportMetrics(true)
 	config.WithLogLevel(zapcore.DebugLevel)
 	config.Introduce.MinNumIntroductions = 0 // since no other peers
+	config.Readiness.CheckInterval = 100 * time.Millisecond
 
 	var err error
 	up := make(chan *Librarian, 1)
@@ -54,13 +58,27 @@ func TestStart_ok(t *testing.T) {
 	assert.Nil(t, err)
 	clientHealth := healthpb.NewHealthClient(conn)
 
-	// confirm ok health check
-	ctx1, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	rp, err := clientHealth.Check(ctx1, &healthpb.HealthCheckRequest{})
-	cancel()
-	assert.Nil(t, err)
+	// confirm ok health check once bootstrapped
+	var header metadata.MD
+	var rp *healthpb.HealthCheckResponse
+	for i := 0; i < 50; i++ {
+		ctx1, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		rp, err = clientHealth.Check(ctx1, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+		cancel()
+		assert.Nil(t, err)
+		if rp.Status == healthpb.HealthCheckResponse_SERVING {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, rp.Status)
 
+	// confirm health check header has signed build info
+	sbi, err := client.FromBuildInfoHeader(header)
+	assert.Nil(t, err)
+	assert.Nil(t, client.VerifySignedBuildInfo(client.NewVerifier(), sbi))
+	assert.Equal(t, librarian.peerID.PublicKeyBytes(), sbi.PubKey)
+
 	// confirm ok metrics
 	metricsAddr := fmt.Sprintf("http://localhost:%d/metrics", config.LocalMetricsPort)
 	resp, err := http.Get(metricsAddr)
@@ -126,12 +144,16 @@ func TestLibrarian_bootstrapPeers_ok(t *testing.T) {
 	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
 	rt := routing.NewEmpty(id.NewPseudoRandom(rng), p, d, routing.NewDefaultParameters())
 	l := &Librarian{
-		config: NewDefaultConfig(),
+		config: newNoStartDelayConfig(),
+		peerID: ecid.NewPseudoRandom(rng),
 		introducer: &fixedIntroducer{
 			result: fixedResult,
 		},
-		rt:     rt,
-		logger: zap.NewNop(),
+		rt:      rt,
+		clients: &fixedPool{err: errors.New("some Get error")},
+		rec:     comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
+		skewRec: comm.NewSkewRecorder(),
+		logger:  zap.NewNop(),
 	}
 
 	err := l.bootstrapPeers(seeds)
@@ -160,7 +182,7 @@ func TestLibrarian_bootstrapPeers_introduceErr(t *testing.T) {
 	backoffMaxElapsedTime = 5 * time.Second
 	rt := routing.NewEmpty(id.NewPseudoRandom(rng), p, d, routing.NewDefaultParameters())
 	l := &Librarian{
-		config: NewDefaultConfig(),
+		config: newNoStartDelayConfig(),
 		peerID: ecid.NewPseudoRandom(rng),
 		introducer: &fixedIntroducer{
 			err: errors.New("some fatal introduce error"),
@@ -193,7 +215,7 @@ func TestLibrarian_bootstrapPeers_noResponsesErr(t *testing.T) {
 	p, d := &fixedPreferer{}, &fixedDoctor{}
 	rt := routing.NewEmpty(id.NewPseudoRandom(rng), p, d, routing.NewDefaultParameters())
 	l := &Librarian{
-		config: NewDefaultConfig().WithPublicAddr(publicAddr).WithLogLevel(zapcore.DebugLevel),
+		config: newNoStartDelayConfig().WithPublicAddr(publicAddr).WithLogLevel(zapcore.DebugLevel),
 		peerID: ecid.NewPseudoRandom(rng),
 		introducer: &fixedIntroducer{
 			result: fixedResult,
@@ -206,6 +228,48 @@ func TestLibrarian_bootstrapPeers_noResponsesErr(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestStartDelay(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	assert.Zero(t, startDelay(0, rng))
+	for c := 0; c < 16; c++ {
+		delay := startDelay(time.Second, rng)
+		assert.True(t, delay >= 0)
+		assert.True(t, delay < time.Second)
+	}
+}
+
+func TestLibrarian_withSeedAddrs(t *testing.T) {
+	bootstraps := []*net.TCPAddr{peer.NewTestPublicAddr(0), peer.NewTestPublicAddr(1)}
+	seeded := []*net.TCPAddr{peer.NewTestPublicAddr(1), peer.NewTestPublicAddr(2)}
+	l := &Librarian{
+		config: NewDefaultConfig(),
+		seeder: &fixedSeeder{addrs: seeded},
+		logger: zap.NewNop(),
+	}
+
+	// no seed hosts means just the bootstraps
+	assert.Equal(t, bootstraps, l.withSeedAddrs(bootstraps))
+
+	// seeded addresses are added to the bootstraps without duplicates
+	l.config.WithSeedHosts([]string{"seeds.example.com"})
+	addrs := l.withSeedAddrs(bootstraps)
+	assert.Equal(t, []*net.TCPAddr{bootstraps[0], bootstraps[1], seeded[1]}, addrs)
+	assert.Len(t, bootstraps, 2)
+
+	// seed error should fall back to just the bootstraps
+	l.seeder = &fixedSeeder{err: errors.New("some Seed error")}
+	assert.Equal(t, bootstraps, l.withSeedAddrs(bootstraps))
+}
+
+type fixedSeeder struct {
+	addrs []*net.TCPAddr
+	err   error
+}
+
+func (f *fixedSeeder) Seed(hosts []string) ([]*net.TCPAddr, error) {
+	return f.addrs, f.err
+}
+
 type fixedIntroducer struct {
 	result *introduce.Result
 	err    error
@@ -215,3 +279,41 @@ func (fi *fixedIntroducer) Introduce(intro *introduce.Introduction, seeds []peer
 	intro.Result = fi.result
 	return fi.err
 }
+
+func TestLibrarian_compactRoutingTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := routing.NewTestWithPeers(rng, 8)
+	config := NewDefaultConfig()
+	config.Routing.CompactInterval = 10 * time.Millisecond
+	l := &Librarian{
+		config: config,
+		rt:     rt,
+		logger: zap.NewNop(),
+		stop:   make(chan struct{}),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		l.compactRoutingTable()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(l.stop)
+	<-done
+
+	// disabled compaction returns immediately
+	config.Routing.CompactInterval = 0
+	l.stop = make(chan struct{})
+	l.compactRoutingTable()
+
+	// as does a negative interval instead of panicking
+	config.Routing.CompactInterval = -time.Second
+	l.compactRoutingTable()
+}
+
+// newNoStartDelayConfig returns a default config that bootstraps without a random delay.
+func newNoStartDelayConfig() *Config {
+	config := NewDefaultConfig()
+	config.Introduce.MaxStartDelay = 0
+	return config
+}
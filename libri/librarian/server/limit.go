@@ -0,0 +1,28 @@
+package server
+
+import (
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkRequesterLimit checks that the requester hasn't recently made too many requests on the
+// endpoint, with the rates of the organization signing its request, if any. When it has, it
+// returns a ResourceExhausted grpc status error and hints in the response trailer how long the
+// requester should wait before retrying.
+func (l *Librarian) checkRequesterLimit(
+	ctx context.Context, requesterID id.ID, meta *api.RequestMetadata, endpoint api.Endpoint,
+) error {
+	retryAfter := l.rqLimiter.Take(requesterID, quotaOrgID(ctx, meta), endpoint)
+	if retryAfter == 0 {
+		return nil
+	}
+	// only fails outside a grpc request, e.g., in tests, where there's no one to hint anyway
+	_ = grpc.SetTrailer(ctx, client.NewRetryAfterTrailer(retryAfter))
+	return status.Error(codes.ResourceExhausted, comm.ErrRequesterLimited.Error())
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLibrarian_checkRequesterLimit(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l := &Librarian{
+		rqLimiter: comm.NewRequesterLimiter(&comm.RequesterLimitParameters{
+			PeerRates:    comm.EndpointRates{api.Find: 1},
+			BurstSeconds: 1,
+		}),
+	}
+	requesterID := id.NewPseudoRandom(rng)
+	meta := &api.RequestMetadata{}
+
+	assert.Nil(t, l.checkRequesterLimit(context.Background(), requesterID, meta, api.Find))
+	err := l.checkRequesterLimit(context.Background(), requesterID, meta, api.Find)
+	assert.Equal(t, codes.ResourceExhausted, status.Convert(err).Code())
+
+	// other endpoints unlimited
+	assert.Nil(t, l.checkRequesterLimit(context.Background(), requesterID, meta, api.Store))
+}
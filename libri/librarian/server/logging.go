@@ -14,12 +14,50 @@ const (
 	logRequestIDShort  = "request_id_short"
 	logFromPubKeyShort = "from_pub_key_short"
 	logNPeers          = "n_peers"
+	logNBuckets        = "n_buckets"
 	logKey             = "key"
 	logMac             = "mac"
 	logOperation       = "operation"
 	logNReplicas       = "n_replicas"
 	logSearch          = "search"
 	logStore           = "store"
+	logPeerID          = "peer_id"
+	logAddress         = "address"
+	logSelfClockSkew   = "self_clock_skew"
+	logNCompacted      = "n_compacted"
+	logNDemoted        = "n_demoted"
+	logNSwept          = "n_swept"
+	logDBDriver        = "db_driver"
+	logNBans           = "n_bans"
+	logReason          = "reason"
+	logExpiry          = "expiry"
+	logRemoved         = "removed"
+	logMaxPageSize     = "max_page_size"
+	logIssuedTime      = "issued_time"
+	logNQuotas         = "n_quotas"
+	logOrgID           = "org_id"
+	logLimitBytes      = "limit_bytes"
+	logUsedBytes       = "used_bytes"
+	logDBName          = "db_name"
+	logLowerBound      = "lower_bound"
+	logUpperBound      = "upper_bound"
+	logNRanges         = "n_ranges"
+	logNKeys           = "n_keys"
+	logNPulled         = "n_pulled"
+	logNHints          = "n_hints"
+	logNHandedOff      = "n_handed_off"
+	logNDropped        = "n_dropped"
+	logHint            = "hint"
+	logReadRepair      = "read_repair"
+	logNMissing        = "n_missing"
+	logNRepaired       = "n_repaired"
+	logNHealthy        = "n_healthy"
+	logPuzzleBits      = "puzzle_bits"
+	logNAllowed        = "n_allowed"
+	logMethod          = "method"
+	logStatus          = "status"
+	logRemoteIP        = "remote_ip"
+	logSpilledPeerIDs  = "spilled_peer_ids"
 )
 
 func rqMetadataFields(md *api.RequestMetadata) []zapcore.Field {
@@ -82,10 +120,28 @@ func verifyPeersResponseFields(rq *api.VerifyRequest, rp *api.VerifyResponse) []
 	}
 }
 
-func storeRequestFields(rq *api.StoreRequest) []zapcore.Field {
+func syncRequestFields(rq *api.SyncRequest) []zapcore.Field {
 	return []zapcore.Field{
+		zap.String(logLowerBound, id.Hex(rq.LowerBound)),
+		zap.String(logUpperBound, id.Hex(rq.UpperBound)),
+	}
+}
+
+func syncResponseFields(rq *api.SyncRequest, rp *api.SyncResponse) []zapcore.Field {
+	return append(syncRequestFields(rq),
+		zap.Int(logNRanges, len(rp.Ranges)),
+		zap.Int(logNKeys, len(rp.Keys)),
+	)
+}
+
+func storeRequestFields(rq *api.StoreRequest) []zapcore.Field {
+	fields := []zapcore.Field{
 		zap.String(logKey, id.Hex(rq.Key)),
 	}
+	if rq.Hint != nil {
+		fields = append(fields, zap.String(logHint, id.Hex(rq.Hint.PeerId)))
+	}
+	return fields
 }
 
 func storeResponseFields(rq *api.StoreRequest, _ *api.StoreResponse) []zapcore.Field {
@@ -97,6 +153,7 @@ func storeResponseFields(rq *api.StoreRequest, _ *api.StoreResponse) []zapcore.F
 func getRequestFields(rq *api.GetRequest) []zapcore.Field {
 	return []zapcore.Field{
 		zap.String(logKey, id.Hex(rq.Key)),
+		zap.Bool(logReadRepair, rq.ReadRepair),
 	}
 }
 
@@ -126,6 +183,18 @@ func putResponseFields(rq *api.PutRequest, rp *api.PutResponse) []zapcore.Field
 	}
 }
 
+// spilledFields returns the IDs of the peers outside the closest set that stored the value in
+// spillover mode.
+func spilledFields(s *store.Store) []zapcore.Field {
+	peerIDs := make([]string, len(s.Result.Spilled))
+	for i, p := range s.Result.Spilled {
+		peerIDs[i] = p.ID().String()
+	}
+	return []zapcore.Field{
+		zap.Strings(logSpilledPeerIDs, peerIDs),
+	}
+}
+
 func storeDetailFields(s *store.Store) []zapcore.Field {
 	return []zapcore.Field{
 		zap.Object(logStore, s),
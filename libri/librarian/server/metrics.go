@@ -15,6 +15,8 @@ const (
 	envelopeLabel = "envelope"
 	entryLabel    = "entry"
 	pageLabel     = "page"
+	systemLabel   = "system"
+	shardLabel    = "shard"
 )
 
 var (
@@ -24,6 +26,10 @@ var (
 	entrySizeKey     = []byte("entry_stored_size")
 	pageCountKey     = []byte("page_stored_count")
 	pageSizeKey      = []byte("page_stored_size")
+	systemCountKey   = []byte("system_stored_count")
+	systemSizeKey    = []byte("system_stored_size")
+	shardCountKey    = []byte("shard_stored_count")
+	shardSizeKey     = []byte("shard_stored_size")
 )
 
 type storageMetrics struct {
@@ -93,6 +99,24 @@ func (sm *storageMetrics) Add(doc *api.Document) error {
 		}
 		sm.count.WithLabelValues(pageLabel).Inc()
 		sm.size.WithLabelValues(pageLabel).Add(float64(len(bytes)))
+	case *api.Document_System:
+		if err := sm.storedMetricAdd(systemCountKey, 1); err != nil {
+			return err
+		}
+		if err := sm.storedMetricAdd(systemSizeKey, uint64(len(bytes))); err != nil {
+			return err
+		}
+		sm.count.WithLabelValues(systemLabel).Inc()
+		sm.size.WithLabelValues(systemLabel).Add(float64(len(bytes)))
+	case *api.Document_Shard, *api.Document_ShardManifest:
+		if err := sm.storedMetricAdd(shardCountKey, 1); err != nil {
+			return err
+		}
+		if err := sm.storedMetricAdd(shardSizeKey, uint64(len(bytes))); err != nil {
+			return err
+		}
+		sm.count.WithLabelValues(shardLabel).Inc()
+		sm.size.WithLabelValues(shardLabel).Add(float64(len(bytes)))
 	}
 	return nil
 }
@@ -122,6 +146,22 @@ func (sm *storageMetrics) initFromStorage() {
 	value, err = sm.getStored(pageSizeKey)
 	errors.MaybePanic(err)
 	sm.size.WithLabelValues(pageLabel).Add(float64(value))
+
+	// system
+	value, err = sm.getStored(systemCountKey)
+	errors.MaybePanic(err)
+	sm.count.WithLabelValues(systemLabel).Add(float64(value))
+	value, err = sm.getStored(systemSizeKey)
+	errors.MaybePanic(err)
+	sm.size.WithLabelValues(systemLabel).Add(float64(value))
+
+	// shard
+	value, err = sm.getStored(shardCountKey)
+	errors.MaybePanic(err)
+	sm.count.WithLabelValues(shardLabel).Add(float64(value))
+	value, err = sm.getStored(shardSizeKey)
+	errors.MaybePanic(err)
+	sm.size.WithLabelValues(shardLabel).Add(float64(value))
 }
 
 func (sm *storageMetrics) storedMetricAdd(key []byte, amount uint64) error {
@@ -32,7 +32,9 @@ func TestStorageMetrics_initAdd(t *testing.T) {
 			Page: api.NewTestPage(rng),
 		},
 	}
-	for _, doc := range []*api.Document{envDoc, entryDoc, pageDoc} {
+	sysDoc, _ := api.NewTestSystemDocument(rng)
+	shardDoc, _ := api.NewTestShardDocument(rng)
+	for _, doc := range []*api.Document{envDoc, entryDoc, pageDoc, sysDoc, shardDoc} {
 		err := sm1.Add(doc)
 		assert.Nil(t, err)
 	}
@@ -41,11 +43,13 @@ func TestStorageMetrics_initAdd(t *testing.T) {
 	sm2 := newStorageMetrics(serverSL)
 
 	// check we have a single count for each doc type
-	countMetrics := make(chan prom.Metric, 3)
+	countMetrics := make(chan prom.Metric, 5)
 	expectedLabelValues := map[string]struct{}{
 		"envelope": {},
 		"entry":    {},
 		"page":     {},
+		"system":   {},
+		"shard":    {},
 	}
 	sm2.count.Collect(countMetrics)
 	close(countMetrics)
@@ -60,10 +64,10 @@ func TestStorageMetrics_initAdd(t *testing.T) {
 		actualCountLabelValues[*written.Label[0].Value] = struct{}{}
 		nCountMetrics++
 	}
-	assert.Equal(t, 3, nCountMetrics)
+	assert.Equal(t, 5, nCountMetrics)
 	assert.Equal(t, expectedLabelValues, actualCountLabelValues)
 
-	sizeMetrics := make(chan prom.Metric, 3)
+	sizeMetrics := make(chan prom.Metric, 5)
 	sm2.size.Collect(sizeMetrics)
 	close(sizeMetrics)
 	nSizeMetrics := 0
@@ -77,7 +81,7 @@ func TestStorageMetrics_initAdd(t *testing.T) {
 		actualSizeLabelValues[*written.Label[0].Value] = struct{}{}
 		nSizeMetrics++
 	}
-	assert.Equal(t, 3, nSizeMetrics)
+	assert.Equal(t, 5, nSizeMetrics)
 }
 
 type fixedSL struct {
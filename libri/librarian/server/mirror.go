@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMirrorFetchTimeout is the default timeout for fetching each document of a mirrored
+// publication.
+const DefaultMirrorFetchTimeout = 30 * time.Second
+
+var (
+	errMirrorReadOnly        = errors.New("mirror does not accept stored documents")
+	errMirrorNotFound        = errors.New("mirrored document not found")
+	errMirrorUnexpectedValue = errors.New("mirrored document has unexpected key")
+	errMirrorUnexpectedPub   = errors.New("mirrored publication does not match its envelope")
+)
+
+// MirrorParameters define read-only public mirror mode, in which the librarian replicates and
+// serves the publications of a set of authors but rejects Store and Put requests, so it doesn't
+// participate in general DHT storage. An organization's content is mirrored by listing the
+// public keys of its authors.
+type MirrorParameters struct {
+	// AuthorPubKeys are the public keys of the authors whose publications are mirrored. Mirror
+	// mode is disabled when empty.
+	AuthorPubKeys [][]byte
+
+	// FetchTimeout is the timeout for fetching each document of a mirrored publication.
+	FetchTimeout time.Duration
+}
+
+// NewDefaultMirrorParameters returns a *MirrorParameters object with default values, which leave
+// mirror mode disabled.
+func NewDefaultMirrorParameters() *MirrorParameters {
+	return &MirrorParameters{
+		FetchTimeout: DefaultMirrorFetchTimeout,
+	}
+}
+
+// Enabled returns whether the librarian is a read-only mirror.
+func (p *MirrorParameters) Enabled() bool {
+	return len(p.AuthorPubKeys) > 0
+}
+
+// mirrors returns whether publications by the author with the given public key are mirrored.
+func (p *MirrorParameters) mirrors(authorPubKey []byte) bool {
+	for _, pubKey := range p.AuthorPubKeys {
+		if bytes.Equal(pubKey, authorPubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotMirror returns a FailedPrecondition error if the librarian is a read-only mirror.
+func (l *Librarian) checkNotMirror() error {
+	if l.config.Mirror.Enabled() {
+		return status.Error(codes.FailedPrecondition, errMirrorReadOnly.Error())
+	}
+	return nil
+}
+
+// mirrorPublications fetches and stores the documents of each new publication by a mirrored
+// author until the server stops.
+func (l *Librarian) mirrorPublications() {
+	pubs, done, err := l.subscribeFrom.New()
+	if err != nil {
+		l.logger.Error("unable to subscribe to mirrored publications", zap.Error(err))
+		return
+	}
+	defer func() {
+		// only close done if it's not already
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	}()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case pub, open := <-pubs:
+			if !open {
+				return
+			}
+			if !l.config.Mirror.mirrors(pub.Value.AuthorPublicKey) {
+				continue
+			}
+			if err := l.mirrorPublication(pub.Value); err != nil {
+				l.logger.Warn("unable to mirror publication", zap.Error(err),
+					zap.Stringer(logKey, id.FromBytes(pub.Value.EnvelopeKey)))
+			}
+		}
+	}
+}
+
+// mirrorPublication fetches and stores the publication's envelope, entry, and any entry pages not
+// already stored. Since the publication's author and entry are self-reported, they are checked
+// against the envelope, whose key verifies its contents, before anything else is mirrored.
+func (l *Librarian) mirrorPublication(pub *api.Publication) error {
+	envelope, err := l.mirrorDocument(id.FromBytes(pub.EnvelopeKey), func(doc *api.Document) error {
+		env := doc.GetEnvelope()
+		if env == nil || !bytes.Equal(env.EntryKey, pub.EntryKey) ||
+			!bytes.Equal(env.AuthorPublicKey, pub.AuthorPublicKey) {
+			return errMirrorUnexpectedPub
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	entry, err := l.mirrorDocument(id.FromBytes(envelope.GetEnvelope().EntryKey), nil)
+	if err != nil {
+		return err
+	}
+	for _, pageKey := range entry.GetEntry().GetPageKeys() {
+		if _, err := l.mirrorDocument(id.FromBytes(pageKey), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mirrorDocument returns the document with the given key, fetching and storing it if it isn't
+// already stored. Fetched documents are only stored if they pass the optional check.
+func (l *Librarian) mirrorDocument(
+	key id.ID, check func(doc *api.Document) error,
+) (*api.Document, error) {
+	existing, err := l.documentSL.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	value := existing
+	if value == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), l.config.Mirror.FetchTimeout)
+		defer cancel()
+		if value, err = l.fetchDocument(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	if check != nil {
+		if err := check(value); err != nil {
+			return nil, err
+		}
+	}
+	if existing != nil {
+		return existing, nil
+	}
+	return value, l.documentSL.Store(key, value)
+}
+
+// fetchDocument searches the network for the document with the given key.
+func (l *Librarian) fetchDocument(ctx context.Context, key id.ID) (*api.Document, error) {
+	s := search.NewSearch(l.peerID, l.orgID, key, l.config.Search)
+	defer s.Release()
+	seeds := l.rt.Find(key, s.Params.NClosestResponses)
+	if err := l.searcher.Search(ctx, s, seeds); err != nil {
+		return nil, err
+	}
+	for _, p := range s.Result.Responded {
+		l.rt.Push(p)
+	}
+	if !s.FoundValue() {
+		return nil, errMirrorNotFound
+	}
+	value := s.Result.Value
+	valueKey, err := api.GetKey(value)
+	if err != nil {
+		return nil, err
+	}
+	if valueKey.Cmp(key) != 0 {
+		return nil, errMirrorUnexpectedValue
+	}
+	return value, nil
+}
+
+// loadMirrored returns the document with the given key if the librarian is a mirror storing it
+// and nil otherwise.
+func (l *Librarian) loadMirrored(key id.ID) (*api.Document, error) {
+	if !l.config.Mirror.Enabled() {
+		return nil, nil
+	}
+	return l.documentSL.Load(key)
+}
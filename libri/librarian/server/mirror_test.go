@@ -0,0 +1,234 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/ecid"
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestMirrorParameters_mirrors(t *testing.T) {
+	p := NewDefaultMirrorParameters()
+	assert.False(t, p.Enabled())
+	assert.False(t, p.mirrors([]byte{1, 2, 3}))
+
+	p.AuthorPubKeys = [][]byte{{1, 2, 3}, {4, 5, 6}}
+	assert.True(t, p.Enabled())
+	assert.True(t, p.mirrors([]byte{4, 5, 6}))
+	assert.False(t, p.mirrors([]byte{7, 8, 9}))
+}
+
+func TestLibrarian_checkNotMirror(t *testing.T) {
+	l := &Librarian{config: NewDefaultConfig()}
+	assert.Nil(t, l.checkNotMirror())
+
+	l.config.Mirror.AuthorPubKeys = [][]byte{{1, 2, 3}}
+	assert.Equal(t, codes.FailedPrecondition, getErrCode(t, l.checkNotMirror()))
+}
+
+func TestLibrarian_mirrorPublication_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	docs := make(map[string]*api.Document)
+	page1, pageKey1 := newTestPageDocument(rng)
+	page2, pageKey2 := newTestPageDocument(rng)
+	entry := &api.Document{Contents: &api.Document_Entry{Entry: &api.Entry{
+		AuthorPublicKey:       ecid.NewPseudoRandom(rng).PublicKeyBytes(),
+		CreatedTime:           1,
+		MetadataCiphertextMac: api.RandBytes(rng, 32),
+		MetadataCiphertext:    api.RandBytes(rng, 64),
+		PageKeys:              [][]byte{pageKey1.Bytes(), pageKey2.Bytes()},
+	}}}
+	entryKey, err := api.GetKey(entry)
+	assert.Nil(t, err)
+	envelope := &api.Document{Contents: &api.Document_Envelope{
+		Envelope: api.NewTestEnvelope(rng),
+	}}
+	envelope.GetEnvelope().EntryKey = entryKey.Bytes()
+	envelopeKey, err := api.GetKey(envelope)
+	assert.Nil(t, err)
+	for key, doc := range map[id.ID]*api.Document{
+		pageKey1: page1, pageKey2: page2, entryKey: entry, envelopeKey: envelope,
+	} {
+		docs[key.String()] = doc
+	}
+
+	l := newMirrorLibrarian(rng, &keyedSearcher{docs: docs})
+	pub := &api.Publication{
+		EnvelopeKey:     envelopeKey.Bytes(),
+		EntryKey:        entryKey.Bytes(),
+		AuthorPublicKey: envelope.GetEnvelope().AuthorPublicKey,
+	}
+	err = l.mirrorPublication(pub)
+	assert.Nil(t, err)
+	for _, key := range []id.ID{envelopeKey, entryKey, pageKey1, pageKey2} {
+		stored, err := l.documentSL.Load(key)
+		assert.Nil(t, err)
+		assert.Equal(t, docs[key.String()], stored)
+	}
+
+	// mirroring again doesn't need to search since all documents are stored
+	l.searcher = &keyedSearcher{err: errors.New("should not search")}
+	assert.Nil(t, l.mirrorPublication(pub))
+}
+
+func TestLibrarian_mirrorPublication_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pub := api.NewTestPublication(rng)
+
+	// search error
+	l := newMirrorLibrarian(rng, &keyedSearcher{err: errors.New("some Search error")})
+	assert.NotNil(t, l.mirrorPublication(pub))
+
+	// envelope not found
+	l = newMirrorLibrarian(rng, &keyedSearcher{docs: map[string]*api.Document{}})
+	assert.Equal(t, errMirrorNotFound, l.mirrorPublication(pub))
+
+	// publication's self-reported author or entry doesn't match its envelope
+	envelope := &api.Document{Contents: &api.Document_Envelope{
+		Envelope: api.NewTestEnvelope(rng),
+	}}
+	envelopeKey, err := api.GetKey(envelope)
+	assert.Nil(t, err)
+	docs := map[string]*api.Document{envelopeKey.String(): envelope}
+	badPubs := []*api.Publication{
+		{
+			EnvelopeKey:     envelopeKey.Bytes(),
+			EntryKey:        envelope.GetEnvelope().EntryKey,
+			AuthorPublicKey: ecid.NewPseudoRandom(rng).PublicKeyBytes(),
+		},
+		{
+			EnvelopeKey:     envelopeKey.Bytes(),
+			EntryKey:        id.NewPseudoRandom(rng).Bytes(),
+			AuthorPublicKey: envelope.GetEnvelope().AuthorPublicKey,
+		},
+	}
+	for _, badPub := range badPubs {
+		l = newMirrorLibrarian(rng, &keyedSearcher{docs: docs})
+		assert.Equal(t, errMirrorUnexpectedPub, l.mirrorPublication(badPub))
+		stored, err := l.documentSL.Load(envelopeKey)
+		assert.Nil(t, err)
+		assert.Nil(t, stored)
+	}
+
+	// publication's envelope is not an envelope
+	page, pageKey := newTestPageDocument(rng)
+	l = newMirrorLibrarian(rng, &keyedSearcher{docs: map[string]*api.Document{
+		pageKey.String(): page,
+	}})
+	err = l.mirrorPublication(&api.Publication{EnvelopeKey: pageKey.Bytes()})
+	assert.Equal(t, errMirrorUnexpectedPub, err)
+}
+
+func TestLibrarian_fetchDocument_unexpectedValue(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, _ := api.NewTestDocument(rng)
+	key := id.NewPseudoRandom(rng)
+	searcher := &keyedSearcher{docs: map[string]*api.Document{key.String(): value}}
+	l := newMirrorLibrarian(rng, searcher)
+
+	fetched, err := l.fetchDocument(context.Background(), key)
+	assert.Equal(t, errMirrorUnexpectedValue, err)
+	assert.Nil(t, fetched)
+}
+
+func TestLibrarian_loadMirrored(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	l := newMirrorLibrarian(rng, &keyedSearcher{})
+	assert.Nil(t, l.documentSL.Store(key, value))
+
+	loaded, err := l.loadMirrored(key)
+	assert.Nil(t, err)
+	assert.Equal(t, value, loaded)
+
+	// non-mirrors always search
+	l.config.Mirror.AuthorPubKeys = nil
+	loaded, err = l.loadMirrored(key)
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
+}
+
+func TestLibrarian_Get_mirrored(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	l := newMirrorLibrarian(rng, &keyedSearcher{err: errors.New("should not search")})
+	assert.Nil(t, l.documentSL.Store(key, value))
+
+	rq := client.NewGetRequest(peerID, orgID, key)
+	rp, err := l.Get(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, value, rp.Value)
+	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+}
+
+func TestLibrarian_Store_mirrorErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	l := newMirrorLibrarian(rng, &keyedSearcher{})
+	value, key := api.NewTestDocument(rng)
+	rq := client.NewStoreRequest(peerID, orgID, key, value)
+
+	rp, err := l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.FailedPrecondition, getErrCode(t, err))
+	qo := l.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Store)
+	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
+}
+
+func newMirrorLibrarian(rng *rand.Rand, searcher search.Searcher) *Librarian {
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 8)
+	config := NewDefaultConfig()
+	config.Mirror.AuthorPubKeys = [][]byte{{1, 2, 3}}
+	return &Librarian{
+		peerID:     peerID,
+		config:     config,
+		rt:         rt,
+		documentSL: storage.NewDocumentSLD(db.NewMemoryDB()),
+		kc:         storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:        storage.NewHashKeyValueChecker(),
+		searcher:   searcher,
+		rqv:        &alwaysRequestVerifier{},
+		rec:        comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
+		allower:    &fixedAllower{},
+		rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		logger:     zap.NewNop(), // clogging.NewDevInfoLogger(),
+	}
+}
+
+func newTestPageDocument(rng *rand.Rand) (*api.Document, id.ID) {
+	doc := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	key, err := api.GetKey(doc)
+	cerrors.MaybePanic(err)
+	return doc, key
+}
+
+// keyedSearcher finds the document with the search's key, if it has one.
+type keyedSearcher struct {
+	docs map[string]*api.Document
+	err  error
+}
+
+func (s *keyedSearcher) Search(ctx context.Context, se *search.Search, seeds []peer.Peer) error {
+	if s.err != nil {
+		return s.err
+	}
+	se.Result = search.NewInitialResult(se.Key, se.Params)
+	se.Result.Value = s.docs[se.Key.String()]
+	return nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"go.uber.org/zap"
+)
+
+// networkParamsTimeout is the timeout of each request for the network parameters.
+const networkParamsTimeout = 5 * time.Second
+
+// AdoptNetworkParameters sets the config values recommended by the network parameters, leaving
+// those they don't recommend unchanged.
+func (c *Config) AdoptNetworkParameters(params *api.NetworkParameters) *Config {
+	if params.NReplicas > 0 {
+		c.Store.NReplicas = uint(params.NReplicas)
+	}
+	if params.MaxPageSize > 0 {
+		c.MaxPageSize = params.MaxPageSize
+	}
+	if len(params.WorkerPoolSizes) > 0 && c.WorkerPoolSizes == nil {
+		c.WorkerPoolSizes = make(WorkerPoolSizes)
+	}
+	for name, size := range params.WorkerPoolSizes {
+		if e, ok := endpointByName(name); ok {
+			c.WorkerPoolSizes[e] = uint(size)
+		}
+	}
+	if len(params.RequesterPeerRates) > 0 || len(params.RequesterOrgRates) > 0 {
+		if c.RequesterLimit == nil {
+			c.WithDefaultRequesterLimit()
+		}
+		c.RequesterLimit.PeerRates = adoptEndpointRates(c.RequesterLimit.PeerRates,
+			params.RequesterPeerRates)
+		c.RequesterLimit.OrgRates = adoptEndpointRates(c.RequesterLimit.OrgRates,
+			params.RequesterOrgRates)
+	}
+	return c
+}
+
+func adoptEndpointRates(
+	rates comm.EndpointRates, recommended map[string]float64,
+) comm.EndpointRates {
+	if rates == nil {
+		rates = make(comm.EndpointRates)
+	}
+	for name, rate := range recommended {
+		if e, ok := endpointByName(name); ok && rate > 0 {
+			rates[e] = rate
+		}
+	}
+	return rates
+}
+
+func endpointByName(name string) (api.Endpoint, bool) {
+	for _, e := range api.Endpoints {
+		if e.String() == name {
+			return e, true
+		}
+	}
+	return api.All, false
+}
+
+// fetchNetworkParameters gets the network parameters from the bootstrap peers and adopts them.
+// Since they are only recommendations, failing to get them isn't fatal.
+func fetchNetworkParameters(config *Config, logger *zap.Logger) {
+	_, clientCreds, err := client.NewTLSCredentials(config.TLS)
+	if err != nil {
+		logger.Warn("unable to load network parameters client TLS credentials", zap.Error(err))
+		return
+	}
+	clients, err := client.NewDefaultLRUPoolWithInterceptors(
+		client.WithCredentials(config.ClientInterceptors, clientCreds))
+	if err != nil {
+		logger.Warn("unable to create network parameters client pool", zap.Error(err))
+		return
+	}
+	defer func() {
+		if err := clients.CloseAll(); err != nil {
+			logger.Warn("error closing network parameters clients", zap.Error(err))
+		}
+	}()
+	lcs := make([]api.Getter, 0, len(config.BootstrapAddrs))
+	for _, addr := range config.BootstrapAddrs {
+		lc, err := clients.Get(addr.String())
+		if err != nil {
+			continue
+		}
+		lcs = append(lcs, lc)
+	}
+
+	// librarian's own ID may not exist yet, so use a throwaway one
+	clientID := ecid.NewRandom()
+	orgSigner := client.NewEmptySigner()
+	if config.OrgID != nil {
+		orgSigner = client.NewECDSASigner(config.OrgID.Key())
+	}
+	getter := client.NewNetworkParametersGetter(clientID, config.OrgID,
+		client.NewECDSASigner(clientID.Key()), orgSigner,
+		ecid.ToPublicKeyBytes(config.NetworkParamsPubKey), networkParamsTimeout)
+	adoptNetworkParameters(config, getter, lcs, logger)
+}
+
+func adoptNetworkParameters(
+	config *Config, getter client.NetworkParametersGetter, lcs []api.Getter, logger *zap.Logger,
+) {
+	params, err := getter.Get(lcs)
+	if err != nil {
+		logger.Warn("unable to get network parameters, using local values", zap.Error(err))
+		return
+	}
+	config.AdoptNetworkParameters(params)
+	logger.Info("adopted network parameters",
+		zap.Uint32(logNReplicas, params.NReplicas),
+		zap.Uint32(logMaxPageSize, params.MaxPageSize),
+		zap.Int64(logIssuedTime, params.IssuedTime),
+	)
+}
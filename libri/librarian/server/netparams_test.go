@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestConfig_AdoptNetworkParameters(t *testing.T) {
+	c := NewDefaultConfig()
+	c.AdoptNetworkParameters(&api.NetworkParameters{
+		NReplicas:   5,
+		MaxPageSize: 1024,
+		WorkerPoolSizes: map[string]uint32{
+			api.Store.String(): 8,
+			"NotAnEndpoint":    4,
+		},
+		RequesterPeerRates: map[string]float64{
+			api.Find.String(): 25,
+			"NotAnEndpoint":   4,
+		},
+		RequesterOrgRates: map[string]float64{api.Put.String(): 50},
+	})
+	assert.Equal(t, uint(5), c.Store.NReplicas)
+	assert.Equal(t, uint32(1024), c.MaxPageSize)
+	assert.Equal(t, uint(8), c.WorkerPoolSizes[api.Store])
+	assert.Equal(t, NewDefaultWorkerPoolSizes()[api.Get], c.WorkerPoolSizes[api.Get])
+	assert.Len(t, c.WorkerPoolSizes, len(NewDefaultWorkerPoolSizes()))
+	defaultLimit := comm.NewDefaultRequesterLimitParameters()
+	assert.Equal(t, 25.0, c.RequesterLimit.PeerRates[api.Find])
+	assert.Equal(t, defaultLimit.PeerRates[api.Store], c.RequesterLimit.PeerRates[api.Store])
+	assert.Len(t, c.RequesterLimit.PeerRates, len(defaultLimit.PeerRates))
+	assert.Equal(t, 50.0, c.RequesterLimit.OrgRates[api.Put])
+	assert.Equal(t, defaultLimit.OrgRates[api.Store], c.RequesterLimit.OrgRates[api.Store])
+
+	// zero values leave local settings unchanged
+	c2 := NewDefaultConfig()
+	c2.AdoptNetworkParameters(&api.NetworkParameters{})
+	assert.Equal(t, NewDefaultConfig().Store, c2.Store)
+	assert.Equal(t, api.DefaultMaxPageSize, c2.MaxPageSize)
+	assert.Equal(t, NewDefaultWorkerPoolSizes(), c2.WorkerPoolSizes)
+	assert.Equal(t, comm.NewDefaultRequesterLimitParameters(), c2.RequesterLimit)
+
+	// rates are adopted even without a local requester limit
+	c3 := NewDefaultConfig()
+	c3.RequesterLimit = nil
+	c3.AdoptNetworkParameters(&api.NetworkParameters{
+		RequesterOrgRates: map[string]float64{api.Put.String(): 50},
+	})
+	assert.Equal(t, 50.0, c3.RequesterLimit.OrgRates[api.Put])
+}
+
+func TestAdoptNetworkParameters(t *testing.T) {
+	// ok
+	c := NewDefaultConfig()
+	getter := &fixedNetworkParametersGetter{params: &api.NetworkParameters{NReplicas: 5}}
+	adoptNetworkParameters(c, getter, nil, zap.NewNop())
+	assert.Equal(t, uint(5), c.Store.NReplicas)
+
+	// Get error leaves local values
+	c = NewDefaultConfig()
+	getter = &fixedNetworkParametersGetter{err: errors.New("some Get error")}
+	adoptNetworkParameters(c, getter, nil, zap.NewNop())
+	assert.Equal(t, NewDefaultConfig().Store, c.Store)
+}
+
+type fixedNetworkParametersGetter struct {
+	params *api.NetworkParameters
+	err    error
+}
+
+func (f *fixedNetworkParametersGetter) Get(lcs []api.Getter) (*api.NetworkParameters, error) {
+	return f.params, f.err
+}
@@ -0,0 +1,35 @@
+package peer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// testdata/peer.golden was serialized in the original storage.Peer format, with only an ID, name,
+// and public address, and stands in for peers' on-disk state. It must never be regenerated: if
+// this test fails, the change to the persistence format is breaking and needs a migration
+// instead.
+
+func TestGolden_FromStored(t *testing.T) {
+	buf, err := ioutil.ReadFile(filepath.Join("testdata", "peer.golden"))
+	assert.Nil(t, err)
+	stored := &storage.Peer{}
+	assert.Nil(t, proto.Unmarshal(buf, stored))
+
+	p := FromStored(stored)
+	assert.Equal(t, bytes.Repeat([]byte{1}, 32), p.ID().Bytes())
+	assert.Equal(t, "peer-001", p.(*peer).name)
+	assert.Equal(t, "", p.Zone())
+	expected := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 20100}
+	assert.Equal(t, expected.String(), p.Address().String())
+	assert.Len(t, p.Addresses(), 1)
+	_, hasLatency := p.Latency()
+	assert.False(t, hasLatency)
+}
@@ -1,12 +1,18 @@
 package peer
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/server/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -14,14 +20,51 @@ const (
 	MissingName = "MISING_NAME"
 )
 
+var errNoAddresses = errors.New("peer has no addresses")
+
 // Peer represents a peer in the network.
 type Peer interface {
 	// ID returns the peer ID.
 	ID() id.ID
 
-	// Address returns the public address of the peer.
+	// Address returns the preferred public address of the peer, which is the address that most
+	// recently succeeded or, if none has, the first address.
 	Address() *net.TCPAddr
 
+	// Zone returns the operator-assigned failure domain (e.g., zone or region) of the peer, or
+	// an empty string if it has none.
+	Zone() string
+
+	// Addresses returns all public addresses of the peer, starting with the preferred address
+	// and followed by the rest in preference order.
+	Addresses() []*net.TCPAddr
+
+	// RecordSuccess records that a query to the given address succeeded, making it the
+	// preferred address.
+	RecordSuccess(address *net.TCPAddr)
+
+	// RecordClockSkew records how far ahead of self the peer's clock appeared to be in its most
+	// recent Introduce response.
+	RecordClockSkew(skew time.Duration)
+
+	// ClockSkew returns the most recently recorded clock skew of the peer and whether one has
+	// been recorded.
+	ClockSkew() (time.Duration, bool)
+
+	// RecordBuildInfo records the peer's most recently verified build info.
+	RecordBuildInfo(info *api.BuildInfo)
+
+	// BuildInfo returns the peer's most recently verified build info or nil if none has been
+	// recorded.
+	BuildInfo() *api.BuildInfo
+
+	// RecordLatency records the moving average round-trip time of queries to the peer.
+	RecordLatency(latency time.Duration)
+
+	// Latency returns the most recently recorded moving average round-trip time of queries to
+	// the peer and whether one has been recorded.
+	Latency() (time.Duration, bool)
+
 	// Merge merges another peer into the existing peer. If there is any conflicting information
 	// between the two, the merge returns an error.
 	Merge(other Peer) error
@@ -37,18 +80,54 @@ type peer struct {
 	// 256-bit ID
 	id id.ID
 
-	address *net.TCPAddr
+	// public addresses in preference order
+	addresses []*net.TCPAddr
+
+	// index in addresses of the address that most recently succeeded
+	lastSuccess int
 
 	// self-reported name
 	name string
+
+	// self-reported failure domain
+	zone string
+
+	// most recently measured clock skew relative to self, if measured
+	clockSkew    time.Duration
+	hasClockSkew bool
+
+	// most recently verified build info, if any
+	buildInfo *api.BuildInfo
+
+	// moving average query round-trip time, if measured
+	latency    time.Duration
+	hasLatency bool
+
+	mu sync.Mutex
 }
 
 // New creates a new Peer instance with empty response stats.
 func New(id id.ID, name string, address *net.TCPAddr) Peer {
+	if address == nil {
+		return NewWithAddresses(id, name, nil)
+	}
+	return NewWithAddresses(id, name, []*net.TCPAddr{address})
+}
+
+// NewWithAddresses creates a new Peer instance with the given public addresses in preference
+// order, e.g., an IPv6 address followed by an IPv4 address for a dual-stack peer.
+func NewWithAddresses(id id.ID, name string, addresses []*net.TCPAddr) Peer {
+	return NewWithZone(id, name, "", addresses)
+}
+
+// NewWithZone creates a new Peer instance in the given failure domain (e.g., zone or region)
+// with the given public addresses in preference order.
+func NewWithZone(id id.ID, name string, zone string, addresses []*net.TCPAddr) Peer {
 	return &peer{
-		id:      id,
-		address: address,
-		name:    name,
+		id:        id,
+		addresses: addresses,
+		name:      name,
+		zone:      zone,
 	}
 }
 
@@ -61,8 +140,77 @@ func (p *peer) ID() id.ID {
 	return p.id
 }
 
+func (p *peer) Zone() string {
+	return p.zone
+}
+
 func (p *peer) Address() *net.TCPAddr {
-	return p.address
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.addresses) == 0 {
+		return nil
+	}
+	return p.addresses[p.lastSuccess]
+}
+
+func (p *peer) Addresses() []*net.TCPAddr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.addresses) == 0 {
+		return nil
+	}
+	addresses := make([]*net.TCPAddr, 0, len(p.addresses))
+	addresses = append(addresses, p.addresses[p.lastSuccess])
+	for i, address := range p.addresses {
+		if i != p.lastSuccess {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+func (p *peer) RecordSuccess(address *net.TCPAddr) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i := indexOf(p.addresses, address); i >= 0 {
+		p.lastSuccess = i
+	}
+}
+
+func (p *peer) RecordClockSkew(skew time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clockSkew, p.hasClockSkew = skew, true
+}
+
+func (p *peer) ClockSkew() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.clockSkew, p.hasClockSkew
+}
+
+func (p *peer) RecordBuildInfo(info *api.BuildInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buildInfo = info
+}
+
+func (p *peer) BuildInfo() *api.BuildInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buildInfo
+}
+
+func (p *peer) RecordLatency(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency, p.hasLatency = latency, true
+}
+
+func (p *peer) Latency() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latency, p.hasLatency
 }
 
 func (p *peer) Merge(other Peer) error {
@@ -73,27 +221,156 @@ func (p *peer) Merge(other Peer) error {
 	if other.(*peer).name != "" {
 		p.name = other.(*peer).name
 	}
-	if p.Address().String() != other.Address().String() {
-		p.address = other.Address()
+	if other.Zone() != "" {
+		p.zone = other.Zone()
+	}
+	if skew, ok := other.ClockSkew(); ok {
+		p.RecordClockSkew(skew)
+	}
+	if info := other.BuildInfo(); info != nil {
+		p.RecordBuildInfo(info)
+	}
+	if latency, ok := other.Latency(); ok {
+		p.RecordLatency(latency)
+	}
+	otherAddresses := other.(*peer).preferenceOrder()
+	otherLastSuccess := other.(*peer).lastSuccessIndex()
+	if len(otherAddresses) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !addressesEqual(p.addresses, otherAddresses) {
+		// keep the preferred address if the other peer also has it, otherwise prefer the
+		// other peer's preferred address
+		prevPreferred := p.addresses
+		if len(p.addresses) > 0 {
+			prevPreferred = p.addresses[p.lastSuccess : p.lastSuccess+1]
+		}
+		p.addresses, p.lastSuccess = otherAddresses, otherLastSuccess
+		if len(prevPreferred) > 0 {
+			if i := indexOf(p.addresses, prevPreferred[0]); i >= 0 {
+				p.lastSuccess = i
+			}
+		}
 	}
 	return nil
 }
 
 func (p *peer) ToStored() *storage.Peer {
+	addresses := p.preferenceOrder()
+	storedAddresses := make([]*storage.Address, len(addresses))
+	for i, address := range addresses {
+		storedAddresses[i] = toStoredAddress(address)
+	}
+	latency, _ := p.Latency()
 	return &storage.Peer{
 		Id:            p.id.Bytes(),
 		Name:          p.name,
 		PublicAddress: toStoredAddress(p.Address()),
+		Addresses:     storedAddresses,
+		Zone:          p.zone,
+		LatencyNanos:  int64(latency),
 	}
 }
 
 func (p *peer) ToAPI() *api.PeerAddress {
-	return &api.PeerAddress{
-		PeerId:   p.id.Bytes(),
-		PeerName: p.name,
-		Ip:       p.Address().IP.String(),
-		Port:     uint32(p.Address().Port),
+	pa := FromAddresses(p.id, p.name, p.Addresses())
+	pa.Zone = p.zone
+	return pa
+}
+
+// preferenceOrder returns the addresses in their original preference order.
+func (p *peer) preferenceOrder() []*net.TCPAddr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*net.TCPAddr(nil), p.addresses...)
+}
+
+func (p *peer) lastSuccessIndex() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastSuccess
+}
+
+// QueryAddresses calls query with each of the peer's addresses, starting with the preferred one,
+// until one succeeds, recording the address that succeeded so it is tried first next time. It
+// only falls back to the next address when the query couldn't connect to the peer (see
+// isConnectivityErr), since other errors would just recur at another address. If none succeed,
+// it returns the error from the last address tried.
+func QueryAddresses(p Peer, query func(address *net.TCPAddr) error) error {
+	err := errNoAddresses
+	for _, address := range p.Addresses() {
+		if err = query(address); err == nil {
+			p.RecordSuccess(address)
+			return nil
+		}
+		if !isConnectivityErr(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// isConnectivityErr returns whether the error is from failing to dial or reach an address rather
+// than from the peer's handling of the query.
+func isConnectivityErr(err error) bool {
+	if _, ok := err.(net.Error); ok {
+		return true
 	}
+	return status.Code(err) == codes.Unavailable
+}
+
+// SameAddresses returns whether two peers have the same set of addresses, regardless of
+// preference order.
+func SameAddresses(p1, p2 Peer) bool {
+	as1, as2 := p1.Addresses(), p2.Addresses()
+	if len(as1) != len(as2) {
+		return false
+	}
+	for _, a := range as2 {
+		if indexOf(as1, a) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WithoutAddresses returns a copy of the peer without any addresses, so that merging it into
+// another peer updates only the name, zone, clock skew, build info, and latency.
+func WithoutAddresses(p Peer) Peer {
+	stub := NewWithZone(p.ID(), p.(*peer).name, p.Zone(), nil)
+	if skew, ok := p.ClockSkew(); ok {
+		stub.RecordClockSkew(skew)
+	}
+	if info := p.BuildInfo(); info != nil {
+		stub.RecordBuildInfo(info)
+	}
+	if latency, ok := p.Latency(); ok {
+		stub.RecordLatency(latency)
+	}
+	return stub
+}
+
+func indexOf(addresses []*net.TCPAddr, address *net.TCPAddr) int {
+	for i, a := range addresses {
+		if a.String() == address.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+func addressesEqual(as1, as2 []*net.TCPAddr) bool {
+	if len(as1) != len(as2) {
+		return false
+	}
+	for i := range as1 {
+		if as1[i].String() != as2[i].String() {
+			return false
+		}
+	}
+	return true
 }
 
 // ToAPIs converts a list of peers into a list of api.PeerAddress objects.
@@ -119,10 +396,11 @@ func NewFromer() Fromer {
 }
 
 func (f *fromer) FromAPI(apiAddress *api.PeerAddress) Peer {
-	return New(
+	return NewWithZone(
 		id.FromBytes(apiAddress.PeerId),
 		apiAddress.PeerName,
-		ToAddress(apiAddress),
+		apiAddress.Zone,
+		ToAddresses(apiAddress),
 	)
 }
 
@@ -134,6 +412,47 @@ func ToAddress(addr *api.PeerAddress) *net.TCPAddr {
 	}
 }
 
+// ToAddresses creates the net.TCPAddrs in preference order from an api.PeerAddress, skipping any
+// additional addresses that don't parse.
+func ToAddresses(addr *api.PeerAddress) []*net.TCPAddr {
+	addresses := make([]*net.TCPAddr, 1, 1+len(addr.AltAddresses))
+	addresses[0] = ToAddress(addr)
+	for _, alt := range addr.AltAddresses {
+		if address := parseAddress(alt); address != nil {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// parseAddress parses an "ip:port" address without resolving any host names, returning nil if it
+// isn't one.
+func parseAddress(address string) *net.TCPAddr {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if ip == nil || err != nil {
+		return nil
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}
+}
+
+// FromAddresses creates an api.PeerAddress from net.TCPAddrs in preference order, the first of
+// which must exist.
+func FromAddresses(id id.ID, name string, addrs []*net.TCPAddr) *api.PeerAddress {
+	pa := FromAddress(id, name, addrs[0])
+	if len(addrs) > 1 {
+		pa.AltAddresses = make([]string, len(addrs)-1)
+		for i, addr := range addrs[1:] {
+			pa.AltAddresses[i] = addr.String()
+		}
+	}
+	return pa
+}
+
 // FromAddress creates an api.PeerAddress from a net.TCPAddr.
 func FromAddress(id id.ID, name string, addr *net.TCPAddr) *api.PeerAddress {
 	return &api.PeerAddress{
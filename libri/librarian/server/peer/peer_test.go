@@ -1,13 +1,17 @@
 package peer
 
 import (
+	"errors"
 	"math/rand"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNew(t *testing.T) {
@@ -104,6 +108,24 @@ func TestFromer_FromAPI(t *testing.T) {
 	assert.Equal(t, p1.Address(), p2.Address())
 }
 
+func TestFromer_FromAPI_addresses(t *testing.T) {
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.1"), Port: 1000},
+	}
+	p1 := NewWithAddresses(id.FromInt64(1), "", addrs)
+	apiP := p1.ToAPI()
+	assert.Equal(t, []string{"192.168.1.1:1000"}, apiP.AltAddresses)
+
+	p2 := NewFromer().FromAPI(apiP)
+	assert.Equal(t, addrs[0].String(), p2.Address().String())
+	assert.True(t, SameAddresses(p1, p2))
+
+	// unparseable additional addresses skipped
+	apiP.AltAddresses = append(apiP.AltAddresses, "not an address")
+	assert.Len(t, NewFromer().FromAPI(apiP).Addresses(), 2)
+}
+
 func TestToAPIs(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	ns := []int{0, 1, 2, 4}
@@ -148,3 +170,265 @@ func TestFromAddress(t *testing.T) {
 		assert.Equal(t, c.port, int(from.Port))
 	}
 }
+
+func TestNewWithAddresses(t *testing.T) {
+	peerID, name := id.FromInt64(1), "test name"
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.1"), Port: 1000},
+	}
+	p := NewWithAddresses(peerID, name, addrs)
+	assert.Equal(t, addrs[0], p.Address())
+	assert.Equal(t, addrs, p.Addresses())
+	assert.Empty(t, p.Zone())
+}
+
+func TestNewWithZone(t *testing.T) {
+	peerID, name, zone := id.FromInt64(1), "test name", "us-east1-b"
+	addrs := []*net.TCPAddr{{IP: net.ParseIP("192.168.1.1"), Port: 1000}}
+	p := NewWithZone(peerID, name, zone, addrs)
+	assert.Equal(t, zone, p.Zone())
+	assert.Equal(t, addrs, p.Addresses())
+
+	// zone should round-trip through API and stored representations
+	assert.Equal(t, zone, p.ToAPI().Zone)
+	assert.Equal(t, zone, NewFromer().FromAPI(p.ToAPI()).Zone())
+	assert.Equal(t, zone, p.ToStored().Zone)
+	assert.Equal(t, zone, FromStored(p.ToStored()).Zone())
+}
+
+func TestPeer_Merge_zone(t *testing.T) {
+	addrs := []*net.TCPAddr{{IP: net.ParseIP("192.168.1.1"), Port: 1000}}
+	p1 := NewWithZone(id.FromInt64(1), "p1", "zone-a", addrs)
+
+	// empty zone should not replace p1's
+	assert.Nil(t, p1.Merge(NewWithAddresses(p1.ID(), "p1", addrs)))
+	assert.Equal(t, "zone-a", p1.Zone())
+
+	// p2's zone should replace p1's
+	assert.Nil(t, p1.Merge(NewWithZone(p1.ID(), "p1", "zone-b", addrs)))
+	assert.Equal(t, "zone-b", p1.Zone())
+}
+
+func TestPeer_RecordSuccess(t *testing.T) {
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.2"), Port: 1000},
+	}
+	p := NewWithAddresses(id.FromInt64(1), "", addrs)
+
+	p.RecordSuccess(addrs[2])
+	assert.Equal(t, addrs[2], p.Address())
+	assert.Equal(t, []*net.TCPAddr{addrs[2], addrs[0], addrs[1]}, p.Addresses())
+
+	// unknown address should be ignored
+	p.RecordSuccess(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1000})
+	assert.Equal(t, addrs[2], p.Address())
+
+	// stub has no addresses
+	p = NewStub(id.FromInt64(1), "")
+	p.RecordSuccess(addrs[0])
+	assert.Nil(t, p.Address())
+	assert.Nil(t, p.Addresses())
+}
+
+func TestPeer_Merge_addresses(t *testing.T) {
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.2"), Port: 1000},
+	}
+	peerID := id.FromInt64(1)
+
+	// preferred address should be kept if other peer also has it
+	p1 := NewWithAddresses(peerID, "", addrs[:2])
+	p1.RecordSuccess(addrs[1])
+	p2 := NewWithAddresses(peerID, "", addrs)
+	assert.Nil(t, p1.Merge(p2))
+	assert.Equal(t, addrs[1], p1.Address())
+	assert.Len(t, p1.Addresses(), 3)
+
+	// otherwise, other peer's preferred address should be used
+	p1 = NewWithAddresses(peerID, "", addrs[:1])
+	p2 = NewWithAddresses(peerID, "", addrs[1:])
+	p2.RecordSuccess(addrs[2])
+	assert.Nil(t, p1.Merge(p2))
+	assert.Equal(t, addrs[2], p1.Address())
+	assert.Equal(t, []*net.TCPAddr{addrs[2], addrs[1]}, p1.Addresses())
+
+	// stub shouldn't clear addresses
+	p1 = NewWithAddresses(peerID, "", addrs)
+	assert.Nil(t, p1.Merge(NewStub(peerID, "")))
+	assert.Equal(t, addrs, p1.Addresses())
+
+	// merging with self should be a no-op
+	assert.Nil(t, p1.Merge(p1))
+	assert.Equal(t, addrs, p1.Addresses())
+}
+
+func TestQueryAddresses(t *testing.T) {
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.1"), Port: 1000},
+	}
+	p := NewWithAddresses(id.FromInt64(1), "", addrs)
+
+	// first address fails, second succeeds and becomes preferred
+	queried := make([]*net.TCPAddr, 0)
+	err := QueryAddresses(p, func(address *net.TCPAddr) error {
+		queried = append(queried, address)
+		if address == addrs[0] {
+			return status.Error(codes.Unavailable, "some dial error")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, addrs, queried)
+	assert.Equal(t, addrs[1], p.Address())
+
+	// preferred address tried first next time
+	queried = make([]*net.TCPAddr, 0)
+	err = QueryAddresses(p, func(address *net.TCPAddr) error {
+		queried = append(queried, address)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, addrs[1:], queried)
+
+	// all addresses fail to connect
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("some dial error")}
+	queried = make([]*net.TCPAddr, 0)
+	err = QueryAddresses(p, func(address *net.TCPAddr) error {
+		queried = append(queried, address)
+		return dialErr
+	})
+	assert.Equal(t, dialErr, err)
+	assert.Len(t, queried, 2)
+
+	// no fallback to the next address when the peer itself errors
+	queryErr := errors.New("some query error")
+	queried = make([]*net.TCPAddr, 0)
+	err = QueryAddresses(p, func(address *net.TCPAddr) error {
+		queried = append(queried, address)
+		return queryErr
+	})
+	assert.Equal(t, queryErr, err)
+	assert.Len(t, queried, 1)
+
+	// no addresses
+	err = QueryAddresses(NewStub(id.FromInt64(1), ""), func(address *net.TCPAddr) error {
+		return nil
+	})
+	assert.Equal(t, errNoAddresses, err)
+}
+
+func TestSameAddresses(t *testing.T) {
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("192.168.1.1"), Port: 1000},
+		{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+	}
+	p1 := NewWithAddresses(id.FromInt64(1), "", addrs)
+	p2 := NewWithAddresses(id.FromInt64(1), "", []*net.TCPAddr{addrs[1], addrs[0]})
+	p3 := New(id.FromInt64(1), "", addrs[0])
+	p4 := New(id.FromInt64(1), "", &net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 1000})
+
+	assert.True(t, SameAddresses(p1, p2))
+	assert.False(t, SameAddresses(p1, p3))
+	assert.False(t, SameAddresses(p3, p4))
+}
+
+func TestWithoutAddresses(t *testing.T) {
+	addrs := []*net.TCPAddr{{IP: net.ParseIP("192.168.1.1"), Port: 1}}
+	p1 := NewWithZone(id.FromInt64(1), "some name", "some zone", addrs)
+	p2 := WithoutAddresses(p1)
+	assert.Equal(t, p1.ID(), p2.ID())
+	assert.Equal(t, p1.Zone(), p2.Zone())
+	assert.Nil(t, p2.Address())
+	assert.Len(t, p2.Addresses(), 0)
+
+	// merging should update name but not addresses
+	p3 := New(id.FromInt64(1), "", &net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 1})
+	assert.Nil(t, p3.Merge(p2))
+	assert.Equal(t, "some name", p3.(*peer).name)
+	assert.Equal(t, "192.168.1.2:1", p3.Address().String())
+}
+
+func TestPeer_ClockSkew(t *testing.T) {
+	p1 := New(id.FromInt64(1), "", nil)
+	_, measured := p1.ClockSkew()
+	assert.False(t, measured)
+
+	p1.RecordClockSkew(time.Second)
+	skew, measured := p1.ClockSkew()
+	assert.True(t, measured)
+	assert.Equal(t, time.Second, skew)
+
+	// merging should take other's skew if measured
+	p2 := New(id.FromInt64(1), "", nil)
+	assert.Nil(t, p2.Merge(p1))
+	skew, measured = p2.ClockSkew()
+	assert.True(t, measured)
+	assert.Equal(t, time.Second, skew)
+
+	assert.Nil(t, p2.Merge(New(id.FromInt64(1), "", nil)))
+	skew, _ = p2.ClockSkew()
+	assert.Equal(t, time.Second, skew)
+
+	// as should merging a peer without addresses
+	p1.RecordClockSkew(time.Minute)
+	assert.Nil(t, p2.Merge(WithoutAddresses(p1)))
+	skew, _ = p2.ClockSkew()
+	assert.Equal(t, time.Minute, skew)
+}
+
+func TestPeer_Latency(t *testing.T) {
+	p1 := New(id.FromInt64(1), "", nil)
+	_, measured := p1.Latency()
+	assert.False(t, measured)
+
+	p1.RecordLatency(10 * time.Millisecond)
+	latency, measured := p1.Latency()
+	assert.True(t, measured)
+	assert.Equal(t, 10*time.Millisecond, latency)
+
+	// merging should take other's latency if measured
+	p2 := New(id.FromInt64(1), "", nil)
+	assert.Nil(t, p2.Merge(p1))
+	latency, measured = p2.Latency()
+	assert.True(t, measured)
+	assert.Equal(t, 10*time.Millisecond, latency)
+
+	assert.Nil(t, p2.Merge(New(id.FromInt64(1), "", nil)))
+	latency, _ = p2.Latency()
+	assert.Equal(t, 10*time.Millisecond, latency)
+
+	// as should merging a peer without addresses
+	p1.RecordLatency(20 * time.Millisecond)
+	assert.Nil(t, p2.Merge(WithoutAddresses(p1)))
+	latency, _ = p2.Latency()
+	assert.Equal(t, 20*time.Millisecond, latency)
+}
+
+func TestPeer_BuildInfo(t *testing.T) {
+	p1 := New(id.FromInt64(1), "", nil)
+	assert.Nil(t, p1.BuildInfo())
+
+	info1 := &api.BuildInfo{Version: "0.1.0", ProtocolVersion: 1}
+	p1.RecordBuildInfo(info1)
+	assert.Equal(t, info1, p1.BuildInfo())
+
+	// merging should take other's build info if recorded
+	p2 := New(id.FromInt64(1), "", nil)
+	assert.Nil(t, p2.Merge(p1))
+	assert.Equal(t, info1, p2.BuildInfo())
+
+	assert.Nil(t, p2.Merge(New(id.FromInt64(1), "", nil)))
+	assert.Equal(t, info1, p2.BuildInfo())
+
+	// as should merging a peer without addresses
+	info2 := &api.BuildInfo{Version: "0.2.0", ProtocolVersion: 1}
+	p1.RecordBuildInfo(info2)
+	assert.Nil(t, p2.Merge(WithoutAddresses(p1)))
+	assert.Equal(t, info2, p2.BuildInfo())
+}
@@ -2,6 +2,7 @@ package peer
 
 import (
 	"net"
+	"time"
 
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/server/storage"
@@ -9,11 +10,29 @@ import (
 
 // FromStored creates a new peer.Peer instance from a storage.Peer instance.
 func FromStored(stored *storage.Peer) Peer {
-	return New(
-		id.FromBytes(stored.Id),
-		stored.Name,
-		fromStoredAddress(stored.PublicAddress),
-	)
+	var p Peer
+	if len(stored.Addresses) == 0 {
+		// stored before peers had multiple addresses
+		p = NewWithZone(
+			id.FromBytes(stored.Id),
+			stored.Name,
+			stored.Zone,
+			[]*net.TCPAddr{fromStoredAddress(stored.PublicAddress)},
+		)
+	} else {
+		addresses := make([]*net.TCPAddr, len(stored.Addresses))
+		for i, storedAddress := range stored.Addresses {
+			addresses[i] = fromStoredAddress(storedAddress)
+		}
+		p = NewWithZone(id.FromBytes(stored.Id), stored.Name, stored.Zone, addresses)
+		if stored.PublicAddress != nil {
+			p.RecordSuccess(fromStoredAddress(stored.PublicAddress))
+		}
+	}
+	if stored.LatencyNanos > 0 {
+		p.RecordLatency(time.Duration(stored.LatencyNanos))
+	}
+	return p
 }
 
 // fromStoredAddress creates a net.TCPAddr from a storage.Address.
@@ -4,7 +4,9 @@ import (
 	"math/rand"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/server/storage"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +23,35 @@ func TestToStored(t *testing.T) {
 	AssertPeersEqual(t, sp, p)
 }
 
+func TestFromToStored_addresses(t *testing.T) {
+	addrs := []*net.TCPAddr{
+		{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+		{IP: net.ParseIP("192.168.1.1"), Port: 1000},
+	}
+	p1 := NewWithAddresses(id.FromInt64(1), "some name", addrs)
+	p1.RecordSuccess(addrs[1])
+	sp := p1.ToStored()
+	assert.Len(t, sp.Addresses, 2)
+	AssertPeersEqual(t, sp, p1)
+
+	p2 := FromStored(sp)
+	assert.Equal(t, p1.Address(), p2.Address())
+	assert.Equal(t, p1.Addresses(), p2.Addresses())
+}
+
+func TestFromToStored_latency(t *testing.T) {
+	p1 := NewTestPeer(rand.New(rand.NewSource(0)), 0)
+	_, measured := FromStored(p1.ToStored()).Latency()
+	assert.False(t, measured)
+
+	p1.RecordLatency(10 * time.Millisecond)
+	sp := p1.ToStored()
+	assert.Equal(t, int64(10*time.Millisecond), sp.LatencyNanos)
+	latency, measured := FromStored(sp).Latency()
+	assert.True(t, measured)
+	assert.Equal(t, 10*time.Millisecond, latency)
+}
+
 func TestFromStoredAddress(t *testing.T) {
 	ip, port := "192.168.1.1", uint32(1000)
 	sa := &storage.Address{Ip: ip, Port: port}
@@ -5,7 +5,6 @@ import (
 	"math/rand"
 	"net"
 	"testing"
-	"time"
 
 	cerrors "github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/id"
@@ -43,7 +42,6 @@ func NewTestPeers(rng *rand.Rand, n int) []Peer {
 // NewTestStoredPeer generates a new storage.Peer suitable for testing using a random number
 // generator for the ID and an index.
 func NewTestStoredPeer(rng *rand.Rand, idx int) *storage.Peer {
-	now := time.Unix(int64(idx), 0).UTC()
 	return &storage.Peer{
 		Id:   id.NewPseudoRandom(rng).Bytes(),
 		Name: fmt.Sprintf("peer-%d", idx+1),
@@ -51,15 +49,6 @@ func NewTestStoredPeer(rng *rand.Rand, idx int) *storage.Peer {
 			Ip:   "192.168.1.1",
 			Port: uint32(20100 + idx),
 		},
-		QueryOutcomes: &storage.QueryOutcomes{
-			Responses: &storage.QueryTypeOutcomes{
-				Earliest: now.Unix(),
-				Latest:   now.Unix(),
-				NQueries: 1,
-				NErrors:  0,
-			},
-			Requests: &storage.QueryTypeOutcomes{}, // everything will be zero
-		},
 	}
 }
 
@@ -69,4 +58,12 @@ func AssertPeersEqual(t *testing.T, sp *storage.Peer, p Peer) {
 	publicAddres := p.(*peer).Address()
 	assert.Equal(t, sp.PublicAddress.Ip, publicAddres.IP.String())
 	assert.Equal(t, sp.PublicAddress.Port, uint32(publicAddres.Port))
+	if len(sp.Addresses) > 0 {
+		addresses := p.(*peer).preferenceOrder()
+		assert.Equal(t, len(sp.Addresses), len(addresses))
+		for i, address := range addresses {
+			assert.Equal(t, sp.Addresses[i].Ip, address.IP.String())
+			assert.Equal(t, sp.Addresses[i].Port, uint32(address.Port))
+		}
+	}
 }
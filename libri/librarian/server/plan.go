@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
+)
+
+// ErrMissingRoutingTable indicates when a plan is requested from a DB without a stored routing
+// table, e.g., because the librarian has never been started with it.
+var ErrMissingRoutingTable = errors.New("no routing table stored in DB")
+
+// ErrLibrarianRunning indicates when a plan is requested from a DB that a running librarian
+// still has open.
+var ErrLibrarianRunning = errors.New("DB in use by a running librarian, which must be stopped")
+
+// Plan describes the peers from the local routing table that a search for or store of a key
+// would start with. It is made without any network calls, so the peers the search actually
+// finds may be closer than those planned, but it shows how the local routing table places the
+// key.
+type Plan struct {
+	// Key is the target of the search or store
+	Key id.ID
+
+	// Seeds are the peers the search would be seeded with, ordered by increasing distance to
+	// the key
+	Seeds []peer.Peer
+
+	// Queried are the seeds a (single-path) search would query first, one per concurrent query
+	Queried []peer.Peer
+
+	// StoreTo are the peers a store would send the value to if the search found no peers
+	// closer than the seeds
+	StoreTo []peer.Peer
+
+	// SelfAmongClosest is whether self is among the peers closest to the key, and so would be
+	// expected to hold a replica of its value
+	SelfAmongClosest bool
+}
+
+// NewPlan creates a new Plan for the key from the peers in the routing table.
+func NewPlan(
+	rt routing.Table, key id.ID, searchParams *search.Parameters, storeParams *store.Parameters,
+) *Plan {
+	found := rt.Find(key, searchParams.NClosestResponses)
+	closest := search.NewClosestPeers(key, uint(len(found)))
+	closest.SafePushMany(found)
+	seeds := closest.Peers()
+	return &Plan{
+		Key:              key,
+		Seeds:            seeds,
+		Queried:          seeds[:minInt(int(searchParams.Concurrency), len(seeds))],
+		StoreTo:          seeds[:minInt(int(storeParams.NReplicas), len(seeds))],
+		SelfAmongClosest: rt.AmongClosest(key, storeParams.NReplicas),
+	}
+}
+
+// Plan returns the Plan for a search for or store of the key from the librarian's routing table.
+func (l *Librarian) Plan(key id.ID) *Plan {
+	return NewPlan(l.rt, key, l.config.Search, l.config.Store)
+}
+
+// LoadPlan returns the Plan for a search for or store of the key from the routing table stored
+// in the configured DB, returning ErrLibrarianRunning if a running librarian has it open.
+func LoadPlan(config *Config, key id.ID) (*Plan, error) {
+	rdb, err := db.Open(config.DBDriver, config.DbDir)
+	if err == db.ErrLocked {
+		return nil, ErrLibrarianRunning
+	} else if err != nil {
+		return nil, err
+	}
+	defer rdb.Close()
+
+	// peer health doesn't survive restarts, so plan from routing positions and reputations alone
+	serverSL := storage.NewServerSL(rdb)
+	judge, err := comm.LoadReputationJudge(serverSL, config.Reputation)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := routing.LoadCheckpoint(
+		serverSL,
+		storage.NewRoutingSLD(rdb),
+		comm.NewReputationPreferer(judge),
+		comm.NewNaiveDoctor(),
+		config.Routing,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, ErrMissingRoutingTable
+	}
+	return NewPlan(rt, key, config.Search, config.Store), nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
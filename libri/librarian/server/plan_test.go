@@ -0,0 +1,87 @@
+package server
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPlan(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := routing.NewTestWithPeers(rng, 64)
+	config := NewDefaultConfig()
+	config.Search.Concurrency = 3
+	key := id.NewPseudoRandom(rng)
+	nPeers := rt.NumPeers()
+
+	p := NewPlan(rt, key, config.Search, config.Store)
+	assert.Equal(t, key, p.Key)
+	assert.Equal(t, int(config.Search.NClosestResponses), len(p.Seeds))
+	assert.Equal(t, int(config.Search.Concurrency), len(p.Queried))
+	assert.Equal(t, int(config.Store.NReplicas), len(p.StoreTo))
+	assert.Equal(t, p.Seeds[:len(p.Queried)], p.Queried)
+	assert.Equal(t, p.Seeds[:len(p.StoreTo)], p.StoreTo)
+	for i := 1; i < len(p.Seeds); i++ {
+		prev, cur := p.Seeds[i-1].ID().Distance(key), p.Seeds[i].ID().Distance(key)
+		assert.True(t, prev.Cmp(cur) < 0)
+	}
+	assert.Equal(t, rt.AmongClosest(key, config.Store.NReplicas), p.SelfAmongClosest)
+
+	// planning shouldn't remove any peers from the routing table
+	assert.Equal(t, nPeers, rt.NumPeers())
+
+	// should plan with all the peers when there are fewer than needed
+	rt, _, _, _ = routing.NewTestWithPeers(rng, 2)
+	p = NewPlan(rt, key, config.Search, config.Store)
+	assert.Len(t, p.Seeds, 2)
+	assert.Len(t, p.Queried, 2)
+	assert.Len(t, p.StoreTo, 2)
+}
+
+func TestLoadPlan(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	dataDir, err := ioutil.TempDir("", "test-load-plan")
+	defer func() { err = os.RemoveAll(dataDir) }()
+	assert.Nil(t, err)
+	config := NewDefaultConfig()
+	config.WithDataDir(dataDir).WithDefaultDBDir()
+	key := id.NewPseudoRandom(rng)
+
+	// should error without a stored routing table
+	p, err := LoadPlan(config, key)
+	assert.Equal(t, ErrMissingRoutingTable, err)
+	assert.Nil(t, p)
+
+	rt, _, _, _ := routing.NewTestWithPeers(rng, 64)
+	rdb, err := db.Open(config.DBDriver, config.DbDir)
+	assert.Nil(t, err)
+	assert.Nil(t, rt.Save(storage.NewServerSL(rdb)))
+
+	// should error while the DB is still open, e.g., by a running librarian
+	p, err = LoadPlan(config, key)
+	assert.Equal(t, ErrLibrarianRunning, err)
+	assert.Nil(t, p)
+	rdb.Close()
+
+	p, err = LoadPlan(config, key)
+	assert.Nil(t, err)
+	expected := NewPlan(rt, key, config.Search, config.Store)
+	assert.Equal(t, len(expected.Seeds), len(p.Seeds))
+	for i, s := range expected.Seeds {
+		assert.Equal(t, s.ID(), p.Seeds[i].ID())
+	}
+}
+
+func TestLoadPlan_err(t *testing.T) {
+	config := NewDefaultConfig().WithDBDriver("some unknown driver")
+	p, err := LoadPlan(config, id.NewPseudoRandom(rand.New(rand.NewSource(0))))
+	assert.NotNil(t, err)
+	assert.Nil(t, p)
+}
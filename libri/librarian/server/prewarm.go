@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"go.uber.org/zap"
+)
+
+// prewarmClosestPeers dials the peers closest to self, which are the ones most likely to be
+// involved in replication, and health-checks each with an Introduce request for zero other
+// peers. This way the first searches and stores after startup reuse warm connections, and the
+// Doctor already has a recent response from each of those peers.
+func (l *Librarian) prewarmClosestPeers() {
+	closest := l.rt.Find(l.peerID.ID(), l.config.NPrewarmPeers)
+	var wg sync.WaitGroup
+	nHealthy := make(chan struct{}, len(closest))
+	for _, p := range closest {
+		wg.Add(1)
+		go func(p peer.Peer) {
+			defer wg.Done()
+			if err := l.pingPeer(p); err != nil {
+				l.record(p.ID(), api.Introduce, comm.Response, comm.Error)
+				l.logger.Debug("failed to prewarm peer",
+					zap.Stringer("peer_id", p.ID()),
+					zap.Stringer("address", p.Address()),
+					zap.Error(err),
+				)
+				return
+			}
+			l.record(p.ID(), api.Introduce, comm.Response, comm.Success)
+			nHealthy <- struct{}{}
+		}(p)
+	}
+	wg.Wait()
+	l.logger.Info("prewarmed closest peers",
+		zap.Int("n_peers", len(closest)),
+		zap.Int("n_healthy", len(nHealthy)),
+	)
+}
+
+// pingPeer sends the peer an Introduce request for zero other peers, recording its clock skew
+// from the response.
+func (l *Librarian) pingPeer(p peer.Peer) error {
+	lc, err := l.clients.Get(p.Address().String())
+	if err != nil {
+		return err
+	}
+	rq := client.NewIntroduceRequest(l.peerID, l.config.OrgID, l.apiSelf, 0)
+	ctx, cancel, err := client.NewSignedTimeoutContext(l.signer, l.orgSigner, rq,
+		l.config.Introduce.Timeout)
+	if err != nil {
+		return err
+	}
+	sent := time.Now()
+	rp, err := lc.Introduce(ctx, rq)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rp.Metadata.RequestId, rq.Metadata.RequestId) {
+		return client.ErrUnexpectedRequestID
+	}
+	if skew, ok := comm.EstimateClockSkew(sent, time.Now(), rp.TimeNanos); ok {
+		p.RecordClockSkew(skew)
+		l.skewRec.Record(p.ID(), skew)
+	}
+	return nil
+}
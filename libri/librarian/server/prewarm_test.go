@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestLibrarian_prewarmClosestPeers(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	lc := &fixedIntroducerClient{skew: time.Minute}
+	config := NewDefaultConfig()
+	l := &Librarian{
+		peerID:    peerID,
+		config:    config,
+		rt:        rt,
+		clients:   &fixedPool{lc: lc},
+		signer:    &client.TestNoOpSigner{},
+		orgSigner: &client.TestNoOpSigner{},
+		rec:       rec,
+		skewRec:   comm.NewSkewRecorder(),
+		logger:    zap.NewNop(),
+	}
+
+	l.prewarmClosestPeers()
+	closest := rt.Find(peerID.ID(), config.NPrewarmPeers)
+	assert.Equal(t, int(config.NPrewarmPeers), len(closest))
+	assert.Equal(t, len(closest), lc.nCalls)
+	for _, p := range closest {
+		qo := rec.Get(p.ID(), api.Introduce)
+		assert.Equal(t, uint64(1), qo[comm.Response][comm.Success].Count)
+		skew, measured := p.ClockSkew()
+		assert.True(t, measured)
+		assert.InDelta(t, time.Minute.Seconds(), skew.Seconds(), 1.0)
+	}
+	selfSkew, ok := l.skewRec.SelfSkew()
+	assert.True(t, ok)
+	assert.InDelta(t, -time.Minute.Seconds(), selfSkew.Seconds(), 1.0)
+
+	// check failures are recorded as errors
+	l.clients = &fixedPool{lc: &fixedIntroducerClient{err: errors.New("some Introduce error")}}
+	l.prewarmClosestPeers()
+	for _, p := range closest {
+		qo := rec.Get(p.ID(), api.Introduce)
+		assert.Equal(t, uint64(1), qo[comm.Response][comm.Error].Count)
+	}
+}
+
+func TestLibrarian_pingPeer_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 8)
+	p := rt.Find(peerID.ID(), 1)[0]
+	l := &Librarian{
+		peerID:    peerID,
+		config:    NewDefaultConfig(),
+		signer:    &client.TestNoOpSigner{},
+		orgSigner: &client.TestNoOpSigner{},
+	}
+
+	// pool error
+	l.clients = &fixedPool{err: errors.New("some Get error")}
+	assert.NotNil(t, l.pingPeer(p))
+
+	// signing error
+	l.clients = &fixedPool{lc: &fixedIntroducerClient{}}
+	l.signer = &client.TestErrSigner{}
+	assert.NotNil(t, l.pingPeer(p))
+
+	// unexpected request ID
+	l.signer = &client.TestNoOpSigner{}
+	l.clients = &fixedPool{lc: &fixedIntroducerClient{requestID: api.RandBytes(rng, 32)}}
+	assert.Equal(t, client.ErrUnexpectedRequestID, l.pingPeer(p))
+}
+
+type fixedPool struct {
+	lc  api.LibrarianClient
+	err error
+}
+
+func (f *fixedPool) Get(address string) (api.LibrarianClient, error) {
+	return f.lc, f.err
+}
+
+func (f *fixedPool) CloseAll() error {
+	return nil
+}
+
+type fixedIntroducerClient struct {
+	api.LibrarianClient
+	requestID []byte
+	skew      time.Duration
+	err       error
+	nCalls    int
+	mu        sync.Mutex
+}
+
+func (f *fixedIntroducerClient) Introduce(
+	ctx context.Context, in *api.IntroduceRequest, opts ...grpc.CallOption,
+) (*api.IntroduceResponse, error) {
+	f.mu.Lock()
+	f.nCalls++
+	f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	requestID := f.requestID
+	if requestID == nil {
+		requestID = in.Metadata.RequestId
+	}
+	rp := &api.IntroduceResponse{
+		Metadata: &api.ResponseMetadata{RequestId: requestID},
+	}
+	if f.skew != 0 {
+		rp.TimeNanos = time.Now().Add(f.skew).UnixNano()
+	}
+	return rp, nil
+}
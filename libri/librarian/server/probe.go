@@ -0,0 +1,66 @@
+package server
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"go.uber.org/zap"
+)
+
+// probePeers periodically probes the health of a random sample of the routing table peers until
+// the server stops.
+func (l *Librarian) probePeers() {
+	if !l.config.Probe.Enabled() {
+		return
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		timer := time.NewTimer(l.config.Probe.NextInterval(rng))
+		select {
+		case <-l.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			l.probePeersOnce(rng)
+		}
+	}
+}
+
+// probePeersOnce pings a random sample of the routing table peers. Each outcome is recorded with
+// the doctor and as an Introduce response, so probes also feed the eviction of failing peers and
+// peers' reputations. Probes older than their TTL are then forgotten, so the doctor doesn't keep
+// those of peers long since evicted.
+func (l *Librarian) probePeersOnce(rng *rand.Rand) {
+	defer l.doctor.Prune()
+	sample := l.rt.Sample(l.config.Probe.NPeers, rng)
+	var wg sync.WaitGroup
+	nHealthy := make(chan struct{}, len(sample))
+	for _, p := range sample {
+		wg.Add(1)
+		go func(p peer.Peer) {
+			defer wg.Done()
+			err := l.pingPeer(p)
+			l.doctor.RecordProbe(p.ID(), err)
+			if err != nil {
+				l.record(p.ID(), api.Introduce, comm.Response, comm.Error)
+				l.logger.Debug("failed to probe peer",
+					zap.Stringer(logPeerID, p.ID()),
+					zap.Stringer(logAddress, p.Address()),
+					zap.Error(err),
+				)
+				return
+			}
+			l.record(p.ID(), api.Introduce, comm.Response, comm.Success)
+			nHealthy <- struct{}{}
+		}(p)
+	}
+	wg.Wait()
+	l.logger.Debug("probed peers",
+		zap.Int(logNPeers, len(sample)),
+		zap.Int(logNHealthy, len(nHealthy)),
+	)
+}
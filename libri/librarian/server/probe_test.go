@@ -0,0 +1,55 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLibrarian_probePeersOnce(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	lc := &fixedIntroducerClient{}
+	config := NewDefaultConfig()
+	config.Probe.MaxFailures = 1
+	doctor := comm.NewProbingDoctor(comm.NewNaiveDoctor(), config.Probe)
+	l := &Librarian{
+		peerID:    peerID,
+		config:    config,
+		rt:        rt,
+		clients:   &fixedPool{lc: lc},
+		signer:    &client.TestNoOpSigner{},
+		orgSigner: &client.TestNoOpSigner{},
+		rec:       rec,
+		skewRec:   comm.NewSkewRecorder(),
+		doctor:    doctor,
+		logger:    zap.NewNop(),
+	}
+
+	// healthy peers' probes are recorded as successes
+	l.probePeersOnce(rand.New(rand.NewSource(0)))
+	sample := rt.Sample(config.Probe.NPeers, rand.New(rand.NewSource(0)))
+	assert.Equal(t, len(sample), lc.nCalls)
+	for _, p := range sample {
+		qo := rec.Get(p.ID(), api.Introduce)
+		assert.Equal(t, uint64(1), qo[comm.Response][comm.Success].Count)
+		assert.True(t, doctor.Healthy(p.ID()))
+	}
+
+	// failed probes mark peers unhealthy and are recorded as errors
+	l.clients = &fixedPool{lc: &fixedIntroducerClient{err: errors.New("some Introduce error")}}
+	l.probePeersOnce(rand.New(rand.NewSource(0)))
+	for _, p := range sample {
+		qo := rec.Get(p.ID(), api.Introduce)
+		assert.Equal(t, uint64(1), qo[comm.Response][comm.Error].Count)
+		assert.False(t, doctor.Healthy(p.ID()))
+	}
+}
@@ -0,0 +1,419 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultOrgQuotaLimitBytes is the default maximum number of bytes each organization may store,
+// with zero meaning unlimited.
+const DefaultOrgQuotaLimitBytes = 0
+
+var (
+	errQuotaExceeded     = errors.New("organization storage quota exceeded")
+	errInvalidQuotaOrgID = errors.New("quota org ID must be 32 bytes")
+)
+
+// QuotaParameters define the storage quotas enforced on each organization's Store requests.
+type QuotaParameters struct {
+	// DefaultLimitBytes is the maximum number of bytes each organization without a limit set by
+	// the admin may store, with zero meaning unlimited.
+	DefaultLimitBytes uint64
+}
+
+// NewDefaultQuotaParameters returns a *QuotaParameters object with default values.
+func NewDefaultQuotaParameters() *QuotaParameters {
+	return &QuotaParameters{
+		DefaultLimitBytes: DefaultOrgQuotaLimitBytes,
+	}
+}
+
+// orgQuota is an organization's storage quota and the bytes stored against it.
+type orgQuota struct {
+	orgID       id.ID
+	limitBytes  uint64
+	usedBytes   uint64
+	customLimit bool
+}
+
+// orgQuotas tracks the bytes stored by each organization and the organization each stored
+// document is charged to, persisting them so they survive restarts, and enforces each
+// organization's limit.
+type orgQuotas interface {
+	// Check returns errQuotaExceeded if storing the bytes would exceed the organization's limit,
+	// without charging them.
+	Check(orgID id.ID, nBytes uint64) error
+
+	// Reserve charges the bytes of the document with the given key to the organization,
+	// returning errQuotaExceeded if doing so would exceed its limit. A document already charged
+	// isn't charged again.
+	Reserve(orgID, key id.ID, nBytes uint64) error
+
+	// Release releases the bytes charged for the document with the given key, if any, e.g., when
+	// it is deleted or expires or when storing it fails.
+	Release(key id.ID) error
+
+	// OriginOrgID returns the ID of the organization the document with the given key is charged
+	// to and whether it is charged to one.
+	OriginOrgID(key id.ID) (id.ID, bool, error)
+
+	// Set sets the organization's limit, with zero meaning unlimited, optionally resetting the
+	// bytes it has stored to zero. It returns the updated quota.
+	Set(orgID id.ID, limitBytes uint64, resetUsed bool) (*orgQuota, error)
+
+	// List returns the quotas, with their effective limits, ordered by org ID.
+	List() []*orgQuota
+}
+
+type orgQuotasImpl struct {
+	quotas  map[string]*orgQuota
+	sld     cstorage.StorerLoaderDeleter
+	charges cstorage.StorerLoaderDeleter
+	params  *QuotaParameters
+	mu      sync.Mutex
+}
+
+// loadOrgQuotas loads the persisted organization quotas, each stored under its org ID in sld, and
+// uses charges to store the organization each document is charged to.
+func loadOrgQuotas(
+	sld, charges cstorage.StorerLoaderDeleter, params *QuotaParameters,
+) (orgQuotas, error) {
+	q := &orgQuotasImpl{
+		quotas:  make(map[string]*orgQuota),
+		sld:     sld,
+		charges: charges,
+		params:  params,
+	}
+	var loadErr error
+	done := make(chan struct{})
+	lb, ub := bytes.Repeat([]byte{0}, id.Length), bytes.Repeat([]byte{255}, id.Length)
+	err := sld.Iterate(lb, ub, done, func(key, value []byte) {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		stored := &sstorage.OrgQuota{}
+		if err := proto.Unmarshal(value, stored); err != nil {
+			loadErr = err
+			close(done)
+			return
+		}
+		oq := &orgQuota{
+			orgID:       id.FromBytes(stored.OrgId),
+			limitBytes:  stored.LimitBytes,
+			usedBytes:   stored.UsedBytes,
+			customLimit: stored.CustomLimit,
+		}
+		q.quotas[oq.orgID.String()] = oq
+	})
+	if err != nil {
+		return nil, err
+	}
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return q, nil
+}
+
+func (q *orgQuotasImpl) Check(orgID id.ID, nBytes uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	oq := q.get(orgID)
+	if limit := q.limit(oq); limit != 0 && oq.usedBytes+nBytes > limit {
+		return errQuotaExceeded
+	}
+	return nil
+}
+
+func (q *orgQuotasImpl) Reserve(orgID, key id.ID, nBytes uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	charge, err := q.loadCharge(key)
+	if err != nil || charge != nil {
+		return err
+	}
+	oq := q.get(orgID)
+	limit := q.limit(oq)
+	if limit != 0 && oq.usedBytes+nBytes > limit {
+		return errQuotaExceeded
+	}
+	if err := q.update(oq, func(oq *orgQuota) { oq.usedBytes += nBytes }); err != nil {
+		return err
+	}
+	charge = &sstorage.QuotaCharge{OrgId: orgID.Bytes(), NBytes: nBytes}
+	if err := q.storeCharge(key, charge); err != nil {
+		// undo the reservation so the organization isn't charged for an untracked document
+		if err2 := q.update(q.get(orgID), func(oq *orgQuota) { oq.release(nBytes) }); err2 != nil {
+			return err2
+		}
+		return err
+	}
+	return nil
+}
+
+func (q *orgQuotasImpl) Release(key id.ID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	charge, err := q.loadCharge(key)
+	if err != nil || charge == nil {
+		return err
+	}
+	oq := q.get(id.FromBytes(charge.OrgId))
+	if err := q.update(oq, func(oq *orgQuota) { oq.release(charge.NBytes) }); err != nil {
+		return err
+	}
+	return q.charges.Delete(key.Bytes())
+}
+
+func (q *orgQuotasImpl) OriginOrgID(key id.ID) (id.ID, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	charge, err := q.loadCharge(key)
+	if err != nil || charge == nil {
+		return nil, false, err
+	}
+	return id.FromBytes(charge.OrgId), true, nil
+}
+
+func (q *orgQuotasImpl) Set(orgID id.ID, limitBytes uint64, resetUsed bool) (*orgQuota, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	oq := q.get(orgID)
+	err := q.update(oq, func(oq *orgQuota) {
+		oq.limitBytes, oq.customLimit = limitBytes, true
+		if resetUsed {
+			oq.usedBytes = 0
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q.effective(q.quotas[orgID.String()]), nil
+}
+
+func (q *orgQuotasImpl) List() []*orgQuota {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	quotas := make([]*orgQuota, 0, len(q.quotas))
+	for _, oq := range q.quotas {
+		quotas = append(quotas, q.effective(oq))
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].orgID.Cmp(quotas[j].orgID) < 0 })
+	return quotas
+}
+
+// get returns a copy of the organization's quota, which is empty if it has none.
+func (q *orgQuotasImpl) get(orgID id.ID) *orgQuota {
+	if oq, in := q.quotas[orgID.String()]; in {
+		cp := *oq
+		return &cp
+	}
+	return &orgQuota{orgID: orgID}
+}
+
+// update applies the change to the quota and persists it, leaving the quotas as they were if it
+// can't be persisted.
+func (q *orgQuotasImpl) update(oq *orgQuota, change func(oq *orgQuota)) error {
+	change(oq)
+	if err := q.save(oq); err != nil {
+		return err
+	}
+	q.quotas[oq.orgID.String()] = oq
+	return nil
+}
+
+func (q *orgQuotasImpl) limit(oq *orgQuota) uint64 {
+	if oq.customLimit {
+		return oq.limitBytes
+	}
+	return q.params.DefaultLimitBytes
+}
+
+// effective returns a copy of the quota with its effective limit.
+func (q *orgQuotasImpl) effective(oq *orgQuota) *orgQuota {
+	return &orgQuota{
+		orgID:       oq.orgID,
+		limitBytes:  q.limit(oq),
+		usedBytes:   oq.usedBytes,
+		customLimit: oq.customLimit,
+	}
+}
+
+// save persists the quota under its org ID.
+func (q *orgQuotasImpl) save(oq *orgQuota) error {
+	value, err := proto.Marshal(&sstorage.OrgQuota{
+		OrgId:       oq.orgID.Bytes(),
+		LimitBytes:  oq.limitBytes,
+		UsedBytes:   oq.usedBytes,
+		CustomLimit: oq.customLimit,
+	})
+	cerrors.MaybePanic(err) // should never happen
+	return q.sld.Store(oq.orgID.Bytes(), value)
+}
+
+// loadCharge loads the charge for the document with the given key, returning nil if it has none.
+func (q *orgQuotasImpl) loadCharge(key id.ID) (*sstorage.QuotaCharge, error) {
+	value, err := q.charges.Load(key.Bytes())
+	if err != nil || value == nil {
+		return nil, err
+	}
+	charge := &sstorage.QuotaCharge{}
+	if err := proto.Unmarshal(value, charge); err != nil {
+		return nil, err
+	}
+	return charge, nil
+}
+
+func (q *orgQuotasImpl) storeCharge(key id.ID, charge *sstorage.QuotaCharge) error {
+	value, err := proto.Marshal(charge)
+	cerrors.MaybePanic(err) // should never happen
+	return q.charges.Store(key.Bytes(), value)
+}
+
+// release removes the bytes from those stored, leaving none if there were fewer.
+func (oq *orgQuota) release(nBytes uint64) {
+	if nBytes > oq.usedBytes {
+		oq.usedBytes = 0
+		return
+	}
+	oq.usedBytes -= nBytes
+}
+
+// newQuotaDocumentHooks returns the DocumentHooks releasing the bytes charged for each document
+// when it is removed, whether deleted, expired, or dropped for failing verification. Documents
+// moving between storage tiers remain charged, since they are still stored.
+func newQuotaDocumentHooks(quotas orgQuotas, logger *zap.Logger) cstorage.DocumentHooks {
+	return &cstorage.DocumentHookFuncs{
+		Delete: func(key id.ID) {
+			if err := quotas.Release(key); err != nil {
+				logger.Error("error releasing quota", zap.String(logKey, id.Hex(key.Bytes())),
+					zap.Error(err))
+			}
+		},
+	}
+}
+
+// quotaOrgID returns the ID of the organization the request's stored bytes are charged to. Only
+// requests signed by their organization are charged to it, so one organization can't consume
+// another's quota; all others are charged to the zero ID.
+func quotaOrgID(ctx context.Context, meta *api.RequestMetadata) id.ID {
+	_, encOrgToken, err := client.FromSignatureContext(ctx)
+	if err != nil || encOrgToken == "" {
+		return id.LowerBound
+	}
+	orgPubKey, err := ecid.FromPublicKeyBytes(meta.OrgPubKey)
+	if err != nil {
+		return id.LowerBound
+	}
+	return id.FromPublicKey(orgPubKey)
+}
+
+// storeQuotaOrgID returns the ID of the organization a Store request's value is charged to: the
+// organization whose request the Store forwards if given, since forwarding librarians sign with
+// their own organization, otherwise the organization signing the request.
+func storeQuotaOrgID(ctx context.Context, rq *api.StoreRequest) id.ID {
+	if len(rq.OriginOrgId) == id.Length {
+		return id.FromBytes(rq.OriginOrgId)
+	}
+	return quotaOrgID(ctx, rq.Metadata)
+}
+
+// checkQuota checks that the value fits within the organization's quota. It returns a
+// ResourceExhausted grpc status error if the quota would be exceeded.
+func (l *Librarian) checkQuota(orgID id.ID, value *api.Document) error {
+	if err := l.quotas.Check(orgID, uint64(proto.Size(value))); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return nil
+}
+
+// reserveQuota charges the bytes for the value stored under the given key to the organization's
+// quota. It returns a ResourceExhausted grpc status error if the quota would be exceeded.
+func (l *Librarian) reserveQuota(orgID, key id.ID, value *api.Document) error {
+	err := l.quotas.Reserve(orgID, key, uint64(proto.Size(value)))
+	if err == errQuotaExceeded {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return err
+}
+
+// ListQuotas lists the organizations' quotas to requests signed by the admin key.
+func (l *Librarian) ListQuotas(ctx context.Context, rq *api.ListQuotasRequest) (
+	*api.ListQuotasResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received list quotas request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+
+	quotas := l.quotas.List()
+	rp := &api.ListQuotasResponse{
+		Metadata: l.NewResponseMetadata(rq.Metadata),
+		Quotas:   make([]*api.OrgQuota, len(quotas)),
+	}
+	for i, oq := range quotas {
+		rp.Quotas[i] = toAPIQuota(oq)
+	}
+	lg.Debug("listed quotas", zap.Int(logNQuotas, len(quotas)))
+	return rp, nil
+}
+
+// SetQuota sets and persists an organization's quota for requests signed by the admin key.
+func (l *Librarian) SetQuota(ctx context.Context, rq *api.SetQuotaRequest) (
+	*api.SetQuotaResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received set quota request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if len(rq.OrgId) != id.Length {
+		return nil, logReturnInvalidRqErr(lg, errInvalidQuotaOrgID)
+	}
+
+	orgID := id.FromBytes(rq.OrgId)
+	oq, err := l.quotas.Set(orgID, rq.LimitBytes, rq.ResetUsed)
+	if err != nil {
+		return nil, logReturnInternalErr(lg, "error saving quota", err)
+	}
+	lg.Info("set quota",
+		zap.Stringer(logOrgID, orgID),
+		zap.Uint64(logLimitBytes, oq.limitBytes),
+		zap.Uint64(logUsedBytes, oq.usedBytes),
+	)
+	return &api.SetQuotaResponse{
+		Metadata: l.NewResponseMetadata(rq.Metadata),
+		Quota:    toAPIQuota(oq),
+	}, nil
+}
+
+func toAPIQuota(oq *orgQuota) *api.OrgQuota {
+	return &api.OrgQuota{
+		OrgId:      oq.orgID.Bytes(),
+		LimitBytes: oq.limitBytes,
+		UsedBytes:  oq.usedBytes,
+	}
+}
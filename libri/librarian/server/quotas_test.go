@@ -0,0 +1,362 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/ecid"
+	cerrors "github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/store"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestOrgQuotas_ReserveRelease(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	sld, charges := cstorage.NewQuotaSLD(kvdb), cstorage.NewQuotaChargeSLD(kvdb)
+	params := &QuotaParameters{DefaultLimitBytes: 100}
+	q, err := loadOrgQuotas(sld, charges, params)
+	assert.Nil(t, err)
+	assert.Len(t, q.List(), 0)
+	orgID1, orgID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	key1, key2, key3, key4 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+
+	assert.Nil(t, q.Check(orgID1, 100))
+	assert.Equal(t, errQuotaExceeded, q.Check(orgID1, 101))
+	assert.Nil(t, q.Reserve(orgID1, key1, 60))
+	assert.Nil(t, q.Reserve(orgID1, key2, 40))
+	assert.Equal(t, errQuotaExceeded, q.Check(orgID1, 1))
+	assert.Equal(t, errQuotaExceeded, q.Reserve(orgID1, key3, 1))
+	assert.Nil(t, q.Reserve(orgID2, key4, 10))
+
+	// documents already charged aren't charged again
+	assert.Nil(t, q.Reserve(orgID1, key1, 60))
+
+	// documents are charged to the org that reserved them
+	originOrgID, in, err := q.OriginOrgID(key4)
+	assert.Nil(t, err)
+	assert.True(t, in)
+	assert.Equal(t, orgID2, originOrgID)
+	_, in, err = q.OriginOrgID(key3)
+	assert.Nil(t, err)
+	assert.False(t, in)
+
+	// releasing makes room again
+	assert.Nil(t, q.Release(key1))
+	assert.Nil(t, q.Reserve(orgID1, key3, 1))
+	_, in, err = q.OriginOrgID(key1)
+	assert.Nil(t, err)
+	assert.False(t, in)
+
+	assert.Nil(t, q.Release(key4))
+
+	// releasing an uncharged document does nothing
+	assert.Nil(t, q.Release(key1))
+	assert.Nil(t, q.Release(key4))
+
+	// check quotas and charges survive reloading
+	q2, err := loadOrgQuotas(sld, charges, params)
+	assert.Nil(t, err)
+	quotas := q2.List()
+	assert.Len(t, quotas, 2)
+	assert.True(t, quotas[0].orgID.Cmp(quotas[1].orgID) < 0)
+	for _, oq := range quotas {
+		assert.Equal(t, uint64(100), oq.limitBytes)
+		if oq.orgID.Cmp(orgID1) == 0 {
+			assert.Equal(t, uint64(41), oq.usedBytes)
+		} else {
+			assert.Zero(t, oq.usedBytes)
+		}
+	}
+	assert.Nil(t, q2.Release(key2))
+	assert.Equal(t, uint64(1), q2.List()[0].usedBytes+q2.List()[1].usedBytes)
+}
+
+func TestOrgQuotas_Set(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	q := newTestOrgQuotas(&QuotaParameters{DefaultLimitBytes: 100})
+	orgID := id.NewPseudoRandom(rng)
+	assert.Nil(t, q.Reserve(orgID, id.NewPseudoRandom(rng), 80))
+
+	// custom limit overrides the default
+	oq, err := q.Set(orgID, 200, false)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(200), oq.limitBytes)
+	assert.Equal(t, uint64(80), oq.usedBytes)
+	assert.Nil(t, q.Reserve(orgID, id.NewPseudoRandom(rng), 120))
+	assert.Equal(t, errQuotaExceeded, q.Reserve(orgID, id.NewPseudoRandom(rng), 1))
+
+	// zero custom limit means unlimited
+	oq, err = q.Set(orgID, 0, true)
+	assert.Nil(t, err)
+	assert.Zero(t, oq.limitBytes)
+	assert.Zero(t, oq.usedBytes)
+	assert.Nil(t, q.Reserve(orgID, id.NewPseudoRandom(rng), 1000))
+}
+
+func TestOrgQuotas_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	orgID, key := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	params := NewDefaultQuotaParameters()
+
+	// check Iterate error bubbles up
+	q, err := loadOrgQuotas(&cstorage.TestSLD{IterateErr: errors.New("some Iterate error")},
+		&cstorage.TestSLD{}, params)
+	assert.NotNil(t, err)
+	assert.Nil(t, q)
+
+	// check unmarshal error bubbles up
+	kvdb := db.NewMemoryDB()
+	sld := cstorage.NewQuotaSLD(kvdb)
+	assert.Nil(t, sld.Store(orgID.Bytes(), []byte{255, 255}))
+	q, err = loadOrgQuotas(sld, cstorage.NewQuotaChargeSLD(kvdb), params)
+	assert.NotNil(t, err)
+	assert.Nil(t, q)
+
+	// check Store error leaves the quotas as they were
+	failing := &cstorage.TestSLD{}
+	q, err = loadOrgQuotas(failing, cstorage.NewQuotaChargeSLD(db.NewMemoryDB()), params)
+	assert.Nil(t, err)
+	assert.Nil(t, q.Reserve(orgID, key, 10))
+	failing.StoreErr = errors.New("some Store error")
+	assert.NotNil(t, q.Reserve(orgID, id.NewPseudoRandom(rng), 10))
+	assert.NotNil(t, q.Release(key))
+	_, err = q.Set(orgID, 100, true)
+	assert.NotNil(t, err)
+	assert.NotNil(t, q.Reserve(id.NewPseudoRandom(rng), id.NewPseudoRandom(rng), 10))
+	quotas := q.List()
+	assert.Len(t, quotas, 1)
+	assert.Equal(t, uint64(10), quotas[0].usedBytes)
+	assert.False(t, quotas[0].customLimit)
+
+	// check charge Store error undoes the reservation
+	charges := &cstorage.TestSLD{StoreErr: errors.New("some Store error")}
+	q, err = loadOrgQuotas(cstorage.NewQuotaSLD(db.NewMemoryDB()), charges, params)
+	assert.Nil(t, err)
+	assert.NotNil(t, q.Reserve(orgID, key, 10))
+	assert.Zero(t, q.List()[0].usedBytes)
+
+	// check charge Load error bubbles up
+	charges = &cstorage.TestSLD{LoadErr: errors.New("some Load error")}
+	q, err = loadOrgQuotas(cstorage.NewQuotaSLD(db.NewMemoryDB()), charges, params)
+	assert.Nil(t, err)
+	assert.NotNil(t, q.Reserve(orgID, key, 10))
+	assert.NotNil(t, q.Release(key))
+	_, _, err = q.OriginOrgID(key)
+	assert.NotNil(t, err)
+}
+
+func TestQuotaDocumentHooks(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	q := newTestOrgQuotas(NewDefaultQuotaParameters())
+	orgID, key := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	assert.Nil(t, q.Reserve(orgID, key, 10))
+	hooks := newQuotaDocumentHooks(q, zap.NewNop())
+
+	// deleting the document releases its charge
+	hooks.OnDelete(key)
+	assert.Zero(t, q.List()[0].usedBytes)
+	_, in, err := q.OriginOrgID(key)
+	assert.Nil(t, err)
+	assert.False(t, in)
+}
+
+func TestStoreQuotaOrgID(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	rq := client.NewStoreRequest(peerID, orgID, key, value)
+	ctx := client.NewIncomingSignatureContext(context.Background(), "some.signed.token",
+		"some.signed.org-token")
+
+	// charged to the signing org
+	assert.Equal(t, orgID.ID(), storeQuotaOrgID(ctx, rq))
+
+	// forwarded requests charged to the origin org
+	originOrgID := id.NewPseudoRandom(rng)
+	rq.OriginOrgId = originOrgID.Bytes()
+	assert.Equal(t, originOrgID, storeQuotaOrgID(ctx, rq))
+	assert.Equal(t, originOrgID, storeQuotaOrgID(context.Background(), rq))
+
+	// invalid origin org ID ignored
+	rq.OriginOrgId = []byte{1, 2, 3}
+	assert.Equal(t, orgID.ID(), storeQuotaOrgID(ctx, rq))
+}
+
+func TestQuotaOrgID(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	orgID := ecid.NewPseudoRandom(rng)
+	meta := &api.RequestMetadata{OrgPubKey: orgID.PublicKeyBytes()}
+
+	// no signature context
+	assert.Equal(t, id.LowerBound, quotaOrgID(context.Background(), meta))
+
+	// not signed by the org
+	ctx := client.NewIncomingSignatureContext(context.Background(), "some.signed.token", "")
+	assert.Equal(t, id.LowerBound, quotaOrgID(ctx, meta))
+
+	// signed by the org
+	ctx = client.NewIncomingSignatureContext(context.Background(), "some.signed.token",
+		"some.signed.org-token")
+	assert.Equal(t, orgID.ID(), quotaOrgID(ctx, meta))
+
+	// invalid org public key
+	meta.OrgPubKey = []byte{1, 2, 3}
+	assert.Equal(t, id.LowerBound, quotaOrgID(ctx, meta))
+}
+
+func TestLibrarian_Store_quotaExceeded(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
+	orgID := ecid.NewPseudoRandom(rng)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	config := NewDefaultConfig().WithQuota(&QuotaParameters{DefaultLimitBytes: 1})
+	sld := cstorage.NewTestDocSLD()
+	l := &Librarian{
+		config:       config,
+		peerID:       peerID,
+		signer:       client.NewECDSASigner(peerID.Key()),
+		rt:           rt,
+		kc:           cstorage.NewExactLengthChecker(cstorage.EntriesKeyLength),
+		kvc:          cstorage.NewHashKeyValueChecker(),
+		rqv:          &alwaysRequestVerifier{},
+		documentSL:   sld,
+		quotas:       newTestOrgQuotas(config.Quota),
+		rec:          rec,
+		allower:      &fixedAllower{},
+		rqLimiter:    comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		recentStores: newRecentStores(recentStoresSize, recentStoresTTL),
+		logger:       zap.NewNop(), // clogging.NewDevInfoLogger(),
+	}
+	value, key := api.NewTestDocument(rng)
+	rq := client.NewStoreRequest(peerID, orgID, key, value)
+
+	rp, err := l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.ResourceExhausted, getErrCode(t, err))
+	assert.Len(t, sld.Stored, 0)
+}
+
+func TestLibrarian_Put_quota(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	ctx := client.NewIncomingSignatureContext(context.Background(), "some.signed.token",
+		"some.signed.org-token")
+	rq := client.NewPutRequest(peerID, orgID, key, value)
+
+	// replicas are charged to the requester's org
+	storer := &fixedStorer{result: store.NewInitialResult(search.NewInitialResult(key,
+		search.NewDefaultParameters()))}
+	l := newPutLibrarian(rng, nil, nil)
+	l.storer = storer
+	_, err := l.Put(ctx, rq)
+	assert.NotNil(t, err) // since store result neither stored nor exists
+	assert.Equal(t, orgID.ID().Bytes(), storer.store.CreateRq().OriginOrgId)
+
+	// quota exceeded
+	l = newPutLibrarian(rng, nil, nil)
+	l.quotas = newTestOrgQuotas(&QuotaParameters{DefaultLimitBytes: 1})
+	rp, err := l.Put(ctx, rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.ResourceExhausted, getErrCode(t, err))
+	qo := l.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Put)
+	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
+}
+
+func TestLibrarian_ListSetQuota_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID := ecid.NewPseudoRandom(rng)
+	l := newQuotasLibrarian(rng, adminID, newTestOrgQuotas(NewDefaultQuotaParameters()))
+	ctx := context.Background()
+	orgID1, orgID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	assert.Nil(t, l.quotas.Reserve(orgID1, id.NewPseudoRandom(rng), 50))
+
+	rp1, err := l.SetQuota(ctx, client.NewSetQuotaRequest(adminID, nil, orgID2, 1024, false))
+	assert.Nil(t, err)
+	assert.NotNil(t, rp1.Metadata)
+	assert.Equal(t, &api.OrgQuota{OrgId: orgID2.Bytes(), LimitBytes: 1024}, rp1.Quota)
+
+	rp2, err := l.ListQuotas(ctx, client.NewListQuotasRequest(adminID, nil))
+	assert.Nil(t, err)
+	assert.Len(t, rp2.Quotas, 2)
+	for _, oq := range rp2.Quotas {
+		if id.FromBytes(oq.OrgId).Cmp(orgID1) == 0 {
+			assert.Equal(t, &api.OrgQuota{OrgId: orgID1.Bytes(), UsedBytes: 50}, oq)
+		} else {
+			assert.Equal(t, rp1.Quota, oq)
+		}
+	}
+
+	rp1, err = l.SetQuota(ctx, client.NewSetQuotaRequest(adminID, nil, orgID1, 2048, true))
+	assert.Nil(t, err)
+	assert.Equal(t, &api.OrgQuota{OrgId: orgID1.Bytes(), LimitBytes: 2048}, rp1.Quota)
+}
+
+func TestLibrarian_ListSetQuota_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	ctx := context.Background()
+	orgID := id.NewPseudoRandom(rng)
+
+	// check non-admin requester denied
+	l := newQuotasLibrarian(rng, adminID, newTestOrgQuotas(NewDefaultQuotaParameters()))
+	_, err := l.ListQuotas(ctx, client.NewListQuotasRequest(otherID, nil))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	_, err = l.SetQuota(ctx, client.NewSetQuotaRequest(otherID, nil, orgID, 1024, false))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// check request error bubbles up
+	listRq := client.NewListQuotasRequest(adminID, nil)
+	listRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.ListQuotas(ctx, listRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	setRq := client.NewSetQuotaRequest(adminID, nil, orgID, 1024, false)
+	setRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.SetQuota(ctx, setRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check invalid org ID
+	rq := client.NewSetQuotaRequest(adminID, nil, orgID, 1024, false)
+	rq.OrgId = []byte{1}
+	_, err = l.SetQuota(ctx, rq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check Store error bubbles up
+	q, err := loadOrgQuotas(&cstorage.TestSLD{StoreErr: errors.New("some Store error")},
+		cstorage.NewQuotaChargeSLD(db.NewMemoryDB()), NewDefaultQuotaParameters())
+	assert.Nil(t, err)
+	l = newQuotasLibrarian(rng, adminID, q)
+	_, err = l.SetQuota(ctx, client.NewSetQuotaRequest(adminID, nil, orgID, 1024, false))
+	assert.Equal(t, codes.Internal, getErrCode(t, err))
+}
+
+func newQuotasLibrarian(rng *rand.Rand, adminID ecid.ID, quotas orgQuotas) *Librarian {
+	return &Librarian{
+		peerID: ecid.NewPseudoRandom(rng),
+		config: NewDefaultConfig().WithAdminPubKey(&adminID.Key().PublicKey),
+		rqv:    &alwaysRequestVerifier{},
+		quotas: quotas,
+		logger: zap.NewNop(),
+	}
+}
+
+func newTestOrgQuotas(params *QuotaParameters) orgQuotas {
+	kvdb := db.NewMemoryDB()
+	q, err := loadOrgQuotas(cstorage.NewQuotaSLD(kvdb), cstorage.NewQuotaChargeSLD(kvdb), params)
+	cerrors.MaybePanic(err)
+	return q
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultReadinessMinPeers is the default minimum number of routing table peers a librarian
+	// needs to report itself as serving. It is zero so seeds and single-node librarians, which may
+	// never have any peers, still serve once bootstrapped.
+	DefaultReadinessMinPeers = uint(0)
+
+	// DefaultReadinessCheckInterval is the default interval between checks of whether the
+	// librarian is ready to serve.
+	DefaultReadinessCheckInterval = 5 * time.Second
+)
+
+// healthServices are the services whose serving status the health service reports: the overall
+// server (the empty service name) and the Librarian service.
+var healthServices = []string{"", "api.Librarian"}
+
+// ReadinessParameters define when a librarian reports itself as serving to health checks, so load
+// balancers and orchestrators (e.g., Kubernetes readiness probes) only route requests to
+// librarians able to handle them.
+type ReadinessParameters struct {
+	// MinPeers is the minimum number of routing table peers needed to serve, since searches and
+	// stores can't succeed without them.
+	MinPeers uint
+
+	// CheckInterval is the interval between checks of the routing table size.
+	CheckInterval time.Duration
+}
+
+// NewDefaultReadinessParameters returns a *ReadinessParameters object with default values.
+func NewDefaultReadinessParameters() *ReadinessParameters {
+	return &ReadinessParameters{
+		MinPeers:      DefaultReadinessMinPeers,
+		CheckInterval: DefaultReadinessCheckInterval,
+	}
+}
+
+// updateReadiness reports NOT_SERVING until bootstrap completes and then periodically reports
+// whether the routing table has enough peers to serve until the server stops.
+func (l *Librarian) updateReadiness(bootstrapped chan struct{}) {
+	select {
+	case <-l.stop:
+		return
+	case <-bootstrapped:
+	}
+	ticker := time.NewTicker(l.config.Readiness.CheckInterval)
+	defer ticker.Stop()
+	for {
+		l.checkReadiness()
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReadiness sets the serving status according to whether the routing table has at least the
+// minimum number of peers.
+func (l *Librarian) checkReadiness() {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if l.rt.NumPeers() >= int(l.config.Readiness.MinPeers) {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	l.setServingStatus(status)
+}
+
+// setServingStatus sets the serving status of the health services, logging when it changes.
+func (l *Librarian) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	l.servingMu.Lock()
+	defer l.servingMu.Unlock()
+	if status == l.serving {
+		return
+	}
+	for _, service := range healthServices {
+		l.health.SetServingStatus(service, status)
+	}
+	l.serving = status
+	l.logger.Info("set serving status",
+		zap.Stringer(logStatus, status),
+		zap.Int(logNPeers, l.rt.NumPeers()),
+	)
+}
+
+// healthServer is a healthpb.HealthServer reporting the serving status set for each service.
+// Unlike health.Server, it reports the status set for the overall server (the empty service name)
+// rather than always reporting it as serving, so readiness probes see when the librarian isn't.
+type healthServer struct {
+	mu       sync.Mutex
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+}
+
+func newHealthServer() *healthServer {
+	return &healthServer{
+		statuses: make(map[string]healthpb.HealthCheckResponse_ServingStatus),
+	}
+}
+
+func (s *healthServer) Check(
+	ctx context.Context, rq *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if servingStatus, in := s.statuses[rq.Service]; in {
+		return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+	}
+	if rq.Service == "" {
+		// overall server is serving until told otherwise
+		return &healthpb.HealthCheckResponse{
+			Status: healthpb.HealthCheckResponse_SERVING,
+		}, nil
+	}
+	return nil, status.Error(codes.NotFound, "unknown service")
+}
+
+func (s *healthServer) Watch(
+	rq *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer,
+) error {
+	return status.Error(codes.Unimplemented, "watching is not supported")
+}
+
+// SetServingStatus sets the serving status of the service.
+func (s *healthServer) SetServingStatus(
+	service string, servingStatus healthpb.HealthCheckResponse_ServingStatus,
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[service] = servingStatus
+}
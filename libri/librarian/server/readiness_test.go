@@ -0,0 +1,99 @@
+package server
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestLibrarian_checkReadiness(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := routing.NewTestWithPeers(rng, 8)
+	config := NewDefaultConfig()
+	l := &Librarian{
+		config: config,
+		rt:     rt,
+		health: newHealthServer(),
+		logger: zap.NewNop(),
+	}
+
+	// enough peers to serve
+	config.Readiness.MinPeers = 8
+	l.checkReadiness()
+	for _, service := range healthServices {
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, l, service))
+	}
+
+	// too few peers to serve
+	config.Readiness.MinPeers = 9
+	l.checkReadiness()
+	for _, service := range healthServices {
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, l, service))
+	}
+}
+
+func TestLibrarian_updateReadiness(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := routing.NewTestWithPeers(rng, 8)
+	config := NewDefaultConfig()
+	config.Readiness.CheckInterval = 10 * time.Millisecond
+	l := &Librarian{
+		config: config,
+		rt:     rt,
+		health: newHealthServer(),
+		logger: zap.NewNop(),
+		stop:   make(chan struct{}),
+	}
+	l.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	bootstrapped := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		l.updateReadiness(bootstrapped)
+		close(done)
+	}()
+
+	// not serving until bootstrapped
+	time.Sleep(5 * config.Readiness.CheckInterval)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, l, ""))
+
+	close(bootstrapped)
+	time.Sleep(5 * config.Readiness.CheckInterval)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, l, ""))
+
+	close(l.stop)
+	<-done
+}
+
+func TestHealthServer(t *testing.T) {
+	s := newHealthServer()
+	rp, err := s.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, rp.Status)
+
+	// overall server's status can be set like any other service's
+	s.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	rp, err = s.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, rp.Status)
+
+	rp, err = s.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.NotFound, getErrCode(t, err))
+}
+
+func servingStatus(
+	t *testing.T, l *Librarian, service string,
+) healthpb.HealthCheckResponse_ServingStatus {
+	rp, err := l.health.Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: service,
+	})
+	assert.Nil(t, err)
+	return rp.Status
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"go.uber.org/zap"
+)
+
+// maxConcurrentReadRepairs is the max number of read repairs in progress at once. Repairs beyond
+// this are dropped (and logged) rather than piling up behind slow or unreachable peers.
+const maxConcurrentReadRepairs = 16
+
+// readRepairParams returns the search parameters for the Get request, recording the peers
+// missing the value when the request asks for read repair even if the librarian doesn't by
+// default.
+func readRepairParams(params *search.Parameters, rq *api.GetRequest) *search.Parameters {
+	if !rq.ReadRepair || params.ReadRepair {
+		return params
+	}
+	repairParams := *params
+	repairParams.ReadRepair = true
+	return &repairParams
+}
+
+// maybeReadRepair read-repairs the document in the background unless too many read repairs are
+// already in progress.
+func (l *Librarian) maybeReadRepair(
+	key id.ID, value *api.Document, expireTime int64, missing []peer.Peer,
+) {
+	select {
+	case l.readRepairs <- struct{}{}:
+	default:
+		l.logger.Debug("dropping read repair since too many in progress",
+			zap.Stringer(logKey, key))
+		return
+	}
+	go func() {
+		defer func() { <-l.readRepairs }()
+		l.readRepair(key, value, expireTime, missing)
+	}()
+}
+
+// readRepair re-stores the document found by a Get, with its (Unix second) expire time if
+// non-zero, with the closest peers that responded without it, so popular documents heal their
+// replication as they're read. Failures are left to anti-entropy and the next read.
+func (l *Librarian) readRepair(
+	key id.ID, value *api.Document, expireTime int64, missing []peer.Peer,
+) {
+	if checkExpireTime(expireTime, time.Now()) != nil {
+		// already expired, so nothing to repair
+		return
+	}
+	nRepaired := 0
+	for _, p := range missing {
+		rq := client.NewStoreRequest(l.peerID, l.orgID, key, value)
+		rq.ExpireTime = expireTime
+		err := peer.QueryAddresses(p, func(address *net.TCPAddr) error {
+			return l.storeTo(address, rq, l.config.Search.Timeout)
+		})
+		if err != nil {
+			comm.MaybeRecordRpErr(l.rec, p.ID(), api.Store, err)
+			l.logger.Debug("error read-repairing document",
+				zap.Stringer(logKey, key),
+				zap.Stringer(logPeerID, p.ID()),
+				zap.Error(err),
+			)
+			continue
+		}
+		l.record(p.ID(), api.Store, comm.Response, comm.Success)
+		nRepaired++
+	}
+	l.logger.Info("read-repaired document",
+		zap.Stringer(logKey, key),
+		zap.Int(logNMissing, len(missing)),
+		zap.Int(logNRepaired, nRepaired),
+	)
+}
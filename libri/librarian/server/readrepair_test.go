@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRepairParams(t *testing.T) {
+	params := search.NewDefaultParameters()
+
+	// request not asking for read repair uses librarian's params
+	assert.Equal(t, params, readRepairParams(params, &api.GetRequest{}))
+
+	// request asking for it records missing peers without changing librarian's params
+	repairParams := readRepairParams(params, &api.GetRequest{ReadRepair: true})
+	assert.True(t, repairParams.ReadRepair)
+	assert.False(t, params.ReadRepair)
+	assert.Equal(t, params.NClosestResponses, repairParams.NClosestResponses)
+
+	// librarian already doing read repair uses its params
+	params.ReadRepair = true
+	assert.Equal(t, params, readRepairParams(params, &api.GetRequest{ReadRepair: true}))
+}
+
+func TestLibrarian_readRepair(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l, lc := newTestHandoffLibrarian(rng)
+	value, key := api.NewTestDocument(rng)
+	missing := peer.NewTestPeers(rng, 3)
+	expireTime := time.Now().Add(time.Hour).Unix()
+
+	l.readRepair(key, value, expireTime, missing)
+	assert.Len(t, lc.stored, len(missing))
+	for _, rq := range lc.stored {
+		assert.Equal(t, key.Bytes(), rq.Key)
+		assert.Equal(t, value, rq.Value)
+		assert.Equal(t, expireTime, rq.ExpireTime)
+	}
+
+	// already expired document isn't repaired
+	lc.stored = nil
+	l.readRepair(key, value, time.Now().Add(-time.Minute).Unix(), missing)
+	assert.Empty(t, lc.stored)
+
+	// errors don't stop the repair
+	lc.err = errors.New("some Store error")
+	l.readRepair(key, value, 0, missing)
+	assert.Empty(t, lc.stored)
+}
+
+func TestLibrarian_maybeReadRepair(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	l, lc := newTestHandoffLibrarian(rng)
+	value, key := api.NewTestDocument(rng)
+	missing := peer.NewTestPeers(rng, 3)
+
+	// repair dropped when too many in progress
+	l.readRepairs = make(chan struct{}, 1)
+	l.readRepairs <- struct{}{}
+	l.maybeReadRepair(key, value, 0, missing)
+	assert.Empty(t, lc.stored)
+
+	// repair runs in background and releases its slot when done
+	<-l.readRepairs
+	l.maybeReadRepair(key, value, 0, missing)
+	l.readRepairs <- struct{}{}
+	assert.Len(t, lc.stored, len(missing))
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+// RefreshRange searches for a random target in the range [lowerBound, upperBound), usually that
+// of a routing table bucket, adding the peers that responded to the routing table. It returns the
+// number of responding peers.
+func (l *Librarian) RefreshRange(ctx context.Context, lowerBound, upperBound id.ID) (int, error) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	s, err := search.NewRefreshSearch(l.peerID, l.orgID, lowerBound, upperBound, l.config.Search,
+		rng)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Release()
+	seeds := l.rt.Find(s.Key, s.Params.NClosestResponses)
+	if err = l.searcher.Search(ctx, s, seeds); err != nil {
+		return 0, err
+	}
+
+	// add found peers to routing table
+	for _, p := range s.Result.Responded {
+		l.rt.Push(p)
+	}
+	l.logger.Debug("refreshed range",
+		zap.Stringer("lower_bound", lowerBound),
+		zap.Stringer("upper_bound", upperBound),
+		zap.Int("n_responded", len(s.Result.Responded)),
+	)
+	return len(s.Result.Responded), nil
+}
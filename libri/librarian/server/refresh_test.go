@@ -0,0 +1,49 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestLibrarian_RefreshRange_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	lb, ub := id.LowerBound, id.UpperBound
+	result := search.NewInitialResult(id.NewPseudoRandom(rng), search.NewDefaultParameters())
+	newPeers := make([]peer.Peer, 4)
+	for i := range newPeers {
+		newPeers[i] = peer.NewTestPeer(rng, 100+i)
+		result.Responded[newPeers[i].ID().String()] = newPeers[i]
+	}
+	l := newGetLibrarian(rng, result, nil)
+	nPeers := l.rt.NumPeers()
+
+	nResponded, err := l.RefreshRange(context.Background(), lb, ub)
+	assert.Nil(t, err)
+	assert.Equal(t, len(newPeers), nResponded)
+	assert.Equal(t, nPeers+len(newPeers), l.rt.NumPeers())
+	for _, p := range newPeers {
+		_, in := l.rt.Get(p.ID())
+		assert.True(t, in)
+	}
+}
+
+func TestLibrarian_RefreshRange_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	// check empty range error bubbles up
+	l := newGetLibrarian(rng, nil, nil)
+	_, err := l.RefreshRange(context.Background(), id.UpperBound, id.LowerBound)
+	assert.Equal(t, search.ErrEmptyRange, err)
+
+	// check Search error bubbles up
+	l = newGetLibrarian(rng, nil, errors.New("some Search error"))
+	_, err = l.RefreshRange(context.Background(), id.LowerBound, id.UpperBound)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,63 @@
+package replicate
+
+import (
+	"math"
+
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/verify"
+	"go.uber.org/zap"
+)
+
+// WithChurnGetter sets the ChurnGetter whose statistics the replicator uses to tune the number of
+// replicas it maintains of each document, when its MaxNReplicas parameter is set.
+func WithChurnGetter(r Replicator, cg comm.ChurnGetter) Replicator {
+	r.(*replicator).churn = cg
+	return r
+}
+
+// TuneNReplicas returns the number of replicas needed for the probability that the peers holding
+// every one of them disconnect within the repair window to be at most the max loss probability,
+// bounded below by nReplicas and above by the MaxNReplicas parameter. Session lengths are assumed
+// to be exponentially distributed with the median of the churn statistics, so a peer disconnects
+// within the window w with probability 1 - 2^(-w/median). Without ended sessions to estimate the
+// median from, nReplicas is returned.
+func TuneNReplicas(nReplicas uint, stats *comm.ChurnStats, params *Parameters) uint {
+	if params.MaxNReplicas <= nReplicas || stats.MedianSession <= 0 {
+		return nReplicas
+	}
+	ratio := float64(params.RepairWindow) / float64(stats.MedianSession)
+	pDisconnect := 1 - math.Pow(2, -ratio)
+	if pDisconnect <= 0 {
+		return nReplicas
+	}
+	if pDisconnect >= 1 || params.MaxLossProb <= 0 {
+		return params.MaxNReplicas
+	}
+	needed := math.Ceil(math.Log(params.MaxLossProb) / math.Log(pDisconnect))
+	if needed <= float64(nReplicas) {
+		return nReplicas
+	}
+	if needed >= float64(params.MaxNReplicas) {
+		return params.MaxNReplicas
+	}
+	return uint(needed)
+}
+
+// tunedVerifyParams returns the verify parameters with the number of replicas tuned to the
+// network's churn, if the replicator tunes it.
+func (r *replicator) tunedVerifyParams() *verify.Parameters {
+	if r.churn == nil || r.replicatorParams.MaxNReplicas == 0 {
+		return r.verifyParams
+	}
+	nReplicas := TuneNReplicas(r.verifyParams.NReplicas, r.churn.Stats(), r.replicatorParams)
+	if nReplicas == r.verifyParams.NReplicas {
+		return r.verifyParams
+	}
+	r.logger.Debug("tuned number of replicas to churn",
+		zap.Uint(logNReplicas, nReplicas),
+	)
+	tuned := *r.verifyParams
+	tuned.NReplicas = nReplicas
+	tuned.NClosestResponses += nReplicas - r.verifyParams.NReplicas
+	return &tuned
+}
@@ -0,0 +1,72 @@
+package replicate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/verify"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestTuneNReplicas(t *testing.T) {
+	params := &Parameters{MaxNReplicas: 8, RepairWindow: time.Hour, MaxLossProb: 0.01}
+	cases := []struct {
+		median   time.Duration
+		params   *Parameters
+		expected uint
+	}{
+		{median: 0, params: params, expected: 3},                // no ended sessions
+		{median: time.Hour, params: &Parameters{}, expected: 3}, // tuning disabled
+		{median: 10 * time.Hour, params: params, expected: 3},   // low churn
+		{median: 2 * time.Hour, params: params, expected: 4},    // moderate churn
+		{median: time.Hour, params: params, expected: 7},        // high churn
+		{median: time.Minute, params: params, expected: 8},      // extreme churn
+		{median: time.Nanosecond, params: params, expected: 8},  // certain disconnects
+		{median: 1000 * time.Hour, params: params, expected: 3}, // negligible churn
+	}
+	for i, c := range cases {
+		stats := &comm.ChurnStats{MedianSession: c.median}
+		assert.Equal(t, c.expected, TuneNReplicas(3, stats, c.params), "case %d", i)
+	}
+}
+
+func TestReplicator_tunedVerifyParams(t *testing.T) {
+	verifyParams := verify.NewDefaultParameters()
+	r := &replicator{
+		verifyParams:     verifyParams,
+		replicatorParams: NewDefaultParameters(),
+		logger:           zap.NewNop(),
+	}
+
+	// no churn getter or tuning disabled
+	assert.Equal(t, verifyParams, r.tunedVerifyParams())
+	WithChurnGetter(r, &fixedChurnGetter{stats: &comm.ChurnStats{MedianSession: time.Hour}})
+	assert.Equal(t, verifyParams, r.tunedVerifyParams())
+
+	// tuned up with high churn
+	r.replicatorParams.MaxNReplicas = 8
+	tuned := r.tunedVerifyParams()
+	assert.Equal(t, uint(8), tuned.NReplicas)
+	assert.Equal(t, verifyParams.NClosestResponses+8-verifyParams.NReplicas,
+		tuned.NClosestResponses)
+	assert.Equal(t, verifyParams.NMaxErrors, tuned.NMaxErrors)
+
+	// left alone with low churn
+	r.churn = &fixedChurnGetter{stats: &comm.ChurnStats{MedianSession: 1000 * time.Hour}}
+	assert.Equal(t, verifyParams, r.tunedVerifyParams())
+}
+
+type fixedChurnGetter struct {
+	stats *comm.ChurnStats
+}
+
+func (f *fixedChurnGetter) Availability(peerID id.ID) (*comm.PeerAvailability, bool) {
+	return nil, false
+}
+
+func (f *fixedChurnGetter) Stats() *comm.ChurnStats {
+	return f.stats
+}
@@ -51,8 +51,9 @@ func (s replicationStatus) String() string {
 }
 
 type metrics struct {
-	verification *prom.CounterVec
-	replication  *prom.CounterVec
+	verification    *prom.CounterVec
+	replication     *prom.CounterVec
+	underreplicated prom.Gauge
 }
 
 func newMetrics() *metrics {
@@ -74,9 +75,18 @@ func newMetrics() *metrics {
 		},
 		[]string{"result"},
 	)
+	underreplicated := prom.NewGauge(
+		prom.GaugeOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "underreplicated_documents",
+			Help:      "Number of stored documents last verified as under-replicated",
+		},
+	)
 	return &metrics{
-		verification: verifications,
-		replication:  replications,
+		verification:    verifications,
+		replication:     replications,
+		underreplicated: underreplicated,
 	}
 }
 
@@ -88,9 +98,14 @@ func (m *metrics) incReplication(result result) {
 	m.replication.WithLabelValues(result.String()).Inc()
 }
 
+func (m *metrics) setUnderreplicated(n int) {
+	m.underreplicated.Set(float64(n))
+}
+
 func (m *metrics) register() {
 	prom.MustRegister(m.verification)
 	prom.MustRegister(m.replication)
+	prom.MustRegister(m.underreplicated)
 
 	// populate zero counts
 	for _, r := range []result{succeeded, exhausted, errored} {
@@ -106,4 +121,5 @@ func (m *metrics) register() {
 func (m *metrics) unregister() {
 	prom.Unregister(m.verification)
 	prom.Unregister(m.replication)
+	prom.Unregister(m.underreplicated)
 }
@@ -59,3 +59,14 @@ func TestMetrics_incReplication(t *testing.T) {
 	}
 	assert.Equal(t, 3, c)
 }
+
+func TestMetrics_setUnderreplicated(t *testing.T) {
+	m := newMetrics()
+	m.register()
+	defer m.unregister()
+
+	m.setUnderreplicated(3)
+	written := dto.Metric{}
+	m.underreplicated.Write(&written)
+	assert.Equal(t, float64(3), *written.Gauge.Value)
+}
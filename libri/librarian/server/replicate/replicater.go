@@ -13,6 +13,7 @@ import (
 	"github.com/drausin/libri/libri/common/storage"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/drausin/libri/libri/librarian/server/routing"
 	"github.com/drausin/libri/libri/librarian/server/search"
@@ -21,6 +22,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -41,6 +43,24 @@ const (
 	// metrics.
 	DefaultReportMetrics = true
 
+	// DefaultSampleRate is the default fraction of stored documents verified on each pass.
+	DefaultSampleRate = float32(1.0)
+
+	// DefaultReplicateInterval is the default minimum amount of time between replication stores.
+	DefaultReplicateInterval = 100 * time.Millisecond
+
+	// DefaultMaxNReplicas is the default maximum number of replicas the number of replicas is
+	// tuned up to given the network's churn, with zero disabling tuning.
+	DefaultMaxNReplicas = uint(0)
+
+	// DefaultRepairWindow is the default expected time for a lost replica to be detected and
+	// repaired.
+	DefaultRepairWindow = 1 * time.Hour
+
+	// DefaultMaxLossProb is the default maximum tolerated probability of losing every replica of
+	// a document within the repair window.
+	DefaultMaxLossProb = 1e-6
+
 	// macKeySize is the size of the MAC key used for verify operations.
 	macKeySize = 32
 
@@ -52,8 +72,9 @@ const (
 	underreplicatedQueueSize = 32
 
 	// logger keys
-	logVerify = "verify"
-	logStore  = "store"
+	logVerify    = "verify"
+	logStore     = "store"
+	logNReplicas = "n_replicas"
 )
 
 var (
@@ -67,6 +88,28 @@ type Parameters struct {
 	VerifyTimeout        time.Duration
 	MaxErrRate           float32
 	ReportMetrics        bool
+
+	// SampleRate is the fraction of stored documents (randomly) sampled for verification on
+	// each pass through them. Zero uses DefaultSampleRate.
+	SampleRate float32
+
+	// ReplicateInterval is the minimum amount of time between replication stores across all
+	// replicator routines, limiting the rate at which under-replicated documents are re-stored.
+	// Zero disables the limit.
+	ReplicateInterval time.Duration
+
+	// MaxNReplicas is the maximum number of replicas each document's required number of
+	// replicas is tuned up to given the network's churn, if the replicator has churn statistics.
+	// Zero disables tuning.
+	MaxNReplicas uint
+
+	// RepairWindow is the expected time for a lost replica to be detected and repaired, over
+	// which the peers holding a document's replicas must not all disconnect.
+	RepairWindow time.Duration
+
+	// MaxLossProb is the maximum tolerated probability that the peers holding every replica of a
+	// document disconnect within the repair window.
+	MaxLossProb float64
 }
 
 // NewDefaultParameters returns the default replicator parameters.
@@ -77,6 +120,11 @@ func NewDefaultParameters() *Parameters {
 		VerifyTimeout:        DefaultVerifyTimeout,
 		MaxErrRate:           DefaultMaxErrRate,
 		ReportMetrics:        DefaultReportMetrics,
+		SampleRate:           DefaultSampleRate,
+		ReplicateInterval:    DefaultReplicateInterval,
+		MaxNReplicas:         DefaultMaxNReplicas,
+		RepairWindow:         DefaultRepairWindow,
+		MaxLossProb:          DefaultMaxLossProb,
 	}
 }
 
@@ -103,8 +151,14 @@ type replicator struct {
 	storeParams      *store.Parameters
 	metrics          *metrics
 	underreplicated  chan *verify.Verify
+	underKeys        map[string]struct{}
+	limiter          *time.Ticker
+	churn            comm.ChurnGetter
+	origins          OriginOrgGetter
 	stop             chan struct{}
 	stopped          chan struct{}
+	ctx              context.Context
+	cancel           context.CancelFunc
 	errs             chan error
 	fatal            chan error
 	logger           *zap.Logger
@@ -112,6 +166,20 @@ type replicator struct {
 	mu               sync.Mutex
 }
 
+// OriginOrgGetter gets the organization each stored document is charged to.
+type OriginOrgGetter interface {
+	// OriginOrgID returns the ID of the organization the document with the given key is charged
+	// to and whether it is charged to one.
+	OriginOrgID(key id.ID) (id.ID, bool, error)
+}
+
+// WithOriginOrgGetter sets the OriginOrgGetter the replicator uses to charge the new replicas it
+// stores to the organization that originally stored each document.
+func WithOriginOrgGetter(r Replicator, og OriginOrgGetter) Replicator {
+	r.(*replicator).origins = og
+	return r
+}
+
 // NewReplicator returns a new Replicator.
 func NewReplicator(
 	peerID ecid.ID,
@@ -126,6 +194,11 @@ func NewReplicator(
 	rng *rand.Rand,
 	logger *zap.Logger,
 ) Replicator {
+	ctx, cancel := context.WithCancel(context.Background())
+	var limiter *time.Ticker
+	if replicatorParams.ReplicateInterval > 0 {
+		limiter = time.NewTicker(replicatorParams.ReplicateInterval)
+	}
 	return &replicator{
 		peerID:           peerID,
 		orgID:            orgID,
@@ -138,9 +211,13 @@ func NewReplicator(
 		storeParams:      storeParams,
 		metrics:          newMetrics(),
 		underreplicated:  make(chan *verify.Verify, underreplicatedQueueSize),
+		underKeys:        make(map[string]struct{}),
+		limiter:          limiter,
 		errs:             make(chan error, errQueueSize),
 		stop:             make(chan struct{}),
 		stopped:          make(chan struct{}),
+		ctx:              ctx,
+		cancel:           cancel,
 		fatal:            make(chan error, 1),
 		rng:              rng,
 		logger:           logger,
@@ -181,6 +258,10 @@ func (r *replicator) Start() error {
 func (r *replicator) Stop() {
 	r.logger.Info("ending replicator")
 	safeClose(r.stop)
+	r.cancel() // abandon any in-flight verifications and stores
+	if r.limiter != nil {
+		r.limiter.Stop()
+	}
 	r.wrapLock(func() {
 		safeCloseErrChan(r.errs)
 		safeCloseVerifyChan(r.underreplicated)
@@ -210,9 +291,24 @@ func (r *replicator) verify() {
 		case <-pause:
 		}
 
-		if err := r.docS.Iterate(r.stop, r.verifyValue); err != nil {
+		// only verify a sample of documents on each pass to spread out the load; shards are
+		// skipped since each is only stored on the single peer closest to it
+		sampleRate := r.sampleRate()
+		stillStored := make(map[string]struct{})
+		sample := func(key id.ID, value []byte) {
+			if !api.IsShardKey(key) && rng.Float32() < sampleRate {
+				r.verifyValue(key, value)
+			}
+			r.wrapLock(func() {
+				if _, in := r.underKeys[key.String()]; in {
+					stillStored[key.String()] = struct{}{}
+				}
+			})
+		}
+		if err := r.docS.Iterate(r.stop, sample); err != nil {
 			r.fatal <- err
 		}
+		r.forgetUnderreplicated(stillStored)
 
 		select {
 		case <-r.stop: // exit if we've received stop signal
@@ -234,15 +330,22 @@ func (r *replicator) verifyValue(key id.ID, value []byte) {
 	_, err := crand.Read(macKey)
 	cerrors.MaybePanic(err) // should never happen
 
-	v := verify.NewVerify(r.peerID, r.orgID, key, value, macKey, r.verifyParams)
-	seeds := r.rt.Find(key, r.verifyParams.NClosestResponses)
+	verifyParams := r.tunedVerifyParams()
+	v := verify.NewVerify(r.peerID, r.orgID, key, value, macKey, verifyParams)
+	seeds := r.rt.Find(key, verifyParams.NClosestResponses)
 
 	operation := func() error {
-		v.Result = verify.NewInitialResult(key, r.verifyParams)
-		return r.verifier.Verify(v, seeds)
+		v.Result = verify.NewInitialResult(key, verifyParams)
+		return r.verifier.Verify(r.ctx, v, seeds)
 	}
 	err = backoff.Retry(operation, client.NewExpBackoff(r.replicatorParams.VerifyTimeout))
+	if r.ctx.Err() != nil {
+		// replicator is stopping
+		return
+	}
 
+	// only count the document as under-replicated while its latest verification says it is
+	r.markUnderreplicated(key, err == nil && !v.Exhausted() && v.UnderReplicated())
 	if err != nil { // implies v.Errored()
 		r.logger.Error("document verification errored", zap.Object(logVerify, v))
 		r.metrics.incVerification(errored, unknown)
@@ -277,35 +380,133 @@ func (r *replicator) verifyValue(key id.ID, value []byte) {
 func (r *replicator) replicate(wg *sync.WaitGroup) {
 	defer wg.Done()
 	for v := range r.underreplicated {
-		s := newStore(r.peerID, r.orgID, v, *r.storeParams)
+		if !r.waitReplicate() {
+			return
+		}
+		s := NewStore(r.peerID, r.orgID, v, *r.storeParams)
+		r.maybeExpire(s, v.Key)
+		r.maybeChargeOrigin(s, v.Key)
 		// empty seeds b/c verification has already, in effect, replaced the search component of
 		// the store operation
-		if err := r.storer.Store(s, []peer.Peer{}); err != nil {
+		if err := r.storer.Store(r.ctx, s, []peer.Peer{}); err != nil {
 			r.metrics.incReplication(errored)
 			r.logger.Error("replication store failed", zap.Object(logStore, s))
 			maybeSendErrChan(r.errs, err)
+			s.Release()
 			continue
 		}
 		if s.Stored() {
 			r.metrics.incReplication(succeeded)
+			r.markUnderreplicated(v.Key, false)
 			r.logger.Info("stored additional replicas", zap.Object(logStore, s))
 		} else {
 			r.metrics.incReplication(errored)
 			r.logger.Error("failed to store additional replicas", zap.Object(logStore, s))
 		}
+		s.Release()
 		// for all other non-Stored outcomes for the store, we basically give up and hope to
 		// replicate on next pass
 		r.wrapLock(func() { maybeSendErrChan(r.errs, nil) })
 	}
 }
 
+// maybeExpire makes the new replicas of a document stored with an expiration time expire at the
+// same time.
+func (r *replicator) maybeExpire(s *store.Store, key id.ID) {
+	expiring, ok := r.docS.(storage.ExpiringDocumentSLD)
+	if !ok {
+		return
+	}
+	expires, in, err := expiring.ExpireTime(key)
+	if err != nil {
+		r.logger.Error("error getting document expire time", zap.Error(err))
+		return
+	}
+	if in {
+		s.ExpireAt(expires)
+	}
+}
+
+// maybeChargeOrigin makes the peers storing the new replicas of a document charge them to the
+// organization the document was originally charged to rather than to the replicator's own.
+func (r *replicator) maybeChargeOrigin(s *store.Store, key id.ID) {
+	if r.origins == nil {
+		return
+	}
+	orgID, in, err := r.origins.OriginOrgID(key)
+	if err != nil {
+		r.logger.Error("error getting document origin org ID", zap.Error(err))
+		return
+	}
+	if in {
+		s.ChargeOrg(orgID)
+	}
+}
+
+// waitReplicate waits until the rate limit allows another replication store, returning false if
+// the replicator is stopping instead.
+func (r *replicator) waitReplicate() bool {
+	if r.limiter == nil {
+		return true
+	}
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-r.limiter.C:
+		return true
+	}
+}
+
+// sampleRate returns the fraction of stored documents to verify on each pass.
+func (r *replicator) sampleRate() float32 {
+	if r.replicatorParams.SampleRate <= 0 {
+		return DefaultSampleRate
+	}
+	return r.replicatorParams.SampleRate
+}
+
+// forgetUnderreplicated stops counting the under-replicated documents no longer stored, e.g.,
+// because they were deleted or expired, given those found still stored during a pass.
+func (r *replicator) forgetUnderreplicated(stillStored map[string]struct{}) {
+	select {
+	case <-r.stop:
+		// pass may have ended early, so not all stored documents were found
+		return
+	default:
+	}
+	r.wrapLock(func() {
+		for key := range r.underKeys {
+			if _, in := stillStored[key]; !in {
+				delete(r.underKeys, key)
+			}
+		}
+		r.metrics.setUnderreplicated(len(r.underKeys))
+	})
+}
+
+// markUnderreplicated records whether the document with the given key is under-replicated,
+// updating the under-replicated document count metric.
+func (r *replicator) markUnderreplicated(key id.ID, under bool) {
+	r.wrapLock(func() {
+		if under {
+			r.underKeys[key.String()] = struct{}{}
+		} else {
+			delete(r.underKeys, key.String())
+		}
+		r.metrics.setUnderreplicated(len(r.underKeys))
+	})
+}
+
 func (r *replicator) wrapLock(operation func()) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	operation()
 }
 
-func newStore(peerID, orgID ecid.ID, v *verify.Verify, storeParams store.Parameters) *store.Store {
+// NewStore creates a store operation that brings the document of an under-replicated
+// verification back to full replication, reusing the closest peers found during verification in
+// place of a new search.
+func NewStore(peerID, orgID ecid.ID, v *verify.Verify, storeParams store.Parameters) *store.Store {
 	value := &api.Document{}
 	cerrors.MaybePanic(proto.Unmarshal(v.Value, value)) // should never happen
 	searchParams := &search.Parameters{
@@ -320,6 +521,7 @@ func newStore(peerID, orgID ecid.ID, v *verify.Verify, storeParams store.Paramet
 
 	// construct minimal search result from verify
 	s.Search.Params.NMaxErrors = v.Params.NMaxErrors
+	s.Search.Result.Release()
 	s.Search.Result = &search.Result{
 		Closest:   v.Result.Closest,
 		Unqueried: v.Result.Unqueried,
@@ -25,6 +25,7 @@ import (
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"golang.org/x/net/context"
 )
 
 func TestNewDefaultParameters(t *testing.T) {
@@ -32,11 +33,15 @@ func TestNewDefaultParameters(t *testing.T) {
 	assert.NotZero(t, p.VerifyInterval)
 	assert.NotZero(t, p.ReplicateConcurrency)
 	assert.NotZero(t, p.MaxErrRate)
+	assert.NotZero(t, p.SampleRate)
+	assert.NotZero(t, p.ReplicateInterval)
+	assert.NotZero(t, p.RepairWindow)
+	assert.NotZero(t, p.MaxLossProb)
 }
 
 func TestReplicator_StartStop(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	assert.Nil(t, err)
 	defer cleanup()
 	defer kvdb.Close()
@@ -145,13 +150,16 @@ func TestReplicator_verify(t *testing.T) {
 	r := replicator{
 		peerID:           selfID,
 		verifyParams:     verify.NewDefaultParameters(),
-		replicatorParams: &Parameters{VerifyInterval: 10 * time.Millisecond},
+		replicatorParams: &Parameters{VerifyInterval: 10 * time.Millisecond, SampleRate: 1.0},
 		storeParams:      store.NewDefaultParameters(),
 		docS:             storage.NewTestDocSLD(),
+		metrics:          newMetrics(),
 		underreplicated:  make(chan *verify.Verify, 1),
+		underKeys:        make(map[string]struct{}),
 		errs:             make(chan error, 8),
 		stop:             make(chan struct{}),
 		stopped:          make(chan struct{}),
+		ctx:              context.Background(),
 		fatal:            make(chan error, 1),
 		rt:               rt,
 		rng:              rng,
@@ -220,7 +228,9 @@ func TestReplicator_verifyValue(t *testing.T) {
 		storeParams:      store.NewDefaultParameters(),
 		metrics:          newMetrics(),
 		underreplicated:  make(chan *verify.Verify, 1),
+		underKeys:        make(map[string]struct{}),
 		errs:             make(chan error, 1),
+		ctx:              context.Background(),
 		rt:               rt,
 		rng:              rng,
 		logger:           zap.NewNop(),
@@ -247,6 +257,7 @@ func TestReplicator_verifyValue(t *testing.T) {
 	default:
 	}
 	checkPromMetric(t, r.metrics.verification, 1, succeeded, full)
+	checkPromGauge(t, r.metrics.underreplicated, 0)
 
 	// check that when a verify operation has UnderReplicated() == true, get msg in toReplicated
 	// and nil error
@@ -267,6 +278,7 @@ func TestReplicator_verifyValue(t *testing.T) {
 	v := <-r.underreplicated
 	assert.Equal(t, key, v.Key)
 	checkPromMetric(t, r.metrics.verification, 1, succeeded, under)
+	checkPromGauge(t, r.metrics.underreplicated, 1)
 
 	// check that when verify is exhausted, we get an error
 	for unqueried.Len() > 0 {
@@ -289,6 +301,11 @@ func TestReplicator_verifyValue(t *testing.T) {
 	}
 	checkPromMetric(t, r.metrics.verification, 1, exhausted, unknown)
 
+	// check that exhausted and errored verifications no longer count the document as
+	// under-replicated
+	checkPromGauge(t, r.metrics.underreplicated, 0)
+	r.markUnderreplicated(key, true)
+
 	// check that when verify errors, we get an error
 	r.verifier = &fixedVerifier{
 		err:    errors.New("some Verify error"),
@@ -303,6 +320,40 @@ func TestReplicator_verifyValue(t *testing.T) {
 	default:
 	}
 	checkPromMetric(t, r.metrics.verification, 1, errored, unknown)
+
+	checkPromGauge(t, r.metrics.underreplicated, 0)
+}
+
+func TestReplicator_sampleRate(t *testing.T) {
+	r := replicator{replicatorParams: &Parameters{}}
+	assert.Equal(t, DefaultSampleRate, r.sampleRate())
+
+	r.replicatorParams.SampleRate = 0.5
+	assert.Equal(t, float32(0.5), r.sampleRate())
+}
+
+func TestReplicator_forgetUnderreplicated(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key1, key2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	r := replicator{
+		metrics:   newMetrics(),
+		underKeys: make(map[string]struct{}),
+		stop:      make(chan struct{}),
+	}
+	r.markUnderreplicated(key1, true)
+	r.markUnderreplicated(key2, true)
+	checkPromGauge(t, r.metrics.underreplicated, 2)
+
+	// documents no longer stored are forgotten
+	r.forgetUnderreplicated(map[string]struct{}{key1.String(): {}})
+	checkPromGauge(t, r.metrics.underreplicated, 1)
+	_, in := r.underKeys[key1.String()]
+	assert.True(t, in)
+
+	// nothing forgotten when stopping, since pass may not have found all stored documents
+	close(r.stop)
+	r.forgetUnderreplicated(map[string]struct{}{})
+	checkPromGauge(t, r.metrics.underreplicated, 1)
 }
 
 func checkPromMetric(t *testing.T, metrics *prom.CounterVec, expected int, labels ...fmt.Stringer) {
@@ -327,6 +378,12 @@ func checkPromMetric(t *testing.T, metrics *prom.CounterVec, expected int, label
 	assert.True(t, asserted)
 }
 
+func checkPromGauge(t *testing.T, gauge prom.Gauge, expected int) {
+	written := dto.Metric{}
+	gauge.Write(&written)
+	assert.Equal(t, float64(expected), *written.Gauge.Value)
+}
+
 func peerMap(peerArr []peer.Peer) map[string]peer.Peer {
 	peerMap := make(map[string]peer.Peer)
 	for _, p := range peerArr {
@@ -340,7 +397,7 @@ type fixedVerifier struct {
 	err    error
 }
 
-func (f *fixedVerifier) Verify(v *verify.Verify, seeds []peer.Peer) error {
+func (f *fixedVerifier) Verify(ctx context.Context, v *verify.Verify, seeds []peer.Peer) error {
 	v.Result = f.result
 	return f.err
 }
@@ -359,7 +416,9 @@ func TestReplicator_replicate(t *testing.T) {
 		storeParams:     store.NewDefaultParameters(),
 		metrics:         newMetrics(),
 		underreplicated: make(chan *verify.Verify, 1),
+		underKeys:       make(map[string]struct{}),
 		errs:            make(chan error, 1),
+		ctx:             context.Background(),
 		logger:          zap.NewNop(), // server.NewDevLogger(zap.DebugLevel),
 	}
 
@@ -367,6 +426,7 @@ func TestReplicator_replicate(t *testing.T) {
 	go r.replicate(new(sync.WaitGroup))
 
 	// check that when storer returns result where Stored() == true, NReplicated increases
+	r.markUnderreplicated(key, true)
 	r.storer = &fixedStorer{
 		result: &store.Result{Responded: peer.NewTestPeers(rng, int(verifyParams.NReplicas))},
 	}
@@ -374,6 +434,7 @@ func TestReplicator_replicate(t *testing.T) {
 	err = <-r.errs
 	assert.Nil(t, err)
 	checkPromMetric(t, r.metrics.replication, 1, succeeded)
+	checkPromGauge(t, r.metrics.underreplicated, 0)
 
 	// check that when storer returns result where Stored() == false, NReplicated does not increase
 	r.storer = &fixedStorer{
@@ -392,12 +453,145 @@ func TestReplicator_replicate(t *testing.T) {
 	checkPromMetric(t, r.metrics.replication, 2, errored)
 }
 
+func TestReplicator_replicate_rateLimited(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	macKey := api.RandBytes(rng, 32)
+	verifyParams := verify.NewDefaultParameters()
+	newReplicator := func(ctx context.Context, interval time.Duration) *replicator {
+		return &replicator{
+			peerID:          peerID,
+			storeParams:     store.NewDefaultParameters(),
+			metrics:         newMetrics(),
+			underreplicated: make(chan *verify.Verify, 3),
+			underKeys:       make(map[string]struct{}),
+			limiter:         time.NewTicker(interval),
+			errs:            make(chan error, 3),
+			ctx:             ctx,
+			logger:          zap.NewNop(),
+			storer: &fixedStorer{
+				result: &store.Result{
+					Responded: peer.NewTestPeers(rng, int(verifyParams.NReplicas)),
+				},
+			},
+		}
+	}
+
+	// check that stores are spaced out by at least the replicate interval
+	interval := 25 * time.Millisecond
+	r := newReplicator(context.Background(), interval)
+	defer r.limiter.Stop()
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	go r.replicate(wg)
+	start := time.Now()
+	for c := 0; c < 3; c++ {
+		r.underreplicated <- verify.NewVerify(peerID, orgID, key, valueBytes, macKey,
+			verifyParams)
+	}
+	for c := 0; c < 3; c++ {
+		assert.Nil(t, <-r.errs)
+	}
+	assert.True(t, time.Since(start) >= 3*interval)
+	close(r.underreplicated)
+	wg.Wait()
+
+	// check that replicate routine exits while waiting when context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = newReplicator(ctx, time.Hour)
+	defer r.limiter.Stop()
+	wg.Add(1)
+	go r.replicate(wg)
+	r.underreplicated <- verify.NewVerify(peerID, orgID, key, valueBytes, macKey, verifyParams)
+	wg.Wait()
+	assert.Len(t, r.errs, 0)
+}
+
+func TestReplicator_maybeExpire(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	kvdb := db.NewMemoryDB()
+	expiring := storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb)
+	expires := time.Now().Add(time.Hour)
+	assert.Nil(t, expiring.StoreExpiring(key, value, expires))
+	newStore := func() *store.Store {
+		return store.NewStore(peerID, orgID, key, value, search.NewDefaultParameters(),
+			store.NewDefaultParameters())
+	}
+
+	// new replicas expire at same time as stored document
+	r := &replicator{docS: expiring, logger: zap.NewNop()}
+	s := newStore()
+	r.maybeExpire(s, key)
+	assert.Equal(t, expires.Unix(), s.CreateRq().ExpireTime)
+
+	// documents without expiration times have replicas without them
+	s = newStore()
+	r.maybeExpire(s, id.NewPseudoRandom(rng))
+	assert.Zero(t, s.CreateRq().ExpireTime)
+
+	// non-expiring storage doesn't set expiration times
+	r.docS = storage.NewTestDocSLD()
+	s = newStore()
+	r.maybeExpire(s, key)
+	assert.Zero(t, s.CreateRq().ExpireTime)
+}
+
+func TestReplicator_maybeChargeOrigin(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	newStore := func() *store.Store {
+		return store.NewStore(peerID, orgID, key, value, search.NewDefaultParameters(),
+			store.NewDefaultParameters())
+	}
+
+	// no origin getter leaves replicas charged to the replicator's org
+	r := &replicator{logger: zap.NewNop()}
+	s := newStore()
+	r.maybeChargeOrigin(s, key)
+	assert.Nil(t, s.CreateRq().OriginOrgId)
+
+	// new replicas charged to same org as stored document
+	originOrgID := id.NewPseudoRandom(rng)
+	WithOriginOrgGetter(r, &fixedOriginOrgGetter{orgID: originOrgID, in: true})
+	s = newStore()
+	r.maybeChargeOrigin(s, key)
+	assert.Equal(t, originOrgID.Bytes(), s.CreateRq().OriginOrgId)
+
+	// uncharged documents and errors leave replicas charged to the replicator's org
+	for _, og := range []*fixedOriginOrgGetter{{}, {err: errors.New("some Load error")}} {
+		r.origins = og
+		s = newStore()
+		r.maybeChargeOrigin(s, key)
+		assert.Nil(t, s.CreateRq().OriginOrgId)
+	}
+}
+
+type fixedOriginOrgGetter struct {
+	orgID id.ID
+	in    bool
+	err   error
+}
+
+func (f *fixedOriginOrgGetter) OriginOrgID(key id.ID) (id.ID, bool, error) {
+	return f.orgID, f.in, f.err
+}
+
 type fixedStorer struct {
 	result *store.Result
 	err    error
 }
 
-func (s *fixedStorer) Store(store *store.Store, seeds []peer.Peer) error {
+func (s *fixedStorer) Store(ctx context.Context, store *store.Store, seeds []peer.Peer) error {
 	if s.err != nil {
 		return s.err
 	}
@@ -430,7 +624,7 @@ func TestNewStore(t *testing.T) {
 	assert.True(t, v.UnderReplicated())
 	assert.False(t, v.FullyReplicated())
 
-	s := newStore(peerID, orgID, v, *store.NewDefaultParameters())
+	s := NewStore(peerID, orgID, v, *store.NewDefaultParameters())
 	assert.Equal(t, verifyParams.NMaxErrors, s.Search.Params.NMaxErrors)
 	assert.Equal(t, uint(1), s.Params.NReplicas)
 	assert.Equal(t, uint(4), s.Search.Params.NClosestResponses)
@@ -0,0 +1,80 @@
+package routing
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/server/peer"
+)
+
+// ErrAddressConfirmThrottled indicates that a peer's claimed change of address was not confirmed
+// because another confirmation for that peer happened too recently.
+var ErrAddressConfirmThrottled = errors.New("address confirmation throttled")
+
+// AddressConfirmer confirms that a peer is reachable at its claimed addresses.
+type AddressConfirmer interface {
+	// Confirm returns nil if the peer responds as itself at each of its claimed addresses.
+	Confirm(claimed peer.Peer) error
+}
+
+// AddressUpdater applies a known peer's claimed change of address to the routing table once the
+// new addresses have been confirmed.
+type AddressUpdater interface {
+	// MaybeUpdate confirms and applies the claimed peer's addresses if the peer is known with
+	// different ones, returning whether the table was updated.
+	MaybeUpdate(claimed peer.Peer) (bool, error)
+}
+
+type addressUpdater struct {
+	rt        Table
+	confirmer AddressConfirmer
+	params    *Parameters
+
+	// peer ID string -> time of latest confirmation attempt
+	attempts map[string]time.Time
+	mu       sync.Mutex
+}
+
+// NewAddressUpdater returns a new AddressUpdater for the table using the given confirmer.
+func NewAddressUpdater(rt Table, confirmer AddressConfirmer, params *Parameters) AddressUpdater {
+	return &addressUpdater{
+		rt:        rt,
+		confirmer: confirmer,
+		params:    params,
+		attempts:  make(map[string]time.Time),
+	}
+}
+
+func (u *addressUpdater) MaybeUpdate(claimed peer.Peer) (bool, error) {
+	known, in := u.rt.Get(claimed.ID())
+	if !in || len(claimed.Addresses()) == 0 || peer.SameAddresses(known, claimed) {
+		return false, nil
+	}
+	if !u.startAttempt(claimed) {
+		return false, ErrAddressConfirmThrottled
+	}
+	if err := u.confirmer.Confirm(claimed); err != nil {
+		return false, err
+	}
+	return u.rt.UpdateAddresses(claimed), nil
+}
+
+// startAttempt records an attempt to confirm the claimed peer's addresses, returning false if
+// the previous attempt was within the confirm interval.
+func (u *addressUpdater) startAttempt(claimed peer.Peer) bool {
+	now := time.Now()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for idStr, attempted := range u.attempts {
+		if now.Sub(attempted) >= u.params.AddressConfirmInterval {
+			delete(u.attempts, idStr)
+		}
+	}
+	idStr := claimed.ID().String()
+	if _, in := u.attempts[idStr]; in {
+		return false
+	}
+	u.attempts[idStr] = now
+	return true
+}
@@ -0,0 +1,99 @@
+package routing
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressUpdater_MaybeUpdate(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	known := rt.Find(rt.SelfID(), 1)[0]
+	newAddr := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 20100}
+	claimed := peer.New(known.ID(), "", newAddr)
+	params := NewDefaultParameters()
+
+	// confirmed address change should be applied
+	confirmer := &fixedAddressConfirmer{}
+	u := NewAddressUpdater(rt, confirmer, params)
+	updated, err := u.MaybeUpdate(claimed)
+	assert.Nil(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, 1, confirmer.nConfirms)
+	updatedPeer, _ := rt.Get(known.ID())
+	assert.Equal(t, newAddr.String(), updatedPeer.Address().String())
+
+	// no change of address shouldn't need confirmation
+	updated, err = u.MaybeUpdate(claimed)
+	assert.Nil(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, 1, confirmer.nConfirms)
+
+	// unknown peer shouldn't need confirmation
+	updated, err = u.MaybeUpdate(peer.NewTestPeer(rng, 100))
+	assert.Nil(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, 1, confirmer.nConfirms)
+}
+
+func TestAddressUpdater_MaybeUpdate_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	known := rt.Find(rt.SelfID(), 1)[0]
+	prevAddr := known.Address()
+	claimed := peer.New(known.ID(), "", &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 1})
+	params := NewDefaultParameters()
+
+	// unconfirmed address change shouldn't be applied
+	confirmer := &fixedAddressConfirmer{err: errors.New("some confirm error")}
+	u := NewAddressUpdater(rt, confirmer, params)
+	updated, err := u.MaybeUpdate(claimed)
+	assert.Equal(t, confirmer.err, err)
+	assert.False(t, updated)
+	assert.Equal(t, prevAddr, known.Address())
+
+	// another attempt within the interval should be throttled
+	updated, err = u.MaybeUpdate(claimed)
+	assert.Equal(t, ErrAddressConfirmThrottled, err)
+	assert.False(t, updated)
+	assert.Equal(t, 1, confirmer.nConfirms)
+
+	// but allowed after the interval
+	params.AddressConfirmInterval = 0
+	_, err = u.MaybeUpdate(claimed)
+	assert.Equal(t, confirmer.err, err)
+	assert.Equal(t, 2, confirmer.nConfirms)
+}
+
+func TestAddressUpdater_startAttempt(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.AddressConfirmInterval = 10 * time.Millisecond
+	u := NewAddressUpdater(nil, nil, params).(*addressUpdater)
+	p1, p2 := peer.NewTestPeer(rng, 0), peer.NewTestPeer(rng, 1)
+
+	assert.True(t, u.startAttempt(p1))
+	assert.False(t, u.startAttempt(p1))
+	assert.True(t, u.startAttempt(p2))
+
+	// old attempts should be pruned
+	u.attempts[p1.ID().String()] = time.Now().Add(-params.AddressConfirmInterval)
+	assert.True(t, u.startAttempt(p1))
+	assert.Len(t, u.attempts, 2)
+}
+
+type fixedAddressConfirmer struct {
+	nConfirms int
+	err       error
+}
+
+func (f *fixedAddressConfirmer) Confirm(claimed peer.Peer) error {
+	f.nConfirms++
+	return f.err
+}
@@ -2,6 +2,7 @@ package routing
 
 import (
 	"math/big"
+	"sync"
 
 	"container/heap"
 
@@ -39,19 +40,28 @@ type bucket struct {
 	// positions (i.e., indices) of each peer (keyed by ID string) in the heap.
 	positions map[string]int
 
+	// most entries positions has held since it was created or last compacted
+	peakPositions int
+
 	// determines peer ordering within a bucket
 	preferer comm.Preferer
 
 	// determines whether a peer is healthy
 	doctor comm.Doctor
+
+	// guards the active peers heap
+	mu sync.Mutex
 }
 
-// newFirstBucket creates a new instance of the first bucket (spanning the entire ID range)
-func newFirstBucket(maxActivePeers uint, preferer comm.Preferer, doctor comm.Doctor) *bucket {
+// newFirstBucket creates a new instance of the first bucket (spanning the entire ID range up to
+// the given upper bound)
+func newFirstBucket(
+	upperBound id.ID, maxActivePeers uint, preferer comm.Preferer, doctor comm.Doctor,
+) *bucket {
 	return &bucket{
 		depth:          0,
 		lowerBound:     id.LowerBound,
-		upperBound:     id.UpperBound,
+		upperBound:     upperBound,
 		idMass:         1.0,
 		idCumMass:      1.0,
 		maxActivePeers: maxActivePeers,
@@ -82,6 +92,9 @@ func (b *bucket) Swap(i, j int) {
 func (b *bucket) Push(p interface{}) {
 	b.activePeers = append(b.activePeers, p.(peer.Peer))
 	b.positions[p.(peer.Peer).ID().String()] = len(b.activePeers) - 1
+	if len(b.positions) > b.peakPositions {
+		b.peakPositions = len(b.positions)
+	}
 }
 
 // Pop removes the root peer from the routing bucket.
@@ -128,6 +141,17 @@ func (b *bucket) Vacancy() bool {
 	return len(b.activePeers) < int(b.maxActivePeers)
 }
 
+// SubnetPeers returns the number of active peers whose preferred address is in the given subnet.
+func (b *bucket) SubnetPeers(sn string) uint {
+	n := uint(0)
+	for _, p := range b.activePeers {
+		if subnet(p.Address()) == sn {
+			n++
+		}
+	}
+	return n
+}
+
 // Contains returns whether the bucket's ID range contains the target.
 func (b *bucket) Contains(target id.ID) bool {
 	return target.Cmp(b.lowerBound) >= 0 && target.Cmp(b.upperBound) < 0
@@ -2,9 +2,12 @@ package routing
 
 import (
 	"container/heap"
+	"fmt"
 	"math/rand"
+	"net"
 	"testing"
 
+	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
@@ -13,9 +16,9 @@ import (
 
 func TestBucket_PushPop(t *testing.T) {
 	for n := 1; n <= 128; n *= 2 {
-		rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
-		preferer, doctor := comm.NewRpPreferer(rec), comm.NewNaiveDoctor()
-		b := newFirstBucket(DefaultMaxActivePeers, preferer, doctor)
+		rec := newTestReputationJudge()
+		preferer, doctor := comm.NewReputationPreferer(rec), comm.NewNaiveDoctor()
+		b := newFirstBucket(id.UpperBound, DefaultMaxActivePeers, preferer, doctor)
 		rng := rand.New(rand.NewSource(int64(n)))
 		for i, p := range peer.NewTestPeers(rng, n) {
 
@@ -35,9 +38,9 @@ func TestBucket_PushPop(t *testing.T) {
 }
 
 func TestBucket_Peak(t *testing.T) {
-	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
-	preferer, doctor := comm.NewRpPreferer(rec), comm.NewNaiveDoctor()
-	b := newFirstBucket(DefaultMaxActivePeers, preferer, doctor)
+	preferer, doctor := comm.NewReputationPreferer(newTestReputationJudge()),
+		comm.NewNaiveDoctor()
+	b := newFirstBucket(id.UpperBound, DefaultMaxActivePeers, preferer, doctor)
 
 	// nothing to peak b/c bucket is empty
 	assert.Equal(t, 0, len(b.Peak(2)))
@@ -52,3 +55,24 @@ func TestBucket_Peak(t *testing.T) {
 	assert.Equal(t, 4, len(b.Peak(4)))
 	assert.Equal(t, 4, len(b.Peak(8)))
 }
+
+func TestBucket_SubnetPeers(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	b := newFirstBucket(id.UpperBound, DefaultMaxActivePeers, &fixedPreferer{},
+		comm.NewNaiveDoctor())
+	for i := 0; i < 3; i++ {
+		heap.Push(b, newTestSubnetPeer(rng, "203.0.113", i))
+	}
+	heap.Push(b, newTestSubnetPeer(rng, "198.51.100", 0))
+	heap.Push(b, peer.NewTestPeer(rng, 0)) // loopback
+
+	assert.Equal(t, uint(3), b.SubnetPeers("203.0.113.0/24"))
+	assert.Equal(t, uint(1), b.SubnetPeers("198.51.100.0/24"))
+	assert.Equal(t, uint(0), b.SubnetPeers("192.0.2.0/24"))
+}
+
+// newTestSubnetPeer returns a new peer with a random ID and an address in the given /24 prefix.
+func newTestSubnetPeer(rng *rand.Rand, prefix string, idx int) peer.Peer {
+	address := &net.TCPAddr{IP: net.ParseIP(fmt.Sprintf("%s.%d", prefix, idx+1)), Port: 20100}
+	return peer.New(id.NewPseudoRandom(rng), fmt.Sprintf("test-peer-%d", idx+1), address)
+}
@@ -0,0 +1,208 @@
+package routing
+
+import (
+	"sync"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	sstorage "github.com/drausin/libri/libri/librarian/server/storage"
+	"github.com/golang/protobuf/proto"
+)
+
+// Checkpointer persists the routing table incrementally. A full snapshot of the table is stored
+// via Table.Save, and between snapshots only the peers changed since the last checkpoint are
+// stored as deltas, keyed by peer ID. A delta for a peer no longer in the table is a tombstone:
+// a stored peer with no addresses, which the table never contains.
+type Checkpointer interface {
+	// Checkpoint persists the peers changed since the previous checkpoint, or a full snapshot
+	// if this is the first checkpoint or every Parameters.FullCheckpointEvery checkpoints.
+	Checkpoint() error
+
+	// Snapshot persists a full snapshot of the table and removes any deltas.
+	Snapshot() error
+
+	// Close persists a final full snapshot, after which checkpoints and snapshots are no-ops,
+	// e.g., since the underlying storage is about to be closed.
+	Close() error
+}
+
+type checkpointer struct {
+	rt           *table
+	snapshots    cstorage.Storer
+	deltas       cstorage.StorerLoaderDeleter
+	params       *Parameters
+	nCheckpoints uint
+	closed       bool
+	mu           sync.Mutex
+}
+
+// NewCheckpointer returns a new Checkpointer storing full snapshots of the table via snapshots
+// and deltas via deltas.
+func NewCheckpointer(
+	rt Table, snapshots cstorage.Storer, deltas cstorage.StorerLoaderDeleter, params *Parameters,
+) Checkpointer {
+	return &checkpointer{
+		rt:        rt.(*table),
+		snapshots: snapshots,
+		deltas:    deltas,
+		params:    params,
+	}
+}
+
+func (c *checkpointer) Checkpoint() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	full := c.nCheckpoints == 0 ||
+		(c.params.FullCheckpointEvery > 0 && c.nCheckpoints%c.params.FullCheckpointEvery == 0)
+	c.nCheckpoints++
+	if full {
+		return c.snapshot()
+	}
+	for _, peerID := range c.rt.dirty.pop() {
+		p, in := c.rt.getCheckpointPeer(peerID)
+		sp := &sstorage.Peer{Id: peerID.Bytes()} // tombstone
+		if in {
+			sp = p.ToStored()
+		}
+		value, err := proto.Marshal(sp)
+		if err != nil {
+			return err
+		}
+		if err := c.deltas.Store(peerID.Bytes(), value); err != nil {
+			// leave for the next checkpoint
+			c.rt.dirty.mark(peerID)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *checkpointer) Snapshot() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	return c.snapshot()
+}
+
+func (c *checkpointer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.snapshot()
+}
+
+func (c *checkpointer) snapshot() error {
+	// changes during the snapshot will be captured by the next checkpoint
+	c.rt.dirty.pop()
+	if err := c.rt.Save(c.snapshots); err != nil {
+		return err
+	}
+	keys := make([][]byte, 0)
+	err := iterateDeltas(c.deltas, func(key, value []byte) {
+		keys = append(keys, key)
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := c.deltas.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCheckpoint retrieves the routing table from its latest full snapshot plus any deltas
+// stored since. It returns nil if no snapshot has been stored.
+func LoadCheckpoint(
+	snapshots cstorage.Loader,
+	deltas cstorage.Storer,
+	preferer comm.Preferer,
+	doctor comm.Doctor,
+	params *Parameters,
+) (Table, error) {
+	stored, err := loadStored(snapshots)
+	if stored == nil || err != nil {
+		return nil, err
+	}
+	storedPeers := make(map[string]*sstorage.Peer)
+	for _, sp := range stored.Peers {
+		storedPeers[id.FromBytes(sp.Id).String()] = sp
+	}
+	var unmarshalErr error
+	err = iterateDeltas(deltas, func(key, value []byte) {
+		sp := &sstorage.Peer{}
+		if err := proto.Unmarshal(value, sp); err != nil {
+			unmarshalErr = err
+			return
+		}
+		idStr := id.FromBytes(key).String()
+		if sp.PublicAddress == nil && len(sp.Addresses) == 0 {
+			delete(storedPeers, idStr)
+			return
+		}
+		storedPeers[idStr] = sp
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	stored.Peers = make([]*sstorage.Peer, 0, len(storedPeers))
+	for _, sp := range storedPeers {
+		stored.Peers = append(stored.Peers, sp)
+	}
+	return fromStored(stored, params, preferer, doctor), nil
+}
+
+func iterateDeltas(deltas cstorage.Storer, callback func(key, value []byte)) error {
+	lb, ub := id.LowerBound.Bytes(), id.UpperBound.Bytes()
+	return deltas.Iterate(lb, ub, make(chan struct{}), callback)
+}
+
+// getCheckpointPeer returns the peer with the given ID if it's in a bucket or the sibling list.
+func (rt *table) getCheckpointPeer(peerID id.ID) (peer.Peer, bool) {
+	if p, in := rt.Get(peerID); in {
+		return p, true
+	}
+	return rt.siblings.get(peerID)
+}
+
+// dirtyPeers tracks the IDs of peers changed since the last checkpoint.
+type dirtyPeers struct {
+	ids map[string]id.ID
+	mu  sync.Mutex
+}
+
+func newDirtyPeers() *dirtyPeers {
+	return &dirtyPeers{ids: make(map[string]id.ID)}
+}
+
+func (d *dirtyPeers) mark(peerID id.ID) {
+	d.mu.Lock()
+	d.ids[peerID.String()] = peerID
+	d.mu.Unlock()
+}
+
+// pop returns the dirty peer IDs and resets them.
+func (d *dirtyPeers) pop() []id.ID {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]id.ID, 0, len(d.ids))
+	for _, peerID := range d.ids {
+		ids = append(ids, peerID)
+	}
+	d.ids = make(map[string]id.ID)
+	return ids
+}
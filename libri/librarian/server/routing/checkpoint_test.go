@@ -0,0 +1,119 @@
+package routing
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/db"
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointer_CheckpointLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	snapshots, deltas := cstorage.NewServerSL(kvdb), cstorage.NewRoutingSLD(kvdb)
+	params := NewDefaultParameters()
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	// few enough peers that they all fit in the first bucket, so every push adds its peer
+	// regardless of the order they're loaded in
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	c := NewCheckpointer(rt, snapshots, deltas, params)
+
+	// first checkpoint is a full snapshot
+	assert.Nil(t, c.Checkpoint())
+	assert.Equal(t, 0, countDeltas(t, deltas))
+
+	// subsequent checkpoints store only changed peers
+	evicted := rt.Sample(1, rng)[0]
+	assert.True(t, rt.Evict(evicted.ID()))
+	added := peer.NewTestPeer(rng, 8)
+	assert.Equal(t, Added, rt.Push(added))
+	assert.Nil(t, c.Checkpoint())
+	assert.Equal(t, 2, countDeltas(t, deltas))
+
+	// nothing changed since the last checkpoint
+	assert.Nil(t, c.Checkpoint())
+	assert.Equal(t, 2, countDeltas(t, deltas))
+
+	rt2, err := LoadCheckpoint(snapshots, deltas, p, d, params)
+	assert.Nil(t, err)
+	assert.Equal(t, rt.NumPeers(), rt2.NumPeers())
+	_, in := rt2.Get(evicted.ID())
+	assert.False(t, in)
+	_, in = rt2.Get(added.ID())
+	assert.True(t, in)
+
+	// snapshot removes deltas
+	assert.Nil(t, c.Snapshot())
+	assert.Equal(t, 0, countDeltas(t, deltas))
+	rt3, err := LoadCheckpoint(snapshots, deltas, p, d, params)
+	assert.Nil(t, err)
+	assert.Equal(t, rt.NumPeers(), rt3.NumPeers())
+}
+
+func TestCheckpointer_Checkpoint_fullEvery(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	snapshots, deltas := cstorage.NewServerSL(kvdb), cstorage.NewRoutingSLD(kvdb)
+	params := NewDefaultParameters()
+	params.FullCheckpointEvery = 3
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	c := NewCheckpointer(rt, snapshots, deltas, params)
+
+	nDeltas := []int{0, 1, 2, 0, 1}
+	for i, expected := range nDeltas {
+		assert.Equal(t, Added, rt.Push(peer.NewTestPeer(rng, 100+i)))
+		assert.Nil(t, c.Checkpoint())
+		assert.Equal(t, expected, countDeltas(t, deltas), "checkpoint %d", i)
+	}
+}
+
+func TestCheckpointer_Close(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	snapshots, deltas := cstorage.NewServerSL(kvdb), cstorage.NewRoutingSLD(kvdb)
+	params := NewDefaultParameters()
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	c := NewCheckpointer(rt, snapshots, deltas, params)
+	assert.Nil(t, c.Checkpoint())
+
+	assert.Equal(t, Added, rt.Push(peer.NewTestPeer(rng, 8)))
+	assert.Nil(t, c.Close())
+	assert.Equal(t, 0, countDeltas(t, deltas))
+
+	// checkpoints after close are no-ops
+	assert.Equal(t, Added, rt.Push(peer.NewTestPeer(rng, 9)))
+	assert.Nil(t, c.Checkpoint())
+	assert.Equal(t, 0, countDeltas(t, deltas))
+	assert.Equal(t, 1, len(rt.(*table).dirty.pop()))
+}
+
+func TestLoadCheckpoint_none(t *testing.T) {
+	kvdb := db.NewMemoryDB()
+	snapshots, deltas := cstorage.NewServerSL(kvdb), cstorage.NewRoutingSLD(kvdb)
+	rt, err := LoadCheckpoint(snapshots, deltas, &fixedPreferer{}, &fixedDoctor{},
+		NewDefaultParameters())
+	assert.Nil(t, err)
+	assert.Nil(t, rt)
+}
+
+func TestDirtyPeers_markPop(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	d := newDirtyPeers()
+	id1, id2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	d.mark(id1)
+	d.mark(id2)
+	d.mark(id1)
+	assert.Len(t, d.pop(), 2)
+	assert.Len(t, d.pop(), 0)
+}
+
+func countDeltas(t *testing.T, deltas cstorage.Storer) int {
+	n := 0
+	err := iterateDeltas(deltas, func(key, value []byte) { n++ })
+	assert.Nil(t, err)
+	return n
+}
@@ -0,0 +1,62 @@
+package routing
+
+import (
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+)
+
+const (
+	// peerEntryBytes is the size of an entry in a bucket's peers slice, i.e., an interface value.
+	peerEntryBytes = 16
+
+	// positionEntryBytes roughly estimates the size of an entry in a bucket's positions map: the
+	// string header and its hex ID bytes, the int position, and per-entry map overhead.
+	positionEntryBytes = 16 + 2*id.Length + 8 + 8
+
+	// minCompactSlack is the number of unused entries a bucket's peers slice or positions map
+	// may have beyond twice the number of its peers before it needs compaction, which keeps
+	// small buckets from being needlessly rebuilt.
+	minCompactSlack = 4
+)
+
+// Compact rebuilds the peers slice and positions map of each bucket left oversized by churn,
+// returning the number of buckets compacted. This method is concurrency-safe.
+func (rt *table) Compact() int {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	nCompacted := 0
+	for _, b := range rt.buckets {
+		b.mu.Lock()
+		if b.needsCompaction() {
+			b.compact()
+			nCompacted++
+		}
+		b.mu.Unlock()
+	}
+	return nCompacted
+}
+
+// needsCompaction returns whether the bucket's peers slice or positions map has (or has had,
+// since Go maps don't shrink as entries are deleted) more than twice the capacity its current
+// peers need.
+func (b *bucket) needsCompaction() bool {
+	maxLen := 2*len(b.activePeers) + minCompactSlack
+	return cap(b.activePeers) > maxLen || b.peakPositions > maxLen
+}
+
+// compact rebuilds the bucket's peers slice and positions map at their current size.
+func (b *bucket) compact() {
+	activePeers := make([]peer.Peer, len(b.activePeers))
+	copy(activePeers, b.activePeers)
+	positions := make(map[string]int, len(b.positions))
+	for k, v := range b.positions {
+		positions[k] = v
+	}
+	b.activePeers, b.positions, b.peakPositions = activePeers, positions, len(positions)
+}
+
+// memoryEstimate roughly estimates the bytes used by the bucket's peers slice and positions map,
+// excluding the peers themselves.
+func (b *bucket) memoryEstimate() int {
+	return cap(b.activePeers)*peerEntryBytes + b.peakPositions*positionEntryBytes
+}
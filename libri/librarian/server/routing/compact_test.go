@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"container/heap"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket_compact(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	b := newFirstBucket(id.UpperBound, 128, comm.NewReputationPreferer(newTestReputationJudge()),
+		comm.NewNaiveDoctor())
+	assert.False(t, b.needsCompaction())
+
+	// simulate heavy churn leaving only a few peers
+	for _, p := range peer.NewTestPeers(rng, 64) {
+		heap.Push(b, p)
+	}
+	for b.Len() > 4 {
+		heap.Pop(b)
+	}
+	assert.Equal(t, 64, b.peakPositions)
+	assert.True(t, b.needsCompaction())
+	before := b.memoryEstimate()
+	remaining := append([]peer.Peer{}, b.activePeers...)
+
+	b.compact()
+	assert.False(t, b.needsCompaction())
+	assert.True(t, b.memoryEstimate() < before)
+	assert.Equal(t, 4, cap(b.activePeers))
+	assert.Equal(t, 4, b.peakPositions)
+	assert.Equal(t, remaining, b.activePeers)
+	for i, p := range b.activePeers {
+		assert.Equal(t, i, b.positions[p.ID().String()])
+	}
+
+	// heap still works after compaction
+	heap.Push(b, peer.NewTestPeer(rng, 64))
+	assert.Equal(t, 5, b.Len())
+	assert.Equal(t, 5, b.peakPositions)
+}
+
+func TestTable_Compact(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 128)
+
+	// splits may already have left some buckets oversized
+	rt.Compact()
+	assert.Zero(t, rt.Compact())
+
+	// simulate churn in the first bucket
+	b := rt.(*table).buckets[0]
+	b.peakPositions = 4*b.Len() + minCompactSlack + 1
+	nPeers := rt.NumPeers()
+
+	assert.Equal(t, 1, rt.Compact())
+	assert.Equal(t, nPeers, rt.NumPeers())
+	assert.Zero(t, rt.Compact())
+}
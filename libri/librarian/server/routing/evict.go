@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"sync"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+)
+
+// evictingRecorder is a comm.QueryRecorder that tracks the number of consecutive failed responses
+// from each peer and evicts a peer from the routing table once that number reaches the
+// MaxConsecutiveFailures parameter. Since the table keeps no replacement cache, the evicted
+// peer's slot is simply left open for the next pushed peer.
+type evictingRecorder struct {
+	inner  comm.QueryRecorder
+	rt     Table
+	params *Parameters
+
+	// number of consecutive failed responses, keyed by peer ID string
+	failures map[string]uint
+	mu       sync.Mutex
+}
+
+// NewEvictingRecorder returns a comm.QueryRecorder that records outcomes with the inner recorder
+// and evicts peers from the routing table after too many consecutive failed responses.
+func NewEvictingRecorder(
+	inner comm.QueryRecorder, rt Table, params *Parameters,
+) comm.QueryRecorder {
+	return &evictingRecorder{
+		inner:    inner,
+		rt:       rt,
+		params:   params,
+		failures: make(map[string]uint),
+	}
+}
+
+func (r *evictingRecorder) Record(peerID id.ID, endpoint api.Endpoint, qt comm.QueryType,
+	o comm.Outcome) {
+	r.inner.Record(peerID, endpoint, qt, o)
+	if qt != comm.Response || r.params.MaxConsecutiveFailures == 0 {
+		// only responses tell us whether the peer is failing
+		return
+	}
+	if r.maybeIncrementFailures(peerID, o) {
+		r.rt.Evict(peerID)
+	}
+}
+
+// maybeIncrementFailures updates the consecutive failures for the peer given the outcome and
+// returns whether the peer should be evicted.
+func (r *evictingRecorder) maybeIncrementFailures(peerID id.ID, o comm.Outcome) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idStr := peerID.String()
+	if o == comm.Success {
+		delete(r.failures, idStr)
+		return false
+	}
+	r.failures[idStr]++
+	if r.failures[idStr] < r.params.MaxConsecutiveFailures {
+		return false
+	}
+	delete(r.failures, idStr)
+	return true
+}
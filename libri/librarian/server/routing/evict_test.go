@@ -0,0 +1,56 @@
+package routing
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvictingRecorder_Record(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 128)
+	params := &Parameters{MaxConsecutiveFailures: 3}
+	inner := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	r := NewEvictingRecorder(inner, rt, params)
+	p1 := rt.Sample(1, rng)[0]
+
+	// requests and intermittent errors don't evict
+	r.Record(p1.ID(), api.Find, comm.Request, comm.Error)
+	r.Record(p1.ID(), api.Find, comm.Response, comm.Error)
+	r.Record(p1.ID(), api.Find, comm.Response, comm.Error)
+	r.Record(p1.ID(), api.Find, comm.Response, comm.Success)
+	r.Record(p1.ID(), api.Find, comm.Response, comm.Error)
+	r.Record(p1.ID(), api.Verify, comm.Response, comm.Error)
+	_, exists := rt.Get(p1.ID())
+	assert.True(t, exists)
+
+	// third consecutive error evicts
+	r.Record(p1.ID(), api.Find, comm.Response, comm.Error)
+	_, exists = rt.Get(p1.ID())
+	assert.False(t, exists)
+
+	// inner recorder still gets all outcomes, per endpoint
+	qo := inner.Get(p1.ID(), api.Find)
+	assert.Equal(t, uint64(4), qo[comm.Response][comm.Error].Count)
+	assert.Equal(t, uint64(1), qo[comm.Request][comm.Error].Count)
+	qo = inner.Get(p1.ID(), api.Verify)
+	assert.Equal(t, uint64(1), qo[comm.Response][comm.Error].Count)
+}
+
+func TestEvictingRecorder_Record_disabled(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 128)
+	params := &Parameters{MaxConsecutiveFailures: 0}
+	inner := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	r := NewEvictingRecorder(inner, rt, params)
+	p1 := rt.Sample(1, rng)[0]
+
+	for c := 0; c < 10; c++ {
+		r.Record(p1.ID(), api.Find, comm.Response, comm.Error)
+	}
+	_, exists := rt.Get(p1.ID())
+	assert.True(t, exists)
+}
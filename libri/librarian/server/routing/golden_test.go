@@ -0,0 +1,39 @@
+package routing
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// testdata/routing_table.golden was serialized in the original storage.RoutingTable format, whose
+// peers have only an ID, name, and public address, and stands in for peers' on-disk state. It must
+// never be regenerated: if this test fails, the change to the persistence format is breaking and
+// needs a migration instead.
+
+func TestGolden_Load(t *testing.T) {
+	buf, err := ioutil.ReadFile(filepath.Join("testdata", "routing_table.golden"))
+	assert.Nil(t, err)
+
+	rt, err := Load(&cstorage.TestSLD{Bytes: buf}, &fixedPreferer{}, &fixedDoctor{healthy: true},
+		NewDefaultParameters())
+	assert.Nil(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{2}, 32), rt.SelfID().Bytes())
+	assert.Equal(t, 2, rt.NumPeers())
+
+	expected := map[string]string{
+		id.FromBytes(bytes.Repeat([]byte{1}, 32)).String(): "192.168.1.1:20100",
+		id.FromBytes(bytes.Repeat([]byte{3}, 32)).String(): "192.168.1.2:20101",
+	}
+	for idStr, address := range expected {
+		p, in := rt.(*table).peers[idStr]
+		if assert.True(t, in) {
+			assert.Equal(t, address, p.Address().String())
+		}
+	}
+}
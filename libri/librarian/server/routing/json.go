@@ -0,0 +1,228 @@
+package routing
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+)
+
+// Snapshot is a portable JSON representation of a routing table, independent of the storage
+// backend used by Save and Load.
+type Snapshot struct {
+
+	// SelfID is the hex ID of the table's peer
+	SelfID string `json:"self_id"`
+
+	// Buckets are the table's buckets, ordered by ID range
+	Buckets []*BucketSnapshot `json:"buckets"`
+}
+
+// BucketSnapshot is a portable JSON representation of a routing table bucket.
+type BucketSnapshot struct {
+
+	// Depth is the bit depth of the bucket in the routing tree
+	Depth uint `json:"depth"`
+
+	// LowerBound is the (inclusive) hex lower bound of IDs in the bucket
+	LowerBound string `json:"lower_bound"`
+
+	// UpperBound is the (exclusive) hex upper bound of IDs in the bucket
+	UpperBound string `json:"upper_bound"`
+
+	// ContainsSelf is whether the bucket contains the table's self ID
+	ContainsSelf bool `json:"contains_self"`
+
+	// Peers are the peers in the bucket
+	Peers []*PeerSnapshot `json:"peers"`
+}
+
+// PeerSnapshot is a portable JSON representation of a peer in the routing table.
+type PeerSnapshot struct {
+
+	// ID is the hex ID of the peer
+	ID string `json:"id"`
+
+	// Name is the self-reported name of the peer
+	Name string `json:"name"`
+
+	// Address is the public host:port address of the peer
+	Address string `json:"address"`
+
+	// Responses contains the peer's response stats across all endpoints, if known
+	Responses *ResponseStats `json:"responses,omitempty"`
+}
+
+// ResponseStats contains the response stats for a peer.
+type ResponseStats struct {
+
+	// NSuccesses is the number of successful responses from the peer
+	NSuccesses uint64 `json:"n_successes"`
+
+	// NErrors is the number of errored responses from the peer
+	NErrors uint64 `json:"n_errors"`
+
+	// LatestSuccess is the time of the most recent successful response
+	LatestSuccess time.Time `json:"latest_success"`
+
+	// LatestError is the time of the most recent errored response
+	LatestError time.Time `json:"latest_error"`
+
+	// NIntegrityFailures is the number of responses showing the peer serving corrupted data
+	NIntegrityFailures uint64 `json:"n_integrity_failures"`
+}
+
+// ExportJSON writes a JSON snapshot of the routing table to w. If qg is not nil, each peer's
+// response stats are included. This method is concurrency-safe.
+func (rt *table) ExportJSON(w io.Writer, qg comm.QueryGetter) error {
+	return json.NewEncoder(w).Encode(rt.Snapshot(qg))
+}
+
+// ImportJSON reads a JSON snapshot of a routing table from r and pushes its peers into the
+// table, returning the number of peers added. The snapshot may come from a table with a
+// different self ID, since bucket boundaries are determined by this table's self ID rather than
+// the snapshot's. Response stats are informational only and are not imported. This method is
+// concurrency-safe.
+func (rt *table) ImportJSON(r io.Reader) (int, error) {
+	s := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return 0, err
+	}
+	peers := make([]peer.Peer, 0)
+	for _, b := range s.Buckets {
+		for _, ps := range b.Peers {
+			p, err := ps.toPeer()
+			if err != nil {
+				return 0, err
+			}
+			peers = append(peers, p)
+		}
+	}
+	pushed := make([]peer.Peer, 0, len(peers))
+	for _, p := range peers {
+		if status := rt.Push(p); status == Added || status == Replaced {
+			pushed = append(pushed, p)
+		}
+	}
+
+	// later pushes may replace earlier ones, so only count those still in the table
+	nAdded := 0
+	for _, p := range pushed {
+		if _, in := rt.Get(p.ID()); in {
+			nAdded++
+		}
+	}
+	return nAdded, nil
+}
+
+// Snapshot returns a snapshot of the routing table. If qg is not nil, each peer's response stats
+// are included. This method is concurrency-safe.
+func (rt *table) Snapshot(qg comm.QueryGetter) *Snapshot {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	s := &Snapshot{
+		SelfID:  rt.selfID.String(),
+		Buckets: make([]*BucketSnapshot, len(rt.buckets)),
+	}
+	for i, b := range rt.buckets {
+		b.mu.Lock()
+		bs := &BucketSnapshot{
+			Depth:        b.depth,
+			LowerBound:   b.lowerBound.String(),
+			UpperBound:   b.upperBound.String(),
+			ContainsSelf: b.containsSelf,
+			Peers:        make([]*PeerSnapshot, len(b.activePeers)),
+		}
+		for j, p := range b.activePeers {
+			bs.Peers[j] = newPeerSnapshot(p, qg)
+		}
+		b.mu.Unlock()
+		s.Buckets[i] = bs
+	}
+	return s
+}
+
+func newPeerSnapshot(p peer.Peer, qg comm.QueryGetter) *PeerSnapshot {
+	apiP := p.ToAPI()
+	ps := &PeerSnapshot{
+		ID:      p.ID().String(),
+		Name:    apiP.PeerName,
+		Address: p.Address().String(),
+	}
+	if qg != nil {
+		rps := qg.Get(p.ID(), api.All)[comm.Response]
+		ps.Responses = &ResponseStats{
+			NSuccesses:    rps[comm.Success].Count,
+			NErrors:       rps[comm.Error].Count,
+			LatestSuccess: rps[comm.Success].Latest,
+			LatestError:   rps[comm.Error].Latest,
+
+			NIntegrityFailures: rps[comm.IntegrityFailure].Count,
+		}
+	}
+	return ps
+}
+
+// ToAPI converts the bucket snapshot into its API representation.
+func (bs *BucketSnapshot) ToAPI() (*api.RoutingBucket, error) {
+	lower, err := id.FromString(bs.LowerBound)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := id.FromString(bs.UpperBound)
+	if err != nil {
+		return nil, err
+	}
+	b := &api.RoutingBucket{
+		Depth:        uint32(bs.Depth),
+		LowerBound:   lower.Bytes(),
+		UpperBound:   upper.Bytes(),
+		ContainsSelf: bs.ContainsSelf,
+		Peers:        make([]*api.RoutingPeer, len(bs.Peers)),
+	}
+	for i, ps := range bs.Peers {
+		p, err := ps.toPeer()
+		if err != nil {
+			return nil, err
+		}
+		b.Peers[i] = &api.RoutingPeer{Address: p.ToAPI()}
+		if ps.Responses != nil {
+			b.Peers[i].NResponseSuccesses = ps.Responses.NSuccesses
+			b.Peers[i].NResponseErrors = ps.Responses.NErrors
+			b.Peers[i].LatestSuccessNanos = toUnixNanos(ps.Responses.LatestSuccess)
+			b.Peers[i].LatestErrorNanos = toUnixNanos(ps.Responses.LatestError)
+		}
+	}
+	return b, nil
+}
+
+// toUnixNanos returns the nanoseconds since the Unix epoch of t or zero if t is unset.
+func toUnixNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func (ps *PeerSnapshot) toPeer() (peer.Peer, error) {
+	peerID, err := id.FromString(ps.ID)
+	if err != nil {
+		return nil, err
+	}
+	host, portStr, err := net.SplitHostPort(ps.Address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+	addr := &net.TCPAddr{IP: net.ParseIP(host), Port: port}
+	return peer.New(peerID, ps.Name, addr), nil
+}
@@ -0,0 +1,149 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTable_ExportImportJSON(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt1, _, nAdded, _ := NewTestWithPeers(rng, 128)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	p1 := rt1.Sample(1, rng)[0]
+	rec.Record(p1.ID(), api.Find, comm.Response, comm.Success)
+	rec.Record(p1.ID(), api.Find, comm.Response, comm.Error)
+	rec.Record(p1.ID(), api.Verify, comm.Response, comm.IntegrityFailure)
+
+	buf := new(bytes.Buffer)
+	err := rt1.ExportJSON(buf, rec)
+	assert.Nil(t, err)
+
+	// check snapshot format
+	s := &Snapshot{}
+	err = json.Unmarshal(buf.Bytes(), s)
+	assert.Nil(t, err)
+	assert.Equal(t, rt1.SelfID().String(), s.SelfID)
+	assert.Equal(t, rt1.NumBuckets(), len(s.Buckets))
+	nPeers := 0
+	for i, bs := range s.Buckets {
+		b := rt1.(*table).buckets[i]
+		assert.Equal(t, b.depth, bs.Depth)
+		assert.Equal(t, b.lowerBound.String(), bs.LowerBound)
+		assert.Equal(t, b.upperBound.String(), bs.UpperBound)
+		assert.Equal(t, b.containsSelf, bs.ContainsSelf)
+		for _, ps := range bs.Peers {
+			assert.NotNil(t, ps.Responses)
+			if ps.ID == p1.ID().String() {
+				assert.Equal(t, uint64(1), ps.Responses.NSuccesses)
+				assert.Equal(t, uint64(1), ps.Responses.NErrors)
+				assert.Equal(t, uint64(1), ps.Responses.NIntegrityFailures)
+			}
+		}
+		nPeers += len(bs.Peers)
+	}
+	assert.Equal(t, nAdded, nPeers)
+
+	// import into an empty table with the same self ID
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	rt2 := NewEmpty(rt1.SelfID(), p, d, NewDefaultParameters())
+	nImported, err := rt2.ImportJSON(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, nAdded, nImported)
+	assert.Equal(t, rt1.NumPeers(), rt2.NumPeers())
+	for peerIDStr, p1 := range rt1.(*table).peers {
+		p2, in := rt2.(*table).peers[peerIDStr]
+		assert.True(t, in)
+		assert.Equal(t, p1.ToAPI(), p2.ToAPI())
+	}
+
+	// import into a table with a different self ID
+	rt3 := NewEmpty(ecid.NewPseudoRandom(rng).ID(), p, d, NewDefaultParameters())
+	nImported, err = rt3.ImportJSON(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.True(t, nImported > 0)
+	assert.Equal(t, nImported, rt3.NumPeers())
+}
+
+func TestTable_ExportJSON_noStats(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	buf := new(bytes.Buffer)
+	err := rt.ExportJSON(buf, nil)
+	assert.Nil(t, err)
+
+	s := &Snapshot{}
+	err = json.Unmarshal(buf.Bytes(), s)
+	assert.Nil(t, err)
+	for _, bs := range s.Buckets {
+		for _, ps := range bs.Peers {
+			assert.Nil(t, ps.Responses)
+		}
+	}
+}
+
+func TestTable_ImportJSON_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 0)
+	p := peer.NewTestPeer(rng, 0)
+	cases := []string{
+		"not JSON",
+		`{"buckets": [{"peers": [{"id": "bad ID", "address": "127.0.0.1:20100"}]}]}`,
+		`{"buckets": [{"peers": [{"id": "` + p.ID().String() + `", "address": "bad"}]}]}`,
+		`{"buckets": [{"peers": [{"id": "` + p.ID().String() + `", "address": "host:port"}]}]}`,
+	}
+	for _, c := range cases {
+		nImported, err := rt.ImportJSON(bytes.NewBufferString(c))
+		assert.NotNil(t, err, c)
+		assert.Zero(t, nImported)
+		assert.Zero(t, rt.NumPeers())
+	}
+}
+
+func TestBucketSnapshot_ToAPI(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 64)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	p1 := rt.Sample(1, rng)[0]
+	rec.Record(p1.ID(), api.Find, comm.Response, comm.Success)
+
+	s := rt.Snapshot(rec)
+	for i, bs := range s.Buckets {
+		b := rt.(*table).buckets[i]
+		apiB, err := bs.ToAPI()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(b.depth), apiB.Depth)
+		assert.Equal(t, b.lowerBound.Bytes(), apiB.LowerBound)
+		assert.Equal(t, b.upperBound.Bytes(), apiB.UpperBound)
+		assert.Equal(t, b.containsSelf, apiB.ContainsSelf)
+		assert.Equal(t, len(b.activePeers), len(apiB.Peers))
+		for _, rp := range apiB.Peers {
+			p, in := rt.Get(id.FromBytes(rp.Address.PeerId))
+			assert.True(t, in)
+			assert.Equal(t, p.ToAPI(), rp.Address)
+			if p.ID().Cmp(p1.ID()) == 0 {
+				assert.Equal(t, uint64(1), rp.NResponseSuccesses)
+				assert.NotZero(t, rp.LatestSuccessNanos)
+			} else {
+				assert.Zero(t, rp.NResponseSuccesses)
+				assert.Zero(t, rp.LatestSuccessNanos)
+			}
+			assert.Zero(t, rp.NResponseErrors)
+			assert.Zero(t, rp.LatestErrorNanos)
+		}
+	}
+
+	// bad bounds
+	bs := &BucketSnapshot{LowerBound: "not hex"}
+	apiB, err := bs.ToAPI()
+	assert.NotNil(t, err)
+	assert.Nil(t, apiB)
+}
@@ -0,0 +1,61 @@
+package routing
+
+// KeyspaceEstimate is an estimate of the size of the network and of self's share of the keyspace.
+type KeyspaceEstimate struct {
+
+	// NetworkSize is the estimated number of peers in the network, including self
+	NetworkSize float64
+
+	// SelfShare is the estimated fraction of the keyspace closer to self than to any other peer
+	SelfShare float64
+
+	// NPeers is the number of peers the estimate is based on
+	NPeers int
+
+	// IDMass is the fraction of the keyspace the estimate is based on
+	IDMass float64
+}
+
+// ReplicaShare returns the estimated fraction of all documents self holds a replica of when each
+// document is stored by the nReplicas peers closest to its key.
+func (e *KeyspaceEstimate) ReplicaShare(nReplicas uint) float64 {
+	share := float64(nReplicas) * e.SelfShare
+	if share > 1.0 {
+		return 1.0
+	}
+	return share
+}
+
+// EstimateKeyspace estimates the network size from the density of peers in the buckets that
+// haven't turned any away, i.e., those with a vacancy and the bucket containing self (which
+// splits rather than turning peers away until it reaches the max depth). These buckets should
+// know of (nearly) every peer in their ranges, which is usually true of the deep buckets near
+// self. Full buckets only bound the density from below, so when every bucket has turned peers
+// away, the estimate is just the number of known peers. This method is concurrency-safe.
+func (rt *table) EstimateKeyspace() *KeyspaceEstimate {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	completePeers, completeMass, allPeers := 1, 0.0, 1 // 1 for self
+	for _, b := range rt.buckets {
+		b.mu.Lock()
+		n, vacancy := b.Len(), b.Vacancy()
+		b.mu.Unlock()
+		allPeers += n
+		if vacancy || (b.containsSelf && !rt.params.atMaxDepth(b.depth)) {
+			completePeers += n
+			completeMass += b.idMass
+		}
+	}
+	e := &KeyspaceEstimate{NPeers: completePeers, IDMass: completeMass}
+	if completeMass == 0.0 {
+		// every bucket may have turned peers away, so best we can do is the number we know of
+		e.NPeers, e.IDMass = allPeers, 1.0
+	}
+	e.NetworkSize = float64(e.NPeers) / e.IDMass
+	if e.NetworkSize < float64(allPeers) {
+		// can't be fewer peers than we already know of
+		e.NetworkSize = float64(allPeers)
+	}
+	e.SelfShare = 1.0 / e.NetworkSize
+	return e
+}
@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyspaceEstimate_ReplicaShare(t *testing.T) {
+	e := &KeyspaceEstimate{NetworkSize: 100, SelfShare: 0.01}
+	assert.InDelta(t, 0.03, e.ReplicaShare(3), 1e-9)
+
+	e = &KeyspaceEstimate{NetworkSize: 2, SelfShare: 0.5}
+	assert.Equal(t, 1.0, e.ReplicaShare(3))
+}
+
+func TestTable_EstimateKeyspace_empty(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	rt := NewEmpty(id.NewPseudoRandom(rng), p, d, NewDefaultParameters())
+
+	// just self
+	e := rt.EstimateKeyspace()
+	assert.Equal(t, 1.0, e.NetworkSize)
+	assert.Equal(t, 1.0, e.SelfShare)
+	assert.Equal(t, 1, e.NPeers)
+	assert.Equal(t, 1.0, e.IDMass)
+}
+
+func TestTable_EstimateKeyspace(t *testing.T) {
+	for s := 0; s < 4; s++ {
+		rng := rand.New(rand.NewSource(int64(s)))
+		for _, nNetwork := range []int{64, 512, 4096} {
+			p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+			rt := NewEmpty(id.NewPseudoRandom(rng), p, d, NewDefaultParameters())
+
+			// push all peers in the network, as if learned of over time
+			for _, p := range peer.NewTestPeers(rng, nNetwork) {
+				rt.Push(p)
+			}
+			e := rt.EstimateKeyspace()
+			info := map[string]interface{}{"seed": s, "n_network": nNetwork}
+			assert.True(t, e.NetworkSize > float64(nNetwork)/3, info)
+			assert.True(t, e.NetworkSize < float64(nNetwork)*3, info)
+			assert.True(t, e.NetworkSize >= float64(rt.NumPeers()+1), info)
+			assert.InDelta(t, 1.0/e.NetworkSize, e.SelfShare, 1e-12, info)
+			assert.True(t, e.IDMass > 0.0 && e.IDMass <= 1.0, info)
+		}
+	}
+}
+
+func TestTable_EstimateKeyspace_full(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.MaxBucketPeers = 2
+	params.MaxDepth = 1
+	params.MaxDepthBucketPeers = 2
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	rt := NewEmpty(id.NewPseudoRandom(rng), p, d, params)
+	for _, p := range peer.NewTestPeers(rng, 64) {
+		rt.Push(p)
+	}
+	for _, b := range rt.(*table).buckets {
+		assert.False(t, b.Vacancy())
+	}
+
+	// with every bucket full (and at the max depth), the estimate falls back to the number of
+	// known peers
+	e := rt.EstimateKeyspace()
+	assert.Equal(t, float64(rt.NumPeers()+1), e.NetworkSize)
+	assert.Equal(t, 1.0, e.IDMass)
+}
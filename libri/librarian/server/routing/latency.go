@@ -0,0 +1,45 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+)
+
+// NewLatencyTracker returns a comm.LatencyTracker that keeps the moving average latencies of the
+// peers in the routing table on their peer records, so they're saved with the table and survive
+// restarts. A peer's first recorded latency is smoothed from its stored one, if any.
+func NewLatencyTracker(inner comm.LatencyTracker, rt Table) comm.LatencyTracker {
+	return &latencyTracker{inner: inner, rt: rt}
+}
+
+type latencyTracker struct {
+	inner comm.LatencyTracker
+	rt    Table
+}
+
+func (t *latencyTracker) RecordLatency(peerID id.ID, latency time.Duration) {
+	p, inTable := t.rt.Get(peerID)
+	if _, tracked := t.inner.Latency(peerID); !tracked && inTable {
+		if stored, ok := p.Latency(); ok {
+			t.inner.RecordLatency(peerID, stored)
+		}
+	}
+	t.inner.RecordLatency(peerID, latency)
+	if inTable {
+		if smoothed, ok := t.inner.Latency(peerID); ok {
+			p.RecordLatency(smoothed)
+		}
+	}
+}
+
+func (t *latencyTracker) Latency(peerID id.ID) (time.Duration, bool) {
+	if latency, ok := t.inner.Latency(peerID); ok {
+		return latency, true
+	}
+	if p, in := t.rt.Get(peerID); in {
+		return p.Latency()
+	}
+	return 0, false
+}
@@ -0,0 +1,47 @@
+package routing
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyTracker_RecordLatency_Latency(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	preferer := comm.NewReputationPreferer(newTestReputationJudge())
+	rt := NewEmpty(id.NewPseudoRandom(rng), preferer, comm.NewNaiveDoctor(),
+		NewDefaultParameters())
+	p1, p2, p3 := peer.NewTestPeer(rng, 1), peer.NewTestPeer(rng, 2), peer.NewTestPeer(rng, 3)
+	p1.RecordLatency(100 * time.Millisecond) // e.g., loaded from storage
+	p2.RecordLatency(100 * time.Millisecond)
+	assert.Equal(t, Added, rt.Push(p1))
+	assert.Equal(t, Added, rt.Push(p2))
+	tracker := NewLatencyTracker(comm.NewLatencyTracker(0.5), rt)
+
+	// stored latency used before any recorded
+	latency, ok := tracker.Latency(p2.ID())
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, latency)
+
+	// first recorded latency smoothed from stored one and kept on peer in table
+	tracker.RecordLatency(p1.ID(), 50*time.Millisecond)
+	latency, ok = tracker.Latency(p1.ID())
+	assert.True(t, ok)
+	assert.Equal(t, 75*time.Millisecond, latency)
+	latency, _ = p1.Latency()
+	assert.Equal(t, 75*time.Millisecond, latency)
+	assert.Equal(t, int64(75*time.Millisecond), p1.ToStored().LatencyNanos)
+
+	// peers not in table still tracked
+	_, ok = tracker.Latency(p3.ID())
+	assert.False(t, ok)
+	tracker.RecordLatency(p3.ID(), 50*time.Millisecond)
+	latency, ok = tracker.Latency(p3.ID())
+	assert.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, latency)
+}
@@ -0,0 +1,235 @@
+package routing
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	promNamespace = "libri"
+	promSubsystem = "routing_table"
+
+	bucketLabel = "bucket"
+	depthLabel  = "depth"
+
+	// churnWindow is the window over which peer adds and removals are counted.
+	churnWindow = time.Minute
+)
+
+// Metrics is a snapshot of the health of a routing table.
+type Metrics struct {
+
+	// NPeers is the total number of peers in the table
+	NPeers int
+
+	// NBuckets is the number of buckets in the table
+	NBuckets int
+
+	// BucketPeers is the number of peers in each bucket, ordered by ID range
+	BucketPeers []int
+
+	// BucketFill is the fraction of its capacity each bucket has filled, ordered by ID range
+	BucketFill []float64
+
+	// BucketMemory is the estimated bytes used by each bucket's peer collections (excluding the
+	// peers themselves), ordered by ID range
+	BucketMemory []int
+
+	// DepthCounts is the number of buckets at each depth of the tree
+	DepthCounts map[uint]int
+
+	// NAdded is the total number of peers added to the table
+	NAdded uint64
+
+	// NRemoved is the total number of peers removed from the table, whether by eviction,
+	// replacement, or being unhealthy
+	NRemoved uint64
+
+	// AddsPerMinute is the number of peers added over the last full minute
+	AddsPerMinute uint64
+
+	// RemovalsPerMinute is the number of peers removed over the last full minute
+	RemovalsPerMinute uint64
+
+	// EstimatedNetworkSize is the estimated number of peers in the network
+	EstimatedNetworkSize float64
+}
+
+// Metrics returns a snapshot of the table's health. This method is concurrency-safe.
+func (rt *table) Metrics() *Metrics {
+	rt.mu.RLock()
+	m := &Metrics{
+		NBuckets:     len(rt.buckets),
+		BucketPeers:  make([]int, len(rt.buckets)),
+		BucketFill:   make([]float64, len(rt.buckets)),
+		BucketMemory: make([]int, len(rt.buckets)),
+		DepthCounts:  make(map[uint]int),
+	}
+	for i, b := range rt.buckets {
+		b.mu.Lock()
+		m.BucketPeers[i] = b.Len()
+		m.BucketFill[i] = float64(b.Len()) / float64(b.maxActivePeers)
+		m.BucketMemory[i] = b.memoryEstimate()
+		m.DepthCounts[b.depth]++
+		b.mu.Unlock()
+	}
+	rt.mu.RUnlock()
+	m.NPeers = rt.NumPeers()
+	m.NAdded, m.NRemoved, m.AddsPerMinute, m.RemovalsPerMinute = rt.churn.counts()
+	m.EstimatedNetworkSize = rt.EstimateKeyspace().NetworkSize
+	return m
+}
+
+// churn counts peers added to and removed from the table, both in total and over the last full
+// window.
+type churn struct {
+	nAdded      uint64
+	nRemoved    uint64
+	windowStart time.Time
+	windowAdded uint64
+	windowRemvd uint64
+	prevAdded   uint64
+	prevRemoved uint64
+	window      time.Duration
+	mu          sync.Mutex
+}
+
+func newChurn(window time.Duration) *churn {
+	return &churn{
+		window:      window,
+		windowStart: time.Now(),
+	}
+}
+
+func (c *churn) added() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maybeNextWindow()
+	c.nAdded++
+	c.windowAdded++
+}
+
+func (c *churn) removed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maybeNextWindow()
+	c.nRemoved++
+	c.windowRemvd++
+}
+
+// counts returns the total adds and removals and those in the last full window.
+func (c *churn) counts() (uint64, uint64, uint64, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maybeNextWindow()
+	return c.nAdded, c.nRemoved, c.prevAdded, c.prevRemoved
+}
+
+func (c *churn) maybeNextWindow() {
+	elapsed := time.Since(c.windowStart)
+	if elapsed < c.window {
+		return
+	}
+	if elapsed < 2*c.window {
+		c.prevAdded, c.prevRemoved = c.windowAdded, c.windowRemvd
+	} else {
+		// no events in the last full window
+		c.prevAdded, c.prevRemoved = 0, 0
+	}
+	c.windowAdded, c.windowRemvd = 0, 0
+	c.windowStart = c.windowStart.Add(elapsed / c.window * c.window)
+}
+
+// NewPromCollector returns a Prometheus collector that reports the routing table's Metrics each
+// time it is scraped.
+func NewPromCollector(rt Table) prom.Collector {
+	return &promCollector{
+		rt: rt,
+		nPeers: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "peers"),
+			"Total number of peers in the routing table.",
+			nil, nil,
+		),
+		nBuckets: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "buckets"),
+			"Number of buckets in the routing table.",
+			nil, nil,
+		),
+		bucketFill: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "bucket_fill"),
+			"Fraction of its capacity each routing table bucket has filled.",
+			[]string{bucketLabel}, nil,
+		),
+		bucketMemory: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "bucket_memory_bytes"),
+			"Estimated bytes used by each routing table bucket's peer collections.",
+			[]string{bucketLabel}, nil,
+		),
+		depthBuckets: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "depth_buckets"),
+			"Number of routing table buckets at each depth of the tree.",
+			[]string{depthLabel}, nil,
+		),
+		nAdded: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "peers_added_total"),
+			"Total number of peers added to the routing table.",
+			nil, nil,
+		),
+		nRemoved: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "peers_removed_total"),
+			"Total number of peers removed from the routing table.",
+			nil, nil,
+		),
+		networkSize: prom.NewDesc(
+			prom.BuildFQName(promNamespace, promSubsystem, "estimated_network_size"),
+			"Number of peers in the network estimated from routing table bucket densities.",
+			nil, nil,
+		),
+	}
+}
+
+type promCollector struct {
+	rt           Table
+	nPeers       *prom.Desc
+	nBuckets     *prom.Desc
+	bucketFill   *prom.Desc
+	bucketMemory *prom.Desc
+	depthBuckets *prom.Desc
+	nAdded       *prom.Desc
+	nRemoved     *prom.Desc
+	networkSize  *prom.Desc
+}
+
+func (c *promCollector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.nPeers
+	ch <- c.nBuckets
+	ch <- c.bucketFill
+	ch <- c.bucketMemory
+	ch <- c.depthBuckets
+	ch <- c.nAdded
+	ch <- c.nRemoved
+	ch <- c.networkSize
+}
+
+func (c *promCollector) Collect(ch chan<- prom.Metric) {
+	m := c.rt.Metrics()
+	ch <- prom.MustNewConstMetric(c.nPeers, prom.GaugeValue, float64(m.NPeers))
+	ch <- prom.MustNewConstMetric(c.nBuckets, prom.GaugeValue, float64(m.NBuckets))
+	for i, fill := range m.BucketFill {
+		ch <- prom.MustNewConstMetric(c.bucketFill, prom.GaugeValue, fill, strconv.Itoa(i))
+	}
+	for i, mem := range m.BucketMemory {
+		ch <- prom.MustNewConstMetric(c.bucketMemory, prom.GaugeValue, float64(mem),
+			strconv.Itoa(i))
+	}
+	for depth, n := range m.DepthCounts {
+		ch <- prom.MustNewConstMetric(c.depthBuckets, prom.GaugeValue, float64(n),
+			strconv.Itoa(int(depth)))
+	}
+	ch <- prom.MustNewConstMetric(c.nAdded, prom.CounterValue, float64(m.NAdded))
+	ch <- prom.MustNewConstMetric(c.nRemoved, prom.CounterValue, float64(m.NRemoved))
+	ch <- prom.MustNewConstMetric(c.networkSize, prom.GaugeValue, m.EstimatedNetworkSize)
+}
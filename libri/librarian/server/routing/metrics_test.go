@@ -0,0 +1,109 @@
+package routing
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTable_Metrics(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, n := range []int{0, 8, 128, 1024} {
+		rt, _, _, _ := NewTestWithPeers(rng, n)
+		m := rt.Metrics()
+
+		assert.Equal(t, rt.NumPeers(), m.NPeers)
+		assert.Equal(t, len(rt.(*table).buckets), m.NBuckets)
+		assert.Len(t, m.BucketPeers, m.NBuckets)
+		assert.Len(t, m.BucketFill, m.NBuckets)
+		assert.Len(t, m.BucketMemory, m.NBuckets)
+
+		nBucketPeers, nDepthBuckets := 0, 0
+		for i, nPeers := range m.BucketPeers {
+			nBucketPeers += nPeers
+			assert.True(t, m.BucketFill[i] >= 0 && m.BucketFill[i] <= 1)
+			assert.True(t, m.BucketMemory[i] >= nPeers*(peerEntryBytes+positionEntryBytes))
+		}
+		for _, nBuckets := range m.DepthCounts {
+			nDepthBuckets += nBuckets
+		}
+		assert.Equal(t, m.NPeers, nBucketPeers)
+		assert.Equal(t, m.NBuckets, nDepthBuckets)
+
+		// every peer in the table was added, and every peer added but not in the table was
+		// removed
+		assert.Equal(t, uint64(m.NPeers), m.NAdded-m.NRemoved)
+		assert.True(t, m.EstimatedNetworkSize >= float64(m.NPeers+1))
+	}
+}
+
+func TestTable_Metrics_evict(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	m1 := rt.Metrics()
+
+	ps := rt.Find(rt.SelfID(), 1)
+	assert.True(t, rt.Evict(ps[0].ID()))
+	m2 := rt.Metrics()
+	assert.Equal(t, m1.NPeers-1, m2.NPeers)
+	assert.Equal(t, m1.NAdded, m2.NAdded)
+	assert.Equal(t, m1.NRemoved+1, m2.NRemoved)
+}
+
+func TestChurn(t *testing.T) {
+	window := time.Minute
+	c := newChurn(window)
+	for i := 0; i < 3; i++ {
+		c.added()
+	}
+	c.removed()
+
+	// nothing in previous window yet
+	nAdded, nRemoved, windowAdded, windowRemoved := c.counts()
+	assert.Equal(t, uint64(3), nAdded)
+	assert.Equal(t, uint64(1), nRemoved)
+	assert.Zero(t, windowAdded)
+	assert.Zero(t, windowRemoved)
+
+	// current window becomes previous window
+	c.windowStart = c.windowStart.Add(-window)
+	nAdded, nRemoved, windowAdded, windowRemoved = c.counts()
+	assert.Equal(t, uint64(3), nAdded)
+	assert.Equal(t, uint64(1), nRemoved)
+	assert.Equal(t, uint64(3), windowAdded)
+	assert.Equal(t, uint64(1), windowRemoved)
+
+	// no events in the last full window
+	c.windowStart = c.windowStart.Add(-2 * window)
+	nAdded, nRemoved, windowAdded, windowRemoved = c.counts()
+	assert.Equal(t, uint64(3), nAdded)
+	assert.Equal(t, uint64(1), nRemoved)
+	assert.Zero(t, windowAdded)
+	assert.Zero(t, windowRemoved)
+}
+
+func TestPromCollector(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 128)
+	c := NewPromCollector(rt)
+
+	descs := make(chan *prom.Desc, 16)
+	c.Describe(descs)
+	close(descs)
+	assert.Len(t, descs, 8)
+
+	m := rt.Metrics()
+	metrics := make(chan prom.Metric, 128)
+	c.Collect(metrics)
+	close(metrics)
+	assert.Len(t, metrics, 5+2*m.NBuckets+len(m.DepthCounts))
+
+	registry := prom.NewRegistry()
+	assert.Nil(t, registry.Register(c))
+	mfs, err := registry.Gather()
+	assert.Nil(t, err)
+	assert.Len(t, mfs, 8)
+}
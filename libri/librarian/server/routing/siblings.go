@@ -0,0 +1,136 @@
+package routing
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+)
+
+// siblings is the S/Kademlia sibling list, tracking the peers closest to self independent of the
+// bucket tree. Since buckets far from self may keep peers based on preference rather than
+// distance, the sibling list is what lets a peer reliably decide whether it is among the closest
+// to a given key.
+type siblings struct {
+	// this peer's node ID
+	selfID id.ID
+
+	// maximum number of siblings
+	max uint
+
+	// maximum number of siblings from the same subnet, with zero meaning no limit
+	maxSubnetPeers uint
+
+	// sibling peers, ordered by increasing distance to self
+	peers []peer.Peer
+
+	// distances to self of each sibling peer
+	distances []*big.Int
+
+	mu sync.RWMutex
+}
+
+func newSiblings(selfID id.ID, max, maxSubnetPeers uint) *siblings {
+	return &siblings{
+		selfID:         selfID,
+		max:            max,
+		maxSubnetPeers: maxSubnetPeers,
+		peers:          make([]peer.Peer, 0, max),
+		distances:      make([]*big.Int, 0, max),
+	}
+}
+
+// offer adds the peer to the sibling list if it is closer to self than the farthest sibling or
+// the list isn't yet full, unless the list already has the max number of peers from its subnet.
+func (s *siblings) offer(p peer.Peer) {
+	if s.max == 0 {
+		return
+	}
+	dist := s.selfID.Distance(p.ID())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.search(dist)
+	if i < len(s.peers) && s.distances[i].Cmp(dist) == 0 {
+		// already a sibling
+		return
+	}
+	if i == len(s.peers) && len(s.peers) == int(s.max) {
+		// farther than all existing siblings
+		return
+	}
+	if s.subnetFull(p) {
+		// don't let peers from one subnet monopolize the sibling list
+		return
+	}
+	s.peers = append(s.peers, nil)
+	s.distances = append(s.distances, nil)
+	copy(s.peers[i+1:], s.peers[i:])
+	copy(s.distances[i+1:], s.distances[i:])
+	s.peers[i], s.distances[i] = p, dist
+	if len(s.peers) > int(s.max) {
+		s.peers = s.peers[:s.max]
+		s.distances = s.distances[:s.max]
+	}
+}
+
+// subnetFull returns whether the list already has the max number of peers from the subnet of the
+// peer's preferred address.
+func (s *siblings) subnetFull(p peer.Peer) bool {
+	if s.maxSubnetPeers == 0 {
+		return false
+	}
+	sn := subnet(p.Address())
+	if sn == "" {
+		return false
+	}
+	n := uint(0)
+	for _, sibling := range s.peers {
+		if subnet(sibling.Address()) == sn {
+			n++
+		}
+	}
+	return n >= s.maxSubnetPeers
+}
+
+// get returns the sibling with the given ID (if it's there).
+func (s *siblings) get(peerID id.ID) (peer.Peer, bool) {
+	dist := s.selfID.Distance(peerID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.search(dist)
+	if i == len(s.peers) || s.distances[i].Cmp(dist) != 0 {
+		return nil, false
+	}
+	return s.peers[i], true
+}
+
+// remove removes the peer with the given ID from the sibling list (if it's there).
+func (s *siblings) remove(peerID id.ID) {
+	dist := s.selfID.Distance(peerID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.search(dist)
+	if i == len(s.peers) || s.distances[i].Cmp(dist) != 0 {
+		return
+	}
+	s.peers = append(s.peers[:i], s.peers[i+1:]...)
+	s.distances = append(s.distances[:i], s.distances[i+1:]...)
+}
+
+// list returns a copy of the sibling peers, ordered by increasing distance to self.
+func (s *siblings) list() []peer.Peer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ps := make([]peer.Peer, len(s.peers))
+	copy(ps, s.peers)
+	return ps
+}
+
+// search returns the index of the first sibling at least the given distance from self.
+func (s *siblings) search(dist *big.Int) int {
+	return sort.Search(len(s.distances), func(i int) bool {
+		return s.distances[i].Cmp(dist) >= 0
+	})
+}
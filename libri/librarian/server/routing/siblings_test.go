@@ -0,0 +1,108 @@
+package routing
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSiblings_offer(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	selfID := id.NewPseudoRandom(rng)
+	for _, max := range []uint{1, 4, 32} {
+		s := newSiblings(selfID, max, 0)
+		ps := peer.NewTestPeers(rng, 64)
+		for _, p := range ps {
+			s.offer(p)
+		}
+		// re-offering existing siblings is a no-op
+		for _, p := range s.list() {
+			s.offer(p)
+		}
+
+		siblings := s.list()
+		assert.Equal(t, int(max), len(siblings))
+
+		// siblings should be the closest of all offered peers
+		sorted := make([]peer.Peer, len(ps))
+		copy(sorted, ps)
+		sortByDistance(sorted, selfID)
+		assert.Equal(t, sorted[:max], siblings)
+	}
+}
+
+func TestSiblings_offer_zero(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := newSiblings(id.NewPseudoRandom(rng), 0, 0)
+	s.offer(peer.NewTestPeer(rng, 0))
+	assert.Len(t, s.list(), 0)
+}
+
+func TestSiblings_offer_subnet(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := newSiblings(id.NewPseudoRandom(rng), 8, 2)
+	for c := 0; c < 8; c++ {
+		address := &net.TCPAddr{IP: net.IPv4(203, 0, 113, byte(c+1)), Port: 20100}
+		s.offer(peer.New(id.NewPseudoRandom(rng), "", address))
+	}
+	assert.Len(t, s.list(), 2)
+
+	// peers from other subnets are still offered
+	for c := 0; c < 4; c++ {
+		address := &net.TCPAddr{IP: net.IPv4(198, 51, byte(c), 1), Port: 20100}
+		s.offer(peer.New(id.NewPseudoRandom(rng), "", address))
+	}
+	assert.Len(t, s.list(), 6)
+}
+
+func TestSiblings_remove(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := newSiblings(id.NewPseudoRandom(rng), 8, 0)
+	ps := peer.NewTestPeers(rng, 8)
+	for _, p := range ps {
+		s.offer(p)
+	}
+	s.remove(ps[0].ID())
+	assert.Len(t, s.list(), 7)
+	for _, p := range s.list() {
+		assert.NotEqual(t, ps[0].ID(), p.ID())
+	}
+
+	// removing a non-sibling is a no-op
+	s.remove(id.NewPseudoRandom(rng))
+	assert.Len(t, s.list(), 7)
+}
+
+func TestSiblings_get(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := newSiblings(id.NewPseudoRandom(rng), 8, 0)
+	ps := peer.NewTestPeers(rng, 8)
+	for _, p := range ps {
+		s.offer(p)
+	}
+	for _, p := range ps {
+		sibling, in := s.get(p.ID())
+		assert.True(t, in)
+		assert.Equal(t, p, sibling)
+	}
+
+	sibling, in := s.get(id.NewPseudoRandom(rng))
+	assert.False(t, in)
+	assert.Nil(t, sibling)
+}
+
+func sortByDistance(ps []peer.Peer, target id.ID) {
+	for i := 1; i < len(ps); i++ {
+		for j := i; j > 0; j-- {
+			dj := target.Distance(ps[j].ID())
+			if target.Distance(ps[j-1].ID()).Cmp(dj) <= 0 {
+				break
+			}
+			ps[j-1], ps[j] = ps[j], ps[j-1]
+		}
+	}
+}
@@ -15,16 +15,23 @@ var tableKey = []byte("RoutingTable")
 func Load(
 	nl cstorage.Loader, preferer comm.Preferer, doctor comm.Doctor, params *Parameters,
 ) (Table, error) {
+	stored, err := loadStored(nl)
+	if stored == nil || err != nil {
+		return nil, err
+	}
+	return fromStored(stored, params, preferer, doctor), nil
+}
+
+func loadStored(nl cstorage.Loader) (*sstorage.RoutingTable, error) {
 	bytes, err := nl.Load(tableKey)
 	if bytes == nil || err != nil {
 		return nil, err
 	}
 	stored := &sstorage.RoutingTable{}
-	err = proto.Unmarshal(bytes, stored)
-	if err != nil {
+	if err = proto.Unmarshal(bytes, stored); err != nil {
 		return nil, err
 	}
-	return fromStored(stored, params, preferer, doctor), nil
+	return stored, nil
 }
 
 // Save stores a representation of the routing table to the KV DB.
@@ -51,14 +58,21 @@ func fromStored(
 	return rt
 }
 
-// toStored creates a new StoredRoutingTable instance from the Table instance.
+// toStored creates a new StoredRoutingTable instance from the Table instance. Siblings not also
+// in a bucket are stored alongside the bucket peers, so the sibling list is rebuilt on load.
 func toStored(rt Table) *sstorage.RoutingTable {
-	storedPeers := make([]*sstorage.Peer, len(rt.(*table).peers))
-	i := 0
-	for _, p := range rt.(*table).peers {
-		storedPeers[i] = p.ToStored()
-		i++
+	impl := rt.(*table)
+	impl.peersMu.RLock()
+	storedPeers := make([]*sstorage.Peer, 0, len(impl.peers))
+	for _, p := range impl.peers {
+		storedPeers = append(storedPeers, p.ToStored())
+	}
+	for _, p := range impl.siblings.list() {
+		if _, in := impl.peers[p.ID().String()]; !in {
+			storedPeers = append(storedPeers, p.ToStored())
+		}
 	}
+	impl.peersMu.RUnlock()
 	return &sstorage.RoutingTable{
 		SelfId: rt.SelfID().Bytes(),
 		Peers:  storedPeers,
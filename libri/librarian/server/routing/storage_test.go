@@ -32,7 +32,7 @@ func TestToRoutingTable(t *testing.T) {
 }
 
 func TestRoutingTable_SaveLoad(t *testing.T) {
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -42,8 +42,8 @@ func TestRoutingTable_SaveLoad(t *testing.T) {
 	selfID := ecid.NewPseudoRandom(rng)
 	params := NewDefaultParameters()
 	ps := peer.NewTestPeers(rng, 8)
-	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
-	p, d := comm.NewRpPreferer(rec), &fixedDoctor{healthy: true}
+	rec := newTestReputationJudge()
+	p, d := comm.NewReputationPreferer(rec), &fixedDoctor{healthy: true}
 	for i, p := range ps {
 		for j := 0; j < i+1; j++ {
 			rec.Record(p.ID(), api.Verify, comm.Response, comm.Success)
@@ -62,6 +62,7 @@ func TestRoutingTable_SaveLoad(t *testing.T) {
 	assert.Equal(t, len(rt1.(*table).peers), len(rt2.(*table).peers))
 	assert.Equal(t, rt1.(*table).NumPeers(), rt2.(*table).NumPeers())
 	assert.Equal(t, rt1.(*table).peers, rt2.(*table).peers)
+	assert.Equal(t, rt1.Siblings(), rt2.Siblings())
 
 	// descend into the protected table fields to check equality
 	for bi, bucket1 := range rt1.(*table).buckets {
@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"net"
+
+	errors2 "github.com/drausin/libri/libri/common/errors"
+)
+
+const (
+	// ipv4SubnetBits is the prefix length of the IPv4 subnets whose peers are grouped together.
+	ipv4SubnetBits = 24
+
+	// ipv6SubnetBits is the prefix length of the IPv6 subnets whose peers are grouped together.
+	ipv6SubnetBits = 64
+)
+
+// privateIPNets are the ranges of private addresses, which are exempt from subnet limits along
+// with loopback and link-local addresses so that local and LAN clusters aren't limited.
+var privateIPNets = mustParseCIDRs(
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+)
+
+// subnet returns the /24 (IPv4) or /64 (IPv6) subnet containing the address, or an empty
+// string if the address is exempt from subnet limits.
+func subnet(address *net.TCPAddr) string {
+	if address == nil || exemptIP(address.IP) {
+		return ""
+	}
+	if ip4 := address.IP.To4(); ip4 != nil {
+		return (&net.IPNet{
+			IP:   ip4.Mask(net.CIDRMask(ipv4SubnetBits, 32)),
+			Mask: net.CIDRMask(ipv4SubnetBits, 32),
+		}).String()
+	}
+	return (&net.IPNet{
+		IP:   address.IP.Mask(net.CIDRMask(ipv6SubnetBits, 128)),
+		Mask: net.CIDRMask(ipv6SubnetBits, 128),
+	}).String()
+}
+
+func exemptIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, ipNet := range privateIPNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	ipNets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		var err error
+		_, ipNets[i], err = net.ParseCIDR(cidr)
+		errors2.MaybePanic(err)
+	}
+	return ipNets
+}
@@ -0,0 +1,32 @@
+package routing
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubnet(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.7":      "203.0.113.0/24",
+		"203.0.113.250":    "203.0.113.0/24",
+		"198.51.100.1":     "198.51.100.0/24",
+		"2001:db8:1:2::7":  "2001:db8:1:2::/64",
+		"2001:db8:1:2:a::": "2001:db8:1:2::/64",
+		"2001:db8:1:3::7":  "2001:db8:1:3::/64",
+		"127.0.0.1":        "", // loopback
+		"::1":              "", // loopback
+		"10.1.2.3":         "", // private
+		"172.16.5.4":       "", // private
+		"192.168.1.1":      "", // private
+		"fd00::1":          "", // private
+		"169.254.1.1":      "", // link-local
+		"0.0.0.0":          "", // unspecified
+	}
+	for ipStr, expected := range cases {
+		address := &net.TCPAddr{IP: net.ParseIP(ipStr), Port: 20100}
+		assert.Equal(t, expected, subnet(address), ipStr)
+	}
+	assert.Equal(t, "", subnet(nil))
+}
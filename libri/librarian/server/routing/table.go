@@ -4,10 +4,11 @@ import (
 	"container/heap"
 	"errors"
 	"fmt"
-	"math/big"
+	"io"
 	"math/rand"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/drausin/libri/libri/common/ecid"
 	errors2 "github.com/drausin/libri/libri/common/errors"
@@ -17,6 +18,14 @@ import (
 	"github.com/drausin/libri/libri/librarian/server/peer"
 )
 
+var (
+	// ErrNegativeCheckpointInterval indicates that the checkpoint interval is negative.
+	ErrNegativeCheckpointInterval = errors.New("routing table checkpoint interval is negative")
+
+	// ErrNegativeCompactInterval indicates that the compaction interval is negative.
+	ErrNegativeCompactInterval = errors.New("routing table compaction interval is negative")
+)
+
 const (
 	// Existed denotes that the peer already existed in the table.
 	Existed PushStatus = iota
@@ -34,8 +43,57 @@ const (
 const (
 	// DefaultMaxActivePeers returns the default number of maximum number of peers in a bucket.
 	DefaultMaxActivePeers = uint(16)
+
+	// DefaultNSiblings is the default number of peers closest to self kept in the sibling list.
+	DefaultNSiblings = uint(32)
+
+	// DefaultMaxConsecutiveFailures is the default number of consecutive failed queries after
+	// which a peer is evicted from the table.
+	DefaultMaxConsecutiveFailures = uint(5)
+
+	// DefaultAddressConfirmInterval is the default minimum time between confirmations of a
+	// peer's claimed change of address.
+	DefaultAddressConfirmInterval = 1 * time.Minute
+
+	// DefaultMaxDepth is the default maximum depth of the bucket tree. Reaching it with random
+	// IDs would require far more peers than any network will have, so in practice it only
+	// bounds trees shaped by adversarial ID distributions.
+	DefaultMaxDepth = uint(64)
+
+	// DefaultCheckpointInterval is the default interval between routing table checkpoints.
+	DefaultCheckpointInterval = 5 * time.Second
+
+	// DefaultFullCheckpointEvery is the default number of checkpoints between full snapshots,
+	// i.e., hourly with the default checkpoint interval.
+	DefaultFullCheckpointEvery = uint(720)
+
+	// DefaultMaxDepthBucketPeers is the default maximum number of peers in the self-containing
+	// bucket once it reaches the maximum depth.
+	DefaultMaxDepthBucketPeers = 4 * DefaultMaxActivePeers
+
+	// DefaultMaxSubnetBucketPeers is the default maximum number of peers in a bucket from the
+	// same subnet.
+	DefaultMaxSubnetBucketPeers = uint(2)
+
+	// DefaultCompactInterval is the default interval between compactions of the table's buckets.
+	DefaultCompactInterval = 1 * time.Hour
 )
 
+const (
+	// ConfirmAddresses keeps a known peer's addresses when it is pushed with different ones,
+	// leaving the change to be confirmed and then applied via UpdateAddresses. This prevents
+	// peers from redirecting traffic for other peers by reporting false addresses for them. It
+	// is the zero value, so parameters without an explicit policy fail closed.
+	ConfirmAddresses AddressPolicy = iota
+
+	// AcceptAddresses immediately replaces a known peer's addresses with those it is pushed
+	// with.
+	AcceptAddresses
+)
+
+// AddressPolicy determines how the table handles a known peer pushed with different addresses.
+type AddressPolicy int
+
 // PushStatus indicates different outcomes when adding a peer to the routing table.
 type PushStatus int
 
@@ -54,7 +112,7 @@ func (p PushStatus) String() string {
 }
 
 // Table defines how routes to a particular target map to specific peers, held in a tree of
-// buckets.
+// buckets. All Table methods are safe for concurrent use.
 type Table interface {
 	// SelfID returns the table's selfID.
 	SelfID() id.ID
@@ -69,6 +127,21 @@ type Table interface {
 	// indicator for whether the peer existed.
 	Get(peerID id.ID) (peer.Peer, bool)
 
+	// Evict removes the peer with the given ID from its bucket and returns whether it existed.
+	Evict(peerID id.ID) bool
+
+	// UpdateAddresses replaces the addresses of the known peer with those of the given peer,
+	// returning whether the peer was known. Callers are expected to have confirmed the new
+	// addresses.
+	UpdateAddresses(p peer.Peer) bool
+
+	// Siblings returns the peers closest to self, ordered by increasing distance, independent
+	// of the buckets they may or may not be in.
+	Siblings() []peer.Peer
+
+	// AmongClosest returns whether self is among the k peers closest to the given key.
+	AmongClosest(key id.ID, k uint) bool
+
 	// Sample returns k peers in the table sampled (approximately) uniformly from the ID space.
 	// Peers are sampled from buckets with probability proportional to the amount of ID
 	// space the bucket covers.
@@ -82,21 +155,180 @@ type Table interface {
 
 	// Save saves the table via the NamespaceStorer
 	Save(ns storage.Storer) error
+
+	// ExportJSON writes a portable JSON snapshot of the table to w, including each peer's
+	// response stats from qg (if not nil).
+	ExportJSON(w io.Writer, qg comm.QueryGetter) error
+
+	// Snapshot returns a snapshot of the table's buckets and peers, including each peer's
+	// response stats from qg (if not nil).
+	Snapshot(qg comm.QueryGetter) *Snapshot
+
+	// ImportJSON pushes the peers from a JSON snapshot read from r into the table, returning the
+	// number of peers added.
+	ImportJSON(r io.Reader) (int, error)
+
+	// Metrics returns a snapshot of the table's health, including bucket occupancy, tree depth
+	// distribution, and peer churn.
+	Metrics() *Metrics
+
+	// EstimateKeyspace estimates the size of the network and self's share of the keyspace from
+	// the density of peers in the table's buckets.
+	EstimateKeyspace() *KeyspaceEstimate
+
+	// Compact rebuilds the peer collections of buckets left oversized by churn, returning the
+	// number of buckets compacted.
+	Compact() int
 }
 
 // Parameters are the parameters of the routing table.
 type Parameters struct {
 	// MaxBucketPeers is the maximum number of peers in a bucket.
 	MaxBucketPeers uint
+
+	// BucketPeersByDepth optionally gives the maximum number of peers in a bucket at the given
+	// depth, e.g., to keep larger buckets near self and smaller ones far away. When nil or when
+	// it returns zero, MaxBucketPeers is used.
+	BucketPeersByDepth func(depth uint) uint
+
+	// NSiblings is the number of peers closest to self kept in the sibling list.
+	NSiblings uint
+
+	// MaxConsecutiveFailures is the number of consecutive failed queries to a peer after which
+	// it is evicted from the table. Zero disables eviction.
+	MaxConsecutiveFailures uint
+
+	// AddressPolicy determines how a known peer pushed with different addresses is handled.
+	AddressPolicy AddressPolicy
+
+	// MaxDepth is the maximum depth of the bucket tree, beyond which the self-containing bucket
+	// stops splitting. Zero means the depth is only limited by the ID length.
+	MaxDepth uint
+
+	// MaxDepthBucketPeers is the maximum number of peers in the self-containing bucket at
+	// MaxDepth, which is usually larger than that of other buckets to make up for it no longer
+	// splitting. When zero, the usual per-depth maximum is used.
+	MaxDepthBucketPeers uint
+
+	// AddressConfirmInterval is the minimum time between confirmations of a peer's claimed
+	// change of address.
+	AddressConfirmInterval time.Duration
+
+	// CheckpointInterval is the interval between checkpoints persisting the peers changed since
+	// the previous one. Zero disables checkpoints, so only the snapshot on close persists the
+	// table.
+	CheckpointInterval time.Duration
+
+	// FullCheckpointEvery is the number of checkpoints between full snapshots of the table. Zero
+	// means only the first checkpoint is a full snapshot.
+	FullCheckpointEvery uint
+
+	// MaxSubnetBucketPeers is the maximum number of peers in a bucket whose preferred addresses
+	// share a /24 (IPv4) or /64 (IPv6) subnet, which raises the cost of eclipsing self with
+	// peers from a few hosts. Loopback and private addresses are exempt. Zero disables the limit.
+	MaxSubnetBucketPeers uint
+
+	// KeyspaceWidth is the byte width of the IDs in the keyspace the table covers, e.g., to give
+	// test networks a smaller keyspace. It must be in [1, id.Length], and zero means id.Length.
+	// Peers with IDs outside the keyspace are dropped.
+	KeyspaceWidth uint
+
+	// CompactInterval is the interval between compactions of the buckets' peer collections,
+	// which rebuild those left oversized by heavy churn so long-running librarians' memory stays
+	// flat. Zero disables compaction.
+	CompactInterval time.Duration
+
+	// PuzzleDifficulty is the minimum number of leading zero puzzle bits (see ecid.PuzzleBits)
+	// of the IDs of peers added to the table, however they were learned of (e.g., from Find
+	// responses), so Sybil IDs can't cheaply fill it. Zero means no requirement.
+	PuzzleDifficulty uint
 }
 
 // NewDefaultParameters creates a new set of default parameters.
 func NewDefaultParameters() *Parameters {
 	return &Parameters{
-		MaxBucketPeers: DefaultMaxActivePeers,
+		MaxBucketPeers:         DefaultMaxActivePeers,
+		NSiblings:              DefaultNSiblings,
+		MaxConsecutiveFailures: DefaultMaxConsecutiveFailures,
+		AddressPolicy:          ConfirmAddresses,
+		AddressConfirmInterval: DefaultAddressConfirmInterval,
+		MaxDepth:               DefaultMaxDepth,
+		MaxDepthBucketPeers:    DefaultMaxDepthBucketPeers,
+		CheckpointInterval:     DefaultCheckpointInterval,
+		FullCheckpointEvery:    DefaultFullCheckpointEvery,
+		MaxSubnetBucketPeers:   DefaultMaxSubnetBucketPeers,
+		KeyspaceWidth:          id.Length,
+		CompactInterval:        DefaultCompactInterval,
 	}
 }
 
+// Validate returns an error if any of the parameters are invalid.
+func (p *Parameters) Validate() error {
+	if p.CheckpointInterval < 0 {
+		return ErrNegativeCheckpointInterval
+	}
+	if p.CompactInterval < 0 {
+		return ErrNegativeCompactInterval
+	}
+	if err := ecid.CheckPuzzleDifficulty(p.PuzzleDifficulty); err != nil {
+		return err
+	}
+	return id.CheckWidth(p.keyspaceWidth())
+}
+
+// keyspaceWidth returns the byte width of the IDs in the table's keyspace.
+func (p *Parameters) keyspaceWidth() uint {
+	return id.WidthOrDefault(p.KeyspaceWidth)
+}
+
+// keyspaceBits returns the number of bits in the IDs of the table's keyspace.
+func (p *Parameters) keyspaceBits() uint {
+	return p.keyspaceWidth() * 8
+}
+
+// bucketPeers returns the maximum number of peers in a bucket at the given depth.
+func (p *Parameters) bucketPeers(depth uint) uint {
+	if p.BucketPeersByDepth != nil {
+		if n := p.BucketPeersByDepth(depth); n > 0 {
+			return n
+		}
+	}
+	return p.MaxBucketPeers
+}
+
+// atMaxDepth returns whether a bucket at the given depth is at the maximum depth and so can't
+// split any further.
+func (p *Parameters) atMaxDepth(depth uint) bool {
+	return (p.MaxDepth > 0 && depth >= p.MaxDepth) || depth >= p.keyspaceBits()-1
+}
+
+// maxActivePeers returns the maximum number of peers in a bucket at the given depth, accounting
+// for the larger capacity of the self-containing bucket at the maximum depth.
+func (p *Parameters) maxActivePeers(depth uint, containsSelf bool) uint {
+	if containsSelf && p.atMaxDepth(depth) && p.MaxDepthBucketPeers > 0 {
+		return p.MaxDepthBucketPeers
+	}
+	return p.bucketPeers(depth)
+}
+
+// LinearBucketPeersByDepth returns a BucketPeersByDepth function that linearly scales the number
+// of peers from shallow at depth 0 to deep at fullDepth and beyond.
+func LinearBucketPeersByDepth(shallow, deep, fullDepth uint) func(depth uint) uint {
+	return func(depth uint) uint {
+		if depth >= fullDepth {
+			return deep
+		}
+		span := float64(deep) - float64(shallow)
+		return uint(float64(shallow) + span*float64(depth)/float64(fullDepth) + 0.5)
+	}
+}
+
+// table is a Table whose concurrency is managed by a few levels of locks, always acquired in the
+// order of table.mu -> bucket.mu -> table.peersMu. The table mu is read-locked by every operation
+// and only write-locked when splitting a bucket, since that is the only operation that changes
+// the tree of buckets itself. Each bucket's mu guards that bucket's peer heap, so pushes to and
+// lookups on different buckets do not block each other. The peersMu guards the peers map. The
+// sibling list manages its own lock, which is always acquired last.
 type table struct {
 	// this peer's node ID
 	selfID id.ID
@@ -107,24 +339,72 @@ type table struct {
 	// routing buckets ordered by the max ID possible in each bucket.
 	buckets []*bucket
 
+	// peers closest to self, independent of buckets
+	siblings *siblings
+
+	// counts peers added and removed
+	churn *churn
+
+	// peers changed since the last checkpoint
+	dirty *dirtyPeers
+
+	// peers banned from the table
+	blacklist comm.Blacklister
+
 	// defines some aspects of behavior
 	params *Parameters
 
-	// manages pushes and pops
-	mu sync.Mutex
+	// guards the structure of the bucket tree
+	mu sync.RWMutex
+
+	// guards the peers map
+	peersMu sync.RWMutex
 }
 
-// NewEmpty creates a new routing table without peers.
+// NewEmpty creates a new routing table without peers. It panics if the keyspace width is invalid
+// or the self ID lies outside the keyspace.
 func NewEmpty(selfID id.ID, preferer comm.Preferer, doctor comm.Doctor, params *Parameters) Table {
-	firstBucket := newFirstBucket(params.MaxBucketPeers, preferer, doctor)
+	width := params.keyspaceWidth()
+	errors2.MaybePanic(id.CheckWidth(width))
+	if !id.InKeyspace(selfID, width) {
+		panic(fmt.Errorf("self ID %s outside %d-byte keyspace", selfID, width))
+	}
+	firstBucket := newFirstBucket(id.UpperBoundOf(width), params.maxActivePeers(0, true),
+		preferer, doctor)
 	return &table{
-		selfID:  selfID,
-		peers:   make(map[string]peer.Peer),
-		buckets: []*bucket{firstBucket},
-		params:  params,
+		selfID:    selfID,
+		peers:     make(map[string]peer.Peer),
+		buckets:   []*bucket{firstBucket},
+		siblings:  newSiblings(selfID, params.NSiblings, params.MaxSubnetBucketPeers),
+		churn:     newChurn(churnWindow),
+		dirty:     newDirtyPeers(),
+		blacklist: comm.NewNaiveBlacklister(),
+		params:    params,
 	}
 }
 
+// WithBlacklister sets the Blacklister the table consults to drop pushed peers that are banned,
+// either by ID or by IP. It does not evict peers already in the table, which is left to the
+// Blacklister's ban callback.
+func WithBlacklister(rt Table, bl comm.Blacklister) Table {
+	rt.(*table).blacklist = bl
+	return rt
+}
+
+// NewKnower returns a comm.Knower that knows the peers currently in the routing table.
+func NewKnower(rt Table) comm.Knower {
+	return &tableKnower{rt: rt}
+}
+
+type tableKnower struct {
+	rt Table
+}
+
+func (k *tableKnower) Know(peerID id.ID) bool {
+	_, in := k.rt.Get(peerID)
+	return in
+}
+
 // NewWithPeers creates a new routing table with peers, returning it and the number of peers added.
 func NewWithPeers(
 	selfID id.ID,
@@ -148,9 +428,8 @@ func NewTestWithPeers(rng *rand.Rand, n int) (Table, ecid.ID, int, comm.Preferer
 	peerID := ecid.NewPseudoRandom(rng)
 	params := NewDefaultParameters()
 	ps := peer.NewTestPeers(rng, n)
-	k := comm.NewAlwaysKnower()
-	rec := comm.NewQueryRecorderGetter(k)
-	preferer := comm.NewRpPreferer(rec)
+	judge := comm.NewReputationJudge(&storage.TestSLD{}, comm.NewDefaultReputationParameters())
+	preferer := comm.NewReputationPreferer(judge)
 	doctor := comm.NewNaiveDoctor()
 	rt, nAdded := NewWithPeers(peerID.ID(), preferer, doctor, params, ps)
 	return rt, peerID, nAdded, preferer
@@ -162,10 +441,14 @@ func (rt *table) SelfID() id.ID {
 }
 
 func (rt *table) NumPeers() int {
+	rt.peersMu.RLock()
+	defer rt.peersMu.RUnlock()
 	return len(rt.peers)
 }
 
 func (rt *table) NumBuckets() int {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
 	return rt.Len()
 }
 
@@ -176,66 +459,101 @@ func (rt *table) Push(new peer.Peer) PushStatus {
 		// don't add self
 		return Dropped
 	}
+	if !id.InKeyspace(new.ID(), rt.params.keyspaceWidth()) {
+		// can't route to peers outside the keyspace
+		return Dropped
+	}
+	if !ecid.SolvesPuzzle(new.ID(), rt.params.PuzzleDifficulty) {
+		return Dropped
+	}
+	if rt.banned(new) {
+		return Dropped
+	}
+	rt.dirty.mark(new.ID())
 
-	rt.mu.Lock()
+	if new.Address() != nil {
+		rt.siblings.offer(new)
+	}
+
+	for {
+		status, full := rt.pushBucket(new)
+		if full == nil {
+			return status
+		}
+		// no vacancy in the bucket and it contains the self ID, so split the bucket (which
+		// requires the table write lock) and try again; since each split deepens the bucket
+		// containing self, this ends once that bucket has a vacancy or reaches the max depth
+		rt.maybeSplitBucket(full)
+	}
+}
+
+// pushBucket adds the peer into its bucket while holding the table read lock and that bucket's
+// lock. If the bucket needs splitting before the peer can be added, it returns that bucket
+// without adding the peer.
+func (rt *table) pushBucket(new peer.Peer) (PushStatus, *bucket) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
 
 	// get the bucket to insert into
-	bucketIdx := rt.bucketIndex(new.ID())
-	insertBucket := rt.buckets[bucketIdx]
+	insertBucket := rt.buckets[rt.bucketIndex(new.ID())]
+	insertBucket.mu.Lock()
+	defer insertBucket.mu.Unlock()
 
 	// take opportunity to remove an unhealthy root if necessary
 	if insertBucket.unhealthyRoot() {
 		popped := heap.Pop(insertBucket).(peer.Peer)
-		delete(rt.peers, popped.ID().String())
+		rt.deletePeer(popped)
 	}
 
 	if pHeapIdx, in := insertBucket.positions[new.ID().String()]; in {
-		err := insertBucket.activePeers[pHeapIdx].Merge(new)
+		existing := insertBucket.activePeers[pHeapIdx]
+		if rt.params.AddressPolicy == ConfirmAddresses && !peer.SameAddresses(existing, new) {
+			// address changes must be confirmed before they're applied via UpdateAddresses
+			new = peer.WithoutAddresses(new)
+		}
+		err := existing.Merge(new)
 		errors2.MaybePanic(err) // should never happen
 		heap.Fix(insertBucket, pHeapIdx)
-		rt.mu.Unlock()
-		return Existed
+		return Existed, nil
 	}
-	if _, exists := rt.peers[new.ID().String()]; exists {
+	if _, exists := rt.getPeer(new.ID()); exists {
 		// should never happen, but check just in case
 		panic(errors.New("peer should be found in its insert bucket if in peers map"))
 	}
 
 	if new.Address() == nil {
 		// don't add if doesn't have public address
-		rt.mu.Unlock()
-		return Dropped
+		return Dropped, nil
+	}
+
+	if rt.needsSplit(insertBucket) {
+		return Dropped, insertBucket
 	}
 
-	if !insertBucket.Vacancy() && insertBucket.containsSelf {
-		// no vacancy in the bucket and it contains the self ID, so split the bucket and
-		// insert via (single) recursive call
-		rt.splitBucket(bucketIdx)
-		rt.mu.Unlock()
-		return rt.Push(new)
+	if rt.subnetFull(insertBucket, new) {
+		// don't let peers from one subnet monopolize a bucket
+		return Dropped, nil
 	}
 
 	// add peer to bucket, possibly popping one off if it's over capacity
 	heap.Push(insertBucket, new)
-	rt.peers[new.ID().String()] = new
+	rt.addPeer(new)
 	if len(insertBucket.activePeers) > int(insertBucket.maxActivePeers) {
 		popped := heap.Pop(insertBucket).(peer.Peer)
-		delete(rt.peers, popped.ID().String())
-		rt.mu.Unlock()
+		rt.deletePeer(popped)
 		if popped == new {
-			return Dropped
+			return Dropped, nil
 		}
-		return Replaced
+		return Replaced, nil
 	}
-	rt.mu.Unlock()
-	return Added
+	return Added, nil
 }
 
 // Find removes and returns the k peers in the bucket(s) closest to the given target. This method
 // is concurrency safe.
 func (rt *table) Find(target id.ID, k uint) []peer.Peer {
-	rt.mu.Lock()
-	defer rt.mu.Unlock()
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
 	if int(k) > rt.NumPeers() {
 		k = uint(rt.NumPeers())
 	}
@@ -247,7 +565,10 @@ func (rt *table) Find(target id.ID, k uint) []peer.Peer {
 	next := make([]peer.Peer, 0, k)
 	for len(next) < int(k) && (fwdIdx < len(rt.buckets) || bkwdIdx >= 0) {
 		bucketIdx := rt.chooseBucketIndex(target, fwdIdx, bkwdIdx)
-		found := rt.buckets[bucketIdx].Find(target, k-uint(len(next)))
+		b := rt.buckets[bucketIdx]
+		b.mu.Lock()
+		found := b.Find(target, k-uint(len(next)))
+		b.mu.Unlock()
 		next = append(next, found...)
 
 		// (in|de)crement the appropriate index
@@ -263,24 +584,105 @@ func (rt *table) Find(target id.ID, k uint) []peer.Peer {
 
 // Get returns the peer (if it exists) in the table with the given ID.
 func (rt *table) Get(peerID id.ID) (peer.Peer, bool) {
-	rt.mu.Lock()
-	defer rt.mu.Unlock()
-	if p, exists := rt.peers[peerID.String()]; exists {
-		return p, true
+	return rt.getPeer(peerID)
+}
+
+// UpdateAddresses replaces the addresses of the known peer with those of the given peer. This
+// method is concurrency-safe.
+func (rt *table) UpdateAddresses(p peer.Peer) bool {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	b := rt.buckets[rt.bucketIndex(p.ID())]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rt.dirty.mark(p.ID())
+	updated := false
+	if pHeapIdx, in := b.positions[p.ID().String()]; in {
+		errors2.MaybePanic(b.activePeers[pHeapIdx].Merge(p)) // should never happen
+		updated = true
+	}
+	if sibling, in := rt.siblings.get(p.ID()); in {
+		errors2.MaybePanic(sibling.Merge(p)) // should never happen
+		updated = true
 	}
-	return nil, false
+	return updated
+}
+
+// subnetFull returns whether the bucket already has the max number of peers from the subnet of
+// the peer's preferred address.
+func (rt *table) subnetFull(b *bucket, p peer.Peer) bool {
+	if rt.params.MaxSubnetBucketPeers == 0 {
+		return false
+	}
+	sn := subnet(p.Address())
+	return sn != "" && b.SubnetPeers(sn) >= rt.params.MaxSubnetBucketPeers
+}
+
+// banned returns whether the peer is banned by ID or by the IP of any of its addresses.
+func (rt *table) banned(p peer.Peer) bool {
+	if rt.blacklist.Banned(p.ID()) {
+		return true
+	}
+	for _, address := range p.Addresses() {
+		if rt.blacklist.BannedIP(address.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evict removes the peer (if it exists) with the given ID from its bucket. This method is
+// concurrency-safe.
+func (rt *table) Evict(peerID id.ID) bool {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	b := rt.buckets[rt.bucketIndex(peerID)]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pHeapIdx, in := b.positions[peerID.String()]
+	if !in {
+		return false
+	}
+	evicted := heap.Remove(b, pHeapIdx).(peer.Peer)
+	rt.deletePeer(evicted)
+	rt.siblings.remove(peerID)
+	return true
+}
+
+// Siblings returns the peers closest to self. This method is concurrency-safe.
+func (rt *table) Siblings() []peer.Peer {
+	return rt.siblings.list()
+}
+
+// AmongClosest returns whether self is among the k peers closest to the given key, counting the
+// known peers (from both the sibling list and the buckets) that are closer to the key than self.
+// This method is concurrency-safe.
+func (rt *table) AmongClosest(key id.ID, k uint) bool {
+	closer := make(map[string]struct{})
+	for _, ps := range [][]peer.Peer{rt.siblings.list(), rt.Find(key, k)} {
+		for _, p := range ps {
+			if id.CmpDistance(key, p.ID(), rt.selfID) < 0 {
+				closer[p.ID().String()] = struct{}{}
+			}
+		}
+	}
+	return uint(len(closer)) < k
 }
 
 func (rt *table) Sample(k uint, rng *rand.Rand) []peer.Peer {
-	rt.mu.Lock()
-	defer rt.mu.Unlock()
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	nPeers := rt.NumPeers()
 
 	// get number of peers to peak from each bucket
 	bucketCounts := make([]uint, len(rt.buckets))
-	for c := 0; c < int(k) && c < len(rt.peers); {
+	for c := 0; c < int(k) && c < nPeers; {
 		density := rng.Float64()
 		idx := rt.densityBucketIndex(density)
-		if int(bucketCounts[idx]) < rt.buckets[idx].Len() {
+		rt.buckets[idx].mu.Lock()
+		bucketLen := rt.buckets[idx].Len()
+		rt.buckets[idx].mu.Unlock()
+		if int(bucketCounts[idx]) < bucketLen {
 			// if we have more peers in the bucket available for sampling; when this is
 			// not the case, sample again, making the distribution of buckets only
 			// approximately uniform over the ID space
@@ -293,7 +695,9 @@ func (rt *table) Sample(k uint, rng *rand.Rand) []peer.Peer {
 	sample := make([]peer.Peer, 0, k)
 	for i := 0; i < len(bucketCounts); i++ {
 		if bucketCounts[i] > 0 {
+			rt.buckets[i].mu.Lock()
 			sample = append(sample, rt.buckets[i].Peak(bucketCounts[i])...)
+			rt.buckets[i].mu.Unlock()
 		}
 	}
 	return sample
@@ -370,42 +774,84 @@ func (rt *table) densityBucketIndex(idDensity float64) int {
 	})
 }
 
-// splitBucket splits the bucketIdx into two and relocates the nodes appropriately
+func (rt *table) getPeer(peerID id.ID) (peer.Peer, bool) {
+	rt.peersMu.RLock()
+	defer rt.peersMu.RUnlock()
+	p, exists := rt.peers[peerID.String()]
+	return p, exists
+}
+
+func (rt *table) addPeer(p peer.Peer) {
+	rt.peersMu.Lock()
+	rt.peers[p.ID().String()] = p
+	rt.peersMu.Unlock()
+	rt.churn.added()
+}
+
+func (rt *table) deletePeer(p peer.Peer) {
+	rt.peersMu.Lock()
+	delete(rt.peers, p.ID().String())
+	rt.peersMu.Unlock()
+	rt.churn.removed()
+	rt.dirty.mark(p.ID())
+}
+
+// maybeSplitBucket splits the given bucket if it is still in the table and still needs splitting,
+// since another goroutine may have split it between the caller releasing its read lock and this
+// method acquiring the write lock.
+func (rt *table) maybeSplitBucket(b *bucket) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	bucketIdx := rt.bucketIndex(b.lowerBound)
+	if rt.buckets[bucketIdx] != b || !rt.needsSplit(b) {
+		return
+	}
+	rt.splitBucket(bucketIdx)
+}
+
+// needsSplit returns whether the bucket is full, contains the self ID, and is shallower than the
+// maximum depth. The caller must hold the bucket lock or the table write lock.
+func (rt *table) needsSplit(b *bucket) bool {
+	return !b.Vacancy() && b.containsSelf && !rt.params.atMaxDepth(b.depth)
+}
+
+// splitBucket splits the bucketIdx into two and relocates the nodes appropriately. The caller
+// must hold the table write lock.
 func (rt *table) splitBucket(bucketIdx int) {
 	current := rt.buckets[bucketIdx]
 
 	// define the bounds of the two new buckets from those of the current bucket
-	middle := splitLowerBound(current.lowerBound, current.depth)
+	middle := id.SplitLowerBound(current.lowerBound, current.depth, rt.params.keyspaceBits())
 	newIDMass := current.idMass / 2.0
 
 	// create the new buckets
 	left := &bucket{
-		depth:          current.depth + 1,
-		lowerBound:     current.lowerBound,
-		upperBound:     middle,
-		idMass:         newIDMass,
-		idCumMass:      current.idCumMass - newIDMass,
-		maxActivePeers: current.maxActivePeers,
-		activePeers:    make([]peer.Peer, 0),
-		positions:      make(map[string]int),
-		preferer:       current.preferer,
-		doctor:         current.doctor,
+		depth:       current.depth + 1,
+		lowerBound:  current.lowerBound,
+		upperBound:  middle,
+		idMass:      newIDMass,
+		idCumMass:   current.idCumMass - newIDMass,
+		activePeers: make([]peer.Peer, 0),
+		positions:   make(map[string]int),
+		preferer:    current.preferer,
+		doctor:      current.doctor,
 	}
 	left.containsSelf = left.Contains(rt.selfID)
+	left.maxActivePeers = rt.params.maxActivePeers(left.depth, left.containsSelf)
 
 	right := &bucket{
-		depth:          current.depth + 1,
-		lowerBound:     middle,
-		upperBound:     current.upperBound,
-		idMass:         newIDMass,
-		idCumMass:      current.idCumMass,
-		maxActivePeers: current.maxActivePeers,
-		activePeers:    make([]peer.Peer, 0),
-		positions:      make(map[string]int),
-		preferer:       current.preferer,
-		doctor:         current.doctor,
+		depth:       current.depth + 1,
+		lowerBound:  middle,
+		upperBound:  current.upperBound,
+		idMass:      newIDMass,
+		idCumMass:   current.idCumMass,
+		activePeers: make([]peer.Peer, 0),
+		positions:   make(map[string]int),
+		preferer:    current.preferer,
+		doctor:      current.doctor,
 	}
 	right.containsSelf = right.Contains(rt.selfID)
+	right.maxActivePeers = rt.params.maxActivePeers(right.depth, right.containsSelf)
 
 	// fill the buckets with existing peers
 	for _, p := range current.activePeers {
@@ -416,19 +862,17 @@ func (rt *table) splitBucket(bucketIdx int) {
 		}
 	}
 
-	// replace the current bucket with the two new ones
-	rt.buckets[bucketIdx] = left           // replace the current bucket with left
-	rt.buckets = append(rt.buckets, right) // right should actually be just to the right of left
-	sort.Sort(rt)                          // but we let Sort handle moving it back there
-}
+	// drop least-preferred peers from new buckets smaller than their share of the current one
+	for _, b := range []*bucket{left, right} {
+		for len(b.activePeers) > int(b.maxActivePeers) {
+			rt.deletePeer(heap.Pop(b).(peer.Peer))
+		}
+	}
 
-// splitLowerBound extends a lower bound one bit deeper with a 1 bit, thereby splitting
-// the domain implied by the current lower bound and depth
-// e.g.,
-// 	extendLowerBound(00000000, 0) -> 10000000
-// 	extendLowerBound(10000000, 1) -> 20100000
-//	...
-// 	extendLowerBound(20100000, 4) -> 11001000
-func splitLowerBound(lowerBound id.ID, depth uint) id.ID {
-	return id.FromInt(new(big.Int).SetBit(lowerBound.Int(), int(id.Length*8-depth-1), 1))
+	// replace the current bucket with the two new ones, keeping the buckets in order
+	buckets := make([]*bucket, 0, len(rt.buckets)+1)
+	buckets = append(buckets, rt.buckets[:bucketIdx]...)
+	buckets = append(buckets, left, right)
+	buckets = append(buckets, rt.buckets[bucketIdx+1:]...)
+	rt.buckets = buckets
 }
@@ -2,13 +2,17 @@ package routing
 
 import (
 	"fmt"
-	"math/big"
 	"math/rand"
+	"net"
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/stretchr/testify/assert"
 )
@@ -45,6 +49,124 @@ func TestTable_NewWithPeers_concurrent(t *testing.T) {
 	}
 }
 
+func TestTable_concurrent(t *testing.T) {
+	// meant to be run with the race detector to check that pushes, splits, and lookups on
+	// different goroutines don't race with each other
+	rng := rand.New(rand.NewSource(int64(0)))
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	rt := NewEmpty(id.NewPseudoRandom(rng), p, d, NewDefaultParameters())
+	targets := make([]id.ID, 16)
+	for i := range targets {
+		targets[i] = id.NewPseudoRandom(rng)
+	}
+	concurrency, nPeers := 4, 256
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(4)
+
+		// push (and thus split) peers
+		go func(i int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(i + 1)))
+			for _, p := range peer.NewTestPeers(rng, nPeers) {
+				rt.Push(p)
+			}
+		}(i)
+
+		// explicitly split the bucket containing self, if it still needs it
+		go func() {
+			defer wg.Done()
+			impl := rt.(*table)
+			for c := 0; c < nPeers; c++ {
+				impl.mu.RLock()
+				selfBucket := impl.buckets[impl.bucketIndex(impl.selfID)]
+				impl.mu.RUnlock()
+				impl.maybeSplitBucket(selfBucket)
+			}
+		}()
+
+		// find and sample peers
+		go func(i int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(i + 1)))
+			for c := 0; c < nPeers; c++ {
+				rt.Find(targets[c%len(targets)], 8)
+				rt.Sample(8, rng)
+			}
+		}(i)
+
+		// get peers and table state
+		go func(i int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(i + 1)))
+			ssl := &storage.TestSLD{}
+			for _, p := range peer.NewTestPeers(rng, nPeers) {
+				rt.Get(p.ID())
+				rt.NumPeers()
+				rt.NumBuckets()
+			}
+			assert.Nil(t, rt.Save(ssl))
+		}(i)
+	}
+	wg.Wait()
+
+	checkTableConsistent(t, rt, rt.NumPeers())
+}
+
+func TestTable_concurrentSplits(t *testing.T) {
+	// randomized pushes and evictions from many goroutines, with small buckets so they're
+	// split often, should always leave the table consistent
+	for s := 0; s < 8; s++ {
+		rng := rand.New(rand.NewSource(int64(s)))
+		params := NewDefaultParameters()
+		params.MaxBucketPeers = uint(2 + rng.Intn(6))
+		params.MaxDepthBucketPeers = params.MaxBucketPeers
+		// shallow enough that the deepest buckets' ID masses still register in the cumulative
+		// masses checkTableConsistent checks increase
+		params.MaxDepth = 32
+		p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+		selfID := id.NewPseudoRandom(rng)
+		rt := NewEmpty(selfID, p, d, params)
+		concurrency, nPeers := 2+rng.Intn(7), 64+rng.Intn(128)
+
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				rng := rand.New(rand.NewSource(int64(s*concurrency + i + 1)))
+				ps := peer.NewTestPeers(rng, nPeers)
+				for j, p := range ps {
+					if rng.Intn(2) == 0 {
+						// peers near self make splits of the self-containing bucket likelier
+						idBytes := selfID.Bytes()
+						idBytes[1+rng.Intn(len(idBytes)-1)] ^= byte(1 + rng.Intn(255))
+						p = peer.New(id.FromBytes(idBytes), "", p.Address())
+						ps[j] = p
+					}
+					rt.Push(p)
+					if j > 0 && rng.Intn(4) == 0 {
+						rt.Evict(ps[rng.Intn(j)].ID())
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		checkTableConsistent(t, rt, rt.NumPeers())
+		impl := rt.(*table)
+		assert.Equal(t, id.LowerBound, impl.buckets[0].lowerBound)
+		assert.Equal(t, id.UpperBound, impl.buckets[len(impl.buckets)-1].upperBound)
+		for _, b := range impl.buckets {
+			if !b.containsSelf {
+				continue
+			}
+			// if the bucket containing self can still be split, it must have a vacancy
+			assert.True(t, b.Vacancy() || params.atMaxDepth(b.depth))
+		}
+	}
+}
+
 func TestTable_NumPeers(t *testing.T) {
 	for s := 0; s < 16; s++ {
 		// make sure handles zero peers
@@ -136,6 +258,191 @@ func TestTable_Push_existing(t *testing.T) {
 	}
 }
 
+func TestTable_Push_subnetFlood(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	preferer, doctor := &fixedPreferer{}, comm.NewNaiveDoctor()
+	rt := NewEmpty(id.NewPseudoRandom(rng), preferer, doctor, params)
+
+	// flood of peers from the same subnet gets at most a few peers into each bucket
+	nFlood := 0
+	for i := 0; i < 1024; i++ {
+		if rt.Push(newTestSubnetPeer(rng, "203.0.113", i%250)) != Dropped {
+			nFlood++
+		}
+	}
+	nBuckets := rt.NumBuckets()
+	assert.True(t, nFlood <= int(params.MaxSubnetBucketPeers)*nBuckets)
+	for _, b := range rt.(*table).buckets {
+		assert.True(t, b.SubnetPeers("203.0.113.0/24") <= params.MaxSubnetBucketPeers)
+	}
+	assert.True(t, len(rt.Siblings()) <= int(params.MaxSubnetBucketPeers))
+
+	// so peers from other subnets can still be added
+	nDiverse := 0
+	for i := 0; i < 128; i++ {
+		if rt.Push(newTestSubnetPeer(rng, fmt.Sprintf("198.51.%d", i), 0)) != Dropped {
+			nDiverse++
+		}
+	}
+	assert.True(t, nDiverse > nFlood)
+
+	// without the limit, the flood fills the table
+	params.MaxSubnetBucketPeers = 0
+	rt = NewEmpty(id.NewPseudoRandom(rng), preferer, doctor, params)
+	nFlood = 0
+	for i := 0; i < 1024; i++ {
+		if rt.Push(newTestSubnetPeer(rng, "203.0.113", i%250)) != Dropped {
+			nFlood++
+		}
+	}
+	assert.True(t, nFlood > int(DefaultMaxSubnetBucketPeers)*rt.NumBuckets())
+}
+
+func TestTable_Push_banned(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 0) // empty
+	bl := comm.NewBlacklister(comm.NewDefaultBlacklistParameters(), nil)
+	rt = WithBlacklister(rt, bl)
+
+	// banned peer ID is dropped
+	p1 := peer.NewTestPeer(rng, 1)
+	bl.Ban(p1.ID(), comm.Permanent)
+	assert.Equal(t, Dropped, rt.Push(p1))
+	_, in := rt.Get(p1.ID())
+	assert.False(t, in)
+
+	// peer with address in banned IP range is dropped
+	_, ipNet, err := net.ParseCIDR("127.0.0.0/8")
+	assert.Nil(t, err)
+	bl.BanIPNet(ipNet, comm.Permanent)
+	p2 := peer.NewTestPeer(rng, 2)
+	assert.Equal(t, Dropped, rt.Push(p2))
+	assert.Equal(t, 0, rt.NumPeers())
+}
+
+func TestTable_Push_puzzle(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.PuzzleDifficulty = 4
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	rt := NewEmpty(id.NewPseudoRandom(rng), p, d, params)
+
+	// peers learned of however, e.g., from Find responses, must solve the puzzle
+	unsolved := peer.New(id.FromInt64(0), "", peer.NewTestPublicAddr(1))
+	for ecid.SolvesPuzzle(unsolved.ID(), params.PuzzleDifficulty) {
+		unsolved = peer.New(id.NewPseudoRandom(rng), "", peer.NewTestPublicAddr(1))
+	}
+	assert.Equal(t, Dropped, rt.Push(unsolved))
+	assert.Equal(t, 0, rt.NumPeers())
+
+	solved := ecid.NewPseudoRandomSolving(rng, params.PuzzleDifficulty)
+	assert.Equal(t, Added, rt.Push(peer.New(solved.ID(), "", peer.NewTestPublicAddr(2))))
+	assert.Equal(t, 1, rt.NumPeers())
+}
+
+func TestTable_Evict(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, nAdded, _ := NewTestWithPeers(rng, 128)
+	p1 := rt.Sample(1, rng)[0]
+
+	assert.True(t, rt.Evict(p1.ID()))
+	_, exists := rt.Get(p1.ID())
+	assert.False(t, exists)
+	assert.Equal(t, nAdded-1, rt.NumPeers())
+	b := rt.(*table).buckets[rt.(*table).bucketIndex(p1.ID())]
+	_, in := b.positions[p1.ID().String()]
+	assert.False(t, in)
+	assert.Equal(t, len(b.positions), b.Len())
+
+	// evicting again is a no-op
+	assert.False(t, rt.Evict(p1.ID()))
+	assert.Equal(t, nAdded-1, rt.NumPeers())
+
+	// peer can be re-added after eviction
+	assert.Equal(t, Added, rt.Push(p1))
+}
+
+func TestNewKnower(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	k := NewKnower(rt)
+	p1 := rt.Sample(1, rng)[0]
+
+	assert.True(t, k.Know(p1.ID()))
+	assert.False(t, k.Know(id.NewPseudoRandom(rng)))
+
+	rt.Evict(p1.ID())
+	assert.False(t, k.Know(p1.ID()))
+}
+
+func TestTable_Evict_sibling(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := NewTestWithPeers(rng, 128)
+	p1 := rt.Siblings()[0]
+
+	assert.True(t, rt.Evict(p1.ID()))
+	for _, p := range rt.Siblings() {
+		assert.NotEqual(t, p1.ID(), p.ID())
+	}
+}
+
+func TestTable_Siblings(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, n := range []int{0, 8, 128, 1024} {
+		rt, peerID, _, _ := NewTestWithPeers(rng, n)
+		siblings := rt.Siblings()
+		assert.True(t, len(siblings) <= int(DefaultNSiblings))
+		if n >= int(DefaultNSiblings) {
+			assert.Equal(t, int(DefaultNSiblings), len(siblings))
+		}
+
+		// siblings should be ordered by increasing distance from self
+		for i := 1; i < len(siblings); i++ {
+			d1 := peerID.ID().Distance(siblings[i-1].ID())
+			d2 := peerID.ID().Distance(siblings[i].ID())
+			assert.True(t, d1.Cmp(d2) < 0)
+		}
+	}
+}
+
+func TestTable_Siblings_dropped(t *testing.T) {
+	// with small buckets, many peers are dropped from the buckets but siblings should still be
+	// the closest peers pushed
+	rng := rand.New(rand.NewSource(0))
+	selfID := id.NewPseudoRandom(rng)
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	params := NewDefaultParameters()
+	params.MaxBucketPeers = 2
+	params.NSiblings = 8
+	ps := peer.NewTestPeers(rng, 256)
+	rt, _ := NewWithPeers(selfID, p, d, params, ps)
+
+	sortByDistance(ps, selfID)
+	assert.Equal(t, ps[:params.NSiblings], rt.Siblings())
+}
+
+func TestTable_AmongClosest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := NewTestWithPeers(rng, 128)
+
+	// self is always the closest to its own ID
+	assert.True(t, rt.AmongClosest(peerID.ID(), 1))
+
+	// self is never the single closest to another known peer's ID
+	closest := rt.Siblings()[0]
+	assert.False(t, rt.AmongClosest(closest.ID(), 1))
+
+	// for random keys, self should be among the closest for only some of them
+	nAmong := 0
+	for i := 0; i < 64; i++ {
+		if rt.AmongClosest(id.NewPseudoRandom(rng), 8) {
+			nAmong++
+		}
+	}
+	assert.True(t, nAmong < 64)
+}
+
 func TestTable_Find(t *testing.T) {
 
 	// make sure we support popping 0 peers
@@ -412,26 +719,184 @@ func TestTable_splitBucket(t *testing.T) {
 	}
 }
 
-func TestSplitLowerBound_Ok(t *testing.T) {
-	check := func(lowerBound id.ID, depth uint, expected id.ID) {
-		actual := splitLowerBound(lowerBound, depth)
-		assert.Equal(t, expected, actual)
+func TestTable_splitBucket_bucketPeersByDepth(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.BucketPeersByDepth = func(depth uint) uint {
+		return DefaultMaxActivePeers / (depth + 1)
+	}
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	rt := NewEmpty(id.NewPseudoRandom(rng), p, d, params).(*table)
+	assert.Equal(t, DefaultMaxActivePeers, rt.buckets[0].maxActivePeers)
+	for _, p := range peer.NewTestPeers(rng, int(DefaultMaxActivePeers)) {
+		rt.Push(p)
+	}
+
+	// new buckets should be sized for their depth, dropping peers beyond that size
+	rt.splitBucket(0)
+	for _, b := range rt.buckets {
+		assert.Equal(t, DefaultMaxActivePeers/2, b.maxActivePeers)
+		assert.True(t, b.Len() <= int(b.maxActivePeers))
+	}
+	checkTableConsistent(t, rt, len(rt.peers))
+
+	// pushes honor the depth-specific sizes too
+	for _, p := range peer.NewTestPeers(rng, 256) {
+		rt.Push(p)
+	}
+	for _, b := range rt.buckets {
+		assert.Equal(t, params.bucketPeers(b.depth), b.maxActivePeers)
+		assert.True(t, b.Len() <= int(b.maxActivePeers))
 	}
+	checkTableConsistent(t, rt, len(rt.peers))
+}
+
+func TestParameters_Validate(t *testing.T) {
+	assert.Nil(t, NewDefaultParameters().Validate())
+
+	// zero intervals disable checkpoints and compaction
+	p := NewDefaultParameters()
+	p.CheckpointInterval, p.CompactInterval = 0, 0
+	assert.Nil(t, p.Validate())
+
+	p.CheckpointInterval = -time.Second
+	assert.Equal(t, ErrNegativeCheckpointInterval, p.Validate())
 
-	check(id.FromInt64(0), 0, newIDLsh(1, 255))                    // no prefix
-	check(newIDLsh(128, 248), 1, newIDLsh(192, 248))               // prefix 1
-	check(newIDLsh(1, 254), 2, newIDLsh(3, 253))                   // prefix 01
-	check(newIDLsh(3, 254), 2, newIDLsh(7, 253))                   // prefix 11
-	check(newIDLsh(170, 248), 7, newIDLsh(171, 248))               // prefix 1010101
-	check(newIDLsh(128, 248), 7, newIDLsh(129, 248))               // prefix 1000000
-	check(newIDLsh(254, 248), 7, newIDLsh(255, 248))               // prefix 1111111
-	check(newIDLsh(0, 248), 8, newIDLsh(128, 240))                 // prefix 00000000
-	check(newIDLsh(128, 248), 8, newIDLsh(128<<8|128, 240))        // prefix 10000000
-	check(newIDLsh(255, 248), 8, newIDLsh(255<<8|128, 240))        // prefix 11111111
-	check(newIDLsh(0, 240), 9, newIDLsh(64, 240))                  // prefix 00000000 0
-	check(newIDLsh(128, 240), 9, newIDLsh(192, 240))               // prefix 00000000 1
-	check(newIDLsh(255, 248), 9, newIDLsh(255<<8|64, 240))         // prefix 11111111 0
-	check(newIDLsh(255<<8|128, 240), 9, newIDLsh(255<<8|192, 240)) // prefix 11111111 1
+	p = NewDefaultParameters()
+	p.CompactInterval = -time.Second
+	assert.Equal(t, ErrNegativeCompactInterval, p.Validate())
+
+	p = NewDefaultParameters()
+	p.KeyspaceWidth = id.Length + 1
+	assert.NotNil(t, p.Validate())
+
+	p = NewDefaultParameters()
+	p.PuzzleDifficulty = ecid.MaxPuzzleDifficulty + 1
+	assert.Equal(t, ecid.ErrPuzzleTooDifficult, p.Validate())
+}
+
+func TestParameters_bucketPeers(t *testing.T) {
+	params := &Parameters{MaxBucketPeers: 8}
+	assert.Equal(t, uint(8), params.bucketPeers(3))
+
+	params.BucketPeersByDepth = func(depth uint) uint { return depth }
+	assert.Equal(t, uint(8), params.bucketPeers(0)) // zero falls back to MaxBucketPeers
+	assert.Equal(t, uint(3), params.bucketPeers(3))
+}
+
+func TestTable_Push_maxDepth(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.MaxBucketPeers = 4
+	params.MaxDepth = 6
+	params.MaxDepthBucketPeers = 16
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	selfID := id.NewPseudoRandom(rng)
+	rt := NewEmpty(selfID, p, d, params).(*table)
+
+	// adversarial peers all sharing a long prefix with self would otherwise keep splitting the
+	// self-containing bucket
+	for i := 0; i < 64; i++ {
+		idBytes := selfID.Bytes()
+		idBytes[len(idBytes)-1] ^= byte(i + 1)
+		rt.Push(peer.New(id.FromBytes(idBytes), "", peer.NewTestPublicAddr(i)))
+	}
+	for _, b := range rt.buckets {
+		assert.True(t, b.depth <= params.MaxDepth)
+		assert.True(t, b.Len() <= int(b.maxActivePeers))
+		if b.containsSelf {
+			assert.Equal(t, params.MaxDepth, b.depth)
+			assert.Equal(t, params.MaxDepthBucketPeers, b.maxActivePeers)
+			assert.Equal(t, int(params.MaxDepthBucketPeers), b.Len())
+		}
+	}
+	assert.Equal(t, int(params.MaxDepth)+1, rt.NumBuckets())
+	checkTableConsistent(t, rt, len(rt.peers))
+}
+
+func TestTable_Push_keyspaceWidth(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.KeyspaceWidth = 1
+	params.MaxDepth = 0
+	params.MaxBucketPeers = 2
+	params.MaxDepthBucketPeers = 0
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+	selfID := id.NewPseudoRandomOfWidth(rng, params.KeyspaceWidth)
+	rt := NewEmpty(selfID, p, d, params).(*table)
+
+	// fill the 1-byte keyspace, which can only split 7 times
+	for i := 0; i < 256; i++ {
+		rt.Push(peer.New(id.FromInt64(int64(i)), "", peer.NewTestPublicAddr(i)))
+	}
+	assert.Equal(t, id.UpperBoundOf(params.KeyspaceWidth), rt.buckets[len(rt.buckets)-1].upperBound)
+	for _, b := range rt.buckets {
+		assert.True(t, b.depth <= params.keyspaceBits()-1)
+		assert.True(t, id.InKeyspace(b.lowerBound, params.KeyspaceWidth))
+	}
+	assert.Equal(t, int(params.keyspaceBits()), rt.NumBuckets())
+	checkTableConsistent(t, rt, len(rt.peers))
+
+	// peers outside the keyspace (including its exclusive upper bound) are dropped
+	for _, i := range []int{255, 256} {
+		outside := peer.New(id.FromInt64(int64(i)), "", peer.NewTestPublicAddr(i))
+		assert.Equal(t, Dropped, rt.Push(outside))
+		_, in := rt.Get(outside.ID())
+		assert.False(t, in)
+	}
+}
+
+func TestNewEmpty_keyspaceWidthErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p, d := &fixedPreferer{}, &fixedDoctor{healthy: true}
+
+	params := NewDefaultParameters()
+	params.KeyspaceWidth = id.Length + 1
+	assert.Panics(t, func() { NewEmpty(id.NewPseudoRandom(rng), p, d, params) })
+
+	// self ID outside the keyspace
+	params.KeyspaceWidth = 2
+	assert.Panics(t, func() { NewEmpty(id.FromInt64(1<<16-1), p, d, params) })
+
+	// zero width means full ID length
+	params.KeyspaceWidth = 0
+	rt := NewEmpty(id.NewPseudoRandom(rng), p, d, params).(*table)
+	assert.Equal(t, id.UpperBound, rt.buckets[0].upperBound)
+}
+
+func TestParameters_maxActivePeers(t *testing.T) {
+	params := &Parameters{MaxBucketPeers: 8, MaxDepth: 4, MaxDepthBucketPeers: 32}
+	assert.Equal(t, uint(8), params.maxActivePeers(3, true))
+	assert.Equal(t, uint(8), params.maxActivePeers(4, false))
+	assert.Equal(t, uint(32), params.maxActivePeers(4, true))
+
+	// zero MaxDepthBucketPeers falls back to usual max
+	params.MaxDepthBucketPeers = 0
+	assert.Equal(t, uint(8), params.maxActivePeers(4, true))
+
+	// zero MaxDepth is only limited by ID length
+	params.MaxDepth = 0
+	assert.False(t, params.atMaxDepth(100))
+	assert.True(t, params.atMaxDepth(id.Length*8-1))
+
+	// and the keyspace width
+	params.KeyspaceWidth = 1
+	assert.False(t, params.atMaxDepth(6))
+	assert.True(t, params.atMaxDepth(7))
+}
+
+func TestLinearBucketPeersByDepth(t *testing.T) {
+	f := LinearBucketPeersByDepth(4, 20, 8)
+	assert.Equal(t, uint(4), f(0))
+	assert.Equal(t, uint(12), f(4))
+	assert.Equal(t, uint(20), f(8))
+	assert.Equal(t, uint(20), f(100))
+
+	// can also shrink with depth
+	f = LinearBucketPeersByDepth(20, 4, 8)
+	assert.Equal(t, uint(20), f(0))
+	assert.Equal(t, uint(12), f(4))
+	assert.Equal(t, uint(4), f(9))
 }
 
 func newSimpleTable() *table {
@@ -479,7 +944,7 @@ func checkTableConsistent(t *testing.T, rt Table, nExpectedPeers int) {
 			// bucket boundaries should be adjacent
 			prev := rt.(*table).buckets[i-1]
 			assert.Equal(t, cur.lowerBound, prev.upperBound)
-			assert.Equal(t, idCumMass, cur.idCumMass)
+			assert.InDelta(t, idCumMass, cur.idCumMass, 1e-9)
 			assert.True(t, cur.idCumMass > prev.idCumMass)
 		}
 		if cur.containsSelf {
@@ -488,14 +953,26 @@ func checkTableConsistent(t *testing.T, rt Table, nExpectedPeers int) {
 		checkBucketConsistent(t, cur, rt.SelfID())
 		nPeers += cur.Len()
 
+		// bucket peers should be those in the peers map
+		for _, p := range cur.activePeers {
+			q, in := rt.(*table).peers[p.ID().String()]
+			assert.True(t, in)
+			assert.Equal(t, p, q)
+		}
+
 	}
-	assert.Equal(t, 1.0, idCumMass)
+	assert.InDelta(t, 1.0, idCumMass, 1e-9)
 	assert.Equal(t, nExpectedPeers, nPeers)
 	assert.Equal(t, 1, nContainSelf)
 }
 
 func checkBucketConsistent(t *testing.T, b *bucket, selfID id.ID) {
 	assert.Equal(t, b.containsSelf, b.Contains(selfID))
+	assert.True(t, b.Len() <= int(b.maxActivePeers))
+	assert.Equal(t, len(b.activePeers), len(b.positions))
+	for i, p := range b.activePeers {
+		assert.Equal(t, i, b.positions[p.ID().String()])
+	}
 	for p := range b.activePeers {
 		pID := b.activePeers[p].ID()
 		assert.True(t, pID.Cmp(b.lowerBound) >= 0)
@@ -503,10 +980,6 @@ func checkBucketConsistent(t *testing.T, b *bucket, selfID id.ID) {
 	}
 }
 
-func newIDLsh(x int64, n uint) id.ID {
-	return id.FromInt(new(big.Int).Lsh(big.NewInt(x), n))
-}
-
 func checkPoppedPeers(t *testing.T, k uint, numActivePeers int, ps []peer.Peer, info string) {
 	if k < uint(numActivePeers) {
 		// should get k peers
@@ -529,3 +1002,34 @@ func checkPoppedPeers(t *testing.T, k uint, numActivePeers int, ps []peer.Peer,
 		seen[p.ID().String()] = struct{}{}
 	}
 }
+
+func TestTable_Push_addressPolicy(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	newAddr := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 20100}
+
+	// with ConfirmAddresses, pushing a known peer with a new address shouldn't change it
+	rt, _, _, _ := NewTestWithPeers(rng, 8)
+	known := rt.Find(rt.SelfID(), 1)[0]
+	prevAddr := known.Address()
+	assert.Equal(t, Existed, rt.Push(peer.New(known.ID(), "new name", newAddr)))
+	assert.Equal(t, prevAddr, known.Address())
+
+	// but UpdateAddresses should
+	assert.True(t, rt.UpdateAddresses(peer.New(known.ID(), "", newAddr)))
+	assert.Equal(t, newAddr.String(), known.Address().String())
+	assert.False(t, rt.UpdateAddresses(peer.NewTestPeer(rng, 100)))
+
+	// with AcceptAddresses, pushing a known peer with a new address should change it
+	rt, _, _, _ = NewTestWithPeers(rng, 8)
+	rt.(*table).params.AddressPolicy = AcceptAddresses
+	known = rt.Find(rt.SelfID(), 1)[0]
+	assert.Equal(t, Existed, rt.Push(peer.New(known.ID(), "", newAddr)))
+	assert.Equal(t, newAddr.String(), known.Address().String())
+
+	// unset policy confirms addresses
+	assert.Equal(t, ConfirmAddresses, (&Parameters{}).AddressPolicy)
+}
+
+func newTestReputationJudge() comm.ReputationJudge {
+	return comm.NewReputationJudge(&storage.TestSLD{}, comm.NewDefaultReputationParameters())
+}
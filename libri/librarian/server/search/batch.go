@@ -0,0 +1,114 @@
+package search
+
+import (
+	"sync"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"golang.org/x/net/context"
+)
+
+// DefaultMaxBatchQueries is the default maximum number of concurrent queries across all the
+// searches in a batch.
+const DefaultMaxBatchQueries = uint(16)
+
+// BatchSearch contains the searches for many keys, e.g., the pages of an entry, that share their
+// seed peers and a budget of concurrent queries.
+type BatchSearch struct {
+	// Keys being searched for
+	Keys []id.ID
+
+	// Searches for each key, in the same order as Keys
+	Searches []*Search
+}
+
+// NewBatchSearch creates a new BatchSearch for the given keys, each searched with the given
+// parameters, with at most maxQueries concurrent queries across all of the searches. A zero
+// maxQueries means the searches are only bounded by their own concurrency.
+func NewBatchSearch(
+	peerID, orgID ecid.ID, keys []id.ID, params *Parameters, maxQueries uint,
+) *BatchSearch {
+	budget := newQuerySlots(maxQueries)
+	searches := make([]*Search, len(keys))
+	for i, key := range keys {
+		searches[i] = NewSearch(peerID, orgID, key, params)
+		searches[i].budget = budget
+	}
+	return &BatchSearch{
+		Keys:     keys,
+		Searches: searches,
+	}
+}
+
+// Release releases the results of each search in the batch.
+func (b *BatchSearch) Release() {
+	for _, s := range b.Searches {
+		s.Release()
+	}
+}
+
+// BatchSearcher executes batches of searches.
+type BatchSearcher interface {
+	// SearchBatch concurrently executes each search in the batch from the same list of seeds,
+	// returning the fatal error (or nil) of each search in the same order as the batch's keys.
+	SearchBatch(ctx context.Context, batch *BatchSearch, seeds []peer.Peer) []error
+}
+
+type batchSearcher struct {
+	searcher Searcher
+}
+
+// NewBatchSearcher returns a BatchSearcher that executes each search in a batch with the given
+// Searcher, so they share its connection pool.
+func NewBatchSearcher(s Searcher) BatchSearcher {
+	return &batchSearcher{searcher: s}
+}
+
+func (bs *batchSearcher) SearchBatch(
+	ctx context.Context, batch *BatchSearch, seeds []peer.Peer,
+) []error {
+	errs := make([]error, len(batch.Searches))
+	var wg sync.WaitGroup
+	for i, s := range batch.Searches {
+		wg.Add(1)
+		go func(i int, s *Search) {
+			defer wg.Done()
+			errs[i] = bs.searcher.Search(ctx, s, seeds)
+		}(i, s)
+	}
+	wg.Wait()
+	return errs
+}
+
+// querySlots is a semaphore bounding the number of concurrent queries. A nil querySlots is
+// unbounded.
+type querySlots chan struct{}
+
+func newQuerySlots(n uint) querySlots {
+	if n == 0 {
+		return nil
+	}
+	return make(querySlots, n)
+}
+
+// acquire waits for a free slot, returning the context error if the context ends first.
+func (qs querySlots) acquire(ctx context.Context) error {
+	if qs == nil {
+		return nil
+	}
+	select {
+	case qs <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire.
+func (qs querySlots) release() {
+	if qs == nil {
+		return
+	}
+	<-qs
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestNewBatchSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	keys := []id.ID{id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)}
+
+	b := NewBatchSearch(peerID, orgID, keys, NewDefaultParameters(), 4)
+	assert.Len(t, b.Searches, len(keys))
+	for i, s := range b.Searches {
+		assert.Equal(t, keys[i], s.Key)
+		assert.Equal(t, 4, cap(s.budget))
+	}
+	assert.Equal(t, b.Searches[0].budget, b.Searches[1].budget)
+
+	b = NewBatchSearch(peerID, orgID, keys, NewDefaultParameters(), 0)
+	assert.Nil(t, b.Searches[0].budget)
+	b.Release()
+}
+
+func TestBatchSearcher_SearchBatch(t *testing.T) {
+	n, nKeys := 32, 8
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	orgID := ecid.NewPseudoRandom(rng)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	bs := NewBatchSearcher(NewTestSearcher(peersMap, addressFinders, rec))
+
+	keys := make([]id.ID, nKeys)
+	for i := range keys {
+		keys[i] = id.NewPseudoRandom(rng)
+	}
+	params := &Parameters{
+		NClosestResponses: 6,
+		NMaxErrors:        DefaultNMaxErrors,
+		Concurrency:       3,
+		Timeout:           DefaultQueryTimeout,
+	}
+	batch := NewBatchSearch(peerID, orgID, keys, params, 2)
+	seeds := NewTestSeeds(peers, selfPeerIdxs)
+
+	errs := bs.SearchBatch(context.Background(), batch, seeds)
+	assert.Len(t, errs, nKeys)
+	for i, s := range batch.Searches {
+		assert.Nil(t, errs[i])
+		assert.True(t, s.FoundClosestPeers())
+		assert.Zero(t, len(s.Result.Errored))
+	}
+	assert.Zero(t, len(batch.Searches[0].budget))
+}
+
+func TestQuerySlots(t *testing.T) {
+	qs := newQuerySlots(1)
+	assert.Nil(t, qs.acquire(context.Background()))
+
+	// no free slots, so acquiring waits until context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, qs.acquire(ctx))
+
+	qs.release()
+	assert.Nil(t, qs.acquire(context.Background()))
+	qs.release()
+
+	// nil slots are unbounded
+	var unbounded querySlots
+	for c := 0; c < 3; c++ {
+		assert.Nil(t, unbounded.acquire(context.Background()))
+	}
+	unbounded.release()
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"sync"
+	"time"
+)
+
+// concurrencyController adapts the number of in-flight queries of a search in an
+// additive-increase, multiplicative-decrease fashion. Queries responding within the target
+// round-trip time increase the concurrency by one, while query errors (most often timeouts)
+// halve it. The concurrency always stays within [min, max].
+type concurrencyController struct {
+	mu        sync.Mutex
+	min       uint
+	max       uint
+	targetRTT time.Duration
+	current   uint
+	inFlight  uint
+}
+
+// newConcurrencyController creates a new concurrencyController for the given search parameters.
+// Each query's target RTT is the latency budget spread across the NClosestResponses sequential
+// queries a search would need at a concurrency of one.
+func newConcurrencyController(params *Parameters) *concurrencyController {
+	min, max := params.Concurrency, params.maxConcurrency()
+	if min == 0 {
+		min = 1
+	}
+	var targetRTT time.Duration
+	if params.NClosestResponses > 0 {
+		targetRTT = params.LatencyBudget / time.Duration(params.NClosestResponses)
+	}
+	return &concurrencyController{
+		min:       min,
+		max:       max,
+		targetRTT: targetRTT,
+		current:   min,
+	}
+}
+
+// Concurrency returns the current number of allowed in-flight queries.
+func (cc *concurrencyController) Concurrency() uint {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.current
+}
+
+// sent records that a query has been queued.
+func (cc *concurrencyController) sent() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.inFlight++
+}
+
+// received records a query's response (or error), adapts the concurrency accordingly, and
+// returns the number of additional queries to queue to reach it.
+func (cc *concurrencyController) received(rtt time.Duration, err error) uint {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.inFlight > 0 {
+		cc.inFlight--
+	}
+	if err != nil {
+		cc.current /= 2
+		if cc.current < cc.min {
+			cc.current = cc.min
+		}
+	} else if rtt <= cc.targetRTT && cc.current < cc.max {
+		cc.current++
+	}
+	if cc.inFlight >= cc.current {
+		return 0
+	}
+	return cc.current - cc.inFlight
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+)
+
+func TestNewConcurrencyController(t *testing.T) {
+	params := &Parameters{
+		NClosestResponses: 4,
+		Concurrency:       2,
+		MaxConcurrency:    8,
+		LatencyBudget:     2 * time.Second,
+	}
+	cc := newConcurrencyController(params)
+	assert.Equal(t, uint(2), cc.min)
+	assert.Equal(t, uint(8), cc.max)
+	assert.Equal(t, 500*time.Millisecond, cc.targetRTT)
+	assert.Equal(t, uint(2), cc.Concurrency())
+
+	// zero concurrency still allows one query
+	params.Concurrency = 0
+	cc = newConcurrencyController(params)
+	assert.Equal(t, uint(1), cc.Concurrency())
+}
+
+func TestConcurrencyController_received(t *testing.T) {
+	cc := newConcurrencyController(&Parameters{
+		NClosestResponses: 1,
+		Concurrency:       1,
+		MaxConcurrency:    4,
+		LatencyBudget:     time.Second,
+	})
+	cc.sent()
+
+	// fast responses increase concurrency up to max
+	assert.Equal(t, uint(2), cc.received(100*time.Millisecond, nil))
+	assert.Equal(t, uint(2), cc.Concurrency())
+	cc.sent()
+	cc.sent()
+	assert.Equal(t, uint(2), cc.received(100*time.Millisecond, nil))
+	assert.Equal(t, uint(3), cc.Concurrency())
+	cc.sent()
+	cc.sent()
+	assert.Equal(t, uint(2), cc.received(100*time.Millisecond, nil))
+	assert.Equal(t, uint(4), cc.Concurrency())
+	cc.sent()
+	cc.sent()
+	assert.Equal(t, uint(1), cc.received(100*time.Millisecond, nil))
+	assert.Equal(t, uint(4), cc.Concurrency())
+	cc.sent()
+
+	// slow responses keep concurrency
+	assert.Equal(t, uint(1), cc.received(2*time.Second, nil))
+	assert.Equal(t, uint(4), cc.Concurrency())
+	cc.sent()
+
+	// errors halve concurrency down to min, queueing nothing while at or above it
+	assert.Equal(t, uint(0), cc.received(time.Second, errors.New("some timeout")))
+	assert.Equal(t, uint(2), cc.Concurrency())
+	assert.Equal(t, uint(0), cc.received(time.Second, errors.New("some timeout")))
+	assert.Equal(t, uint(1), cc.Concurrency())
+	assert.Equal(t, uint(0), cc.received(time.Second, errors.New("some timeout")))
+	assert.Equal(t, uint(1), cc.Concurrency())
+	assert.Equal(t, uint(1), cc.received(time.Second, errors.New("some timeout")))
+	assert.Equal(t, uint(1), cc.Concurrency())
+}
+
+func TestParameters_Adaptive(t *testing.T) {
+	params := NewDefaultParameters()
+	assert.False(t, params.Adaptive())
+	assert.Equal(t, params.Concurrency, params.maxConcurrency())
+
+	params.MaxConcurrency = params.Concurrency + 4
+	assert.True(t, params.Adaptive())
+	assert.Equal(t, params.MaxConcurrency, params.maxConcurrency())
+}
+
+func TestSearcher_Search_adaptive(t *testing.T) {
+	n, nClosestResponses := 32, uint(6)
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	orgID := ecid.NewPseudoRandom(rng)
+	key := id.NewPseudoRandom(rng)
+
+	for maxConcurrency := uint(2); maxConcurrency <= 6; maxConcurrency += 2 {
+		info := fmt.Sprintf("maxConcurrency: %d", maxConcurrency)
+		rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+		searcher := NewTestSearcher(peersMap, addressFinders, rec)
+		search := NewSearch(peerID, orgID, key, &Parameters{
+			NClosestResponses: nClosestResponses,
+			NMaxErrors:        DefaultNMaxErrors,
+			Concurrency:       1,
+			Timeout:           DefaultQueryTimeout,
+			MaxConcurrency:    maxConcurrency,
+			LatencyBudget:     DefaultLatencyBudget,
+		})
+		seeds := NewTestSeeds(peers, selfPeerIdxs)
+
+		err := searcher.Search(context.Background(), search, seeds)
+		assert.Nil(t, err, info)
+		assert.True(t, search.FoundClosestPeers(), info)
+		assert.False(t, search.Errored(), info)
+		assert.Equal(t, int(nClosestResponses), search.Result.Closest.Len(), info)
+
+		// test peers respond quickly, so concurrency should have grown
+		assert.NotNil(t, search.cc, info)
+		assert.True(t, search.cc.Concurrency() > 1, info)
+		assert.True(t, search.cc.Concurrency() <= maxConcurrency, info)
+
+		oe := zapcore.NewMapObjectEncoder()
+		assert.Nil(t, search.MarshalLogObject(oe))
+		assert.Equal(t, search.cc.Concurrency(), oe.Fields[logChosenConcurrency])
+	}
+}
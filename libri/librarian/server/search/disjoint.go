@@ -0,0 +1,144 @@
+package search
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"golang.org/x/net/context"
+)
+
+// pathClaims tracks which disjoint path (if any) each peer belongs to, ensuring no two paths
+// query the same peer.
+type pathClaims struct {
+	mu    sync.Mutex
+	paths map[string]int
+}
+
+func newPathClaims() *pathClaims {
+	return &pathClaims{paths: make(map[string]int)}
+}
+
+// claim claims the peer for the given path, returning false if another path already claimed it.
+func (c *pathClaims) claim(peerID id.ID, path int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	claimant, claimed := c.paths[peerID.String()]
+	if !claimed {
+		c.paths[peerID.String()] = path
+		return true
+	}
+	return claimant == path
+}
+
+// searchDisjoint runs Parameters.NDisjointPaths concurrent (S/Kademlia-style) searches that never
+// query the same peers and merges their results into the given search.
+func (s *searcher) searchDisjoint(ctx context.Context, search *Search, seeds []peer.Peer) error {
+	nPaths := int(search.Params.NDisjointPaths)
+	pathParams := *search.Params
+	pathParams.NDisjointPaths = 1
+	claims := newPathClaims()
+	pathSeeds := splitSeeds(search.Key, s.unbanned(seeds), nPaths, claims)
+
+	paths := make([]*Search, nPaths)
+	var wg sync.WaitGroup
+	for i := range paths {
+		paths[i] = &Search{
+			Key:      search.Key,
+			CreatRq:  search.CreatRq,
+			Result:   NewInitialResult(search.Key, &pathParams),
+			Params:   &pathParams,
+			Observer: search.Observer,
+			path:     i,
+			claims:   claims,
+			budget:   search.budget,
+		}
+		if len(pathSeeds[i]) == 0 {
+			// fewer seeds than paths
+			continue
+		}
+		wg.Add(1)
+		go func(path *Search, seeds []peer.Peer) {
+			defer wg.Done()
+			_ = s.search(ctx, path, seeds) // fatal errors are in each path's result
+		}(paths[i], pathSeeds[i])
+	}
+	wg.Wait()
+
+	search.wrapLock(func() { mergePaths(search.Result, paths, search.Params) })
+	return search.Result.FatalErr
+}
+
+// splitSeeds splits the seeds into nPaths groups, claimed by their respective paths, dealing them
+// in order of increasing distance to the key so each path starts from some close peers.
+func splitSeeds(key id.ID, seeds []peer.Peer, nPaths int, claims *pathClaims) [][]peer.Peer {
+	sorted := make([]peer.Peer, len(seeds))
+	copy(sorted, seeds)
+	sort.Slice(sorted, func(i, j int) bool {
+		return id.CmpDistance(key, sorted[i].ID(), sorted[j].ID()) < 0
+	})
+	pathSeeds := make([][]peer.Peer, nPaths)
+	next := 0
+	for _, seed := range sorted {
+		if !claims.claim(seed.ID(), next) {
+			// duplicate seed
+			continue
+		}
+		pathSeeds[next] = append(pathSeeds[next], seed)
+		next = (next + 1) % nPaths
+	}
+	return pathSeeds
+}
+
+// mergePaths merges the results of the disjoint paths into r. The merged search only fails if
+// every path did without finding the value, timing out (or being canceled) if any of the paths
+// did.
+func mergePaths(r *Result, paths []*Search, params *Parameters) {
+	r.Paths = make([]*Result, len(paths))
+	nFailed := 0
+	var interrupted error
+	for i, path := range paths {
+		pr := path.Result
+		r.Paths[i] = pr
+		if r.Value == nil {
+			r.Value, r.ExpireTime = pr.Value, pr.ExpireTime
+		}
+		r.Closest.SafePushMany(pr.Closest.Peers())
+		for peerIDStr := range pr.Queried {
+			r.Queried[peerIDStr] = struct{}{}
+		}
+		for peerIDStr, p := range pr.Responded {
+			if params.MaxResponded == 0 || uint(len(r.Responded)) < params.MaxResponded {
+				r.Responded[peerIDStr] = p
+			}
+		}
+		for _, p := range pr.Missing {
+			r.addMissing(p, params.MaxResponded)
+		}
+		if pr.FatalErr != nil {
+			nFailed++
+		}
+		if pr.FatalErr == ErrSearchTimeout || pr.FatalErr == ErrSearchCanceled {
+			interrupted = pr.FatalErr
+		}
+	}
+
+	// only keep errors beyond NMaxErrors, which make the merged search errored, if every path
+	// failed
+	maxErrors := params.NMaxErrors
+	if nFailed == len(paths) && r.Value == nil {
+		maxErrors++
+		r.FatalErr = ErrTooManyFindErrors
+		if interrupted != nil {
+			r.FatalErr = interrupted
+		}
+	}
+	for _, pr := range r.Paths {
+		for peerIDStr, err := range pr.Errored {
+			if uint(len(r.Errored)) < maxErrors {
+				r.Errored[peerIDStr] = err
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestPathClaims_claim(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID1, peerID2 := id.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
+	c := newPathClaims()
+
+	assert.True(t, c.claim(peerID1, 0))
+	assert.True(t, c.claim(peerID1, 0)) // re-claiming by same path is fine
+	assert.False(t, c.claim(peerID1, 1))
+	assert.True(t, c.claim(peerID2, 1))
+	assert.False(t, c.claim(peerID2, 0))
+}
+
+func TestSplitSeeds(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	seeds := peer.NewTestPeers(rng, 10)
+	seeds = append(seeds, seeds[0], seeds[3]) // duplicates should be ignored
+	nPaths := 3
+	claims := newPathClaims()
+
+	pathSeeds := splitSeeds(key, seeds, nPaths, claims)
+	assert.Len(t, pathSeeds, nPaths)
+	assert.Len(t, pathSeeds[0], 4)
+	assert.Len(t, pathSeeds[1], 3)
+	assert.Len(t, pathSeeds[2], 3)
+
+	// each path starts with one of the closest nPaths seeds, and no seed is in two paths
+	sorted := make([]peer.Peer, 10)
+	copy(sorted, seeds[:10])
+	sort.Slice(sorted, func(i, j int) bool {
+		return id.CmpDistance(key, sorted[i].ID(), sorted[j].ID()) < 0
+	})
+	for i, ps := range pathSeeds {
+		assert.Equal(t, sorted[i].ID(), ps[0].ID())
+		for j, p := range ps {
+			assert.False(t, claims.claim(p.ID(), (i+1)%nPaths))
+			if j > 0 {
+				assert.True(t, key.Distance(ps[j-1].ID()).Cmp(key.Distance(p.ID())) < 0)
+			}
+		}
+	}
+
+	// fewer seeds than paths
+	pathSeeds = splitSeeds(key, seeds[:2], nPaths, newPathClaims())
+	assert.Len(t, pathSeeds[0], 1)
+	assert.Len(t, pathSeeds[1], 1)
+	assert.Len(t, pathSeeds[2], 0)
+}
+
+func TestMergePaths(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	params := NewDefaultParameters()
+	params.NClosestResponses = 4
+	params.NDisjointPaths = 2
+	ps := peer.NewTestPeers(rng, 8)
+
+	newPaths := func() []*Search {
+		paths := make([]*Search, params.NDisjointPaths)
+		for i := range paths {
+			paths[i] = &Search{Key: key, Result: NewInitialResult(key, params), Params: params}
+		}
+		return paths
+	}
+
+	// one path failing doesn't fail the merged result
+	paths := newPaths()
+	for i, p := range ps {
+		pr := paths[i%2].Result
+		pr.Queried[p.ID().String()] = struct{}{}
+		if i%2 == 0 {
+			pr.Closest.SafePush(p)
+			pr.Responded[p.ID().String()] = p
+			pr.addMissing(p, params.MaxResponded)
+		} else {
+			pr.Errored[p.ID().String()] = errors.New("some Find error")
+		}
+	}
+	paths[1].Result.FatalErr = ErrTooManyFindErrors
+	r := NewInitialResult(key, params)
+	mergePaths(r, paths, params)
+	assert.Nil(t, r.FatalErr)
+	assert.Len(t, r.Paths, 2)
+	assert.Equal(t, 4, r.Closest.Len())
+	assert.Len(t, r.Queried, 8)
+	assert.Len(t, r.Responded, 4)
+	assert.Len(t, r.Missing, 4)
+	assert.Equal(t, int(params.NMaxErrors), len(r.Errored))
+	s := &Search{Key: key, Result: r, Params: params}
+	assert.False(t, s.Errored())
+	assert.True(t, s.FoundClosestPeers())
+
+	// all paths failing fails the merged result
+	paths = newPaths()
+	for i, p := range ps {
+		paths[i%2].Result.Errored[p.ID().String()] = errors.New("some Find error")
+	}
+	paths[0].Result.FatalErr = ErrTooManyFindErrors
+	paths[1].Result.FatalErr = ErrTooManyFindErrors
+	r = NewInitialResult(key, params)
+	mergePaths(r, paths, params)
+	assert.Equal(t, ErrTooManyFindErrors, r.FatalErr)
+	assert.Equal(t, int(params.NMaxErrors+1), len(r.Errored))
+
+	// value found on one path
+	paths = newPaths()
+	value, _ := api.NewTestDocument(rng)
+	paths[1].Result.Value = value
+	paths[0].Result.FatalErr = ErrTooManyFindErrors
+	r = NewInitialResult(key, params)
+	mergePaths(r, paths, params)
+	assert.Nil(t, r.FatalErr)
+	assert.Equal(t, value, r.Value)
+
+	// releasing merged result also releases paths
+	r.Release()
+	assert.Nil(t, r.Paths)
+	assert.Nil(t, paths[0].Result.Queried)
+	assert.Nil(t, paths[1].Result.Queried)
+}
+
+func TestSearcher_Search_disjoint(t *testing.T) {
+	n, nClosestResponses := 64, uint(6)
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	orgID := ecid.NewPseudoRandom(rng)
+	key := id.NewPseudoRandom(rng)
+
+	for nPaths := uint(2); nPaths <= 3; nPaths++ {
+		info := fmt.Sprintf("nPaths: %d", nPaths)
+		rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+		searcher := NewTestSearcher(peersMap, addressFinders, rec)
+		search := NewSearch(peerID, orgID, key, &Parameters{
+			NClosestResponses: nClosestResponses,
+			NMaxErrors:        DefaultNMaxErrors,
+			Concurrency:       2,
+			Timeout:           DefaultQueryTimeout,
+			NDisjointPaths:    nPaths,
+		})
+		seeds := NewTestSeeds(peers, selfPeerIdxs)
+
+		err := searcher.Search(context.Background(), search, seeds)
+		assert.Nil(t, err, info)
+		assert.True(t, search.FoundClosestPeers(), info)
+		assert.False(t, search.Errored(), info)
+		assert.Equal(t, int(nClosestResponses), search.Result.Closest.Len(), info)
+		assert.Len(t, search.Result.Paths, int(nPaths), info)
+
+		// no peer is queried by more than one path
+		queriedPath := make(map[string]int)
+		nQueried := 0
+		for i, pr := range search.Result.Paths {
+			for peerIDStr := range pr.Queried {
+				j, in := queriedPath[peerIDStr]
+				assert.False(t, in, fmt.Sprintf("%s, peer queried by paths %d and %d", info,
+					i, j))
+				queriedPath[peerIDStr] = i
+			}
+			nQueried += len(pr.Queried)
+		}
+		assert.Equal(t, nQueried, len(search.Result.Queried), info)
+		search.Release()
+	}
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	outcomeLabel = "outcome"
+
+	foundValueOutcome   = "found_value"
+	foundClosestOutcome = "found_closest_peers"
+	erroredOutcome      = "errored"
+	exhaustedOutcome    = "exhausted"
+	timeoutOutcome      = "timeout"
+	canceledOutcome     = "canceled"
+	errorOutcome        = "error"
+)
+
+// PromSearcher is a Searcher that reports the outcomes and durations of its searches as
+// Prometheus metrics.
+type PromSearcher interface {
+	Searcher
+
+	// Register registers the Prometheus metrics with the default Prometheus registerer.
+	Register()
+
+	// Unregister unregisters the Prometheus metrics from the default Prometheus registerer.
+	Unregister()
+}
+
+type promSearcher struct {
+	inner    Searcher
+	searches *prom.CounterVec
+	duration *prom.HistogramVec
+}
+
+// NewPromSearcher returns a PromSearcher wrapping the given Searcher.
+func NewPromSearcher(inner Searcher) PromSearcher {
+	return &promSearcher{
+		inner: inner,
+		searches: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: promNamespace,
+				Subsystem: promSubsystem,
+				Name:      "searches_total",
+				Help:      "Number of searches, by outcome.",
+			},
+			[]string{outcomeLabel},
+		),
+		duration: prom.NewHistogramVec(
+			prom.HistogramOpts{
+				Namespace: promNamespace,
+				Subsystem: promSubsystem,
+				Name:      "duration_seconds",
+				Help:      "Duration of searches, by outcome.",
+				Buckets:   prom.DefBuckets,
+			},
+			[]string{outcomeLabel},
+		),
+	}
+}
+
+func (s *promSearcher) Search(ctx context.Context, search *Search, seeds []peer.Peer) error {
+	start := time.Now()
+	err := s.inner.Search(ctx, search, seeds)
+	outcome := searchOutcome(search, err)
+	s.searches.WithLabelValues(outcome).Inc()
+	s.duration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (s *promSearcher) Register() {
+	prom.MustRegister(s.searches)
+	prom.MustRegister(s.duration)
+}
+
+func (s *promSearcher) Unregister() {
+	_ = prom.Unregister(s.searches)
+	_ = prom.Unregister(s.duration)
+}
+
+// searchOutcome returns the outcome label of a search that returned the given error.
+func searchOutcome(search *Search, err error) string {
+	switch {
+	case err == ErrSearchCanceled || err == context.Canceled:
+		return canceledOutcome
+	case err == ErrSearchTimeout || err == context.DeadlineExceeded:
+		return timeoutOutcome
+	case err != nil:
+		return errorOutcome
+	case search.FoundValue():
+		return foundValueOutcome
+	case search.FoundClosestPeers():
+		return foundClosestOutcome
+	case search.Errored():
+		return erroredOutcome
+	default:
+		return exhaustedOutcome
+	}
+}
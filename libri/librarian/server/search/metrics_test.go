@@ -0,0 +1,54 @@
+package search
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestPromSearcher_Search(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	inner := &fixedSearcher{closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses))}
+	s := NewPromSearcher(inner).(*promSearcher)
+
+	err := s.Search(context.Background(), newTestNotFoundSearch(rng, id.NewPseudoRandom(rng)), nil)
+	assert.Nil(t, err)
+	inner.value = &api.Document{}
+	err = s.Search(context.Background(), newTestNotFoundSearch(rng, id.NewPseudoRandom(rng)), nil)
+	assert.Nil(t, err)
+	inner.err = errors.New("some Search error")
+	err = s.Search(context.Background(), newTestNotFoundSearch(rng, id.NewPseudoRandom(rng)), nil)
+	assert.NotNil(t, err)
+
+	for _, outcome := range []string{foundClosestOutcome, foundValueOutcome, errorOutcome} {
+		written := &dto.Metric{}
+		assert.Nil(t, s.searches.WithLabelValues(outcome).Write(written))
+		assert.Equal(t, float64(1), *written.Counter.Value, outcome)
+		assert.Nil(t, s.duration.WithLabelValues(outcome).(prom.Histogram).Write(written))
+		assert.Equal(t, uint64(1), *written.Histogram.SampleCount, outcome)
+	}
+
+	s.Register()
+	s.Unregister()
+}
+
+func TestSearchOutcome(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	search := newTestNotFoundSearch(rng, id.NewPseudoRandom(rng))
+	assert.Equal(t, canceledOutcome, searchOutcome(search, ErrSearchCanceled))
+	assert.Equal(t, timeoutOutcome, searchOutcome(search, ErrSearchTimeout))
+	assert.Equal(t, canceledOutcome, searchOutcome(search, context.Canceled))
+	assert.Equal(t, timeoutOutcome, searchOutcome(search, context.DeadlineExceeded))
+	assert.Equal(t, exhaustedOutcome, searchOutcome(search, nil))
+
+	search.Result.FatalErr = errors.New("some fatal error")
+	assert.Equal(t, erroredOutcome, searchOutcome(search, nil))
+}
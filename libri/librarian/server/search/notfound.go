@@ -0,0 +1,147 @@
+package search
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/drausin/libri/libri/common/errors"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/hashicorp/golang-lru"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	promNamespace = "libri"
+	promSubsystem = "search"
+
+	resultLabel = "result"
+	hitResult   = "hit"
+	missResult  = "miss"
+)
+
+// CachingSearcher is a Searcher that remembers the keys it recently searched for and didn't find.
+type CachingSearcher interface {
+	Searcher
+
+	// CacheStats returns the number of searches answered from the cache and those that weren't.
+	CacheStats() (hits uint64, misses uint64)
+
+	// Forget removes the key from the cache, e.g., once a value for it has been stored, so
+	// searches for it don't miss the value until the cached entry expires.
+	Forget(key id.ID)
+
+	// Register registers the Prometheus metrics with the default Prometheus registerer.
+	Register()
+
+	// Unregister unregisters the Prometheus metrics from the default Prometheus registerer.
+	Unregister()
+}
+
+type notFoundEntry struct {
+	closest []peer.Peer
+	expires time.Time
+}
+
+type notFoundSearcher struct {
+	inner   Searcher
+	ttl     time.Duration
+	cache   *lru.Cache
+	hits    uint64
+	misses  uint64
+	lookups *prom.CounterVec
+}
+
+// NewNotFoundCachingSearcher returns a CachingSearcher that remembers, for the given TTL, up to
+// size keys that searches found the closest peers to but not the value for. Searches for such a
+// key within the TTL are answered with the cached closest peers instead of querying the network,
+// so bursts of requests for a missing key only trigger one lookup.
+func NewNotFoundCachingSearcher(inner Searcher, ttl time.Duration, size uint) CachingSearcher {
+	cache, err := lru.New(int(size))
+	errors.MaybePanic(err) // only errors for non-positive size
+	return &notFoundSearcher{
+		inner: inner,
+		ttl:   ttl,
+		cache: cache,
+		lookups: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: promNamespace,
+				Subsystem: promSubsystem,
+				Name:      "not_found_cache_lookups_total",
+				Help:      "Number of searches looked up in the not found cache, by result.",
+			},
+			[]string{resultLabel},
+		),
+	}
+}
+
+func (s *notFoundSearcher) Search(ctx context.Context, search *Search, seeds []peer.Peer) error {
+	if search.refresh {
+		// refresh searches target random keys, so caching them would only evict useful entries
+		return s.inner.Search(ctx, search, seeds)
+	}
+	key := search.Key.String()
+	if s.answerFromCache(key, search) {
+		atomic.AddUint64(&s.hits, 1)
+		s.lookups.WithLabelValues(hitResult).Inc()
+		return nil
+	}
+	atomic.AddUint64(&s.misses, 1)
+	s.lookups.WithLabelValues(missResult).Inc()
+
+	if err := s.inner.Search(ctx, search, seeds); err != nil {
+		return err
+	}
+	if search.FoundValue() {
+		s.cache.Remove(key)
+	} else if search.FoundClosestPeers() {
+		search.Mu.Lock()
+		closest := search.Result.Closest.Peers()
+		search.Mu.Unlock()
+		s.cache.Add(key, &notFoundEntry{
+			closest: closest,
+			expires: time.Now().Add(s.ttl),
+		})
+	}
+	return nil
+}
+
+// answerFromCache adds a search's cached closest peers to its result, returning whether there
+// were enough unexpired ones to finish the search.
+func (s *notFoundSearcher) answerFromCache(key string, search *Search) bool {
+	value, in := s.cache.Get(key)
+	if !in {
+		return false
+	}
+	entry := value.(*notFoundEntry)
+	if time.Now().After(entry.expires) {
+		s.cache.Remove(key)
+		return false
+	}
+	if uint(len(entry.closest)) < search.Params.NClosestResponses {
+		return false
+	}
+	search.wrapLock(func() {
+		for _, p := range entry.closest {
+			search.Result.Closest.SafePush(p)
+		}
+	})
+	return true
+}
+
+func (s *notFoundSearcher) Forget(key id.ID) {
+	s.cache.Remove(key.String())
+}
+
+func (s *notFoundSearcher) CacheStats() (uint64, uint64) {
+	return atomic.LoadUint64(&s.hits), atomic.LoadUint64(&s.misses)
+}
+
+func (s *notFoundSearcher) Register() {
+	prom.MustRegister(s.lookups)
+}
+
+func (s *notFoundSearcher) Unregister() {
+	_ = prom.Unregister(s.lookups)
+}
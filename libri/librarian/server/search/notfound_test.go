@@ -0,0 +1,168 @@
+package search
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestParameters_CachesNotFound(t *testing.T) {
+	p := NewDefaultParameters()
+	assert.False(t, p.CachesNotFound()) // disabled by default
+
+	p.NotFoundTTL = 10 * time.Second
+	assert.True(t, p.CachesNotFound())
+
+	p.NotFoundCacheSize = 0
+	assert.False(t, p.CachesNotFound())
+}
+
+func TestNotFoundSearcher_Search_notFound(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	inner := &fixedSearcher{closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses))}
+	s := NewNotFoundCachingSearcher(inner, time.Minute, 8)
+
+	// first search misses cache and queries network
+	search1 := newTestNotFoundSearch(rng, key)
+	err := s.Search(context.Background(), search1, nil)
+	assert.Nil(t, err)
+	assert.True(t, search1.FoundClosestPeers())
+	assert.Equal(t, 1, inner.nCalls)
+
+	// repeated search is answered from cache
+	search2 := newTestNotFoundSearch(rng, key)
+	err = s.Search(context.Background(), search2, nil)
+	assert.Nil(t, err)
+	assert.True(t, search2.FoundClosestPeers())
+	assert.False(t, search2.FoundValue())
+	assert.Equal(t, 1, inner.nCalls)
+	assert.Equal(t, search1.Result.Closest.Peers(), search2.Result.Closest.Peers())
+
+	// search for different key misses cache
+	search3 := newTestNotFoundSearch(rng, id.NewPseudoRandom(rng))
+	err = s.Search(context.Background(), search3, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.nCalls)
+
+	hits, misses := s.CacheStats()
+	assert.Equal(t, uint64(1), hits)
+	assert.Equal(t, uint64(2), misses)
+}
+
+func TestNotFoundSearcher_Search_refresh(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	inner := &fixedSearcher{closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses))}
+	s := NewNotFoundCachingSearcher(inner, time.Minute, 8)
+	lower, upper := id.FromInt64(0), id.FromInt64(1000)
+	search1, err := NewRefreshSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		lower, upper, NewDefaultParameters(), rng)
+	assert.Nil(t, err)
+
+	// refresh searches bypass the cache entirely
+	err = s.Search(context.Background(), search1, nil)
+	assert.Nil(t, err)
+	err = s.Search(context.Background(), newTestNotFoundSearch(rng, search1.Key), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.nCalls)
+
+	hits, misses := s.CacheStats()
+	assert.Zero(t, hits)
+	assert.Equal(t, uint64(1), misses)
+}
+
+func TestNotFoundSearcher_Search_expired(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	inner := &fixedSearcher{closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses))}
+	s := NewNotFoundCachingSearcher(inner, time.Millisecond, 8)
+
+	err := s.Search(context.Background(), newTestNotFoundSearch(rng, key), nil)
+	assert.Nil(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	// cached result has expired, so search queries network again
+	err = s.Search(context.Background(), newTestNotFoundSearch(rng, key), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.nCalls)
+
+	hits, misses := s.CacheStats()
+	assert.Zero(t, hits)
+	assert.Equal(t, uint64(2), misses)
+}
+
+func TestNotFoundSearcher_Search_notCached(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	cases := map[string]*fixedSearcher{
+		"found value": {
+			closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses)),
+			value:   &api.Document{},
+		},
+		"too few closest": {
+			closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses)-1),
+		},
+		"error": {
+			closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses)),
+			err:     errors.New("some search error"),
+		},
+	}
+	for desc, inner := range cases {
+		s := NewNotFoundCachingSearcher(inner, time.Minute, 8)
+		for i := 0; i < 2; i++ {
+			err := s.Search(context.Background(), newTestNotFoundSearch(rng, key), nil)
+			assert.Equal(t, inner.err, err, desc)
+		}
+		assert.Equal(t, 2, inner.nCalls, desc)
+	}
+}
+
+func TestNotFoundSearcher_Forget(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	inner := &fixedSearcher{closest: peer.NewTestPeers(rng, int(DefaultNClosestResponses))}
+	s := NewNotFoundCachingSearcher(inner, time.Minute, 8)
+
+	err := s.Search(context.Background(), newTestNotFoundSearch(rng, key), nil)
+	assert.Nil(t, err)
+
+	// forgotten key (e.g., since just stored) queries network again
+	s.Forget(key)
+	err = s.Search(context.Background(), newTestNotFoundSearch(rng, key), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.nCalls)
+}
+
+func TestNotFoundSearcher_Register(t *testing.T) {
+	s := NewNotFoundCachingSearcher(&fixedSearcher{}, time.Minute, 8)
+	s.Register()
+	s.Unregister()
+}
+
+func newTestNotFoundSearch(rng *rand.Rand, key id.ID) *Search {
+	return NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng), key,
+		NewDefaultParameters())
+}
+
+// fixedSearcher adds the same closest peers and value to every search.
+type fixedSearcher struct {
+	closest []peer.Peer
+	value   *api.Document
+	err     error
+	nCalls  int
+}
+
+func (f *fixedSearcher) Search(ctx context.Context, search *Search, seeds []peer.Peer) error {
+	f.nCalls++
+	search.Result.Closest.SafePushMany(f.closest)
+	search.Result.Value = f.value
+	return f.err
+}
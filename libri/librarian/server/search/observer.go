@@ -0,0 +1,80 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/drausin/libri/libri/librarian/server/peer"
+)
+
+const (
+	// PeerQueried denotes that a Find request is being sent to a peer.
+	PeerQueried EventType = iota
+
+	// PeerResponded denotes that a peer's Find response (or error) has been processed.
+	PeerResponded
+
+	// ClosestChanged denotes that a responding peer joined the closest peers found so far.
+	ClosestChanged
+
+	// ValueFound denotes that a peer responded with the value and the search accepted it.
+	ValueFound
+)
+
+// EventType is the type of a search progress Event.
+type EventType int
+
+func (t EventType) String() string {
+	switch t {
+	case PeerQueried:
+		return "PeerQueried"
+	case PeerResponded:
+		return "PeerResponded"
+	case ClosestChanged:
+		return "ClosestChanged"
+	case ValueFound:
+		return "ValueFound"
+	}
+	panic(fmt.Errorf("unknown EventType value %d", t))
+}
+
+// Event is a step in the progress of a search.
+type Event struct {
+	// Type of the event
+	Type EventType
+
+	// Peer the event concerns
+	Peer peer.Peer
+
+	// Err is the error querying the peer or processing its response for PeerResponded events,
+	// if any
+	Err error
+
+	// NClosest is the number of closest peers found so far for ClosestChanged events
+	NClosest int
+}
+
+// Observer observes the progress of a search.
+type Observer interface {
+	// Observe handles a search event. It is called synchronously from the search's goroutines
+	// (concurrently for disjoint-path searches), so it should return quickly.
+	Observe(e *Event)
+}
+
+// ObserverFunc adapts a function into an Observer.
+type ObserverFunc func(e *Event)
+
+// Observe calls the function with the event.
+func (f ObserverFunc) Observe(e *Event) {
+	f(e)
+}
+
+// NewChanObserver returns an Observer that streams events to the given channel. Events are
+// dropped rather than blocking the search when the channel is full.
+func NewChanObserver(events chan<- *Event) Observer {
+	return ObserverFunc(func(e *Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestEventType_String(t *testing.T) {
+	assert.Equal(t, "PeerQueried", PeerQueried.String())
+	assert.Equal(t, "PeerResponded", PeerResponded.String())
+	assert.Equal(t, "ClosestChanged", ClosestChanged.String())
+	assert.Equal(t, "ValueFound", ValueFound.String())
+	assert.Panics(t, func() {
+		_ = EventType(-1).String()
+	})
+}
+
+func TestNewChanObserver(t *testing.T) {
+	events := make(chan *Event, 1)
+	o := NewChanObserver(events)
+	e1, e2 := &Event{Type: PeerQueried}, &Event{Type: PeerResponded}
+
+	o.Observe(e1)
+	o.Observe(e2) // dropped rather than blocking since channel is full
+	assert.Equal(t, e1, <-events)
+	assert.Len(t, events, 0)
+}
+
+func TestSearcher_Search_observer(t *testing.T) {
+	n, nClosestResponses := 32, uint(6)
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	orgID := ecid.NewPseudoRandom(rng)
+	key := id.NewPseudoRandom(rng)
+	searcher := NewTestSearcher(peersMap, addressFinders, &fixedRecorder{})
+	search := NewSearch(peerID, orgID, key, &Parameters{
+		NClosestResponses: nClosestResponses,
+		NMaxErrors:        DefaultNMaxErrors,
+		Concurrency:       3,
+		Timeout:           DefaultQueryTimeout,
+	})
+	obs := &collectingObserver{counts: make(map[EventType]int)}
+	search.Observer = obs
+
+	err := searcher.Search(context.Background(), search, NewTestSeeds(peers, selfPeerIdxs))
+	assert.Nil(t, err)
+	assert.True(t, search.FoundClosestPeers())
+
+	// every queried peer was observed being queried and responding
+	assert.Equal(t, len(search.Result.Queried), obs.counts[PeerQueried])
+	assert.Equal(t, len(search.Result.Queried), obs.counts[PeerResponded])
+
+	// closest peers only changed when they joined, ending with the full set
+	assert.True(t, obs.counts[ClosestChanged] >= int(nClosestResponses))
+	assert.Equal(t, int(nClosestResponses), obs.lastNClosest)
+	assert.Zero(t, obs.counts[ValueFound])
+}
+
+func TestSearcher_processAnyResponse_observer(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := &searcher{
+		rp:  NewResponseProcessor(peer.NewFromer(), comm.NewNaiveDoctor()),
+		rec: &fixedRecorder{},
+		bl:  comm.NewNaiveBlacklister(),
+	}
+	search := NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng), NewDefaultParameters())
+	obs := &collectingObserver{counts: make(map[EventType]int)}
+	search.Observer = obs
+	p := peer.NewTestPeer(rng, 0)
+
+	// value response
+	s.processAnyReponse(&peerResponse{
+		peer:     p,
+		response: &api.FindResponse{Value: &api.Document{}},
+	}, search)
+	assert.Equal(t, 1, obs.counts[PeerResponded])
+	assert.Equal(t, 1, obs.counts[ClosestChanged])
+	assert.Equal(t, 1, obs.counts[ValueFound])
+
+	// same peer responding again doesn't change the closest peers
+	s.processAnyReponse(&peerResponse{
+		peer:     p,
+		response: &api.FindResponse{Peers: []*api.PeerAddress{}},
+	}, search)
+	assert.Equal(t, 2, obs.counts[PeerResponded])
+	assert.Equal(t, 1, obs.counts[ClosestChanged])
+
+	// errored response
+	s.processAnyReponse(&peerResponse{peer: peer.NewTestPeer(rng, 1), err: errInvalidResponse},
+		search)
+	assert.Equal(t, 3, obs.counts[PeerResponded])
+	assert.Equal(t, errInvalidResponse, obs.lastErr)
+
+	// aborted responses aren't observed
+	s.processAnyReponse(&peerResponse{peer: peer.NewTestPeer(rng, 2), aborted: true}, search)
+	assert.Equal(t, 3, obs.counts[PeerResponded])
+}
+
+func TestSearcher_processAnyResponse_observerQuorum(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := &searcher{
+		rp:  NewResponseProcessor(peer.NewFromer(), comm.NewNaiveDoctor()),
+		rec: &fixedRecorder{},
+		bl:  comm.NewNaiveBlacklister(),
+	}
+	params := NewDefaultParameters()
+	params.ValueQuorum = 2
+	search := NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng), params)
+	obs := &collectingObserver{counts: make(map[EventType]int)}
+	search.Observer = obs
+	value, _ := api.NewTestDocument(rng)
+
+	// value isn't found until the quorum is reached
+	s.processAnyReponse(&peerResponse{
+		peer:     peer.NewTestPeer(rng, 0),
+		response: &api.FindResponse{Value: value},
+	}, search)
+	assert.Zero(t, obs.counts[ValueFound])
+
+	s.processAnyReponse(&peerResponse{
+		peer:     peer.NewTestPeer(rng, 1),
+		response: &api.FindResponse{Value: value},
+	}, search)
+	assert.Equal(t, 1, obs.counts[ValueFound])
+}
+
+type collectingObserver struct {
+	counts       map[EventType]int
+	lastNClosest int
+	lastErr      error
+	mu           sync.Mutex
+}
+
+func (o *collectingObserver) Observe(e *Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.counts[e.Type]++
+	if e.Type == ClosestChanged {
+		o.lastNClosest = e.NClosest
+	}
+	if e.Err != nil {
+		o.lastErr = e.Err
+	}
+}
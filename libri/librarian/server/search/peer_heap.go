@@ -6,9 +6,38 @@ import (
 
 	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
 )
 
+// Comparator compares two peers with the given distances to the target, returning a negative
+// number if the first should be ordered before the second, a positive number if after, and zero if
+// their order doesn't matter.
+type Comparator func(p1, p2 peer.Peer, dist1, dist2 *big.Int) int
+
+// DistanceComparator orders peers purely by their distance to the target.
+func DistanceComparator(p1, p2 peer.Peer, dist1, dist2 *big.Int) int {
+	return dist1.Cmp(dist2)
+}
+
+// NewPreferenceComparator returns a Comparator that keeps distance as the primary order but, among
+// peers in the same distance bucket (i.e., sharing the same length of common prefix with the
+// target), orders the peers the Preferer prefers first, so flaky or slow peers come later.
+func NewPreferenceComparator(p comm.Preferer) Comparator {
+	return func(p1, p2 peer.Peer, dist1, dist2 *big.Int) int {
+		if c := dist1.BitLen() - dist2.BitLen(); c != 0 {
+			return c
+		}
+		if p.Prefer(p1.ID(), p2.ID()) {
+			return -1
+		}
+		if p.Prefer(p2.ID(), p1.ID()) {
+			return 1
+		}
+		return dist1.Cmp(dist2)
+	}
+}
+
 // PeerDistanceHeap represents a heap of peers sorted by their distance to a given target.
 type PeerDistanceHeap interface {
 	heap.Interface
@@ -24,6 +53,10 @@ type PeerDistanceHeap interface {
 	// PeakDistance returns the distance from the root of the heap to the target.
 	PeakDistance() *big.Int
 
+	// MinDistance returns the smallest distance from a peer in the heap to the target, which
+	// differs from PeakDistance for min-heaps not ordered purely by distance.
+	MinDistance() *big.Int
+
 	// PeakPeer returns (but does not remove) the the root of the heap.
 	PeakPeer() peer.Peer
 
@@ -73,6 +106,9 @@ type peerDistanceHeap struct {
 
 	// capacity of the heap
 	capacity int
+
+	// orders the peers, by distance if nil
+	cmp Comparator
 }
 
 // NewClosestPeers returns a ClosestPeers instance for a given target with a given capacity.
@@ -87,6 +123,14 @@ func NewClosestPeers(target id.ID, capacity uint) ClosestPeers {
 	}
 }
 
+// NewClosestPeersWithComparator returns a ClosestPeers instance for a given target with a given
+// capacity whose root is the first peer in the comparator's order.
+func NewClosestPeersWithComparator(target id.ID, capacity uint, cmp Comparator) ClosestPeers {
+	cp := NewClosestPeers(target, capacity).(*peerDistanceHeap)
+	cp.cmp = cmp
+	return cp
+}
+
 // NewFarthestPeers returns a FarthestPeers instance for a given target with a given capacity.
 func NewFarthestPeers(target id.ID, capacity uint) FarthestPeers {
 	return &peerDistanceHeap{
@@ -103,6 +147,19 @@ func (pdh *peerDistanceHeap) PeakDistance() *big.Int {
 	return pdh.distances[0]
 }
 
+func (pdh *peerDistanceHeap) MinDistance() *big.Int {
+	if pdh.cmp == nil && pdh.sign > 0 {
+		return pdh.distances[0]
+	}
+	min := pdh.distances[0]
+	for _, dist := range pdh.distances[1:] {
+		if dist.Cmp(min) < 0 {
+			min = dist
+		}
+	}
+	return min
+}
+
 func (pdh *peerDistanceHeap) PeakPeer() peer.Peer {
 	return pdh.peers[0]
 }
@@ -169,7 +226,11 @@ func (pdh *peerDistanceHeap) Capacity() int {
 
 // Less returns whether peer i is closer (or farther in case of max heap) to the target than peer j.
 func (pdh *peerDistanceHeap) Less(i, j int) bool {
-	return less(pdh.sign, pdh.distances[i], pdh.distances[j])
+	if pdh.cmp == nil {
+		return less(pdh.sign, pdh.distances[i], pdh.distances[j])
+	}
+	c := pdh.cmp(pdh.peers[i], pdh.peers[j], pdh.distances[i], pdh.distances[j])
+	return pdh.sign*c < 0
 }
 
 // Swap swaps the peers in position i and j.
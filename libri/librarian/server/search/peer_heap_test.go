@@ -2,6 +2,7 @@ package search
 
 import (
 	"container/heap"
+	"math/big"
 	"math/rand"
 	"testing"
 
@@ -142,3 +143,60 @@ func testPeerDistanceHeapSafePushCapacity(t *testing.T, rng *rand.Rand, pdh Peer
 		prevDistance = pdh.PeakDistance()
 	}
 }
+
+func TestNewPreferenceComparator(t *testing.T) {
+	target := id.FromInt64(0)
+	p1 := peer.New(id.FromInt64(4), "", nil) // distance bucket 3
+	p2 := peer.New(id.FromInt64(5), "", nil) // distance bucket 3
+	p3 := peer.New(id.FromInt64(8), "", nil) // distance bucket 4
+	dist := func(p peer.Peer) *big.Int { return p.ID().Distance(target) }
+	pref := &fixedPreferer{preferred: p2.ID()}
+	cmp := NewPreferenceComparator(pref)
+
+	// preferred peer comes first within the same bucket, even though it is farther
+	assert.True(t, cmp(p2, p1, dist(p2), dist(p1)) < 0)
+	assert.True(t, cmp(p1, p2, dist(p1), dist(p2)) > 0)
+
+	// distance bucket takes precedence over preference
+	pref.preferred = p3.ID()
+	assert.True(t, cmp(p1, p3, dist(p1), dist(p3)) < 0)
+
+	// no preference falls back to distance
+	pref.preferred = nil
+	assert.True(t, cmp(p1, p2, dist(p1), dist(p2)) < 0)
+	assert.Equal(t, DistanceComparator(p1, p2, dist(p1), dist(p2)), cmp(p1, p2, dist(p1), dist(p2)))
+}
+
+func TestClosestPeersWithComparator(t *testing.T) {
+	target := id.FromInt64(0)
+	p1 := peer.New(id.FromInt64(4), "", nil)
+	p2 := peer.New(id.FromInt64(5), "", nil)
+	p3 := peer.New(id.FromInt64(8), "", nil)
+	cp := NewClosestPeersWithComparator(target, 3,
+		NewPreferenceComparator(&fixedPreferer{preferred: p2.ID()}))
+	cp.SafePushMany([]peer.Peer{p3, p1, p2})
+
+	assert.Equal(t, p2, cp.PeakPeer())
+	assert.Equal(t, big.NewInt(5), cp.PeakDistance())
+	assert.Equal(t, big.NewInt(4), cp.MinDistance())
+	assert.Equal(t, []peer.Peer{p2, p1, p3}, cp.Peers())
+}
+
+func TestPeerDistanceHeap_MinDistance(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	target := id.NewPseudoRandom(rng)
+	cp, fp := NewClosestPeers(target, 8), NewFarthestPeers(target, 8)
+	ps := peer.NewTestPeers(rng, 8)
+	cp.SafePushMany(ps)
+	fp.SafePushMany(ps)
+	assert.Equal(t, cp.PeakDistance(), cp.MinDistance())
+	assert.Equal(t, cp.PeakDistance(), fp.MinDistance())
+}
+
+type fixedPreferer struct {
+	preferred id.ID
+}
+
+func (p *fixedPreferer) Prefer(peerID1, peerID2 id.ID) bool {
+	return p.preferred != nil && peerID1.Cmp(p.preferred) == 0
+}
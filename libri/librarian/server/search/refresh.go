@@ -0,0 +1,27 @@
+package search
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+)
+
+// ErrEmptyRange indicates when a refresh search's range contains no IDs.
+var ErrEmptyRange = errors.New("range lower bound must be less than upper bound")
+
+// NewRefreshSearch creates a new Search for a random target in the range [lowerBound,
+// upperBound), usually that of a routing table bucket. Refreshing a bucket this way discovers the
+// peers in its part of the keyspace, which the caller then adds to the routing table.
+func NewRefreshSearch(
+	peerID, orgID ecid.ID, lowerBound, upperBound id.ID, params *Parameters, rng *rand.Rand,
+) (*Search, error) {
+	if lowerBound.Cmp(upperBound) >= 0 {
+		return nil, ErrEmptyRange
+	}
+	target := id.NewPseudoRandomInRange(rng, lowerBound, upperBound)
+	s := NewSearch(peerID, orgID, target, params)
+	s.refresh = true
+	return s, nil
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshSearch_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	lb, ub := id.FromInt64(1<<20), id.FromInt64(1<<21)
+	params := NewDefaultParameters()
+	for c := 0; c < 16; c++ {
+		s, err := NewRefreshSearch(peerID, orgID, lb, ub, params, rng)
+		assert.Nil(t, err)
+		assert.True(t, s.Key.Cmp(lb) >= 0)
+		assert.True(t, s.Key.Cmp(ub) < 0)
+		assert.Equal(t, params, s.Params)
+		assert.Equal(t, s.Key.Bytes(), s.CreatRq().Key)
+	}
+}
+
+func TestNewRefreshSearch_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	params := NewDefaultParameters()
+
+	// check empty and inverted ranges error
+	s, err := NewRefreshSearch(peerID, orgID, id.FromInt64(1), id.FromInt64(1), params, rng)
+	assert.Equal(t, ErrEmptyRange, err)
+	assert.Nil(t, s)
+	s, err = NewRefreshSearch(peerID, orgID, id.FromInt64(2), id.FromInt64(1), params, rng)
+	assert.Equal(t, ErrEmptyRange, err)
+	assert.Nil(t, s)
+}
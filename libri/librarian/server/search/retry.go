@@ -0,0 +1,114 @@
+package search
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"golang.org/x/net/context"
+)
+
+type retryingSearcher struct {
+	inner Searcher
+	rng   *rand.Rand
+	mu    sync.Mutex
+}
+
+// NewRetryingSearcher returns a Searcher that retries searches failing with too many Find errors,
+// which usually stem from transient network blips, up to the search's Parameters.MaxRetries
+// times. It waits a jittered exponential backoff before each retry and seeds it with the peers
+// discovered in the earlier attempts as well as the original seeds. Timed out and canceled
+// searches aren't retried, and the search's Parameters.TotalTimeout bounds all of the attempts
+// together rather than each one.
+func NewRetryingSearcher(inner Searcher, rng *rand.Rand) Searcher {
+	return &retryingSearcher{
+		inner: inner,
+		rng:   rng,
+	}
+}
+
+func (s *retryingSearcher) Search(parent context.Context, search *Search, seeds []peer.Peer) error {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if search.Params.TotalTimeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, search.Params.TotalTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	defer cancel()
+	err := s.retry(ctx, search, seeds)
+	if err != nil && ctx.Err() != nil && parent.Err() == nil {
+		// our own deadline across the attempts ended the search rather than the caller's context
+		err = ErrSearchTimeout
+		search.wrapLock(func() { search.Result.FatalErr = err })
+	}
+	return err
+}
+
+func (s *retryingSearcher) retry(ctx context.Context, search *Search, seeds []peer.Peer) error {
+	err := s.inner.Search(ctx, search, seeds)
+	for attempt := uint(0); attempt < search.Params.MaxRetries; attempt++ {
+		if err != ErrTooManyFindErrors || search.FoundValue() || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(s.backoff(search.Params, attempt)):
+		}
+		err = s.inner.Search(ctx, search, resetForRetry(search, seeds))
+	}
+	return err
+}
+
+// backoff returns a duration drawn uniformly from [0, b), where b is the initial retry backoff
+// doubled for each previous attempt and capped at the max retry backoff.
+func (s *retryingSearcher) backoff(params *Parameters, attempt uint) time.Duration {
+	b := params.RetryInitialBackoff
+	for c := uint(0); c < attempt && b < params.RetryMaxBackoff; c++ {
+		b *= 2
+	}
+	if b > params.RetryMaxBackoff {
+		b = params.RetryMaxBackoff
+	}
+	if b <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(s.rng.Int63n(int64(b)))
+}
+
+// resetForRetry replaces the search's result with a fresh one, returning the seeds for the retry:
+// the original seeds plus the peers the previous attempt discovered.
+func resetForRetry(search *Search, seeds []peer.Peer) []peer.Peer {
+	search.Mu.Lock()
+	defer search.Mu.Unlock()
+	retrySeeds := make([]peer.Peer, 0, len(seeds)+len(search.Result.Responded)+
+		search.Result.Unqueried.Len())
+	seen := make(map[string]struct{})
+	add := func(ps ...peer.Peer) {
+		for _, p := range ps {
+			key := p.ID().String()
+			if _, in := seen[key]; !in {
+				seen[key] = struct{}{}
+				retrySeeds = append(retrySeeds, p)
+			}
+		}
+	}
+	add(search.Result.Closest.Peers()...)
+	for _, p := range search.Result.Responded {
+		add(p)
+	}
+	add(search.Result.Unqueried.Peers()...)
+	for _, pr := range search.Result.Paths {
+		add(pr.Unqueried.Peers()...)
+	}
+	add(seeds...)
+
+	search.Result.Release()
+	search.Result = NewInitialResult(search.Key, search.Params)
+	search.cc = nil
+	return retrySeeds
+}
@@ -0,0 +1,177 @@
+package search
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestParameters_Retries(t *testing.T) {
+	p := NewDefaultParameters()
+	assert.True(t, p.Retries())
+
+	p.MaxRetries = 0
+	assert.False(t, p.Retries())
+}
+
+func TestRetryingSearcher_Search_recovers(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	seeds := peer.NewTestPeers(rng, 4)
+	discovered := peer.NewTestPeers(rng, 4)
+	inner := &flakySearcher{
+		nErrs:      2,
+		discovered: discovered,
+		closest:    peer.NewTestPeers(rng, int(DefaultNClosestResponses)),
+	}
+	s := NewRetryingSearcher(inner, rng)
+	search := newTestRetrySearch(rng, 2)
+
+	err := s.Search(context.Background(), search, seeds)
+	assert.Nil(t, err)
+	assert.True(t, search.FoundClosestPeers())
+	assert.Equal(t, 3, inner.nCalls)
+	assert.Empty(t, search.Result.Errored)
+
+	// retries are seeded with the peers discovered before as well as the original seeds
+	assert.Len(t, inner.seeds[0], len(seeds))
+	assert.Len(t, inner.seeds[1], len(seeds)+len(discovered))
+	for _, p := range append(seeds, discovered...) {
+		assert.Contains(t, inner.seeds[1], p)
+	}
+}
+
+func TestRetryingSearcher_Search_gives_up(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	seeds := peer.NewTestPeers(rng, 4)
+
+	// too many Find errors retried up to max retries
+	inner := &flakySearcher{nErrs: 10, err: ErrTooManyFindErrors}
+	s := NewRetryingSearcher(inner, rng)
+	err := s.Search(context.Background(), newTestRetrySearch(rng, 2), seeds)
+	assert.Equal(t, ErrTooManyFindErrors, err)
+	assert.Equal(t, 3, inner.nCalls)
+
+	// retries disabled
+	inner = &flakySearcher{nErrs: 10, err: ErrTooManyFindErrors}
+	s = NewRetryingSearcher(inner, rng)
+	err = s.Search(context.Background(), newTestRetrySearch(rng, 0), seeds)
+	assert.Equal(t, ErrTooManyFindErrors, err)
+	assert.Equal(t, 1, inner.nCalls)
+
+	// timeouts and cancellations aren't retried
+	for _, fatalErr := range []error{ErrSearchTimeout, ErrSearchCanceled} {
+		inner = &flakySearcher{nErrs: 10, err: fatalErr}
+		s = NewRetryingSearcher(inner, rng)
+		err = s.Search(context.Background(), newTestRetrySearch(rng, 2), seeds)
+		assert.Equal(t, fatalErr, err)
+		assert.Equal(t, 1, inner.nCalls)
+	}
+
+	// canceled context stops retrying
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inner = &flakySearcher{nErrs: 10, err: ErrTooManyFindErrors}
+	s = NewRetryingSearcher(inner, rng)
+	err = s.Search(ctx, newTestRetrySearch(rng, 2), seeds)
+	assert.Equal(t, ErrTooManyFindErrors, err)
+	assert.Equal(t, 1, inner.nCalls)
+}
+
+func TestRetryingSearcher_Search_totalTimeout(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	seeds := peer.NewTestPeers(rng, 4)
+	inner := &flakySearcher{nErrs: 100, err: ErrTooManyFindErrors, delay: 20 * time.Millisecond}
+	s := NewRetryingSearcher(inner, rng)
+	search := newTestRetrySearch(rng, 100)
+	search.Params.TotalTimeout = 50 * time.Millisecond
+
+	// total timeout bounds all the attempts together
+	start := time.Now()
+	err := s.Search(context.Background(), search, seeds)
+	assert.Equal(t, ErrSearchTimeout, err)
+	assert.Equal(t, ErrSearchTimeout, search.Result.FatalErr)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.True(t, inner.nCalls < 10)
+
+	// caller's own deadline isn't reported as the total timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	inner = &flakySearcher{nErrs: 100, err: ErrTooManyFindErrors, delay: 20 * time.Millisecond}
+	s = NewRetryingSearcher(inner, rng)
+	err = s.Search(ctx, newTestRetrySearch(rng, 100), seeds)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrSearchTimeout, err)
+}
+
+func TestRetryingSearcher_backoff(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := NewRetryingSearcher(nil, rng).(*retryingSearcher)
+	params := &Parameters{
+		RetryInitialBackoff: 100 * time.Millisecond,
+		RetryMaxBackoff:     time.Second,
+	}
+	for attempt := uint(0); attempt < 8; attempt++ {
+		maxBackoff := params.RetryInitialBackoff << attempt
+		if maxBackoff > params.RetryMaxBackoff {
+			maxBackoff = params.RetryMaxBackoff
+		}
+		for c := 0; c < 16; c++ {
+			b := s.backoff(params, attempt)
+			assert.True(t, b >= 0)
+			assert.True(t, b < maxBackoff)
+		}
+	}
+
+	params.RetryInitialBackoff = 0
+	assert.Zero(t, s.backoff(params, 0))
+}
+
+func newTestRetrySearch(rng *rand.Rand, maxRetries uint) *Search {
+	params := NewDefaultParameters()
+	params.MaxRetries = maxRetries
+	params.RetryInitialBackoff = time.Millisecond
+	params.RetryMaxBackoff = 5 * time.Millisecond
+	return NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng), params)
+}
+
+// flakySearcher fails the first nErrs searches after discovering some peers, then finds the
+// closest peers.
+type flakySearcher struct {
+	nErrs      int
+	err        error
+	discovered []peer.Peer
+	closest    []peer.Peer
+	delay      time.Duration
+	nCalls     int
+	seeds      [][]peer.Peer
+}
+
+func (f *flakySearcher) Search(ctx context.Context, search *Search, seeds []peer.Peer) error {
+	f.nCalls++
+	f.seeds = append(f.seeds, seeds)
+	if f.delay > 0 {
+		// undelayed searches don't wait on the context, so its cancellation can't race them
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.delay):
+		}
+	}
+	if f.nCalls <= f.nErrs {
+		search.Result.Unqueried.SafePushMany(f.discovered)
+		search.Result.Errored["some peer"] = ErrTooManyFindErrors
+		if f.err == nil {
+			return ErrTooManyFindErrors
+		}
+		return f.err
+	}
+	search.Result.Closest.SafePushMany(f.closest)
+	return nil
+}
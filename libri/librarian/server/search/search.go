@@ -27,15 +27,96 @@ const (
 	// DefaultQueryTimeout is the timeout for each query to a peer.
 	DefaultQueryTimeout = 3 * time.Second
 
+	// DefaultTotalTimeout is the default timeout for the whole search.
+	DefaultTotalTimeout = 30 * time.Second
+
+	// DefaultMaxResponded is the default maximum number of responding peers tracked in
+	// Result.Responded.
+	DefaultMaxResponded = uint(64)
+
+	// DefaultNDisjointPaths is the default number of disjoint lookup paths in a search.
+	DefaultNDisjointPaths = uint(1)
+
+	// DefaultMaxConcurrency is the default maximum number of parallel search workers, which
+	// disables adaptive concurrency.
+	DefaultMaxConcurrency = uint(0)
+
+	// DefaultLatencyBudget is the default target duration of an adaptive concurrency search.
+	DefaultLatencyBudget = 2 * time.Second
+
+	// DefaultHedgeFraction is the default fraction of the query timeout after which queries are
+	// hedged, which disables hedging.
+	DefaultHedgeFraction = 0.0
+
+	// DefaultValueQuorum is the default number of distinct peers that must return the same value,
+	// which accepts the first value returned.
+	DefaultValueQuorum = uint(1)
+
+	// DefaultVerifyValueKey is the default setting for whether to check returned values against
+	// the search key.
+	DefaultVerifyValueKey = false
+
+	// DefaultReputationWeighted is the default setting for whether to order unqueried peers by
+	// reputation within each distance bucket.
+	DefaultReputationWeighted = false
+
+	// DefaultLatencyWeighted is the default setting for whether to order unqueried peers by
+	// latency within each distance bucket.
+	DefaultLatencyWeighted = false
+
+	// DefaultReadRepair is the default setting for whether to record the responding peers missing
+	// a found value.
+	DefaultReadRepair = false
+
+	// DefaultNotFoundTTL is the default duration a not-found search result is cached for, which
+	// disables the cache since a librarian only forgets the keys stored via itself.
+	DefaultNotFoundTTL = time.Duration(0)
+
+	// DefaultNotFoundCacheSize is the default maximum number of cached not-found search results.
+	DefaultNotFoundCacheSize = uint(1024)
+
+	// DefaultMaxRetries is the default maximum number of times a search failing with too many
+	// Find errors is retried.
+	DefaultMaxRetries = uint(2)
+
+	// DefaultRetryInitialBackoff is the default initial backoff before retrying a search.
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+
+	// DefaultRetryMaxBackoff is the default maximum backoff before retrying a search.
+	DefaultRetryMaxBackoff = 1 * time.Second
+
+	// maxPooledMapLen is the maximum length of a released Result map that is returned to its
+	// pool for reuse. Larger maps are left for garbage collection so the pools don't pin the
+	// memory of unusually large searches.
+	maxPooledMapLen = 256
+
 	// logging keys
 	logKey               = "key"
 	logNClosestResponses = "n_closest_responses"
 	logNMaxErrors        = "n_max_errors"
 	logConcurrency       = "concurrency"
 	logTimeout           = "timeout"
+	logTotalTimeout      = "total_timeout"
+	logMaxResponded      = "max_responded"
+	logNDisjointPaths    = "n_disjoint_paths"
+	logMaxConcurrency    = "max_concurrency"
+	logLatencyBudget     = "latency_budget"
+	logHedgeFraction     = "hedge_fraction"
+	logValueQuorum       = "value_quorum"
+	logRepWeighted       = "reputation_weighted"
+	logLatencyWeighted   = "latency_weighted"
+	logVerifyValueKey    = "verify_value_key"
+	logReadRepair        = "read_repair"
+	logNotFoundTTL       = "not_found_ttl"
+	logNotFoundCacheSize = "not_found_cache_size"
+	logMaxRetries        = "max_retries"
+	logRetryMaxBackoff   = "retry_max_backoff"
+	logChosenConcurrency = "chosen_concurrency"
+	logNPaths            = "n_paths"
 	logNClosest          = "n_closest"
 	logNUnqueried        = "n_unqueried"
 	logNResponded        = "n_responded"
+	logNMissing          = "n_missing"
 	logErrors            = "errors"
 	logFatalError        = "fatal_error"
 	logResult            = "result"
@@ -61,24 +142,199 @@ type Parameters struct {
 
 	// Timeout for queries to individual peers
 	Timeout time.Duration
+
+	// TotalTimeout for the whole search, after which it stops issuing queries, abandons those in
+	// flight, and fails with ErrSearchTimeout. Zero means no overall timeout.
+	TotalTimeout time.Duration
+
+	// MaxResponded is the maximum number of responding peers tracked in Result.Responded, with
+	// zero meaning no limit. Responding peers beyond it still count toward the closest peers.
+	MaxResponded uint
+
+	// NDisjointPaths is the number of disjoint lookup paths, each querying different peers, whose
+	// results are merged at the end of the search. Using more than one path protects the search
+	// against adversarial peers returning poisoned closest peers, since each path would need to
+	// encounter one for all of them to be misled.
+	NDisjointPaths uint
+
+	// MaxConcurrency is the maximum number of concurrent queries when adapting the concurrency
+	// to observed query round-trip times. If it is greater than Concurrency, the search starts
+	// with Concurrency queries and increases the number as peers respond quickly, backing off
+	// as query errors (e.g., timeouts) accumulate. Otherwise, the concurrency is fixed.
+	MaxConcurrency uint
+
+	// LatencyBudget is the target duration of an adaptive concurrency search. Queries whose
+	// round-trip time is within LatencyBudget / NClosestResponses are considered fast.
+	LatencyBudget time.Duration
+
+	// HedgeFraction is the fraction of Timeout after which a query the peer hasn't yet answered
+	// is hedged with a duplicate query to the next unqueried peer, taking whichever response
+	// arrives first. Hedging cuts the tail latency added by slow peers at the expense of extra
+	// queries. Values outside (0, 1) disable hedging.
+	HedgeFraction float64
+
+	// ValueQuorum is the number of distinct peers that must return the same value before the
+	// search accepts it, protecting against a single malicious peer returning a forged value.
+	// Values verified against the key (see VerifyValueKey) need no quorum. Zero and one accept
+	// the first value returned.
+	ValueQuorum uint
+
+	// VerifyValueKey determines whether returned values are checked against the search key, with
+	// mismatched ones treated as invalid responses. Since a (non-system) document's key is the
+	// hash of its contents, a matching value is accepted without waiting for ValueQuorum.
+	VerifyValueKey bool
+
+	// ReputationWeighted determines whether unqueried peers in the same distance bucket (see
+	// NewPreferenceComparator) are ordered by their past responses, so flaky or slow peers are
+	// queried later. Distance remains the primary order.
+	ReputationWeighted bool
+
+	// LatencyWeighted determines whether unqueried peers in the same distance bucket are ordered
+	// by their moving average round-trip times, so fast peers are queried first in
+	// latency-sensitive searches (e.g., for Gets). It takes precedence over ReputationWeighted and
+	// requires the searcher to have a LatencyTracker (see WithLatencyTracker).
+	LatencyWeighted bool
+
+	// Macaroon is an optional encoded macaroon attached to Find requests sent to peers that
+	// require one to return values.
+	Macaroon string
+
+	// ReadRepair determines whether the responding peers that didn't return the value are
+	// recorded in Result.Missing, so the caller can re-store the value they should have had
+	// (see Result.MissingClosest) and heal its replication as it's read.
+	ReadRepair bool
+
+	// NotFoundTTL is how long the librarian remembers a key it searched for and didn't find,
+	// answering repeated searches for the key with the cached closest peers instead of querying
+	// the network. Zero disables the cache.
+	NotFoundTTL time.Duration
+
+	// NotFoundCacheSize is the maximum number of not-found keys the librarian remembers. Zero
+	// disables the cache.
+	NotFoundCacheSize uint
+
+	// MaxRetries is the maximum number of times a search failing with too many Find errors (e.g.,
+	// from a transient network blip) is retried, reusing the peers discovered in earlier
+	// attempts. Zero disables retries.
+	MaxRetries uint
+
+	// RetryInitialBackoff is the initial backoff before retrying a search. It doubles on each
+	// consecutive retry, up to RetryMaxBackoff, and the actual wait is jittered uniformly between
+	// zero and that value.
+	RetryInitialBackoff time.Duration
+
+	// RetryMaxBackoff is the maximum backoff before retrying a search.
+	RetryMaxBackoff time.Duration
+
+	// KeyspaceWidth is the byte width of the IDs in the keyspace searched, which should match that
+	// of the routing table. It must be in [1, id.Length], and zero means id.Length. Searches for
+	// keys outside the keyspace fail with ErrKeyOutsideKeyspace.
+	KeyspaceWidth uint
 }
 
 // NewDefaultParameters creates an instance with default parameters.
 func NewDefaultParameters() *Parameters {
 	return &Parameters{
-		NClosestResponses: DefaultNClosestResponses,
-		NMaxErrors:        DefaultNMaxErrors,
-		Concurrency:       DefaultConcurrency,
-		Timeout:           DefaultQueryTimeout,
+		NClosestResponses:   DefaultNClosestResponses,
+		NMaxErrors:          DefaultNMaxErrors,
+		Concurrency:         DefaultConcurrency,
+		Timeout:             DefaultQueryTimeout,
+		TotalTimeout:        DefaultTotalTimeout,
+		MaxResponded:        DefaultMaxResponded,
+		NDisjointPaths:      DefaultNDisjointPaths,
+		MaxConcurrency:      DefaultMaxConcurrency,
+		LatencyBudget:       DefaultLatencyBudget,
+		HedgeFraction:       DefaultHedgeFraction,
+		ValueQuorum:         DefaultValueQuorum,
+		VerifyValueKey:      DefaultVerifyValueKey,
+		ReputationWeighted:  DefaultReputationWeighted,
+		LatencyWeighted:     DefaultLatencyWeighted,
+		ReadRepair:          DefaultReadRepair,
+		NotFoundTTL:         DefaultNotFoundTTL,
+		NotFoundCacheSize:   DefaultNotFoundCacheSize,
+		MaxRetries:          DefaultMaxRetries,
+		RetryInitialBackoff: DefaultRetryInitialBackoff,
+		RetryMaxBackoff:     DefaultRetryMaxBackoff,
+		KeyspaceWidth:       id.Length,
 	}
 }
 
+// Validate returns an error if the parameters are invalid.
+func (p *Parameters) Validate() error {
+	return id.CheckWidth(id.WidthOrDefault(p.KeyspaceWidth))
+}
+
+// Adaptive returns whether the search concurrency adapts to observed query round-trip times.
+func (p *Parameters) Adaptive() bool {
+	return p.MaxConcurrency > p.Concurrency
+}
+
+// maxConcurrency returns the maximum number of concurrent queries the search may use.
+func (p *Parameters) maxConcurrency() uint {
+	if p.Adaptive() {
+		return p.MaxConcurrency
+	}
+	return p.Concurrency
+}
+
+// hedgeDelay returns how long to wait for a peer's response before hedging its query, or zero if
+// the search doesn't hedge.
+func (p *Parameters) hedgeDelay() time.Duration {
+	if p.HedgeFraction <= 0 || p.HedgeFraction >= 1 {
+		return 0
+	}
+	return time.Duration(p.HedgeFraction * float64(p.Timeout))
+}
+
+// CachesNotFound returns whether recent not-found search results are cached.
+func (p *Parameters) CachesNotFound() bool {
+	return p.NotFoundTTL > 0 && p.NotFoundCacheSize > 0
+}
+
+// Retries returns whether searches failing with too many Find errors are retried.
+func (p *Parameters) Retries() bool {
+	return p.MaxRetries > 0
+}
+
 // MarshalLogObject converts the Parameters into an object (which will become json) for logging.
 func (p *Parameters) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	oe.AddUint(logNClosestResponses, p.NClosestResponses)
 	oe.AddUint(logNMaxErrors, p.NMaxErrors)
 	oe.AddUint(logConcurrency, p.Concurrency)
 	oe.AddDuration(logTimeout, p.Timeout)
+	oe.AddDuration(logTotalTimeout, p.TotalTimeout)
+	oe.AddUint(logMaxResponded, p.MaxResponded)
+	oe.AddUint(logNDisjointPaths, p.NDisjointPaths)
+	if p.Adaptive() {
+		oe.AddUint(logMaxConcurrency, p.MaxConcurrency)
+		oe.AddDuration(logLatencyBudget, p.LatencyBudget)
+	}
+	if p.hedgeDelay() > 0 {
+		oe.AddFloat64(logHedgeFraction, p.HedgeFraction)
+	}
+	if p.ValueQuorum > 1 {
+		oe.AddUint(logValueQuorum, p.ValueQuorum)
+	}
+	if p.VerifyValueKey {
+		oe.AddBool(logVerifyValueKey, p.VerifyValueKey)
+	}
+	if p.ReputationWeighted {
+		oe.AddBool(logRepWeighted, p.ReputationWeighted)
+	}
+	if p.LatencyWeighted {
+		oe.AddBool(logLatencyWeighted, p.LatencyWeighted)
+	}
+	if p.ReadRepair {
+		oe.AddBool(logReadRepair, p.ReadRepair)
+	}
+	if p.CachesNotFound() {
+		oe.AddDuration(logNotFoundTTL, p.NotFoundTTL)
+		oe.AddUint(logNotFoundCacheSize, p.NotFoundCacheSize)
+	}
+	if p.Retries() {
+		oe.AddUint(logMaxRetries, p.MaxRetries)
+		oe.AddDuration(logRetryMaxBackoff, p.RetryMaxBackoff)
+	}
 	return nil
 }
 
@@ -87,6 +343,9 @@ type Result struct {
 	// Value found when looking for one, otherwise nil
 	Value *api.Document
 
+	// ExpireTime is the Unix time (seconds) the found value expires, or zero if it never does
+	ExpireTime int64
+
 	// Closest is a heap of the responding peers found closest to the target
 	Closest FarthestPeers
 
@@ -97,33 +356,140 @@ type Result struct {
 	// haven't yet necessarily responded or errored)
 	Queried map[string]struct{}
 
-	// Responded is a map of all peers that responded during search
+	// Responded is a map of the peers that responded during search, up to
+	// Parameters.MaxResponded
 	Responded map[string]peer.Peer
 
-	// Errored contains the errors received by each peer (via string representation of peer ID)
+	// Errored contains the errors received by each peer (via string representation of peer ID),
+	// up to the first error beyond Parameters.NMaxErrors
 	Errored map[string]error
 
+	// Missing is a map of the responding peers that didn't return the value, up to
+	// Parameters.MaxResponded, when the search records them (see Parameters.ReadRepair)
+	Missing map[string]peer.Peer
+
 	// FatalErr is a fatal error that occurred during the search
 	FatalErr error
+
+	// Paths contains the result of each disjoint lookup path, indexed by path, when the search
+	// used more than one (see Parameters.NDisjointPaths)
+	Paths []*Result
+
+	// distinct peers (keyed by peer ID) that returned each value (keyed by hash) when the
+	// search requires a quorum
+	valueVotes map[string]map[string]struct{}
+
+	// whether the maps came from (and should be returned to) the pools
+	pooled bool
 }
 
+var (
+	queriedPool = sync.Pool{
+		New: func() interface{} { return make(map[string]struct{}) },
+	}
+	respondedPool = sync.Pool{
+		New: func() interface{} { return make(map[string]peer.Peer) },
+	}
+	erroredPool = sync.Pool{
+		New: func() interface{} { return make(map[string]error) },
+	}
+)
+
 // NewInitialResult creates a new Result object for the beginning of a search.
 func NewInitialResult(key id.ID, params *Parameters) *Result {
 	return &Result{
 		Value:     nil,
 		Closest:   NewFarthestPeers(key, params.NClosestResponses),
-		Unqueried: NewClosestPeers(key, params.NClosestResponses*params.Concurrency),
-		Queried:   make(map[string]struct{}),
-		Responded: make(map[string]peer.Peer),
-		Errored:   make(map[string]error),
+		Unqueried: NewClosestPeers(key, params.NClosestResponses*params.maxConcurrency()),
+		Queried:   queriedPool.Get().(map[string]struct{}),
+		Responded: respondedPool.Get().(map[string]peer.Peer),
+		Errored:   erroredPool.Get().(map[string]error),
+		pooled:    true,
+	}
+}
+
+// Release drops the Result's references to peers and errors, returning its maps to their pools
+// for reuse by later searches. The Result's collections must not be used after it is released.
+func (r *Result) Release() {
+	for _, pr := range r.Paths {
+		pr.Release()
+	}
+	if r.pooled {
+		if len(r.Queried) <= maxPooledMapLen {
+			for k := range r.Queried {
+				delete(r.Queried, k)
+			}
+			queriedPool.Put(r.Queried)
+		}
+		if len(r.Responded) <= maxPooledMapLen {
+			for k := range r.Responded {
+				delete(r.Responded, k)
+			}
+			respondedPool.Put(r.Responded)
+		}
+		if len(r.Errored) <= maxPooledMapLen {
+			for k := range r.Errored {
+				delete(r.Errored, k)
+			}
+			erroredPool.Put(r.Errored)
+		}
+		r.pooled = false
+	}
+	r.Closest, r.Unqueried = nil, nil
+	r.Queried, r.Responded, r.Errored = nil, nil, nil
+	r.Missing = nil
+	r.Paths = nil
+	r.valueVotes = nil
+}
+
+// addMissing records that the peer responded without the value.
+func (r *Result) addMissing(p peer.Peer, maxMissing uint) {
+	if r.Missing == nil {
+		r.Missing = make(map[string]peer.Peer)
+	}
+	if maxMissing == 0 || uint(len(r.Missing)) < maxMissing {
+		r.Missing[p.ID().String()] = p
 	}
 }
 
+// MissingClosest returns the closest peers that responded without the value, which should
+// (re-)store it if it was found.
+func (r *Result) MissingClosest() []peer.Peer {
+	missing := make([]peer.Peer, 0, len(r.Missing))
+	for _, p := range r.Closest.Peers() {
+		if _, in := r.Missing[p.ID().String()]; in {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// addValueVote records that the peer with the given ID returned the value with the given hash,
+// returning the number of distinct peers that have returned it.
+func (r *Result) addValueVote(valueHash string, peerID id.ID) uint {
+	if r.valueVotes == nil {
+		r.valueVotes = make(map[string]map[string]struct{})
+	}
+	voters, in := r.valueVotes[valueHash]
+	if !in {
+		voters = make(map[string]struct{})
+		r.valueVotes[valueHash] = voters
+	}
+	voters[peerID.String()] = struct{}{}
+	return uint(len(voters))
+}
+
 // MarshalLogObject converts the Result into an object (which will become json) for logging.
 func (r *Result) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	oe.AddInt(logNClosest, r.Closest.Len())
 	oe.AddInt(logNUnqueried, r.Unqueried.Len())
 	oe.AddInt(logNResponded, len(r.Responded))
+	if len(r.Missing) > 0 {
+		oe.AddInt(logNMissing, len(r.Missing))
+	}
+	if len(r.Paths) > 0 {
+		oe.AddInt(logNPaths, len(r.Paths))
+	}
 	errors.MaybePanic(oe.AddArray(logErrors, clogging.ToErrArray(r.Errored)))
 	if r.FatalErr != nil {
 		oe.AddString(logFatalError, r.FatalErr.Error())
@@ -145,8 +511,26 @@ type Search struct {
 	// parameters defining the search
 	Params *Parameters
 
+	// Observer optionally observes the search's progress
+	Observer Observer
+
 	// mutex used to synchronizes reads and writes to this instance
 	Mu sync.Mutex
+
+	// index of the disjoint path this search is, if it is one
+	path int
+
+	// peers claimed by each disjoint path of the parent search, if this search is one
+	claims *pathClaims
+
+	// adapts the search concurrency, if it is adaptive
+	cc *concurrencyController
+
+	// bounds the concurrent queries of all the searches in a batch, if this search is in one
+	budget querySlots
+
+	// whether this search refreshes a routing table range rather than looking for its key
+	refresh bool
 }
 
 // NewSearch creates a new Search instance for a given target, search type, and search parameters.
@@ -162,11 +546,25 @@ func NewSearch(peerID, orgID ecid.ID, key id.ID, params *Parameters) *Search {
 	}
 }
 
+// Release releases the search's result once the caller is done with it, so that retaining the
+// Search (e.g., in a log buffer or leaked goroutine) doesn't also retain every peer it touched.
+// Only the result's Value, ExpireTime and FatalErr remain usable after release.
+func (s *Search) Release() {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	if s.Result != nil {
+		s.Result.Release()
+	}
+}
+
 // MarshalLogObject converts the Search into an object (which will become json) for logging.
 func (s *Search) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	oe.AddString(logKey, id.Hex(s.Key.Bytes()))
 	errors.MaybePanic(oe.AddObject(logParams, s.Params))
 	errors.MaybePanic(oe.AddObject(logResult, s.Result))
+	if cc := s.concurrency(); cc != nil {
+		oe.AddUint(logChosenConcurrency, cc.Concurrency())
+	}
 	oe.AddBool(logFinished, s.Finished())
 	oe.AddBool(logFoundClosestPeers, s.FoundClosestPeers())
 	oe.AddBool(logFoundValue, s.FoundValue())
@@ -189,7 +587,7 @@ func (s *Search) FoundClosestPeers() bool {
 	}
 
 	// closest peers heap should have a max distance less than the min unqueried distance
-	return s.Result.Closest.PeakDistance().Cmp(s.Result.Unqueried.PeakDistance()) <= 0
+	return s.Result.Closest.PeakDistance().Cmp(s.Result.Unqueried.MinDistance()) <= 0
 }
 
 // FoundValue returns whether the search has found the target value.
@@ -227,6 +625,33 @@ func (s *Search) AddQueried(p peer.Peer) {
 	s.Result.Queried[p.ID().String()] = struct{}{}
 }
 
+// addQueriedOnce adds a peer to the queried set, returning false if it was already there. It
+// prevents a peer re-discovered while queued (e.g., during a hedge) from being queried and
+// counted twice.
+func (s *Search) addQueriedOnce(p peer.Peer) bool {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	key := p.ID().String()
+	if _, in := s.Result.Queried[key]; in {
+		return false
+	}
+	s.Result.Queried[key] = struct{}{}
+	return true
+}
+
+func (s *Search) concurrency() *concurrencyController {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	return s.cc
+}
+
+// observe passes the event to the search's Observer, if it has one.
+func (s *Search) observe(e *Event) {
+	if s.Observer != nil {
+		s.Observer.Observe(e)
+	}
+}
+
 func (s *Search) wrapLock(operation func()) {
 	s.Mu.Lock()
 	defer s.Mu.Unlock()
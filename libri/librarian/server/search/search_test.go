@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
@@ -20,6 +21,20 @@ func TestNewDefaultParameters(t *testing.T) {
 	assert.NotZero(t, p.NMaxErrors)
 	assert.NotZero(t, p.Concurrency)
 	assert.NotZero(t, p.Timeout)
+	assert.NotZero(t, p.MaxResponded)
+	assert.NotZero(t, p.NDisjointPaths)
+	assert.NotZero(t, p.TotalTimeout)
+}
+
+func TestParameters_Validate(t *testing.T) {
+	p := NewDefaultParameters()
+	assert.Nil(t, p.Validate())
+
+	p.KeyspaceWidth = 0 // defaults to id.Length
+	assert.Nil(t, p.Validate())
+
+	p.KeyspaceWidth = id.Length + 1
+	assert.NotNil(t, p.Validate())
 }
 
 func TestParameters_MarshalLogObject(t *testing.T) {
@@ -27,6 +42,34 @@ func TestParameters_MarshalLogObject(t *testing.T) {
 	p := NewDefaultParameters()
 	err := p.MarshalLogObject(oe)
 	assert.Nil(t, err)
+
+	p.HedgeFraction = 0.5
+	p.ValueQuorum = 2
+	p.VerifyValueKey = true
+	p.ReadRepair = true
+	err = p.MarshalLogObject(oe)
+	assert.Nil(t, err)
+}
+
+func TestParameters_hedgeDelay(t *testing.T) {
+	p := &Parameters{Timeout: time.Second}
+	assert.Zero(t, p.hedgeDelay())
+
+	p.HedgeFraction = 0.25
+	assert.Equal(t, 250*time.Millisecond, p.hedgeDelay())
+
+	p.HedgeFraction = 1.0
+	assert.Zero(t, p.hedgeDelay())
+}
+
+func TestSearch_addQueriedOnce(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	s := NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng), id.NewPseudoRandom(rng),
+		NewDefaultParameters())
+	p := peer.NewTestPeer(rng, 0)
+	assert.True(t, s.addQueriedOnce(p))
+	assert.False(t, s.addQueriedOnce(p))
+	assert.Len(t, s.Result.Queried, 1)
 }
 
 func TestResult_MarshalLogObject(t *testing.T) {
@@ -35,6 +78,7 @@ func TestResult_MarshalLogObject(t *testing.T) {
 	r := NewInitialResult(id.NewPseudoRandom(rng), NewDefaultParameters())
 	r.Errored["some peer ID"] = errors.New("some error")
 	r.FatalErr = errors.New("some fatal error")
+	r.addMissing(peer.NewTestPeer(rng, 0), 0)
 	err := r.MarshalLogObject(oe)
 	assert.Nil(t, err)
 }
@@ -140,3 +184,74 @@ func TestSearch_Exhausted(t *testing.T) {
 	search2.Result.Unqueried.SafePush(peer.New(id.FromInt64(1), "", nil))
 	assert.False(t, search1.Exhausted())
 }
+
+func TestResult_Release(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := NewInitialResult(id.NewPseudoRandom(rng), NewDefaultParameters())
+	p := peer.NewTestPeer(rng, 0)
+	r.Queried[p.ID().String()] = struct{}{}
+	r.Responded[p.ID().String()] = p
+	r.Errored[p.ID().String()] = errors.New("some error")
+	r.Value, _ = api.NewTestDocument(rng)
+
+	r.Release()
+	assert.Nil(t, r.Closest)
+	assert.Nil(t, r.Unqueried)
+	assert.Nil(t, r.Queried)
+	assert.Nil(t, r.Responded)
+	assert.Nil(t, r.Errored)
+	assert.NotNil(t, r.Value)
+
+	// releasing again is a no-op
+	r.Release()
+
+	// new results always start empty, even when reusing released maps
+	for c := 0; c < 8; c++ {
+		r2 := NewInitialResult(id.NewPseudoRandom(rng), NewDefaultParameters())
+		assert.Len(t, r2.Queried, 0)
+		assert.Len(t, r2.Responded, 0)
+		assert.Len(t, r2.Errored, 0)
+		r2.Release()
+	}
+}
+
+func TestResult_Release_notPooled(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p := peer.NewTestPeer(rng, 0)
+	responded := map[string]peer.Peer{p.ID().String(): p}
+	r := &Result{Responded: responded}
+
+	// maps not from the pools are left alone
+	r.Release()
+	assert.Nil(t, r.Responded)
+	assert.Len(t, responded, 1)
+}
+
+func TestSearch_Release_retained(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	params := NewDefaultParameters()
+	params.MaxResponded = 0
+	peers := peer.NewTestPeers(rng, 128)
+	nSearches := 16
+
+	// callers retaining finished searches don't also retain every peer they touched
+	retained := make([]*Search, nSearches)
+	for i := range retained {
+		s := NewSearch(peerID, orgID, id.NewPseudoRandom(rng), params)
+		for _, p := range peers {
+			s.AddQueried(p)
+			s.Result.Responded[p.ID().String()] = p
+		}
+		s.Result.Errored[peers[0].ID().String()] = errors.New("some Find error")
+		s.Release()
+		retained[i] = s
+	}
+	for _, s := range retained {
+		assert.Nil(t, s.Result.Closest)
+		assert.Nil(t, s.Result.Unqueried)
+		assert.Nil(t, s.Result.Queried)
+		assert.Nil(t, s.Result.Responded)
+		assert.Nil(t, s.Result.Errored)
+	}
+}
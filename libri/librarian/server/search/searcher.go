@@ -3,7 +3,9 @@ package search
 import (
 	"bytes"
 	"container/heap"
+	"crypto/sha256"
 	"errors"
+	"net"
 	"sync"
 	"time"
 
@@ -12,6 +14,8 @@ import (
 	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
 )
 
 const searcherFindRetryTimeout = 25 * time.Millisecond
@@ -21,13 +25,23 @@ var (
 	// errors.
 	ErrTooManyFindErrors = errors.New("too many Find errors")
 
+	// ErrSearchTimeout indicates when a search has exceeded its total timeout.
+	ErrSearchTimeout = errors.New("search exceeded total timeout")
+
+	// ErrSearchCanceled indicates when a search's context was canceled before it finished.
+	ErrSearchCanceled = errors.New("search canceled")
+
+	// ErrKeyOutsideKeyspace indicates when a search's key lies outside the keyspace of the search
+	// parameters' width.
+	ErrKeyOutsideKeyspace = errors.New("search key outside keyspace")
+
 	errInvalidResponse = errors.New("FindResponse contains neither value nor peer addresses")
 )
 
 // Searcher executes searches for particular keys.
 type Searcher interface {
-	// Search executes a search from a list of seeds.
-	Search(search *Search, seeds []peer.Peer) error
+	// Search executes a search from a list of seeds, abandoning it if the context is canceled.
+	Search(ctx context.Context, search *Search, seeds []peer.Peer) error
 }
 
 type searcher struct {
@@ -35,86 +49,198 @@ type searcher struct {
 	orgSigner     client.Signer
 	finderCreator client.FinderCreator
 	doc           comm.Doctor
+	bl            comm.Blacklister
 	rp            ResponseProcessor
 	rec           comm.QueryRecorder
+	krec          comm.KeyspaceRecorder
+	pref          comm.Preferer
+	judge         comm.ReputationJudge
+	latencies     comm.LatencyTracker
+	latencyPref   comm.Preferer
+	tracer        Tracer
 }
 
-// NewSearcher returns a new Searcher with the given Querier and ResponseProcessor.
+// NewSearcher returns a new Searcher with the given Querier and ResponseProcessor. If the
+// recorder is also a ReputationJudge, reputation-weighted searches prefer peers by their
+// reputations and the searcher records peers' response latencies with it.
 func NewSearcher(
 	peerSigner client.Signer,
 	orgSigner client.Signer,
 	rec comm.QueryRecorder,
+	krec comm.KeyspaceRecorder,
 	doc comm.Doctor,
+	bl comm.Blacklister,
 	c client.FinderCreator,
 	rp ResponseProcessor,
 ) Searcher {
-	return &searcher{
+	s := &searcher{
 		peerSigner:    peerSigner,
 		orgSigner:     orgSigner,
 		finderCreator: c,
 		doc:           doc,
+		bl:            bl,
 		rp:            rp,
 		rec:           rec,
+		krec:          krec,
+		tracer:        NewNoOpTracer(),
+	}
+	if judge, ok := rec.(comm.ReputationJudge); ok {
+		s.judge = judge
+		s.pref = comm.NewReputationPreferer(judge)
 	}
+	return s
 }
 
-// NewDefaultSearcher creates a new Searcher with default sub-object instantiations.
+// NewDefaultSearcher creates a new Searcher with default sub-object instantiations. Banned peers
+// are neither queried nor added to searches' unqueried peers.
 func NewDefaultSearcher(
 	peerSigner client.Signer,
 	orgSigner client.Signer,
 	rec comm.QueryRecorder,
+	krec comm.KeyspaceRecorder,
 	doc comm.Doctor,
+	bl comm.Blacklister,
 	clients client.Pool,
 ) Searcher {
 	return NewSearcher(
 		peerSigner,
 		orgSigner,
 		rec,
+		krec,
 		doc,
+		bl,
 		client.NewFinderCreator(clients),
-		NewResponseProcessor(peer.NewFromer(), doc),
+		NewResponseProcessor(peer.NewFromer(), comm.NewBlacklistDoctor(doc, bl)),
 	)
 }
 
-func (s *searcher) Search(search *Search, seeds []peer.Peer) error {
+// WithLatencyTracker sets the LatencyTracker a Searcher created by NewSearcher or
+// NewDefaultSearcher records peers' response latencies with, which latency-weighted searches then
+// prefer peers by.
+func WithLatencyTracker(s Searcher, lt comm.LatencyTracker) Searcher {
+	s.(*searcher).latencies = lt
+	s.(*searcher).latencyPref = comm.NewLatencyPreferer(lt)
+	return s
+}
+
+func (s *searcher) Search(parent context.Context, search *Search, seeds []peer.Peer) error {
+	if !id.InKeyspace(search.Key, id.WidthOrDefault(search.Params.KeyspaceWidth)) {
+		search.wrapLock(func() { search.Result.FatalErr = ErrKeyOutsideKeyspace })
+		return ErrKeyOutsideKeyspace
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if search.Params.TotalTimeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, search.Params.TotalTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	defer cancel()
+	ctx, span := startSearchSpan(ctx, s.tracer, search)
+	defer endSearchSpan(span, search)
+	var err error
+	if search.Params.NDisjointPaths > 1 {
+		err = s.searchDisjoint(ctx, search, seeds)
+	} else {
+		err = s.search(ctx, search, seeds)
+	}
+	if (err == ErrSearchTimeout || err == ErrSearchCanceled) && parent.Err() != nil {
+		// the caller's context ended the search, so pass its error through unchanged
+		err = parent.Err()
+		search.wrapLock(func() { search.Result.FatalErr = err })
+	}
+	return err
+}
+
+// search executes a single-path search, abandoning it when the context is done.
+func (s *searcher) search(parent context.Context, search *Search, seeds []peer.Peer) error {
+	ctx, cancel := context.WithCancel(parent)
+	interrupted := make(chan struct{})
+	go func() {
+		defer close(interrupted)
+		<-ctx.Done()
+		if err := interruptErr(parent); err != nil && !search.Finished() {
+			search.wrapLock(func() {
+				if search.Result.FatalErr == nil {
+					search.Result.FatalErr = err
+				}
+			})
+		}
+	}()
+
 	toQuery := NewQueryQueue()
 	peerResponses := make(chan *peerResponse, 1)
+	var cc *concurrencyController
+	if search.Params.Adaptive() {
+		cc = newConcurrencyController(search.Params)
+		search.wrapLock(func() { search.cc = cc })
+	}
+
+	if search.Params.LatencyWeighted && s.latencyPref != nil {
+		search.wrapLock(func() { reweightUnqueried(search.Result, search.Key, s.latencyPref) })
+	} else if search.Params.ReputationWeighted && s.pref != nil {
+		search.wrapLock(func() { reweightUnqueried(search.Result, search.Key, s.pref) })
+	}
 
 	// add seeds and queue some of them for querying
-	search.Result.Unqueried.SafePushMany(seeds)
+	search.Result.Unqueried.SafePushMany(s.unbanned(seeds))
 
-	go func() {
-		for c := uint(0); c < search.Params.Concurrency; c++ {
-			if next := getNextToQuery(search); next != nil {
-				toQuery.MaybeSend(next)
+	initial := make([]peer.Peer, 0, search.Params.Concurrency)
+	for c := uint(0); c < search.Params.Concurrency; c++ {
+		if next := getNextToQuery(search); next != nil {
+			initial = append(initial, next)
+			if cc != nil {
+				cc.sent()
 			}
 		}
+	}
+	go func() {
+		for _, next := range initial {
+			toQuery.MaybeSend(next)
+		}
 	}()
 
-	// goroutine that processes responses and queues up next peer to query
+	// goroutine that processes responses and queues up next peer(s) to query
 	var wg1 sync.WaitGroup
 	wg1.Add(1)
 	go func(wg2 *sync.WaitGroup) {
 		defer wg2.Done()
 		for pr := range peerResponses {
 			s.processAnyReponse(pr, search)
-			maybeSendNextToQuery(toQuery, search)
+			if pr.hedge {
+				// hedge queries don't take a concurrency slot, so don't replace them
+				if search.Finished() || search.Exhausted() {
+					toQuery.MaybeClose()
+				}
+				continue
+			}
+			if cc == nil {
+				maybeSendNextToQuery(toQuery, search)
+				continue
+			}
+			maybeSendNextToQueryAdaptive(toQuery, search, cc, cc.received(pr.rtt, pr.err))
 		}
 	}(&wg1)
 
-	// concurrent goroutines that issue queries to peers
+	// concurrent goroutines that issue queries to peers, enough for the max concurrency
 	var wg3 sync.WaitGroup
-	for c := uint(0); c < search.Params.Concurrency; c++ {
+	for c := uint(0); c < search.Params.maxConcurrency(); c++ {
 		wg3.Add(1)
 		go func(wg4 *sync.WaitGroup) {
 			defer wg4.Done()
 			for next := range toQuery.Peers {
-				search.AddQueried(next)
-				response, err := s.query(next, search)
-				peerResponses <- &peerResponse{
-					peer:     next,
-					response: response,
-					err:      err,
+				if ctx.Err() != nil {
+					// search has been abandoned, so don't bother querying
+					peerResponses <- &peerResponse{peer: next, err: ctx.Err(), aborted: true}
+					continue
+				}
+				if !search.addQueriedOnce(next) {
+					// peer was re-discovered and queried while this one was queued
+					peerResponses <- &peerResponse{peer: next, aborted: true}
+					continue
+				}
+				for _, pr := range s.queryHedged(ctx, next, search) {
+					peerResponses <- pr
 				}
 			}
 		}(&wg3)
@@ -123,20 +249,135 @@ func (s *searcher) Search(search *Search, seeds []peer.Peer) error {
 	close(peerResponses)
 
 	wg1.Wait()
+
+	// wait for interrupt goroutine so it can't change the result after we return
+	cancel()
+	<-interrupted
 	return search.Result.FatalErr
 }
 
-func (s *searcher) query(next peer.Peer, search *Search) (*api.FindResponse, error) {
-	lc, err := s.finderCreator.Create(next.Address().String())
+// reweightUnqueried replaces the result's unqueried peers with a heap ordering them by preference
+// within each distance bucket.
+func reweightUnqueried(result *Result, key id.ID, pref comm.Preferer) {
+	prev := result.Unqueried
+	result.Unqueried = NewClosestPeersWithComparator(key, uint(prev.Capacity()),
+		NewPreferenceComparator(pref))
+	for prev.Len() > 0 {
+		result.Unqueried.SafePush(heap.Pop(prev).(peer.Peer))
+	}
+}
+
+// interruptErr returns the fatal error for a search whose (parent) context is done, or nil if
+// it isn't.
+func interruptErr(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return ErrSearchTimeout
+	default:
+		return ErrSearchCanceled
+	}
+}
+
+// queryHedged queries the already-claimed peer. If the search hedges and the peer hasn't
+// responded within the hedge delay, it also queries the next unqueried peer, and the first
+// successful response abandons the other query. The returned responses are in the order they
+// completed, with the second marked as a hedge so it doesn't replace the worker's concurrency
+// slot.
+func (s *searcher) queryHedged(
+	ctx context.Context, next peer.Peer, search *Search,
+) []*peerResponse {
+	delay := search.Params.hedgeDelay()
+	if delay == 0 {
+		return []*peerResponse{s.queryPeer(ctx, next, search)}
+	}
+	hedgeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	responses := make(chan *peerResponse, 2)
+	go func() { responses <- s.queryPeer(hedgeCtx, next, search) }()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case pr := <-responses:
+		return []*peerResponse{pr}
+	case <-timer.C:
+	}
+	hedge := getNextToQuery(search)
+	if hedge == nil || !search.addQueriedOnce(hedge) {
+		return []*peerResponse{<-responses}
+	}
+	go func() { responses <- s.queryPeer(hedgeCtx, hedge, search) }()
+
+	first := <-responses
+	if first.err == nil {
+		// take the first response, abandoning the slower query
+		cancel()
+	}
+	second := <-responses
+	second.hedge = true
+	return []*peerResponse{first, second}
+}
+
+// queryPeer queries a peer already added to the search's queried peers.
+func (s *searcher) queryPeer(ctx context.Context, next peer.Peer, search *Search) *peerResponse {
+	if err := search.budget.acquire(ctx); err != nil {
+		return &peerResponse{peer: next, err: err, aborted: true}
+	}
+	defer search.budget.release()
+	search.observe(&Event{Type: PeerQueried, Peer: next})
+	ctx, span := s.tracer.Start(ctx, QuerySpanName)
+	start := time.Now()
+	response, address, err := s.query(ctx, next, search)
+	rtt := time.Since(start)
+	aborted := err != nil && ctx.Err() != nil
+	throttled := err == comm.ErrQueryThrottled
+	if !aborted && !throttled {
+		s.recordLatency(search.Key, next, rtt, err)
+	}
+	pr := &peerResponse{
+		peer:      next,
+		address:   address,
+		response:  response,
+		err:       err,
+		rtt:       rtt,
+		aborted:   aborted,
+		throttled: throttled,
+	}
+	endQuerySpan(span, next, pr)
+	return pr
+}
+
+// query queries the peer's addresses in turn, returning the response along with the address last
+// queried.
+func (s *searcher) query(ctx context.Context, next peer.Peer, search *Search) (
+	*api.FindResponse, *net.TCPAddr, error) {
+	var rp *api.FindResponse
+	var queried *net.TCPAddr
+	err := peer.QueryAddresses(next, func(address *net.TCPAddr) error {
+		var err error
+		queried = address
+		rp, err = s.queryAddress(ctx, address, search)
+		return err
+	})
+	return rp, queried, err
+}
+
+func (s *searcher) queryAddress(
+	parent context.Context, address *net.TCPAddr, search *Search,
+) (*api.FindResponse, error) {
+	lc, err := s.finderCreator.Create(address.String())
 	if err != nil {
 		return nil, err
 	}
 	rq := search.CreatRq()
-	ctx, cancel, err := client.NewSignedTimeoutContext(s.peerSigner, s.orgSigner, rq,
+	ctx, cancel, err := client.NewSignedTimeoutContextFrom(parent, s.peerSigner, s.orgSigner, rq,
 		search.Params.Timeout)
 	if err != nil {
 		return nil, err
 	}
+	ctx = client.AddMacaroonContext(ctx, search.Params.Macaroon)
 	retryFindClient := client.NewRetryFinder(lc, searcherFindRetryTimeout)
 	rp, err := retryFindClient.Find(ctx, rq)
 	cancel()
@@ -186,8 +427,19 @@ func (qq *QueryQueue) MaybeClose() {
 
 type peerResponse struct {
 	peer     peer.Peer
+	address  *net.TCPAddr
 	response *api.FindResponse
 	err      error
+	rtt      time.Duration
+
+	// whether the query was abandoned b/c the search was, so the error isn't the peer's fault
+	aborted bool
+
+	// whether the query was dropped by the outbound throttle, so the peer was never sent it
+	throttled bool
+
+	// whether the response is the extra one from a hedged query
+	hedge bool
 }
 
 func getNextToQuery(search *Search) peer.Peer {
@@ -203,6 +455,10 @@ func getNextToQuery(search *Search) peer.Peer {
 	if _, alreadyQueried := search.Result.Queried[next.ID().String()]; alreadyQueried {
 		return nil
 	}
+	if search.claims != nil && !search.claims.claim(next.ID(), search.path) {
+		// another disjoint path has or will query the peer
+		return nil
+	}
 	return next
 }
 
@@ -219,19 +475,82 @@ func maybeSendNextToQuery(toQuery *QueryQueue, search *Search) {
 	}
 }
 
+// maybeSendNextToQueryAdaptive queues up to n next peers to query. When n is zero, it only
+// closes the queue if the search is done, since other queries are still in flight.
+func maybeSendNextToQueryAdaptive(
+	toQuery *QueryQueue, search *Search, cc *concurrencyController, n uint,
+) {
+	if n == 0 {
+		if search.Finished() || search.Exhausted() {
+			toQuery.MaybeClose()
+		}
+		return
+	}
+	cc.sent()
+	maybeSendNextToQuery(toQuery, search)
+	for c := uint(1); c < n; c++ {
+		next := getNextToQuery(search)
+		if next == nil {
+			return
+		}
+		cc.sent()
+		toQuery.MaybeSend(next)
+	}
+}
+
 func (s *searcher) processAnyReponse(pr *peerResponse, search *Search) {
-	if pr.err != nil {
-		s.recordError(pr.peer, pr.err, search)
-	} else if err := s.rp.Process(pr.response, search); err != nil {
-		s.recordError(pr.peer, err, search)
+	if pr.aborted || pr.throttled {
+		return
+	} else if pr.err != nil {
+		s.recordError(pr.peer, pr.address, pr.err, search)
+		search.observe(&Event{Type: PeerResponded, Peer: pr.peer, Err: pr.err})
+	} else if err := s.rp.Process(pr.response, pr.peer, search); err != nil {
+		s.recordError(pr.peer, pr.address, err, search)
+		search.observe(&Event{Type: PeerResponded, Peer: pr.peer, Err: err})
 	} else {
-		s.recordSuccess(pr.peer, search)
+		nClosest, closestChanged := s.recordSuccess(pr.peer, search)
+		if pr.response.Value == nil && search.Params.ReadRepair {
+			search.wrapLock(func() {
+				search.Result.addMissing(pr.peer, search.Params.MaxResponded)
+			})
+		}
+		search.observe(&Event{Type: PeerResponded, Peer: pr.peer})
+		if closestChanged {
+			search.observe(&Event{Type: ClosestChanged, Peer: pr.peer, NClosest: nClosest})
+		}
+		if pr.response.Value != nil && search.FoundValue() {
+			search.observe(&Event{Type: ValueFound, Peer: pr.peer})
+		}
+	}
+}
+
+func (s *searcher) unbanned(peers []peer.Peer) []peer.Peer {
+	unbanned := make([]peer.Peer, 0, len(peers))
+	for _, p := range peers {
+		if !s.bl.Banned(p.ID()) && !s.bannedAddress(p.Address()) {
+			unbanned = append(unbanned, p)
+		}
 	}
+	return unbanned
 }
 
-func (s *searcher) recordError(p peer.Peer, err error, search *Search) {
+func (s *searcher) bannedAddress(address *net.TCPAddr) bool {
+	return address != nil && s.bl.BannedIP(address.IP)
+}
+
+// recordError records the error from querying the peer at the given address. Protocol violations
+// are attributed to the address rather than the peer ID, since the ID is only what we were told
+// the peer is and isn't authenticated by the response.
+func (s *searcher) recordError(p peer.Peer, address *net.TCPAddr, err error, search *Search) {
+	protocolViolation := err == errInvalidResponse || err == client.ErrUnexpectedRequestID
+	if protocolViolation && address != nil {
+		s.bl.RecordIPMisbehavior(address.IP, comm.ProtocolViolation)
+	}
 	search.wrapLock(func() {
-		search.Result.Errored[p.ID().String()] = err
+		// errors beyond the first one past NMaxErrors don't change the outcome
+		if uint(len(search.Result.Errored)) <= search.Params.NMaxErrors {
+			search.Result.Errored[p.ID().String()] = err
+		}
 	})
 	if search.Errored() {
 		search.wrapLock(func() {
@@ -241,19 +560,44 @@ func (s *searcher) recordError(p peer.Peer, err error, search *Search) {
 	comm.MaybeRecordRpErr(s.rec, p.ID(), api.Find, err)
 }
 
-func (s *searcher) recordSuccess(p peer.Peer, search *Search) {
+func (s *searcher) recordLatency(key id.ID, p peer.Peer, latency time.Duration, err error) {
+	if err != nil {
+		s.krec.Record(key, latency, comm.Error)
+		return
+	}
+	s.krec.Record(key, latency, comm.Success)
+	if s.judge != nil {
+		s.judge.RecordLatency(p.ID(), latency)
+	}
+	if s.latencies != nil {
+		s.latencies.RecordLatency(p.ID(), latency)
+	}
+}
+
+// recordSuccess records the peer's successful response, returning the number of closest peers
+// and whether the peer joined them.
+func (s *searcher) recordSuccess(p peer.Peer, search *Search) (int, bool) {
+	var nClosest int
+	var closestChanged bool
 	search.wrapLock(func() {
+		wasClosest := search.Result.Closest.In(p.ID())
 		search.Result.Closest.SafePush(p)
-		search.Result.Responded[p.ID().String()] = p
+		closestChanged = !wasClosest && search.Result.Closest.In(p.ID())
+		nClosest = search.Result.Closest.Len()
+		maxResponded := search.Params.MaxResponded
+		if maxResponded == 0 || uint(len(search.Result.Responded)) < maxResponded {
+			search.Result.Responded[p.ID().String()] = p
+		}
 	})
 	s.rec.Record(p.ID(), api.Find, comm.Response, comm.Success)
+	return nClosest, closestChanged
 }
 
 // ResponseProcessor handles an api.FindResponse
 type ResponseProcessor interface {
-	// Process handles an api.FindResponse, adding newly discovered peers to the unqueried
-	// ClosestPeers heap.
-	Process(*api.FindResponse, *Search) error
+	// Process handles an api.FindResponse from the given peer, adding newly discovered peers to
+	// the unqueried ClosestPeers heap.
+	Process(rp *api.FindResponse, from peer.Peer, s *Search) error
 }
 
 type responseProcessor struct {
@@ -270,11 +614,10 @@ func NewResponseProcessor(f peer.Fromer, doc comm.Doctor) ResponseProcessor {
 }
 
 // Process processes an api.FindResponse, updating the result with the newly found peers.
-func (frp *responseProcessor) Process(rp *api.FindResponse, s *Search) error {
+func (frp *responseProcessor) Process(rp *api.FindResponse, from peer.Peer, s *Search) error {
 	if rp.Value != nil {
 		// response has value we're searching for
-		s.Result.Value = rp.Value
-		return nil
+		return processValue(rp, from, s)
 	}
 
 	if rp.Peers != nil {
@@ -304,6 +647,38 @@ func (frp *responseProcessor) Process(rp *api.FindResponse, s *Search) error {
 	return errInvalidResponse
 }
 
+// processValue sets the result value once it has been verified against the search key or
+// returned by Parameters.ValueQuorum distinct peers. Votes are counted by the ID of the peer we
+// queried rather than the self-reported response public key, which any one peer could vary.
+func processValue(rp *api.FindResponse, from peer.Peer, s *Search) error {
+	verified := false
+	if s.Params.VerifyValueKey {
+		key, err := api.GetKey(rp.Value)
+		if err != nil || key.Cmp(s.Key) != 0 {
+			return errInvalidResponse
+		}
+		// system document and shard manifest keys don't depend on their contents, so they need
+		// a quorum
+		verified = rp.Value.GetSystem() == nil && rp.Value.GetShardManifest() == nil
+	}
+	if verified || s.Params.ValueQuorum <= 1 {
+		s.wrapLock(func() { s.Result.Value, s.Result.ExpireTime = rp.Value, rp.ExpireTime })
+		return nil
+	}
+	valueBytes, err := proto.Marshal(rp.Value)
+	if err != nil {
+		return err
+	}
+	valueHash := sha256.Sum256(valueBytes)
+	s.wrapLock(func() {
+		nVotes := s.Result.addValueVote(string(valueHash[:]), from.ID())
+		if nVotes >= s.Params.ValueQuorum {
+			s.Result.Value, s.Result.ExpireTime = rp.Value, rp.ExpireTime
+		}
+	})
+	return nil
+}
+
 // AddPeers adds a list of peer address to the unqueried heap.
 func AddPeers(
 	queried map[string]struct{},
@@ -5,16 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
+	cstorage "github.com/drausin/libri/libri/common/storage"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 )
 
 func TestNewDefaultSearcher(t *testing.T) {
@@ -22,7 +26,9 @@ func TestNewDefaultSearcher(t *testing.T) {
 		&client.TestNoOpSigner{},
 		&client.TestNoOpSigner{},
 		&fixedRecorder{},
+		comm.NewNoOpKeyspaceRecorder(),
 		comm.NewNaiveDoctor(),
+		comm.NewNaiveBlacklister(),
 		nil,
 	)
 	assert.NotNil(t, s.(*searcher).peerSigner)
@@ -30,6 +36,8 @@ func TestNewDefaultSearcher(t *testing.T) {
 	assert.NotNil(t, s.(*searcher).finderCreator)
 	assert.NotNil(t, s.(*searcher).rp)
 	assert.NotNil(t, s.(*searcher).rec)
+	assert.NotNil(t, s.(*searcher).krec)
+	assert.NotNil(t, s.(*searcher).bl)
 }
 
 func TestSearcher_Search_ok(t *testing.T) {
@@ -57,7 +65,7 @@ func TestSearcher_Search_ok(t *testing.T) {
 		seeds := NewTestSeeds(peers, selfPeerIdxs)
 
 		// do the search!
-		err := searcher.Search(search, seeds)
+		err := searcher.Search(context.Background(), search, seeds)
 
 		// checks
 		assert.Nil(t, err)
@@ -105,9 +113,11 @@ func TestSearcher_Search_queryErr(t *testing.T) {
 	searcherImpl.(*searcher).finderCreator = &TestFinderCreator{
 		err: errors.New("some Create error"),
 	}
+	krg := comm.NewKeyspaceRecorderGetter(comm.DefaultKeyspacePrefixBits)
+	searcherImpl.(*searcher).krec = krg
 
 	// do the search!
-	err := searcherImpl.Search(search, seeds)
+	err := searcherImpl.Search(context.Background(), search, seeds)
 
 	// checks
 	assert.Equal(t, ErrTooManyFindErrors, err)
@@ -120,11 +130,22 @@ func TestSearcher_Search_queryErr(t *testing.T) {
 	assert.True(t, 0 < search.Result.Unqueried.Len())
 	assert.Equal(t, 0, len(search.Result.Responded))
 	assert.Equal(t, len(search.Result.Errored), rec.nErrors)
+
+	// check query latencies got recorded in the target key's region
+	nQueries, nErrors := uint64(0), uint64(0)
+	for _, rl := range krg.Regions() {
+		nQueries += rl.NQueries
+		nErrors += rl.NErrors
+	}
+	assert.Equal(t, uint64(len(search.Result.Queried)), nQueries)
+	assert.Equal(t, nQueries, nErrors)
 }
 
 type errResponseProcessor struct{}
 
-func (erp *errResponseProcessor) Process(rp *api.FindResponse, search *Search) error {
+func (erp *errResponseProcessor) Process(
+	rp *api.FindResponse, from peer.Peer, search *Search,
+) error {
 	return errors.New("some processing error")
 }
 
@@ -137,7 +158,7 @@ func TestSearcher_Search_rpErr(t *testing.T) {
 	searcherImpl.(*searcher).rp = &errResponseProcessor{}
 
 	// do the search!
-	err := searcherImpl.Search(search, seeds)
+	err := searcherImpl.Search(context.Background(), search, seeds)
 
 	// checks
 	assert.NotNil(t, err)
@@ -153,6 +174,199 @@ func TestSearcher_Search_rpErr(t *testing.T) {
 	assert.Equal(t, len(search.Result.Errored), rec.nErrors)
 }
 
+func TestSearcher_Search_totalTimeout(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, selfPeerIdxs, peers := newTestSearch(rec)
+	seeds := NewTestSeeds(peers, selfPeerIdxs)
+	search.Params.TotalTimeout = 50 * time.Millisecond
+	search.Params.Timeout = 10 * time.Second
+
+	// all queries hang until their context is done
+	searcherImpl.(*searcher).finderCreator = &blockingFinderCreator{}
+
+	start := time.Now()
+	err := searcherImpl.Search(context.Background(), search, seeds)
+
+	assert.Equal(t, ErrSearchTimeout, err)
+	assert.True(t, time.Since(start) < search.Params.Timeout)
+	assert.True(t, search.Finished())
+	assert.False(t, search.FoundClosestPeers())
+
+	// abandoned queries aren't the peers' fault
+	assert.Equal(t, 0, len(search.Result.Errored))
+	assert.Zero(t, rec.nErrors)
+}
+
+func TestSearcher_Search_totalTimeoutDisjoint(t *testing.T) {
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	searcherImpl, search, selfPeerIdxs, peers := newTestSearch(rec)
+	seeds := NewTestSeeds(peers, selfPeerIdxs)
+	search.Params.TotalTimeout = 50 * time.Millisecond
+	search.Params.Timeout = 10 * time.Second
+	search.Params.NDisjointPaths = 2
+	searcherImpl.(*searcher).finderCreator = &blockingFinderCreator{}
+
+	err := searcherImpl.Search(context.Background(), search, seeds)
+	assert.Equal(t, ErrSearchTimeout, err)
+}
+
+func TestSearcher_Search_canceled(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, selfPeerIdxs, peers := newTestSearch(rec)
+	seeds := NewTestSeeds(peers, selfPeerIdxs)
+	search.Params.Timeout = 10 * time.Second
+	searcherImpl.(*searcher).finderCreator = &blockingFinderCreator{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := searcherImpl.Search(ctx, search, seeds)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, search.Result.FatalErr)
+	assert.True(t, time.Since(start) < search.Params.Timeout)
+	assert.Equal(t, 0, len(search.Result.Errored))
+	assert.Zero(t, rec.nErrors)
+}
+
+func TestSearcher_Search_parentTimeout(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, selfPeerIdxs, peers := newTestSearch(rec)
+	seeds := NewTestSeeds(peers, selfPeerIdxs)
+	search.Params.TotalTimeout = 10 * time.Second
+	search.Params.Timeout = 10 * time.Second
+	searcherImpl.(*searcher).finderCreator = &blockingFinderCreator{}
+
+	// parent deadline before the search's own total timeout is passed through unchanged
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := searcherImpl.Search(ctx, search, seeds)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, context.DeadlineExceeded, search.Result.FatalErr)
+}
+
+func TestSearcher_Search_outsideKeyspace(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, selfPeerIdxs, peers := newTestSearch(rec)
+	seeds := NewTestSeeds(peers, selfPeerIdxs)
+	search.Params.KeyspaceWidth = 1
+
+	err := searcherImpl.Search(context.Background(), search, seeds)
+	assert.Equal(t, ErrKeyOutsideKeyspace, err)
+	assert.True(t, search.Errored())
+	assert.Zero(t, rec.nErrors)
+}
+
+func TestSearcher_Search_hedged(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, selfPeerIdxs, peers := newTestSearch(rec)
+	seeds := NewTestSeeds(peers, selfPeerIdxs)
+	search.Params.Timeout = 10 * time.Second
+	search.Params.HedgeFraction = 0.005 // 50ms
+	search.Params.Concurrency = 2
+
+	// every other peer is slow to respond
+	fc := searcherImpl.(*searcher).finderCreator.(*TestFinderCreator)
+	i := 0
+	for address, f := range fc.finders {
+		if i%2 == 0 {
+			fc.finders[address] = &slowFinder{inner: f, delay: 500 * time.Millisecond}
+		}
+		i++
+	}
+
+	start := time.Now()
+	err := searcherImpl.Search(context.Background(), search, seeds)
+
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < search.Params.Timeout)
+	assert.True(t, search.FoundClosestPeers())
+	assert.Equal(t, 0, len(search.Result.Errored))
+	assert.Zero(t, rec.nErrors)
+	assert.True(t, len(search.Result.Responded) < len(search.Result.Queried))
+}
+
+func TestSearcher_queryHedged(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	slow, fast := peer.NewTestPeer(rng, 0), peer.NewTestPeer(rng, 1)
+	fc := &TestFinderCreator{finders: map[string]api.Finder{
+		slow.Address().String(): &slowFinder{inner: &fixedFinder{}, delay: time.Minute},
+		fast.Address().String(): &fixedFinder{addresses: newPeerAddresses(rng, 4)},
+	}}
+	s := &searcher{
+		peerSigner:    &client.TestNoOpSigner{},
+		orgSigner:     &client.TestNoOpSigner{},
+		finderCreator: fc,
+		rec:           &fixedRecorder{},
+		krec:          comm.NewNoOpKeyspaceRecorder(),
+		tracer:        NewNoOpTracer(),
+	}
+	params := NewDefaultParameters()
+	params.HedgeFraction = 0.01
+
+	// without hedging, only queries primary peer
+	search := NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng), &Parameters{Timeout: 10 * time.Millisecond})
+	search.Result.Unqueried.SafePush(fast)
+	prs := s.queryHedged(context.Background(), slow, search)
+	assert.Len(t, prs, 1)
+	assert.Equal(t, slow, prs[0].peer)
+	assert.NotNil(t, prs[0].err)
+
+	// with hedging, fast peer responds first and slow peer's query is abandoned
+	search = NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng), params)
+	search.Result.Unqueried.SafePush(fast)
+	assert.True(t, search.addQueriedOnce(slow))
+	prs = s.queryHedged(context.Background(), slow, search)
+	assert.Len(t, prs, 2)
+	assert.Equal(t, fast, prs[0].peer)
+	assert.Nil(t, prs[0].err)
+	assert.False(t, prs[0].hedge)
+	assert.Equal(t, slow, prs[1].peer)
+	assert.True(t, prs[1].aborted)
+	assert.True(t, prs[1].hedge)
+	assert.Len(t, search.Result.Queried, 2)
+
+	// with hedging but no unqueried peers, just waits for primary peer
+	search = NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng),
+		id.NewPseudoRandom(rng), params)
+	prs = s.queryHedged(context.Background(), fast, search)
+	assert.Len(t, prs, 1)
+	assert.Equal(t, fast, prs[0].peer)
+}
+
+// slowFinder responds like its inner finder after a delay, unless its context is done first.
+type slowFinder struct {
+	inner api.Finder
+	delay time.Duration
+}
+
+func (f *slowFinder) Find(ctx context.Context, rq *api.FindRequest, opts ...grpc.CallOption) (
+	*api.FindResponse, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(f.delay):
+		return f.inner.Find(ctx, rq, opts...)
+	}
+}
+
+type blockingFinder struct{}
+
+func (f *blockingFinder) Find(ctx context.Context, rq *api.FindRequest, opts ...grpc.CallOption) (
+	*api.FindResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type blockingFinderCreator struct{}
+
+func (c *blockingFinderCreator) Create(address string) (api.Finder, error) {
+	return &blockingFinder{}, nil
+}
+
 func newTestSearch(rec comm.QueryRecorder) (Searcher, *Search, []int, []peer.Peer) {
 	n, nClosestResponses := 32, uint(8)
 	rng := rand.New(rand.NewSource(int64(n)))
@@ -189,8 +403,9 @@ func TestSearcher_query_ok(t *testing.T) {
 		rp: nil,
 	}
 
-	rp, err := s.query(next, search)
+	rp, address, err := s.query(context.Background(), next, search)
 	assert.Nil(t, err)
+	assert.Equal(t, next.Address(), address)
 	assert.NotNil(t, rp.Metadata.RequestId)
 	assert.Nil(t, rp.Value)
 }
@@ -240,7 +455,7 @@ func TestSearcher_query_err(t *testing.T) {
 
 	for i, c := range cases {
 		info := fmt.Sprintf("case %d", i)
-		rp, err := c.query(next, search)
+		rp, _, err := c.query(context.Background(), next, search)
 		assert.Nil(t, rp, info)
 		assert.NotNil(t, err, info)
 	}
@@ -250,23 +465,90 @@ func TestResponseProcessor_Process_Value(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	key := id.NewPseudoRandom(rng)
 	rp := NewResponseProcessor(peer.NewFromer(), comm.NewNaiveDoctor())
+	from := peer.NewTestPeer(rng, 0)
 	s := &Search{
+		Key:    key,
 		Result: NewInitialResult(key, NewDefaultParameters()),
+		Params: NewDefaultParameters(),
 	}
 
 	// create response with the value
 	value, _ := api.NewTestDocument(rng)
 	response2 := &api.FindResponse{
-		Peers: nil,
-		Value: value,
+		Peers:      nil,
+		Value:      value,
+		ExpireTime: 1234,
 	}
 
-	// check that the result value is set
+	// check that the result value and its expire time are set
 	prevUnqueriedLength := s.Result.Unqueried.Len()
-	err := rp.Process(response2, s)
+	err := rp.Process(response2, from, s)
 	assert.Nil(t, err)
 	assert.Equal(t, prevUnqueriedLength, s.Result.Unqueried.Len())
 	assert.Equal(t, value, s.Result.Value)
+	assert.Equal(t, int64(1234), s.Result.ExpireTime)
+}
+
+func TestResponseProcessor_Process_ValueQuorum(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	forged, _ := api.NewTestDocument(rng)
+	rp := NewResponseProcessor(peer.NewFromer(), comm.NewNaiveDoctor())
+	params := NewDefaultParameters()
+	params.ValueQuorum = 2
+	s := NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng), key, params)
+	from1, from2 := peer.NewTestPeer(rng, 1), peer.NewTestPeer(rng, 2)
+	newResponse := func(value *api.Document) *api.FindResponse {
+		return &api.FindResponse{
+			Metadata: &api.ResponseMetadata{
+				PubKey: ecid.NewPseudoRandom(rng).PublicKeyBytes(),
+			},
+			Value: value,
+		}
+	}
+
+	// first value isn't trusted alone
+	err := rp.Process(newResponse(value), from1, s)
+	assert.Nil(t, err)
+	assert.False(t, s.FoundValue())
+
+	// same peer returning value again doesn't count twice, even under a different public key
+	err = rp.Process(newResponse(value), from1, s)
+	assert.Nil(t, err)
+	assert.False(t, s.FoundValue())
+
+	// different value doesn't count toward quorum
+	err = rp.Process(newResponse(forged), from2, s)
+	assert.Nil(t, err)
+	assert.False(t, s.FoundValue())
+
+	// second distinct peer returning the value reaches the quorum
+	err = rp.Process(newResponse(value), from2, s)
+	assert.Nil(t, err)
+	assert.True(t, s.FoundValue())
+	assert.Equal(t, value, s.Result.Value)
+}
+
+func TestResponseProcessor_Process_VerifyValueKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	forged, _ := api.NewTestDocument(rng)
+	rp := NewResponseProcessor(peer.NewFromer(), comm.NewNaiveDoctor())
+	from := peer.NewTestPeer(rng, 0)
+	params := NewDefaultParameters()
+	params.ValueQuorum = 3
+	params.VerifyValueKey = true
+	s := NewSearch(ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng), key, params)
+
+	// value not matching the key is an invalid response
+	err := rp.Process(&api.FindResponse{Value: forged}, from, s)
+	assert.Equal(t, errInvalidResponse, err)
+	assert.False(t, s.FoundValue())
+
+	// value matching the key is accepted without a quorum
+	err = rp.Process(&api.FindResponse{Value: value}, from, s)
+	assert.Nil(t, err)
+	assert.Equal(t, value, s.Result.Value)
 }
 
 func TestResponseProcessor_Process_Addresses(t *testing.T) {
@@ -279,6 +561,7 @@ func TestResponseProcessor_Process_Addresses(t *testing.T) {
 	peerID, key := ecid.NewPseudoRandom(rng), id.NewPseudoRandom(rng)
 	orgID := ecid.NewPseudoRandom(rng)
 	rp := NewResponseProcessor(peer.NewFromer(), comm.NewNaiveDoctor())
+	from := peer.NewTestPeer(rng, 0)
 	s := NewSearch(peerID, orgID, key, NewDefaultParameters())
 
 	// create response or nAddresses and process it
@@ -286,7 +569,7 @@ func TestResponseProcessor_Process_Addresses(t *testing.T) {
 		Peers: peerAddresses,
 		Value: nil,
 	}
-	err := rp.Process(response, s)
+	err := rp.Process(response, from, s)
 	assert.Nil(t, err)
 	assert.Equal(t, nAddresses, s.Result.Unqueried.Len())
 }
@@ -295,6 +578,7 @@ func TestResponseProcessor_Process_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	key := id.NewPseudoRandom(rng)
 	rp := NewResponseProcessor(peer.NewFromer(), comm.NewNaiveDoctor())
+	from := peer.NewTestPeer(rng, 0)
 	s := &Search{
 		Result: NewInitialResult(key, NewDefaultParameters()),
 	}
@@ -304,7 +588,7 @@ func TestResponseProcessor_Process_err(t *testing.T) {
 		Peers: nil,
 		Value: nil,
 	}
-	err := rp.Process(response2, s)
+	err := rp.Process(response2, from, s)
 	assert.NotNil(t, err)
 }
 
@@ -386,3 +670,171 @@ type fixedDoctor struct {
 func (d *fixedDoctor) Healthy(peerID id.ID) bool {
 	return d.healthy
 }
+
+func TestSearcher_recordSuccessError_bounded(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, _, peers := newTestSearch(rec)
+	s := searcherImpl.(*searcher)
+	search.Params.MaxResponded = 4
+
+	for _, p := range peers {
+		s.recordSuccess(p, search)
+		s.recordError(p, p.Address(), errors.New("some Find error"), search)
+	}
+	assert.Len(t, search.Result.Responded, int(search.Params.MaxResponded))
+	assert.Equal(t, int(search.Params.NClosestResponses), search.Result.Closest.Len())
+	assert.Len(t, search.Result.Errored, int(search.Params.NMaxErrors+1))
+	assert.True(t, search.Errored())
+}
+
+func TestSearcher_blacklist(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, _, _ := newTestSearch(rec)
+	s := searcherImpl.(*searcher)
+	params := comm.NewDefaultBlacklistParameters()
+	params.MaxMisbehaviors = 1
+	s.bl = comm.NewBlacklister(params, nil)
+	peers := []peer.Peer{
+		peer.New(id.FromInt64(1), "", &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 20100}),
+		peer.New(id.FromInt64(2), "", &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 20100}),
+		peer.New(id.FromInt64(3), "", nil),
+	}
+
+	// non-protocol errors aren't misbehaviors
+	s.recordError(peers[0], peers[0].Address(), errors.New("some Find error"), search)
+	assert.False(t, s.bl.BannedIP(peers[0].Address().IP))
+
+	// protocol violations are, but against the responding address rather than the unauthenticated
+	// peer ID, and peers at banned addresses are excluded from seeds
+	s.recordError(peers[1], peers[1].Address(), errInvalidResponse, search)
+	assert.False(t, s.bl.Banned(peers[1].ID()))
+	assert.True(t, s.bl.BannedIP(peers[1].Address().IP))
+	unbanned := s.unbanned(peers)
+	assert.Equal(t, []peer.Peer{peers[0], peers[2]}, unbanned)
+}
+
+func TestReweightUnqueried(t *testing.T) {
+	key := id.FromInt64(0)
+	p1 := peer.New(id.FromInt64(4), "", nil)
+	p2 := peer.New(id.FromInt64(5), "", nil)
+	result := NewInitialResult(key, NewDefaultParameters())
+	result.Unqueried.SafePushMany([]peer.Peer{p1, p2})
+	assert.Equal(t, p1, result.Unqueried.PeakPeer())
+
+	reweightUnqueried(result, key, &fixedPreferer{preferred: p2.ID()})
+	assert.Equal(t, 2, result.Unqueried.Len())
+	assert.Equal(t, p2, result.Unqueried.PeakPeer())
+}
+
+func TestSearcher_Search_reputationWeighted(t *testing.T) {
+	n := 32
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	s := NewTestSearcher(peersMap, addressFinders, rec)
+
+	// recorder isn't a ReputationJudge, so searches aren't reweighted
+	assert.Nil(t, s.(*searcher).pref)
+
+	params := NewDefaultParameters()
+	params.ReputationWeighted = true
+	search := NewSearch(peerID, ecid.NewPseudoRandom(rng), id.NewPseudoRandom(rng), params)
+	err := s.Search(context.Background(), search, NewTestSeeds(peers, selfPeerIdxs))
+	assert.Nil(t, err)
+	assert.True(t, search.FoundClosestPeers())
+}
+
+func TestSearcher_processAnyReponse_readRepair(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rec := &fixedRecorder{}
+	searcherImpl, search, _, peers := newTestSearch(rec)
+	s := searcherImpl.(*searcher)
+	value, _ := api.NewTestDocument(rng)
+	peersRp := &api.FindResponse{Peers: []*api.PeerAddress{peers[2].ToAPI()}}
+	valueRp := &api.FindResponse{Value: value}
+
+	// missing peers not recorded by default
+	s.processAnyReponse(&peerResponse{peer: peers[0], response: peersRp}, search)
+	assert.Nil(t, search.Result.Missing)
+
+	search.Params.ReadRepair = true
+	s.processAnyReponse(&peerResponse{peer: peers[0], response: peersRp}, search)
+	s.processAnyReponse(&peerResponse{peer: peers[1], response: valueRp}, search)
+	assert.True(t, search.FoundValue())
+	assert.Len(t, search.Result.Missing, 1)
+	assert.Contains(t, search.Result.Missing, peers[0].ID().String())
+	assert.Equal(t, []peer.Peer{peers[0]}, search.Result.MissingClosest())
+}
+
+func TestSearcher_processAnyReponse_throttled(t *testing.T) {
+	rec := &fixedRecorder{}
+	searcherImpl, search, _, peers := newTestSearch(rec)
+	s := searcherImpl.(*searcher)
+
+	// throttled queries were never sent, so they're neither errors nor responses
+	pr := &peerResponse{peer: peers[0], err: comm.ErrQueryThrottled, throttled: true}
+	s.processAnyReponse(pr, search)
+	assert.Empty(t, search.Result.Errored)
+	assert.Empty(t, search.Result.Responded)
+	assert.Zero(t, rec.nErrors)
+}
+
+func TestSearcher_Search_readRepair(t *testing.T) {
+	rec := &fixedRecorder{}
+	s, search, selfPeerIdxs, peers := newTestSearch(rec)
+	search.Params.ReadRepair = true
+
+	// no test peer has the value, so all the closest are missing it
+	err := s.Search(context.Background(), search, NewTestSeeds(peers, selfPeerIdxs))
+	assert.Nil(t, err)
+	assert.True(t, search.FoundClosestPeers())
+	assert.Equal(t, len(search.Result.Responded), len(search.Result.Missing))
+	assert.Len(t, search.Result.MissingClosest(), search.Result.Closest.Len())
+}
+
+func TestSearcher_Search_reputationJudge(t *testing.T) {
+	n := 32
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	judge, err := comm.LoadReputationJudge(&cstorage.TestSLD{},
+		comm.NewDefaultReputationParameters())
+	assert.Nil(t, err)
+	s := NewTestSearcher(peersMap, addressFinders, judge)
+	assert.Equal(t, judge, s.(*searcher).judge)
+	assert.NotNil(t, s.(*searcher).pref)
+	neutral := judge.Score(id.NewPseudoRandom(rng))
+
+	params := NewDefaultParameters()
+	params.ReputationWeighted = true
+	search := NewSearch(peerID, ecid.NewPseudoRandom(rng), id.NewPseudoRandom(rng), params)
+	err = s.Search(context.Background(), search, NewTestSeeds(peers, selfPeerIdxs))
+	assert.Nil(t, err)
+	assert.True(t, search.FoundClosestPeers())
+
+	// responding peers' successes and latencies are judged
+	for _, p := range search.Result.Responded {
+		assert.True(t, judge.Score(p.ID()) > neutral)
+	}
+}
+
+func TestSearcher_Search_latencyWeighted(t *testing.T) {
+	n := 32
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	lt := comm.NewLatencyTracker(comm.DefaultLatencySmoothing)
+	s := WithLatencyTracker(NewTestSearcher(peersMap, addressFinders, &fixedRecorder{}), lt)
+	assert.NotNil(t, s.(*searcher).latencyPref)
+
+	params := NewDefaultParameters()
+	params.LatencyWeighted = true
+	search := NewSearch(peerID, ecid.NewPseudoRandom(rng), id.NewPseudoRandom(rng), params)
+	err := s.Search(context.Background(), search, NewTestSeeds(peers, selfPeerIdxs))
+	assert.Nil(t, err)
+	assert.True(t, search.FoundClosestPeers())
+
+	// responding peers' latencies are tracked
+	for _, p := range search.Result.Responded {
+		_, ok := lt.Latency(p.ID())
+		assert.True(t, ok)
+	}
+}
@@ -82,7 +82,9 @@ func NewTestSearcher(
 		&client.TestNoOpSigner{},
 		&client.TestNoOpSigner{},
 		rec,
+		comm.NewNoOpKeyspaceRecorder(),
 		doc,
+		comm.NewNaiveBlacklister(),
 		&TestFinderCreator{finders: addressFinders},
 		&responseProcessor{
 			fromer: &TestFromer{Peers: peersMap},
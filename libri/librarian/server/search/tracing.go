@@ -0,0 +1,125 @@
+package search
+
+import (
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"golang.org/x/net/context"
+)
+
+const (
+	// SearchSpanName is the name of the span tracing a whole search.
+	SearchSpanName = "search"
+
+	// QuerySpanName is the name of the span tracing a search's query to a single peer.
+	QuerySpanName = "search.query"
+
+	// span attribute keys
+	attrKey            = "search.key"
+	attrNDisjointPaths = "search.n_disjoint_paths"
+	attrNQueried       = "search.n_queried"
+	attrNResponded     = "search.n_responded"
+	attrFoundValue     = "search.found_value"
+	attrPeerID         = "peer.id"
+	attrRTT            = "query.rtt"
+	attrNPeers         = "query.n_peers"
+	attrValue          = "query.value"
+)
+
+// Attribute is a key-value pair describing a traced span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is an operation traced by a Tracer. Its methods mirror those of an OpenTelemetry span, so
+// one is easily adapted to it.
+type Span interface {
+	// SetAttributes sets the attributes of the span.
+	SetAttributes(attrs ...Attribute)
+
+	// RecordError records an error that occurred during the span.
+	RecordError(err error)
+
+	// End ends the span.
+	End()
+}
+
+// Tracer starts the spans tracing searches: one span per search (named SearchSpanName) with a
+// child span per peer query (named QuerySpanName) recording the query's round-trip time, error,
+// and number of peers returned. Its methods mirror those of an OpenTelemetry tracer, so one is
+// easily adapted to it.
+type Tracer interface {
+	// Start starts a span with the given name as a child of any span in the context, returning
+	// a context containing the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// NewNoOpTracer returns a Tracer whose spans do nothing.
+func NewNoOpTracer() Tracer {
+	return noOpTracer{}
+}
+
+type noOpTracer struct{}
+
+func (noOpTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noOpSpan{}
+}
+
+type noOpSpan struct{}
+
+func (noOpSpan) SetAttributes(attrs ...Attribute) {}
+
+func (noOpSpan) RecordError(err error) {}
+
+func (noOpSpan) End() {}
+
+// WithTracer sets the Tracer tracing the searches of a Searcher created by NewSearcher or
+// NewDefaultSearcher, which otherwise aren't traced.
+func WithTracer(s Searcher, t Tracer) Searcher {
+	s.(*searcher).tracer = t
+	return s
+}
+
+// startSearchSpan starts the span tracing a whole search.
+func startSearchSpan(ctx context.Context, t Tracer, search *Search) (context.Context, Span) {
+	ctx, span := t.Start(ctx, SearchSpanName)
+	span.SetAttributes(
+		Attribute{Key: attrKey, Value: id.Hex(search.Key.Bytes())},
+		Attribute{Key: attrNDisjointPaths, Value: search.Params.NDisjointPaths},
+	)
+	return ctx, span
+}
+
+// endSearchSpan records the search's outcome on its span and ends it.
+func endSearchSpan(span Span, search *Search) {
+	search.Mu.Lock()
+	span.SetAttributes(
+		Attribute{Key: attrNQueried, Value: len(search.Result.Queried)},
+		Attribute{Key: attrNResponded, Value: len(search.Result.Responded)},
+		Attribute{Key: attrFoundValue, Value: search.Result.Value != nil},
+	)
+	if search.Result.FatalErr != nil {
+		span.RecordError(search.Result.FatalErr)
+	}
+	search.Mu.Unlock()
+	span.End()
+}
+
+// endQuerySpan records a peer query's outcome on its span and ends it.
+func endQuerySpan(span Span, p peer.Peer, pr *peerResponse) {
+	attrs := []Attribute{
+		{Key: attrPeerID, Value: id.Hex(p.ID().Bytes())},
+		{Key: attrRTT, Value: pr.rtt},
+	}
+	if pr.response != nil {
+		attrs = append(attrs,
+			Attribute{Key: attrNPeers, Value: len(pr.response.Peers)},
+			Attribute{Key: attrValue, Value: pr.response.Value != nil},
+		)
+	}
+	span.SetAttributes(attrs...)
+	if pr.err != nil {
+		span.RecordError(pr.err)
+	}
+	span.End()
+}
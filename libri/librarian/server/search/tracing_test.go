@@ -0,0 +1,137 @@
+package search
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestNoOpTracer(t *testing.T) {
+	ctx := context.Background()
+	ctx2, span := NewNoOpTracer().Start(ctx, SearchSpanName)
+	assert.Equal(t, ctx, ctx2)
+	span.SetAttributes(Attribute{Key: "some key", Value: 1})
+	span.RecordError(errors.New("some error"))
+	span.End()
+}
+
+func TestSearcher_Search_tracer(t *testing.T) {
+	n, nClosestResponses := 32, uint(6)
+	rng := rand.New(rand.NewSource(int64(n)))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := NewTestPeers(rng, n)
+	orgID := ecid.NewPseudoRandom(rng)
+	key := id.NewPseudoRandom(rng)
+	tracer := &recordingTracer{}
+	searcher := WithTracer(NewTestSearcher(peersMap, addressFinders, &fixedRecorder{}), tracer)
+	search := NewSearch(peerID, orgID, key, &Parameters{
+		NClosestResponses: nClosestResponses,
+		NMaxErrors:        DefaultNMaxErrors,
+		Concurrency:       3,
+		Timeout:           DefaultQueryTimeout,
+	})
+
+	err := searcher.Search(context.Background(), search, NewTestSeeds(peers, selfPeerIdxs))
+	assert.Nil(t, err)
+	assert.True(t, search.FoundClosestPeers())
+
+	// one search span with a child span per queried peer
+	searchSpans, querySpans := tracer.spansNamed(SearchSpanName), tracer.spansNamed(QuerySpanName)
+	assert.Len(t, searchSpans, 1)
+	assert.Len(t, querySpans, len(search.Result.Queried))
+	searchSpan := searchSpans[0]
+	assert.True(t, searchSpan.ended)
+	assert.Equal(t, id.Hex(key.Bytes()), searchSpan.attrs[attrKey])
+	assert.Equal(t, len(search.Result.Queried), searchSpan.attrs[attrNQueried])
+	assert.Equal(t, false, searchSpan.attrs[attrFoundValue])
+	assert.Nil(t, searchSpan.err)
+	for _, qs := range querySpans {
+		assert.True(t, qs.ended)
+		assert.Equal(t, searchSpan, qs.parent)
+		assert.Contains(t, qs.attrs, attrPeerID)
+		assert.Contains(t, qs.attrs, attrRTT)
+		assert.Contains(t, qs.attrs, attrNPeers)
+		assert.Nil(t, qs.err)
+	}
+}
+
+func TestEndQuerySpan(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p := peer.NewTestPeer(rng, 0)
+
+	// ok response records number of peers returned
+	span := &recordingSpan{attrs: make(map[string]interface{})}
+	endQuerySpan(span, p, &peerResponse{
+		response: &api.FindResponse{Peers: newPeerAddresses(rng, 4)},
+		rtt:      time.Millisecond,
+	})
+	assert.True(t, span.ended)
+	assert.Equal(t, 4, span.attrs[attrNPeers])
+	assert.Equal(t, time.Millisecond, span.attrs[attrRTT])
+	assert.Nil(t, span.err)
+
+	// error response records error
+	span = &recordingSpan{attrs: make(map[string]interface{})}
+	endQuerySpan(span, p, &peerResponse{err: errors.New("some Find error")})
+	assert.True(t, span.ended)
+	assert.NotContains(t, span.attrs, attrNPeers)
+	assert.NotNil(t, span.err)
+}
+
+type spanCtxKey struct{}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+	mu    sync.Mutex
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordingSpan{name: name, attrs: make(map[string]interface{})}
+	span.parent, _ = ctx.Value(spanCtxKey{}).(*recordingSpan)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanCtxKey{}, span), span
+}
+
+func (t *recordingTracer) spansNamed(name string) []*recordingSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	named := make([]*recordingSpan, 0)
+	for _, s := range t.spans {
+		if s.name == name {
+			named = append(named, s)
+		}
+	}
+	return named
+}
+
+type recordingSpan struct {
+	name   string
+	parent *recordingSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordingSpan) End() {
+	s.ended = true
+}
@@ -0,0 +1,119 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SRVService is the service name of libri DNS SRV seed records, i.e., records are looked up
+	// under _libri._tcp.<host>.
+	SRVService = "libri"
+
+	// SRVProto is the protocol of libri DNS SRV seed records.
+	SRVProto = "tcp"
+)
+
+// ErrNoAddrs indicates that a seed host's records contained no librarian addresses.
+var ErrNoAddrs = errors.New("no librarian addresses in seed records")
+
+// Resolver resolves a DNS seed hostname into librarian addresses.
+type Resolver interface {
+	// Resolve returns the librarian addresses published under the given host.
+	Resolve(ctx context.Context, host string) ([]*net.TCPAddr, error)
+}
+
+type txtResolver struct {
+	lookupTXT func(ctx context.Context, host string) ([]string, error)
+}
+
+// NewTXTResolver returns a Resolver that reads librarian addresses from the TXT records of a
+// host. Each record may contain one or more whitespace- or comma-separated IPv4:Port addresses.
+func NewTXTResolver() Resolver {
+	return &txtResolver{lookupTXT: net.DefaultResolver.LookupTXT}
+}
+
+func (r *txtResolver) Resolve(ctx context.Context, host string) ([]*net.TCPAddr, error) {
+	records, err := r.lookupTXT(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]*net.TCPAddr, 0, len(records))
+	for _, record := range records {
+		fields := strings.FieldsFunc(record, func(c rune) bool {
+			return c == ',' || c == ' ' || c == '\t'
+		})
+		for _, field := range fields {
+			addr, err := parseIPAddr(field)
+			if err != nil {
+				// skip anything that isn't an address since TXT records are shared with
+				// other uses
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, ErrNoAddrs
+	}
+	return addrs, nil
+}
+
+type srvResolver struct {
+	lookupSRV    func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	lookupIPAddr func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewSRVResolver returns a Resolver that reads librarian addresses from the _libri._tcp SRV
+// records of a host, resolving each target to its IPv4 addresses.
+func NewSRVResolver() Resolver {
+	return &srvResolver{
+		lookupSRV:    net.DefaultResolver.LookupSRV,
+		lookupIPAddr: net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+func (r *srvResolver) Resolve(ctx context.Context, host string) ([]*net.TCPAddr, error) {
+	_, records, err := r.lookupSRV(ctx, SRVService, SRVProto, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]*net.TCPAddr, 0, len(records))
+	for _, record := range records {
+		ips, err := r.lookupIPAddr(ctx, record.Target)
+		if err != nil {
+			// other targets may still resolve
+			continue
+		}
+		for _, ip := range ips {
+			if ip.IP.To4() == nil {
+				continue
+			}
+			addrs = append(addrs, &net.TCPAddr{IP: ip.IP, Port: int(record.Port)})
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, ErrNoAddrs
+	}
+	return addrs, nil
+}
+
+// parseIPAddr parses an IPv4:Port address without performing any (further) DNS lookups.
+func parseIPAddr(s string) (*net.TCPAddr, error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() == nil {
+		return nil, errors.New("invalid IPv4 address")
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
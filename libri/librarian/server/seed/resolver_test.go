@@ -0,0 +1,119 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTXTResolver_Resolve_ok(t *testing.T) {
+	r := &txtResolver{
+		lookupTXT: func(ctx context.Context, host string) ([]string, error) {
+			return []string{
+				"1.2.3.4:20100, 1.2.3.5:20100",
+				"v=spf1 include:_spf.example.com ~all",
+				"1.2.3.6:20101",
+			}, nil
+		},
+	}
+	addrs, err := r.Resolve(context.Background(), "seeds.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.2.3.4:20100", "1.2.3.5:20100", "1.2.3.6:20101"}, addrStrs(addrs))
+}
+
+func TestTXTResolver_Resolve_err(t *testing.T) {
+	r := &txtResolver{
+		lookupTXT: func(ctx context.Context, host string) ([]string, error) {
+			return nil, errors.New("some LookupTXT error")
+		},
+	}
+	addrs, err := r.Resolve(context.Background(), "seeds.example.com")
+	assert.NotNil(t, err)
+	assert.Nil(t, addrs)
+
+	r = &txtResolver{
+		lookupTXT: func(ctx context.Context, host string) ([]string, error) {
+			return []string{"not an address", "localhost:20100", "[::1]:20100"}, nil
+		},
+	}
+	addrs, err = r.Resolve(context.Background(), "seeds.example.com")
+	assert.Equal(t, ErrNoAddrs, err)
+	assert.Nil(t, addrs)
+}
+
+func TestSRVResolver_Resolve_ok(t *testing.T) {
+	r := &srvResolver{
+		lookupSRV: func(ctx context.Context, service, proto, name string) (
+			string, []*net.SRV, error) {
+			assert.Equal(t, SRVService, service)
+			assert.Equal(t, SRVProto, proto)
+			return "", []*net.SRV{
+				{Target: "librarian-0.example.com.", Port: 20100},
+				{Target: "librarian-1.example.com.", Port: 20101},
+				{Target: "missing.example.com.", Port: 20102},
+			}, nil
+		},
+		lookupIPAddr: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			switch host {
+			case "librarian-0.example.com.":
+				return []net.IPAddr{{IP: net.ParseIP("1.2.3.4")}, {IP: net.ParseIP("::1")}}, nil
+			case "librarian-1.example.com.":
+				return []net.IPAddr{{IP: net.ParseIP("1.2.3.5")}}, nil
+			default:
+				return nil, errors.New("some LookupIPAddr error")
+			}
+		},
+	}
+	addrs, err := r.Resolve(context.Background(), "seeds.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.2.3.4:20100", "1.2.3.5:20101"}, addrStrs(addrs))
+}
+
+func TestSRVResolver_Resolve_err(t *testing.T) {
+	r := &srvResolver{
+		lookupSRV: func(ctx context.Context, service, proto, name string) (
+			string, []*net.SRV, error) {
+			return "", nil, errors.New("some LookupSRV error")
+		},
+	}
+	addrs, err := r.Resolve(context.Background(), "seeds.example.com")
+	assert.NotNil(t, err)
+	assert.Nil(t, addrs)
+
+	r = &srvResolver{
+		lookupSRV: func(ctx context.Context, service, proto, name string) (
+			string, []*net.SRV, error) {
+			return "", []*net.SRV{{Target: "missing.example.com.", Port: 20100}}, nil
+		},
+		lookupIPAddr: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			return nil, errors.New("some LookupIPAddr error")
+		},
+	}
+	addrs, err = r.Resolve(context.Background(), "seeds.example.com")
+	assert.Equal(t, ErrNoAddrs, err)
+	assert.Nil(t, addrs)
+}
+
+func TestParseIPAddr(t *testing.T) {
+	addr, err := parseIPAddr("1.2.3.4:20100")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.3.4:20100", addr.String())
+
+	for _, s := range []string{"1.2.3.4", "localhost:20100", "[::1]:20100", "1.2.3.4:port",
+		"1.2.3.4:70000"} {
+		addr, err = parseIPAddr(s)
+		assert.NotNil(t, err, s)
+		assert.Nil(t, addr, s)
+	}
+}
+
+func addrStrs(addrs []*net.TCPAddr) []string {
+	strs := make([]string, len(addrs))
+	for i, addr := range addrs {
+		strs[i] = addr.String()
+	}
+	return strs
+}
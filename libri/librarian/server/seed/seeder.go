@@ -0,0 +1,145 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	cbackoff "github.com/cenkalti/backoff"
+	"github.com/drausin/libri/libri/librarian/client"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// DefaultTimeout is the default timeout for each resolution of a seed host.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultMaxElapsedTime is the default maximum time spent retrying the resolution of a seed
+	// host.
+	DefaultMaxElapsedTime = 30 * time.Second
+
+	// ErrNoSeeds indicates that none of the seed hosts resolved to any librarian addresses.
+	ErrNoSeeds = errors.New("no seed hosts resolved to librarian addresses")
+)
+
+const (
+	logTimeout        = "timeout"
+	logMaxElapsedTime = "max_elapsed_time"
+)
+
+// Parameters define how seed hosts are resolved.
+type Parameters struct {
+	// Timeout for each resolution of a seed host
+	Timeout time.Duration
+
+	// MaxElapsedTime is the maximum time spent retrying the resolution of a seed host
+	MaxElapsedTime time.Duration
+}
+
+// NewDefaultParameters creates a new instance of default seed parameters.
+func NewDefaultParameters() *Parameters {
+	return &Parameters{
+		Timeout:        DefaultTimeout,
+		MaxElapsedTime: DefaultMaxElapsedTime,
+	}
+}
+
+// MarshalLogObject converts the Parameters into an object that will be logged.
+func (p *Parameters) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddDuration(logTimeout, p.Timeout)
+	oe.AddDuration(logMaxElapsedTime, p.MaxElapsedTime)
+	return nil
+}
+
+// Seeder resolves DNS seed hosts into librarian addresses to bootstrap from.
+type Seeder interface {
+	// Seed returns the de-duplicated librarian addresses resolved from all the hosts. It only
+	// returns an error if no host resolved to any addresses.
+	Seed(hosts []string) ([]*net.TCPAddr, error)
+}
+
+type seeder struct {
+	resolvers []Resolver
+	params    *Parameters
+}
+
+// NewSeeder creates a new Seeder that tries each of the resolvers for each seed host.
+func NewSeeder(params *Parameters, resolvers ...Resolver) Seeder {
+	return &seeder{
+		resolvers: resolvers,
+		params:    params,
+	}
+}
+
+// NewDefaultSeeder creates a new Seeder using both TXT and SRV records.
+func NewDefaultSeeder(params *Parameters) Seeder {
+	return NewSeeder(params, NewTXTResolver(), NewSRVResolver())
+}
+
+func (s *seeder) Seed(hosts []string) ([]*net.TCPAddr, error) {
+	addrs := make([]*net.TCPAddr, 0)
+	seen := make(map[string]struct{})
+	var lastErr error
+	for _, host := range hosts {
+		for _, r := range s.resolvers {
+			resolved, err := s.resolve(r, host)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			for _, addr := range resolved {
+				if _, in := seen[addr.String()]; in {
+					continue
+				}
+				seen[addr.String()] = struct{}{}
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, ErrNoSeeds
+	}
+	return addrs, nil
+}
+
+// resolve resolves the host with the resolver, retrying only on temporary errors like timeouts
+// and not on, e.g., the host not existing or its records lacking addresses, which retrying won't
+// fix.
+func (s *seeder) resolve(r Resolver, host string) ([]*net.TCPAddr, error) {
+	var addrs []*net.TCPAddr
+	var resolveErr error
+	operation := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), s.params.Timeout)
+		defer cancel()
+		addrs, resolveErr = r.Resolve(ctx, host)
+		if resolveErr != nil && !temporary(resolveErr) {
+			return nil
+		}
+		return resolveErr
+	}
+	if err := cbackoff.Retry(operation, client.NewExpBackoff(s.params.MaxElapsedTime)); err != nil {
+		return nil, err
+	}
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	return addrs, nil
+}
+
+// temporary returns whether the resolution error may not recur on retry.
+func temporary(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	if dnsErr, ok := err.(*net.DNSError); ok {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	if netErr, ok := err.(net.Error); ok {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+	return false
+}
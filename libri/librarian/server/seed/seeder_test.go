@@ -0,0 +1,125 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParameters_MarshalLogObject(t *testing.T) {
+	oe := zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig())
+	err := NewDefaultParameters().MarshalLogObject(oe)
+	assert.Nil(t, err)
+}
+
+func TestSeeder_Seed_ok(t *testing.T) {
+	r1 := &fixedResolver{addrs: map[string][]*net.TCPAddr{
+		"seeds1.example.com": {tcpAddr("1.2.3.4:20100"), tcpAddr("1.2.3.5:20100")},
+		"seeds2.example.com": {tcpAddr("1.2.3.5:20100")},
+	}}
+	r2 := &fixedResolver{addrs: map[string][]*net.TCPAddr{
+		"seeds2.example.com": {tcpAddr("1.2.3.6:20100")},
+	}}
+	s := NewSeeder(newTestParameters(), r1, r2)
+
+	addrs, err := s.Seed([]string{"seeds1.example.com", "seeds2.example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.2.3.4:20100", "1.2.3.5:20100", "1.2.3.6:20100"}, addrStrs(addrs))
+}
+
+func TestSeeder_Seed_retry(t *testing.T) {
+	r := &fixedResolver{
+		addrs: map[string][]*net.TCPAddr{
+			"seeds.example.com": {tcpAddr("1.2.3.4:20100")},
+		},
+		nErrs: 2,
+	}
+	s := NewSeeder(newTestParameters(), r)
+
+	// transient errors should be retried
+	addrs, err := s.Seed([]string{"seeds.example.com"})
+	assert.Nil(t, err)
+	assert.Len(t, addrs, 1)
+	assert.Equal(t, 3, r.nCalls)
+
+	// but records without addresses shouldn't be
+	r = &fixedResolver{addrs: map[string][]*net.TCPAddr{}}
+	s = NewSeeder(newTestParameters(), r)
+	addrs, err = s.Seed([]string{"seeds.example.com"})
+	assert.Equal(t, ErrNoAddrs, err)
+	assert.Nil(t, addrs)
+	assert.Equal(t, 1, r.nCalls)
+
+	// nor should hosts that don't exist
+	notFound := &net.DNSError{Err: "no such host", Name: "seeds.example.com"}
+	r = &fixedResolver{err: notFound}
+	s = NewSeeder(newTestParameters(), r)
+	addrs, err = s.Seed([]string{"seeds.example.com"})
+	assert.Equal(t, notFound, err)
+	assert.Nil(t, addrs)
+	assert.Equal(t, 1, r.nCalls)
+}
+
+func TestTemporary(t *testing.T) {
+	assert.True(t, temporary(context.DeadlineExceeded))
+	assert.True(t, temporary(&net.DNSError{IsTimeout: true}))
+	assert.True(t, temporary(&net.DNSError{IsTemporary: true}))
+	assert.False(t, temporary(&net.DNSError{Err: "no such host"}))
+	assert.False(t, temporary(ErrNoAddrs))
+	assert.False(t, temporary(errors.New("some other error")))
+}
+
+func TestSeeder_Seed_err(t *testing.T) {
+	s := NewSeeder(newTestParameters(), &fixedResolver{err: errors.New("some Resolve error")})
+	addrs, err := s.Seed([]string{"seeds.example.com"})
+	assert.NotNil(t, err)
+	assert.Nil(t, addrs)
+
+	s = NewSeeder(newTestParameters())
+	addrs, err = s.Seed([]string{"seeds.example.com"})
+	assert.Equal(t, ErrNoSeeds, err)
+	assert.Nil(t, addrs)
+}
+
+func newTestParameters() *Parameters {
+	return &Parameters{
+		Timeout:        100 * time.Millisecond,
+		MaxElapsedTime: 500 * time.Millisecond,
+	}
+}
+
+func tcpAddr(s string) *net.TCPAddr {
+	addr, err := net.ResolveTCPAddr("tcp4", s)
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
+type fixedResolver struct {
+	addrs  map[string][]*net.TCPAddr
+	err    error
+	nErrs  int
+	nCalls int
+}
+
+func (f *fixedResolver) Resolve(ctx context.Context, host string) ([]*net.TCPAddr, error) {
+	f.nCalls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.nCalls <= f.nErrs {
+		return nil, &net.DNSError{Err: "some transient Resolve error", IsTemporary: true}
+	}
+	addrs, in := f.addrs[host]
+	if !in {
+		return nil, ErrNoAddrs
+	}
+	return addrs, nil
+}
@@ -5,13 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
-
+	"sync"
 	"time"
 
 	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/logging"
 	"github.com/drausin/libri/libri/common/storage"
 	"github.com/drausin/libri/libri/common/subscribe"
 	"github.com/drausin/libri/libri/librarian/api"
@@ -22,25 +24,39 @@ import (
 	"github.com/drausin/libri/libri/librarian/server/replicate"
 	"github.com/drausin/libri/libri/librarian/server/routing"
 	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/drausin/libri/libri/librarian/server/seed"
 	"github.com/drausin/libri/libri/librarian/server/store"
 	"github.com/drausin/libri/libri/librarian/server/verify"
+	"github.com/drausin/libri/version"
+	"github.com/golang/protobuf/proto"
+	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/willf/bloom"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 )
 
 const (
 	newPublicationsSlack = 16
+
+	// MaxPutNReplicas is the maximum number of replicas a Put request may ask its value be
+	// stored with.
+	MaxPutNReplicas = uint(16)
 )
 
 var (
+	errPageTooLarge           = errors.New("page larger than max page size")
 	errBadPeerIDSig           = errors.New("stated client peer ID does not match signature")
 	errStoreUnexpectedResult  = errors.New("unexpected store result")
 	errSearchUnexpectedResult = errors.New("unexpected search result")
+	errAdminDisabled          = errors.New("admin requests are disabled")
+	errNotAdmin               = errors.New("requester is not the admin")
+	errAlreadyExpired         = errors.New("expire time has already passed")
 )
 
 // Librarian is the main service of a single peer in the peer to peer network.
@@ -60,6 +76,13 @@ type Librarian struct {
 	// executes introductions to peers
 	introducer introduce.Introducer
 
+	// limits the rate of introductions served to other peers
+	introThrottle introduce.Throttle
+
+	// minimum number of leading zero puzzle bits (see ecid.PuzzleBits) of the peers introducing
+	// themselves
+	introPuzzle uint
+
 	// executes searches for peers and keys
 	searcher search.Searcher
 
@@ -81,47 +104,160 @@ type Librarian struct {
 	// verifies requests from peers
 	rqv RequestVerifier
 
+	// verifies store authorization tokens on Store and Put requests, if they are required
+	sav client.StoreAuthVerifier
+
+	// verifies macaroons on Get, Put, Store, and value-returning Find requests, if they are
+	// required
+	mv client.MacaroonVerifier
+
 	// key-value store DB used for all external storage
 	db db.KVDB
 
 	// SL for server data
 	serverSL storage.StorerLoader
 
+	// key-value store DB used for the cold tier of stored documents, if they are tiered
+	coldDB db.KVDB
+
 	// SL for p2p stored documents
 	documentSL storage.DocumentSL
 
+	// tiered SL for p2p stored documents, if they are tiered
+	tiered storage.TieredDocumentSLD
+
+	// SL for p2p stored documents with expiration times
+	expiring storage.ExpiringDocumentSLD
+
+	// snapshot of the keys stored, for syncing with neighbors
+	storedKeys *storedKeysCache
+
+	// hints of documents stored on behalf of unreachable peers, to hand off when they return
+	hints *hintStore
+
+	// slots for the read repairs in progress
+	readRepairs chan struct{}
+
 	// ensures keys are valid
 	kc storage.Checker
 
 	// ensures keys and values are valid
 	kvc storage.KeyValueChecker
 
+	// verifies system document signatures
+	sysv client.Verifier
+
 	// creates new peers
 	fromer peer.Fromer
 
+	// resolves DNS seed hosts into bootstrap addresses
+	seeder seed.Seeder
+
 	// signs requests
 	signer client.Signer
 
+	// signs requests with the organization key
+	orgSigner client.Signer
+
 	// librarian client connection pool
 	clients client.Pool
 
 	// routing table of peers
 	rt routing.Table
 
+	// applies confirmed changes of known peers' addresses to the routing table
+	addressUpdater routing.AddressUpdater
+
+	// persists the routing table incrementally
+	rtCheckpointer routing.Checkpointer
+
 	// Prometheus counters for storage metrics
 	storageMetrics *storageMetrics
 
+	// Prometheus counters for storage tier metrics
+	tieringMetrics *tieringMetrics
+
+	// Prometheus metrics for DBs becoming read-only after disk errors
+	diskMetrics *diskMetrics
+
+	// Prometheus metrics for requests rejected for missing or invalid signatures
+	authMetrics *authMetrics
+
+	// Prometheus collector for routing table metrics
+	rtMetrics prom.Collector
+
+	// Prometheus collector for the DBs' internal statistics
+	dbMetrics prom.Collector
+
+	// Prometheus metrics for the outcomes and durations of searches
+	searchMetrics search.PromSearcher
+
 	// recorder of query outcomes for each peer
 	rec comm.QueryRecorder
 
+	// getter of each peer's (daily) query outcomes
+	qg comm.QueryGetter
+
+	// getters of each peer's query outcomes in each window, which are checkpointed
+	windowQGs comm.WindowQueryGetters
+
+	// judge of each peer's reputation
+	judge comm.ReputationJudge
+
+	// judge of each peer's health, informed by active probes
+	doctor comm.ProbingDoctor
+
+	// recorder of peers' apparent clock skews relative to self
+	skewRec comm.SkewRecorder
+
+	// rate limits outbound queries to each peer
+	throttler comm.Throttler
+
+	// bans misbehaving peers from the routing table and searches
+	blacklist comm.Blacklister
+
+	// persisted bans managed by the admin
+	banList comm.BanList
+
+	// signed allow-list of the peers allowed in a private cluster
+	allowList *allowList
+
+	// (maybe nil) mutual TLS credentials of connections from other peers
+	serverCreds credentials.TransportCredentials
+
+	// persisted per-organization storage quotas
+	quotas orgQuotas
+
 	// determines whether requests are allowed
 	allower comm.Allower
 
+	// limits the rates of requests from each requester peer and organization
+	rqLimiter comm.RequesterLimiter
+
+	// samples and traces requests
+	tracer *tracer
+
+	// remembers recently completed Puts to detect retries
+	recentPuts *recentPuts
+
+	// remembers responses to recently completed Stores to detect retries
+	recentStores *recentStores
+
+	// number of requests currently being handled
+	nInFlight int64
+
 	// logger for this instance
 	logger *zap.Logger
 
 	// health server
-	health *health.Server
+	health *healthServer
+
+	// serving status last reported by the health server
+	serving   healthpb.HealthCheckResponse_ServingStatus
+	servingMu sync.Mutex
+
+	// build info signed with the peer key
+	buildInfo *api.SignedBuildInfo
 
 	// metrics server
 	metrics *http.Server
@@ -135,16 +271,59 @@ type Librarian struct {
 
 // NewLibrarian creates a new librarian instance.
 func NewLibrarian(config *Config, logger *zap.Logger) (*Librarian, error) {
-	rdb, err := db.NewRocksDB(config.DbDir)
+	logger = logging.NewDedupLogger(logger, logging.DefaultDedupWindow, logPeerID)
+	if err := validateConfig(config); err != nil {
+		logger.Error("invalid config", zap.Error(err))
+		return nil, err
+	}
+	rdb, err := db.Open(config.DBDriver, config.DbDir)
 	if err != nil {
-		logger.Error("unable to init RocksDB", zap.Error(err))
+		logger.Error("unable to init DB", zap.String(logDBDriver, config.DBDriver),
+			zap.Error(err))
 		return nil, err
 	}
+	diskMetrics := newDiskMetrics()
+	rdb = diskMetrics.guard(rdb, mainDBName, logger)
 	serverSL := storage.NewServerSL(rdb)
+	routingSLD := storage.NewRoutingSLD(rdb)
 	documentSL := storage.NewDocumentSLD(rdb)
+	tieringMetrics := newTieringMetrics()
+	var coldDB db.KVDB
+	var tiered storage.TieredDocumentSLD
+	if config.Tiering.Enabled() {
+		coldDB, err = db.Open(config.DBDriver, config.Tiering.ColdDbDir)
+		if err != nil {
+			logger.Error("unable to init cold tier DB", zap.String(logDBDriver, config.DBDriver),
+				zap.Error(err))
+			return nil, err
+		}
+		coldDB = diskMetrics.guard(coldDB, coldDBName, logger)
+		tiered = storage.NewTieredDocumentSLD(documentSL, storage.NewDocumentSLD(coldDB),
+			tieringMetrics)
+		documentSL = tiered
+	}
+	quotas, err := loadOrgQuotas(storage.NewQuotaSLD(rdb), storage.NewQuotaChargeSLD(rdb),
+		config.Quota)
+	if err != nil {
+		return nil, err
+	}
+	hints, err := loadHintStore(storage.NewHintSLD(rdb), config.Handoff)
+	if err != nil {
+		return nil, err
+	}
+	recentStores := newRecentStores(recentStoresSize, recentStoresTTL)
+	hooks := getDocumentHooks(config, logger, newQuotaDocumentHooks(quotas, logger),
+		recentStores.documentHooks())
+	documentSL = storage.NewHookedDocumentSLD(documentSL, hooks)
+	dbs := map[string]db.KVDB{mainDBName: rdb}
+	if coldDB != nil {
+		dbs[coldDBName] = coldDB
+	}
+	expiring := storage.NewExpiringDocumentSLD(documentSL, rdb)
+	documentSL = expiring
 
 	// get peer ID and immediately save it so subsequent restarts have it
-	peerID, err := loadOrCreatePeerID(logger, serverSL)
+	peerID, err := loadOrCreatePeerID(logger, serverSL, config.Introduce.PuzzleDifficulty)
 	if err != nil {
 		return nil, err
 	}
@@ -152,18 +331,56 @@ func NewLibrarian(config *Config, logger *zap.Logger) (*Librarian, error) {
 
 	knower := comm.NewAlwaysKnower()
 
-	// TODO (drausin) load recorder from storage instead of initializing empty
 	windows := []time.Duration{comm.Second, comm.Day, comm.Week}
-	recorder, getters := comm.NewWindowQueryRecorderGetters(knower, windows)
-	if config.ReportMetrics {
-		recorder = comm.NewPromScalarRecorder(peerID.ID(), recorder)
+	recorder, getters, err := comm.LoadWindowQueryRecorderGetters(serverSL, knower, windows)
+	if err != nil {
+		return nil, err
 	}
-	prefer := comm.NewRpPreferer(getters[comm.Day])
+	judge, err := comm.LoadReputationJudge(serverSL, config.Reputation)
+	if err != nil {
+		return nil, err
+	}
+	prefer := comm.NewReputationPreferer(judge)
 	allower := comm.NewDefaultAllower(knower, getters)
-	doctor := comm.NewResponseTimeDoctor(getters[comm.Day])
+	doctor := comm.NewProbingDoctor(comm.NewResponseTimeDoctor(getters[comm.Day]), config.Probe)
 
-	rt := routing.NewEmpty(peerID.ID(), prefer, doctor, config.Routing)
-	clients, err := client.NewDefaultLRUPool()
+	rt, err := loadOrCreateRoutingTable(selfLogger, serverSL, routingSLD, peerID.ID(), prefer,
+		doctor, config.Routing)
+	if err != nil {
+		return nil, err
+	}
+	evict := func(peerID id.ID) { rt.Evict(peerID) }
+	blacklist := comm.NewGreylister(config.Greylist, comm.NewBlacklister(config.Blacklist, evict),
+		evict)
+	allowed := comm.NewAllowList(evict)
+	if config.AllowList.Enabled() {
+		blacklist = comm.NewAllowListBlacklister(allowed, blacklist)
+	}
+	rt = routing.WithBlacklister(rt, blacklist)
+	allowList, err := newAllowList(config.AllowList, config.AdminPubKey, allowed)
+	if err != nil {
+		return nil, err
+	}
+	banList, err := comm.LoadBanList(serverSL, blacklist)
+	if err != nil {
+		return nil, err
+	}
+	recorder = routing.NewEvictingRecorder(recorder, rt, config.Routing)
+	if config.ReportMetrics {
+		recorder = comm.NewPromScalarRecorder(peerID.ID(), recorder)
+	}
+	churn := comm.NewChurnRecorderGetter(comm.DefaultSessionTimeout, routing.NewKnower(rt))
+	recorder = comm.NewChurnRecorder(recorder, churn)
+	judged := comm.NewJudgedRecorder(recorder, judge)
+	recorder = judged
+	serverCreds, clientCreds, err := client.NewTLSCredentials(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	throttler := comm.NewThrottler(config.Throttle)
+	clients, err := client.NewDefaultLRUPoolWithInterceptors(withThrottler(
+		withTracePropagator(client.WithCredentials(config.ClientInterceptors, clientCreds),
+			config.TracePropagator), throttler, config.Throttle))
 	if err != nil {
 		return nil, err
 	}
@@ -173,24 +390,67 @@ func NewLibrarian(config *Config, logger *zap.Logger) (*Librarian, error) {
 		orgSigner = client.NewECDSASigner(config.OrgID.Key())
 	}
 
-	searcher := search.NewDefaultSearcher(peerSigner, orgSigner, recorder, doctor, clients)
-	storer := store.NewStorer(peerSigner, orgSigner, recorder, doctor, searcher,
-		client.NewStorerCreator(clients))
-	introducer := introduce.NewDefaultIntroducer(peerSigner, orgSigner, recorder, peerID.ID(),
-		clients)
+	keyspaceRec := comm.NewKeyspaceRecorderGetter(comm.DefaultKeyspacePrefixBits)
+	searcher := search.NewDefaultSearcher(peerSigner, orgSigner, recorder, keyspaceRec, doctor,
+		blacklist, clients)
+	if config.Tracer != nil {
+		searcher = search.WithTracer(searcher, config.Tracer)
+	}
+	latencies := routing.NewLatencyTracker(comm.NewLatencyTracker(comm.DefaultLatencySmoothing), rt)
+	searcher = search.WithLatencyTracker(searcher, latencies)
+	if config.Search.Retries() {
+		retryRng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		searcher = search.NewRetryingSearcher(searcher, retryRng)
+	}
+	searchMetrics := search.NewPromSearcher(searcher)
+	searcher = searchMetrics
+	innerStorer := store.NewStorer(peerSigner, orgSigner, recorder, doctor, searcher,
+		client.NewStorerCreator(clients), client.NewVerifierCreator(clients))
+	if config.Tracer != nil {
+		innerStorer = store.WithTracer(innerStorer, config.Tracer)
+	}
+	storer := store.NewPromStorer(innerStorer)
+	if config.Search.CachesNotFound() {
+		// only Gets use the cache, since Puts need fresh closest peers to store to
+		searcher = search.NewNotFoundCachingSearcher(searcher, config.Search.NotFoundTTL,
+			config.Search.NotFoundCacheSize)
+	}
+	skewRec := comm.NewSkewRecorder()
+	introducer := introduce.NewDefaultIntroducer(peerSigner, orgSigner, recorder, skewRec,
+		peerID.ID(), clients)
 	verifier := verify.NewDefaultVerifier(peerSigner, orgSigner, recorder, doctor, clients)
+	publicAddrs := append([]*net.TCPAddr{config.PublicAddr}, config.AltPublicAddrs...)
+	apiSelf := peer.FromAddresses(peerID.ID(), config.PublicName, publicAddrs)
+	apiSelf.Zone = config.Zone
+	apiSelf.Mirror = config.Mirror.Enabled()
+	addressUpdater := routing.NewAddressUpdater(rt, &introduceConfirmer{
+		peerID:     peerID,
+		orgID:      config.OrgID,
+		apiSelf:    apiSelf,
+		signer:     peerSigner,
+		orgSigner:  orgSigner,
+		introducer: client.NewIntroducerCreator(clients),
+		timeout:    config.Introduce.Timeout,
+	}, config.Routing)
 
 	newPubs := make(chan *subscribe.KeyedPub, newPublicationsSlack)
 	recentPubs, err := subscribe.NewRecentPublications(config.SubscribeTo.RecentCacheSize)
 	if err != nil {
 		return nil, err
 	}
+	buildInfo, err := client.NewSignedBuildInfo(peerSigner, peerID.PublicKeyBytes(),
+		newBuildInfo(version.Current))
+	if err != nil {
+		return nil, err
+	}
 	clientBalancer := routing.NewClientBalancer(rt, clients)
 	subscribeTo := subscribe.NewTo(config.SubscribeTo, selfLogger, peerID, config.OrgID,
 		clientBalancer, peerSigner, orgSigner, recentPubs, newPubs)
 
 	metricsSM := http.NewServeMux()
 	metricsSM.Handle("/metrics", promhttp.Handler())
+	metricsSM.Handle(keyspaceLatenciesPath, newKeyspaceLatenciesHandler(keyspaceRec))
+	metricsSM.Handle(churnPath, newChurnHandler(churn))
 	metrics := &http.Server{Addr: fmt.Sprintf(":%d", config.LocalMetricsPort), Handler: metricsSM}
 
 	rng := rand.New(rand.NewSource(peerID.Int().Int64()))
@@ -207,13 +467,29 @@ func NewLibrarian(config *Config, logger *zap.Logger) (*Librarian, error) {
 		rng,
 		selfLogger,
 	)
+	replicator = replicate.WithChurnGetter(replicator, churn)
+	replicator = replicate.WithOriginOrgGetter(replicator, quotas)
 	storageMetrics := newStorageMetrics(serverSL)
+	traceRng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var sav client.StoreAuthVerifier
+	if config.StoreAuthPubKey != nil {
+		sav = client.NewStoreAuthVerifier(config.StoreAuthPubKey)
+	}
+	var mv client.MacaroonVerifier
+	if len(config.MacaroonRootKey) > 0 {
+		mv = client.NewMacaroonVerifier(config.MacaroonRootKey)
+	}
+
+	rqTracer := newTracer(config.TraceSampleRates, traceRng, selfLogger).
+		withSpans(config.Tracer, config.TracePropagator)
 
 	return &Librarian{
 		peerID:         peerID,
 		config:         config,
-		apiSelf:        peer.FromAddress(peerID.ID(), config.PublicName, config.PublicAddr),
+		apiSelf:        apiSelf,
 		introducer:     introducer,
+		introThrottle:  introduce.NewThrottle(config.Introduce.MaxServedPerSecond),
+		introPuzzle:    config.Introduce.PuzzleDifficulty,
 		searcher:       searcher,
 		replicator:     replicator,
 		storer:         storer,
@@ -221,26 +497,96 @@ func NewLibrarian(config *Config, logger *zap.Logger) (*Librarian, error) {
 		subscribeTo:    subscribeTo,
 		RecentPubs:     recentPubs,
 		rqv:            NewRequestVerifier(),
+		sav:            sav,
+		mv:             mv,
 		db:             rdb,
+		coldDB:         coldDB,
 		serverSL:       serverSL,
 		documentSL:     documentSL,
+		tiered:         tiered,
+		expiring:       expiring,
+		storedKeys:     newStoredKeysCache(expiring),
+		hints:          hints,
+		readRepairs:    make(chan struct{}, maxConcurrentReadRepairs),
 		kc:             storage.NewExactLengthChecker(storage.EntriesKeyLength),
-		kvc:            storage.NewHashKeyValueChecker(),
+		kvc:            storage.NewDocumentKeyValueChecker(),
+		sysv:           client.NewVerifier(),
 		fromer:         peer.NewFromer(),
+		seeder:         seed.NewDefaultSeeder(config.Seed),
 		signer:         peerSigner,
+		orgSigner:      orgSigner,
 		clients:        clients,
 		rt:             rt,
+		addressUpdater: addressUpdater,
+		rtCheckpointer: routing.NewCheckpointer(rt, serverSL, routingSLD, config.Routing),
 		storageMetrics: storageMetrics,
+		tieringMetrics: tieringMetrics,
+		diskMetrics:    diskMetrics,
+		authMetrics:    newAuthMetrics(),
+		rtMetrics:      routing.NewPromCollector(rt),
+		dbMetrics:      newDBStatsCollector(dbs),
+		searchMetrics:  searchMetrics,
 		rec:            recorder,
+		qg:             getters[comm.Day],
+		windowQGs:      getters,
+		judge:          judged,
+		doctor:         doctor,
+		skewRec:        skewRec,
+		throttler:      throttler,
+		blacklist:      blacklist,
+		banList:        banList,
+		allowList:      allowList,
+		serverCreds:    serverCreds,
+		quotas:         quotas,
 		allower:        allower,
+		rqLimiter:      comm.NewRequesterLimiter(config.RequesterLimit),
+		tracer:         rqTracer,
+		recentPuts:     newRecentPuts(recentPutsSize),
+		recentStores:   recentStores,
 		logger:         selfLogger,
-		health:         health.NewServer(),
+		health:         newHealthServer(),
+		buildInfo:      buildInfo,
 		metrics:        metrics,
 		stop:           make(chan struct{}),
 		stopped:        make(chan struct{}),
 	}, nil
 }
 
+// withThrottler returns the client interceptors with the throttler's innermost, so any of the
+// embedding application's interceptors also see the time queries spend throttled.
+func withThrottler(
+	interceptors *client.Interceptors, throttler comm.Throttler, params *comm.ThrottleParameters,
+) *client.Interceptors {
+	if !params.Enabled() {
+		return interceptors
+	}
+	throttled := &client.Interceptors{}
+	if interceptors != nil {
+		*throttled = *interceptors
+	}
+	n := len(throttled.Unary)
+	throttled.Unary = append(throttled.Unary[:n:n], throttler.UnaryClientInterceptor())
+	return throttled
+}
+
+// withTracePropagator returns the client interceptors injecting the trace context of each query's
+// span into its metadata, if the propagator isn't nil.
+func withTracePropagator(
+	interceptors *client.Interceptors, p client.TracePropagator,
+) *client.Interceptors {
+	if p == nil {
+		return interceptors
+	}
+	traced := &client.Interceptors{}
+	if interceptors != nil {
+		*traced = *interceptors
+	}
+	nUnary, nStream := len(traced.Unary), len(traced.Stream)
+	traced.Unary = append(traced.Unary[:nUnary:nUnary], client.NewTracingUnaryInterceptor(p))
+	traced.Stream = append(traced.Stream[:nStream:nStream], client.NewTracingStreamInterceptor(p))
+	return traced
+}
+
 // Introduce receives and gives identifying information about the peer in the network.
 func (l *Librarian) Introduce(ctx context.Context, rq *api.IntroduceRequest) (
 	*api.IntroduceResponse, error) {
@@ -248,7 +594,7 @@ func (l *Librarian) Introduce(ctx context.Context, rq *api.IntroduceRequest) (
 	lg.Debug("received introduce request", introduceRequestFields(rq)...)
 	endpoint := api.Introduce
 
-	requesterID, err := l.checkRequest(ctx, rq, rq.Metadata)
+	requesterID, err := l.checkRequest(rq.Metadata)
 	if err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnInvalidRqErr(lg, err)
@@ -262,19 +608,35 @@ func (l *Librarian) Introduce(ctx context.Context, rq *api.IntroduceRequest) (
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnInvalidRqErr(lg, errBadPeerIDSig)
 	}
+	if !ecid.SolvesPuzzle(requesterID, l.introPuzzle) {
+		// don't let cheaply-made peers into the routing table
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnInvalidRqErr(lg, ecid.ErrPuzzleUnsolved)
+	}
+	if retryAfter := l.introThrottle.Take(time.Now()); retryAfter > 0 {
+		// hint to the requester how long to wait, smoothing out mass (re)starts; the requester
+		// hasn't done anything wrong, so its request isn't recorded as an error
+		_ = grpc.SetTrailer(ctx, client.NewRetryAfterTrailer(retryAfter))
+		return nil, logReturnNotAllowedErr(lg, introduce.NewThrottledErr())
+	}
 	l.record(requesterID, endpoint, comm.Request, comm.Success)
 
-	// add peer to routing table (if space)
-	l.rt.Push(requester)
+	// add peer to routing table (if space), confirming any change of address before applying it;
+	// read-only mirrors aren't added, since they don't store documents for other peers
+	if !rq.Self.Mirror && l.rt.Push(requester) == routing.Existed {
+		go l.maybeUpdateAddress(requester)
+	}
 
 	// get random peers for client, using request ID as unique source of entropy for sample
 	seed := int64(binary.BigEndian.Uint64(rq.Metadata.RequestId[:8]))
 	peers := l.rt.Sample(uint(rq.NumPeers), rand.New(rand.NewSource(seed)))
 
 	rp := &api.IntroduceResponse{
-		Metadata: l.NewResponseMetadata(rq.Metadata),
-		Self:     l.apiSelf,
-		Peers:    peer.ToAPIs(peers),
+		Metadata:  l.NewResponseMetadata(rq.Metadata),
+		Self:      l.apiSelf,
+		Peers:     peer.ToAPIs(peers),
+		TimeNanos: time.Now().UnixNano(),
+		BuildInfo: l.buildInfo,
 	}
 	lg.Info("introduced", introduceResponseFields(rp)...)
 	return rp, nil
@@ -286,7 +648,7 @@ func (l *Librarian) Find(ctx context.Context, rq *api.FindRequest) (*api.FindRes
 	lg.Debug("received find request", findRequestFields(rq)...)
 	endpoint := api.Find
 
-	requesterID, err := l.checkRequestAndKey(ctx, rq, rq.Metadata, rq.Key)
+	requesterID, err := l.checkRequestAndKey(rq.Metadata, rq.Key)
 	if err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnInvalidRqErr(lg, err)
@@ -295,20 +657,39 @@ func (l *Librarian) Find(ctx context.Context, rq *api.FindRequest) (*api.FindRes
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnNotAllowedErr(lg, err)
 	}
+	if err := l.checkRequesterLimit(ctx, requesterID, rq.Metadata, endpoint); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
 	l.record(requesterID, endpoint, comm.Request, comm.Success)
 
 	value, err := l.documentSL.Load(id.FromBytes(rq.Key))
+	if err == storage.ErrCorruptDocument {
+		// corrupt value has been removed, so respond as if we never had it
+		lg.Error("removed corrupt document", zap.Error(err))
+		value, err = nil, nil
+	}
 	if err != nil {
 		// something went wrong during load
 		return nil, logReturnInternalErr(lg, "error loading document", err)
 	}
 
-	// we have the value, so return it
+	// we have the value, so return it if the requester may get it
 	if value != nil {
+		if err = l.checkFindMacaroon(ctx, rq); err != nil {
+			return nil, logReturnNotAllowedErr(lg, err)
+		}
+		expires, hasExpiry, err := l.expiring.ExpireTime(id.FromBytes(rq.Key))
+		if err != nil {
+			return nil, logReturnInternalErr(lg, "error loading expire time", err)
+		}
 		rp := &api.FindResponse{
 			Metadata: l.NewResponseMetadata(rq.Metadata),
 			Value:    value,
 		}
+		if hasExpiry {
+			rp.ExpireTime = expires.Unix()
+		}
 		lg.Info("found value", findValueResponseFields(rq, rp)...)
 		return rp, nil
 	}
@@ -333,7 +714,7 @@ func (l *Librarian) Verify(
 	lg.Debug("received verify request", verifyRequestFields(rq)...)
 	endpoint := api.Verify
 
-	requesterID, err := l.checkRequestAndKey(ctx, rq, rq.Metadata, rq.Key)
+	requesterID, err := l.checkRequestAndKey(rq.Metadata, rq.Key)
 	if err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnInvalidRqErr(lg, err)
@@ -342,9 +723,22 @@ func (l *Librarian) Verify(
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnNotAllowedErr(lg, err)
 	}
+	if err := l.checkRequesterLimit(ctx, requesterID, rq.Metadata, endpoint); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
 	l.record(requesterID, endpoint, comm.Request, comm.Success)
 
-	mac, err := l.documentSL.Mac(id.FromBytes(rq.Key), rq.MacKey)
+	key := id.FromBytes(rq.Key)
+	mac, err := l.macDocument(key, rq)
+	if err == storage.ErrCorruptDocument {
+		// corrupt value has been removed, so respond as if we never had it
+		lg.Error("removed corrupt document", zap.Error(err))
+		mac, err = nil, nil
+	}
+	if err == api.ErrSliceOutOfRange {
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
 	if err != nil {
 		// something went wrong during load
 		return nil, logReturnInternalErr(lg, "error MACing document", err)
@@ -361,7 +755,6 @@ func (l *Librarian) Verify(
 	}
 
 	// otherwise, return the peers closest to the key
-	key := id.FromBytes(rq.Key)
 	closest := l.rt.Find(key, uint(rq.NumPeers))
 	rp := &api.VerifyResponse{
 		Metadata: l.NewResponseMetadata(rq.Metadata),
@@ -371,6 +764,23 @@ func (l *Librarian) Verify(
 	return rp, nil
 }
 
+// macDocument returns the MAC of the stored document, or of just the requested slice of its
+// serialized bytes for proof of possession challenges, or nil if the document isn't stored.
+func (l *Librarian) macDocument(key id.ID, rq *api.VerifyRequest) ([]byte, error) {
+	if rq.SliceLength == 0 {
+		return l.documentSL.Mac(key, rq.MacKey)
+	}
+	doc, err := l.documentSL.Load(key)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	docBytes, err := proto.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return api.SliceHMAC256(docBytes, rq.MacKey, rq.SliceOffset, rq.SliceLength)
+}
+
 // Store stores the value.
 func (l *Librarian) Store(ctx context.Context, rq *api.StoreRequest) (
 	*api.StoreResponse, error) {
@@ -378,30 +788,125 @@ func (l *Librarian) Store(ctx context.Context, rq *api.StoreRequest) (
 	lg.Debug("received store request", storeRequestFields(rq)...)
 	endpoint := api.Store
 
-	requesterID, err := l.checkRequestAndKeyValue(ctx, rq, rq.Metadata, rq.Key, rq.Value)
+	requesterID, err := l.checkRequestAndKeyValue(rq.Metadata, rq.Key, rq.Value)
 	if err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnInvalidRqErr(lg, err)
 	}
+	if err = l.checkPageSize(rq.Value); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err = checkExpireTime(rq.ExpireTime, time.Now()); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
+	if err = checkHint(rq.Hint, l.peerID.ID()); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
 	if err := l.allower.Allow(requesterID, endpoint); err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnNotAllowedErr(lg, err)
 	}
+	if err := l.checkRequesterLimit(ctx, requesterID, rq.Metadata, endpoint); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if err := l.checkStoreAuth(ctx, rq.Metadata); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if err := l.checkStoreMacaroon(ctx, rq); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if err := l.checkNotMirror(); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
 	l.record(requesterID, endpoint, comm.Request, comm.Success)
 
-	if err := l.documentSL.Store(id.FromBytes(rq.Key), rq.Value); err != nil {
-		return nil, logReturnInternalErr(lg, "error storing document", err)
+	key := id.FromBytes(rq.Key)
+	mac, err := documentMAC(key, rq.Value)
+	if err != nil {
+		return nil, logReturnInternalErr(lg, "error MACing document", err)
+	}
+	if prev, in := l.recentStores.get(key, mac); in {
+		// retried Store, so return the original response without reading or writing the
+		// document again
+		if err := l.reconcileExpireTime(key, rq.ExpireTime); err != nil {
+			l.recentStores.remove(key)
+			return nil, logReturnStorageErr(lg, "error updating expire time", err)
+		}
+		rp := &api.StoreResponse{
+			Metadata:      l.NewResponseMetadata(rq.Metadata),
+			AlreadyStored: true,
+			Receipt:       prev.Receipt,
+		}
+		lg.Debug("recently stored", storeResponseFields(rq, rp)...)
+		return rp, nil
+	}
+	alreadyStored, err := l.alreadyStored(key, mac)
+	if err != nil {
+		return nil, logReturnInternalErr(lg, "error MACing stored document", err)
+	}
+	receipt, err := client.NewSignedStoreReceipt(l.signer, l.peerID.PublicKeyBytes(), rq.Key,
+		rq.Value, time.Now())
+	if err != nil {
+		return nil, logReturnInternalErr(lg, "error signing store receipt", err)
+	}
+	if err := l.addHint(key, requesterID, rq.Hint); err != nil {
+		if err == errTooManyHints {
+			return nil, logReturnNotAllowedErr(lg,
+				status.Error(codes.ResourceExhausted, err.Error()))
+		}
+		return nil, logReturnStorageErr(lg, "error adding hint", err)
+	}
+	if alreadyStored {
+		// e.g., a retried Store, so don't re-write it, double-count it, or re-publish it
+		if err := l.reconcileExpireTime(key, rq.ExpireTime); err != nil {
+			l.recentStores.remove(key)
+			return nil, logReturnStorageErr(lg, "error updating expire time", err)
+		}
+		rp := &api.StoreResponse{
+			Metadata:      l.NewResponseMetadata(rq.Metadata),
+			AlreadyStored: true,
+			Receipt:       receipt,
+		}
+		l.recentStores.add(key, mac, rp)
+		lg.Debug("already stored", storeResponseFields(rq, rp)...)
+		return rp, nil
 	}
+	if err := l.reserveQuota(storeQuotaOrgID(ctx, rq), key, rq.Value); err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			return nil, logReturnNotAllowedErr(lg, err)
+		}
+		return nil, logReturnStorageErr(lg, "error reserving quota", err)
+	}
+	if err := l.storeDocument(key, rq.Value, rq.ExpireTime); err != nil {
+		l.recentStores.remove(key)
+		if err := l.quotas.Release(key); err != nil {
+			lg.Error("error releasing quota", zap.Error(err))
+		}
+		return nil, logReturnStorageErr(lg, "error storing document", err)
+	}
+	l.forgetNotFound(key)
 	if err := l.storageMetrics.Add(rq.Value); err != nil {
 		// don't hard-fail on this since just internal book-keeping
 		lg.Error("error storing metric", zap.Error(err))
 	}
-	if err := l.subscribeTo.Send(api.GetPublication(rq.Key, rq.Value)); err != nil {
-		return nil, logReturnInternalErr(lg, "error sending publication", err)
+	if rq.Hint == nil {
+		// hinted documents are published by their intended holders once handed off
+		if err := l.subscribeTo.Send(api.GetPublication(rq.Key, rq.Value)); err != nil {
+			return nil, logReturnInternalErr(lg, "error sending publication", err)
+		}
 	}
 	rp := &api.StoreResponse{
 		Metadata: l.NewResponseMetadata(rq.Metadata),
+		Receipt:  receipt,
 	}
+	l.recentStores.add(key, mac, rp)
 	l.logger.Debug("stored", storeResponseFields(rq, rp)...)
 	return rp, nil
 }
@@ -413,7 +918,7 @@ func (l *Librarian) Get(ctx context.Context, rq *api.GetRequest) (*api.GetRespon
 	lg.Debug("received get request", getRequestFields(rq)...)
 	endpoint := api.Get
 
-	requesterID, err := l.checkRequestAndKey(ctx, rq, rq.Metadata, rq.Key)
+	requesterID, err := l.checkRequestAndKey(rq.Metadata, rq.Key)
 	if err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnInvalidRqErr(lg, err)
@@ -422,12 +927,29 @@ func (l *Librarian) Get(ctx context.Context, rq *api.GetRequest) (*api.GetRespon
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnNotAllowedErr(lg, err)
 	}
+	if err = l.checkGetMacaroon(ctx, rq); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
 	l.record(requesterID, endpoint, comm.Request, comm.Success)
 
 	key := id.FromBytes(rq.Key)
-	s := search.NewSearch(l.peerID, l.orgID, key, l.config.Search)
+	if mirrored, err := l.loadMirrored(key); err != nil {
+		return nil, logReturnInternalErr(lg, "error loading mirrored document", err)
+	} else if mirrored != nil {
+		rp := &api.GetResponse{
+			Metadata: l.NewResponseMetadata(rq.Metadata),
+			Value:    mirrored,
+		}
+		lg.Info("got mirrored value", getResponseFields(rq, rp)...)
+		return rp, nil
+	}
+	s := search.NewSearch(l.peerID, l.orgID, key, getSearchParams(l.config, rq))
+	defer s.Release()
 	seeds := l.rt.Find(key, s.Params.NClosestResponses)
-	if err = l.searcher.Search(s, seeds); err != nil {
+	if err = l.searcher.Search(ctx, s, seeds); interrupted(err) {
+		return nil, logReturnCanceledErr(lg, err)
+	} else if err != nil {
 		return nil, logReturnInternalErr(lg, "error searching", err)
 	}
 
@@ -437,6 +959,9 @@ func (l *Librarian) Get(ctx context.Context, rq *api.GetRequest) (*api.GetRespon
 	}
 
 	if s.FoundValue() {
+		if missing := s.Result.MissingClosest(); len(missing) > 0 {
+			l.maybeReadRepair(key, s.Result.Value, s.Result.ExpireTime, missing)
+		}
 		rp := &api.GetResponse{
 			Metadata: l.NewResponseMetadata(rq.Metadata),
 			Value:    s.Result.Value,
@@ -444,6 +969,23 @@ func (l *Librarian) Get(ctx context.Context, rq *api.GetRequest) (*api.GetRespon
 		lg.Info("got value", getResponseFields(rq, rp)...)
 		return rp, nil
 	}
+	// value may have been stored as erasure-coded shards instead of full replicas; the manifest
+	// search is released along with the value search, after the latter's result is last read
+	manifestSearch := l.newShardManifestSearch(key)
+	defer manifestSearch.Release()
+	value, err := l.getShards(ctx, key, manifestSearch)
+	if interrupted(err) {
+		return nil, logReturnCanceledErr(lg, err)
+	} else if err == nil && value != nil {
+		rp := &api.GetResponse{
+			Metadata: l.NewResponseMetadata(rq.Metadata),
+			Value:    value,
+		}
+		lg.Info("got erasure-coded value", getResponseFields(rq, rp)...)
+		return rp, nil
+	} else if err != nil && err != store.ErrTooFewShards {
+		lg.Warn("error reconstructing value from shards", zap.Error(err))
+	}
 	if s.FoundClosestPeers() {
 		rp := &api.GetResponse{
 			Metadata: l.NewResponseMetadata(rq.Metadata),
@@ -463,6 +1005,19 @@ func (l *Librarian) Get(ctx context.Context, rq *api.GetRequest) (*api.GetRespon
 	return nil, logReturnInternalErr(lg, "search errored", errSearchUnexpectedResult, fs...)
 }
 
+// getSearchParams returns the search parameters for the Get request, which differ from those of
+// the librarian's other searches in reading repair when the request asks (see readRepairParams)
+// and in ordering peers by latency when GetLatencyWeighted.
+func getSearchParams(c *Config, rq *api.GetRequest) *search.Parameters {
+	params := readRepairParams(c.Search, rq)
+	if !c.GetLatencyWeighted || params.LatencyWeighted {
+		return params
+	}
+	getParams := *params
+	getParams.LatencyWeighted = true
+	return &getParams
+}
+
 // Put stores a given key and value. This endpoint handles the internals of finding the right
 // peers to store the value in and then sending them store requests.
 func (l *Librarian) Put(ctx context.Context, rq *api.PutRequest) (*api.PutResponse, error) {
@@ -470,40 +1025,96 @@ func (l *Librarian) Put(ctx context.Context, rq *api.PutRequest) (*api.PutRespon
 	lg.Debug("received put request", putRequestFields(rq)...)
 	endpoint := api.Put
 
-	requesterID, err := l.checkRequestAndKeyValue(ctx, rq, rq.Metadata, rq.Key, rq.Value)
+	requesterID, err := l.checkRequestAndKeyValue(rq.Metadata, rq.Key, rq.Value)
 	if err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnInvalidRqErr(lg, err)
 	}
+	if err = l.checkPageSize(rq.Value); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnInvalidRqErr(lg, err)
+	}
 	if err = l.allower.Allow(requesterID, endpoint); err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return nil, logReturnNotAllowedErr(lg, err)
 	}
+	if err = l.checkStoreAuth(ctx, rq.Metadata); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if err = l.checkPutMacaroon(ctx, rq); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	if err = l.checkNotMirror(); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
+	orgID := quotaOrgID(ctx, rq.Metadata)
+	if err = l.checkQuota(orgID, rq.Value); err != nil {
+		l.record(requesterID, endpoint, comm.Request, comm.Error)
+		return nil, logReturnNotAllowedErr(lg, err)
+	}
 	l.record(requesterID, endpoint, comm.Request, comm.Success)
 
 	key := id.FromBytes(rq.Key)
+	mac, err := documentMAC(key, rq.Value)
+	if err != nil {
+		return nil, logReturnInternalErr(lg, "error MACing document", err)
+	}
+	if nReplicas, in := l.recentPuts.get(key, mac); in {
+		// e.g., a client retrying after a slow response, so don't store the value again
+		rp := &api.PutResponse{
+			Metadata:  l.NewResponseMetadata(rq.Metadata),
+			Operation: api.PutOperation_ALREADY_STORED,
+			NReplicas: nReplicas,
+		}
+		lg.Info("put already stored value", putResponseFields(rq, rp)...)
+		return rp, nil
+	}
+	if l.config.Store.ErasureCoded(proto.Size(rq.Value)) {
+		return l.putShards(ctx, lg, rq, key, mac, orgID)
+	}
 	s := store.NewStore(
 		l.peerID,
 		l.orgID,
 		key,
 		rq.Value,
 		l.config.Search,
-		l.config.Store,
+		l.putStoreParams(rq),
 	)
+	defer s.Release()
+	if expires := putExpireTime(rq); !expires.IsZero() {
+		s.ExpireAt(expires)
+	}
+	// charge the stored replicas to the requester's org rather than this librarian's
+	s.ChargeOrg(orgID)
 	lg.Debug("beginning store queries", zap.String(logKey, id.Hex(rq.Key)))
 	seeds := l.rt.Find(key, s.Search.Params.NClosestResponses)
-	if err = l.storer.Store(s, seeds); err != nil {
+	err = l.storer.Store(ctx, s, seeds)
+	if interrupted(err) {
+		return nil, logReturnCanceledErr(lg, err, storeDetailFields(s)...)
+	} else if err != nil {
 		fs := storeDetailFields(s)
 		return nil, logReturnInternalErr(lg, "error storing", err, fs...)
 	}
 	for _, p := range s.Result.Responded {
 		l.rt.Push(p)
 	}
+	if s.Stored() || s.Exists() {
+		l.forgetNotFound(key)
+	}
 	if s.Stored() {
 		rp := &api.PutResponse{
 			Metadata:  l.NewResponseMetadata(rq.Metadata),
 			Operation: api.PutOperation_STORED,
 			NReplicas: uint32(len(s.Result.Responded)),
+			Receipts:  s.Result.Receipts,
+		}
+		l.recentPuts.add(key, mac, rp.NReplicas)
+		if len(s.Result.Spilled) > 0 {
+			// replicas outside the closest set suggest the closest peers are unhealthy
+			lg.Warn("stored replicas outside closest peers", spilledFields(s)...)
 		}
 		lg.Info("put new value", putResponseFields(rq, rp)...)
 		return rp, nil
@@ -514,6 +1125,7 @@ func (l *Librarian) Put(ctx context.Context, rq *api.PutRequest) (*api.PutRespon
 			Operation: api.PutOperation_LEFT_EXISTING,
 			NReplicas: uint32(len(s.Result.Responded)),
 		}
+		l.recentPuts.add(key, mac, rp.NReplicas)
 		lg.Info("put existing value", putResponseFields(rq, rp)...)
 		return rp, nil
 	}
@@ -528,6 +1140,23 @@ func (l *Librarian) Put(ctx context.Context, rq *api.PutRequest) (*api.PutRespon
 	return nil, logReturnInternalErr(lg, "store errored", errStoreUnexpectedResult)
 }
 
+// putStoreParams returns the parameters for storing a Put request's value: the request's number of
+// replicas if given (up to MaxPutNReplicas), otherwise the store policy's parameters for the
+// value if there is a policy, otherwise the default.
+func (l *Librarian) putStoreParams(rq *api.PutRequest) *store.Parameters {
+	if rq.NReplicas > 0 {
+		nReplicas := uint(rq.NReplicas)
+		if nReplicas > MaxPutNReplicas {
+			nReplicas = MaxPutNReplicas
+		}
+		return store.WithNReplicas(l.config.Store, nReplicas)
+	}
+	if l.config.StorePolicy != nil {
+		return l.config.StorePolicy.Parameters(rq.Value, l.config.Store)
+	}
+	return l.config.Store
+}
+
 // Subscribe begins a subscription to the peer's publication stream (from its own subscriptions to
 // other peers).
 func (l *Librarian) Subscribe(rq *api.SubscribeRequest, from api.Librarian_SubscribeServer) error {
@@ -535,7 +1164,7 @@ func (l *Librarian) Subscribe(rq *api.SubscribeRequest, from api.Librarian_Subsc
 	lg.Debug("received subscribe request")
 	endpoint := api.Subscribe
 
-	requesterID, err := l.checkRequest(from.Context(), rq, rq.Metadata)
+	requesterID, err := l.checkRequest(rq.Metadata)
 	if err != nil {
 		l.record(requesterID, endpoint, comm.Request, comm.Error)
 		return logReturnInvalidRqErr(lg, err)
@@ -610,3 +1239,37 @@ func maybeSend(
 	}
 	return nil
 }
+
+// RoutingTable streams the contents of the librarian's routing table, one bucket per response,
+// to requests signed by the admin key.
+func (l *Librarian) RoutingTable(
+	rq *api.RoutingTableRequest, to api.Librarian_RoutingTableServer,
+) error {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received routing table request")
+
+	if _, err := l.checkRequest(rq.Metadata); err != nil {
+		return logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(rq.Metadata); err != nil {
+		return logReturnNotAllowedErr(lg, err)
+	}
+
+	snapshot := l.rt.Snapshot(l.qg)
+	responseMetadata := l.NewResponseMetadata(rq.Metadata)
+	for _, bs := range snapshot.Buckets {
+		bucket, err := bs.ToAPI()
+		if err != nil {
+			return logReturnInternalErr(lg, "routing bucket conversion error", err)
+		}
+		rp := &api.RoutingTableResponse{
+			Metadata: responseMetadata,
+			Bucket:   bucket,
+		}
+		if err := to.Send(rp); err != nil {
+			return logReturnUnavailErr(lg, "routing table send error", err)
+		}
+	}
+	lg.Debug("sent routing table", zap.Int(logNBuckets, len(snapshot.Buckets)))
+	return nil
+}
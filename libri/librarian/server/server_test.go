@@ -10,6 +10,7 @@ import (
 	"math/rand"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/ecid"
@@ -21,6 +22,7 @@ import (
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/introduce"
 	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/drausin/libri/libri/librarian/server/routing"
 	"github.com/drausin/libri/libri/librarian/server/search"
@@ -28,7 +30,9 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -71,6 +75,46 @@ func TestNewLibrarian(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestWithThrottler(t *testing.T) {
+	throttler := comm.NewThrottler(comm.NewDefaultThrottleParameters())
+
+	// no added interceptor when disabled
+	interceptors := &client.Interceptors{}
+	assert.Equal(t, interceptors,
+		withThrottler(interceptors, throttler, &comm.ThrottleParameters{}))
+
+	throttled := withThrottler(nil, throttler, comm.NewDefaultThrottleParameters())
+	assert.Len(t, throttled.Unary, 1)
+
+	// throttler added after existing interceptors without modifying them
+	interceptors.Unary = throttled.Unary
+	throttled = withThrottler(interceptors, throttler, comm.NewDefaultThrottleParameters())
+	assert.Len(t, throttled.Unary, 2)
+	assert.Len(t, interceptors.Unary, 1)
+}
+
+func TestWithTracePropagator(t *testing.T) {
+	interceptors := &client.Interceptors{
+		Unary: []grpc.UnaryClientInterceptor{comm.NewThrottler(
+			comm.NewDefaultThrottleParameters()).UnaryClientInterceptor()},
+	}
+
+	// no interceptors added when nil
+	assert.Equal(t, interceptors, withTracePropagator(interceptors, nil))
+
+	p := &fixedPropagator{}
+	traced := withTracePropagator(nil, p)
+	assert.Len(t, traced.Unary, 1)
+	assert.Len(t, traced.Stream, 1)
+
+	// tracing interceptors appended without modifying existing interceptors
+	traced = withTracePropagator(interceptors, p)
+	assert.Len(t, traced.Unary, 2)
+	assert.Len(t, traced.Stream, 1)
+	assert.Len(t, interceptors.Unary, 1)
+	assert.Nil(t, interceptors.Stream)
+}
+
 func newTestLibrarian() *Librarian {
 	config := newTestConfig()
 	l, err := NewLibrarian(config, zap.NewNop())
@@ -84,6 +128,7 @@ func newTestConfig() *Config {
 	cerrors.MaybePanic(err)
 	config.WithDataDir(dir).WithDefaultDBDir() // resets default DB dir given new data dir
 	config.WithReportMetrics(false)            // otherwise can get duplicate Prom registrations
+	config.Introduce.MaxStartDelay = 0
 	return config
 }
 
@@ -108,14 +153,16 @@ func TestLibrarian_Introduce_ok(t *testing.T) {
 			PublicName: peerName,
 			LocalPort:  publicAddr.Port,
 		},
-		apiSelf: peer.FromAddress(serverID.ID(), peerName, publicAddr),
-		fromer:  peer.NewFromer(),
-		peerID:  serverID,
-		rt:      rt,
-		rqv:     &alwaysRequestVerifier{},
-		rec:     rec,
-		allower: &fixedAllower{},
-		logger:  zap.NewNop(), // clogging.NewDevInfoLogger(),
+		apiSelf:       peer.FromAddress(serverID.ID(), peerName, publicAddr),
+		fromer:        peer.NewFromer(),
+		peerID:        serverID,
+		rt:            rt,
+		rqv:           &alwaysRequestVerifier{},
+		rec:           rec,
+		allower:       &fixedAllower{},
+		introThrottle: introduce.NewThrottle(0),
+		buildInfo:     &api.SignedBuildInfo{BuildInfo: &api.BuildInfo{Version: "0.1.0"}},
+		logger:        zap.NewNop(), // clogging.NewDevInfoLogger(),
 	}
 
 	clientID, clientPeerIdx := ecid.NewPseudoRandom(rng), 1
@@ -142,8 +189,25 @@ func TestLibrarian_Introduce_ok(t *testing.T) {
 	assert.Equal(t, serverID.ID().Bytes(), rp.Self.PeerId)
 	assert.Equal(t, peerName, rp.Self.PeerName)
 	assert.Equal(t, int(numPeers), len(rp.Peers))
+	assert.Equal(t, lib.buildInfo, rp.BuildInfo)
+	assert.NotZero(t, rp.TimeNanos)
 	qo := rec.Get(clientImpl.ID(), api.Introduce)
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Success].Count))
+
+	// read-only mirrors are introduced but not added to the routing table
+	mirrorID := ecid.NewPseudoRandom(rng)
+	mirrorSelf := peer.New(mirrorID.ID(), "mirror", peer.NewTestPublicAddr(2)).ToAPI()
+	mirrorSelf.Mirror = true
+	rq = &api.IntroduceRequest{
+		Metadata: newTestRequestMetadata(rng, mirrorID),
+		Self:     mirrorSelf,
+		NumPeers: numPeers,
+	}
+	rp, err = lib.Introduce(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, int(numPeers), len(rp.Peers))
+	_, exists = lib.rt.Get(mirrorID.ID())
+	assert.False(t, exists)
 }
 
 func TestLibrarian_Introduce_checkRequestErr(t *testing.T) {
@@ -198,6 +262,70 @@ func TestLibrarian_Introduce_allowErr(t *testing.T) {
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
 }
 
+func TestLibrarian_Introduce_throttled(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := routing.NewTestWithPeers(rng, 0)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+
+	lib := &Librarian{
+		fromer:        peer.NewFromer(),
+		rt:            rt,
+		rqv:           &alwaysRequestVerifier{},
+		rec:           rec,
+		allower:       &fixedAllower{},
+		introThrottle: &fixedThrottle{retryAfter: time.Second},
+		logger:        zap.NewNop(), // clogging.NewDevInfoLogger()
+	}
+
+	clientID, clientPeerIdx := ecid.NewPseudoRandom(rng), 1
+	client1 := peer.New(
+		clientID.ID(),
+		"client",
+		peer.NewTestPublicAddr(clientPeerIdx),
+	)
+	rq := &api.IntroduceRequest{
+		Metadata: newTestRequestMetadata(rng, clientID),
+		Self:     client1.ToAPI(),
+	}
+	stream := &trailerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	rp, err := lib.Introduce(ctx, rq)
+
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.ResourceExhausted, getErrCode(t, err))
+	retryAfter, hinted := client.FromRetryAfterTrailer(stream.trailer)
+	assert.True(t, hinted)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// throttled requester isn't blamed for it
+	qo := rec.Get(clientID.ID(), api.Introduce)
+	assert.Zero(t, qo[comm.Request][comm.Error].Count)
+}
+
+type fixedThrottle struct {
+	retryAfter time.Duration
+}
+
+func (f *fixedThrottle) Take(now time.Time) time.Duration {
+	return f.retryAfter
+}
+
+// trailerStream captures the trailer a handler sets.
+type trailerStream struct {
+	trailer metadata.MD
+}
+
+func (f *trailerStream) Method() string { return "" }
+
+func (f *trailerStream) SetHeader(md metadata.MD) error { return nil }
+
+func (f *trailerStream) SendHeader(md metadata.MD) error { return nil }
+
+func (f *trailerStream) SetTrailer(md metadata.MD) error {
+	f.trailer = metadata.Join(f.trailer, md)
+	return nil
+}
+
 func TestLibrarian_Introduce_peerIDErr(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	rt, _, _, _ := routing.NewTestWithPeers(rng, 0)
@@ -236,8 +364,43 @@ func TestLibrarian_Introduce_peerIDErr(t *testing.T) {
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
 }
 
+func TestLibrarian_Introduce_puzzleErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, _, _, _ := routing.NewTestWithPeers(rng, 0)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+
+	lib := &Librarian{
+		fromer:        peer.NewFromer(),
+		rt:            rt,
+		rqv:           &alwaysRequestVerifier{},
+		rec:           rec,
+		allower:       &fixedAllower{},
+		introThrottle: introduce.NewThrottle(0),
+		introPuzzle:   4,
+		logger:        zap.NewNop(), // clogging.NewDevInfoLogger()
+	}
+
+	clientID := ecid.NewPseudoRandom(rng)
+	for ecid.SolvesPuzzle(clientID.ID(), lib.introPuzzle) {
+		clientID = ecid.NewPseudoRandom(rng)
+	}
+	client1 := peer.New(clientID.ID(), "client", peer.NewTestPublicAddr(1))
+	rq := &api.IntroduceRequest{
+		Metadata: newTestRequestMetadata(rng, clientID),
+		Self:     client1.ToAPI(),
+	}
+	rp, err := lib.Introduce(context.Background(), rq)
+
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	_, exists := lib.rt.Get(client1.ID())
+	assert.False(t, exists)
+	qo := rec.Get(clientID.ID(), api.Introduce)
+	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
+}
+
 func TestLibrarian_Find_peers(t *testing.T) {
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -258,6 +421,7 @@ func TestLibrarian_Find_peers(t *testing.T) {
 				rqv:        &alwaysRequestVerifier{},
 				rec:        rec,
 				allower:    &fixedAllower{},
+				rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
 				logger:     zap.NewNop(), // clogging.NewDevInfoLogger()
 			}
 
@@ -303,22 +467,25 @@ func checkPeersFindResponse(
 func TestLibrarian_Find_value(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
 
 	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	expiring := storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb)
 	l := &Librarian{
 		peerID:     peerID,
 		db:         kvdb,
 		serverSL:   storage.NewServerSL(kvdb),
-		documentSL: storage.NewDocumentSLD(kvdb),
+		documentSL: expiring,
+		expiring:   expiring,
 		rt:         rt,
 		kc:         storage.NewExactLengthChecker(storage.EntriesKeyLength),
 		rqv:        &alwaysRequestVerifier{},
 		rec:        rec,
 		allower:    &fixedAllower{},
+		rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
 		logger:     zap.NewNop(), // clogging.NewDevInfoLogger()
 	}
 
@@ -343,7 +510,59 @@ func TestLibrarian_Find_value(t *testing.T) {
 	assert.NotNil(t, rp.Value)
 	assert.Nil(t, rp.Peers)
 	assert.Equal(t, value, rp.Value)
+	assert.Zero(t, rp.ExpireTime)
 	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+
+	// expiring value is returned with its expire time
+	value, key = api.NewTestDocument(rng)
+	expires := time.Now().Add(time.Hour)
+	assert.Nil(t, expiring.StoreExpiring(key, value, expires))
+	rq.Metadata, rq.Key = newTestRequestMetadata(rng, l.peerID), key.Bytes()
+	rp, err = l.Find(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, value, rp.Value)
+	assert.Equal(t, expires.Unix(), rp.ExpireTime)
+}
+
+func TestLibrarian_FindVerify_corruptDocument(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, nAdded, _ := routing.NewTestWithPeers(rng, 64)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	numClosest := uint32(routing.DefaultMaxActivePeers)
+	key := id.NewPseudoRandom(rng)
+	l := &Librarian{
+		peerID:     peerID,
+		documentSL: &storage.TestDocSLD{LoadErr: storage.ErrCorruptDocument},
+		kc:         storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		rt:         rt,
+		rqv:        &alwaysRequestVerifier{},
+		rec:        rec,
+		allower:    &fixedAllower{},
+		rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		logger:     zap.NewNop(), // clogging.NewDevInfoLogger()
+	}
+
+	// corrupt document should be treated as missing
+	findRq := &api.FindRequest{
+		Metadata: newTestRequestMetadata(rng, l.peerID),
+		Key:      key.Bytes(),
+		NumPeers: numClosest,
+	}
+	findRp, err := l.Find(context.Background(), findRq)
+	assert.Nil(t, err)
+	checkPeersFindResponse(t, findRq, findRp, nAdded, numClosest)
+
+	l.documentSL = &storage.TestDocSLD{MacErr: storage.ErrCorruptDocument}
+	verifyRq := &api.VerifyRequest{
+		Metadata: newTestRequestMetadata(rng, l.peerID),
+		Key:      key.Bytes(),
+		MacKey:   api.RandBytes(rng, api.HMACKeyLength),
+		NumPeers: numClosest,
+	}
+	verifyRp, err := l.Verify(context.Background(), verifyRq)
+	assert.Nil(t, err)
+	assert.Nil(t, verifyRp.Mac)
+	assert.Equal(t, int(numClosest), len(verifyRp.Peers))
 }
 
 func TestLibrarian_Find_err(t *testing.T) {
@@ -374,12 +593,13 @@ func TestLibrarian_Find_err(t *testing.T) {
 
 		"allow error": {
 			l: &Librarian{
-				logger:  zap.NewNop(), // clogging.NewDevInfoLogger()
-				rqv:     &alwaysRequestVerifier{},
-				kc:      storage.NewExactLengthChecker(storage.EntriesKeyLength),
-				rec:     rec,
-				allower: &fixedAllower{errNotAllowed},
-				rt:      rt,
+				logger:    zap.NewNop(), // clogging.NewDevInfoLogger()
+				rqv:       &alwaysRequestVerifier{},
+				kc:        storage.NewExactLengthChecker(storage.EntriesKeyLength),
+				rec:       rec,
+				allower:   &fixedAllower{errNotAllowed},
+				rqLimiter: comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+				rt:        rt,
 			},
 			rqCreator: func() *api.FindRequest {
 				return client.NewFindRequest(peerID, orgID, key, uint(8))
@@ -395,6 +615,7 @@ func TestLibrarian_Find_err(t *testing.T) {
 				documentSL: &storage.TestDocSLD{LoadErr: errors.New("some Load error")},
 				rec:        rec,
 				allower:    &fixedAllower{},
+				rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
 				rt:         rt,
 			},
 			rqCreator: func() *api.FindRequest {
@@ -414,7 +635,7 @@ func TestLibrarian_Find_err(t *testing.T) {
 func TestLibrarian_Verify_value(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -430,6 +651,7 @@ func TestLibrarian_Verify_value(t *testing.T) {
 		rqv:        &alwaysRequestVerifier{},
 		rec:        rec,
 		allower:    &fixedAllower{},
+		rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
 		logger:     zap.NewNop(), // clogging.NewDevInfoLogger(),
 	}
 
@@ -464,10 +686,24 @@ func TestLibrarian_Verify_value(t *testing.T) {
 	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
 	qo := rec.Get(l.peerID.ID(), api.Verify)
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Success].Count))
+
+	// check MAC of just a slice of the value, as for proof of possession challenges
+	rq.SliceOffset, rq.SliceLength = 8, 16
+	rp, err = l.Verify(context.Background(), rq)
+	assert.Nil(t, err)
+	expectedSliceMAC, err := api.SliceHMAC256(valueBytes, macKey, 8, 16)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedSliceMAC, rp.Mac)
+
+	// check slice past the end of the value is invalid
+	rq.SliceOffset = uint32(len(valueBytes))
+	rp, err = l.Verify(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
 }
 
 func TestLibrarian_Verify_peers(t *testing.T) {
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
@@ -488,6 +724,7 @@ func TestLibrarian_Verify_peers(t *testing.T) {
 				rqv:        &alwaysRequestVerifier{},
 				rec:        rec,
 				allower:    &fixedAllower{},
+				rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
 				logger:     zap.NewNop(), // clogging.NewDevInfoLogger(),
 			}
 
@@ -558,12 +795,13 @@ func TestLibrarian_Verify_err(t *testing.T) {
 
 		"allow error": {
 			l: &Librarian{
-				logger:  zap.NewNop(), // clogging.NewDevInfoLogger()
-				rqv:     &alwaysRequestVerifier{},
-				kc:      storage.NewExactLengthChecker(storage.EntriesKeyLength),
-				rec:     rec,
-				allower: &fixedAllower{errNotAllowed},
-				rt:      rt,
+				logger:    zap.NewNop(), // clogging.NewDevInfoLogger()
+				rqv:       &alwaysRequestVerifier{},
+				kc:        storage.NewExactLengthChecker(storage.EntriesKeyLength),
+				rec:       rec,
+				allower:   &fixedAllower{errNotAllowed},
+				rqLimiter: comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+				rt:        rt,
 			},
 			rqCreator: func() *api.VerifyRequest {
 				return client.NewVerifyRequest(peerID, orgID, key, macKey, uint(8))
@@ -580,6 +818,7 @@ func TestLibrarian_Verify_err(t *testing.T) {
 				rt:         rt,
 				rec:        rec,
 				allower:    &fixedAllower{},
+				rqLimiter:  comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
 			},
 			rqCreator: func() *api.VerifyRequest {
 				return client.NewVerifyRequest(peerID, orgID, key, macKey, uint(8))
@@ -598,26 +837,36 @@ func TestLibrarian_Verify_err(t *testing.T) {
 func TestLibrarian_Store_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
-	kvdb, cleanup, err := db.NewTempDirRocksDB()
+	kvdb, cleanup, err := db.NewTempDirDB()
 	defer cleanup()
 	defer kvdb.Close()
 	assert.Nil(t, err)
 
 	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
 	serverSL := storage.NewServerSL(kvdb)
+	expiring := storage.NewExpiringDocumentSLD(storage.NewDocumentSLD(kvdb), kvdb)
 	l := &Librarian{
+		config:         NewDefaultConfig(),
 		peerID:         peerID,
+		signer:         client.NewECDSASigner(peerID.Key()),
 		rt:             rt,
 		db:             kvdb,
 		serverSL:       serverSL,
-		documentSL:     storage.NewDocumentSLD(kvdb),
+		documentSL:     expiring,
+		expiring:       expiring,
 		subscribeTo:    &fixedTo{},
 		kc:             storage.NewExactLengthChecker(storage.EntriesKeyLength),
 		kvc:            storage.NewHashKeyValueChecker(),
 		rqv:            &alwaysRequestVerifier{},
 		storageMetrics: newStorageMetrics(serverSL),
+		quotas:         newTestOrgQuotas(NewDefaultQuotaParameters()),
 		rec:            rec,
 		allower:        &fixedAllower{},
+		rqLimiter:      comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		recentStores:   newRecentStores(recentStoresSize, recentStoresTTL),
+		hints:          newHintStore(storage.NewHintSLD(kvdb), NewDefaultHandoffParameters()),
+		fromer:         peer.NewFromer(),
+		searcher:       &forgetfulSearcher{},
 		logger:         zap.NewNop(), // clogging.NewDevInfoLogger(),
 	}
 	defer l.storageMetrics.unregister()
@@ -639,8 +888,86 @@ func TestLibrarian_Store_ok(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, value, stored)
 	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+	assert.False(t, rp.AlreadyStored)
+	assert.Nil(t, client.CheckSignedStoreReceipt(client.NewVerifier(), rp.Receipt, key.Bytes(),
+		value))
+	assert.Equal(t, peerID.PublicKeyBytes(), rp.Receipt.Receipt.PeerPubKey)
+	assert.Equal(t, []id.ID{key}, l.searcher.(*forgetfulSearcher).forgotten)
 	qo := rec.Get(l.peerID.ID(), api.Store)
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Success].Count))
+
+	// retried request gets original receipt without reading the stored value
+	receipt := rp.Receipt
+	l.subscribeTo = &fixedTo{sendErr: errors.New("should not publish again")}
+	l.documentSL = &storage.TestDocSLD{MacErr: errors.New("should not read stored value")}
+	rq.Metadata = newTestRequestMetadata(rng, l.peerID)
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.True(t, rp.AlreadyStored)
+	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+	assert.Equal(t, receipt, rp.Receipt)
+
+	// retried request after it's no longer recent finds value already stored
+	l.documentSL = expiring
+	l.recentStores = newRecentStores(recentStoresSize, recentStoresTTL)
+	rq.Metadata = newTestRequestMetadata(rng, l.peerID)
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.True(t, rp.AlreadyStored)
+	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+	assert.Nil(t, client.CheckSignedStoreReceipt(client.NewVerifier(), rp.Receipt, key.Bytes(),
+		value))
+
+	// TTL'd request for already stored value makes it expire, and permanent request makes it
+	// permanent again
+	rq.Metadata = newTestRequestMetadata(rng, l.peerID)
+	rq.ExpireTime = time.Now().Add(time.Hour).Unix()
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.True(t, rp.AlreadyStored)
+	expires, in, err := expiring.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.True(t, in)
+	assert.Equal(t, rq.ExpireTime, expires.Unix())
+	rq.Metadata, rq.ExpireTime = newTestRequestMetadata(rng, l.peerID), 0
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.True(t, rp.AlreadyStored)
+	_, in, err = expiring.ExpireTime(key)
+	assert.Nil(t, err)
+	assert.False(t, in)
+
+	// already expired value is rejected
+	value, key = api.NewTestDocument(rng)
+	rq = &api.StoreRequest{
+		Metadata:   newTestRequestMetadata(rng, l.peerID),
+		Key:        key.Bytes(),
+		Value:      value,
+		ExpireTime: time.Now().Add(-time.Minute).Unix(),
+	}
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// value stored on behalf of an unreachable peer records a hint without publishing it, which
+	// l.subscribeTo would fail
+	holder := l.rt.Find(key, l.config.Store.NReplicas)[0]
+	rq.Metadata, rq.ExpireTime, rq.Hint = newTestRequestMetadata(rng, l.peerID), 0, holder.ToAPI()
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+	hs, err := l.hints.list(DefaultHandoffMaxHints)
+	assert.Nil(t, err)
+	assert.Len(t, hs, 1)
+	assert.Equal(t, key, hs[0].key)
+	assert.Equal(t, holder.ID(), hs[0].holder.ID())
+
+	// hint for self is rejected
+	rq.Metadata = newTestRequestMetadata(rng, l.peerID)
+	rq.Hint = peer.FromAddress(l.peerID.ID(), "self", holder.Address())
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
 }
 
 func newTestRequestMetadata(rng *rand.Rand, peerID ecid.ID) *api.RequestMetadata {
@@ -677,14 +1004,16 @@ func TestLibrarian_Store_notAllowedError(t *testing.T) {
 	orgID := ecid.NewPseudoRandom(rng)
 	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
 	l := &Librarian{
-		peerID:  peerID,
-		rt:      rt,
-		kc:      storage.NewExactLengthChecker(storage.EntriesKeyLength),
-		kvc:     storage.NewHashKeyValueChecker(),
-		rqv:     &alwaysRequestVerifier{},
-		rec:     rec,
-		allower: &fixedAllower{errNotAllowed},
-		logger:  zap.NewNop(), // clogging.NewDevInfoLogger(),
+		config:    NewDefaultConfig(),
+		peerID:    peerID,
+		rt:        rt,
+		kc:        storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:       storage.NewHashKeyValueChecker(),
+		rqv:       &alwaysRequestVerifier{},
+		rec:       rec,
+		allower:   &fixedAllower{errNotAllowed},
+		rqLimiter: comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		logger:    zap.NewNop(), // clogging.NewDevInfoLogger(),
 	}
 	value, key := api.NewTestDocument(rng)
 	rq := client.NewStoreRequest(peerID, orgID, key, value)
@@ -696,6 +1025,143 @@ func TestLibrarian_Store_notAllowedError(t *testing.T) {
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
 }
 
+func TestLibrarian_Store_storeAuthError(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
+	orgID, operatorID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	l := &Librarian{
+		config:    NewDefaultConfig(),
+		peerID:    peerID,
+		rt:        rt,
+		kc:        storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:       storage.NewHashKeyValueChecker(),
+		rqv:       &alwaysRequestVerifier{},
+		sav:       client.NewStoreAuthVerifier(&operatorID.Key().PublicKey),
+		rec:       rec,
+		allower:   &fixedAllower{},
+		rqLimiter: comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		logger:    zap.NewNop(), // clogging.NewDevInfoLogger(),
+	}
+	value, key := api.NewTestDocument(rng)
+	rq := client.NewStoreRequest(peerID, orgID, key, value)
+
+	// missing token
+	rp, err := l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// token issued to different org
+	token, err := client.NewStoreAuthToken(operatorID.Key(), operatorID.PublicKeyBytes(),
+		time.Time{})
+	assert.Nil(t, err)
+	ctx := client.NewIncomingStoreAuthContext(context.Background(), token)
+	rp, err = l.Store(ctx, rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	qo := rec.Get(peerID.ID(), api.Store)
+	assert.Equal(t, 2, int(qo[comm.Request][comm.Error].Count))
+}
+
+func TestLibrarian_checkStoreAuth(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	orgID, operatorID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	meta := &api.RequestMetadata{OrgPubKey: orgID.PublicKeyBytes()}
+
+	// no token required
+	l := &Librarian{}
+	assert.Nil(t, l.checkStoreAuth(context.Background(), meta))
+
+	// valid token
+	l.sav = client.NewStoreAuthVerifier(&operatorID.Key().PublicKey)
+	token, err := client.NewStoreAuthToken(operatorID.Key(), orgID.PublicKeyBytes(),
+		time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	ctx := client.NewIncomingStoreAuthContext(context.Background(), token)
+	assert.Nil(t, l.checkStoreAuth(ctx, meta))
+
+	// expired token
+	token, err = client.NewStoreAuthToken(operatorID.Key(), orgID.PublicKeyBytes(),
+		time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+	ctx = client.NewIncomingStoreAuthContext(context.Background(), token)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, l.checkStoreAuth(ctx, meta)))
+}
+
+func TestLibrarian_checkMacaroon(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	rootKey := []byte("some root key")
+	entry, entryKey := api.NewTestDocument(rng)
+	envelope := &api.Document{Contents: &api.Document_Envelope{
+		Envelope: api.NewTestEnvelope(rng),
+	}}
+	envelopeKey, err := api.GetKey(envelope)
+	assert.Nil(t, err)
+	_, systemKey := api.NewTestSystemDocument(rng)
+	getRq := client.NewGetRequest(peerID, orgID, entryKey)
+	putRq := client.NewPutRequest(peerID, orgID, entryKey, entry)
+	shareRq := client.NewPutRequest(peerID, orgID, envelopeKey, envelope)
+	systemRq := client.NewGetRequest(peerID, orgID, systemKey)
+	findRq := client.NewFindRequest(peerID, orgID, entryKey, 8)
+	systemFindRq := client.NewFindRequest(peerID, orgID, systemKey, 8)
+	storeRq := client.NewStoreRequest(peerID, orgID, entryKey, entry)
+	storeShareRq := client.NewStoreRequest(peerID, orgID, envelopeKey, envelope)
+	orgSignedCtx := client.NewIncomingSignatureContext(context.Background(), "token",
+		"org token")
+
+	// no macaroon required
+	l := &Librarian{}
+	assert.Nil(t, l.checkGetMacaroon(context.Background(), getRq))
+	assert.Nil(t, l.checkPutMacaroon(context.Background(), putRq))
+	assert.Nil(t, l.checkFindMacaroon(context.Background(), findRq))
+	assert.Nil(t, l.checkStoreMacaroon(context.Background(), storeRq))
+
+	// missing macaroon
+	l.mv = client.NewMacaroonVerifier(rootKey)
+	err = l.checkGetMacaroon(orgSignedCtx, getRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	err = l.checkFindMacaroon(orgSignedCtx, findRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	err = l.checkStoreMacaroon(orgSignedCtx, storeRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// system documents remain public
+	assert.Nil(t, l.checkGetMacaroon(context.Background(), systemRq))
+	assert.Nil(t, l.checkFindMacaroon(context.Background(), systemFindRq))
+
+	// macaroon allowing gets and puts but not shares
+	encMacaroon, err := client.NewMacaroonToken(rootKey, orgID.PublicKeyBytes(),
+		[]string{client.MacaroonOpGet, client.MacaroonOpPut}, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	ctx := client.NewIncomingMacaroonContext(orgSignedCtx, encMacaroon)
+	assert.Nil(t, l.checkGetMacaroon(ctx, getRq))
+	assert.Nil(t, l.checkPutMacaroon(ctx, putRq))
+	assert.Nil(t, l.checkFindMacaroon(ctx, findRq))
+	assert.Nil(t, l.checkStoreMacaroon(ctx, storeRq))
+	err = l.checkPutMacaroon(ctx, shareRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	err = l.checkStoreMacaroon(ctx, storeShareRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// org caveat unsatisfied when the request isn't signed by the org
+	unsignedCtx := client.NewIncomingSignatureContext(context.Background(), "token", "")
+	ctx = client.NewIncomingMacaroonContext(unsignedCtx, encMacaroon)
+	err = l.checkPutMacaroon(ctx, putRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	err = l.checkStoreMacaroon(ctx, storeRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// macaroon issued to different org
+	encMacaroon, err = client.NewMacaroonToken(rootKey, peerID.PublicKeyBytes(),
+		[]string{client.MacaroonOpShare}, time.Time{})
+	assert.Nil(t, err)
+	ctx = client.NewIncomingMacaroonContext(orgSignedCtx, encMacaroon)
+	err = l.checkPutMacaroon(ctx, shareRq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+}
+
 func TestLibrarian_Store_storeError(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
@@ -704,15 +1170,20 @@ func TestLibrarian_Store_storeError(t *testing.T) {
 	sld.StoreErr = errors.New("some Store error")
 	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
 	l := &Librarian{
-		peerID:     peerID,
-		rt:         rt,
-		kc:         storage.NewExactLengthChecker(storage.EntriesKeyLength),
-		kvc:        storage.NewHashKeyValueChecker(),
-		rqv:        &alwaysRequestVerifier{},
-		documentSL: sld,
-		rec:        rec,
-		allower:    &fixedAllower{},
-		logger:     zap.NewNop(), // clogging.NewDevInfoLogger(),
+		config:       NewDefaultConfig(),
+		peerID:       peerID,
+		signer:       client.NewECDSASigner(peerID.Key()),
+		rt:           rt,
+		kc:           storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:          storage.NewHashKeyValueChecker(),
+		rqv:          &alwaysRequestVerifier{},
+		documentSL:   sld,
+		quotas:       newTestOrgQuotas(NewDefaultQuotaParameters()),
+		rec:          rec,
+		allower:      &fixedAllower{},
+		rqLimiter:    comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		recentStores: newRecentStores(recentStoresSize, recentStoresTTL),
+		logger:       zap.NewNop(), // clogging.NewDevInfoLogger(),
 	}
 	value, key := api.NewTestDocument(rng)
 	rq := client.NewStoreRequest(peerID, orgID, key, value)
@@ -722,6 +1193,80 @@ func TestLibrarian_Store_storeError(t *testing.T) {
 	assert.Equal(t, codes.Internal, getErrCode(t, err))
 	qo := rec.Get(peerID.ID(), api.Store)
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Success].Count))
+
+	// reserved quota is released
+	assert.Zero(t, l.quotas.List()[0].usedBytes)
+
+	// failed Store forgets any recent Store of the document
+	otherMAC := []byte{1, 2, 3}
+	l.recentStores.add(key, otherMAC, &api.StoreResponse{})
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.NotNil(t, err)
+	_, in := l.recentStores.get(key, otherMAC)
+	assert.False(t, in)
+
+	// read-only DB after disk error tells client to try another peer
+	sld.StoreErr = db.ErrReadOnly
+	rp, err = l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.Unavailable, getErrCode(t, err))
+}
+
+func TestLibrarian_Store_macError(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
+	orgID := ecid.NewPseudoRandom(rng)
+	sld := storage.NewTestDocSLD()
+	sld.MacErr = errors.New("some Mac error")
+	l := &Librarian{
+		config:       NewDefaultConfig(),
+		peerID:       peerID,
+		rt:           rt,
+		kc:           storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:          storage.NewHashKeyValueChecker(),
+		rqv:          &alwaysRequestVerifier{},
+		documentSL:   sld,
+		rec:          comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
+		allower:      &fixedAllower{},
+		rqLimiter:    comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		recentStores: newRecentStores(recentStoresSize, recentStoresTTL),
+		logger:       zap.NewNop(), // clogging.NewDevInfoLogger(),
+	}
+	value, key := api.NewTestDocument(rng)
+	rq := client.NewStoreRequest(peerID, orgID, key, value)
+
+	rp, err := l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.Internal, getErrCode(t, err))
+}
+
+func TestLibrarian_Store_signError(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 64)
+	orgID := ecid.NewPseudoRandom(rng)
+	sld := storage.NewTestDocSLD()
+	l := &Librarian{
+		config:       NewDefaultConfig(),
+		peerID:       peerID,
+		signer:       &client.TestErrSigner{},
+		rt:           rt,
+		kc:           storage.NewExactLengthChecker(storage.EntriesKeyLength),
+		kvc:          storage.NewHashKeyValueChecker(),
+		rqv:          &alwaysRequestVerifier{},
+		documentSL:   sld,
+		rec:          comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
+		allower:      &fixedAllower{},
+		rqLimiter:    comm.NewRequesterLimiter(comm.NewDefaultRequesterLimitParameters()),
+		recentStores: newRecentStores(recentStoresSize, recentStoresTTL),
+		logger:       zap.NewNop(), // clogging.NewDevInfoLogger(),
+	}
+	value, key := api.NewTestDocument(rng)
+	rq := client.NewStoreRequest(peerID, orgID, key, value)
+
+	rp, err := l.Store(context.Background(), rq)
+	assert.Nil(t, rp)
+	assert.Equal(t, codes.Internal, getErrCode(t, err))
 }
 
 type fixedSearcher struct {
@@ -729,7 +1274,9 @@ type fixedSearcher struct {
 	err    error
 }
 
-func (s *fixedSearcher) Search(search *search.Search, seeds []peer.Peer) error {
+func (s *fixedSearcher) Search(
+	ctx context.Context, search *search.Search, seeds []peer.Peer,
+) error {
 	if s.err != nil {
 		return s.err
 	}
@@ -737,6 +1284,16 @@ func (s *fixedSearcher) Search(search *search.Search, seeds []peer.Peer) error {
 	return nil
 }
 
+// forgetfulSearcher is a CachingSearcher recording the keys it's told to forget.
+type forgetfulSearcher struct {
+	search.CachingSearcher
+	forgotten []id.ID
+}
+
+func (f *forgetfulSearcher) Forget(key id.ID) {
+	f.forgotten = append(f.forgotten, key)
+}
+
 func TestLibrarian_Get_FoundValue(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	value, key := api.NewTestDocument(rng)
@@ -865,6 +1422,26 @@ func TestLibrarian_Get_checkRequestError(t *testing.T) {
 	assert.Equal(t, 0, int(qo[comm.Request][comm.Success].Count))
 }
 
+func TestLibrarian_Get_canceledErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	key, peerID := id.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+
+	cases := map[error]codes.Code{
+		search.ErrSearchCanceled: codes.Canceled,
+		context.Canceled:         codes.Canceled,
+		context.DeadlineExceeded: codes.DeadlineExceeded,
+	}
+	for searchErr, code := range cases {
+		l := newGetLibrarian(rng, nil, searchErr)
+		rq := client.NewGetRequest(peerID, orgID, key)
+
+		rp, err := l.Get(context.Background(), rq)
+		assert.Equal(t, code, getErrCode(t, err))
+		assert.Nil(t, rp)
+	}
+}
+
 func TestLibrarian_Get_notAllowedErr(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	key, peerID := id.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
@@ -906,9 +1483,11 @@ func newGetLibrarian(rng *rand.Rand, searchResult *search.Result, searchErr erro
 type fixedStorer struct {
 	result *store.Result
 	err    error
+	store  *store.Store
 }
 
-func (s *fixedStorer) Store(store *store.Store, seeds []peer.Peer) error {
+func (s *fixedStorer) Store(ctx context.Context, store *store.Store, seeds []peer.Peer) error {
+	s.store = store
 	if s.err != nil {
 		return s.err
 	}
@@ -927,9 +1506,12 @@ func TestLibrarian_Put_Stored(t *testing.T) {
 	nReplicas := searchParams.NClosestResponses
 	addedResult := store.NewInitialResult(search.NewInitialResult(key, searchParams))
 	addedResult.Responded = peer.NewTestPeers(rng, int(nReplicas))
+	addedResult.Spilled = addedResult.Responded[:1]
 
 	// create librarian and request
 	l := newPutLibrarian(rng, addedResult, nil)
+	cs := &forgetfulSearcher{}
+	l.searcher = cs
 	rq := client.NewPutRequest(peerID, orgID, key, value)
 
 	// since fixedSearcher returns fixed value, should get that back in response
@@ -940,6 +1522,81 @@ func TestLibrarian_Put_Stored(t *testing.T) {
 	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
 	qo := l.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Put)
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Success].Count))
+
+	// stored key no longer cached as not found
+	assert.Equal(t, []id.ID{key}, cs.forgotten)
+}
+
+func TestSpilledFields(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	spilled := peer.NewTestPeers(rng, 2)
+	fs := spilledFields(&store.Store{Result: &store.Result{Spilled: spilled}})
+	assert.Len(t, fs, 1)
+	assert.Equal(t, logSpilledPeerIDs, fs[0].Key)
+	enc := zapcore.NewMapObjectEncoder()
+	fs[0].AddTo(enc)
+	assert.Equal(t, []interface{}{spilled[0].ID().String(), spilled[1].ID().String()},
+		enc.Fields[logSpilledPeerIDs])
+}
+
+func TestLibrarian_putStoreParams(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	envelope := &api.Document{
+		Contents: &api.Document_Envelope{Envelope: api.NewTestEnvelope(rng)},
+	}
+	envelopeKey, err := api.GetKey(envelope)
+	assert.Nil(t, err)
+	page := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+	pageKey, err := api.GetKey(page)
+	assert.Nil(t, err)
+	l := &Librarian{config: NewDefaultConfig()}
+	envelopeRq := client.NewPutRequest(peerID, orgID, envelopeKey, envelope)
+	pageRq := client.NewPutRequest(peerID, orgID, pageKey, page)
+
+	// no policy uses default
+	assert.Equal(t, l.config.Store, l.putStoreParams(envelopeRq))
+
+	// policy overrides default for given types
+	l.config.WithStorePolicy(store.NewReplicationPolicy(api.TypeReplicas{api.EnvelopeType: 5}))
+	assert.Equal(t, uint(5), l.putStoreParams(envelopeRq).NReplicas)
+	assert.Equal(t, l.config.Store, l.putStoreParams(pageRq))
+
+	// request's number of replicas overrides policy, up to max
+	envelopeRq.NReplicas = 7
+	assert.Equal(t, uint(7), l.putStoreParams(envelopeRq).NReplicas)
+	envelopeRq.NReplicas = 1024
+	assert.Equal(t, MaxPutNReplicas, l.putStoreParams(envelopeRq).NReplicas)
+	assert.Equal(t, store.DefaultNReplicas, l.config.Store.NReplicas)
+}
+
+func TestLibrarian_Put_AlreadyStored(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+
+	searchParams := search.NewDefaultParameters()
+	nReplicas := searchParams.NClosestResponses
+	addedResult := store.NewInitialResult(search.NewInitialResult(key, searchParams))
+	addedResult.Responded = peer.NewTestPeers(rng, int(nReplicas))
+	l := newPutLibrarian(rng, addedResult, nil)
+
+	rp, err := l.Put(context.Background(), client.NewPutRequest(peerID, orgID, key, value))
+	assert.Nil(t, err)
+	assert.Equal(t, api.PutOperation_STORED, rp.Operation)
+
+	// retried Put returns quickly without storing again
+	l.storer = &fixedStorer{err: errors.New("should not store again")}
+	rq := client.NewPutRequest(peerID, orgID, key, value)
+	rp, err = l.Put(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, api.PutOperation_ALREADY_STORED, rp.Operation)
+	assert.Equal(t, uint32(nReplicas), rp.NReplicas)
+	assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+	qo := l.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Put)
+	assert.Equal(t, 2, int(qo[comm.Request][comm.Success].Count))
+	assert.Zero(t, int(qo[comm.Request][comm.Error].Count))
 }
 
 func TestLibrarian_Put_Exists(t *testing.T) {
@@ -1028,6 +1685,27 @@ func TestLibrarian_Put_checkRequestError(t *testing.T) {
 	assert.Equal(t, 0, int(qo[comm.Request][comm.Success].Count))
 }
 
+func TestLibrarian_Put_canceledErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+
+	cases := map[error]codes.Code{
+		store.ErrStoreCanceled:   codes.Canceled,
+		search.ErrSearchCanceled: codes.Canceled,
+		context.DeadlineExceeded: codes.DeadlineExceeded,
+	}
+	for cancelErr, code := range cases {
+		l := newPutLibrarian(rng, nil, cancelErr)
+		rq := client.NewPutRequest(peerID, orgID, key, value)
+
+		rp, err := l.Put(context.Background(), rq)
+		assert.Equal(t, code, getErrCode(t, err))
+		assert.Nil(t, rp)
+	}
+}
+
 func TestLibrarian_Put_notAllowedErr(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	value, key := api.NewTestDocument(rng)
@@ -1047,6 +1725,24 @@ func TestLibrarian_Put_notAllowedErr(t *testing.T) {
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count)) // request was ok
 }
 
+func TestLibrarian_Put_storeAuthErr(t *testing.T) {
+	rng := rand.New(rand.NewSource(int64(0)))
+	value, key := api.NewTestDocument(rng)
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID, operatorID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+
+	// create librarian and request
+	l := newPutLibrarian(rng, nil, nil)
+	l.sav = client.NewStoreAuthVerifier(&operatorID.Key().PublicKey)
+	rq := client.NewPutRequest(peerID, orgID, key, value)
+
+	rp, err := l.Put(context.Background(), rq)
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	assert.Nil(t, rp)
+	qo := l.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Put)
+	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
+}
+
 func TestLibrarian_Subscribe_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	nPubs := 64
@@ -1126,6 +1822,73 @@ func TestLibrarian_Subscribe_ok(t *testing.T) {
 	wg.Wait()
 }
 
+func TestLibrarian_RoutingTable_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, nAdded, _ := routing.NewTestWithPeers(rng, 64)
+	adminID := ecid.NewPseudoRandom(rng)
+	rec := comm.NewQueryRecorderGetter(comm.NewAlwaysKnower())
+	l := &Librarian{
+		peerID: peerID,
+		config: NewDefaultConfig().WithAdminPubKey(&adminID.Key().PublicKey),
+		rqv:    &alwaysRequestVerifier{},
+		rt:     rt,
+		rec:    rec,
+		qg:     rec,
+		logger: zap.NewNop(),
+	}
+
+	rq := client.NewRoutingTableRequest(adminID, nil)
+	to := &fixedLibrarianRoutingTableServer{}
+	err := l.RoutingTable(rq, to)
+	assert.Nil(t, err)
+	assert.Equal(t, rt.NumBuckets(), len(to.sent))
+	nPeers := 0
+	for _, rp := range to.sent {
+		assert.Equal(t, rq.Metadata.RequestId, rp.Metadata.RequestId)
+		nPeers += len(rp.Bucket.Peers)
+	}
+	assert.Equal(t, nAdded, nPeers)
+}
+
+func TestLibrarian_RoutingTable_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rt, peerID, _, _ := routing.NewTestWithPeers(rng, 8)
+	adminID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	newLibrarian := func(config *Config) *Librarian {
+		return &Librarian{
+			peerID: peerID,
+			config: config,
+			rt:     rt,
+			logger: zap.NewNop(),
+		}
+	}
+	adminConfig := NewDefaultConfig().WithAdminPubKey(&adminID.Key().PublicKey)
+
+	// check request error bubbles up
+	l := newLibrarian(adminConfig)
+	rq := client.NewRoutingTableRequest(adminID, nil)
+	rq.Metadata.PubKey = []byte("bad pub key")
+	err := l.RoutingTable(rq, &fixedLibrarianRoutingTableServer{})
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check admin requests disabled by default
+	l = newLibrarian(NewDefaultConfig())
+	err = l.RoutingTable(client.NewRoutingTableRequest(adminID, nil),
+		&fixedLibrarianRoutingTableServer{})
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// check non-admin requester denied
+	l = newLibrarian(adminConfig)
+	err = l.RoutingTable(client.NewRoutingTableRequest(otherID, nil),
+		&fixedLibrarianRoutingTableServer{})
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// check send error bubbles up
+	err = l.RoutingTable(client.NewRoutingTableRequest(adminID, nil),
+		&fixedLibrarianRoutingTableServer{err: errors.New("some send error")})
+	assert.Equal(t, codes.Unavailable, getErrCode(t, err))
+}
+
 func TestLibrarian_Subscribe_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	sub, err := subscribe.NewFPSubscription(1.0, rng) // get everything
@@ -1140,15 +1903,13 @@ func TestLibrarian_Subscribe_err(t *testing.T) {
 
 	// check request error bubbles up
 	l1 := &Librarian{
-		rqv:    &neverRequestVerifier{},
 		rt:     rt,
 		rec:    comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
 		logger: zap.NewNop(), // clogging.NewDevInfoLogger(),
 	}
+	rq.Metadata.PubKey = []byte("bad pub key")
 	err = l1.Subscribe(rq, from)
 	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
-	qo := l1.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Subscribe)
-	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
 
 	sub6, err := subscribe.NewFPSubscription(1.0, rng)
 	assert.Nil(t, err)
@@ -1162,7 +1923,7 @@ func TestLibrarian_Subscribe_err(t *testing.T) {
 	}
 	err = l6.Subscribe(rq6, from)
 	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
-	qo = l6.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Subscribe)
+	qo := l6.rec.(comm.QueryRecorderGetter).Get(peerID.ID(), api.Subscribe)
 	assert.Equal(t, 1, int(qo[comm.Request][comm.Error].Count))
 
 	// check author filter error bubbles up
@@ -1330,6 +2091,42 @@ func (f *fixedLibrarianSubscribeServer) RecvMsg(m interface{}) error {
 	return nil
 }
 
+type fixedLibrarianRoutingTableServer struct {
+	sent []*api.RoutingTableResponse
+	err  error
+}
+
+func (f *fixedLibrarianRoutingTableServer) Send(rp *api.RoutingTableResponse) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, rp)
+	return nil
+}
+
+// the stubs below are just to satisfy the Librarian_RoutingTableServer interface
+func (f *fixedLibrarianRoutingTableServer) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (f *fixedLibrarianRoutingTableServer) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (f *fixedLibrarianRoutingTableServer) SetTrailer(metadata.MD) {}
+
+func (f *fixedLibrarianRoutingTableServer) Context() context.Context {
+	return nil
+}
+
+func (f *fixedLibrarianRoutingTableServer) SendMsg(m interface{}) error {
+	return nil
+}
+
+func (f *fixedLibrarianRoutingTableServer) RecvMsg(m interface{}) error {
+	return nil
+}
+
 type fixedAllower struct {
 	allow error
 }
@@ -1351,9 +2148,27 @@ func newPutLibrarian(rng *rand.Rand, storeResult *store.Result, searchErr error)
 			result: storeResult,
 			err:    searchErr,
 		},
-		rqv:     &alwaysRequestVerifier{},
-		rec:     comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
-		allower: &fixedAllower{},
-		logger:  clogging.NewDevInfoLogger(),
+		rqv:        &alwaysRequestVerifier{},
+		rec:        comm.NewQueryRecorderGetter(comm.NewAlwaysKnower()),
+		allower:    &fixedAllower{},
+		quotas:     newTestOrgQuotas(NewDefaultQuotaParameters()),
+		recentPuts: newRecentPuts(recentPutsSize),
+		logger:     clogging.NewDevInfoLogger(),
 	}
 }
+
+func TestGetSearchParams(t *testing.T) {
+	c := NewDefaultConfig()
+	rq := &api.GetRequest{ReadRepair: true}
+
+	// Gets use librarian's params unless they differ for them
+	assert.Equal(t, c.Search, getSearchParams(c, &api.GetRequest{}))
+
+	// latency weighting only applies to Gets, not the librarian's other searches
+	c.GetLatencyWeighted = true
+	getParams := getSearchParams(c, rq)
+	assert.True(t, getParams.LatencyWeighted)
+	assert.True(t, getParams.ReadRepair)
+	assert.False(t, c.Search.LatencyWeighted)
+	assert.False(t, c.Search.ReadRepair)
+}
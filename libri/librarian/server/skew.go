@@ -0,0 +1,22 @@
+package server
+
+import (
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maybeWarnClockSkew warns if self's clock appears skewed relative to the network, since
+// response recency and token freshness depend on reasonably synchronized clocks.
+func (l *Librarian) maybeWarnClockSkew() {
+	selfSkew, ok := l.skewRec.SelfSkew()
+	if !ok {
+		return
+	}
+	fields := []zapcore.Field{zap.Duration(logSelfClockSkew, selfSkew)}
+	if selfSkew > comm.DefaultMaxSelfClockSkew || -selfSkew > comm.DefaultMaxSelfClockSkew {
+		l.logger.Warn("self clock appears skewed relative to network", fields...)
+		return
+	}
+	l.logger.Debug("self clock in sync with network", fields...)
+}
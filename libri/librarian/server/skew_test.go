@@ -0,0 +1,30 @@
+package server
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLibrarian_maybeWarnClockSkew(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, peerSkew := range []time.Duration{0, time.Hour, -time.Hour} {
+		l := &Librarian{skewRec: comm.NewSkewRecorder(), logger: zap.NewNop()}
+
+		// no estimate yet
+		l.maybeWarnClockSkew()
+
+		for _, p := range peer.NewTestPeers(rng, 8) {
+			l.skewRec.Record(p.ID(), peerSkew)
+		}
+		selfSkew, ok := l.skewRec.SelfSkew()
+		assert.True(t, ok)
+		assert.Equal(t, -peerSkew, selfSkew)
+		l.maybeWarnClockSkew()
+	}
+}
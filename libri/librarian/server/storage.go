@@ -2,7 +2,10 @@ package server
 
 import (
 	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/routing"
 	"github.com/golang/protobuf/proto"
 	"go.uber.org/zap"
 )
@@ -23,7 +26,9 @@ var (
 	peerIDKey = []byte("PeerID")
 )
 
-func loadOrCreatePeerID(logger *zap.Logger, nsl storage.StorerLoader) (ecid.ID, error) {
+func loadOrCreatePeerID(
+	logger *zap.Logger, nsl storage.StorerLoader, puzzleDifficulty uint,
+) (ecid.ID, error) {
 	bytes, err := nsl.Load(peerIDKey)
 	if err != nil {
 		logger.Error("error loading peer ID", zap.Error(err))
@@ -42,15 +47,46 @@ func loadOrCreatePeerID(logger *zap.Logger, nsl storage.StorerLoader) (ecid.ID,
 			return nil, err
 		}
 		logger.Info("loaded exsting peer ID", zap.String(LoggerPeerID, peerID.String()))
+		if !ecid.SolvesPuzzle(peerID.ID(), puzzleDifficulty) {
+			// peers requiring the same puzzle difficulty will refuse introductions from self
+			logger.Warn("existing peer ID does not solve puzzle",
+				zap.String(LoggerPeerID, peerID.String()),
+				zap.Uint(logPuzzleBits, puzzleDifficulty),
+			)
+		}
 		return peerID, nil
 	}
 
-	// return new PeerID
-	peerID := ecid.NewRandom()
+	// return new PeerID, solving the puzzle peers require
+	peerID := ecid.NewRandomSolving(puzzleDifficulty)
 	logger.Info("created new peer ID", zap.String(LoggerPeerID, peerID.String()))
 	return peerID, savePeerID(nsl, peerID)
 }
 
+func loadOrCreateRoutingTable(
+	logger *zap.Logger,
+	snapshots storage.StorerLoader,
+	deltas storage.StorerLoaderDeleter,
+	selfID id.ID,
+	preferer comm.Preferer,
+	doctor comm.Doctor,
+	params *routing.Parameters,
+) (routing.Table, error) {
+	rt, err := routing.LoadCheckpoint(snapshots, deltas, preferer, doctor, params)
+	if err != nil {
+		logger.Error("error loading routing table", zap.Error(err))
+		return nil, err
+	}
+	if rt != nil && rt.SelfID().Cmp(selfID) == 0 {
+		logger.Info("loaded existing routing table",
+			zap.Int(NumPeers, rt.NumPeers()),
+			zap.Int(NumBuckets, rt.NumBuckets()),
+		)
+		return rt, nil
+	}
+	return routing.NewEmpty(selfID, preferer, doctor, params), nil
+}
+
 func savePeerID(ns storage.Storer, peerID ecid.ID) error {
 	bytes, err := proto.Marshal(ecid.ToStored(peerID))
 	if err != nil {
@@ -5,9 +5,12 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/drausin/libri/libri/common/db"
 	"github.com/drausin/libri/libri/common/ecid"
 	clogging "github.com/drausin/libri/libri/common/logging"
 	cstorage "github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/routing"
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,7 +18,7 @@ import (
 func TestLoadOrCreatePeerID_ok(t *testing.T) {
 
 	// create new peer ID
-	id1, err := loadOrCreatePeerID(clogging.NewDevInfoLogger(), &cstorage.TestSLD{})
+	id1, err := loadOrCreatePeerID(clogging.NewDevInfoLogger(), &cstorage.TestSLD{}, 0)
 	assert.NotNil(t, id1)
 	assert.Nil(t, err)
 
@@ -25,22 +28,43 @@ func TestLoadOrCreatePeerID_ok(t *testing.T) {
 	bytes, err := proto.Marshal(ecid.ToStored(peerID2))
 	assert.Nil(t, err)
 
-	id2, err := loadOrCreatePeerID(clogging.NewDevInfoLogger(), &cstorage.TestSLD{Bytes: bytes})
+	id2, err := loadOrCreatePeerID(clogging.NewDevInfoLogger(), &cstorage.TestSLD{Bytes: bytes}, 0)
 
 	assert.Equal(t, peerID2, id2)
 	assert.Nil(t, err)
 }
 
+func TestLoadOrCreatePeerID_puzzle(t *testing.T) {
+	lg := clogging.NewDevInfoLogger()
+
+	// new peer ID solves puzzle
+	id1, err := loadOrCreatePeerID(lg, &cstorage.TestSLD{}, 4)
+	assert.Nil(t, err)
+	assert.True(t, ecid.SolvesPuzzle(id1.ID(), 4))
+
+	// existing peer ID loaded even if it doesn't
+	rng := rand.New(rand.NewSource(0))
+	peerID2 := ecid.NewPseudoRandom(rng)
+	for ecid.SolvesPuzzle(peerID2.ID(), 4) {
+		peerID2 = ecid.NewPseudoRandom(rng)
+	}
+	bytes, err := proto.Marshal(ecid.ToStored(peerID2))
+	assert.Nil(t, err)
+	id2, err := loadOrCreatePeerID(lg, &cstorage.TestSLD{Bytes: bytes}, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, peerID2, id2)
+}
+
 func TestLoadOrCreatePeerID_err(t *testing.T) {
 	id1, err := loadOrCreatePeerID(clogging.NewDevInfoLogger(), &cstorage.TestSLD{
 		LoadErr: errors.New("some load error"),
-	})
+	}, 0)
 	assert.Nil(t, id1)
 	assert.NotNil(t, err)
 
 	id2, err := loadOrCreatePeerID(clogging.NewDevInfoLogger(), &cstorage.TestSLD{
 		Bytes: []byte("the wrong bytes"),
-	})
+	}, 0)
 	assert.Nil(t, id2)
 	assert.NotNil(t, err)
 }
@@ -49,3 +73,33 @@ func TestSavePeerID(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	assert.Nil(t, savePeerID(&cstorage.TestSLD{}, ecid.NewPseudoRandom(rng)))
 }
+
+func TestLoadOrCreateRoutingTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	kvdb := db.NewMemoryDB()
+	snapshots, deltas := cstorage.NewServerSL(kvdb), cstorage.NewRoutingSLD(kvdb)
+	p, d := &fixedPreferer{}, &fixedDoctor{}
+	params := routing.NewDefaultParameters()
+	lg := clogging.NewDevInfoLogger()
+
+	// create new routing table
+	selfID := ecid.NewPseudoRandom(rng).ID()
+	rt1, err := loadOrCreateRoutingTable(lg, snapshots, deltas, selfID, p, d, params)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, rt1.NumPeers())
+
+	rt1.Push(peer.NewTestPeer(rng, 0))
+	assert.Nil(t, routing.NewCheckpointer(rt1, snapshots, deltas, params).Checkpoint())
+
+	// load existing routing table
+	rt2, err := loadOrCreateRoutingTable(lg, snapshots, deltas, selfID, p, d, params)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rt2.NumPeers())
+
+	// create new routing table when stored table is for a different self ID
+	otherID := ecid.NewPseudoRandom(rng).ID()
+	rt3, err := loadOrCreateRoutingTable(lg, snapshots, deltas, otherID, p, d, params)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, rt3.NumPeers())
+	assert.Equal(t, otherID, rt3.SelfID())
+}
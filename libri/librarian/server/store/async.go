@@ -0,0 +1,141 @@
+package store
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrUnknownOperation indicates when an async store operation ID is unknown, either because
+	// it was never started or because it has been forgotten.
+	ErrUnknownOperation = errors.New("unknown async store operation")
+
+	// ErrNotStored indicates when a store finished without storing sufficient replicas.
+	ErrNotStored = errors.New("store finished without storing sufficient replicas")
+)
+
+// OperationID identifies an async store operation.
+type OperationID uint64
+
+// Operation is a handle to a store executing in the background.
+type Operation struct {
+	// ID of the operation
+	ID OperationID
+
+	// Store being executed, whose result is only safe to read once the operation is done
+	Store *Store
+
+	done chan struct{}
+	err  error
+	mu   sync.Mutex
+}
+
+// Done returns a channel that is closed once the store finishes, for subscribing to its
+// completion.
+func (o *Operation) Done() <-chan struct{} {
+	return o.done
+}
+
+// Finished returns whether the store has finished, for polling its completion.
+func (o *Operation) Finished() bool {
+	select {
+	case <-o.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the error the store finished with, which is nil if it is still running or it
+// stored sufficient replicas.
+func (o *Operation) Err() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+func (o *Operation) finish(err error) {
+	o.mu.Lock()
+	o.err = err
+	o.mu.Unlock()
+	close(o.done)
+}
+
+// AsyncStorer executes store operations in the background.
+type AsyncStorer interface {
+	// StoreAsync starts a store operation, starting with a given set of seed peers, and
+	// immediately returns its ID. The operation is abandoned if the context is done.
+	StoreAsync(ctx context.Context, store *Store, seeds []peer.Peer) OperationID
+
+	// Operation returns the operation with the given ID, for polling or subscribing to its
+	// completion.
+	Operation(id OperationID) (*Operation, error)
+
+	// Forget drops the operation with the given ID, releasing its store once it is done.
+	Forget(id OperationID)
+}
+
+type asyncStorer struct {
+	inner Storer
+	next  OperationID
+	ops   map[OperationID]*Operation
+	mu    sync.Mutex
+}
+
+// NewAsyncStorer returns a new AsyncStorer executing each store in the background with the given
+// Storer. Operations are kept until they are forgotten.
+func NewAsyncStorer(inner Storer) AsyncStorer {
+	return &asyncStorer{
+		inner: inner,
+		ops:   make(map[OperationID]*Operation),
+	}
+}
+
+func (a *asyncStorer) StoreAsync(ctx context.Context, store *Store, seeds []peer.Peer) OperationID {
+	a.mu.Lock()
+	a.next++
+	op := &Operation{
+		ID:    a.next,
+		Store: store,
+		done:  make(chan struct{}),
+	}
+	a.ops[op.ID] = op
+	a.mu.Unlock()
+
+	go func() {
+		err := a.inner.Store(ctx, store, seeds)
+		if err == nil && !store.Stored() {
+			err = ErrNotStored
+		}
+		op.finish(err)
+	}()
+	return op.ID
+}
+
+func (a *asyncStorer) Operation(id OperationID) (*Operation, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	op, in := a.ops[id]
+	if !in {
+		return nil, ErrUnknownOperation
+	}
+	return op, nil
+}
+
+func (a *asyncStorer) Forget(id OperationID) {
+	a.mu.Lock()
+	op, in := a.ops[id]
+	delete(a.ops, id)
+	a.mu.Unlock()
+	if !in {
+		return
+	}
+	go func() {
+		// don't release the store from under the operation still executing it
+		<-op.done
+		op.Store.Release()
+	}()
+}
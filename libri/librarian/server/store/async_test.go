@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	ssearch "github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestAsyncStorer_StoreAsync_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	inner := &blockingStorer{
+		responded: peer.NewTestPeers(rng, int(DefaultNReplicas)),
+		proceed:   make(chan struct{}),
+	}
+	as := NewAsyncStorer(inner)
+	s := newTestAsyncStore(rng)
+
+	opID := as.StoreAsync(context.Background(), s, []peer.Peer{})
+	op, err := as.Operation(opID)
+	assert.Nil(t, err)
+	assert.Equal(t, opID, op.ID)
+	assert.Equal(t, s, op.Store)
+
+	// still running until inner store proceeds
+	assert.False(t, op.Finished())
+	assert.Nil(t, op.Err())
+
+	close(inner.proceed)
+	<-op.Done()
+	assert.True(t, op.Finished())
+	assert.Nil(t, op.Err())
+	assert.True(t, op.Store.Stored())
+
+	// forgotten operations are unknown and their stores released
+	as.Forget(opID)
+	op, err = as.Operation(opID)
+	assert.Equal(t, ErrUnknownOperation, err)
+	assert.Nil(t, op)
+}
+
+func TestAsyncStorer_StoreAsync_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	// inner store error
+	as := NewAsyncStorer(errStorer{})
+	op, err := as.Operation(as.StoreAsync(context.Background(), newTestAsyncStore(rng), nil))
+	assert.Nil(t, err)
+	<-op.Done()
+	assert.NotNil(t, op.Err())
+
+	// insufficient replicas stored
+	proceed := make(chan struct{})
+	close(proceed)
+	as = NewAsyncStorer(&blockingStorer{proceed: proceed})
+	op, err = as.Operation(as.StoreAsync(context.Background(), newTestAsyncStore(rng), nil))
+	assert.Nil(t, err)
+	<-op.Done()
+	assert.Equal(t, ErrNotStored, op.Err())
+}
+
+func TestAsyncStorer_StoreAsync_ids(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	proceed := make(chan struct{})
+	close(proceed)
+	as := NewAsyncStorer(&blockingStorer{proceed: proceed})
+
+	opID1 := as.StoreAsync(context.Background(), newTestAsyncStore(rng), nil)
+	opID2 := as.StoreAsync(context.Background(), newTestAsyncStore(rng), nil)
+	assert.NotEqual(t, opID1, opID2)
+
+	// forgetting unknown operation is a no-op
+	as.Forget(OperationID(0))
+	_, err := as.Operation(opID1)
+	assert.Nil(t, err)
+}
+
+func newTestAsyncStore(rng *rand.Rand) *Store {
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	return NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(),
+		NewDefaultParameters())
+}
+
+type blockingStorer struct {
+	responded []peer.Peer
+	proceed   chan struct{}
+}
+
+func (b *blockingStorer) Store(ctx context.Context, store *Store, seeds []peer.Peer) error {
+	<-b.proceed
+	store.Result = NewInitialResult(ssearch.NewInitialResult(store.Search.Key,
+		store.Search.Params))
+	store.Result.Responded = b.responded
+	return nil
+}
+
+type errStorer struct{}
+
+func (errStorer) Store(ctx context.Context, store *Store, seeds []peer.Peer) error {
+	return errors.New("some Store error")
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"golang.org/x/net/context"
+)
+
+// BatchStore contains things involved in storing many key/value pairs in one operation.
+type BatchStore struct {
+	// Stores are the stores of each key/value pair, in the order given, whose results are the
+	// per-document results of the batch
+	Stores []*Store
+
+	// Params defining the batch; its Concurrency is the budget of concurrent queries shared by
+	// all the stores
+	Params *Parameters
+}
+
+// NewBatchStore creates a new BatchStore instance for the given keys and their values, which must
+// have the same length. Each store queries peers one at a time, so no more than the store
+// parameters' Concurrency queries are in flight across the batch. The optional replication policy
+// overrides the store parameters' NReplicas for the values it gives a number of replicas.
+func NewBatchStore(
+	peerID ecid.ID,
+	orgID ecid.ID,
+	keys []id.ID,
+	values []*api.Document,
+	searchParams *search.Parameters,
+	storeParams *Parameters,
+	replication api.ReplicationPolicy,
+) *BatchStore {
+	stores := make([]*Store, len(keys))
+	for i, key := range keys {
+		params := *storeParams // by value so each store can change its own
+		params.Concurrency = 1
+		if replication != nil {
+			if nReplicas := replication.NReplicas(values[i]); nReplicas > 0 {
+				params.NReplicas = nReplicas
+			}
+		}
+		stores[i] = NewStore(peerID, orgID, key, values[i], searchParams, &params)
+	}
+	return &BatchStore{
+		Stores: stores,
+		Params: storeParams,
+	}
+}
+
+// NStored returns the number of stores that have stored sufficient replicas.
+func (b *BatchStore) NStored() int {
+	nStored := 0
+	for _, s := range b.Stores {
+		if s.Result != nil && s.Stored() {
+			nStored++
+		}
+	}
+	return nStored
+}
+
+// Release releases each of the batch's stores once the caller is done with them.
+func (b *BatchStore) Release() {
+	for _, s := range b.Stores {
+		s.Release()
+	}
+}
+
+// BatchStorer executes batch store operations.
+type BatchStorer interface {
+	// StoreBatch executes each of the batch's stores, starting with a given set of seed peers,
+	// and returns the first fatal error among them, if any. The batch is abandoned if the
+	// context is done.
+	StoreBatch(ctx context.Context, batch *BatchStore, seeds []peer.Peer) error
+}
+
+type batchStorer struct {
+	inner Storer
+}
+
+// NewBatchStorer returns a new BatchStorer executing each store of a batch with the given
+// Storer, whose librarian connections are reused across them.
+func NewBatchStorer(inner Storer) BatchStorer {
+	return &batchStorer{inner: inner}
+}
+
+func (b *batchStorer) StoreBatch(ctx context.Context, batch *BatchStore, seeds []peer.Peer) error {
+	// store in key order, so each store can seed its search with the peers that stored the
+	// nearby key before it, which are likely also close to its own key
+	sorted := make([]*Store, len(batch.Stores))
+	copy(sorted, batch.Stores)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Search.Key.Bytes(), sorted[j].Search.Key.Bytes()) < 0
+	})
+
+	var mu sync.Mutex
+	nearby := make([]peer.Peer, 0)
+	slots := make(chan struct{}, maxUint(batch.Params.Concurrency, 1))
+	var wg sync.WaitGroup
+	for _, s := range sorted {
+		if ctx.Err() != nil {
+			s.Result = NewFatalResult(ErrStoreCanceled)
+			continue
+		}
+		slots <- struct{}{}
+		mu.Lock()
+		storeSeeds := append(append(make([]peer.Peer, 0), nearby...), seeds...)
+		mu.Unlock()
+		wg.Add(1)
+		go func(s *Store) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := b.inner.Store(ctx, s, storeSeeds); err != nil {
+				return
+			}
+			s.wrapLock(func() {
+				mu.Lock()
+				nearby = append(make([]peer.Peer, 0), s.Result.Responded...)
+				mu.Unlock()
+			})
+		}(s)
+	}
+	wg.Wait()
+
+	for _, s := range batch.Stores {
+		if s.Result != nil && s.Result.FatalErr != nil {
+			return s.Result.FatalErr
+		}
+	}
+	return nil
+}
+
+func maxUint(x, y uint) uint {
+	if x > y {
+		return x
+	}
+	return y
+}
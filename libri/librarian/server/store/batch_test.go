@@ -0,0 +1,135 @@
+package store
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	cid "github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	ssearch "github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestNewBatchStore(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	keys, values := newTestBatch(rng, 4)
+	storeParams := NewDefaultParameters()
+
+	batch := NewBatchStore(peerID, orgID, keys, values, ssearch.NewDefaultParameters(),
+		storeParams, nil)
+	assert.Len(t, batch.Stores, len(keys))
+	assert.Equal(t, storeParams, batch.Params)
+	for i, s := range batch.Stores {
+		assert.Equal(t, keys[i], s.Search.Key)
+		assert.Equal(t, values[i], s.CreateRq().Value)
+		assert.Equal(t, uint(1), s.Params.Concurrency)
+		assert.Equal(t, uint(1), s.Search.Params.Concurrency)
+	}
+
+	// original params unchanged
+	assert.Equal(t, DefaultConcurrency, storeParams.Concurrency)
+}
+
+func TestNewBatchStore_replication(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	keys, values := newTestBatch(rng, 4)
+	replication := api.TypeReplicas{api.GetDocumentType(values[0]): 7}
+
+	batch := NewBatchStore(peerID, orgID, keys, values, ssearch.NewDefaultParameters(),
+		NewDefaultParameters(), replication)
+	for i, s := range batch.Stores {
+		expected := DefaultNReplicas
+		if n := replication.NReplicas(values[i]); n > 0 {
+			expected = n
+		}
+		assert.Equal(t, expected, s.Params.NReplicas)
+	}
+	assert.Equal(t, uint(7), batch.Stores[0].Params.NReplicas)
+}
+
+func TestBatchStorer_StoreBatch_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := ssearch.NewTestPeers(rng, 32)
+	orgID := ecid.NewPseudoRandom(rng)
+	rec := &fixedRecorder{}
+	bs := NewBatchStorer(&storer{
+		searcher:      ssearch.NewTestSearcher(peersMap, addressFinders, rec),
+		storerCreator: &fixedStorerCreator{},
+		peerSigner:    &client.TestNoOpSigner{},
+		orgSigner:     &client.TestNoOpSigner{},
+		rec:           rec,
+		doc:           comm.NewNaiveDoctor(),
+		window:        newSendWindow(),
+	})
+	searchParams := &ssearch.Parameters{
+		NMaxErrors: DefaultNMaxErrors,
+		Timeout:    DefaultQueryTimeout,
+	}
+	keys, values := newTestBatch(rng, 8)
+	batch := NewBatchStore(peerID, orgID, keys, values, searchParams, NewDefaultParameters(),
+		nil)
+	seeds := ssearch.NewTestSeeds(peers, selfPeerIdxs)
+
+	err := bs.StoreBatch(context.Background(), batch, seeds)
+	assert.Nil(t, err)
+	assert.Equal(t, len(keys), batch.NStored())
+	for _, s := range batch.Stores {
+		assert.True(t, s.Stored())
+		assert.Len(t, s.Result.Responded, int(DefaultNReplicas))
+	}
+
+	batch.Release()
+	for _, s := range batch.Stores {
+		assert.Nil(t, s.Result.Responded)
+	}
+}
+
+func TestBatchStorer_StoreBatch_canceled(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	bs := NewBatchStorer(&storer{searcher: &errSearcher{}})
+	keys, values := newTestBatch(rng, 4)
+	batch := NewBatchStore(peerID, orgID, keys, values, ssearch.NewDefaultParameters(),
+		NewDefaultParameters(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := bs.StoreBatch(ctx, batch, []peer.Peer{})
+	assert.Equal(t, ErrStoreCanceled, err)
+	assert.Zero(t, batch.NStored())
+	for _, s := range batch.Stores {
+		assert.Equal(t, ErrStoreCanceled, s.Result.FatalErr)
+	}
+}
+
+func TestBatchStorer_StoreBatch_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	bs := NewBatchStorer(&storer{searcher: &errSearcher{}})
+	keys, values := newTestBatch(rng, 4)
+	batch := NewBatchStore(peerID, orgID, keys, values, ssearch.NewDefaultParameters(),
+		NewDefaultParameters(), nil)
+
+	// search errors are surfaced with each store's result
+	err := bs.StoreBatch(context.Background(), batch, []peer.Peer{})
+	assert.NotNil(t, err)
+	assert.Zero(t, batch.NStored())
+	for _, s := range batch.Stores {
+		assert.NotNil(t, s.Result.FatalErr)
+	}
+}
+
+func newTestBatch(rng *rand.Rand, n int) ([]cid.ID, []*api.Document) {
+	keys, values := make([]cid.ID, n), make([]*api.Document, n)
+	for i := range keys {
+		values[i], keys[i] = api.NewTestDocument(rng)
+	}
+	return keys, values
+}
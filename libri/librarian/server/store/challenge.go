@@ -0,0 +1,82 @@
+package store
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// challenge checks that the peer possesses the value it just acked storing by requesting the MAC
+// of a random slice of the value's serialized bytes under a fresh MAC key.
+func (s *storer) challenge(ctx context.Context, next peer.Peer, store *Store) error {
+	valueBytes, err := proto.Marshal(store.CreateRq().Value)
+	if err != nil {
+		return err
+	}
+	macKey := make([]byte, api.HMACKeyLength)
+	if _, err = rand.Read(macKey); err != nil {
+		return err
+	}
+	offset, length := challengeSlice(macKey, len(valueBytes), store.Params.ChallengeLength)
+	expected, err := api.SliceHMAC256(valueBytes, macKey, offset, length)
+	if err != nil {
+		return err
+	}
+
+	rq := store.CreateChallengeRq(macKey, offset, length)
+	var rp *api.VerifyResponse
+	err = peer.QueryAddresses(next, func(address *net.TCPAddr) error {
+		var err error
+		rp, err = s.challengeAddress(ctx, address, rq, store)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(rp.Mac, expected) {
+		return ErrFailedChallenge
+	}
+	return nil
+}
+
+func (s *storer) challengeAddress(
+	parent context.Context, address *net.TCPAddr, rq *api.VerifyRequest, store *Store,
+) (*api.VerifyResponse, error) {
+	lc, err := s.verifierCreator.Create(address.String())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel, err := client.NewSignedTimeoutContextFrom(parent, s.peerSigner, s.orgSigner, rq,
+		store.Params.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	rp, err := lc.Verify(ctx, rq)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(rp.Metadata.RequestId, rq.Metadata.RequestId) {
+		return nil, client.ErrUnexpectedRequestID
+	}
+	return rp, nil
+}
+
+// challengeSlice returns the offset and length of the slice of the value to challenge, using the
+// (random) MAC key to pick the offset.
+func challengeSlice(macKey []byte, valueLen int, maxLength uint) (uint32, uint32) {
+	length := uint32(valueLen)
+	if maxLength < uint(valueLen) {
+		length = uint32(maxLength)
+	}
+	nOffsets := uint32(valueLen) - length + 1
+	return binary.BigEndian.Uint32(macKey) % nOffsets, length
+}
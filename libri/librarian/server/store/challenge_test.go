@@ -0,0 +1,121 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	ssearch "github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestStorer_query_challenge(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	valueBytes, err := proto.Marshal(value)
+	assert.Nil(t, err)
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	store := NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(),
+		&Parameters{Timeout: DefaultQueryTimeout, ChallengeLength: 64})
+	next := peer.NewTestPeer(rng, 0)
+	newStorer := func(vc client.VerifierCreator) *storer {
+		return &storer{
+			peerSigner:      &client.TestNoOpSigner{},
+			orgSigner:       &client.TestNoOpSigner{},
+			storerCreator:   &fixedStorerCreator{},
+			verifierCreator: vc,
+		}
+	}
+
+	// peer possessing the value passes the challenge
+	s := newStorer(&fixedVerifierCreator{verifier: &possessingVerifier{value: valueBytes}})
+	rp, err := s.query(context.Background(), next, store)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+
+	cases := map[string]*storer{
+		"create error": newStorer(&fixedVerifierCreator{err: errors.New("some Create error")}),
+		"verify error": newStorer(&fixedVerifierCreator{
+			verifier: &possessingVerifier{err: errors.New("some Verify error")},
+		}),
+		"unexpected request ID": newStorer(&fixedVerifierCreator{
+			verifier: &possessingVerifier{value: valueBytes, requestID: []byte{1, 2, 3, 4}},
+		}),
+		"discarded value": newStorer(&fixedVerifierCreator{
+			verifier: &possessingVerifier{},
+		}),
+		"different value": newStorer(&fixedVerifierCreator{
+			verifier: &possessingVerifier{value: make([]byte, len(valueBytes))},
+		}),
+	}
+	for desc, c := range cases {
+		_, err := c.query(context.Background(), next, store)
+		assert.NotNil(t, err, desc)
+	}
+	_, err = cases["different value"].query(context.Background(), next, store)
+	assert.Equal(t, ErrFailedChallenge, err)
+}
+
+func TestChallengeSlice(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for c := 0; c < 16; c++ {
+		macKey := api.RandBytes(rng, api.HMACKeyLength)
+		for _, valueLen := range []int{1, 64, 1024} {
+			for _, maxLength := range []uint{1, 64, 256} {
+				info := fmt.Sprintf("valueLen: %d, maxLength: %d", valueLen, maxLength)
+				offset, length := challengeSlice(macKey, valueLen, maxLength)
+				assert.True(t, length > 0, info)
+				assert.True(t, uint(length) <= maxLength, info)
+				assert.True(t, int(offset+length) <= valueLen, info)
+			}
+		}
+	}
+}
+
+type fixedVerifierCreator struct {
+	verifier api.Verifier
+	err      error
+}
+
+func (c *fixedVerifierCreator) Create(address string) (api.Verifier, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.verifier, nil
+}
+
+// possessingVerifier MACs slices of the value it possesses, or returns no MAC if it has no value.
+type possessingVerifier struct {
+	value     []byte
+	requestID []byte
+	err       error
+}
+
+func (v *possessingVerifier) Verify(
+	ctx context.Context, rq *api.VerifyRequest, opts ...grpc.CallOption,
+) (*api.VerifyResponse, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	requestID := v.requestID
+	if requestID == nil {
+		requestID = rq.Metadata.RequestId
+	}
+	rp := &api.VerifyResponse{Metadata: &api.ResponseMetadata{RequestId: requestID}}
+	if v.value != nil {
+		mac, err := api.SliceHMAC256(v.value, rq.MacKey, rq.SliceOffset, rq.SliceLength)
+		if err != nil {
+			return nil, err
+		}
+		rp.Mac = mac
+	}
+	return rp, nil
+}
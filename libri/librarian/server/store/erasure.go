@@ -0,0 +1,310 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	// ErrInvalidShardCounts indicates when the number of data and parity shards can't be used
+	// to split a document.
+	ErrInvalidShardCounts = errors.New("invalid number of data and parity shards")
+
+	// ErrTooFewShards indicates when fewer consistent shards than the number of data shards are
+	// available to reconstruct a document.
+	ErrTooFewShards = errors.New("too few shards to reconstruct document")
+
+	errSingularMatrix = errors.New("singular matrix")
+)
+
+// NewShardStores splits the value into the data and parity shards defined by the store
+// parameters and returns a Store for each as well as one for the manifest listing them. Each
+// shard is stored under its own (pseudo-random) shard key on the single peer closest to it, so the
+// shards land on distinct peers and the parity shards provide the redundancy NReplicas full
+// copies otherwise would. The manifest is small, so it is stored with the full NReplicas.
+func NewShardStores(
+	peerID ecid.ID,
+	orgID ecid.ID,
+	key id.ID,
+	value *api.Document,
+	searchParams *search.Parameters,
+	storeParams *Parameters,
+) ([]*Store, *Store, error) {
+	shards, manifest, err := NewShards(key, value, storeParams.NDataShards,
+		storeParams.NParityShards)
+	if err != nil {
+		return nil, nil, err
+	}
+	shardParams := *storeParams // by value to avoid changing original store params
+	shardParams.NReplicas = 1
+	stores := make([]*Store, len(shards))
+	for i, shard := range shards {
+		shardKey := api.GetShardKey(shard.GetShard())
+		stores[i] = NewStore(peerID, orgID, shardKey, shard, searchParams, &shardParams)
+	}
+	manifestKey := api.GetShardManifestKey(key.Bytes())
+	manifestStore := NewStore(peerID, orgID, manifestKey, manifest, searchParams, storeParams)
+	return stores, manifestStore, nil
+}
+
+// NewShards splits the document with the given key into nData data shards and nParity
+// Reed-Solomon parity shards, any nData of which can reconstruct it. It also returns the shard
+// manifest document listing the shards' keys in index order.
+func NewShards(
+	key id.ID, value *api.Document, nData, nParity uint,
+) ([]*api.Document, *api.Document, error) {
+	if nData == 0 || nData+nParity > api.MaxShards {
+		return nil, nil, ErrInvalidShardCounts
+	}
+	valueBytes, err := proto.Marshal(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	shardSize := (len(valueBytes) + int(nData) - 1) / int(nData)
+	padded := make([]byte, shardSize*int(nData))
+	copy(padded, valueBytes)
+	data := make([][]byte, nData)
+	for i := range data {
+		data[i] = padded[i*shardSize : (i+1)*shardSize]
+	}
+	rs := newReedSolomon(int(nData), int(nParity))
+	all := append(data, rs.encode(data)...)
+
+	docs := make([]*api.Document, len(all))
+	manifest := &api.ShardManifest{
+		OriginalKey:   key.Bytes(),
+		NDataShards:   uint32(nData),
+		NParityShards: uint32(nParity),
+		Size:          uint64(len(valueBytes)),
+		ShardKeys:     make([][]byte, len(all)),
+	}
+	for i, shardData := range all {
+		shard := &api.Shard{
+			OriginalKey:   key.Bytes(),
+			Index:         uint32(i),
+			NDataShards:   uint32(nData),
+			NParityShards: uint32(nParity),
+			Size:          uint64(len(valueBytes)),
+			Data:          shardData,
+		}
+		docs[i] = &api.Document{Contents: &api.Document_Shard{Shard: shard}}
+		manifest.ShardKeys[i] = api.GetShardKey(shard).Bytes()
+	}
+	manifestDoc := &api.Document{Contents: &api.Document_ShardManifest{ShardManifest: manifest}}
+	return docs, manifestDoc, nil
+}
+
+// JoinShards reconstructs the document with the given key from its shards, indexed in the order
+// of the manifest's shard keys, which may be missing (nil) as long as at least the number of data
+// shards of them are present. Shards whose keys differ from those in the manifest are ignored, so
+// a bogus shard can't prevent reconstruction from the others. The reconstructed document is also
+// checked against the key, so a bogus manifest results in an api.ErrUnexpectedKey error.
+func JoinShards(
+	key id.ID, manifest *api.ShardManifest, shards []*api.Shard,
+) (*api.Document, error) {
+	if err := api.ValidateShardManifest(manifest); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(manifest.OriginalKey, key.Bytes()) {
+		return nil, api.ErrUnexpectedKey
+	}
+	nData, nParity := int(manifest.NDataShards), int(manifest.NParityShards)
+	indexed, nPresent, shardSize := make([][]byte, nData+nParity), 0, -1
+	for i, shard := range shards {
+		if i >= len(indexed) || !verifiedShard(manifest, uint32(i), shard) {
+			continue
+		}
+		if shardSize == -1 {
+			shardSize = len(shard.Data)
+		}
+		if len(shard.Data) != shardSize {
+			continue
+		}
+		indexed[i] = shard.Data
+		nPresent++
+	}
+	if nPresent < nData {
+		return nil, ErrTooFewShards
+	}
+	data, err := newReedSolomon(nData, nParity).reconstruct(indexed)
+	if err != nil {
+		return nil, err
+	}
+	valueBytes := bytes.Join(data, nil)
+	if manifest.Size > uint64(len(valueBytes)) {
+		return nil, api.ErrShardSizeOutOfRange
+	}
+	value := &api.Document{}
+	if err := proto.Unmarshal(valueBytes[:manifest.Size], value); err != nil {
+		return nil, err
+	}
+	valueKey, err := api.GetKey(value)
+	if err != nil {
+		return nil, err
+	}
+	if key.Cmp(valueKey) != 0 {
+		return nil, api.ErrUnexpectedKey
+	}
+	return value, nil
+}
+
+// verifiedShard returns whether the shard is the one with the given index in the manifest.
+func verifiedShard(manifest *api.ShardManifest, index uint32, shard *api.Shard) bool {
+	return shard != nil &&
+		shard.Index == index &&
+		bytes.Equal(api.GetShardKey(shard).Bytes(), manifest.ShardKeys[index])
+}
+
+// reedSolomon is a systematic Reed-Solomon erasure code over GF(2^8). Its encoding matrix is the
+// identity over the data shards stacked on a Cauchy matrix for the parity shards, so every square
+// submatrix of nData of its rows is invertible.
+type reedSolomon struct {
+	nData   int
+	nParity int
+	matrix  [][]byte
+}
+
+func newReedSolomon(nData, nParity int) *reedSolomon {
+	matrix := make([][]byte, nData+nParity)
+	for i := range matrix {
+		matrix[i] = make([]byte, nData)
+		if i < nData {
+			matrix[i][i] = 1
+			continue
+		}
+		for j := range matrix[i] {
+			// rows and columns index disjoint sets of field elements, so x ^ y is never zero
+			matrix[i][j] = gfInv(byte(i) ^ byte(j))
+		}
+	}
+	return &reedSolomon{
+		nData:   nData,
+		nParity: nParity,
+		matrix:  matrix,
+	}
+}
+
+// encode returns the parity shards of the given equal-length data shards.
+func (rs *reedSolomon) encode(data [][]byte) [][]byte {
+	parity := make([][]byte, rs.nParity)
+	for i := range parity {
+		parity[i] = make([]byte, len(data[0]))
+		gfMulAddRows(parity[i], rs.matrix[rs.nData+i], data)
+	}
+	return parity
+}
+
+// reconstruct returns the data shards from the given data and parity shards indexed by shard
+// index, with missing shards nil. At least nData shards must be present.
+func (rs *reedSolomon) reconstruct(shards [][]byte) ([][]byte, error) {
+	rows, present := make([][]byte, 0, rs.nData), make([][]byte, 0, rs.nData)
+	for i, shard := range shards {
+		if shard != nil && len(rows) < rs.nData {
+			rows = append(rows, rs.matrix[i])
+			present = append(present, shard)
+		}
+	}
+	if len(rows) < rs.nData {
+		return nil, ErrTooFewShards
+	}
+	decode, err := gfInvert(rows)
+	if err != nil {
+		return nil, err
+	}
+	data := make([][]byte, rs.nData)
+	for i := range data {
+		if shards[i] != nil {
+			data[i] = shards[i]
+			continue
+		}
+		data[i] = make([]byte, len(present[0]))
+		gfMulAddRows(data[i], decode[i], present)
+	}
+	return data, nil
+}
+
+// GF(2^8) arithmetic with the primitive polynomial x^8 + x^4 + x^3 + x^2 + 1 and generator 2.
+var (
+	gfExp [2 * 255]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i], gfExp[i+255] = byte(x), byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfInv returns the multiplicative inverse of a, which must not be zero.
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfMulAddRows sets out to the sum of the rows weighted by the coefficients.
+func gfMulAddRows(out []byte, coeffs []byte, rows [][]byte) {
+	for j, c := range coeffs {
+		if c == 0 {
+			continue
+		}
+		for k, v := range rows[j] {
+			out[k] ^= gfMul(c, v)
+		}
+	}
+}
+
+// gfInvert returns the inverse of the given square matrix via Gauss-Jordan elimination.
+func gfInvert(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	work, inv := make([][]byte, n), make([][]byte, n)
+	for i := range m {
+		work[i] = append([]byte(nil), m[i]...)
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && work[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errSingularMatrix
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		scale := gfInv(work[col][col])
+		for k := 0; k < n; k++ {
+			work[col][k] = gfMul(work[col][k], scale)
+			inv[col][k] = gfMul(inv[col][k], scale)
+		}
+		for row := 0; row < n; row++ {
+			if row == col || work[row][col] == 0 {
+				continue
+			}
+			factor := work[row][col]
+			for k := 0; k < n; k++ {
+				work[row][k] ^= gfMul(factor, work[col][k])
+				inv[row][k] ^= gfMul(factor, inv[col][k])
+			}
+		}
+	}
+	return inv, nil
+}
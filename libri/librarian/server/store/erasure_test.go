@@ -0,0 +1,173 @@
+package store
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameters_ErasureCoded(t *testing.T) {
+	p := NewDefaultParameters()
+	assert.False(t, p.ErasureCoded(int(p.MinErasureSize)))
+
+	p.NDataShards = 4
+	assert.True(t, p.ErasureCoded(int(p.MinErasureSize)))
+	assert.False(t, p.ErasureCoded(int(p.MinErasureSize)-1))
+}
+
+func TestNewShardStores(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	params := NewDefaultParameters()
+	params.NDataShards, params.NParityShards = 4, 2
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+
+	stores, manifest, err := NewShardStores(peerID, orgID, key, value,
+		search.NewDefaultParameters(), params)
+	assert.Nil(t, err)
+	assert.Len(t, stores, 6)
+	for _, s := range stores {
+		assert.Equal(t, uint(1), s.Params.NReplicas)
+		rq := s.CreateRq()
+		assert.Equal(t, api.GetShardKey(rq.Value.GetShard()), s.Search.Key)
+		assert.Equal(t, s.Search.Key.Bytes(), rq.Key)
+		assert.Nil(t, api.ValidateDocument(rq.Value))
+	}
+	assert.Equal(t, DefaultNReplicas, params.NReplicas)
+
+	// manifest lists the shard keys and is fully replicated
+	assert.Equal(t, DefaultNReplicas, manifest.Params.NReplicas)
+	assert.Equal(t, api.GetShardManifestKey(key.Bytes()), manifest.Search.Key)
+	rq := manifest.CreateRq()
+	assert.Nil(t, api.ValidateDocument(rq.Value))
+	for i, shardKey := range rq.Value.GetShardManifest().ShardKeys {
+		assert.Equal(t, stores[i].Search.Key.Bytes(), shardKey)
+	}
+
+	params.NDataShards = 0
+	stores, manifest, err = NewShardStores(peerID, orgID, key, value,
+		search.NewDefaultParameters(), params)
+	assert.Equal(t, ErrInvalidShardCounts, err)
+	assert.Nil(t, stores)
+	assert.Nil(t, manifest)
+}
+
+func TestNewShards_JoinShards_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	for nData := uint(1); nData <= 6; nData++ {
+		for nParity := uint(0); nParity <= 3; nParity++ {
+			docs, manifestDoc, err := NewShards(key, value, nData, nParity)
+			assert.Nil(t, err)
+			assert.Len(t, docs, int(nData+nParity))
+			assert.Nil(t, api.ValidateDocument(manifestDoc))
+			shards := make([]*api.Shard, len(docs))
+			for i, doc := range docs {
+				assert.Nil(t, api.ValidateDocument(doc))
+				shards[i] = doc.GetShard()
+			}
+
+			// any nParity shards can be missing
+			for _, i := range rng.Perm(len(shards))[:nParity] {
+				shards[i] = nil
+			}
+			joined, err := JoinShards(key, manifestDoc.GetShardManifest(), shards)
+			assert.Nil(t, err)
+			assert.Equal(t, value, joined)
+		}
+	}
+}
+
+func TestNewShards_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+
+	docs, manifest, err := NewShards(key, value, 0, 2)
+	assert.Equal(t, ErrInvalidShardCounts, err)
+	assert.Nil(t, docs)
+	assert.Nil(t, manifest)
+
+	docs, manifest, err = NewShards(key, value, 200, 57)
+	assert.Equal(t, ErrInvalidShardCounts, err)
+	assert.Nil(t, docs)
+	assert.Nil(t, manifest)
+}
+
+func TestJoinShards_bogus(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	docs, manifestDoc, err := NewShards(key, value, 3, 2)
+	assert.Nil(t, err)
+	shards := make([]*api.Shard, len(docs))
+	for i, doc := range docs {
+		shards[i] = doc.GetShard()
+	}
+
+	// bogus shard in place of a genuine one is skipped rather than failing reconstruction
+	bogus := *shards[0]
+	bogus.Data = api.RandBytes(rng, len(bogus.Data))
+	shards[0] = &bogus
+	joined, err := JoinShards(key, manifestDoc.GetShardManifest(), shards)
+	assert.Nil(t, err)
+	assert.Equal(t, value, joined)
+}
+
+func TestJoinShards_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	value, key := api.NewTestDocument(rng)
+	newShards := func() (*api.ShardManifest, []*api.Shard) {
+		docs, manifestDoc, err := NewShards(key, value, 3, 2)
+		assert.Nil(t, err)
+		shards := make([]*api.Shard, len(docs))
+		for i, doc := range docs {
+			shards[i] = doc.GetShard()
+		}
+		return manifestDoc.GetShardManifest(), shards
+	}
+
+	// invalid manifest
+	manifest, shards := newShards()
+	_, err := JoinShards(key, nil, shards)
+	assert.Equal(t, api.ErrMissingShardManifest, err)
+	manifest.NDataShards = 0
+	_, err = JoinShards(key, manifest, shards)
+	assert.Equal(t, api.ErrZeroDataShards, err)
+
+	// manifest of another document
+	manifest, shards = newShards()
+	_, err = JoinShards(id.NewPseudoRandom(rng), manifest, shards)
+	assert.Equal(t, api.ErrUnexpectedKey, err)
+
+	// too few shards
+	manifest, shards = newShards()
+	shards[0], shards[2], shards[4] = nil, nil, nil
+	_, err = JoinShards(key, manifest, shards)
+	assert.Equal(t, ErrTooFewShards, err)
+
+	// misplaced and modified shards don't count
+	manifest, shards = newShards()
+	shards[2] = shards[1]
+	shards[3].Size++
+	shards[4].Data[0]++
+	_, err = JoinShards(key, manifest, shards)
+	assert.Equal(t, ErrTooFewShards, err)
+
+	// manifest listing shards of another document
+	manifest, _ = newShards()
+	otherValue, _ := api.NewTestDocument(rng)
+	otherDocs, _, err := NewShards(key, otherValue, 3, 2)
+	assert.Nil(t, err)
+	otherShards := make([]*api.Shard, len(otherDocs))
+	for i, doc := range otherDocs {
+		otherShards[i] = doc.GetShard()
+		manifest.ShardKeys[i] = api.GetShardKey(otherShards[i]).Bytes()
+	}
+	manifest.Size = otherShards[0].Size
+	_, err = JoinShards(key, manifest, otherShards)
+	assert.Equal(t, api.ErrUnexpectedKey, err)
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	promNamespace = "libri"
+	promSubsystem = "store"
+
+	outcomeLabel = "outcome"
+
+	storedOutcome    = "stored"
+	existsOutcome    = "exists"
+	exhaustedOutcome = "exhausted"
+	erroredOutcome   = "errored"
+	canceledOutcome  = "canceled"
+	errorOutcome     = "error"
+)
+
+// PromStorer is a Storer that reports the outcomes and durations of its stores as Prometheus
+// metrics.
+type PromStorer interface {
+	Storer
+
+	// Register registers the Prometheus metrics with the default Prometheus registerer.
+	Register()
+
+	// Unregister unregisters the Prometheus metrics from the default Prometheus registerer.
+	Unregister()
+}
+
+type promStorer struct {
+	inner    Storer
+	stores   *prom.CounterVec
+	duration *prom.HistogramVec
+}
+
+// NewPromStorer returns a PromStorer wrapping the given Storer.
+func NewPromStorer(inner Storer) PromStorer {
+	return &promStorer{
+		inner: inner,
+		stores: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: promNamespace,
+				Subsystem: promSubsystem,
+				Name:      "stores_total",
+				Help:      "Number of store operations, by outcome.",
+			},
+			[]string{outcomeLabel},
+		),
+		duration: prom.NewHistogramVec(
+			prom.HistogramOpts{
+				Namespace: promNamespace,
+				Subsystem: promSubsystem,
+				Name:      "duration_seconds",
+				Help:      "Duration of store operations, including their searches, by outcome.",
+				Buckets:   prom.DefBuckets,
+			},
+			[]string{outcomeLabel},
+		),
+	}
+}
+
+func (s *promStorer) Store(ctx context.Context, store *Store, seeds []peer.Peer) error {
+	start := time.Now()
+	err := s.inner.Store(ctx, store, seeds)
+	outcome := storeOutcome(store, err)
+	s.stores.WithLabelValues(outcome).Inc()
+	s.duration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (s *promStorer) Register() {
+	prom.MustRegister(s.stores)
+	prom.MustRegister(s.duration)
+}
+
+func (s *promStorer) Unregister() {
+	_ = prom.Unregister(s.stores)
+	_ = prom.Unregister(s.duration)
+}
+
+// storeOutcome returns the outcome label of a store that returned the given error.
+func storeOutcome(store *Store, err error) string {
+	switch {
+	case err == ErrStoreCanceled || err == context.Canceled || err == context.DeadlineExceeded:
+		return canceledOutcome
+	case err == ErrTooManyStoreErrors:
+		return erroredOutcome
+	case err != nil:
+		return errorOutcome
+	case store.Stored():
+		return storedOutcome
+	case store.Exists():
+		return existsOutcome
+	default:
+		return exhaustedOutcome
+	}
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	ssearch "github.com/drausin/libri/libri/librarian/server/search"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestPromStorer_Store(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	inner := &blockingStorer{
+		responded: peer.NewTestPeers(rng, int(DefaultNReplicas)),
+		proceed:   make(chan struct{}),
+	}
+	close(inner.proceed)
+	s := NewPromStorer(inner).(*promStorer)
+
+	err := s.Store(context.Background(), newTestAsyncStore(rng), nil)
+	assert.Nil(t, err)
+	s.inner = errStorer{}
+	err = s.Store(context.Background(), newTestAsyncStore(rng), nil)
+	assert.NotNil(t, err)
+
+	for _, outcome := range []string{storedOutcome, errorOutcome} {
+		written := &dto.Metric{}
+		assert.Nil(t, s.stores.WithLabelValues(outcome).Write(written))
+		assert.Equal(t, float64(1), *written.Counter.Value, outcome)
+		assert.Nil(t, s.duration.WithLabelValues(outcome).(prom.Histogram).Write(written))
+		assert.Equal(t, uint64(1), *written.Histogram.SampleCount, outcome)
+	}
+
+	s.Register()
+	s.Unregister()
+}
+
+func TestStoreOutcome(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	store := newTestAsyncStore(rng)
+	assert.Equal(t, canceledOutcome, storeOutcome(store, ErrStoreCanceled))
+	assert.Equal(t, canceledOutcome, storeOutcome(store, context.DeadlineExceeded))
+	assert.Equal(t, erroredOutcome, storeOutcome(store, ErrTooManyStoreErrors))
+
+	store.Result = NewInitialResult(ssearch.NewInitialResult(store.Search.Key,
+		store.Search.Params))
+	assert.Equal(t, exhaustedOutcome, storeOutcome(store, nil))
+
+	store.Result.Search.Value = &api.Document{}
+	assert.Equal(t, existsOutcome, storeOutcome(store, nil))
+}
@@ -0,0 +1,36 @@
+package store
+
+import "github.com/drausin/libri/libri/librarian/server/peer"
+
+// PlacementScorer scores candidate peers for storing a replica, so stores can prefer peers unlike
+// those already chosen, e.g., in distinct failure domains.
+type PlacementScorer interface {
+	// Score returns the non-negative cost of storing a replica on the candidate given the peers
+	// already chosen. Stores prefer the candidate with the lowest cost, breaking ties by
+	// distance to the key.
+	Score(candidate peer.Peer, placed []peer.Peer) uint
+}
+
+type zoneScorer struct{}
+
+// NewZoneScorer returns a PlacementScorer preferring candidates in zones with fewer of the
+// already chosen peers, spreading replicas across distinct zones when the candidates allow.
+// Candidates without a zone are never penalized, so stores among peers that don't advertise zones
+// remain closest first.
+func NewZoneScorer() PlacementScorer {
+	return zoneScorer{}
+}
+
+func (zoneScorer) Score(candidate peer.Peer, placed []peer.Peer) uint {
+	zone := candidate.Zone()
+	if zone == "" {
+		return 0
+	}
+	n := uint(0)
+	for _, p := range placed {
+		if p.Zone() == zone {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	cid "github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneScorer_Score(t *testing.T) {
+	zs := newTestZonePeers("a", "a", "b", "")
+	s := NewZoneScorer()
+
+	assert.Equal(t, uint(0), s.Score(zs[0], nil))
+	assert.Equal(t, uint(1), s.Score(zs[0], zs[1:2]))
+	assert.Equal(t, uint(1), s.Score(zs[0], zs[1:])) // other zones don't count
+	assert.Equal(t, uint(0), s.Score(zs[2], zs[:2]))
+
+	// peers w/o zones are never penalized
+	assert.Equal(t, uint(0), s.Score(zs[3], zs[3:]))
+}
+
+func TestGetNextToQuery_placement(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	zs := newTestZonePeers("a", "a", "a", "b", "c")
+	w := newSendWindow()
+	s := newTestWindowStore(rng, zs)
+
+	// spreads across zones before reusing one
+	assert.Equal(t, zs[0], getNextToQuery(s, w))
+	assert.Equal(t, zs[3], getNextToQuery(s, w))
+	assert.Equal(t, zs[4], getNextToQuery(s, w))
+	assert.Equal(t, zs[1], getNextToQuery(s, w))
+
+	// errored peers no longer count against their zone
+	s = newTestWindowStore(rng, zs[:4])
+	assert.Equal(t, zs[0], getNextToQuery(s, w))
+	s.Result.unplace(zs[0])
+	assert.Equal(t, zs[1], getNextToQuery(s, w))
+
+	// saturated peers are still skipped
+	s = newTestWindowStore(rng, zs)
+	acquired, _ := w.tryAcquire(zs[3].ID(), 1)
+	assert.True(t, acquired)
+	assert.Equal(t, zs[0], getNextToQuery(s, w))
+	assert.Equal(t, zs[4], getNextToQuery(s, w))
+	w.release(zs[3].ID())
+
+	// w/o placement scorer, stores to closest first
+	s = newTestWindowStore(rng, zs)
+	s.Params.Placement = nil
+	assert.Equal(t, zs[0], getNextToQuery(s, w))
+	assert.Equal(t, zs[1], getNextToQuery(s, w))
+}
+
+func newTestZonePeers(zones ...string) []peer.Peer {
+	peers := make([]peer.Peer, len(zones))
+	for i, zone := range zones {
+		addr := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 20100 + i}
+		peers[i] = peer.NewWithZone(cid.FromInt64(int64(i+1)), "", zone, []*net.TCPAddr{addr})
+	}
+	return peers
+}
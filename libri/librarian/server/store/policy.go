@@ -0,0 +1,37 @@
+package store
+
+import "github.com/drausin/libri/libri/librarian/api"
+
+// Policy overrides the parameters of stores of particular documents, e.g., so critical metadata
+// is stored with more replicas than bulk payload.
+type Policy interface {
+	// Parameters returns the parameters for storing the value, given the defaults, which it
+	// doesn't modify.
+	Parameters(value *api.Document, defaults *Parameters) *Parameters
+}
+
+type replicationPolicy struct {
+	replicas api.ReplicationPolicy
+}
+
+// NewReplicationPolicy returns a Policy overriding the default number of replicas with the
+// (non-zero) number from the api.ReplicationPolicy, e.g., an api.TypeReplicas giving the number
+// for each document type.
+func NewReplicationPolicy(replicas api.ReplicationPolicy) Policy {
+	return &replicationPolicy{replicas: replicas}
+}
+
+func (p *replicationPolicy) Parameters(value *api.Document, defaults *Parameters) *Parameters {
+	return WithNReplicas(defaults, p.replicas.NReplicas(value))
+}
+
+// WithNReplicas returns a copy of the parameters with the given number of replicas, or the
+// parameters themselves if it is zero or unchanged.
+func WithNReplicas(params *Parameters, nReplicas uint) *Parameters {
+	if nReplicas == 0 || nReplicas == params.NReplicas {
+		return params
+	}
+	updated := *params // by value to avoid changing original params
+	updated.NReplicas = nReplicas
+	return &updated
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicationPolicy_Parameters(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p := NewReplicationPolicy(api.TypeReplicas{api.EnvelopeType: 5})
+	defaults := NewDefaultParameters()
+	envelope := &api.Document{
+		Contents: &api.Document_Envelope{Envelope: api.NewTestEnvelope(rng)},
+	}
+	page := &api.Document{Contents: &api.Document_Page{Page: api.NewTestPage(rng)}}
+
+	params := p.Parameters(envelope, defaults)
+	assert.Equal(t, uint(5), params.NReplicas)
+	assert.Equal(t, defaults.NMaxErrors, params.NMaxErrors)
+	assert.Equal(t, DefaultNReplicas, defaults.NReplicas)
+
+	assert.Equal(t, defaults, p.Parameters(page, defaults))
+}
+
+func TestWithNReplicas(t *testing.T) {
+	params := NewDefaultParameters()
+	assert.True(t, params == WithNReplicas(params, 0))
+	assert.True(t, params == WithNReplicas(params, params.NReplicas))
+
+	updated := WithNReplicas(params, 7)
+	assert.Equal(t, uint(7), updated.NReplicas)
+	assert.Equal(t, DefaultNReplicas, params.NReplicas)
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -28,13 +29,47 @@ const (
 	// DefaultQueryTimeout is the timeout for each query to a peer.
 	DefaultQueryTimeout = 5 * time.Second
 
+	// DefaultNDataShards is the default number of data shards documents are split into in
+	// erasure-coded mode, which is disabled by default.
+	DefaultNDataShards = uint(0)
+
+	// DefaultNParityShards is the default number of parity shards in erasure-coded mode.
+	DefaultNParityShards = uint(2)
+
+	// DefaultMinErasureSize is the default minimum marshaled size of documents stored in
+	// erasure-coded mode.
+	DefaultMinErasureSize = uint(64 * 1024)
+
+	// DefaultSpillover is whether stores spill over to peers outside the closest set by default.
+	DefaultSpillover = false
+
+	// DefaultMaxPeerInFlight is the default maximum number of Store requests in flight to each
+	// peer across all stores sharing a Storer.
+	DefaultMaxPeerInFlight = uint(2)
+
+	// DefaultChallengeLength is the default length of the slice of the stored value peers must
+	// prove they possess, with zero disabling challenges.
+	DefaultChallengeLength = uint(0)
+
+	// DefaultHintedHandoff is whether stores hand off the values of unreachable peers to others
+	// by default.
+	DefaultHintedHandoff = false
+
 	logSearch      = "search"
 	logNReplicas   = "n_replicas"
 	logNMaxErrors  = "n_max_errors"
 	logConcurrency = "concurrency"
 	logTimeout     = "timeout"
+	logNDataShards = "n_data_shards"
+	logNParity     = "n_parity_shards"
 	logNUnqueried  = "n_unqueried"
 	logNResponded  = "n_responded"
+	logNSpilled    = "n_spilled"
+	logNHinted     = "n_hinted"
+	logHinted      = "hinted_handoff"
+	logSpillover   = "spillover"
+	logMaxInFlight = "max_peer_in_flight"
+	logChallenge   = "challenge_length"
 	logErrors      = "errors"
 	logFatalError  = "fatal_error"
 	logResult      = "result"
@@ -59,6 +94,49 @@ type Parameters struct {
 
 	// timeout for queries to individual peers
 	Timeout time.Duration
+
+	// optional store authorization token attached to Store requests sent to peers that require
+	// one
+	AuthToken string
+
+	// optional encoded macaroon attached to Store requests sent to peers that require one
+	Macaroon string
+
+	// NDataShards is the number of data shards documents are split into when stored in
+	// erasure-coded mode instead of as NReplicas full copies. Zero disables erasure coding.
+	NDataShards uint
+
+	// NParityShards is the number of Reed-Solomon parity shards computed from the data shards,
+	// i.e., the number of shards that may be lost with the document still recoverable.
+	NParityShards uint
+
+	// MinErasureSize is the minimum marshaled size of documents stored in erasure-coded mode.
+	// Smaller documents are fully replicated, since sharding them saves little space.
+	MinErasureSize uint
+
+	// Spillover is whether to continue storing to the search's other responding peers, closest
+	// first, when too many of the closest NReplicas + NMaxErrors peers error, until NReplicas
+	// have stored the value.
+	Spillover bool
+
+	// MaxPeerInFlight is the maximum number of Store requests in flight to each peer across all
+	// stores sharing the Storer. Stores prefer the closest peers not at this maximum, so slow
+	// peers get fewer assignments. Zero means unlimited.
+	MaxPeerInFlight uint
+
+	// ChallengeLength is the length of a random slice of the value's serialized bytes each peer
+	// must return the MAC of after storing it before being counted as having stored it, proving
+	// it persisted the value rather than acking and discarding it. Zero disables challenges.
+	ChallengeLength uint
+
+	// HintedHandoff is whether, when a peer is unreachable (e.g., times out), the next peer
+	// queried stores the value with a hint recording the unreachable peer as its intended
+	// holder, so the value is handed off to it when it returns.
+	HintedHandoff bool
+
+	// Placement optionally scores the candidate peers to store to, e.g., to spread replicas
+	// across failure domains. Nil stores to the closest candidates first.
+	Placement PlacementScorer
 }
 
 // NewDefaultParameters creates an instance with default parameters.
@@ -68,15 +146,45 @@ func NewDefaultParameters() *Parameters {
 		NMaxErrors:  DefaultNMaxErrors,
 		Concurrency: DefaultConcurrency,
 		Timeout:     DefaultQueryTimeout,
+
+		NDataShards:    DefaultNDataShards,
+		NParityShards:  DefaultNParityShards,
+		MinErasureSize: DefaultMinErasureSize,
+		Spillover:      DefaultSpillover,
+
+		MaxPeerInFlight: DefaultMaxPeerInFlight,
+		ChallengeLength: DefaultChallengeLength,
+		HintedHandoff:   DefaultHintedHandoff,
+		Placement:       NewZoneScorer(),
 	}
 }
 
+// ErasureCoded returns whether a document of the given marshaled size is stored in erasure-coded
+// mode.
+func (p *Parameters) ErasureCoded(size int) bool {
+	return p.NDataShards > 0 && uint(size) >= p.MinErasureSize
+}
+
 // MarshalLogObject marshals the parameters to to a zap ObjectEncoder (usually a JsonEncoder).
 func (p *Parameters) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	oe.AddUint(logNReplicas, p.NReplicas)
 	oe.AddUint(logNMaxErrors, p.NMaxErrors)
 	oe.AddUint(logConcurrency, p.Concurrency)
 	oe.AddDuration(logTimeout, p.Timeout)
+	if p.NDataShards > 0 {
+		oe.AddUint(logNDataShards, p.NDataShards)
+		oe.AddUint(logNParity, p.NParityShards)
+	}
+	if p.Spillover {
+		oe.AddBool(logSpillover, p.Spillover)
+	}
+	oe.AddUint(logMaxInFlight, p.MaxPeerInFlight)
+	if p.ChallengeLength > 0 {
+		oe.AddUint(logChallenge, p.ChallengeLength)
+	}
+	if p.HintedHandoff {
+		oe.AddBool(logHinted, p.HintedHandoff)
+	}
 	return nil
 }
 
@@ -86,6 +194,17 @@ type Result struct {
 	// Responded contains the peers that have successfully stored the value
 	Responded []peer.Peer
 
+	// Receipts contains the signed receipts from the responded peers that returned one
+	Receipts []*api.SignedStoreReceipt
+
+	// Spilled contains the responded peers outside the closest set from the search, which only
+	// store the value in spillover mode
+	Spilled []peer.Peer
+
+	// Hinted contains the responded peers that stored the value on behalf of an unreachable
+	// peer, with a hint to hand it off to that peer when it returns
+	Hinted []peer.Peer
+
 	// Unqueried is a queue of peers to send store queries to
 	Unqueried []peer.Peer
 
@@ -97,6 +216,24 @@ type Result struct {
 
 	// FatalErr is the fatal error that occurred during the search
 	FatalErr error
+
+	// nCandidates is the number of peers queued to send store queries to initially
+	nCandidates int
+
+	// backups contains the IDs of the queued peers outside the closest set from the search
+	backups map[string]struct{}
+
+	// placed contains the dequeued peers that haven't errored, which placement scores are
+	// relative to
+	placed []peer.Peer
+
+	// unhinted contains the unreachable peers waiting for another peer to store the value on
+	// their behalf
+	unhinted []peer.Peer
+
+	// hints maps the IDs of the queued peers storing the value on behalf of an unreachable peer
+	// to that peer
+	hints map[string]peer.Peer
 }
 
 // NewInitialResult creates a new Result object from the final search result.
@@ -111,11 +248,109 @@ func NewInitialResult(sr *search.Result) *Result {
 	}
 	return &Result{
 		// send store queries to the closest peers from the search
-		Unqueried: unqueried,
-		Responded: make([]peer.Peer, 0, len(unqueried)),
-		Search:    sr,
-		Errors:    make([]error, 0),
+		Unqueried:   unqueried,
+		Responded:   make([]peer.Peer, 0, len(unqueried)),
+		Receipts:    make([]*api.SignedStoreReceipt, 0, len(unqueried)),
+		Search:      sr,
+		Errors:      make([]error, 0),
+		nCandidates: len(unqueried),
+	}
+}
+
+// addBackups queues the other peers that responded during the search, closest first, after the
+// closest peers, so the store can spill over to them.
+func (r *Result) addBackups(key id.ID, sr *search.Result) {
+	closest := make(map[string]struct{}, len(r.Unqueried))
+	for _, p := range r.Unqueried {
+		closest[p.ID().String()] = struct{}{}
 	}
+	backups := make([]peer.Peer, 0, len(sr.Responded))
+	for _, p := range sr.Responded {
+		if _, in := closest[p.ID().String()]; !in {
+			backups = append(backups, p)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return id.CmpDistance(key, backups[i].ID(), backups[j].ID()) < 0
+	})
+	r.backups = make(map[string]struct{}, len(backups))
+	for _, p := range backups {
+		r.backups[p.ID().String()] = struct{}{}
+	}
+	r.Unqueried = append(r.Unqueried, backups...)
+	r.nCandidates = len(r.Unqueried)
+}
+
+// isBackup returns whether the peer is outside the closest set from the search.
+func (r *Result) isBackup(p peer.Peer) bool {
+	_, in := r.backups[p.ID().String()]
+	return in
+}
+
+// unplace removes an errored peer from the placed peers, so it no longer counts against
+// candidates in the same failure domain.
+func (r *Result) unplace(p peer.Peer) {
+	for i, placed := range r.placed {
+		if placed.ID().Cmp(p.ID()) == 0 {
+			r.placed = append(r.placed[:i:i], r.placed[i+1:]...)
+			return
+		}
+	}
+}
+
+// requeue returns the dequeued peer to the front of the unqueried peers, undoing its placement and
+// leaving any hint assigned to it for the next peer dequeued.
+func (r *Result) requeue(p peer.Peer) {
+	r.skip(p)
+	r.Unqueried = append([]peer.Peer{p}, r.Unqueried...)
+}
+
+// skip undoes the dequeued peer's placement, leaving any hint assigned to it for the next peer
+// dequeued.
+func (r *Result) skip(p peer.Peer) {
+	r.unplace(p)
+	key := p.ID().String()
+	if holder, in := r.hints[key]; in {
+		delete(r.hints, key)
+		r.unhinted = append([]peer.Peer{holder}, r.unhinted...)
+	}
+}
+
+// queueHint queues the intended holder of the value for the next peer dequeued to store it on
+// its behalf when the errored peer was unreachable or was itself storing it on the holder's
+// behalf.
+func (r *Result) queueHint(p peer.Peer, err error) {
+	key := p.ID().String()
+	if holder, in := r.hints[key]; in {
+		delete(r.hints, key)
+		r.unhinted = append(r.unhinted, holder)
+		return
+	}
+	if unreachable(err) {
+		r.unhinted = append(r.unhinted, p)
+	}
+}
+
+// assignHint makes the dequeued peer store the value on behalf of the next unreachable peer, if
+// any.
+func (r *Result) assignHint(p peer.Peer) {
+	if len(r.unhinted) == 0 {
+		return
+	}
+	if r.hints == nil {
+		r.hints = make(map[string]peer.Peer)
+	}
+	r.hints[p.ID().String()] = r.unhinted[0]
+	r.unhinted = r.unhinted[1:]
+}
+
+// hintFor returns the unreachable peer the given peer is storing the value on behalf of, or nil
+// if none (including before the store has a result, as when querying a single peer directly).
+func (r *Result) hintFor(p peer.Peer) peer.Peer {
+	if r == nil {
+		return nil
+	}
+	return r.hints[p.ID().String()]
 }
 
 // NewFatalResult creates a new Result object with a fatal error.
@@ -132,6 +367,12 @@ func (r *Result) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	}
 	oe.AddInt(logNUnqueried, len(r.Unqueried))
 	oe.AddInt(logNResponded, len(r.Responded))
+	if len(r.Spilled) > 0 {
+		oe.AddInt(logNSpilled, len(r.Spilled))
+	}
+	if len(r.Hinted) > 0 {
+		oe.AddInt(logNHinted, len(r.Hinted))
+	}
 	errors.MaybePanic(oe.AddArray(logErrors, clogging.ErrArray(r.Errors)))
 	if r.FatalErr != nil {
 		oe.AddString(logFatalError, r.FatalErr.Error())
@@ -144,6 +385,9 @@ type Store struct {
 	// CreateRq creates new Store requests
 	CreateRq func() *api.StoreRequest
 
+	// CreateChallengeRq creates new requests challenging peers to prove they possess the value
+	CreateChallengeRq func(macKey []byte, offset, length uint32) *api.VerifyRequest
+
 	// Result of the store
 	Result *Result
 
@@ -175,10 +419,47 @@ func NewStore(
 	createRq := func() *api.StoreRequest {
 		return client.NewStoreRequest(peerID, orgID, key, value)
 	}
+	createChallengeRq := func(macKey []byte, offset, length uint32) *api.VerifyRequest {
+		return client.NewChallengeRequest(peerID, orgID, key, macKey, offset, length)
+	}
 	return &Store{
-		CreateRq: createRq,
-		Search:   search.NewSearch(peerID, orgID, key, &updatedSearchParams),
-		Params:   storeParams,
+		CreateRq:          createRq,
+		CreateChallengeRq: createChallengeRq,
+		Search:            search.NewSearch(peerID, orgID, key, &updatedSearchParams),
+		Params:            storeParams,
+	}
+}
+
+// ExpireAt sets the time at which the peers storing the value should expire it.
+func (s *Store) ExpireAt(expires time.Time) {
+	createRq := s.CreateRq
+	s.CreateRq = func() *api.StoreRequest {
+		rq := createRq()
+		rq.ExpireTime = expires.Unix()
+		return rq
+	}
+}
+
+// ChargeOrg sets the organization whose quota the peers storing the value charge it to, e.g., the
+// organization whose request is being forwarded.
+func (s *Store) ChargeOrg(orgID id.ID) {
+	createRq := s.CreateRq
+	s.CreateRq = func() *api.StoreRequest {
+		rq := createRq()
+		rq.OriginOrgId = orgID.Bytes()
+		return rq
+	}
+}
+
+// Release releases the store's search and drops its references to peers, receipts, and errors once
+// the caller is done with it. Only the result's FatalErr remains usable after release.
+func (s *Store) Release() {
+	s.Search.Release()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Result != nil {
+		s.Result.Responded, s.Result.Unqueried, s.Result.Errors = nil, nil, nil
+		s.Result.Receipts, s.Result.Spilled, s.Result.backups = nil, nil, nil
 	}
 }
 
@@ -218,11 +499,19 @@ func (s *Store) Exists() bool {
 	return s.Result.Search.Value != nil
 }
 
-// Errored returns whether the store has encountered too many errors when querying the peers.
+// Errored returns whether the store has encountered too many errors when querying the peers. In
+// spillover mode, that is when too few peers remain to store the replicas.
 func (s *Store) Errored() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return len(s.Result.Errors) >= int(s.Params.NMaxErrors) || s.Result.FatalErr != nil
+	if s.Result.FatalErr != nil {
+		return true
+	}
+	if s.Params.Spillover {
+		nErrors := len(s.Result.Errors)
+		return nErrors > 0 && s.Result.nCandidates-nErrors < int(s.Params.NReplicas)
+	}
+	return len(s.Result.Errors) >= int(s.Params.NMaxErrors)
 }
 
 // Exhausted returns whether the store has exhausted all peers to store the value in.
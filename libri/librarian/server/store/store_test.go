@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/drausin/libri/libri/common/ecid"
 	"github.com/drausin/libri/libri/common/id"
@@ -47,6 +48,8 @@ func TestNewDefaultParameters(t *testing.T) {
 	assert.NotZero(t, p.NMaxErrors)
 	assert.NotZero(t, p.Concurrency)
 	assert.NotZero(t, p.Timeout)
+	assert.Equal(t, DefaultSpillover, p.Spillover)
+	assert.NotNil(t, p.Placement)
 }
 
 func TestParameters_MarshalLogObject(t *testing.T) {
@@ -88,6 +91,66 @@ func TestStore_MarshalLogObject_ok(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestStore_ExpireAt(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	s := NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(),
+		NewDefaultParameters())
+	assert.Zero(t, s.CreateRq().ExpireTime)
+
+	expires := time.Now().Add(time.Hour)
+	s.ExpireAt(expires)
+	rq := s.CreateRq()
+	assert.Equal(t, expires.Unix(), rq.ExpireTime)
+	assert.Equal(t, key.Bytes(), rq.Key)
+}
+
+func TestStore_ChargeOrg(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	s := NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(),
+		NewDefaultParameters())
+	assert.Nil(t, s.CreateRq().OriginOrgId)
+
+	originOrgID := id.NewPseudoRandom(rng)
+	s.ChargeOrg(originOrgID)
+	rq := s.CreateRq()
+	assert.Equal(t, originOrgID.Bytes(), rq.OriginOrgId)
+	assert.Equal(t, key.Bytes(), rq.Key)
+}
+
+func TestStore_Release(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID := ecid.NewPseudoRandom(rng)
+	orgID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	s := NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(),
+		NewDefaultParameters())
+
+	// release before store has a result
+	s.Release()
+	assert.Nil(t, s.Search.Result.Responded)
+
+	s = NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(),
+		NewDefaultParameters())
+	s.Result = NewInitialResult(s.Search.Result)
+	s.Result.Responded = []peer.Peer{peer.NewTestPeer(rng, 0)}
+	s.Result.Receipts = []*api.SignedStoreReceipt{{Signature: "some signature"}}
+	s.Result.Errors = []error{errors.New("some Store error")}
+	s.Result.FatalErr = errors.New("some fatal error")
+	s.Release()
+	assert.Nil(t, s.Result.Responded)
+	assert.Nil(t, s.Result.Receipts)
+	assert.Nil(t, s.Result.Unqueried)
+	assert.Nil(t, s.Result.Errors)
+	assert.Nil(t, s.Search.Result.Responded)
+	assert.NotNil(t, s.Result.FatalErr)
+}
+
 func TestStore_Stored(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	peerID := ecid.NewPseudoRandom(rng)
@@ -164,3 +227,51 @@ func TestStore_Errored(t *testing.T) {
 	assert.True(t, s.Errored())
 	assert.True(t, s.Finished())
 }
+
+func TestStore_Errored_spillover(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	key := id.NewPseudoRandom(rng)
+	s := &Store{
+		Params: &Parameters{
+			NReplicas:  3,
+			NMaxErrors: 1,
+			Spillover:  true,
+		},
+		Result: &Result{nCandidates: 6},
+	}
+
+	// errors beyond NMaxErrors are tolerated while enough peers remain
+	s.Result.Errors = append(s.Result.Errors, errors.New("1"), errors.New("2"))
+	assert.False(t, s.Errored())
+
+	// until too few remain to store the replicas
+	s.Result.Errors = append(s.Result.Errors, errors.New("3"), errors.New("4"))
+	assert.True(t, s.Errored())
+
+	// no errors when there are too few candidates to begin with
+	s.Result = &Result{}
+	assert.False(t, s.Errored())
+	s.Result.FatalErr = errors.New("some fatal error")
+	assert.True(t, s.Errored())
+
+	// backups are queued after the closest peers, closest first
+	peers := peer.NewTestPeers(rng, 8)
+	searchResult := ssearch.NewInitialResult(key, ssearch.NewDefaultParameters())
+	for _, p := range peers {
+		searchResult.Responded[p.ID().String()] = p
+	}
+	r := &Result{Unqueried: peers[:2]}
+	r.addBackups(key, searchResult)
+	assert.Len(t, r.Unqueried, 8)
+	assert.Equal(t, 8, r.nCandidates)
+	assert.Equal(t, peers[:2], r.Unqueried[:2])
+	for i := 2; i < len(r.Unqueried); i++ {
+		assert.True(t, r.isBackup(r.Unqueried[i]))
+		if i > 2 {
+			dist1 := key.Distance(r.Unqueried[i-1].ID())
+			dist2 := key.Distance(r.Unqueried[i].ID())
+			assert.True(t, dist1.Cmp(dist2) < 0)
+		}
+	}
+	assert.False(t, r.isBackup(peers[0]))
+}
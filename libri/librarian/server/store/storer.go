@@ -3,14 +3,20 @@ package store
 import (
 	"bytes"
 	"errors"
+	"net"
 	"sync"
 	"time"
 
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
 	"github.com/drausin/libri/libri/librarian/api"
 	"github.com/drausin/libri/libri/librarian/client"
 	"github.com/drausin/libri/libri/librarian/server/comm"
 	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/drausin/libri/libri/librarian/server/search"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const storerStoreRetryTimeout = 25 * time.Millisecond
@@ -18,24 +24,41 @@ const storerStoreRetryTimeout = 25 * time.Millisecond
 var (
 	// ErrTooManyStoreErrors indicates when a store has encountered too many Store request errors.
 	ErrTooManyStoreErrors = errors.New("too many Find errors")
+
+	// ErrStoreCanceled indicates when a store's context was done before it finished.
+	ErrStoreCanceled = errors.New("store canceled")
+
+	// ErrUnexpectedReceipt indicates when a peer's store receipt is from a different peer than
+	// expected.
+	ErrUnexpectedReceipt = errors.New("unexpected store receipt peer")
+
+	// ErrFailedChallenge indicates when a peer does not return the expected MAC of the stored
+	// value's challenged slice, i.e., when it can't prove it possesses the value.
+	ErrFailedChallenge = errors.New("peer failed proof of possession challenge")
 )
 
 // Storer executes store operations.
 type Storer interface {
-	// Store executes a store operation, starting with a given set of seed peers.
-	Store(store *Store, seeds []peer.Peer) error
+	// Store executes a store operation, starting with a given set of seed peers. The operation
+	// is abandoned if the context is done.
+	Store(ctx context.Context, store *Store, seeds []peer.Peer) error
 }
 
 type storer struct {
-	peerSigner    client.Signer
-	orgSigner     client.Signer
-	searcher      search.Searcher
-	storerCreator client.StorerCreator
-	doc           comm.Doctor
-	rec           comm.QueryRecorder
+	peerSigner      client.Signer
+	orgSigner       client.Signer
+	searcher        search.Searcher
+	storerCreator   client.StorerCreator
+	verifierCreator client.VerifierCreator
+	verifier        client.Verifier
+	doc             comm.Doctor
+	rec             comm.QueryRecorder
+	window          *sendWindow
+	tracer          search.Tracer
 }
 
-// NewStorer creates a new Storer instance with given Searcher and StoreQuerier instances.
+// NewStorer creates a new Storer instance with given Searcher and StoreQuerier instances. The
+// VerifierCreator is used for proof of possession challenges.
 func NewStorer(
 	peerSigner client.Signer,
 	orgSigner client.Signer,
@@ -43,14 +66,19 @@ func NewStorer(
 	doc comm.Doctor,
 	searcher search.Searcher,
 	c client.StorerCreator,
+	vc client.VerifierCreator,
 ) Storer {
 	return &storer{
-		peerSigner:    peerSigner,
-		orgSigner:     orgSigner,
-		searcher:      searcher,
-		storerCreator: c,
-		doc:           doc,
-		rec:           rec,
+		peerSigner:      peerSigner,
+		orgSigner:       orgSigner,
+		searcher:        searcher,
+		storerCreator:   c,
+		verifierCreator: vc,
+		verifier:        client.NewVerifier(),
+		doc:             doc,
+		rec:             rec,
+		window:          newSendWindow(),
+		tracer:          search.NewNoOpTracer(),
 	}
 }
 
@@ -59,6 +87,7 @@ func NewDefaultStorer(
 	peerSigner client.Signer,
 	orgSigner client.Signer,
 	rec comm.QueryRecorder,
+	krec comm.KeyspaceRecorder,
 	doc comm.Doctor,
 	clients client.Pool,
 ) Storer {
@@ -67,8 +96,10 @@ func NewDefaultStorer(
 		orgSigner,
 		rec,
 		doc,
-		search.NewDefaultSearcher(peerSigner, orgSigner, rec, doc, clients),
+		search.NewDefaultSearcher(peerSigner, orgSigner, rec, krec, doc,
+			comm.NewNaiveBlacklister(), clients),
 		client.NewStorerCreator(clients),
+		client.NewVerifierCreator(clients),
 	)
 }
 
@@ -76,9 +107,20 @@ type peerResponse struct {
 	peer     peer.Peer
 	response *api.StoreResponse
 	err      error
+
+	// whether the query was abandoned b/c the store was, so the error isn't the peer's fault
+	aborted bool
+
+	// whether the peer wasn't queried b/c its send window was full, so it should be requeued
+	requeued bool
+
+	// whether the query was dropped by the outbound throttle, so the peer was never sent it
+	throttled bool
 }
 
-func (s *storer) Store(store *Store, seeds []peer.Peer) error {
+func (s *storer) Store(ctx context.Context, store *Store, seeds []peer.Peer) error {
+	ctx, span := startStoreSpan(ctx, s.getTracer(), store)
+	defer endStoreSpan(span, store)
 	if len(seeds) < int(store.Params.Concurrency) {
 		// fall back to single worker when we have insufficient seeds (usually only the case for
 		// demo clusters with 3 or so peers)
@@ -89,13 +131,16 @@ func (s *storer) Store(store *Store, seeds []peer.Peer) error {
 	if !store.Search.Finished() {
 		// sometimes the search has been pre-populated (e.g., by a verification) and is already
 		// finished, so only do search if that's not the case
-		if err := s.searcher.Search(store.Search, seeds); err != nil {
+		if err := s.searcher.Search(ctx, store.Search, seeds); err != nil {
 			store.Result = NewFatalResult(err)
 			return err
 		}
 	}
 	store.Search.Mu.Lock()
 	store.Result = NewInitialResult(store.Search.Result)
+	if store.Params.Spillover {
+		store.Result.addBackups(store.Search.Key, store.Search.Result)
+	}
 	store.Search.Mu.Unlock()
 
 	// queue of peers to send Store requests to
@@ -103,7 +148,7 @@ func (s *storer) Store(store *Store, seeds []peer.Peer) error {
 	peerResponses := make(chan *peerResponse, store.Params.NReplicas)
 
 	for c := uint(0); c < store.Params.NReplicas; c++ {
-		sendNextToQuery(toQuery, store)
+		sendNextToQuery(toQuery, store, s.window)
 	}
 
 	var wg1 sync.WaitGroup
@@ -121,11 +166,33 @@ func (s *storer) Store(store *Store, seeds []peer.Peer) error {
 		go func(wg4 *sync.WaitGroup) {
 			defer wg4.Done()
 			for next := range toQuery {
-				response, err := s.query(next, store)
+				if ctx.Err() != nil {
+					// store has been abandoned, so don't bother querying
+					peerResponses <- &peerResponse{peer: next, err: ctx.Err(), aborted: true}
+					continue
+				}
+				maxInFlight := store.Params.MaxPeerInFlight
+				if acquired, freed := s.window.tryAcquire(next.ID(), maxInFlight); !acquired {
+					// rather than wait on the saturated peer, requeue it once any peer has room
+					// so the next query goes to whichever peer that is
+					select {
+					case <-freed:
+						peerResponses <- &peerResponse{peer: next, requeued: true}
+					case <-ctx.Done():
+						peerResponses <- &peerResponse{peer: next, err: ctx.Err(), aborted: true}
+					}
+					continue
+				}
+				queryCtx, span := s.getTracer().Start(ctx, QuerySpanName)
+				response, err := s.query(queryCtx, next, store)
+				endQuerySpan(span, next, err)
+				s.window.release(next.ID())
 				peerResponses <- &peerResponse{
-					peer:     next,
-					response: response,
-					err:      err,
+					peer:      next,
+					response:  response,
+					err:       err,
+					aborted:   err != nil && ctx.Err() != nil,
+					throttled: err == comm.ErrQueryThrottled,
 				}
 			}
 		}(&wg3)
@@ -137,17 +204,65 @@ func (s *storer) Store(store *Store, seeds []peer.Peer) error {
 	return store.Result.FatalErr
 }
 
-func (s *storer) query(next peer.Peer, store *Store) (*api.StoreResponse, error) {
-	lc, err := s.storerCreator.Create(next.Address().String())
+func (s *storer) query(ctx context.Context, next peer.Peer, store *Store) (
+	*api.StoreResponse, error) {
+	var hint *api.PeerAddress
+	store.wrapLock(func() {
+		if holder := store.Result.hintFor(next); holder != nil {
+			hint = holder.ToAPI()
+		}
+	})
+	var rp *api.StoreResponse
+	err := peer.QueryAddresses(next, func(address *net.TCPAddr) error {
+		var err error
+		rp, err = s.queryAddress(ctx, address, store, hint)
+		return err
+	})
+	if err == nil && rp.Receipt != nil {
+		// peers may not return receipts, but those that do must return valid ones
+		err = s.checkReceipt(rp.Receipt, next, store)
+	}
+	if err == nil && store.Params.ChallengeLength > 0 {
+		// only count the peer as storing the value once it proves it possesses it
+		err = s.challenge(ctx, next, store)
+	}
+	return rp, err
+}
+
+// checkReceipt checks that the signed receipt is for the stored key and value from the queried
+// peer.
+func (s *storer) checkReceipt(ssr *api.SignedStoreReceipt, next peer.Peer, store *Store) error {
+	err := client.CheckSignedStoreReceipt(s.verifier, ssr, store.Search.Key.Bytes(),
+		store.CreateRq().Value)
+	if err != nil {
+		return err
+	}
+	pubKey, err := ecid.FromPublicKeyBytes(ssr.Receipt.PeerPubKey)
+	if err != nil {
+		return err
+	}
+	if id.FromPublicKey(pubKey).Cmp(next.ID()) != 0 {
+		return ErrUnexpectedReceipt
+	}
+	return nil
+}
+
+func (s *storer) queryAddress(
+	parent context.Context, address *net.TCPAddr, store *Store, hint *api.PeerAddress,
+) (*api.StoreResponse, error) {
+	lc, err := s.storerCreator.Create(address.String())
 	if err != nil {
 		return nil, err
 	}
 	rq := store.CreateRq()
-	ctx, cancel, err := client.NewSignedTimeoutContext(s.peerSigner, s.orgSigner, rq,
+	rq.Hint = hint
+	ctx, cancel, err := client.NewSignedTimeoutContextFrom(parent, s.peerSigner, s.orgSigner, rq,
 		store.Params.Timeout)
 	if err != nil {
 		return nil, err
 	}
+	ctx = client.AddStoreAuthContext(ctx, store.Params.AuthToken)
+	ctx = client.AddMacaroonContext(ctx, store.Params.Macaroon)
 	retryStoreClient := client.NewRetryStorer(lc, storerStoreRetryTimeout)
 	rp, err := retryStoreClient.Store(ctx, rq)
 	cancel()
@@ -162,6 +277,30 @@ func (s *storer) query(next peer.Peer, store *Store) (*api.StoreResponse, error)
 }
 
 func (s *storer) processAnyReponse(pr *peerResponse, toQuery chan peer.Peer, store *Store) {
+	if pr.requeued {
+		store.wrapLock(func() {
+			store.Result.requeue(pr.peer)
+		})
+		sendNextToQuery(toQuery, store, s.window)
+		return
+	}
+	if pr.throttled {
+		// skip the peer without counting an error against the store
+		store.wrapLock(func() {
+			store.Result.skip(pr.peer)
+		})
+		sendNextToQuery(toQuery, store, s.window)
+		return
+	}
+	if pr.aborted {
+		if !store.Finished() {
+			store.wrapLock(func() {
+				store.Result.FatalErr = ErrStoreCanceled
+			})
+		}
+		drainClose(toQuery)
+		return
+	}
 	errored := false
 	if pr.err != nil {
 		// if we had an issue querying, skip to next peer
@@ -174,10 +313,25 @@ func (s *storer) processAnyReponse(pr *peerResponse, toQuery chan peer.Peer, sto
 			})
 		}
 		errored = true
+		store.wrapLock(func() {
+			store.Result.unplace(pr.peer)
+			if store.Params.HintedHandoff {
+				store.Result.queueHint(pr.peer, pr.err)
+			}
+		})
 		comm.MaybeRecordRpErr(s.rec, pr.peer.ID(), api.Store, pr.err)
 	} else {
 		store.wrapLock(func() {
 			store.Result.Responded = append(store.Result.Responded, pr.peer)
+			if store.Result.isBackup(pr.peer) {
+				store.Result.Spilled = append(store.Result.Spilled, pr.peer)
+			}
+			if store.Result.hintFor(pr.peer) != nil {
+				store.Result.Hinted = append(store.Result.Hinted, pr.peer)
+			}
+			if receipt := pr.response.GetReceipt(); receipt != nil {
+				store.Result.Receipts = append(store.Result.Receipts, receipt)
+			}
 		})
 		s.rec.Record(pr.peer.ID(), api.Store, comm.Response, comm.Success)
 	}
@@ -186,7 +340,7 @@ func (s *storer) processAnyReponse(pr *peerResponse, toQuery chan peer.Peer, sto
 	if errored && !finished {
 		// since we've already queue NReplicas into toQuery above, only queue more
 		// if we get an error
-		sendNextToQuery(toQuery, store)
+		sendNextToQuery(toQuery, store, s.window)
 	} else if finished {
 		maybeClose(toQuery)
 	}
@@ -200,22 +354,76 @@ func maybeClose(toQuery chan peer.Peer) {
 	}
 }
 
-func getNextToQuery(store *Store) peer.Peer {
+// drainClose discards any queued peers and closes the queue if it isn't already closed.
+func drainClose(toQuery chan peer.Peer) {
+	for {
+		select {
+		case _, open := <-toQuery:
+			if !open {
+				return
+			}
+		default:
+			close(toQuery)
+			return
+		}
+	}
+}
+
+// getNextToQuery dequeues the closest unqueried peer with room in its send window, or, when the
+// store has a placement scorer, the one with the lowest placement score. When every unqueried peer
+// is saturated, it dequeues the closest one, which is requeued if it still has no room.
+func getNextToQuery(store *Store, window *sendWindow) peer.Peer {
 	if store.Finished() {
 		return nil
 	}
 	store.mu.Lock()
 	defer store.mu.Unlock()
-	if len(store.Result.Unqueried) == 0 {
+	unqueried := store.Result.Unqueried
+	if len(unqueried) == 0 {
 		return nil
 	}
-	next := store.Result.Unqueried[0]
-	store.Result.Unqueried = store.Result.Unqueried[1:]
+	i, iScore := -1, uint(0)
+	for j, p := range unqueried {
+		if window.saturated(p.ID(), store.Params.MaxPeerInFlight) {
+			continue
+		}
+		score := uint(0)
+		if store.Params.Placement != nil {
+			score = store.Params.Placement.Score(p, store.Result.placed)
+		}
+		if i == -1 || score < iScore {
+			i, iScore = j, score
+		}
+		if iScore == 0 {
+			// no farther peer can score lower
+			break
+		}
+	}
+	if i == -1 {
+		i = 0
+	}
+	next := unqueried[i]
+	store.Result.Unqueried = append(unqueried[:i:i], unqueried[i+1:]...)
+	store.Result.placed = append(store.Result.placed, next)
+	store.Result.assignHint(next)
 	return next
 }
 
-func sendNextToQuery(toQuery chan peer.Peer, store *Store) bool {
-	if next := getNextToQuery(store); next != nil {
+// unreachable returns whether the error querying a peer indicates it's (perhaps temporarily)
+// unreachable, e.g., it timed out, rather than that it rejected the Store.
+func unreachable(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
+func sendNextToQuery(toQuery chan peer.Peer, store *Store, window *sendWindow) bool {
+	if next := getNextToQuery(store, window); next != nil {
 		toQuery <- next
 	}
 	return store.Finished()
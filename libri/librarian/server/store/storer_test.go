@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/drausin/libri/libri/common/ecid"
 	cid "github.com/drausin/libri/libri/common/id"
@@ -16,6 +18,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewDefaultStorer(t *testing.T) {
@@ -23,6 +27,7 @@ func TestNewDefaultStorer(t *testing.T) {
 		&client.TestNoOpSigner{},
 		&client.TestNoOpSigner{},
 		&fixedRecorder{},
+		comm.NewNoOpKeyspaceRecorder(),
 		comm.NewNaiveDoctor(),
 		nil,
 	)
@@ -30,6 +35,8 @@ func TestNewDefaultStorer(t *testing.T) {
 	assert.NotNil(t, s.(*storer).orgSigner)
 	assert.NotNil(t, s.(*storer).searcher)
 	assert.NotNil(t, s.(*storer).storerCreator)
+	assert.NotNil(t, s.(*storer).verifierCreator)
+	assert.NotNil(t, s.(*storer).verifier)
 }
 
 func TestStorer_Store_ok(t *testing.T) {
@@ -52,6 +59,7 @@ func TestStorer_Store_ok(t *testing.T) {
 			orgSigner:     &client.TestNoOpSigner{},
 			rec:           rec,
 			doc:           comm.NewNaiveDoctor(),
+			window:        newSendWindow(),
 		}
 
 		for _, concurrency := range concurrencies {
@@ -78,7 +86,7 @@ func TestStorer_Store_ok(t *testing.T) {
 			}
 
 			// do the store!
-			err := storer.Store(store, seeds)
+			err := storer.Store(context.Background(), store, seeds)
 
 			// checks
 			assert.Nil(t, err, info)
@@ -96,6 +104,45 @@ func TestStorer_Store_ok(t *testing.T) {
 	}
 }
 
+func TestStorer_Store_saturated(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peers, peersMap, addressFinders, selfPeerIdxs, peerID := ssearch.NewTestPeers(rng, 8)
+	orgID := ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	rec := &fixedRecorder{}
+	window := newSendWindow()
+	storer := &storer{
+		searcher:      ssearch.NewTestSearcher(peersMap, addressFinders, rec),
+		storerCreator: &fixedStorerCreator{},
+		peerSigner:    &client.TestNoOpSigner{},
+		orgSigner:     &client.TestNoOpSigner{},
+		rec:           rec,
+		doc:           comm.NewNaiveDoctor(),
+		window:        window,
+	}
+	storeParams := NewDefaultParameters()
+	storeParams.MaxPeerInFlight = 1
+	store := NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(), storeParams)
+
+	// every peer starts saturated, with room freed a peer at a time
+	for _, p := range peers {
+		acquired, _ := window.tryAcquire(p.ID(), 1)
+		assert.True(t, acquired)
+	}
+	go func() {
+		for _, p := range peers {
+			time.Sleep(5 * time.Millisecond)
+			window.release(p.ID())
+		}
+	}()
+
+	err := storer.Store(context.Background(), store, ssearch.NewTestSeeds(peers, selfPeerIdxs))
+	assert.Nil(t, err)
+	assert.True(t, store.Stored())
+	assert.Equal(t, int(storeParams.NReplicas), len(store.Result.Responded))
+	assert.Len(t, store.Result.placed, len(store.Result.Responded))
+}
+
 func TestStorer_Store_queryErr(t *testing.T) {
 	rec := &fixedRecorder{}
 	storerImpl, store, selfPeerIdxs, peers, _ := newTestStore(rec)
@@ -107,7 +154,7 @@ func TestStorer_Store_queryErr(t *testing.T) {
 	}
 
 	// do the search!
-	err := storerImpl.Store(store, seeds)
+	err := storerImpl.Store(context.Background(), store, seeds)
 
 	// checks
 	assert.NotNil(t, err)
@@ -123,6 +170,170 @@ func TestStorer_Store_queryErr(t *testing.T) {
 	assert.True(t, rec.nErrors > 0)
 }
 
+func TestStorer_Store_spillover(t *testing.T) {
+	for _, spillover := range []bool{false, true} {
+		rec := &fixedRecorder{}
+		storerImpl, store, selfPeerIdxs, peers, _ := newTestStore(rec)
+		seeds := ssearch.NewTestSeeds(peers, selfPeerIdxs)
+		store.Params.Spillover = spillover
+
+		// more of the closest peers error than NMaxErrors tolerates
+		nErrs := int(store.Params.NMaxErrors) + 1
+		storerImpl.(*storer).storerCreator = &firstErrStorerCreator{
+			nErrs:   nErrs,
+			errored: make(map[string]struct{}),
+		}
+
+		// the search only sometimes hears from a peer past the closest set before finishing, so
+		// make sure there is one to spill over to
+		err := storerImpl.(*storer).searcher.Search(context.Background(), store.Search, seeds)
+		assert.Nil(t, err)
+		backup := store.Search.Result.Unqueried.Peers()[0]
+		store.Search.Result.Responded[backup.ID().String()] = backup
+
+		err = storerImpl.Store(context.Background(), store, seeds)
+		info := fmt.Sprintf("spillover: %v", spillover)
+		if !spillover {
+			assert.Equal(t, ErrTooManyStoreErrors, err, info)
+			assert.False(t, store.Stored(), info)
+			assert.Empty(t, store.Result.Spilled, info)
+			continue
+		}
+		assert.Nil(t, err, info)
+		assert.True(t, store.Stored(), info)
+		assert.False(t, store.Errored(), info)
+		assert.Len(t, store.Result.Errors, nErrs, info)
+
+		// the closest peers that didn't error store replicas before the backups
+		nClosest := int(store.Params.NReplicas+store.Params.NMaxErrors) - nErrs
+		assert.Len(t, store.Result.Spilled, int(store.Params.NReplicas)-nClosest, info)
+		for _, p := range store.Result.Spilled {
+			assert.True(t, store.Result.isBackup(p), info)
+		}
+	}
+}
+
+func TestStorer_Store_hintedHandoff(t *testing.T) {
+	for _, hintedHandoff := range []bool{false, true} {
+		rec := &fixedRecorder{}
+		storerImpl, store, selfPeerIdxs, peers, _ := newTestStore(rec)
+		seeds := ssearch.NewTestSeeds(peers, selfPeerIdxs)
+		store.Params.HintedHandoff = hintedHandoff
+
+		// some of the closest peers are unreachable
+		nErrs := int(store.Params.NMaxErrors) - 1
+		hs := &hintStorer{}
+		storerImpl.(*storer).storerCreator = &firstErrStorerCreator{
+			nErrs:   nErrs,
+			errored: make(map[string]struct{}),
+			err:     status.Error(codes.Unavailable, "some unavailable error"),
+			storer:  hs,
+		}
+
+		err := storerImpl.Store(context.Background(), store, seeds)
+		info := fmt.Sprintf("hinted handoff: %v", hintedHandoff)
+		assert.Nil(t, err, info)
+		assert.True(t, store.Stored(), info)
+		if !hintedHandoff {
+			assert.Empty(t, store.Result.Hinted, info)
+			assert.Empty(t, hs.hints, info)
+			continue
+		}
+
+		// each unreachable peer has another store the value on its behalf
+		assert.Len(t, store.Result.Hinted, nErrs, info)
+		assert.Len(t, hs.hints, nErrs, info)
+		for _, hint := range hs.hints {
+			assert.NotNil(t, hint.PeerId, info)
+		}
+	}
+}
+
+func TestResult_queueHint(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ps := peer.NewTestPeers(rng, 3)
+	unavailableErr := status.Error(codes.Unavailable, "some unavailable error")
+	r := &Result{}
+
+	// peers that reject the store aren't hinted
+	r.queueHint(ps[0], errors.New("some Store error"))
+	assert.Empty(t, r.unhinted)
+
+	// unreachable peers are hinted to the next peer dequeued
+	r.queueHint(ps[0], unavailableErr)
+	r.assignHint(ps[1])
+	assert.Empty(t, r.unhinted)
+	assert.Equal(t, ps[0], r.hintFor(ps[1]))
+	r.assignHint(ps[2])
+	assert.Nil(t, r.hintFor(ps[2]))
+
+	// substitute errors re-queue the original peer for the next one
+	r.queueHint(ps[1], errors.New("some Store error"))
+	assert.Nil(t, r.hintFor(ps[1]))
+	assert.Equal(t, []peer.Peer{ps[0]}, r.unhinted)
+}
+
+func TestResult_requeue(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ps := peer.NewTestPeers(rng, 4)
+	r := &Result{Unqueried: []peer.Peer{ps[1], ps[2]}, unhinted: []peer.Peer{ps[3]}}
+	r.placed = []peer.Peer{ps[0]}
+	r.assignHint(ps[0])
+	assert.Equal(t, ps[3], r.hintFor(ps[0]))
+
+	// requeued peer is unplaced and its hint left for the next peer dequeued
+	r.requeue(ps[0])
+	assert.Equal(t, []peer.Peer{ps[0], ps[1], ps[2]}, r.Unqueried)
+	assert.Empty(t, r.placed)
+	assert.Nil(t, r.hintFor(ps[0]))
+	assert.Equal(t, []peer.Peer{ps[3]}, r.unhinted)
+}
+
+func TestResult_skip(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ps := peer.NewTestPeers(rng, 4)
+	r := &Result{Unqueried: []peer.Peer{ps[1], ps[2]}, unhinted: []peer.Peer{ps[3]}}
+	r.placed = []peer.Peer{ps[0]}
+	r.assignHint(ps[0])
+
+	// skipped peer is unplaced and not requeued, with its hint left for the next peer dequeued
+	r.skip(ps[0])
+	assert.Equal(t, []peer.Peer{ps[1], ps[2]}, r.Unqueried)
+	assert.Empty(t, r.placed)
+	assert.Nil(t, r.hintFor(ps[0]))
+	assert.Equal(t, []peer.Peer{ps[3]}, r.unhinted)
+}
+
+func TestUnreachable(t *testing.T) {
+	assert.True(t, unreachable(context.DeadlineExceeded))
+	assert.True(t, unreachable(status.Error(codes.DeadlineExceeded, "")))
+	assert.True(t, unreachable(status.Error(codes.Unavailable, "")))
+	assert.False(t, unreachable(status.Error(codes.InvalidArgument, "")))
+	assert.False(t, unreachable(errors.New("some Store error")))
+	assert.False(t, unreachable(nil))
+}
+
+func TestStorer_Store_canceled(t *testing.T) {
+	rec := &fixedRecorder{}
+	storerImpl, store, selfPeerIdxs, peers, _ := newTestStore(rec)
+	seeds := ssearch.NewTestSeeds(peers, selfPeerIdxs)
+
+	// do the search beforehand, so only the store queries see the canceled context
+	err := storerImpl.(*storer).searcher.Search(context.Background(), store.Search, seeds)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = storerImpl.Store(ctx, store, seeds)
+
+	assert.Equal(t, ErrStoreCanceled, err)
+	assert.True(t, store.Finished())
+	assert.False(t, store.Stored())
+	assert.Equal(t, 0, len(store.Result.Responded))
+	assert.Equal(t, 0, len(store.Result.Errors))
+	assert.Equal(t, 0, rec.nErrors)
+}
+
 func TestStorer_Store_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	s := &storer{
@@ -137,7 +348,7 @@ func TestStorer_Store_err(t *testing.T) {
 		Params: NewDefaultParameters(),
 		Search: ssearch.NewSearch(peerID, orgID, key, ssearch.NewDefaultParameters()),
 	}
-	assert.NotNil(t, s.Store(store, []peer.Peer{}))
+	assert.NotNil(t, s.Store(context.Background(), store, []peer.Peer{}))
 }
 
 func TestStorer_query_err(t *testing.T) {
@@ -184,12 +395,51 @@ func TestStorer_query_err(t *testing.T) {
 	next := peer.NewTestPeer(rng, 0)
 	for i, c := range cases {
 		info := fmt.Sprintf("case %d", i)
-		rp1, err := c.query(next, store)
+		rp1, err := c.query(context.Background(), next, store)
 		assert.Nil(t, rp1, info)
 		assert.NotNil(t, err, info)
 	}
 }
 
+func TestStorer_checkReceipt(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	next := peer.NewStub(peerID.ID(), "peer")
+	value, key := api.NewTestDocument(rng)
+	store := NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(),
+		NewDefaultParameters())
+	s := &storer{verifier: client.NewVerifier()}
+	signer := client.NewECDSASigner(peerID.Key())
+
+	ssr, err := client.NewSignedStoreReceipt(signer, peerID.PublicKeyBytes(), key.Bytes(),
+		value, time.Now())
+	assert.Nil(t, err)
+	assert.Nil(t, s.checkReceipt(ssr, next, store))
+
+	// receipt for a different peer
+	other := peer.NewStub(cid.NewPseudoRandom(rng), "other")
+	assert.Equal(t, ErrUnexpectedReceipt, s.checkReceipt(ssr, other, store))
+
+	// receipt for a different key
+	ssr, err = client.NewSignedStoreReceipt(signer, peerID.PublicKeyBytes(),
+		cid.NewPseudoRandom(rng).Bytes(), value, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, client.ErrUnexpectedStoreReceipt, s.checkReceipt(ssr, next, store))
+
+	// receipt for a different value
+	otherValue, _ := api.NewTestDocument(rng)
+	ssr, err = client.NewSignedStoreReceipt(signer, peerID.PublicKeyBytes(), key.Bytes(),
+		otherValue, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, client.ErrUnexpectedStoreReceipt, s.checkReceipt(ssr, next, store))
+
+	// receipt not signed by the peer
+	ssr, err = client.NewSignedStoreReceipt(client.NewECDSASigner(orgID.Key()),
+		peerID.PublicKeyBytes(), key.Bytes(), value, time.Now())
+	assert.Nil(t, err)
+	assert.NotNil(t, s.checkReceipt(ssr, next, store))
+}
+
 func newTestStore(rec comm.QueryRecorder) (Storer, *Store, []int, []peer.Peer, cid.ID) {
 	n := 32
 	rng := rand.New(rand.NewSource(int64(n)))
@@ -202,7 +452,9 @@ func newTestStore(rec comm.QueryRecorder) (Storer, *Store, []int, []peer.Peer, c
 		searcher:      ssearch.NewTestSearcher(peersMap, addressFinders, rec),
 		storerCreator: &fixedStorerCreator{},
 		peerSigner:    &client.TestNoOpSigner{},
+		orgSigner:     &client.TestNoOpSigner{},
 		rec:           rec,
+		window:        newSendWindow(),
 	}
 
 	concurrency := uint(1)
@@ -223,7 +475,9 @@ func newTestStore(rec comm.QueryRecorder) (Storer, *Store, []int, []peer.Peer, c
 
 type errSearcher struct{}
 
-func (es *errSearcher) Search(search *ssearch.Search, seeds []peer.Peer) error {
+func (es *errSearcher) Search(
+	ctx context.Context, search *ssearch.Search, seeds []peer.Peer,
+) error {
 	return errors.New("some search error")
 }
 
@@ -242,6 +496,48 @@ func (c *fixedStorerCreator) Create(address string) (api.Storer, error) {
 	return &fixedStorer{}, nil
 }
 
+// firstErrStorerCreator errors when creating Storers for the first nErrs distinct addresses.
+type firstErrStorerCreator struct {
+	nErrs   int
+	errored map[string]struct{}
+	err     error
+	storer  api.Storer
+	mu      sync.Mutex
+}
+
+func (c *firstErrStorerCreator) Create(address string) (api.Storer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, in := c.errored[address]; in || len(c.errored) < c.nErrs {
+		c.errored[address] = struct{}{}
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, errors.New("some Create error")
+	}
+	if c.storer != nil {
+		return c.storer, nil
+	}
+	return &fixedStorer{}, nil
+}
+
+// hintStorer records the hints of the Store requests it receives.
+type hintStorer struct {
+	fixedStorer
+	hints []*api.PeerAddress
+	mu    sync.Mutex
+}
+
+func (h *hintStorer) Store(ctx context.Context, rq *api.StoreRequest, opts ...grpc.CallOption) (
+	*api.StoreResponse, error) {
+	if rq.Hint != nil {
+		h.mu.Lock()
+		h.hints = append(h.hints, rq.Hint)
+		h.mu.Unlock()
+	}
+	return h.fixedStorer.Store(ctx, rq, opts...)
+}
+
 type fixedStorer struct {
 	requestID []byte
 	err       error
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"golang.org/x/net/context"
+)
+
+const (
+	// StoreSpanName is the name of the span tracing a whole store, including its search.
+	StoreSpanName = "store"
+
+	// QuerySpanName is the name of the span tracing a store's query to a single peer.
+	QuerySpanName = "store.query"
+
+	// span attribute keys
+	attrKey        = "store.key"
+	attrNReplicas  = "store.n_replicas"
+	attrNResponded = "store.n_responded"
+	attrNErrors    = "store.n_errors"
+	attrPeerID     = "peer.id"
+)
+
+// WithTracer sets the Tracer tracing the stores of a Storer created by NewStorer or
+// NewDefaultStorer, which otherwise aren't traced. Each store has a span (named StoreSpanName),
+// with its search's span and a span per peer query (named QuerySpanName) as children.
+func WithTracer(s Storer, t search.Tracer) Storer {
+	s.(*storer).tracer = t
+	return s
+}
+
+// getTracer returns the storer's tracer, or a no-op one if it has none.
+func (s *storer) getTracer() search.Tracer {
+	if s.tracer == nil {
+		return search.NewNoOpTracer()
+	}
+	return s.tracer
+}
+
+// startStoreSpan starts the span tracing a whole store.
+func startStoreSpan(
+	ctx context.Context, t search.Tracer, store *Store,
+) (context.Context, search.Span) {
+	ctx, span := t.Start(ctx, StoreSpanName)
+	span.SetAttributes(
+		search.Attribute{Key: attrKey, Value: id.Hex(store.Search.Key.Bytes())},
+		search.Attribute{Key: attrNReplicas, Value: store.Params.NReplicas},
+	)
+	return ctx, span
+}
+
+// endStoreSpan records the store's outcome on its span and ends it.
+func endStoreSpan(span search.Span, store *Store) {
+	store.wrapLock(func() {
+		if store.Result == nil {
+			return
+		}
+		span.SetAttributes(
+			search.Attribute{Key: attrNResponded, Value: len(store.Result.Responded)},
+			search.Attribute{Key: attrNErrors, Value: len(store.Result.Errors)},
+		)
+		if store.Result.FatalErr != nil {
+			span.RecordError(store.Result.FatalErr)
+		}
+	})
+	span.End()
+}
+
+// endQuerySpan records a peer query's outcome on its span and ends it.
+func endQuerySpan(span search.Span, p peer.Peer, err error) {
+	span.SetAttributes(search.Attribute{Key: attrPeerID, Value: id.Hex(p.ID().Bytes())})
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	ssearch "github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestStorer_Store_tracer(t *testing.T) {
+	rec := &fixedRecorder{}
+	storerImpl, store, selfPeerIdxs, peers, key := newTestStore(rec)
+	tracer := &recordingTracer{}
+	storerImpl = WithTracer(storerImpl, tracer)
+	seeds := ssearch.NewTestSeeds(peers, selfPeerIdxs)
+
+	err := storerImpl.Store(context.Background(), store, seeds)
+	assert.Nil(t, err)
+	assert.True(t, store.Stored())
+
+	// one store span with a child span per queried peer
+	storeSpans, querySpans := tracer.spansNamed(StoreSpanName), tracer.spansNamed(QuerySpanName)
+	assert.Len(t, storeSpans, 1)
+	assert.True(t, len(querySpans) >= len(store.Result.Responded))
+	storeSpan := storeSpans[0]
+	assert.True(t, storeSpan.ended)
+	assert.Equal(t, id.Hex(key.Bytes()), storeSpan.attrs[attrKey])
+	assert.Equal(t, len(store.Result.Responded), storeSpan.attrs[attrNResponded])
+	assert.Nil(t, storeSpan.err)
+	for _, qs := range querySpans {
+		assert.True(t, qs.ended)
+		assert.Equal(t, storeSpan, qs.parent)
+		assert.Contains(t, qs.attrs, attrPeerID)
+	}
+}
+
+func TestEndStoreSpan(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	store := newTestAsyncStore(rng)
+
+	// store without result, e.g., because its search failed
+	span := &recordingSpan{attrs: make(map[string]interface{})}
+	endStoreSpan(span, store)
+	assert.True(t, span.ended)
+	assert.NotContains(t, span.attrs, attrNResponded)
+
+	// fatal error recorded
+	store.Result = NewFatalResult(ErrStoreCanceled)
+	span = &recordingSpan{attrs: make(map[string]interface{})}
+	endStoreSpan(span, store)
+	assert.Equal(t, 0, span.attrs[attrNResponded])
+	assert.Equal(t, ErrStoreCanceled, span.err)
+}
+
+func TestEndQuerySpan(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p := peer.NewTestPeer(rng, 0)
+
+	span := &recordingSpan{attrs: make(map[string]interface{})}
+	endQuerySpan(span, p, nil)
+	assert.True(t, span.ended)
+	assert.Equal(t, id.Hex(p.ID().Bytes()), span.attrs[attrPeerID])
+	assert.Nil(t, span.err)
+
+	span = &recordingSpan{attrs: make(map[string]interface{})}
+	endQuerySpan(span, p, errors.New("some Store error"))
+	assert.NotNil(t, span.err)
+}
+
+type spanCtxKey struct{}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+	mu    sync.Mutex
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, ssearch.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordingSpan{name: name, attrs: make(map[string]interface{})}
+	span.parent, _ = ctx.Value(spanCtxKey{}).(*recordingSpan)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanCtxKey{}, span), span
+}
+
+func (t *recordingTracer) spansNamed(name string) []*recordingSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	named := make([]*recordingSpan, 0)
+	for _, s := range t.spans {
+		if s.name == name {
+			named = append(named, s)
+		}
+	}
+	return named
+}
+
+type recordingSpan struct {
+	name   string
+	parent *recordingSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+	mu     sync.Mutex
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...ssearch.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordingSpan) End() {
+	s.ended = true
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+// sendWindow tracks the Store requests in flight to each peer across all the stores sharing a
+// Storer, so a slow peer degrades to fewer assignments rather than tying up the workers of every
+// store waiting on it.
+type sendWindow struct {
+	inFlight map[string]uint
+	freed    chan struct{}
+	mu       sync.Mutex
+}
+
+func newSendWindow() *sendWindow {
+	return &sendWindow{
+		inFlight: make(map[string]uint),
+		freed:    make(chan struct{}),
+	}
+}
+
+// saturated returns whether the peer already has the maximum number of requests in flight, with
+// a zero maximum meaning unlimited.
+func (w *sendWindow) saturated(peerID id.ID, max uint) bool {
+	if max == 0 {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.inFlight[peerID.String()] >= max
+}
+
+// tryAcquire adds a request in flight to the peer if it has room, returning whether it did. When
+// it doesn't, the returned channel is closed once any peer's request in flight is released, so
+// the caller can wait to retry without being tied to the saturated peer.
+func (w *sendWindow) tryAcquire(peerID id.ID, max uint) (bool, <-chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := peerID.String()
+	if max == 0 || w.inFlight[key] < max {
+		w.inFlight[key]++
+		return true, nil
+	}
+	return false, w.freed
+}
+
+// release removes a request in flight to the peer, waking any callers waiting for room.
+func (w *sendWindow) release(peerID id.ID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := peerID.String()
+	if w.inFlight[key] <= 1 {
+		delete(w.inFlight, key)
+	} else {
+		w.inFlight[key]--
+	}
+	close(w.freed)
+	w.freed = make(chan struct{})
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	cid "github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/server/peer"
+	ssearch "github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendWindow_tryAcquireRelease(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	w := newSendWindow()
+	peerID1, peerID2 := cid.NewPseudoRandom(rng), cid.NewPseudoRandom(rng)
+
+	assert.False(t, w.saturated(peerID1, 2))
+	acquired, _ := w.tryAcquire(peerID1, 2)
+	assert.True(t, acquired)
+	assert.False(t, w.saturated(peerID1, 2))
+	acquired, _ = w.tryAcquire(peerID1, 2)
+	assert.True(t, acquired)
+	assert.True(t, w.saturated(peerID1, 2))
+	assert.False(t, w.saturated(peerID2, 2))
+
+	// zero max means unlimited
+	assert.False(t, w.saturated(peerID1, 0))
+	acquired, _ = w.tryAcquire(peerID1, 0)
+	assert.True(t, acquired)
+
+	w.release(peerID1)
+	w.release(peerID1)
+	assert.False(t, w.saturated(peerID1, 2))
+	w.release(peerID1)
+	assert.Len(t, w.inFlight, 0)
+}
+
+func TestSendWindow_tryAcquire_saturated(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	w := newSendWindow()
+	peerID1, peerID2 := cid.NewPseudoRandom(rng), cid.NewPseudoRandom(rng)
+	acquired, _ := w.tryAcquire(peerID1, 1)
+	assert.True(t, acquired)
+	acquired, _ = w.tryAcquire(peerID2, 1)
+	assert.True(t, acquired)
+
+	// doesn't wait for the saturated peer
+	acquired, freed := w.tryAcquire(peerID1, 1)
+	assert.False(t, acquired)
+	select {
+	case <-freed:
+		assert.Fail(t, "freed before any release")
+	default:
+	}
+
+	// freed once any peer has room
+	w.release(peerID2)
+	select {
+	case <-freed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "not freed after release")
+	}
+	assert.True(t, w.saturated(peerID1, 1))
+}
+
+func TestGetNextToQuery(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	peers := peer.NewTestPeers(rng, 3)
+	w := newSendWindow()
+	s := newTestWindowStore(rng, peers)
+
+	// skips saturated closest peer
+	acquired, _ := w.tryAcquire(peers[0].ID(), 1)
+	assert.True(t, acquired)
+	assert.Equal(t, peers[1], getNextToQuery(s, w))
+	assert.Equal(t, []peer.Peer{peers[0], peers[2]}, s.Result.Unqueried)
+
+	// falls back to closest peer when all are saturated
+	acquired, _ = w.tryAcquire(peers[2].ID(), 1)
+	assert.True(t, acquired)
+	assert.Equal(t, peers[0], getNextToQuery(s, w))
+	assert.Equal(t, peers[2], getNextToQuery(s, w))
+	assert.Nil(t, getNextToQuery(s, w))
+}
+
+func newTestWindowStore(rng *rand.Rand, unqueried []peer.Peer) *Store {
+	peerID, orgID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	value, key := api.NewTestDocument(rng)
+	params := NewDefaultParameters()
+	params.MaxPeerInFlight = 1
+	s := NewStore(peerID, orgID, key, value, ssearch.NewDefaultParameters(), params)
+	s.Result = NewInitialResult(ssearch.NewInitialResult(key, s.Search.Params))
+	s.Result.Unqueried = unqueried
+	return s
+}
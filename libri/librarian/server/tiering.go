@@ -0,0 +1,192 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultTieringDemoteAfter is the default duration after which documents not accessed are
+	// demoted to the cold tier.
+	DefaultTieringDemoteAfter = 7 * 24 * time.Hour
+
+	// DefaultTieringInterval is the default interval between runs of the tiering job.
+	DefaultTieringInterval = 10 * time.Minute
+
+	// DefaultTieringMaxDemotions is the default maximum number of documents demoted per run of
+	// the tiering job.
+	DefaultTieringMaxDemotions = uint(1024)
+
+	tierLabel     = "tier"
+	fromTierLabel = "from_tier"
+	toTierLabel   = "to_tier"
+)
+
+var (
+	errTieringDisabled = errors.New("storage tiering disabled")
+	errInvalidPinKey   = errors.New("pinned document key must be 32 bytes")
+)
+
+// TieringParameters define how stored documents are split between a hot DB on a fast volume and
+// a cold DB on a slower (and cheaper) one.
+type TieringParameters struct {
+	// ColdDbDir is the local directory of the cold tier DB, typically on a slower volume than
+	// the main DB, which holds the hot tier. Empty disables tiering, keeping all documents in the
+	// main DB.
+	ColdDbDir string
+
+	// DemoteAfter is the duration after which documents not accessed (i.e., stored or loaded)
+	// are demoted to the cold tier. Documents loaded from the cold tier are promoted back.
+	DemoteAfter time.Duration
+
+	// Interval is the interval between runs of the tiering job demoting documents.
+	Interval time.Duration
+
+	// MaxDemotions is the maximum number of documents demoted per run of the tiering job.
+	MaxDemotions uint
+}
+
+// NewDefaultTieringParameters returns a *TieringParameters object with default values, which
+// disable tiering.
+func NewDefaultTieringParameters() *TieringParameters {
+	return &TieringParameters{
+		DemoteAfter:  DefaultTieringDemoteAfter,
+		Interval:     DefaultTieringInterval,
+		MaxDemotions: DefaultTieringMaxDemotions,
+	}
+}
+
+// Enabled returns whether documents are tiered.
+func (p *TieringParameters) Enabled() bool {
+	return p.ColdDbDir != ""
+}
+
+// demoteDocuments periodically demotes documents not accessed recently to the cold tier until the
+// server stops.
+func (l *Librarian) demoteDocuments() {
+	if l.tiered == nil {
+		return
+	}
+	ticker := time.NewTicker(l.config.Tiering.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.demoteOnce()
+		}
+	}
+}
+
+func (l *Librarian) demoteOnce() {
+	notAccessedSince := time.Now().Add(-l.config.Tiering.DemoteAfter)
+	nDemoted, err := l.tiered.Demote(notAccessedSince, l.config.Tiering.MaxDemotions)
+	if err != nil {
+		l.logger.Error("error demoting documents to cold tier", zap.Error(err),
+			zap.Uint(logNDemoted, nDemoted))
+		return
+	}
+	l.logger.Debug("demoted documents to cold tier", zap.Uint(logNDemoted, nDemoted))
+}
+
+// Pin keeps a document in the hot tier for requests signed by the admin key.
+func (l *Librarian) Pin(ctx context.Context, rq *api.PinRequest) (*api.PinResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received pin request")
+
+	if err := l.checkPinRequest(lg, rq.Metadata, rq.Key); err != nil {
+		return nil, err
+	}
+	if err := l.tiered.Pin(id.FromBytes(rq.Key)); err != nil {
+		return nil, logReturnStorageErr(lg, "error promoting pinned document", err)
+	}
+	lg.Info("pinned document", zap.String(logKey, id.Hex(rq.Key)))
+	return &api.PinResponse{Metadata: l.NewResponseMetadata(rq.Metadata)}, nil
+}
+
+// Unpin lets a pinned document be demoted to the cold tier for requests signed by the admin key.
+func (l *Librarian) Unpin(ctx context.Context, rq *api.UnpinRequest) (*api.UnpinResponse, error) {
+	lg := l.logger.With(rqMetadataFields(rq.Metadata)...)
+	lg.Debug("received unpin request")
+
+	if err := l.checkPinRequest(lg, rq.Metadata, rq.Key); err != nil {
+		return nil, err
+	}
+	l.tiered.Unpin(id.FromBytes(rq.Key))
+	lg.Info("unpinned document", zap.String(logKey, id.Hex(rq.Key)))
+	return &api.UnpinResponse{Metadata: l.NewResponseMetadata(rq.Metadata)}, nil
+}
+
+func (l *Librarian) checkPinRequest(lg *zap.Logger, meta *api.RequestMetadata, key []byte) error {
+	if _, err := l.checkRequest(meta); err != nil {
+		return logReturnInvalidRqErr(lg, err)
+	}
+	if err := l.checkAdmin(meta); err != nil {
+		return logReturnNotAllowedErr(lg, err)
+	}
+	if l.tiered == nil {
+		return logReturnNotAllowedErr(lg,
+			status.Error(codes.FailedPrecondition, errTieringDisabled.Error()))
+	}
+	if len(key) != id.Length {
+		return logReturnInvalidRqErr(lg, errInvalidPinKey)
+	}
+	return nil
+}
+
+// tieringMetrics reports documents loaded from and migrated between storage tiers.
+type tieringMetrics struct {
+	loads      *prom.CounterVec
+	migrations *prom.CounterVec
+}
+
+func newTieringMetrics() *tieringMetrics {
+	return &tieringMetrics{
+		loads: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: "libri",
+				Subsystem: "storage",
+				Name:      "tier_loads_total",
+				Help:      "Number of documents loaded, by storage tier.",
+			},
+			[]string{tierLabel},
+		),
+		migrations: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: "libri",
+				Subsystem: "storage",
+				Name:      "tier_migrations_total",
+				Help:      "Number of documents migrated between storage tiers.",
+			},
+			[]string{fromTierLabel, toTierLabel},
+		),
+	}
+}
+
+func (tm *tieringMetrics) Loaded(tier storage.Tier) {
+	tm.loads.WithLabelValues(tier.String()).Inc()
+}
+
+func (tm *tieringMetrics) Migrated(from, to storage.Tier) {
+	tm.migrations.WithLabelValues(from.String(), to.String()).Inc()
+}
+
+func (tm *tieringMetrics) register() {
+	prom.MustRegister(tm.loads)
+	prom.MustRegister(tm.migrations)
+}
+
+func (tm *tieringMetrics) unregister() {
+	_ = prom.Unregister(tm.loads)
+	_ = prom.Unregister(tm.migrations)
+}
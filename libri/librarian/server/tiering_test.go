@@ -0,0 +1,158 @@
+package server
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/common/storage"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestLibrarian_demoteOnce(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	hot, cold := storage.NewTestDocSLD(), storage.NewTestDocSLD()
+	tm := newTieringMetrics()
+	tiered := storage.NewTieredDocumentSLD(hot, cold, tm)
+	value, key := api.NewTestDocument(rng)
+	assert.Nil(t, tiered.Store(key, value))
+
+	config := NewDefaultConfig()
+	l := &Librarian{config: config, tiered: tiered, logger: zap.NewNop()}
+
+	// recently stored doc isn't demoted
+	l.demoteOnce()
+	assert.Len(t, hot.Stored, 1)
+	assert.Len(t, cold.Stored, 0)
+
+	config.Tiering.DemoteAfter = -time.Minute
+	l.demoteOnce()
+	assert.Len(t, hot.Stored, 0)
+	assert.Len(t, cold.Stored, 1)
+
+	// check error doesn't panic
+	hot.IterateErr = errors.New("some Iterate error")
+	l.demoteOnce()
+}
+
+func TestLibrarian_demoteDocuments(t *testing.T) {
+	// returns immediately when tiering is disabled
+	l := &Librarian{}
+	l.demoteDocuments()
+
+	config := NewDefaultConfig()
+	config.Tiering.Interval = time.Millisecond
+	l = &Librarian{
+		config: config,
+		tiered: storage.NewTieredDocumentSLD(storage.NewTestDocSLD(), storage.NewTestDocSLD(),
+			newTieringMetrics()),
+		logger: zap.NewNop(),
+		stop:   make(chan struct{}),
+	}
+	done := make(chan struct{})
+	go func() {
+		l.demoteDocuments()
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(l.stop)
+	<-done
+}
+
+func TestLibrarian_PinUnpin_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID := ecid.NewPseudoRandom(rng)
+	hot, cold := storage.NewTestDocSLD(), storage.NewTestDocSLD()
+	l := newPinLibrarian(rng, adminID, storage.NewTieredDocumentSLD(hot, cold,
+		newTieringMetrics()))
+	ctx := context.Background()
+	value, key := api.NewTestDocument(rng)
+	cold.Stored[key.String()] = value
+
+	// pinned doc is promoted and not demoted
+	rp1, err := l.Pin(ctx, client.NewPinRequest(adminID, nil, key))
+	assert.Nil(t, err)
+	assert.NotNil(t, rp1.Metadata)
+	assert.Len(t, hot.Stored, 1)
+	l.config.Tiering.DemoteAfter = -time.Minute
+	l.demoteOnce()
+	assert.Len(t, hot.Stored, 1)
+
+	// unpinned doc is demoted
+	rp2, err := l.Unpin(ctx, client.NewUnpinRequest(adminID, nil, key))
+	assert.Nil(t, err)
+	assert.NotNil(t, rp2.Metadata)
+	l.demoteOnce()
+	assert.Len(t, hot.Stored, 0)
+	assert.Len(t, cold.Stored, 1)
+}
+
+func TestLibrarian_PinUnpin_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	adminID, otherID := ecid.NewPseudoRandom(rng), ecid.NewPseudoRandom(rng)
+	ctx := context.Background()
+	key := id.NewPseudoRandom(rng)
+	hot, cold := storage.NewTestDocSLD(), storage.NewTestDocSLD()
+	tiered := storage.NewTieredDocumentSLD(hot, cold, newTieringMetrics())
+
+	// check non-admin requester denied
+	l := newPinLibrarian(rng, adminID, tiered)
+	_, err := l.Pin(ctx, client.NewPinRequest(otherID, nil, key))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+	_, err = l.Unpin(ctx, client.NewUnpinRequest(otherID, nil, key))
+	assert.Equal(t, codes.PermissionDenied, getErrCode(t, err))
+
+	// check request error bubbles up
+	pinRq := client.NewPinRequest(adminID, nil, key)
+	pinRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.Pin(ctx, pinRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+	unpinRq := client.NewUnpinRequest(adminID, nil, key)
+	unpinRq.Metadata.PubKey = []byte("bad pub key")
+	_, err = l.Unpin(ctx, unpinRq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check invalid key
+	rq := client.NewPinRequest(adminID, nil, key)
+	rq.Key = []byte{1}
+	_, err = l.Pin(ctx, rq)
+	assert.Equal(t, codes.InvalidArgument, getErrCode(t, err))
+
+	// check promotion error bubbles up
+	value, _ := api.NewTestDocument(rng)
+	cold.Stored[key.String()] = value
+	hot.StoreErr = errors.New("some Store error")
+	_, err = l.Pin(ctx, client.NewPinRequest(adminID, nil, key))
+	assert.Equal(t, codes.Internal, getErrCode(t, err))
+
+	// check tiering disabled
+	l = newPinLibrarian(rng, adminID, nil)
+	_, err = l.Pin(ctx, client.NewPinRequest(adminID, nil, key))
+	assert.Equal(t, codes.FailedPrecondition, getErrCode(t, err))
+}
+
+func newPinLibrarian(rng *rand.Rand, adminID ecid.ID, tiered storage.TieredDocumentSLD) *Librarian {
+	return &Librarian{
+		peerID: ecid.NewPseudoRandom(rng),
+		config: NewDefaultConfig().WithAdminPubKey(&adminID.Key().PublicKey),
+		rqv:    &alwaysRequestVerifier{},
+		tiered: tiered,
+		logger: zap.NewNop(),
+	}
+}
+
+func TestTieringMetrics(t *testing.T) {
+	tm := newTieringMetrics()
+	tm.register()
+	tm.Loaded(storage.Hot)
+	tm.Migrated(storage.Hot, storage.Cold)
+	tm.unregister()
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	logEndpoint = "endpoint"
+	logLatency  = "latency"
+	logCode     = "code"
+)
+
+const (
+	// RequestSpanPrefix prefixes the endpoint in the name of the span tracing the handling of a
+	// request, e.g., "librarian.Put".
+	RequestSpanPrefix = "librarian."
+
+	// span attribute keys
+	attrRequestID = "request.id"
+	attrCode      = "rpc.code"
+)
+
+// TraceSampleRates maps each endpoint to the fraction of its requests that are traced. Endpoints
+// missing from the map are never traced.
+type TraceSampleRates map[api.Endpoint]float64
+
+// NewDefaultTraceSampleRates returns the default trace sample rates, which trace all requests
+// that mutate state (Store, Put, Subscribe) but only a small fraction of the high-QPS read
+// requests.
+func NewDefaultTraceSampleRates() TraceSampleRates {
+	return TraceSampleRates{
+		api.Introduce: 0.01,
+		api.Find:      0.01,
+		api.Verify:    0.01,
+		api.Get:       0.1,
+		api.Store:     1.0,
+		api.Put:       1.0,
+		api.Subscribe: 1.0,
+		api.Sync:      0.01,
+	}
+}
+
+// tracer samples requests according to the per-endpoint rates and logs the sampled ones. It also
+// starts a span for each request as a child of the remote span whose trace context the request
+// carries, so the searches and stores the request triggers are traced with it across librarians.
+type tracer struct {
+	rates      TraceSampleRates
+	rng        *rand.Rand
+	mu         sync.Mutex
+	spans      search.Tracer
+	propagator client.TracePropagator
+	logger     *zap.Logger
+}
+
+func newTracer(rates TraceSampleRates, rng *rand.Rand, logger *zap.Logger) *tracer {
+	return &tracer{
+		rates:  rates,
+		rng:    rng,
+		spans:  search.NewNoOpTracer(),
+		logger: logger,
+	}
+}
+
+// withSpans sets the Tracer starting the requests' spans and the TracePropagator extracting the
+// requests' trace contexts, either of which may be nil.
+func (t *tracer) withSpans(spans search.Tracer, propagator client.TracePropagator) *tracer {
+	if spans != nil {
+		t.spans = spans
+	}
+	t.propagator = propagator
+	return t
+}
+
+// sample returns whether a request to the given endpoint should be traced.
+func (t *tracer) sample(e api.Endpoint) bool {
+	rate := t.rates[e]
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rng.Float64() < rate
+}
+
+// unaryInterceptor returns a unary server interceptor that traces sampled requests.
+func (t *tracer) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		e, ok := methodEndpoint(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+		ctx, span := t.startSpan(ctx, e, req)
+		start := time.Now()
+		rp, err := handler(ctx, req)
+		endRequestSpan(span, err)
+		if t.sample(e) {
+			t.trace(e, req, time.Since(start), err)
+		}
+		return rp, err
+	}
+}
+
+// streamInterceptor returns a stream server interceptor that traces sampled streams.
+func (t *tracer) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		e, ok := methodEndpoint(info.FullMethod)
+		if !ok {
+			return handler(srv, ss)
+		}
+		ctx, span := t.startSpan(ss.Context(), e, nil)
+		start := time.Now()
+		err := handler(srv, &tracedServerStream{ServerStream: ss, ctx: ctx})
+		endRequestSpan(span, err)
+		if t.sample(e) {
+			t.trace(e, nil, time.Since(start), err)
+		}
+		return err
+	}
+}
+
+// startSpan starts the span tracing the handling of a request to the endpoint, as a child of the
+// remote span whose trace context is in the incoming metadata.
+func (t *tracer) startSpan(
+	ctx context.Context, e api.Endpoint, req interface{},
+) (context.Context, search.Span) {
+	if t.propagator != nil {
+		ctx = client.ExtractTraceContext(ctx, t.propagator)
+	}
+	ctx, span := t.spans.Start(ctx, RequestSpanPrefix+e.String())
+	if rq, ok := req.(interface{ GetMetadata() *api.RequestMetadata }); ok {
+		if md := rq.GetMetadata(); md != nil {
+			span.SetAttributes(search.Attribute{Key: attrRequestID, Value: id.Hex(md.RequestId)})
+		}
+	}
+	return ctx, span
+}
+
+// endRequestSpan records the outcome of a request's handling on its span and ends it.
+func endRequestSpan(span search.Span, err error) {
+	span.SetAttributes(search.Attribute{Key: attrCode, Value: status.Code(err).String()})
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// tracedServerStream is a server stream whose context contains the stream's span.
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (t *tracer) trace(e api.Endpoint, req interface{}, latency time.Duration, err error) {
+	fields := []zap.Field{
+		zap.Stringer(logEndpoint, e),
+		zap.Duration(logLatency, latency),
+		zap.Stringer(logCode, status.Code(err)),
+	}
+	if rq, ok := req.(interface{ GetMetadata() *api.RequestMetadata }); ok {
+		if md := rq.GetMetadata(); md != nil {
+			fields = append(fields, zap.String(logRequestIDShort, id.ShortHex(md.RequestId)))
+		}
+	}
+	t.logger.Info("traced request", fields...)
+}
+
+// methodEndpoint returns the endpoint for the full gRPC method name, e.g.,
+// "/api.Librarian/Store".
+func methodEndpoint(fullMethod string) (api.Endpoint, bool) {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, e := range api.Endpoints {
+		if e.String() == method {
+			return e, true
+		}
+	}
+	return api.All, false
+}
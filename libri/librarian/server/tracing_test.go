@@ -0,0 +1,237 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/api"
+	"github.com/drausin/libri/libri/librarian/client"
+	"github.com/drausin/libri/libri/librarian/server/search"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTracer_sample(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rates := TraceSampleRates{
+		api.Store: 1.0,
+		api.Find:  0.1,
+		api.Get:   0.0,
+	}
+	tr := newTracer(rates, rng, zap.NewNop())
+	n := 10000
+	nStore, nFind, nGet, nVerify := 0, 0, 0, 0
+	for i := 0; i < n; i++ {
+		if tr.sample(api.Store) {
+			nStore++
+		}
+		if tr.sample(api.Find) {
+			nFind++
+		}
+		if tr.sample(api.Get) {
+			nGet++
+		}
+		if tr.sample(api.Verify) {
+			nVerify++
+		}
+	}
+	assert.Equal(t, n, nStore)
+	assert.InDelta(t, 0.1, float64(nFind)/float64(n), 0.02)
+	assert.Zero(t, nGet)
+	assert.Zero(t, nVerify) // missing from rates
+}
+
+func TestTracer_unaryInterceptor(t *testing.T) {
+	logs := new(bytes.Buffer)
+	rates := TraceSampleRates{api.Store: 1.0}
+	tr := newTracer(rates, rand.New(rand.NewSource(0)), newBufferLogger(logs))
+	nHandled := 0
+	handlerErr := errors.New("some handler error")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		nHandled++
+		return nil, handlerErr
+	}
+	interceptor := tr.unaryInterceptor()
+	rq := &api.StoreRequest{Metadata: &api.RequestMetadata{RequestId: []byte{1, 2, 3}}}
+
+	// sampled endpoint
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.Librarian/Store"}
+	_, err := interceptor(context.Background(), rq, info, handler)
+	assert.Equal(t, handlerErr, err)
+	assert.Equal(t, 1, nHandled)
+	assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("\n")))
+	assert.Contains(t, logs.String(), logRequestIDShort)
+
+	// unsampled endpoint
+	info = &grpc.UnaryServerInfo{FullMethod: "/api.Librarian/Find"}
+	_, err = interceptor(context.Background(), &api.FindRequest{}, info, handler)
+	assert.Equal(t, handlerErr, err)
+	assert.Equal(t, 2, nHandled)
+	assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("\n")))
+
+	// unknown method
+	info = &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	_, err = interceptor(context.Background(), nil, info, handler)
+	assert.Equal(t, handlerErr, err)
+	assert.Equal(t, 3, nHandled)
+	assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("\n")))
+}
+
+func TestTracer_streamInterceptor(t *testing.T) {
+	logs := new(bytes.Buffer)
+	rates := TraceSampleRates{api.Subscribe: 1.0}
+	tr := newTracer(rates, rand.New(rand.NewSource(0)), newBufferLogger(logs))
+	handler := func(srv interface{}, stream grpc.ServerStream) error { return nil }
+	interceptor := tr.streamInterceptor()
+
+	info := &grpc.StreamServerInfo{FullMethod: "/api.Librarian/Subscribe"}
+	err := interceptor(nil, &fixedServerStream{ctx: context.Background()}, info, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("\n")))
+}
+
+func TestTracer_unaryInterceptor_spans(t *testing.T) {
+	spans := &recordingTracer{}
+	tr := newTracer(TraceSampleRates{}, rand.New(rand.NewSource(0)), zap.NewNop()).
+		withSpans(spans, &fixedPropagator{})
+	var handledCtx context.Context
+	handlerErr := errors.New("some handler error")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handledCtx = ctx
+		return nil, handlerErr
+	}
+	interceptor := tr.unaryInterceptor()
+	rq := &api.PutRequest{Metadata: &api.RequestMetadata{RequestId: []byte{1, 2, 3}}}
+
+	// span is a child of the remote span in the incoming metadata
+	md := metadata.Pairs(testTraceHeader, "some trace")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.Librarian/Put"}
+	_, err := interceptor(ctx, rq, info, handler)
+	assert.Equal(t, handlerErr, err)
+	assert.Len(t, spans.spans, 1)
+	span := spans.spans[0]
+	assert.Equal(t, RequestSpanPrefix+"Put", span.name)
+	assert.Equal(t, "some trace", span.remote)
+	assert.Equal(t, span, handledCtx.Value(spanCtxKey{}))
+	assert.Equal(t, id.Hex(rq.Metadata.RequestId), span.attrs[attrRequestID])
+	assert.Equal(t, handlerErr, span.err)
+	assert.True(t, span.ended)
+
+	// no span for unknown method
+	info = &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	_, err = interceptor(ctx, nil, info, handler)
+	assert.Equal(t, handlerErr, err)
+	assert.Len(t, spans.spans, 1)
+}
+
+func TestTracer_streamInterceptor_spans(t *testing.T) {
+	spans := &recordingTracer{}
+	tr := newTracer(TraceSampleRates{}, rand.New(rand.NewSource(0)), zap.NewNop()).
+		withSpans(spans, nil)
+	var handledCtx context.Context
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		handledCtx = stream.Context()
+		return nil
+	}
+	interceptor := tr.streamInterceptor()
+
+	info := &grpc.StreamServerInfo{FullMethod: "/api.Librarian/Subscribe"}
+	err := interceptor(nil, &fixedServerStream{ctx: context.Background()}, info, handler)
+	assert.Nil(t, err)
+	assert.Len(t, spans.spans, 1)
+	span := spans.spans[0]
+	assert.Equal(t, RequestSpanPrefix+"Subscribe", span.name)
+	assert.Equal(t, "", span.remote)
+	assert.Equal(t, span, handledCtx.Value(spanCtxKey{}))
+	assert.Nil(t, span.err)
+	assert.True(t, span.ended)
+}
+
+func TestMethodEndpoint(t *testing.T) {
+	for _, e := range api.Endpoints {
+		e2, ok := methodEndpoint("/api.Librarian/" + e.String())
+		assert.True(t, ok)
+		assert.Equal(t, e, e2)
+	}
+	_, ok := methodEndpoint("/grpc.health.v1.Health/Check")
+	assert.False(t, ok)
+}
+
+type fixedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fixedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+const testTraceHeader = "traceparent"
+
+type traceCtxKey struct{}
+
+// fixedPropagator propagates the trace string in the context under a single header.
+type fixedPropagator struct{}
+
+func (p *fixedPropagator) Inject(ctx context.Context, carrier client.TextMapCarrier) {
+	if trace, ok := ctx.Value(traceCtxKey{}).(string); ok {
+		carrier.Set(testTraceHeader, trace)
+	}
+}
+
+func (p *fixedPropagator) Extract(
+	ctx context.Context, carrier client.TextMapCarrier,
+) context.Context {
+	if trace := carrier.Get(testTraceHeader); trace != "" {
+		return context.WithValue(ctx, traceCtxKey{}, trace)
+	}
+	return ctx
+}
+
+type spanCtxKey struct{}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, search.Span) {
+	span := &recordingSpan{name: name, attrs: make(map[string]interface{})}
+	span.remote, _ = ctx.Value(traceCtxKey{}).(string)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanCtxKey{}, span), span
+}
+
+type recordingSpan struct {
+	name   string
+	remote string
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...search.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordingSpan) End() {
+	s.ended = true
+}
+
+func newBufferLogger(buf *bytes.Buffer) *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(zapcore.NewCore(encoder, zapcore.AddSync(buf), zapcore.InfoLevel))
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/drausin/libri/libri/common/id"
+)
+
+var (
+	// errMissingParams indicates that the config lacks some of the parameters validated below.
+	errMissingParams = errors.New("missing routing, search, probe, or introduce parameters")
+
+	// errKeyspaceMismatch indicates that searches (and so stores) and the routing table use
+	// different keyspace widths.
+	errKeyspaceMismatch = errors.New("search and routing keyspace widths differ")
+
+	// errPuzzleMismatch indicates that introductions and the routing table require different
+	// puzzle difficulties of peers' IDs.
+	errPuzzleMismatch = errors.New("introduce and routing puzzle difficulties differ")
+)
+
+// validateConfig returns an error if any of the config's parameters are invalid, e.g., those that
+// would otherwise panic the server's background routines.
+func validateConfig(c *Config) error {
+	if c.Routing == nil || c.Search == nil || c.Probe == nil || c.Introduce == nil {
+		return errMissingParams
+	}
+	if err := c.Routing.Validate(); err != nil {
+		return err
+	}
+	if err := c.Search.Validate(); err != nil {
+		return err
+	}
+	if err := c.Probe.Validate(); err != nil {
+		return err
+	}
+	// stores search for the closest peers with the search parameters, so this covers them too
+	if id.WidthOrDefault(c.Search.KeyspaceWidth) != id.WidthOrDefault(c.Routing.KeyspaceWidth) {
+		return errKeyspaceMismatch
+	}
+	// the routing table's check covers peers learned of other than by introduction, and its
+	// validation that the difficulty isn't too high covers creating self's ID
+	if c.Introduce.PuzzleDifficulty != c.Routing.PuzzleDifficulty {
+		return errPuzzleMismatch
+	}
+	return nil
+}
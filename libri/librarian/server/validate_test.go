@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/ecid"
+	"github.com/drausin/libri/libri/common/id"
+	"github.com/drausin/libri/libri/librarian/server/comm"
+	"github.com/drausin/libri/libri/librarian/server/routing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	assert.Nil(t, validateConfig(NewDefaultConfig()))
+	assert.Equal(t, errMissingParams, validateConfig(&Config{}))
+
+	c := NewDefaultConfig()
+	c.Routing.CheckpointInterval = -1
+	assert.Equal(t, routing.ErrNegativeCheckpointInterval, validateConfig(c))
+
+	c = NewDefaultConfig()
+	c.Routing.CompactInterval = -time.Second
+	assert.Equal(t, routing.ErrNegativeCompactInterval, validateConfig(c))
+
+	c = NewDefaultConfig()
+	c.Search.KeyspaceWidth = id.Length + 1
+	assert.NotNil(t, validateConfig(c))
+
+	c = NewDefaultConfig()
+	c.Probe.Jitter = 1
+	assert.Equal(t, comm.ErrInvalidProbeJitter, validateConfig(c))
+
+	c = NewDefaultConfig()
+	c.Routing.KeyspaceWidth = 8
+	assert.Equal(t, errKeyspaceMismatch, validateConfig(c))
+
+	// zero widths default to the full ID length
+	c.Routing.KeyspaceWidth, c.Search.KeyspaceWidth = 0, id.Length
+	assert.Nil(t, validateConfig(c))
+
+	c = NewDefaultConfig()
+	c.Introduce.PuzzleDifficulty = 8
+	assert.Equal(t, errPuzzleMismatch, validateConfig(c))
+	c.Routing.PuzzleDifficulty = 8
+	assert.Nil(t, validateConfig(c))
+
+	c.Introduce.PuzzleDifficulty = ecid.MaxPuzzleDifficulty + 1
+	c.Routing.PuzzleDifficulty = ecid.MaxPuzzleDifficulty + 1
+	assert.Equal(t, ecid.ErrPuzzleTooDifficult, validateConfig(c))
+}
@@ -3,6 +3,7 @@ package verify
 import (
 	"bytes"
 	"container/heap"
+	"net"
 	"sync"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/drausin/libri/libri/librarian/server/peer"
 	"github.com/drausin/libri/libri/librarian/server/search"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 const verifierVerifyRetryTimeout = 25 * time.Millisecond
@@ -21,12 +23,13 @@ var (
 		"addresses")
 	errTooManyVerifyErrors = errors.New("too many Verify errors")
 	errUnexpectedVerifyMAC = errors.New("unexpected Verify MAC")
+	errVerifyCanceled      = errors.New("verify canceled")
 )
 
 // Verifier executes verifications for particular keys.
 type Verifier interface {
-	// Verify executes the verification.
-	Verify(verify *Verify, seeds []peer.Peer) error
+	// Verify executes the verification, abandoning it if the context is done.
+	Verify(ctx context.Context, verify *Verify, seeds []peer.Peer) error
 }
 
 type verifier struct {
@@ -74,9 +77,15 @@ type peerResponse struct {
 	peer     peer.Peer
 	response *api.VerifyResponse
 	err      error
+
+	// whether the query was abandoned b/c the verify was, so the error isn't the peer's fault
+	aborted bool
+
+	// whether the query was dropped by the outbound throttle, so the peer was never sent it
+	throttled bool
 }
 
-func (v *verifier) Verify(verify *Verify, seeds []peer.Peer) error {
+func (v *verifier) Verify(ctx context.Context, verify *Verify, seeds []peer.Peer) error {
 	toQuery := search.NewQueryQueue()
 	peerResponses := make(chan *peerResponse, 1)
 
@@ -108,12 +117,19 @@ func (v *verifier) Verify(verify *Verify, seeds []peer.Peer) error {
 		go func(wg4 *sync.WaitGroup) {
 			defer wg4.Done()
 			for next := range toQuery.Peers {
+				if ctx.Err() != nil {
+					// verify has been abandoned, so don't bother querying
+					peerResponses <- &peerResponse{peer: next, err: ctx.Err(), aborted: true}
+					continue
+				}
 				verify.AddQueried(next)
-				response, err := v.query(next, verify)
+				response, err := v.query(ctx, next, verify)
 				peerResponses <- &peerResponse{
-					peer:     next,
-					response: response,
-					err:      err,
+					peer:      next,
+					response:  response,
+					err:       err,
+					aborted:   err != nil && ctx.Err() != nil,
+					throttled: err == comm.ErrQueryThrottled,
 				}
 			}
 		}(&wg3)
@@ -125,13 +141,26 @@ func (v *verifier) Verify(verify *Verify, seeds []peer.Peer) error {
 	return verify.Result.FatalErr
 }
 
-func (v *verifier) query(next peer.Peer, verify *Verify) (*api.VerifyResponse, error) {
-	lc, err := v.verifierCreator.Create(next.Address().String())
+func (v *verifier) query(ctx context.Context, next peer.Peer, verify *Verify) (
+	*api.VerifyResponse, error) {
+	var rp *api.VerifyResponse
+	err := peer.QueryAddresses(next, func(address *net.TCPAddr) error {
+		var err error
+		rp, err = v.queryAddress(ctx, address, verify)
+		return err
+	})
+	return rp, err
+}
+
+func (v *verifier) queryAddress(
+	parent context.Context, address *net.TCPAddr, verify *Verify,
+) (*api.VerifyResponse, error) {
+	lc, err := v.verifierCreator.Create(address.String())
 	if err != nil {
 		return nil, err
 	}
 	rq := verify.CreateRq()
-	ctx, cancel, err := client.NewSignedTimeoutContext(v.peerSigner, v.orgSigner, rq,
+	ctx, cancel, err := client.NewSignedTimeoutContextFrom(parent, v.peerSigner, v.orgSigner, rq,
 		verify.Params.Timeout)
 	if err != nil {
 		return nil, err
@@ -150,6 +179,18 @@ func (v *verifier) query(next peer.Peer, verify *Verify) (*api.VerifyResponse, e
 }
 
 func (v *verifier) processAnyReponse(pr *peerResponse, verify *Verify) {
+	if pr.aborted {
+		verify.wrapLock(func() {
+			if verify.Result.FatalErr == nil {
+				verify.Result.FatalErr = errVerifyCanceled
+			}
+		})
+		return
+	}
+	if pr.throttled {
+		// skip the peer without counting an error against the verify
+		return
+	}
 	if pr.err != nil {
 		v.recordError(pr.peer, pr.err, verify)
 		return
@@ -168,6 +209,11 @@ func (v *verifier) recordError(p peer.Peer, err error, verify *Verify) {
 			verify.Result.FatalErr = errTooManyVerifyErrors
 		}
 	})
+	if err == errUnexpectedVerifyMAC {
+		// peer has a corrupted copy of the value
+		v.rec.Record(p.ID(), api.Verify, comm.Response, comm.IntegrityFailure)
+		return
+	}
 	comm.MaybeRecordRpErr(v.rec, p.ID(), api.Verify, err)
 }
 
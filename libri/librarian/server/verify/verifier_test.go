@@ -62,7 +62,7 @@ func TestVerifier_Verify_ok(t *testing.T) {
 		seeds := search.NewTestSeeds(peers, selfPeerIdxs)
 
 		// verify!
-		err := verifier.Verify(v, seeds)
+		err := verifier.Verify(context.Background(), v, seeds)
 
 		// checks
 		assert.Nil(t, err)
@@ -111,7 +111,7 @@ func TestVerifier_Verify_queryErr(t *testing.T) {
 	}
 
 	// do the verify!
-	err := verifierImpl.Verify(verify, seeds)
+	err := verifierImpl.Verify(context.Background(), verify, seeds)
 
 	// checks
 	assert.Equal(t, errTooManyVerifyErrors, err)
@@ -126,6 +126,24 @@ func TestVerifier_Verify_queryErr(t *testing.T) {
 	assert.Equal(t, 0, len(verify.Result.Responded))
 }
 
+func TestVerifier_Verify_canceled(t *testing.T) {
+	verifierImpl, verify, selfPeerIdxs, peers := newTestVerify()
+	seeds := search.NewTestSeeds(peers, selfPeerIdxs)
+	rec := &fixedRecorder{}
+	verifierImpl.(*verifier).rec = rec
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := verifierImpl.Verify(ctx, verify, seeds)
+
+	// abandoned queries aren't the peers' fault
+	assert.Equal(t, errVerifyCanceled, err)
+	assert.True(t, verify.Finished())
+	assert.Equal(t, 0, len(verify.Result.Errored))
+	assert.Equal(t, 0, len(verify.Result.Responded))
+	assert.Equal(t, 0, rec.nErrors)
+}
+
 func TestVerifier_Verify_rpErr(t *testing.T) {
 	verifierImpl, verify, selfPeerIdxs, peers := newTestVerify()
 	seeds := search.NewTestSeeds(peers, selfPeerIdxs)
@@ -134,7 +152,7 @@ func TestVerifier_Verify_rpErr(t *testing.T) {
 	verifierImpl.(*verifier).rp = &errResponseProcessor{}
 
 	// do the verify!
-	err := verifierImpl.Verify(verify, seeds)
+	err := verifierImpl.Verify(context.Background(), verify, seeds)
 
 	// checks
 	assert.NotNil(t, err)
@@ -169,7 +187,7 @@ func TestVerifier_query_ok(t *testing.T) {
 		rp: nil,
 	}
 
-	rp, err := verifierImpl.query(next, v)
+	rp, err := verifierImpl.query(context.Background(), next, v)
 	assert.Nil(t, err)
 	assert.NotNil(t, rp.Metadata.RequestId)
 	assert.Nil(t, rp.Mac)
@@ -229,7 +247,7 @@ func TestVerifier_query_err(t *testing.T) {
 
 	for i, c := range cases {
 		info := fmt.Sprintf("case %d", i)
-		rp, err := c.query(next, v)
+		rp, err := c.query(context.Background(), next, v)
 		assert.Nil(t, rp, info)
 		assert.NotNil(t, err, info)
 	}
@@ -263,6 +281,27 @@ func TestResponseProcessor_Process_MAC(t *testing.T) {
 	assert.Equal(t, errInvalidResponse, err)
 }
 
+func TestVerifier_recordError(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	rec := &fixedRecorder{}
+	v := &verifier{rec: rec}
+	verify := &Verify{
+		Result: NewInitialResult(id.NewPseudoRandom(rng), NewDefaultParameters()),
+		Params: NewDefaultParameters(),
+	}
+
+	// check unexpected MAC recorded as integrity failure
+	v.recordError(peer.NewTestPeer(rng, 0), errUnexpectedVerifyMAC, verify)
+	assert.Equal(t, 1, rec.nIntegrityFailures)
+	assert.Equal(t, 0, rec.nErrors)
+
+	// check other errors recorded as plain errors
+	v.recordError(peer.NewTestPeer(rng, 1), errInvalidResponse, verify)
+	assert.Equal(t, 1, rec.nIntegrityFailures)
+	assert.Equal(t, 1, rec.nErrors)
+	assert.Equal(t, 2, len(verify.Result.Errored))
+}
+
 func TestResponseProcessor_Process_Addresses(t *testing.T) {
 	rng := rand.New(rand.NewSource(int64(0)))
 	nAddresses := 6
@@ -382,16 +421,20 @@ func newPeerAddresses(rng *rand.Rand, n int) []*api.PeerAddress {
 }
 
 type fixedRecorder struct {
-	nSuccesses int
-	nErrors    int
+	nSuccesses         int
+	nErrors            int
+	nIntegrityFailures int
 }
 
 func (f *fixedRecorder) Record(
 	peerID id.ID, endpoint api.Endpoint, qt comm.QueryType, o comm.Outcome,
 ) {
-	if o == comm.Success {
+	switch o {
+	case comm.Success:
 		f.nSuccesses++
-	} else {
+	case comm.IntegrityFailure:
+		f.nIntegrityFailures++
+	default:
 		f.nErrors++
 	}
 }
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/drausin/libri/libri/common/subscribe"
+	"github.com/drausin/libri/libri/librarian/api"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// workerQueueFactor is the number of requests per worker that may wait in a pool's queue
+	// before further requests are rejected.
+	workerQueueFactor = 2
+
+	endpointLabel = "endpoint"
+)
+
+// states of a workerJob
+const (
+	jobQueued int32 = iota
+	jobRunning
+	jobAbandoned
+)
+
+var errWorkerPoolFull = status.Error(codes.ResourceExhausted, "endpoint worker pool queue full")
+
+// WorkerPoolSizes maps each endpoint to the number of workers handling its requests. Requests to
+// endpoints missing from the map are each handled in their own goroutine.
+type WorkerPoolSizes map[api.Endpoint]uint
+
+// NewDefaultWorkerPoolSizes returns the default worker pool sizes, which bound the requests that
+// search or store (Find, Store, Get, Put) and the long-lived subscriptions but leave the cheap
+// Introduce and Verify requests unbounded.
+func NewDefaultWorkerPoolSizes() WorkerPoolSizes {
+	return WorkerPoolSizes{
+		api.Find:      64,
+		api.Store:     32,
+		api.Get:       32,
+		api.Put:       16,
+		api.Subscribe: subscribe.DefaultNMaxSubscriptions,
+	}
+}
+
+// workerPools handles requests to each endpoint with a fixed number of workers, queueing those
+// that arrive while all the workers are busy and rejecting them once the queue is full.
+type workerPools struct {
+	pools   map[api.Endpoint]*workerPool
+	metrics *workerPoolMetrics
+}
+
+func newWorkerPools(sizes WorkerPoolSizes) *workerPools {
+	wps := &workerPools{
+		pools:   make(map[api.Endpoint]*workerPool),
+		metrics: newWorkerPoolMetrics(),
+	}
+	for e, nWorkers := range sizes {
+		if nWorkers == 0 {
+			continue
+		}
+		wps.pools[e] = newWorkerPool(nWorkers,
+			wps.metrics.queueDepth.WithLabelValues(e.String()),
+			wps.metrics.rejected.WithLabelValues(e.String()),
+		)
+	}
+	return wps
+}
+
+// unaryInterceptor returns a unary server interceptor that handles requests on their endpoint's
+// worker pool.
+func (wps *workerPools) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		wp, ok := wps.get(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+		var rp interface{}
+		var err error
+		if poolErr := wp.do(ctx, func() { rp, err = handler(ctx, req) }); poolErr != nil {
+			return nil, poolErr
+		}
+		return rp, err
+	}
+}
+
+// streamInterceptor returns a stream server interceptor that handles streams on their endpoint's
+// worker pool.
+func (wps *workerPools) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		wp, ok := wps.get(info.FullMethod)
+		if !ok {
+			return handler(srv, ss)
+		}
+		var err error
+		if poolErr := wp.do(ss.Context(), func() { err = handler(srv, ss) }); poolErr != nil {
+			return poolErr
+		}
+		return err
+	}
+}
+
+func (wps *workerPools) get(fullMethod string) (*workerPool, bool) {
+	e, ok := methodEndpoint(fullMethod)
+	if !ok {
+		return nil, false
+	}
+	wp, ok := wps.pools[e]
+	return wp, ok
+}
+
+// stop stops the workers after they finish the queued requests. It should only be called once no
+// more requests can arrive, e.g., after the gRPC server has stopped.
+func (wps *workerPools) stop() {
+	for _, wp := range wps.pools {
+		wp.stop()
+	}
+}
+
+type workerJob struct {
+	ctx   context.Context
+	run   func()
+	done  chan struct{}
+	state int32
+}
+
+type workerPool struct {
+	jobs       chan *workerJob
+	wg         sync.WaitGroup
+	queueDepth prom.Gauge
+	rejected   prom.Counter
+}
+
+func newWorkerPool(nWorkers uint, queueDepth prom.Gauge, rejected prom.Counter) *workerPool {
+	wp := &workerPool{
+		jobs:       make(chan *workerJob, nWorkers*workerQueueFactor),
+		queueDepth: queueDepth,
+		rejected:   rejected,
+	}
+	wp.wg.Add(int(nWorkers))
+	for c := uint(0); c < nWorkers; c++ {
+		go wp.work()
+	}
+	return wp
+}
+
+func (wp *workerPool) work() {
+	defer wp.wg.Done()
+	for job := range wp.jobs {
+		wp.queueDepth.Dec()
+		if job.ctx.Err() == nil && atomic.CompareAndSwapInt32(&job.state, jobQueued, jobRunning) {
+			// skip jobs whose requests were canceled or timed out while queued
+			job.run()
+		}
+		close(job.done)
+	}
+}
+
+// do runs the operation on one of the pool's workers and waits for it to finish. It returns
+// errWorkerPoolFull without running the operation if the pool's queue is full or the context error
+// if the context ended while the operation was queued, in which case it returns immediately
+// rather than waiting for a worker to dequeue the operation.
+func (wp *workerPool) do(ctx context.Context, operation func()) error {
+	job := &workerJob{ctx: ctx, run: operation, done: make(chan struct{})}
+	wp.queueDepth.Inc()
+	select {
+	case wp.jobs <- job:
+	default:
+		wp.queueDepth.Dec()
+		wp.rejected.Inc()
+		return errWorkerPoolFull
+	}
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&job.state, jobQueued, jobAbandoned) {
+			// the worker will skip the job once it's dequeued
+			return ctx.Err()
+		}
+		// the operation is already running, so wait for it to notice the context ended
+		<-job.done
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (wp *workerPool) stop() {
+	close(wp.jobs)
+	wp.wg.Wait()
+}
+
+type workerPoolMetrics struct {
+	queueDepth *prom.GaugeVec
+	rejected   *prom.CounterVec
+}
+
+func newWorkerPoolMetrics() *workerPoolMetrics {
+	return &workerPoolMetrics{
+		queueDepth: prom.NewGaugeVec(
+			prom.GaugeOpts{
+				Namespace: "grpc",
+				Subsystem: "server",
+				Name:      "worker_queue_depth",
+				Help:      "Number of requests waiting for an endpoint worker.",
+			},
+			[]string{endpointLabel},
+		),
+		rejected: prom.NewCounterVec(
+			prom.CounterOpts{
+				Namespace: "grpc",
+				Subsystem: "server",
+				Name:      "worker_rejected_total",
+				Help:      "Number of requests rejected because an endpoint worker queue was full.",
+			},
+			[]string{endpointLabel},
+		),
+	}
+}
+
+func (wpm *workerPoolMetrics) register() {
+	prom.MustRegister(wpm.queueDepth)
+	prom.MustRegister(wpm.rejected)
+}
+
+func (wpm *workerPoolMetrics) unregister() {
+	_ = prom.Unregister(wpm.queueDepth)
+	_ = prom.Unregister(wpm.rejected)
+}
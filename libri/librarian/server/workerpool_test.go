@@ -0,0 +1,157 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/librarian/api"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestWorkerPools_unaryInterceptor(t *testing.T) {
+	wps := newWorkerPools(WorkerPoolSizes{api.Find: 2, api.Get: 0})
+	defer wps.stop()
+	_, hasGet := wps.pools[api.Get]
+	assert.False(t, hasGet)
+
+	handlerErr := errors.New("some handler error")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, handlerErr
+	}
+	interceptor := wps.unaryInterceptor()
+	for _, method := range []string{
+		"/api.Librarian/Find",          // pooled
+		"/api.Librarian/Get",           // zero size, so unbounded
+		"/grpc.health.v1.Health/Check", // unknown method
+	} {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		rp, err := interceptor(context.Background(), "rq", info, handler)
+		assert.Equal(t, "rq", rp, method)
+		assert.Equal(t, handlerErr, err, method)
+	}
+}
+
+func TestWorkerPools_streamInterceptor(t *testing.T) {
+	wps := newWorkerPools(WorkerPoolSizes{api.Subscribe: 1})
+	defer wps.stop()
+
+	handlerErr := errors.New("some handler error")
+	nHandled := 0
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		nHandled++
+		return handlerErr
+	}
+	interceptor := wps.streamInterceptor()
+	ss := &fixedContextServerStream{ctx: context.Background()}
+	for _, method := range []string{"/api.Librarian/Subscribe", "/api.Librarian/Find"} {
+		info := &grpc.StreamServerInfo{FullMethod: method}
+		err := interceptor(nil, ss, info, handler)
+		assert.Equal(t, handlerErr, err, method)
+	}
+	assert.Equal(t, 2, nHandled)
+}
+
+func TestWorkerPool_do_full(t *testing.T) {
+	queueDepth, rejected := newTestWorkerPoolMetrics()
+	wp := newWorkerPool(1, queueDepth, rejected)
+	defer wp.stop()
+
+	// occupy the only worker
+	release, started := make(chan struct{}), make(chan struct{})
+	go func() {
+		err := wp.do(context.Background(), func() {
+			close(started)
+			<-release
+		})
+		assert.Nil(t, err)
+	}()
+	<-started
+
+	// fill the queue
+	queued := make(chan error, workerQueueFactor)
+	for c := 0; c < workerQueueFactor; c++ {
+		go func() { queued <- wp.do(context.Background(), func() {}) }()
+	}
+	for len(wp.jobs) < workerQueueFactor {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, float64(workerQueueFactor), gaugeValue(queueDepth))
+
+	// queue is full, so next request is rejected
+	err := wp.do(context.Background(), func() { assert.Fail(t, "should not run") })
+	assert.Equal(t, errWorkerPoolFull, err)
+	assert.Equal(t, 1.0, counterValue(rejected))
+
+	// queued requests run once worker is free
+	close(release)
+	for c := 0; c < workerQueueFactor; c++ {
+		assert.Nil(t, <-queued)
+	}
+	assert.Zero(t, gaugeValue(queueDepth))
+}
+
+func TestWorkerPool_do_canceled(t *testing.T) {
+	queueDepth, rejected := newTestWorkerPoolMetrics()
+	wp := newWorkerPool(1, queueDepth, rejected)
+	defer wp.stop()
+
+	// occupy the only worker
+	release, started := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = wp.do(context.Background(), func() {
+			close(started)
+			<-release
+		})
+	}()
+	<-started
+
+	// request canceled while queued is skipped
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan error, 1)
+	go func() { queued <- wp.do(ctx, func() { assert.Fail(t, "should not run") }) }()
+	for len(wp.jobs) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	// returns without waiting for the busy worker to dequeue it
+	assert.Equal(t, context.Canceled, <-queued)
+	close(release)
+}
+
+func TestWorkerPoolMetrics_register(t *testing.T) {
+	wpm := newWorkerPoolMetrics()
+	wpm.register()
+	wpm.unregister()
+}
+
+type fixedContextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *fixedContextServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+func newTestWorkerPoolMetrics() (prom.Gauge, prom.Counter) {
+	return prom.NewGauge(prom.GaugeOpts{Name: "test_queue_depth"}),
+		prom.NewCounter(prom.CounterOpts{Name: "test_rejected"})
+}
+
+func gaugeValue(g prom.Gauge) float64 {
+	written := &dto.Metric{}
+	_ = g.Write(written)
+	return *written.Gauge.Value
+}
+
+func counterValue(c prom.Counter) float64 {
+	written := &dto.Metric{}
+	_ = c.Write(written)
+	return *written.Counter.Value
+}
@@ -2,8 +2,9 @@
 
 set -eou pipefail
 
-# Build a static binary for use in Docker container. This script is mainly intended for CircleCI
-# builds and does't at the moment work locally on OSX.
+# Build a static binary for use in Docker container. The binary uses the pure-Go LevelDB storage
+# driver and secp256k1 implementation, so it needs no cgo and can be cross-compiled from any
+# platform.
 #
 # Usage:
 #
@@ -20,9 +21,9 @@ GIT_REVISION_VAR="${VERSION_PKG}.GitRevision=$(git rev-parse --short HEAD)"
 BUILD_DATE_VAR="${VERSION_PKG}.BuildDate=$(date -u +"%Y-%m-%d")"
 VERSION_VARS="-X ${GIT_BRANCH_VAR} -X ${GIT_REVISION_VAR} -X ${BUILD_DATE_VAR}"
 
-GOOS=linux go build \
-    -ldflags "-extldflags '-lpthread -static' ${VERSION_VARS}" \
+CGO_ENABLED=0 GOOS=linux go build \
+    -ldflags "${VERSION_VARS}" \
+    -tags nocgo \
     -a \
-    -installsuffix cgo \
     -o ${OUTPUT_FILE} \
     libri/main.go
@@ -27,6 +27,10 @@ var (
 
 var semverString = "0.6.0"
 
+// ProtocolVersion is the version of the librarian wire protocol, incremented whenever a change
+// to it would break compatibility with peers running older builds.
+const ProtocolVersion = uint32(1)
+
 const (
 	develop         = "develop"
 	master          = "master"